@@ -0,0 +1,251 @@
+// Package activity merges audit entries, alerts, BGP session events and
+// config versions into a single feed, newest first, so a dashboard can
+// show what happened recently with one query. A context acting for a
+// tenant only sees that tenant's alerts, session events and config
+// versions.
+package activity
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/padminisys/flintroute/internal/database"
+	"github.com/padminisys/flintroute/internal/tenancy"
+	"github.com/padminisys/flintroute/pkg/models"
+	"gorm.io/gorm"
+)
+
+// Item types, one per source
+const (
+	TypeAudit         = "audit"
+	TypeAlert         = "alert"
+	TypeSession       = "session"
+	TypeConfigVersion = "config_version"
+)
+
+// Types lists every item type, in the order items with the same time are
+// listed
+var Types = []string{TypeAudit, TypeAlert, TypeSession, TypeConfigVersion}
+
+// ErrInvalidCursor is returned for a cursor not issued by the feed
+var ErrInvalidCursor = errors.New("invalid activity cursor")
+
+// Item is an entry of the feed
+type Item struct {
+	Type     string    `json:"type"`
+	ID       uint      `json:"id"` // ID of the audit entry, alert, session event or config version
+	Time     time.Time `json:"time"`
+	Summary  string    `json:"summary"`
+	Severity string    `json:"severity,omitempty"` // alerts only
+	PeerID   *uint     `json:"peer_id,omitempty"`
+	Actor    string    `json:"actor,omitempty"` // user behind audit entries and config versions
+}
+
+// Query selects a page of the feed
+type Query struct {
+	// Types limits the feed to these item types; empty means all
+	Types []string
+	// Cursor continues from a previous page's NextCursor
+	Cursor string
+	Limit  int
+}
+
+// Page is a page of the feed. NextCursor is empty on the last page.
+type Page struct {
+	Items      []Item `json:"items"`
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// cursor is the position of the last item of a page
+type cursor struct {
+	time time.Time
+	rank int
+	id   uint
+}
+
+// Feed reads the activity feed from the database
+type Feed struct {
+	db *database.DB
+}
+
+// NewFeed creates an activity feed
+func NewFeed(db *database.DB) *Feed {
+	return &Feed{db: db}
+}
+
+// List returns a page of the feed, newest first
+func (f *Feed) List(ctx context.Context, query Query) (*Page, error) {
+	types := query.Types
+	if len(types) == 0 {
+		types = Types
+	}
+	var after *cursor
+	if query.Cursor != "" {
+		cur, err := decodeCursor(query.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		after = &cur
+	}
+
+	// Each source returns at most one item more than the page, so the
+	// merged items tell whether another page follows
+	var items []Item
+	for rank, typ := range Types {
+		if !slices.Contains(types, typ) {
+			continue
+		}
+		fetched, err := f.fetch(ctx, typ, f.after(ctx, rank, after), query.Limit+1)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list %s activity: %w", typ, err)
+		}
+		items = append(items, fetched...)
+	}
+
+	sort.Slice(items, func(i, j int) bool {
+		a, b := position(items[i]), position(items[j])
+		if !a.time.Equal(b.time) {
+			return a.time.After(b.time)
+		}
+		if a.rank != b.rank {
+			return a.rank < b.rank
+		}
+		return a.id > b.id
+	})
+
+	page := &Page{Items: items}
+	if len(items) > query.Limit {
+		page.Items = items[:query.Limit]
+		page.NextCursor = encodeCursor(position(page.Items[query.Limit-1]))
+	}
+	if page.Items == nil {
+		page.Items = []Item{}
+	}
+	return page, nil
+}
+
+// after scopes a source of the given rank to the items past cur
+func (f *Feed) after(ctx context.Context, rank int, cur *cursor) *gorm.DB {
+	tx := f.db.WithContext(ctx)
+	if cur == nil {
+		return tx
+	}
+	switch {
+	case rank < cur.rank:
+		return tx.Where("created_at < ?", cur.time)
+	case rank > cur.rank:
+		return tx.Where("created_at <= ?", cur.time)
+	default:
+		return tx.Where("created_at < ? OR (created_at = ? AND id < ?)", cur.time, cur.time, cur.id)
+	}
+}
+
+// fetch returns the newest limit items of a type
+func (f *Feed) fetch(ctx context.Context, typ string, tx *gorm.DB, limit int) ([]Item, error) {
+	tx = tx.Order("created_at DESC, id DESC").Limit(limit)
+
+	var items []Item
+	switch typ {
+	case TypeAudit:
+		var entries []models.AuditEntry
+		if err := tx.Find(&entries).Error; err != nil {
+			return nil, err
+		}
+		for _, entry := range entries {
+			summary := entry.Action
+			if entry.Target != "" {
+				summary += " " + entry.Target
+			}
+			items = append(items, Item{Type: typ, ID: entry.ID, Time: entry.CreatedAt, Summary: summary, Actor: entry.Username})
+		}
+	case TypeAlert:
+		var alerts []models.Alert
+		if err := tx.Scopes(tenancy.Scope(ctx, "alerts")).Find(&alerts).Error; err != nil {
+			return nil, err
+		}
+		for _, alert := range alerts {
+			items = append(items, Item{Type: typ, ID: alert.ID, Time: alert.CreatedAt, Summary: alert.Message, Severity: alert.Severity, PeerID: alert.PeerID})
+		}
+	case TypeSession:
+		var events []models.SessionEvent
+		if err := tx.Scopes(tenancy.PeerScope(ctx, "peer_id")).Find(&events).Error; err != nil {
+			return nil, err
+		}
+		for _, event := range events {
+			peerID := event.PeerID
+			summary := fmt.Sprintf("Session with %s changed from %s to %s", event.IPAddress, event.OldState, event.NewState)
+			if event.InMaintenance {
+				summary += " during maintenance"
+			}
+			items = append(items, Item{Type: typ, ID: event.ID, Time: event.CreatedAt, Summary: summary, PeerID: &peerID})
+		}
+	case TypeConfigVersion:
+		var versions []models.ConfigVersion
+		if err := tx.Scopes(tenancy.Scope(ctx, "config_versions")).Omit("config").Preload("User").Find(&versions).Error; err != nil {
+			return nil, err
+		}
+		for _, version := range versions {
+			summary := version.Description
+			if summary == "" {
+				summary = "Configuration saved"
+			}
+			items = append(items, Item{Type: typ, ID: version.ID, Time: version.CreatedAt, Summary: summary, Actor: version.User.Username})
+		}
+	}
+	return items, nil
+}
+
+// ValidType reports whether typ is a known item type
+func ValidType(typ string) bool {
+	return slices.Contains(Types, typ)
+}
+
+// position returns the cursor pointing at item
+func position(item Item) cursor {
+	rank := 0
+	for i, typ := range Types {
+		if typ == item.Type {
+			rank = i
+			break
+		}
+	}
+	return cursor{time: item.Time, rank: rank, id: item.ID}
+}
+
+// encodeCursor encodes a cursor as an opaque string
+func encodeCursor(cur cursor) string {
+	raw := fmt.Sprintf("%d.%d.%d", cur.time.UnixNano(), cur.rank, cur.id)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// decodeCursor parses a cursor made by encodeCursor
+func decodeCursor(s string) (cursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return cursor{}, ErrInvalidCursor
+	}
+	parts := strings.Split(string(raw), ".")
+	if len(parts) != 3 {
+		return cursor{}, ErrInvalidCursor
+	}
+	nanos, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return cursor{}, ErrInvalidCursor
+	}
+	rank, err := strconv.Atoi(parts[1])
+	if err != nil || rank < 0 || rank >= len(Types) {
+		return cursor{}, ErrInvalidCursor
+	}
+	id, err := strconv.ParseUint(parts[2], 10, 64)
+	if err != nil {
+		return cursor{}, ErrInvalidCursor
+	}
+	return cursor{time: time.Unix(0, nanos), rank: rank, id: uint(id)}, nil
+}
@@ -0,0 +1,91 @@
+package activity
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/padminisys/flintroute/internal/testutil"
+	"github.com/padminisys/flintroute/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestList(t *testing.T) {
+	ctx := context.Background()
+	db := testutil.SetupTestDB(t)
+	t.Cleanup(func() { testutil.CleanupTestDB(t, db) })
+	feed := NewFeed(db)
+
+	user := &models.User{Username: "alice", Email: "alice@example.com", PasswordHash: "x", Role: "operator"}
+	require.NoError(t, db.Create(user).Error)
+
+	base := time.Now().Add(-time.Hour)
+	peerID := uint(7)
+	require.NoError(t, db.Create(&models.AuditEntry{CreatedAt: base, Username: "admin", Action: "snapshot.create", Target: "nightly"}).Error)
+	require.NoError(t, db.Create(&models.ConfigVersion{CreatedAt: base.Add(time.Minute), Description: "Before maintenance", Config: "!", Hash: "a", CreatedBy: user.ID}).Error)
+	require.NoError(t, db.Create(&models.SessionEvent{CreatedAt: base.Add(2 * time.Minute), PeerID: peerID, IPAddress: "192.0.2.1", OldState: "Established", NewState: "Idle", InMaintenance: true}).Error)
+	// Items at the same time are ordered by type, then newest first
+	require.NoError(t, db.Create(&models.Alert{CreatedAt: base.Add(3 * time.Minute), Type: "peer_down", Severity: "critical", Message: "Peer 192.0.2.1 is down", PeerID: &peerID}).Error)
+	require.NoError(t, db.Create(&models.SessionEvent{CreatedAt: base.Add(3 * time.Minute), PeerID: peerID, IPAddress: "192.0.2.1", OldState: "Idle", NewState: "Active"}).Error)
+	require.NoError(t, db.Create(&models.SessionEvent{CreatedAt: base.Add(3 * time.Minute), PeerID: peerID, IPAddress: "192.0.2.1", OldState: "Active", NewState: "Connect"}).Error)
+
+	t.Run("Merges sources newest first", func(t *testing.T) {
+		page, err := feed.List(ctx, Query{Limit: 10})
+		require.NoError(t, err)
+		require.Len(t, page.Items, 6)
+		assert.Empty(t, page.NextCursor)
+
+		assert.Equal(t, TypeAlert, page.Items[0].Type)
+		assert.Equal(t, "critical", page.Items[0].Severity)
+		assert.Equal(t, "Session with 192.0.2.1 changed from Active to Connect", page.Items[1].Summary)
+		assert.Equal(t, "Session with 192.0.2.1 changed from Idle to Active", page.Items[2].Summary)
+		assert.Equal(t, "Session with 192.0.2.1 changed from Established to Idle during maintenance", page.Items[3].Summary)
+		assert.Equal(t, Item{Type: TypeConfigVersion, ID: 1, Time: page.Items[4].Time, Summary: "Before maintenance", Actor: "alice"}, page.Items[4])
+		assert.Equal(t, "snapshot.create nightly", page.Items[5].Summary)
+		assert.Equal(t, "admin", page.Items[5].Actor)
+	})
+
+	t.Run("Pages through the feed with cursors", func(t *testing.T) {
+		var summaries []string
+		query := Query{Limit: 2}
+		for pages := 0; ; pages++ {
+			require.Less(t, pages, 3)
+			page, err := feed.List(ctx, query)
+			require.NoError(t, err)
+			for _, item := range page.Items {
+				summaries = append(summaries, item.Summary)
+			}
+			if page.NextCursor == "" {
+				break
+			}
+			query.Cursor = page.NextCursor
+		}
+
+		all, err := feed.List(ctx, Query{Limit: 10})
+		require.NoError(t, err)
+		require.Len(t, summaries, len(all.Items))
+		for i, item := range all.Items {
+			assert.Equal(t, item.Summary, summaries[i])
+		}
+	})
+
+	t.Run("Filters by type", func(t *testing.T) {
+		page, err := feed.List(ctx, Query{Types: []string{TypeSession}, Limit: 2})
+		require.NoError(t, err)
+		require.Len(t, page.Items, 2)
+		require.NotEmpty(t, page.NextCursor)
+
+		page, err = feed.List(ctx, Query{Types: []string{TypeSession}, Cursor: page.NextCursor, Limit: 2})
+		require.NoError(t, err)
+		require.Len(t, page.Items, 1)
+		assert.Equal(t, TypeSession, page.Items[0].Type)
+		assert.Equal(t, peerID, *page.Items[0].PeerID)
+		assert.Empty(t, page.NextCursor)
+	})
+
+	t.Run("Rejects invalid cursors", func(t *testing.T) {
+		_, err := feed.List(ctx, Query{Cursor: "not-a-cursor", Limit: 10})
+		assert.ErrorIs(t, err, ErrInvalidCursor)
+	})
+}
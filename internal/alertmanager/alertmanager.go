@@ -0,0 +1,337 @@
+// Package alertmanager exchanges alerts with Prometheus Alertmanager. Alerts
+// received by an Alertmanager webhook receiver are stored as FlintRoute
+// alerts, and FlintRoute alerts are converted to Alertmanager alerts so they
+// can be pushed to or scraped by existing alerting pipelines.
+package alertmanager
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/padminisys/flintroute/internal/database"
+	"github.com/padminisys/flintroute/internal/tenancy"
+	"github.com/padminisys/flintroute/internal/websocket"
+	"github.com/padminisys/flintroute/pkg/models"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// Labels set on exported alerts. Ingested alerts are matched to peers by
+// LabelPeer.
+const (
+	LabelAlertName = "alertname"
+	LabelSeverity  = "severity"
+	LabelRouter    = "router"
+	LabelPeer      = "peer" // peer IP address
+	LabelPeerName  = "peer_name"
+	LabelRemoteASN = "remote_asn"
+	// LabelTagPrefix prefixes a label for each of the peer's tags, e.g.
+	// tag_pop="fra1"
+	LabelTagPrefix = "tag_"
+	// LabelAlertID identifies the FlintRoute alert an exported alert was
+	// created from. Ingested alerts carrying it are FlintRoute's own alerts
+	// routed back and are ignored.
+	LabelAlertID = "flintroute_alert_id"
+)
+
+// Annotations set on exported alerts and read from ingested ones
+const (
+	AnnotationSummary     = "summary"
+	AnnotationDescription = "description"
+)
+
+// Alert statuses used by the Alertmanager webhook payload
+const (
+	StatusFiring   = "firing"
+	StatusResolved = "resolved"
+)
+
+// Alert is an alert in the format accepted by Alertmanager's
+// POST /api/v2/alerts
+type Alert struct {
+	Labels       map[string]string `json:"labels"`
+	Annotations  map[string]string `json:"annotations,omitempty"`
+	StartsAt     time.Time         `json:"startsAt"`
+	EndsAt       *time.Time        `json:"endsAt,omitempty"`
+	GeneratorURL string            `json:"generatorURL,omitempty"`
+}
+
+// WebhookMessage is the payload Alertmanager sends to webhook receivers
+type WebhookMessage struct {
+	Version           string            `json:"version"`
+	GroupKey          string            `json:"groupKey"`
+	Status            string            `json:"status"`
+	Receiver          string            `json:"receiver"`
+	GroupLabels       map[string]string `json:"groupLabels"`
+	CommonLabels      map[string]string `json:"commonLabels"`
+	CommonAnnotations map[string]string `json:"commonAnnotations"`
+	ExternalURL       string            `json:"externalURL"`
+	Alerts            []WebhookAlert    `json:"alerts" binding:"dive"`
+}
+
+// WebhookAlert is a single alert in a webhook payload
+type WebhookAlert struct {
+	Status       string            `json:"status" binding:"required,oneof=firing resolved"`
+	Labels       map[string]string `json:"labels"`
+	Annotations  map[string]string `json:"annotations"`
+	StartsAt     time.Time         `json:"startsAt"`
+	EndsAt       time.Time         `json:"endsAt"`
+	GeneratorURL string            `json:"generatorURL"`
+	Fingerprint  string            `json:"fingerprint"`
+}
+
+// IngestResult counts what an ingested webhook payload changed
+type IngestResult struct {
+	Created  int `json:"created"`
+	Updated  int `json:"updated"`
+	Resolved int `json:"resolved"`
+	Ignored  int `json:"ignored"`
+}
+
+// Options configures how FlintRoute alerts are exported
+type Options struct {
+	// Router is the value of the router label; defaults to the hostname
+	Router string
+	// ExternalURL is set as the generator URL of exported alerts
+	ExternalURL string
+	// Labels are added to every exported alert
+	Labels map[string]string
+}
+
+// Service converts between FlintRoute and Alertmanager alerts
+type Service struct {
+	db      *database.DB
+	wsHub   *websocket.Hub
+	options Options
+	logger  *zap.Logger
+}
+
+// NewService creates a new Alertmanager service
+func NewService(db *database.DB, wsHub *websocket.Hub, opts Options, logger *zap.Logger) *Service {
+	if opts.Router == "" {
+		opts.Router, _ = os.Hostname()
+	}
+
+	return &Service{
+		db:      db,
+		wsHub:   wsHub,
+		options: opts,
+		logger:  logger,
+	}
+}
+
+// Ingest stores the alerts of an Alertmanager webhook payload. Firing
+// alerts create a FlintRoute alert, or update the open alert with the same
+// fingerprint; resolved alerts resolve it.
+func (s *Service) Ingest(ctx context.Context, msg *WebhookMessage) (*IngestResult, error) {
+	result := &IngestResult{}
+	for i := range msg.Alerts {
+		in := &msg.Alerts[i]
+		if _, ok := in.Labels[LabelAlertID]; ok {
+			result.Ignored++
+			continue
+		}
+
+		fingerprint := in.Fingerprint
+		if fingerprint == "" {
+			fingerprint = labelsFingerprint(in.Labels)
+		}
+
+		var existing models.Alert
+		err := s.db.Where("source = ? AND fingerprint = ? AND resolved_at IS NULL AND archived_at IS NULL", models.AlertSourceAlertmanager, fingerprint).
+			Order("id DESC").First(&existing).Error
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return result, fmt.Errorf("failed to look up alert: %w", err)
+		}
+		found := err == nil
+
+		if in.Status == StatusResolved {
+			if !found {
+				result.Ignored++
+				continue
+			}
+			resolvedAt := in.EndsAt
+			if resolvedAt.IsZero() {
+				resolvedAt = time.Now()
+			}
+			existing.ResolvedAt = &resolvedAt
+			if err := s.db.Save(&existing).Error; err != nil {
+				return result, fmt.Errorf("failed to resolve alert: %w", err)
+			}
+			result.Resolved++
+			continue
+		}
+
+		alert := s.fromAlertmanager(ctx, in)
+		alert.Fingerprint = fingerprint
+		if found {
+			existing.Type = alert.Type
+			existing.Severity = alert.Severity
+			existing.Message = alert.Message
+			existing.Details = alert.Details
+			existing.PeerID = alert.PeerID
+			existing.Labels = alert.Labels
+			if err := s.db.Save(&existing).Error; err != nil {
+				return result, fmt.Errorf("failed to update alert: %w", err)
+			}
+			result.Updated++
+			continue
+		}
+
+		if err := s.db.Create(alert).Error; err != nil {
+			return result, fmt.Errorf("failed to create alert: %w", err)
+		}
+		s.wsHub.BroadcastAlert(ctx, alert)
+		result.Created++
+	}
+
+	s.logger.Info("Ingested Alertmanager alerts",
+		zap.String("receiver", msg.Receiver),
+		zap.Int("created", result.Created),
+		zap.Int("updated", result.Updated),
+		zap.Int("resolved", result.Resolved),
+	)
+
+	return result, nil
+}
+
+// fromAlertmanager maps an Alertmanager alert to a FlintRoute alert. The
+// alertname label becomes the type, the summary annotation the message,
+// and the peer label is matched against peer IP addresses.
+func (s *Service) fromAlertmanager(ctx context.Context, in *WebhookAlert) *models.Alert {
+	alert := &models.Alert{
+		Type:     in.Labels[LabelAlertName],
+		Severity: normalizeSeverity(in.Labels[LabelSeverity]),
+		Message:  in.Annotations[AnnotationSummary],
+		Details:  in.Annotations[AnnotationDescription],
+		Source:   models.AlertSourceAlertmanager,
+		Labels:   in.Labels,
+	}
+	if alert.Type == "" {
+		alert.Type = "alertmanager"
+	}
+	if alert.Message == "" {
+		alert.Message, alert.Details = alert.Details, ""
+	}
+	if alert.Message == "" {
+		alert.Message = alert.Type
+	}
+
+	if ip := in.Labels[LabelPeer]; ip != "" {
+		var peer models.BGPPeer
+		if err := s.db.WithContext(ctx).Where("ip_address = ?", ip).First(&peer).Error; err == nil {
+			alert.PeerID = &peer.ID
+			alert.TenantID = peer.TenantID
+		}
+	}
+
+	return alert
+}
+
+// normalizeSeverity maps common Alertmanager severity labels onto
+// FlintRoute's info, warning, error and critical levels
+func normalizeSeverity(severity string) string {
+	switch strings.ToLower(severity) {
+	case "critical", "page", "emergency", "alert":
+		return "critical"
+	case "error", "high", "major":
+		return "error"
+	case "info", "informational", "low", "none", "notice":
+		return "info"
+	default:
+		return "warning"
+	}
+}
+
+// labelsFingerprint derives a stable identifier from an alert's label set,
+// for Alertmanager versions that do not send fingerprints
+func labelsFingerprint(labels map[string]string) string {
+	names := make([]string, 0, len(labels))
+	for name := range labels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	h := sha256.New()
+	for _, name := range names {
+		fmt.Fprintf(h, "%s\xff%s\xff", name, labels[name])
+	}
+	return hex.EncodeToString(h.Sum(nil))[:16]
+}
+
+// ToAlertmanager converts a FlintRoute alert to an Alertmanager alert. The
+// alert's Peer and its Tags should be preloaded for the peer labels to be
+// set.
+func (s *Service) ToAlertmanager(alert *models.Alert) *Alert {
+	labels := make(map[string]string, len(s.options.Labels)+7)
+	for name, value := range s.options.Labels {
+		labels[name] = value
+	}
+	labels[LabelAlertName] = alert.Type
+	labels[LabelSeverity] = alert.Severity
+	labels[LabelAlertID] = strconv.FormatUint(uint64(alert.ID), 10)
+	if s.options.Router != "" {
+		labels[LabelRouter] = s.options.Router
+	}
+	if alert.Peer != nil {
+		labels[LabelPeer] = alert.Peer.IPAddress
+		labels[LabelPeerName] = alert.Peer.Name
+		labels[LabelRemoteASN] = strconv.FormatUint(uint64(alert.Peer.RemoteASN), 10)
+		for _, tag := range alert.Peer.Tags {
+			labels[tagLabel(tag.Key)] = tag.Value
+		}
+	}
+
+	annotations := map[string]string{AnnotationSummary: alert.Message}
+	if alert.Details != "" {
+		annotations[AnnotationDescription] = alert.Details
+	}
+
+	return &Alert{
+		Labels:       labels,
+		Annotations:  annotations,
+		StartsAt:     alert.CreatedAt,
+		EndsAt:       alert.AcknowledgedAt,
+		GeneratorURL: s.options.ExternalURL,
+	}
+}
+
+// tagLabel returns the label name for a peer tag key. Label names only
+// allow letters, digits and underscores.
+func tagLabel(key string) string {
+	return LabelTagPrefix + strings.Map(func(r rune) rune {
+		if r == '.' || r == '-' {
+			return '_'
+		}
+		return r
+	}, key)
+}
+
+// Export returns FlintRoute's own alerts in Alertmanager format. Alerts are
+// firing until they are acknowledged; alerts acknowledged after since are
+// included as resolved so Alertmanager can close them. Only the alerts of the
+// tenant ctx acts for are exported.
+func (s *Service) Export(ctx context.Context, since time.Time) ([]*Alert, error) {
+	var alerts []*models.Alert
+	if err := s.db.WithContext(ctx).Preload("Peer.Tags").
+		Scopes(tenancy.Scope(ctx, "alerts")).
+		Where("source = ?", models.AlertSourceFlintRoute).
+		Where("acknowledged = ? OR acknowledged_at > ?", false, since).
+		Order("id").Find(&alerts).Error; err != nil {
+		return nil, err
+	}
+
+	exported := make([]*Alert, 0, len(alerts))
+	for _, alert := range alerts {
+		exported = append(exported, s.ToAlertmanager(alert))
+	}
+	return exported, nil
+}
@@ -0,0 +1,223 @@
+package alertmanager
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/padminisys/flintroute/internal/tenancy"
+	"github.com/padminisys/flintroute/internal/testutil"
+	"github.com/padminisys/flintroute/internal/websocket"
+	"github.com/padminisys/flintroute/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func setupTestService(t *testing.T) *Service {
+	t.Helper()
+
+	logger := zap.NewNop()
+	return NewService(testutil.SetupTestDB(t), websocket.NewHub(logger), Options{
+		Router:      "edge-1",
+		ExternalURL: "https://flintroute.example.com",
+		Labels:      map[string]string{"site": "fra1"},
+	}, logger)
+}
+
+func firingAlert(fingerprint string) WebhookAlert {
+	return WebhookAlert{
+		Status: StatusFiring,
+		Labels: map[string]string{
+			LabelAlertName: "BGPSessionDown",
+			LabelSeverity:  "page",
+			LabelPeer:      "192.0.2.1",
+		},
+		Annotations: map[string]string{
+			AnnotationSummary:     "BGP session to 192.0.2.1 is down",
+			AnnotationDescription: "Down for 5 minutes",
+		},
+		StartsAt:    time.Now().Add(-5 * time.Minute),
+		Fingerprint: fingerprint,
+	}
+}
+
+func TestIngest(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Creates alerts mapped to peers", func(t *testing.T) {
+		s := setupTestService(t)
+		peer := &models.BGPPeer{Name: "transit", IPAddress: "192.0.2.1", ASN: 65000, RemoteASN: 64500, Enabled: true}
+		require.NoError(t, s.db.Create(peer).Error)
+
+		result, err := s.Ingest(ctx, &WebhookMessage{Alerts: []WebhookAlert{firingAlert("abc")}})
+		require.NoError(t, err)
+		assert.Equal(t, 1, result.Created)
+
+		var alert models.Alert
+		require.NoError(t, s.db.First(&alert).Error)
+		assert.Equal(t, "BGPSessionDown", alert.Type)
+		assert.Equal(t, "critical", alert.Severity)
+		assert.Equal(t, "BGP session to 192.0.2.1 is down", alert.Message)
+		assert.Equal(t, "Down for 5 minutes", alert.Details)
+		assert.Equal(t, models.AlertSourceAlertmanager, alert.Source)
+		assert.Equal(t, "abc", alert.Fingerprint)
+		assert.Equal(t, "192.0.2.1", alert.Labels[LabelPeer])
+		require.NotNil(t, alert.PeerID)
+		assert.Equal(t, peer.ID, *alert.PeerID)
+	})
+
+	t.Run("Updates open alert with the same fingerprint", func(t *testing.T) {
+		s := setupTestService(t)
+
+		_, err := s.Ingest(ctx, &WebhookMessage{Alerts: []WebhookAlert{firingAlert("abc")}})
+		require.NoError(t, err)
+
+		again := firingAlert("abc")
+		again.Annotations[AnnotationSummary] = "Still down"
+		result, err := s.Ingest(ctx, &WebhookMessage{Alerts: []WebhookAlert{again}})
+		require.NoError(t, err)
+		assert.Equal(t, 1, result.Updated)
+
+		var alerts []models.Alert
+		require.NoError(t, s.db.Find(&alerts).Error)
+		require.Len(t, alerts, 1)
+		assert.Equal(t, "Still down", alerts[0].Message)
+	})
+
+	t.Run("Resolves open alert", func(t *testing.T) {
+		s := setupTestService(t)
+
+		_, err := s.Ingest(ctx, &WebhookMessage{Alerts: []WebhookAlert{firingAlert("abc")}})
+		require.NoError(t, err)
+
+		resolved := firingAlert("abc")
+		resolved.Status = StatusResolved
+		resolved.EndsAt = time.Now()
+		result, err := s.Ingest(ctx, &WebhookMessage{Alerts: []WebhookAlert{resolved}})
+		require.NoError(t, err)
+		assert.Equal(t, 1, result.Resolved)
+
+		var alert models.Alert
+		require.NoError(t, s.db.First(&alert).Error)
+		assert.NotNil(t, alert.ResolvedAt)
+
+		// Firing again after resolution opens a new alert
+		result, err = s.Ingest(ctx, &WebhookMessage{Alerts: []WebhookAlert{firingAlert("abc")}})
+		require.NoError(t, err)
+		assert.Equal(t, 1, result.Created)
+	})
+
+	t.Run("Ignores FlintRoute's own alerts", func(t *testing.T) {
+		s := setupTestService(t)
+
+		own := firingAlert("abc")
+		own.Labels[LabelAlertID] = "7"
+		result, err := s.Ingest(ctx, &WebhookMessage{Alerts: []WebhookAlert{own}})
+		require.NoError(t, err)
+		assert.Equal(t, 1, result.Ignored)
+
+		var count int64
+		s.db.Model(&models.Alert{}).Count(&count)
+		assert.Zero(t, count)
+	})
+
+	t.Run("Derives fingerprint from labels", func(t *testing.T) {
+		s := setupTestService(t)
+
+		_, err := s.Ingest(ctx, &WebhookMessage{Alerts: []WebhookAlert{firingAlert("")}})
+		require.NoError(t, err)
+
+		var alert models.Alert
+		require.NoError(t, s.db.First(&alert).Error)
+		assert.Equal(t, labelsFingerprint(alert.Labels), alert.Fingerprint)
+		assert.Len(t, alert.Fingerprint, 16)
+	})
+}
+
+func TestNormalizeSeverity(t *testing.T) {
+	assert.Equal(t, "critical", normalizeSeverity("critical"))
+	assert.Equal(t, "critical", normalizeSeverity("PAGE"))
+	assert.Equal(t, "error", normalizeSeverity("error"))
+	assert.Equal(t, "info", normalizeSeverity("info"))
+	assert.Equal(t, "warning", normalizeSeverity("warning"))
+	assert.Equal(t, "warning", normalizeSeverity(""))
+}
+
+func TestExport(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Maps labels and skips ingested alerts", func(t *testing.T) {
+		s := setupTestService(t)
+		peer := &models.BGPPeer{
+			Name: "transit", IPAddress: "192.0.2.1", ASN: 65000, RemoteASN: 64500, Enabled: true,
+			Tags: models.NewPeerTags(map[string]string{"pop": "fra1", "peer.type": "transit"}),
+		}
+		require.NoError(t, s.db.Create(peer).Error)
+		alert := &models.Alert{Type: "peer_down", Severity: "warning", Message: "Peer down", PeerID: &peer.ID}
+		require.NoError(t, s.db.Create(alert).Error)
+		require.NoError(t, s.db.Create(&models.Alert{
+			Type: "BGPSessionDown", Severity: "critical", Message: "Down", Source: models.AlertSourceAlertmanager,
+		}).Error)
+
+		exported, err := s.Export(ctx, time.Now())
+		require.NoError(t, err)
+		require.Len(t, exported, 1)
+
+		assert.Equal(t, map[string]string{
+			LabelAlertName:  "peer_down",
+			LabelSeverity:   "warning",
+			LabelRouter:     "edge-1",
+			LabelPeer:       "192.0.2.1",
+			LabelPeerName:   "transit",
+			LabelRemoteASN:  "64500",
+			LabelAlertID:    "1",
+			"site":          "fra1",
+			"tag_pop":       "fra1",
+			"tag_peer_type": "transit",
+		}, exported[0].Labels)
+		assert.Equal(t, "Peer down", exported[0].Annotations[AnnotationSummary])
+		assert.Equal(t, "https://flintroute.example.com", exported[0].GeneratorURL)
+		assert.Nil(t, exported[0].EndsAt)
+	})
+
+	t.Run("Includes recent acknowledgements as resolved", func(t *testing.T) {
+		s := setupTestService(t)
+		since := time.Now().Add(-time.Minute)
+		recent := time.Now()
+		old := since.Add(-time.Hour)
+		require.NoError(t, s.db.Create(&models.Alert{
+			Type: "peer_down", Severity: "warning", Message: "Recent", Acknowledged: true, AcknowledgedAt: &recent,
+		}).Error)
+		require.NoError(t, s.db.Create(&models.Alert{
+			Type: "peer_down", Severity: "warning", Message: "Old", Acknowledged: true, AcknowledgedAt: &old,
+		}).Error)
+
+		exported, err := s.Export(ctx, since)
+		require.NoError(t, err)
+		require.Len(t, exported, 1)
+		assert.Equal(t, "Recent", exported[0].Annotations[AnnotationSummary])
+		require.NotNil(t, exported[0].EndsAt)
+	})
+
+	t.Run("Exports only the tenant's alerts", func(t *testing.T) {
+		s := setupTestService(t)
+		tenant := &models.Tenant{Name: "acme"}
+		require.NoError(t, s.db.Create(tenant).Error)
+		for _, alert := range []*models.Alert{
+			{Type: "peer_down", Severity: "warning", Message: "acme", TenantID: &tenant.ID},
+			{Type: "frr_unreachable", Severity: "critical", Message: "instance"},
+		} {
+			require.NoError(t, s.db.Create(alert).Error)
+		}
+
+		exported, err := s.Export(tenancy.WithTenant(ctx, tenant.ID), time.Now())
+		require.NoError(t, err)
+		require.Len(t, exported, 1)
+		assert.Equal(t, "acme", exported[0].Annotations[AnnotationSummary])
+
+		exported, err = s.Export(ctx, time.Now())
+		require.NoError(t, err)
+		assert.Len(t, exported, 2)
+	})
+}
@@ -0,0 +1,130 @@
+package alertmanager
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Pusher periodically sends FlintRoute alerts to Alertmanager instances
+type Pusher struct {
+	service    *Service
+	urls       []string
+	interval   time.Duration
+	httpClient *http.Client
+	logger     *zap.Logger
+
+	lastPush time.Time // acknowledgements after this are pushed as resolved
+}
+
+// NewPusher creates a pusher sending alerts to the given Alertmanager base
+// URLs every interval
+func NewPusher(service *Service, urls []string, interval time.Duration, logger *zap.Logger) *Pusher {
+	return &Pusher{
+		service:    service,
+		urls:       urls,
+		interval:   interval,
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+		logger:     logger,
+		lastPush:   time.Now().Add(-interval),
+	}
+}
+
+// Push sends all firing alerts, and alerts acknowledged since the last
+// successful push as resolved, to every Alertmanager. Firing alerts expire
+// after three intervals so Alertmanager resolves them if FlintRoute stops
+// pushing.
+func (p *Pusher) Push(ctx context.Context) error {
+	started := time.Now()
+	alerts, err := p.service.Export(ctx, p.lastPush)
+	if err != nil {
+		return fmt.Errorf("failed to export alerts: %w", err)
+	}
+	if len(alerts) == 0 {
+		p.lastPush = started
+		return nil
+	}
+
+	expiry := started.Add(3 * p.interval)
+	for _, alert := range alerts {
+		if alert.EndsAt == nil {
+			alert.EndsAt = &expiry
+		}
+	}
+
+	body, err := json.Marshal(alerts)
+	if err != nil {
+		return fmt.Errorf("failed to encode alerts: %w", err)
+	}
+
+	var errs []error
+	for _, url := range p.urls {
+		if err := p.send(ctx, url, body); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", url, err))
+		}
+	}
+	if len(errs) == len(p.urls) {
+		return errors.Join(errs...)
+	}
+	for _, err := range errs {
+		p.logger.Warn("Failed to push alerts to Alertmanager", zap.Error(err))
+	}
+
+	p.lastPush = started
+	p.logger.Debug("Pushed alerts to Alertmanager", zap.Int("count", len(alerts)))
+	return nil
+}
+
+// send posts alerts to a single Alertmanager
+func (p *Pusher) send(ctx context.Context, baseURL string, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost,
+		strings.TrimSuffix(baseURL, "/")+"/api/v2/alerts", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("Alertmanager returned %s", resp.Status)
+	}
+	return nil
+}
+
+// Start pushes alerts immediately and then every interval
+func (p *Pusher) Start(ctx context.Context) {
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	p.logger.Info("Started Alertmanager push",
+		zap.Strings("urls", p.urls),
+		zap.Duration("interval", p.interval),
+	)
+
+	for {
+		if err := p.Push(ctx); err != nil {
+			p.logger.Error("Failed to push alerts to Alertmanager", zap.Error(err))
+		}
+
+		select {
+		case <-ctx.Done():
+			p.logger.Info("Stopped Alertmanager push")
+			return
+		case <-ticker.C:
+		}
+	}
+}
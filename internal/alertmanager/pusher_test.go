@@ -0,0 +1,54 @@
+package alertmanager
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/padminisys/flintroute/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestPush(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Posts firing alerts with expiry", func(t *testing.T) {
+		var received []*Alert
+		am := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "/api/v2/alerts", r.URL.Path)
+			assert.NoError(t, json.NewDecoder(r.Body).Decode(&received))
+		}))
+		defer am.Close()
+
+		s := setupTestService(t)
+		require.NoError(t, s.db.Create(&models.Alert{Type: "peer_down", Severity: "warning", Message: "Peer down"}).Error)
+
+		pusher := NewPusher(s, []string{am.URL + "/"}, time.Minute, zap.NewNop())
+		require.NoError(t, pusher.Push(ctx))
+
+		require.Len(t, received, 1)
+		assert.Equal(t, "peer_down", received[0].Labels[LabelAlertName])
+		require.NotNil(t, received[0].EndsAt)
+		assert.True(t, received[0].EndsAt.After(time.Now().Add(2*time.Minute)))
+	})
+
+	t.Run("Fails when every Alertmanager fails", func(t *testing.T) {
+		am := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusBadRequest)
+		}))
+		defer am.Close()
+
+		s := setupTestService(t)
+		require.NoError(t, s.db.Create(&models.Alert{Type: "peer_down", Severity: "warning", Message: "Peer down"}).Error)
+
+		pusher := NewPusher(s, []string{am.URL}, time.Minute, zap.NewNop())
+		lastPush := pusher.lastPush
+		assert.Error(t, pusher.Push(ctx))
+		assert.Equal(t, lastPush, pusher.lastPush)
+	})
+}
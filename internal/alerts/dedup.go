@@ -0,0 +1,208 @@
+package alerts
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/padminisys/flintroute/internal/database"
+	"github.com/padminisys/flintroute/pkg/models"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// DedupPolicy controls how repeated alerts are folded together and when
+// notifications for them are held back. Zero values disable the
+// corresponding step.
+type DedupPolicy struct {
+	// Window folds an alert into the open alert of the same type and peer
+	// last seen within it. Acknowledged, resolved and archived alerts are
+	// not open.
+	Window time.Duration
+	// StormThreshold is the number of occurrences of an alert type and peer
+	// within StormWindow past which they are no longer notified
+	StormThreshold int
+	StormWindow    time.Duration
+}
+
+// RaiseResult tells how a raised alert was stored
+type RaiseResult struct {
+	// Duplicate reports that the alert was counted on an open alert
+	Duplicate bool
+	// Suppressed reports that the alert is part of a storm and should not
+	// be notified
+	Suppressed bool
+}
+
+// Deduplicator stores alerts, counting repeats on the open alert of the
+// same type and peer, and detects alert storms
+type Deduplicator struct {
+	db     *database.DB
+	policy DedupPolicy
+	logger *zap.Logger
+
+	mu     sync.Mutex
+	recent map[string][]time.Time // occurrences within StormWindow by key
+}
+
+// NewDeduplicator creates a deduplicator
+func NewDeduplicator(db *database.DB, policy DedupPolicy, logger *zap.Logger) *Deduplicator {
+	return &Deduplicator{
+		db:     db,
+		policy: policy,
+		logger: logger,
+		recent: make(map[string][]time.Time),
+	}
+}
+
+// dedupKey identifies the alerts folded together. Alerts that aren't about
+// a peer are folded per tenant.
+func dedupKey(alert *models.Alert) string {
+	switch {
+	case alert.PeerID != nil:
+		return fmt.Sprintf("%s/%d", alert.Type, *alert.PeerID)
+	case alert.TenantID != nil:
+		return fmt.Sprintf("%s/tenant/%d", alert.Type, *alert.TenantID)
+	default:
+		return alert.Type
+	}
+}
+
+// Raise stores alert, or counts it on the open alert of the same type and
+// peer and updates that alert's severity, message and details. Either way
+// alert is set to the stored alert; its Peer is kept.
+func (d *Deduplicator) Raise(ctx context.Context, alert *models.Alert) (*RaiseResult, error) {
+	now := time.Now()
+	result := &RaiseResult{Suppressed: d.storm(dedupKey(alert), now)}
+	suppressed := 0
+	if result.Suppressed {
+		suppressed = 1
+	}
+
+	if d.policy.Window > 0 {
+		existing, err := d.openAlert(ctx, alert, now)
+		if err != nil {
+			return nil, err
+		}
+		if existing != nil {
+			err := d.db.WithContext(ctx).Model(existing).Updates(map[string]interface{}{
+				"occurrences":  gorm.Expr("occurrences + 1"),
+				"suppressed":   gorm.Expr("suppressed + ?", suppressed),
+				"last_seen_at": now,
+				"severity":     alert.Severity,
+				"message":      alert.Message,
+				"details":      alert.Details,
+			}).Error
+			if err != nil {
+				return nil, fmt.Errorf("failed to update alert: %w", err)
+			}
+			if err := d.db.WithContext(ctx).First(existing, existing.ID).Error; err != nil {
+				return nil, fmt.Errorf("failed to reload alert: %w", err)
+			}
+
+			peer := alert.Peer
+			*alert = *existing
+			alert.Peer = peer
+			result.Duplicate = true
+			return result, nil
+		}
+	}
+
+	alert.FirstSeenAt = now
+	alert.LastSeenAt = now
+	alert.Suppressed = suppressed
+	if err := d.stampTenant(ctx, alert); err != nil {
+		return nil, err
+	}
+	if err := d.db.WithContext(ctx).Omit(clause.Associations).Create(alert).Error; err != nil {
+		return nil, fmt.Errorf("failed to create alert: %w", err)
+	}
+	return result, nil
+}
+
+// stampTenant gives an alert about a peer the peer's tenant
+func (d *Deduplicator) stampTenant(ctx context.Context, alert *models.Alert) error {
+	if alert.TenantID != nil || alert.PeerID == nil {
+		return nil
+	}
+	if alert.Peer != nil {
+		alert.TenantID = alert.Peer.TenantID
+		return nil
+	}
+
+	var peer models.BGPPeer
+	err := d.db.WithContext(ctx).Unscoped().Select("id", "tenant_id").First(&peer, *alert.PeerID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to look up alert peer: %w", err)
+	}
+	alert.TenantID = peer.TenantID
+	return nil
+}
+
+// openAlert finds the open alert alert repeats, or returns nil
+func (d *Deduplicator) openAlert(ctx context.Context, alert *models.Alert, now time.Time) (*models.Alert, error) {
+	query := d.db.WithContext(ctx).
+		Where("type = ? AND source = ?", alert.Type, models.AlertSourceFlintRoute).
+		Where("acknowledged = ? AND resolved_at IS NULL AND archived_at IS NULL", false).
+		Where("last_seen_at >= ?", now.Add(-d.policy.Window))
+	switch {
+	case alert.PeerID != nil:
+		query = query.Where("peer_id = ?", *alert.PeerID)
+	case alert.TenantID != nil:
+		query = query.Where("peer_id IS NULL AND tenant_id = ?", *alert.TenantID)
+	default:
+		query = query.Where("peer_id IS NULL AND tenant_id IS NULL")
+	}
+
+	var existing models.Alert
+	if err := query.Order("id DESC").First(&existing).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to look up open alert: %w", err)
+	}
+	return &existing, nil
+}
+
+// storm records an occurrence of key and reports whether it exceeds the
+// storm threshold
+func (d *Deduplicator) storm(key string, now time.Time) bool {
+	if d.policy.StormThreshold <= 0 || d.policy.StormWindow <= 0 {
+		return false
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	cutoff := now.Add(-d.policy.StormWindow)
+	recent := d.recent[key][:0]
+	for _, seen := range d.recent[key] {
+		if seen.After(cutoff) {
+			recent = append(recent, seen)
+		}
+	}
+	recent = append(recent, now)
+	d.recent[key] = recent
+
+	// Forget keys that have gone quiet
+	for other, times := range d.recent {
+		if len(times) > 0 && !times[len(times)-1].After(cutoff) {
+			delete(d.recent, other)
+		}
+	}
+
+	if len(recent) == d.policy.StormThreshold+1 {
+		d.logger.Warn("Alert storm, suppressing notifications",
+			zap.String("alert", key),
+			zap.Int("occurrences", len(recent)),
+			zap.Duration("window", d.policy.StormWindow),
+		)
+	}
+	return len(recent) > d.policy.StormThreshold
+}
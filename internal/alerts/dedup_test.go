@@ -0,0 +1,149 @@
+package alerts
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/padminisys/flintroute/internal/testutil"
+	"github.com/padminisys/flintroute/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func peerDown(peerID uint, message string) *models.Alert {
+	return &models.Alert{Type: "peer_down", Severity: "warning", Message: message, PeerID: &peerID}
+}
+
+func TestDeduplicatorRaise(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Counts repeats on the open alert", func(t *testing.T) {
+		db := testutil.SetupTestDB(t)
+		dedup := NewDeduplicator(db, DedupPolicy{Window: time.Hour}, zap.NewNop())
+
+		first := peerDown(1, "down once")
+		result, err := dedup.Raise(ctx, first)
+		require.NoError(t, err)
+		assert.False(t, result.Duplicate)
+		assert.Equal(t, 1, first.Occurrences)
+
+		peer := &models.BGPPeer{Name: "edge"}
+		repeat := peerDown(1, "down again")
+		repeat.Peer = peer
+		result, err = dedup.Raise(ctx, repeat)
+		require.NoError(t, err)
+		assert.True(t, result.Duplicate)
+		assert.Equal(t, first.ID, repeat.ID)
+		assert.Equal(t, 2, repeat.Occurrences)
+		assert.Equal(t, "down again", repeat.Message)
+		assert.Same(t, peer, repeat.Peer)
+		assert.False(t, repeat.LastSeenAt.Before(repeat.FirstSeenAt))
+
+		// Another peer and another type are separate alerts
+		_, err = dedup.Raise(ctx, peerDown(2, "other peer"))
+		require.NoError(t, err)
+		result, err = dedup.Raise(ctx, &models.Alert{Type: "peer_up", Severity: "info", Message: "up", PeerID: first.PeerID})
+		require.NoError(t, err)
+		assert.False(t, result.Duplicate)
+
+		var count int64
+		require.NoError(t, db.Model(&models.Alert{}).Count(&count).Error)
+		assert.Equal(t, int64(3), count)
+	})
+
+	t.Run("Alerts without a peer are counted per tenant", func(t *testing.T) {
+		db := testutil.SetupTestDB(t)
+		dedup := NewDeduplicator(db, DedupPolicy{Window: time.Hour}, zap.NewNop())
+		acme, globex := uint(1), uint(2)
+
+		restored := func(tenant *uint) *models.Alert {
+			return &models.Alert{Type: "config_restored", Severity: "info", Message: "Restored", TenantID: tenant}
+		}
+		for _, tenant := range []*uint{&acme, &globex, nil} {
+			result, err := dedup.Raise(ctx, restored(tenant))
+			require.NoError(t, err)
+			assert.False(t, result.Duplicate)
+		}
+		for _, tenant := range []*uint{&acme, nil} {
+			alert := restored(tenant)
+			result, err := dedup.Raise(ctx, alert)
+			require.NoError(t, err)
+			assert.True(t, result.Duplicate)
+			assert.Equal(t, tenant, alert.TenantID)
+			assert.Equal(t, 2, alert.Occurrences)
+		}
+
+		var count int64
+		require.NoError(t, db.Model(&models.Alert{}).Count(&count).Error)
+		assert.Equal(t, int64(3), count)
+	})
+
+	t.Run("Acknowledged and stale alerts are not reopened", func(t *testing.T) {
+		db := testutil.SetupTestDB(t)
+		dedup := NewDeduplicator(db, DedupPolicy{Window: time.Hour}, zap.NewNop())
+
+		acked := peerDown(1, "acked")
+		_, err := dedup.Raise(ctx, acked)
+		require.NoError(t, err)
+		require.NoError(t, db.Model(acked).Update("acknowledged", true).Error)
+
+		stale := peerDown(2, "stale")
+		_, err = dedup.Raise(ctx, stale)
+		require.NoError(t, err)
+		require.NoError(t, db.Model(stale).UpdateColumn("last_seen_at", time.Now().Add(-2*time.Hour)).Error)
+
+		for _, alert := range []*models.Alert{peerDown(1, "again"), peerDown(2, "again")} {
+			result, err := dedup.Raise(ctx, alert)
+			require.NoError(t, err)
+			assert.False(t, result.Duplicate)
+		}
+	})
+
+	t.Run("Stores every alert without a window", func(t *testing.T) {
+		db := testutil.SetupTestDB(t)
+		dedup := NewDeduplicator(db, DedupPolicy{}, zap.NewNop())
+
+		for i := 0; i < 3; i++ {
+			result, err := dedup.Raise(ctx, peerDown(1, "down"))
+			require.NoError(t, err)
+			assert.Equal(t, RaiseResult{}, *result)
+		}
+
+		var count int64
+		require.NoError(t, db.Model(&models.Alert{}).Count(&count).Error)
+		assert.Equal(t, int64(3), count)
+	})
+
+	t.Run("Suppresses notifications during a storm", func(t *testing.T) {
+		db := testutil.SetupTestDB(t)
+		dedup := NewDeduplicator(db, DedupPolicy{Window: time.Hour, StormThreshold: 3, StormWindow: time.Minute}, zap.NewNop())
+
+		var suppressed []bool
+		alert := peerDown(1, "flap")
+		for i := 0; i < 5; i++ {
+			alert = peerDown(1, "flap")
+			result, err := dedup.Raise(ctx, alert)
+			require.NoError(t, err)
+			suppressed = append(suppressed, result.Suppressed)
+		}
+		assert.Equal(t, []bool{false, false, false, true, true}, suppressed)
+		assert.Equal(t, 5, alert.Occurrences)
+		assert.Equal(t, 2, alert.Suppressed)
+
+		// Other peers are unaffected
+		result, err := dedup.Raise(ctx, peerDown(2, "down"))
+		require.NoError(t, err)
+		assert.False(t, result.Suppressed)
+	})
+
+	t.Run("Storm ends when the window passes", func(t *testing.T) {
+		dedup := NewDeduplicator(testutil.SetupTestDB(t), DedupPolicy{StormThreshold: 1, StormWindow: time.Minute}, zap.NewNop())
+
+		now := time.Now()
+		assert.False(t, dedup.storm("peer_down/1", now))
+		assert.True(t, dedup.storm("peer_down/1", now.Add(time.Second)))
+		assert.False(t, dedup.storm("peer_down/1", now.Add(2*time.Minute)))
+	})
+}
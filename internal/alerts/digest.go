@@ -0,0 +1,201 @@
+package alerts
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/padminisys/flintroute/internal/database"
+	"github.com/padminisys/flintroute/internal/webhooks"
+	"github.com/padminisys/flintroute/pkg/models"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// digestTopAlerts is how many alerts a digest lists individually
+const digestTopAlerts = 10
+
+// DigestSummary summarises the alerts seen during a period
+type DigestSummary struct {
+	From time.Time `json:"from"`
+	To   time.Time `json:"to"`
+	// Alerts counts the alerts last seen during the period
+	Alerts     int            `json:"alerts"`
+	BySeverity map[string]int `json:"by_severity"`
+	// Suppressed counts the occurrences of those alerts held back during
+	// alert storms
+	Suppressed int `json:"suppressed"`
+	// Top lists the alerts with the most occurrences
+	Top []DigestAlert `json:"top"`
+}
+
+// DigestAlert is an alert listed in a digest
+type DigestAlert struct {
+	ID           uint      `json:"id"`
+	Type         string    `json:"type"`
+	Severity     string    `json:"severity"`
+	Message      string    `json:"message"`
+	PeerID       *uint     `json:"peer_id,omitempty"`
+	Occurrences  int       `json:"occurrences"`
+	FirstSeenAt  time.Time `json:"first_seen_at"`
+	LastSeenAt   time.Time `json:"last_seen_at"`
+	Acknowledged bool      `json:"acknowledged"`
+}
+
+// Digest periodically sends a summary of recent alerts as a webhook event
+// and, when a mailer is set, by email
+type Digest struct {
+	db       *database.DB
+	webhooks *webhooks.Service
+	mailer   *Mailer
+	logger   *zap.Logger
+}
+
+// NewDigest creates a digest sender. webhooks and mailer are optional.
+func NewDigest(db *database.DB, webhookService *webhooks.Service, mailer *Mailer, logger *zap.Logger) *Digest {
+	return &Digest{
+		db:       db,
+		webhooks: webhookService,
+		mailer:   mailer,
+		logger:   logger,
+	}
+}
+
+// Summarize summarises the alerts last seen in [from, to)
+func (d *Digest) Summarize(ctx context.Context, from, to time.Time) (*DigestSummary, error) {
+	summary := &DigestSummary{
+		From:       from,
+		To:         to,
+		BySeverity: make(map[string]int),
+		Top:        []DigestAlert{},
+	}
+	period := d.db.WithContext(ctx).Model(&models.Alert{}).
+		Where("last_seen_at >= ? AND last_seen_at < ?", from, to)
+
+	var counts []struct {
+		Severity   string
+		Count      int
+		Suppressed int
+	}
+	if err := period.Session(&gorm.Session{}).
+		Select("severity, COUNT(*) AS count, SUM(suppressed) AS suppressed").
+		Group("severity").Scan(&counts).Error; err != nil {
+		return nil, fmt.Errorf("failed to count alerts: %w", err)
+	}
+	for _, c := range counts {
+		summary.Alerts += c.Count
+		summary.Suppressed += c.Suppressed
+		summary.BySeverity[c.Severity] = c.Count
+	}
+
+	var top []models.Alert
+	if err := period.Session(&gorm.Session{}).
+		Order("occurrences DESC, last_seen_at DESC").Limit(digestTopAlerts).
+		Find(&top).Error; err != nil {
+		return nil, fmt.Errorf("failed to list alerts: %w", err)
+	}
+	for _, alert := range top {
+		summary.Top = append(summary.Top, DigestAlert{
+			ID:           alert.ID,
+			Type:         alert.Type,
+			Severity:     alert.Severity,
+			Message:      alert.Message,
+			PeerID:       alert.PeerID,
+			Occurrences:  alert.Occurrences,
+			FirstSeenAt:  alert.FirstSeenAt,
+			LastSeenAt:   alert.LastSeenAt,
+			Acknowledged: alert.Acknowledged,
+		})
+	}
+	return summary, nil
+}
+
+// Send summarises the alerts last seen in [from, to) and sends the digest.
+// Nothing is sent for a period without alerts.
+func (d *Digest) Send(ctx context.Context, from, to time.Time) (*DigestSummary, error) {
+	summary, err := d.Summarize(ctx, from, to)
+	if err != nil {
+		return nil, err
+	}
+	if summary.Alerts == 0 {
+		return summary, nil
+	}
+
+	d.webhooks.Publish(ctx, webhooks.EventAlertDigest, summary)
+	if d.mailer != nil {
+		if err := d.mailer.SendTemplate(nil, TemplateDigest, summary); err != nil {
+			return summary, err
+		}
+	}
+	return summary, nil
+}
+
+// SeverityCount is the number of alerts of a severity in a digest
+type SeverityCount struct {
+	Severity string
+	Count    int
+}
+
+// SeverityCounts returns the alert counts by severity, most severe first
+func (s *DigestSummary) SeverityCounts() []SeverityCount {
+	counts := make([]SeverityCount, 0, len(s.BySeverity))
+	for severity, count := range s.BySeverity {
+		counts = append(counts, SeverityCount{Severity: severity, Count: count})
+	}
+	sort.Slice(counts, func(i, j int) bool {
+		return severityRank(counts[i].Severity) < severityRank(counts[j].Severity)
+	})
+	return counts
+}
+
+// severityRank orders severities from most to least severe
+func severityRank(severity string) int {
+	switch severity {
+	case "critical":
+		return 0
+	case "error":
+		return 1
+	case "warning":
+		return 2
+	case "info":
+		return 3
+	}
+	return 4
+}
+
+func plural(n int) string {
+	if n == 1 {
+		return ""
+	}
+	return "s"
+}
+
+// Start sends a digest of the alerts seen during each interval until ctx
+// is cancelled
+func (d *Digest) Start(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	d.logger.Info("Started alert digest",
+		zap.Duration("interval", interval),
+		zap.Bool("email", d.mailer != nil),
+	)
+
+	from := time.Now()
+	for {
+		select {
+		case <-ctx.Done():
+			d.logger.Info("Stopped alert digest")
+			return
+		case now := <-ticker.C:
+			summary, err := d.Send(ctx, from, now)
+			if err != nil {
+				d.logger.Error("Failed to send alert digest", zap.Error(err))
+			} else if summary.Alerts > 0 {
+				d.logger.Info("Sent alert digest", zap.Int("alerts", summary.Alerts))
+			}
+			from = now
+		}
+	}
+}
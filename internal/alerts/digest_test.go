@@ -0,0 +1,87 @@
+package alerts
+
+import (
+	"context"
+	"net/smtp"
+	"testing"
+	"time"
+
+	"github.com/padminisys/flintroute/internal/testutil"
+	"github.com/padminisys/flintroute/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestDigest(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+
+	seed := func(t *testing.T) *Digest {
+		db := testutil.SetupTestDB(t)
+		for _, alert := range []models.Alert{
+			{Type: "peer_down", Severity: "warning", Message: "Peer 10.0.0.1 down", Occurrences: 40, Suppressed: 30},
+			{Type: "frr_unreachable", Severity: "critical", Message: "FRR is unreachable"},
+			{Type: "peer_up", Severity: "info", Message: "Peer 10.0.0.1 up", Occurrences: 39},
+			// Last seen before the period
+			{Type: "peer_down", Severity: "warning", Message: "Old", LastSeenAt: now.Add(-2 * time.Hour), FirstSeenAt: now.Add(-3 * time.Hour)},
+		} {
+			require.NoError(t, db.Create(&alert).Error)
+		}
+		return NewDigest(db, nil, nil, zap.NewNop())
+	}
+
+	t.Run("Summarizes alerts seen in the period", func(t *testing.T) {
+		digest := seed(t)
+
+		summary, err := digest.Summarize(ctx, now.Add(-time.Hour), now.Add(time.Minute))
+		require.NoError(t, err)
+		assert.Equal(t, 3, summary.Alerts)
+		assert.Equal(t, 30, summary.Suppressed)
+		assert.Equal(t, map[string]int{"warning": 1, "critical": 1, "info": 1}, summary.BySeverity)
+		require.Len(t, summary.Top, 3)
+		assert.Equal(t, 40, summary.Top[0].Occurrences)
+
+		email, err := DefaultTemplates().Render(TemplateDigest, summary)
+		require.NoError(t, err)
+		assert.Equal(t, "FlintRoute alert digest: 3 alerts (1 critical)", email.Subject)
+		text := email.Text
+		assert.Contains(t, text, "3 alerts: 1 critical, 1 warning, 1 info")
+		assert.Contains(t, text, "30 notifications suppressed during alert storms")
+		assert.Contains(t, text, "  40x  warning   Peer 10.0.0.1 down")
+	})
+
+	t.Run("Emails the digest", func(t *testing.T) {
+		digest := seed(t)
+		var sent []byte
+		mailer := NewMailer(MailConfig{Host: "smtp.example.com", From: "flintroute@example.com", To: []string{"noc@example.com"}})
+		mailer.send = func(addr string, auth smtp.Auth, from string, to []string, msg []byte) error {
+			assert.Equal(t, "smtp.example.com:587", addr)
+			assert.Nil(t, auth)
+			assert.Equal(t, []string{"noc@example.com"}, to)
+			sent = msg
+			return nil
+		}
+		digest.mailer = mailer
+
+		_, err := digest.Send(ctx, now.Add(-time.Hour), now.Add(time.Minute))
+		require.NoError(t, err)
+		assert.Contains(t, string(sent), "Subject: FlintRoute alert digest: 3 alerts (1 critical)\r\n")
+		assert.Contains(t, string(sent), "To: noc@example.com\r\n")
+		assert.Contains(t, string(sent), "Content-Type: multipart/alternative; boundary=")
+		assert.Contains(t, string(sent), "Content-Type: text/html; charset=UTF-8")
+	})
+
+	t.Run("Sends nothing for a quiet period", func(t *testing.T) {
+		digest := seed(t)
+		digest.mailer = NewMailer(MailConfig{Host: "smtp.example.com"})
+		digest.mailer.send = func(string, smtp.Auth, string, []string, []byte) error {
+			t.Fatal("digest sent for a quiet period")
+			return nil
+		}
+
+		summary, err := digest.Send(ctx, now.Add(time.Hour), now.Add(2*time.Hour))
+		require.NoError(t, err)
+		assert.Zero(t, summary.Alerts)
+	})
+}
@@ -0,0 +1,142 @@
+package alerts
+
+import (
+	"bytes"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MailConfig configures sending email. To are the recipients of Send.
+type MailConfig struct {
+	Host string
+	Port int
+	// Username and Password authenticate with PLAIN auth when Username is
+	// set. net/smtp only sends them over TLS or to localhost.
+	Username string
+	Password string
+	From     string
+	To       []string
+	// Templates render the emails of SendTemplate; nil uses the built-in
+	// templates
+	Templates *Templates
+}
+
+// Mailer sends email through an SMTP server, using STARTTLS when the
+// server offers it
+type Mailer struct {
+	config MailConfig
+	send   func(addr string, auth smtp.Auth, from string, to []string, msg []byte) error
+}
+
+// NewMailer creates a mailer
+func NewMailer(config MailConfig) *Mailer {
+	if config.Port == 0 {
+		config.Port = 587
+	}
+	if config.Templates == nil {
+		config.Templates = DefaultTemplates()
+	}
+	return &Mailer{config: config, send: smtp.SendMail}
+}
+
+// Send emails subject and body to the configured recipients
+func (m *Mailer) Send(subject, body string) error {
+	return m.SendTo(m.config.To, subject, body)
+}
+
+// SendTo emails subject and body to the given recipients
+func (m *Mailer) SendTo(to []string, subject, body string) error {
+	return m.SendEmail(to, &Email{Subject: subject, Text: body})
+}
+
+// Templates returns the templates the mailer renders emails with
+func (m *Mailer) Templates() *Templates {
+	return m.config.Templates
+}
+
+// SendTemplate renders the email template name with data and sends it to
+// the given recipients, or the configured ones when to is empty
+func (m *Mailer) SendTemplate(to []string, name string, data interface{}) error {
+	email, err := m.config.Templates.Render(name, data)
+	if err != nil {
+		return err
+	}
+	if len(to) == 0 {
+		to = m.config.To
+	}
+	return m.SendEmail(to, email)
+}
+
+// SendEmail sends email to the given recipients, as multipart/alternative
+// when it has an HTML body
+func (m *Mailer) SendEmail(to []string, email *Email) error {
+	var auth smtp.Auth
+	if m.config.Username != "" {
+		auth = smtp.PlainAuth("", m.config.Username, m.config.Password, m.config.Host)
+	}
+
+	addr := net.JoinHostPort(m.config.Host, strconv.Itoa(m.config.Port))
+	msg, err := m.message(to, email)
+	if err != nil {
+		return fmt.Errorf("failed to build email: %w", err)
+	}
+	if err := m.send(addr, auth, m.config.From, to, msg); err != nil {
+		return fmt.Errorf("failed to send email: %w", err)
+	}
+	return nil
+}
+
+// message renders the email to the recipients with its headers
+func (m *Mailer) message(to []string, email *Email) ([]byte, error) {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "From: %s\r\n", m.config.From)
+	fmt.Fprintf(&b, "To: %s\r\n", strings.Join(to, ", "))
+	fmt.Fprintf(&b, "Subject: %s\r\n", mime.QEncoding.Encode("UTF-8", email.Subject))
+	fmt.Fprintf(&b, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	if email.HTML == "" {
+		b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n\r\n")
+		b.WriteString(crlf(email.Text))
+		return b.Bytes(), nil
+	}
+
+	parts := multipart.NewWriter(&b)
+	fmt.Fprintf(&b, "Content-Type: multipart/alternative; boundary=%s\r\n\r\n", parts.Boundary())
+	// Clients show the last alternative they support, so HTML goes last
+	for _, part := range []struct{ contentType, body string }{
+		{"text/plain; charset=UTF-8", email.Text},
+		{"text/html; charset=UTF-8", email.HTML},
+	} {
+		w, err := parts.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		qp := quotedprintable.NewWriter(w)
+		if _, err := qp.Write([]byte(crlf(part.body))); err != nil {
+			return nil, err
+		}
+		if err := qp.Close(); err != nil {
+			return nil, err
+		}
+	}
+	if err := parts.Close(); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+// crlf converts the line endings of text to CRLF
+func crlf(text string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(text, "\r\n", "\n"), "\n", "\r\n")
+}
@@ -0,0 +1,365 @@
+package alerts
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"time"
+
+	"github.com/padminisys/flintroute/internal/database"
+	"github.com/padminisys/flintroute/pkg/models"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// Notification channels
+const (
+	ChannelEmail   = "email"
+	ChannelWebhook = "webhook"
+	ChannelNone    = "none"
+)
+
+// Severities are the alert severities, most severe first
+var Severities = []string{"critical", "error", "warning", "info"}
+
+// weekdays are the day names quiet hours use, indexed by time.Weekday
+var weekdays = []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}
+
+// ErrInvalidSettings is returned for notification settings that don't
+// validate
+var ErrInvalidSettings = errors.New("invalid notification settings")
+
+// Delivery is a notification sent, or attempted, to a user
+type Delivery struct {
+	UserID  uint   `json:"user_id"`
+	Channel string `json:"channel"`
+	Error   string `json:"error,omitempty"`
+}
+
+// Notifier notifies users of alerts on the channels their notification
+// settings pick, or the admin-set defaults for users without their own
+type Notifier struct {
+	db         *database.DB
+	mailer     *Mailer
+	httpClient *http.Client
+	logger     *zap.Logger
+}
+
+// NewNotifier creates a notifier. Without a mailer the email channel is
+// skipped.
+func NewNotifier(db *database.DB, mailer *Mailer, logger *zap.Logger) *Notifier {
+	return &Notifier{
+		db:         db,
+		mailer:     mailer,
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+		logger:     logger,
+	}
+}
+
+// ValidateSettings checks settings' severities, channels, quiet hours,
+// timezone and webhook URL
+func ValidateSettings(settings *models.NotificationSettings) error {
+	usesWebhook := false
+	for i, rule := range settings.Rules {
+		if len(rule.Channels) == 0 {
+			return fmt.Errorf("%w: rule %d has no channels", ErrInvalidSettings, i+1)
+		}
+		for _, severity := range rule.Severities {
+			if !slices.Contains(Severities, severity) {
+				return fmt.Errorf("%w: unknown severity %q in rule %d", ErrInvalidSettings, severity, i+1)
+			}
+		}
+		for _, channel := range rule.Channels {
+			switch channel {
+			case ChannelEmail, ChannelNone:
+			case ChannelWebhook:
+				usesWebhook = true
+			default:
+				return fmt.Errorf("%w: unknown channel %q in rule %d", ErrInvalidSettings, channel, i+1)
+			}
+		}
+	}
+	for i, window := range settings.QuietHours {
+		if _, err := clockMinutes(window.Start); err != nil {
+			return fmt.Errorf("%w: quiet hours %d: %v", ErrInvalidSettings, i+1, err)
+		}
+		if _, err := clockMinutes(window.End); err != nil {
+			return fmt.Errorf("%w: quiet hours %d: %v", ErrInvalidSettings, i+1, err)
+		}
+		for _, day := range window.Days {
+			if !slices.Contains(weekdays, day) {
+				return fmt.Errorf("%w: unknown day %q in quiet hours %d", ErrInvalidSettings, day, i+1)
+			}
+		}
+	}
+	for _, severity := range settings.QuietHoursBypass {
+		if !slices.Contains(Severities, severity) {
+			return fmt.Errorf("%w: unknown severity %q in quiet_hours_bypass", ErrInvalidSettings, severity)
+		}
+	}
+	if _, err := time.LoadLocation(settings.Timezone); err != nil {
+		return fmt.Errorf("%w: unknown timezone %q", ErrInvalidSettings, settings.Timezone)
+	}
+	if settings.WebhookURL != "" {
+		u, err := url.Parse(settings.WebhookURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("%w: webhook_url must be an http or https URL", ErrInvalidSettings)
+		}
+	} else if usesWebhook {
+		return fmt.Errorf("%w: the webhook channel needs a webhook_url", ErrInvalidSettings)
+	}
+	return nil
+}
+
+// clockMinutes parses "HH:MM" into minutes past midnight
+func clockMinutes(clock string) (int, error) {
+	t, err := time.Parse("15:04", clock)
+	if err != nil {
+		return 0, fmt.Errorf("%q is not a time of day (HH:MM)", clock)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// Channels returns the channels settings notify alert on at now: those of
+// the first rule matching it, none during quiet hours unless its severity
+// bypasses them
+func Channels(settings *models.NotificationSettings, alert *models.Alert, now time.Time) []string {
+	var channels []string
+	for _, rule := range settings.Rules {
+		if (len(rule.Severities) == 0 || slices.Contains(rule.Severities, alert.Severity)) &&
+			(len(rule.Types) == 0 || slices.Contains(rule.Types, alert.Type)) {
+			channels = rule.Channels
+			break
+		}
+	}
+	channels = slices.DeleteFunc(slices.Clone(channels), func(channel string) bool { return channel == ChannelNone })
+	if len(channels) == 0 {
+		return nil
+	}
+	if !slices.Contains(settings.QuietHoursBypass, alert.Severity) && InQuietHours(settings, now) {
+		return nil
+	}
+	return channels
+}
+
+// InQuietHours reports whether now falls in one of settings' quiet hours,
+// in its timezone. A window ending the next day belongs to the day it
+// starts on.
+func InQuietHours(settings *models.NotificationSettings, now time.Time) bool {
+	if loc, err := time.LoadLocation(settings.Timezone); err == nil {
+		now = now.In(loc)
+	}
+	minute := now.Hour()*60 + now.Minute()
+	today := weekdays[now.Weekday()]
+	yesterday := weekdays[(now.Weekday()+6)%7]
+
+	for _, window := range settings.QuietHours {
+		start, err := clockMinutes(window.Start)
+		if err != nil {
+			continue
+		}
+		end, err := clockMinutes(window.End)
+		if err != nil {
+			continue
+		}
+		on := func(day string) bool { return len(window.Days) == 0 || slices.Contains(window.Days, day) }
+
+		if start <= end {
+			if on(today) && minute >= start && minute < end {
+				return true
+			}
+			continue
+		}
+		// Past midnight: the evening of today or the morning after yesterday
+		if (on(today) && minute >= start) || (on(yesterday) && minute < end) {
+			return true
+		}
+	}
+	return false
+}
+
+// Defaults returns the settings of users without their own. Until admins
+// set them nobody is notified.
+func (n *Notifier) Defaults(ctx context.Context) (*models.NotificationSettings, error) {
+	return n.settings(ctx, 0)
+}
+
+// SetDefaults validates and stores the settings of users without their own
+func (n *Notifier) SetDefaults(ctx context.Context, settings *models.NotificationSettings) error {
+	return n.save(ctx, 0, settings)
+}
+
+// UserSettings returns a user's settings, or the defaults when the user has
+// none, reporting which
+func (n *Notifier) UserSettings(ctx context.Context, userID uint) (settings *models.NotificationSettings, inherited bool, err error) {
+	settings, err = n.settings(ctx, userID)
+	if err != nil {
+		return nil, false, err
+	}
+	if settings.ID != 0 {
+		return settings, false, nil
+	}
+	settings, err = n.Defaults(ctx)
+	return settings, true, err
+}
+
+// SetUserSettings validates and stores a user's settings
+func (n *Notifier) SetUserSettings(ctx context.Context, userID uint, settings *models.NotificationSettings) error {
+	return n.save(ctx, userID, settings)
+}
+
+// ResetUserSettings drops a user's settings, so the defaults apply again
+func (n *Notifier) ResetUserSettings(ctx context.Context, userID uint) error {
+	if err := n.db.WithContext(ctx).Where("user_id = ?", userID).Delete(&models.NotificationSettings{}).Error; err != nil {
+		return fmt.Errorf("failed to reset notification settings: %w", err)
+	}
+	return nil
+}
+
+// settings loads the settings stored for userID, or empty settings
+func (n *Notifier) settings(ctx context.Context, userID uint) (*models.NotificationSettings, error) {
+	var settings models.NotificationSettings
+	err := n.db.WithContext(ctx).Where("user_id = ?", userID).First(&settings).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return &models.NotificationSettings{UserID: userID, Rules: []models.NotificationRule{}, QuietHours: []models.QuietHours{}}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load notification settings: %w", err)
+	}
+	return &settings, nil
+}
+
+// save validates settings and stores them for userID, replacing any stored
+// before
+func (n *Notifier) save(ctx context.Context, userID uint, settings *models.NotificationSettings) error {
+	if err := ValidateSettings(settings); err != nil {
+		return err
+	}
+	if settings.Rules == nil {
+		settings.Rules = []models.NotificationRule{}
+	}
+	if settings.QuietHours == nil {
+		settings.QuietHours = []models.QuietHours{}
+	}
+
+	existing, err := n.settings(ctx, userID)
+	if err != nil {
+		return err
+	}
+	settings.ID = existing.ID
+	settings.CreatedAt = existing.CreatedAt
+	settings.UserID = userID
+	if err := n.db.WithContext(ctx).Save(settings).Error; err != nil {
+		return fmt.Errorf("failed to save notification settings: %w", err)
+	}
+	return nil
+}
+
+// Notify notifies users of a newly raised alert in the background
+func (n *Notifier) Notify(ctx context.Context, alert *models.Alert) {
+	if n == nil {
+		return
+	}
+	alertCopy := *alert
+	go func() {
+		if _, err := n.Dispatch(context.WithoutCancel(ctx), &alertCopy, time.Now()); err != nil {
+			n.logger.Error("Failed to notify users of alert", zap.Uint("alert_id", alert.ID), zap.Error(err))
+		}
+	}()
+}
+
+// Dispatch sends alert to every active user on the channels their settings
+// pick at now, returning what was sent
+func (n *Notifier) Dispatch(ctx context.Context, alert *models.Alert, now time.Time) ([]Delivery, error) {
+	var users []models.User
+	if err := n.db.WithContext(ctx).Where("active = ?", true).Order("id").Find(&users).Error; err != nil {
+		return nil, fmt.Errorf("failed to list users: %w", err)
+	}
+	var stored []models.NotificationSettings
+	if err := n.db.WithContext(ctx).Find(&stored).Error; err != nil {
+		return nil, fmt.Errorf("failed to load notification settings: %w", err)
+	}
+	byUser := make(map[uint]*models.NotificationSettings, len(stored))
+	for i := range stored {
+		byUser[stored[i].UserID] = &stored[i]
+	}
+	defaults := byUser[0]
+
+	var deliveries []Delivery
+	for _, user := range users {
+		settings := byUser[user.ID]
+		if settings == nil {
+			settings = defaults
+		}
+		if settings == nil {
+			continue
+		}
+
+		for _, channel := range Channels(settings, alert, now) {
+			var err error
+			switch channel {
+			case ChannelEmail:
+				if n.mailer == nil || user.Email == "" {
+					continue
+				}
+				err = n.mailer.SendTemplate([]string{user.Email}, TemplateAlert, alert)
+			case ChannelWebhook:
+				if settings.WebhookURL == "" {
+					continue
+				}
+				err = n.post(ctx, settings.WebhookURL, &user, alert)
+			default:
+				continue
+			}
+
+			delivery := Delivery{UserID: user.ID, Channel: channel}
+			if err != nil {
+				delivery.Error = err.Error()
+				n.logger.Warn("Failed to notify user of alert",
+					zap.String("user", user.Username),
+					zap.String("channel", channel),
+					zap.Uint("alert_id", alert.ID),
+					zap.Error(err),
+				)
+			}
+			deliveries = append(deliveries, delivery)
+		}
+	}
+	return deliveries, nil
+}
+
+// notificationPayload is the body posted to a user's webhook
+type notificationPayload struct {
+	Event string        `json:"event"`
+	User  string        `json:"user"`
+	Alert *models.Alert `json:"alert"`
+}
+
+// post sends alert to a user's webhook
+func (n *Notifier) post(ctx context.Context, webhookURL string, user *models.User, alert *models.Alert) error {
+	body, err := json.Marshal(notificationPayload{Event: "alert", User: user.Username, Alert: alert})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhookURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "FlintRoute-Notifications")
+
+	resp, err := n.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook responded %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,179 @@
+package alerts
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/smtp"
+	"testing"
+	"time"
+
+	"github.com/padminisys/flintroute/internal/testutil"
+	"github.com/padminisys/flintroute/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestChannels(t *testing.T) {
+	settings := &models.NotificationSettings{
+		Rules: []models.NotificationRule{
+			{Types: []string{"peer_up"}, Channels: []string{ChannelNone}},
+			{Severities: []string{"critical", "error"}, Channels: []string{ChannelEmail, ChannelWebhook}},
+			{Severities: []string{"warning"}, Channels: []string{ChannelWebhook}},
+		},
+		QuietHours: []models.QuietHours{
+			{Start: "22:00", End: "07:00"},
+			{Days: []string{"sat", "sun"}, Start: "00:00", End: "23:59"},
+		},
+		QuietHoursBypass: []string{"critical"},
+		Timezone:         "Europe/Berlin",
+	}
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	require.NoError(t, err)
+	wednesdayNoon := time.Date(2025, 1, 8, 12, 0, 0, 0, berlin)
+	wednesdayNight := time.Date(2025, 1, 8, 23, 30, 0, 0, berlin)
+
+	alert := func(alertType, severity string) *models.Alert {
+		return &models.Alert{Type: alertType, Severity: severity}
+	}
+
+	t.Run("The first matching rule picks the channels", func(t *testing.T) {
+		assert.Equal(t, []string{ChannelEmail, ChannelWebhook}, Channels(settings, alert("peer_down", "error"), wednesdayNoon))
+		assert.Equal(t, []string{ChannelWebhook}, Channels(settings, alert("peer_down", "warning"), wednesdayNoon))
+		assert.Empty(t, Channels(settings, alert("peer_up", "critical"), wednesdayNoon))
+		assert.Empty(t, Channels(settings, alert("peer_down", "info"), wednesdayNoon))
+	})
+
+	t.Run("Quiet hours hold back all but bypassing severities", func(t *testing.T) {
+		assert.Empty(t, Channels(settings, alert("peer_down", "error"), wednesdayNight))
+		assert.Equal(t, []string{ChannelEmail, ChannelWebhook}, Channels(settings, alert("peer_down", "critical"), wednesdayNight))
+	})
+
+	t.Run("Quiet hours are in the user's timezone", func(t *testing.T) {
+		// 22:30 UTC is 23:30 in Berlin
+		assert.True(t, InQuietHours(settings, time.Date(2025, 1, 8, 22, 30, 0, 0, time.UTC)))
+		assert.False(t, InQuietHours(settings, time.Date(2025, 1, 8, 20, 30, 0, 0, time.UTC)))
+		// Past midnight the window started the evening before
+		assert.True(t, InQuietHours(settings, time.Date(2025, 1, 9, 6, 59, 0, 0, berlin)))
+		assert.False(t, InQuietHours(settings, time.Date(2025, 1, 9, 7, 0, 0, 0, berlin)))
+		// Weekends are quiet all day
+		assert.True(t, InQuietHours(settings, time.Date(2025, 1, 11, 12, 0, 0, 0, berlin)))
+	})
+}
+
+func TestValidateSettings(t *testing.T) {
+	valid := func() *models.NotificationSettings {
+		return &models.NotificationSettings{
+			Rules:      []models.NotificationRule{{Severities: []string{"critical"}, Channels: []string{ChannelWebhook}}},
+			QuietHours: []models.QuietHours{{Days: []string{"mon"}, Start: "22:00", End: "06:00"}},
+			Timezone:   "America/New_York",
+			WebhookURL: "https://hooks.example.com/noc",
+		}
+	}
+	require.NoError(t, ValidateSettings(valid()))
+
+	for name, change := range map[string]func(*models.NotificationSettings){
+		"unknown severity":      func(s *models.NotificationSettings) { s.Rules[0].Severities = []string{"fatal"} },
+		"unknown channel":       func(s *models.NotificationSettings) { s.Rules[0].Channels = []string{"sms"} },
+		"rule without channels": func(s *models.NotificationSettings) { s.Rules[0].Channels = nil },
+		"bad quiet hours":       func(s *models.NotificationSettings) { s.QuietHours[0].End = "25:00" },
+		"unknown day":           func(s *models.NotificationSettings) { s.QuietHours[0].Days = []string{"monday"} },
+		"unknown timezone":      func(s *models.NotificationSettings) { s.Timezone = "Mars/Olympus" },
+		"webhook without URL":   func(s *models.NotificationSettings) { s.WebhookURL = "" },
+		"webhook URL not HTTP":  func(s *models.NotificationSettings) { s.WebhookURL = "ftp://example.com" },
+		"unknown bypass":        func(s *models.NotificationSettings) { s.QuietHoursBypass = []string{"urgent"} },
+	} {
+		t.Run(name, func(t *testing.T) {
+			settings := valid()
+			change(settings)
+			assert.ErrorIs(t, ValidateSettings(settings), ErrInvalidSettings)
+		})
+	}
+}
+
+func TestNotifier(t *testing.T) {
+	ctx := context.Background()
+	db := testutil.SetupTestDB(t)
+
+	var posted []notificationPayload
+	hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload notificationPayload
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&payload))
+		posted = append(posted, payload)
+	}))
+	defer hook.Close()
+
+	var mailed []string
+	mailer := NewMailer(MailConfig{Host: "smtp.example.com", From: "flintroute@example.com"})
+	mailer.send = func(addr string, auth smtp.Auth, from string, to []string, msg []byte) error {
+		mailed = append(mailed, to...)
+		return nil
+	}
+	notifier := NewNotifier(db, mailer, zap.NewNop())
+
+	users := []models.User{
+		{Username: "alice", PasswordHash: "x", Email: "alice@example.com", Role: "operator", Active: true},
+		{Username: "bob", PasswordHash: "x", Email: "bob@example.com", Role: "user", Active: true},
+		{Username: "carol", PasswordHash: "x", Email: "carol@example.com", Role: "user", Active: true},
+	}
+	for i := range users {
+		require.NoError(t, db.Create(&users[i]).Error)
+	}
+	require.NoError(t, db.Model(&users[2]).Update("active", false).Error)
+	// Leave the seeded admin out of the recipients
+	require.NoError(t, db.Model(&models.User{}).Where("username = ?", "admin").Update("active", false).Error)
+
+	alert := &models.Alert{ID: 7, Type: "peer_down", Severity: "warning", Message: "Peer 10.0.0.1 down"}
+
+	t.Run("Nobody is notified without defaults", func(t *testing.T) {
+		deliveries, err := notifier.Dispatch(ctx, alert, time.Now())
+		require.NoError(t, err)
+		assert.Empty(t, deliveries)
+
+		settings, inherited, err := notifier.UserSettings(ctx, users[0].ID)
+		require.NoError(t, err)
+		assert.True(t, inherited)
+		assert.Empty(t, settings.Rules)
+	})
+
+	t.Run("Users without settings get the defaults", func(t *testing.T) {
+		require.NoError(t, notifier.SetDefaults(ctx, &models.NotificationSettings{
+			Rules: []models.NotificationRule{{Channels: []string{ChannelEmail}}},
+		}))
+		require.NoError(t, notifier.SetUserSettings(ctx, users[1].ID, &models.NotificationSettings{
+			Rules:      []models.NotificationRule{{Severities: []string{"warning"}, Channels: []string{ChannelWebhook}}},
+			WebhookURL: hook.URL,
+		}))
+
+		deliveries, err := notifier.Dispatch(ctx, alert, time.Now())
+		require.NoError(t, err)
+		assert.Equal(t, []Delivery{
+			{UserID: users[0].ID, Channel: ChannelEmail},
+			{UserID: users[1].ID, Channel: ChannelWebhook},
+		}, deliveries)
+		assert.Equal(t, []string{"alice@example.com"}, mailed)
+		require.Len(t, posted, 1)
+		assert.Equal(t, "bob", posted[0].User)
+		assert.Equal(t, "Peer 10.0.0.1 down", posted[0].Alert.Message)
+
+		settings, inherited, err := notifier.UserSettings(ctx, users[1].ID)
+		require.NoError(t, err)
+		assert.False(t, inherited)
+		assert.Equal(t, hook.URL, settings.WebhookURL)
+	})
+
+	t.Run("Resetting falls back to the defaults", func(t *testing.T) {
+		require.NoError(t, notifier.ResetUserSettings(ctx, users[1].ID))
+		settings, inherited, err := notifier.UserSettings(ctx, users[1].ID)
+		require.NoError(t, err)
+		assert.True(t, inherited)
+		assert.Equal(t, []string{ChannelEmail}, settings.Rules[0].Channels)
+	})
+
+	t.Run("Invalid settings are not stored", func(t *testing.T) {
+		err := notifier.SetUserSettings(ctx, users[0].ID, &models.NotificationSettings{Timezone: "Nowhere"})
+		assert.ErrorIs(t, err, ErrInvalidSettings)
+	})
+}
@@ -0,0 +1,196 @@
+// Package alerts implements alert housekeeping: archiving old alerts that
+// need no further attention and purging alerts past their retention period.
+package alerts
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/padminisys/flintroute/internal/database"
+	"github.com/padminisys/flintroute/pkg/models"
+	"go.uber.org/zap"
+)
+
+// purgeBatchSize is how many alerts are exported and deleted at a time
+const purgeBatchSize = 500
+
+// RetentionPolicy controls when alerts are archived and deleted. Zero
+// durations disable the corresponding step.
+type RetentionPolicy struct {
+	// ArchiveAfter archives acknowledged or resolved alerts older than this
+	ArchiveAfter time.Duration
+	// DeleteAfter permanently deletes alerts of any state older than this
+	DeleteAfter time.Duration
+	// ExportDir receives an NDJSON file of the alerts before they are
+	// deleted; empty skips the export
+	ExportDir string
+}
+
+// RetentionResult summarises one retention run
+type RetentionResult struct {
+	Archived int64
+	Deleted  int64
+	// ExportFile is the NDJSON file purged alerts were written to, if any
+	ExportFile string
+}
+
+// Retention applies a RetentionPolicy to stored alerts
+type Retention struct {
+	db     *database.DB
+	policy RetentionPolicy
+	logger *zap.Logger
+}
+
+// NewRetention creates a retention job
+func NewRetention(db *database.DB, policy RetentionPolicy, logger *zap.Logger) *Retention {
+	return &Retention{
+		db:     db,
+		policy: policy,
+		logger: logger,
+	}
+}
+
+// Apply archives and purges alerts relative to now
+func (r *Retention) Apply(ctx context.Context, now time.Time) (*RetentionResult, error) {
+	result := &RetentionResult{}
+
+	if r.policy.ArchiveAfter > 0 {
+		cutoff := now.Add(-r.policy.ArchiveAfter)
+		tx := r.db.WithContext(ctx).Model(&models.Alert{}).
+			Where("archived_at IS NULL AND created_at < ?", cutoff).
+			Where("acknowledged = ? OR resolved_at IS NOT NULL", true).
+			Update("archived_at", now)
+		if tx.Error != nil {
+			return result, fmt.Errorf("failed to archive alerts: %w", tx.Error)
+		}
+		result.Archived = tx.RowsAffected
+	}
+
+	if r.policy.DeleteAfter > 0 {
+		if err := r.purge(ctx, now, result); err != nil {
+			return result, err
+		}
+	}
+
+	return result, nil
+}
+
+// purge exports and deletes alerts older than DeleteAfter in batches. A
+// batch is only deleted once it has been written to the export file.
+func (r *Retention) purge(ctx context.Context, now time.Time, result *RetentionResult) error {
+	cutoff := now.Add(-r.policy.DeleteAfter)
+
+	var export *os.File
+	var writer *bufio.Writer
+	defer func() {
+		if export != nil {
+			export.Close()
+		}
+	}()
+
+	for {
+		var batch []models.Alert
+		if err := r.db.WithContext(ctx).Unscoped().
+			Where("created_at < ?", cutoff).
+			Order("id").Limit(purgeBatchSize).
+			Find(&batch).Error; err != nil {
+			return fmt.Errorf("failed to load alerts to purge: %w", err)
+		}
+		if len(batch) == 0 {
+			return nil
+		}
+
+		if r.policy.ExportDir != "" {
+			if export == nil {
+				var err error
+				export, err = createExportFile(r.policy.ExportDir, now)
+				if err != nil {
+					return err
+				}
+				writer = bufio.NewWriter(export)
+				result.ExportFile = export.Name()
+			}
+			if err := writeNDJSON(writer, batch); err != nil {
+				return fmt.Errorf("failed to export alerts: %w", err)
+			}
+			if err := writer.Flush(); err != nil {
+				return fmt.Errorf("failed to export alerts: %w", err)
+			}
+			if err := export.Sync(); err != nil {
+				return fmt.Errorf("failed to export alerts: %w", err)
+			}
+		}
+
+		ids := make([]uint, len(batch))
+		for i, alert := range batch {
+			ids[i] = alert.ID
+		}
+		tx := r.db.WithContext(ctx).Unscoped().Delete(&models.Alert{}, ids)
+		if tx.Error != nil {
+			return fmt.Errorf("failed to delete alerts: %w", tx.Error)
+		}
+		result.Deleted += tx.RowsAffected
+	}
+}
+
+// createExportFile creates a new timestamped NDJSON file in dir
+func createExportFile(dir string, now time.Time) (*os.File, error) {
+	if err := os.MkdirAll(dir, 0750); err != nil {
+		return nil, fmt.Errorf("failed to create alert export directory: %w", err)
+	}
+
+	path := filepath.Join(dir, fmt.Sprintf("alerts-%s.ndjson", now.UTC().Format("20060102T150405Z")))
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0640)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create alert export file: %w", err)
+	}
+	return file, nil
+}
+
+// writeNDJSON writes one JSON-encoded alert per line
+func writeNDJSON(w *bufio.Writer, alerts []models.Alert) error {
+	encoder := json.NewEncoder(w)
+	for i := range alerts {
+		if err := encoder.Encode(&alerts[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Start applies the policy every interval until ctx is cancelled
+func (r *Retention) Start(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	r.logger.Info("Started alert retention",
+		zap.Duration("archive_after", r.policy.ArchiveAfter),
+		zap.Duration("delete_after", r.policy.DeleteAfter),
+		zap.Duration("interval", interval),
+	)
+
+	for {
+		result, err := r.Apply(ctx, time.Now())
+		if err != nil {
+			r.logger.Error("Failed to apply alert retention", zap.Error(err))
+		} else if result.Archived > 0 || result.Deleted > 0 {
+			r.logger.Info("Applied alert retention",
+				zap.Int64("archived", result.Archived),
+				zap.Int64("deleted", result.Deleted),
+				zap.String("export_file", result.ExportFile),
+			)
+		}
+
+		select {
+		case <-ctx.Done():
+			r.logger.Info("Stopped alert retention")
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package alerts
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/padminisys/flintroute/internal/database"
+	"github.com/padminisys/flintroute/internal/testutil"
+	"github.com/padminisys/flintroute/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+const day = 24 * time.Hour
+
+func createAlert(t *testing.T, db *database.DB, alert models.Alert, age time.Duration) *models.Alert {
+	t.Helper()
+	alert.Type = "peer_down"
+	alert.Message = "Peer down"
+	require.NoError(t, db.Create(&alert).Error)
+	require.NoError(t, db.Model(&alert).UpdateColumn("created_at", time.Now().Add(-age)).Error)
+	return &alert
+}
+
+func TestRetentionApply(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Archives acknowledged and resolved alerts", func(t *testing.T) {
+		db := testutil.SetupTestDB(t)
+		now := time.Now()
+		acked := createAlert(t, db, models.Alert{Severity: "warning", Acknowledged: true}, 40*day)
+		resolved := createAlert(t, db, models.Alert{Severity: "critical", ResolvedAt: &now}, 40*day)
+		open := createAlert(t, db, models.Alert{Severity: "critical"}, 40*day)
+		recent := createAlert(t, db, models.Alert{Severity: "warning", Acknowledged: true}, day)
+
+		retention := NewRetention(db, RetentionPolicy{ArchiveAfter: 30 * day}, zap.NewNop())
+		result, err := retention.Apply(ctx, now)
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), result.Archived)
+		assert.Zero(t, result.Deleted)
+
+		for _, tc := range []struct {
+			alert    *models.Alert
+			archived bool
+		}{{acked, true}, {resolved, true}, {open, false}, {recent, false}} {
+			var alert models.Alert
+			require.NoError(t, db.First(&alert, tc.alert.ID).Error)
+			assert.Equal(t, tc.archived, alert.ArchivedAt != nil, "alert %d", alert.ID)
+		}
+
+		// Already archived alerts are left alone
+		result, err = retention.Apply(ctx, now)
+		require.NoError(t, err)
+		assert.Zero(t, result.Archived)
+	})
+
+	t.Run("Exports alerts before deleting them", func(t *testing.T) {
+		db := testutil.SetupTestDB(t)
+		old := createAlert(t, db, models.Alert{Severity: "critical"}, 100*day)
+		kept := createAlert(t, db, models.Alert{Severity: "warning"}, 10*day)
+
+		dir := t.TempDir()
+		retention := NewRetention(db, RetentionPolicy{DeleteAfter: 90 * day, ExportDir: dir}, zap.NewNop())
+		result, err := retention.Apply(ctx, time.Now())
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), result.Deleted)
+		require.NotEmpty(t, result.ExportFile)
+
+		var remaining []models.Alert
+		require.NoError(t, db.Unscoped().Find(&remaining).Error)
+		require.Len(t, remaining, 1)
+		assert.Equal(t, kept.ID, remaining[0].ID)
+
+		file, err := os.Open(result.ExportFile)
+		require.NoError(t, err)
+		defer file.Close()
+
+		var exported []models.Alert
+		scanner := bufio.NewScanner(file)
+		for scanner.Scan() {
+			var alert models.Alert
+			require.NoError(t, json.Unmarshal(scanner.Bytes(), &alert))
+			exported = append(exported, alert)
+		}
+		require.Len(t, exported, 1)
+		assert.Equal(t, old.ID, exported[0].ID)
+		assert.Equal(t, "critical", exported[0].Severity)
+	})
+
+	t.Run("Keeps alerts when the export fails", func(t *testing.T) {
+		db := testutil.SetupTestDB(t)
+		createAlert(t, db, models.Alert{Severity: "critical"}, 100*day)
+
+		// A file where the export directory should be
+		dir := t.TempDir() + "/exports"
+		require.NoError(t, os.WriteFile(dir, nil, 0600))
+
+		retention := NewRetention(db, RetentionPolicy{DeleteAfter: 90 * day, ExportDir: dir}, zap.NewNop())
+		_, err := retention.Apply(ctx, time.Now())
+		assert.Error(t, err)
+
+		var count int64
+		require.NoError(t, db.Model(&models.Alert{}).Count(&count).Error)
+		assert.Equal(t, int64(1), count)
+	})
+
+	t.Run("Does nothing with an empty policy", func(t *testing.T) {
+		db := testutil.SetupTestDB(t)
+		createAlert(t, db, models.Alert{Severity: "critical", Acknowledged: true}, 1000*day)
+
+		result, err := NewRetention(db, RetentionPolicy{}, zap.NewNop()).Apply(ctx, time.Now())
+		require.NoError(t, err)
+		assert.Zero(t, result.Archived)
+		assert.Zero(t, result.Deleted)
+	})
+}
@@ -0,0 +1,277 @@
+package alerts
+
+import (
+	"bytes"
+	"embed"
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	texttemplate "text/template"
+	"time"
+
+	"github.com/padminisys/flintroute/pkg/models"
+)
+
+// Email templates
+const (
+	TemplateAlert           = "alert"
+	TemplateDigest          = "digest"
+	TemplateChangeRequested = "change_requested"
+	TemplatePasswordReset   = "password_reset"
+)
+
+// TemplateNames are the email templates, in the order they are documented
+var TemplateNames = []string{TemplateAlert, TemplateDigest, TemplateChangeRequested, TemplatePasswordReset}
+
+// ErrUnknownTemplate is returned for an email template that doesn't exist
+var ErrUnknownTemplate = errors.New("unknown email template")
+
+//go:embed templates
+var defaultTemplateFS embed.FS
+
+// Branding is what emails show of the deployment sending them
+type Branding struct {
+	// Name is the product name in subjects and headers
+	Name string
+	// URL links to the deployment's web UI
+	URL string
+	// LogoURL is shown in place of Name in HTML headers
+	LogoURL string
+	// Color is the accent color of HTML emails
+	Color string
+	// Footer closes every email
+	Footer string
+}
+
+// DefaultBranding is the branding of emails, where a deployment doesn't
+// override it
+var DefaultBranding = Branding{
+	Name:   "FlintRoute",
+	Color:  "#1f6feb",
+	Footer: "Sent by FlintRoute",
+}
+
+// withDefaults fills in the fields b leaves empty from DefaultBranding
+func (b Branding) withDefaults() Branding {
+	if b.Name == "" {
+		b.Name = DefaultBranding.Name
+	}
+	if b.Color == "" {
+		b.Color = DefaultBranding.Color
+	}
+	if b.Footer == "" {
+		b.Footer = DefaultBranding.Footer
+	}
+	return b
+}
+
+// Email is a rendered email, with plain-text and HTML alternatives of its
+// body
+type Email struct {
+	Subject string `json:"subject"`
+	Text    string `json:"text"`
+	HTML    string `json:"html"`
+}
+
+// ChangeRequestEmail is the data of the change_requested template, sent to
+// the admins who can approve a change request
+type ChangeRequestEmail struct {
+	Change *models.ChangeRequest
+	// RequestedBy is the username of the requester
+	RequestedBy string
+}
+
+// PasswordResetEmail is the data of the password_reset template
+type PasswordResetEmail struct {
+	Username  string
+	ResetURL  string
+	ExpiresAt time.Time
+}
+
+// emailTemplate is an email template parsed, each part with the layout it
+// renders into
+type emailTemplate struct {
+	subject *texttemplate.Template
+	text    *texttemplate.Template
+	html    *htmltemplate.Template
+}
+
+// templateData is what templates render: the branding as .Brand and the
+// template's data as .Data
+type templateData struct {
+	Brand Branding
+	Data  interface{}
+}
+
+// Templates render emails from Go templates. Each template has a subject,
+// a plain-text body and an HTML body, in files named <name>.subject,
+// <name>.txt and <name>.html; the bodies define a "content" template that
+// layout.txt and layout.html wrap.
+type Templates struct {
+	brand     Branding
+	templates map[string]*emailTemplate
+}
+
+var templateFuncs = map[string]interface{}{
+	"datetime": func(t time.Time) string {
+		return t.UTC().Format("2006-01-02 15:04 MST")
+	},
+	"plural": plural,
+}
+
+// DefaultTemplates returns the built-in templates with the default
+// branding
+func DefaultTemplates() *Templates {
+	templates, err := LoadTemplates("", Branding{})
+	if err != nil {
+		panic(err)
+	}
+	return templates
+}
+
+// LoadTemplates parses the built-in templates, replacing each file that dir
+// holds a file of the same name for. An empty dir uses the built-in
+// templates only. Branding fields left empty get the defaults.
+func LoadTemplates(dir string, brand Branding) (*Templates, error) {
+	read := func(file string) (string, error) {
+		if dir != "" {
+			data, err := os.ReadFile(filepath.Join(dir, file))
+			if err == nil {
+				return string(data), nil
+			}
+			if !errors.Is(err, fs.ErrNotExist) {
+				return "", fmt.Errorf("failed to read email template %s: %w", file, err)
+			}
+		}
+		data, err := defaultTemplateFS.ReadFile("templates/" + file)
+		if err != nil {
+			return "", fmt.Errorf("failed to read email template %s: %w", file, err)
+		}
+		return string(data), nil
+	}
+
+	textLayout, err := read("layout.txt")
+	if err != nil {
+		return nil, err
+	}
+	htmlLayout, err := read("layout.html")
+	if err != nil {
+		return nil, err
+	}
+
+	t := &Templates{brand: brand.withDefaults(), templates: make(map[string]*emailTemplate)}
+	for _, name := range TemplateNames {
+		subject, err := read(name + ".subject")
+		if err != nil {
+			return nil, err
+		}
+		text, err := read(name + ".txt")
+		if err != nil {
+			return nil, err
+		}
+		html, err := read(name + ".html")
+		if err != nil {
+			return nil, err
+		}
+
+		parsed := &emailTemplate{}
+		if parsed.subject, err = texttemplate.New(name + ".subject").Funcs(templateFuncs).Parse(subject); err != nil {
+			return nil, fmt.Errorf("failed to parse email template %s: %w", name+".subject", err)
+		}
+		if parsed.text, err = texttemplate.New("layout.txt").Funcs(templateFuncs).Parse(textLayout); err != nil {
+			return nil, fmt.Errorf("failed to parse email template layout.txt: %w", err)
+		}
+		if _, err = parsed.text.New(name + ".txt").Parse(text); err != nil {
+			return nil, fmt.Errorf("failed to parse email template %s: %w", name+".txt", err)
+		}
+		if parsed.html, err = htmltemplate.New("layout.html").Funcs(templateFuncs).Parse(htmlLayout); err != nil {
+			return nil, fmt.Errorf("failed to parse email template layout.html: %w", err)
+		}
+		if _, err = parsed.html.New(name + ".html").Parse(html); err != nil {
+			return nil, fmt.Errorf("failed to parse email template %s: %w", name+".html", err)
+		}
+		t.templates[name] = parsed
+	}
+	return t, nil
+}
+
+// Branding returns the branding the templates render with
+func (t *Templates) Branding() Branding {
+	return t.brand
+}
+
+// Render renders the email template name with data
+func (t *Templates) Render(name string, data interface{}) (*Email, error) {
+	parsed, ok := t.templates[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownTemplate, name)
+	}
+	values := templateData{Brand: t.brand, Data: data}
+
+	var subject, text, html bytes.Buffer
+	if err := parsed.subject.Execute(&subject, values); err != nil {
+		return nil, fmt.Errorf("failed to render %s email subject: %w", name, err)
+	}
+	if err := parsed.text.Execute(&text, values); err != nil {
+		return nil, fmt.Errorf("failed to render %s email text: %w", name, err)
+	}
+	if err := parsed.html.Execute(&html, values); err != nil {
+		return nil, fmt.Errorf("failed to render %s email HTML: %w", name, err)
+	}
+	return &Email{
+		// Subjects are a single header line
+		Subject: strings.Join(strings.Fields(subject.String()), " "),
+		Text:    strings.TrimSpace(text.String()) + "\n",
+		HTML:    html.String(),
+	}, nil
+}
+
+// SampleData returns example data for the email template name, for test
+// sends and previews
+func SampleData(name string) (interface{}, error) {
+	now := time.Now()
+	switch name {
+	case TemplateAlert:
+		return &models.Alert{
+			ID:          1,
+			Type:        "peer_down",
+			Severity:    "critical",
+			Message:     "BGP session with 192.0.2.1 went down",
+			Details:     "Session state changed from Established to Idle",
+			FirstSeenAt: now,
+			LastSeenAt:  now,
+		}, nil
+	case TemplateDigest:
+		return &DigestSummary{
+			From:       now.Add(-time.Hour),
+			To:         now,
+			Alerts:     2,
+			BySeverity: map[string]int{"critical": 1, "warning": 1},
+			Suppressed: 4,
+			Top: []DigestAlert{
+				{ID: 1, Type: "peer_down", Severity: "critical", Message: "BGP session with 192.0.2.1 went down", Occurrences: 5, FirstSeenAt: now.Add(-time.Hour), LastSeenAt: now},
+				{ID: 2, Type: "prefix_anomaly", Severity: "warning", Message: "Prefixes from 198.51.100.7 dropped by 60%", Occurrences: 1, FirstSeenAt: now, LastSeenAt: now},
+			},
+		}, nil
+	case TemplateChangeRequested:
+		change := &models.ChangeRequest{
+			ID:        1,
+			CreatedAt: now,
+			Operation: "peer.delete",
+			Status:    models.ChangePending,
+			Summary:   "Delete peer 192.0.2.1 (AS64500)",
+		}
+		return &ChangeRequestEmail{Change: change, RequestedBy: "operator"}, nil
+	case TemplatePasswordReset:
+		return &PasswordResetEmail{
+			Username:  "operator",
+			ResetURL:  "https://flintroute.example.com/reset?token=example",
+			ExpiresAt: now.Add(time.Hour),
+		}, nil
+	}
+	return nil, fmt.Errorf("%w: %s", ErrUnknownTemplate, name)
+}
@@ -0,0 +1,99 @@
+package alerts
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTemplates(t *testing.T) {
+	t.Run("Renders every template with its sample data", func(t *testing.T) {
+		templates := DefaultTemplates()
+		for _, name := range TemplateNames {
+			data, err := SampleData(name)
+			require.NoError(t, err)
+			email, err := templates.Render(name, data)
+			require.NoError(t, err, name)
+			assert.NotEmpty(t, email.Subject, name)
+			assert.NotContains(t, email.Subject, "\n", name)
+			assert.True(t, strings.HasSuffix(email.Text, "--\nSent by FlintRoute\n"), name)
+			assert.Contains(t, email.HTML, "<strong style=\"font-size:18px;\">FlintRoute</strong>", name)
+			assert.Contains(t, email.HTML, "Sent by FlintRoute", name)
+		}
+	})
+
+	t.Run("Renders alerts", func(t *testing.T) {
+		data, err := SampleData(TemplateAlert)
+		require.NoError(t, err)
+		email, err := DefaultTemplates().Render(TemplateAlert, data)
+		require.NoError(t, err)
+		assert.Equal(t, "FlintRoute critical: BGP session with 192.0.2.1 went down", email.Subject)
+		assert.Contains(t, email.Text, "Severity: critical\nType: peer_down\n")
+		assert.Contains(t, email.Text, "\nSession state changed from Established to Idle\n")
+		assert.Contains(t, email.HTML, "<strong>critical</strong>")
+	})
+
+	t.Run("Escapes HTML", func(t *testing.T) {
+		data, err := SampleData(TemplateChangeRequested)
+		require.NoError(t, err)
+		data.(*ChangeRequestEmail).Change.Summary = "Delete <b>peer</b>"
+		email, err := DefaultTemplates().Render(TemplateChangeRequested, data)
+		require.NoError(t, err)
+		assert.Contains(t, email.Text, "  Delete <b>peer</b>\n")
+		assert.Contains(t, email.HTML, "Delete &lt;b&gt;peer&lt;/b&gt;")
+	})
+
+	t.Run("Applies branding", func(t *testing.T) {
+		templates, err := LoadTemplates("", Branding{
+			Name:    "Acme NOC",
+			URL:     "https://noc.acme.example",
+			LogoURL: "https://noc.acme.example/logo.png",
+			Color:   "#ff6600",
+		})
+		require.NoError(t, err)
+		data, err := SampleData(TemplateChangeRequested)
+		require.NoError(t, err)
+		email, err := templates.Render(TemplateChangeRequested, data)
+		require.NoError(t, err)
+		assert.Equal(t, "Acme NOC approval requested: Delete peer 192.0.2.1 (AS64500)", email.Subject)
+		assert.Contains(t, email.Text, "Review it at https://noc.acme.example\n")
+		assert.Contains(t, email.Text, "Sent by FlintRoute\nhttps://noc.acme.example\n")
+		assert.Contains(t, email.HTML, `<img src="https://noc.acme.example/logo.png" alt="Acme NOC" height="32">`)
+		assert.Contains(t, email.HTML, "border-bottom:4px solid #ff6600;")
+	})
+
+	t.Run("Overrides templates from a directory", func(t *testing.T) {
+		dir := t.TempDir()
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "alert.subject"), []byte("[{{.Data.Severity}}] {{.Data.Message}}\n"), 0o644))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "layout.txt"), []byte("{{template \"content\" .}}\nThe NOC team\n"), 0o644))
+
+		templates, err := LoadTemplates(dir, Branding{})
+		require.NoError(t, err)
+		data, err := SampleData(TemplateAlert)
+		require.NoError(t, err)
+		email, err := templates.Render(TemplateAlert, data)
+		require.NoError(t, err)
+		assert.Equal(t, "[critical] BGP session with 192.0.2.1 went down", email.Subject)
+		assert.True(t, strings.HasSuffix(email.Text, "\nThe NOC team\n"))
+		assert.Contains(t, email.HTML, "Sent by FlintRoute")
+	})
+
+	t.Run("Rejects templates that don't parse", func(t *testing.T) {
+		dir := t.TempDir()
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "digest.html"), []byte("{{define \"content\"}}{{.Data.Alerts"), 0o644))
+
+		_, err := LoadTemplates(dir, Branding{})
+		assert.ErrorContains(t, err, "failed to parse email template digest.html")
+	})
+
+	t.Run("Rejects unknown templates", func(t *testing.T) {
+		_, err := DefaultTemplates().Render("welcome", nil)
+		assert.ErrorIs(t, err, ErrUnknownTemplate)
+		_, err = SampleData("welcome")
+		assert.ErrorIs(t, err, ErrUnknownTemplate)
+	})
+}
@@ -0,0 +1,72 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/padminisys/flintroute/internal/activity"
+	"github.com/padminisys/flintroute/internal/apierror"
+	authpkg "github.com/padminisys/flintroute/internal/auth"
+	"go.uber.org/zap"
+)
+
+// handleListActivity handles listing recent audit entries, alerts, session
+// events and config versions in one feed, newest first. Audit entries are
+// only listed for admins.
+func (s *Server) handleListActivity(c *gin.Context) {
+	limit := 100
+	if raw := c.Query("limit"); raw != "" {
+		var err error
+		limit, err = strconv.Atoi(raw)
+		if err != nil || limit < 1 || limit > 1000 {
+			apierror.Respond(c, http.StatusBadRequest, apierror.CodeValidationFailed, "limit must be between 1 and 1000")
+			return
+		}
+	}
+
+	role, _ := authpkg.GetRole(c)
+	admin := authpkg.HasRole(role, authpkg.RoleAdmin)
+
+	var types []string
+	if raw := c.Query("types"); raw != "" {
+		for _, typ := range strings.Split(raw, ",") {
+			typ = strings.TrimSpace(typ)
+			if !activity.ValidType(typ) {
+				apierror.Respond(c, http.StatusBadRequest, apierror.CodeValidationFailed,
+					"types must be a comma-separated list of "+strings.Join(activity.Types, ", "))
+				return
+			}
+			if typ == activity.TypeAudit && !admin {
+				apierror.Respond(c, http.StatusForbidden, apierror.CodeForbidden, "Audit entries are only listed for admins")
+				return
+			}
+			types = append(types, typ)
+		}
+	} else if !admin {
+		for _, typ := range activity.Types {
+			if typ != activity.TypeAudit {
+				types = append(types, typ)
+			}
+		}
+	}
+
+	page, err := s.activity.List(c.Request.Context(), activity.Query{
+		Types:  types,
+		Cursor: c.Query("cursor"),
+		Limit:  limit,
+	})
+	if errors.Is(err, activity.ErrInvalidCursor) {
+		apierror.Respond(c, http.StatusBadRequest, apierror.CodeValidationFailed, "Invalid cursor")
+		return
+	}
+	if err != nil {
+		s.logger.Error("Failed to list activity", zap.Error(err))
+		apierror.Respond(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to list activity")
+		return
+	}
+
+	c.JSON(http.StatusOK, page)
+}
@@ -0,0 +1,75 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/padminisys/flintroute/internal/activity"
+	"github.com/padminisys/flintroute/internal/auth"
+	"github.com/padminisys/flintroute/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandleListActivity(t *testing.T) {
+	server, db := setupTestServer(t)
+	server.activity = activity.NewFeed(server.db)
+
+	require.NoError(t, db.Create(&models.AuditEntry{Username: "admin", Action: "snapshot.create"}).Error)
+	require.NoError(t, db.Create(&models.Alert{Type: "peer_down", Severity: "critical", Message: "Peer down"}).Error)
+	require.NoError(t, db.Create(&models.SessionEvent{PeerID: 1, IPAddress: "192.0.2.1", OldState: "Established", NewState: "Idle"}).Error)
+
+	routerFor := func(role string) *gin.Engine {
+		router := gin.New()
+		router.Use(func(c *gin.Context) { c.Set("role", role) })
+		router.GET("/activity", server.handleListActivity)
+		return router
+	}
+	list := func(router *gin.Engine, path string) activity.Page {
+		w := profileRequest(router, "GET", path, "")
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var page activity.Page
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &page))
+		return page
+	}
+
+	t.Run("Audit entries are listed for admins only", func(t *testing.T) {
+		page := list(routerFor(auth.RoleAdmin), "/activity")
+		assert.Len(t, page.Items, 3)
+
+		page = list(routerFor(auth.RoleUser), "/activity")
+		require.Len(t, page.Items, 2)
+		for _, item := range page.Items {
+			assert.NotEqual(t, activity.TypeAudit, item.Type)
+		}
+
+		w := profileRequest(routerFor(auth.RoleUser), "GET", "/activity?types=audit", "")
+		assert.Equal(t, http.StatusForbidden, w.Code)
+	})
+
+	t.Run("Filters and pages", func(t *testing.T) {
+		router := routerFor(auth.RoleOperator)
+		page := list(router, "/activity?limit=1")
+		require.Len(t, page.Items, 1)
+		require.NotEmpty(t, page.NextCursor)
+
+		page = list(router, "/activity?limit=1&cursor="+page.NextCursor)
+		require.Len(t, page.Items, 1)
+		assert.Empty(t, page.NextCursor)
+
+		page = list(router, "/activity?types=session")
+		require.Len(t, page.Items, 1)
+		assert.Equal(t, activity.TypeSession, page.Items[0].Type)
+	})
+
+	t.Run("Rejects invalid queries", func(t *testing.T) {
+		router := routerFor(auth.RoleAdmin)
+		for _, path := range []string{"/activity?types=bogus", "/activity?limit=0", "/activity?cursor=bogus"} {
+			w := profileRequest(router, "GET", path, "")
+			assert.Equal(t, http.StatusBadRequest, w.Code, path)
+			assert.Contains(t, w.Body.String(), "VALIDATION_FAILED")
+		}
+	})
+}
@@ -0,0 +1,51 @@
+package api
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/padminisys/flintroute/internal/alertmanager"
+	"github.com/padminisys/flintroute/internal/apierror"
+	"go.uber.org/zap"
+)
+
+// handleAlertmanagerWebhook handles alerts sent by an Alertmanager webhook
+// receiver, authenticated by the shared bearer token
+func (s *Server) handleAlertmanagerWebhook(c *gin.Context) {
+	token := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+	if subtle.ConstantTimeCompare([]byte(token), []byte(s.alertmanagerToken)) != 1 {
+		apierror.Respond(c, http.StatusUnauthorized, apierror.CodeInvalidToken, "Invalid receiver token")
+		return
+	}
+
+	var msg alertmanager.WebhookMessage
+	if err := c.ShouldBindJSON(&msg); err != nil {
+		apierror.Validation(c, err)
+		return
+	}
+
+	result, err := s.alertmanagerService.Ingest(c.Request.Context(), &msg)
+	if err != nil {
+		s.logger.Error("Failed to ingest Alertmanager alerts", zap.Error(err))
+		apierror.Respond(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to ingest alerts")
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// handleAlertmanagerExport handles listing unacknowledged FlintRoute alerts
+// in the format accepted by Alertmanager's alerts API
+func (s *Server) handleAlertmanagerExport(c *gin.Context) {
+	alerts, err := s.alertmanagerService.Export(c.Request.Context(), time.Now())
+	if err != nil {
+		s.logger.Error("Failed to export alerts", zap.Error(err))
+		apierror.Respond(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to export alerts")
+		return
+	}
+
+	c.JSON(http.StatusOK, alerts)
+}
@@ -1,14 +1,18 @@
 package api
 
 import (
+	"errors"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
-	"github.com/padminisys/flintroute/internal/models"
+	"github.com/padminisys/flintroute/internal/apierror"
+	authpkg "github.com/padminisys/flintroute/internal/auth"
+	"github.com/padminisys/flintroute/internal/repository"
+	"github.com/padminisys/flintroute/pkg/models"
 	"go.uber.org/zap"
 	"golang.org/x/crypto/bcrypt"
-	"gorm.io/gorm"
 )
 
 // LoginRequest represents a login request
@@ -19,9 +23,9 @@ type LoginRequest struct {
 
 // LoginResponse represents a login response
 type LoginResponse struct {
-	AccessToken  string `json:"access_token"`
-	RefreshToken string `json:"refresh_token"`
-	ExpiresIn    int64  `json:"expires_in"`
+	AccessToken  string   `json:"access_token"`
+	RefreshToken string   `json:"refresh_token"`
+	ExpiresIn    int64    `json:"expires_in"`
 	User         UserInfo `json:"user"`
 }
 
@@ -42,47 +46,47 @@ type RefreshRequest struct {
 func (s *Server) handleLogin(c *gin.Context) {
 	var req LoginRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		apierror.Validation(c, err)
 		return
 	}
 
 	// Find user
-	var user models.User
-	if err := s.db.Where("username = ?", req.Username).First(&user).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials"})
+	user, err := s.repos.Users.GetByUsername(c.Request.Context(), req.Username)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			apierror.Respond(c, http.StatusUnauthorized, apierror.CodeInvalidCredentials, "Invalid credentials")
 			return
 		}
 		s.logger.Error("Database error", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		apierror.Respond(c, http.StatusInternalServerError, apierror.CodeInternal, "Internal server error")
 		return
 	}
 
 	// Verify password
 	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(req.Password)); err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials"})
+		apierror.Respond(c, http.StatusUnauthorized, apierror.CodeInvalidCredentials, "Invalid credentials")
 		return
 	}
 
 	// Check if user is active (after password verification for security)
 	if !user.Active {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Account is disabled"})
+		apierror.Respond(c, http.StatusUnauthorized, apierror.CodeAccountDisabled, "Account is disabled")
 		return
 	}
 
 	// Generate access token
-	accessToken, err := s.jwtManager.GenerateToken(&user)
+	accessToken, err := s.jwtManager.GenerateToken(user)
 	if err != nil {
 		s.logger.Error("Failed to generate access token", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		apierror.Respond(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to generate token")
 		return
 	}
 
 	// Generate refresh token
-	refreshToken, expiresAt, err := s.jwtManager.GenerateRefreshToken(&user)
+	refreshToken, expiresAt, err := s.jwtManager.GenerateRefreshToken(user)
 	if err != nil {
 		s.logger.Error("Failed to generate refresh token", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		apierror.Respond(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to generate token")
 		return
 	}
 
@@ -91,10 +95,12 @@ func (s *Server) handleLogin(c *gin.Context) {
 		UserID:    user.ID,
 		Token:     refreshToken,
 		ExpiresAt: expiresAt,
+		UserAgent: c.Request.UserAgent(),
+		ClientIP:  c.ClientIP(),
 	}
 	if err := s.db.Create(&tokenModel).Error; err != nil {
 		s.logger.Error("Failed to store refresh token", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to store token"})
+		apierror.Respond(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to store token")
 		return
 	}
 
@@ -117,56 +123,56 @@ func (s *Server) handleLogin(c *gin.Context) {
 func (s *Server) handleRefreshToken(c *gin.Context) {
 	var req RefreshRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		apierror.Validation(c, err)
 		return
 	}
 
 	// Validate refresh token
 	claims, err := s.jwtManager.ValidateToken(req.RefreshToken)
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired refresh token"})
+		apierror.Respond(c, http.StatusUnauthorized, apierror.CodeInvalidToken, "Invalid or expired refresh token")
 		return
 	}
 
 	// Check if refresh token exists and is not revoked
 	var tokenModel models.RefreshToken
 	if err := s.db.Where("token = ? AND revoked = ?", req.RefreshToken, false).First(&tokenModel).Error; err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid refresh token"})
+		apierror.Respond(c, http.StatusUnauthorized, apierror.CodeInvalidToken, "Invalid refresh token")
 		return
 	}
 
 	// Check if token is expired
 	if time.Now().After(tokenModel.ExpiresAt) {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Refresh token expired"})
+		apierror.Respond(c, http.StatusUnauthorized, apierror.CodeInvalidToken, "Refresh token expired")
 		return
 	}
 
 	// Get user
-	var user models.User
-	if err := s.db.First(&user, claims.UserID).Error; err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found"})
+	user, err := s.repos.Users.GetByID(c.Request.Context(), claims.UserID)
+	if err != nil {
+		apierror.Respond(c, http.StatusUnauthorized, apierror.CodeInvalidToken, "User not found")
 		return
 	}
 
 	// Check if user is active
 	if !user.Active {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Account is disabled"})
+		apierror.Respond(c, http.StatusUnauthorized, apierror.CodeAccountDisabled, "Account is disabled")
 		return
 	}
 
 	// Generate new access token
-	accessToken, err := s.jwtManager.GenerateToken(&user)
+	accessToken, err := s.jwtManager.GenerateToken(user)
 	if err != nil {
 		s.logger.Error("Failed to generate access token", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		apierror.Respond(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to generate token")
 		return
 	}
 
 	// Generate new refresh token
-	newRefreshToken, expiresAt, err := s.jwtManager.GenerateRefreshToken(&user)
+	newRefreshToken, expiresAt, err := s.jwtManager.GenerateRefreshToken(user)
 	if err != nil {
 		s.logger.Error("Failed to generate refresh token", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		apierror.Respond(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to generate token")
 		return
 	}
 
@@ -181,10 +187,12 @@ func (s *Server) handleRefreshToken(c *gin.Context) {
 		UserID:    user.ID,
 		Token:     newRefreshToken,
 		ExpiresAt: expiresAt,
+		UserAgent: c.Request.UserAgent(),
+		ClientIP:  c.ClientIP(),
 	}
 	if err := s.db.Create(&newTokenModel).Error; err != nil {
 		s.logger.Error("Failed to store refresh token", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to store token"})
+		apierror.Respond(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to store token")
 		return
 	}
 
@@ -220,14 +228,103 @@ func (s *Server) handleLogout(c *gin.Context) {
 		return
 	}
 
-	// Revoke all refresh tokens for this user
+	// Revoke all refresh tokens for this user, unless an admin impersonating
+	// the user is logging out
+	if claims.Act == nil {
+		if err := s.db.Model(&models.RefreshToken{}).
+			Where("user_id = ? AND revoked = ?", claims.UserID, false).
+			Update("revoked", true).Error; err != nil {
+			s.logger.Error("Failed to revoke tokens", zap.Error(err))
+		}
+	}
+
+	// Revoke the access token used to log out
+	if err := s.denylist.RevokeToken(c.Request.Context(), claims); err != nil {
+		s.logger.Error("Failed to revoke access token", zap.Error(err))
+	}
+
+	s.logger.Info("User logged out", zap.String("username", claims.Username))
+
+	c.JSON(http.StatusOK, gin.H{"message": "Logged out successfully"})
+}
+
+// handleDisableUser handles disabling a user's account. The user's refresh
+// tokens and access tokens are revoked, so the user is signed out at once.
+func (s *Server) handleDisableUser(c *gin.Context) {
+	user := s.userFromParam(c)
+	if user == nil {
+		return
+	}
+
+	if adminID, _ := authpkg.GetUserID(c); adminID == user.ID {
+		apierror.Respond(c, http.StatusBadRequest, apierror.CodeValidationFailed, "Cannot disable your own account")
+		return
+	}
+
+	if err := s.repos.Users.Update(c.Request.Context(), user, "active", false); err != nil {
+		s.logger.Error("Failed to disable user", zap.Error(err))
+		apierror.Respond(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to disable user")
+		return
+	}
+
 	if err := s.db.Model(&models.RefreshToken{}).
-		Where("user_id = ? AND revoked = ?", claims.UserID, false).
+		Where("user_id = ? AND revoked = ?", user.ID, false).
 		Update("revoked", true).Error; err != nil {
 		s.logger.Error("Failed to revoke tokens", zap.Error(err))
 	}
+	if err := s.denylist.RevokeUser(c.Request.Context(), user.ID); err != nil {
+		s.logger.Error("Failed to revoke access tokens", zap.Error(err))
+	}
 
-	s.logger.Info("User logged out", zap.String("username", claims.Username))
+	s.logger.Info("Disabled user", zap.String("username", user.Username))
 
-	c.JSON(http.StatusOK, gin.H{"message": "Logged out successfully"})
-}
\ No newline at end of file
+	c.JSON(http.StatusOK, user)
+}
+
+// handleEnableUser handles re-enabling a disabled user's account
+func (s *Server) handleEnableUser(c *gin.Context) {
+	user := s.userFromParam(c)
+	if user == nil {
+		return
+	}
+
+	if err := s.repos.Users.Update(c.Request.Context(), user, "active", true); err != nil {
+		s.logger.Error("Failed to enable user", zap.Error(err))
+		apierror.Respond(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to enable user")
+		return
+	}
+
+	s.logger.Info("Enabled user", zap.String("username", user.Username))
+
+	c.JSON(http.StatusOK, user)
+}
+
+// userFromParam loads the user named by the :id parameter. It responds with
+// an error and returns nil when the ID is invalid or unknown.
+func (s *Server) userFromParam(c *gin.Context) *models.User {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		apierror.Respond(c, http.StatusBadRequest, apierror.CodeInvalidID, "Invalid user ID")
+		return nil
+	}
+
+	user, err := s.repos.Users.GetByID(c.Request.Context(), uint(id))
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			apierror.Respond(c, http.StatusNotFound, apierror.CodeNotFound, "User not found")
+			return nil
+		}
+		s.logger.Error("Database error", zap.Error(err))
+		apierror.Respond(c, http.StatusInternalServerError, apierror.CodeInternal, "Internal server error")
+		return nil
+	}
+	return user
+}
+
+// handleJWKS serves the public keys FlintRoute tokens are signed with, so
+// other services can validate them. It is empty when tokens are signed
+// with the shared HMAC secret.
+func (s *Server) handleJWKS(c *gin.Context) {
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, s.jwtManager.JWKS())
+}
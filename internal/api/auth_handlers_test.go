@@ -2,16 +2,20 @@ package api
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
 	"testing"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/padminisys/flintroute/internal/auth"
 	"github.com/padminisys/flintroute/internal/database"
-	"github.com/padminisys/flintroute/internal/models"
+	"github.com/padminisys/flintroute/internal/repository"
+	"github.com/padminisys/flintroute/internal/store"
+	"github.com/padminisys/flintroute/pkg/models"
 	"github.com/stretchr/testify/assert"
 	"go.uber.org/zap"
 	"golang.org/x/crypto/bcrypt"
@@ -30,11 +34,15 @@ func setupTestServer(t *testing.T) (*Server, *gorm.DB) {
 	assert.NoError(t, err)
 
 	jwtManager := auth.NewJWTManager("test-secret", 15*time.Minute, 7*24*time.Hour)
+	denylist, err := auth.NewDenylist(dbWrapper.DB, store.NewMemory(), 15*time.Minute, logger)
+	assert.NoError(t, err)
 
 	server := &Server{
 		db:         dbWrapper,
+		repos:      repository.New(dbWrapper),
 		logger:     logger,
 		jwtManager: jwtManager,
+		denylist:   denylist,
 	}
 
 	return server, dbWrapper.GetDB()
@@ -336,7 +344,7 @@ func TestHandleLogout(t *testing.T) {
 		}
 		db.Create(token1)
 		db.Model(token1).Update("revoked", false)
-		
+
 		token2 := &models.RefreshToken{
 			UserID:    user.ID,
 			Token:     refreshToken2,
@@ -363,6 +371,13 @@ func TestHandleLogout(t *testing.T) {
 		var count int64
 		db.Model(&models.RefreshToken{}).Where("user_id = ? AND revoked = ?", user.ID, false).Count(&count)
 		assert.Equal(t, int64(0), count)
+
+		// Verify the access token is revoked
+		claims, err := server.jwtManager.ValidateToken(accessToken)
+		assert.NoError(t, err)
+		revoked, err := server.denylist.IsRevoked(context.Background(), claims)
+		assert.NoError(t, err)
+		assert.True(t, revoked)
 	})
 
 	t.Run("Logout without authorization header", func(t *testing.T) {
@@ -391,6 +406,86 @@ func TestHandleLogout(t *testing.T) {
 	})
 }
 
+func TestHandleDisableUser(t *testing.T) {
+	server, db := setupTestServer(t)
+
+	user := models.User{Username: "disableuser", Email: "disable@example.com", Role: "user", Active: true}
+	db.Create(&user)
+
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set("user_id", uint(999))
+		c.Next()
+	})
+	router.POST("/users/:id/disable", server.handleDisableUser)
+	router.POST("/users/:id/enable", server.handleEnableUser)
+
+	post := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, path, nil))
+		return w
+	}
+
+	t.Run("Disabling revokes the user's tokens", func(t *testing.T) {
+		accessToken, _ := server.jwtManager.GenerateToken(&user)
+		refreshToken, expiresAt, _ := server.jwtManager.GenerateRefreshToken(&user)
+		db.Create(&models.RefreshToken{UserID: user.ID, Token: refreshToken, ExpiresAt: expiresAt})
+
+		w := post("/users/" + strconv.FormatUint(uint64(user.ID), 10) + "/disable")
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var stored models.User
+		db.First(&stored, user.ID)
+		assert.False(t, stored.Active)
+
+		var count int64
+		db.Model(&models.RefreshToken{}).Where("user_id = ? AND revoked = ?", user.ID, false).Count(&count)
+		assert.Equal(t, int64(0), count)
+
+		claims, err := server.jwtManager.ValidateToken(accessToken)
+		assert.NoError(t, err)
+		revoked, err := server.denylist.IsRevoked(context.Background(), claims)
+		assert.NoError(t, err)
+		assert.True(t, revoked)
+	})
+
+	t.Run("Enabling reactivates the account", func(t *testing.T) {
+		w := post("/users/" + strconv.FormatUint(uint64(user.ID), 10) + "/enable")
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var stored models.User
+		db.First(&stored, user.ID)
+		assert.True(t, stored.Active)
+	})
+
+	t.Run("Cannot disable own account", func(t *testing.T) {
+		self := models.User{ID: 999, Username: "self", Email: "self@example.com", Role: "admin", Active: true}
+		db.Create(&self)
+
+		w := post("/users/999/disable")
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("Unknown user", func(t *testing.T) {
+		w := post("/users/12345/disable")
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+}
+
+func TestHandleJWKS(t *testing.T) {
+	server, _ := setupTestServer(t)
+
+	router := gin.New()
+	router.GET("/.well-known/jwks.json", server.handleJWKS)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/.well-known/jwks.json", nil))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	// HMAC secrets are never published
+	assert.JSONEq(t, `{"keys": []}`, w.Body.String())
+}
+
 func TestLoginResponse(t *testing.T) {
 	t.Run("Create login response", func(t *testing.T) {
 		response := LoginResponse{
@@ -426,4 +521,4 @@ func TestUserInfo(t *testing.T) {
 		assert.Equal(t, "test@example.com", userInfo.Email)
 		assert.Equal(t, "user", userInfo.Role)
 	})
-}
\ No newline at end of file
+}
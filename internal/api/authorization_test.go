@@ -0,0 +1,228 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/padminisys/flintroute/internal/auth"
+	"github.com/padminisys/flintroute/internal/changes"
+	"github.com/padminisys/flintroute/internal/gitops"
+	"github.com/padminisys/flintroute/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// routeRoles lists the role each protected route requires
+var routeRoles = map[string]string{
+	"GET /api/v1/bgp/peers":                          auth.RoleUser,
+	"GET /api/v1/bgp/peers/search":                   auth.RoleUser,
+	"POST /api/v1/bgp/peers":                         auth.RoleOperator,
+	"POST /api/v1/bgp/peers/bulk":                    auth.RoleOperator,
+	"POST /api/v1/bgp/peers/adopt":                   auth.RoleOperator,
+	"GET /api/v1/bgp/peers/trash":                    auth.RoleUser,
+	"POST /api/v1/bgp/peers/trash/purge":             auth.RoleOperator,
+	"GET /api/v1/bgp/peers/schedule":                 auth.RoleUser,
+	"POST /api/v1/bgp/peers/:id/restore":             auth.RoleOperator,
+	"GET /api/v1/bgp/peers/:id":                      auth.RoleUser,
+	"GET /api/v1/bgp/peers/:id/frr-config":           auth.RoleUser,
+	"PUT /api/v1/bgp/peers/:id":                      auth.RoleOperator,
+	"PATCH /api/v1/bgp/peers/:id":                    auth.RoleOperator,
+	"PUT /api/v1/bgp/peers/:id/password":             auth.RoleOperator,
+	"POST /api/v1/bgp/peers/:id/test":                auth.RoleOperator,
+	"GET /api/v1/bgp/peers/:id/reachability":         auth.RoleUser,
+	"GET /api/v1/bgp/peers/:id/state-timeline":       auth.RoleUser,
+	"GET /api/v1/bgp/peers/:id/transactions":         auth.RoleUser,
+	"POST /api/v1/bgp/peers/:id/maintenance":         auth.RoleOperator,
+	"DELETE /api/v1/bgp/peers/:id/maintenance":       auth.RoleOperator,
+	"GET /api/v1/bgp/peers/:id/schedule":             auth.RoleUser,
+	"POST /api/v1/bgp/peers/:id/schedule":            auth.RoleOperator,
+	"DELETE /api/v1/bgp/peers/:id/schedule/:job_id":  auth.RoleOperator,
+	"PUT /api/v1/bgp/peers/:id/demo-state":           auth.RoleOperator,
+	"DELETE /api/v1/bgp/peers/:id/demo-state":        auth.RoleOperator,
+	"DELETE /api/v1/bgp/peers/:id":                   auth.RoleOperator,
+	"GET /api/v1/bgp/global":                         auth.RoleUser,
+	"PUT /api/v1/bgp/global":                         auth.RoleOperator,
+	"GET /api/v1/bgp/sessions":                       auth.RoleUser,
+	"GET /api/v1/bgp/sessions/:id":                   auth.RoleUser,
+	"GET /api/v1/bgp/sessions/export":                auth.RoleUser,
+	"GET /api/v1/bgp/peer-templates":                 auth.RoleUser,
+	"POST /api/v1/bgp/peer-templates":                auth.RoleOperator,
+	"GET /api/v1/bgp/peer-templates/:id":             auth.RoleUser,
+	"PUT /api/v1/bgp/peer-templates/:id":             auth.RoleOperator,
+	"DELETE /api/v1/bgp/peer-templates/:id":          auth.RoleOperator,
+	"POST /api/v1/bgp/peer-templates/:id/sync":       auth.RoleOperator,
+	"GET /api/v1/peeringdb/asn/:asn":                 auth.RoleUser,
+	"GET /api/v1/bgp/community-lists":                auth.RoleUser,
+	"POST /api/v1/bgp/community-lists":               auth.RoleOperator,
+	"GET /api/v1/bgp/community-lists/:name":          auth.RoleUser,
+	"PUT /api/v1/bgp/community-lists/:name":          auth.RoleOperator,
+	"DELETE /api/v1/bgp/community-lists/:name":       auth.RoleOperator,
+	"GET /api/v1/bgp/as-path-lists":                  auth.RoleUser,
+	"POST /api/v1/bgp/as-path-lists":                 auth.RoleOperator,
+	"GET /api/v1/bgp/as-path-lists/:name":            auth.RoleUser,
+	"PUT /api/v1/bgp/as-path-lists/:name":            auth.RoleOperator,
+	"DELETE /api/v1/bgp/as-path-lists/:name":         auth.RoleOperator,
+	"GET /api/v1/bgp/drift":                          auth.RoleUser,
+	"POST /api/v1/bgp/reconcile":                     auth.RoleOperator,
+	"GET /api/v1/bgp/sync":                           auth.RoleUser,
+	"GET /api/v1/leader":                             auth.RoleUser,
+	"GET /api/v1/frr/status":                         auth.RoleUser,
+	"GET /api/v1/frr/transactions":                   auth.RoleUser,
+	"GET /api/v1/frr/transactions/:id":               auth.RoleUser,
+	"POST /api/v1/frr/transactions/:id/backup":       auth.RoleOperator,
+	"GET /api/v1/monitoring/status":                  auth.RoleUser,
+	"GET /api/v1/bgp/top-talkers":                    auth.RoleUser,
+	"GET /api/v1/bgp/stats":                          auth.RoleUser,
+	"GET /api/v1/reports/sla":                        auth.RoleUser,
+	"GET /api/v1/bgp/anomalies":                      auth.RoleUser,
+	"POST /api/v1/bgp/anomalies/analyze":             auth.RoleOperator,
+	"GET /api/v1/bgp/reachability":                   auth.RoleUser,
+	"POST /api/v1/bgp/reachability/probe":            auth.RoleOperator,
+	"GET /api/v1/state":                              auth.RoleAdmin,
+	"POST /api/v1/state":                             auth.RoleAdmin,
+	"GET /api/v1/admin/audit":                        auth.RoleAdmin,
+	"GET /api/v1/admin/cache":                        auth.RoleAdmin,
+	"GET /api/v1/admin/database/snapshots":           auth.RoleAdmin,
+	"POST /api/v1/admin/database/snapshots":          auth.RoleAdmin,
+	"GET /api/v1/admin/database/snapshots/:id":       auth.RoleAdmin,
+	"DELETE /api/v1/admin/database/snapshots/:id":    auth.RoleAdmin,
+	"POST /api/v1/admin/database/restore/:id":        auth.RoleAdmin,
+	"GET /api/v1/admin/log-level":                    auth.RoleAdmin,
+	"PUT /api/v1/admin/log-level":                    auth.RoleAdmin,
+	"GET /api/v1/admin/read-only":                    auth.RoleAdmin,
+	"PUT /api/v1/admin/read-only":                    auth.RoleAdmin,
+	"GET /api/v1/admin/monitoring":                   auth.RoleAdmin,
+	"POST /api/v1/admin/email/test":                  auth.RoleAdmin,
+	"GET /api/v1/admin/notifications/defaults":       auth.RoleAdmin,
+	"PUT /api/v1/admin/notifications/defaults":       auth.RoleAdmin,
+	"POST /api/v1/admin/monitoring/pause":            auth.RoleAdmin,
+	"POST /api/v1/admin/monitoring/resume":           auth.RoleAdmin,
+	"POST /api/v1/admin/impersonate/:user_id":        auth.RoleAdmin,
+	"POST /api/v1/admin/users/:id/disable":           auth.RoleAdmin,
+	"POST /api/v1/admin/users/:id/enable":            auth.RoleAdmin,
+	"PUT /api/v1/admin/users/:id/quota":              auth.RoleAdmin,
+	"GET /api/v1/admin/usage":                        auth.RoleAdmin,
+	"GET /api/v1/admin/websocket":                    auth.RoleAdmin,
+	"GET /api/v1/admin/tenants":                      auth.RoleAdmin,
+	"POST /api/v1/admin/tenants":                     auth.RoleAdmin,
+	"GET /api/v1/admin/tenants/:id":                  auth.RoleAdmin,
+	"PUT /api/v1/admin/tenants/:id":                  auth.RoleAdmin,
+	"DELETE /api/v1/admin/tenants/:id":               auth.RoleAdmin,
+	"GET /api/v1/admin/tenants/:id/members":          auth.RoleAdmin,
+	"PUT /api/v1/admin/tenants/:id/members/:user":    auth.RoleAdmin,
+	"DELETE /api/v1/admin/tenants/:id/members/:user": auth.RoleAdmin,
+	"GET /api/v1/gitops/status":                      auth.RoleUser,
+	"GET /api/v1/gitops/plan":                        auth.RoleUser,
+	"POST /api/v1/gitops/sync":                       auth.RoleOperator,
+	"GET /api/v1/config/running":                     auth.RoleUser,
+	"GET /api/v1/config/versions":                    auth.RoleUser,
+	"PATCH /api/v1/config/versions/:id":              auth.RoleOperator,
+	"GET /api/v1/config/versions/:id/transactions":   auth.RoleUser,
+	"POST /api/v1/config/backup":                     auth.RoleOperator,
+	"GET /api/v1/config/restore/:id/preview":         auth.RoleUser,
+	"POST /api/v1/config/restore/:id":                auth.RoleOperator,
+	"POST /api/v1/config/import-running":             auth.RoleOperator,
+	"GET /api/v1/jobs/:id":                           auth.RoleUser,
+	"GET /api/v1/activity":                           auth.RoleUser,
+	"GET /api/v1/changes":                            auth.RoleUser,
+	"GET /api/v1/changes/:id":                        auth.RoleUser,
+	"POST /api/v1/changes/:id/approve":               auth.RoleAdmin,
+	"POST /api/v1/changes/:id/reject":                auth.RoleAdmin,
+	"GET /api/v1/webhooks":                           auth.RoleUser,
+	"POST /api/v1/webhooks":                          auth.RoleOperator,
+	"GET /api/v1/webhooks/events":                    auth.RoleUser,
+	"GET /api/v1/webhooks/:id":                       auth.RoleUser,
+	"PUT /api/v1/webhooks/:id":                       auth.RoleOperator,
+	"DELETE /api/v1/webhooks/:id":                    auth.RoleOperator,
+	"POST /api/v1/webhooks/:id/ping":                 auth.RoleOperator,
+	"GET /api/v1/webhooks/:id/deliveries":            auth.RoleUser,
+	"POST /api/v1/webhooks/deliveries/:id/redeliver": auth.RoleOperator,
+	"GET /api/v1/alerts":                             auth.RoleUser,
+	"GET /api/v1/alerts/summary":                     auth.RoleUser,
+	"GET /api/v1/alerts/export":                      auth.RoleUser,
+	"POST /api/v1/alerts/acknowledge-all":            auth.RoleOperator,
+	"POST /api/v1/alerts/:id/acknowledge":            auth.RoleOperator,
+	"GET /api/v1/alertmanager/alerts":                auth.RoleUser,
+}
+
+// openRoutes need authentication, or none, but no particular role
+var openRoutes = map[string]bool{
+	"POST /api/v1/auth/login":         true,
+	"POST /api/v1/auth/refresh":       true,
+	"POST /api/v1/auth/logout":        true,
+	"GET /api/v1/me":                  true,
+	"PUT /api/v1/me":                  true,
+	"PUT /api/v1/me/password":         true,
+	"GET /api/v1/me/sessions":         true,
+	"DELETE /api/v1/me/sessions/:id":  true,
+	"GET /api/v1/me/preferences":      true,
+	"PUT /api/v1/me/preferences":      true,
+	"GET /api/v1/me/notifications":    true,
+	"PUT /api/v1/me/notifications":    true,
+	"DELETE /api/v1/me/notifications": true,
+	"GET /api/v1/me/tenants":          true,
+	"GET /api/v1/usage":               true,
+	"GET /api/v1/ws":                  true,
+	"GET /api/v1/events":              true,
+	"GET /api/v1/events/history":      true,
+}
+
+func setupAuthorizationRouter(t *testing.T) (*gin.Engine, *auth.JWTManager) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	jwtManager := auth.NewJWTManager("test-secret", 15*time.Minute, 7*24*time.Hour)
+	server := &Server{
+		router:       gin.New(),
+		logger:       zap.NewNop(),
+		jwtManager:   jwtManager,
+		gitopsSyncer: &gitops.Syncer{},
+		changes:      &changes.Service{},
+	}
+	server.setupRoutes()
+
+	return server.router, jwtManager
+}
+
+func TestRouteRoles(t *testing.T) {
+	router, jwtManager := setupAuthorizationRouter(t)
+
+	t.Run("Every route declares a role", func(t *testing.T) {
+		for _, route := range router.Routes() {
+			key := route.Method + " " + route.Path
+			if !strings.HasPrefix(route.Path, "/api/") || openRoutes[key] {
+				continue
+			}
+			assert.Contains(t, routeRoles, key)
+		}
+	})
+
+	t.Run("Lower roles are forbidden", func(t *testing.T) {
+		roles := []string{auth.RoleUser, auth.RoleOperator, auth.RoleAdmin, "guest"}
+		for route, required := range routeRoles {
+			method, path, _ := strings.Cut(route, " ")
+			path = strings.NewReplacer(":id", "1", ":name", "LIST").Replace(path)
+
+			for _, role := range roles {
+				if auth.HasRole(role, required) {
+					continue
+				}
+
+				token, err := jwtManager.GenerateToken(&models.User{ID: 1, Username: "u", Role: role})
+				require.NoError(t, err)
+
+				req := httptest.NewRequest(method, path, nil)
+				req.Header.Set("Authorization", "Bearer "+token)
+				w := httptest.NewRecorder()
+				router.ServeHTTP(w, req)
+
+				assert.Equal(t, http.StatusForbidden, w.Code, "%s as %s", route, role)
+			}
+		}
+	})
+}
@@ -1,20 +1,34 @@
 package api
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
+	"sort"
 	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
-	"github.com/padminisys/flintroute/internal/models"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/padminisys/flintroute/internal/apierror"
+	"github.com/padminisys/flintroute/internal/bgp"
+	"github.com/padminisys/flintroute/internal/frr"
+	"github.com/padminisys/flintroute/pkg/models"
 	"go.uber.org/zap"
 )
 
-// CreatePeerRequest represents a request to create a BGP peer
+// CreatePeerRequest represents a request to create a BGP peer. A peer
+// provisioned from a template gets the template's settings for the fields
+// the request leaves out. ASN defaults to the router's.
 type CreatePeerRequest struct {
 	Name            string `json:"name" binding:"required"`
 	IPAddress       string `json:"ip_address" binding:"required"`
-	ASN             uint32 `json:"asn" binding:"required"`
-	RemoteASN       uint32 `json:"remote_asn" binding:"required"`
+	TemplateID      *uint  `json:"template_id"`
+	ASN             uint32 `json:"asn"`
+	RemoteASN       uint32 `json:"remote_asn" binding:"required_without=TemplateID"`
 	Description     string `json:"description"`
 	Enabled         bool   `json:"enabled"`
 	Password        string `json:"password"`
@@ -26,14 +40,117 @@ type CreatePeerRequest struct {
 	PrefixListOut   string `json:"prefix_list_out"`
 	MaxPrefixes     int    `json:"max_prefixes"`
 	LocalPreference int    `json:"local_preference"`
+	// Tags are key/value labels used to group and filter peers
+	Tags map[string]string `json:"tags"`
+	PeerOptions
 }
 
-// UpdatePeerRequest represents a request to update a BGP peer
+// peer returns the peer the request describes
+func (req *CreatePeerRequest) peer() *models.BGPPeer {
+	peer := &models.BGPPeer{
+		Name:            req.Name,
+		IPAddress:       req.IPAddress,
+		ASN:             req.ASN,
+		RemoteASN:       req.RemoteASN,
+		Description:     req.Description,
+		Enabled:         req.Enabled,
+		Password:        req.Password,
+		Multihop:        req.Multihop,
+		UpdateSource:    req.UpdateSource,
+		RouteMapIn:      req.RouteMapIn,
+		RouteMapOut:     req.RouteMapOut,
+		PrefixListIn:    req.PrefixListIn,
+		PrefixListOut:   req.PrefixListOut,
+		MaxPrefixes:     req.MaxPrefixes,
+		LocalPreference: req.LocalPreference,
+		Tags:            models.NewPeerTags(req.Tags),
+	}
+	req.PeerOptions.applyTo(peer)
+	return peer
+}
+
+// provisionPeer returns the peer a create request describes, applying its
+// template, if any. fields are the fields the request set; they override
+// the template's.
+func (s *Server) provisionPeer(ctx context.Context, req *CreatePeerRequest, fields []string) (*models.BGPPeer, error) {
+	peer := req.peer()
+	if req.TemplateID == nil {
+		return peer, nil
+	}
+
+	if err := s.bgpService.ApplyTemplate(ctx, peer, *req.TemplateID, fields); err != nil {
+		return nil, err
+	}
+	if peer.RemoteASN == 0 {
+		return nil, fmt.Errorf("%w: remote_asn is required, in the request or its template", bgp.ErrInvalidPeer)
+	}
+	return peer, nil
+}
+
+// requestFields returns the names of the top-level fields of a JSON body
+// bound with ShouldBindBodyWith
+func requestFields(c *gin.Context) []string {
+	body, ok := c.Get(gin.BodyBytesKey)
+	if !ok {
+		return nil
+	}
+	data, _ := body.([]byte)
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil
+	}
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// PeerOptions holds a peer's timers and advanced options. Zero values keep
+// FRR's defaults.
+type PeerOptions struct {
+	Keepalive           int    `json:"keepalive"`
+	HoldTime            int    `json:"holdtime"`
+	ConnectRetry        int    `json:"connect_retry"`
+	Passive             bool   `json:"passive"`
+	TTLSecurityHops     int    `json:"ttl_security_hops"`
+	NextHopSelf         bool   `json:"next_hop_self"`
+	SoftReconfigInbound bool   `json:"soft_reconfiguration_inbound"`
+	RemovePrivateAS     bool   `json:"remove_private_as"`
+	AllowASIn           int    `json:"allowas_in"`
+	LocalAS             uint32 `json:"local_as"`
+	LocalASMode         string `json:"local_as_mode"`
+	MaxPrefixThreshold  int    `json:"max_prefix_threshold"`
+	MaxPrefixAction     string `json:"max_prefix_action"`
+	MaxPrefixRestart    int    `json:"max_prefix_restart"`
+}
+
+// applyTo copies the options onto peer
+func (o *PeerOptions) applyTo(peer *models.BGPPeer) {
+	peer.Keepalive = o.Keepalive
+	peer.HoldTime = o.HoldTime
+	peer.ConnectRetry = o.ConnectRetry
+	peer.Passive = o.Passive
+	peer.TTLSecurityHops = o.TTLSecurityHops
+	peer.NextHopSelf = o.NextHopSelf
+	peer.SoftReconfigInbound = o.SoftReconfigInbound
+	peer.RemovePrivateAS = o.RemovePrivateAS
+	peer.AllowASIn = o.AllowASIn
+	peer.LocalAS = o.LocalAS
+	peer.LocalASMode = o.LocalASMode
+	peer.MaxPrefixThreshold = o.MaxPrefixThreshold
+	peer.MaxPrefixAction = o.MaxPrefixAction
+	peer.MaxPrefixRestart = o.MaxPrefixRestart
+}
+
+// UpdatePeerRequest represents a request to update a BGP peer.
+// The password is changed through the dedicated password endpoint.
 type UpdatePeerRequest struct {
 	Name            string `json:"name"`
 	Description     string `json:"description"`
 	Enabled         bool   `json:"enabled"`
-	Password        string `json:"password"`
 	Multihop        int    `json:"multihop"`
 	UpdateSource    string `json:"update_source"`
 	RouteMapIn      string `json:"route_map_in"`
@@ -42,66 +159,254 @@ type UpdatePeerRequest struct {
 	PrefixListOut   string `json:"prefix_list_out"`
 	MaxPrefixes     int    `json:"max_prefixes"`
 	LocalPreference int    `json:"local_preference"`
+	// Tags are key/value labels used to group and filter peers
+	Tags map[string]string `json:"tags"`
+	PeerOptions
+}
+
+// PatchPeerRequest represents a partial update of a BGP peer.
+// Omitted fields keep their current values.
+type PatchPeerRequest struct {
+	Name                *string `json:"name"`
+	Description         *string `json:"description"`
+	Enabled             *bool   `json:"enabled"`
+	Multihop            *int    `json:"multihop"`
+	UpdateSource        *string `json:"update_source"`
+	RouteMapIn          *string `json:"route_map_in"`
+	RouteMapOut         *string `json:"route_map_out"`
+	PrefixListIn        *string `json:"prefix_list_in"`
+	PrefixListOut       *string `json:"prefix_list_out"`
+	MaxPrefixes         *int    `json:"max_prefixes"`
+	MaxPrefixThreshold  *int    `json:"max_prefix_threshold"`
+	MaxPrefixAction     *string `json:"max_prefix_action"`
+	MaxPrefixRestart    *int    `json:"max_prefix_restart"`
+	LocalPreference     *int    `json:"local_preference"`
+	Keepalive           *int    `json:"keepalive"`
+	HoldTime            *int    `json:"holdtime"`
+	ConnectRetry        *int    `json:"connect_retry"`
+	Passive             *bool   `json:"passive"`
+	TTLSecurityHops     *int    `json:"ttl_security_hops"`
+	NextHopSelf         *bool   `json:"next_hop_self"`
+	SoftReconfigInbound *bool   `json:"soft_reconfiguration_inbound"`
+	RemovePrivateAS     *bool   `json:"remove_private_as"`
+	AllowASIn           *int    `json:"allowas_in"`
+	LocalAS             *uint32 `json:"local_as"`
+	LocalASMode         *string `json:"local_as_mode"`
+	// Tags replaces all of the peer's tags when present
+	Tags map[string]string `json:"tags"`
+}
+
+// StartMaintenanceRequest represents a request to put a peer under
+// maintenance. Without Until the window lasts until it is ended.
+type StartMaintenanceRequest struct {
+	Until *time.Time `json:"until"`
+}
+
+// BulkPeersRequest represents an action applied to every peer matching the
+// tag selectors
+type BulkPeersRequest struct {
+	Tags   []string `json:"tags" binding:"required,min=1"`
+	Action string   `json:"action" binding:"required,oneof=enable disable delete"`
+}
+
+// AdoptNeighborsRequest represents a request to adopt FRR neighbors as
+// peers. Without neighbors, the neighbors that could be are only listed.
+type AdoptNeighborsRequest struct {
+	Neighbors []string `json:"neighbors" binding:"dive,ip"`
+}
+
+// SetPeerPasswordRequest represents a request to rotate a peer's password.
+// An empty password removes authentication.
+type SetPeerPasswordRequest struct {
+	Password *string `json:"password" binding:"required"`
+}
+
+// tagSelectors parses the request's ?tag= filters. It responds with an
+// error and returns false when a selector is invalid.
+func tagSelectors(c *gin.Context) ([]bgp.TagSelector, bool) {
+	selectors, err := bgp.ParseTagSelectors(c.QueryArray("tag"))
+	if err != nil {
+		apierror.Respond(c, http.StatusBadRequest, apierror.CodeValidationFailed, err.Error())
+		return nil, false
+	}
+	return selectors, true
 }
 
-// handleListPeers handles listing all BGP peers
+// handleListPeers handles listing BGP peers, optionally filtered by tag
 func (s *Server) handleListPeers(c *gin.Context) {
-	peers, err := s.bgpService.ListPeers(c.Request.Context())
+	selectors, ok := tagSelectors(c)
+	if !ok {
+		return
+	}
+
+	body, err := s.cachedJSON(c, bgp.CachePeers, c.QueryArray("tag"), func() (interface{}, error) {
+		peers, err := s.bgpService.ListPeers(c.Request.Context(), selectors...)
+		return gin.H{"peers": peers}, err
+	})
 	if err != nil {
 		s.logger.Error("Failed to list peers", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list peers"})
+		apierror.Respond(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to list peers")
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"peers": peers})
+	respondWithETag(c, jsonContentType, body)
+}
+
+// Peer search page sizes
+const (
+	defaultPeerSearchLimit = 50
+	maxPeerSearchLimit     = 500
+)
+
+// PeerSearchResponse is a page of the peers matching a search query
+type PeerSearchResponse struct {
+	Query  string `json:"query"`
+	Limit  int    `json:"limit"`
+	Offset int    `json:"offset"`
+	*bgp.PeerSearchResult
+}
+
+// handleSearchPeers handles searching peers with a query such as
+// asn:65010 state:Established tag:pop=ams name~"transit", given as "q",
+// returning a page of them with facet counts of every match
+func (s *Server) handleSearchPeers(c *gin.Context) {
+	limit := defaultPeerSearchLimit
+	if raw := c.Query("limit"); raw != "" {
+		var err error
+		limit, err = strconv.Atoi(raw)
+		if err != nil || limit < 1 || limit > maxPeerSearchLimit {
+			apierror.Respond(c, http.StatusBadRequest, apierror.CodeValidationFailed, fmt.Sprintf("limit must be between 1 and %d", maxPeerSearchLimit))
+			return
+		}
+	}
+	offset := 0
+	if raw := c.Query("offset"); raw != "" {
+		var err error
+		offset, err = strconv.Atoi(raw)
+		if err != nil || offset < 0 {
+			apierror.Respond(c, http.StatusBadRequest, apierror.CodeValidationFailed, "offset must be a non-negative integer")
+			return
+		}
+	}
+
+	query := c.Query("q")
+	filters, err := bgp.ParsePeerQuery(query)
+	if err != nil {
+		apierror.Respond(c, http.StatusBadRequest, apierror.CodeValidationFailed, err.Error())
+		return
+	}
+
+	result, err := s.bgpService.SearchPeers(c.Request.Context(), bgp.PeerSearch{Filters: filters, Limit: limit, Offset: offset})
+	if err != nil {
+		s.logger.Error("Failed to search peers", zap.Error(err))
+		apierror.Respond(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to search peers")
+		return
+	}
+
+	c.JSON(http.StatusOK, PeerSearchResponse{Query: query, Limit: limit, Offset: offset, PeerSearchResult: result})
 }
 
 // handleGetPeer handles getting a specific BGP peer
 func (s *Server) handleGetPeer(c *gin.Context) {
 	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid peer ID"})
+		apierror.Respond(c, http.StatusBadRequest, apierror.CodeInvalidID, "Invalid peer ID")
 		return
 	}
 
 	peer, err := s.bgpService.GetPeer(c.Request.Context(), uint(id))
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Peer not found"})
+		s.respondPeerError(c, err, "Failed to get peer")
 		return
 	}
 
 	c.JSON(http.StatusOK, peer)
 }
 
+// handleGetPeerFRRConfig handles previewing the FRR configuration generated
+// for a peer as plain text
+func (s *Server) handleGetPeerFRRConfig(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		apierror.Respond(c, http.StatusBadRequest, apierror.CodeInvalidID, "Invalid peer ID")
+		return
+	}
+
+	config, err := s.bgpService.PeerFRRConfig(c.Request.Context(), uint(id))
+	if err != nil {
+		s.respondPeerError(c, err, "Failed to render peer config")
+		return
+	}
+
+	respondWithETag(c, "text/plain; charset=utf-8", []byte(config))
+}
+
+// handleTestPeer handles checking that a peer can be connected to, e.g.
+// before enabling it
+func (s *Server) handleTestPeer(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		apierror.Respond(c, http.StatusBadRequest, apierror.CodeInvalidID, "Invalid peer ID")
+		return
+	}
+
+	test, err := s.bgpService.TestPeer(c.Request.Context(), uint(id))
+	if err != nil {
+		s.respondPeerError(c, err, "Failed to test peer")
+		return
+	}
+
+	c.JSON(http.StatusOK, test)
+}
+
 // handleCreatePeer handles creating a new BGP peer
 func (s *Server) handleCreatePeer(c *gin.Context) {
 	var req CreatePeerRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+	if err := c.ShouldBindBodyWith(&req, binding.JSON); err != nil {
+		apierror.Validation(c, err)
 		return
 	}
 
-	peer := &models.BGPPeer{
-		Name:            req.Name,
-		IPAddress:       req.IPAddress,
-		ASN:             req.ASN,
-		RemoteASN:       req.RemoteASN,
-		Description:     req.Description,
-		Enabled:         req.Enabled,
-		Password:        req.Password,
-		Multihop:        req.Multihop,
-		UpdateSource:    req.UpdateSource,
-		RouteMapIn:      req.RouteMapIn,
-		RouteMapOut:     req.RouteMapOut,
-		PrefixListIn:    req.PrefixListIn,
-		PrefixListOut:   req.PrefixListOut,
-		MaxPrefixes:     req.MaxPrefixes,
-		LocalPreference: req.LocalPreference,
+	fields := requestFields(c)
+	peer, err := s.provisionPeer(c.Request.Context(), &req, fields)
+	if err != nil {
+		s.respondPeerError(c, err, "Failed to apply peer template")
+		return
+	}
+
+	if c.Query("validate_asn") == "true" && !s.validatePeerASN(c, peer) {
+		return
+	}
+
+	if c.Query("dry_run") == "true" {
+		plan, err := s.bgpService.PlanCreatePeer(c.Request.Context(), peer)
+		if err != nil {
+			s.respondPeerError(c, err, "Failed to validate peer")
+			return
+		}
+		c.JSON(http.StatusOK, plan)
+		return
+	}
+
+	if s.changes != nil {
+		// Validate now so that only a change that can be applied is held
+		// for approval
+		if _, err := s.bgpService.PlanCreatePeer(c.Request.Context(), peer); err != nil {
+			s.respondPeerError(c, err, "Failed to validate peer")
+			return
+		}
+		password := req.Password
+		req.Password = ""
+		payload := peerCreatePayload{CreatePeerRequest: req}
+		if req.TemplateID != nil {
+			payload.Fields = fields
+		}
+		s.submitChange(c, ChangePeerCreate, fmt.Sprintf("Create peer %s (%s)", req.Name, req.IPAddress), payload, password)
+		return
 	}
 
 	if err := s.bgpService.CreatePeer(c.Request.Context(), peer); err != nil {
-		s.logger.Error("Failed to create peer", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create peer"})
+		s.respondPeerError(c, err, "Failed to create peer")
 		return
 	}
 
@@ -112,13 +417,13 @@ func (s *Server) handleCreatePeer(c *gin.Context) {
 func (s *Server) handleUpdatePeer(c *gin.Context) {
 	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid peer ID"})
+		apierror.Respond(c, http.StatusBadRequest, apierror.CodeInvalidID, "Invalid peer ID")
 		return
 	}
 
 	var req UpdatePeerRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+	if err := c.ShouldBindBodyWith(&req, binding.JSON); err != nil {
+		apierror.Validation(c, err)
 		return
 	}
 
@@ -126,7 +431,6 @@ func (s *Server) handleUpdatePeer(c *gin.Context) {
 		Name:            req.Name,
 		Description:     req.Description,
 		Enabled:         req.Enabled,
-		Password:        req.Password,
 		Multihop:        req.Multihop,
 		UpdateSource:    req.UpdateSource,
 		RouteMapIn:      req.RouteMapIn,
@@ -135,11 +439,23 @@ func (s *Server) handleUpdatePeer(c *gin.Context) {
 		PrefixListOut:   req.PrefixListOut,
 		MaxPrefixes:     req.MaxPrefixes,
 		LocalPreference: req.LocalPreference,
+		Tags:            models.NewPeerTags(req.Tags),
+	}
+	req.PeerOptions.applyTo(updates)
+	updates.TemplateOverrides = requestFields(c)
+
+	if c.Query("dry_run") == "true" {
+		plan, err := s.bgpService.PlanUpdatePeer(c.Request.Context(), uint(id), updates)
+		if err != nil {
+			s.respondPeerError(c, err, "Failed to validate peer")
+			return
+		}
+		c.JSON(http.StatusOK, plan)
+		return
 	}
 
 	if err := s.bgpService.UpdatePeer(c.Request.Context(), uint(id), updates); err != nil {
-		s.logger.Error("Failed to update peer", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update peer"})
+		s.respondPeerError(c, err, "Failed to update peer")
 		return
 	}
 
@@ -147,48 +463,605 @@ func (s *Server) handleUpdatePeer(c *gin.Context) {
 	c.JSON(http.StatusOK, peer)
 }
 
+// handlePatchPeer handles partially updating a BGP peer
+func (s *Server) handlePatchPeer(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		apierror.Respond(c, http.StatusBadRequest, apierror.CodeInvalidID, "Invalid peer ID")
+		return
+	}
+
+	var req PatchPeerRequest
+	if err := c.ShouldBindBodyWith(&req, binding.JSON); err != nil {
+		apierror.Validation(c, err)
+		return
+	}
+
+	patch := &bgp.PeerPatch{
+		Name:                req.Name,
+		Description:         req.Description,
+		Enabled:             req.Enabled,
+		Multihop:            req.Multihop,
+		UpdateSource:        req.UpdateSource,
+		RouteMapIn:          req.RouteMapIn,
+		RouteMapOut:         req.RouteMapOut,
+		PrefixListIn:        req.PrefixListIn,
+		PrefixListOut:       req.PrefixListOut,
+		MaxPrefixes:         req.MaxPrefixes,
+		MaxPrefixThreshold:  req.MaxPrefixThreshold,
+		MaxPrefixAction:     req.MaxPrefixAction,
+		MaxPrefixRestart:    req.MaxPrefixRestart,
+		LocalPreference:     req.LocalPreference,
+		Keepalive:           req.Keepalive,
+		HoldTime:            req.HoldTime,
+		ConnectRetry:        req.ConnectRetry,
+		Passive:             req.Passive,
+		TTLSecurityHops:     req.TTLSecurityHops,
+		NextHopSelf:         req.NextHopSelf,
+		SoftReconfigInbound: req.SoftReconfigInbound,
+		RemovePrivateAS:     req.RemovePrivateAS,
+		AllowASIn:           req.AllowASIn,
+		LocalAS:             req.LocalAS,
+		LocalASMode:         req.LocalASMode,
+		Tags:                req.Tags,
+		TemplateOverrides:   requestFields(c),
+	}
+
+	if c.Query("dry_run") == "true" {
+		plan, err := s.bgpService.PlanPatchPeer(c.Request.Context(), uint(id), patch)
+		if err != nil {
+			s.respondPeerError(c, err, "Failed to validate peer")
+			return
+		}
+		c.JSON(http.StatusOK, plan)
+		return
+	}
+
+	if err := s.bgpService.PatchPeer(c.Request.Context(), uint(id), patch); err != nil {
+		s.respondPeerError(c, err, "Failed to update peer")
+		return
+	}
+
+	peer, _ := s.bgpService.GetPeer(c.Request.Context(), uint(id))
+	c.JSON(http.StatusOK, peer)
+}
+
+// handleSetPeerPassword handles rotating a BGP peer's password
+func (s *Server) handleSetPeerPassword(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		apierror.Respond(c, http.StatusBadRequest, apierror.CodeInvalidID, "Invalid peer ID")
+		return
+	}
+
+	var req SetPeerPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apierror.Validation(c, err)
+		return
+	}
+
+	if err := s.bgpService.SetPeerPassword(c.Request.Context(), uint(id), *req.Password); err != nil {
+		s.respondPeerError(c, err, "Failed to set peer password")
+		return
+	}
+
+	peer, _ := s.bgpService.GetPeer(c.Request.Context(), uint(id))
+	c.JSON(http.StatusOK, peer)
+}
+
+// handleStartMaintenance handles putting a BGP peer under maintenance
+func (s *Server) handleStartMaintenance(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		apierror.Respond(c, http.StatusBadRequest, apierror.CodeInvalidID, "Invalid peer ID")
+		return
+	}
+
+	// The body is optional; without one the window has no end time
+	var req StartMaintenanceRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+			apierror.Validation(c, err)
+			return
+		}
+	}
+
+	peer, err := s.bgpService.StartMaintenance(c.Request.Context(), uint(id), req.Until)
+	if err != nil {
+		s.respondPeerError(c, err, "Failed to start peer maintenance")
+		return
+	}
+
+	c.JSON(http.StatusOK, peer)
+}
+
+// handleEndMaintenance handles taking a BGP peer out of maintenance
+func (s *Server) handleEndMaintenance(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		apierror.Respond(c, http.StatusBadRequest, apierror.CodeInvalidID, "Invalid peer ID")
+		return
+	}
+
+	peer, err := s.bgpService.EndMaintenance(c.Request.Context(), uint(id))
+	if err != nil {
+		s.respondPeerError(c, err, "Failed to end peer maintenance")
+		return
+	}
+
+	c.JSON(http.StatusOK, peer)
+}
+
 // handleDeletePeer handles deleting a BGP peer
 func (s *Server) handleDeletePeer(c *gin.Context) {
 	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid peer ID"})
+		apierror.Respond(c, http.StatusBadRequest, apierror.CodeInvalidID, "Invalid peer ID")
+		return
+	}
+
+	if s.changes != nil {
+		peer, err := s.bgpService.GetPeer(c.Request.Context(), uint(id))
+		if err != nil {
+			s.respondPeerError(c, err, "Failed to delete peer")
+			return
+		}
+		s.submitChange(c, ChangePeerDelete, fmt.Sprintf("Delete peer %s (%s)", peer.Name, peer.IPAddress),
+			peerDeletePayload{PeerID: peer.ID, Name: peer.Name, IPAddress: peer.IPAddress}, "")
 		return
 	}
 
 	if err := s.bgpService.DeletePeer(c.Request.Context(), uint(id)); err != nil {
-		s.logger.Error("Failed to delete peer", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete peer"})
+		s.respondPeerError(c, err, "Failed to delete peer")
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "Peer deleted successfully"})
 }
 
-// handleListSessions handles listing all BGP sessions
+// handleListDeletedPeers handles listing the peers in the trash
+func (s *Server) handleListDeletedPeers(c *gin.Context) {
+	peers, err := s.bgpService.ListDeletedPeers(c.Request.Context())
+	if err != nil {
+		s.logger.Error("Failed to list deleted peers", zap.Error(err))
+		apierror.Respond(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to list deleted peers")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"peers": peers})
+}
+
+// handleRestorePeer handles taking a BGP peer out of the trash
+func (s *Server) handleRestorePeer(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		apierror.Respond(c, http.StatusBadRequest, apierror.CodeInvalidID, "Invalid peer ID")
+		return
+	}
+
+	peer, err := s.bgpService.RestorePeer(c.Request.Context(), uint(id))
+	if err != nil {
+		s.respondPeerError(c, err, "Failed to restore peer")
+		return
+	}
+
+	c.JSON(http.StatusOK, peer)
+}
+
+// handlePurgeDeletedPeers handles permanently removing peers from the
+// trash. "older_than" limits it to peers deleted longer ago than the given
+// duration; without it the trash is emptied.
+func (s *Server) handlePurgeDeletedPeers(c *gin.Context) {
+	var olderThan time.Duration
+	if raw := c.Query("older_than"); raw != "" {
+		var err error
+		olderThan, err = time.ParseDuration(raw)
+		if err != nil || olderThan < 0 {
+			apierror.Respond(c, http.StatusBadRequest, apierror.CodeValidationFailed, "older_than must be a non-negative duration")
+			return
+		}
+	}
+
+	peers, err := s.bgpService.PurgeDeletedPeers(c.Request.Context(), olderThan)
+	if err != nil {
+		s.logger.Error("Failed to purge deleted peers", zap.Error(err))
+		apierror.Respond(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to purge deleted peers")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"purged": len(peers), "peers": peers})
+}
+
+// handleBulkPeers handles enabling, disabling or deleting every peer
+// matching the given tags
+func (s *Server) handleBulkPeers(c *gin.Context) {
+	var req BulkPeersRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apierror.Validation(c, err)
+		return
+	}
+
+	selectors, err := bgp.ParseTagSelectors(req.Tags)
+	if err != nil {
+		apierror.Respond(c, http.StatusBadRequest, apierror.CodeValidationFailed, err.Error())
+		return
+	}
+
+	results, err := s.bgpService.BulkUpdatePeers(c.Request.Context(), selectors, req.Action)
+	if err != nil {
+		s.logger.Error("Failed to run bulk peer action", zap.Error(err))
+		apierror.Respond(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to run bulk peer action")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"action": req.Action, "peers": results})
+}
+
+// handleAdoptNeighbors handles creating peers for neighbors FRR runs that
+// FlintRoute doesn't manage, with their current settings, and listing the
+// others
+func (s *Server) handleAdoptNeighbors(c *gin.Context) {
+	var req AdoptNeighborsRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			apierror.Validation(c, err)
+			return
+		}
+	}
+
+	report, err := s.bgpService.AdoptNeighbors(c.Request.Context(), req.Neighbors)
+	if err != nil {
+		s.logger.Error("Failed to adopt neighbors", zap.Error(err))
+		switch {
+		case errors.Is(err, frr.ErrNotConnected):
+			apierror.Respond(c, http.StatusServiceUnavailable, apierror.CodeFRRUnavailable, "FRR is unavailable")
+		case errors.Is(err, bgp.ErrNoBGPInstance):
+			apierror.Respond(c, http.StatusUnprocessableEntity, apierror.CodeValidationFailed, err.Error())
+		default:
+			apierror.Respond(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to adopt neighbors")
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// handleListSessions handles listing BGP sessions, optionally filtered by
+// their peer's tags
 func (s *Server) handleListSessions(c *gin.Context) {
-	sessions, err := s.bgpService.ListSessions(c.Request.Context())
+	selectors, ok := tagSelectors(c)
+	if !ok {
+		return
+	}
+
+	body, err := s.cachedJSON(c, bgp.CacheSessions, c.QueryArray("tag"), func() (interface{}, error) {
+		sessions, err := s.bgpService.ListSessions(c.Request.Context(), selectors...)
+		return gin.H{"sessions": sessions}, err
+	})
 	if err != nil {
 		s.logger.Error("Failed to list sessions", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list sessions"})
+		apierror.Respond(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to list sessions")
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"sessions": sessions})
+	respondWithETag(c, jsonContentType, body)
 }
 
 // handleGetSession handles getting a specific BGP session
 func (s *Server) handleGetSession(c *gin.Context) {
 	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid session ID"})
+		apierror.Respond(c, http.StatusBadRequest, apierror.CodeInvalidID, "Invalid session ID")
 		return
 	}
 
 	session, err := s.bgpService.GetSession(c.Request.Context(), uint(id))
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Session not found"})
+		if errors.Is(err, bgp.ErrSessionNotFound) {
+			apierror.Respond(c, http.StatusNotFound, apierror.CodeSessionNotFound, "Session not found")
+			return
+		}
+		s.logger.Error("Failed to get session", zap.Error(err))
+		apierror.Respond(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to get session")
 		return
 	}
 
 	c.JSON(http.StatusOK, session)
-}
\ No newline at end of file
+}
+
+// handleGetDrift handles comparing stored peers with FRR's running configuration
+func (s *Server) handleGetDrift(c *gin.Context) {
+	report, err := s.bgpService.DetectDrift(c.Request.Context())
+	if err != nil {
+		s.respondDriftError(c, err, "Failed to detect drift")
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// handleReconcile handles queueing a reconciliation pass. The drift report
+// is the job's result.
+func (s *Server) handleReconcile(c *gin.Context) {
+	s.enqueueJob(c, JobReconcile, nil, "Failed to queue reconciliation")
+}
+
+// handleGetTopTalkers handles ranking peers by a session counter, given as
+// "by" (prefixes_received by default)
+func (s *Server) handleGetTopTalkers(c *gin.Context) {
+	limit := 10
+	if raw := c.Query("limit"); raw != "" {
+		var err error
+		limit, err = strconv.Atoi(raw)
+		if err != nil || limit < 1 || limit > 100 {
+			apierror.Respond(c, http.StatusBadRequest, apierror.CodeValidationFailed, "limit must be between 1 and 100")
+			return
+		}
+	}
+
+	talkers, err := s.bgpService.TopTalkers(c.Request.Context(), c.DefaultQuery("by", "prefixes_received"), limit)
+	if err != nil {
+		if errors.Is(err, bgp.ErrInvalidMetric) {
+			apierror.Respond(c, http.StatusBadRequest, apierror.CodeValidationFailed, err.Error())
+			return
+		}
+		s.logger.Error("Failed to rank peers", zap.Error(err))
+		apierror.Respond(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to rank peers")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"top_talkers": talkers})
+}
+
+// handleGetSessionStats handles aggregating session statistics by remote
+// ASN, peer group, tag or router
+func (s *Server) handleGetSessionStats(c *gin.Context) {
+	report, err := s.bgpService.SessionStatistics(c.Request.Context(), c.DefaultQuery("by", bgp.StatsByRemoteASN), c.Query("tag_key"))
+	if err != nil {
+		if errors.Is(err, bgp.ErrInvalidDimension) {
+			apierror.Respond(c, http.StatusBadRequest, apierror.CodeValidationFailed, err.Error())
+			return
+		}
+		s.logger.Error("Failed to aggregate session statistics", zap.Error(err))
+		apierror.Respond(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to aggregate session statistics")
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// handleGetAnomalies handles getting the result of the last prefix count
+// analysis
+func (s *Server) handleGetAnomalies(c *gin.Context) {
+	report := s.bgpService.LastAnomalyReport(c.Request.Context())
+	if report == nil {
+		apierror.Respond(c, http.StatusNotFound, apierror.CodeNotFound, "No prefix analysis has run yet")
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// handleAnalyzePrefixes handles queueing a prefix count analysis. The
+// anomaly report is the job's result.
+func (s *Server) handleAnalyzePrefixes(c *gin.Context) {
+	s.enqueueJob(c, JobAnalyzePrefixes, nil, "Failed to queue prefix analysis")
+}
+
+// handleGetReachability handles getting the result of the last peer
+// reachability probe round, limited to the tenant's peers
+func (s *Server) handleGetReachability(c *gin.Context) {
+	report := s.bgpService.LastReachabilityReport(c.Request.Context())
+	if report == nil {
+		apierror.Respond(c, http.StatusNotFound, apierror.CodeNotFound, "No reachability probe has run yet")
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// handleProbeReachability handles queueing a reachability probe round of
+// every enabled peer of the tenant. The reachability report is the job's
+// result.
+func (s *Server) handleProbeReachability(c *gin.Context) {
+	s.enqueueJob(c, JobProbeReachability, nil, "Failed to queue reachability probe")
+}
+
+// handleGetPeerReachability handles getting a peer's reachability samples
+// over the last window, an hour by default
+func (s *Server) handleGetPeerReachability(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		apierror.Respond(c, http.StatusBadRequest, apierror.CodeInvalidID, "Invalid peer ID")
+		return
+	}
+	window, err := time.ParseDuration(c.DefaultQuery("window", "1h"))
+	if err != nil || window <= 0 {
+		apierror.Respond(c, http.StatusBadRequest, apierror.CodeValidationFailed, "window must be a positive duration such as 1h")
+		return
+	}
+
+	samples, err := s.bgpService.PeerReachabilityHistory(c.Request.Context(), uint(id), time.Now().Add(-window))
+	if err != nil {
+		s.respondPeerError(c, err, "Failed to get peer reachability")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"peer_id": id, "window": window.String(), "samples": samples})
+}
+
+// State timeline buckets
+const (
+	defaultTimelineRange   = 24 * time.Hour
+	defaultTimelineBuckets = 48
+	maxTimelineBuckets     = 1000
+)
+
+// handleGetPeerStateTimeline handles getting a peer's session states over
+// the range ending now, a day by default, split into buckets of bucket
+// (1/48th of the range by default) for swimlane charts
+func (s *Server) handleGetPeerStateTimeline(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		apierror.Respond(c, http.StatusBadRequest, apierror.CodeInvalidID, "Invalid peer ID")
+		return
+	}
+	window := defaultTimelineRange
+	if raw := c.Query("range"); raw != "" {
+		if window, err = parseReportRange(raw); err != nil {
+			apierror.Respond(c, http.StatusBadRequest, apierror.CodeValidationFailed, err.Error())
+			return
+		}
+	}
+	bucket := (window / defaultTimelineBuckets).Truncate(time.Second)
+	if raw := c.Query("bucket"); raw != "" {
+		if bucket, err = parseReportRange(raw); err != nil {
+			apierror.Respond(c, http.StatusBadRequest, apierror.CodeValidationFailed, "bucket must be a positive duration such as 1h or 1d")
+			return
+		}
+	}
+	if bucket < time.Second || window/bucket > maxTimelineBuckets {
+		apierror.Respond(c, http.StatusBadRequest, apierror.CodeValidationFailed,
+			fmt.Sprintf("bucket must be at least a second and split the range into at most %d buckets", maxTimelineBuckets))
+		return
+	}
+
+	end := time.Now()
+	timeline, err := s.bgpService.PeerStateTimeline(c.Request.Context(), uint(id), end.Add(-window), end, bucket)
+	if err != nil {
+		s.respondPeerError(c, err, "Failed to get peer state timeline")
+		return
+	}
+
+	c.JSON(http.StatusOK, timeline)
+}
+
+// handleGetPeerSync handles getting the result of the last full peer sync,
+// run when FlintRoute starts and when FRR reconnects
+func (s *Server) handleGetPeerSync(c *gin.Context) {
+	report := s.bgpService.LastSyncReport(c.Request.Context())
+	if report == nil {
+		apierror.Respond(c, http.StatusNotFound, apierror.CodeNotFound, "No peer sync has run yet")
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// handleGetMonitoring handles getting the BGP session monitor's state
+func (s *Server) handleGetMonitoring(c *gin.Context) {
+	c.JSON(http.StatusOK, s.bgpService.MonitorStatus())
+}
+
+// handleGetCacheStats handles reporting response cache hits and misses
+func (s *Server) handleGetCacheStats(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"enabled":    s.cache != nil,
+		"ttl":        s.cache.TTL().String(),
+		"namespaces": s.cache.Stats(),
+	})
+}
+
+// handleGetWebSocketStats handles reporting connected WebSocket and SSE
+// clients and the events dropped for slow ones
+func (s *Server) handleGetWebSocketStats(c *gin.Context) {
+	c.JSON(http.StatusOK, s.wsHub.Stats())
+}
+
+// handlePauseMonitoring handles pausing the BGP session monitor, for example
+// during FRR maintenance
+func (s *Server) handlePauseMonitoring(c *gin.Context) {
+	s.bgpService.PauseMonitoring()
+	c.JSON(http.StatusOK, s.bgpService.MonitorStatus())
+}
+
+// handleResumeMonitoring handles resuming the BGP session monitor
+func (s *Server) handleResumeMonitoring(c *gin.Context) {
+	s.bgpService.ResumeMonitoring()
+	c.JSON(http.StatusOK, s.bgpService.MonitorStatus())
+}
+
+// respondDriftError maps a drift detection error to an API error
+func (s *Server) respondDriftError(c *gin.Context, err error, message string) {
+	s.logger.Error(message, zap.Error(err))
+	if errors.Is(err, frr.ErrNotConnected) {
+		apierror.Respond(c, http.StatusServiceUnavailable, apierror.CodeFRRUnavailable, message+": FRR is unavailable")
+		return
+	}
+
+	apierror.Respond(c, http.StatusInternalServerError, apierror.CodeInternal, message)
+}
+
+// respondPeerError maps a BGP service error for a peer operation to an API error
+func (s *Server) respondPeerError(c *gin.Context, err error, message string) {
+	if errors.Is(err, bgp.ErrPeerNotFound) {
+		apierror.Respond(c, http.StatusNotFound, apierror.CodePeerNotFound, "Peer not found")
+		return
+	}
+	if errors.Is(err, bgp.ErrInvalidPeer) {
+		apierror.Respond(c, http.StatusBadRequest, apierror.CodeValidationFailed, err.Error())
+		return
+	}
+	if errors.Is(err, bgp.ErrPeerExists) {
+		apierror.Respond(c, http.StatusConflict, apierror.CodePeerExists, err.Error())
+		return
+	}
+	if errors.Is(err, bgp.ErrASNMismatch) {
+		apierror.Respond(c, http.StatusConflict, apierror.CodeASNMismatch, err.Error())
+		return
+	}
+	if errors.Is(err, bgp.ErrTemplateNotFound) {
+		apierror.Respond(c, http.StatusNotFound, apierror.CodeTemplateNotFound, "Peer template not found")
+		return
+	}
+	if errors.Is(err, bgp.ErrInvalidTemplate) {
+		apierror.Respond(c, http.StatusBadRequest, apierror.CodeValidationFailed, err.Error())
+		return
+	}
+	if errors.Is(err, bgp.ErrPeerNotInTrash) {
+		apierror.Respond(c, http.StatusConflict, apierror.CodePeerNotDeleted, err.Error())
+		return
+	}
+	if respondFRRRejection(c, err) {
+		return
+	}
+
+	s.logger.Error(message, zap.Error(err))
+	if errors.Is(err, bgp.ErrFRRApplyFailed) {
+		code := apierror.CodeFRRApplyFailed
+		if errors.Is(err, frr.ErrNotConnected) {
+			code = apierror.CodeFRRUnavailable
+		}
+		apierror.Respond(c, http.StatusBadGateway, code, message+": FRR rejected the change")
+		return
+	}
+
+	apierror.Respond(c, http.StatusInternalServerError, apierror.CodeInternal, message)
+}
+
+// respondFRRRejection writes a 422 for configuration FRR rejected, saying
+// what to change and where, and reports whether err was one. Changes FRR
+// refused to apply give FRR_VALIDATION; dry runs, which apply nothing,
+// give FRR_CONFIG_REJECTED.
+func respondFRRRejection(c *gin.Context, err error) bool {
+	if !errors.Is(err, frr.ErrConfigRejected) {
+		return false
+	}
+
+	code := apierror.CodeFRRConfigRejected
+	if errors.Is(err, bgp.ErrFRRApplyFailed) {
+		code = apierror.CodeFRRValidation
+	}
+	configErr, ok := frr.AsConfigError(err)
+	if !ok {
+		apierror.Respond(c, http.StatusUnprocessableEntity, code, err.Error())
+		return true
+	}
+	apierror.RespondFRR(c, http.StatusUnprocessableEntity, code, configErr.Error(), &apierror.FRRDetail{
+		Command: configErr.Command,
+		Line:    configErr.Line,
+		Path:    configErr.Path,
+		Raw:     configErr.Raw,
+	})
+	return true
+}
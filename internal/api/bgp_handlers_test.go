@@ -0,0 +1,399 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/padminisys/flintroute/internal/apierror"
+	"github.com/padminisys/flintroute/internal/auth"
+	"github.com/padminisys/flintroute/internal/bgp"
+	"github.com/padminisys/flintroute/internal/config"
+	"github.com/padminisys/flintroute/internal/frr"
+	"github.com/padminisys/flintroute/internal/store"
+	"github.com/padminisys/flintroute/internal/testutil"
+	"github.com/padminisys/flintroute/internal/websocket"
+	"github.com/padminisys/flintroute/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// mockedServer is an API server with its full route and middleware stack
+// on a mocked BGP service and config version store
+type mockedServer struct {
+	*Server
+	bgp      *mockBGPService
+	versions *mockConfigVersionStore
+	users    map[string]*models.User
+}
+
+func newMockedServer(t *testing.T) *mockedServer {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	logger := zap.NewNop()
+	db := testutil.SetupTestDB(t)
+	t.Cleanup(func() { testutil.CleanupTestDB(t, db) })
+
+	sharedStore := store.NewMemory()
+	denylist, err := auth.NewDenylist(db.DB, sharedStore, 15*time.Minute, logger)
+	require.NoError(t, err)
+
+	server := &mockedServer{
+		bgp:      &mockBGPService{},
+		versions: &mockConfigVersionStore{},
+		users:    make(map[string]*models.User),
+	}
+	server.Server = NewServerWithServices(&config.Config{}, Services{
+		DB:             db,
+		WSHub:          websocket.NewHub(logger),
+		BGP:            server.bgp,
+		ConfigVersions: server.versions,
+		JWTManager:     auth.NewJWTManager("test-secret", 15*time.Minute, 7*24*time.Hour),
+		Denylist:       denylist,
+		Store:          sharedStore,
+		Logger:         logger,
+	})
+
+	for _, role := range []string{auth.RoleUser, auth.RoleOperator, auth.RoleAdmin} {
+		user := &models.User{Username: "test-" + role, Email: role + "@test.example.com", PasswordHash: "x", Role: role, Active: true}
+		require.NoError(t, db.Create(user).Error)
+		server.users[role] = user
+	}
+	return server
+}
+
+// request sends a request as a user with role through the full router
+func (s *mockedServer) request(t *testing.T, role, method, path, body string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	token, err := s.jwtManager.GenerateToken(s.users[role])
+	require.NoError(t, err)
+
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer "+token)
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, req)
+	return w
+}
+
+func TestBGPHandlers(t *testing.T) {
+	peer := &models.BGPPeer{ID: 1, Name: "edge", IPAddress: "192.0.2.1", ASN: 65000, RemoteASN: 65001, Enabled: true}
+
+	t.Run("Lists peers filtered by tag", func(t *testing.T) {
+		server := newMockedServer(t)
+		server.bgp.On("ListPeers", mock.Anything, []bgp.TagSelector{{Key: "role", Value: "ixp"}}).Return([]*models.BGPPeer{peer}, nil).Once()
+
+		w := server.request(t, auth.RoleUser, "GET", "/api/v1/bgp/peers?tag=role:ixp", "")
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		assert.Contains(t, w.Body.String(), `"ip_address":"192.0.2.1"`)
+
+		w = server.request(t, auth.RoleUser, "GET", "/api/v1/bgp/peers?tag=-bad", "")
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		server.bgp.AssertExpectations(t)
+	})
+
+	t.Run("Searches peers", func(t *testing.T) {
+		server := newMockedServer(t)
+		filters, err := bgp.ParsePeerQuery("asn:65001 tag:pop=ams")
+		require.NoError(t, err)
+		server.bgp.On("SearchPeers", mock.Anything, bgp.PeerSearch{Filters: filters, Limit: 10, Offset: 20}).Return(&bgp.PeerSearchResult{
+			Peers:  []*models.BGPPeer{peer},
+			Total:  21,
+			Facets: bgp.PeerFacets{State: []bgp.FacetCount{{Value: "Established", Count: 21}}},
+		}, nil).Once()
+
+		w := server.request(t, auth.RoleUser, "GET", "/api/v1/bgp/peers/search?q=asn:65001+tag:pop%3Dams&limit=10&offset=20", "")
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var resp PeerSearchResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.Equal(t, "asn:65001 tag:pop=ams", resp.Query)
+		assert.Equal(t, int64(21), resp.Total)
+		assert.Equal(t, 10, resp.Limit)
+		require.Len(t, resp.Peers, 1)
+		assert.Equal(t, []bgp.FacetCount{{Value: "Established", Count: 21}}, resp.Facets.State)
+
+		for _, query := range []string{"q=color:blue", "q=asn:x", "limit=0", "limit=501", "offset=-1"} {
+			w = server.request(t, auth.RoleUser, "GET", "/api/v1/bgp/peers/search?"+query, "")
+			assert.Equal(t, http.StatusBadRequest, w.Code, query)
+		}
+		server.bgp.AssertExpectations(t)
+	})
+
+	t.Run("Reports peer reachability", func(t *testing.T) {
+		server := newMockedServer(t)
+		server.bgp.On("LastReachabilityReport", mock.Anything).Return(nil).Once()
+		w := server.request(t, auth.RoleUser, "GET", "/api/v1/bgp/reachability", "")
+		assert.Equal(t, http.StatusNotFound, w.Code)
+
+		server.bgp.On("LastReachabilityReport", mock.Anything).Return(&bgp.ReachabilityReport{
+			Method: bgp.ReachabilityICMP,
+			Peers:  []*bgp.PeerReachability{{PeerID: 1, Diagnosis: bgp.DiagnosisBGPDown}},
+		}).Once()
+		w = server.request(t, auth.RoleUser, "GET", "/api/v1/bgp/reachability", "")
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		assert.Contains(t, w.Body.String(), `"diagnosis":"bgp_down"`)
+
+		server.bgp.On("PeerReachabilityHistory", mock.Anything, uint(1), mock.MatchedBy(func(since time.Time) bool {
+			return time.Since(since).Round(time.Minute) == 6*time.Hour
+		})).Return([]models.ReachabilitySample{{PeerID: 1, Method: "icmp", Sent: 3, Received: 3}}, nil).Once()
+		w = server.request(t, auth.RoleUser, "GET", "/api/v1/bgp/peers/1/reachability?window=6h", "")
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		assert.Contains(t, w.Body.String(), `"window":"6h0m0s"`)
+		assert.Contains(t, w.Body.String(), `"received":3`)
+
+		server.bgp.On("PeerReachabilityHistory", mock.Anything, uint(2), mock.Anything).Return(nil, bgp.ErrPeerNotFound).Once()
+		w = server.request(t, auth.RoleUser, "GET", "/api/v1/bgp/peers/2/reachability", "")
+		assert.Equal(t, http.StatusNotFound, w.Code)
+
+		w = server.request(t, auth.RoleUser, "GET", "/api/v1/bgp/peers/1/reachability?window=-1h", "")
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		server.bgp.AssertExpectations(t)
+	})
+
+	t.Run("Reports peer state timelines", func(t *testing.T) {
+		server := newMockedServer(t)
+		server.bgp.On("PeerStateTimeline", mock.Anything, uint(1), mock.Anything, mock.Anything, 30*time.Minute).Return(&bgp.StateTimeline{PeerID: 1, Bucket: 1800}, nil).Once()
+		w := server.request(t, auth.RoleUser, "GET", "/api/v1/bgp/peers/1/state-timeline", "")
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		assert.Contains(t, w.Body.String(), `"bucket":1800`)
+
+		server.bgp.On("PeerStateTimeline", mock.Anything, uint(1), mock.MatchedBy(func(start time.Time) bool {
+			return time.Since(start).Round(time.Minute) == 7*24*time.Hour
+		}), mock.Anything, 24*time.Hour).Return(&bgp.StateTimeline{PeerID: 1}, nil).Once()
+		w = server.request(t, auth.RoleUser, "GET", "/api/v1/bgp/peers/1/state-timeline?range=7d&bucket=1d", "")
+		assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+		server.bgp.On("PeerStateTimeline", mock.Anything, uint(2), mock.Anything, mock.Anything, mock.Anything).Return(nil, bgp.ErrPeerNotFound).Once()
+		w = server.request(t, auth.RoleUser, "GET", "/api/v1/bgp/peers/2/state-timeline", "")
+		assert.Equal(t, http.StatusNotFound, w.Code)
+
+		for _, query := range []string{"range=-1h", "bucket=soon", "range=30d&bucket=1m"} {
+			w = server.request(t, auth.RoleUser, "GET", "/api/v1/bgp/peers/1/state-timeline?"+query, "")
+			assert.Equal(t, http.StatusBadRequest, w.Code, query)
+		}
+		server.bgp.AssertExpectations(t)
+	})
+
+	t.Run("Adopts neighbors", func(t *testing.T) {
+		server := newMockedServer(t)
+		server.bgp.On("AdoptNeighbors", mock.Anything, []string(nil)).Return(&bgp.AdoptReport{
+			Candidates: []*bgp.AdoptableNeighbor{{IPAddress: "192.0.2.7", Peer: &models.BGPPeer{IPAddress: "192.0.2.7"}}},
+		}, nil).Once()
+		w := server.request(t, auth.RoleOperator, "POST", "/api/v1/bgp/peers/adopt", "")
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		assert.Contains(t, w.Body.String(), `"ip_address":"192.0.2.7"`)
+
+		server.bgp.On("AdoptNeighbors", mock.Anything, []string{"192.0.2.7"}).Return(&bgp.AdoptReport{
+			Adopted: []*models.BGPPeer{{ID: 9, IPAddress: "192.0.2.7"}},
+		}, nil).Once()
+		w = server.request(t, auth.RoleOperator, "POST", "/api/v1/bgp/peers/adopt", `{"neighbors": ["192.0.2.7"]}`)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		assert.Contains(t, w.Body.String(), `"id":9`)
+
+		w = server.request(t, auth.RoleOperator, "POST", "/api/v1/bgp/peers/adopt", `{"neighbors": ["edge-1"]}`)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+
+		server.bgp.On("AdoptNeighbors", mock.Anything, mock.Anything).Return(nil, frr.ErrNotConnected).Once()
+		w = server.request(t, auth.RoleOperator, "POST", "/api/v1/bgp/peers/adopt", `{}`)
+		assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+		server.bgp.AssertExpectations(t)
+	})
+
+	t.Run("Maps peer lookup errors", func(t *testing.T) {
+		server := newMockedServer(t)
+		server.bgp.On("GetPeer", mock.Anything, uint(1)).Return(peer, nil)
+		server.bgp.On("GetPeer", mock.Anything, uint(2)).Return(nil, bgp.ErrPeerNotFound)
+		server.bgp.On("GetPeer", mock.Anything, uint(3)).Return(nil, errors.New("database is locked"))
+
+		w := server.request(t, auth.RoleUser, "GET", "/api/v1/bgp/peers/1", "")
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		w = server.request(t, auth.RoleUser, "GET", "/api/v1/bgp/peers/2", "")
+		assert.Equal(t, http.StatusNotFound, w.Code)
+		assert.Contains(t, w.Body.String(), "PEER_NOT_FOUND")
+
+		w = server.request(t, auth.RoleUser, "GET", "/api/v1/bgp/peers/3", "")
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+		assert.NotContains(t, w.Body.String(), "database is locked", "internal errors are not leaked")
+
+		w = server.request(t, auth.RoleUser, "GET", "/api/v1/bgp/peers/abc", "")
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, w.Body.String(), "INVALID_ID")
+	})
+
+	t.Run("Creates peers", func(t *testing.T) {
+		server := newMockedServer(t)
+		server.bgp.On("CreatePeer", mock.Anything, mock.MatchedBy(func(p *models.BGPPeer) bool {
+			return p.IPAddress == "192.0.2.1" && p.RemoteASN == 65001 && p.HoldTime == 90 && p.Tags.Map()["role"] == "ixp"
+		})).Return(nil).Once()
+
+		body := `{"name": "edge", "ip_address": "192.0.2.1", "asn": 65000, "remote_asn": 65001, "holdtime": 90, "tags": {"role": "ixp"}}`
+		w := server.request(t, auth.RoleUser, "POST", "/api/v1/bgp/peers", body)
+		assert.Equal(t, http.StatusForbidden, w.Code)
+
+		w = server.request(t, auth.RoleOperator, "POST", "/api/v1/bgp/peers", body)
+		assert.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+
+		w = server.request(t, auth.RoleOperator, "POST", "/api/v1/bgp/peers", `{"name": "edge"}`)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, w.Body.String(), "VALIDATION_FAILED")
+		assert.Contains(t, w.Body.String(), `{"field":"remote_asn","message":"required"}`)
+		server.bgp.AssertExpectations(t)
+	})
+
+	t.Run("Rejects local ASN mismatches", func(t *testing.T) {
+		server := newMockedServer(t)
+		server.bgp.On("CreatePeer", mock.Anything, mock.MatchedBy(func(p *models.BGPPeer) bool {
+			return p.ASN == 0 && p.LocalAS == 64999 && p.LocalASMode == models.LocalASReplaceAS
+		})).Return(fmt.Errorf("%w: asn 64999 differs from the router's AS 65000", bgp.ErrASNMismatch)).Once()
+
+		body := `{"name": "edge", "ip_address": "192.0.2.1", "remote_asn": 65001, "local_as": 64999, "local_as_mode": "replace-as"}`
+		w := server.request(t, auth.RoleOperator, "POST", "/api/v1/bgp/peers", body)
+		assert.Equal(t, http.StatusConflict, w.Code)
+		assert.Contains(t, w.Body.String(), "ASN_MISMATCH")
+		server.bgp.AssertExpectations(t)
+	})
+
+	t.Run("Dry runs only plan", func(t *testing.T) {
+		server := newMockedServer(t)
+		server.bgp.On("PlanPatchPeer", mock.Anything, uint(1), mock.MatchedBy(func(p *bgp.PeerPatch) bool {
+			return p.Enabled != nil && !*p.Enabled
+		})).Return(&bgp.PeerPlan{}, nil).Once()
+
+		w := server.request(t, auth.RoleOperator, "PATCH", "/api/v1/bgp/peers/1?dry_run=true", `{"enabled": false}`)
+		assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		server.bgp.AssertExpectations(t)
+		server.bgp.AssertNotCalled(t, "PatchPeer", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Maps FRR failures", func(t *testing.T) {
+		tests := []struct {
+			err    error
+			status int
+			code   string
+		}{
+			{fmt.Errorf("%w: %w", bgp.ErrFRRApplyFailed, frr.ErrNotConnected), http.StatusBadGateway, "FRR_UNAVAILABLE"},
+			{fmt.Errorf("%w: timeout", bgp.ErrFRRApplyFailed), http.StatusBadGateway, "FRR_APPLY_FAILED"},
+			{fmt.Errorf("%w: unknown command", frr.ErrConfigRejected), http.StatusUnprocessableEntity, "FRR_CONFIG_REJECTED"},
+			{fmt.Errorf("%w: 192.0.2.1", bgp.ErrPeerExists), http.StatusConflict, "PEER_EXISTS"},
+			{fmt.Errorf("%w: hold time below 3 seconds", bgp.ErrInvalidPeer), http.StatusBadRequest, "VALIDATION_FAILED"},
+		}
+		for _, tt := range tests {
+			server := newMockedServer(t)
+			server.bgp.On("UpdatePeer", mock.Anything, uint(1), mock.Anything).Return(tt.err)
+
+			w := server.request(t, auth.RoleOperator, "PUT", "/api/v1/bgp/peers/1", `{"name": "edge"}`)
+			assert.Equal(t, tt.status, w.Code, tt.err.Error())
+			assert.Contains(t, w.Body.String(), tt.code, tt.err.Error())
+		}
+
+		server := newMockedServer(t)
+		server.bgp.On("UpdatePeer", mock.Anything, uint(1), mock.Anything).Return(fmt.Errorf("%w: %w", bgp.ErrFRRApplyFailed, &frr.ConfigError{
+			Message: "this FRR version doesn't support the statement",
+			Command: "neighbor 192.0.2.1 route-map",
+			Line:    12,
+			Raw:     "line 12: % Unknown command: neighbor 192.0.2.1 route-map",
+		}))
+		w := server.request(t, auth.RoleOperator, "PUT", "/api/v1/bgp/peers/1", `{"name": "edge"}`)
+		assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+		var resp apierror.Response
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.Equal(t, apierror.CodeFRRValidation, resp.Code)
+		assert.Equal(t, "FRR rejected the configuration: this FRR version doesn't support the statement (line 12: neighbor 192.0.2.1 route-map)", resp.Error)
+		assert.Equal(t, &apierror.FRRDetail{
+			Command: "neighbor 192.0.2.1 route-map",
+			Line:    12,
+			Raw:     "line 12: % Unknown command: neighbor 192.0.2.1 route-map",
+		}, resp.FRR)
+
+		server = newMockedServer(t)
+		server.bgp.On("DetectDrift", mock.Anything).Return(nil, fmt.Errorf("failed to read running config: %w", frr.ErrNotConnected))
+		w = server.request(t, auth.RoleUser, "GET", "/api/v1/bgp/drift", "")
+		assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+		assert.Contains(t, w.Body.String(), "FRR_UNAVAILABLE")
+	})
+
+	t.Run("Maps session errors", func(t *testing.T) {
+		server := newMockedServer(t)
+		server.bgp.On("GetSession", mock.Anything, uint(1)).Return(&models.BGPSession{PeerID: 1, State: "Established"}, nil)
+		server.bgp.On("GetSession", mock.Anything, uint(2)).Return(nil, bgp.ErrSessionNotFound)
+		server.bgp.On("TopTalkers", mock.Anything, "bogus", 10).Return(nil, fmt.Errorf("%w: bogus", bgp.ErrInvalidMetric))
+
+		w := server.request(t, auth.RoleUser, "GET", "/api/v1/bgp/sessions/1", "")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), "Established")
+
+		w = server.request(t, auth.RoleUser, "GET", "/api/v1/bgp/sessions/2", "")
+		assert.Equal(t, http.StatusNotFound, w.Code)
+		assert.Contains(t, w.Body.String(), "SESSION_NOT_FOUND")
+
+		w = server.request(t, auth.RoleUser, "GET", "/api/v1/bgp/top-talkers?by=bogus", "")
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+
+		w = server.request(t, auth.RoleUser, "GET", "/api/v1/bgp/top-talkers?limit=0", "")
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		server.bgp.AssertNumberOfCalls(t, "TopTalkers", 1)
+	})
+
+	t.Run("Pauses monitoring", func(t *testing.T) {
+		server := newMockedServer(t)
+		server.bgp.On("PauseMonitoring").Once()
+		server.bgp.On("MonitorStatus").Return(bgp.MonitorStatus{Paused: true})
+		server.bgp.On("LastSyncReport", mock.Anything).Return(nil)
+
+		w := server.request(t, auth.RoleOperator, "POST", "/api/v1/admin/monitoring/pause", "")
+		assert.Equal(t, http.StatusForbidden, w.Code)
+
+		w = server.request(t, auth.RoleAdmin, "POST", "/api/v1/admin/monitoring/pause", "")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), `"paused":true`)
+
+		w = server.request(t, auth.RoleUser, "GET", "/api/v1/bgp/sync", "")
+		assert.Equal(t, http.StatusNotFound, w.Code)
+		server.bgp.AssertExpectations(t)
+	})
+
+	t.Run("Reports monitoring statistics", func(t *testing.T) {
+		server := newMockedServer(t)
+		lastSuccess := time.Unix(1700000000, 0)
+		server.bgp.On("MonitorStatus").Return(bgp.MonitorStatus{
+			Running:      true,
+			FRRReachable: true,
+			Stats: models.MonitorStats{
+				Cycles:          10,
+				FailedCycles:    2,
+				LastDurationMS:  1500,
+				LastPeersPolled: 3,
+				LastSuccessAt:   &lastSuccess,
+			},
+		})
+
+		w := server.request(t, auth.RoleUser, "GET", "/api/v1/monitoring/status", "")
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), `"failed_cycles":2`)
+
+		w = httptest.NewRecorder()
+		server.router.ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), "flintroute_monitor_cycles_total 10")
+		assert.Contains(t, w.Body.String(), "flintroute_monitor_failed_cycles_total 2")
+		assert.Contains(t, w.Body.String(), "flintroute_monitor_last_cycle_duration_seconds 1.5")
+		assert.Contains(t, w.Body.String(), "flintroute_monitor_last_success_timestamp_seconds 1.7e+09")
+		assert.Contains(t, w.Body.String(), "flintroute_event_clients 0")
+		assert.Contains(t, w.Body.String(), "flintroute_event_dropped_messages_total 0")
+		assert.Contains(t, w.Body.String(), "flintroute_event_slow_client_disconnects_total 0")
+		assert.Contains(t, w.Body.String(), "go_goroutines")
+	})
+}
@@ -0,0 +1,203 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/padminisys/flintroute/internal/apierror"
+	authpkg "github.com/padminisys/flintroute/internal/auth"
+	"github.com/padminisys/flintroute/internal/changes"
+	"github.com/padminisys/flintroute/pkg/models"
+	"go.uber.org/zap"
+)
+
+// Operations held for approval when the two-person rule is enabled
+const (
+	ChangePeerCreate    = "peer.create"
+	ChangePeerDelete    = "peer.delete"
+	ChangeConfigRestore = "config.restore"
+)
+
+// peerCreatePayload holds the request of a peer creation change. Fields
+// are the fields the request set when it uses a template.
+type peerCreatePayload struct {
+	CreatePeerRequest
+	Fields []string `json:"fields,omitempty"`
+}
+
+// peerDeletePayload holds the parameters of a peer deletion change
+type peerDeletePayload struct {
+	PeerID    uint   `json:"peer_id"`
+	Name      string `json:"name"`
+	IPAddress string `json:"ip_address"`
+}
+
+// ReviewChangeRequest represents an approval or rejection of a change
+// request
+type ReviewChangeRequest struct {
+	Comment string `json:"comment"`
+}
+
+// registerChanges sets the appliers for the operations held for approval
+func (s *Server) registerChanges() {
+	s.changes.Register(ChangePeerCreate, s.applyPeerCreate)
+	s.changes.Register(ChangePeerDelete, s.applyPeerDelete)
+	s.changes.Register(ChangeConfigRestore, s.applyConfigRestore)
+}
+
+// applyPeerCreate creates the peer of an approved peer.create change. The
+// peer's password is the change's secret.
+func (s *Server) applyPeerCreate(ctx context.Context, change *models.ChangeRequest, secret string) (interface{}, error) {
+	var payload peerCreatePayload
+	if err := json.Unmarshal(change.Payload, &payload); err != nil {
+		return nil, fmt.Errorf("invalid change payload: %w", err)
+	}
+	payload.Password = secret
+
+	peer, err := s.provisionPeer(ctx, &payload.CreatePeerRequest, payload.Fields)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.bgpService.CreatePeer(ctx, peer); err != nil {
+		return nil, err
+	}
+	return peer, nil
+}
+
+// applyPeerDelete deletes the peer of an approved peer.delete change
+func (s *Server) applyPeerDelete(ctx context.Context, change *models.ChangeRequest, secret string) (interface{}, error) {
+	var payload peerDeletePayload
+	if err := json.Unmarshal(change.Payload, &payload); err != nil {
+		return nil, fmt.Errorf("invalid change payload: %w", err)
+	}
+
+	if err := s.bgpService.DeletePeer(ctx, payload.PeerID); err != nil {
+		return nil, err
+	}
+	return payload, nil
+}
+
+// applyConfigRestore queues the restore job of an approved config.restore
+// change on behalf of the requester. The job is the change's result.
+func (s *Server) applyConfigRestore(ctx context.Context, change *models.ChangeRequest, secret string) (interface{}, error) {
+	var payload restoreJobPayload
+	if err := json.Unmarshal(change.Payload, &payload); err != nil {
+		return nil, fmt.Errorf("invalid change payload: %w", err)
+	}
+
+	return s.jobs.Enqueue(ctx, JobConfigRestore, payload, &change.RequestedBy)
+}
+
+// submitChange holds a change for approval on behalf of the current user
+// and responds with 202 Accepted and the change request's location
+func (s *Server) submitChange(c *gin.Context, operation, summary string, payload interface{}, secret string) {
+	userID, ok := authpkg.GetUserID(c)
+	if !ok {
+		apierror.Respond(c, http.StatusUnauthorized, apierror.CodeUnauthorized, "User not authenticated")
+		return
+	}
+
+	change, err := s.changes.Submit(c.Request.Context(), operation, summary, payload, secret, userID)
+	if err != nil {
+		s.logger.Error("Failed to submit change request", zap.Error(err))
+		apierror.Respond(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to submit change request")
+		return
+	}
+
+	c.Header("Location", fmt.Sprintf("/api/v1/changes/%d", change.ID))
+	c.JSON(http.StatusAccepted, change)
+}
+
+// handleListChanges handles listing change requests, optionally filtered
+// by status
+func (s *Server) handleListChanges(c *gin.Context) {
+	list, err := s.changes.List(c.Request.Context(), c.Query("status"))
+	if err != nil {
+		s.logger.Error("Failed to list change requests", zap.Error(err))
+		apierror.Respond(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to list change requests")
+		return
+	}
+
+	c.JSON(http.StatusOK, list)
+}
+
+// handleGetChange handles getting a change request
+func (s *Server) handleGetChange(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		apierror.Respond(c, http.StatusBadRequest, apierror.CodeInvalidID, "Invalid change request ID")
+		return
+	}
+
+	change, err := s.changes.Get(c.Request.Context(), uint(id))
+	if err != nil {
+		s.respondChangeError(c, err, "Failed to get change request")
+		return
+	}
+
+	c.JSON(http.StatusOK, change)
+}
+
+// handleApproveChange handles approving a change request, which applies it
+func (s *Server) handleApproveChange(c *gin.Context) {
+	s.reviewChange(c, s.changes.Approve, "Failed to approve change request")
+}
+
+// handleRejectChange handles rejecting a change request
+func (s *Server) handleRejectChange(c *gin.Context) {
+	s.reviewChange(c, s.changes.Reject, "Failed to reject change request")
+}
+
+// reviewChange records the current user's review of a change request
+func (s *Server) reviewChange(c *gin.Context, review func(ctx context.Context, id, reviewer uint, comment string) (*models.ChangeRequest, error), message string) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		apierror.Respond(c, http.StatusBadRequest, apierror.CodeInvalidID, "Invalid change request ID")
+		return
+	}
+
+	userID, ok := authpkg.GetUserID(c)
+	if !ok {
+		apierror.Respond(c, http.StatusUnauthorized, apierror.CodeUnauthorized, "User not authenticated")
+		return
+	}
+
+	// The body is optional; it only carries the reviewer's comment
+	var req ReviewChangeRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+			apierror.Validation(c, err)
+			return
+		}
+	}
+
+	change, err := review(c.Request.Context(), uint(id), userID, req.Comment)
+	if err != nil {
+		s.respondChangeError(c, err, message)
+		return
+	}
+
+	c.JSON(http.StatusOK, change)
+}
+
+// respondChangeError responds with the API error matching a change request
+// service error
+func (s *Server) respondChangeError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, changes.ErrChangeNotFound):
+		apierror.Respond(c, http.StatusNotFound, apierror.CodeChangeNotFound, "Change request not found")
+	case errors.Is(err, changes.ErrNotPending):
+		apierror.Respond(c, http.StatusConflict, apierror.CodeChangeNotPending, err.Error())
+	case errors.Is(err, changes.ErrSelfReview):
+		apierror.Respond(c, http.StatusForbidden, apierror.CodeSelfReview, err.Error())
+	default:
+		s.logger.Error(message, zap.Error(err))
+		apierror.Respond(c, http.StatusInternalServerError, apierror.CodeInternal, message)
+	}
+}
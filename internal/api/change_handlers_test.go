@@ -0,0 +1,196 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/padminisys/flintroute/internal/changes"
+	"github.com/padminisys/flintroute/internal/encryption"
+	"github.com/padminisys/flintroute/internal/jobs"
+	"github.com/padminisys/flintroute/internal/websocket"
+	"github.com/padminisys/flintroute/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// setupChangeRouter creates a router with the two-person rule enabled. The
+// X-User-ID header picks the acting user, and X-Tenant-ID the tenant they
+// act for.
+func setupChangeRouter(t *testing.T) (*gin.Engine, *gorm.DB) {
+	server, db := setupTestServer(t)
+	server.wsHub = websocket.NewHub(server.logger)
+	go server.wsHub.Run()
+	server.jobs = jobs.NewQueue(server.db, server.wsHub, 1, server.logger)
+	server.registerJobs()
+
+	cipher, err := encryption.NewCipher(bytes.Repeat([]byte{0x42}, encryption.KeySize))
+	require.NoError(t, err)
+	server.changes = changes.NewService(server.db, cipher, server.wsHub, nil, nil, server.logger)
+	server.registerChanges()
+
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		userID, _ := strconv.ParseUint(c.GetHeader("X-User-ID"), 10, 32)
+		c.Set("user_id", uint(userID))
+		if tenantID, err := strconv.ParseUint(c.GetHeader(TenantHeader), 10, 32); err == nil {
+			actForTenant(c, uint(tenantID))
+		}
+	})
+	router.POST("/config/restore/:id", server.handleRestoreConfig)
+	router.GET("/changes", server.handleListChanges)
+	router.GET("/changes/:id", server.handleGetChange)
+	router.POST("/changes/:id/approve", server.handleApproveChange)
+	router.POST("/changes/:id/reject", server.handleRejectChange)
+
+	return router, db
+}
+
+// serveAs serves a request on behalf of userID
+func serveAs(router *gin.Engine, userID, method, path, body string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("X-User-ID", userID)
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestRestoreConfigNeedsApproval(t *testing.T) {
+	router, db := setupChangeRouter(t)
+	version := models.ConfigVersion{Config: "router bgp 65001", Hash: "abc123", Description: "Baseline"}
+	require.NoError(t, db.Create(&version).Error)
+
+	w := serveAs(router, "1", "POST", "/config/restore/1", "")
+	require.Equal(t, http.StatusAccepted, w.Code)
+	assert.Equal(t, "/api/v1/changes/1", w.Header().Get("Location"))
+	var change models.ChangeRequest
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &change))
+	assert.Equal(t, ChangeConfigRestore, change.Operation)
+	assert.Equal(t, models.ChangePending, change.Status)
+
+	// Nothing is queued until the change is approved
+	var count int64
+	db.Model(&models.Job{}).Count(&count)
+	assert.Zero(t, count)
+
+	t.Run("Requesters can't approve their own changes", func(t *testing.T) {
+		w := serveAs(router, "1", "POST", "/changes/1/approve", "")
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		assert.Contains(t, w.Body.String(), "SELF_REVIEW")
+	})
+
+	t.Run("Lists pending changes", func(t *testing.T) {
+		w := serveAs(router, "2", "GET", "/changes?status=pending", "")
+		require.Equal(t, http.StatusOK, w.Code)
+		var list []models.ChangeRequest
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &list))
+		require.Len(t, list, 1)
+		assert.Equal(t, change.ID, list[0].ID)
+	})
+
+	t.Run("A second admin's approval applies the change", func(t *testing.T) {
+		w := serveAs(router, "2", "POST", "/changes/1/approve", `{"comment":"ok"}`)
+		require.Equal(t, http.StatusOK, w.Code)
+		var approved models.ChangeRequest
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &approved))
+		assert.Equal(t, models.ChangeApplied, approved.Status)
+		assert.Equal(t, "ok", approved.Comment)
+
+		// The restore job runs on behalf of the requester
+		var job models.Job
+		require.NoError(t, db.First(&job).Error)
+		assert.Equal(t, JobConfigRestore, job.Type)
+		require.NotNil(t, job.CreatedBy)
+		assert.Equal(t, uint(1), *job.CreatedBy)
+	})
+
+	t.Run("Reviewed changes can't be reviewed again", func(t *testing.T) {
+		w := serveAs(router, "3", "POST", "/changes/1/reject", "")
+		assert.Equal(t, http.StatusConflict, w.Code)
+	})
+
+	t.Run("Missing change", func(t *testing.T) {
+		w := serveAs(router, "2", "GET", "/changes/9", "")
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+}
+
+func TestChangesAreScopedToTenants(t *testing.T) {
+	router, db := setupChangeRouter(t)
+	acme, globex := uint(1), uint(2)
+	for _, tenant := range []*uint{&acme, &globex, nil} {
+		version := models.ConfigVersion{Config: "router bgp 65001", Hash: fmt.Sprint("hash", tenant), TenantID: tenant}
+		require.NoError(t, db.Create(&version).Error)
+	}
+	for i, tenant := range []string{"1", "2", ""} {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", fmt.Sprintf("/config/restore/%d", i+1), nil)
+		req.Header.Set("X-User-ID", "1")
+		if tenant != "" {
+			req.Header.Set(TenantHeader, tenant)
+		}
+		router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusAccepted, w.Code, w.Body.String())
+	}
+
+	// serveFor serves a request on behalf of user 2 acting for tenant
+	serveFor := func(tenant, method, path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, path, nil)
+		req.Header.Set("X-User-ID", "2")
+		req.Header.Set(TenantHeader, tenant)
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("Lists the tenant's changes only", func(t *testing.T) {
+		w := serveFor("1", "GET", "/changes")
+		require.Equal(t, http.StatusOK, w.Code)
+		var list []models.ChangeRequest
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &list))
+		require.Len(t, list, 1)
+		assert.Equal(t, uint(1), list[0].ID)
+		require.NotNil(t, list[0].TenantID)
+		assert.Equal(t, acme, *list[0].TenantID)
+
+		w = serveAs(router, "2", "GET", "/changes", "")
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &list))
+		assert.Len(t, list, 3, "acting across tenants lists every change")
+	})
+
+	t.Run("Other tenants' changes are not found", func(t *testing.T) {
+		for _, method := range []struct{ method, path string }{
+			{"GET", "/changes/2"},
+			{"GET", "/changes/3"},
+			{"POST", "/changes/2/approve"},
+			{"POST", "/changes/2/reject"},
+		} {
+			w := serveFor("1", method.method, method.path)
+			assert.Equal(t, http.StatusNotFound, w.Code, "%s %s", method.method, method.path)
+		}
+
+		var change models.ChangeRequest
+		require.NoError(t, db.First(&change, 2).Error)
+		assert.Equal(t, models.ChangePending, change.Status)
+	})
+
+	t.Run("Approval applies the change for its tenant", func(t *testing.T) {
+		w := serveAs(router, "2", "POST", "/changes/2/approve", "")
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+		var job models.Job
+		require.NoError(t, db.Where("type = ?", JobConfigRestore).First(&job).Error)
+		require.NotNil(t, job.TenantID, "the requester's tenant can follow the restore")
+		assert.Equal(t, globex, *job.TenantID)
+	})
+}
@@ -0,0 +1,163 @@
+package api
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+// gzipMinSize is the smallest response worth compressing
+const gzipMinSize = 1024
+
+// gzipWriters pools gzip writers between responses
+var gzipWriters = sync.Pool{
+	New: func() interface{} {
+		return gzip.NewWriter(io.Discard)
+	},
+}
+
+// gzipMiddleware compresses responses for clients that accept gzip.
+// Responses smaller than gzipMinSize, event streams and WebSocket upgrades
+// are sent as-is.
+func gzipMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Writer.Header().Add("Vary", "Accept-Encoding")
+		if !acceptsGzip(c.Request) || c.Request.Method == http.MethodHead || c.GetHeader("Upgrade") != "" {
+			c.Next()
+			return
+		}
+
+		w := &gzipWriter{ResponseWriter: c.Writer}
+		c.Writer = w
+		defer w.close()
+
+		c.Next()
+	}
+}
+
+// acceptsGzip reports whether the request's Accept-Encoding allows gzip
+func acceptsGzip(r *http.Request) bool {
+	for _, encoding := range strings.Split(r.Header.Get("Accept-Encoding"), ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(encoding), ";")
+		if !strings.EqualFold(strings.TrimSpace(name), "gzip") {
+			continue
+		}
+		// "gzip;q=0" explicitly refuses gzip
+		q, ok := strings.CutPrefix(strings.ReplaceAll(params, " ", ""), "q=")
+		if ok {
+			if v, err := strconv.ParseFloat(q, 64); err == nil && v == 0 {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// gzipWriter buffers the start of a response until it knows whether the
+// response is worth compressing, then either compresses or passes through
+// the rest
+type gzipWriter struct {
+	gin.ResponseWriter
+	buf     bytes.Buffer
+	decided bool
+	gz      *gzip.Writer
+}
+
+// Write buffers or writes response data
+func (w *gzipWriter) Write(data []byte) (int, error) {
+	if !w.decided {
+		w.buf.Write(data)
+		if err := w.decide(false); err != nil {
+			return 0, err
+		}
+		return len(data), nil
+	}
+	if w.gz != nil {
+		return w.gz.Write(data)
+	}
+	return w.ResponseWriter.Write(data)
+}
+
+// WriteString buffers or writes response data
+func (w *gzipWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+// WriteHeaderNow commits the headers, deciding on compression with what
+// has been written so far
+func (w *gzipWriter) WriteHeaderNow() {
+	if !w.decided {
+		w.decide(true)
+	}
+	w.ResponseWriter.WriteHeaderNow()
+}
+
+// Flush sends what has been written so far, e.g. for streamed responses
+func (w *gzipWriter) Flush() {
+	if !w.decided {
+		w.decide(true)
+	}
+	if w.gz != nil {
+		w.gz.Flush()
+	}
+	w.ResponseWriter.Flush()
+}
+
+// decide chooses between compressing and passing the response through once
+// enough of it is known, and writes out the buffered data. final forces a
+// decision for responses that end or flush while still buffered.
+func (w *gzipWriter) decide(final bool) error {
+	header := w.Header()
+	compress := header.Get("Content-Encoding") == "" &&
+		!strings.HasPrefix(header.Get("Content-Type"), "text/event-stream")
+	if length, err := strconv.Atoi(header.Get("Content-Length")); err == nil && length < gzipMinSize {
+		compress = false
+	}
+	if compress && w.buf.Len() < gzipMinSize {
+		if !final {
+			return nil
+		}
+		compress = false
+	}
+
+	w.decided = true
+	if compress {
+		header.Set("Content-Encoding", "gzip")
+		header.Del("Content-Length")
+		w.gz = gzipWriters.Get().(*gzip.Writer)
+		w.gz.Reset(w.ResponseWriter)
+	}
+
+	data := w.buf.Bytes()
+	w.buf = bytes.Buffer{}
+	if len(data) == 0 {
+		return nil
+	}
+	var err error
+	if w.gz != nil {
+		_, err = w.gz.Write(data)
+	} else {
+		_, err = w.ResponseWriter.Write(data)
+	}
+	return err
+}
+
+// close writes out a response still being buffered and finishes the gzip
+// stream
+func (w *gzipWriter) close() {
+	if !w.decided && w.buf.Len() > 0 {
+		w.decide(true)
+	}
+	if w.gz != nil {
+		w.gz.Close()
+		gzipWriters.Put(w.gz)
+		w.gz = nil
+	}
+}
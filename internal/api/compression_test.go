@@ -0,0 +1,91 @@
+package api
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGzipMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	large := strings.Repeat("router bgp 65000\n", 200)
+
+	router := gin.New()
+	router.Use(gzipMiddleware())
+	router.GET("/large", func(c *gin.Context) { c.String(http.StatusOK, large) })
+	router.GET("/small", func(c *gin.Context) { c.String(http.StatusOK, "ok") })
+	router.GET("/stream", func(c *gin.Context) {
+		c.Header("Content-Type", "text/event-stream")
+		c.String(http.StatusOK, large)
+	})
+	router.GET("/running", func(c *gin.Context) {
+		respondWithETag(c, "text/plain; charset=utf-8", []byte(large))
+	})
+
+	get := func(path, acceptEncoding string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if acceptEncoding != "" {
+			req.Header.Set("Accept-Encoding", acceptEncoding)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	gunzip := func(t *testing.T, w *httptest.ResponseRecorder) string {
+		t.Helper()
+		r, err := gzip.NewReader(w.Body)
+		require.NoError(t, err)
+		body, err := io.ReadAll(r)
+		require.NoError(t, err)
+		return string(body)
+	}
+
+	t.Run("Compresses large responses", func(t *testing.T) {
+		w := get("/large", "br, gzip")
+
+		assert.Equal(t, "gzip", w.Header().Get("Content-Encoding"))
+		assert.Contains(t, w.Header().Values("Vary"), "Accept-Encoding")
+		assert.Less(t, w.Body.Len(), len(large))
+		assert.Equal(t, large, gunzip(t, w))
+	})
+
+	t.Run("Drops the Content-Length of compressed responses", func(t *testing.T) {
+		w := get("/running", "gzip")
+
+		assert.Equal(t, "gzip", w.Header().Get("Content-Encoding"))
+		assert.Empty(t, w.Header().Get("Content-Length"))
+		assert.Equal(t, large, gunzip(t, w))
+	})
+
+	t.Run("Sends small responses as-is", func(t *testing.T) {
+		w := get("/small", "gzip")
+
+		assert.Empty(t, w.Header().Get("Content-Encoding"))
+		assert.Equal(t, "ok", w.Body.String())
+	})
+
+	t.Run("Sends event streams as-is", func(t *testing.T) {
+		w := get("/stream", "gzip")
+
+		assert.Empty(t, w.Header().Get("Content-Encoding"))
+		assert.Equal(t, large, w.Body.String())
+	})
+
+	t.Run("Respects clients without gzip", func(t *testing.T) {
+		for _, acceptEncoding := range []string{"", "br", "gzip;q=0"} {
+			w := get("/running", acceptEncoding)
+
+			assert.Empty(t, w.Header().Get("Content-Encoding"), acceptEncoding)
+			assert.Equal(t, large, w.Body.String())
+			assert.Equal(t, "3400", w.Header().Get("Content-Length"))
+		}
+	})
+}
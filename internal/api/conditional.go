@@ -0,0 +1,77 @@
+package api
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/padminisys/flintroute/internal/apierror"
+)
+
+// jsonContentType is the content type of JSON responses
+const jsonContentType = "application/json; charset=utf-8"
+
+// cachedJSON returns obj encoded as JSON from the response cache, loading
+// it with load on a miss. Entries are keyed by the tenant the request acts
+// for and the query values that select them.
+func (s *Server) cachedJSON(c *gin.Context, namespace string, key []string, load func() (interface{}, error)) ([]byte, error) {
+	key = append([]string{tenantKey(c)}, key...)
+	body, err := s.cache.GetOrLoad(namespace, strings.Join(key, "\n"), func() (interface{}, error) {
+		obj, err := load()
+		if err != nil {
+			return nil, err
+		}
+		return json.Marshal(obj)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return body.([]byte), nil
+}
+
+// respondJSONWithETag responds with obj as JSON and a weak ETag over the
+// body, or with 304 Not Modified when the ETag matches If-None-Match
+func respondJSONWithETag(c *gin.Context, obj interface{}) {
+	body, err := json.Marshal(obj)
+	if err != nil {
+		apierror.Respond(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to encode response")
+		return
+	}
+	respondWithETag(c, jsonContentType, body)
+}
+
+// respondWithETag responds with body and a weak ETag over it, or with 304
+// Not Modified when the ETag matches If-None-Match. The body is sent with a
+// Content-Length. The ETag is weak because the body may be sent compressed.
+func respondWithETag(c *gin.Context, contentType string, body []byte) {
+	sum := sha256.Sum256(body)
+	etag := `W/"` + hex.EncodeToString(sum[:16]) + `"`
+
+	c.Header("ETag", etag)
+	c.Header("Cache-Control", "private, no-cache")
+	if etagMatches(c.GetHeader("If-None-Match"), etag) {
+		c.Status(http.StatusNotModified)
+		return
+	}
+
+	c.DataFromReader(http.StatusOK, int64(len(body)), contentType, bytes.NewReader(body), nil)
+}
+
+// etagMatches reports whether an If-None-Match header matches etag, using
+// the weak comparison RFC 9110 requires for If-None-Match
+func etagMatches(ifNoneMatch, etag string) bool {
+	if ifNoneMatch == "" {
+		return false
+	}
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+	return false
+}
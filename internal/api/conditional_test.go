@@ -0,0 +1,53 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/padminisys/flintroute/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListETags(t *testing.T) {
+	router, db := setupAlertRouter(t)
+
+	get := func(ifNoneMatch string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/alerts", nil)
+		if ifNoneMatch != "" {
+			req.Header.Set("If-None-Match", ifNoneMatch)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	first := get("")
+	require.Equal(t, http.StatusOK, first.Code)
+	etag := first.Header().Get("ETag")
+	require.NotEmpty(t, etag)
+
+	t.Run("Unchanged lists are not resent", func(t *testing.T) {
+		w := get(etag)
+
+		assert.Equal(t, http.StatusNotModified, w.Code)
+		assert.Empty(t, w.Body.String())
+		assert.Equal(t, etag, w.Header().Get("ETag"))
+	})
+
+	t.Run("Matches any of several ETags", func(t *testing.T) {
+		assert.Equal(t, http.StatusNotModified, get(`W/"stale", `+etag).Code)
+		assert.Equal(t, http.StatusNotModified, get("*").Code)
+	})
+
+	t.Run("Changed lists get a new ETag", func(t *testing.T) {
+		require.NoError(t, db.Create(&models.Alert{Type: "peer_down", Severity: "critical", Message: "new"}).Error)
+
+		w := get(etag)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.NotEqual(t, etag, w.Header().Get("ETag"))
+		assert.Contains(t, w.Body.String(), `"message":"new"`)
+	})
+}
@@ -1,194 +1,684 @@
 package api
 
 import (
+	"context"
 	"crypto/sha256"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
+	"regexp"
+	"slices"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/padminisys/flintroute/internal/apierror"
 	authpkg "github.com/padminisys/flintroute/internal/auth"
-	"github.com/padminisys/flintroute/internal/models"
+	"github.com/padminisys/flintroute/internal/bgp"
+	"github.com/padminisys/flintroute/internal/frr"
+	"github.com/padminisys/flintroute/internal/frrconf"
+	"github.com/padminisys/flintroute/internal/repository"
+	"github.com/padminisys/flintroute/internal/tenancy"
+	"github.com/padminisys/flintroute/internal/webhooks"
+	"github.com/padminisys/flintroute/pkg/models"
 	"go.uber.org/zap"
 )
 
 // BackupConfigRequest represents a request to backup configuration
 type BackupConfigRequest struct {
+	Description string   `json:"description"`
+	Name        string   `json:"name" binding:"max=100"`
+	Labels      []string `json:"labels" binding:"max=16"`
+	Pinned      bool     `json:"pinned"`
+}
+
+// UpdateConfigVersionRequest renames, relabels or pins a config version.
+// Omitted fields are left as they are; an empty name or label list clears
+// them.
+type UpdateConfigVersionRequest struct {
+	Name   *string   `json:"name" binding:"omitempty,max=100"`
+	Labels *[]string `json:"labels" binding:"omitempty,max=16"`
+	Pinned *bool     `json:"pinned"`
+}
+
+// RestoreConfigRequest overrides how a restore is verified. Omitted fields
+// default to config_versions.restore.
+type RestoreConfigRequest struct {
+	// VerifyWindow is how long sessions are watched after the restore,
+	// such as "5m"; "0" skips verification
+	VerifyWindow    string `json:"verify_window"`
+	MaxSessionsDown *int   `json:"max_sessions_down" binding:"omitempty,min=0"`
+}
+
+// RestorePreview shows what restoring a configuration version would change
+type RestorePreview struct {
+	VersionID   uint   `json:"version_id"`
 	Description string `json:"description"`
+	// Diff compares FRR's running configuration with the version's, as
+	// "- " and "+ " lines
+	Diff []string `json:"diff"`
+	// Peers is the checklist of peers the restore adds, removes or
+	// reconfigures
+	Peers []RestorePeerCheck `json:"peers"`
+	// BGPGlobal is set when the version's BGP global configuration is
+	// restored with it
+	BGPGlobal    bool                `json:"bgp_global"`
+	Verification restoreVerification `json:"verification"`
+}
+
+// RestorePeerCheck is a peer affected by a restore, with its stored peer
+// and current session state when FlintRoute manages it
+type RestorePeerCheck struct {
+	frrconf.NeighborChange
+	PeerID       *uint  `json:"peer_id,omitempty"`
+	PeerName     string `json:"peer_name,omitempty"`
+	SessionState string `json:"session_state,omitempty"`
+}
+
+// configLabelPattern matches config version labels such as "golden" or
+// "pre-migration"
+var configLabelPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]{0,62}$`)
+
+// normalizeLabels checks labels and drops duplicates, keeping their order
+func normalizeLabels(labels []string) ([]string, error) {
+	normalized := make([]string, 0, len(labels))
+	for _, label := range labels {
+		if !configLabelPattern.MatchString(label) {
+			return nil, fmt.Errorf("label %q must be 1-63 letters, digits, '_', '.' or '-'", label)
+		}
+		if !slices.Contains(normalized, label) {
+			normalized = append(normalized, label)
+		}
+	}
+	return normalized, nil
+}
+
+// describeVersion names a version in messages, such as
+// `config version 4 "before upgrade" (pinned; labels: golden)`
+func describeVersion(version *models.ConfigVersion) string {
+	description := fmt.Sprintf("config version %d", version.ID)
+	if version.Name != "" {
+		description += fmt.Sprintf(" %q", version.Name)
+	}
+
+	var notes []string
+	if version.Pinned {
+		notes = append(notes, "pinned")
+	}
+	if len(version.Labels) > 0 {
+		notes = append(notes, "labels: "+strings.Join(version.Labels, ", "))
+	}
+	if len(notes) > 0 {
+		description += " (" + strings.Join(notes, "; ") + ")"
+	}
+	return description
 }
 
 // handleListConfigVersions handles listing all configuration versions
 func (s *Server) handleListConfigVersions(c *gin.Context) {
-	var versions []models.ConfigVersion
-	if err := s.db.Preload("User").Order("created_at DESC").Find(&versions).Error; err != nil {
+	versions, err := s.repos.Configs.List(c.Request.Context())
+	if err != nil {
 		s.logger.Error("Failed to list config versions", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list config versions"})
+		apierror.Respond(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to list config versions")
+		return
+	}
+
+	if err := s.configVersions.LoadAll(c.Request.Context(), versions); err != nil {
+		s.logger.Error("Failed to load config versions", zap.Error(err))
+		apierror.Respond(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to load config versions")
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"versions": versions})
+	respondJSONWithETag(c, gin.H{"versions": versions})
+}
+
+// handleGetRunningConfig handles getting FRR's running configuration as
+// plain text
+func (s *Server) handleGetRunningConfig(c *gin.Context) {
+	config, err := s.bgpService.GetRunningConfig(c.Request.Context())
+	if err != nil {
+		s.logger.Error("Failed to get running config", zap.Error(err))
+		if errors.Is(err, frr.ErrNotConnected) {
+			apierror.Respond(c, http.StatusServiceUnavailable, apierror.CodeFRRUnavailable, "FRR is unavailable")
+			return
+		}
+		apierror.Respond(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to get running config")
+		return
+	}
+
+	respondWithETag(c, "text/plain; charset=utf-8", []byte(config))
+}
+
+// handleGetFRRStatus handles getting FRR's version, daemons and northbound
+// capabilities
+func (s *Server) handleGetFRRStatus(c *gin.Context) {
+	status, err := s.bgpService.FRRStatus(c.Request.Context())
+	if err != nil {
+		s.logger.Error("Failed to get FRR status", zap.Error(err))
+		if errors.Is(err, frr.ErrNotConnected) {
+			apierror.Respond(c, http.StatusServiceUnavailable, apierror.CodeFRRUnavailable, "FRR is unavailable")
+			return
+		}
+		apierror.Respond(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to get FRR status")
+		return
+	}
+
+	c.JSON(http.StatusOK, status)
 }
 
 // handleBackupConfig handles backing up the current configuration
 func (s *Server) handleBackupConfig(c *gin.Context) {
 	var req BackupConfigRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		apierror.Validation(c, err)
 		return
 	}
+	labels, err := normalizeLabels(req.Labels)
+	if err != nil {
+		apierror.Respond(c, http.StatusBadRequest, apierror.CodeValidationFailed, err.Error())
+		return
+	}
+	req.Labels = labels
 
 	// Get current user ID
 	userID, exists := authpkg.GetUserID(c)
 	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		apierror.Respond(c, http.StatusUnauthorized, apierror.CodeUnauthorized, "User not authenticated")
 		return
 	}
 
-	// Get current FRR configuration
-	config, err := s.bgpService.GetRunningConfig(c.Request.Context())
+	version, created, err := s.backupConfig(c.Request.Context(), userID, &req)
 	if err != nil {
-		s.logger.Error("Failed to get running config", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get running config"})
+		s.logger.Error("Failed to backup config", zap.Error(err))
+		if errors.Is(err, frr.ErrNotConnected) {
+			apierror.Respond(c, http.StatusServiceUnavailable, apierror.CodeFRRUnavailable, "FRR is unavailable")
+			return
+		}
+		apierror.Respond(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to backup config")
 		return
 	}
 
-	// Calculate hash
-	hash := fmt.Sprintf("%x", sha256.Sum256([]byte(config)))
-
-	// Check if this config already exists
-	var existingVersion models.ConfigVersion
-	if err := s.db.Where("hash = ?", hash).First(&existingVersion).Error; err == nil {
+	if !created {
 		c.JSON(http.StatusOK, gin.H{
 			"message": "Configuration already backed up",
-			"version": existingVersion,
+			"version": version,
 		})
 		return
 	}
 
+	c.JSON(http.StatusCreated, version)
+}
+
+// backupConfig saves FRR's running configuration as a config version taken
+// by userID. req's labels must be normalized. When a version with the same
+// configuration exists, it is returned instead and created is false.
+func (s *Server) backupConfig(ctx context.Context, userID uint, req *BackupConfigRequest) (version *models.ConfigVersion, created bool, err error) {
+	// Get current FRR configuration
+	config, err := s.bgpService.GetRunningConfig(ctx)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to get running config: %w", err)
+	}
+
+	// Keep FlintRoute's global BGP settings with the version, so restoring
+	// it restores them too
+	global, err := s.bgpService.GetGlobalConfig(ctx)
+	if err != nil && !errors.Is(err, bgp.ErrGlobalConfigNotFound) {
+		return nil, false, fmt.Errorf("failed to get BGP global configuration: %w", err)
+	}
+
+	// Calculate hash
+	hash := fmt.Sprintf("%x", sha256.Sum256([]byte(config)))
+
+	// Check if this config already exists
+	if existingVersion, err := s.repos.Configs.FindByHash(ctx, hash); err == nil {
+		if err := s.configVersions.Load(ctx, existingVersion); err != nil {
+			return nil, false, fmt.Errorf("failed to load config version: %w", err)
+		}
+		return existingVersion, false, nil
+	}
+
 	// Create new version
-	version := models.ConfigVersion{
+	version = &models.ConfigVersion{
 		Description: req.Description,
 		Config:      config,
 		Hash:        hash,
 		CreatedBy:   userID,
+		BGPGlobal:   global,
+		TenantID:    tenancy.ID(ctx),
+		Name:        req.Name,
+		Labels:      req.Labels,
+		Pinned:      req.Pinned,
 	}
 
-	if err := s.db.Create(&version).Error; err != nil {
-		s.logger.Error("Failed to create config version", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to backup config"})
-		return
+	if err := s.configVersions.Save(ctx, version); err != nil {
+		return nil, false, fmt.Errorf("failed to create config version: %w", err)
 	}
 
 	// Load user info
-	s.db.Preload("User").First(&version, version.ID)
+	if user, err := s.repos.Users.GetByID(ctx, version.CreatedBy); err == nil {
+		version.User = *user
+	}
+
+	s.webhookService.Publish(ctx, webhooks.EventConfigBackedUp, version)
 
 	s.logger.Info("Configuration backed up",
 		zap.Uint("version_id", version.ID),
 		zap.Uint("user_id", userID),
 	)
 
-	c.JSON(http.StatusCreated, version)
+	return version, true, nil
+}
+
+// handleImportRunningConfig handles importing FRR's running configuration
+// into FlintRoute's peers and policies. With ?dry_run=true it reports what
+// would be imported without storing anything.
+func (s *Server) handleImportRunningConfig(c *gin.Context) {
+	report, err := s.bgpService.ImportRunningConfig(c.Request.Context(), c.Query("dry_run") == "true")
+	if err != nil {
+		s.logger.Error("Failed to import running config", zap.Error(err))
+		switch {
+		case errors.Is(err, frr.ErrNotConnected):
+			apierror.Respond(c, http.StatusServiceUnavailable, apierror.CodeFRRUnavailable, "FRR is unavailable")
+		case errors.Is(err, bgp.ErrNoBGPInstance):
+			apierror.Respond(c, http.StatusUnprocessableEntity, apierror.CodeValidationFailed, err.Error())
+		default:
+			apierror.Respond(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to import running config")
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
 }
 
-// handleRestoreConfig handles restoring a configuration version
+// handleRestoreConfig handles restoring a configuration version. The
+// restore runs as a background job.
 func (s *Server) handleRestoreConfig(c *gin.Context) {
 	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid version ID"})
+		apierror.Respond(c, http.StatusBadRequest, apierror.CodeInvalidID, "Invalid version ID")
 		return
 	}
 
 	// Get version
-	var version models.ConfigVersion
-	if err := s.db.First(&version, id).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Version not found"})
+	version, err := s.repos.Configs.Get(c.Request.Context(), uint(id))
+	if err != nil {
+		apierror.Respond(c, http.StatusNotFound, apierror.CodeVersionNotFound, "Version not found")
 		return
 	}
 
-	// TODO: Implement actual configuration restore to FRR
-	// This would involve applying the configuration to FRR via gRPC
-	s.logger.Info("Configuration restore requested",
-		zap.Uint("version_id", uint(id)),
-	)
+	var req RestoreConfigRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+			apierror.Validation(c, err)
+			return
+		}
+	}
+	if req.VerifyWindow != "" {
+		if window, err := time.ParseDuration(req.VerifyWindow); err != nil || window < 0 {
+			apierror.Respond(c, http.StatusBadRequest, apierror.CodeValidationFailed, "Invalid verify_window")
+			return
+		}
+	}
+	payload := restoreJobPayload{
+		VersionID:       version.ID,
+		VerifyWindow:    req.VerifyWindow,
+		MaxSessionsDown: req.MaxSessionsDown,
+	}
+
+	if s.changes != nil {
+		s.submitChange(c, ChangeConfigRestore, "Restore "+describeVersion(version), payload, "")
+		return
+	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"message": "Configuration restore initiated",
-		"version": version,
-	})
+	s.enqueueJob(c, JobConfigRestore, payload, "Failed to queue config restore")
 }
 
-// handleListAlerts handles listing all alerts
-func (s *Server) handleListAlerts(c *gin.Context) {
-	// Parse query parameters
-	acknowledged := c.Query("acknowledged")
-	severity := c.Query("severity")
+// handleRestorePreview handles previewing a restore: the diff between FRR's
+// running configuration and the version's, and the peers it affects
+func (s *Server) handleRestorePreview(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		apierror.Respond(c, http.StatusBadRequest, apierror.CodeInvalidID, "Invalid version ID")
+		return
+	}
 
-	query := s.db.Preload("Peer").Preload("User").Order("created_at DESC")
+	ctx := c.Request.Context()
+	version, err := s.repos.Configs.Get(ctx, uint(id))
+	if err != nil {
+		apierror.Respond(c, http.StatusNotFound, apierror.CodeVersionNotFound, "Version not found")
+		return
+	}
+	if err := s.configVersions.Load(ctx, version); err != nil {
+		s.logger.Error("Failed to load config version", zap.Error(err))
+		apierror.Respond(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to load config version")
+		return
+	}
 
-	if acknowledged != "" {
-		ack := acknowledged == "true"
-		query = query.Where("acknowledged = ?", ack)
+	running, err := s.bgpService.GetRunningConfig(ctx)
+	if err != nil {
+		s.logger.Error("Failed to get running config", zap.Error(err))
+		if errors.Is(err, frr.ErrNotConnected) {
+			apierror.Respond(c, http.StatusServiceUnavailable, apierror.CodeFRRUnavailable, "FRR is unavailable")
+			return
+		}
+		apierror.Respond(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to get running config")
+		return
+	}
+
+	preview := RestorePreview{
+		VersionID:    version.ID,
+		Description:  describeVersion(version),
+		Diff:         frrconf.Diff(running, version.Config),
+		Peers:        []RestorePeerCheck{},
+		BGPGlobal:    version.BGPGlobal != nil,
+		Verification: s.restoreVerification(restoreJobPayload{}),
+	}
+	if preview.Diff == nil {
+		preview.Diff = []string{}
+	}
+
+	changes := frrconf.ChangedNeighbors(frrconf.Parse(running), frrconf.Parse(version.Config))
+	if len(changes) > 0 {
+		peers, err := s.bgpService.ListPeers(ctx)
+		if err != nil {
+			s.logger.Error("Failed to list peers", zap.Error(err))
+			apierror.Respond(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to preview restore")
+			return
+		}
+		sessions, err := s.bgpService.ListSessions(ctx)
+		if err != nil {
+			s.logger.Error("Failed to list sessions", zap.Error(err))
+			apierror.Respond(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to preview restore")
+			return
+		}
+		states := make(map[uint]string, len(sessions))
+		for _, session := range sessions {
+			states[session.PeerID] = session.State
+		}
+
+		for _, change := range changes {
+			check := RestorePeerCheck{NeighborChange: change}
+			for _, peer := range peers {
+				if peer.IPAddress == change.Name {
+					check.PeerID = &peer.ID
+					check.PeerName = peer.Name
+					check.SessionState = states[peer.ID]
+					break
+				}
+			}
+			preview.Peers = append(preview.Peers, check)
+		}
+	}
+
+	c.JSON(http.StatusOK, preview)
+}
+
+// handleUpdateConfigVersion handles renaming, labeling and pinning a
+// configuration version. Pinned versions are kept by retention pruning.
+func (s *Server) handleUpdateConfigVersion(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		apierror.Respond(c, http.StatusBadRequest, apierror.CodeInvalidID, "Invalid version ID")
+		return
+	}
+
+	var req UpdateConfigVersionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apierror.Validation(c, err)
+		return
+	}
+
+	version, err := s.repos.Configs.Get(c.Request.Context(), uint(id))
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			apierror.Respond(c, http.StatusNotFound, apierror.CodeVersionNotFound, "Version not found")
+			return
+		}
+		s.logger.Error("Failed to get config version", zap.Error(err))
+		apierror.Respond(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to update config version")
+		return
 	}
 
-	if severity != "" {
-		query = query.Where("severity = ?", severity)
+	if req.Name != nil {
+		version.Name = *req.Name
+	}
+	if req.Labels != nil {
+		labels, err := normalizeLabels(*req.Labels)
+		if err != nil {
+			apierror.Respond(c, http.StatusBadRequest, apierror.CodeValidationFailed, err.Error())
+			return
+		}
+		version.Labels = labels
+	}
+	if req.Pinned != nil {
+		version.Pinned = *req.Pinned
 	}
 
-	var alerts []models.Alert
-	if err := query.Find(&alerts).Error; err != nil {
+	if err := s.repos.Configs.Annotate(c.Request.Context(), version); err != nil {
+		s.logger.Error("Failed to update config version", zap.Error(err))
+		apierror.Respond(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to update config version")
+		return
+	}
+	if err := s.configVersions.Load(c.Request.Context(), version); err != nil {
+		s.logger.Error("Failed to load config version", zap.Error(err))
+		apierror.Respond(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to load config version")
+		return
+	}
+
+	s.logger.Info("Updated config version",
+		zap.Uint("version_id", version.ID),
+		zap.String("name", version.Name),
+		zap.Strings("labels", version.Labels),
+		zap.Bool("pinned", version.Pinned),
+	)
+
+	c.JSON(http.StatusOK, version)
+}
+
+// handleListAlerts handles listing alerts, newest first. With a limit or a
+// cursor they are listed a page at a time.
+func (s *Server) handleListAlerts(c *gin.Context) {
+	filter, ok := alertFilter(c)
+	if !ok {
+		return
+	}
+
+	if c.Query("limit") == "" && c.Query("cursor") == "" {
+		alerts, err := s.repos.Alerts.List(c.Request.Context(), filter)
+		if err != nil {
+			s.logger.Error("Failed to list alerts", zap.Error(err))
+			apierror.Respond(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to list alerts")
+			return
+		}
+		respondJSONWithETag(c, gin.H{"alerts": alerts})
+		return
+	}
+
+	page, ok := pageQuery(c)
+	if !ok {
+		return
+	}
+	alerts, next, err := s.repos.Alerts.ListPage(c.Request.Context(), filter, page)
+	if err != nil {
 		s.logger.Error("Failed to list alerts", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list alerts"})
+		apierror.Respond(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to list alerts")
 		return
 	}
+	respondJSONWithETag(c, withNextCursor(gin.H{"alerts": alerts}, next))
+}
+
+// alertFilter builds the alert filter selected by the request's query
+// parameters, shared by listing and exporting. It responds with an error
+// and returns false when the filters are invalid.
+func alertFilter(c *gin.Context) (repository.AlertFilter, bool) {
+	selectors, ok := tagSelectors(c)
+	if !ok {
+		return repository.AlertFilter{}, false
+	}
+
+	// Archived alerts are hidden unless explicitly requested
+	filter := repository.AlertFilter{
+		Archived: c.Query("archived") == "true",
+		Severity: c.Query("severity"),
+		Source:   c.Query("source"),
+		Tags:     selectors,
+	}
+	if acknowledged := c.Query("acknowledged"); acknowledged != "" {
+		ack := acknowledged == "true"
+		filter.Acknowledged = &ack
+	}
 
-	c.JSON(http.StatusOK, gin.H{"alerts": alerts})
+	return filter, true
 }
 
 // handleAcknowledgeAlert handles acknowledging an alert
 func (s *Server) handleAcknowledgeAlert(c *gin.Context) {
 	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid alert ID"})
+		apierror.Respond(c, http.StatusBadRequest, apierror.CodeInvalidID, "Invalid alert ID")
 		return
 	}
 
 	// Get current user ID
 	userID, exists := authpkg.GetUserID(c)
 	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		apierror.Respond(c, http.StatusUnauthorized, apierror.CodeUnauthorized, "User not authenticated")
 		return
 	}
 
-	// Get alert
-	var alert models.Alert
-	if err := s.db.First(&alert, id).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Alert not found"})
+	alert, acknowledged, err := s.acknowledgeAlert(c.Request.Context(), uint(id), userID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			apierror.Respond(c, http.StatusNotFound, apierror.CodeAlertNotFound, "Alert not found")
+			return
+		}
+		s.logger.Error("Failed to acknowledge alert", zap.Error(err))
+		apierror.Respond(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to acknowledge alert")
 		return
 	}
 
 	// Check if already acknowledged
-	if alert.Acknowledged {
+	if !acknowledged {
 		c.JSON(http.StatusOK, gin.H{"message": "Alert already acknowledged"})
 		return
 	}
 
-	// Acknowledge alert
+	c.JSON(http.StatusOK, alert)
+}
+
+// acknowledgeAlert acknowledges an alert on behalf of userID. acknowledged
+// is false when the alert already was.
+func (s *Server) acknowledgeAlert(ctx context.Context, id, userID uint) (alert *models.Alert, acknowledged bool, err error) {
+	alert, err = s.repos.Alerts.Get(ctx, id)
+	if err != nil {
+		return nil, false, err
+	}
+	if alert.Acknowledged {
+		return alert, false, nil
+	}
+
 	now := time.Now()
 	alert.Acknowledged = true
 	alert.AcknowledgedAt = &now
 	alert.AcknowledgedBy = &userID
 
-	if err := s.db.Save(&alert).Error; err != nil {
-		s.logger.Error("Failed to acknowledge alert", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to acknowledge alert"})
-		return
+	if err := s.repos.Alerts.Save(ctx, alert); err != nil {
+		return nil, false, fmt.Errorf("failed to save alert: %w", err)
 	}
 
 	s.logger.Info("Alert acknowledged",
-		zap.Uint("alert_id", uint(id)),
+		zap.Uint("alert_id", id),
 		zap.Uint("user_id", userID),
 	)
 
-	c.JSON(http.StatusOK, alert)
-}
\ No newline at end of file
+	return alert, true, nil
+}
+
+// AcknowledgeAlertsRequest filters the alerts acknowledged in bulk. Empty
+// fields match every alert.
+type AcknowledgeAlertsRequest struct {
+	Severity string     `json:"severity"`
+	Source   string     `json:"source"`
+	Type     string     `json:"type"`
+	PeerID   *uint      `json:"peer_id"`
+	Before   *time.Time `json:"before"`
+}
+
+// handleAcknowledgeAllAlerts handles acknowledging every unacknowledged,
+// unarchived alert matching the request filters
+func (s *Server) handleAcknowledgeAllAlerts(c *gin.Context) {
+	// The body is optional; without one every alert matches
+	var req AcknowledgeAlertsRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+			apierror.Validation(c, err)
+			return
+		}
+	}
+
+	// Get current user ID
+	userID, exists := authpkg.GetUserID(c)
+	if !exists {
+		apierror.Respond(c, http.StatusUnauthorized, apierror.CodeUnauthorized, "User not authenticated")
+		return
+	}
+
+	acknowledged, err := s.repos.Alerts.Acknowledge(c.Request.Context(), repository.AlertFilter{
+		Severity: req.Severity,
+		Source:   req.Source,
+		Type:     req.Type,
+		PeerID:   req.PeerID,
+		Before:   req.Before,
+	}, userID, time.Now())
+	if err != nil {
+		s.logger.Error("Failed to acknowledge alerts", zap.Error(err))
+		apierror.Respond(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to acknowledge alerts")
+		return
+	}
+
+	s.logger.Info("Alerts acknowledged",
+		zap.Int64("count", acknowledged),
+		zap.Uint("user_id", userID),
+	)
+
+	c.JSON(http.StatusOK, gin.H{"acknowledged": acknowledged})
+}
+
+// AlertSeverityCount counts alerts of one severity
+type AlertSeverityCount struct {
+	Total          int64 `json:"total"`
+	Unacknowledged int64 `json:"unacknowledged"`
+}
+
+// AlertSummary counts unarchived alerts by severity
+type AlertSummary struct {
+	Total          int64                         `json:"total"`
+	Unacknowledged int64                         `json:"unacknowledged"`
+	BySeverity     map[string]AlertSeverityCount `json:"by_severity"`
+}
+
+// handleAlertSummary handles counting unarchived alerts by severity
+func (s *Server) handleAlertSummary(c *gin.Context) {
+	rows, err := s.repos.Alerts.CountBySeverity(c.Request.Context(), repository.AlertFilter{Source: c.Query("source")})
+	if err != nil {
+		s.logger.Error("Failed to summarize alerts", zap.Error(err))
+		apierror.Respond(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to summarize alerts")
+		return
+	}
+
+	summary := AlertSummary{BySeverity: make(map[string]AlertSeverityCount)}
+	for _, row := range rows {
+		count := summary.BySeverity[row.Severity]
+		count.Total += row.Count
+		summary.Total += row.Count
+		if !row.Acknowledged {
+			count.Unacknowledged += row.Count
+			summary.Unacknowledged += row.Count
+		}
+		summary.BySeverity[row.Severity] = count
+	}
+
+	c.JSON(http.StatusOK, summary)
+}
@@ -0,0 +1,460 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/padminisys/flintroute/internal/auth"
+	"github.com/padminisys/flintroute/internal/bgp"
+	"github.com/padminisys/flintroute/internal/config"
+	"github.com/padminisys/flintroute/internal/frr"
+	"github.com/padminisys/flintroute/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func setupAlertRouter(t *testing.T) (*gin.Engine, *gorm.DB) {
+	server, db := setupTestServer(t)
+
+	router := gin.New()
+	router.Use(func(c *gin.Context) { c.Set("user_id", uint(1)) })
+	router.GET("/alerts", server.handleListAlerts)
+	router.GET("/alerts/summary", server.handleAlertSummary)
+	router.GET("/alerts/export", server.handleExportAlerts)
+	router.POST("/alerts/acknowledge-all", server.handleAcknowledgeAllAlerts)
+
+	now := time.Now()
+	for _, alert := range []models.Alert{
+		{Type: "peer_down", Severity: "critical", Message: "down", Source: models.AlertSourceFlintRoute},
+		{Type: "peer_down", Severity: "critical", Message: "down", Source: models.AlertSourceAlertmanager},
+		{Type: "flapping", Severity: "warning", Message: "flap", Source: models.AlertSourceFlintRoute},
+		{Type: "peer_down", Severity: "critical", Message: "old", Acknowledged: true, ArchivedAt: &now},
+	} {
+		require.NoError(t, db.Create(&alert).Error)
+	}
+
+	return router, db
+}
+
+func TestHandleAcknowledgeAllAlerts(t *testing.T) {
+	t.Run("Acknowledges matching alerts", func(t *testing.T) {
+		router, db := setupAlertRouter(t)
+
+		body, _ := json.Marshal(AcknowledgeAlertsRequest{Severity: "critical", Source: models.AlertSourceFlintRoute})
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/alerts/acknowledge-all", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(w, req)
+
+		require.Equal(t, http.StatusOK, w.Code)
+		var resp map[string]int64
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.Equal(t, int64(1), resp["acknowledged"])
+
+		var alert models.Alert
+		require.NoError(t, db.Where("source = ?", models.AlertSourceFlintRoute).Where("severity = ?", "critical").First(&alert).Error)
+		assert.True(t, alert.Acknowledged)
+		require.NotNil(t, alert.AcknowledgedBy)
+		assert.Equal(t, uint(1), *alert.AcknowledgedBy)
+	})
+
+	t.Run("Acknowledges everything without a body", func(t *testing.T) {
+		router, _ := setupAlertRouter(t)
+
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/alerts/acknowledge-all", nil)
+		router.ServeHTTP(w, req)
+
+		require.Equal(t, http.StatusOK, w.Code)
+		var resp map[string]int64
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.Equal(t, int64(3), resp["acknowledged"])
+	})
+}
+
+func TestHandleAlertSummary(t *testing.T) {
+	router, _ := setupAlertRouter(t)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/alerts/summary", nil)
+	router.ServeHTTP(w, req)
+
+	require.Equal(t, http.StatusOK, w.Code)
+	var summary AlertSummary
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &summary))
+	assert.Equal(t, int64(3), summary.Total)
+	assert.Equal(t, int64(3), summary.Unacknowledged)
+	assert.Equal(t, AlertSeverityCount{Total: 2, Unacknowledged: 2}, summary.BySeverity["critical"])
+	assert.Equal(t, AlertSeverityCount{Total: 1, Unacknowledged: 1}, summary.BySeverity["warning"])
+}
+
+func TestHandleListAlertsArchived(t *testing.T) {
+	router, _ := setupAlertRouter(t)
+
+	for query, want := range map[string]int{"": 3, "?archived=true": 1} {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/alerts"+query, nil)
+		router.ServeHTTP(w, req)
+
+		require.Equal(t, http.StatusOK, w.Code)
+		var resp struct {
+			Alerts []models.Alert `json:"alerts"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.Len(t, resp.Alerts, want, "query %q", query)
+	}
+}
+
+func TestHandleListAlertsByTag(t *testing.T) {
+	router, db := setupAlertRouter(t)
+
+	peer := &models.BGPPeer{
+		Name: "transit", IPAddress: "192.0.2.1", ASN: 65000, RemoteASN: 64500,
+		Tags: models.NewPeerTags(map[string]string{"pop": "fra1"}),
+	}
+	require.NoError(t, db.Create(peer).Error)
+	require.NoError(t, db.Create(&models.Alert{Type: "peer_down", Severity: "critical", Message: "down", PeerID: &peer.ID}).Error)
+
+	for query, want := range map[string]int{"?tag=pop:fra1": 1, "?tag=pop": 1, "?tag=pop:ams1": 0} {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/alerts"+query, nil)
+		router.ServeHTTP(w, req)
+
+		require.Equal(t, http.StatusOK, w.Code)
+		var resp struct {
+			Alerts []models.Alert `json:"alerts"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		require.Len(t, resp.Alerts, want, "query %q", query)
+		if want > 0 {
+			assert.Equal(t, "fra1", resp.Alerts[0].Peer.Tags.Map()["pop"])
+		}
+	}
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/alerts?tag=bad%20key", nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestConfigHandlers(t *testing.T) {
+	t.Run("Backs up the running config once", func(t *testing.T) {
+		server := newMockedServer(t)
+		server.bgp.On("GetRunningConfig", mock.Anything).Return("router bgp 65000\n", nil)
+		server.bgp.On("GetGlobalConfig", mock.Anything).Return(nil, bgp.ErrGlobalConfigNotFound)
+		server.versions.On("Save", mock.Anything, mock.AnythingOfType("*models.ConfigVersion")).Run(func(args mock.Arguments) {
+			require.NoError(t, server.db.Create(args.Get(1)).Error)
+		}).Return(nil).Once()
+		server.versions.On("Load", mock.Anything, mock.AnythingOfType("*models.ConfigVersion")).Return(nil).Once()
+
+		w := server.request(t, auth.RoleOperator, "POST", "/api/v1/config/backup", `{"description": "before upgrade"}`)
+		require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+		var version models.ConfigVersion
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &version))
+		assert.Equal(t, server.users[auth.RoleOperator].ID, version.CreatedBy)
+		assert.Equal(t, "test-operator", version.User.Username)
+		assert.Nil(t, version.BGPGlobal)
+
+		w = server.request(t, auth.RoleOperator, "POST", "/api/v1/config/backup", `{}`)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		assert.Contains(t, w.Body.String(), "already backed up")
+		server.versions.AssertExpectations(t)
+	})
+
+	t.Run("Reports failures", func(t *testing.T) {
+		server := newMockedServer(t)
+		server.bgp.On("GetRunningConfig", mock.Anything).Return("", fmt.Errorf("failed to run vtysh: %w", frr.ErrNotConnected))
+		server.bgp.On("ImportRunningConfig", mock.Anything, true).Return(nil, bgp.ErrNoBGPInstance)
+		server.versions.On("LoadAll", mock.Anything, mock.Anything).Return(errors.New("bucket unreachable"))
+
+		w := server.request(t, auth.RoleUser, "GET", "/api/v1/config/running", "")
+		assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+		assert.Contains(t, w.Body.String(), "FRR_UNAVAILABLE")
+
+		w = server.request(t, auth.RoleOperator, "POST", "/api/v1/config/backup", `{}`)
+		assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+		server.versions.AssertNotCalled(t, "Save", mock.Anything, mock.Anything)
+
+		w = server.request(t, auth.RoleOperator, "POST", "/api/v1/config/import-running?dry_run=true", "")
+		assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+
+		w = server.request(t, auth.RoleUser, "GET", "/api/v1/config/versions", "")
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+		assert.NotContains(t, w.Body.String(), "bucket unreachable")
+	})
+
+	t.Run("Reports FRR status", func(t *testing.T) {
+		server := newMockedServer(t)
+		server.bgp.On("FRRStatus", mock.Anything).Return(&frr.Status{
+			Version: "9.1",
+			Daemons: []frr.DaemonStatus{{Name: "bfdd"}, {Name: "bgpd", Running: true}, {Name: "zebra", Running: true}},
+		}, nil).Once()
+		server.bgp.On("FRRStatus", mock.Anything).Return(nil, frr.ErrNotConnected)
+
+		w := server.request(t, auth.RoleUser, "GET", "/api/v1/frr/status", "")
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var status frr.Status
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &status))
+		assert.True(t, status.AtLeast("8.4"))
+		assert.False(t, status.Running("bfdd"))
+
+		w = server.request(t, auth.RoleUser, "GET", "/api/v1/frr/status", "")
+		assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+		assert.Contains(t, w.Body.String(), "FRR_UNAVAILABLE")
+	})
+
+	t.Run("Queues restores", func(t *testing.T) {
+		server := newMockedServer(t)
+		version := &models.ConfigVersion{Config: "!", Hash: "abc", CreatedBy: server.users[auth.RoleAdmin].ID}
+		require.NoError(t, server.db.Create(version).Error)
+
+		w := server.request(t, auth.RoleOperator, "POST", fmt.Sprintf("/api/v1/config/restore/%d", version.ID), "")
+		require.Equal(t, http.StatusAccepted, w.Code, w.Body.String())
+		assert.Contains(t, w.Body.String(), JobConfigRestore)
+
+		w = server.request(t, auth.RoleOperator, "POST", "/api/v1/config/restore/999", "")
+		assert.Equal(t, http.StatusNotFound, w.Code)
+		assert.Contains(t, w.Body.String(), "VERSION_NOT_FOUND")
+	})
+
+	t.Run("Names, labels and pins versions", func(t *testing.T) {
+		server := newMockedServer(t)
+		server.versions.On("Load", mock.Anything, mock.AnythingOfType("*models.ConfigVersion")).Return(nil)
+		version := &models.ConfigVersion{Config: "!", Hash: "abc", CreatedBy: server.users[auth.RoleAdmin].ID, Name: "nightly"}
+		require.NoError(t, server.db.Create(version).Error)
+		path := fmt.Sprintf("/api/v1/config/versions/%d", version.ID)
+
+		w := server.request(t, auth.RoleOperator, "PATCH", path, `{"labels": ["golden", "pre-migration", "golden"], "pinned": true}`)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var updated models.ConfigVersion
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &updated))
+		assert.Equal(t, "nightly", updated.Name)
+		assert.Equal(t, []string{"golden", "pre-migration"}, updated.Labels)
+		assert.True(t, updated.Pinned)
+
+		w = server.request(t, auth.RoleOperator, "PATCH", path, `{"name": "before upgrade", "labels": []}`)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+		var stored models.ConfigVersion
+		require.NoError(t, server.db.First(&stored, version.ID).Error)
+		assert.Equal(t, "before upgrade", stored.Name)
+		assert.Empty(t, stored.Labels)
+		assert.True(t, stored.Pinned)
+
+		w = server.request(t, auth.RoleOperator, "PATCH", path, `{"labels": ["not a label"]}`)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		w = server.request(t, auth.RoleOperator, "PATCH", "/api/v1/config/versions/999", `{"pinned": true}`)
+		assert.Equal(t, http.StatusNotFound, w.Code)
+		w = server.request(t, auth.RoleUser, "PATCH", path, `{"pinned": false}`)
+		assert.Equal(t, http.StatusForbidden, w.Code)
+	})
+
+	t.Run("Describes the version in restore requests", func(t *testing.T) {
+		version := &models.ConfigVersion{ID: 4, Name: "before upgrade", Labels: []string{"golden"}, Pinned: true}
+		assert.Equal(t, `config version 4 "before upgrade" (pinned; labels: golden)`, describeVersion(version))
+		assert.Equal(t, "config version 5", describeVersion(&models.ConfigVersion{ID: 5}))
+	})
+}
+
+func TestConfigRestore(t *testing.T) {
+	running := "router bgp 65000\n neighbor 192.0.2.1 remote-as 65001\n neighbor 192.0.2.1 shutdown\nexit\n"
+	previous := &models.BGPGlobalConfig{ASN: 65000, RouterID: "10.0.0.1"}
+	established := []*models.BGPSession{{PeerID: 1, State: "Established", Peer: models.BGPPeer{ID: 1, IPAddress: "192.0.2.1"}}}
+	idle := []*models.BGPSession{{PeerID: 1, State: "Idle", Peer: models.BGPPeer{ID: 1, IPAddress: "192.0.2.1"}}}
+
+	setup := func(t *testing.T) (*mockedServer, *models.ConfigVersion) {
+		server := newMockedServer(t)
+		server.versions.On("Load", mock.Anything, mock.AnythingOfType("*models.ConfigVersion")).Return(nil)
+		server.versions.On("Save", mock.Anything, mock.AnythingOfType("*models.ConfigVersion")).Run(func(args mock.Arguments) {
+			require.NoError(t, server.db.Create(args.Get(1).(*models.ConfigVersion)).Error)
+		}).Return(nil)
+		server.bgp.On("GetRunningConfig", mock.Anything).Return(running, nil)
+
+		version := &models.ConfigVersion{
+			Config:    "router bgp 65000\n neighbor 192.0.2.1 remote-as 65001\n neighbor 192.0.2.2 remote-as 65002\nexit\n",
+			Hash:      "abc",
+			CreatedBy: server.users[auth.RoleAdmin].ID,
+			Name:      "golden",
+			BGPGlobal: &models.BGPGlobalConfig{ASN: 65000, RouterID: "10.0.0.2"},
+		}
+		require.NoError(t, server.db.Create(version).Error)
+		return server, version
+	}
+
+	restore := func(t *testing.T, server *mockedServer, payload string) (*restoreResult, error) {
+		job := &models.Job{ID: 1, Payload: payload}
+		result, err := server.runConfigRestore(context.Background(), job, func(int, string) {})
+		if err != nil {
+			return nil, err
+		}
+		return result.(*restoreResult), nil
+	}
+
+	t.Run("Previews restores", func(t *testing.T) {
+		server, version := setup(t)
+		server.bgp.On("ListPeers", mock.Anything, mock.Anything).Return([]*models.BGPPeer{{ID: 1, Name: "edge", IPAddress: "192.0.2.1"}}, nil)
+		server.bgp.On("ListSessions", mock.Anything, mock.Anything).Return(idle, nil)
+
+		w := server.request(t, auth.RoleUser, "GET", fmt.Sprintf("/api/v1/config/restore/%d/preview", version.ID), "")
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var preview RestorePreview
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &preview))
+		assert.Equal(t, []string{"-  neighbor 192.0.2.1 shutdown", "+  neighbor 192.0.2.2 remote-as 65002"}, preview.Diff)
+		assert.True(t, preview.BGPGlobal)
+		require.Len(t, preview.Peers, 2)
+		assert.Equal(t, "192.0.2.1", preview.Peers[0].Name)
+		assert.Equal(t, "changed", preview.Peers[0].Change)
+		require.NotNil(t, preview.Peers[0].PeerID)
+		assert.Equal(t, "edge", preview.Peers[0].PeerName)
+		assert.Equal(t, "Idle", preview.Peers[0].SessionState)
+		assert.Equal(t, "added", preview.Peers[1].Change)
+		assert.Nil(t, preview.Peers[1].PeerID)
+
+		w = server.request(t, auth.RoleUser, "GET", "/api/v1/config/restore/999/preview", "")
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("Rejects invalid verification settings", func(t *testing.T) {
+		server, version := setup(t)
+		path := fmt.Sprintf("/api/v1/config/restore/%d", version.ID)
+
+		w := server.request(t, auth.RoleOperator, "POST", path, `{"verify_window": "soon"}`)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		w = server.request(t, auth.RoleOperator, "POST", path, `{"max_sessions_down": -1}`)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+
+		w = server.request(t, auth.RoleOperator, "POST", path, `{"verify_window": "5m", "max_sessions_down": 2}`)
+		require.Equal(t, http.StatusAccepted, w.Code, w.Body.String())
+		var job models.Job
+		require.NoError(t, server.db.Order("id DESC").First(&job).Error)
+		assert.JSONEq(t, fmt.Sprintf(`{"version_id":%d,"verify_window":"5m","max_sessions_down":2}`, version.ID), job.Payload)
+	})
+
+	t.Run("Rolls back when sessions go down", func(t *testing.T) {
+		server, version := setup(t)
+		server.config.ConfigVersions.Restore = config.RestoreConfig{VerifyWindow: "1s", CheckInterval: "5ms"}
+		server.bgp.On("GetGlobalConfig", mock.Anything).Return(previous, nil)
+		server.bgp.On("ListSessions", mock.Anything, mock.Anything).Return(established, nil).Once()
+		server.bgp.On("ListSessions", mock.Anything, mock.Anything).Return(idle, nil)
+		server.bgp.On("SaveGlobalConfig", mock.Anything, version.BGPGlobal).Return(nil).Once()
+		server.bgp.On("SaveGlobalConfig", mock.Anything, previous).Return(nil).Once()
+
+		result, err := restore(t, server, fmt.Sprintf(`{"version_id":%d}`, version.ID))
+		require.NoError(t, err)
+		assert.Equal(t, RestoreRolledBack, result.Outcome)
+		assert.Equal(t, []string{"192.0.2.1"}, result.SessionsDown)
+		assert.NotEmpty(t, result.Log)
+		server.bgp.AssertExpectations(t)
+
+		// The configuration from before the restore was kept
+		var snapshot models.ConfigVersion
+		require.NoError(t, server.db.First(&snapshot, result.SnapshotID).Error)
+		assert.Equal(t, running, snapshot.Config)
+		assert.Equal(t, []string{preRestoreLabel}, snapshot.Labels)
+
+		var alert models.Alert
+		require.NoError(t, server.db.Where("type = ?", "config_restore_rolled_back").First(&alert).Error)
+		assert.Equal(t, "critical", alert.Severity)
+		assert.Contains(t, alert.Details, "192.0.2.1")
+	})
+
+	t.Run("Keeps restores whose sessions stay up", func(t *testing.T) {
+		server, version := setup(t)
+		server.config.ConfigVersions.Restore = config.RestoreConfig{VerifyWindow: "1s", CheckInterval: "5ms"}
+		server.bgp.On("GetGlobalConfig", mock.Anything).Return(previous, nil)
+		server.bgp.On("ListSessions", mock.Anything, mock.Anything).Return(established, nil).Once()
+		server.bgp.On("ListSessions", mock.Anything, mock.Anything).Return(idle, nil)
+		server.bgp.On("SaveGlobalConfig", mock.Anything, version.BGPGlobal).Return(nil).Once()
+
+		// One session may go down, and the window is overridden
+		result, err := restore(t, server, fmt.Sprintf(`{"version_id":%d,"verify_window":"20ms","max_sessions_down":1}`, version.ID))
+		require.NoError(t, err)
+		assert.Equal(t, RestoreVerified, result.Outcome)
+		assert.Equal(t, "20ms", result.Verification.Window)
+		assert.Equal(t, []string{"192.0.2.1"}, result.SessionsDown)
+		server.bgp.AssertNotCalled(t, "SaveGlobalConfig", mock.Anything, previous)
+
+		var count int64
+		require.NoError(t, server.db.Model(&models.Alert{}).Where("type = ?", "config_restored").Count(&count).Error)
+		assert.EqualValues(t, 1, count)
+	})
+}
+
+func TestAlertHandlersAuthorization(t *testing.T) {
+	server := newMockedServer(t)
+	alert := &models.Alert{Type: "peer_down", Severity: "critical", Message: "down"}
+	require.NoError(t, server.db.Create(alert).Error)
+	path := fmt.Sprintf("/api/v1/alerts/%d/acknowledge", alert.ID)
+
+	w := server.request(t, auth.RoleUser, "GET", "/api/v1/alerts", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"message":"down"`)
+
+	w = server.request(t, auth.RoleUser, "POST", path, "")
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	w = server.request(t, auth.RoleOperator, "POST", path, "")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var acknowledged models.Alert
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &acknowledged))
+	assert.True(t, acknowledged.Acknowledged)
+	assert.Equal(t, server.users[auth.RoleOperator].ID, *acknowledged.AcknowledgedBy)
+
+	w = server.request(t, auth.RoleOperator, "POST", "/api/v1/alerts/999/acknowledge", "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Contains(t, w.Body.String(), "ALERT_NOT_FOUND")
+}
+
+func TestListAlertPages(t *testing.T) {
+	server := newMockedServer(t)
+	for _, message := range []string{"first", "second", "third"} {
+		require.NoError(t, server.db.Create(&models.Alert{Type: "peer_down", Severity: "critical", Message: message}).Error)
+	}
+
+	type page struct {
+		Alerts     []models.Alert `json:"alerts"`
+		NextCursor string         `json:"next_cursor"`
+	}
+	list := func(t *testing.T, query string) page {
+		w := server.request(t, auth.RoleUser, "GET", "/api/v1/alerts"+query, "")
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var resp page
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		return resp
+	}
+
+	t.Run("Lists every alert without a limit", func(t *testing.T) {
+		resp := list(t, "")
+		assert.Len(t, resp.Alerts, 3)
+		assert.Empty(t, resp.NextCursor)
+	})
+
+	t.Run("Follows the cursor to the last page", func(t *testing.T) {
+		resp := list(t, "?limit=2")
+		require.Len(t, resp.Alerts, 2)
+		assert.Equal(t, "third", resp.Alerts[0].Message)
+		require.NotEmpty(t, resp.NextCursor)
+
+		resp = list(t, "?limit=2&cursor="+resp.NextCursor)
+		require.Len(t, resp.Alerts, 1)
+		assert.Equal(t, "first", resp.Alerts[0].Message)
+		assert.Empty(t, resp.NextCursor)
+	})
+
+	t.Run("Invalid page", func(t *testing.T) {
+		for _, query := range []string{"?cursor=not-a-cursor", "?limit=0", "?limit=1001"} {
+			w := server.request(t, auth.RoleUser, "GET", "/api/v1/alerts"+query, "")
+			assert.Equal(t, http.StatusBadRequest, w.Code, query)
+		}
+	})
+}
@@ -0,0 +1,78 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/padminisys/flintroute/internal/apierror"
+	"github.com/padminisys/flintroute/pkg/models"
+)
+
+// PinDemoStateRequest represents a request to pin a simulated peer's
+// session state in demo mode
+type PinDemoStateRequest struct {
+	State     string `json:"state" binding:"required,oneof=Idle Connect Active OpenSent OpenConfirm Established"`
+	LastError string `json:"last_error"`
+}
+
+// handlePinDemoState handles pinning a peer's simulated session in a
+// state, e.g. to show how FlintRoute reports a session that is down
+func (s *Server) handlePinDemoState(c *gin.Context) {
+	peer, ok := s.demoPeer(c)
+	if !ok {
+		return
+	}
+
+	var req PinDemoStateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apierror.Validation(c, err)
+		return
+	}
+
+	if err := s.demo.PinSessionState(peer.IPAddress, req.State, req.LastError); err != nil {
+		apierror.Respond(c, http.StatusNotFound, apierror.CodeSessionNotFound, "Peer has no simulated session; it is disabled")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"peer_id": peer.ID, "state": req.State, "last_error": req.LastError, "pinned": true})
+}
+
+// handleUnpinDemoState handles letting a peer's simulated session change
+// state again; a session that is down re-establishes
+func (s *Server) handleUnpinDemoState(c *gin.Context) {
+	peer, ok := s.demoPeer(c)
+	if !ok {
+		return
+	}
+
+	if err := s.demo.UnpinSessionState(peer.IPAddress); err != nil {
+		apierror.Respond(c, http.StatusNotFound, apierror.CodeNotFound, "Peer's session state is not pinned")
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// demoPeer returns the peer a demo mode request is for. It responds with
+// an error and returns false outside demo mode or when there is no such
+// peer.
+func (s *Server) demoPeer(c *gin.Context) (*models.BGPPeer, bool) {
+	if s.demo == nil {
+		apierror.Respond(c, http.StatusConflict, apierror.CodeDemoDisabled, "Session states can only be pinned in demo mode")
+		return nil, false
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		apierror.Respond(c, http.StatusBadRequest, apierror.CodeInvalidID, "Invalid peer ID")
+		return nil, false
+	}
+
+	peer, err := s.bgpService.GetPeer(c.Request.Context(), uint(id))
+	if err != nil {
+		s.respondPeerError(c, err, "Failed to get peer")
+		return nil, false
+	}
+	return peer, true
+}
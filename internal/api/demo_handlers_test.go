@@ -0,0 +1,67 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/padminisys/flintroute/internal/bgp"
+	"github.com/padminisys/flintroute/internal/frr"
+	"github.com/padminisys/flintroute/internal/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandleDemoState(t *testing.T) {
+	server, _ := setupTestServer(t)
+	demo := frr.NewDemoClient(frr.DemoOptions{}, server.logger)
+	require.NoError(t, demo.Connect(context.Background()))
+	defer demo.Close()
+	server.bgpService = bgp.NewService(server.db, demo, websocket.NewHub(server.logger), bgp.ServiceConfig{}, server.logger)
+
+	router := gin.New()
+	router.POST("/bgp/peers", server.handleCreatePeer)
+	router.PUT("/bgp/peers/:id/demo-state", server.handlePinDemoState)
+	router.DELETE("/bgp/peers/:id/demo-state", server.handleUnpinDemoState)
+
+	w := profileRequest(router, "POST", "/bgp/peers", `{"name": "demo", "ip_address": "192.0.2.1", "asn": 65001, "remote_asn": 65002, "enabled": true}`)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	sessionState := func() string {
+		state, err := demo.GetBGPSessionState(context.Background(), "192.0.2.1")
+		require.NoError(t, err)
+		return state.State
+	}
+	require.Eventually(t, func() bool { return sessionState() == "Established" }, time.Second, time.Millisecond)
+
+	t.Run("Only in demo mode", func(t *testing.T) {
+		server.demo = nil
+		w := profileRequest(router, "PUT", "/bgp/peers/1/demo-state", `{"state": "Idle"}`)
+		assert.Equal(t, http.StatusConflict, w.Code)
+		assert.Contains(t, w.Body.String(), "DEMO_MODE_DISABLED")
+	})
+	server.demo = demo
+
+	t.Run("Pin and unpin a session state", func(t *testing.T) {
+		w := profileRequest(router, "PUT", "/bgp/peers/1/demo-state", `{"state": "Active", "last_error": "Hold Timer Expired"}`)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		assert.Equal(t, "Active", sessionState())
+
+		w = profileRequest(router, "DELETE", "/bgp/peers/1/demo-state", "")
+		assert.Equal(t, http.StatusNoContent, w.Code)
+		require.Eventually(t, func() bool { return sessionState() == "Established" }, time.Second, time.Millisecond)
+
+		w = profileRequest(router, "DELETE", "/bgp/peers/1/demo-state", "")
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("Invalid requests", func(t *testing.T) {
+		w := profileRequest(router, "PUT", "/bgp/peers/1/demo-state", `{"state": "Down"}`)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+
+		w = profileRequest(router, "PUT", "/bgp/peers/99/demo-state", `{"state": "Idle"}`)
+		assert.Equal(t, http.StatusNotFound, w.Code)
+		assert.Contains(t, w.Body.String(), "PEER_NOT_FOUND")
+	})
+}
@@ -0,0 +1,243 @@
+package api
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/padminisys/flintroute/internal/apierror"
+	"github.com/padminisys/flintroute/pkg/models"
+	"go.uber.org/zap"
+)
+
+// Export formats
+const (
+	ExportCSV    = "csv"
+	ExportNDJSON = "ndjson"
+)
+
+// exportBatchSize is the number of alerts read and streamed at a time
+const exportBatchSize = 500
+
+// alertExportColumns are the CSV columns of an alert export
+var alertExportColumns = []string{
+	"id", "created_at", "type", "severity", "source", "message", "details",
+	"peer_id", "peer_name", "peer_ip", "acknowledged", "acknowledged_at",
+	"acknowledged_by", "resolved_at", "archived_at",
+}
+
+// sessionExportColumns are the CSV columns of a session export
+var sessionExportColumns = []string{
+	"id", "peer_id", "peer_name", "peer_ip", "remote_asn", "state", "uptime",
+	"prefixes_received", "prefixes_sent", "messages_received", "messages_sent",
+	"last_error", "last_reset", "in_maintenance", "updated_at",
+}
+
+// handleExportAlerts handles streaming the alerts selected by the list
+// filters as CSV or NDJSON, oldest first
+func (s *Server) handleExportAlerts(c *gin.Context) {
+	format, ok := exportFormat(c)
+	if !ok {
+		return
+	}
+	filter, ok := alertFilter(c)
+	if !ok {
+		return
+	}
+
+	w := startExport(c, "alerts", format, alertExportColumns)
+	err := s.repos.Alerts.Each(c.Request.Context(), filter, exportBatchSize, func(batch []models.Alert) error {
+		for i := range batch {
+			if err := w.write(&batch[i], alertExportRow(&batch[i])); err != nil {
+				return err
+			}
+		}
+		return w.flush()
+	})
+	if err == nil {
+		err = w.flush()
+	}
+	if err != nil {
+		s.logger.Error("Failed to export alerts", zap.Error(err))
+		// Once streaming has started, the truncated export is all the
+		// client gets
+		if !c.Writer.Written() {
+			w.abort()
+			apierror.Respond(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to export alerts")
+		}
+	}
+}
+
+// handleExportSessions handles streaming the BGP sessions selected by the
+// list filters as CSV or NDJSON
+func (s *Server) handleExportSessions(c *gin.Context) {
+	format, ok := exportFormat(c)
+	if !ok {
+		return
+	}
+	selectors, ok := tagSelectors(c)
+	if !ok {
+		return
+	}
+
+	sessions, err := s.bgpService.ListSessions(c.Request.Context(), selectors...)
+	if err != nil {
+		s.logger.Error("Failed to list sessions", zap.Error(err))
+		apierror.Respond(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to export sessions")
+		return
+	}
+
+	w := startExport(c, "sessions", format, sessionExportColumns)
+	for _, session := range sessions {
+		if err := w.write(session, sessionExportRow(session)); err != nil {
+			s.logger.Error("Failed to export sessions", zap.Error(err))
+			return
+		}
+	}
+	if err := w.flush(); err != nil {
+		s.logger.Error("Failed to export sessions", zap.Error(err))
+	}
+}
+
+// exportFormat returns the requested export format, CSV by default. It
+// responds with an error and returns false for unknown formats.
+func exportFormat(c *gin.Context) (string, bool) {
+	format := c.DefaultQuery("format", ExportCSV)
+	switch format {
+	case ExportCSV, ExportNDJSON:
+		return format, true
+	default:
+		apierror.Respond(c, http.StatusBadRequest, apierror.CodeValidationFailed,
+			fmt.Sprintf("Unknown export format %q (must be %s or %s)", format, ExportCSV, ExportNDJSON))
+		return "", false
+	}
+}
+
+// exportWriter writes records as CSV rows or NDJSON lines, flushing them to
+// the client as it goes
+type exportWriter struct {
+	c   *gin.Context
+	csv *csv.Writer
+	enc *json.Encoder
+}
+
+// startExport sets the headers of an export download named after what it
+// holds and returns its writer. CSV exports start with a header row.
+func startExport(c *gin.Context, name, format string, columns []string) *exportWriter {
+	filename := fmt.Sprintf("%s-%s.%s", name, time.Now().UTC().Format("20060102T150405Z"), format)
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	c.Status(http.StatusOK)
+
+	w := &exportWriter{c: c}
+	if format == ExportNDJSON {
+		c.Header("Content-Type", "application/x-ndjson")
+		w.enc = json.NewEncoder(c.Writer)
+		return w
+	}
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	w.csv = csv.NewWriter(c.Writer)
+	w.csv.Write(columns)
+	return w
+}
+
+// write writes a record: row in CSV exports, record itself in NDJSON ones
+func (w *exportWriter) write(record interface{}, row []string) error {
+	if w.enc != nil {
+		return w.enc.Encode(record)
+	}
+	return w.csv.Write(row)
+}
+
+// flush sends what has been written so far to the client
+func (w *exportWriter) flush() error {
+	if w.csv != nil {
+		w.csv.Flush()
+		if err := w.csv.Error(); err != nil {
+			return err
+		}
+	}
+	w.c.Writer.Flush()
+	if err := w.c.Request.Context().Err(); err != nil {
+		return fmt.Errorf("client went away: %w", err)
+	}
+	return nil
+}
+
+// abort clears the download headers so that an error can be sent instead of
+// an export that hasn't started
+func (w *exportWriter) abort() {
+	w.c.Header("Content-Disposition", "")
+	w.c.Header("Content-Type", "")
+}
+
+// alertExportRow returns the CSV row of an alert, in alertExportColumns order
+func alertExportRow(alert *models.Alert) []string {
+	var peerName, peerIP string
+	if alert.Peer != nil {
+		peerName, peerIP = alert.Peer.Name, alert.Peer.IPAddress
+	}
+	return []string{
+		formatUint(alert.ID),
+		formatTime(&alert.CreatedAt),
+		alert.Type,
+		alert.Severity,
+		alert.Source,
+		alert.Message,
+		alert.Details,
+		formatUintPtr(alert.PeerID),
+		peerName,
+		peerIP,
+		strconv.FormatBool(alert.Acknowledged),
+		formatTime(alert.AcknowledgedAt),
+		formatUintPtr(alert.AcknowledgedBy),
+		formatTime(alert.ResolvedAt),
+		formatTime(alert.ArchivedAt),
+	}
+}
+
+// sessionExportRow returns the CSV row of a session, in
+// sessionExportColumns order
+func sessionExportRow(session *models.BGPSession) []string {
+	return []string{
+		formatUint(session.ID),
+		formatUint(session.PeerID),
+		session.Peer.Name,
+		session.Peer.IPAddress,
+		strconv.FormatUint(uint64(session.Peer.RemoteASN), 10),
+		session.State,
+		strconv.FormatInt(session.Uptime, 10),
+		strconv.Itoa(session.PrefixesReceived),
+		strconv.Itoa(session.PrefixesSent),
+		strconv.FormatInt(session.MessagesReceived, 10),
+		strconv.FormatInt(session.MessagesSent, 10),
+		session.LastError,
+		formatTime(&session.LastReset),
+		strconv.FormatBool(session.InMaintenance),
+		formatTime(&session.UpdatedAt),
+	}
+}
+
+// formatUint formats an ID
+func formatUint(n uint) string {
+	return strconv.FormatUint(uint64(n), 10)
+}
+
+// formatUintPtr formats an optional ID, empty when unset
+func formatUintPtr(n *uint) string {
+	if n == nil {
+		return ""
+	}
+	return formatUint(*n)
+}
+
+// formatTime formats a time as RFC 3339 in UTC, empty when unset
+func formatTime(t *time.Time) string {
+	if t == nil || t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
@@ -0,0 +1,111 @@
+package api
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/padminisys/flintroute/internal/bgp"
+	"github.com/padminisys/flintroute/internal/frr"
+	"github.com/padminisys/flintroute/internal/websocket"
+	"github.com/padminisys/flintroute/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandleExportAlerts(t *testing.T) {
+	t.Run("CSV with the list filters", func(t *testing.T) {
+		router, _ := setupAlertRouter(t)
+
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/alerts/export?severity=critical", nil)
+		router.ServeHTTP(w, req)
+
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "text/csv; charset=utf-8", w.Header().Get("Content-Type"))
+		assert.Contains(t, w.Header().Get("Content-Disposition"), `attachment; filename="alerts-`)
+
+		records, err := csv.NewReader(w.Body).ReadAll()
+		require.NoError(t, err)
+		require.Len(t, records, 3)
+		assert.Equal(t, alertExportColumns, records[0])
+		assert.Equal(t, []string{"peer_down", "critical", models.AlertSourceFlintRoute}, records[1][2:5])
+		assert.Equal(t, models.AlertSourceAlertmanager, records[2][4])
+	})
+
+	t.Run("NDJSON", func(t *testing.T) {
+		router, _ := setupAlertRouter(t)
+
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/alerts/export?format=ndjson&archived=true", nil)
+		router.ServeHTTP(w, req)
+
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "application/x-ndjson", w.Header().Get("Content-Type"))
+
+		var alerts []models.Alert
+		scanner := bufio.NewScanner(w.Body)
+		for scanner.Scan() {
+			var alert models.Alert
+			require.NoError(t, json.Unmarshal(scanner.Bytes(), &alert))
+			alerts = append(alerts, alert)
+		}
+		require.Len(t, alerts, 1)
+		assert.Equal(t, "old", alerts[0].Message)
+	})
+
+	t.Run("CSV header without alerts", func(t *testing.T) {
+		router, _ := setupAlertRouter(t)
+
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/alerts/export?severity=info", nil)
+		router.ServeHTTP(w, req)
+
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, strings.Join(alertExportColumns, ",")+"\n", w.Body.String())
+	})
+
+	t.Run("Rejects unknown formats", func(t *testing.T) {
+		router, _ := setupAlertRouter(t)
+
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/alerts/export?format=xlsx", nil)
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
+
+func TestHandleExportSessions(t *testing.T) {
+	server, db := setupTestServer(t)
+	server.bgpService = bgp.NewService(server.db, frr.NewMockClient(), websocket.NewHub(server.logger), bgp.ServiceConfig{}, server.logger)
+
+	router := gin.New()
+	router.GET("/bgp/sessions/export", server.handleExportSessions)
+
+	for ip, pop := range map[string]string{"192.0.2.1": "fra1", "192.0.2.2": "ams1"} {
+		peer := &models.BGPPeer{
+			Name: "transit-" + pop, IPAddress: ip, ASN: 65000, RemoteASN: 64500,
+			Tags: models.NewPeerTags(map[string]string{"pop": pop}),
+		}
+		require.NoError(t, db.Create(peer).Error)
+		require.NoError(t, db.Create(&models.BGPSession{PeerID: peer.ID, State: "Idle", LastError: "hold timer expired, reset"}).Error)
+	}
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/bgp/sessions/export?tag=pop:fra1", nil)
+	router.ServeHTTP(w, req)
+
+	require.Equal(t, http.StatusOK, w.Code)
+	records, err := csv.NewReader(w.Body).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, records, 2)
+	assert.Equal(t, sessionExportColumns, records[0])
+	assert.Equal(t, []string{"transit-fra1", "192.0.2.1", "64500", "Idle"}, records[1][2:6])
+	assert.Equal(t, "hold timer expired, reset", records[1][11])
+}
@@ -0,0 +1,248 @@
+package api
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/padminisys/flintroute/internal/apierror"
+	authpkg "github.com/padminisys/flintroute/internal/auth"
+	"github.com/padminisys/flintroute/internal/frr"
+	"github.com/padminisys/flintroute/internal/frrlog"
+	"github.com/padminisys/flintroute/internal/repository"
+	"github.com/padminisys/flintroute/pkg/models"
+	"go.uber.org/zap"
+)
+
+// frrInitiatorMiddleware records the authenticated user as the initiator
+// of the changes a request pushes to FRR
+func (s *Server) frrInitiatorMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if userID, ok := authpkg.GetUserID(c); ok {
+			username, _ := authpkg.GetUsername(c)
+			c.Request = c.Request.WithContext(frrlog.WithUser(c.Request.Context(), userID, username))
+		}
+		c.Next()
+	}
+}
+
+// handleListFRRTransactions handles listing the changes pushed to FRR,
+// newest first
+func (s *Server) handleListFRRTransactions(c *gin.Context) {
+	query, ok := frrTransactionQuery(c)
+	if !ok {
+		return
+	}
+	for _, filter := range []struct {
+		param string
+		value **uint
+	}{
+		{"peer_id", &query.PeerID},
+		{"config_version_id", &query.ConfigVersionID},
+		{"job_id", &query.JobID},
+	} {
+		raw := c.Query(filter.param)
+		if raw == "" {
+			continue
+		}
+		id, err := strconv.ParseUint(raw, 10, 32)
+		if err != nil {
+			apierror.Respond(c, http.StatusBadRequest, apierror.CodeValidationFailed, filter.param+" must be an ID")
+			return
+		}
+		value := uint(id)
+		*filter.value = &value
+	}
+	query.Username = c.Query("user")
+	query.Operation = c.Query("operation")
+	query.Result = c.Query("result")
+	switch query.Result {
+	case "", models.FRRTransactionSucceeded, models.FRRTransactionFailed:
+	default:
+		apierror.Respond(c, http.StatusBadRequest, apierror.CodeValidationFailed, "result must be succeeded or failed")
+		return
+	}
+
+	transactions, err := s.frrLog.List(c.Request.Context(), query)
+	s.respondFRRTransactions(c, transactions, query, err)
+}
+
+// handleGetFRRTransaction handles getting a change pushed to FRR
+func (s *Server) handleGetFRRTransaction(c *gin.Context) {
+	transaction, ok := s.getFRRTransaction(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, transaction)
+}
+
+// handleBackupFRRTransaction handles backing up the configuration an
+// out-of-band change left FRR running, as long as FRR still runs it
+func (s *Server) handleBackupFRRTransaction(c *gin.Context) {
+	transaction, ok := s.getFRRTransaction(c)
+	if !ok {
+		return
+	}
+	if transaction.Operation != frrlog.OpOutOfBand {
+		apierror.Respond(c, http.StatusUnprocessableEntity, apierror.CodeValidationFailed,
+			"Only out-of-band changes are backed up here; use POST /api/v1/config/backup")
+		return
+	}
+
+	userID, exists := authpkg.GetUserID(c)
+	if !exists {
+		apierror.Respond(c, http.StatusUnauthorized, apierror.CodeUnauthorized, "User not authenticated")
+		return
+	}
+
+	// FRR may have been changed again since, through FlintRoute or not
+	running, err := s.bgpService.GetRunningConfig(c.Request.Context())
+	if err == nil && fmt.Sprintf("%x", sha256.Sum256([]byte(running))) != transaction.CommitID {
+		apierror.Respond(c, http.StatusConflict, apierror.CodeConfigChanged,
+			"FRR's running configuration has changed since; back it up with POST /api/v1/config/backup")
+		return
+	}
+
+	version, created, err := s.backupConfig(c.Request.Context(), userID, &BackupConfigRequest{
+		Description: fmt.Sprintf("Out-of-band change detected at %s", transaction.CreatedAt.UTC().Format(time.RFC3339)),
+		Labels:      []string{"out-of-band"},
+	})
+	if err != nil {
+		s.logger.Error("Failed to back up out-of-band change", zap.Error(err))
+		if errors.Is(err, frr.ErrNotConnected) {
+			apierror.Respond(c, http.StatusServiceUnavailable, apierror.CodeFRRUnavailable, "FRR is unavailable")
+			return
+		}
+		apierror.Respond(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to backup config")
+		return
+	}
+
+	if !created {
+		c.JSON(http.StatusOK, gin.H{
+			"message": "Configuration already backed up",
+			"version": version,
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, version)
+}
+
+// getFRRTransaction gets the transaction named by the id path parameter,
+// responding with an error if it can't
+func (s *Server) getFRRTransaction(c *gin.Context) (*models.FRRTransaction, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		apierror.Respond(c, http.StatusBadRequest, apierror.CodeInvalidID, "Invalid transaction ID")
+		return nil, false
+	}
+
+	transaction, err := s.frrLog.Get(c.Request.Context(), uint(id))
+	if err != nil {
+		if errors.Is(err, frrlog.ErrTransactionNotFound) {
+			apierror.Respond(c, http.StatusNotFound, apierror.CodeFRRTxNotFound, "FRR transaction not found")
+			return nil, false
+		}
+		s.logger.Error("Failed to get FRR transaction", zap.Error(err))
+		apierror.Respond(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to get FRR transaction")
+		return nil, false
+	}
+	return transaction, true
+}
+
+// handleListPeerFRRTransactions handles listing the changes pushed to FRR
+// for a peer
+func (s *Server) handleListPeerFRRTransactions(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		apierror.Respond(c, http.StatusBadRequest, apierror.CodeInvalidID, "Invalid peer ID")
+		return
+	}
+	query, ok := frrTransactionQuery(c)
+	if !ok {
+		return
+	}
+
+	if _, err := s.bgpService.GetPeer(c.Request.Context(), uint(id)); err != nil {
+		s.respondPeerError(c, err, "Failed to list peer FRR transactions")
+		return
+	}
+
+	peerID := uint(id)
+	query.PeerID = &peerID
+	transactions, err := s.frrLog.List(c.Request.Context(), query)
+	s.respondFRRTransactions(c, transactions, query, err)
+}
+
+// handleListConfigVersionFRRTransactions handles listing the changes
+// pushed to FRR while restoring or rolling back to a config version, and
+// those that produced its configuration
+func (s *Server) handleListConfigVersionFRRTransactions(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		apierror.Respond(c, http.StatusBadRequest, apierror.CodeInvalidID, "Invalid version ID")
+		return
+	}
+	query, ok := frrTransactionQuery(c)
+	if !ok {
+		return
+	}
+
+	version, err := s.repos.Configs.Get(c.Request.Context(), uint(id))
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			apierror.Respond(c, http.StatusNotFound, apierror.CodeVersionNotFound, "Version not found")
+			return
+		}
+		s.logger.Error("Failed to get config version", zap.Error(err))
+		apierror.Respond(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to list config version FRR transactions")
+		return
+	}
+
+	transactions, err := s.frrLog.ListForVersion(c.Request.Context(), version, query)
+	s.respondFRRTransactions(c, transactions, query, err)
+}
+
+// frrTransactionQuery parses the limit and before query parameters,
+// responding with an error if they are invalid
+func frrTransactionQuery(c *gin.Context) (frrlog.Query, bool) {
+	query := frrlog.Query{Limit: 50}
+	if raw := c.Query("limit"); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil || limit < 1 || limit > 500 {
+			apierror.Respond(c, http.StatusBadRequest, apierror.CodeValidationFailed, "limit must be between 1 and 500")
+			return query, false
+		}
+		query.Limit = limit
+	}
+	if raw := c.Query("before"); raw != "" {
+		before, err := strconv.ParseUint(raw, 10, 32)
+		if err != nil {
+			apierror.Respond(c, http.StatusBadRequest, apierror.CodeValidationFailed, "before must be a transaction ID")
+			return query, false
+		}
+		query.Before = uint(before)
+	}
+	return query, true
+}
+
+// respondFRRTransactions responds with a page of transactions and, when it
+// is full, the ID to continue from
+func (s *Server) respondFRRTransactions(c *gin.Context, transactions []models.FRRTransaction, query frrlog.Query, err error) {
+	if err != nil {
+		s.logger.Error("Failed to list FRR transactions", zap.Error(err))
+		apierror.Respond(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to list FRR transactions")
+		return
+	}
+
+	body := gin.H{"transactions": transactions}
+	if len(transactions) == query.Limit {
+		body["next_before"] = transactions[len(transactions)-1].ID
+	}
+	c.JSON(http.StatusOK, body)
+}
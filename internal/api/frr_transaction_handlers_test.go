@@ -0,0 +1,124 @@
+package api
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/padminisys/flintroute/internal/bgp"
+	"github.com/padminisys/flintroute/internal/frr"
+	"github.com/padminisys/flintroute/internal/frrlog"
+	"github.com/padminisys/flintroute/internal/websocket"
+	"github.com/padminisys/flintroute/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandleFRRTransactions(t *testing.T) {
+	server, db := setupTestServer(t)
+	server.frrLog = frrlog.NewLog(server.db, server.logger)
+	demo := frr.NewDemoClient(frr.DemoOptions{}, server.logger)
+	require.NoError(t, demo.Connect(context.Background()))
+	defer demo.Close()
+	server.bgpService = bgp.NewService(server.db, server.frrLog.Wrap(demo), websocket.NewHub(server.logger),
+		bgp.ServiceConfig{ConsistencyMode: bgp.ConsistencyStrict}, server.logger)
+
+	user := models.User{Username: "operator", PasswordHash: "x", Email: "operator@example.com", Role: "operator"}
+	require.NoError(t, db.Create(&user).Error)
+
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set("user_id", user.ID)
+		c.Set("username", user.Username)
+	}, server.frrInitiatorMiddleware())
+	router.POST("/bgp/peers", server.handleCreatePeer)
+	router.GET("/bgp/peers/:id/transactions", server.handleListPeerFRRTransactions)
+	router.GET("/frr/transactions", server.handleListFRRTransactions)
+	router.GET("/frr/transactions/:id", server.handleGetFRRTransaction)
+	router.GET("/config/versions/:id/transactions", server.handleListConfigVersionFRRTransactions)
+
+	list := func(t *testing.T, path string) (transactions []models.FRRTransaction, nextBefore uint) {
+		t.Helper()
+		w := profileRequest(router, "GET", path, "")
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var resp struct {
+			Transactions []models.FRRTransaction `json:"transactions"`
+			NextBefore   uint                    `json:"next_before"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		return resp.Transactions, resp.NextBefore
+	}
+
+	for i, ip := range []string{"192.0.2.1", "192.0.2.2"} {
+		w := profileRequest(router, "POST", "/bgp/peers", fmt.Sprintf(
+			`{"name": "peer%d", "ip_address": %q, "asn": 65000, "remote_asn": 65001, "enabled": true}`, i, ip))
+		require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	}
+	server.frrLog.Wait()
+
+	t.Run("List changes pushed to FRR", func(t *testing.T) {
+		transactions, nextBefore := list(t, "/frr/transactions")
+		require.Len(t, transactions, 2)
+		assert.Zero(t, nextBefore)
+		assert.Equal(t, "192.0.2.2", transactions[0].Target)
+		assert.Equal(t, frrlog.OpPeerAdd, transactions[0].Operation)
+		assert.Equal(t, "operator", transactions[0].Username)
+		assert.NotEmpty(t, transactions[0].CommitID)
+		assert.Equal(t, transactions[1].CommitID, transactions[0].ParentCommitID)
+
+		transactions, nextBefore = list(t, "/frr/transactions?limit=1&user=operator&result=succeeded")
+		require.Len(t, transactions, 1)
+		assert.Equal(t, transactions[0].ID, nextBefore)
+
+		transactions, _ = list(t, fmt.Sprintf("/frr/transactions?before=%d", nextBefore))
+		require.Len(t, transactions, 1)
+		assert.Equal(t, "192.0.2.1", transactions[0].Target)
+
+		w := profileRequest(router, "GET", "/frr/transactions?result=maybe", "")
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("Get a change", func(t *testing.T) {
+		transactions, _ := list(t, "/frr/transactions")
+		w := profileRequest(router, "GET", fmt.Sprintf("/frr/transactions/%d", transactions[0].ID), "")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), `"operation":"peer.add"`)
+
+		w = profileRequest(router, "GET", "/frr/transactions/999", "")
+		assert.Equal(t, http.StatusNotFound, w.Code)
+		assert.Contains(t, w.Body.String(), "FRR_TRANSACTION_NOT_FOUND")
+	})
+
+	t.Run("List a peer's changes", func(t *testing.T) {
+		peers, err := server.bgpService.ListPeers(context.Background())
+		require.NoError(t, err)
+		require.Len(t, peers, 2)
+
+		transactions, _ := list(t, fmt.Sprintf("/bgp/peers/%d/transactions", peers[0].ID))
+		require.Len(t, transactions, 1)
+		assert.Equal(t, &peers[0].ID, transactions[0].PeerID)
+		assert.Equal(t, peers[0].IPAddress, transactions[0].Target)
+
+		w := profileRequest(router, "GET", "/bgp/peers/999/transactions", "")
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("List the changes that produced a config version", func(t *testing.T) {
+		running, err := demo.GetRunningConfig(context.Background())
+		require.NoError(t, err)
+		version := models.ConfigVersion{Config: running, Hash: fmt.Sprintf("%x", sha256.Sum256([]byte(running))), CreatedBy: user.ID}
+		require.NoError(t, db.Create(&version).Error)
+
+		transactions, _ := list(t, fmt.Sprintf("/config/versions/%d/transactions", version.ID))
+		require.Len(t, transactions, 1)
+		assert.Equal(t, "192.0.2.2", transactions[0].Target)
+		assert.Equal(t, version.Hash, transactions[0].CommitID)
+
+		w := profileRequest(router, "GET", "/config/versions/999/transactions", "")
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+}
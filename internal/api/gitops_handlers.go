@@ -0,0 +1,95 @@
+package api
+
+import (
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/padminisys/flintroute/internal/apierror"
+	authpkg "github.com/padminisys/flintroute/internal/auth"
+	"github.com/padminisys/flintroute/internal/bgp"
+	"github.com/padminisys/flintroute/internal/frr"
+	"github.com/padminisys/flintroute/internal/gitops"
+	"go.uber.org/zap"
+)
+
+// maxWebhookBody limits the size of accepted webhook payloads
+const maxWebhookBody = 1 << 20
+
+// handleGitOpsWebhook handles push notifications from the Git host by
+// scheduling a sync
+func (s *Server) handleGitOpsWebhook(c *gin.Context) {
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, maxWebhookBody))
+	if err != nil {
+		apierror.Respond(c, http.StatusBadRequest, apierror.CodeValidationFailed, "Failed to read request body")
+		return
+	}
+
+	if !gitops.VerifyWebhook(s.gitopsWebhookSecret, c.Request.Header, body) {
+		apierror.Respond(c, http.StatusUnauthorized, apierror.CodeInvalidSignature, "Invalid webhook signature")
+		return
+	}
+
+	if branch := gitops.PushedBranch(body); branch != "" && branch != s.config.GitOps.Branch {
+		c.JSON(http.StatusOK, gin.H{"message": "Push to another branch ignored"})
+		return
+	}
+
+	s.gitopsSyncer.Trigger()
+	s.logger.Info("GitOps sync scheduled by webhook")
+
+	c.JSON(http.StatusAccepted, gin.H{"message": "Sync scheduled"})
+}
+
+// handleGitOpsStatus handles reporting the outcome of the last sync
+func (s *Server) handleGitOpsStatus(c *gin.Context) {
+	c.JSON(http.StatusOK, s.gitopsSyncer.Status())
+}
+
+// handleGitOpsPlan handles previewing the changes a sync would make
+func (s *Server) handleGitOpsPlan(c *gin.Context) {
+	plan, err := s.gitopsSyncer.Plan(c.Request.Context())
+	if err != nil {
+		s.respondGitOpsError(c, err, "Failed to plan GitOps sync")
+		return
+	}
+
+	c.JSON(http.StatusOK, plan)
+}
+
+// handleGitOpsSync handles queueing a sync. The sync result is the job's
+// result.
+func (s *Server) handleGitOpsSync(c *gin.Context) {
+	if _, exists := authpkg.GetUserID(c); !exists {
+		apierror.Respond(c, http.StatusUnauthorized, apierror.CodeUnauthorized, "User not authenticated")
+		return
+	}
+
+	s.enqueueJob(c, JobGitOpsSync, nil, "Failed to queue GitOps sync")
+}
+
+// respondGitOpsError maps a GitOps sync error to an API error. Fetch,
+// validation and conflict messages are returned as-is so they can be
+// fixed in the repository.
+func (s *Server) respondGitOpsError(c *gin.Context, err error, message string) {
+	s.logger.Error(message, zap.Error(err))
+
+	switch {
+	case errors.Is(err, gitops.ErrFetchFailed):
+		apierror.Respond(c, http.StatusBadGateway, apierror.CodeGitOpsFetchFailed, err.Error())
+	case errors.Is(err, gitops.ErrInvalidDefinitions):
+		apierror.Respond(c, http.StatusUnprocessableEntity, apierror.CodeGitOpsInvalid, err.Error())
+	case errors.Is(err, gitops.ErrConflict):
+		apierror.Respond(c, http.StatusConflict, apierror.CodeGitOpsConflict, err.Error())
+	case respondFRRRejection(c, err):
+	case errors.Is(err, bgp.ErrFRRApplyFailed):
+		code := apierror.CodeFRRApplyFailed
+		if errors.Is(err, frr.ErrNotConnected) {
+			code = apierror.CodeFRRUnavailable
+		}
+		apierror.Respond(c, http.StatusBadGateway, code, message+": FRR rejected the change")
+	default:
+		apierror.Respond(c, http.StatusInternalServerError, apierror.CodeInternal, message)
+	}
+}
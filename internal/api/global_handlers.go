@@ -0,0 +1,105 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/padminisys/flintroute/internal/apierror"
+	"github.com/padminisys/flintroute/internal/bgp"
+	"github.com/padminisys/flintroute/internal/frr"
+	"github.com/padminisys/flintroute/pkg/models"
+	"go.uber.org/zap"
+)
+
+// BGPGlobalRequest replaces the BGP global configuration. Omitted fields
+// are reset; ebgp_requires_policy defaults to true, as in FRR.
+type BGPGlobalRequest struct {
+	ASN                uint32 `json:"asn" binding:"required"`
+	RouterID           string `json:"router_id" binding:"omitempty,ipv4"`
+	GracefulRestart    bool   `json:"graceful_restart"`
+	RestartTime        int    `json:"graceful_restart_time"`
+	StalePathTime      int    `json:"graceful_restart_stalepath_time"`
+	EBGPRequiresPolicy *bool  `json:"ebgp_requires_policy"`
+	LogNeighborChanges bool   `json:"log_neighbor_changes"`
+	AlwaysCompareMED   bool   `json:"always_compare_med"`
+	CompareRouterID    bool   `json:"bestpath_compare_router_id"`
+	MultipathRelax     bool   `json:"bestpath_multipath_relax"`
+	MEDMissingAsWorst  bool   `json:"bestpath_med_missing_as_worst"`
+}
+
+// config returns the global configuration the request describes
+func (req *BGPGlobalRequest) config() *models.BGPGlobalConfig {
+	return &models.BGPGlobalConfig{
+		ASN:                req.ASN,
+		RouterID:           req.RouterID,
+		GracefulRestart:    req.GracefulRestart,
+		RestartTime:        req.RestartTime,
+		StalePathTime:      req.StalePathTime,
+		EBGPRequiresPolicy: req.EBGPRequiresPolicy == nil || *req.EBGPRequiresPolicy,
+		LogNeighborChanges: req.LogNeighborChanges,
+		AlwaysCompareMED:   req.AlwaysCompareMED,
+		CompareRouterID:    req.CompareRouterID,
+		MultipathRelax:     req.MultipathRelax,
+		MEDMissingAsWorst:  req.MEDMissingAsWorst,
+	}
+}
+
+// handleGetBGPGlobal handles getting the BGP global configuration
+func (s *Server) handleGetBGPGlobal(c *gin.Context) {
+	cfg, err := s.bgpService.GetGlobalConfig(c.Request.Context())
+	if err != nil {
+		s.respondGlobalError(c, err, "Failed to get BGP global configuration")
+		return
+	}
+
+	c.JSON(http.StatusOK, cfg)
+}
+
+// handleUpdateBGPGlobal handles replacing the BGP global configuration and
+// applying it to FRR
+func (s *Server) handleUpdateBGPGlobal(c *gin.Context) {
+	var req BGPGlobalRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apierror.Validation(c, err)
+		return
+	}
+
+	cfg := req.config()
+	if err := s.bgpService.SaveGlobalConfig(c.Request.Context(), cfg); err != nil {
+		s.respondGlobalError(c, err, "Failed to update BGP global configuration")
+		return
+	}
+
+	c.JSON(http.StatusOK, cfg)
+}
+
+// respondGlobalError maps a BGP service error for the global configuration
+// to an API error
+func (s *Server) respondGlobalError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, bgp.ErrGlobalConfigNotFound):
+		apierror.Respond(c, http.StatusNotFound, apierror.CodeNotFound, "BGP global configuration not set")
+		return
+	case errors.Is(err, bgp.ErrInvalidGlobalConfig):
+		apierror.Respond(c, http.StatusBadRequest, apierror.CodeValidationFailed, err.Error())
+		return
+	case errors.Is(err, bgp.ErrASNMismatch):
+		apierror.Respond(c, http.StatusConflict, apierror.CodeASNMismatch, err.Error())
+		return
+	case respondFRRRejection(c, err):
+		return
+	}
+
+	s.logger.Error(message, zap.Error(err))
+	if errors.Is(err, bgp.ErrFRRApplyFailed) {
+		code := apierror.CodeFRRApplyFailed
+		if errors.Is(err, frr.ErrNotConnected) {
+			code = apierror.CodeFRRUnavailable
+		}
+		apierror.Respond(c, http.StatusBadGateway, code, message+": FRR rejected the change")
+		return
+	}
+
+	apierror.Respond(c, http.StatusInternalServerError, apierror.CodeInternal, message)
+}
@@ -0,0 +1,77 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/padminisys/flintroute/internal/apierror"
+	"github.com/padminisys/flintroute/internal/bgp"
+	"github.com/padminisys/flintroute/internal/frr"
+	"github.com/padminisys/flintroute/internal/websocket"
+	"github.com/padminisys/flintroute/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandleBGPGlobal(t *testing.T) {
+	server, db := setupTestServer(t)
+	client := frr.NewMockClient()
+	client.On("ApplyBGPGlobal", mock.Anything, mock.Anything).Return(nil)
+	server.bgpService = bgp.NewService(server.db, client, websocket.NewHub(server.logger), bgp.ServiceConfig{}, server.logger)
+
+	router := gin.New()
+	router.GET("/bgp/global", server.handleGetBGPGlobal)
+	router.PUT("/bgp/global", server.handleUpdateBGPGlobal)
+
+	put := func(body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("PUT", "/bgp/global", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("Not set", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/bgp/global", nil)
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("Replaces the configuration", func(t *testing.T) {
+		w := put(`{"asn": 65001, "router_id": "10.0.0.1", "graceful_restart": true, "graceful_restart_time": 120}`)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+		w = httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/bgp/global", nil)
+		router.ServeHTTP(w, req)
+
+		require.Equal(t, http.StatusOK, w.Code)
+		var cfg models.BGPGlobalConfig
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &cfg))
+		assert.Equal(t, uint32(65001), cfg.ASN)
+		assert.Equal(t, 120, cfg.RestartTime)
+		assert.True(t, cfg.EBGPRequiresPolicy, "ebgp_requires_policy defaults to true")
+	})
+
+	t.Run("Invalid settings", func(t *testing.T) {
+		assert.Equal(t, http.StatusBadRequest, put(`{"asn": 65001, "router_id": "2001:db8::1"}`).Code)
+		assert.Equal(t, http.StatusBadRequest, put(`{"asn": 65001, "graceful_restart_time": 120}`).Code)
+	})
+
+	t.Run("ASN differs from peers", func(t *testing.T) {
+		require.NoError(t, db.Create(&models.BGPPeer{Name: "transit", IPAddress: "192.0.2.1", ASN: 65001, RemoteASN: 64500}).Error)
+
+		w := put(`{"asn": 65002}`)
+		assert.Equal(t, http.StatusConflict, w.Code)
+		var resp apierror.Response
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.Equal(t, apierror.CodeASNMismatch, resp.Code)
+	})
+}
@@ -0,0 +1,349 @@
+package api
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/padminisys/flintroute/internal/apierror"
+	authpkg "github.com/padminisys/flintroute/internal/auth"
+	"github.com/padminisys/flintroute/internal/config"
+	"github.com/padminisys/flintroute/internal/frrlog"
+	"github.com/padminisys/flintroute/internal/repository"
+	"github.com/padminisys/flintroute/internal/secrets"
+	"github.com/padminisys/flintroute/internal/tenancy"
+	"github.com/padminisys/flintroute/pkg/flintroutepb"
+	"github.com/padminisys/flintroute/pkg/models"
+	"go.uber.org/zap"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"gorm.io/gorm"
+)
+
+// gRPC metadata keys. The tenant key selects the tenant a call acts for, as
+// the X-Tenant-ID header does for REST.
+const (
+	grpcAPIKeyMetadata = "x-api-key"
+	grpcTenantMetadata = "x-tenant-id"
+)
+
+// grpcErrorDomain is the domain of the ErrorInfo details of gRPC errors,
+// whose reason is the REST API's error code
+const grpcErrorDomain = "flintroute.padminisys.com"
+
+// apiKey is an auth.api_keys entry with its key resolved
+type apiKey struct {
+	name     string
+	key      string
+	username string
+}
+
+// resolveAPIKeys resolves the keys of auth.api_keys
+func resolveAPIKeys(ctx context.Context, entries []config.APIKeyConfig, resolver *secrets.Resolver) ([]apiKey, error) {
+	keys := make([]apiKey, 0, len(entries))
+	for _, entry := range entries {
+		key, err := resolver.Resolve(ctx, entry.Key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve API key %s: %w", entry.Name, err)
+		}
+		keys = append(keys, apiKey{name: entry.Name, key: key, username: entry.Username})
+	}
+	return keys, nil
+}
+
+// grpcMethod is the access a gRPC method needs. Changes are refused, like
+// the REST API's, on a standby instance, while starting and in read-only
+// mode.
+type grpcMethod struct {
+	role   string
+	change bool
+}
+
+// grpcMethods lists the access every gRPC method needs; calls to methods
+// not listed are refused
+var grpcMethods = map[string]grpcMethod{
+	flintroutepb.PeerService_ListPeers_FullMethodName:            {role: authpkg.RoleUser},
+	flintroutepb.PeerService_GetPeer_FullMethodName:              {role: authpkg.RoleUser},
+	flintroutepb.PeerService_CreatePeer_FullMethodName:           {role: authpkg.RoleOperator, change: true},
+	flintroutepb.PeerService_UpdatePeer_FullMethodName:           {role: authpkg.RoleOperator, change: true},
+	flintroutepb.PeerService_DeletePeer_FullMethodName:           {role: authpkg.RoleOperator, change: true},
+	flintroutepb.SessionService_ListSessions_FullMethodName:      {role: authpkg.RoleUser},
+	flintroutepb.SessionService_GetSession_FullMethodName:        {role: authpkg.RoleUser},
+	flintroutepb.ConfigService_GetRunningConfig_FullMethodName:   {role: authpkg.RoleUser},
+	flintroutepb.ConfigService_ListConfigVersions_FullMethodName: {role: authpkg.RoleUser},
+	flintroutepb.ConfigService_BackupConfig_FullMethodName:       {role: authpkg.RoleOperator, change: true},
+	flintroutepb.AlertService_ListAlerts_FullMethodName:          {role: authpkg.RoleUser},
+	flintroutepb.AlertService_AcknowledgeAlert_FullMethodName:    {role: authpkg.RoleOperator, change: true},
+}
+
+// grpcPrincipal is the user a gRPC call is made as
+type grpcPrincipal struct {
+	userID   uint
+	username string
+	role     string
+	// impersonator is the admin impersonating the user; empty unless the
+	// call uses an impersonation token
+	impersonator string
+}
+
+type grpcPrincipalKey struct{}
+
+// grpcPrincipalFrom returns the user a gRPC call is made as
+func grpcPrincipalFrom(ctx context.Context) (*grpcPrincipal, bool) {
+	principal, ok := ctx.Value(grpcPrincipalKey{}).(*grpcPrincipal)
+	return principal, ok
+}
+
+// newGRPCServer creates the gRPC server, serving the same peers, sessions,
+// config versions and alerts as the REST API through the same services
+func (s *Server) newGRPCServer() *grpc.Server {
+	server := grpc.NewServer(grpc.UnaryInterceptor(s.grpcInterceptor))
+	flintroutepb.RegisterPeerServiceServer(server, &grpcPeerService{server: s})
+	flintroutepb.RegisterSessionServiceServer(server, &grpcSessionService{server: s})
+	flintroutepb.RegisterConfigServiceServer(server, &grpcConfigService{server: s})
+	flintroutepb.RegisterAlertServiceServer(server, &grpcAlertService{server: s})
+	return server
+}
+
+// grpcInterceptor applies the REST API's middleware to gRPC calls: changes
+// are refused on standby, while starting and in read-only mode, callers are
+// authenticated with an access token or API key, their requests count
+// against their quota, calls act for a tenant when multi-tenancy is on,
+// roles are checked, and impersonated changes are audited.
+func (s *Server) grpcInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	method, ok := grpcMethods[info.FullMethod]
+	if !ok {
+		return nil, grpcError(codes.PermissionDenied, apierror.CodeForbidden, "Method not allowed")
+	}
+	if method.change {
+		if err := s.grpcChangeAllowed(); err != nil {
+			return nil, err
+		}
+	}
+
+	principal, err := s.authenticateGRPC(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ctx = context.WithValue(ctx, grpcPrincipalKey{}, principal)
+
+	start := time.Now()
+	if s.usage != nil {
+		if err := s.admitGRPC(ctx, principal.userID, start); err != nil {
+			return nil, err
+		}
+	}
+
+	ctx, role, err := s.grpcTenant(ctx, principal)
+	if err != nil {
+		return nil, err
+	}
+	if !authpkg.HasRole(role, method.role) {
+		return nil, grpcError(codes.PermissionDenied, apierror.CodeForbidden,
+			strings.ToUpper(method.role[:1])+method.role[1:]+" access required")
+	}
+
+	resp, err := handler(frrlog.WithUser(ctx, principal.userID, principal.username), req)
+
+	if s.usage != nil {
+		// Only the outcome counts, so any error stands for a failed request
+		httpStatus := http.StatusOK
+		if err != nil {
+			httpStatus = http.StatusBadRequest
+		}
+		s.usage.record(principal.userID, start, httpStatus, time.Since(start), true)
+	}
+	if method.change && principal.impersonator != "" {
+		if auditErr := s.db.WithContext(ctx).Create(&models.AuditEntry{
+			Username: principal.impersonator,
+			Action:   ActionImpersonatedRequest,
+			Target:   impersonationTarget(principal.userID, principal.username),
+			Detail:   fmt.Sprintf("gRPC %s: %s", info.FullMethod, status.Code(err)),
+		}).Error; auditErr != nil {
+			s.logger.Error("Failed to audit impersonated request", zap.Error(auditErr))
+		}
+	}
+	return resp, err
+}
+
+// grpcChangeAllowed refuses changes for the reasons the leader, startup and
+// read-only middleware refuse them over REST
+func (s *Server) grpcChangeAllowed() error {
+	if s.elector != nil && !s.elector.IsLeader() {
+		message := "This FlintRoute instance is on standby; send changes to the leader"
+		if holder := s.elector.Status().Holder; holder != "" {
+			message += " (" + holder + ")"
+		}
+		return grpcError(codes.Unavailable, apierror.CodeNotLeader, message)
+	}
+	if s.startup != nil && !s.startup.Ready() {
+		return grpcError(codes.Unavailable, apierror.CodeStarting,
+			fmt.Sprintf("FlintRoute is starting (%s); changes are accepted once it is ready", s.startup.Status().State))
+	}
+	if s.readOnly != nil && s.readOnly.Enabled() {
+		message := "FlintRoute is in read-only mode"
+		if reason := s.readOnly.Status().Reason; reason != "" {
+			message += ": " + reason
+		}
+		return grpcError(codes.FailedPrecondition, apierror.CodeReadOnly, message)
+	}
+	return nil
+}
+
+// authenticateGRPC returns the user a call is made as, from its API key or
+// access token
+func (s *Server) authenticateGRPC(ctx context.Context) (*grpcPrincipal, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	if keys := md.Get(grpcAPIKeyMetadata); len(keys) > 0 {
+		return s.apiKeyPrincipal(ctx, keys[0])
+	}
+
+	values := md.Get("authorization")
+	if len(values) == 0 {
+		return nil, grpcError(codes.Unauthenticated, apierror.CodeUnauthorized, "Authorization or x-api-key metadata required")
+	}
+	token, ok := strings.CutPrefix(values[0], "Bearer ")
+	if !ok {
+		return nil, grpcError(codes.Unauthenticated, apierror.CodeUnauthorized, "Invalid authorization metadata format")
+	}
+
+	claims, err := s.jwtManager.ValidateToken(token)
+	if err != nil {
+		return nil, grpcError(codes.Unauthenticated, apierror.CodeInvalidToken, "Invalid or expired token")
+	}
+	if s.denylist != nil {
+		revoked, err := s.denylist.IsRevoked(ctx, claims)
+		if err != nil {
+			// Without the denylist a revoked token could get through
+			return nil, grpcError(codes.Unavailable, apierror.CodeStoreUnavailable, "Unable to check token revocation")
+		}
+		if revoked {
+			return nil, grpcError(codes.Unauthenticated, apierror.CodeInvalidToken, "Token has been revoked")
+		}
+	}
+
+	principal := &grpcPrincipal{userID: claims.UserID, username: claims.Username, role: claims.Role}
+	if claims.Act != nil {
+		principal.impersonator = claims.Act.Subject
+	}
+	return principal, nil
+}
+
+// apiKeyPrincipal returns the user an API key acts as. The user's current
+// role applies, and keys of disabled users are refused.
+func (s *Server) apiKeyPrincipal(ctx context.Context, key string) (*grpcPrincipal, error) {
+	var match *apiKey
+	for i := range s.apiKeys {
+		if subtle.ConstantTimeCompare([]byte(s.apiKeys[i].key), []byte(key)) == 1 {
+			match = &s.apiKeys[i]
+		}
+	}
+	if match == nil {
+		return nil, grpcError(codes.Unauthenticated, apierror.CodeInvalidToken, "Invalid API key")
+	}
+
+	user, err := s.repos.Users.GetByUsername(ctx, match.username)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			s.logger.Warn("API key names an unknown user",
+				zap.String("api_key", match.name),
+				zap.String("username", match.username),
+			)
+			return nil, grpcError(codes.Unauthenticated, apierror.CodeInvalidToken, "Invalid API key")
+		}
+		s.logger.Error("Failed to get API key user", zap.String("api_key", match.name), zap.Error(err))
+		return nil, grpcError(codes.Internal, apierror.CodeInternal, "Failed to authenticate")
+	}
+	if !user.Active {
+		return nil, grpcError(codes.Unauthenticated, apierror.CodeAccountDisabled, "Account is disabled")
+	}
+
+	return &grpcPrincipal{userID: user.ID, username: user.Username, role: user.Role}, nil
+}
+
+// admitGRPC counts a call against its user's hourly quota, refusing it when
+// the quota is used up
+func (s *Server) admitGRPC(ctx context.Context, userID uint, start time.Time) error {
+	limit, err := s.usage.quota(ctx, userID, start)
+	if err != nil {
+		s.logger.Error("Failed to load request quota", zap.Uint("user_id", userID), zap.Error(err))
+	}
+	allowed, _, _, err := s.usage.admit(ctx, userID, limit, start)
+	if err != nil {
+		s.logger.Warn("Failed to count request against quota", zap.Uint("user_id", userID), zap.Error(err))
+	}
+	if !allowed {
+		return grpcError(codes.ResourceExhausted, apierror.CodeQuotaExceeded,
+			fmt.Sprintf("Request quota of %d per hour exceeded", limit))
+	}
+	return nil
+}
+
+// grpcTenant makes a call act for the tenant its x-tenant-id metadata
+// selects, by the tenant middleware's rules, and returns the role the call
+// has there. Without multi-tenancy the user's own role applies.
+func (s *Server) grpcTenant(ctx context.Context, principal *grpcPrincipal) (context.Context, string, error) {
+	if !s.tenancy {
+		return ctx, principal.role, nil
+	}
+
+	var tenantID uint
+	md, _ := metadata.FromIncomingContext(ctx)
+	values := md.Get(grpcTenantMetadata)
+	selected := len(values) > 0
+	if selected {
+		id, err := strconv.ParseUint(values[0], 10, 32)
+		if err != nil {
+			return nil, "", grpcError(codes.InvalidArgument, apierror.CodeInvalidID, "Invalid x-tenant-id metadata")
+		}
+		tenantID = uint(id)
+	}
+
+	if authpkg.HasRole(principal.role, authpkg.RoleAdmin) {
+		if !selected {
+			return ctx, principal.role, nil
+		}
+		if err := s.db.WithContext(ctx).First(&models.Tenant{}, tenantID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, "", grpcError(codes.NotFound, apierror.CodeTenantNotFound, "Tenant not found")
+			}
+			s.logger.Error("Failed to get tenant", zap.Error(err))
+			return nil, "", grpcError(codes.Internal, apierror.CodeInternal, "Failed to get tenant")
+		}
+		return tenancy.WithTenant(ctx, tenantID), principal.role, nil
+	}
+
+	memberships, err := s.tenantMemberships(ctx, principal.userID, tenantID, selected)
+	if err != nil {
+		s.logger.Error("Failed to look up tenant memberships", zap.Error(err))
+		return nil, "", grpcError(codes.Internal, apierror.CodeInternal, "Failed to look up tenant memberships")
+	}
+	switch {
+	case len(memberships) == 0 && selected:
+		return nil, "", grpcError(codes.PermissionDenied, apierror.CodeNotTenantMember, "Not a member of this tenant")
+	case len(memberships) == 0:
+		return nil, "", grpcError(codes.PermissionDenied, apierror.CodeNotTenantMember, "Not a member of any tenant")
+	case len(memberships) > 1:
+		return nil, "", grpcError(codes.InvalidArgument, apierror.CodeTenantRequired,
+			"Member of several tenants; select one with the x-tenant-id metadata")
+	}
+	return tenancy.WithTenant(ctx, memberships[0].TenantID), memberships[0].Role, nil
+}
+
+// grpcError returns a gRPC error whose ErrorInfo detail carries the REST
+// API's error code for the same failure
+func grpcError(c codes.Code, code apierror.Code, message string) error {
+	st := status.New(c, message)
+	if detailed, err := st.WithDetails(&errdetails.ErrorInfo{Reason: string(code), Domain: grpcErrorDomain}); err == nil {
+		st = detailed
+	}
+	return st.Err()
+}
@@ -0,0 +1,510 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/padminisys/flintroute/internal/apierror"
+	"github.com/padminisys/flintroute/internal/bgp"
+	"github.com/padminisys/flintroute/internal/frr"
+	"github.com/padminisys/flintroute/internal/repository"
+	"github.com/padminisys/flintroute/pkg/flintroutepb"
+	"github.com/padminisys/flintroute/pkg/models"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// grpcPeerService serves flintroute.v1.PeerService
+type grpcPeerService struct {
+	flintroutepb.UnimplementedPeerServiceServer
+	server *Server
+}
+
+// ListPeers lists peers, optionally only those with every given tag
+func (p *grpcPeerService) ListPeers(ctx context.Context, req *flintroutepb.ListPeersRequest) (*flintroutepb.ListPeersResponse, error) {
+	selectors, err := bgp.ParseTagSelectors(req.GetTags())
+	if err != nil {
+		return nil, grpcError(codes.InvalidArgument, apierror.CodeValidationFailed, err.Error())
+	}
+
+	peers, err := p.server.bgpService.ListPeers(ctx, selectors...)
+	if err != nil {
+		p.server.logger.Error("Failed to list peers", zap.Error(err))
+		return nil, grpcError(codes.Internal, apierror.CodeInternal, "Failed to list peers")
+	}
+
+	resp := &flintroutepb.ListPeersResponse{Peers: make([]*flintroutepb.Peer, 0, len(peers))}
+	for _, peer := range peers {
+		resp.Peers = append(resp.Peers, peerToProto(peer))
+	}
+	return resp, nil
+}
+
+// GetPeer gets a peer
+func (p *grpcPeerService) GetPeer(ctx context.Context, req *flintroutepb.GetPeerRequest) (*flintroutepb.Peer, error) {
+	peer, err := p.server.bgpService.GetPeer(ctx, uint(req.GetId()))
+	if err != nil {
+		return nil, p.server.grpcPeerError(err, "Failed to get peer")
+	}
+	return peerToProto(peer), nil
+}
+
+// CreatePeer creates a peer and applies it to FRR
+func (p *grpcPeerService) CreatePeer(ctx context.Context, req *flintroutepb.CreatePeerRequest) (*flintroutepb.Peer, error) {
+	if req.GetName() == "" || req.GetIpAddress() == "" {
+		return nil, grpcError(codes.InvalidArgument, apierror.CodeValidationFailed, "name and ip_address are required")
+	}
+	if req.RemoteAsn == 0 && req.TemplateId == nil {
+		return nil, grpcError(codes.InvalidArgument, apierror.CodeValidationFailed, "remote_asn is required without template_id")
+	}
+	if p.server.changes != nil {
+		return nil, grpcError(codes.FailedPrecondition, apierror.CodeForbidden,
+			"Creating peers needs approval; request it through the REST API")
+	}
+
+	create := &CreatePeerRequest{
+		Name:            req.Name,
+		IPAddress:       req.IpAddress,
+		ASN:             req.Asn,
+		RemoteASN:       req.RemoteAsn,
+		Description:     req.Description,
+		Enabled:         req.Enabled,
+		Password:        req.Password,
+		Multihop:        int(req.Multihop),
+		UpdateSource:    req.UpdateSource,
+		RouteMapIn:      req.RouteMapIn,
+		RouteMapOut:     req.RouteMapOut,
+		PrefixListIn:    req.PrefixListIn,
+		PrefixListOut:   req.PrefixListOut,
+		MaxPrefixes:     int(req.MaxPrefixes),
+		LocalPreference: int(req.LocalPreference),
+		Tags:            req.Tags,
+		PeerOptions: PeerOptions{
+			Keepalive:           int(req.Keepalive),
+			HoldTime:            int(req.Holdtime),
+			ConnectRetry:        int(req.ConnectRetry),
+			Passive:             req.Passive,
+			TTLSecurityHops:     int(req.TtlSecurityHops),
+			NextHopSelf:         req.NextHopSelf,
+			SoftReconfigInbound: req.SoftReconfigurationInbound,
+			RemovePrivateAS:     req.RemovePrivateAs,
+			AllowASIn:           int(req.AllowasIn),
+			LocalAS:             req.LocalAs,
+			LocalASMode:         req.LocalAsMode,
+			MaxPrefixThreshold:  int(req.MaxPrefixThreshold),
+			MaxPrefixAction:     req.MaxPrefixAction,
+			MaxPrefixRestart:    int(req.MaxPrefixRestart),
+		},
+	}
+	if req.TemplateId != nil {
+		id := uint(*req.TemplateId)
+		create.TemplateID = &id
+	}
+
+	peer, err := p.server.provisionPeer(ctx, create, setFields(req))
+	if err != nil {
+		return nil, p.server.grpcPeerError(err, "Failed to apply peer template")
+	}
+	if err := p.server.bgpService.CreatePeer(ctx, peer); err != nil {
+		return nil, p.server.grpcPeerError(err, "Failed to create peer")
+	}
+	return peerToProto(peer), nil
+}
+
+// UpdatePeer changes the fields set in the request
+func (p *grpcPeerService) UpdatePeer(ctx context.Context, req *flintroutepb.UpdatePeerRequest) (*flintroutepb.Peer, error) {
+	patch := &bgp.PeerPatch{
+		Name:                req.Name,
+		Description:         req.Description,
+		Enabled:             req.Enabled,
+		Multihop:            intFromProto(req.Multihop),
+		UpdateSource:        req.UpdateSource,
+		RouteMapIn:          req.RouteMapIn,
+		RouteMapOut:         req.RouteMapOut,
+		PrefixListIn:        req.PrefixListIn,
+		PrefixListOut:       req.PrefixListOut,
+		MaxPrefixes:         intFromProto(req.MaxPrefixes),
+		MaxPrefixThreshold:  intFromProto(req.MaxPrefixThreshold),
+		MaxPrefixAction:     req.MaxPrefixAction,
+		MaxPrefixRestart:    intFromProto(req.MaxPrefixRestart),
+		LocalPreference:     intFromProto(req.LocalPreference),
+		Keepalive:           intFromProto(req.Keepalive),
+		HoldTime:            intFromProto(req.Holdtime),
+		ConnectRetry:        intFromProto(req.ConnectRetry),
+		Passive:             req.Passive,
+		TTLSecurityHops:     intFromProto(req.TtlSecurityHops),
+		NextHopSelf:         req.NextHopSelf,
+		SoftReconfigInbound: req.SoftReconfigurationInbound,
+		RemovePrivateAS:     req.RemovePrivateAs,
+		AllowASIn:           intFromProto(req.AllowasIn),
+		LocalAS:             req.LocalAs,
+		LocalASMode:         req.LocalAsMode,
+		TemplateOverrides:   setFields(req),
+	}
+	if req.Tags != nil {
+		// An empty map, unlike nil, removes every tag
+		patch.Tags = make(map[string]string, len(req.Tags.Values))
+		for key, value := range req.Tags.Values {
+			patch.Tags[key] = value
+		}
+	}
+
+	if err := p.server.bgpService.PatchPeer(ctx, uint(req.GetId()), patch); err != nil {
+		return nil, p.server.grpcPeerError(err, "Failed to update peer")
+	}
+	peer, err := p.server.bgpService.GetPeer(ctx, uint(req.GetId()))
+	if err != nil {
+		return nil, p.server.grpcPeerError(err, "Failed to get peer")
+	}
+	return peerToProto(peer), nil
+}
+
+// DeletePeer moves a peer to the trash and removes it from FRR
+func (p *grpcPeerService) DeletePeer(ctx context.Context, req *flintroutepb.DeletePeerRequest) (*flintroutepb.DeletePeerResponse, error) {
+	if p.server.changes != nil {
+		return nil, grpcError(codes.FailedPrecondition, apierror.CodeForbidden,
+			"Deleting peers needs approval; request it through the REST API")
+	}
+	if err := p.server.bgpService.DeletePeer(ctx, uint(req.GetId())); err != nil {
+		return nil, p.server.grpcPeerError(err, "Failed to delete peer")
+	}
+	return &flintroutepb.DeletePeerResponse{}, nil
+}
+
+// grpcSessionService serves flintroute.v1.SessionService
+type grpcSessionService struct {
+	flintroutepb.UnimplementedSessionServiceServer
+	server *Server
+}
+
+// ListSessions lists sessions, optionally only those of peers with every
+// given tag
+func (ss *grpcSessionService) ListSessions(ctx context.Context, req *flintroutepb.ListSessionsRequest) (*flintroutepb.ListSessionsResponse, error) {
+	selectors, err := bgp.ParseTagSelectors(req.GetTags())
+	if err != nil {
+		return nil, grpcError(codes.InvalidArgument, apierror.CodeValidationFailed, err.Error())
+	}
+
+	sessions, err := ss.server.bgpService.ListSessions(ctx, selectors...)
+	if err != nil {
+		ss.server.logger.Error("Failed to list sessions", zap.Error(err))
+		return nil, grpcError(codes.Internal, apierror.CodeInternal, "Failed to list sessions")
+	}
+
+	resp := &flintroutepb.ListSessionsResponse{Sessions: make([]*flintroutepb.Session, 0, len(sessions))}
+	for _, session := range sessions {
+		resp.Sessions = append(resp.Sessions, sessionToProto(session))
+	}
+	return resp, nil
+}
+
+// GetSession gets a peer's session
+func (ss *grpcSessionService) GetSession(ctx context.Context, req *flintroutepb.GetSessionRequest) (*flintroutepb.Session, error) {
+	session, err := ss.server.bgpService.GetSession(ctx, uint(req.GetPeerId()))
+	if err != nil {
+		if errors.Is(err, bgp.ErrSessionNotFound) {
+			return nil, grpcError(codes.NotFound, apierror.CodeSessionNotFound, "Session not found")
+		}
+		ss.server.logger.Error("Failed to get session", zap.Error(err))
+		return nil, grpcError(codes.Internal, apierror.CodeInternal, "Failed to get session")
+	}
+	return sessionToProto(session), nil
+}
+
+// grpcConfigService serves flintroute.v1.ConfigService
+type grpcConfigService struct {
+	flintroutepb.UnimplementedConfigServiceServer
+	server *Server
+}
+
+// GetRunningConfig gets FRR's running configuration
+func (cs *grpcConfigService) GetRunningConfig(ctx context.Context, _ *flintroutepb.GetRunningConfigRequest) (*flintroutepb.GetRunningConfigResponse, error) {
+	config, err := cs.server.bgpService.GetRunningConfig(ctx)
+	if err != nil {
+		cs.server.logger.Error("Failed to get running config", zap.Error(err))
+		if errors.Is(err, frr.ErrNotConnected) {
+			return nil, grpcError(codes.Unavailable, apierror.CodeFRRUnavailable, "FRR is unavailable")
+		}
+		return nil, grpcError(codes.Internal, apierror.CodeInternal, "Failed to get running config")
+	}
+	return &flintroutepb.GetRunningConfigResponse{Config: config}, nil
+}
+
+// ListConfigVersions lists the config versions
+func (cs *grpcConfigService) ListConfigVersions(ctx context.Context, _ *flintroutepb.ListConfigVersionsRequest) (*flintroutepb.ListConfigVersionsResponse, error) {
+	versions, err := cs.server.repos.Configs.List(ctx)
+	if err != nil {
+		cs.server.logger.Error("Failed to list config versions", zap.Error(err))
+		return nil, grpcError(codes.Internal, apierror.CodeInternal, "Failed to list config versions")
+	}
+	if err := cs.server.configVersions.LoadAll(ctx, versions); err != nil {
+		cs.server.logger.Error("Failed to load config versions", zap.Error(err))
+		return nil, grpcError(codes.Internal, apierror.CodeInternal, "Failed to load config versions")
+	}
+
+	resp := &flintroutepb.ListConfigVersionsResponse{Versions: make([]*flintroutepb.ConfigVersion, 0, len(versions))}
+	for i := range versions {
+		resp.Versions = append(resp.Versions, configVersionToProto(&versions[i]))
+	}
+	return resp, nil
+}
+
+// BackupConfig backs up FRR's running configuration
+func (cs *grpcConfigService) BackupConfig(ctx context.Context, req *flintroutepb.BackupConfigRequest) (*flintroutepb.BackupConfigResponse, error) {
+	labels, err := normalizeLabels(req.GetLabels())
+	if err != nil {
+		return nil, grpcError(codes.InvalidArgument, apierror.CodeValidationFailed, err.Error())
+	}
+	principal, _ := grpcPrincipalFrom(ctx)
+
+	version, created, err := cs.server.backupConfig(ctx, principal.userID, &BackupConfigRequest{
+		Description: req.GetDescription(),
+		Name:        req.GetName(),
+		Labels:      labels,
+		Pinned:      req.GetPinned(),
+	})
+	if err != nil {
+		cs.server.logger.Error("Failed to backup config", zap.Error(err))
+		if errors.Is(err, frr.ErrNotConnected) {
+			return nil, grpcError(codes.Unavailable, apierror.CodeFRRUnavailable, "FRR is unavailable")
+		}
+		return nil, grpcError(codes.Internal, apierror.CodeInternal, "Failed to backup config")
+	}
+	return &flintroutepb.BackupConfigResponse{Version: configVersionToProto(version), Created: created}, nil
+}
+
+// grpcAlertService serves flintroute.v1.AlertService
+type grpcAlertService struct {
+	flintroutepb.UnimplementedAlertServiceServer
+	server *Server
+}
+
+// ListAlerts lists the alerts matching the request's filters
+func (as *grpcAlertService) ListAlerts(ctx context.Context, req *flintroutepb.ListAlertsRequest) (*flintroutepb.ListAlertsResponse, error) {
+	selectors, err := bgp.ParseTagSelectors(req.GetTags())
+	if err != nil {
+		return nil, grpcError(codes.InvalidArgument, apierror.CodeValidationFailed, err.Error())
+	}
+
+	alerts, err := as.server.repos.Alerts.List(ctx, repository.AlertFilter{
+		Archived:     req.GetArchived(),
+		Severity:     req.GetSeverity(),
+		Source:       req.GetSource(),
+		Tags:         selectors,
+		Acknowledged: req.Acknowledged,
+	})
+	if err != nil {
+		as.server.logger.Error("Failed to list alerts", zap.Error(err))
+		return nil, grpcError(codes.Internal, apierror.CodeInternal, "Failed to list alerts")
+	}
+
+	resp := &flintroutepb.ListAlertsResponse{Alerts: make([]*flintroutepb.Alert, 0, len(alerts))}
+	for i := range alerts {
+		resp.Alerts = append(resp.Alerts, alertToProto(&alerts[i]))
+	}
+	return resp, nil
+}
+
+// AcknowledgeAlert acknowledges an alert; acknowledging it again changes
+// nothing
+func (as *grpcAlertService) AcknowledgeAlert(ctx context.Context, req *flintroutepb.AcknowledgeAlertRequest) (*flintroutepb.Alert, error) {
+	principal, _ := grpcPrincipalFrom(ctx)
+	alert, _, err := as.server.acknowledgeAlert(ctx, uint(req.GetId()), principal.userID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, grpcError(codes.NotFound, apierror.CodeAlertNotFound, "Alert not found")
+		}
+		as.server.logger.Error("Failed to acknowledge alert", zap.Error(err))
+		return nil, grpcError(codes.Internal, apierror.CodeInternal, "Failed to acknowledge alert")
+	}
+	return alertToProto(alert), nil
+}
+
+// grpcPeerError maps a BGP service error for a peer operation to a gRPC
+// error, as respondPeerError does for REST
+func (s *Server) grpcPeerError(err error, message string) error {
+	switch {
+	case errors.Is(err, bgp.ErrPeerNotFound):
+		return grpcError(codes.NotFound, apierror.CodePeerNotFound, "Peer not found")
+	case errors.Is(err, bgp.ErrInvalidPeer), errors.Is(err, bgp.ErrInvalidTemplate):
+		return grpcError(codes.InvalidArgument, apierror.CodeValidationFailed, err.Error())
+	case errors.Is(err, bgp.ErrPeerExists):
+		return grpcError(codes.AlreadyExists, apierror.CodePeerExists, err.Error())
+	case errors.Is(err, bgp.ErrASNMismatch):
+		return grpcError(codes.FailedPrecondition, apierror.CodeASNMismatch, err.Error())
+	case errors.Is(err, bgp.ErrTemplateNotFound):
+		return grpcError(codes.NotFound, apierror.CodeTemplateNotFound, "Peer template not found")
+	case errors.Is(err, frr.ErrConfigRejected):
+		code := apierror.CodeFRRConfigRejected
+		if errors.Is(err, bgp.ErrFRRApplyFailed) {
+			code = apierror.CodeFRRValidation
+		}
+		return grpcError(codes.InvalidArgument, code, err.Error())
+	}
+
+	s.logger.Error(message, zap.Error(err))
+	if errors.Is(err, bgp.ErrFRRApplyFailed) {
+		code := apierror.CodeFRRApplyFailed
+		if errors.Is(err, frr.ErrNotConnected) {
+			code = apierror.CodeFRRUnavailable
+		}
+		return grpcError(codes.Unavailable, code, message+": FRR rejected the change")
+	}
+	return grpcError(codes.Internal, apierror.CodeInternal, message)
+}
+
+// setFields returns the names of the fields set in a request, which match
+// the REST API's field names, sorted
+func setFields(req interface{ ProtoReflect() protoreflect.Message }) []string {
+	var names []string
+	req.ProtoReflect().Range(func(field protoreflect.FieldDescriptor, _ protoreflect.Value) bool {
+		names = append(names, string(field.Name()))
+		return true
+	})
+	sort.Strings(names)
+	return names
+}
+
+// intFromProto converts an optional protobuf integer
+func intFromProto(v *int32) *int {
+	if v == nil {
+		return nil
+	}
+	n := int(*v)
+	return &n
+}
+
+// uint32FromID converts an optional ID to protobuf
+func uint32FromID(id *uint) *uint32 {
+	if id == nil {
+		return nil
+	}
+	v := uint32(*id)
+	return &v
+}
+
+// timestampOf converts an optional time to protobuf, leaving zero times
+// unset
+func timestampOf(t *time.Time) *timestamppb.Timestamp {
+	if t == nil || t.IsZero() {
+		return nil
+	}
+	return timestamppb.New(*t)
+}
+
+// peerToProto converts a peer to protobuf
+func peerToProto(peer *models.BGPPeer) *flintroutepb.Peer {
+	return &flintroutepb.Peer{
+		Id:                         uint32(peer.ID),
+		Name:                       peer.Name,
+		IpAddress:                  peer.IPAddress,
+		Asn:                        peer.ASN,
+		RemoteAsn:                  peer.RemoteASN,
+		Description:                peer.Description,
+		Enabled:                    peer.Enabled,
+		HasPassword:                peer.HasPassword,
+		Multihop:                   int32(peer.Multihop),
+		UpdateSource:               peer.UpdateSource,
+		RouteMapIn:                 peer.RouteMapIn,
+		RouteMapOut:                peer.RouteMapOut,
+		PrefixListIn:               peer.PrefixListIn,
+		PrefixListOut:              peer.PrefixListOut,
+		MaxPrefixes:                int32(peer.MaxPrefixes),
+		MaxPrefixThreshold:         int32(peer.MaxPrefixThreshold),
+		MaxPrefixAction:            peer.MaxPrefixAction,
+		MaxPrefixRestart:           int32(peer.MaxPrefixRestart),
+		LocalPreference:            int32(peer.LocalPreference),
+		Keepalive:                  int32(peer.Keepalive),
+		Holdtime:                   int32(peer.HoldTime),
+		ConnectRetry:               int32(peer.ConnectRetry),
+		Passive:                    peer.Passive,
+		TtlSecurityHops:            int32(peer.TTLSecurityHops),
+		NextHopSelf:                peer.NextHopSelf,
+		SoftReconfigurationInbound: peer.SoftReconfigInbound,
+		RemovePrivateAs:            peer.RemovePrivateAS,
+		AllowasIn:                  int32(peer.AllowASIn),
+		LocalAs:                    peer.LocalAS,
+		LocalAsMode:                peer.LocalASMode,
+		Tags:                       peer.Tags.Map(),
+		SyncStatus:                 peer.SyncStatus,
+		SyncError:                  peer.SyncError,
+		ManagedBy:                  peer.ManagedBy,
+		TemplateId:                 uint32FromID(peer.TemplateID),
+		TenantId:                   uint32FromID(peer.TenantID),
+		MaintenanceSince:           timestampOf(peer.MaintenanceSince),
+		MaintenanceUntil:           timestampOf(peer.MaintenanceUntil),
+		CreatedAt:                  timestampOf(&peer.CreatedAt),
+		UpdatedAt:                  timestampOf(&peer.UpdatedAt),
+	}
+}
+
+// sessionToProto converts a session to protobuf
+func sessionToProto(session *models.BGPSession) *flintroutepb.Session {
+	return &flintroutepb.Session{
+		Id:               uint32(session.ID),
+		PeerId:           uint32(session.PeerID),
+		PeerName:         session.Peer.Name,
+		IpAddress:        session.Peer.IPAddress,
+		State:            session.State,
+		Uptime:           session.Uptime,
+		PrefixesReceived: int32(session.PrefixesReceived),
+		PrefixesSent:     int32(session.PrefixesSent),
+		MessagesReceived: session.MessagesReceived,
+		MessagesSent:     session.MessagesSent,
+		LastError:        session.LastError,
+		LastReset:        timestampOf(&session.LastReset),
+		InMaintenance:    session.InMaintenance,
+		RemoteRouterId:   session.RemoteRouterID,
+		BgpVersion:       int32(session.BGPVersion),
+		HoldTime:         int32(session.HoldTime),
+		KeepaliveTime:    int32(session.KeepaliveTime),
+		FourByteAsn:      session.FourByteASN,
+		AddressFamilies:  session.AddressFamilies,
+		GracefulRestart:  session.GracefulRestart,
+		UpdatedAt:        timestampOf(&session.UpdatedAt),
+	}
+}
+
+// configVersionToProto converts a config version to protobuf
+func configVersionToProto(version *models.ConfigVersion) *flintroutepb.ConfigVersion {
+	return &flintroutepb.ConfigVersion{
+		Id:                uint32(version.ID),
+		Description:       version.Description,
+		Config:            version.Config,
+		Hash:              version.Hash,
+		CreatedBy:         uint32(version.CreatedBy),
+		CreatedByUsername: version.User.Username,
+		CommitSha:         version.CommitSHA,
+		Name:              version.Name,
+		Labels:            version.Labels,
+		Pinned:            version.Pinned,
+		TenantId:          uint32FromID(version.TenantID),
+		CreatedAt:         timestampOf(&version.CreatedAt),
+	}
+}
+
+// alertToProto converts an alert to protobuf
+func alertToProto(alert *models.Alert) *flintroutepb.Alert {
+	return &flintroutepb.Alert{
+		Id:             uint32(alert.ID),
+		Type:           alert.Type,
+		Severity:       alert.Severity,
+		Message:        alert.Message,
+		Details:        alert.Details,
+		PeerId:         uint32FromID(alert.PeerID),
+		Acknowledged:   alert.Acknowledged,
+		AcknowledgedAt: timestampOf(alert.AcknowledgedAt),
+		AcknowledgedBy: uint32FromID(alert.AcknowledgedBy),
+		Source:         alert.Source,
+		Labels:         alert.Labels,
+		Occurrences:    int32(alert.Occurrences),
+		FirstSeenAt:    timestampOf(&alert.FirstSeenAt),
+		ResolvedAt:     timestampOf(alert.ResolvedAt),
+		ArchivedAt:     timestampOf(alert.ArchivedAt),
+		CreatedAt:      timestampOf(&alert.CreatedAt),
+	}
+}
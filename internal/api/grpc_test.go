@@ -0,0 +1,162 @@
+package api
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/padminisys/flintroute/internal/apierror"
+	"github.com/padminisys/flintroute/internal/auth"
+	"github.com/padminisys/flintroute/internal/bgp"
+	"github.com/padminisys/flintroute/pkg/flintroutepb"
+	"github.com/padminisys/flintroute/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// dialGRPC serves the server's gRPC API in memory and connects to it
+func dialGRPC(t *testing.T, server *Server) *grpc.ClientConn {
+	t.Helper()
+
+	listener := bufconn.Listen(1 << 20)
+	grpcServer := server.newGRPCServer()
+	go grpcServer.Serve(listener)
+	t.Cleanup(grpcServer.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufconn",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+// assertGRPCError checks a gRPC error's code and the REST error code in its
+// ErrorInfo
+func assertGRPCError(t *testing.T, err error, code codes.Code, reason apierror.Code) {
+	t.Helper()
+
+	st, ok := status.FromError(err)
+	require.True(t, ok, "not a gRPC error: %v", err)
+	assert.Equal(t, code, st.Code(), st.Message())
+	require.Len(t, st.Details(), 1)
+	info, ok := st.Details()[0].(*errdetails.ErrorInfo)
+	require.True(t, ok)
+	assert.Equal(t, string(reason), info.Reason)
+}
+
+func TestGRPC(t *testing.T) {
+	server := newMockedServer(t)
+	server.apiKeys = []apiKey{{name: "automation", key: "test-api-key", username: "test-operator"}}
+	conn := dialGRPC(t, server.Server)
+	peers := flintroutepb.NewPeerServiceClient(conn)
+
+	withToken := func(t *testing.T, role string) context.Context {
+		token, err := server.jwtManager.GenerateToken(server.users[role])
+		require.NoError(t, err)
+		return metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer "+token)
+	}
+	withAPIKey := metadata.AppendToOutgoingContext(context.Background(), "x-api-key", "test-api-key")
+
+	peer := &models.BGPPeer{ID: 1, Name: "edge", IPAddress: "192.0.2.1", ASN: 65000, RemoteASN: 65001, Enabled: true,
+		Tags: models.NewPeerTags(map[string]string{"pop": "fra1"})}
+
+	t.Run("Calls must authenticate", func(t *testing.T) {
+		_, err := peers.ListPeers(context.Background(), &flintroutepb.ListPeersRequest{})
+		assertGRPCError(t, err, codes.Unauthenticated, apierror.CodeUnauthorized)
+
+		ctx := metadata.AppendToOutgoingContext(context.Background(), "x-api-key", "wrong")
+		_, err = peers.ListPeers(ctx, &flintroutepb.ListPeersRequest{})
+		assertGRPCError(t, err, codes.Unauthenticated, apierror.CodeInvalidToken)
+	})
+
+	t.Run("Users read with access tokens", func(t *testing.T) {
+		server.bgp.On("ListPeers", mock.Anything, []bgp.TagSelector{{Key: "pop", Value: "fra1"}}).Return([]*models.BGPPeer{peer}, nil).Once()
+
+		resp, err := peers.ListPeers(withToken(t, auth.RoleUser), &flintroutepb.ListPeersRequest{Tags: []string{"pop:fra1"}})
+		require.NoError(t, err)
+		require.Len(t, resp.Peers, 1)
+		assert.Equal(t, "192.0.2.1", resp.Peers[0].IpAddress)
+		assert.Equal(t, map[string]string{"pop": "fra1"}, resp.Peers[0].Tags)
+
+		_, err = peers.DeletePeer(withToken(t, auth.RoleUser), &flintroutepb.DeletePeerRequest{Id: 1})
+		assertGRPCError(t, err, codes.PermissionDenied, apierror.CodeForbidden)
+	})
+
+	t.Run("API keys act as their user", func(t *testing.T) {
+		server.bgp.On("CreatePeer", mock.Anything, mock.MatchedBy(func(p *models.BGPPeer) bool {
+			return p.Name == "transit" && p.IPAddress == "192.0.2.2" && p.RemoteASN == 65002 && p.Keepalive == 10
+		})).Return(nil).Once()
+
+		created, err := peers.CreatePeer(withAPIKey, &flintroutepb.CreatePeerRequest{
+			Name: "transit", IpAddress: "192.0.2.2", RemoteAsn: 65002, Keepalive: 10,
+		})
+		require.NoError(t, err)
+		assert.Equal(t, int32(10), created.Keepalive)
+		server.bgp.AssertExpectations(t)
+	})
+
+	t.Run("Updates only set fields", func(t *testing.T) {
+		description := "backup transit"
+		server.bgp.On("PatchPeer", mock.Anything, uint(1), mock.MatchedBy(func(patch *bgp.PeerPatch) bool {
+			return patch.Description != nil && *patch.Description == description && patch.Enabled == nil &&
+				patch.Tags != nil && len(patch.Tags) == 0
+		})).Return(nil).Once()
+		server.bgp.On("GetPeer", mock.Anything, uint(1)).Return(peer, nil).Once()
+
+		_, err := peers.UpdatePeer(withAPIKey, &flintroutepb.UpdatePeerRequest{
+			Id: 1, Description: &description, Tags: &flintroutepb.PeerTags{},
+		})
+		require.NoError(t, err)
+		server.bgp.AssertExpectations(t)
+	})
+
+	t.Run("Service errors map to codes", func(t *testing.T) {
+		server.bgp.On("GetPeer", mock.Anything, uint(9)).Return(nil, bgp.ErrPeerNotFound).Once()
+
+		_, err := peers.GetPeer(withToken(t, auth.RoleUser), &flintroutepb.GetPeerRequest{Id: 9})
+		assertGRPCError(t, err, codes.NotFound, apierror.CodePeerNotFound)
+
+		_, err = peers.CreatePeer(withAPIKey, &flintroutepb.CreatePeerRequest{Name: "incomplete"})
+		assertGRPCError(t, err, codes.InvalidArgument, apierror.CodeValidationFailed)
+	})
+
+	t.Run("Read-only mode refuses changes", func(t *testing.T) {
+		server.readOnly.set(true, "maintenance", "test-admin")
+		defer server.readOnly.set(false, "", "test-admin")
+
+		_, err := peers.DeletePeer(withToken(t, auth.RoleAdmin), &flintroutepb.DeletePeerRequest{Id: 1})
+		assertGRPCError(t, err, codes.FailedPrecondition, apierror.CodeReadOnly)
+		server.bgp.AssertNotCalled(t, "DeletePeer", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Alerts are acknowledged by the caller", func(t *testing.T) {
+		alert := models.Alert{Type: "peer_down", Severity: "critical", Message: "down", Source: models.AlertSourceFlintRoute}
+		require.NoError(t, server.db.Create(&alert).Error)
+		alerts := flintroutepb.NewAlertServiceClient(conn)
+
+		unacknowledged := false
+		resp, err := alerts.ListAlerts(withToken(t, auth.RoleUser), &flintroutepb.ListAlertsRequest{Acknowledged: &unacknowledged})
+		require.NoError(t, err)
+		require.Len(t, resp.Alerts, 1)
+
+		acked, err := alerts.AcknowledgeAlert(withAPIKey, &flintroutepb.AcknowledgeAlertRequest{Id: uint32(alert.ID)})
+		require.NoError(t, err)
+		assert.True(t, acked.Acknowledged)
+		assert.Equal(t, uint32(server.users[auth.RoleOperator].ID), acked.GetAcknowledgedBy())
+
+		_, err = alerts.AcknowledgeAlert(withAPIKey, &flintroutepb.AcknowledgeAlertRequest{Id: 999})
+		assertGRPCError(t, err, codes.NotFound, apierror.CodeAlertNotFound)
+	})
+}
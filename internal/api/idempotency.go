@@ -0,0 +1,197 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/padminisys/flintroute/internal/apierror"
+	authpkg "github.com/padminisys/flintroute/internal/auth"
+	"github.com/padminisys/flintroute/pkg/models"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+const (
+	// idempotencyKeyHeader is the request header carrying the client's key
+	idempotencyKeyHeader = "Idempotency-Key"
+	// idempotencyReplayedHeader marks a response replayed from a stored key
+	idempotencyReplayedHeader = "Idempotent-Replayed"
+	// maxIdempotencyKeyLength bounds the keys clients may send
+	maxIdempotencyKeyLength = 255
+)
+
+// idempotencyKeys stores the responses to POSTs sent with an
+// Idempotency-Key for window
+type idempotencyKeys struct {
+	db     *gorm.DB
+	window time.Duration
+	logger *zap.Logger
+}
+
+// newIdempotencyKeys returns a store keeping responses for window
+func newIdempotencyKeys(db *gorm.DB, window time.Duration, logger *zap.Logger) *idempotencyKeys {
+	return &idempotencyKeys{db: db, window: window, logger: logger}
+}
+
+// Prune drops keys whose window has passed
+func (k *idempotencyKeys) Prune(ctx context.Context) error {
+	return k.db.WithContext(ctx).Where("expires_at <= ?", time.Now()).Delete(&models.IdempotencyKey{}).Error
+}
+
+// Start prunes expired keys every interval until ctx is done
+func (k *idempotencyKeys) Start(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if err := k.Prune(ctx); err != nil {
+			k.logger.Error("Failed to prune idempotency keys", zap.Error(err))
+		}
+	}
+}
+
+// idempotencyMiddleware makes POSTs sent with an Idempotency-Key header
+// safe to retry. The first request with a key runs and its response is
+// stored for the user; later requests with the key get that response back
+// with Idempotent-Replayed: true instead of running again. Reusing a key for
+// a different request, or while the first is still running, is rejected.
+// Server errors aren't stored, so the request can be retried.
+func (s *Server) idempotencyMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader(idempotencyKeyHeader)
+		if s.idempotency == nil || c.Request.Method != http.MethodPost || key == "" {
+			c.Next()
+			return
+		}
+		if len(key) > maxIdempotencyKeyLength {
+			apierror.Respond(c, http.StatusBadRequest, apierror.CodeValidationFailed, "Idempotency-Key must be at most 255 characters")
+			c.Abort()
+			return
+		}
+		userID, ok := authpkg.GetUserID(c)
+		if !ok {
+			c.Next()
+			return
+		}
+
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			apierror.Respond(c, http.StatusBadRequest, apierror.CodeValidationFailed, "Failed to read request body")
+			c.Abort()
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+		// The tenant isn't resolved yet, but the header selects it
+		tenant := c.GetHeader(TenantHeader)
+		hash := requestHash(c.Request.Method, c.Request.URL.RequestURI(), tenant, body)
+
+		db := s.idempotency.db.WithContext(c.Request.Context())
+		record := &models.IdempotencyKey{
+			UserID:      userID,
+			Tenant:      tenant,
+			Key:         key,
+			RequestHash: hash,
+			ExpiresAt:   time.Now().Add(s.idempotency.window),
+		}
+		if err := claimIdempotencyKey(db, record); err != nil {
+			var existing models.IdempotencyKey
+			if lookupErr := db.Where("user_id = ? AND tenant = ? AND key = ?", userID, tenant, key).First(&existing).Error; lookupErr != nil {
+				s.logger.Error("Failed to claim idempotency key", zap.Error(err))
+				apierror.Respond(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to check Idempotency-Key")
+				c.Abort()
+				return
+			}
+			switch {
+			case existing.RequestHash != hash:
+				apierror.Respond(c, http.StatusUnprocessableEntity, apierror.CodeIdempotencyReused,
+					"Idempotency-Key was already used for a different request")
+			case existing.StatusCode == 0:
+				apierror.Respond(c, http.StatusConflict, apierror.CodeIdempotencyInUse,
+					"A request with this Idempotency-Key is still in progress")
+			default:
+				c.Header(idempotencyReplayedHeader, "true")
+				c.Data(existing.StatusCode, existing.ContentType, existing.Body)
+			}
+			c.Abort()
+			return
+		}
+
+		w := &recordingWriter{ResponseWriter: c.Writer}
+		c.Writer = w
+		completed := false
+		defer func() {
+			s.finishIdempotentRequest(c, record, w, completed)
+		}()
+		c.Next()
+		completed = true
+	}
+}
+
+// finishIdempotentRequest stores the response to a request holding an
+// idempotency key. The key is released instead when the request failed
+// with a server error or didn't complete, e.g. because the handler
+// panicked, so that it can be retried.
+func (s *Server) finishIdempotentRequest(c *gin.Context, record *models.IdempotencyKey, w *recordingWriter, completed bool) {
+	// Keep the outcome even if the client has gone away
+	db := s.idempotency.db.WithContext(context.WithoutCancel(c.Request.Context()))
+	var err error
+	if status := w.Status(); !completed || status >= http.StatusInternalServerError {
+		err = db.Delete(record).Error
+	} else {
+		err = db.Model(record).Updates(map[string]interface{}{
+			"status_code":  status,
+			"content_type": w.Header().Get("Content-Type"),
+			"body":         w.body.Bytes(),
+		}).Error
+	}
+	if err != nil {
+		s.logger.Error("Failed to store idempotent response", zap.String("key", record.Key), zap.Error(err))
+	}
+}
+
+// requestHash identifies a request by its method, URL, tenant and body
+func requestHash(method, uri, tenant string, body []byte) string {
+	sum := sha256.Sum256([]byte(method + "\n" + uri + "\n" + tenant + "\n" + string(body)))
+	return hex.EncodeToString(sum[:])
+}
+
+// claimIdempotencyKey inserts record, replacing an expired key of the same
+// user, tenant and name. It fails when the key is still held.
+func claimIdempotencyKey(db *gorm.DB, record *models.IdempotencyKey) error {
+	err := db.Where("user_id = ? AND tenant = ? AND key = ? AND expires_at <= ?", record.UserID, record.Tenant, record.Key, time.Now()).
+		Delete(&models.IdempotencyKey{}).Error
+	if err != nil {
+		return err
+	}
+	return db.Create(record).Error
+}
+
+// recordingWriter keeps a copy of the response body
+type recordingWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+// Write records and writes response data
+func (w *recordingWriter) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+// WriteString records and writes response data
+func (w *recordingWriter) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
@@ -0,0 +1,157 @@
+package api
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/padminisys/flintroute/internal/apierror"
+	"github.com/padminisys/flintroute/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestIdempotencyMiddleware(t *testing.T) {
+	server, db := setupTestServer(t)
+	server.router = gin.New()
+	server.router.Use(gin.RecoveryWithWriter(io.Discard))
+	server.idempotency = newIdempotencyKeys(db, time.Hour, zap.NewNop())
+
+	var created, failed, panicked int
+	v1 := server.router.Group("/api/v1")
+	v1.Use(func(c *gin.Context) {
+		c.Set("user_id", uint(1))
+		if c.GetHeader("X-User") == "other" {
+			c.Set("user_id", uint(2))
+		}
+	}, server.idempotencyMiddleware())
+	v1.POST("/bgp/peers", func(c *gin.Context) {
+		created++
+		c.JSON(http.StatusCreated, gin.H{"id": created})
+	})
+	v1.POST("/admin/config/backup", func(c *gin.Context) {
+		failed++
+		apierror.Respond(c, http.StatusBadGateway, apierror.CodeFRRUnavailable, "FRR is unavailable")
+	})
+
+	v1.POST("/bgp/sync", func(c *gin.Context) {
+		panicked++
+		panic("sync failed")
+	})
+
+	requestAs := func(tenant, path, key, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", path, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		if key != "" {
+			req.Header.Set(idempotencyKeyHeader, key)
+		}
+		if tenant != "" {
+			req.Header.Set(TenantHeader, tenant)
+		}
+		server.router.ServeHTTP(w, req)
+		return w
+	}
+	request := func(path, key, body string) *httptest.ResponseRecorder {
+		return requestAs("", path, key, body)
+	}
+
+	t.Run("Replays the first response", func(t *testing.T) {
+		first := request("/api/v1/bgp/peers", "create-1", `{"ip_address":"10.0.0.1"}`)
+		require.Equal(t, http.StatusCreated, first.Code)
+		assert.Empty(t, first.Header().Get(idempotencyReplayedHeader))
+
+		replay := request("/api/v1/bgp/peers", "create-1", `{"ip_address":"10.0.0.1"}`)
+		assert.Equal(t, http.StatusCreated, replay.Code)
+		assert.Equal(t, "true", replay.Header().Get(idempotencyReplayedHeader))
+		assert.Equal(t, first.Body.String(), replay.Body.String())
+		assert.Equal(t, first.Header().Get("Content-Type"), replay.Header().Get("Content-Type"))
+		assert.Equal(t, 1, created)
+	})
+
+	t.Run("Requests without a key always run", func(t *testing.T) {
+		request("/api/v1/bgp/peers", "", `{}`)
+		request("/api/v1/bgp/peers", "", `{}`)
+		assert.Equal(t, 3, created)
+	})
+
+	t.Run("Reusing a key for another request is rejected", func(t *testing.T) {
+		w := request("/api/v1/bgp/peers", "create-1", `{"ip_address":"10.0.0.2"}`)
+		assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+		assert.Contains(t, w.Body.String(), string(apierror.CodeIdempotencyReused))
+		assert.Equal(t, 3, created)
+	})
+
+	t.Run("Keys are per user", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/api/v1/bgp/peers", bytes.NewBufferString(`{"ip_address":"10.0.0.1"}`))
+		req.Header.Set(idempotencyKeyHeader, "create-1")
+		req.Header.Set("X-User", "other")
+		server.router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusCreated, w.Code)
+		assert.Empty(t, w.Header().Get(idempotencyReplayedHeader))
+		assert.Equal(t, 4, created)
+	})
+
+	t.Run("Keys are per tenant", func(t *testing.T) {
+		for _, tenant := range []string{"1", "2"} {
+			w := requestAs(tenant, "/api/v1/bgp/peers", "create-tenant", `{"ip_address":"10.0.0.9"}`)
+			assert.Equal(t, http.StatusCreated, w.Code)
+			assert.Empty(t, w.Header().Get(idempotencyReplayedHeader), tenant)
+		}
+		w := requestAs("2", "/api/v1/bgp/peers", "create-tenant", `{"ip_address":"10.0.0.9"}`)
+		assert.Equal(t, "true", w.Header().Get(idempotencyReplayedHeader))
+		assert.Equal(t, 6, created)
+	})
+
+	t.Run("A panicking request releases its key", func(t *testing.T) {
+		for i := 0; i < 2; i++ {
+			w := request("/api/v1/bgp/sync", "sync-1", "")
+			assert.Equal(t, http.StatusInternalServerError, w.Code)
+		}
+		assert.Equal(t, 2, panicked)
+
+		var count int64
+		require.NoError(t, db.Model(&models.IdempotencyKey{}).Where("key = ?", "sync-1").Count(&count).Error)
+		assert.Zero(t, count)
+	})
+
+	t.Run("A key still in progress is rejected", func(t *testing.T) {
+		require.NoError(t, db.Create(&models.IdempotencyKey{
+			UserID:      1,
+			Key:         "running",
+			RequestHash: requestHash("POST", "/api/v1/bgp/peers", "", []byte(`{}`)),
+			ExpiresAt:   time.Now().Add(time.Hour),
+		}).Error)
+		w := request("/api/v1/bgp/peers", "running", `{}`)
+		assert.Equal(t, http.StatusConflict, w.Code)
+		assert.Contains(t, w.Body.String(), string(apierror.CodeIdempotencyInUse))
+	})
+
+	t.Run("Server errors are not stored", func(t *testing.T) {
+		assert.Equal(t, http.StatusBadGateway, request("/api/v1/admin/config/backup", "backup-1", "").Code)
+		assert.Equal(t, http.StatusBadGateway, request("/api/v1/admin/config/backup", "backup-1", "").Code)
+		assert.Equal(t, 2, failed)
+	})
+
+	t.Run("Expired keys are replaced and pruned", func(t *testing.T) {
+		require.NoError(t, db.Model(&models.IdempotencyKey{}).Where("key = ?", "create-1").
+			Update("expires_at", time.Now().Add(-time.Minute)).Error)
+
+		w := request("/api/v1/bgp/peers", "create-1", `{"ip_address":"10.0.0.2"}`)
+		assert.Equal(t, http.StatusCreated, w.Code)
+		assert.Empty(t, w.Header().Get(idempotencyReplayedHeader))
+
+		require.NoError(t, db.Model(&models.IdempotencyKey{}).Where("1 = 1").
+			Update("expires_at", time.Now().Add(-time.Minute)).Error)
+		require.NoError(t, server.idempotency.Prune(t.Context()))
+		var count int64
+		require.NoError(t, db.Model(&models.IdempotencyKey{}).Count(&count).Error)
+		assert.Zero(t, count)
+	})
+}
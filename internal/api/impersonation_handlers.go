@@ -0,0 +1,184 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/padminisys/flintroute/internal/apierror"
+	authpkg "github.com/padminisys/flintroute/internal/auth"
+	"github.com/padminisys/flintroute/internal/repository"
+	"github.com/padminisys/flintroute/pkg/models"
+	"go.uber.org/zap"
+)
+
+// Audit actions recorded for impersonation
+const (
+	// ActionImpersonate is an admin getting a token acting as a user
+	ActionImpersonate = "user.impersonate"
+	// ActionImpersonatedRequest is a change made with an impersonation token
+	ActionImpersonatedRequest = "user.impersonated_request"
+)
+
+// defaultImpersonationExpiry is how long impersonation tokens are valid,
+// unless configured
+const defaultImpersonationExpiry = 10 * time.Minute
+
+// ImpersonateRequest is an optional reason for impersonating a user, kept in
+// the audit log
+type ImpersonateRequest struct {
+	Reason string `json:"reason" binding:"max=500"`
+}
+
+// ImpersonateResponse is a token acting as a user. There is no refresh
+// token; once it expires the admin has to impersonate the user again.
+type ImpersonateResponse struct {
+	AccessToken  string    `json:"access_token"`
+	ExpiresIn    int64     `json:"expires_in"`
+	ExpiresAt    time.Time `json:"expires_at"`
+	User         UserInfo  `json:"user"`
+	Impersonator UserInfo  `json:"impersonator"`
+}
+
+// handleImpersonate handles an admin getting a short-lived token acting as
+// another user, to see FlintRoute as they do
+func (s *Server) handleImpersonate(c *gin.Context) {
+	if !s.config.Auth.Impersonation.Enabled {
+		apierror.Respond(c, http.StatusForbidden, apierror.CodeForbidden, "Impersonation is disabled")
+		return
+	}
+	if _, impersonating := authpkg.GetImpersonator(c); impersonating {
+		apierror.Respond(c, http.StatusForbidden, apierror.CodeForbidden, "Cannot impersonate while impersonating")
+		return
+	}
+
+	var req ImpersonateRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+			apierror.Validation(c, err)
+			return
+		}
+	}
+
+	id, err := strconv.ParseUint(c.Param("user_id"), 10, 32)
+	if err != nil {
+		apierror.Respond(c, http.StatusBadRequest, apierror.CodeInvalidID, "Invalid user ID")
+		return
+	}
+	admin := s.currentUser(c)
+	if admin == nil {
+		return
+	}
+	user, err := s.repos.Users.GetByID(c.Request.Context(), uint(id))
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			apierror.Respond(c, http.StatusNotFound, apierror.CodeNotFound, "User not found")
+			return
+		}
+		s.logger.Error("Database error", zap.Error(err))
+		apierror.Respond(c, http.StatusInternalServerError, apierror.CodeInternal, "Internal server error")
+		return
+	}
+
+	switch {
+	case user.ID == admin.ID:
+		apierror.Respond(c, http.StatusBadRequest, apierror.CodeValidationFailed, "Cannot impersonate yourself")
+		return
+	case !user.Active:
+		apierror.Respond(c, http.StatusBadRequest, apierror.CodeValidationFailed, "Cannot impersonate a disabled user")
+		return
+	case user.Role == authpkg.RoleAdmin:
+		apierror.Respond(c, http.StatusForbidden, apierror.CodeForbidden, "Admins cannot be impersonated")
+		return
+	}
+
+	expiry, err := time.ParseDuration(s.config.Auth.Impersonation.TokenExpiry)
+	if err != nil || expiry <= 0 {
+		expiry = defaultImpersonationExpiry
+	}
+	token, claims, err := s.jwtManager.GenerateImpersonationToken(user, admin, expiry)
+	if err != nil {
+		s.logger.Error("Failed to generate impersonation token", zap.Error(err))
+		apierror.Respond(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to generate token")
+		return
+	}
+
+	detail := fmt.Sprintf("token %s valid until %s", claims.ID, claims.ExpiresAt.UTC().Format(time.RFC3339))
+	if req.Reason != "" {
+		detail += ": " + req.Reason
+	}
+	if err := s.db.WithContext(c.Request.Context()).Create(&models.AuditEntry{
+		Username: admin.Username,
+		Action:   ActionImpersonate,
+		Target:   impersonationTarget(user.ID, user.Username),
+		Detail:   detail,
+	}).Error; err != nil {
+		// Impersonation is only allowed with a trail
+		s.logger.Error("Failed to audit impersonation", zap.Error(err))
+		apierror.Respond(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to record impersonation")
+		return
+	}
+
+	s.logger.Warn("Admin impersonating user",
+		zap.String("impersonator", admin.Username),
+		zap.String("username", user.Username),
+		zap.String("token_id", claims.ID),
+		zap.Duration("expiry", expiry),
+		zap.String("reason", req.Reason),
+	)
+
+	c.JSON(http.StatusOK, ImpersonateResponse{
+		AccessToken: token,
+		ExpiresIn:   int64(expiry.Seconds()),
+		ExpiresAt:   claims.ExpiresAt.Time,
+		User: UserInfo{
+			ID:       user.ID,
+			Username: user.Username,
+			Email:    user.Email,
+			Role:     user.Role,
+		},
+		Impersonator: UserInfo{
+			ID:       admin.ID,
+			Username: admin.Username,
+			Email:    admin.Email,
+			Role:     admin.Role,
+		},
+	})
+}
+
+// impersonationAuditMiddleware records the changes made with impersonation
+// tokens in the audit log, under the impersonating admin
+func (s *Server) impersonationAuditMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		impersonator, ok := authpkg.GetImpersonator(c)
+		if !ok {
+			return
+		}
+		switch c.Request.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			return
+		}
+
+		userID, _ := authpkg.GetUserID(c)
+		username, _ := authpkg.GetUsername(c)
+		if err := s.db.WithContext(c.Request.Context()).Create(&models.AuditEntry{
+			Username: impersonator,
+			Action:   ActionImpersonatedRequest,
+			Target:   impersonationTarget(userID, username),
+			Detail:   fmt.Sprintf("%s %s: %d", c.Request.Method, c.Request.URL.Path, c.Writer.Status()),
+		}).Error; err != nil {
+			s.logger.Error("Failed to audit impersonated request", zap.Error(err))
+		}
+	}
+}
+
+// impersonationTarget describes an impersonated user in the audit log
+func impersonationTarget(id uint, username string) string {
+	return fmt.Sprintf("user %d (%s)", id, username)
+}
@@ -0,0 +1,128 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/padminisys/flintroute/internal/auth"
+	"github.com/padminisys/flintroute/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestImpersonation(t *testing.T) {
+	// send sends a request through the full router with token
+	send := func(server *mockedServer, token, method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		if body != "" {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		w := httptest.NewRecorder()
+		server.router.ServeHTTP(w, req)
+		return w
+	}
+	impersonate := func(t *testing.T, server *mockedServer, user *models.User, body string) *httptest.ResponseRecorder {
+		return server.request(t, auth.RoleAdmin, "POST", fmt.Sprintf("/api/v1/admin/impersonate/%d", user.ID), body)
+	}
+
+	t.Run("Disabled", func(t *testing.T) {
+		server := newMockedServer(t)
+
+		w := impersonate(t, server, server.users[auth.RoleOperator], "")
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		assert.Contains(t, w.Body.String(), "Impersonation is disabled")
+	})
+
+	t.Run("Acts as the user with a trail", func(t *testing.T) {
+		server := newMockedServer(t)
+		server.config.Auth.Impersonation.Enabled = true
+		server.config.Auth.Impersonation.TokenExpiry = "5m"
+		operator, admin := server.users[auth.RoleOperator], server.users[auth.RoleAdmin]
+		require.NoError(t, server.db.Create(&models.RefreshToken{UserID: operator.ID, Token: "operator-refresh", ExpiresAt: time.Now().Add(time.Hour)}).Error)
+
+		w := impersonate(t, server, operator, `{"reason":"Ticket 4711: can't see peers"}`)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var resp ImpersonateResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.Equal(t, int64(300), resp.ExpiresIn)
+		assert.Equal(t, "test-operator", resp.User.Username)
+		assert.Equal(t, "test-admin", resp.Impersonator.Username)
+
+		claims, err := server.jwtManager.ValidateToken(resp.AccessToken)
+		require.NoError(t, err)
+		assert.Equal(t, operator.ID, claims.UserID)
+		assert.Equal(t, auth.RoleOperator, claims.Role)
+		assert.Equal(t, &auth.Actor{Subject: "test-admin", UserID: admin.ID}, claims.Act)
+
+		w = send(server, resp.AccessToken, "GET", "/api/v1/me", "")
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), `"username":"test-operator"`)
+
+		// The user's role applies, not the admin's
+		w = send(server, resp.AccessToken, "GET", "/api/v1/admin/monitoring", "")
+		assert.Equal(t, http.StatusForbidden, w.Code)
+
+		w = send(server, resp.AccessToken, "PUT", "/api/v1/me/preferences", `{"theme":"dark"}`)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+		w = send(server, resp.AccessToken, "PUT", "/api/v1/me/password", `{"current_password":"x","new_password":"new-password"}`)
+		assert.Equal(t, http.StatusForbidden, w.Code)
+
+		var entries []models.AuditEntry
+		require.NoError(t, server.db.Order("id").Find(&entries).Error)
+		require.Len(t, entries, 3)
+		assert.Equal(t, ActionImpersonate, entries[0].Action)
+		assert.Equal(t, "test-admin", entries[0].Username)
+		assert.Equal(t, fmt.Sprintf("user %d (test-operator)", operator.ID), entries[0].Target)
+		assert.Contains(t, entries[0].Detail, "Ticket 4711: can't see peers")
+		assert.Equal(t, ActionImpersonatedRequest, entries[1].Action)
+		assert.Equal(t, "test-admin", entries[1].Username)
+		assert.Equal(t, "PUT /api/v1/me/preferences: 200", entries[1].Detail)
+		assert.Equal(t, "PUT /api/v1/me/password: 403", entries[2].Detail)
+
+		// Logging out ends the impersonation without signing the user out
+		w = send(server, resp.AccessToken, "POST", "/api/v1/auth/logout", "")
+		require.Equal(t, http.StatusOK, w.Code)
+		w = send(server, resp.AccessToken, "GET", "/api/v1/me", "")
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		var refresh models.RefreshToken
+		require.NoError(t, server.db.Where("token = ?", "operator-refresh").First(&refresh).Error)
+		assert.False(t, refresh.Revoked)
+	})
+
+	t.Run("Rejects", func(t *testing.T) {
+		server := newMockedServer(t)
+		server.config.Auth.Impersonation.Enabled = true
+		disabled := &models.User{Username: "disabled", Email: "disabled@test.example.com", PasswordHash: "x", Role: auth.RoleUser, Active: true}
+		require.NoError(t, server.db.Create(disabled).Error)
+		require.NoError(t, server.db.Model(disabled).Update("active", false).Error)
+		otherAdmin := &models.User{Username: "other-admin", Email: "other@test.example.com", PasswordHash: "x", Role: auth.RoleAdmin, Active: true}
+		require.NoError(t, server.db.Create(otherAdmin).Error)
+
+		for name, tc := range map[string]struct {
+			path string
+			code int
+		}{
+			"yourself":      {fmt.Sprintf("/api/v1/admin/impersonate/%d", server.users[auth.RoleAdmin].ID), http.StatusBadRequest},
+			"disabled user": {fmt.Sprintf("/api/v1/admin/impersonate/%d", disabled.ID), http.StatusBadRequest},
+			"admins":        {fmt.Sprintf("/api/v1/admin/impersonate/%d", otherAdmin.ID), http.StatusForbidden},
+			"unknown user":  {"/api/v1/admin/impersonate/999", http.StatusNotFound},
+			"invalid ID":    {"/api/v1/admin/impersonate/bob", http.StatusBadRequest},
+		} {
+			t.Run(name, func(t *testing.T) {
+				w := server.request(t, auth.RoleAdmin, "POST", tc.path, "")
+				assert.Equal(t, tc.code, w.Code, w.Body.String())
+			})
+		}
+
+		var count int64
+		require.NoError(t, server.db.Model(&models.AuditEntry{}).Count(&count).Error)
+		assert.Zero(t, count)
+	})
+}
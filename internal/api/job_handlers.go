@@ -0,0 +1,267 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/padminisys/flintroute/internal/apierror"
+	authpkg "github.com/padminisys/flintroute/internal/auth"
+	"github.com/padminisys/flintroute/internal/frrlog"
+	"github.com/padminisys/flintroute/internal/jobs"
+	"github.com/padminisys/flintroute/internal/tenancy"
+	"github.com/padminisys/flintroute/internal/webhooks"
+	"github.com/padminisys/flintroute/pkg/models"
+	"go.uber.org/zap"
+)
+
+// Background job types
+const (
+	JobConfigRestore     = "config.restore"
+	JobReconcile         = "bgp.reconcile"
+	JobGitOpsSync        = "gitops.sync"
+	JobAnalyzePrefixes   = "bgp.analyze_prefixes"
+	JobProbeReachability = "bgp.probe_reachability"
+	JobPeerAction        = "bgp.peer_action"
+)
+
+// restoreJobPayload holds the parameters of a config restore job. Unset
+// verification settings default to config_versions.restore.
+type restoreJobPayload struct {
+	VersionID       uint   `json:"version_id"`
+	VerifyWindow    string `json:"verify_window,omitempty"`
+	MaxSessionsDown *int   `json:"max_sessions_down,omitempty"`
+}
+
+// jobTenant returns ctx acting for the tenant the job was queued for
+func jobTenant(ctx context.Context, job *models.Job) context.Context {
+	if job.TenantID != nil {
+		ctx = tenancy.WithTenant(ctx, *job.TenantID)
+	}
+	return ctx
+}
+
+// registerJobs sets the handlers for the background job types
+func (s *Server) registerJobs() {
+	s.jobs.Register(JobConfigRestore, s.runConfigRestore)
+	s.jobs.Register(JobReconcile, s.runReconcile)
+	s.jobs.Register(JobAnalyzePrefixes, s.runAnalyzePrefixes)
+	s.jobs.Register(JobProbeReachability, s.runProbeReachability)
+	s.jobs.Register(JobPeerAction, s.runPeerAction)
+	s.jobs.Announce(JobPeerAction, s.announceBefore, s.announcePeerAction)
+	if s.gitopsSyncer != nil {
+		s.jobs.Register(JobGitOpsSync, s.runGitOpsSync)
+	}
+}
+
+// handleGetJob handles getting a background job's status, progress and
+// result
+func (s *Server) handleGetJob(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		apierror.Respond(c, http.StatusBadRequest, apierror.CodeInvalidID, "Invalid job ID")
+		return
+	}
+
+	job, err := s.jobs.Get(c.Request.Context(), uint(id))
+	if err != nil {
+		if errors.Is(err, jobs.ErrJobNotFound) {
+			apierror.Respond(c, http.StatusNotFound, apierror.CodeJobNotFound, "Job not found")
+			return
+		}
+		s.logger.Error("Failed to get job", zap.Error(err))
+		apierror.Respond(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to get job")
+		return
+	}
+
+	c.JSON(http.StatusOK, job)
+}
+
+// enqueueJob queues a job on behalf of the current user and responds with
+// 202 Accepted and the job's location
+func (s *Server) enqueueJob(c *gin.Context, jobType string, payload interface{}, message string) {
+	var createdBy *uint
+	if userID, ok := authpkg.GetUserID(c); ok {
+		createdBy = &userID
+	}
+
+	job, err := s.jobs.Enqueue(c.Request.Context(), jobType, payload, createdBy)
+	if err != nil {
+		s.logger.Error(message, zap.Error(err))
+		apierror.Respond(c, http.StatusInternalServerError, apierror.CodeInternal, message)
+		return
+	}
+
+	c.Header("Location", fmt.Sprintf("/api/v1/jobs/%d", job.ID))
+	c.JSON(http.StatusAccepted, job)
+}
+
+// runConfigRestore restores a configuration version. The configuration
+// from before the restore is saved first; when the sessions that were
+// established go down during the verification window, it is applied again.
+func (s *Server) runConfigRestore(ctx context.Context, job *models.Job, progress jobs.Progress) (interface{}, error) {
+	var payload restoreJobPayload
+	if err := json.Unmarshal([]byte(job.Payload), &payload); err != nil {
+		return nil, fmt.Errorf("invalid job payload: %w", err)
+	}
+
+	run := &restoreRun{logger: s.logger, job: job, progress: progress}
+	run.result.VersionID = payload.VersionID
+	run.step(5, "Loading configuration version")
+	version, err := s.repos.Configs.Get(ctx, payload.VersionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get config version %d: %w", payload.VersionID, err)
+	}
+	if err := s.configVersions.Load(ctx, version); err != nil {
+		return nil, err
+	}
+	run.result.Name = version.Name
+	run.result.Labels = version.Labels
+	run.result.Pinned = version.Pinned
+	verification := s.restoreVerification(payload)
+	run.result.Verification = verification
+
+	run.step(10, "Saving the configuration from before the restore")
+	snapshot, previousGlobal, err := s.saveBeforeRestore(ctx, job, version)
+	if err != nil {
+		return nil, fmt.Errorf("failed to save the configuration before restoring: %w", err)
+	}
+	run.result.SnapshotID = snapshot.ID
+	run.step(20, fmt.Sprintf("Saved the configuration from before the restore as config version %d", snapshot.ID))
+
+	established, err := s.establishedSessions(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	restoreCtx := frrlog.WithConfigVersion(ctx, version.ID)
+	if version.BGPGlobal != nil {
+		run.step(30, "Restoring BGP global configuration")
+		global := *version.BGPGlobal
+		if err := s.bgpService.SaveGlobalConfig(restoreCtx, &global); err != nil {
+			return nil, fmt.Errorf("failed to restore BGP global configuration: %w", err)
+		}
+	}
+
+	// TODO: Implement actual configuration restore to FRR
+	// This would involve applying the configuration to FRR via gRPC
+	run.step(50, "Applying configuration")
+
+	run.result.Outcome = RestoreApplied
+	if verification.window > 0 && len(established) > 0 {
+		run.step(60, fmt.Sprintf("Verifying %d established sessions for %s", len(established), verification.window))
+		down, err := s.verifyRestore(ctx, established, verification)
+		if err != nil {
+			return nil, err
+		}
+		run.result.SessionsDown = down
+
+		if len(down) > verification.MaxSessionsDown {
+			run.step(80, fmt.Sprintf("%d sessions went down, more than the %d allowed; rolling back to config version %d",
+				len(down), verification.MaxSessionsDown, snapshot.ID))
+			if err := s.rollbackRestore(frrlog.WithConfigVersion(ctx, snapshot.ID), run, version, previousGlobal); err != nil {
+				return nil, err
+			}
+			run.result.Outcome = RestoreRolledBack
+			run.step(95, fmt.Sprintf("Rolled back to config version %d", snapshot.ID))
+
+			s.raiseAlert(ctx, &models.Alert{
+				Type:     "config_restore_rolled_back",
+				Severity: "critical",
+				Message:  fmt.Sprintf("Rolled back restore of %s", describeVersion(version)),
+				Details:  fmt.Sprintf("Sessions down after the restore: %s", strings.Join(down, ", ")),
+				TenantID: version.TenantID,
+			})
+			return &run.result, nil
+		}
+		run.result.Outcome = RestoreVerified
+		run.step(90, fmt.Sprintf("Verified with %d of %d sessions down", len(down), len(established)))
+	}
+
+	s.webhookService.Publish(ctx, webhooks.EventConfigRestored, version)
+
+	s.raiseAlert(ctx, &models.Alert{
+		Type:     "config_restored",
+		Severity: "info",
+		Message:  fmt.Sprintf("Restored %s", describeVersion(version)),
+		Details:  version.Description,
+		TenantID: version.TenantID,
+	})
+
+	return &run.result, nil
+}
+
+// rollbackRestore applies the configuration from before a restore again
+func (s *Server) rollbackRestore(ctx context.Context, run *restoreRun, version *models.ConfigVersion, previousGlobal *models.BGPGlobalConfig) error {
+	if version.BGPGlobal == nil {
+		return nil
+	}
+	if previousGlobal == nil {
+		run.step(85, "No BGP global configuration was set before the restore; keeping the restored one")
+		return nil
+	}
+
+	run.step(85, "Restoring the previous BGP global configuration")
+	global := *previousGlobal
+	if err := s.bgpService.SaveGlobalConfig(ctx, &global); err != nil {
+		return fmt.Errorf("failed to roll back BGP global configuration: %w", err)
+	}
+	return nil
+}
+
+// raiseAlert stores an alert and sends it to connected clients, SNMP and
+// notified users, the way the BGP service raises its alerts: a repeat of an
+// open alert updates it and notifies neither users nor SNMP, and during an
+// alert storm nobody is notified
+func (s *Server) raiseAlert(ctx context.Context, alert *models.Alert) {
+	result, err := s.alertDedup.Raise(ctx, alert)
+	if err != nil {
+		s.logger.Error("Failed to create alert", zap.String("type", alert.Type), zap.Error(err))
+		return
+	}
+	if result.Suppressed {
+		return
+	}
+
+	s.wsHub.BroadcastAlert(ctx, alert)
+	if result.Duplicate {
+		return
+	}
+	s.trapSender.SendAlert(ctx, alert)
+	s.notifier.Notify(ctx, alert)
+}
+
+// runReconcile runs a reconciliation pass between stored peers and FRR
+func (s *Server) runReconcile(ctx context.Context, job *models.Job, progress jobs.Progress) (interface{}, error) {
+	progress(10, "Comparing stored peers with FRR")
+	return s.bgpService.Reconcile(ctx)
+}
+
+// runAnalyzePrefixes compares peers' prefix counts with their counts a
+// window ago
+func (s *Server) runAnalyzePrefixes(ctx context.Context, job *models.Job, progress jobs.Progress) (interface{}, error) {
+	progress(10, "Analyzing prefix counts")
+	return s.bgpService.AnalyzePrefixes(ctx)
+}
+
+// runProbeReachability probes the address of every enabled peer of the
+// job's tenant
+func (s *Server) runProbeReachability(ctx context.Context, job *models.Job, progress jobs.Progress) (interface{}, error) {
+	progress(10, "Probing peer addresses")
+	return s.bgpService.ProbeReachability(jobTenant(ctx, job))
+}
+
+// runGitOpsSync fetches the GitOps repository and applies its definitions
+func (s *Server) runGitOpsSync(ctx context.Context, job *models.Job, progress jobs.Progress) (interface{}, error) {
+	if job.CreatedBy == nil {
+		return nil, errors.New("GitOps sync jobs require a user")
+	}
+
+	progress(10, "Fetching repository")
+	return s.gitopsSyncer.Sync(ctx, *job.CreatedBy)
+}
@@ -0,0 +1,147 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/padminisys/flintroute/internal/alerts"
+	"github.com/padminisys/flintroute/internal/jobs"
+	"github.com/padminisys/flintroute/internal/websocket"
+	"github.com/padminisys/flintroute/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func setupJobRouter(t *testing.T) (*gin.Engine, *gorm.DB) {
+	server, db := setupTestServer(t)
+	server.wsHub = websocket.NewHub(server.logger)
+	go server.wsHub.Run()
+	server.jobs = jobs.NewQueue(server.db, server.wsHub, 1, server.logger)
+	server.registerJobs()
+
+	router := gin.New()
+	router.Use(func(c *gin.Context) { c.Set("user_id", uint(1)) })
+	router.GET("/jobs/:id", server.handleGetJob)
+	router.POST("/config/restore/:id", server.handleRestoreConfig)
+
+	return router, db
+}
+
+func TestHandleRestoreConfigQueuesJob(t *testing.T) {
+	t.Run("Queues a restore job", func(t *testing.T) {
+		router, db := setupJobRouter(t)
+		version := models.ConfigVersion{Config: "router bgp 65001", Hash: "abc123", Description: "Baseline"}
+		require.NoError(t, db.Create(&version).Error)
+
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/config/restore/1", nil)
+		router.ServeHTTP(w, req)
+
+		require.Equal(t, http.StatusAccepted, w.Code)
+		var job models.Job
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &job))
+		assert.Equal(t, JobConfigRestore, job.Type)
+		assert.Equal(t, models.JobQueued, job.Status)
+		assert.Equal(t, "/api/v1/jobs/1", w.Header().Get("Location"))
+
+		var stored models.Job
+		require.NoError(t, db.First(&stored, job.ID).Error)
+		assert.JSONEq(t, `{"version_id":1}`, stored.Payload)
+		require.NotNil(t, stored.CreatedBy)
+		assert.Equal(t, uint(1), *stored.CreatedBy)
+	})
+
+	t.Run("Missing version", func(t *testing.T) {
+		router, db := setupJobRouter(t)
+
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/config/restore/9", nil)
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusNotFound, w.Code)
+		var count int64
+		db.Model(&models.Job{}).Count(&count)
+		assert.Zero(t, count)
+	})
+}
+
+func TestHandleGetJob(t *testing.T) {
+	router, db := setupJobRouter(t)
+	job := models.Job{
+		Type:     JobReconcile,
+		Status:   models.JobSucceeded,
+		Progress: 100,
+		Result:   json.RawMessage(`{"in_sync":true}`),
+	}
+	require.NoError(t, db.Create(&job).Error)
+
+	t.Run("Returns the job", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/jobs/1", nil)
+		router.ServeHTTP(w, req)
+
+		require.Equal(t, http.StatusOK, w.Code)
+		var resp map[string]interface{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.Equal(t, models.JobSucceeded, resp["status"])
+		assert.Equal(t, map[string]interface{}{"in_sync": true}, resp["result"])
+		assert.NotContains(t, resp, "payload")
+	})
+
+	t.Run("Missing job", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/jobs/42", nil)
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusNotFound, w.Code)
+		assert.Contains(t, w.Body.String(), "JOB_NOT_FOUND")
+	})
+
+	t.Run("Invalid ID", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/jobs/abc", nil)
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
+
+func TestRaiseAlert(t *testing.T) {
+	server := newMockedServer(t)
+	server.alertDedup = alerts.NewDeduplicator(server.db, alerts.DedupPolicy{
+		Window:         time.Hour,
+		StormThreshold: 2,
+		StormWindow:    time.Minute,
+	}, server.logger)
+	tenantID := uint(3)
+
+	// The second occurrence is a repeat, the third is held back as a storm
+	for range 3 {
+		server.raiseAlert(context.Background(), &models.Alert{
+			Type:     "config_drift",
+			Severity: "warning",
+			Message:  "Running configuration drifted",
+			TenantID: &tenantID,
+		})
+	}
+	server.raiseAlert(context.Background(), &models.Alert{
+		Type:     "config_drift",
+		Severity: "warning",
+		Message:  "Running configuration drifted",
+	})
+
+	var stored []models.Alert
+	require.NoError(t, server.db.Order("id").Find(&stored).Error)
+	require.Len(t, stored, 2, "repeats are folded into the open alert of their tenant")
+	assert.Equal(t, 3, stored[0].Occurrences)
+	assert.Equal(t, 1, stored[0].Suppressed)
+	assert.Equal(t, &tenantID, stored[0].TenantID)
+	assert.Equal(t, 1, stored[1].Occurrences)
+	assert.Nil(t, stored[1].TenantID)
+}
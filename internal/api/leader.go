@@ -0,0 +1,43 @@
+package api
+
+import (
+	"net/http"
+	"slices"
+
+	"github.com/gin-gonic/gin"
+	"github.com/padminisys/flintroute/internal/apierror"
+	"github.com/padminisys/flintroute/internal/leader"
+)
+
+// leaderMiddleware rejects changes with 503 on a standby instance, so that
+// only the leader applies them to FRR. Reads, signing in and out, and the
+// changes read-only mode accepts are served by every instance.
+func (s *Server) leaderMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if s.elector == nil || s.elector.IsLeader() || !isChange(c) ||
+			slices.Contains(readOnlyExempt, c.Request.URL.Path) {
+			c.Next()
+			return
+		}
+
+		message := "This FlintRoute instance is on standby; send changes to the leader"
+		if holder := s.elector.Status().Holder; holder != "" {
+			message += " (" + holder + ")"
+		}
+		apierror.Respond(c, http.StatusServiceUnavailable, apierror.CodeNotLeader, message)
+	}
+}
+
+// leadership returns this instance's view of the leadership. Without HA it
+// always leads.
+func (s *Server) leadership() leader.Status {
+	if s.elector == nil {
+		return leader.Status{Leader: true}
+	}
+	return s.elector.Status()
+}
+
+// handleGetLeader handles reporting which instance leads
+func (s *Server) handleGetLeader(c *gin.Context) {
+	c.JSON(http.StatusOK, s.leadership())
+}
@@ -0,0 +1,108 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/padminisys/flintroute/internal/apierror"
+	"github.com/padminisys/flintroute/internal/bgp"
+	"github.com/padminisys/flintroute/internal/frr"
+	"github.com/padminisys/flintroute/internal/leader"
+	"github.com/padminisys/flintroute/internal/websocket"
+	"github.com/padminisys/flintroute/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLeaderMiddleware(t *testing.T) {
+	server, _ := setupTestServer(t)
+	server.router = gin.New()
+	server.bgpService = bgp.NewService(server.db, frr.NewMockClient(), websocket.NewHub(server.logger), bgp.ServiceConfig{}, server.logger)
+
+	// Another instance holds the lease
+	require.NoError(t, server.db.Create(&models.LeaderLease{
+		Name:      leader.LeaseName,
+		Holder:    "flintroute-1",
+		ExpiresAt: time.Now().Add(time.Hour),
+	}).Error)
+	server.elector = leader.NewElector(server.db, leader.Config{
+		Identity:      "flintroute-2",
+		LeaseDuration: time.Hour,
+		RenewInterval: 10 * time.Millisecond,
+	}, server.logger)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go server.elector.Run(ctx, func(context.Context) {})
+	require.Eventually(t, func() bool { return server.elector.Status().Holder != "" }, 5*time.Second, 10*time.Millisecond)
+
+	v1 := server.router.Group("/api/v1")
+	v1.Use(server.leaderMiddleware())
+	for _, method := range []string{"GET", "POST"} {
+		v1.Handle(method, "/bgp/peers", func(c *gin.Context) { c.Status(http.StatusNoContent) })
+	}
+	v1.POST("/auth/login", func(c *gin.Context) { c.Status(http.StatusNoContent) })
+	v1.GET("/leader", server.handleGetLeader)
+	server.router.GET("/health/ready", server.handleReady)
+
+	request := func(method, path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, path, nil)
+		server.router.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("Standby rejects changes", func(t *testing.T) {
+		w := request("POST", "/api/v1/bgp/peers")
+		assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+
+		var resp apierror.Response
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.Equal(t, apierror.CodeNotLeader, resp.Code)
+		assert.Contains(t, resp.Error, "flintroute-1")
+	})
+
+	t.Run("Standby serves reads and sign in", func(t *testing.T) {
+		assert.Equal(t, http.StatusNoContent, request("GET", "/api/v1/bgp/peers").Code)
+		assert.Equal(t, http.StatusNoContent, request("POST", "/api/v1/auth/login").Code)
+
+		w := request("GET", "/api/v1/leader")
+		require.Equal(t, http.StatusOK, w.Code)
+		var status leader.Status
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &status))
+		assert.True(t, status.Enabled)
+		assert.False(t, status.Leader)
+		assert.Equal(t, "flintroute-2", status.Identity)
+		assert.Equal(t, "flintroute-1", status.Holder)
+	})
+
+	t.Run("Standby is not ready", func(t *testing.T) {
+		w := request("GET", "/health/ready")
+		assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+
+		var resp map[string]interface{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.Equal(t, "standby", resp["status"])
+	})
+}
+
+func TestHandleGetLeaderWithoutHA(t *testing.T) {
+	server, _ := setupTestServer(t)
+
+	router := gin.New()
+	router.GET("/leader", server.handleGetLeader)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/leader", nil)
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var status leader.Status
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &status))
+	assert.False(t, status.Enabled)
+	assert.True(t, status.Leader)
+}
@@ -0,0 +1,66 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/padminisys/flintroute/internal/apierror"
+	authpkg "github.com/padminisys/flintroute/internal/auth"
+	"github.com/padminisys/flintroute/internal/logging"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// LogLevelRequest represents a request to change the log level
+type LogLevelRequest struct {
+	Level string `json:"level" binding:"required,oneof=debug info warn error"`
+}
+
+// handleGetLogLevel handles reporting the current log level and whether it
+// can be changed
+func (s *Server) handleGetLogLevel(c *gin.Context) {
+	level := zapcore.LevelOf(s.logger.Core())
+	if s.logLevel != nil {
+		level = s.logLevel.Level()
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"level":      level.String(),
+		"adjustable": s.logLevel != nil,
+	})
+}
+
+// handleSetLogLevel handles changing the log level without a restart, for
+// example to debug a live issue
+func (s *Server) handleSetLogLevel(c *gin.Context) {
+	if s.logLevel == nil {
+		apierror.Respond(c, http.StatusNotFound, apierror.CodeNotFound, "The log level of this server cannot be changed at runtime")
+		return
+	}
+
+	var req LogLevelRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apierror.Validation(c, err)
+		return
+	}
+	level, err := logging.ParseLevel(req.Level)
+	if err != nil {
+		apierror.Respond(c, http.StatusBadRequest, apierror.CodeValidationFailed, err.Error())
+		return
+	}
+
+	previous := s.logLevel.Level()
+	s.logLevel.SetLevel(level)
+
+	username, _ := authpkg.GetUsername(c)
+	// Logged at warn so that the change shows up whatever the new level is
+	s.logger.Warn("Log level changed",
+		zap.String("from", previous.String()),
+		zap.String("to", level.String()),
+		zap.String("username", username),
+	)
+
+	c.JSON(http.StatusOK, gin.H{
+		"level":      level.String(),
+		"adjustable": true,
+	})
+}
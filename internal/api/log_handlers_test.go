@@ -0,0 +1,98 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestHandleLogLevel(t *testing.T) {
+	t.Run("Changes the level", func(t *testing.T) {
+		server, _ := setupTestServer(t)
+		server.SetLogLevel(zap.NewAtomicLevelAt(zapcore.InfoLevel))
+
+		router := gin.New()
+		router.GET("/admin/log-level", server.handleGetLogLevel)
+		router.PUT("/admin/log-level", server.handleSetLogLevel)
+
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("PUT", "/admin/log-level", bytes.NewBufferString(`{"level":"debug"}`))
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, zapcore.DebugLevel, server.logLevel.Level())
+
+		w = httptest.NewRecorder()
+		req, _ = http.NewRequest("GET", "/admin/log-level", nil)
+		router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var resp map[string]interface{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.Equal(t, "debug", resp["level"])
+		assert.Equal(t, true, resp["adjustable"])
+	})
+
+	t.Run("Rejects unknown levels", func(t *testing.T) {
+		server, _ := setupTestServer(t)
+		server.SetLogLevel(zap.NewAtomicLevelAt(zapcore.InfoLevel))
+
+		router := gin.New()
+		router.PUT("/admin/log-level", server.handleSetLogLevel)
+
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("PUT", "/admin/log-level", bytes.NewBufferString(`{"level":"trace"}`))
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Equal(t, zapcore.InfoLevel, server.logLevel.Level())
+	})
+
+	t.Run("Fixed level", func(t *testing.T) {
+		server, _ := setupTestServer(t)
+
+		router := gin.New()
+		router.PUT("/admin/log-level", server.handleSetLogLevel)
+
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("PUT", "/admin/log-level", bytes.NewBufferString(`{"level":"debug"}`))
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+}
+
+func TestLoggingMiddlewareSampling(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	core, logs := observer.New(zapcore.InfoLevel)
+
+	router := gin.New()
+	router.Use(loggingMiddleware(zap.New(core), 0))
+	router.GET("/ok", func(c *gin.Context) { c.Status(http.StatusOK) })
+	router.GET("/fail", func(c *gin.Context) { c.Status(http.StatusInternalServerError) })
+	router.GET("/slow", func(c *gin.Context) {
+		time.Sleep(slowRequestThreshold)
+		c.Status(http.StatusOK)
+	})
+
+	for _, path := range []string{"/ok", "/ok", "/fail", "/slow"} {
+		req, _ := http.NewRequest("GET", path, nil)
+		router.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	var paths []string
+	for _, entry := range logs.All() {
+		paths = append(paths, entry.ContextMap()["path"].(string))
+	}
+	assert.Equal(t, []string{"/fail", "/slow"}, paths)
+}
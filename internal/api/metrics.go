@@ -0,0 +1,207 @@
+package api
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/padminisys/flintroute/internal/cache"
+	"github.com/padminisys/flintroute/internal/frr"
+	"github.com/padminisys/flintroute/internal/websocket"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Descriptions of the BGP session monitor's metrics
+var (
+	monitorRunningDesc = prometheus.NewDesc("flintroute_monitor_running",
+		"Whether the BGP session monitor runs on this instance.", nil, nil)
+	monitorPausedDesc = prometheus.NewDesc("flintroute_monitor_paused",
+		"Whether BGP session monitoring is paused.", nil, nil)
+	frrReachableDesc = prometheus.NewDesc("flintroute_frr_reachable",
+		"Whether the last poll reached FRR.", nil, nil)
+	monitorCyclesDesc = prometheus.NewDesc("flintroute_monitor_cycles_total",
+		"Polling cycles run by the BGP session monitor.", nil, nil)
+	monitorFailedCyclesDesc = prometheus.NewDesc("flintroute_monitor_failed_cycles_total",
+		"Polling cycles that had errors.", nil, nil)
+	monitorSlowCyclesDesc = prometheus.NewDesc("flintroute_monitor_slow_cycles_total",
+		"Polling cycles that took longer than the poll interval.", nil, nil)
+	monitorConsecutiveFailuresDesc = prometheus.NewDesc("flintroute_monitor_consecutive_failures",
+		"Polling cycles in a row that had errors.", nil, nil)
+	monitorLastDurationDesc = prometheus.NewDesc("flintroute_monitor_last_cycle_duration_seconds",
+		"How long the last polling cycle took.", nil, nil)
+	monitorLastPeersDesc = prometheus.NewDesc("flintroute_monitor_last_cycle_peers",
+		"Peers polled by the last polling cycle.", nil, nil)
+	monitorLastSuccessDesc = prometheus.NewDesc("flintroute_monitor_last_success_timestamp_seconds",
+		"When a polling cycle last finished without errors, as a Unix time.", nil, nil)
+)
+
+// monitorCollector exports the BGP session monitor's state and statistics,
+// read on every scrape
+type monitorCollector struct {
+	server *Server
+}
+
+// Describe sends the descriptions of the monitor's metrics
+func (m monitorCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- monitorRunningDesc
+	ch <- monitorPausedDesc
+	ch <- frrReachableDesc
+	ch <- monitorCyclesDesc
+	ch <- monitorFailedCyclesDesc
+	ch <- monitorSlowCyclesDesc
+	ch <- monitorConsecutiveFailuresDesc
+	ch <- monitorLastDurationDesc
+	ch <- monitorLastPeersDesc
+	ch <- monitorLastSuccessDesc
+}
+
+// Collect sends the monitor's current metrics
+func (m monitorCollector) Collect(ch chan<- prometheus.Metric) {
+	status := m.server.bgpService.MonitorStatus()
+	stats := status.Stats
+
+	ch <- prometheus.MustNewConstMetric(monitorRunningDesc, prometheus.GaugeValue, boolValue(status.Running))
+	ch <- prometheus.MustNewConstMetric(monitorPausedDesc, prometheus.GaugeValue, boolValue(status.Paused))
+	ch <- prometheus.MustNewConstMetric(frrReachableDesc, prometheus.GaugeValue, boolValue(status.FRRReachable))
+	ch <- prometheus.MustNewConstMetric(monitorCyclesDesc, prometheus.CounterValue, float64(stats.Cycles))
+	ch <- prometheus.MustNewConstMetric(monitorFailedCyclesDesc, prometheus.CounterValue, float64(stats.FailedCycles))
+	ch <- prometheus.MustNewConstMetric(monitorSlowCyclesDesc, prometheus.CounterValue, float64(stats.SlowCycles))
+	ch <- prometheus.MustNewConstMetric(monitorConsecutiveFailuresDesc, prometheus.GaugeValue, float64(stats.ConsecutiveFailures))
+	ch <- prometheus.MustNewConstMetric(monitorLastDurationDesc, prometheus.GaugeValue, float64(stats.LastDurationMS)/1000)
+	ch <- prometheus.MustNewConstMetric(monitorLastPeersDesc, prometheus.GaugeValue, float64(stats.LastPeersPolled))
+	if stats.LastSuccessAt != nil {
+		ch <- prometheus.MustNewConstMetric(monitorLastSuccessDesc, prometheus.GaugeValue, float64(stats.LastSuccessAt.Unix()))
+	}
+}
+
+// Descriptions of the FRR limiter's metrics
+var (
+	frrCircuitOpenDesc = prometheus.NewDesc("flintroute_frr_circuit_open",
+		"Whether the FRR circuit breaker fails operations without sending them.", nil, nil)
+	frrInFlightDesc = prometheus.NewDesc("flintroute_frr_operations_in_flight",
+		"Operations being sent to FRR.", nil, nil)
+	frrThrottledDesc = prometheus.NewDesc("flintroute_frr_throttled_changes_total",
+		"Configuration changes that waited for the FRR rate limit.", nil, nil)
+	frrRejectedDesc = prometheus.NewDesc("flintroute_frr_rejected_operations_total",
+		"Operations failed by the open FRR circuit breaker.", nil, nil)
+)
+
+// frrLimitCollector exports the FRR limiter's state, read on every scrape
+type frrLimitCollector struct {
+	limiter *frr.LimitedClient
+}
+
+// Describe sends the descriptions of the limiter's metrics
+func (f frrLimitCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- frrCircuitOpenDesc
+	ch <- frrInFlightDesc
+	ch <- frrThrottledDesc
+	ch <- frrRejectedDesc
+}
+
+// Collect sends the limiter's current metrics
+func (f frrLimitCollector) Collect(ch chan<- prometheus.Metric) {
+	stats := f.limiter.Stats()
+
+	ch <- prometheus.MustNewConstMetric(frrCircuitOpenDesc, prometheus.GaugeValue, boolValue(stats.Circuit != frr.CircuitClosed))
+	ch <- prometheus.MustNewConstMetric(frrInFlightDesc, prometheus.GaugeValue, float64(stats.InFlight))
+	ch <- prometheus.MustNewConstMetric(frrThrottledDesc, prometheus.CounterValue, float64(stats.Throttled))
+	ch <- prometheus.MustNewConstMetric(frrRejectedDesc, prometheus.CounterValue, float64(stats.Rejected))
+}
+
+// Descriptions of the event hub's metrics
+var (
+	hubClientsDesc = prometheus.NewDesc("flintroute_event_clients",
+		"WebSocket and SSE clients connected to this instance.", nil, nil)
+	hubDroppedDesc = prometheus.NewDesc("flintroute_event_dropped_messages_total",
+		"Events discarded because a client's send buffer was full.", nil, nil)
+	hubSlowDisconnectsDesc = prometheus.NewDesc("flintroute_event_slow_client_disconnects_total",
+		"Clients disconnected for reading events too slowly.", nil, nil)
+)
+
+// hubCollector exports the event hub's clients and drop counters, read on
+// every scrape
+type hubCollector struct {
+	hub *websocket.Hub
+}
+
+// Describe sends the descriptions of the hub's metrics
+func (h hubCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- hubClientsDesc
+	ch <- hubDroppedDesc
+	ch <- hubSlowDisconnectsDesc
+}
+
+// Collect sends the hub's current metrics
+func (h hubCollector) Collect(ch chan<- prometheus.Metric) {
+	stats := h.hub.Stats()
+
+	ch <- prometheus.MustNewConstMetric(hubClientsDesc, prometheus.GaugeValue, float64(len(stats.Clients)))
+	ch <- prometheus.MustNewConstMetric(hubDroppedDesc, prometheus.CounterValue, float64(stats.Dropped))
+	ch <- prometheus.MustNewConstMetric(hubSlowDisconnectsDesc, prometheus.CounterValue, float64(stats.SlowDisconnects))
+}
+
+// Descriptions of the response cache's metrics, labelled by namespace
+var (
+	cacheEntriesDesc = prometheus.NewDesc("flintroute_cache_entries",
+		"Unexpired entries in the response cache.", []string{"namespace"}, nil)
+	cacheHitsDesc = prometheus.NewDesc("flintroute_cache_hits_total",
+		"Reads served from the response cache.", []string{"namespace"}, nil)
+	cacheMissesDesc = prometheus.NewDesc("flintroute_cache_misses_total",
+		"Reads that had to load the value.", []string{"namespace"}, nil)
+	cacheInvalidationsDesc = prometheus.NewDesc("flintroute_cache_invalidations_total",
+		"Times the namespace was invalidated by a change.", []string{"namespace"}, nil)
+)
+
+// cacheCollector exports the response cache's statistics, read on every
+// scrape
+type cacheCollector struct {
+	cache *cache.Cache
+}
+
+// Describe sends the descriptions of the cache's metrics
+func (c cacheCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- cacheEntriesDesc
+	ch <- cacheHitsDesc
+	ch <- cacheMissesDesc
+	ch <- cacheInvalidationsDesc
+}
+
+// Collect sends the cache's current metrics
+func (c cacheCollector) Collect(ch chan<- prometheus.Metric) {
+	for _, stats := range c.cache.Stats() {
+		ch <- prometheus.MustNewConstMetric(cacheEntriesDesc, prometheus.GaugeValue, float64(stats.Entries), stats.Namespace)
+		ch <- prometheus.MustNewConstMetric(cacheHitsDesc, prometheus.CounterValue, float64(stats.Hits), stats.Namespace)
+		ch <- prometheus.MustNewConstMetric(cacheMissesDesc, prometheus.CounterValue, float64(stats.Misses), stats.Namespace)
+		ch <- prometheus.MustNewConstMetric(cacheInvalidationsDesc, prometheus.CounterValue, float64(stats.Invalidations), stats.Namespace)
+	}
+}
+
+// boolValue returns 1 for true and 0 for false
+func boolValue(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// metricsHandler serves the monitor's, FRR limiter's, event hub's and
+// response cache's metrics, along with the Go runtime's and the process's,
+// in the Prometheus text format
+func (s *Server) metricsHandler() gin.HandlerFunc {
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(
+		monitorCollector{server: s},
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+	if s.frrLimiter != nil {
+		registry.MustRegister(frrLimitCollector{limiter: s.frrLimiter})
+	}
+	if s.wsHub != nil {
+		registry.MustRegister(hubCollector{hub: s.wsHub})
+	}
+	if s.cache != nil {
+		registry.MustRegister(cacheCollector{cache: s.cache})
+	}
+	return gin.WrapH(promhttp.HandlerFor(registry, promhttp.HandlerOpts{}))
+}
@@ -0,0 +1,37 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/padminisys/flintroute/internal/bgp"
+	"github.com/padminisys/flintroute/internal/cache"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCacheMetrics(t *testing.T) {
+	server := newMockedServer(t)
+	server.bgp.On("MonitorStatus").Return(bgp.MonitorStatus{})
+	server.cache = cache.New(time.Minute)
+
+	load := func() (interface{}, error) { return "peers", nil }
+	for i := 0; i < 3; i++ {
+		_, err := server.cache.GetOrLoad("peers", "", load)
+		require.NoError(t, err)
+	}
+	server.cache.Invalidate("alerts")
+
+	router := gin.New()
+	router.GET("/metrics", server.metricsHandler())
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `flintroute_cache_entries{namespace="peers"} 1`)
+	assert.Contains(t, w.Body.String(), `flintroute_cache_hits_total{namespace="peers"} 2`)
+	assert.Contains(t, w.Body.String(), `flintroute_cache_misses_total{namespace="peers"} 1`)
+	assert.Contains(t, w.Body.String(), `flintroute_cache_invalidations_total{namespace="alerts"} 1`)
+}
@@ -0,0 +1,530 @@
+package api
+
+import (
+	"context"
+	"time"
+
+	"github.com/padminisys/flintroute/internal/bgp"
+	"github.com/padminisys/flintroute/internal/frr"
+	"github.com/padminisys/flintroute/pkg/models"
+	"github.com/stretchr/testify/mock"
+)
+
+// mockBGPService is a mock implementation of the BGP service for handler
+// tests
+type mockBGPService struct {
+	mock.Mock
+}
+
+// ListPeers mocks the ListPeers method
+func (m *mockBGPService) ListPeers(ctx context.Context, selectors ...bgp.TagSelector) ([]*models.BGPPeer, error) {
+	args := m.Called(ctx, selectors)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*models.BGPPeer), args.Error(1)
+}
+
+// SearchPeers mocks the SearchPeers method
+func (m *mockBGPService) SearchPeers(ctx context.Context, search bgp.PeerSearch) (*bgp.PeerSearchResult, error) {
+	args := m.Called(ctx, search)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*bgp.PeerSearchResult), args.Error(1)
+}
+
+// GetPeer mocks the GetPeer method
+func (m *mockBGPService) GetPeer(ctx context.Context, id uint) (*models.BGPPeer, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.BGPPeer), args.Error(1)
+}
+
+// PeerFRRConfig mocks the PeerFRRConfig method
+func (m *mockBGPService) PeerFRRConfig(ctx context.Context, id uint) (string, error) {
+	args := m.Called(ctx, id)
+	return args.String(0), args.Error(1)
+}
+
+// TestPeer mocks the TestPeer method
+func (m *mockBGPService) TestPeer(ctx context.Context, id uint) (*bgp.PeerTest, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*bgp.PeerTest), args.Error(1)
+}
+
+// PlanCreatePeer mocks the PlanCreatePeer method
+func (m *mockBGPService) PlanCreatePeer(ctx context.Context, peer *models.BGPPeer) (*bgp.PeerPlan, error) {
+	args := m.Called(ctx, peer)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*bgp.PeerPlan), args.Error(1)
+}
+
+// CreatePeer mocks the CreatePeer method
+func (m *mockBGPService) CreatePeer(ctx context.Context, peer *models.BGPPeer) error {
+	args := m.Called(ctx, peer)
+	return args.Error(0)
+}
+
+// PlanUpdatePeer mocks the PlanUpdatePeer method
+func (m *mockBGPService) PlanUpdatePeer(ctx context.Context, id uint, updates *models.BGPPeer) (*bgp.PeerPlan, error) {
+	args := m.Called(ctx, id, updates)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*bgp.PeerPlan), args.Error(1)
+}
+
+// UpdatePeer mocks the UpdatePeer method
+func (m *mockBGPService) UpdatePeer(ctx context.Context, id uint, updates *models.BGPPeer) error {
+	args := m.Called(ctx, id, updates)
+	return args.Error(0)
+}
+
+// PlanPatchPeer mocks the PlanPatchPeer method
+func (m *mockBGPService) PlanPatchPeer(ctx context.Context, id uint, patch *bgp.PeerPatch) (*bgp.PeerPlan, error) {
+	args := m.Called(ctx, id, patch)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*bgp.PeerPlan), args.Error(1)
+}
+
+// PatchPeer mocks the PatchPeer method
+func (m *mockBGPService) PatchPeer(ctx context.Context, id uint, patch *bgp.PeerPatch) error {
+	args := m.Called(ctx, id, patch)
+	return args.Error(0)
+}
+
+// SetPeerPassword mocks the SetPeerPassword method
+func (m *mockBGPService) SetPeerPassword(ctx context.Context, id uint, password string) error {
+	args := m.Called(ctx, id, password)
+	return args.Error(0)
+}
+
+// DeletePeer mocks the DeletePeer method
+func (m *mockBGPService) DeletePeer(ctx context.Context, id uint) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+// BulkUpdatePeers mocks the BulkUpdatePeers method
+func (m *mockBGPService) BulkUpdatePeers(ctx context.Context, selectors []bgp.TagSelector, action string) ([]*bgp.BulkResult, error) {
+	args := m.Called(ctx, selectors, action)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*bgp.BulkResult), args.Error(1)
+}
+
+// StartMaintenance mocks the StartMaintenance method
+func (m *mockBGPService) StartMaintenance(ctx context.Context, id uint, until *time.Time) (*models.BGPPeer, error) {
+	args := m.Called(ctx, id, until)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.BGPPeer), args.Error(1)
+}
+
+// EndMaintenance mocks the EndMaintenance method
+func (m *mockBGPService) EndMaintenance(ctx context.Context, id uint) (*models.BGPPeer, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.BGPPeer), args.Error(1)
+}
+
+// RunPeerAction mocks the RunPeerAction method
+func (m *mockBGPService) RunPeerAction(ctx context.Context, id uint, action string) (*models.BGPPeer, error) {
+	args := m.Called(ctx, id, action)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.BGPPeer), args.Error(1)
+}
+
+// AnnouncePeerAction mocks the AnnouncePeerAction method
+func (m *mockBGPService) AnnouncePeerAction(ctx context.Context, id uint, action string, runAt time.Time) {
+	m.Called(ctx, id, action, runAt)
+}
+
+// ListDeletedPeers mocks the ListDeletedPeers method
+func (m *mockBGPService) ListDeletedPeers(ctx context.Context) ([]*bgp.DeletedPeer, error) {
+	args := m.Called(ctx)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*bgp.DeletedPeer), args.Error(1)
+}
+
+// RestorePeer mocks the RestorePeer method
+func (m *mockBGPService) RestorePeer(ctx context.Context, id uint) (*models.BGPPeer, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.BGPPeer), args.Error(1)
+}
+
+// PurgeDeletedPeers mocks the PurgeDeletedPeers method
+func (m *mockBGPService) PurgeDeletedPeers(ctx context.Context, olderThan time.Duration) ([]*models.BGPPeer, error) {
+	args := m.Called(ctx, olderThan)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*models.BGPPeer), args.Error(1)
+}
+
+// ListTemplates mocks the ListTemplates method
+func (m *mockBGPService) ListTemplates(ctx context.Context) ([]*models.PeerTemplate, error) {
+	args := m.Called(ctx)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*models.PeerTemplate), args.Error(1)
+}
+
+// GetTemplate mocks the GetTemplate method
+func (m *mockBGPService) GetTemplate(ctx context.Context, id uint) (*models.PeerTemplate, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.PeerTemplate), args.Error(1)
+}
+
+// ResolveTemplate mocks the ResolveTemplate method
+func (m *mockBGPService) ResolveTemplate(ctx context.Context, id uint) (*models.PeerTemplateSettings, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.PeerTemplateSettings), args.Error(1)
+}
+
+// ApplyTemplate mocks the ApplyTemplate method
+func (m *mockBGPService) ApplyTemplate(ctx context.Context, peer *models.BGPPeer, templateID uint, fields []string) error {
+	args := m.Called(ctx, peer, templateID, fields)
+	return args.Error(0)
+}
+
+// CreateTemplate mocks the CreateTemplate method
+func (m *mockBGPService) CreateTemplate(ctx context.Context, template *models.PeerTemplate) error {
+	args := m.Called(ctx, template)
+	return args.Error(0)
+}
+
+// UpdateTemplate mocks the UpdateTemplate method
+func (m *mockBGPService) UpdateTemplate(ctx context.Context, id uint, updates *models.PeerTemplate) (*models.PeerTemplate, error) {
+	args := m.Called(ctx, id, updates)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.PeerTemplate), args.Error(1)
+}
+
+// DeleteTemplate mocks the DeleteTemplate method
+func (m *mockBGPService) DeleteTemplate(ctx context.Context, id uint) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+// SyncTemplate mocks the SyncTemplate method
+func (m *mockBGPService) SyncTemplate(ctx context.Context, id uint) ([]*bgp.BulkResult, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*bgp.BulkResult), args.Error(1)
+}
+
+// ListSessions mocks the ListSessions method
+func (m *mockBGPService) ListSessions(ctx context.Context, selectors ...bgp.TagSelector) ([]*models.BGPSession, error) {
+	args := m.Called(ctx, selectors)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*models.BGPSession), args.Error(1)
+}
+
+// GetSession mocks the GetSession method
+func (m *mockBGPService) GetSession(ctx context.Context, peerID uint) (*models.BGPSession, error) {
+	args := m.Called(ctx, peerID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.BGPSession), args.Error(1)
+}
+
+// TopTalkers mocks the TopTalkers method
+func (m *mockBGPService) TopTalkers(ctx context.Context, metric string, limit int) ([]*bgp.TopTalker, error) {
+	args := m.Called(ctx, metric, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*bgp.TopTalker), args.Error(1)
+}
+
+// SessionStatistics mocks the SessionStatistics method
+func (m *mockBGPService) SessionStatistics(ctx context.Context, by, tagKey string) (*bgp.StatsReport, error) {
+	args := m.Called(ctx, by, tagKey)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*bgp.StatsReport), args.Error(1)
+}
+
+// SLAReport mocks the SLAReport method
+func (m *mockBGPService) SLAReport(ctx context.Context, window time.Duration, selectors ...bgp.TagSelector) (*bgp.SLAReport, error) {
+	args := m.Called(ctx, window, selectors)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*bgp.SLAReport), args.Error(1)
+}
+
+// AnalyzePrefixes mocks the AnalyzePrefixes method
+func (m *mockBGPService) AnalyzePrefixes(ctx context.Context) (*bgp.AnomalyReport, error) {
+	args := m.Called(ctx)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*bgp.AnomalyReport), args.Error(1)
+}
+
+// LastAnomalyReport mocks the LastAnomalyReport method
+func (m *mockBGPService) LastAnomalyReport(ctx context.Context) *bgp.AnomalyReport {
+	args := m.Called(ctx)
+	if args.Get(0) == nil {
+		return nil
+	}
+	return args.Get(0).(*bgp.AnomalyReport)
+}
+
+// ProbeReachability mocks the ProbeReachability method
+func (m *mockBGPService) ProbeReachability(ctx context.Context) (*bgp.ReachabilityReport, error) {
+	args := m.Called(ctx)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*bgp.ReachabilityReport), args.Error(1)
+}
+
+// LastReachabilityReport mocks the LastReachabilityReport method
+func (m *mockBGPService) LastReachabilityReport(ctx context.Context) *bgp.ReachabilityReport {
+	args := m.Called(ctx)
+	if args.Get(0) == nil {
+		return nil
+	}
+	return args.Get(0).(*bgp.ReachabilityReport)
+}
+
+// PeerReachabilityHistory mocks the PeerReachabilityHistory method
+func (m *mockBGPService) PeerReachabilityHistory(ctx context.Context, id uint, since time.Time) ([]models.ReachabilitySample, error) {
+	args := m.Called(ctx, id, since)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.ReachabilitySample), args.Error(1)
+}
+
+// PeerStateTimeline mocks the PeerStateTimeline method
+func (m *mockBGPService) PeerStateTimeline(ctx context.Context, id uint, start, end time.Time, bucket time.Duration) (*bgp.StateTimeline, error) {
+	args := m.Called(ctx, id, start, end, bucket)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*bgp.StateTimeline), args.Error(1)
+}
+
+// MonitorStatus mocks the MonitorStatus method
+func (m *mockBGPService) MonitorStatus() bgp.MonitorStatus {
+	args := m.Called()
+	return args.Get(0).(bgp.MonitorStatus)
+}
+
+// PauseMonitoring mocks the PauseMonitoring method
+func (m *mockBGPService) PauseMonitoring() {
+	m.Called()
+}
+
+// ResumeMonitoring mocks the ResumeMonitoring method
+func (m *mockBGPService) ResumeMonitoring() {
+	m.Called()
+}
+
+// GetRunningConfig mocks the GetRunningConfig method
+func (m *mockBGPService) GetRunningConfig(ctx context.Context) (string, error) {
+	args := m.Called(ctx)
+	return args.String(0), args.Error(1)
+}
+
+// FRRStatus mocks the FRRStatus method
+func (m *mockBGPService) FRRStatus(ctx context.Context) (*frr.Status, error) {
+	args := m.Called(ctx)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*frr.Status), args.Error(1)
+}
+
+// AdoptNeighbors mocks the AdoptNeighbors method
+func (m *mockBGPService) AdoptNeighbors(ctx context.Context, addresses []string) (*bgp.AdoptReport, error) {
+	args := m.Called(ctx, addresses)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*bgp.AdoptReport), args.Error(1)
+}
+
+// ImportRunningConfig mocks the ImportRunningConfig method
+func (m *mockBGPService) ImportRunningConfig(ctx context.Context, dryRun bool) (*bgp.ImportReport, error) {
+	args := m.Called(ctx, dryRun)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*bgp.ImportReport), args.Error(1)
+}
+
+// DetectDrift mocks the DetectDrift method
+func (m *mockBGPService) DetectDrift(ctx context.Context) (*bgp.DriftReport, error) {
+	args := m.Called(ctx)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*bgp.DriftReport), args.Error(1)
+}
+
+// Reconcile mocks the Reconcile method
+func (m *mockBGPService) Reconcile(ctx context.Context) (*bgp.DriftReport, error) {
+	args := m.Called(ctx)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*bgp.DriftReport), args.Error(1)
+}
+
+// SyncAllPeers mocks the SyncAllPeers method
+func (m *mockBGPService) SyncAllPeers(ctx context.Context, trigger string) (*bgp.PeerSyncReport, error) {
+	args := m.Called(ctx, trigger)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*bgp.PeerSyncReport), args.Error(1)
+}
+
+// LastSyncReport mocks the LastSyncReport method
+func (m *mockBGPService) LastSyncReport(ctx context.Context) *bgp.PeerSyncReport {
+	args := m.Called(ctx)
+	if args.Get(0) == nil {
+		return nil
+	}
+	return args.Get(0).(*bgp.PeerSyncReport)
+}
+
+// GetGlobalConfig mocks the GetGlobalConfig method
+func (m *mockBGPService) GetGlobalConfig(ctx context.Context) (*models.BGPGlobalConfig, error) {
+	args := m.Called(ctx)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.BGPGlobalConfig), args.Error(1)
+}
+
+// SaveGlobalConfig mocks the SaveGlobalConfig method
+func (m *mockBGPService) SaveGlobalConfig(ctx context.Context, cfg *models.BGPGlobalConfig) error {
+	args := m.Called(ctx, cfg)
+	return args.Error(0)
+}
+
+// ListCommunityLists mocks the ListCommunityLists method
+func (m *mockBGPService) ListCommunityLists(ctx context.Context) ([]*models.CommunityList, error) {
+	args := m.Called(ctx)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*models.CommunityList), args.Error(1)
+}
+
+// GetCommunityList mocks the GetCommunityList method
+func (m *mockBGPService) GetCommunityList(ctx context.Context, name string) (*models.CommunityList, error) {
+	args := m.Called(ctx, name)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.CommunityList), args.Error(1)
+}
+
+// SaveCommunityList mocks the SaveCommunityList method
+func (m *mockBGPService) SaveCommunityList(ctx context.Context, list *models.CommunityList) error {
+	args := m.Called(ctx, list)
+	return args.Error(0)
+}
+
+// DeleteCommunityList mocks the DeleteCommunityList method
+func (m *mockBGPService) DeleteCommunityList(ctx context.Context, name string) error {
+	args := m.Called(ctx, name)
+	return args.Error(0)
+}
+
+// ListASPathLists mocks the ListASPathLists method
+func (m *mockBGPService) ListASPathLists(ctx context.Context) ([]*models.ASPathList, error) {
+	args := m.Called(ctx)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*models.ASPathList), args.Error(1)
+}
+
+// GetASPathList mocks the GetASPathList method
+func (m *mockBGPService) GetASPathList(ctx context.Context, name string) (*models.ASPathList, error) {
+	args := m.Called(ctx, name)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.ASPathList), args.Error(1)
+}
+
+// SaveASPathList mocks the SaveASPathList method
+func (m *mockBGPService) SaveASPathList(ctx context.Context, list *models.ASPathList) error {
+	args := m.Called(ctx, list)
+	return args.Error(0)
+}
+
+// DeleteASPathList mocks the DeleteASPathList method
+func (m *mockBGPService) DeleteASPathList(ctx context.Context, name string) error {
+	args := m.Called(ctx, name)
+	return args.Error(0)
+}
+
+// mockConfigVersionStore is a mock implementation of the config version
+// store for handler tests
+type mockConfigVersionStore struct {
+	mock.Mock
+}
+
+// Save mocks the Save method
+func (m *mockConfigVersionStore) Save(ctx context.Context, version *models.ConfigVersion) error {
+	args := m.Called(ctx, version)
+	return args.Error(0)
+}
+
+// Load mocks the Load method
+func (m *mockConfigVersionStore) Load(ctx context.Context, version *models.ConfigVersion) error {
+	args := m.Called(ctx, version)
+	return args.Error(0)
+}
+
+// LoadAll mocks the LoadAll method
+func (m *mockConfigVersionStore) LoadAll(ctx context.Context, versions []models.ConfigVersion) error {
+	args := m.Called(ctx, versions)
+	return args.Error(0)
+}
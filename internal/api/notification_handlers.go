@@ -0,0 +1,179 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/padminisys/flintroute/internal/alerts"
+	"github.com/padminisys/flintroute/internal/apierror"
+	"github.com/padminisys/flintroute/pkg/models"
+	"go.uber.org/zap"
+)
+
+// NotificationSettingsResponse is a user's notification settings. Inherited
+// is set when the user has none of their own and gets the admin defaults.
+type NotificationSettingsResponse struct {
+	*models.NotificationSettings
+	Inherited bool `json:"inherited"`
+}
+
+// handleGetNotificationSettings handles getting the current user's
+// notification settings
+func (s *Server) handleGetNotificationSettings(c *gin.Context) {
+	user := s.currentUser(c)
+	if user == nil {
+		return
+	}
+
+	settings, inherited, err := s.notifier.UserSettings(c.Request.Context(), user.ID)
+	if err != nil {
+		s.logger.Error("Failed to get notification settings", zap.Error(err))
+		apierror.Respond(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to get notification settings")
+		return
+	}
+
+	c.JSON(http.StatusOK, NotificationSettingsResponse{NotificationSettings: settings, Inherited: inherited})
+}
+
+// handleUpdateNotificationSettings handles replacing the current user's
+// notification settings
+func (s *Server) handleUpdateNotificationSettings(c *gin.Context) {
+	var settings models.NotificationSettings
+	if err := c.ShouldBindJSON(&settings); err != nil {
+		apierror.Validation(c, err)
+		return
+	}
+
+	user := s.currentUser(c)
+	if user == nil {
+		return
+	}
+
+	if err := s.notifier.SetUserSettings(c.Request.Context(), user.ID, &settings); err != nil {
+		s.respondNotificationError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, NotificationSettingsResponse{NotificationSettings: &settings})
+}
+
+// handleResetNotificationSettings handles removing the current user's
+// notification settings, so the admin defaults apply again
+func (s *Server) handleResetNotificationSettings(c *gin.Context) {
+	user := s.currentUser(c)
+	if user == nil {
+		return
+	}
+
+	if err := s.notifier.ResetUserSettings(c.Request.Context(), user.ID); err != nil {
+		s.respondNotificationError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Notification settings reset to the defaults"})
+}
+
+// handleGetNotificationDefaults handles getting the notification settings
+// of users without their own
+func (s *Server) handleGetNotificationDefaults(c *gin.Context) {
+	settings, err := s.notifier.Defaults(c.Request.Context())
+	if err != nil {
+		s.logger.Error("Failed to get notification defaults", zap.Error(err))
+		apierror.Respond(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to get notification defaults")
+		return
+	}
+
+	c.JSON(http.StatusOK, settings)
+}
+
+// handleUpdateNotificationDefaults handles replacing the notification
+// settings of users without their own
+func (s *Server) handleUpdateNotificationDefaults(c *gin.Context) {
+	var settings models.NotificationSettings
+	if err := c.ShouldBindJSON(&settings); err != nil {
+		apierror.Validation(c, err)
+		return
+	}
+
+	if err := s.notifier.SetDefaults(c.Request.Context(), &settings); err != nil {
+		s.respondNotificationError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, &settings)
+}
+
+// respondNotificationError maps notifier errors to API errors
+func (s *Server) respondNotificationError(c *gin.Context, err error) {
+	if errors.Is(err, alerts.ErrInvalidSettings) {
+		apierror.Respond(c, http.StatusBadRequest, apierror.CodeValidationFailed, err.Error())
+		return
+	}
+	s.logger.Error("Failed to save notification settings", zap.Error(err))
+	apierror.Respond(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to save notification settings")
+}
+
+// TestEmailRequest asks for an email template to be sent with sample data
+type TestEmailRequest struct {
+	Template string `json:"template" binding:"required"`
+	// To defaults to the admin's own address
+	To string `json:"to" binding:"omitempty,email"`
+}
+
+// TestEmailResponse is a test email sent
+type TestEmailResponse struct {
+	Template string `json:"template"`
+	To       string `json:"to"`
+	Subject  string `json:"subject"`
+}
+
+// handleSendTestEmail handles sending an email template with sample data,
+// to check SMTP settings, templates and branding
+func (s *Server) handleSendTestEmail(c *gin.Context) {
+	user := s.currentUser(c)
+	if user == nil {
+		return
+	}
+
+	var req TestEmailRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apierror.Validation(c, err)
+		return
+	}
+	if s.mailer == nil {
+		apierror.Respond(c, http.StatusServiceUnavailable, apierror.CodeEmailUnavailable, "Email is disabled")
+		return
+	}
+	data, err := alerts.SampleData(req.Template)
+	if err != nil {
+		apierror.Respond(c, http.StatusBadRequest, apierror.CodeValidationFailed, err.Error())
+		return
+	}
+	if req.To == "" {
+		req.To = user.Email
+	}
+	if req.To == "" {
+		apierror.Respond(c, http.StatusBadRequest, apierror.CodeValidationFailed, "to is required for users without an email address")
+		return
+	}
+
+	email, err := s.mailer.Templates().Render(req.Template, data)
+	if err != nil {
+		s.logger.Error("Failed to render test email", zap.String("template", req.Template), zap.Error(err))
+		apierror.Respond(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to render email template")
+		return
+	}
+	if err := s.mailer.SendEmail([]string{req.To}, email); err != nil {
+		s.logger.Warn("Failed to send test email", zap.String("template", req.Template), zap.Error(err))
+		apierror.Respond(c, http.StatusBadGateway, apierror.CodeEmailUnavailable, err.Error())
+		return
+	}
+
+	s.logger.Info("Sent test email",
+		zap.String("template", req.Template),
+		zap.String("to", req.To),
+		zap.String("username", user.Username),
+	)
+	c.JSON(http.StatusOK, TestEmailResponse{Template: req.Template, To: req.To, Subject: email.Subject})
+}
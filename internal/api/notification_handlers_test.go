@@ -0,0 +1,178 @@
+package api
+
+import (
+	"net"
+	"net/http"
+	"net/textproto"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/padminisys/flintroute/internal/alerts"
+	"github.com/padminisys/flintroute/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestHandleNotificationSettings(t *testing.T) {
+	server, db := setupTestServer(t)
+	server.notifier = alerts.NewNotifier(server.db, nil, zap.NewNop())
+
+	user := &models.User{Username: "alice", PasswordHash: "x", Email: "alice@example.com", Role: "user", Active: true}
+	require.NoError(t, db.Create(user).Error)
+
+	router := gin.New()
+	router.Use(func(c *gin.Context) { c.Set("user_id", user.ID) })
+	router.GET("/me/notifications", server.handleGetNotificationSettings)
+	router.PUT("/me/notifications", server.handleUpdateNotificationSettings)
+	router.DELETE("/me/notifications", server.handleResetNotificationSettings)
+	router.GET("/admin/notifications/defaults", server.handleGetNotificationDefaults)
+	router.PUT("/admin/notifications/defaults", server.handleUpdateNotificationDefaults)
+
+	t.Run("Users inherit the defaults", func(t *testing.T) {
+		w := profileRequest(router, "PUT", "/admin/notifications/defaults", `{"rules":[{"severities":["critical"],"channels":["email"]}]}`)
+		require.Equal(t, http.StatusOK, w.Code)
+
+		w = profileRequest(router, "GET", "/me/notifications", "")
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), `"inherited":true`)
+		assert.Contains(t, w.Body.String(), `"channels":["email"]`)
+	})
+
+	t.Run("Users set their own", func(t *testing.T) {
+		w := profileRequest(router, "PUT", "/me/notifications", `{
+			"rules": [{"severities": ["critical", "error"], "channels": ["webhook"]}],
+			"quiet_hours": [{"start": "22:00", "end": "07:00"}],
+			"quiet_hours_bypass": ["critical"],
+			"timezone": "Asia/Kolkata",
+			"webhook_url": "https://hooks.example.com/alice"
+		}`)
+		require.Equal(t, http.StatusOK, w.Code)
+
+		w = profileRequest(router, "GET", "/me/notifications", "")
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), `"inherited":false`)
+		assert.Contains(t, w.Body.String(), `"timezone":"Asia/Kolkata"`)
+
+		w = profileRequest(router, "GET", "/admin/notifications/defaults", "")
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.NotContains(t, w.Body.String(), "Asia/Kolkata")
+	})
+
+	t.Run("Reject invalid settings", func(t *testing.T) {
+		w := profileRequest(router, "PUT", "/me/notifications", `{"rules":[{"channels":["sms"]}]}`)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, w.Body.String(), "VALIDATION_FAILED")
+
+		w = profileRequest(router, "PUT", "/admin/notifications/defaults", `{"timezone":"Nowhere/Special"}`)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("Reset to the defaults", func(t *testing.T) {
+		w := profileRequest(router, "DELETE", "/me/notifications", "")
+		require.Equal(t, http.StatusOK, w.Code)
+
+		w = profileRequest(router, "GET", "/me/notifications", "")
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), `"inherited":true`)
+	})
+}
+
+// fakeSMTPServer accepts email over SMTP on localhost and passes each
+// message's data to received
+func fakeSMTPServer(t *testing.T) (host string, port int, received <-chan string) {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { listener.Close() })
+
+	messages := make(chan string, 10)
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				text := textproto.NewConn(conn)
+				text.PrintfLine("220 localhost ESMTP")
+				for {
+					line, err := text.ReadLine()
+					if err != nil {
+						return
+					}
+					switch command := strings.ToUpper(strings.Fields(line + " ")[0]); command {
+					case "DATA":
+						text.PrintfLine("354 Go ahead")
+						data, err := text.ReadDotLines()
+						if err != nil {
+							return
+						}
+						messages <- strings.Join(data, "\n")
+						text.PrintfLine("250 OK")
+					case "QUIT":
+						text.PrintfLine("221 Bye")
+						return
+					default:
+						text.PrintfLine("250 OK")
+					}
+				}
+			}()
+		}
+	}()
+
+	addr := listener.Addr().(*net.TCPAddr)
+	return addr.IP.String(), addr.Port, messages
+}
+
+func TestHandleSendTestEmail(t *testing.T) {
+	server, db := setupTestServer(t)
+
+	admin := &models.User{Username: "alice", PasswordHash: "x", Email: "alice@example.com", Role: "admin", Active: true}
+	require.NoError(t, db.Create(admin).Error)
+
+	router := gin.New()
+	router.Use(func(c *gin.Context) { c.Set("user_id", admin.ID) })
+	router.POST("/admin/email/test", server.handleSendTestEmail)
+
+	t.Run("Email disabled", func(t *testing.T) {
+		w := profileRequest(router, "POST", "/admin/email/test", `{"template":"alert"}`)
+		assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+		assert.Contains(t, w.Body.String(), "EMAIL_UNAVAILABLE")
+	})
+
+	host, port, received := fakeSMTPServer(t)
+	templates, err := alerts.LoadTemplates("", alerts.Branding{Name: "Acme NOC"})
+	require.NoError(t, err)
+	server.mailer = alerts.NewMailer(alerts.MailConfig{Host: host, Port: port, From: "flintroute@example.com", Templates: templates})
+
+	t.Run("Sends the template to the admin", func(t *testing.T) {
+		w := profileRequest(router, "POST", "/admin/email/test", `{"template":"change_requested"}`)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		assert.JSONEq(t, `{"template":"change_requested","to":"alice@example.com","subject":"Acme NOC approval requested: Delete peer 192.0.2.1 (AS64500)"}`, w.Body.String())
+
+		msg := <-received
+		assert.Contains(t, msg, "To: alice@example.com")
+		assert.Contains(t, msg, "Subject: Acme NOC approval requested: Delete peer 192.0.2.1 (AS64500)")
+		assert.Contains(t, msg, "Content-Type: multipart/alternative")
+	})
+
+	t.Run("Sends to another address", func(t *testing.T) {
+		w := profileRequest(router, "POST", "/admin/email/test", `{"template":"password_reset","to":"noc@example.com"}`)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		assert.Contains(t, <-received, "To: noc@example.com")
+	})
+
+	t.Run("Unknown template", func(t *testing.T) {
+		w := profileRequest(router, "POST", "/admin/email/test", `{"template":"welcome"}`)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, w.Body.String(), "unknown email template")
+	})
+
+	t.Run("Invalid address", func(t *testing.T) {
+		w := profileRequest(router, "POST", "/admin/email/test", `{"template":"alert","to":"not-an-address"}`)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
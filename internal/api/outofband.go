@@ -0,0 +1,66 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/padminisys/flintroute/internal/frr"
+	"github.com/padminisys/flintroute/pkg/models"
+	"go.uber.org/zap"
+)
+
+// startOutOfBandDetector periodically checks FRR's running configuration
+// for changes made outside FlintRoute, such as through vtysh
+func (s *Server) startOutOfBandDetector(ctx context.Context, frrClient frr.FRRClient, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	s.logger.Info("Started out-of-band change detection", zap.Duration("interval", interval))
+
+	for {
+		select {
+		case <-ctx.Done():
+			s.logger.Info("Stopped out-of-band change detection")
+			return
+		case <-ticker.C:
+			s.detectOutOfBand(ctx, frrClient)
+		}
+	}
+}
+
+// detectOutOfBand raises a config_drift alert, with the diff, when FRR's
+// running configuration has changed outside FlintRoute
+func (s *Server) detectOutOfBand(ctx context.Context, frrClient frr.FRRClient) {
+	if !frrClient.IsConnected() {
+		return
+	}
+
+	transaction, err := s.frrLog.DetectOutOfBand(ctx, frrClient)
+	if err != nil {
+		s.logger.Error("Failed to check FRR for out-of-band changes", zap.Error(err))
+		return
+	}
+	if transaction == nil {
+		return
+	}
+
+	s.logger.Warn("Detected a change to FRR made outside FlintRoute",
+		zap.Uint("transaction_id", transaction.ID),
+		zap.Int("lines", len(transaction.Delta)),
+	)
+
+	details := "The configuration before the change is unknown, as FlintRoute restarted since."
+	if len(transaction.Delta) > 0 {
+		details = strings.Join(transaction.Delta, "\n")
+	}
+	details += fmt.Sprintf("\n\nBack it up as a config version with POST /api/v1/frr/transactions/%d/backup.", transaction.ID)
+
+	s.raiseAlert(ctx, &models.Alert{
+		Type:     "config_drift",
+		Severity: "warning",
+		Message:  "FRR's running configuration was changed outside FlintRoute",
+		Details:  details,
+	})
+}
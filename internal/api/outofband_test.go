@@ -0,0 +1,91 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/padminisys/flintroute/internal/auth"
+	"github.com/padminisys/flintroute/internal/bgp"
+	"github.com/padminisys/flintroute/internal/frr"
+	"github.com/padminisys/flintroute/internal/frrlog"
+	"github.com/padminisys/flintroute/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOutOfBandChanges(t *testing.T) {
+	server := newMockedServer(t)
+	demo := frr.NewDemoClient(frr.DemoOptions{}, server.logger)
+	require.NoError(t, demo.Connect(context.Background()))
+	defer demo.Close()
+	client := server.frrLog.Wrap(demo)
+	ctx := context.Background()
+
+	alerts := func(t *testing.T) []models.Alert {
+		t.Helper()
+		var alerts []models.Alert
+		require.NoError(t, server.db.Where("type = ?", "config_drift").Order("id").Find(&alerts).Error)
+		return alerts
+	}
+
+	require.NoError(t, client.AddBGPPeer(ctx, &frr.BGPPeerConfig{IPAddress: "192.0.2.1", ASN: 65000, RemoteASN: 65001}))
+	server.detectOutOfBand(ctx, client)
+	require.Empty(t, alerts(t), "FlintRoute's own change")
+
+	require.NoError(t, demo.AddBGPPeer(ctx, &frr.BGPPeerConfig{IPAddress: "192.0.2.2", ASN: 65000, RemoteASN: 65002}))
+	running, err := demo.GetRunningConfig(ctx)
+	require.NoError(t, err)
+	server.detectOutOfBand(ctx, client)
+
+	transactions, err := server.frrLog.List(ctx, frrlog.Query{Operation: frrlog.OpOutOfBand})
+	require.NoError(t, err)
+	require.Len(t, transactions, 1)
+	outOfBand := transactions[0]
+
+	t.Run("Alerts with the diff", func(t *testing.T) {
+		raised := alerts(t)
+		require.Len(t, raised, 1)
+		assert.Equal(t, "warning", raised[0].Severity)
+		assert.Contains(t, raised[0].Details, "+  neighbor 192.0.2.2 remote-as 65002")
+		assert.Contains(t, raised[0].Details, fmt.Sprintf("POST /api/v1/frr/transactions/%d/backup", outOfBand.ID))
+
+		server.detectOutOfBand(ctx, client)
+		assert.Len(t, alerts(t), 1, "raised once")
+	})
+
+	t.Run("Backs up the change", func(t *testing.T) {
+		server.bgp.On("GetRunningConfig", mock.Anything).Return("router bgp 65000\n", nil).Once()
+		w := server.request(t, auth.RoleOperator, "POST", fmt.Sprintf("/api/v1/frr/transactions/%d/backup", outOfBand.ID), "")
+		assert.Equal(t, http.StatusConflict, w.Code)
+		assert.Contains(t, w.Body.String(), "CONFIG_CHANGED")
+
+		server.bgp.On("GetRunningConfig", mock.Anything).Return(running, nil)
+		server.bgp.On("GetGlobalConfig", mock.Anything).Return(nil, bgp.ErrGlobalConfigNotFound)
+		server.versions.On("Save", mock.Anything, mock.AnythingOfType("*models.ConfigVersion")).Run(func(args mock.Arguments) {
+			require.NoError(t, server.db.Create(args.Get(1)).Error)
+		}).Return(nil).Once()
+
+		w = server.request(t, auth.RoleOperator, "POST", fmt.Sprintf("/api/v1/frr/transactions/%d/backup", outOfBand.ID), "")
+		require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+		var version models.ConfigVersion
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &version))
+		assert.Equal(t, outOfBand.CommitID, version.Hash)
+		assert.Equal(t, []string{"out-of-band"}, version.Labels)
+	})
+
+	t.Run("Backs up only out-of-band changes", func(t *testing.T) {
+		transactions, err := server.frrLog.List(ctx, frrlog.Query{Operation: frrlog.OpPeerAdd})
+		require.NoError(t, err)
+		require.Len(t, transactions, 1)
+
+		w := server.request(t, auth.RoleOperator, "POST", fmt.Sprintf("/api/v1/frr/transactions/%d/backup", transactions[0].ID), "")
+		assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+
+		w = server.request(t, auth.RoleOperator, "POST", "/api/v1/frr/transactions/999/backup", "")
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+}
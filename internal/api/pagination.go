@@ -0,0 +1,46 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/padminisys/flintroute/internal/apierror"
+	"github.com/padminisys/flintroute/internal/repository"
+)
+
+// defaultPageLimit is the size of a page when the request doesn't give one
+const defaultPageLimit = 100
+
+// pageQuery parses the limit and cursor query parameters of a listing
+// paginated by cursor. It responds with an error and returns false when
+// either is invalid.
+func pageQuery(c *gin.Context) (repository.PageQuery, bool) {
+	page := repository.PageQuery{Limit: defaultPageLimit}
+	if raw := c.Query("limit"); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil || limit < 1 || limit > 1000 {
+			apierror.Respond(c, http.StatusBadRequest, apierror.CodeValidationFailed, "limit must be between 1 and 1000")
+			return page, false
+		}
+		page.Limit = limit
+	}
+	if raw := c.Query("cursor"); raw != "" {
+		cursor, err := repository.ParseCursor(raw)
+		if err != nil {
+			apierror.Respond(c, http.StatusBadRequest, apierror.CodeValidationFailed, "Invalid cursor")
+			return page, false
+		}
+		page.Cursor = cursor
+	}
+	return page, true
+}
+
+// withNextCursor adds the cursor of the next page to a page's response,
+// unless it is the last page
+func withNextCursor(resp gin.H, next *repository.Cursor) gin.H {
+	if next != nil {
+		resp["next_cursor"] = next.String()
+	}
+	return resp
+}
@@ -0,0 +1,222 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/padminisys/flintroute/internal/apierror"
+	authpkg "github.com/padminisys/flintroute/internal/auth"
+	"github.com/padminisys/flintroute/internal/jobs"
+	"github.com/padminisys/flintroute/pkg/models"
+	"go.uber.org/zap"
+)
+
+// SchedulePeerActionRequest represents a request to enable or shut down a
+// peer at a later time, such as the start or end of a maintenance window
+type SchedulePeerActionRequest struct {
+	Action string    `json:"action" binding:"required,oneof=enable shutdown"`
+	RunAt  time.Time `json:"run_at" binding:"required"`
+}
+
+// peerActionPayload holds the parameters of a scheduled peer action job
+type peerActionPayload struct {
+	PeerID uint   `json:"peer_id"`
+	Action string `json:"action"`
+}
+
+// ScheduledPeerAction is a scheduled peer action: the job that runs it and
+// the peer and action it runs
+type ScheduledPeerAction struct {
+	*models.Job
+	PeerID uint   `json:"peer_id"`
+	Action string `json:"action"`
+}
+
+// scheduledPeerAction decodes a peer action job
+func scheduledPeerAction(job *models.Job) (*ScheduledPeerAction, error) {
+	var payload peerActionPayload
+	if err := json.Unmarshal([]byte(job.Payload), &payload); err != nil {
+		return nil, fmt.Errorf("invalid job payload: %w", err)
+	}
+	return &ScheduledPeerAction{Job: job, PeerID: payload.PeerID, Action: payload.Action}, nil
+}
+
+// scheduledPeerActions returns the peer actions that have not run yet,
+// soonest first, for every peer or for peerID only
+func (s *Server) scheduledPeerActions(ctx context.Context, peerID *uint) ([]*ScheduledPeerAction, error) {
+	scheduled, err := s.jobs.Scheduled(ctx, JobPeerAction)
+	if err != nil {
+		return nil, err
+	}
+
+	actions := make([]*ScheduledPeerAction, 0, len(scheduled))
+	for _, job := range scheduled {
+		action, err := scheduledPeerAction(job)
+		if err != nil {
+			return nil, err
+		}
+		if peerID == nil || action.PeerID == *peerID {
+			actions = append(actions, action)
+		}
+	}
+	return actions, nil
+}
+
+// handleListScheduledPeerActions handles listing the scheduled actions of
+// every peer
+func (s *Server) handleListScheduledPeerActions(c *gin.Context) {
+	actions, err := s.scheduledPeerActions(c.Request.Context(), nil)
+	if err != nil {
+		s.logger.Error("Failed to list scheduled peer actions", zap.Error(err))
+		apierror.Respond(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to list scheduled peer actions")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"actions": actions})
+}
+
+// handleListPeerScheduledActions handles listing a peer's scheduled
+// actions
+func (s *Server) handleListPeerScheduledActions(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		apierror.Respond(c, http.StatusBadRequest, apierror.CodeInvalidID, "Invalid peer ID")
+		return
+	}
+
+	peer, err := s.bgpService.GetPeer(c.Request.Context(), uint(id))
+	if err != nil {
+		s.respondPeerError(c, err, "Failed to get peer")
+		return
+	}
+
+	actions, err := s.scheduledPeerActions(c.Request.Context(), &peer.ID)
+	if err != nil {
+		s.logger.Error("Failed to list scheduled peer actions", zap.Error(err))
+		apierror.Respond(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to list scheduled peer actions")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"actions": actions})
+}
+
+// handleSchedulePeerAction handles scheduling a peer to be enabled or shut
+// down at a later time
+func (s *Server) handleSchedulePeerAction(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		apierror.Respond(c, http.StatusBadRequest, apierror.CodeInvalidID, "Invalid peer ID")
+		return
+	}
+
+	var req SchedulePeerActionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apierror.Validation(c, err)
+		return
+	}
+	if !req.RunAt.After(time.Now()) {
+		apierror.Respond(c, http.StatusBadRequest, apierror.CodeValidationFailed, "run_at must be in the future")
+		return
+	}
+
+	peer, err := s.bgpService.GetPeer(c.Request.Context(), uint(id))
+	if err != nil {
+		s.respondPeerError(c, err, "Failed to get peer")
+		return
+	}
+
+	var createdBy *uint
+	if userID, ok := authpkg.GetUserID(c); ok {
+		createdBy = &userID
+	}
+
+	payload := peerActionPayload{PeerID: peer.ID, Action: req.Action}
+	job, err := s.jobs.Schedule(c.Request.Context(), JobPeerAction, payload, req.RunAt, createdBy)
+	if err != nil {
+		s.logger.Error("Failed to schedule peer action", zap.Error(err))
+		apierror.Respond(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to schedule peer action")
+		return
+	}
+
+	c.Header("Location", fmt.Sprintf("/api/v1/jobs/%d", job.ID))
+	c.JSON(http.StatusCreated, ScheduledPeerAction{Job: job, PeerID: peer.ID, Action: req.Action})
+}
+
+// handleCancelPeerAction handles cancelling a peer's scheduled action
+// before it runs
+func (s *Server) handleCancelPeerAction(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		apierror.Respond(c, http.StatusBadRequest, apierror.CodeInvalidID, "Invalid peer ID")
+		return
+	}
+	jobID, err := strconv.ParseUint(c.Param("job_id"), 10, 32)
+	if err != nil {
+		apierror.Respond(c, http.StatusBadRequest, apierror.CodeInvalidID, "Invalid job ID")
+		return
+	}
+
+	ctx := c.Request.Context()
+	job, err := s.jobs.Get(ctx, uint(jobID))
+	if err != nil && !errors.Is(err, jobs.ErrJobNotFound) {
+		s.logger.Error("Failed to get scheduled peer action", zap.Error(err))
+		apierror.Respond(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to cancel peer action")
+		return
+	}
+	// Only the peer's own scheduled actions can be cancelled here
+	var action *ScheduledPeerAction
+	if job != nil && job.Type == JobPeerAction {
+		action, _ = scheduledPeerAction(job)
+	}
+	if action == nil || action.PeerID != uint(id) {
+		apierror.Respond(c, http.StatusNotFound, apierror.CodeJobNotFound, "Scheduled action not found")
+		return
+	}
+
+	cancelled, err := s.jobs.Cancel(ctx, job.ID)
+	if err != nil {
+		if errors.Is(err, jobs.ErrJobNotCancellable) {
+			apierror.Respond(c, http.StatusConflict, apierror.CodeJobNotCancellable,
+				fmt.Sprintf("Scheduled action is %s and can no longer be cancelled", job.Status))
+			return
+		}
+		s.logger.Error("Failed to cancel peer action", zap.Error(err))
+		apierror.Respond(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to cancel peer action")
+		return
+	}
+
+	action.Job = cancelled
+	c.JSON(http.StatusOK, action)
+}
+
+// runPeerAction enables or shuts down a peer as scheduled. Like changes
+// made through the API, it is refused in read-only mode.
+func (s *Server) runPeerAction(ctx context.Context, job *models.Job, progress jobs.Progress) (interface{}, error) {
+	action, err := scheduledPeerAction(job)
+	if err != nil {
+		return nil, err
+	}
+	if s.readOnly != nil && s.readOnly.Enabled() {
+		return nil, errors.New("FlintRoute is in read-only mode")
+	}
+
+	progress(10, fmt.Sprintf("Running %s on peer %d", action.Action, action.PeerID))
+	return s.bgpService.RunPeerAction(ctx, action.PeerID, action.Action)
+}
+
+// announcePeerAction alerts that a scheduled peer action is about to run
+func (s *Server) announcePeerAction(ctx context.Context, job *models.Job) {
+	action, err := scheduledPeerAction(job)
+	if err != nil {
+		s.logger.Error("Failed to announce scheduled peer action", zap.Uint("job_id", job.ID), zap.Error(err))
+		return
+	}
+
+	s.bgpService.AnnouncePeerAction(ctx, action.PeerID, action.Action, *job.RunAt)
+}
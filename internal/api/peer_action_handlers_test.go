@@ -0,0 +1,122 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/padminisys/flintroute/internal/bgp"
+	"github.com/padminisys/flintroute/internal/frr"
+	"github.com/padminisys/flintroute/internal/jobs"
+	"github.com/padminisys/flintroute/internal/websocket"
+	"github.com/padminisys/flintroute/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandleScheduledPeerActions(t *testing.T) {
+	ctx := context.Background()
+	server, db := setupTestServer(t)
+	server.wsHub = websocket.NewHub(server.logger)
+	go server.wsHub.Run()
+	client := frr.NewMockClient()
+	client.On("AddBGPPeer", mock.Anything, mock.Anything).Return(nil)
+	client.On("UpdateBGPPeer", mock.Anything, mock.Anything).Return(nil)
+	server.bgpService = bgp.NewService(server.db, client, server.wsHub, bgp.ServiceConfig{}, server.logger)
+	server.jobs = jobs.NewQueue(server.db, server.wsHub, 1, server.logger)
+	server.announceBefore = 15 * time.Minute
+	server.registerJobs()
+
+	peer := &models.BGPPeer{Name: "acme", IPAddress: "192.0.2.10", ASN: 65000, RemoteASN: 64510, Enabled: true}
+	require.NoError(t, server.bgpService.CreatePeer(ctx, peer))
+
+	router := gin.New()
+	router.Use(func(c *gin.Context) { c.Set("user_id", uint(1)) })
+	router.GET("/bgp/peers/schedule", server.handleListScheduledPeerActions)
+	router.GET("/bgp/peers/:id/schedule", server.handleListPeerScheduledActions)
+	router.POST("/bgp/peers/:id/schedule", server.handleSchedulePeerAction)
+	router.DELETE("/bgp/peers/:id/schedule/:job_id", server.handleCancelPeerAction)
+
+	schedule := func(action string, runAt time.Time) ScheduledPeerAction {
+		t.Helper()
+		w := profileRequest(router, "POST", fmt.Sprintf("/bgp/peers/%d/schedule", peer.ID),
+			fmt.Sprintf(`{"action": %q, "run_at": %q}`, action, runAt.Format(time.RFC3339)))
+		require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+		var scheduled ScheduledPeerAction
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &scheduled))
+		return scheduled
+	}
+
+	t.Run("Reject invalid schedules", func(t *testing.T) {
+		w := profileRequest(router, "POST", fmt.Sprintf("/bgp/peers/%d/schedule", peer.ID),
+			fmt.Sprintf(`{"action": "shutdown", "run_at": %q}`, time.Now().Add(-time.Hour).Format(time.RFC3339)))
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+
+		w = profileRequest(router, "POST", fmt.Sprintf("/bgp/peers/%d/schedule", peer.ID),
+			fmt.Sprintf(`{"action": "restart", "run_at": %q}`, time.Now().Add(time.Hour).Format(time.RFC3339)))
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+
+		w = profileRequest(router, "POST", "/bgp/peers/999/schedule",
+			fmt.Sprintf(`{"action": "shutdown", "run_at": %q}`, time.Now().Add(time.Hour).Format(time.RFC3339)))
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("Schedule, list and cancel actions", func(t *testing.T) {
+		enable := schedule(bgp.PeerActionEnable, time.Now().Add(2*time.Hour))
+		assert.Equal(t, models.JobQueued, enable.Status)
+		assert.Equal(t, peer.ID, enable.PeerID)
+
+		w := profileRequest(router, "GET", "/bgp/peers/schedule", "")
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), `"action":"enable"`)
+
+		w = profileRequest(router, "DELETE", fmt.Sprintf("/bgp/peers/%d/schedule/%d", peer.ID, enable.ID), "")
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		assert.Contains(t, w.Body.String(), `"status":"cancelled"`)
+
+		w = profileRequest(router, "DELETE", fmt.Sprintf("/bgp/peers/%d/schedule/%d", peer.ID, enable.ID), "")
+		assert.Equal(t, http.StatusConflict, w.Code)
+		assert.Contains(t, w.Body.String(), "JOB_NOT_CANCELLABLE")
+
+		w = profileRequest(router, "DELETE", fmt.Sprintf("/bgp/peers/%d/schedule/999", peer.ID), "")
+		assert.Equal(t, http.StatusNotFound, w.Code)
+
+		w = profileRequest(router, "GET", fmt.Sprintf("/bgp/peers/%d/schedule", peer.ID), "")
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.JSONEq(t, `{"actions": []}`, w.Body.String())
+	})
+
+	t.Run("Announce and run due actions", func(t *testing.T) {
+		shutdown := schedule(bgp.PeerActionShutdown, time.Now().Add(time.Minute))
+
+		// Bring the action forward rather than waiting for it
+		require.NoError(t, db.Model(&models.Job{}).Where("id = ?", shutdown.ID).
+			Update("run_at", time.Now().Add(-time.Second)).Error)
+		runCtx, cancel := context.WithCancel(ctx)
+		go server.jobs.Start(runCtx, 10*time.Millisecond)
+		defer cancel()
+
+		require.Eventually(t, func() bool {
+			job, err := server.jobs.Get(ctx, shutdown.ID)
+			return err == nil && job.Done()
+		}, 5*time.Second, 20*time.Millisecond)
+
+		job, err := server.jobs.Get(ctx, shutdown.ID)
+		require.NoError(t, err)
+		assert.Equal(t, models.JobSucceeded, job.Status, job.Error)
+		assert.NotNil(t, job.AnnouncedAt)
+
+		stored, err := server.bgpService.GetPeer(ctx, peer.ID)
+		require.NoError(t, err)
+		assert.False(t, stored.Enabled)
+
+		var alert models.Alert
+		require.NoError(t, db.Where("type = ?", "peer_action_scheduled").First(&alert).Error)
+		assert.Contains(t, alert.Message, "will be shut down")
+	})
+}
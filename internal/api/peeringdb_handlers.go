@@ -0,0 +1,71 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/padminisys/flintroute/internal/apierror"
+	"github.com/padminisys/flintroute/internal/peeringdb"
+	"github.com/padminisys/flintroute/pkg/models"
+	"go.uber.org/zap"
+)
+
+// handleLookupPeeringDB handles looking up an ASN in PeeringDB to pre-fill
+// a peer: its contacts, recommended prefix limits and IXP LAN addresses
+func (s *Server) handleLookupPeeringDB(c *gin.Context) {
+	asn, err := strconv.ParseUint(c.Param("asn"), 10, 32)
+	if err != nil || asn == 0 {
+		apierror.Respond(c, http.StatusBadRequest, apierror.CodeValidationFailed, "Invalid ASN")
+		return
+	}
+
+	if s.peeringDB == nil {
+		apierror.Respond(c, http.StatusServiceUnavailable, apierror.CodePeeringDBDown, "PeeringDB lookups are disabled")
+		return
+	}
+
+	network, err := s.peeringDB.Lookup(c.Request.Context(), uint32(asn))
+	if err != nil {
+		s.respondPeeringDBError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, network)
+}
+
+// validatePeerASN checks a new peer's remote ASN and address against
+// PeeringDB. It responds with an error and returns false when they don't
+// match or PeeringDB can't be queried.
+func (s *Server) validatePeerASN(c *gin.Context, peer *models.BGPPeer) bool {
+	if s.peeringDB == nil {
+		apierror.Respond(c, http.StatusServiceUnavailable, apierror.CodePeeringDBDown, "PeeringDB lookups are disabled")
+		return false
+	}
+
+	if err := s.peeringDB.Validate(c.Request.Context(), peer.RemoteASN, peer.IPAddress); err != nil {
+		s.respondPeeringDBError(c, err)
+		return false
+	}
+	return true
+}
+
+// respondPeeringDBError maps a PeeringDB client error to an API error
+func (s *Server) respondPeeringDBError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, peeringdb.ErrNotFound):
+		apierror.Respond(c, http.StatusNotFound, apierror.CodeASNNotFound, err.Error())
+		return
+	case errors.Is(err, peeringdb.ErrMismatch):
+		apierror.Respond(c, http.StatusUnprocessableEntity, apierror.CodePeeringDBMismatch, err.Error())
+		return
+	case errors.Is(err, peeringdb.ErrUnavailable):
+		s.logger.Warn("PeeringDB query failed", zap.Error(err))
+		apierror.Respond(c, http.StatusBadGateway, apierror.CodePeeringDBDown, "PeeringDB is unavailable")
+		return
+	}
+
+	s.logger.Error("Failed to query PeeringDB", zap.Error(err))
+	apierror.Respond(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to query PeeringDB")
+}
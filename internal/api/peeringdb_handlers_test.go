@@ -0,0 +1,75 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/padminisys/flintroute/internal/bgp"
+	"github.com/padminisys/flintroute/internal/frr"
+	"github.com/padminisys/flintroute/internal/peeringdb"
+	"github.com/padminisys/flintroute/internal/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandlePeeringDB(t *testing.T) {
+	server, _ := setupTestServer(t)
+	server.bgpService = bgp.NewService(server.db, frr.NewMockClient(), websocket.NewHub(server.logger), bgp.ServiceConfig{}, server.logger)
+
+	router := gin.New()
+	router.GET("/peeringdb/asn/:asn", server.handleLookupPeeringDB)
+	router.POST("/bgp/peers", server.handleCreatePeer)
+
+	t.Run("Lookups are disabled without PeeringDB", func(t *testing.T) {
+		w := profileRequest(router, "GET", "/peeringdb/asn/64510", "")
+		assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+		assert.Contains(t, w.Body.String(), "PEERINGDB_UNAVAILABLE")
+	})
+
+	peeringDB := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/net" && r.URL.Query().Get("asn") == "64510":
+			w.Write([]byte(`{"data": [{"asn": 64510, "name": "Example Networks", "info_prefixes4": 1200, "info_prefixes6": 300,
+				"netixlan_set": [{"asn": 64510, "ix_id": 31, "name": "DE-CIX Frankfurt", "ipaddr4": "80.81.192.10"}]}]}`))
+		case r.URL.Path == "/netixlan" && r.URL.Query().Get("ipaddr4") == "80.81.192.20":
+			w.Write([]byte(`{"data": [{"asn": 64520, "name": "DE-CIX Frankfurt"}]}`))
+		default:
+			w.Write([]byte(`{"data": []}`))
+		}
+	}))
+	defer peeringDB.Close()
+	server.peeringDB = peeringdb.NewClient(peeringDB.URL, "", 5*time.Second, time.Hour, server.logger)
+
+	t.Run("Look up an ASN", func(t *testing.T) {
+		w := profileRequest(router, "GET", "/peeringdb/asn/64510", "")
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		assert.Contains(t, w.Body.String(), `"max_prefixes_ipv4":1200`)
+		assert.Contains(t, w.Body.String(), `"ipv4":"80.81.192.10"`)
+
+		w = profileRequest(router, "GET", "/peeringdb/asn/64999", "")
+		assert.Equal(t, http.StatusNotFound, w.Code)
+		assert.Contains(t, w.Body.String(), "ASN_NOT_FOUND")
+
+		w = profileRequest(router, "GET", "/peeringdb/asn/abc", "")
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("Validate new peers against PeeringDB", func(t *testing.T) {
+		w := profileRequest(router, "POST", "/bgp/peers?validate_asn=true",
+			`{"name": "wrong", "ip_address": "80.81.192.20", "asn": 65000, "remote_asn": 64510}`)
+		assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+		assert.Contains(t, w.Body.String(), "AS64520")
+
+		w = profileRequest(router, "POST", "/bgp/peers?validate_asn=true",
+			`{"name": "unknown", "ip_address": "192.0.2.1", "asn": 65000, "remote_asn": 64999}`)
+		assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+		assert.Contains(t, w.Body.String(), "PEERINGDB_MISMATCH")
+
+		w = profileRequest(router, "POST", "/bgp/peers?validate_asn=true",
+			`{"name": "example", "ip_address": "80.81.192.10", "asn": 65000, "remote_asn": 64510}`)
+		assert.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	})
+}
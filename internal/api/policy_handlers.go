@@ -0,0 +1,245 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/padminisys/flintroute/internal/apierror"
+	"github.com/padminisys/flintroute/internal/bgp"
+	"github.com/padminisys/flintroute/internal/frr"
+	"github.com/padminisys/flintroute/pkg/models"
+	"go.uber.org/zap"
+)
+
+// CreateCommunityListRequest represents a request to create a community-list
+type CreateCommunityListRequest struct {
+	Name string `json:"name" binding:"required"`
+	UpdateCommunityListRequest
+}
+
+// UpdateCommunityListRequest represents a request to replace a
+// community-list's entries. Type defaults to standard.
+type UpdateCommunityListRequest struct {
+	Type    string                      `json:"type"`
+	Entries []models.CommunityListEntry `json:"entries" binding:"required,min=1"`
+}
+
+// list returns the community-list described by the request
+func (r *UpdateCommunityListRequest) list(name string) *models.CommunityList {
+	listType := r.Type
+	if listType == "" {
+		listType = models.CommunityListStandard
+	}
+	return &models.CommunityList{Name: name, Type: listType, Entries: r.Entries}
+}
+
+// CreateASPathListRequest represents a request to create an as-path
+// access-list
+type CreateASPathListRequest struct {
+	Name string `json:"name" binding:"required"`
+	UpdateASPathListRequest
+}
+
+// UpdateASPathListRequest represents a request to replace an as-path
+// access-list's entries
+type UpdateASPathListRequest struct {
+	Entries []models.ASPathListEntry `json:"entries" binding:"required,min=1"`
+}
+
+// handleListCommunityLists handles listing all community-lists
+func (s *Server) handleListCommunityLists(c *gin.Context) {
+	lists, err := s.bgpService.ListCommunityLists(c.Request.Context())
+	if err != nil {
+		s.respondPolicyError(c, err, "Failed to list community-lists")
+		return
+	}
+
+	respondJSONWithETag(c, gin.H{"community_lists": lists})
+}
+
+// handleGetCommunityList handles getting a community-list by name
+func (s *Server) handleGetCommunityList(c *gin.Context) {
+	list, err := s.bgpService.GetCommunityList(c.Request.Context(), c.Param("name"))
+	if err != nil {
+		s.respondPolicyError(c, err, "Failed to get community-list")
+		return
+	}
+
+	c.JSON(http.StatusOK, list)
+}
+
+// handleCreateCommunityList handles creating a community-list
+func (s *Server) handleCreateCommunityList(c *gin.Context) {
+	var req CreateCommunityListRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apierror.Validation(c, err)
+		return
+	}
+
+	ctx := c.Request.Context()
+	if _, err := s.bgpService.GetCommunityList(ctx, req.Name); err == nil {
+		apierror.Respond(c, http.StatusConflict, apierror.CodePolicyExists, "Community-list already exists")
+		return
+	} else if !errors.Is(err, bgp.ErrPolicyNotFound) {
+		s.respondPolicyError(c, err, "Failed to create community-list")
+		return
+	}
+
+	list := req.list(req.Name)
+	if err := s.bgpService.SaveCommunityList(ctx, list); err != nil {
+		s.respondPolicyError(c, err, "Failed to create community-list")
+		return
+	}
+
+	c.JSON(http.StatusCreated, list)
+}
+
+// handleUpdateCommunityList handles replacing a community-list. Lists
+// managed by GitOps stay managed by it.
+func (s *Server) handleUpdateCommunityList(c *gin.Context) {
+	var req UpdateCommunityListRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apierror.Validation(c, err)
+		return
+	}
+
+	ctx := c.Request.Context()
+	existing, err := s.bgpService.GetCommunityList(ctx, c.Param("name"))
+	if err != nil {
+		s.respondPolicyError(c, err, "Failed to update community-list")
+		return
+	}
+
+	list := req.list(existing.Name)
+	list.ManagedBy = existing.ManagedBy
+	if err := s.bgpService.SaveCommunityList(ctx, list); err != nil {
+		s.respondPolicyError(c, err, "Failed to update community-list")
+		return
+	}
+
+	c.JSON(http.StatusOK, list)
+}
+
+// handleDeleteCommunityList handles deleting a community-list
+func (s *Server) handleDeleteCommunityList(c *gin.Context) {
+	if err := s.bgpService.DeleteCommunityList(c.Request.Context(), c.Param("name")); err != nil {
+		s.respondPolicyError(c, err, "Failed to delete community-list")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Community-list deleted successfully"})
+}
+
+// handleListASPathLists handles listing all as-path access-lists
+func (s *Server) handleListASPathLists(c *gin.Context) {
+	lists, err := s.bgpService.ListASPathLists(c.Request.Context())
+	if err != nil {
+		s.respondPolicyError(c, err, "Failed to list as-path-lists")
+		return
+	}
+
+	respondJSONWithETag(c, gin.H{"as_path_lists": lists})
+}
+
+// handleGetASPathList handles getting an as-path access-list by name
+func (s *Server) handleGetASPathList(c *gin.Context) {
+	list, err := s.bgpService.GetASPathList(c.Request.Context(), c.Param("name"))
+	if err != nil {
+		s.respondPolicyError(c, err, "Failed to get as-path-list")
+		return
+	}
+
+	c.JSON(http.StatusOK, list)
+}
+
+// handleCreateASPathList handles creating an as-path access-list
+func (s *Server) handleCreateASPathList(c *gin.Context) {
+	var req CreateASPathListRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apierror.Validation(c, err)
+		return
+	}
+
+	ctx := c.Request.Context()
+	if _, err := s.bgpService.GetASPathList(ctx, req.Name); err == nil {
+		apierror.Respond(c, http.StatusConflict, apierror.CodePolicyExists, "As-path-list already exists")
+		return
+	} else if !errors.Is(err, bgp.ErrPolicyNotFound) {
+		s.respondPolicyError(c, err, "Failed to create as-path-list")
+		return
+	}
+
+	list := &models.ASPathList{Name: req.Name, Entries: req.Entries}
+	if err := s.bgpService.SaveASPathList(ctx, list); err != nil {
+		s.respondPolicyError(c, err, "Failed to create as-path-list")
+		return
+	}
+
+	c.JSON(http.StatusCreated, list)
+}
+
+// handleUpdateASPathList handles replacing an as-path access-list. Lists
+// managed by GitOps stay managed by it.
+func (s *Server) handleUpdateASPathList(c *gin.Context) {
+	var req UpdateASPathListRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apierror.Validation(c, err)
+		return
+	}
+
+	ctx := c.Request.Context()
+	existing, err := s.bgpService.GetASPathList(ctx, c.Param("name"))
+	if err != nil {
+		s.respondPolicyError(c, err, "Failed to update as-path-list")
+		return
+	}
+
+	list := &models.ASPathList{Name: existing.Name, Entries: req.Entries, ManagedBy: existing.ManagedBy}
+	if err := s.bgpService.SaveASPathList(ctx, list); err != nil {
+		s.respondPolicyError(c, err, "Failed to update as-path-list")
+		return
+	}
+
+	c.JSON(http.StatusOK, list)
+}
+
+// handleDeleteASPathList handles deleting an as-path access-list
+func (s *Server) handleDeleteASPathList(c *gin.Context) {
+	if err := s.bgpService.DeleteASPathList(c.Request.Context(), c.Param("name")); err != nil {
+		s.respondPolicyError(c, err, "Failed to delete as-path-list")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "As-path-list deleted successfully"})
+}
+
+// respondPolicyError maps a BGP service error for a policy operation to an
+// API error
+func (s *Server) respondPolicyError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, bgp.ErrPolicyNotFound):
+		apierror.Respond(c, http.StatusNotFound, apierror.CodePolicyNotFound, "Policy not found")
+		return
+	case errors.Is(err, bgp.ErrInvalidPolicy):
+		apierror.Respond(c, http.StatusBadRequest, apierror.CodeValidationFailed, err.Error())
+		return
+	case errors.Is(err, bgp.ErrPolicyInUse):
+		apierror.Respond(c, http.StatusConflict, apierror.CodePolicyInUse, err.Error())
+		return
+	case respondFRRRejection(c, err):
+		return
+	}
+
+	s.logger.Error(message, zap.Error(err))
+	if errors.Is(err, bgp.ErrFRRApplyFailed) {
+		code := apierror.CodeFRRApplyFailed
+		if errors.Is(err, frr.ErrNotConnected) {
+			code = apierror.CodeFRRUnavailable
+		}
+		apierror.Respond(c, http.StatusBadGateway, code, message+": FRR rejected the change")
+		return
+	}
+
+	apierror.Respond(c, http.StatusInternalServerError, apierror.CodeInternal, message)
+}
@@ -0,0 +1,270 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/padminisys/flintroute/internal/apierror"
+	authpkg "github.com/padminisys/flintroute/internal/auth"
+	"github.com/padminisys/flintroute/pkg/models"
+	"go.uber.org/zap"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// maxPreferencesSize limits the stored preferences document
+const maxPreferencesSize = 64 << 10
+
+// UpdateProfileRequest represents a request to update the current user's
+// profile
+type UpdateProfileRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+// ChangePasswordRequest represents a request to change the current user's
+// password
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password" binding:"required"`
+	NewPassword     string `json:"new_password" binding:"required,min=8"`
+}
+
+// LoginSession describes one of the current user's active refresh tokens
+type LoginSession struct {
+	ID        uint      `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+	UserAgent string    `json:"user_agent,omitempty"`
+	ClientIP  string    `json:"client_ip,omitempty"`
+}
+
+// currentUser loads the authenticated user. It responds with an error and
+// returns nil when the user is unknown.
+func (s *Server) currentUser(c *gin.Context) *models.User {
+	userID, exists := authpkg.GetUserID(c)
+	if !exists {
+		apierror.Respond(c, http.StatusUnauthorized, apierror.CodeUnauthorized, "User not authenticated")
+		return nil
+	}
+
+	user, err := s.repos.Users.GetByID(c.Request.Context(), userID)
+	if err != nil {
+		apierror.Respond(c, http.StatusUnauthorized, apierror.CodeUnauthorized, "User not found")
+		return nil
+	}
+	return user
+}
+
+// handleGetProfile handles getting the current user's profile
+func (s *Server) handleGetProfile(c *gin.Context) {
+	user := s.currentUser(c)
+	if user == nil {
+		return
+	}
+
+	c.JSON(http.StatusOK, user)
+}
+
+// handleUpdateProfile handles updating the current user's profile. The
+// username and role are managed by administrators.
+func (s *Server) handleUpdateProfile(c *gin.Context) {
+	var req UpdateProfileRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apierror.Validation(c, err)
+		return
+	}
+
+	user := s.currentUser(c)
+	if user == nil {
+		return
+	}
+
+	taken, err := s.repos.Users.EmailTaken(c.Request.Context(), req.Email, user.ID)
+	if err != nil {
+		s.logger.Error("Failed to check email", zap.Error(err))
+		apierror.Respond(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to update profile")
+		return
+	}
+	if taken {
+		apierror.Respond(c, http.StatusConflict, apierror.CodeEmailInUse, "Email is already in use")
+		return
+	}
+
+	user.Email = req.Email
+	if err := s.repos.Users.Update(c.Request.Context(), user, "email", user.Email); err != nil {
+		s.logger.Error("Failed to update profile", zap.Error(err))
+		apierror.Respond(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to update profile")
+		return
+	}
+
+	s.logger.Info("User updated profile", zap.String("username", user.Username))
+
+	c.JSON(http.StatusOK, user)
+}
+
+// handleChangePassword handles changing the current user's password. All
+// of the user's refresh tokens are revoked, so other sessions have to log in
+// again once their access token expires.
+func (s *Server) handleChangePassword(c *gin.Context) {
+	if _, impersonating := authpkg.GetImpersonator(c); impersonating {
+		apierror.Respond(c, http.StatusForbidden, apierror.CodeForbidden, "Passwords cannot be changed while impersonating")
+		return
+	}
+
+	var req ChangePasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apierror.Validation(c, err)
+		return
+	}
+
+	user := s.currentUser(c)
+	if user == nil {
+		return
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(req.CurrentPassword)); err != nil {
+		apierror.Respond(c, http.StatusBadRequest, apierror.CodeInvalidCredentials, "Current password is incorrect")
+		return
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(req.NewPassword), bcrypt.DefaultCost)
+	if err != nil {
+		s.logger.Error("Failed to hash password", zap.Error(err))
+		apierror.Respond(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to change password")
+		return
+	}
+
+	if err := s.repos.Users.Update(c.Request.Context(), user, "password_hash", string(hash)); err != nil {
+		s.logger.Error("Failed to change password", zap.Error(err))
+		apierror.Respond(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to change password")
+		return
+	}
+
+	if err := s.db.Model(&models.RefreshToken{}).
+		Where("user_id = ? AND revoked = ?", user.ID, false).
+		Update("revoked", true).Error; err != nil {
+		s.logger.Error("Failed to revoke tokens", zap.Error(err))
+	}
+
+	s.logger.Info("User changed password", zap.String("username", user.Username))
+
+	c.JSON(http.StatusOK, gin.H{"message": "Password changed"})
+}
+
+// handleListLoginSessions handles listing the current user's active
+// sessions
+func (s *Server) handleListLoginSessions(c *gin.Context) {
+	user := s.currentUser(c)
+	if user == nil {
+		return
+	}
+
+	var tokens []models.RefreshToken
+	if err := s.db.Where("user_id = ? AND revoked = ? AND expires_at > ?", user.ID, false, time.Now()).
+		Order("created_at DESC").Find(&tokens).Error; err != nil {
+		s.logger.Error("Failed to list sessions", zap.Error(err))
+		apierror.Respond(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to list sessions")
+		return
+	}
+
+	sessions := make([]LoginSession, 0, len(tokens))
+	for _, token := range tokens {
+		sessions = append(sessions, LoginSession{
+			ID:        token.ID,
+			CreatedAt: token.CreatedAt,
+			ExpiresAt: token.ExpiresAt,
+			UserAgent: token.UserAgent,
+			ClientIP:  token.ClientIP,
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{"sessions": sessions})
+}
+
+// handleRevokeLoginSession handles revoking one of the current user's
+// sessions
+func (s *Server) handleRevokeLoginSession(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		apierror.Respond(c, http.StatusBadRequest, apierror.CodeInvalidID, "Invalid session ID")
+		return
+	}
+
+	user := s.currentUser(c)
+	if user == nil {
+		return
+	}
+
+	result := s.db.Model(&models.RefreshToken{}).
+		Where("id = ? AND user_id = ? AND revoked = ?", id, user.ID, false).
+		Update("revoked", true)
+	if result.Error != nil {
+		s.logger.Error("Failed to revoke session", zap.Error(result.Error))
+		apierror.Respond(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to revoke session")
+		return
+	}
+	if result.RowsAffected == 0 {
+		apierror.Respond(c, http.StatusNotFound, apierror.CodeNotFound, "Session not found")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Session revoked"})
+}
+
+// handleGetPreferences handles getting the current user's preferences
+func (s *Server) handleGetPreferences(c *gin.Context) {
+	user := s.currentUser(c)
+	if user == nil {
+		return
+	}
+
+	preferences := user.Preferences
+	if len(preferences) == 0 {
+		preferences = json.RawMessage("{}")
+	}
+
+	c.Data(http.StatusOK, "application/json; charset=utf-8", preferences)
+}
+
+// handleUpdatePreferences handles replacing the current user's preferences.
+// The body can be any JSON object; FlintRoute stores it as-is for clients
+// such as the web UI.
+func (s *Server) handleUpdatePreferences(c *gin.Context) {
+	user := s.currentUser(c)
+	if user == nil {
+		return
+	}
+
+	body, err := c.GetRawData()
+	if err != nil {
+		apierror.Respond(c, http.StatusBadRequest, apierror.CodeValidationFailed, "Failed to read request body")
+		return
+	}
+	if len(body) > maxPreferencesSize {
+		apierror.Respond(c, http.StatusBadRequest, apierror.CodeValidationFailed, "Preferences must be at most 64 KiB")
+		return
+	}
+
+	var object map[string]json.RawMessage
+	if err := json.Unmarshal(body, &object); err != nil || object == nil {
+		apierror.Respond(c, http.StatusBadRequest, apierror.CodeValidationFailed, "Preferences must be a JSON object")
+		return
+	}
+
+	var compact bytes.Buffer
+	if err := json.Compact(&compact, body); err != nil {
+		apierror.Respond(c, http.StatusBadRequest, apierror.CodeValidationFailed, "Preferences must be a JSON object")
+		return
+	}
+
+	user.Preferences = compact.Bytes()
+	if err := s.repos.Users.Update(c.Request.Context(), user, "preferences", user.Preferences); err != nil {
+		s.logger.Error("Failed to update preferences", zap.Error(err))
+		apierror.Respond(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to update preferences")
+		return
+	}
+
+	c.Data(http.StatusOK, "application/json; charset=utf-8", user.Preferences)
+}
@@ -0,0 +1,165 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/padminisys/flintroute/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+func setupProfileRouter(t *testing.T) (*gin.Engine, *gorm.DB, *models.User) {
+	server, db := setupTestServer(t)
+
+	hash, err := bcrypt.GenerateFromPassword([]byte("oldpassword"), bcrypt.MinCost)
+	require.NoError(t, err)
+	user := &models.User{Username: "alice", PasswordHash: string(hash), Email: "alice@example.com", Role: "user", Active: true}
+	require.NoError(t, db.Create(user).Error)
+	require.NoError(t, db.Create(&models.User{Username: "bob", PasswordHash: string(hash), Email: "bob@example.com"}).Error)
+
+	router := gin.New()
+	router.Use(func(c *gin.Context) { c.Set("user_id", user.ID) })
+	router.GET("/me", server.handleGetProfile)
+	router.PUT("/me", server.handleUpdateProfile)
+	router.PUT("/me/password", server.handleChangePassword)
+	router.GET("/me/sessions", server.handleListLoginSessions)
+	router.DELETE("/me/sessions/:id", server.handleRevokeLoginSession)
+	router.GET("/me/preferences", server.handleGetPreferences)
+	router.PUT("/me/preferences", server.handleUpdatePreferences)
+
+	return router, db, user
+}
+
+func profileRequest(router *gin.Engine, method, path, body string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest(method, path, bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestHandleProfile(t *testing.T) {
+	router, _, user := setupProfileRouter(t)
+
+	t.Run("Get profile", func(t *testing.T) {
+		w := profileRequest(router, "GET", "/me", "")
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), `"username":"alice"`)
+		assert.NotContains(t, w.Body.String(), "password")
+	})
+
+	t.Run("Update email", func(t *testing.T) {
+		w := profileRequest(router, "PUT", "/me", `{"email":"alice@example.org"}`)
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var updated models.User
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &updated))
+		assert.Equal(t, user.ID, updated.ID)
+		assert.Equal(t, "alice@example.org", updated.Email)
+	})
+
+	t.Run("Reject email in use", func(t *testing.T) {
+		w := profileRequest(router, "PUT", "/me", `{"email":"bob@example.com"}`)
+		assert.Equal(t, http.StatusConflict, w.Code)
+		assert.Contains(t, w.Body.String(), "EMAIL_IN_USE")
+	})
+
+	t.Run("Reject invalid email", func(t *testing.T) {
+		w := profileRequest(router, "PUT", "/me", `{"email":"not-an-email"}`)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
+
+func TestHandleChangePassword(t *testing.T) {
+	router, db, user := setupProfileRouter(t)
+	require.NoError(t, db.Create(&models.RefreshToken{UserID: user.ID, Token: "t1", ExpiresAt: time.Now().Add(time.Hour)}).Error)
+
+	t.Run("Reject wrong current password", func(t *testing.T) {
+		w := profileRequest(router, "PUT", "/me/password", `{"current_password":"wrong","new_password":"newpassword"}`)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, w.Body.String(), "INVALID_CREDENTIALS")
+	})
+
+	t.Run("Reject short new password", func(t *testing.T) {
+		w := profileRequest(router, "PUT", "/me/password", `{"current_password":"oldpassword","new_password":"short"}`)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("Change password and revoke sessions", func(t *testing.T) {
+		w := profileRequest(router, "PUT", "/me/password", `{"current_password":"oldpassword","new_password":"newpassword"}`)
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var stored models.User
+		require.NoError(t, db.First(&stored, user.ID).Error)
+		assert.NoError(t, bcrypt.CompareHashAndPassword([]byte(stored.PasswordHash), []byte("newpassword")))
+
+		var active int64
+		require.NoError(t, db.Model(&models.RefreshToken{}).Where("revoked = ?", false).Count(&active).Error)
+		assert.Zero(t, active)
+	})
+}
+
+func TestHandleLoginSessions(t *testing.T) {
+	router, db, user := setupProfileRouter(t)
+
+	active := &models.RefreshToken{UserID: user.ID, Token: "active", ExpiresAt: time.Now().Add(time.Hour), UserAgent: "curl/8.0", ClientIP: "192.0.2.10"}
+	require.NoError(t, db.Create(active).Error)
+	require.NoError(t, db.Create(&models.RefreshToken{UserID: user.ID, Token: "expired", ExpiresAt: time.Now().Add(-time.Hour)}).Error)
+	require.NoError(t, db.Create(&models.RefreshToken{UserID: user.ID, Token: "revoked", ExpiresAt: time.Now().Add(time.Hour), Revoked: true}).Error)
+	other := &models.RefreshToken{UserID: user.ID + 1, Token: "other", ExpiresAt: time.Now().Add(time.Hour)}
+	require.NoError(t, db.Create(other).Error)
+
+	w := profileRequest(router, "GET", "/me/sessions", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.NotContains(t, w.Body.String(), `"active"`)
+	var resp struct {
+		Sessions []LoginSession `json:"sessions"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	require.Len(t, resp.Sessions, 1)
+	assert.Equal(t, active.ID, resp.Sessions[0].ID)
+	assert.Equal(t, "curl/8.0", resp.Sessions[0].UserAgent)
+
+	t.Run("Cannot revoke another user's session", func(t *testing.T) {
+		w := profileRequest(router, "DELETE", "/me/sessions/"+strconv.FormatUint(uint64(other.ID), 10), "")
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("Revoke session", func(t *testing.T) {
+		w := profileRequest(router, "DELETE", "/me/sessions/"+strconv.FormatUint(uint64(active.ID), 10), "")
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var stored models.RefreshToken
+		require.NoError(t, db.First(&stored, active.ID).Error)
+		assert.True(t, stored.Revoked)
+	})
+}
+
+func TestHandlePreferences(t *testing.T) {
+	router, _, _ := setupProfileRouter(t)
+
+	w := profileRequest(router, "GET", "/me/preferences", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{}`, w.Body.String())
+
+	w = profileRequest(router, "PUT", "/me/preferences", `{"notifications": {"email": true, "severities": ["critical"]}}`)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	w = profileRequest(router, "GET", "/me/preferences", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"notifications":{"email":true,"severities":["critical"]}}`, w.Body.String())
+
+	for _, body := range []string{`[1,2]`, `"text"`, `null`, `{`} {
+		w = profileRequest(router, "PUT", "/me/preferences", body)
+		assert.Equal(t, http.StatusBadRequest, w.Code, body)
+	}
+}
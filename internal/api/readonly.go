@@ -0,0 +1,131 @@
+package api
+
+import (
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/padminisys/flintroute/internal/apierror"
+	authpkg "github.com/padminisys/flintroute/internal/auth"
+	"go.uber.org/zap"
+)
+
+// readOnlyExempt are the changes accepted in read-only mode: turning it off
+// and receiving Alertmanager alerts, which are monitoring data
+var readOnlyExempt = []string{
+	"/api/v1/admin/read-only",
+	"/api/v1/alertmanager/webhook",
+}
+
+// ReadOnlyStatus is whether the API rejects changes, and who turned that on
+type ReadOnlyStatus struct {
+	Enabled bool       `json:"enabled"`
+	Reason  string     `json:"reason,omitempty"`
+	Since   *time.Time `json:"since,omitempty"`
+	// By is the admin who turned read-only mode on, empty when it was set
+	// by server.read_only
+	By string `json:"by,omitempty"`
+}
+
+// SetReadOnlyRequest represents a request to turn read-only mode on or off
+type SetReadOnlyRequest struct {
+	Enabled *bool  `json:"enabled" binding:"required"`
+	Reason  string `json:"reason"`
+}
+
+// readOnly is the server's read-only switch
+type readOnly struct {
+	mu     sync.RWMutex
+	status ReadOnlyStatus
+}
+
+// newReadOnly returns a switch that starts enabled when configured so
+func newReadOnly(enabled bool) *readOnly {
+	ro := &readOnly{}
+	if enabled {
+		now := time.Now()
+		ro.status = ReadOnlyStatus{Enabled: true, Reason: "server.read_only is set", Since: &now}
+	}
+	return ro
+}
+
+// set turns read-only mode on or off
+func (ro *readOnly) set(enabled bool, reason, by string) ReadOnlyStatus {
+	ro.mu.Lock()
+	defer ro.mu.Unlock()
+
+	if !enabled {
+		ro.status = ReadOnlyStatus{}
+		return ro.status
+	}
+	if !ro.status.Enabled {
+		now := time.Now()
+		ro.status.Since = &now
+	}
+	ro.status.Enabled = true
+	ro.status.Reason = reason
+	ro.status.By = by
+	return ro.status
+}
+
+// Status returns a copy of the switch's state
+func (ro *readOnly) Status() ReadOnlyStatus {
+	ro.mu.RLock()
+	defer ro.mu.RUnlock()
+	return ro.status
+}
+
+// Enabled reports whether changes are rejected
+func (ro *readOnly) Enabled() bool {
+	ro.mu.RLock()
+	defer ro.mu.RUnlock()
+	return ro.status.Enabled
+}
+
+// readOnlyMiddleware rejects changes with 423 while read-only mode is on.
+// Reads, signing in and out, and turning the mode off are served
+// throughout. Background work such as session monitoring, reconciliation
+// and scheduled GitOps syncs is not affected.
+func (s *Server) readOnlyMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if s.readOnly == nil || !s.readOnly.Enabled() || !isChange(c) ||
+			slices.Contains(readOnlyExempt, c.Request.URL.Path) {
+			c.Next()
+			return
+		}
+
+		message := "FlintRoute is in read-only mode"
+		if reason := s.readOnly.Status().Reason; reason != "" {
+			message += ": " + reason
+		}
+		apierror.Respond(c, http.StatusLocked, apierror.CodeReadOnly, message)
+	}
+}
+
+// handleGetReadOnly handles reporting whether the API is read-only
+func (s *Server) handleGetReadOnly(c *gin.Context) {
+	c.JSON(http.StatusOK, s.readOnly.Status())
+}
+
+// handleSetReadOnly handles turning read-only mode on or off, for example
+// around a maintenance window
+func (s *Server) handleSetReadOnly(c *gin.Context) {
+	var req SetReadOnlyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apierror.Validation(c, err)
+		return
+	}
+
+	username, _ := authpkg.GetUsername(c)
+	status := s.readOnly.set(*req.Enabled, req.Reason, username)
+
+	s.logger.Warn("Read-only mode changed",
+		zap.Bool("enabled", status.Enabled),
+		zap.String("reason", req.Reason),
+		zap.String("username", username),
+	)
+
+	c.JSON(http.StatusOK, status)
+}
@@ -0,0 +1,88 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/padminisys/flintroute/internal/apierror"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadOnlyMiddleware(t *testing.T) {
+	server, _ := setupTestServer(t)
+	server.router = gin.New()
+	server.readOnly = newReadOnly(false)
+
+	v1 := server.router.Group("/api/v1")
+	v1.Use(server.readOnlyMiddleware())
+	for _, method := range []string{"GET", "POST", "PUT", "DELETE"} {
+		v1.Handle(method, "/bgp/peers", func(c *gin.Context) { c.Status(http.StatusNoContent) })
+	}
+	v1.POST("/auth/login", func(c *gin.Context) { c.Status(http.StatusNoContent) })
+	v1.GET("/admin/read-only", server.handleGetReadOnly)
+	v1.PUT("/admin/read-only", server.handleSetReadOnly)
+
+	request := func(method, path, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, path, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		server.router.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("Changes accepted while off", func(t *testing.T) {
+		assert.Equal(t, http.StatusNoContent, request("POST", "/api/v1/bgp/peers", "").Code)
+	})
+
+	t.Run("Turning on rejects changes", func(t *testing.T) {
+		w := request("PUT", "/api/v1/admin/read-only", `{"enabled":true,"reason":"maintenance"}`)
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var status ReadOnlyStatus
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &status))
+		assert.True(t, status.Enabled)
+		assert.Equal(t, "maintenance", status.Reason)
+		assert.NotNil(t, status.Since)
+
+		for _, method := range []string{"POST", "PUT", "DELETE"} {
+			w := request(method, "/api/v1/bgp/peers", "")
+			assert.Equal(t, http.StatusLocked, w.Code)
+
+			var resp apierror.Response
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+			assert.Equal(t, apierror.CodeReadOnly, resp.Code)
+			assert.Contains(t, resp.Error, "maintenance")
+		}
+	})
+
+	t.Run("Reads and sign in are served", func(t *testing.T) {
+		assert.Equal(t, http.StatusNoContent, request("GET", "/api/v1/bgp/peers", "").Code)
+		assert.Equal(t, http.StatusNoContent, request("POST", "/api/v1/auth/login", "").Code)
+		assert.Equal(t, http.StatusOK, request("GET", "/api/v1/admin/read-only", "").Code)
+	})
+
+	t.Run("Turning off accepts changes again", func(t *testing.T) {
+		w := request("PUT", "/api/v1/admin/read-only", `{"enabled":false}`)
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.False(t, server.readOnly.Enabled())
+		assert.Equal(t, http.StatusNoContent, request("DELETE", "/api/v1/bgp/peers", "").Code)
+	})
+
+	t.Run("Requires enabled", func(t *testing.T) {
+		assert.Equal(t, http.StatusBadRequest, request("PUT", "/api/v1/admin/read-only", `{}`).Code)
+	})
+}
+
+func TestNewReadOnly(t *testing.T) {
+	status := newReadOnly(true).Status()
+	assert.True(t, status.Enabled)
+	assert.Equal(t, "server.read_only is set", status.Reason)
+	assert.Empty(t, status.By)
+
+	assert.False(t, newReadOnly(false).Enabled())
+}
@@ -0,0 +1,111 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/padminisys/flintroute/internal/apierror"
+	"github.com/padminisys/flintroute/internal/bgp"
+	"go.uber.org/zap"
+)
+
+// SLA report ranges
+const (
+	defaultSLARange = 30 * 24 * time.Hour
+	maxSLARange     = 366 * 24 * time.Hour
+)
+
+// slaExportColumns are the CSV columns of an SLA report
+var slaExportColumns = []string{
+	"peer_id", "name", "ip_address", "remote_asn", "monitored", "downtime",
+	"maintenance_downtime", "availability", "incidents", "mttr",
+}
+
+// handleSLAReport handles reporting the availability of peers' sessions
+// over a range such as "30d", as JSON or, with format=csv, a CSV download
+func (s *Server) handleSLAReport(c *gin.Context) {
+	window, err := parseReportRange(c.Query("range"))
+	if err != nil {
+		apierror.Respond(c, http.StatusBadRequest, apierror.CodeValidationFailed, err.Error())
+		return
+	}
+	format := c.DefaultQuery("format", "json")
+	if format != "json" && format != ExportCSV {
+		apierror.Respond(c, http.StatusBadRequest, apierror.CodeValidationFailed,
+			fmt.Sprintf("Unknown report format %q (must be json or %s)", format, ExportCSV))
+		return
+	}
+	selectors, ok := tagSelectors(c)
+	if !ok {
+		return
+	}
+
+	report, err := s.bgpService.SLAReport(c.Request.Context(), window, selectors...)
+	if err != nil {
+		s.logger.Error("Failed to build SLA report", zap.Error(err))
+		apierror.Respond(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to build SLA report")
+		return
+	}
+
+	if format == "json" {
+		c.JSON(http.StatusOK, report)
+		return
+	}
+
+	w := startExport(c, "sla", ExportCSV, slaExportColumns)
+	for _, peer := range report.Peers {
+		if err := w.write(peer, slaExportRow(peer)); err != nil {
+			s.logger.Error("Failed to export SLA report", zap.Error(err))
+			return
+		}
+	}
+	if err := w.flush(); err != nil {
+		s.logger.Error("Failed to export SLA report", zap.Error(err))
+	}
+}
+
+// parseReportRange parses a report range given in days, such as "30d", or
+// as a duration, such as "12h". It defaults to 30 days.
+func parseReportRange(raw string) (time.Duration, error) {
+	if raw == "" {
+		return defaultSLARange, nil
+	}
+
+	var window time.Duration
+	if days, ok := strings.CutSuffix(raw, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil {
+			return 0, fmt.Errorf("invalid range %q", raw)
+		}
+		window = time.Duration(n) * 24 * time.Hour
+	} else {
+		var err error
+		if window, err = time.ParseDuration(raw); err != nil {
+			return 0, fmt.Errorf("invalid range %q", raw)
+		}
+	}
+	if window <= 0 || window > maxSLARange {
+		return 0, fmt.Errorf("range must be positive and at most %d days", int(maxSLARange.Hours()/24))
+	}
+	return window, nil
+}
+
+// slaExportRow returns the CSV row of a peer's SLA
+func slaExportRow(peer *bgp.PeerSLA) []string {
+	return []string{
+		strconv.FormatUint(uint64(peer.PeerID), 10),
+		peer.Name,
+		peer.IPAddress,
+		strconv.FormatUint(uint64(peer.RemoteASN), 10),
+		strconv.FormatInt(peer.Monitored, 10),
+		strconv.FormatInt(peer.Downtime, 10),
+		strconv.FormatInt(peer.MaintenanceDowntime, 10),
+		strconv.FormatFloat(peer.Availability, 'f', 3, 64),
+		strconv.Itoa(len(peer.Incidents)),
+		strconv.FormatInt(peer.MTTR, 10),
+	}
+}
@@ -0,0 +1,77 @@
+package api
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/padminisys/flintroute/internal/auth"
+	"github.com/padminisys/flintroute/internal/bgp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSLAReport(t *testing.T) {
+	report := &bgp.SLAReport{Peers: []*bgp.PeerSLA{{
+		PeerID:       1,
+		Name:         "edge",
+		IPAddress:    "192.0.2.1",
+		RemoteASN:    65001,
+		Monitored:    3600,
+		Downtime:     36,
+		Availability: 99,
+		MTTR:         36,
+		Incidents:    []*bgp.SLAIncident{{Duration: 36}},
+	}}}
+
+	t.Run("Reports as JSON", func(t *testing.T) {
+		server := newMockedServer(t)
+		server.bgp.On("SLAReport", mock.Anything, 7*24*time.Hour, []bgp.TagSelector{{Key: "pop", Value: "fra1"}}).Return(report, nil).Once()
+
+		w := server.request(t, auth.RoleUser, "GET", "/api/v1/reports/sla?range=7d&tag=pop:fra1", "")
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var got bgp.SLAReport
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &got))
+		require.Len(t, got.Peers, 1)
+		assert.Equal(t, 99.0, got.Peers[0].Availability)
+	})
+
+	t.Run("Exports as CSV", func(t *testing.T) {
+		server := newMockedServer(t)
+		server.bgp.On("SLAReport", mock.Anything, defaultSLARange, mock.Anything).Return(report, nil).Once()
+
+		w := server.request(t, auth.RoleUser, "GET", "/api/v1/reports/sla?format=csv", "")
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		assert.Contains(t, w.Header().Get("Content-Disposition"), "sla-")
+		rows, err := csv.NewReader(strings.NewReader(w.Body.String())).ReadAll()
+		require.NoError(t, err)
+		require.Len(t, rows, 2)
+		assert.Equal(t, slaExportColumns, rows[0])
+		assert.Equal(t, []string{"1", "edge", "192.0.2.1", "65001", "3600", "36", "0", "99.000", "1", "36"}, rows[1])
+	})
+
+	t.Run("Rejects invalid ranges and formats", func(t *testing.T) {
+		server := newMockedServer(t)
+		for _, query := range []string{"range=tomorrow", "range=0d", "range=400d", "format=xml"} {
+			w := server.request(t, auth.RoleUser, "GET", "/api/v1/reports/sla?"+query, "")
+			assert.Equal(t, http.StatusBadRequest, w.Code, query)
+		}
+		server.bgp.AssertNotCalled(t, "SLAReport", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func TestParseReportRange(t *testing.T) {
+	for raw, want := range map[string]time.Duration{
+		"":    30 * 24 * time.Hour,
+		"30d": 30 * 24 * time.Hour,
+		"12h": 12 * time.Hour,
+	} {
+		got, err := parseReportRange(raw)
+		require.NoError(t, err, raw)
+		assert.Equal(t, want, got, raw)
+	}
+}
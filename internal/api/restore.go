@@ -0,0 +1,212 @@
+package api
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/padminisys/flintroute/internal/bgp"
+	"github.com/padminisys/flintroute/internal/jobs"
+	"github.com/padminisys/flintroute/pkg/models"
+	"go.uber.org/zap"
+)
+
+// Outcomes of a config restore
+const (
+	// RestoreApplied is a restore applied without verification
+	RestoreApplied = "applied"
+	// RestoreVerified is a restore whose sessions stayed up for the
+	// verification window
+	RestoreVerified = "verified"
+	// RestoreRolledBack is a restore undone because too many sessions
+	// went down
+	RestoreRolledBack = "rolled_back"
+)
+
+// preRestoreLabel labels the versions saved before a restore
+const preRestoreLabel = "pre-restore"
+
+// defaultRestoreCheckInterval is how often sessions are checked while a
+// restore is verified, unless configured
+const defaultRestoreCheckInterval = 10 * time.Second
+
+// restoreVerification is how a restore is verified: the sessions that were
+// established are watched for Window, and the restore is rolled back when
+// more than MaxSessionsDown of them go down
+type restoreVerification struct {
+	Window          string `json:"window"`
+	MaxSessionsDown int    `json:"max_sessions_down"`
+
+	window   time.Duration
+	interval time.Duration
+}
+
+// restoreVerification returns the verification of a restore job,
+// defaulting to config_versions.restore
+func (s *Server) restoreVerification(payload restoreJobPayload) restoreVerification {
+	cfg := s.config.ConfigVersions.Restore
+
+	v := restoreVerification{Window: cfg.VerifyWindow, MaxSessionsDown: cfg.MaxSessionsDown}
+	if payload.VerifyWindow != "" {
+		v.Window = payload.VerifyWindow
+	}
+	if payload.MaxSessionsDown != nil {
+		v.MaxSessionsDown = *payload.MaxSessionsDown
+	}
+
+	if window, err := time.ParseDuration(v.Window); err == nil && window > 0 {
+		v.window = window
+	} else {
+		v.Window = "0s"
+	}
+	v.interval = defaultRestoreCheckInterval
+	if interval, err := time.ParseDuration(cfg.CheckInterval); err == nil && interval > 0 {
+		v.interval = interval
+	}
+	return v
+}
+
+// restoreStep is a step of a restore, as recorded in the job's result
+type restoreStep struct {
+	At      time.Time `json:"at"`
+	Message string    `json:"message"`
+}
+
+// restoreResult is the result of a config restore job
+type restoreResult struct {
+	VersionID uint     `json:"version_id"`
+	Name      string   `json:"name"`
+	Labels    []string `json:"labels"`
+	Pinned    bool     `json:"pinned"`
+	// SnapshotID is the version holding the configuration from before the
+	// restore, which a rollback applies again
+	SnapshotID   uint                `json:"snapshot_id,omitempty"`
+	Outcome      string              `json:"outcome"`
+	Verification restoreVerification `json:"verification"`
+	// SessionsDown are the peers whose sessions were down when
+	// verification ended
+	SessionsDown []string      `json:"sessions_down,omitempty"`
+	Log          []restoreStep `json:"log"`
+}
+
+// restoreRun is a config restore job in progress. Each step is logged,
+// reported as the job's progress and kept in its result.
+type restoreRun struct {
+	logger   *zap.Logger
+	job      *models.Job
+	progress jobs.Progress
+	result   restoreResult
+}
+
+// step records a step of the restore
+func (r *restoreRun) step(percent int, message string) {
+	r.result.Log = append(r.result.Log, restoreStep{At: time.Now(), Message: message})
+	r.progress(percent, message)
+	r.logger.Info("Config restore step",
+		zap.Uint("job_id", r.job.ID),
+		zap.Uint("version_id", r.result.VersionID),
+		zap.String("step", message),
+	)
+}
+
+// saveBeforeRestore saves FRR's running configuration with the BGP global
+// configuration as a version labeled "pre-restore", unless a version of the
+// same configuration exists. The BGP global configuration is returned for
+// a rollback to apply.
+func (s *Server) saveBeforeRestore(ctx context.Context, job *models.Job, version *models.ConfigVersion) (*models.ConfigVersion, *models.BGPGlobalConfig, error) {
+	running, err := s.bgpService.GetRunningConfig(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get running config: %w", err)
+	}
+	global, err := s.bgpService.GetGlobalConfig(ctx)
+	if err != nil && !errors.Is(err, bgp.ErrGlobalConfigNotFound) {
+		return nil, nil, fmt.Errorf("failed to get BGP global configuration: %w", err)
+	}
+
+	hash := fmt.Sprintf("%x", sha256.Sum256([]byte(running)))
+	if existing, err := s.repos.Configs.FindByHash(ctx, hash); err == nil {
+		return existing, global, nil
+	}
+
+	snapshot := &models.ConfigVersion{
+		Description: fmt.Sprintf("Before restoring %s", describeVersion(version)),
+		Config:      running,
+		Hash:        hash,
+		BGPGlobal:   global,
+		TenantID:    version.TenantID,
+		Labels:      []string{preRestoreLabel},
+	}
+	if job.CreatedBy != nil {
+		snapshot.CreatedBy = *job.CreatedBy
+	}
+	if err := s.configVersions.Save(ctx, snapshot); err != nil {
+		return nil, nil, fmt.Errorf("failed to save config version: %w", err)
+	}
+	return snapshot, global, nil
+}
+
+// establishedSessions returns the IP addresses of the peers whose sessions
+// are established, by peer ID
+func (s *Server) establishedSessions(ctx context.Context) (map[uint]string, error) {
+	sessions, err := s.bgpService.ListSessions(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list sessions: %w", err)
+	}
+
+	established := make(map[uint]string)
+	for _, session := range sessions {
+		if session.State == "Established" {
+			established[session.PeerID] = session.Peer.IPAddress
+		}
+	}
+	return established, nil
+}
+
+// sessionsDown returns the peers of established whose sessions are no
+// longer established, in order
+func (s *Server) sessionsDown(ctx context.Context, established map[uint]string) ([]string, error) {
+	current, err := s.establishedSessions(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var down []string
+	for peerID, ip := range established {
+		if _, ok := current[peerID]; !ok {
+			down = append(down, ip)
+		}
+	}
+	slices.Sort(down)
+	return down, nil
+}
+
+// verifyRestore watches the sessions that were established before a restore
+// for the verification window. It returns as soon as more sessions are down
+// than allowed, or with those down when the window ends.
+func (s *Server) verifyRestore(ctx context.Context, established map[uint]string, v restoreVerification) ([]string, error) {
+	deadline := time.NewTimer(v.window)
+	defer deadline.Stop()
+	ticker := time.NewTicker(v.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-ticker.C:
+			down, err := s.sessionsDown(ctx, established)
+			if err != nil {
+				s.logger.Warn("Failed to check sessions during restore verification", zap.Error(err))
+				continue
+			}
+			if len(down) > v.MaxSessionsDown {
+				return down, nil
+			}
+		case <-deadline.C:
+			return s.sessionsDown(ctx, established)
+		}
+	}
+}
@@ -2,34 +2,125 @@ package api
 
 import (
 	"context"
+	"fmt"
+	"math/rand/v2"
+	"net"
 	"net/http"
+	"os"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/padminisys/flintroute/internal/activity"
+	"github.com/padminisys/flintroute/internal/alertmanager"
+	"github.com/padminisys/flintroute/internal/alerts"
+	"github.com/padminisys/flintroute/internal/apierror"
 	authpkg "github.com/padminisys/flintroute/internal/auth"
 	"github.com/padminisys/flintroute/internal/bgp"
+	"github.com/padminisys/flintroute/internal/cache"
+	"github.com/padminisys/flintroute/internal/changes"
 	"github.com/padminisys/flintroute/internal/config"
+	"github.com/padminisys/flintroute/internal/configstore"
 	"github.com/padminisys/flintroute/internal/database"
+	"github.com/padminisys/flintroute/internal/encryption"
 	"github.com/padminisys/flintroute/internal/frr"
+	"github.com/padminisys/flintroute/internal/frrlog"
+	"github.com/padminisys/flintroute/internal/gitops"
+	"github.com/padminisys/flintroute/internal/jobs"
+	"github.com/padminisys/flintroute/internal/leader"
+	"github.com/padminisys/flintroute/internal/peeringdb"
+	"github.com/padminisys/flintroute/internal/repository"
+	"github.com/padminisys/flintroute/internal/requestid"
+	"github.com/padminisys/flintroute/internal/secrets"
+	"github.com/padminisys/flintroute/internal/snapshots"
+	"github.com/padminisys/flintroute/internal/snmp"
+	"github.com/padminisys/flintroute/internal/state"
+	"github.com/padminisys/flintroute/internal/store"
+	"github.com/padminisys/flintroute/internal/tracing"
+	"github.com/padminisys/flintroute/internal/webhooks"
 	"github.com/padminisys/flintroute/internal/websocket"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
 	"go.uber.org/zap"
+	"google.golang.org/grpc"
 )
 
 // Server represents the HTTP server
 type Server struct {
-	router     *gin.Engine
-	httpServer *http.Server
-	config     *config.Config
-	db         *database.DB
-	wsHub      *websocket.Hub
-	bgpService *bgp.Service
-	jwtManager *authpkg.JWTManager
-	logger     *zap.Logger
+	router              *gin.Engine
+	httpServer          *http.Server
+	config              *config.Config
+	db                  *database.DB
+	repos               repository.Repositories
+	wsHub               *websocket.Hub
+	bgpService          BGPService
+	webhookService      *webhooks.Service
+	alertmanagerService *alertmanager.Service
+	alertmanagerToken   string
+	trapSender          *snmp.Sender
+	configVersions      ConfigVersionStore
+	frrLog              *frrlog.Log
+	snapshots           *snapshots.Store
+	jobs                *jobs.Queue
+	changes             *changes.Service
+	jwtManager          *authpkg.JWTManager
+	cache               *cache.Cache
+	denylist            *authpkg.Denylist
+	// store holds the state shared with other instances
+	store  store.Store
+	logger *zap.Logger
+	// logLevel is the level of logger, when it can be changed at runtime
+	logLevel *zap.AtomicLevel
+
+	gitopsSyncer        *gitops.Syncer
+	gitopsWebhookSecret string
+
+	// stateService exports and imports the declarative state document
+	stateService *state.Service
+
+	// startup holds changes back until FRR has the stored configuration
+	startup *startup
+	// readOnly rejects changes while it is on
+	readOnly *readOnly
+	// idempotency replays responses to retried POSTs; nil when disabled
+	idempotency *idempotencyKeys
+	// notifier sends alerts to users by their notification settings
+	notifier *alerts.Notifier
+	// alertDedup stores the alerts the server raises, folding repeats
+	// together like the BGP service's
+	alertDedup *alerts.Deduplicator
+	// mailer emails users at their own addresses; nil without
+	// alerts.notifications.email
+	mailer *alerts.Mailer
+	// usage counts requests per user and enforces request quotas
+	usage *usageTracker
+	// peeringDB looks up peers' networks; nil when lookups are disabled
+	peeringDB *peeringdb.Client
+	// demo simulates FRR in demo mode; nil otherwise
+	demo *frr.DemoClient
+	// frrLimiter paces and caps the operations sent to FRR; nil without an
+	// FRR client
+	frrLimiter *frr.LimitedClient
+	// activity merges audit entries, alerts, session events and config
+	// versions into one feed
+	activity *activity.Feed
+	// announceBefore is how long before a scheduled peer action runs it is
+	// announced
+	announceBefore time.Duration
+	// elector is set when several instances share the database; changes
+	// are only accepted by the leader
+	elector *leader.Elector
+	// tenancy scopes peers, alerts and config versions to the tenant a
+	// request acts for
+	tenancy bool
+	// grpcServer serves the gRPC API; nil unless server.grpc_port is set
+	grpcServer *grpc.Server
+	// apiKeys authenticate gRPC calls as the users they name
+	apiKeys []apiKey
 }
 
 // NewServer creates a new HTTP server
-func NewServer(cfg *config.Config, db *database.DB, wsHub *websocket.Hub, logger *zap.Logger) *Server {
+func NewServer(cfg *config.Config, db *database.DB, wsHub *websocket.Hub, logger *zap.Logger) (*Server, error) {
 	// Parse token expiry durations
 	tokenExpiry, err := time.ParseDuration(cfg.Auth.TokenExpiry)
 	if err != nil {
@@ -41,53 +132,929 @@ func NewServer(cfg *config.Config, db *database.DB, wsHub *websocket.Hub, logger
 		refreshExpiry = 168 * time.Hour // 7 days
 	}
 
+	// Resolve secret references
+	secretResolver := newSecretResolver(cfg.Secrets)
+
 	// Create JWT manager
-	jwtManager := authpkg.NewJWTManager(cfg.Auth.JWTSecret, tokenExpiry, refreshExpiry)
+	jwtManager, jwtKeyRef, jwtKey, err := newJWTManager(cfg.Auth, secretResolver, tokenExpiry, refreshExpiry)
+	if err != nil {
+		return nil, err
+	}
+
+	// Connect the store shared with other instances
+	sharedStore, err := newStore(cfg.Store, secretResolver)
+	if err != nil {
+		return nil, err
+	}
+
+	// Load revoked access tokens
+	denylist, err := authpkg.NewDenylist(db.DB, sharedStore, tokenExpiry, logger)
+	if err != nil {
+		return nil, err
+	}
 
 	// Create FRR client
-	frrClient, err := frr.NewClient(cfg.FRR.GRPCHost, cfg.FRR.GRPCPort, logger)
+	frrClient, err := newFRRClient(cfg.FRR, logger)
 	if err != nil {
 		logger.Error("Failed to create FRR client", zap.Error(err))
 	}
+	demo, _ := frrClient.(*frr.DemoClient)
+
+	// Pace and cap the operations sent to FRR
+	var frrLimiter *frr.LimitedClient
+	if frrClient != nil {
+		frrLimiter = newFRRLimiter(frrClient, cfg.FRR.Limits, logger)
+		frrClient = frrLimiter
+	}
+
+	// Record every change pushed to FRR
+	frrLog := frrlog.NewLog(db, logger)
+	if frrClient != nil {
+		frrClient = frrLog.Wrap(frrClient)
+	}
+
+	// Create password cipher
+	passwordCipher, err := newPasswordCipher(cfg.Database, secretResolver)
+	if err != nil {
+		return nil, err
+	}
+
+	// Create webhook service
+	webhookTimeout, err := time.ParseDuration(cfg.Webhooks.Timeout)
+	if err != nil {
+		webhookTimeout = 10 * time.Second
+	}
+	webhookBackoff, err := time.ParseDuration(cfg.Webhooks.RetryBackoff)
+	if err != nil {
+		webhookBackoff = 30 * time.Second
+	}
+	webhookService := webhooks.NewService(db, passwordCipher, webhooks.Config{
+		MaxAttempts:  cfg.Webhooks.MaxAttempts,
+		Timeout:      webhookTimeout,
+		RetryBackoff: webhookBackoff,
+	}, logger)
+
+	// Create SNMP trap sender
+	var trapSender *snmp.Sender
+	if cfg.SNMP.Enabled {
+		trapSender, err = newTrapSender(cfg.SNMP, secretResolver, logger)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Create response cache
+	cacheTTL, err := time.ParseDuration(cfg.Cache.TTL)
+	if err != nil {
+		cacheTTL = 30 * time.Second
+	}
+	var responseCache *cache.Cache
+	if cacheTTL > 0 {
+		responseCache = cache.New(cacheTTL)
+	}
+
+	// Apply the WebSocket hub's slow client settings
+	wsHub.SetOptions(websocket.Options{
+		SendBuffer:       cfg.WebSocket.SendBuffer,
+		SlowClientPolicy: cfg.WebSocket.SlowClientPolicy,
+		MaxDropped:       cfg.WebSocket.MaxDropped,
+	})
+	if err := wsHub.Persist(db.DB, cfg.WebSocket.EventRetention); err != nil {
+		return nil, fmt.Errorf("failed to load event history: %w", err)
+	}
+	if err := wsHub.Share(sharedStore); err != nil {
+		return nil, fmt.Errorf("failed to share event history: %w", err)
+	}
+
+	// Fold repeated alerts together and hold back alert storms
+	dedupWindow, err := time.ParseDuration(cfg.Alerts.Dedup.Window)
+	if err != nil {
+		dedupWindow = time.Hour
+	}
+	stormWindow, err := time.ParseDuration(cfg.Alerts.Dedup.StormWindow)
+	if err != nil {
+		stormWindow = 5 * time.Minute
+	}
+	alertDedup := alerts.NewDeduplicator(db, alerts.DedupPolicy{
+		Window:         dedupWindow,
+		StormThreshold: cfg.Alerts.Dedup.StormThreshold,
+		StormWindow:    stormWindow,
+	}, logger)
+
+	// Render emails from the built-in templates or the deployment's own
+	brand := cfg.Alerts.Templates.Branding
+	emailTemplates, err := alerts.LoadTemplates(cfg.Alerts.Templates.Dir, alerts.Branding{
+		Name:    brand.Name,
+		URL:     brand.URL,
+		LogoURL: brand.LogoURL,
+		Color:   brand.Color,
+		Footer:  brand.Footer,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Notify users of alerts by their notification settings
+	var notificationMailer *alerts.Mailer
+	if email := cfg.Alerts.Notifications.Email; email.SMTPHost != "" {
+		password, err := secretResolver.Resolve(context.Background(), email.Password)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve alert notification SMTP password: %w", err)
+		}
+		notificationMailer = alerts.NewMailer(alerts.MailConfig{
+			Host:      email.SMTPHost,
+			Port:      email.SMTPPort,
+			Username:  email.Username,
+			Password:  password,
+			From:      email.From,
+			Templates: emailTemplates,
+		})
+	}
+	notifier := alerts.NewNotifier(db, notificationMailer, logger)
+
+	// Detect sudden prefix count changes
+	anomalyWindow, err := time.ParseDuration(cfg.Alerts.Anomalies.Window)
+	if err != nil {
+		anomalyWindow = time.Hour
+	}
+
+	// Probe peer addresses independently of BGP
+	reachabilityTimeout, err := time.ParseDuration(cfg.Peers.Reachability.Timeout)
+	if err != nil {
+		reachabilityTimeout = 2 * time.Second
+	}
+	reachabilityRetention, err := time.ParseDuration(cfg.Peers.Reachability.Retention)
+	if err != nil {
+		reachabilityRetention = 24 * time.Hour
+	}
+
+	repos := repository.New(db)
 
 	// Create BGP service
-	bgpService := bgp.NewService(db, frrClient, wsHub, logger)
+	bgpService := bgp.NewService(db, frrClient, wsHub, bgp.ServiceConfig{
+		ConsistencyMode: bgp.ConsistencyMode(cfg.FRR.ConsistencyMode),
+		AutoHeal:        cfg.FRR.AutoHeal,
+		LocalASN:        cfg.FRR.LocalASN,
+		PasswordCipher:  passwordCipher,
+		Secrets:         secretResolver,
+		Webhooks:        webhookService,
+		Traps:           trapSender,
+		Cache:           responseCache,
+		Alerts:          alertDedup,
+		Notifier:        notifier,
+		Anomalies: bgp.AnomalyPolicy{
+			Window:           anomalyWindow,
+			ThresholdPercent: cfg.Alerts.Anomalies.ThresholdPercent,
+			MinPrefixes:      cfg.Alerts.Anomalies.MinPrefixes,
+		},
+		Reachability: bgp.ReachabilityPolicy{
+			Method:               cfg.Peers.Reachability.Method,
+			Count:                cfg.Peers.Reachability.Count,
+			Timeout:              reachabilityTimeout,
+			LossThresholdPercent: cfg.Peers.Reachability.LossThreshold,
+			Failures:             cfg.Peers.Reachability.Failures,
+			Retention:            reachabilityRetention,
+		},
+		SyncBatchSize:  cfg.FRR.Limits.BatchSize,
+		TrashRetention: time.Duration(cfg.Peers.TrashRetentionDays) * 24 * time.Hour,
+		Peers:          repos.Peers,
+		Sessions:       repos.Sessions,
+	}, logger)
+
+	if err := bgpService.EncryptStoredPasswords(context.Background()); err != nil {
+		return nil, fmt.Errorf("failed to encrypt stored peer passwords: %w", err)
+	}
+
+	// Create config version store
+	configVersions, err := newConfigStore(cfg.ConfigVersions, db, secretResolver, logger)
+	if err != nil {
+		return nil, err
+	}
 
 	// Set Gin mode
 	gin.SetMode(gin.ReleaseMode)
 
-	// Create router
-	router := gin.New()
-	router.Use(gin.Recovery())
-	router.Use(corsMiddleware())
-	router.Use(loggingMiddleware(logger))
+	server := newServer(cfg, Services{
+		DB:             db,
+		WSHub:          wsHub,
+		BGP:            bgpService,
+		ConfigVersions: configVersions,
+		Webhooks:       webhookService,
+		Alerts:         alertDedup,
+		JWTManager:     jwtManager,
+		Denylist:       denylist,
+		Store:          sharedStore,
+		Cache:          responseCache,
+		Repositories:   repos,
+		FRRLog:         frrLog,
+		Logger:         logger,
+	})
+	server.trapSender = trapSender
+	server.notifier = notifier
+	server.stateService = state.NewService(db, bgpService, webhookService, notifier, logger)
+	server.demo = demo
+	server.frrLimiter = frrLimiter
+	server.mailer = notificationMailer
+	if window, err := time.ParseDuration(cfg.Server.IdempotencyWindow); err == nil && window > 0 {
+		server.idempotency = newIdempotencyKeys(db.DB, window, logger)
+	}
+	if cfg.Server.ReadOnly {
+		logger.Warn("Starting in read-only mode; changes are rejected until an admin turns it off")
+	}
+
+	// Create Alertmanager integration
+	server.alertmanagerService = alertmanager.NewService(db, wsHub, alertmanager.Options{
+		Router:      cfg.Alertmanager.Router,
+		ExternalURL: cfg.Alertmanager.ExternalURL,
+		Labels:      cfg.Alertmanager.Labels,
+	}, logger)
+	server.alertmanagerToken, err = secretResolver.Resolve(context.Background(), cfg.Alertmanager.ReceiverToken)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve Alertmanager receiver token: %w", err)
+	}
+
+	// Create PeeringDB client
+	if cfg.PeeringDB.URL != "" {
+		apiKey, err := secretResolver.Resolve(context.Background(), cfg.PeeringDB.APIKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve PeeringDB API key: %w", err)
+		}
+		timeout, err := time.ParseDuration(cfg.PeeringDB.Timeout)
+		if err != nil || timeout <= 0 {
+			timeout = 10 * time.Second
+		}
+		cacheTTL, err := time.ParseDuration(cfg.PeeringDB.CacheTTL)
+		if err != nil || cacheTTL < 0 {
+			cacheTTL = time.Hour
+		}
+		server.peeringDB = peeringdb.NewClient(cfg.PeeringDB.URL, apiKey, timeout, cacheTTL, logger)
+	}
+
+	// Create gRPC server
+	if cfg.Server.GRPCPort > 0 {
+		server.apiKeys, err = resolveAPIKeys(context.Background(), cfg.Auth.APIKeys, secretResolver)
+		if err != nil {
+			return nil, err
+		}
+		server.grpcServer = server.newGRPCServer()
+	}
+
+	// Create database snapshot store
+	server.snapshots, err = newSnapshotStore(cfg.Database.Snapshots, db, secretResolver, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	// Create GitOps syncer
+	if cfg.GitOps.Enabled {
+		if err := server.setupGitOps(secretResolver, bgpService, configVersions); err != nil {
+			return nil, err
+		}
+	}
 
-	server := &Server{
-		router:     router,
-		config:     cfg,
-		db:         db,
-		wsHub:      wsHub,
-		bgpService: bgpService,
-		jwtManager: jwtManager,
-		logger:     logger,
+	// Create background job queue
+	server.announceBefore, err = time.ParseDuration(cfg.Jobs.AnnounceBefore)
+	if err != nil || server.announceBefore < 0 {
+		server.announceBefore = 15 * time.Minute
+	}
+	server.jobs = jobs.NewQueue(db, wsHub, cfg.Jobs.Workers, logger)
+	server.registerJobs()
+
+	// Hold peer and restore changes for approval by a second admin
+	if cfg.Approvals.Enabled {
+		server.changes = changes.NewService(db, passwordCipher, wsHub, webhookService, notificationMailer, logger)
+		server.registerChanges()
 	}
 
 	// Setup routes
 	server.setupRoutes()
 
-	// Start BGP monitoring
-	go bgpService.StartMonitoring(context.Background(), 30*time.Second)
+	// Connect to FRR and sync peers, then start BGP monitoring
+	startupTimeout, err := time.ParseDuration(cfg.FRR.StartupTimeout)
+	if err != nil {
+		startupTimeout = time.Minute
+	}
+	pollInterval, err := time.ParseDuration(cfg.FRR.PollInterval)
+	if err != nil {
+		pollInterval = 30 * time.Second
+	}
+	pollMaxBackoff, err := time.ParseDuration(cfg.FRR.PollMaxBackoff)
+	if err != nil {
+		pollMaxBackoff = 5 * time.Minute
+	}
+	// Work that touches FRR or notifies other systems runs on the leader
+	// only; without HA this instance always leads
+	var leaderTasks []func(ctx context.Context)
+	leaderTasks = append(leaderTasks, func(ctx context.Context) {
+		server.startup.reset()
+		server.runStartup(ctx, frrClient, startupTimeout)
+		bgpService.StartMonitoring(ctx, bgp.MonitorConfig{
+			Interval:   pollInterval,
+			Jitter:     cfg.FRR.PollJitter,
+			MaxBackoff: pollMaxBackoff,
+		})
+	})
+
+	// Start webhook delivery
+	leaderTasks = append(leaderTasks, func(ctx context.Context) {
+		webhookService.Start(ctx, 5*time.Second)
+	})
+
+	// Start background job workers
+	leaderTasks = append(leaderTasks, func(ctx context.Context) {
+		server.jobs.Start(ctx, 5*time.Second)
+	})
+
+	// Prune revoked access tokens once they have expired
+	go denylist.Start(context.Background(), time.Hour)
+
+	// Prune idempotency keys once their window has passed
+	if server.idempotency != nil {
+		go server.idempotency.Start(context.Background(), time.Hour)
+	}
+
+	// Start FRR reconciliation
+	reconcileInterval, err := time.ParseDuration(cfg.FRR.ReconcileInterval)
+	if err != nil {
+		reconcileInterval = 5 * time.Minute
+	}
+	if reconcileInterval > 0 {
+		leaderTasks = append(leaderTasks, func(ctx context.Context) {
+			bgpService.StartReconciler(ctx, reconcileInterval)
+		})
+	}
+
+	// Check FRR for changes made outside FlintRoute
+	outOfBandInterval, err := time.ParseDuration(cfg.FRR.OutOfBandInterval)
+	if err != nil {
+		outOfBandInterval = time.Minute
+	}
+	if frrClient != nil && outOfBandInterval > 0 {
+		leaderTasks = append(leaderTasks, func(ctx context.Context) {
+			server.startOutOfBandDetector(ctx, frrClient, outOfBandInterval)
+		})
+	}
+
+	// Purge peers past their time in the trash
+	if cfg.Peers.TrashRetentionDays > 0 {
+		purgeInterval, err := time.ParseDuration(cfg.Peers.PurgeInterval)
+		if err != nil || purgeInterval <= 0 {
+			purgeInterval = time.Hour
+		}
+		leaderTasks = append(leaderTasks, func(ctx context.Context) {
+			bgpService.StartTrashPurger(ctx, purgeInterval)
+		})
+	}
+
+	// Start prefix anomaly detection
+	anomalyInterval, err := time.ParseDuration(cfg.Alerts.Anomalies.Interval)
+	if err != nil {
+		anomalyInterval = 5 * time.Minute
+	}
+	if anomalyInterval > 0 {
+		leaderTasks = append(leaderTasks, func(ctx context.Context) {
+			bgpService.StartAnomalyDetector(ctx, anomalyInterval)
+		})
+	}
+
+	// Start peer reachability probing; it is off by default
+	reachabilityInterval, err := time.ParseDuration(cfg.Peers.Reachability.Interval)
+	if err != nil {
+		reachabilityInterval = 0
+	}
+	if reachabilityInterval > 0 {
+		leaderTasks = append(leaderTasks, func(ctx context.Context) {
+			bgpService.StartReachabilityMonitor(ctx, reachabilityInterval)
+		})
+	}
+
+	// Pick up JWT secret and signing key rotations
+	refreshInterval, err := time.ParseDuration(cfg.Secrets.RefreshInterval)
+	if err != nil {
+		refreshInterval = 5 * time.Minute
+	}
+	if secrets.IsReference(jwtKeyRef) && refreshInterval > 0 {
+		go secretResolver.Watch(context.Background(), jwtKeyRef, jwtKey, refreshInterval,
+			func(key string) {
+				if cfg.Auth.SigningKey == "" {
+					jwtManager.SetSecret(key)
+				} else if err := jwtManager.SetSigningKey(key); err != nil {
+					logger.Warn("Ignoring invalid JWT signing key", zap.Error(err))
+					return
+				}
+				logger.Info("Rotated JWT signing key", zap.String("kid", jwtManager.KeyID()))
+			},
+			func(err error) {
+				logger.Warn("Failed to refresh JWT signing key", zap.Error(err))
+			},
+		)
+	}
+
+	// Start pushing alerts to Alertmanager
+	if len(cfg.Alertmanager.URLs) > 0 {
+		pushInterval, err := time.ParseDuration(cfg.Alertmanager.PushInterval)
+		if err != nil || pushInterval <= 0 {
+			pushInterval = time.Minute
+		}
+		pusher := alertmanager.NewPusher(server.alertmanagerService, cfg.Alertmanager.URLs, pushInterval, logger)
+		leaderTasks = append(leaderTasks, func(ctx context.Context) {
+			pusher.Start(ctx)
+		})
+	}
+
+	// Start alert retention
+	retention := cfg.Alerts.Retention
+	if retention.ArchiveAfterDays > 0 || retention.DeleteAfterDays > 0 {
+		retentionInterval, err := time.ParseDuration(retention.Interval)
+		if err != nil || retentionInterval <= 0 {
+			retentionInterval = time.Hour
+		}
+		day := 24 * time.Hour
+		alertRetention := alerts.NewRetention(db, alerts.RetentionPolicy{
+			ArchiveAfter: time.Duration(retention.ArchiveAfterDays) * day,
+			DeleteAfter:  time.Duration(retention.DeleteAfterDays) * day,
+			ExportDir:    retention.ExportDir,
+		}, logger)
+		leaderTasks = append(leaderTasks, func(ctx context.Context) {
+			alertRetention.Start(ctx, retentionInterval)
+		})
+	}
+
+	// Start alert digests
+	if cfg.Alerts.Digest.Enabled {
+		digestInterval, err := time.ParseDuration(cfg.Alerts.Digest.Interval)
+		if err != nil || digestInterval <= 0 {
+			digestInterval = time.Hour
+		}
+		var mailer *alerts.Mailer
+		if email := cfg.Alerts.Digest.Email; email.SMTPHost != "" {
+			password, err := secretResolver.Resolve(context.Background(), email.Password)
+			if err != nil {
+				return nil, fmt.Errorf("failed to resolve alert digest SMTP password: %w", err)
+			}
+			mailer = alerts.NewMailer(alerts.MailConfig{
+				Host:      email.SMTPHost,
+				Port:      email.SMTPPort,
+				Username:  email.Username,
+				Password:  password,
+				From:      email.From,
+				To:        email.To,
+				Templates: emailTemplates,
+			})
+		}
+		digest := alerts.NewDigest(db, webhookService, mailer, logger)
+		leaderTasks = append(leaderTasks, func(ctx context.Context) {
+			digest.Start(ctx, digestInterval)
+		})
+	}
+
+	// Start config version pruning
+	if cfg.ConfigVersions.MaxVersions > 0 || cfg.ConfigVersions.MaxAgeDays > 0 {
+		pruneInterval, err := time.ParseDuration(cfg.ConfigVersions.PruneInterval)
+		if err != nil || pruneInterval <= 0 {
+			pruneInterval = time.Hour
+		}
+		leaderTasks = append(leaderTasks, func(ctx context.Context) {
+			configVersions.Start(ctx, pruneInterval)
+		})
+	}
+
+	// Start GitOps sync
+	if server.gitopsSyncer != nil {
+		gitopsInterval, err := time.ParseDuration(cfg.GitOps.Interval)
+		if err != nil {
+			gitopsInterval = 5 * time.Minute
+		}
+		leaderTasks = append(leaderTasks, func(ctx context.Context) {
+			server.gitopsSyncer.Start(ctx, gitopsInterval)
+		})
+	}
+
+	if cfg.HA.Enabled {
+		server.elector = newElector(cfg.HA, db, logger)
+		go server.elector.Run(context.Background(), func(ctx context.Context) {
+			for _, task := range leaderTasks {
+				go task(ctx)
+			}
+		})
+	} else {
+		for _, task := range leaderTasks {
+			go task(context.Background())
+		}
+	}
+
+	return server, nil
+}
+
+// Services are the services an API server's handlers call into
+type Services struct {
+	DB             *database.DB
+	WSHub          *websocket.Hub
+	BGP            BGPService
+	ConfigVersions ConfigVersionStore
+	Webhooks       *webhooks.Service // optional
+	JWTManager     *authpkg.JWTManager
+	Denylist       *authpkg.Denylist
+	Store          store.Store  // optional, in memory when nil
+	Cache          *cache.Cache // optional
+	// Repositories hold the alerts, users and config versions handlers
+	// read and write; those left nil use GORM on DB. Peers and sessions go
+	// through BGP.
+	Repositories repository.Repositories
+	FRRLog       *frrlog.Log // optional, kept in DB when nil
+	Logger       *zap.Logger
+	// Alerts stores the alerts the server raises; when nil, repeats aren't
+	// folded together and storms aren't held back
+	Alerts *alerts.Deduplicator
+}
 
+// NewServerWithServices creates an API server around services that are
+// already built, with its routes set up. Unlike NewServer it connects to
+// nothing and starts no background work, so it is ready at once; tests use
+// it to run handlers against a mocked BGP service.
+func NewServerWithServices(cfg *config.Config, services Services) *Server {
+	server := newServer(cfg, services)
+	server.startup.markReady()
+	// Jobs are queued but not run until the queue is started
+	server.jobs = jobs.NewQueue(services.DB, services.WSHub, cfg.Jobs.Workers, services.Logger)
+	server.registerJobs()
+	server.setupRoutes()
 	return server
 }
 
+// newServer creates a server and its router around services, without
+// routes
+func newServer(cfg *config.Config, services Services) *Server {
+	router := gin.New()
+	router.Use(gin.Recovery())
+	router.Use(requestid.Middleware())
+	router.Use(otelgin.Middleware(tracing.ServiceName))
+	router.Use(corsMiddleware())
+	router.Use(loggingMiddleware(services.Logger, cfg.Logging.RequestSampleRate))
+	router.Use(gzipMiddleware())
+
+	sharedStore := services.Store
+	if sharedStore == nil {
+		sharedStore = store.NewMemory()
+	}
+
+	repos := repository.New(services.DB)
+	if services.Repositories.Alerts != nil {
+		repos.Alerts = services.Repositories.Alerts
+	}
+	if services.Repositories.Users != nil {
+		repos.Users = services.Repositories.Users
+	}
+	if services.Repositories.Configs != nil {
+		repos.Configs = services.Repositories.Configs
+	}
+	frrLog := services.FRRLog
+	if frrLog == nil {
+		frrLog = frrlog.NewLog(services.DB, services.Logger)
+	}
+	alertDedup := services.Alerts
+	if alertDedup == nil {
+		alertDedup = alerts.NewDeduplicator(services.DB, alerts.DedupPolicy{}, services.Logger)
+	}
+
+	return &Server{
+		router:         router,
+		config:         cfg,
+		db:             services.DB,
+		repos:          repos,
+		wsHub:          services.WSHub,
+		bgpService:     services.BGP,
+		configVersions: services.ConfigVersions,
+		frrLog:         frrLog,
+		webhookService: services.Webhooks,
+		alertDedup:     alertDedup,
+		jwtManager:     services.JWTManager,
+		cache:          services.Cache,
+		denylist:       services.Denylist,
+		store:          sharedStore,
+		logger:         services.Logger,
+		startup:        newStartup(services.Logger),
+		readOnly:       newReadOnly(cfg.Server.ReadOnly),
+		usage:          newUsageTracker(services.DB, sharedStore, cfg.Server.RequestQuota),
+		activity:       activity.NewFeed(services.DB),
+		tenancy:        cfg.Tenancy.Enabled,
+	}
+}
+
+// newElector creates the elector for HA operation, named after the host
+// unless ha.identity is set
+func newElector(cfg config.HAConfig, db *database.DB, logger *zap.Logger) *leader.Elector {
+	identity := cfg.Identity
+	if identity == "" {
+		identity, _ = os.Hostname()
+	}
+	leaseDuration, err := time.ParseDuration(cfg.LeaseDuration)
+	if err != nil {
+		leaseDuration = 15 * time.Second
+	}
+	renewInterval, err := time.ParseDuration(cfg.RenewInterval)
+	if err != nil {
+		renewInterval = 5 * time.Second
+	}
+	return leader.NewElector(db, leader.Config{
+		Identity:      identity,
+		LeaseDuration: leaseDuration,
+		RenewInterval: renewInterval,
+	}, logger)
+}
+
+// setupGitOps creates the syncer that applies definitions from the
+// configured Git repository
+func (s *Server) setupGitOps(resolver *secrets.Resolver, bgpService *bgp.Service, versions *configstore.Store) error {
+	cfg := s.config.GitOps
+
+	repoURL, err := resolver.Resolve(context.Background(), cfg.RepoURL)
+	if err != nil {
+		return fmt.Errorf("failed to resolve GitOps repository URL: %w", err)
+	}
+	s.gitopsWebhookSecret, err = resolver.Resolve(context.Background(), cfg.WebhookSecret)
+	if err != nil {
+		return fmt.Errorf("failed to resolve GitOps webhook secret: %w", err)
+	}
+
+	source := gitops.NewGitSource(repoURL, cfg.Branch, cfg.CheckoutDir)
+	s.gitopsSyncer = gitops.NewSyncer(source, bgpService, s.db, gitops.Options{
+		Path:     cfg.Path,
+		Prune:    cfg.Prune,
+		Versions: versions,
+	}, s.logger)
+
+	return nil
+}
+
+// newConfigStore creates the config version store with the configured
+// storage backend, resolving the S3 secret access key
+func newConfigStore(cfg config.ConfigVersionsConfig, db *database.DB, resolver *secrets.Resolver, logger *zap.Logger) (*configstore.Store, error) {
+	opts := configstore.Options{
+		Compress:    cfg.Compress,
+		MinBlobSize: cfg.Storage.MinSize,
+		MaxVersions: cfg.MaxVersions,
+		MaxAge:      time.Duration(cfg.MaxAgeDays) * 24 * time.Hour,
+	}
+
+	switch cfg.Storage.Backend {
+	case "filesystem":
+		opts.Blobs = configstore.NewFilesystemStore(cfg.Storage.Dir)
+	case "s3":
+		var err error
+		opts.Blobs, err = newS3Store(cfg.Storage.S3, resolver)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return configstore.New(db, opts, logger), nil
+}
+
+// newSnapshotStore creates the store of database snapshots
+func newSnapshotStore(cfg config.SnapshotsConfig, db *database.DB, resolver *secrets.Resolver, logger *zap.Logger) (*snapshots.Store, error) {
+	var blobs configstore.BlobStore = configstore.NewFilesystemStore(cfg.Dir)
+	if cfg.Backend == "s3" {
+		var err error
+		blobs, err = newS3Store(cfg.S3, resolver)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return snapshots.New(db, blobs, logger), nil
+}
+
+// newS3Store creates an S3 blob store, resolving the secret access key
+func newS3Store(cfg config.S3Config, resolver *secrets.Resolver) (*configstore.S3Store, error) {
+	secretKey, err := resolver.Resolve(context.Background(), cfg.SecretAccessKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve S3 secret access key: %w", err)
+	}
+	return configstore.NewS3Store(configstore.S3Options{
+		Endpoint:        cfg.Endpoint,
+		Region:          cfg.Region,
+		Bucket:          cfg.Bucket,
+		Prefix:          cfg.Prefix,
+		AccessKeyID:     cfg.AccessKeyID,
+		SecretAccessKey: secretKey,
+		UsePathStyle:    cfg.UsePathStyle,
+	})
+}
+
+// newStore connects the store selected in cfg, resolving the Redis
+// password if it is a secret reference
+func newStore(cfg config.StoreConfig, resolver *secrets.Resolver) (store.Store, error) {
+	password, err := resolver.Resolve(context.Background(), cfg.Redis.Password)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve store redis password: %w", err)
+	}
+	st, err := store.New(store.Config{
+		Backend: cfg.Backend,
+		Redis: store.RedisConfig{
+			Address:   cfg.Redis.Address,
+			Username:  cfg.Redis.Username,
+			Password:  password,
+			DB:        cfg.Redis.DB,
+			KeyPrefix: cfg.Redis.KeyPrefix,
+			TLS:       cfg.Redis.TLS,
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create store: %w", err)
+	}
+	return st, nil
+}
+
+// newFRRClient creates the FRR client for the configured transport. It is
+// connected by the startup flow.
+func newFRRClient(cfg config.FRRConfig, logger *zap.Logger) (frr.FRRClient, error) {
+	if cfg.Demo.Enabled {
+		return newDemoClient(cfg.Demo, logger), nil
+	}
+	if cfg.Transport != "vtysh" {
+		return frr.NewClient(cfg.GRPCHost, cfg.GRPCPort, logger)
+	}
+
+	timeout, err := time.ParseDuration(cfg.Vtysh.Timeout)
+	if err != nil {
+		timeout = 10 * time.Second
+	}
+
+	return frr.NewVtyshClient(frr.VtyshOptions{
+		Path:      cfg.Vtysh.Path,
+		SocketDir: cfg.Vtysh.SocketDir,
+		Timeout:   timeout,
+	}, logger), nil
+}
+
+// newFRRLimiter wraps the FRR client with the configured limits
+func newFRRLimiter(client frr.FRRClient, cfg config.FRRLimitsConfig, logger *zap.Logger) *frr.LimitedClient {
+	openDuration, err := time.ParseDuration(cfg.OpenDuration)
+	if err != nil || openDuration <= 0 {
+		openDuration = 30 * time.Second
+	}
+
+	return frr.NewLimitedClient(client, frr.LimitOptions{
+		Rate:             cfg.Rate,
+		Burst:            cfg.Burst,
+		MaxConcurrent:    cfg.MaxConcurrent,
+		FailureThreshold: cfg.FailureThreshold,
+		OpenDuration:     openDuration,
+	}, logger)
+}
+
+// newDemoClient creates the FRR simulator of demo mode
+func newDemoClient(cfg config.DemoConfig, logger *zap.Logger) *frr.DemoClient {
+	delay, err := time.ParseDuration(cfg.SessionStateDelay)
+	if err != nil || delay < 0 {
+		delay = 2 * time.Second
+	}
+	flapInterval, err := time.ParseDuration(cfg.FlapInterval)
+	if err != nil || flapInterval < 0 {
+		flapInterval = 30 * time.Minute
+	}
+
+	return frr.NewDemoClient(frr.DemoOptions{
+		SessionStateDelay: delay,
+		FlapInterval:      flapInterval,
+	}, logger)
+}
+
+// newTrapSender creates the SNMP trap sender, resolving the community and
+// v3 passphrases
+func newTrapSender(cfg config.SNMPConfig, resolver *secrets.Resolver, logger *zap.Logger) (*snmp.Sender, error) {
+	community, err := resolver.Resolve(context.Background(), cfg.Community)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve SNMP community: %w", err)
+	}
+	authPassphrase, err := resolver.Resolve(context.Background(), cfg.V3.AuthPassphrase)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve SNMPv3 auth passphrase: %w", err)
+	}
+	privPassphrase, err := resolver.Resolve(context.Background(), cfg.V3.PrivPassphrase)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve SNMPv3 privacy passphrase: %w", err)
+	}
+
+	timeout, err := time.ParseDuration(cfg.Timeout)
+	if err != nil {
+		timeout = 5 * time.Second
+	}
+
+	sender, err := snmp.NewSender(snmp.Config{
+		Targets:   cfg.Targets,
+		Version:   cfg.Version,
+		Community: community,
+		V3: snmp.V3Config{
+			Username:       cfg.V3.Username,
+			SecurityLevel:  cfg.V3.SecurityLevel,
+			AuthProtocol:   cfg.V3.AuthProtocol,
+			AuthPassphrase: authPassphrase,
+			PrivProtocol:   cfg.V3.PrivProtocol,
+			PrivPassphrase: privPassphrase,
+			EngineID:       cfg.V3.EngineID,
+		},
+		Severities:    cfg.Severities,
+		EnterpriseOID: cfg.EnterpriseOID,
+		Router:        cfg.Router,
+		Timeout:       timeout,
+		Retries:       cfg.Retries,
+	}, logger)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create SNMP trap sender: %w", err)
+	}
+
+	return sender, nil
+}
+
+// newSecretResolver creates a resolver for secret:// references. The
+// Vault provider is only registered when an address is configured.
+func newSecretResolver(cfg config.SecretsConfig) *secrets.Resolver {
+	providers := []secrets.Provider{
+		secrets.NewEnvProvider(),
+		secrets.NewFileProvider(cfg.File.BaseDir),
+	}
+	if cfg.Vault.Address != "" {
+		providers = append(providers, secrets.NewVaultProvider(cfg.Vault.Address, cfg.Vault.Token, cfg.Vault.Mount))
+	}
+	return secrets.NewResolver(providers...)
+}
+
+// newJWTManager creates the JWT manager from the configured signing key,
+// falling back to the shared secret. It returns the configuration value the
+// key was read from and its resolved value, for watching rotations.
+func newJWTManager(cfg config.AuthConfig, resolver *secrets.Resolver, tokenExpiry, refreshExpiry time.Duration) (*authpkg.JWTManager, string, string, error) {
+	var manager *authpkg.JWTManager
+	ref := cfg.SigningKey
+	if ref == "" {
+		ref = cfg.JWTSecret
+	}
+	key, err := resolver.Resolve(context.Background(), ref)
+	if err != nil {
+		return nil, "", "", fmt.Errorf("failed to resolve JWT signing key: %w", err)
+	}
+
+	if cfg.SigningKey == "" {
+		manager = authpkg.NewJWTManager(key, tokenExpiry, refreshExpiry)
+	} else {
+		manager, err = authpkg.NewJWTManagerWithKey(key, tokenExpiry, refreshExpiry)
+		if err != nil {
+			return nil, "", "", fmt.Errorf("failed to load JWT signing key: %w", err)
+		}
+	}
+
+	for _, keyRef := range cfg.VerificationKeys {
+		publicKey, err := resolver.Resolve(context.Background(), keyRef)
+		if err != nil {
+			return nil, "", "", fmt.Errorf("failed to resolve JWT verification key: %w", err)
+		}
+		if err := manager.AddVerificationKey(publicKey); err != nil {
+			return nil, "", "", fmt.Errorf("failed to load JWT verification key: %w", err)
+		}
+	}
+
+	return manager, ref, key, nil
+}
+
+// newPasswordCipher creates the cipher used to encrypt BGP peer passwords
+// from the configured key, falling back to the key file. The key is
+// resolved once at startup; rotating it requires re-encrypting stored
+// passwords and is not picked up at runtime.
+func newPasswordCipher(cfg config.DatabaseConfig, resolver *secrets.Resolver) (*encryption.Cipher, error) {
+	var key []byte
+	var err error
+	if cfg.EncryptionKey != "" {
+		var encoded string
+		encoded, err = resolver.Resolve(context.Background(), cfg.EncryptionKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve encryption key: %w", err)
+		}
+		key, err = encryption.ParseKey(encoded)
+	} else {
+		key, err = encryption.LoadOrCreateKey(cfg.EncryptionKeyFile)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load encryption key: %w", err)
+	}
+
+	return encryption.NewCipher(key)
+}
+
 // setupRoutes configures all API routes
 func (s *Server) setupRoutes() {
 	// Health check
 	s.router.GET("/health", s.handleHealth)
+	s.router.GET("/health/ready", s.handleReady)
+
+	// Prometheus metrics
+	s.router.GET("/metrics", s.metricsHandler())
+
+	// Public keys for validating FlintRoute tokens
+	s.router.GET("/.well-known/jwks.json", s.handleJWKS)
 
 	// API v1
 	v1 := s.router.Group("/api/v1")
+	v1.Use(s.leaderMiddleware(), s.startupMiddleware(), s.readOnlyMiddleware())
 	{
 		// Public routes
 		auth := v1.Group("/auth")
@@ -96,49 +1063,280 @@ func (s *Server) setupRoutes() {
 			auth.POST("/refresh", s.handleRefreshToken)
 		}
 
+		// GitOps push webhook, authenticated by the shared webhook secret
+		if s.gitopsSyncer != nil && s.gitopsWebhookSecret != "" {
+			v1.POST("/gitops/webhook", s.handleGitOpsWebhook)
+		}
+
+		// Alertmanager webhook receiver, authenticated by the shared token
+		if s.alertmanagerToken != "" {
+			v1.POST("/alertmanager/webhook", s.handleAlertmanagerWebhook)
+		}
+
 		// Protected routes
 		protected := v1.Group("")
-		protected.Use(authpkg.AuthMiddleware(s.jwtManager))
+		protected.Use(authpkg.AuthMiddleware(s.jwtManager, s.denylist), s.frrInitiatorMiddleware(), s.impersonationAuditMiddleware(), s.usageMiddleware(), s.idempotencyMiddleware())
 		{
 			// Auth
 			protected.POST("/auth/logout", s.handleLogout)
 
+			// Current user's profile, open to every role
+			me := protected.Group("/me")
+			{
+				me.GET("", s.handleGetProfile)
+				me.PUT("", s.handleUpdateProfile)
+				me.PUT("/password", s.handleChangePassword)
+				me.GET("/sessions", s.handleListLoginSessions)
+				me.DELETE("/sessions/:id", s.handleRevokeLoginSession)
+				me.GET("/preferences", s.handleGetPreferences)
+				me.PUT("/preferences", s.handleUpdatePreferences)
+				me.GET("/notifications", s.handleGetNotificationSettings)
+				me.PUT("/notifications", s.handleUpdateNotificationSettings)
+				me.DELETE("/notifications", s.handleResetNotificationSettings)
+				me.GET("/tenants", s.handleListMyTenants)
+			}
+
+			// API usage and quota of the current user
+			protected.GET("/usage", s.handleGetUsage)
+
+			// Users can read; changes need an operator
+			readWrite := authpkg.RequireRoles(authpkg.RoleUser, authpkg.RoleOperator)
+			// Peers, sessions, alerts, config versions and activity belong
+			// to a tenant; the tenant's membership role replaces the user's
+			tenant := s.tenantMiddleware()
+			// Configuration shared by every tenant is for admins only while
+			// multi-tenancy is enabled
+			shared := s.sharedMiddleware()
+
 			// BGP Peers
-			peers := protected.Group("/bgp/peers")
+			peers := protected.Group("/bgp/peers", tenant, readWrite)
 			{
 				peers.GET("", s.handleListPeers)
+				peers.GET("/search", s.handleSearchPeers)
 				peers.POST("", s.handleCreatePeer)
+				peers.POST("/bulk", s.handleBulkPeers)
+				peers.POST("/adopt", s.handleAdoptNeighbors)
+				peers.GET("/trash", s.handleListDeletedPeers)
+				peers.POST("/trash/purge", s.handlePurgeDeletedPeers)
+				peers.GET("/schedule", s.handleListScheduledPeerActions)
+				peers.POST("/:id/restore", s.handleRestorePeer)
 				peers.GET("/:id", s.handleGetPeer)
+				peers.GET("/:id/frr-config", s.handleGetPeerFRRConfig)
+				peers.POST("/:id/test", s.handleTestPeer)
+				peers.GET("/:id/reachability", s.handleGetPeerReachability)
+				peers.GET("/:id/state-timeline", s.handleGetPeerStateTimeline)
+				peers.GET("/:id/transactions", s.handleListPeerFRRTransactions)
 				peers.PUT("/:id", s.handleUpdatePeer)
+				peers.PATCH("/:id", s.handlePatchPeer)
+				peers.PUT("/:id/password", s.handleSetPeerPassword)
+				peers.POST("/:id/maintenance", s.handleStartMaintenance)
+				peers.DELETE("/:id/maintenance", s.handleEndMaintenance)
+				peers.GET("/:id/schedule", s.handleListPeerScheduledActions)
+				peers.POST("/:id/schedule", s.handleSchedulePeerAction)
+				peers.DELETE("/:id/schedule/:job_id", s.handleCancelPeerAction)
+				peers.PUT("/:id/demo-state", s.handlePinDemoState)
+				peers.DELETE("/:id/demo-state", s.handleUnpinDemoState)
 				peers.DELETE("/:id", s.handleDeletePeer)
 			}
 
+			// Peer templates
+			templates := protected.Group("/bgp/peer-templates", shared, readWrite)
+			{
+				templates.GET("", s.handleListPeerTemplates)
+				templates.POST("", s.handleCreatePeerTemplate)
+				templates.GET("/:id", s.handleGetPeerTemplate)
+				templates.PUT("/:id", s.handleUpdatePeerTemplate)
+				templates.DELETE("/:id", s.handleDeletePeerTemplate)
+				templates.POST("/:id/sync", s.handleSyncPeerTemplate)
+			}
+
+			// PeeringDB lookups to pre-fill peers
+			protected.GET("/peeringdb/asn/:asn", readWrite, s.handleLookupPeeringDB)
+
+			// BGP global configuration
+			global := protected.Group("/bgp/global", shared, readWrite)
+			{
+				global.GET("", s.handleGetBGPGlobal)
+				global.PUT("", s.handleUpdateBGPGlobal)
+			}
+
 			// BGP Sessions
-			sessions := protected.Group("/bgp/sessions")
+			sessions := protected.Group("/bgp/sessions", tenant, readWrite)
 			{
 				sessions.GET("", s.handleListSessions)
+				sessions.GET("/export", s.handleExportSessions)
 				sessions.GET("/:id", s.handleGetSession)
 			}
 
+			// BGP policy lists
+			communityLists := protected.Group("/bgp/community-lists", shared, readWrite)
+			{
+				communityLists.GET("", s.handleListCommunityLists)
+				communityLists.POST("", s.handleCreateCommunityList)
+				communityLists.GET("/:name", s.handleGetCommunityList)
+				communityLists.PUT("/:name", s.handleUpdateCommunityList)
+				communityLists.DELETE("/:name", s.handleDeleteCommunityList)
+			}
+
+			asPathLists := protected.Group("/bgp/as-path-lists", shared, readWrite)
+			{
+				asPathLists.GET("", s.handleListASPathLists)
+				asPathLists.POST("", s.handleCreateASPathList)
+				asPathLists.GET("/:name", s.handleGetASPathList)
+				asPathLists.PUT("/:name", s.handleUpdateASPathList)
+				asPathLists.DELETE("/:name", s.handleDeleteASPathList)
+			}
+
+			// Drift detection and peer sync
+			protected.GET("/bgp/drift", tenant, readWrite, s.handleGetDrift)
+			protected.POST("/bgp/reconcile", shared, readWrite, s.handleReconcile)
+			protected.GET("/bgp/sync", tenant, readWrite, s.handleGetPeerSync)
+
+			// Which instance leads when several share the database
+			protected.GET("/leader", readWrite, s.handleGetLeader)
+
+			// FRR version and daemons, for clients to gate features on
+			protected.GET("/frr/status", readWrite, s.handleGetFRRStatus)
+
+			// Changes pushed to FRR
+			protected.GET("/frr/transactions", tenant, readWrite, s.handleListFRRTransactions)
+			protected.GET("/frr/transactions/:id", tenant, readWrite, s.handleGetFRRTransaction)
+			protected.POST("/frr/transactions/:id/backup", tenant, readWrite, s.handleBackupFRRTransaction)
+
+			// Session monitor health and polling statistics
+			protected.GET("/monitoring/status", readWrite, s.handleGetMonitoring)
+
+			// Prefix count analysis
+			protected.GET("/bgp/top-talkers", tenant, readWrite, s.handleGetTopTalkers)
+			protected.GET("/bgp/stats", tenant, readWrite, s.handleGetSessionStats)
+
+			// Reports
+			protected.GET("/reports/sla", tenant, readWrite, s.handleSLAReport)
+			protected.GET("/bgp/anomalies", tenant, readWrite, s.handleGetAnomalies)
+			protected.POST("/bgp/anomalies/analyze", shared, readWrite, s.handleAnalyzePrefixes)
+			protected.GET("/bgp/reachability", tenant, readWrite, s.handleGetReachability)
+			protected.POST("/bgp/reachability/probe", tenant, readWrite, s.handleProbeReachability)
+
+			// Declarative state export and import; it includes the users
+			protected.GET("/state", authpkg.AdminMiddleware(), s.handleExportState)
+			protected.POST("/state", authpkg.AdminMiddleware(), s.handleImportState)
+
+			// Administration
+			admin := protected.Group("/admin", authpkg.AdminMiddleware())
+			{
+				admin.GET("/audit", s.handleListAuditEntries)
+				admin.GET("/cache", s.handleGetCacheStats)
+				admin.GET("/database/snapshots", s.handleListSnapshots)
+				admin.POST("/database/snapshots", s.handleCreateSnapshot)
+				admin.GET("/database/snapshots/:id", s.handleDownloadSnapshot)
+				admin.DELETE("/database/snapshots/:id", s.handleDeleteSnapshot)
+				admin.POST("/database/restore/:id", s.handleRestoreSnapshot)
+				admin.GET("/log-level", s.handleGetLogLevel)
+				admin.PUT("/log-level", s.handleSetLogLevel)
+				admin.GET("/read-only", s.handleGetReadOnly)
+				admin.PUT("/read-only", s.handleSetReadOnly)
+				admin.GET("/monitoring", s.handleGetMonitoring)
+				admin.POST("/email/test", s.handleSendTestEmail)
+				admin.GET("/notifications/defaults", s.handleGetNotificationDefaults)
+				admin.PUT("/notifications/defaults", s.handleUpdateNotificationDefaults)
+				admin.POST("/monitoring/pause", s.handlePauseMonitoring)
+				admin.POST("/monitoring/resume", s.handleResumeMonitoring)
+				admin.POST("/impersonate/:user_id", s.handleImpersonate)
+				admin.POST("/users/:id/disable", s.handleDisableUser)
+				admin.POST("/users/:id/enable", s.handleEnableUser)
+				admin.PUT("/users/:id/quota", s.handleSetRequestQuota)
+				admin.GET("/usage", s.handleListUsage)
+				admin.GET("/websocket", s.handleGetWebSocketStats)
+				admin.GET("/tenants", s.handleListTenants)
+				admin.POST("/tenants", s.handleCreateTenant)
+				admin.GET("/tenants/:id", s.handleGetTenant)
+				admin.PUT("/tenants/:id", s.handleUpdateTenant)
+				admin.DELETE("/tenants/:id", s.handleDeleteTenant)
+				admin.GET("/tenants/:id/members", s.handleListTenantMembers)
+				admin.PUT("/tenants/:id/members/:user", s.handleSetTenantMember)
+				admin.DELETE("/tenants/:id/members/:user", s.handleRemoveTenantMember)
+			}
+
+			// GitOps
+			if s.gitopsSyncer != nil {
+				gitopsRoutes := protected.Group("/gitops", readWrite)
+				{
+					gitopsRoutes.GET("/status", s.handleGitOpsStatus)
+					gitopsRoutes.GET("/plan", s.handleGitOpsPlan)
+					gitopsRoutes.POST("/sync", s.handleGitOpsSync)
+				}
+			}
+
 			// Configuration
-			configRoutes := protected.Group("/config")
+			configRoutes := protected.Group("/config", tenant, readWrite)
 			{
+				configRoutes.GET("/running", s.handleGetRunningConfig)
 				configRoutes.GET("/versions", s.handleListConfigVersions)
+				configRoutes.PATCH("/versions/:id", s.handleUpdateConfigVersion)
+				configRoutes.GET("/versions/:id/transactions", s.handleListConfigVersionFRRTransactions)
 				configRoutes.POST("/backup", s.handleBackupConfig)
+				configRoutes.GET("/restore/:id/preview", s.handleRestorePreview)
 				configRoutes.POST("/restore/:id", s.handleRestoreConfig)
+				configRoutes.POST("/import-running", s.handleImportRunningConfig)
+			}
+
+			// Background jobs
+			protected.GET("/jobs/:id", tenant, readWrite, s.handleGetJob)
+
+			// Activity feed
+			protected.GET("/activity", tenant, readWrite, s.handleListActivity)
+
+			// Change requests; an admin other than the requester reviews them,
+			// for the tenant they select if any
+			if s.changes != nil {
+				changeRoutes := protected.Group("/changes")
+				{
+					changeRoutes.GET("", tenant, readWrite, s.handleListChanges)
+					changeRoutes.GET("/:id", tenant, readWrite, s.handleGetChange)
+					changeRoutes.POST("/:id/approve", authpkg.AdminMiddleware(), tenant, s.handleApproveChange)
+					changeRoutes.POST("/:id/reject", authpkg.AdminMiddleware(), tenant, s.handleRejectChange)
+				}
+			}
+
+			// Webhooks, which deliver every tenant's events
+			webhookRoutes := protected.Group("/webhooks", shared, readWrite)
+			{
+				webhookRoutes.GET("", s.handleListWebhooks)
+				webhookRoutes.POST("", s.handleCreateWebhook)
+				webhookRoutes.GET("/events", s.handleListWebhookEvents)
+				webhookRoutes.GET("/:id", s.handleGetWebhook)
+				webhookRoutes.PUT("/:id", s.handleUpdateWebhook)
+				webhookRoutes.DELETE("/:id", s.handleDeleteWebhook)
+				webhookRoutes.POST("/:id/ping", s.handlePingWebhook)
+				webhookRoutes.GET("/:id/deliveries", s.handleListWebhookDeliveries)
+				webhookRoutes.POST("/deliveries/:id/redeliver", s.handleRedeliverWebhook)
 			}
 
 			// Alerts
-			alerts := protected.Group("/alerts")
+			alerts := protected.Group("/alerts", tenant, readWrite)
 			{
 				alerts.GET("", s.handleListAlerts)
+				alerts.GET("/summary", s.handleAlertSummary)
+				alerts.GET("/export", s.handleExportAlerts)
+				alerts.POST("/acknowledge-all", s.handleAcknowledgeAllAlerts)
 				alerts.POST("/:id/acknowledge", s.handleAcknowledgeAlert)
 			}
 
+			// Alertmanager export
+			protected.GET("/alertmanager/alerts", tenant, readWrite, s.handleAlertmanagerExport)
+
 			// WebSocket
-			protected.GET("/ws", func(c *gin.Context) {
+			protected.GET("/ws", tenant, func(c *gin.Context) {
 				s.wsHub.HandleWebSocket(c)
 			})
+
+			// Server-Sent Events
+			protected.GET("/events", tenant, func(c *gin.Context) {
+				s.wsHub.HandleSSE(c)
+			})
+			protected.GET("/events/history", tenant, func(c *gin.Context) {
+				s.wsHub.HandleHistory(c)
+			})
 		}
 	}
 
@@ -150,7 +1348,7 @@ func (s *Server) setupRoutes() {
 	s.router.NoRoute(func(c *gin.Context) {
 		// If it's an API route, return 404 JSON
 		if strings.HasPrefix(c.Request.URL.Path, "/api/") {
-			c.JSON(http.StatusNotFound, gin.H{"error": "endpoint not found"})
+			apierror.Respond(c, http.StatusNotFound, apierror.CodeNotFound, "endpoint not found")
 			return
 		}
 		// Otherwise serve the React app
@@ -168,14 +1366,43 @@ func (s *Server) Start(addr string) error {
 		IdleTimeout:  60 * time.Second,
 	}
 
+	if s.grpcServer != nil {
+		grpcAddr := net.JoinHostPort(s.config.Server.Host, strconv.Itoa(s.config.Server.GRPCPort))
+		listener, err := net.Listen("tcp", grpcAddr)
+		if err != nil {
+			return fmt.Errorf("failed to listen for gRPC: %w", err)
+		}
+		s.logger.Info("Starting gRPC server", zap.String("address", grpcAddr))
+		go func() {
+			if err := s.grpcServer.Serve(listener); err != nil {
+				s.logger.Error("gRPC server stopped", zap.Error(err))
+			}
+		}()
+	}
+
 	s.logger.Info("Starting HTTP server", zap.String("address", addr))
 	return s.httpServer.ListenAndServe()
 }
 
+// SetLogLevel lets admins change the level of the server's logger at runtime
+// through /api/v1/admin/log-level. level must be the one the logger passed to
+// NewServer was built with.
+func (s *Server) SetLogLevel(level zap.AtomicLevel) {
+	s.logLevel = &level
+}
+
 // Shutdown gracefully shuts down the server
 func (s *Server) Shutdown(ctx context.Context) error {
 	s.logger.Info("Shutting down HTTP server")
-	return s.httpServer.Shutdown(ctx)
+	err := s.httpServer.Shutdown(ctx)
+	if s.grpcServer != nil {
+		s.grpcServer.GracefulStop()
+	}
+	s.frrLog.Wait()
+	if closeErr := s.store.Close(); closeErr != nil {
+		s.logger.Warn("Failed to close shared store", zap.Error(closeErr))
+	}
+	return err
 }
 
 // handleHealth handles health check requests
@@ -186,13 +1413,54 @@ func (s *Server) handleHealth(c *gin.Context) {
 	})
 }
 
+// handleReady handles readiness checks. FlintRoute is ready once startup
+// has finished and while its database answers; FRR reachability and the
+// session monitor's last poll are reported but don't affect readiness, so an
+// FRR outage doesn't take the API down with it.
+func (s *Server) handleReady(c *gin.Context) {
+	status, code := "ready", http.StatusOK
+	sqlDB, err := s.db.DB.DB()
+	if err == nil {
+		err = sqlDB.PingContext(c.Request.Context())
+	}
+	switch {
+	case err != nil:
+		s.logger.Warn("Readiness check failed", zap.Error(err))
+		status, code = "unavailable", http.StatusServiceUnavailable
+	case s.elector != nil && !s.elector.IsLeader():
+		status, code = "standby", http.StatusServiceUnavailable
+	case s.startup != nil && !s.startup.Ready():
+		status, code = "starting", http.StatusServiceUnavailable
+	}
+
+	body := gin.H{
+		"status":     status,
+		"time":       time.Now().Unix(),
+		"monitoring": s.bgpService.MonitorStatus(),
+	}
+	if s.startup != nil {
+		body["startup"] = s.startup.Status()
+	}
+	if s.readOnly != nil {
+		body["read_only"] = s.readOnly.Enabled()
+	}
+	if s.elector != nil {
+		body["leader"] = s.elector.Status()
+	}
+	if s.frrLimiter != nil {
+		body["frr_limits"] = s.frrLimiter.Stats()
+	}
+	c.JSON(code, body)
+}
+
 // corsMiddleware adds CORS headers
 func corsMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		c.Writer.Header().Set("Access-Control-Allow-Origin", "*")
 		c.Writer.Header().Set("Access-Control-Allow-Credentials", "true")
-		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, accept, origin, Cache-Control, X-Requested-With")
-		c.Writer.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS, GET, PUT, DELETE")
+		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, accept, origin, Cache-Control, X-Requested-With, X-Request-ID")
+		c.Writer.Header().Set("Access-Control-Expose-Headers", "X-Request-ID")
+		c.Writer.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS, GET, PUT, PATCH, DELETE")
 
 		if c.Request.Method == "OPTIONS" {
 			c.AbortWithStatus(204)
@@ -203,8 +1471,12 @@ func corsMiddleware() gin.HandlerFunc {
 	}
 }
 
-// loggingMiddleware logs HTTP requests
-func loggingMiddleware(logger *zap.Logger) gin.HandlerFunc {
+// slowRequestThreshold is the latency above which a request is always logged
+const slowRequestThreshold = time.Second
+
+// loggingMiddleware logs HTTP requests. Only sampleRate of the successful
+// ones are logged; failed and slow requests always are.
+func loggingMiddleware(logger *zap.Logger, sampleRate float64) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		start := time.Now()
 		path := c.Request.URL.Path
@@ -215,13 +1487,27 @@ func loggingMiddleware(logger *zap.Logger) gin.HandlerFunc {
 		latency := time.Since(start)
 		statusCode := c.Writer.Status()
 
-		logger.Info("HTTP request",
+		// Requests made while impersonating are always logged
+		impersonator, impersonating := authpkg.GetImpersonator(c)
+		if statusCode < http.StatusBadRequest && latency < slowRequestThreshold && !impersonating &&
+			sampleRate < 1 && rand.Float64() >= sampleRate {
+			return
+		}
+
+		fields := []zap.Field{
 			zap.String("method", c.Request.Method),
 			zap.String("path", path),
 			zap.String("query", query),
 			zap.Int("status", statusCode),
 			zap.Duration("latency", latency),
 			zap.String("ip", c.ClientIP()),
-		)
+			requestid.Field(c.Request.Context()),
+			tracing.Field(c.Request.Context()),
+		}
+		if impersonating {
+			username, _ := authpkg.GetUsername(c)
+			fields = append(fields, zap.String("username", username), zap.String("impersonator", impersonator))
+		}
+		logger.Info("HTTP request", fields...)
 	}
-}
\ No newline at end of file
+}
@@ -0,0 +1,101 @@
+package api
+
+import (
+	"context"
+	"time"
+
+	"github.com/padminisys/flintroute/internal/bgp"
+	"github.com/padminisys/flintroute/internal/configstore"
+	"github.com/padminisys/flintroute/internal/frr"
+	"github.com/padminisys/flintroute/pkg/models"
+)
+
+// BGPService is the BGP service the handlers call into. *bgp.Service
+// implements it; handler tests substitute a mock.
+type BGPService interface {
+	// Peers
+	ListPeers(ctx context.Context, selectors ...bgp.TagSelector) ([]*models.BGPPeer, error)
+	SearchPeers(ctx context.Context, search bgp.PeerSearch) (*bgp.PeerSearchResult, error)
+	GetPeer(ctx context.Context, id uint) (*models.BGPPeer, error)
+	PeerFRRConfig(ctx context.Context, id uint) (string, error)
+	TestPeer(ctx context.Context, id uint) (*bgp.PeerTest, error)
+	PlanCreatePeer(ctx context.Context, peer *models.BGPPeer) (*bgp.PeerPlan, error)
+	CreatePeer(ctx context.Context, peer *models.BGPPeer) error
+	PlanUpdatePeer(ctx context.Context, id uint, updates *models.BGPPeer) (*bgp.PeerPlan, error)
+	UpdatePeer(ctx context.Context, id uint, updates *models.BGPPeer) error
+	PlanPatchPeer(ctx context.Context, id uint, patch *bgp.PeerPatch) (*bgp.PeerPlan, error)
+	PatchPeer(ctx context.Context, id uint, patch *bgp.PeerPatch) error
+	SetPeerPassword(ctx context.Context, id uint, password string) error
+	DeletePeer(ctx context.Context, id uint) error
+	BulkUpdatePeers(ctx context.Context, selectors []bgp.TagSelector, action string) ([]*bgp.BulkResult, error)
+	StartMaintenance(ctx context.Context, id uint, until *time.Time) (*models.BGPPeer, error)
+	EndMaintenance(ctx context.Context, id uint) (*models.BGPPeer, error)
+	RunPeerAction(ctx context.Context, id uint, action string) (*models.BGPPeer, error)
+	AnnouncePeerAction(ctx context.Context, id uint, action string, runAt time.Time)
+
+	// Trash
+	ListDeletedPeers(ctx context.Context) ([]*bgp.DeletedPeer, error)
+	RestorePeer(ctx context.Context, id uint) (*models.BGPPeer, error)
+	PurgeDeletedPeers(ctx context.Context, olderThan time.Duration) ([]*models.BGPPeer, error)
+
+	// Peer templates
+	ListTemplates(ctx context.Context) ([]*models.PeerTemplate, error)
+	GetTemplate(ctx context.Context, id uint) (*models.PeerTemplate, error)
+	ResolveTemplate(ctx context.Context, id uint) (*models.PeerTemplateSettings, error)
+	ApplyTemplate(ctx context.Context, peer *models.BGPPeer, templateID uint, fields []string) error
+	CreateTemplate(ctx context.Context, template *models.PeerTemplate) error
+	UpdateTemplate(ctx context.Context, id uint, updates *models.PeerTemplate) (*models.PeerTemplate, error)
+	DeleteTemplate(ctx context.Context, id uint) error
+	SyncTemplate(ctx context.Context, id uint) ([]*bgp.BulkResult, error)
+
+	// Sessions and monitoring
+	ListSessions(ctx context.Context, selectors ...bgp.TagSelector) ([]*models.BGPSession, error)
+	GetSession(ctx context.Context, peerID uint) (*models.BGPSession, error)
+	TopTalkers(ctx context.Context, metric string, limit int) ([]*bgp.TopTalker, error)
+	SessionStatistics(ctx context.Context, by, tagKey string) (*bgp.StatsReport, error)
+	SLAReport(ctx context.Context, window time.Duration, selectors ...bgp.TagSelector) (*bgp.SLAReport, error)
+	AnalyzePrefixes(ctx context.Context) (*bgp.AnomalyReport, error)
+	LastAnomalyReport(ctx context.Context) *bgp.AnomalyReport
+	ProbeReachability(ctx context.Context) (*bgp.ReachabilityReport, error)
+	LastReachabilityReport(ctx context.Context) *bgp.ReachabilityReport
+	PeerReachabilityHistory(ctx context.Context, id uint, since time.Time) ([]models.ReachabilitySample, error)
+	PeerStateTimeline(ctx context.Context, id uint, start, end time.Time, bucket time.Duration) (*bgp.StateTimeline, error)
+	MonitorStatus() bgp.MonitorStatus
+	PauseMonitoring()
+	ResumeMonitoring()
+
+	// FRR configuration
+	GetRunningConfig(ctx context.Context) (string, error)
+	FRRStatus(ctx context.Context) (*frr.Status, error)
+	ImportRunningConfig(ctx context.Context, dryRun bool) (*bgp.ImportReport, error)
+	AdoptNeighbors(ctx context.Context, addresses []string) (*bgp.AdoptReport, error)
+	DetectDrift(ctx context.Context) (*bgp.DriftReport, error)
+	Reconcile(ctx context.Context) (*bgp.DriftReport, error)
+	SyncAllPeers(ctx context.Context, trigger string) (*bgp.PeerSyncReport, error)
+	LastSyncReport(ctx context.Context) *bgp.PeerSyncReport
+
+	// Global configuration and policies
+	GetGlobalConfig(ctx context.Context) (*models.BGPGlobalConfig, error)
+	SaveGlobalConfig(ctx context.Context, cfg *models.BGPGlobalConfig) error
+	ListCommunityLists(ctx context.Context) ([]*models.CommunityList, error)
+	GetCommunityList(ctx context.Context, name string) (*models.CommunityList, error)
+	SaveCommunityList(ctx context.Context, list *models.CommunityList) error
+	DeleteCommunityList(ctx context.Context, name string) error
+	ListASPathLists(ctx context.Context) ([]*models.ASPathList, error)
+	GetASPathList(ctx context.Context, name string) (*models.ASPathList, error)
+	SaveASPathList(ctx context.Context, list *models.ASPathList) error
+	DeleteASPathList(ctx context.Context, name string) error
+}
+
+// ConfigVersionStore stores the configuration of config versions, which
+// may be held outside the database. *configstore.Store implements it.
+type ConfigVersionStore interface {
+	Save(ctx context.Context, version *models.ConfigVersion) error
+	Load(ctx context.Context, version *models.ConfigVersion) error
+	LoadAll(ctx context.Context, versions []models.ConfigVersion) error
+}
+
+var (
+	_ BGPService         = (*bgp.Service)(nil)
+	_ ConfigVersionStore = (*configstore.Store)(nil)
+)
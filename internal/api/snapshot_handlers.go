@@ -0,0 +1,191 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/padminisys/flintroute/internal/apierror"
+	authpkg "github.com/padminisys/flintroute/internal/auth"
+	"github.com/padminisys/flintroute/internal/bgp"
+	"github.com/padminisys/flintroute/internal/database"
+	"github.com/padminisys/flintroute/internal/snapshots"
+	"go.uber.org/zap"
+)
+
+// CreateSnapshotRequest represents a request to snapshot the database
+type CreateSnapshotRequest struct {
+	Note string `json:"note"`
+}
+
+// RestoreSnapshotRequest represents a request to restore a database
+// snapshot. Confirm must be set, as a restore replaces the current peers,
+// policies, users and alerts.
+type RestoreSnapshotRequest struct {
+	Confirm bool `json:"confirm"`
+}
+
+// RestoreSnapshotResponse describes a completed restore and the peer sync
+// that followed it
+type RestoreSnapshotResponse struct {
+	*snapshots.RestoreResult
+	Sync    *bgp.PeerSyncReport `json:"sync,omitempty"`
+	Warning string              `json:"warning,omitempty"`
+}
+
+// handleListSnapshots handles listing database snapshots
+func (s *Server) handleListSnapshots(c *gin.Context) {
+	list, err := s.snapshots.List(c.Request.Context())
+	if err != nil {
+		s.logger.Error("Failed to list database snapshots", zap.Error(err))
+		apierror.Respond(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to list snapshots")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"snapshots": list})
+}
+
+// handleCreateSnapshot handles snapshotting the database
+func (s *Server) handleCreateSnapshot(c *gin.Context) {
+	var req CreateSnapshotRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			apierror.Validation(c, err)
+			return
+		}
+	}
+
+	username, _ := authpkg.GetUsername(c)
+	snapshot, err := s.snapshots.Create(c.Request.Context(), req.Note, username)
+	if err != nil {
+		s.respondSnapshotError(c, err, "Failed to snapshot database")
+		return
+	}
+
+	c.JSON(http.StatusCreated, snapshot)
+}
+
+// handleDownloadSnapshot handles downloading a database snapshot as a
+// SQLite file
+func (s *Server) handleDownloadSnapshot(c *gin.Context) {
+	id, ok := snapshotID(c)
+	if !ok {
+		return
+	}
+
+	snapshot, data, err := s.snapshots.Read(c.Request.Context(), id)
+	if err != nil {
+		s.respondSnapshotError(c, err, "Failed to read snapshot")
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", snapshot.Key))
+	c.Header("X-Checksum-Sha256", snapshot.Checksum)
+	c.Data(http.StatusOK, "application/vnd.sqlite3", data)
+}
+
+// handleDeleteSnapshot handles deleting a database snapshot
+func (s *Server) handleDeleteSnapshot(c *gin.Context) {
+	id, ok := snapshotID(c)
+	if !ok {
+		return
+	}
+
+	username, _ := authpkg.GetUsername(c)
+	if err := s.snapshots.Delete(c.Request.Context(), id, username); err != nil {
+		s.respondSnapshotError(c, err, "Failed to delete snapshot")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Snapshot deleted successfully"})
+}
+
+// handleRestoreSnapshot handles restoring the database from a snapshot.
+// The database is snapshotted first, and every enabled peer is pushed to
+// FRR afterwards so that it matches the restored peers.
+func (s *Server) handleRestoreSnapshot(c *gin.Context) {
+	id, ok := snapshotID(c)
+	if !ok {
+		return
+	}
+
+	var req RestoreSnapshotRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apierror.Validation(c, err)
+		return
+	}
+	if !req.Confirm {
+		apierror.Respond(c, http.StatusBadRequest, apierror.CodeValidationFailed,
+			"Restoring a snapshot replaces the current database; set confirm to true",
+			apierror.FieldError{Field: "confirm", Message: "must be true"})
+		return
+	}
+
+	username, _ := authpkg.GetUsername(c)
+	result, err := s.snapshots.Restore(c.Request.Context(), id, username)
+	if err != nil {
+		s.respondSnapshotError(c, err, "Failed to restore snapshot")
+		return
+	}
+	s.cache.Invalidate(bgp.CachePeers, bgp.CacheSessions)
+
+	resp := RestoreSnapshotResponse{RestoreResult: result}
+	if s.bgpService != nil {
+		resp.Sync, err = s.bgpService.SyncAllPeers(c.Request.Context(), bgp.SyncTriggerRestore)
+		if err != nil {
+			s.logger.Error("Failed to sync BGP peers after restore", zap.Error(err))
+			resp.Warning = "Database restored, but syncing peers to FRR failed: " + err.Error()
+		}
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// handleListAuditEntries handles listing a page of the audit log, newest
+// first
+func (s *Server) handleListAuditEntries(c *gin.Context) {
+	page, ok := pageQuery(c)
+	if !ok {
+		return
+	}
+
+	entries, next, err := s.snapshots.AuditLog(c.Request.Context(), page)
+	if err != nil {
+		s.logger.Error("Failed to list audit entries", zap.Error(err))
+		apierror.Respond(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to list audit entries")
+		return
+	}
+
+	c.JSON(http.StatusOK, withNextCursor(gin.H{"entries": entries}, next))
+}
+
+// snapshotID parses the snapshot ID path parameter, responding with an
+// error if it is invalid
+func snapshotID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		apierror.Respond(c, http.StatusBadRequest, apierror.CodeInvalidID, "Invalid snapshot ID")
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// respondSnapshotError maps snapshot store errors to responses
+func (s *Server) respondSnapshotError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, snapshots.ErrSnapshotNotFound):
+		apierror.Respond(c, http.StatusNotFound, apierror.CodeSnapshotNotFound, "Snapshot not found")
+	case errors.Is(err, snapshots.ErrChecksumMismatch),
+		errors.Is(err, database.ErrInvalidSnapshot),
+		errors.Is(err, database.ErrUnknownSchemaVersion):
+		s.logger.Warn(message, zap.Error(err))
+		apierror.Respond(c, http.StatusUnprocessableEntity, apierror.CodeInvalidSnapshot, err.Error())
+	case errors.Is(err, database.ErrSnapshotsUnsupported):
+		apierror.Respond(c, http.StatusNotImplemented, apierror.CodeUnsupportedDriver, err.Error())
+	default:
+		s.logger.Error(message, zap.Error(err))
+		apierror.Respond(c, http.StatusInternalServerError, apierror.CodeInternal, message)
+	}
+}
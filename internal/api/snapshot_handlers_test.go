@@ -0,0 +1,133 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/padminisys/flintroute/internal/apierror"
+	"github.com/padminisys/flintroute/internal/configstore"
+	"github.com/padminisys/flintroute/internal/snapshots"
+	"github.com/padminisys/flintroute/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSnapshotHandlers(t *testing.T) {
+	server, db := setupTestServer(t)
+	server.snapshots = snapshots.New(server.db, configstore.NewFilesystemStore(t.TempDir()), server.logger)
+
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set("username", "admin")
+		c.Next()
+	})
+	router.GET("/audit", server.handleListAuditEntries)
+	router.GET("/database/snapshots", server.handleListSnapshots)
+	router.POST("/database/snapshots", server.handleCreateSnapshot)
+	router.GET("/database/snapshots/:id", server.handleDownloadSnapshot)
+	router.DELETE("/database/snapshots/:id", server.handleDeleteSnapshot)
+	router.POST("/database/restore/:id", server.handleRestoreSnapshot)
+
+	request := func(method, path, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, path, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	require.NoError(t, db.Create(&models.BGPPeer{Name: "kept", IPAddress: "192.0.2.1", ASN: 65000, RemoteASN: 65001}).Error)
+
+	w := request("POST", "/database/snapshots", `{"note":"before maintenance"}`)
+	require.Equal(t, http.StatusCreated, w.Code)
+	var snapshot models.DatabaseSnapshot
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &snapshot))
+	assert.Equal(t, "before maintenance", snapshot.Note)
+	assert.Equal(t, "admin", snapshot.CreatedBy)
+
+	t.Run("Lists snapshots", func(t *testing.T) {
+		w := request("GET", "/database/snapshots", "")
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var resp struct {
+			Snapshots []models.DatabaseSnapshot `json:"snapshots"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		require.Len(t, resp.Snapshots, 1)
+		assert.Equal(t, snapshot.ID, resp.Snapshots[0].ID)
+	})
+
+	t.Run("Downloads a snapshot", func(t *testing.T) {
+		w := request("GET", "/database/snapshots/1", "")
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "application/vnd.sqlite3", w.Header().Get("Content-Type"))
+		assert.Contains(t, w.Header().Get("Content-Disposition"), snapshot.Key)
+		assert.Equal(t, snapshot.Checksum, w.Header().Get("X-Checksum-Sha256"))
+		assert.Equal(t, "SQLite format 3\x00", w.Body.String()[:16])
+	})
+
+	t.Run("Restore needs confirmation", func(t *testing.T) {
+		w := request("POST", "/database/restore/1", `{}`)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("Restores a snapshot", func(t *testing.T) {
+		require.NoError(t, db.Create(&models.BGPPeer{Name: "added", IPAddress: "192.0.2.2", ASN: 65000, RemoteASN: 65002}).Error)
+
+		w := request("POST", "/database/restore/1", `{"confirm":true}`)
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var resp RestoreSnapshotResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.Equal(t, snapshot.ID, resp.Snapshot.ID)
+		assert.NotZero(t, resp.Backup.ID)
+
+		var peers int64
+		require.NoError(t, db.Model(&models.BGPPeer{}).Count(&peers).Error)
+		assert.Equal(t, int64(1), peers)
+	})
+
+	t.Run("Audit log records the restore", func(t *testing.T) {
+		w := request("GET", "/audit?limit=1", "")
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var resp struct {
+			Entries    []models.AuditEntry `json:"entries"`
+			NextCursor string              `json:"next_cursor"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		require.Len(t, resp.Entries, 1)
+		assert.Equal(t, snapshots.ActionRestore, resp.Entries[0].Action)
+		assert.Equal(t, "admin", resp.Entries[0].Username)
+		require.NotEmpty(t, resp.NextCursor)
+
+		// The next page continues with the older entries
+		restore := resp.Entries[0]
+		w = request("GET", "/audit?limit=1&cursor="+resp.NextCursor, "")
+		require.Equal(t, http.StatusOK, w.Code)
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		require.Len(t, resp.Entries, 1)
+		assert.Less(t, resp.Entries[0].ID, restore.ID)
+
+		w = request("GET", "/audit?cursor=not-a-cursor", "")
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("Unknown snapshot", func(t *testing.T) {
+		w := request("DELETE", "/database/snapshots/42", "")
+		require.Equal(t, http.StatusNotFound, w.Code)
+
+		var resp apierror.Response
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.Equal(t, apierror.CodeSnapshotNotFound, resp.Code)
+	})
+
+	t.Run("Deletes a snapshot", func(t *testing.T) {
+		assert.Equal(t, http.StatusOK, request("DELETE", "/database/snapshots/1", "").Code)
+		assert.Equal(t, http.StatusNotFound, request("GET", "/database/snapshots/1", "").Code)
+	})
+}
@@ -0,0 +1,309 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/padminisys/flintroute/internal/apierror"
+	"github.com/padminisys/flintroute/internal/bgp"
+	"github.com/padminisys/flintroute/internal/database"
+	"github.com/padminisys/flintroute/internal/frr"
+	"go.uber.org/zap"
+)
+
+// Startup stages, in the order the server goes through them
+const (
+	StageMigrate    = "migrate"
+	StageConnectFRR = "connect_frr"
+	StageReconcile  = "reconcile"
+	// StageReady is the state once every stage has finished
+	StageReady = "ready"
+)
+
+// Startup stage statuses
+const (
+	StagePending = "pending"
+	StageRunning = "running"
+	StageDone    = "done"
+	StageFailed  = "failed"
+	StageSkipped = "skipped"
+)
+
+// frrConnectRetry is how long startup waits between FRR connection attempts
+const frrConnectRetry = 5 * time.Second
+
+// StartupStage is the progress of one startup stage
+type StartupStage struct {
+	Name       string     `json:"name"`
+	Status     string     `json:"status"`
+	Detail     string     `json:"detail,omitempty"`
+	StartedAt  *time.Time `json:"started_at,omitempty"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+}
+
+// StartupStatus is the server's progress from a migrated database to
+// accepting changes
+type StartupStatus struct {
+	// State is the running stage, or "ready"
+	State string `json:"state"`
+	Ready bool   `json:"ready"`
+	// Degraded reports that the server became ready although a stage
+	// failed, e.g. FRR was not reachable in time
+	Degraded bool `json:"degraded"`
+	// Progress is the percentage of stages finished
+	Progress  int            `json:"progress"`
+	StartedAt time.Time      `json:"started_at"`
+	ReadyAt   *time.Time     `json:"ready_at,omitempty"`
+	Stages    []StartupStage `json:"stages"`
+}
+
+// startup tracks the server's startup stages. Every transition is logged.
+type startup struct {
+	mu        sync.RWMutex
+	logger    *zap.Logger
+	retry     time.Duration
+	startedAt time.Time
+	readyAt   *time.Time
+	stages    []*StartupStage
+	current   int
+}
+
+// newStartup returns a startup with every stage pending
+func newStartup(logger *zap.Logger) *startup {
+	st := &startup{
+		logger: logger,
+		retry:  frrConnectRetry,
+	}
+	st.reset()
+	return st
+}
+
+// reset marks every stage pending again, for an instance that takes over
+// as leader after having led before
+func (st *startup) reset() {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	st.startedAt = time.Now()
+	st.readyAt = nil
+	st.current = 0
+	st.stages = nil
+	for _, name := range []string{StageMigrate, StageConnectFRR, StageReconcile} {
+		st.stages = append(st.stages, &StartupStage{Name: name, Status: StagePending})
+	}
+}
+
+// begin marks the next stage as running
+func (st *startup) begin() {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	now := time.Now()
+	stage := st.stages[st.current]
+	stage.Status = StageRunning
+	stage.StartedAt = &now
+
+	st.logger.Info("Startup stage started",
+		zap.String("stage", stage.Name),
+		zap.Int("progress", st.progressLocked()),
+	)
+}
+
+// finish ends the running stage with status and moves on to the next one
+func (st *startup) finish(status, detail string) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	now := time.Now()
+	stage := st.stages[st.current]
+	stage.Status = status
+	stage.Detail = detail
+	stage.FinishedAt = &now
+	st.current++
+
+	fields := []zap.Field{
+		zap.String("stage", stage.Name),
+		zap.String("status", status),
+		zap.String("detail", detail),
+		zap.Duration("duration", now.Sub(*stage.StartedAt)),
+		zap.Int("progress", st.progressLocked()),
+	}
+	if status == StageFailed {
+		st.logger.Warn("Startup stage failed", fields...)
+	} else {
+		st.logger.Info("Startup stage finished", fields...)
+	}
+}
+
+// markReady ends startup. Stages that never ran are skipped.
+func (st *startup) markReady() {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	for ; st.current < len(st.stages); st.current++ {
+		st.stages[st.current].Status = StageSkipped
+	}
+	now := time.Now()
+	st.readyAt = &now
+
+	st.logger.Info("FlintRoute is ready",
+		zap.Duration("startup", now.Sub(st.startedAt)),
+		zap.Bool("degraded", st.degradedLocked()),
+	)
+}
+
+// Ready reports whether startup has finished
+func (st *startup) Ready() bool {
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+	return st.readyAt != nil
+}
+
+// Status returns a copy of the startup progress
+func (st *startup) Status() StartupStatus {
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+
+	status := StartupStatus{
+		State:     StageReady,
+		Ready:     st.readyAt != nil,
+		Degraded:  st.degradedLocked(),
+		Progress:  st.progressLocked(),
+		StartedAt: st.startedAt,
+		ReadyAt:   st.readyAt,
+		Stages:    make([]StartupStage, len(st.stages)),
+	}
+	for i, stage := range st.stages {
+		status.Stages[i] = *stage
+	}
+	if !status.Ready && st.current < len(st.stages) {
+		status.State = st.stages[st.current].Name
+	}
+	return status
+}
+
+// progressLocked returns the percentage of finished stages
+func (st *startup) progressLocked() int {
+	return st.current * 100 / len(st.stages)
+}
+
+// degradedLocked reports whether a stage failed
+func (st *startup) degradedLocked() bool {
+	for _, stage := range st.stages {
+		if stage.Status == StageFailed {
+			return true
+		}
+	}
+	return false
+}
+
+// runStartup takes the server from a migrated database to ready: it checks
+// the schema version, connects to FRR and pushes stored peers before
+// checking for drift. FRR not connecting within timeout doesn't hold the
+// server back for good; it becomes ready degraded and keeps connecting in
+// the background, and the session monitor syncs peers once FRR is
+// reachable.
+func (s *Server) runStartup(ctx context.Context, frrClient frr.FRRClient, timeout time.Duration) {
+	st := s.startup
+
+	// The database is migrated before the server is created
+	st.begin()
+	if version, err := database.SchemaVersion(s.db.DB); err != nil {
+		st.finish(StageFailed, err.Error())
+	} else {
+		st.finish(StageDone, "schema version "+version)
+	}
+
+	st.begin()
+	if frrClient == nil {
+		st.finish(StageFailed, "no FRR client")
+		st.markReady()
+		return
+	}
+	connectCtx, cancel := context.WithTimeout(ctx, timeout)
+	err := connectFRR(connectCtx, frrClient, st.retry, s.logger)
+	cancel()
+	if err != nil {
+		st.finish(StageFailed, fmt.Sprintf("FRR not reachable after %s: %s", timeout, err))
+		st.markReady()
+		go connectFRR(ctx, frrClient, st.retry, s.logger)
+		return
+	}
+	st.finish(StageDone, "connected")
+
+	st.begin()
+	if detail, err := s.reconcileAtStartup(ctx); err != nil {
+		st.finish(StageFailed, err.Error())
+	} else {
+		st.finish(StageDone, detail)
+	}
+
+	st.markReady()
+}
+
+// connectFRR connects client, retrying every retry until ctx is done. It
+// returns the last connection error.
+func connectFRR(ctx context.Context, client frr.FRRClient, retry time.Duration, logger *zap.Logger) error {
+	for attempt := 1; ; attempt++ {
+		if client.IsConnected() {
+			return nil
+		}
+		err := client.Connect(ctx)
+		if err == nil {
+			return nil
+		}
+		logger.Debug("Waiting for FRR", zap.Int("attempt", attempt), zap.Error(err))
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(retry):
+		}
+	}
+}
+
+// reconcileAtStartup pushes stored peers to FRR, which may have started
+// without them, and runs a first reconciliation pass
+func (s *Server) reconcileAtStartup(ctx context.Context) (string, error) {
+	report, err := s.bgpService.SyncAllPeers(ctx, bgp.SyncTriggerStartup)
+	if err != nil {
+		return "", fmt.Errorf("failed to sync peers: %w", err)
+	}
+	drift, err := s.bgpService.Reconcile(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to reconcile: %w", err)
+	}
+	return fmt.Sprintf("%d peers applied, %d failed, %d drift entries",
+		report.Applied, report.Failed, len(drift.Entries)), nil
+}
+
+// isChange reports whether a request changes anything, other than signing
+// in and out
+func isChange(c *gin.Context) bool {
+	switch c.Request.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return false
+	}
+	return !strings.HasPrefix(c.Request.URL.Path, "/api/v1/auth/")
+}
+
+// startupMiddleware rejects changes with 503 until startup has finished, so
+// nothing is written while FRR may still lack the stored peers. Reads, and
+// signing in and out, are served throughout.
+func (s *Server) startupMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if s.startup == nil || s.startup.Ready() || !isChange(c) {
+			c.Next()
+			return
+		}
+
+		c.Header("Retry-After", "5")
+		apierror.Respond(c, http.StatusServiceUnavailable, apierror.CodeStarting,
+			fmt.Sprintf("FlintRoute is starting (%s); changes are accepted once it is ready", s.startup.Status().State))
+	}
+}
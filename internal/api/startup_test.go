@@ -0,0 +1,136 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/padminisys/flintroute/internal/apierror"
+	"github.com/padminisys/flintroute/internal/bgp"
+	"github.com/padminisys/flintroute/internal/frr"
+	"github.com/padminisys/flintroute/internal/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunStartup(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Connects, syncs and becomes ready", func(t *testing.T) {
+		server, _ := setupTestServer(t)
+		client := frr.NewMockClient()
+		client.On("IsConnected").Return(false).Twice()
+		client.On("Connect", mock.Anything).Return(errors.New("connection refused")).Once()
+		client.On("Connect", mock.Anything).Return(nil).Once()
+		client.On("IsConnected").Return(true)
+		client.On("ListBGPNeighbors", mock.Anything).Return([]*frr.BGPNeighbor{}, nil)
+		server.bgpService = bgp.NewService(server.db, client, websocket.NewHub(server.logger), bgp.ServiceConfig{}, server.logger)
+		server.startup = newStartup(server.logger)
+		server.startup.retry = time.Millisecond
+
+		server.runStartup(ctx, client, time.Second)
+		client.AssertExpectations(t)
+
+		status := server.startup.Status()
+		assert.True(t, status.Ready)
+		assert.False(t, status.Degraded)
+		assert.Equal(t, StageReady, status.State)
+		assert.Equal(t, 100, status.Progress)
+		require.Len(t, status.Stages, 3)
+		for _, stage := range status.Stages {
+			assert.Equal(t, StageDone, stage.Status, stage.Name)
+		}
+		assert.Contains(t, status.Stages[0].Detail, "schema version")
+		assert.Equal(t, "0 peers applied, 0 failed, 0 drift entries", status.Stages[2].Detail)
+		assert.NotNil(t, server.bgpService.LastSyncReport(context.Background()))
+	})
+
+	t.Run("Ready degraded when FRR doesn't connect in time", func(t *testing.T) {
+		server, _ := setupTestServer(t)
+		client := frr.NewMockClient()
+		client.On("IsConnected").Return(false)
+		client.On("Connect", mock.Anything).Return(errors.New("connection refused"))
+		server.bgpService = bgp.NewService(server.db, client, websocket.NewHub(server.logger), bgp.ServiceConfig{}, server.logger)
+		server.startup = newStartup(server.logger)
+		server.startup.retry = time.Millisecond
+
+		runCtx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		server.runStartup(runCtx, client, 20*time.Millisecond)
+
+		status := server.startup.Status()
+		assert.True(t, status.Ready)
+		assert.True(t, status.Degraded)
+		assert.Equal(t, StageFailed, status.Stages[1].Status)
+		assert.Contains(t, status.Stages[1].Detail, "connection refused")
+		assert.Equal(t, StageSkipped, status.Stages[2].Status)
+		assert.Nil(t, server.bgpService.LastSyncReport(context.Background()))
+	})
+}
+
+func TestStartupMiddleware(t *testing.T) {
+	server, _ := setupTestServer(t)
+	server.router = gin.New()
+	server.startup = newStartup(server.logger)
+	server.bgpService = bgp.NewService(server.db, frr.NewMockClient(), websocket.NewHub(server.logger), bgp.ServiceConfig{}, server.logger)
+
+	v1 := server.router.Group("/api/v1")
+	v1.Use(server.startupMiddleware())
+	for _, method := range []string{"GET", "POST", "DELETE"} {
+		v1.Handle(method, "/bgp/peers", func(c *gin.Context) { c.Status(http.StatusNoContent) })
+	}
+	v1.POST("/auth/login", func(c *gin.Context) { c.Status(http.StatusNoContent) })
+	server.router.GET("/health/ready", server.handleReady)
+
+	request := func(method, path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, path, nil)
+		server.router.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("Changes wait for startup", func(t *testing.T) {
+		for _, method := range []string{"POST", "DELETE"} {
+			w := request(method, "/api/v1/bgp/peers")
+			assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+			assert.Equal(t, "5", w.Header().Get("Retry-After"))
+
+			var resp apierror.Response
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+			assert.Equal(t, apierror.CodeStarting, resp.Code)
+			assert.Contains(t, resp.Error, StageMigrate)
+		}
+	})
+
+	t.Run("Reads and sign in are served", func(t *testing.T) {
+		assert.Equal(t, http.StatusNoContent, request("GET", "/api/v1/bgp/peers").Code)
+		assert.Equal(t, http.StatusNoContent, request("POST", "/api/v1/auth/login").Code)
+	})
+
+	t.Run("Readiness reports progress", func(t *testing.T) {
+		w := request("GET", "/health/ready")
+		assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+
+		var resp struct {
+			Status  string        `json:"status"`
+			Startup StartupStatus `json:"startup"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.Equal(t, "starting", resp.Status)
+		assert.Equal(t, StageMigrate, resp.Startup.State)
+		assert.Equal(t, 0, resp.Startup.Progress)
+	})
+
+	t.Run("Changes accepted once ready", func(t *testing.T) {
+		server.startup.markReady()
+
+		assert.Equal(t, http.StatusNoContent, request("POST", "/api/v1/bgp/peers").Code)
+		assert.Equal(t, http.StatusOK, request("GET", "/health/ready").Code)
+	})
+}
@@ -0,0 +1,107 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/padminisys/flintroute/internal/alerts"
+	"github.com/padminisys/flintroute/internal/apierror"
+	authpkg "github.com/padminisys/flintroute/internal/auth"
+	"github.com/padminisys/flintroute/internal/bgp"
+	"github.com/padminisys/flintroute/internal/frr"
+	"github.com/padminisys/flintroute/internal/state"
+	"github.com/padminisys/flintroute/internal/webhooks"
+	"go.uber.org/zap"
+)
+
+// maxStateBody limits the size of imported state documents
+const maxStateBody = 10 << 20
+
+// handleExportState handles downloading the instance's declarative state
+// as a YAML document
+func (s *Server) handleExportState(c *gin.Context) {
+	doc, err := s.stateService.Export(c.Request.Context())
+	if err != nil {
+		s.logger.Error("Failed to export state", zap.Error(err))
+		apierror.Respond(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to export state")
+		return
+	}
+
+	data, err := state.Marshal(doc)
+	if err != nil {
+		s.logger.Error("Failed to encode state", zap.Error(err))
+		apierror.Respond(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to export state")
+		return
+	}
+
+	filename := fmt.Sprintf("flintroute-state-%s.yaml", doc.ExportedAt.Format("20060102T150405Z"))
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	c.Data(http.StatusOK, "application/yaml", data)
+}
+
+// handleImportState handles loading a YAML or JSON state document. With
+// dry_run=true the changes are only listed; with prune=true objects the
+// document leaves out are deleted.
+func (s *Server) handleImportState(c *gin.Context) {
+	userID, exists := authpkg.GetUserID(c)
+	if !exists {
+		apierror.Respond(c, http.StatusUnauthorized, apierror.CodeUnauthorized, "User not authenticated")
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, maxStateBody))
+	if err != nil {
+		apierror.Respond(c, http.StatusBadRequest, apierror.CodeValidationFailed, "Failed to read request body")
+		return
+	}
+	doc, err := state.Parse(body)
+	if err != nil {
+		apierror.Respond(c, http.StatusUnprocessableEntity, apierror.CodeInvalidState, err.Error())
+		return
+	}
+
+	result, err := s.stateService.Import(c.Request.Context(), doc, state.ImportOptions{
+		DryRun: c.Query("dry_run") == "true",
+		Prune:  c.Query("prune") == "true",
+		UserID: userID,
+	})
+	if err != nil {
+		s.respondStateError(c, err, result)
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// respondStateError maps an import error to an API error. Validation and
+// conflict messages are returned as-is so the document can be fixed.
+func (s *Server) respondStateError(c *gin.Context, err error, result *state.Result) {
+	fields := []zap.Field{zap.Error(err)}
+	if result != nil {
+		fields = append(fields, zap.Int("applied", len(result.Changes)))
+	}
+	s.logger.Error("Failed to import state", fields...)
+
+	switch {
+	case errors.Is(err, state.ErrConflict):
+		apierror.Respond(c, http.StatusConflict, apierror.CodeStateConflict, err.Error())
+	case errors.Is(err, bgp.ErrInvalidPeer), errors.Is(err, bgp.ErrInvalidGlobalConfig),
+		errors.Is(err, bgp.ErrASNMismatch), errors.Is(err, bgp.ErrInvalidTemplate),
+		errors.Is(err, bgp.ErrTemplateInUse), errors.Is(err, bgp.ErrInvalidPolicy),
+		errors.Is(err, bgp.ErrPolicyInUse), errors.Is(err, bgp.ErrPeerExists),
+		errors.Is(err, webhooks.ErrInvalidSubscription), errors.Is(err, alerts.ErrInvalidSettings):
+		apierror.Respond(c, http.StatusUnprocessableEntity, apierror.CodeInvalidState, err.Error())
+	case respondFRRRejection(c, err):
+	case errors.Is(err, bgp.ErrFRRApplyFailed):
+		code := apierror.CodeFRRApplyFailed
+		if errors.Is(err, frr.ErrNotConnected) {
+			code = apierror.CodeFRRUnavailable
+		}
+		apierror.Respond(c, http.StatusBadGateway, code, "Failed to import state: FRR rejected the change")
+	default:
+		apierror.Respond(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to import state")
+	}
+}
@@ -0,0 +1,82 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/padminisys/flintroute/internal/alerts"
+	"github.com/padminisys/flintroute/internal/bgp"
+	"github.com/padminisys/flintroute/internal/frr"
+	"github.com/padminisys/flintroute/internal/state"
+	"github.com/padminisys/flintroute/internal/webhooks"
+	"github.com/padminisys/flintroute/internal/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandleState(t *testing.T) {
+	server, _ := setupTestServer(t)
+	demo := frr.NewDemoClient(frr.DemoOptions{}, server.logger)
+	require.NoError(t, demo.Connect(context.Background()))
+	defer demo.Close()
+	bgpService := bgp.NewService(server.db, demo, websocket.NewHub(server.logger), bgp.ServiceConfig{}, server.logger)
+	server.bgpService = bgpService
+	server.stateService = state.NewService(server.db, bgpService, webhooks.NewService(server.db, nil, webhooks.Config{}, server.logger),
+		alerts.NewNotifier(server.db, nil, server.logger), server.logger)
+
+	router := gin.New()
+	router.Use(func(c *gin.Context) { c.Set("user_id", uint(1)) })
+	router.GET("/state", server.handleExportState)
+	router.POST("/state", server.handleImportState)
+
+	doc := `
+version: 1
+peers:
+  - name: upstream
+    ip_address: 192.0.2.1
+    asn: 65001
+    remote_asn: 65002
+`
+
+	t.Run("Export as YAML", func(t *testing.T) {
+		w := profileRequest(router, "GET", "/state", "")
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		assert.Equal(t, "application/yaml", w.Header().Get("Content-Type"))
+		assert.Contains(t, w.Header().Get("Content-Disposition"), "flintroute-state-")
+		assert.Contains(t, w.Body.String(), "version: 1\n")
+		assert.Contains(t, w.Body.String(), "username: admin\n")
+		assert.NotContains(t, w.Body.String(), "password")
+	})
+
+	t.Run("Dry run and apply", func(t *testing.T) {
+		w := profileRequest(router, "POST", "/state?dry_run=true", doc)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		assert.JSONEq(t, `{"dry_run": true, "changes": [{"action": "create", "kind": "peer", "name": "192.0.2.1"}]}`, w.Body.String())
+
+		peers, err := bgpService.ListPeers(context.Background())
+		require.NoError(t, err)
+		assert.Empty(t, peers)
+
+		w = profileRequest(router, "POST", "/state", doc)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		assert.Contains(t, w.Body.String(), `"dry_run":false`)
+
+		peers, err = bgpService.ListPeers(context.Background())
+		require.NoError(t, err)
+		assert.Len(t, peers, 1)
+	})
+
+	t.Run("Invalid documents", func(t *testing.T) {
+		w := profileRequest(router, "POST", "/state", "version: 1\npeers:\n  - ip_address: nowhere\n")
+		assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+		assert.Contains(t, w.Body.String(), "INVALID_STATE")
+	})
+
+	t.Run("Conflicts", func(t *testing.T) {
+		w := profileRequest(router, "POST", "/state", `{"version": 1, "peers": [{"name": "upstream", "ip_address": "192.0.2.1", "asn": 65001, "remote_asn": 65009}]}`)
+		assert.Equal(t, http.StatusConflict, w.Code)
+		assert.Contains(t, w.Body.String(), "STATE_CONFLICT")
+	})
+}
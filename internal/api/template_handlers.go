@@ -0,0 +1,183 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/padminisys/flintroute/internal/apierror"
+	"github.com/padminisys/flintroute/internal/bgp"
+	"github.com/padminisys/flintroute/pkg/models"
+	"go.uber.org/zap"
+)
+
+// PeerTemplateRequest represents a request to create or replace a peer
+// template. Settings left out are inherited from the parent template.
+type PeerTemplateRequest struct {
+	Name        string                      `json:"name" binding:"required"`
+	Description string                      `json:"description"`
+	ParentID    *uint                       `json:"parent_id"`
+	Settings    models.PeerTemplateSettings `json:"settings"`
+}
+
+// template returns the template the request describes
+func (r *PeerTemplateRequest) template() *models.PeerTemplate {
+	return &models.PeerTemplate{
+		Name:        r.Name,
+		Description: r.Description,
+		ParentID:    r.ParentID,
+		Settings:    r.Settings,
+	}
+}
+
+// PeerTemplateResponse is a peer template with the settings its peers get,
+// including those inherited from its parents
+type PeerTemplateResponse struct {
+	*models.PeerTemplate
+	Resolved *models.PeerTemplateSettings `json:"resolved"`
+}
+
+// templateID parses the :id parameter. It responds with an error and
+// returns false when invalid.
+func templateID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		apierror.Respond(c, http.StatusBadRequest, apierror.CodeInvalidID, "Invalid template ID")
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// handleListPeerTemplates handles listing all peer templates
+func (s *Server) handleListPeerTemplates(c *gin.Context) {
+	templates, err := s.bgpService.ListTemplates(c.Request.Context())
+	if err != nil {
+		s.respondTemplateError(c, err, "Failed to list peer templates")
+		return
+	}
+
+	respondJSONWithETag(c, gin.H{"templates": templates})
+}
+
+// handleGetPeerTemplate handles getting a peer template and its resolved
+// settings
+func (s *Server) handleGetPeerTemplate(c *gin.Context) {
+	id, ok := templateID(c)
+	if !ok {
+		return
+	}
+
+	s.respondTemplate(c, http.StatusOK, id)
+}
+
+// handleCreatePeerTemplate handles creating a peer template
+func (s *Server) handleCreatePeerTemplate(c *gin.Context) {
+	var req PeerTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apierror.Validation(c, err)
+		return
+	}
+
+	template := req.template()
+	if err := s.bgpService.CreateTemplate(c.Request.Context(), template); err != nil {
+		s.respondTemplateError(c, err, "Failed to create peer template")
+		return
+	}
+
+	s.respondTemplate(c, http.StatusCreated, template.ID)
+}
+
+// handleUpdatePeerTemplate handles replacing a peer template. Its peers
+// keep their settings until the template is synced.
+func (s *Server) handleUpdatePeerTemplate(c *gin.Context) {
+	id, ok := templateID(c)
+	if !ok {
+		return
+	}
+
+	var req PeerTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apierror.Validation(c, err)
+		return
+	}
+
+	if _, err := s.bgpService.UpdateTemplate(c.Request.Context(), id, req.template()); err != nil {
+		s.respondTemplateError(c, err, "Failed to update peer template")
+		return
+	}
+
+	s.respondTemplate(c, http.StatusOK, id)
+}
+
+// handleDeletePeerTemplate handles deleting a peer template no peer or
+// template uses
+func (s *Server) handleDeletePeerTemplate(c *gin.Context) {
+	id, ok := templateID(c)
+	if !ok {
+		return
+	}
+
+	if err := s.bgpService.DeleteTemplate(c.Request.Context(), id); err != nil {
+		s.respondTemplateError(c, err, "Failed to delete peer template")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Peer template deleted successfully"})
+}
+
+// handleSyncPeerTemplate handles reapplying a template to its peers,
+// keeping each peer's overrides
+func (s *Server) handleSyncPeerTemplate(c *gin.Context) {
+	id, ok := templateID(c)
+	if !ok {
+		return
+	}
+
+	results, err := s.bgpService.SyncTemplate(c.Request.Context(), id)
+	if err != nil {
+		s.respondTemplateError(c, err, "Failed to sync peer template")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"results": results})
+}
+
+// respondTemplate responds with a template and its resolved settings
+func (s *Server) respondTemplate(c *gin.Context, status int, id uint) {
+	ctx := c.Request.Context()
+	template, err := s.bgpService.GetTemplate(ctx, id)
+	if err != nil {
+		s.respondTemplateError(c, err, "Failed to get peer template")
+		return
+	}
+	resolved, err := s.bgpService.ResolveTemplate(ctx, id)
+	if err != nil {
+		s.respondTemplateError(c, err, "Failed to resolve peer template")
+		return
+	}
+
+	c.JSON(status, PeerTemplateResponse{PeerTemplate: template, Resolved: resolved})
+}
+
+// respondTemplateError maps a BGP service error for a template operation
+// to an API error
+func (s *Server) respondTemplateError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, bgp.ErrTemplateNotFound):
+		apierror.Respond(c, http.StatusNotFound, apierror.CodeTemplateNotFound, "Peer template not found")
+		return
+	case errors.Is(err, bgp.ErrInvalidTemplate):
+		apierror.Respond(c, http.StatusBadRequest, apierror.CodeValidationFailed, err.Error())
+		return
+	case errors.Is(err, bgp.ErrTemplateExists):
+		apierror.Respond(c, http.StatusConflict, apierror.CodeTemplateExists, err.Error())
+		return
+	case errors.Is(err, bgp.ErrTemplateInUse):
+		apierror.Respond(c, http.StatusConflict, apierror.CodeTemplateInUse, err.Error())
+		return
+	}
+
+	s.logger.Error(message, zap.Error(err))
+	apierror.Respond(c, http.StatusInternalServerError, apierror.CodeInternal, message)
+}
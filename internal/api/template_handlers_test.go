@@ -0,0 +1,106 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/padminisys/flintroute/internal/bgp"
+	"github.com/padminisys/flintroute/internal/frr"
+	"github.com/padminisys/flintroute/internal/websocket"
+	"github.com/padminisys/flintroute/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandlePeerTemplates(t *testing.T) {
+	server, _ := setupTestServer(t)
+	client := frr.NewMockClient()
+	client.On("UpdateBGPPeer", mock.Anything, mock.Anything).Return(nil)
+	server.bgpService = bgp.NewService(server.db, client, websocket.NewHub(server.logger), bgp.ServiceConfig{}, server.logger)
+
+	router := gin.New()
+	router.GET("/bgp/peer-templates", server.handleListPeerTemplates)
+	router.POST("/bgp/peer-templates", server.handleCreatePeerTemplate)
+	router.GET("/bgp/peer-templates/:id", server.handleGetPeerTemplate)
+	router.PUT("/bgp/peer-templates/:id", server.handleUpdatePeerTemplate)
+	router.DELETE("/bgp/peer-templates/:id", server.handleDeletePeerTemplate)
+	router.POST("/bgp/peers", server.handleCreatePeer)
+	router.PATCH("/bgp/peers/:id", server.handlePatchPeer)
+
+	w := profileRequest(router, "POST", "/bgp/peer-templates", `{
+		"name": "customers",
+		"settings": {"asn": 65000, "enabled": false, "route_map_in": "CUST-IN", "max_prefixes": 100}
+	}`)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	var customers PeerTemplateResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &customers))
+
+	w = profileRequest(router, "POST", "/bgp/peer-templates", fmt.Sprintf(`{
+		"name": "gold",
+		"parent_id": %d,
+		"settings": {"local_preference": 300}
+	}`, customers.ID))
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	var gold PeerTemplateResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &gold))
+	assert.Equal(t, "CUST-IN", *gold.Resolved.RouteMapIn)
+	assert.Nil(t, gold.Settings.RouteMapIn)
+
+	t.Run("Create peers from a template", func(t *testing.T) {
+		w := profileRequest(router, "POST", "/bgp/peers", fmt.Sprintf(`{
+			"name": "acme", "ip_address": "192.0.2.10", "remote_asn": 64510, "max_prefixes": 500, "template_id": %d
+		}`, gold.ID))
+		require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+
+		var peer models.BGPPeer
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &peer))
+		assert.Equal(t, uint32(65000), peer.ASN)
+		assert.Equal(t, "CUST-IN", peer.RouteMapIn)
+		assert.Equal(t, 300, peer.LocalPreference)
+		assert.Equal(t, 500, peer.MaxPrefixes)
+		assert.False(t, peer.Enabled)
+		assert.Equal(t, gold.ID, *peer.TemplateID)
+		assert.Equal(t, []string{"max_prefixes", "remote_asn"}, peer.TemplateOverrides)
+
+		w = profileRequest(router, "PATCH", fmt.Sprintf("/bgp/peers/%d", peer.ID), `{"route_map_in": "ACME-IN"}`)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		assert.Contains(t, w.Body.String(), `"template_overrides":["max_prefixes","remote_asn","route_map_in"]`)
+	})
+
+	t.Run("Reject peers missing settings or templates, or of another AS", func(t *testing.T) {
+		w := profileRequest(router, "POST", "/bgp/peers", `{"name": "x", "ip_address": "192.0.2.11", "template_id": 999}`)
+		assert.Equal(t, http.StatusNotFound, w.Code)
+		assert.Contains(t, w.Body.String(), "TEMPLATE_NOT_FOUND")
+
+		w = profileRequest(router, "POST", "/bgp/peers", fmt.Sprintf(`{"name": "x", "ip_address": "192.0.2.11", "template_id": %d}`, gold.ID))
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, w.Body.String(), "remote_asn")
+
+		w = profileRequest(router, "POST", "/bgp/peers", `{"name": "x", "ip_address": "192.0.2.11", "asn": 65009, "remote_asn": 64511}`)
+		assert.Equal(t, http.StatusConflict, w.Code)
+		assert.Contains(t, w.Body.String(), "ASN_MISMATCH")
+	})
+
+	t.Run("Reject invalid templates", func(t *testing.T) {
+		w := profileRequest(router, "POST", "/bgp/peer-templates", `{"name": "gold"}`)
+		assert.Equal(t, http.StatusConflict, w.Code)
+		assert.Contains(t, w.Body.String(), "TEMPLATE_EXISTS")
+
+		w = profileRequest(router, "PUT", fmt.Sprintf("/bgp/peer-templates/%d", customers.ID), fmt.Sprintf(`{"name": "customers", "parent_id": %d}`, gold.ID))
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("Templates in use are kept", func(t *testing.T) {
+		w := profileRequest(router, "DELETE", fmt.Sprintf("/bgp/peer-templates/%d", customers.ID), "")
+		assert.Equal(t, http.StatusConflict, w.Code)
+		assert.Contains(t, w.Body.String(), "TEMPLATE_IN_USE")
+
+		w = profileRequest(router, "GET", "/bgp/peer-templates", "")
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), `"name":"customers"`)
+	})
+}
@@ -0,0 +1,414 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/padminisys/flintroute/internal/apierror"
+	authpkg "github.com/padminisys/flintroute/internal/auth"
+	"github.com/padminisys/flintroute/internal/tenancy"
+	"github.com/padminisys/flintroute/pkg/models"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// TenantHeader selects the tenant a request acts for. Users who are members
+// of a single tenant may leave it out; admins leave it out to act across
+// tenants.
+const TenantHeader = "X-Tenant-ID"
+
+// TenantRequest represents a request to create or update a tenant
+type TenantRequest struct {
+	Name        string `json:"name" binding:"required,max=100"`
+	Description string `json:"description"`
+}
+
+// TenantMemberRequest sets a user's role in a tenant
+type TenantMemberRequest struct {
+	Role string `json:"role" binding:"required,oneof=user operator"`
+}
+
+// tenantMiddleware scopes tenant-owned routes to the tenant the request acts
+// for, picked by the X-Tenant-ID header. Admins without the header act
+// across tenants. Other users must be members of the tenant, and their
+// membership role replaces their own. It does nothing unless multi-tenancy
+// is enabled.
+func (s *Server) tenantMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !s.tenancy {
+			c.Next()
+			return
+		}
+
+		var tenantID uint
+		header := c.GetHeader(TenantHeader)
+		if header != "" {
+			id, err := strconv.ParseUint(header, 10, 32)
+			if err != nil {
+				apierror.Respond(c, http.StatusBadRequest, apierror.CodeInvalidID, "Invalid "+TenantHeader+" header")
+				return
+			}
+			tenantID = uint(id)
+		}
+
+		role, _ := authpkg.GetRole(c)
+		if authpkg.HasRole(role, authpkg.RoleAdmin) {
+			if header != "" {
+				if s.tenantFromID(c, tenantID) == nil {
+					return
+				}
+				actForTenant(c, tenantID)
+			}
+			c.Next()
+			return
+		}
+
+		userID, _ := authpkg.GetUserID(c)
+		memberships, err := s.tenantMemberships(c.Request.Context(), userID, tenantID, header != "")
+		if err != nil {
+			s.logger.Error("Failed to look up tenant memberships", zap.Error(err))
+			apierror.Respond(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to look up tenant memberships")
+			return
+		}
+
+		switch {
+		case len(memberships) == 0 && header != "":
+			apierror.Respond(c, http.StatusForbidden, apierror.CodeNotTenantMember, "Not a member of this tenant")
+			return
+		case len(memberships) == 0:
+			apierror.Respond(c, http.StatusForbidden, apierror.CodeNotTenantMember, "Not a member of any tenant")
+			return
+		case len(memberships) > 1:
+			apierror.Respond(c, http.StatusBadRequest, apierror.CodeTenantRequired,
+				"Member of several tenants; select one with the "+TenantHeader+" header")
+			return
+		}
+
+		c.Set("role", memberships[0].Role)
+		actForTenant(c, memberships[0].TenantID)
+		c.Next()
+	}
+}
+
+// sharedMiddleware guards routes to configuration shared by every tenant,
+// such as BGP global settings and policy lists. While multi-tenancy is
+// enabled only admins may use them, since a change made for one tenant would
+// apply to all.
+func (s *Server) sharedMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if s.tenancy {
+			if role, _ := authpkg.GetRole(c); !authpkg.HasRole(role, authpkg.RoleAdmin) {
+				apierror.Respond(c, http.StatusForbidden, apierror.CodeForbidden, "Admin access required while multi-tenancy is enabled")
+				return
+			}
+		}
+		c.Next()
+	}
+}
+
+// tenantMemberships returns up to two of a user's tenant memberships, or
+// only their membership of tenantID when selected is set
+func (s *Server) tenantMemberships(ctx context.Context, userID, tenantID uint, selected bool) ([]models.TenantMember, error) {
+	query := s.db.WithContext(ctx).Where("user_id = ?", userID)
+	if selected {
+		query = query.Where("tenant_id = ?", tenantID)
+	}
+	var memberships []models.TenantMember
+	err := query.Order("tenant_id").Limit(2).Find(&memberships).Error
+	return memberships, err
+}
+
+// actForTenant makes the request act for a tenant
+func actForTenant(c *gin.Context, tenantID uint) {
+	c.Set("tenant_id", tenantID)
+	c.Request = c.Request.WithContext(tenancy.WithTenant(c.Request.Context(), tenantID))
+}
+
+// tenantKey is the response cache key of the tenant the request acts for
+func tenantKey(c *gin.Context) string {
+	id, ok := tenancy.FromContext(c.Request.Context())
+	if !ok {
+		return ""
+	}
+	return "tenant=" + strconv.FormatUint(uint64(id), 10)
+}
+
+// tenantFromID loads a tenant. It responds with an error and returns nil
+// when the tenant doesn't exist.
+func (s *Server) tenantFromID(c *gin.Context, id uint) *models.Tenant {
+	var tenant models.Tenant
+	if err := s.db.First(&tenant, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			apierror.Respond(c, http.StatusNotFound, apierror.CodeTenantNotFound, "Tenant not found")
+			return nil
+		}
+		s.logger.Error("Failed to get tenant", zap.Error(err))
+		apierror.Respond(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to get tenant")
+		return nil
+	}
+	return &tenant
+}
+
+// tenantFromParam loads the tenant named by the :id parameter. It responds
+// with an error and returns nil when the ID is invalid or unknown.
+func (s *Server) tenantFromParam(c *gin.Context) *models.Tenant {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		apierror.Respond(c, http.StatusBadRequest, apierror.CodeInvalidID, "Invalid tenant ID")
+		return nil
+	}
+	return s.tenantFromID(c, uint(id))
+}
+
+// handleListTenants handles listing all tenants
+func (s *Server) handleListTenants(c *gin.Context) {
+	var tenants []models.Tenant
+	if err := s.db.Order("name").Find(&tenants).Error; err != nil {
+		s.logger.Error("Failed to list tenants", zap.Error(err))
+		apierror.Respond(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to list tenants")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"tenants": tenants})
+}
+
+// handleGetTenant handles getting a tenant
+func (s *Server) handleGetTenant(c *gin.Context) {
+	tenant := s.tenantFromParam(c)
+	if tenant == nil {
+		return
+	}
+
+	c.JSON(http.StatusOK, tenant)
+}
+
+// handleCreateTenant handles creating a tenant
+func (s *Server) handleCreateTenant(c *gin.Context) {
+	var req TenantRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apierror.Validation(c, err)
+		return
+	}
+
+	tenant := models.Tenant{Name: req.Name, Description: req.Description}
+	if !s.checkTenantName(c, &tenant) {
+		return
+	}
+	if err := s.db.Create(&tenant).Error; err != nil {
+		s.logger.Error("Failed to create tenant", zap.Error(err))
+		apierror.Respond(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to create tenant")
+		return
+	}
+
+	s.logger.Info("Created tenant", zap.Uint("id", tenant.ID), zap.String("name", tenant.Name))
+
+	c.JSON(http.StatusCreated, tenant)
+}
+
+// handleUpdateTenant handles renaming or describing a tenant
+func (s *Server) handleUpdateTenant(c *gin.Context) {
+	var req TenantRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apierror.Validation(c, err)
+		return
+	}
+
+	tenant := s.tenantFromParam(c)
+	if tenant == nil {
+		return
+	}
+	tenant.Name = req.Name
+	tenant.Description = req.Description
+	if !s.checkTenantName(c, tenant) {
+		return
+	}
+	if err := s.db.Save(tenant).Error; err != nil {
+		s.logger.Error("Failed to update tenant", zap.Error(err))
+		apierror.Respond(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to update tenant")
+		return
+	}
+
+	c.JSON(http.StatusOK, tenant)
+}
+
+// checkTenantName responds with 409 Conflict and returns false when another
+// tenant has tenant's name
+func (s *Server) checkTenantName(c *gin.Context, tenant *models.Tenant) bool {
+	var count int64
+	if err := s.db.Model(&models.Tenant{}).Where("name = ? AND id <> ?", tenant.Name, tenant.ID).Count(&count).Error; err != nil {
+		s.logger.Error("Failed to check tenant name", zap.Error(err))
+		apierror.Respond(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to check tenant name")
+		return false
+	}
+	if count > 0 {
+		apierror.Respond(c, http.StatusConflict, apierror.CodeTenantExists, "A tenant named "+tenant.Name+" already exists")
+		return false
+	}
+	return true
+}
+
+// handleDeleteTenant handles deleting a tenant and its memberships. A tenant
+// that still owns peers, including peers in the trash, can't be deleted.
+func (s *Server) handleDeleteTenant(c *gin.Context) {
+	tenant := s.tenantFromParam(c)
+	if tenant == nil {
+		return
+	}
+
+	var peers int64
+	if err := s.db.Unscoped().Model(&models.BGPPeer{}).Where("tenant_id = ?", tenant.ID).Count(&peers).Error; err != nil {
+		s.logger.Error("Failed to count tenant peers", zap.Error(err))
+		apierror.Respond(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to delete tenant")
+		return
+	}
+	if peers > 0 {
+		apierror.Respond(c, http.StatusConflict, apierror.CodeTenantInUse,
+			"Tenant still owns "+strconv.FormatInt(peers, 10)+" peers; delete and purge them first")
+		return
+	}
+
+	if err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("tenant_id = ?", tenant.ID).Delete(&models.TenantMember{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(tenant).Error
+	}); err != nil {
+		s.logger.Error("Failed to delete tenant", zap.Error(err))
+		apierror.Respond(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to delete tenant")
+		return
+	}
+
+	s.logger.Info("Deleted tenant", zap.Uint("id", tenant.ID), zap.String("name", tenant.Name))
+
+	c.JSON(http.StatusOK, gin.H{"message": "Tenant deleted"})
+}
+
+// handleListTenantMembers handles listing a tenant's members
+func (s *Server) handleListTenantMembers(c *gin.Context) {
+	tenant := s.tenantFromParam(c)
+	if tenant == nil {
+		return
+	}
+
+	var members []models.TenantMember
+	if err := s.db.Preload("User").Where("tenant_id = ?", tenant.ID).Order("user_id").Find(&members).Error; err != nil {
+		s.logger.Error("Failed to list tenant members", zap.Error(err))
+		apierror.Respond(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to list tenant members")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"members": members})
+}
+
+// handleSetTenantMember handles adding a user to a tenant or changing the
+// user's role in it
+func (s *Server) handleSetTenantMember(c *gin.Context) {
+	var req TenantMemberRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apierror.Validation(c, err)
+		return
+	}
+
+	tenant := s.tenantFromParam(c)
+	if tenant == nil {
+		return
+	}
+	user := s.memberFromParam(c)
+	if user == nil {
+		return
+	}
+
+	member := models.TenantMember{TenantID: tenant.ID, UserID: user.ID}
+	status := http.StatusOK
+	err := s.db.Where(&member).First(&member).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		status = http.StatusCreated
+		err = nil
+	}
+	if err == nil {
+		member.Role = req.Role
+		err = s.db.Save(&member).Error
+	}
+	if err != nil {
+		s.logger.Error("Failed to set tenant member", zap.Error(err))
+		apierror.Respond(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to set tenant member")
+		return
+	}
+	member.User = user
+
+	s.logger.Info("Set tenant member",
+		zap.String("tenant", tenant.Name),
+		zap.String("username", user.Username),
+		zap.String("role", member.Role),
+	)
+
+	c.JSON(status, member)
+}
+
+// handleRemoveTenantMember handles removing a user from a tenant
+func (s *Server) handleRemoveTenantMember(c *gin.Context) {
+	tenant := s.tenantFromParam(c)
+	if tenant == nil {
+		return
+	}
+	user := s.memberFromParam(c)
+	if user == nil {
+		return
+	}
+
+	result := s.db.Where("tenant_id = ? AND user_id = ?", tenant.ID, user.ID).Delete(&models.TenantMember{})
+	if result.Error != nil {
+		s.logger.Error("Failed to remove tenant member", zap.Error(result.Error))
+		apierror.Respond(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to remove tenant member")
+		return
+	}
+	if result.RowsAffected == 0 {
+		apierror.Respond(c, http.StatusNotFound, apierror.CodeNotFound, "User is not a member of this tenant")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Member removed"})
+}
+
+// memberFromParam loads the user named by the :user parameter. It
+// responds with an error and returns nil when the ID is invalid or unknown.
+func (s *Server) memberFromParam(c *gin.Context) *models.User {
+	id, err := strconv.ParseUint(c.Param("user"), 10, 32)
+	if err != nil {
+		apierror.Respond(c, http.StatusBadRequest, apierror.CodeInvalidID, "Invalid user ID")
+		return nil
+	}
+
+	var user models.User
+	if err := s.db.First(&user, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			apierror.Respond(c, http.StatusNotFound, apierror.CodeNotFound, "User not found")
+			return nil
+		}
+		s.logger.Error("Database error", zap.Error(err))
+		apierror.Respond(c, http.StatusInternalServerError, apierror.CodeInternal, "Internal server error")
+		return nil
+	}
+	return &user
+}
+
+// handleListMyTenants handles listing the current user's tenant memberships
+func (s *Server) handleListMyTenants(c *gin.Context) {
+	userID, exists := authpkg.GetUserID(c)
+	if !exists {
+		apierror.Respond(c, http.StatusUnauthorized, apierror.CodeUnauthorized, "User not authenticated")
+		return
+	}
+
+	var memberships []models.TenantMember
+	if err := s.db.Preload("Tenant").Where("user_id = ?", userID).Order("tenant_id").Find(&memberships).Error; err != nil {
+		s.logger.Error("Failed to list tenant memberships", zap.Error(err))
+		apierror.Respond(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to list tenant memberships")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"tenants": memberships})
+}
@@ -0,0 +1,330 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/padminisys/flintroute/internal/auth"
+	"github.com/padminisys/flintroute/internal/bgp"
+	"github.com/padminisys/flintroute/internal/tenancy"
+	"github.com/padminisys/flintroute/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// tenantRequest sends a request as a user with role acting for tenant, or
+// for no tenant in particular when tenant is 0
+func (s *mockedServer) tenantRequest(t *testing.T, role string, tenant uint, method, path, body string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	token, err := s.jwtManager.GenerateToken(s.users[role])
+	require.NoError(t, err)
+
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer "+token)
+	if tenant != 0 {
+		req.Header.Set(TenantHeader, fmt.Sprint(tenant))
+	}
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, req)
+	return w
+}
+
+// alertMessages returns the messages of the alerts in a list response
+func alertMessages(t *testing.T, w *httptest.ResponseRecorder) []string {
+	t.Helper()
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	var resp struct {
+		Alerts []models.Alert `json:"alerts"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	messages := []string{}
+	for _, alert := range resp.Alerts {
+		messages = append(messages, alert.Message)
+	}
+	return messages
+}
+
+// actsFor matches a context acting for tenant, or for none when tenant is 0
+func actsFor(tenant uint) interface{} {
+	return mock.MatchedBy(func(ctx context.Context) bool {
+		id, ok := tenancy.FromContext(ctx)
+		return id == tenant && ok == (tenant != 0)
+	})
+}
+
+func TestTenancy(t *testing.T) {
+	// newTenantServer returns a server with multi-tenancy enabled and two
+	// tenants, each with an alert. The user is an operator of acme; the
+	// operator is a user of both.
+	newTenantServer := func(t *testing.T) (*mockedServer, *models.Tenant, *models.Tenant) {
+		server := newMockedServer(t)
+		server.tenancy = true
+
+		acme := &models.Tenant{Name: "acme"}
+		globex := &models.Tenant{Name: "globex"}
+		require.NoError(t, server.db.Create(acme).Error)
+		require.NoError(t, server.db.Create(globex).Error)
+		for _, member := range []models.TenantMember{
+			{TenantID: acme.ID, UserID: server.users[auth.RoleUser].ID, Role: auth.RoleOperator},
+			{TenantID: acme.ID, UserID: server.users[auth.RoleOperator].ID, Role: auth.RoleUser},
+			{TenantID: globex.ID, UserID: server.users[auth.RoleOperator].ID, Role: auth.RoleUser},
+		} {
+			require.NoError(t, server.db.Create(&member).Error)
+		}
+		for _, alert := range []models.Alert{
+			{Type: "peer_down", Severity: "warning", Message: "acme", TenantID: &acme.ID},
+			{Type: "peer_down", Severity: "warning", Message: "globex", TenantID: &globex.ID},
+			{Type: "frr_unreachable", Severity: "critical", Message: "instance"},
+		} {
+			require.NoError(t, server.db.Create(&alert).Error)
+		}
+		return server, acme, globex
+	}
+
+	t.Run("Disabled leaves requests unscoped", func(t *testing.T) {
+		server := newMockedServer(t)
+		server.bgp.On("ListPeers", actsFor(0), mock.Anything).Return([]*models.BGPPeer{}, nil).Once()
+
+		w := server.tenantRequest(t, auth.RoleUser, 0, "GET", "/api/v1/bgp/peers", "")
+		assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		server.bgp.AssertExpectations(t)
+	})
+
+	t.Run("Members act for their only tenant with their membership role", func(t *testing.T) {
+		server, acme, _ := newTenantServer(t)
+		server.bgp.On("ListPeers", actsFor(acme.ID), mock.Anything).Return([]*models.BGPPeer{}, nil).Once()
+
+		w := server.tenantRequest(t, auth.RoleUser, 0, "GET", "/api/v1/bgp/peers", "")
+		assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		server.bgp.AssertExpectations(t)
+
+		assert.Equal(t, []string{"acme"}, alertMessages(t, server.tenantRequest(t, auth.RoleUser, 0, "GET", "/api/v1/alerts", "")))
+
+		// The membership makes the user an operator of acme
+		w = server.tenantRequest(t, auth.RoleUser, 0, "POST", "/api/v1/alerts/acknowledge-all", "")
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		assert.JSONEq(t, `{"acknowledged":1}`, w.Body.String())
+	})
+
+	t.Run("Members of several tenants pick one", func(t *testing.T) {
+		server, _, globex := newTenantServer(t)
+
+		w := server.tenantRequest(t, auth.RoleOperator, 0, "GET", "/api/v1/alerts", "")
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, w.Body.String(), "TENANT_REQUIRED")
+
+		assert.Equal(t, []string{"globex"}, alertMessages(t, server.tenantRequest(t, auth.RoleOperator, globex.ID, "GET", "/api/v1/alerts", "")))
+
+		// Acting for globex as a user, the operator can't change anything
+		w = server.tenantRequest(t, auth.RoleOperator, globex.ID, "POST", "/api/v1/alerts/acknowledge-all", "")
+		assert.Equal(t, http.StatusForbidden, w.Code)
+	})
+
+	t.Run("Other tenants are forbidden", func(t *testing.T) {
+		server, _, globex := newTenantServer(t)
+
+		w := server.tenantRequest(t, auth.RoleUser, globex.ID, "GET", "/api/v1/alerts", "")
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		assert.Contains(t, w.Body.String(), "NOT_TENANT_MEMBER")
+
+		w = server.tenantRequest(t, auth.RoleUser, 0, "POST", "/api/v1/alerts/2/acknowledge", "")
+		assert.Equal(t, http.StatusNotFound, w.Code, "globex's alert is hidden from acme")
+	})
+
+	t.Run("Users in no tenant keep their own routes", func(t *testing.T) {
+		server, _, _ := newTenantServer(t)
+		require.NoError(t, server.db.Where("user_id = ?", server.users[auth.RoleUser].ID).Delete(&models.TenantMember{}).Error)
+
+		w := server.tenantRequest(t, auth.RoleUser, 0, "GET", "/api/v1/alerts", "")
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		assert.Contains(t, w.Body.String(), "Not a member of any tenant")
+
+		w = server.tenantRequest(t, auth.RoleUser, 0, "GET", "/api/v1/me", "")
+		assert.Equal(t, http.StatusOK, w.Code)
+	})
+
+	t.Run("Admins act across tenants or for one", func(t *testing.T) {
+		server, acme, _ := newTenantServer(t)
+
+		assert.ElementsMatch(t, []string{"acme", "globex", "instance"},
+			alertMessages(t, server.tenantRequest(t, auth.RoleAdmin, 0, "GET", "/api/v1/alerts", "")))
+		assert.Equal(t, []string{"acme"},
+			alertMessages(t, server.tenantRequest(t, auth.RoleAdmin, acme.ID, "GET", "/api/v1/alerts", "")))
+
+		w := server.tenantRequest(t, auth.RoleAdmin, 99, "GET", "/api/v1/alerts", "")
+		assert.Equal(t, http.StatusNotFound, w.Code)
+		assert.Contains(t, w.Body.String(), "TENANT_NOT_FOUND")
+	})
+
+	t.Run("Config versions are kept per tenant", func(t *testing.T) {
+		server, acme, globex := newTenantServer(t)
+		creator := server.users[auth.RoleAdmin].ID
+		for _, version := range []models.ConfigVersion{
+			{Description: "acme", Config: "a", Hash: "same", CreatedBy: creator, TenantID: &acme.ID},
+			{Description: "globex", Config: "a", Hash: "same", CreatedBy: creator, TenantID: &globex.ID},
+		} {
+			require.NoError(t, server.db.Create(&version).Error, "tenants may back up the same configuration")
+		}
+		server.versions.On("LoadAll", mock.Anything, mock.Anything).Return(nil)
+
+		w := server.tenantRequest(t, auth.RoleUser, 0, "GET", "/api/v1/config/versions", "")
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		assert.Contains(t, w.Body.String(), `"description":"acme"`)
+		assert.NotContains(t, w.Body.String(), `"description":"globex"`)
+	})
+
+	t.Run("Reachability is reported and probed per tenant", func(t *testing.T) {
+		server, acme, _ := newTenantServer(t)
+		server.bgp.On("LastReachabilityReport", actsFor(acme.ID)).Return(&bgp.ReachabilityReport{
+			Peers: []*bgp.PeerReachability{{PeerID: 1, Name: "acme-peer"}},
+		}).Once()
+
+		w := server.tenantRequest(t, auth.RoleUser, 0, "GET", "/api/v1/bgp/reachability", "")
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		assert.Contains(t, w.Body.String(), `"name":"acme-peer"`)
+
+		w = server.tenantRequest(t, auth.RoleUser, 0, "POST", "/api/v1/bgp/reachability/probe", "")
+		require.Equal(t, http.StatusAccepted, w.Code, w.Body.String())
+		var job models.Job
+		require.NoError(t, server.db.Where("type = ?", JobProbeReachability).First(&job).Error)
+		require.NotNil(t, job.TenantID)
+		assert.Equal(t, acme.ID, *job.TenantID)
+
+		server.bgp.On("ProbeReachability", actsFor(acme.ID)).Return(&bgp.ReachabilityReport{}, nil).Once()
+		_, err := server.runProbeReachability(context.Background(), &job, func(int, string) {})
+		require.NoError(t, err)
+		server.bgp.AssertExpectations(t)
+	})
+
+	t.Run("Jobs of other tenants are not found", func(t *testing.T) {
+		server, acme, globex := newTenantServer(t)
+		ours := &models.Job{Type: JobProbeReachability, Status: models.JobQueued, TenantID: &acme.ID}
+		theirs := &models.Job{Type: JobProbeReachability, Status: models.JobQueued, TenantID: &globex.ID}
+		shared := &models.Job{Type: JobReconcile, Status: models.JobQueued}
+		for _, job := range []*models.Job{ours, theirs, shared} {
+			require.NoError(t, server.db.Create(job).Error)
+		}
+
+		w := server.tenantRequest(t, auth.RoleUser, 0, "GET", fmt.Sprintf("/api/v1/jobs/%d", ours.ID), "")
+		assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		for _, job := range []*models.Job{theirs, shared} {
+			w = server.tenantRequest(t, auth.RoleUser, 0, "GET", fmt.Sprintf("/api/v1/jobs/%d", job.ID), "")
+			assert.Equal(t, http.StatusNotFound, w.Code, w.Body.String())
+		}
+
+		w = server.tenantRequest(t, auth.RoleAdmin, 0, "GET", fmt.Sprintf("/api/v1/jobs/%d", theirs.ID), "")
+		assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	})
+
+	t.Run("Drift, sync and anomaly reports are scoped to the tenant", func(t *testing.T) {
+		server, acme, _ := newTenantServer(t)
+		server.bgp.On("DetectDrift", actsFor(acme.ID)).Return(&bgp.DriftReport{InSync: true}, nil).Once()
+		server.bgp.On("LastSyncReport", actsFor(acme.ID)).Return(&bgp.PeerSyncReport{}).Once()
+		server.bgp.On("LastAnomalyReport", actsFor(acme.ID)).Return(&bgp.AnomalyReport{}).Once()
+
+		for _, path := range []string{"/api/v1/bgp/drift", "/api/v1/bgp/sync", "/api/v1/bgp/anomalies"} {
+			w := server.tenantRequest(t, auth.RoleUser, 0, "GET", path, "")
+			assert.Equal(t, http.StatusOK, w.Code, "%s: %s", path, w.Body.String())
+		}
+		server.bgp.AssertExpectations(t)
+	})
+
+	t.Run("Shared configuration is for admins only", func(t *testing.T) {
+		server, _, _ := newTenantServer(t)
+
+		for _, route := range []struct{ method, path string }{
+			{"GET", "/api/v1/bgp/global"},
+			{"GET", "/api/v1/bgp/peer-templates"},
+			{"GET", "/api/v1/bgp/community-lists"},
+			{"GET", "/api/v1/bgp/as-path-lists"},
+			{"POST", "/api/v1/bgp/reconcile"},
+			{"POST", "/api/v1/bgp/anomalies/analyze"},
+			{"GET", "/api/v1/webhooks"},
+			{"POST", "/api/v1/webhooks"},
+			{"GET", "/api/v1/webhooks/events"},
+		} {
+			w := server.tenantRequest(t, auth.RoleUser, 0, route.method, route.path, "")
+			assert.Equal(t, http.StatusForbidden, w.Code, "%s %s", route.method, route.path)
+		}
+
+		server.bgp.On("GetGlobalConfig", actsFor(0)).Return(&models.BGPGlobalConfig{ASN: 65000}, nil).Once()
+		w := server.tenantRequest(t, auth.RoleAdmin, 0, "GET", "/api/v1/bgp/global", "")
+		assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		server.bgp.AssertExpectations(t)
+	})
+}
+
+func TestTenantHandlers(t *testing.T) {
+	server := newMockedServer(t)
+	user := server.users[auth.RoleUser]
+
+	w := server.request(t, auth.RoleAdmin, "POST", "/api/v1/admin/tenants", `{"name":"acme","description":"ACME Corp"}`)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	var tenant models.Tenant
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &tenant))
+	path := fmt.Sprintf("/api/v1/admin/tenants/%d", tenant.ID)
+
+	t.Run("Rejects duplicate names", func(t *testing.T) {
+		w := server.request(t, auth.RoleAdmin, "POST", "/api/v1/admin/tenants", `{"name":"acme"}`)
+		assert.Equal(t, http.StatusConflict, w.Code)
+		assert.Contains(t, w.Body.String(), "TENANT_EXISTS")
+
+		w = server.request(t, auth.RoleAdmin, "PUT", path, `{"name":"acme","description":"Renamed"}`)
+		require.Equal(t, http.StatusOK, w.Code, "a tenant keeps its own name")
+		assert.Contains(t, w.Body.String(), `"description":"Renamed"`)
+	})
+
+	t.Run("Adds, updates and removes members", func(t *testing.T) {
+		memberPath := fmt.Sprintf("%s/members/%d", path, user.ID)
+
+		w := server.request(t, auth.RoleAdmin, "PUT", memberPath, `{"role":"admin"}`)
+		assert.Equal(t, http.StatusBadRequest, w.Code, "tenant roles stop at operator")
+
+		w = server.request(t, auth.RoleAdmin, "PUT", memberPath, `{"role":"user"}`)
+		assert.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+		w = server.request(t, auth.RoleAdmin, "PUT", memberPath, `{"role":"operator"}`)
+		assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+		w = server.request(t, auth.RoleAdmin, "GET", path+"/members", "")
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), `"role":"operator"`)
+		assert.Contains(t, w.Body.String(), `"username":"test-user"`)
+
+		w = server.request(t, auth.RoleUser, "GET", "/api/v1/me/tenants", "")
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), `"name":"acme"`)
+
+		w = server.request(t, auth.RoleAdmin, "DELETE", memberPath, "")
+		assert.Equal(t, http.StatusOK, w.Code)
+		w = server.request(t, auth.RoleAdmin, "DELETE", memberPath, "")
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("Refuses to delete tenants owning peers", func(t *testing.T) {
+		peer := models.BGPPeer{Name: "edge", IPAddress: "192.0.2.1", ASN: 65000, RemoteASN: 65001, TenantID: &tenant.ID}
+		require.NoError(t, server.db.Create(&peer).Error)
+		require.NoError(t, server.db.Delete(&peer).Error)
+
+		w := server.request(t, auth.RoleAdmin, "DELETE", path, "")
+		assert.Equal(t, http.StatusConflict, w.Code, "peers in the trash still belong to the tenant")
+		assert.Contains(t, w.Body.String(), "TENANT_IN_USE")
+
+		require.NoError(t, server.db.Unscoped().Delete(&peer).Error)
+		w = server.request(t, auth.RoleAdmin, "DELETE", path, "")
+		assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+		w = server.request(t, auth.RoleAdmin, "GET", path, "")
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+}
@@ -0,0 +1,351 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/padminisys/flintroute/internal/apierror"
+	authpkg "github.com/padminisys/flintroute/internal/auth"
+	"github.com/padminisys/flintroute/internal/database"
+	"github.com/padminisys/flintroute/internal/store"
+	"github.com/padminisys/flintroute/pkg/models"
+	"go.uber.org/zap"
+)
+
+// usageHours is how many hours of usage are kept per user
+const usageHours = 24
+
+// quotaCacheTTL is how long a user's request quota is used before it is read
+// again, so quotas set on another instance apply soon
+const quotaCacheTTL = time.Minute
+
+// UsageStats counts the API requests made in a period
+type UsageStats struct {
+	Requests int64 `json:"requests"`
+	// Errors are requests answered with a 4xx or 5xx status
+	Errors int64 `json:"errors"`
+	// Throttled are requests rejected for exceeding the quota; they are not
+	// counted in Requests
+	Throttled    int64   `json:"throttled"`
+	AvgLatencyMS float64 `json:"avg_latency_ms"`
+	MaxLatencyMS float64 `json:"max_latency_ms"`
+}
+
+// UsageHour is the usage of one hour, starting at Hour
+type UsageHour struct {
+	Hour time.Time `json:"hour"`
+	UsageStats
+}
+
+// RequestQuota is a user's request quota in the current hour
+type RequestQuota struct {
+	RequestsPerHour int       `json:"requests_per_hour"`
+	Remaining       int       `json:"remaining"`
+	ResetAt         time.Time `json:"reset_at"`
+}
+
+// UserUsage is a user's API usage over the last hours
+type UserUsage struct {
+	UserID   uint          `json:"user_id"`
+	Username string        `json:"username"`
+	Quota    *RequestQuota `json:"quota"` // nil when unlimited
+	Total    UsageStats    `json:"total"`
+	Hours    []UsageHour   `json:"hours,omitempty"`
+}
+
+// usageBucket counts a user's requests in one hour
+type usageBucket struct {
+	hour       time.Time
+	requests   int64
+	errors     int64
+	throttled  int64
+	timed      int64 // requests with a latency, i.e. not streams
+	latency    time.Duration
+	maxLatency time.Duration
+}
+
+// cachedQuota is a user's request quota as read at loadedAt
+type cachedQuota struct {
+	limit    int
+	loadedAt time.Time
+}
+
+// usageTracker counts API requests and their latency per user in hourly
+// buckets, and enforces request quotas. Usage is kept in memory, so each
+// instance reports the requests it serves; quotas are counted in the store,
+// so instances sharing it enforce them together.
+type usageTracker struct {
+	db           *database.DB
+	store        store.Store
+	defaultQuota int
+
+	mu      sync.Mutex
+	buckets map[uint][]*usageBucket // oldest first
+	quotas  map[uint]cachedQuota
+}
+
+// newUsageTracker creates a usage tracker. defaultQuota applies to users
+// without a quota of their own; 0 is unlimited.
+func newUsageTracker(db *database.DB, st store.Store, defaultQuota int) *usageTracker {
+	return &usageTracker{
+		db:           db,
+		store:        st,
+		defaultQuota: defaultQuota,
+		buckets:      make(map[uint][]*usageBucket),
+		quotas:       make(map[uint]cachedQuota),
+	}
+}
+
+// quota returns how many requests per hour userID may make; 0 is unlimited
+func (t *usageTracker) quota(ctx context.Context, userID uint, now time.Time) (int, error) {
+	t.mu.Lock()
+	cached, ok := t.quotas[userID]
+	t.mu.Unlock()
+	if ok && now.Sub(cached.loadedAt) < quotaCacheTTL {
+		return cached.limit, nil
+	}
+
+	var user models.User
+	if err := t.db.WithContext(ctx).Select("id", "request_quota").First(&user, userID).Error; err != nil {
+		return 0, fmt.Errorf("failed to load request quota: %w", err)
+	}
+	limit := t.defaultQuota
+	if user.RequestQuota != nil {
+		limit = *user.RequestQuota
+	}
+
+	t.mu.Lock()
+	t.quotas[userID] = cachedQuota{limit: limit, loadedAt: now}
+	t.mu.Unlock()
+	return limit, nil
+}
+
+// forget drops userID's cached quota after it changed
+func (t *usageTracker) forget(userID uint) {
+	t.mu.Lock()
+	delete(t.quotas, userID)
+	t.mu.Unlock()
+}
+
+// bucket returns userID's bucket for the hour of now, creating it and
+// dropping buckets older than usageHours. t.mu must be held.
+func (t *usageTracker) bucket(userID uint, now time.Time) *usageBucket {
+	hour := now.Truncate(time.Hour)
+	buckets := t.buckets[userID]
+	if n := len(buckets); n > 0 && buckets[n-1].hour.Equal(hour) {
+		return buckets[n-1]
+	}
+
+	cutoff := hour.Add(-(usageHours - 1) * time.Hour)
+	kept := buckets[:0]
+	for _, b := range buckets {
+		if !b.hour.Before(cutoff) {
+			kept = append(kept, b)
+		}
+	}
+	b := &usageBucket{hour: hour}
+	t.buckets[userID] = append(kept, b)
+	return b
+}
+
+// admit counts a request by userID at now against limit. It reports
+// whether the request is allowed, how many more the user may make this hour
+// and when the quota resets. When the store fails, the requests this
+// instance served are counted instead, and the error is returned too.
+func (t *usageTracker) admit(ctx context.Context, userID uint, limit int, now time.Time) (allowed bool, remaining int, reset time.Time, err error) {
+	hour := now.Truncate(time.Hour)
+	reset = hour.Add(time.Hour)
+	// Requests count towards a quota set later in the hour too. The key is
+	// per hour, so it only has to outlive the hour.
+	count, err := t.store.Incr(ctx, quotaKey(userID, hour), time.Hour)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	b := t.bucket(userID, now)
+	if err != nil {
+		count = b.requests + 1
+	}
+	if limit > 0 && count > int64(limit) {
+		b.throttled++
+		return false, 0, reset, err
+	}
+	b.requests++
+	if limit > 0 {
+		remaining = limit - int(count)
+	}
+	return true, remaining, reset, err
+}
+
+// used returns how many requests userID made against their quota in the
+// hour of now, across instances, or ok false when the store fails
+func (t *usageTracker) used(ctx context.Context, userID uint, now time.Time) (count int64, ok bool) {
+	value, err := t.store.Get(ctx, quotaKey(userID, now.Truncate(time.Hour)))
+	if errors.Is(err, store.ErrNotFound) {
+		return 0, true
+	}
+	if err != nil {
+		return 0, false
+	}
+	count, err = strconv.ParseInt(string(value), 10, 64)
+	return count, err == nil
+}
+
+// quotaKey is the store key counting userID's requests in the given hour
+func quotaKey(userID uint, hour time.Time) string {
+	return fmt.Sprintf("quota:%d:%d", userID, hour.Unix())
+}
+
+// record counts the outcome of a request admitted at start. Streams, such
+// as WebSocket connections, have no meaningful latency and pass timed false.
+func (t *usageTracker) record(userID uint, start time.Time, status int, latency time.Duration, timed bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	hour := start.Truncate(time.Hour)
+	for _, b := range t.buckets[userID] {
+		if !b.hour.Equal(hour) {
+			continue
+		}
+		if status >= http.StatusBadRequest {
+			b.errors++
+		}
+		if timed {
+			b.timed++
+			b.latency += latency
+			if latency > b.maxLatency {
+				b.maxLatency = latency
+			}
+		}
+		return
+	}
+}
+
+// usage returns userID's usage over the hours up to now, oldest first
+func (t *usageTracker) usage(userID uint, hours int, now time.Time) ([]UsageHour, UsageStats) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	cutoff := now.Truncate(time.Hour).Add(-time.Duration(hours-1) * time.Hour)
+	var (
+		perHour []UsageHour
+		total   usageBucket
+	)
+	for _, b := range t.buckets[userID] {
+		if b.hour.Before(cutoff) {
+			continue
+		}
+		perHour = append(perHour, UsageHour{Hour: b.hour, UsageStats: b.stats()})
+		total.requests += b.requests
+		total.errors += b.errors
+		total.throttled += b.throttled
+		total.timed += b.timed
+		total.latency += b.latency
+		total.maxLatency = max(total.maxLatency, b.maxLatency)
+	}
+	return perHour, total.stats()
+}
+
+// users returns the users with usage tracked
+func (t *usageTracker) users() []uint {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	ids := make([]uint, 0, len(t.buckets))
+	for id := range t.buckets {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// stats converts the bucket's counts for the API
+func (b *usageBucket) stats() UsageStats {
+	stats := UsageStats{
+		Requests:     b.requests,
+		Errors:       b.errors,
+		Throttled:    b.throttled,
+		MaxLatencyMS: milliseconds(b.maxLatency),
+	}
+	if b.timed > 0 {
+		stats.AvgLatencyMS = milliseconds(b.latency / time.Duration(b.timed))
+	}
+	return stats
+}
+
+// milliseconds converts d to fractional milliseconds, rounded to
+// microseconds
+func milliseconds(d time.Duration) float64 {
+	return math.Round(float64(d)/float64(time.Microsecond)) / 1000
+}
+
+// usageMiddleware counts authenticated requests per user and rejects those
+// over the user's hourly quota with 429 QUOTA_EXCEEDED. Responses to users
+// with a quota carry X-RateLimit-Limit, X-RateLimit-Remaining and
+// X-RateLimit-Reset headers.
+func (s *Server) usageMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, ok := authpkg.GetUserID(c)
+		if s.usage == nil || !ok {
+			c.Next()
+			return
+		}
+
+		start := time.Now()
+		limit, err := s.usage.quota(c.Request.Context(), userID, start)
+		if err != nil {
+			s.logger.Error("Failed to load request quota", zap.Uint("user_id", userID), zap.Error(err))
+		}
+		allowed, remaining, reset, err := s.usage.admit(c.Request.Context(), userID, limit, start)
+		if err != nil {
+			s.logger.Warn("Failed to count request against quota", zap.Uint("user_id", userID), zap.Error(err))
+		}
+		if limit > 0 {
+			c.Header("X-RateLimit-Limit", strconv.Itoa(limit))
+			c.Header("X-RateLimit-Remaining", strconv.Itoa(remaining))
+			c.Header("X-RateLimit-Reset", strconv.FormatInt(reset.Unix(), 10))
+		}
+		if !allowed {
+			c.Header("Retry-After", strconv.Itoa(int(math.Ceil(reset.Sub(start).Seconds()))))
+			apierror.Respond(c, http.StatusTooManyRequests, apierror.CodeQuotaExceeded,
+				fmt.Sprintf("Request quota of %d per hour exceeded", limit))
+			return
+		}
+
+		c.Next()
+
+		streaming := c.IsWebsocket() || strings.HasPrefix(c.Writer.Header().Get("Content-Type"), "text/event-stream")
+		s.usage.record(userID, start, c.Writer.Status(), time.Since(start), !streaming)
+	}
+}
+
+// userUsage builds the usage report of a user
+func (s *Server) userUsage(ctx context.Context, user *models.User, hours int, withHours bool, now time.Time) UserUsage {
+	perHour, total := s.usage.usage(user.ID, hours, now)
+	usage := UserUsage{UserID: user.ID, Username: user.Username, Total: total}
+	if withHours {
+		usage.Hours = perHour
+	}
+
+	limit := s.usage.defaultQuota
+	if user.RequestQuota != nil {
+		limit = *user.RequestQuota
+	}
+	if limit > 0 {
+		quota := &RequestQuota{RequestsPerHour: limit, Remaining: limit, ResetAt: now.Truncate(time.Hour).Add(time.Hour)}
+		if used, ok := s.usage.used(ctx, user.ID, now); ok {
+			quota.Remaining = max(limit-int(used), 0)
+		} else if n := len(perHour); n > 0 && perHour[n-1].Hour.Equal(now.Truncate(time.Hour)) {
+			quota.Remaining = max(limit-int(perHour[n-1].Requests), 0)
+		}
+		usage.Quota = quota
+	}
+	return usage
+}
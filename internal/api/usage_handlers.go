@@ -0,0 +1,116 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/padminisys/flintroute/internal/apierror"
+	"github.com/padminisys/flintroute/pkg/models"
+	"go.uber.org/zap"
+)
+
+// SetRequestQuotaRequest sets a user's request quota. A null
+// RequestsPerHour uses the server default; 0 is unlimited.
+type SetRequestQuotaRequest struct {
+	RequestsPerHour *int `json:"requests_per_hour" binding:"omitempty,min=0"`
+}
+
+// usageHoursParam parses the hours query parameter, the number of hours of
+// usage to report. It responds with an error and returns 0 when invalid.
+func usageHoursParam(c *gin.Context) int {
+	hours := usageHours
+	if v := c.Query("hours"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > usageHours {
+			apierror.Respond(c, http.StatusBadRequest, apierror.CodeValidationFailed,
+				fmt.Sprintf("hours must be between 1 and %d", usageHours))
+			return 0
+		}
+		hours = n
+	}
+	return hours
+}
+
+// handleGetUsage handles reporting the current user's API usage and quota
+func (s *Server) handleGetUsage(c *gin.Context) {
+	hours := usageHoursParam(c)
+	if hours == 0 {
+		return
+	}
+	user := s.currentUser(c)
+	if user == nil {
+		return
+	}
+
+	c.JSON(http.StatusOK, s.userUsage(c.Request.Context(), user, hours, true, time.Now()))
+}
+
+// handleListUsage handles reporting the API usage of every user who made
+// requests in the period, busiest first
+func (s *Server) handleListUsage(c *gin.Context) {
+	hours := usageHoursParam(c)
+	if hours == 0 {
+		return
+	}
+
+	var users []models.User
+	if ids := s.usage.users(); len(ids) > 0 {
+		var err error
+		if users, err = s.repos.Users.ListByIDs(c.Request.Context(), ids); err != nil {
+			s.logger.Error("Failed to list users", zap.Error(err))
+			apierror.Respond(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to list usage")
+			return
+		}
+	}
+
+	now := time.Now()
+	usage := make([]UserUsage, 0, len(users))
+	for i := range users {
+		u := s.userUsage(c.Request.Context(), &users[i], hours, false, now)
+		if u.Total.Requests > 0 || u.Total.Throttled > 0 {
+			usage = append(usage, u)
+		}
+	}
+	sort.SliceStable(usage, func(i, j int) bool {
+		if usage[i].Total.Requests != usage[j].Total.Requests {
+			return usage[i].Total.Requests > usage[j].Total.Requests
+		}
+		return usage[i].UserID < usage[j].UserID
+	})
+
+	c.JSON(http.StatusOK, gin.H{
+		"hours":         hours,
+		"default_quota": s.usage.defaultQuota,
+		"users":         usage,
+	})
+}
+
+// handleSetRequestQuota handles setting a user's request quota
+func (s *Server) handleSetRequestQuota(c *gin.Context) {
+	var req SetRequestQuotaRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apierror.Validation(c, err)
+		return
+	}
+
+	user := s.userFromParam(c)
+	if user == nil {
+		return
+	}
+
+	if err := s.repos.Users.Update(c.Request.Context(), user, "request_quota", req.RequestsPerHour); err != nil {
+		s.logger.Error("Failed to set request quota", zap.Error(err))
+		apierror.Respond(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to set request quota")
+		return
+	}
+	user.RequestQuota = req.RequestsPerHour
+	s.usage.forget(user.ID)
+
+	s.logger.Info("Set request quota", zap.String("username", user.Username), zap.Any("requests_per_hour", req.RequestsPerHour))
+
+	c.JSON(http.StatusOK, user)
+}
@@ -0,0 +1,168 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/padminisys/flintroute/internal/apierror"
+	"github.com/padminisys/flintroute/internal/store"
+	"github.com/padminisys/flintroute/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUsageMiddleware(t *testing.T) {
+	server, db := setupTestServer(t)
+	server.usage = newUsageTracker(server.db, store.NewMemory(), 0)
+
+	alice := &models.User{Username: "alice", PasswordHash: "x", Email: "alice@example.com", Role: "user", Active: true}
+	bob := &models.User{Username: "bob", PasswordHash: "x", Email: "bob@example.com", Role: "admin", Active: true}
+	require.NoError(t, db.Create(alice).Error)
+	require.NoError(t, db.Create(bob).Error)
+
+	router := gin.New()
+	v1 := router.Group("/api/v1")
+	v1.Use(func(c *gin.Context) {
+		c.Set("user_id", alice.ID)
+		if c.GetHeader("X-User") == "bob" {
+			c.Set("user_id", bob.ID)
+		}
+	}, server.usageMiddleware())
+	v1.GET("/bgp/peers", func(c *gin.Context) { c.JSON(http.StatusOK, gin.H{"peers": []string{}}) })
+	v1.GET("/bgp/peers/:id", func(c *gin.Context) {
+		apierror.Respond(c, http.StatusNotFound, apierror.CodePeerNotFound, "Peer not found")
+	})
+	v1.GET("/usage", server.handleGetUsage)
+	v1.GET("/admin/usage", server.handleListUsage)
+	v1.PUT("/admin/users/:id/quota", server.handleSetRequestQuota)
+
+	request := func(method, path, user, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, path, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-User", user)
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("Counts requests and errors per user", func(t *testing.T) {
+		request("GET", "/api/v1/bgp/peers", "alice", "")
+		request("GET", "/api/v1/bgp/peers/9", "alice", "")
+		request("GET", "/api/v1/bgp/peers", "bob", "")
+
+		w := request("GET", "/api/v1/usage", "alice", "")
+		require.Equal(t, http.StatusOK, w.Code)
+		var usage UserUsage
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &usage))
+		assert.Equal(t, "alice", usage.Username)
+		assert.EqualValues(t, 3, usage.Total.Requests) // including this one
+		assert.EqualValues(t, 1, usage.Total.Errors)
+		assert.Nil(t, usage.Quota)
+		require.Len(t, usage.Hours, 1)
+		assert.Equal(t, time.Now().Truncate(time.Hour).Unix(), usage.Hours[0].Hour.Unix())
+	})
+
+	t.Run("Admins see every user's usage", func(t *testing.T) {
+		w := request("GET", "/api/v1/admin/usage?hours=1", "bob", "")
+		require.Equal(t, http.StatusOK, w.Code)
+		var resp struct {
+			Users []UserUsage `json:"users"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		require.Len(t, resp.Users, 2)
+		assert.Equal(t, "alice", resp.Users[0].Username)
+		assert.EqualValues(t, 3, resp.Users[0].Total.Requests)
+		assert.Empty(t, resp.Users[0].Hours)
+		assert.EqualValues(t, 2, resp.Users[1].Total.Requests)
+
+		assert.Equal(t, http.StatusBadRequest, request("GET", "/api/v1/admin/usage?hours=48", "bob", "").Code)
+	})
+
+	t.Run("Requests over the quota are rejected", func(t *testing.T) {
+		path := "/api/v1/admin/users/" + strconv.FormatUint(uint64(alice.ID), 10) + "/quota"
+		w := request("PUT", path, "bob", `{"requests_per_hour": 5}`)
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), `"request_quota":5`)
+		assert.Equal(t, http.StatusBadRequest, request("PUT", path, "bob", `{"requests_per_hour": -1}`).Code)
+
+		w = request("GET", "/api/v1/bgp/peers", "alice", "")
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "5", w.Header().Get("X-RateLimit-Limit"))
+		assert.Equal(t, "1", w.Header().Get("X-RateLimit-Remaining"))
+
+		assert.Equal(t, http.StatusOK, request("GET", "/api/v1/bgp/peers", "alice", "").Code)
+		w = request("GET", "/api/v1/bgp/peers", "alice", "")
+		assert.Equal(t, http.StatusTooManyRequests, w.Code)
+		assert.Contains(t, w.Body.String(), string(apierror.CodeQuotaExceeded))
+		retryAfter, err := strconv.Atoi(w.Header().Get("Retry-After"))
+		require.NoError(t, err)
+		assert.True(t, retryAfter > 0 && retryAfter <= 3600)
+
+		// Other users keep their own quota
+		assert.Equal(t, http.StatusOK, request("GET", "/api/v1/bgp/peers", "bob", "").Code)
+
+		// Lifting the quota lets the user back in
+		require.Equal(t, http.StatusOK, request("PUT", path, "bob", `{"requests_per_hour": null}`).Code)
+		w = request("GET", "/api/v1/usage", "alice", "")
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Empty(t, w.Header().Get("X-RateLimit-Limit"))
+		var usage UserUsage
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &usage))
+		assert.EqualValues(t, 1, usage.Total.Throttled)
+	})
+}
+
+func TestUsageTrackerDropsOldHours(t *testing.T) {
+	tracker := newUsageTracker(nil, store.NewMemory(), 0)
+	start := time.Date(2025, 3, 1, 10, 30, 0, 0, time.UTC)
+
+	for h := 0; h < usageHours+5; h++ {
+		at := start.Add(time.Duration(h) * time.Hour)
+		allowed, _, _, err := tracker.admit(context.Background(), 1, 0, at)
+		require.NoError(t, err)
+		require.True(t, allowed)
+		tracker.record(1, at, http.StatusOK, time.Duration(h+1)*time.Millisecond, true)
+	}
+
+	now := start.Add((usageHours + 4) * time.Hour)
+	hours, total := tracker.usage(1, usageHours, now)
+	assert.Len(t, hours, usageHours)
+	assert.EqualValues(t, usageHours, total.Requests)
+	assert.Equal(t, float64(usageHours+5), total.MaxLatencyMS)
+
+	hours, total = tracker.usage(1, 2, now)
+	assert.Len(t, hours, 2)
+	assert.Equal(t, 28.5, total.AvgLatencyMS)
+}
+
+func TestUsageTrackerSharesQuota(t *testing.T) {
+	// Two instances sharing a store enforce one quota between them
+	shared := store.NewMemory()
+	first, second := newUsageTracker(nil, shared, 0), newUsageTracker(nil, shared, 0)
+	now := time.Now()
+
+	allowed, remaining, _, err := first.admit(context.Background(), 1, 2, now)
+	require.NoError(t, err)
+	assert.True(t, allowed)
+	assert.Equal(t, 1, remaining)
+
+	allowed, remaining, _, err = second.admit(context.Background(), 1, 2, now)
+	require.NoError(t, err)
+	assert.True(t, allowed)
+	assert.Zero(t, remaining)
+
+	allowed, _, _, err = first.admit(context.Background(), 1, 2, now)
+	require.NoError(t, err)
+	assert.False(t, allowed)
+
+	used, ok := second.used(context.Background(), 1, now)
+	require.True(t, ok)
+	assert.EqualValues(t, 3, used)
+}
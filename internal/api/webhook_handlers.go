@@ -0,0 +1,228 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/padminisys/flintroute/internal/apierror"
+	authpkg "github.com/padminisys/flintroute/internal/auth"
+	"github.com/padminisys/flintroute/internal/webhooks"
+	"github.com/padminisys/flintroute/pkg/models"
+	"go.uber.org/zap"
+)
+
+// WebhookRequest represents a request to create or update a webhook subscription
+type WebhookRequest struct {
+	URL         string   `json:"url" binding:"required,url"`
+	Description string   `json:"description"`
+	Events      []string `json:"events" binding:"required,min=1"`
+	Active      *bool    `json:"active"`
+	// Secret signs deliveries. It is generated on create when omitted and
+	// left unchanged on update when omitted.
+	Secret *string `json:"secret"`
+}
+
+// WebhookCreatedResponse is returned on create. The signing secret is only
+// ever returned here.
+type WebhookCreatedResponse struct {
+	*models.WebhookSubscription
+	Secret string `json:"secret"`
+}
+
+// handleListWebhooks handles listing all webhook subscriptions
+func (s *Server) handleListWebhooks(c *gin.Context) {
+	subs, err := s.webhookService.ListSubscriptions(c.Request.Context())
+	if err != nil {
+		s.respondWebhookError(c, err, "Failed to list webhooks")
+		return
+	}
+
+	respondJSONWithETag(c, gin.H{"webhooks": subs})
+}
+
+// handleListWebhookEvents handles listing the events webhooks can subscribe to
+func (s *Server) handleListWebhookEvents(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"events": webhooks.Events})
+}
+
+// handleCreateWebhook handles creating a webhook subscription
+func (s *Server) handleCreateWebhook(c *gin.Context) {
+	var req WebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apierror.Validation(c, err)
+		return
+	}
+
+	userID, exists := authpkg.GetUserID(c)
+	if !exists {
+		apierror.Respond(c, http.StatusUnauthorized, apierror.CodeUnauthorized, "User not authenticated")
+		return
+	}
+
+	secret := ""
+	if req.Secret != nil {
+		secret = *req.Secret
+	}
+	if secret == "" {
+		generated, err := webhooks.GenerateSecret()
+		if err != nil {
+			s.respondWebhookError(c, err, "Failed to generate webhook secret")
+			return
+		}
+		secret = generated
+	}
+
+	sub := &models.WebhookSubscription{
+		URL:         req.URL,
+		Description: req.Description,
+		Events:      req.Events,
+		Active:      req.Active == nil || *req.Active,
+		CreatedBy:   userID,
+	}
+	if err := s.webhookService.CreateSubscription(c.Request.Context(), sub, secret); err != nil {
+		s.respondWebhookError(c, err, "Failed to create webhook")
+		return
+	}
+
+	c.JSON(http.StatusCreated, WebhookCreatedResponse{WebhookSubscription: sub, Secret: secret})
+}
+
+// handleGetWebhook handles getting a webhook subscription
+func (s *Server) handleGetWebhook(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		apierror.Respond(c, http.StatusBadRequest, apierror.CodeInvalidID, "Invalid webhook ID")
+		return
+	}
+
+	sub, err := s.webhookService.GetSubscription(c.Request.Context(), uint(id))
+	if err != nil {
+		s.respondWebhookError(c, err, "Failed to get webhook")
+		return
+	}
+
+	c.JSON(http.StatusOK, sub)
+}
+
+// handleUpdateWebhook handles replacing a webhook subscription
+func (s *Server) handleUpdateWebhook(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		apierror.Respond(c, http.StatusBadRequest, apierror.CodeInvalidID, "Invalid webhook ID")
+		return
+	}
+
+	var req WebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apierror.Validation(c, err)
+		return
+	}
+
+	updates := &models.WebhookSubscription{
+		URL:         req.URL,
+		Description: req.Description,
+		Events:      req.Events,
+		Active:      req.Active == nil || *req.Active,
+	}
+	sub, err := s.webhookService.UpdateSubscription(c.Request.Context(), uint(id), updates, req.Secret)
+	if err != nil {
+		s.respondWebhookError(c, err, "Failed to update webhook")
+		return
+	}
+
+	c.JSON(http.StatusOK, sub)
+}
+
+// handleDeleteWebhook handles deleting a webhook subscription
+func (s *Server) handleDeleteWebhook(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		apierror.Respond(c, http.StatusBadRequest, apierror.CodeInvalidID, "Invalid webhook ID")
+		return
+	}
+
+	if err := s.webhookService.DeleteSubscription(c.Request.Context(), uint(id)); err != nil {
+		s.respondWebhookError(c, err, "Failed to delete webhook")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Webhook deleted successfully"})
+}
+
+// handlePingWebhook handles sending a ping event to a webhook subscription
+func (s *Server) handlePingWebhook(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		apierror.Respond(c, http.StatusBadRequest, apierror.CodeInvalidID, "Invalid webhook ID")
+		return
+	}
+
+	delivery, err := s.webhookService.Ping(c.Request.Context(), uint(id))
+	if err != nil {
+		s.respondWebhookError(c, err, "Failed to ping webhook")
+		return
+	}
+
+	c.JSON(http.StatusAccepted, delivery)
+}
+
+// handleListWebhookDeliveries handles listing recent deliveries for a
+// webhook subscription
+func (s *Server) handleListWebhookDeliveries(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		apierror.Respond(c, http.StatusBadRequest, apierror.CodeInvalidID, "Invalid webhook ID")
+		return
+	}
+
+	limit := 50
+	if raw := c.Query("limit"); raw != "" {
+		limit, err = strconv.Atoi(raw)
+		if err != nil || limit < 1 || limit > 500 {
+			apierror.Respond(c, http.StatusBadRequest, apierror.CodeValidationFailed, "limit must be between 1 and 500")
+			return
+		}
+	}
+
+	deliveries, err := s.webhookService.ListDeliveries(c.Request.Context(), uint(id), c.Query("status"), limit)
+	if err != nil {
+		s.respondWebhookError(c, err, "Failed to list webhook deliveries")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"deliveries": deliveries})
+}
+
+// handleRedeliverWebhook handles queueing a delivery again
+func (s *Server) handleRedeliverWebhook(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		apierror.Respond(c, http.StatusBadRequest, apierror.CodeInvalidID, "Invalid delivery ID")
+		return
+	}
+
+	delivery, err := s.webhookService.Redeliver(c.Request.Context(), uint(id))
+	if err != nil {
+		s.respondWebhookError(c, err, "Failed to redeliver webhook")
+		return
+	}
+
+	c.JSON(http.StatusAccepted, delivery)
+}
+
+// respondWebhookError maps a webhook service error to an API error
+func (s *Server) respondWebhookError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, webhooks.ErrSubscriptionNotFound):
+		apierror.Respond(c, http.StatusNotFound, apierror.CodeWebhookNotFound, "Webhook not found")
+	case errors.Is(err, webhooks.ErrDeliveryNotFound):
+		apierror.Respond(c, http.StatusNotFound, apierror.CodeDeliveryNotFound, "Delivery not found")
+	case errors.Is(err, webhooks.ErrInvalidSubscription):
+		apierror.Respond(c, http.StatusBadRequest, apierror.CodeValidationFailed, err.Error())
+	default:
+		s.logger.Error(message, zap.Error(err))
+		apierror.Respond(c, http.StatusInternalServerError, apierror.CodeInternal, message)
+	}
+}
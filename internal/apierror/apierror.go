@@ -0,0 +1,136 @@
+package apierror
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/padminisys/flintroute/internal/requestid"
+)
+
+// Code is a machine-readable error identifier returned in API responses
+type Code string
+
+// Error codes returned by the API
+const (
+	CodeValidationFailed   Code = "VALIDATION_FAILED"
+	CodeInvalidID          Code = "INVALID_ID"
+	CodeUnauthorized       Code = "UNAUTHORIZED"
+	CodeInvalidCredentials Code = "INVALID_CREDENTIALS"
+	CodeAccountDisabled    Code = "ACCOUNT_DISABLED"
+	CodeInvalidToken       Code = "INVALID_TOKEN"
+	CodeForbidden          Code = "FORBIDDEN"
+	CodeNotFound           Code = "NOT_FOUND"
+	CodePeerNotFound       Code = "PEER_NOT_FOUND"
+	CodePeerExists         Code = "PEER_EXISTS"
+	CodePeerNotDeleted     Code = "PEER_NOT_DELETED"
+	CodeSessionNotFound    Code = "SESSION_NOT_FOUND"
+	CodeVersionNotFound    Code = "VERSION_NOT_FOUND"
+	CodeSnapshotNotFound   Code = "SNAPSHOT_NOT_FOUND"
+	CodeInvalidSnapshot    Code = "INVALID_SNAPSHOT"
+	CodeUnsupportedDriver  Code = "UNSUPPORTED_DRIVER"
+	CodeAlertNotFound      Code = "ALERT_NOT_FOUND"
+	CodeWebhookNotFound    Code = "WEBHOOK_NOT_FOUND"
+	CodeDeliveryNotFound   Code = "DELIVERY_NOT_FOUND"
+	CodePolicyNotFound     Code = "POLICY_NOT_FOUND"
+	CodePolicyExists       Code = "POLICY_EXISTS"
+	CodePolicyInUse        Code = "POLICY_IN_USE"
+	CodeTemplateNotFound   Code = "TEMPLATE_NOT_FOUND"
+	CodeTemplateExists     Code = "TEMPLATE_EXISTS"
+	CodeTemplateInUse      Code = "TEMPLATE_IN_USE"
+	CodeASNMismatch        Code = "ASN_MISMATCH"
+	CodeJobNotFound        Code = "JOB_NOT_FOUND"
+	CodeJobNotCancellable  Code = "JOB_NOT_CANCELLABLE"
+	CodeFRRTxNotFound      Code = "FRR_TRANSACTION_NOT_FOUND"
+	CodeChangeNotFound     Code = "CHANGE_NOT_FOUND"
+	CodeChangeNotPending   Code = "CHANGE_NOT_PENDING"
+	CodeSelfReview         Code = "SELF_REVIEW"
+	CodeIdempotencyReused  Code = "IDEMPOTENCY_KEY_REUSED"
+	CodeIdempotencyInUse   Code = "IDEMPOTENCY_KEY_IN_USE"
+	CodeFRRUnavailable     Code = "FRR_UNAVAILABLE"
+	CodeFRRApplyFailed     Code = "FRR_APPLY_FAILED"
+	CodeFRRConfigRejected  Code = "FRR_CONFIG_REJECTED"
+	CodeFRRValidation      Code = "FRR_VALIDATION"
+	CodeConfigChanged      Code = "CONFIG_CHANGED"
+	CodeInvalidSignature   Code = "INVALID_SIGNATURE"
+	CodeGitOpsFetchFailed  Code = "GITOPS_FETCH_FAILED"
+	CodeGitOpsInvalid      Code = "GITOPS_INVALID_DEFINITIONS"
+	CodeGitOpsConflict     Code = "GITOPS_CONFLICT"
+	CodeInvalidState       Code = "INVALID_STATE"
+	CodeStateConflict      Code = "STATE_CONFLICT"
+	CodeEmailInUse         Code = "EMAIL_IN_USE"
+	CodeStarting           Code = "STARTING"
+	CodeReadOnly           Code = "READ_ONLY"
+	CodeNotLeader          Code = "NOT_LEADER"
+	CodeQuotaExceeded      Code = "QUOTA_EXCEEDED"
+	CodeASNNotFound        Code = "ASN_NOT_FOUND"
+	CodePeeringDBMismatch  Code = "PEERINGDB_MISMATCH"
+	CodePeeringDBDown      Code = "PEERINGDB_UNAVAILABLE"
+	CodeStoreUnavailable   Code = "STORE_UNAVAILABLE"
+	CodeTenantNotFound     Code = "TENANT_NOT_FOUND"
+	CodeTenantExists       Code = "TENANT_EXISTS"
+	CodeTenantInUse        Code = "TENANT_IN_USE"
+	CodeTenantRequired     Code = "TENANT_REQUIRED"
+	CodeNotTenantMember    Code = "NOT_TENANT_MEMBER"
+	CodeEmailUnavailable   Code = "EMAIL_UNAVAILABLE"
+	CodeDemoDisabled       Code = "DEMO_MODE_DISABLED"
+	CodeInternal           Code = "INTERNAL_ERROR"
+)
+
+// FieldError describes a problem with a single request field
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// Response is the error envelope returned by all API endpoints.
+// Error carries the human-readable message for backwards compatibility.
+type Response struct {
+	Error  string       `json:"error"`
+	Code   Code         `json:"code"`
+	Errors []FieldError `json:"errors,omitempty"`
+	// Details repeats Errors for clients written before it was added
+	Details   []FieldError `json:"details,omitempty"`
+	RequestID string       `json:"request_id,omitempty"`
+	// FRR locates the configuration FRR rejected
+	FRR *FRRDetail `json:"frr,omitempty"`
+}
+
+// FRRDetail locates configuration FRR rejected and keeps its raw output
+type FRRDetail struct {
+	// Command is the rejected statement, when FRR said which
+	Command string `json:"command,omitempty"`
+	// Line is the rejected statement's line in the checked configuration
+	Line int `json:"line,omitempty"`
+	// Path is the northbound data path of the rejected node
+	Path string `json:"path,omitempty"`
+	Raw  string `json:"raw"`
+}
+
+// Respond writes an error envelope with the given status and aborts the chain
+func Respond(c *gin.Context, status int, code Code, message string, fieldErrors ...FieldError) {
+	c.AbortWithStatusJSON(status, Response{
+		Error:     message,
+		Code:      code,
+		Errors:    fieldErrors,
+		Details:   fieldErrors,
+		RequestID: requestid.FromContext(c.Request.Context()),
+	})
+}
+
+// RespondFRR writes an error envelope for configuration FRR rejected,
+// with detail, and aborts the chain
+func RespondFRR(c *gin.Context, status int, code Code, message string, detail *FRRDetail) {
+	c.AbortWithStatusJSON(status, Response{
+		Error:     message,
+		Code:      code,
+		RequestID: requestid.FromContext(c.Request.Context()),
+		FRR:       detail,
+	})
+}
+
+// Validation writes a 400 VALIDATION_FAILED response for a request binding
+// error, listing the problem with each field
+func Validation(c *gin.Context, err error) {
+	message, fieldErrors := translate(err)
+	Respond(c, http.StatusBadRequest, CodeValidationFailed, message, fieldErrors...)
+}
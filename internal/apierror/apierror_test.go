@@ -0,0 +1,105 @@
+package apierror
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/padminisys/flintroute/internal/requestid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRespond(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	t.Run("Writes error envelope and aborts", func(t *testing.T) {
+		router := gin.New()
+		router.Use(requestid.Middleware())
+		called := false
+		router.GET("/test", func(c *gin.Context) {
+			Respond(c, http.StatusNotFound, CodePeerNotFound, "Peer not found")
+		}, func(c *gin.Context) {
+			called = true
+		})
+
+		req := httptest.NewRequest(http.MethodGet, "/test", nil)
+		req.Header.Set("X-Request-ID", "req-123")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusNotFound, w.Code)
+		assert.False(t, called)
+
+		var resp Response
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.Equal(t, "Peer not found", resp.Error)
+		assert.Equal(t, CodePeerNotFound, resp.Code)
+		assert.Equal(t, "req-123", resp.RequestID)
+		assert.Empty(t, resp.Details)
+	})
+}
+
+func TestValidation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	type settings struct {
+		HoldTime int `json:"hold_time" binding:"omitempty,min=3"`
+	}
+	type request struct {
+		Name      string   `json:"name" binding:"required,max=8"`
+		RemoteASN uint32   `json:"remote_asn" binding:"required"`
+		Family    string   `json:"family" binding:"omitempty,oneof=ipv4 ipv6"`
+		Settings  settings `json:"settings"`
+	}
+
+	router := gin.New()
+	router.POST("/test", func(c *gin.Context) {
+		var req request
+		if err := c.ShouldBindJSON(&req); err != nil {
+			Validation(c, err)
+			return
+		}
+		c.Status(http.StatusOK)
+	})
+
+	post := func(body string) Response {
+		req := httptest.NewRequest(http.MethodPost, "/test", strings.NewReader(body))
+		if body == "" {
+			req.Body = http.NoBody
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		var resp Response
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.Equal(t, CodeValidationFailed, resp.Code)
+		return resp
+	}
+
+	t.Run("Reports each field by its JSON name", func(t *testing.T) {
+		resp := post(`{"name": "edge-router-1", "family": "ipv5", "settings": {"hold_time": 1}}`)
+		assert.Equal(t, []FieldError{
+			{Field: "name", Message: "must be at most 8 characters"},
+			{Field: "remote_asn", Message: "required"},
+			{Field: "family", Message: "must be one of: ipv4, ipv6"},
+			{Field: "settings.hold_time", Message: "must be at least 3"},
+		}, resp.Errors)
+		assert.Equal(t, resp.Errors, resp.Details)
+	})
+
+	t.Run("Reports mistyped fields", func(t *testing.T) {
+		resp := post(`{"name": "edge", "remote_asn": "65001"}`)
+		assert.Equal(t, []FieldError{{Field: "remote_asn", Message: "must be a number"}}, resp.Errors)
+	})
+
+	t.Run("Reports missing and malformed bodies", func(t *testing.T) {
+		assert.Equal(t, "Request body is required", post("").Error)
+		resp := post(`{"name": `)
+		assert.Equal(t, "Request body is not valid JSON", resp.Error)
+		assert.Empty(t, resp.Errors)
+	})
+}
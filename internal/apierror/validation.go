@@ -0,0 +1,133 @@
+package apierror
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+func init() {
+	// Report fields by the names clients send rather than Go field names
+	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
+		v.RegisterTagNameFunc(fieldName)
+	}
+}
+
+// fieldName returns the name a struct field is bound from: its JSON name,
+// else its form name, else its Go name
+func fieldName(fld reflect.StructField) string {
+	for _, key := range []string{"json", "form"} {
+		name, _, _ := strings.Cut(fld.Tag.Get(key), ",")
+		if name != "" && name != "-" {
+			return name
+		}
+	}
+	return fld.Name
+}
+
+// translate turns a request binding error into a message and the problem
+// with each field
+func translate(err error) (string, []FieldError) {
+	var validationErrs validator.ValidationErrors
+	if errors.As(err, &validationErrs) {
+		fieldErrors := make([]FieldError, 0, len(validationErrs))
+		for _, fe := range validationErrs {
+			fieldErrors = append(fieldErrors, FieldError{Field: fieldPath(fe), Message: message(fe)})
+		}
+		return "Invalid request", fieldErrors
+	}
+
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) && typeErr.Field != "" {
+		return "Invalid request", []FieldError{{Field: typeErr.Field, Message: "must be " + jsonType(typeErr.Type)}}
+	}
+	var syntaxErr *json.SyntaxError
+	if errors.As(err, &syntaxErr) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return "Request body is not valid JSON", nil
+	}
+	if errors.Is(err, io.EOF) {
+		return "Request body is required", nil
+	}
+	return "Invalid request", nil
+}
+
+// fieldPath returns the path of a field within the request, such as
+// "remote_asn" or "settings.hold_time", without the request type name
+func fieldPath(fe validator.FieldError) string {
+	_, path, ok := strings.Cut(fe.Namespace(), ".")
+	if !ok {
+		return fe.Field()
+	}
+	return path
+}
+
+// message describes a failed validation rule in words
+func message(fe validator.FieldError) string {
+	param := fe.Param()
+	switch fe.Tag() {
+	case "required", "required_if", "required_unless", "required_with", "required_without":
+		return "required"
+	case "min", "gte":
+		return "must be at least " + quantity(fe.Kind(), param)
+	case "max", "lte":
+		return "must be at most " + quantity(fe.Kind(), param)
+	case "gt":
+		return "must be more than " + quantity(fe.Kind(), param)
+	case "lt":
+		return "must be less than " + quantity(fe.Kind(), param)
+	case "len":
+		return "must be exactly " + quantity(fe.Kind(), param)
+	case "oneof":
+		return "must be one of: " + strings.Join(strings.Fields(param), ", ")
+	case "email":
+		return "must be a valid email address"
+	case "ip":
+		return "must be a valid IP address"
+	case "ipv4":
+		return "must be a valid IPv4 address"
+	case "ipv6":
+		return "must be a valid IPv6 address"
+	case "cidr":
+		return "must be a valid CIDR prefix"
+	case "url":
+		return "must be a valid URL"
+	case "hostname":
+		return "must be a valid hostname"
+	case "dive":
+		return "is invalid"
+	}
+	return "failed on the '" + fe.Tag() + "' rule"
+}
+
+// quantity phrases a rule parameter for the kind of field it limits
+func quantity(kind reflect.Kind, param string) string {
+	switch kind {
+	case reflect.String:
+		return param + " characters"
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return param + " items"
+	}
+	return param
+}
+
+// jsonType names the JSON type a Go type is decoded from
+func jsonType(typ reflect.Type) string {
+	switch typ.Kind() {
+	case reflect.Bool:
+		return "a boolean"
+	case reflect.String:
+		return "a string"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return "a number"
+	case reflect.Slice, reflect.Array:
+		return "an array"
+	}
+	return "an object"
+}
@@ -0,0 +1,184 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/padminisys/flintroute/internal/store"
+	"github.com/padminisys/flintroute/pkg/models"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// Denylist rejects access tokens before they expire, so that logging out or
+// disabling an account takes effect immediately. Entries are kept in a
+// store for AuthMiddleware, shared by every instance using it, and
+// persisted so they survive a restart. Store entries expire with the tokens
+// they cover; persisted ones are pruned.
+type Denylist struct {
+	db          *gorm.DB
+	store       store.Store
+	tokenExpiry time.Duration
+	logger      *zap.Logger
+}
+
+// NewDenylist creates a denylist and loads the unexpired entries from the
+// database into st. tokenExpiry is the lifetime of access tokens.
+func NewDenylist(db *gorm.DB, st store.Store, tokenExpiry time.Duration, logger *zap.Logger) (*Denylist, error) {
+	d := &Denylist{
+		db:          db,
+		store:       st,
+		tokenExpiry: tokenExpiry,
+		logger:      logger,
+	}
+
+	var rows []models.RevokedToken
+	if err := db.Where("expires_at > ?", time.Now()).Find(&rows).Error; err != nil {
+		return nil, fmt.Errorf("failed to load revoked tokens: %w", err)
+	}
+	for _, row := range rows {
+		if err := d.add(context.Background(), row); err != nil {
+			return nil, fmt.Errorf("failed to load revoked tokens: %w", err)
+		}
+	}
+
+	return d, nil
+}
+
+// RevokeToken revokes a single access token. Tokens without an ID, issued
+// before tokens carried one, can only be revoked with RevokeUser.
+func (d *Denylist) RevokeToken(ctx context.Context, claims *Claims) error {
+	if claims.ID == "" || claims.ExpiresAt == nil {
+		return nil
+	}
+
+	return d.persist(ctx, models.RevokedToken{
+		JTI:       claims.ID,
+		UserID:    claims.UserID,
+		ExpiresAt: claims.ExpiresAt.Time,
+	})
+}
+
+// RevokeUser revokes every access token issued to a user so far
+func (d *Denylist) RevokeUser(ctx context.Context, userID uint) error {
+	now := time.Now()
+	return d.persist(ctx, models.RevokedToken{
+		CreatedAt: now,
+		UserID:    userID,
+		ExpiresAt: now.Add(d.tokenExpiry),
+	})
+}
+
+// IsRevoked reports whether an access token has been revoked. It fails
+// when the store can't be read.
+func (d *Denylist) IsRevoked(ctx context.Context, claims *Claims) (bool, error) {
+	if claims.ID != "" {
+		_, err := d.store.Get(ctx, tokenKey(claims.ID))
+		if err == nil {
+			return true, nil
+		}
+		if !errors.Is(err, store.ErrNotFound) {
+			return false, fmt.Errorf("failed to check revoked tokens: %w", err)
+		}
+	}
+
+	before, ok, err := d.revokedBefore(ctx, claims.UserID)
+	if err != nil || !ok {
+		return false, err
+	}
+	// Issue times are truncated to the second, so a token issued in the
+	// same second as the revocation is treated as revoked
+	return claims.IssuedAt == nil || !claims.IssuedAt.After(before), nil
+}
+
+// Prune deletes persisted entries whose tokens have expired
+func (d *Denylist) Prune(ctx context.Context) error {
+	if err := d.db.WithContext(ctx).Where("expires_at <= ?", time.Now()).Delete(&models.RevokedToken{}).Error; err != nil {
+		return fmt.Errorf("failed to prune revoked tokens: %w", err)
+	}
+	return nil
+}
+
+// Start prunes expired entries every interval until ctx is done
+func (d *Denylist) Start(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if err := d.Prune(ctx); err != nil {
+			d.logger.Error("Failed to prune revoked tokens", zap.Error(err))
+		}
+	}
+}
+
+// persist stores an entry in the database and adds it to the store
+func (d *Denylist) persist(ctx context.Context, row models.RevokedToken) error {
+	if err := d.db.WithContext(ctx).Create(&row).Error; err != nil {
+		return fmt.Errorf("failed to revoke token: %w", err)
+	}
+	if err := d.add(ctx, row); err != nil {
+		return fmt.Errorf("failed to revoke token: %w", err)
+	}
+	return nil
+}
+
+// add adds a persisted entry to the store, expiring with the tokens it
+// covers. Expired entries are skipped.
+func (d *Denylist) add(ctx context.Context, row models.RevokedToken) error {
+	ttl := time.Until(row.ExpiresAt)
+	if ttl <= 0 {
+		return nil
+	}
+
+	if row.JTI != "" {
+		return d.store.Set(ctx, tokenKey(row.JTI), []byte{1}, ttl)
+	}
+
+	// A later revocation of the user covers the earlier ones, and its
+	// entry lives longer
+	before, ok, err := d.revokedBefore(ctx, row.UserID)
+	if err != nil {
+		return err
+	}
+	if ok && !row.CreatedAt.After(before) {
+		return nil
+	}
+	value := strconv.AppendInt(nil, row.CreatedAt.UnixNano(), 10)
+	return d.store.Set(ctx, userKey(row.UserID), value, ttl)
+}
+
+// revokedBefore returns the time before which all of a user's tokens were
+// revoked, if any were
+func (d *Denylist) revokedBefore(ctx context.Context, userID uint) (time.Time, bool, error) {
+	value, err := d.store.Get(ctx, userKey(userID))
+	if errors.Is(err, store.ErrNotFound) {
+		return time.Time{}, false, nil
+	}
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("failed to check revoked tokens: %w", err)
+	}
+	nanos, err := strconv.ParseInt(string(value), 10, 64)
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("invalid user revocation: %w", err)
+	}
+	return time.Unix(0, nanos), true, nil
+}
+
+// tokenKey is the store key of a revoked token
+func tokenKey(jti string) string {
+	return "denylist:token:" + jti
+}
+
+// userKey is the store key of a user's revocation
+func userKey(userID uint) string {
+	return "denylist:user:" + strconv.FormatUint(uint64(userID), 10)
+}
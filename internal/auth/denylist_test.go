@@ -0,0 +1,171 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gin-gonic/gin"
+	"github.com/padminisys/flintroute/internal/store"
+	"github.com/padminisys/flintroute/internal/testutil"
+	"github.com/padminisys/flintroute/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestDenylist(t *testing.T) {
+	db := testutil.SetupTestDB(t)
+	manager := NewJWTManager("test-secret", 15*time.Minute, 7*24*time.Hour)
+	user := &models.User{ID: 1, Username: "alice", Role: RoleUser}
+	st := store.NewMemory()
+
+	issue := func(t *testing.T) *Claims {
+		t.Helper()
+		token, err := manager.GenerateToken(user)
+		require.NoError(t, err)
+		claims, err := manager.ValidateToken(token)
+		require.NoError(t, err)
+		return claims
+	}
+	isRevoked := func(t *testing.T, denylist *Denylist, claims *Claims) bool {
+		t.Helper()
+		revoked, err := denylist.IsRevoked(context.Background(), claims)
+		require.NoError(t, err)
+		return revoked
+	}
+
+	t.Run("Access tokens have unique IDs", func(t *testing.T) {
+		assert.NotEmpty(t, issue(t).ID)
+		assert.NotEqual(t, issue(t).ID, issue(t).ID)
+	})
+
+	t.Run("Revokes a single token", func(t *testing.T) {
+		denylist, err := NewDenylist(db.DB, st, 15*time.Minute, zap.NewNop())
+		require.NoError(t, err)
+
+		revoked, other := issue(t), issue(t)
+		require.NoError(t, denylist.RevokeToken(context.Background(), revoked))
+
+		assert.True(t, isRevoked(t, denylist, revoked))
+		assert.False(t, isRevoked(t, denylist, other))
+	})
+
+	t.Run("Revokes every token of a user", func(t *testing.T) {
+		denylist, err := NewDenylist(db.DB, st, 15*time.Minute, zap.NewNop())
+		require.NoError(t, err)
+
+		claims, otherUser := issue(t), issue(t)
+		claims.UserID = 2
+		require.NoError(t, denylist.RevokeUser(context.Background(), 2))
+
+		assert.True(t, isRevoked(t, denylist, claims))
+		assert.False(t, isRevoked(t, denylist, otherUser))
+
+		later := issue(t)
+		later.UserID = 2
+		later.IssuedAt.Time = time.Now().Add(time.Second)
+		assert.False(t, isRevoked(t, denylist, later))
+	})
+
+	t.Run("Survives a restart", func(t *testing.T) {
+		denylist, err := NewDenylist(db.DB, st, 15*time.Minute, zap.NewNop())
+		require.NoError(t, err)
+
+		claims := issue(t)
+		require.NoError(t, denylist.RevokeToken(context.Background(), claims))
+
+		reloaded, err := NewDenylist(db.DB, store.NewMemory(), 15*time.Minute, zap.NewNop())
+		require.NoError(t, err)
+		assert.True(t, isRevoked(t, reloaded, claims))
+	})
+
+	t.Run("Shares revocations through the store", func(t *testing.T) {
+		server := miniredis.RunT(t)
+		shared, err := store.NewRedis(store.RedisConfig{Address: server.Addr()})
+		require.NoError(t, err)
+		defer shared.Close()
+
+		first, err := NewDenylist(db.DB, shared, 15*time.Minute, zap.NewNop())
+		require.NoError(t, err)
+		second, err := NewDenylist(db.DB, shared, 15*time.Minute, zap.NewNop())
+		require.NoError(t, err)
+
+		claims := issue(t)
+		claims.UserID = 3
+		require.NoError(t, first.RevokeUser(context.Background(), 3))
+		assert.True(t, isRevoked(t, second, claims))
+
+		server.Close()
+		_, err = second.IsRevoked(context.Background(), claims)
+		assert.Error(t, err)
+	})
+
+	t.Run("Prunes expired entries", func(t *testing.T) {
+		denylist, err := NewDenylist(db.DB, st, 15*time.Minute, zap.NewNop())
+		require.NoError(t, err)
+
+		claims := issue(t)
+		claims.ExpiresAt.Time = time.Now().Add(-time.Second)
+		require.NoError(t, denylist.RevokeToken(context.Background(), claims))
+
+		require.NoError(t, denylist.Prune(context.Background()))
+
+		assert.False(t, isRevoked(t, denylist, claims))
+		var count int64
+		db.Model(&models.RevokedToken{}).Where("jti = ?", claims.ID).Count(&count)
+		assert.Zero(t, count)
+	})
+
+	t.Run("Middleware rejects revoked tokens", func(t *testing.T) {
+		denylist, err := NewDenylist(db.DB, st, 15*time.Minute, zap.NewNop())
+		require.NoError(t, err)
+
+		router := setupTestRouter()
+		router.GET("/protected", AuthMiddleware(manager, denylist), func(c *gin.Context) {
+			c.Status(http.StatusOK)
+		})
+
+		token, err := manager.GenerateToken(user)
+		require.NoError(t, err)
+		claims, err := manager.ValidateToken(token)
+		require.NoError(t, err)
+		require.NoError(t, denylist.RevokeToken(context.Background(), claims))
+
+		req := httptest.NewRequest(http.MethodGet, "/protected", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		assert.Contains(t, w.Body.String(), "Token has been revoked")
+	})
+
+	t.Run("Middleware fails closed when the store is down", func(t *testing.T) {
+		server := miniredis.RunT(t)
+		shared, err := store.NewRedis(store.RedisConfig{Address: server.Addr()})
+		require.NoError(t, err)
+		defer shared.Close()
+		denylist, err := NewDenylist(db.DB, shared, 15*time.Minute, zap.NewNop())
+		require.NoError(t, err)
+		server.Close()
+
+		router := setupTestRouter()
+		router.GET("/protected", AuthMiddleware(manager, denylist), func(c *gin.Context) {
+			c.Status(http.StatusOK)
+		})
+
+		token, err := manager.GenerateToken(user)
+		require.NoError(t, err)
+		req := httptest.NewRequest(http.MethodGet, "/protected", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+		assert.Contains(t, w.Body.String(), "STORE_UNAVAILABLE")
+	})
+}
@@ -4,10 +4,12 @@ import (
 	"crypto/rand"
 	"encoding/hex"
 	"errors"
+	"sort"
+	"sync"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
-	"github.com/padminisys/flintroute/internal/models"
+	"github.com/padminisys/flintroute/pkg/models"
 )
 
 var (
@@ -20,23 +22,178 @@ type Claims struct {
 	UserID   uint   `json:"user_id"`
 	Username string `json:"username"`
 	Role     string `json:"role"`
+	// Act is set on impersonation tokens to the admin acting as the user
+	Act *Actor `json:"act,omitempty"`
 	jwt.RegisteredClaims
 }
 
-// JWTManager manages JWT tokens
+// Actor is the admin an impersonation token was issued to, in the "act"
+// claim of RFC 8693 with the username as its subject
+type Actor struct {
+	Subject string `json:"sub"`
+	UserID  uint   `json:"user_id"`
+}
+
+// JWTManager manages JWT tokens. Tokens are signed with a single current key
+// and carry its ID in the "kid" header; they are verified against every
+// known key, so tokens issued before a rotation stay valid.
 type JWTManager struct {
-	secretKey     string
+	mu            sync.RWMutex
+	signing       *jwtKey
+	keys          map[string]*jwtKey
 	tokenExpiry   time.Duration
 	refreshExpiry time.Duration
 }
 
-// NewJWTManager creates a new JWT manager
+// NewJWTManager creates a new JWT manager that signs tokens with a shared
+// HS256 secret
 func NewJWTManager(secretKey string, tokenExpiry, refreshExpiry time.Duration) *JWTManager {
-	return &JWTManager{
-		secretKey:     secretKey,
+	m := &JWTManager{
+		keys:          make(map[string]*jwtKey),
 		tokenExpiry:   tokenExpiry,
 		refreshExpiry: refreshExpiry,
 	}
+	m.rotate(newHMACKey(secretKey))
+	return m
+}
+
+// NewJWTManagerWithKey creates a new JWT manager that signs tokens with a PEM
+// RSA (RS256) or EC (ES256, ES384 or ES512) private key
+func NewJWTManagerWithKey(privateKey string, tokenExpiry, refreshExpiry time.Duration) (*JWTManager, error) {
+	key, err := parsePrivateKey(privateKey)
+	if err != nil {
+		return nil, err
+	}
+
+	m := &JWTManager{
+		keys:          make(map[string]*jwtKey),
+		tokenExpiry:   tokenExpiry,
+		refreshExpiry: refreshExpiry,
+	}
+	m.rotate(key)
+	return m, nil
+}
+
+// SetSecret replaces the signing secret, e.g. after a secret rotation.
+// Tokens signed with the previous secret are accepted until they would
+// have expired.
+func (m *JWTManager) SetSecret(secretKey string) {
+	m.rotate(newHMACKey(secretKey))
+}
+
+// SetSigningKey replaces the signing key with a PEM private key. Tokens
+// signed with the previous key are accepted until they would have expired.
+func (m *JWTManager) SetSigningKey(privateKey string) error {
+	key, err := parsePrivateKey(privateKey)
+	if err != nil {
+		return err
+	}
+	m.rotate(key)
+	return nil
+}
+
+// AddVerificationKey accepts tokens signed by the private half of a PEM
+// public key or certificate, e.g. the key in use before a restart with a
+// new signing key. The key is published in the JWKS.
+func (m *JWTManager) AddVerificationKey(publicKey string) error {
+	key, err := parsePublicKey(publicKey)
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.keys[key.id]; !ok {
+		m.keys[key.id] = key
+	}
+	return nil
+}
+
+// KeyID returns the ID of the current signing key
+func (m *JWTManager) KeyID() string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.signing.id
+}
+
+// JWKS returns the public keys tokens are verified with. HMAC secrets are
+// never published.
+func (m *JWTManager) JWKS() JWKSet {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	// The signing key comes first, then the others by ID
+	var others []JWK
+	now := time.Now()
+	for _, key := range m.keys {
+		if key == m.signing || key.jwk == nil || m.expired(key, now) {
+			continue
+		}
+		others = append(others, *key.jwk)
+	}
+	sort.Slice(others, func(i, j int) bool { return others[i].KeyID < others[j].KeyID })
+
+	set := JWKSet{Keys: []JWK{}}
+	if m.signing.jwk != nil {
+		set.Keys = append(set.Keys, *m.signing.jwk)
+	}
+	set.Keys = append(set.Keys, others...)
+	return set
+}
+
+// rotate makes key the signing key. The previous signing key keeps
+// verifying tokens until the longest-lived token it signed has expired.
+func (m *JWTManager) rotate(key *jwtKey) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	if previous := m.signing; previous != nil && previous.id != key.id {
+		previous.expiresAt = now.Add(max(m.tokenExpiry, m.refreshExpiry))
+	}
+	for id, k := range m.keys {
+		if m.expired(k, now) {
+			delete(m.keys, id)
+		}
+	}
+
+	key.expiresAt = time.Time{}
+	m.signing = key
+	m.keys[key.id] = key
+}
+
+// expired reports whether a rotated-out key no longer verifies tokens
+func (m *JWTManager) expired(key *jwtKey, now time.Time) bool {
+	return !key.expiresAt.IsZero() && !now.Before(key.expiresAt)
+}
+
+// sign signs claims with the current signing key
+func (m *JWTManager) sign(claims Claims) (string, error) {
+	m.mu.RLock()
+	key := m.signing
+	m.mu.RUnlock()
+
+	token := jwt.NewWithClaims(key.method, claims)
+	token.Header["kid"] = key.id
+	return token.SignedString(key.signKey)
+}
+
+// verificationKey returns the key a token was signed with. Tokens without a
+// key ID predate key IDs and were signed with the HMAC secret.
+func (m *JWTManager) verificationKey(token *jwt.Token) (interface{}, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var key *jwtKey
+	if kid, _ := token.Header["kid"].(string); kid != "" {
+		key = m.keys[kid]
+	} else if _, ok := m.signing.verifyKey.([]byte); ok {
+		key = m.signing
+	}
+	if key == nil || m.expired(key, time.Now()) || token.Method.Alg() != key.method.Alg() {
+		return nil, ErrInvalidToken
+	}
+	return key.verifyKey, nil
 }
 
 // GenerateToken generates a new JWT token for a user
@@ -49,21 +206,17 @@ func (m *JWTManager) GenerateToken(user *models.User) (string, error) {
 			ExpiresAt: jwt.NewNumericDate(time.Now().Add(m.tokenExpiry)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 			NotBefore: jwt.NewNumericDate(time.Now()),
+			ID:        newTokenID(),
 		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(m.secretKey))
+	return m.sign(claims)
 }
 
 // GenerateRefreshToken generates a new refresh token
 func (m *JWTManager) GenerateRefreshToken(user *models.User) (string, time.Time, error) {
 	expiresAt := time.Now().Add(m.refreshExpiry)
-	
-	// Generate unique token ID to prevent duplicates
-	jti := make([]byte, 16)
-	rand.Read(jti)
-	
+
 	claims := Claims{
 		UserID:   user.ID,
 		Username: user.Username,
@@ -72,23 +225,46 @@ func (m *JWTManager) GenerateRefreshToken(user *models.User) (string, time.Time,
 			ExpiresAt: jwt.NewNumericDate(expiresAt),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 			NotBefore: jwt.NewNumericDate(time.Now()),
-			ID:        hex.EncodeToString(jti),
+			ID:        newTokenID(), // unique, to prevent duplicate tokens
 		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	tokenString, err := token.SignedString([]byte(m.secretKey))
+	tokenString, err := m.sign(claims)
 	return tokenString, expiresAt, err
 }
 
+// GenerateImpersonationToken generates a token acting as user on behalf of
+// admin, valid for expiry. The admin is recorded in the token's "act" claim.
+// There is no refresh token, so the token can't be extended.
+func (m *JWTManager) GenerateImpersonationToken(user, admin *models.User, expiry time.Duration) (string, *Claims, error) {
+	now := time.Now()
+	claims := Claims{
+		UserID:   user.ID,
+		Username: user.Username,
+		Role:     user.Role,
+		Act:      &Actor{Subject: admin.Username, UserID: admin.ID},
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(now.Add(expiry)),
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+			ID:        newTokenID(),
+		},
+	}
+
+	token, err := m.sign(claims)
+	return token, &claims, err
+}
+
+// newTokenID generates a random JWT ID, used to revoke individual tokens
+func newTokenID() string {
+	jti := make([]byte, 16)
+	rand.Read(jti)
+	return hex.EncodeToString(jti)
+}
+
 // ValidateToken validates a JWT token and returns the claims
 func (m *JWTManager) ValidateToken(tokenString string) (*Claims, error) {
-	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, ErrInvalidToken
-		}
-		return []byte(m.secretKey), nil
-	})
+	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, m.verificationKey)
 
 	if err != nil {
 		if errors.Is(err, jwt.ErrTokenExpired) {
@@ -103,4 +279,4 @@ func (m *JWTManager) ValidateToken(tokenString string) (*Claims, error) {
 	}
 
 	return claims, nil
-}
\ No newline at end of file
+}
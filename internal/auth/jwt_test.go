@@ -4,8 +4,9 @@ import (
 	"testing"
 	"time"
 
-	"github.com/padminisys/flintroute/internal/models"
+	"github.com/padminisys/flintroute/pkg/models"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestNewJWTManager(t *testing.T) {
@@ -16,11 +17,30 @@ func TestNewJWTManager(t *testing.T) {
 	manager := NewJWTManager(secretKey, tokenExpiry, refreshExpiry)
 
 	assert.NotNil(t, manager)
-	assert.Equal(t, secretKey, manager.secretKey)
+	assert.Equal(t, []byte(secretKey), manager.signing.signKey)
 	assert.Equal(t, tokenExpiry, manager.tokenExpiry)
 	assert.Equal(t, refreshExpiry, manager.refreshExpiry)
 }
 
+func TestSetSecret(t *testing.T) {
+	manager := NewJWTManager("old-secret", 15*time.Minute, 7*24*time.Hour)
+	user := &models.User{ID: 1, Username: "testuser", Role: "admin"}
+
+	oldToken, err := manager.GenerateToken(user)
+	require.NoError(t, err)
+
+	manager.SetSecret("new-secret")
+
+	// Tokens signed with the previous secret stay valid after a rotation
+	_, err = manager.ValidateToken(oldToken)
+	assert.NoError(t, err)
+
+	newToken, err := manager.GenerateToken(user)
+	require.NoError(t, err)
+	_, err = manager.ValidateToken(newToken)
+	assert.NoError(t, err)
+}
+
 func TestGenerateToken(t *testing.T) {
 	manager := NewJWTManager("test-secret", 15*time.Minute, 7*24*time.Hour)
 
@@ -48,6 +68,31 @@ func TestGenerateToken(t *testing.T) {
 	})
 }
 
+func TestGenerateImpersonationToken(t *testing.T) {
+	manager := NewJWTManager("test-secret", 15*time.Minute, 7*24*time.Hour)
+	user := &models.User{ID: 2, Username: "bob", Role: "operator"}
+	admin := &models.User{ID: 1, Username: "alice", Role: "admin"}
+
+	token, issued, err := manager.GenerateImpersonationToken(user, admin, 5*time.Minute)
+	require.NoError(t, err)
+	assert.WithinDuration(t, time.Now().Add(5*time.Minute), issued.ExpiresAt.Time, 2*time.Second)
+
+	claims, err := manager.ValidateToken(token)
+	require.NoError(t, err)
+	assert.Equal(t, user.ID, claims.UserID)
+	assert.Equal(t, "bob", claims.Username)
+	assert.Equal(t, "operator", claims.Role)
+	assert.Equal(t, &Actor{Subject: "alice", UserID: 1}, claims.Act)
+	assert.Equal(t, issued.ID, claims.ID)
+
+	// Regular tokens carry no actor
+	token, err = manager.GenerateToken(user)
+	require.NoError(t, err)
+	claims, err = manager.ValidateToken(token)
+	require.NoError(t, err)
+	assert.Nil(t, claims.Act)
+}
+
 func TestGenerateRefreshToken(t *testing.T) {
 	manager := NewJWTManager("test-secret", 15*time.Minute, 7*24*time.Hour)
 
@@ -215,4 +260,4 @@ func TestRefreshTokenExpiry(t *testing.T) {
 		// Allow 1 second tolerance
 		assert.WithinDuration(t, expectedExpiry, expiresAt, time.Second)
 	})
-}
\ No newline at end of file
+}
@@ -0,0 +1,195 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// ErrUnsupportedKey is returned for keys that can't sign or verify tokens
+var ErrUnsupportedKey = errors.New("unsupported JWT key")
+
+// JWK is a public key in JSON Web Key format
+type JWK struct {
+	KeyType   string `json:"kty"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+	KeyID     string `json:"kid"`
+	// RSA
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+	// EC
+	Curve string `json:"crv,omitempty"`
+	X     string `json:"x,omitempty"`
+	Y     string `json:"y,omitempty"`
+}
+
+// JWKSet is the document served at the JWKS endpoint
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+// jwtKey is a key tokens are signed or verified with
+type jwtKey struct {
+	id     string
+	method jwt.SigningMethod
+	// signKey is nil for keys that only verify tokens
+	signKey   interface{}
+	verifyKey interface{}
+	// jwk is the published public key; nil for HMAC secrets
+	jwk *JWK
+	// expiresAt is when a rotated-out key stops verifying tokens; zero
+	// while the key is in use or configured
+	expiresAt time.Time
+}
+
+// newHMACKey creates an HS256 key from a shared secret. Its ID is derived
+// from the secret so that every instance sharing it agrees on the ID.
+func newHMACKey(secret string) *jwtKey {
+	sum := sha256.Sum256([]byte("flintroute-jwt-key:" + secret))
+	return &jwtKey{
+		id:        "hs-" + hex.EncodeToString(sum[:8]),
+		method:    jwt.SigningMethodHS256,
+		signKey:   []byte(secret),
+		verifyKey: []byte(secret),
+	}
+}
+
+// parsePrivateKey parses a PEM RSA or EC private key
+func parsePrivateKey(pemKey string) (*jwtKey, error) {
+	block, _ := pem.Decode([]byte(pemKey))
+	if block == nil {
+		return nil, fmt.Errorf("%w: no PEM block found", ErrUnsupportedKey)
+	}
+
+	var private interface{}
+	var err error
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		private, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		private, err = x509.ParseECPrivateKey(block.Bytes)
+	default:
+		private, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnsupportedKey, err)
+	}
+
+	signer, ok := private.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("%w: %T", ErrUnsupportedKey, private)
+	}
+	key, err := newPublicKey(signer.Public())
+	if err != nil {
+		return nil, err
+	}
+	key.signKey = private
+	return key, nil
+}
+
+// parsePublicKey parses a PEM RSA or EC public key
+func parsePublicKey(pemKey string) (*jwtKey, error) {
+	block, _ := pem.Decode([]byte(pemKey))
+	if block == nil {
+		return nil, fmt.Errorf("%w: no PEM block found", ErrUnsupportedKey)
+	}
+
+	var public interface{}
+	var err error
+	switch block.Type {
+	case "RSA PUBLIC KEY":
+		public, err = x509.ParsePKCS1PublicKey(block.Bytes)
+	case "CERTIFICATE":
+		var cert *x509.Certificate
+		if cert, err = x509.ParseCertificate(block.Bytes); err == nil {
+			public = cert.PublicKey
+		}
+	default:
+		public, err = x509.ParsePKIXPublicKey(block.Bytes)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnsupportedKey, err)
+	}
+
+	return newPublicKey(public)
+}
+
+// newPublicKey creates a verification key for an RSA or EC public key. Its
+// ID is the key's RFC 7638 thumbprint.
+func newPublicKey(public crypto.PublicKey) (*jwtKey, error) {
+	key := &jwtKey{verifyKey: public}
+
+	switch public := public.(type) {
+	case *rsa.PublicKey:
+		key.method = jwt.SigningMethodRS256
+		key.jwk = &JWK{
+			KeyType: "RSA",
+			N:       base64.RawURLEncoding.EncodeToString(public.N.Bytes()),
+			E:       base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes()),
+		}
+	case *ecdsa.PublicKey:
+		var size int
+		switch public.Curve {
+		case elliptic.P256():
+			key.method, size = jwt.SigningMethodES256, 32
+		case elliptic.P384():
+			key.method, size = jwt.SigningMethodES384, 48
+		case elliptic.P521():
+			key.method, size = jwt.SigningMethodES512, 66
+		default:
+			return nil, fmt.Errorf("%w: unsupported curve %s", ErrUnsupportedKey, public.Curve.Params().Name)
+		}
+		key.jwk = &JWK{
+			KeyType: "EC",
+			Curve:   public.Curve.Params().Name,
+			X:       base64.RawURLEncoding.EncodeToString(public.X.FillBytes(make([]byte, size))),
+			Y:       base64.RawURLEncoding.EncodeToString(public.Y.FillBytes(make([]byte, size))),
+		}
+	default:
+		return nil, fmt.Errorf("%w: %T", ErrUnsupportedKey, public)
+	}
+
+	key.id = thumbprint(key.jwk)
+	key.jwk.Use = "sig"
+	key.jwk.Algorithm = key.method.Alg()
+	key.jwk.KeyID = key.id
+	return key, nil
+}
+
+// thumbprint computes a JWK's RFC 7638 thumbprint from its required
+// members in lexicographic order
+func thumbprint(jwk *JWK) string {
+	var members interface{}
+	if jwk.KeyType == "RSA" {
+		members = struct {
+			E   string `json:"e"`
+			Kty string `json:"kty"`
+			N   string `json:"n"`
+		}{jwk.E, jwk.KeyType, jwk.N}
+	} else {
+		members = struct {
+			Crv string `json:"crv"`
+			Kty string `json:"kty"`
+			X   string `json:"x"`
+			Y   string `json:"y"`
+		}{jwk.Curve, jwk.KeyType, jwk.X, jwk.Y}
+	}
+
+	data, _ := json.Marshal(members)
+	sum := sha256.Sum256(data)
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
@@ -0,0 +1,197 @@
+package auth
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/padminisys/flintroute/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// generateKey returns a PEM private key and its PEM public key
+func generateKey(t *testing.T, kind string) (string, string) {
+	t.Helper()
+
+	var private interface{}
+	var public interface{}
+	switch kind {
+	case "RSA":
+		key, err := rsa.GenerateKey(rand.Reader, 2048)
+		require.NoError(t, err)
+		private, public = key, &key.PublicKey
+	case "EC":
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		require.NoError(t, err)
+		private, public = key, &key.PublicKey
+	}
+
+	privateDER, err := x509.MarshalPKCS8PrivateKey(private)
+	require.NoError(t, err)
+	publicDER, err := x509.MarshalPKIXPublicKey(public)
+	require.NoError(t, err)
+
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privateDER})),
+		string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicDER}))
+}
+
+func TestAsymmetricKeys(t *testing.T) {
+	user := &models.User{ID: 1, Username: "testuser", Role: RoleAdmin}
+
+	for kind, alg := range map[string]string{"RSA": "RS256", "EC": "ES256"} {
+		t.Run(kind+" keys sign "+alg+" tokens", func(t *testing.T) {
+			privateKey, _ := generateKey(t, kind)
+			manager, err := NewJWTManagerWithKey(privateKey, 15*time.Minute, 7*24*time.Hour)
+			require.NoError(t, err)
+
+			token, err := manager.GenerateToken(user)
+			require.NoError(t, err)
+
+			parsed, _, err := jwt.NewParser().ParseUnverified(token, &Claims{})
+			require.NoError(t, err)
+			assert.Equal(t, alg, parsed.Method.Alg())
+			assert.Equal(t, manager.KeyID(), parsed.Header["kid"])
+
+			claims, err := manager.ValidateToken(token)
+			require.NoError(t, err)
+			assert.Equal(t, user.ID, claims.UserID)
+		})
+	}
+
+	t.Run("Rejects keys it can't use", func(t *testing.T) {
+		_, err := NewJWTManagerWithKey("not a key", 15*time.Minute, 7*24*time.Hour)
+		assert.ErrorIs(t, err, ErrUnsupportedKey)
+	})
+}
+
+func TestKeyRotation(t *testing.T) {
+	user := &models.User{ID: 1, Username: "testuser", Role: RoleAdmin}
+
+	t.Run("Previous key verifies until its tokens expire", func(t *testing.T) {
+		oldKey, _ := generateKey(t, "EC")
+		newKey, _ := generateKey(t, "RSA")
+		manager, err := NewJWTManagerWithKey(oldKey, 15*time.Minute, 7*24*time.Hour)
+		require.NoError(t, err)
+
+		oldToken, err := manager.GenerateToken(user)
+		require.NoError(t, err)
+		oldID := manager.KeyID()
+
+		require.NoError(t, manager.SetSigningKey(newKey))
+		assert.NotEqual(t, oldID, manager.KeyID())
+
+		_, err = manager.ValidateToken(oldToken)
+		assert.NoError(t, err)
+		assert.Len(t, manager.JWKS().Keys, 2)
+
+		assert.WithinDuration(t, time.Now().Add(7*24*time.Hour), manager.keys[oldID].expiresAt, time.Minute)
+		manager.keys[oldID].expiresAt = time.Now()
+
+		_, err = manager.ValidateToken(oldToken)
+		assert.Error(t, err)
+		assert.Len(t, manager.JWKS().Keys, 1)
+	})
+
+	t.Run("Verification keys accept another signer's tokens", func(t *testing.T) {
+		otherKey, otherPublic := generateKey(t, "RSA")
+		other, err := NewJWTManagerWithKey(otherKey, 15*time.Minute, 7*24*time.Hour)
+		require.NoError(t, err)
+		token, err := other.GenerateToken(user)
+		require.NoError(t, err)
+
+		signingKey, _ := generateKey(t, "EC")
+		manager, err := NewJWTManagerWithKey(signingKey, 15*time.Minute, 7*24*time.Hour)
+		require.NoError(t, err)
+
+		_, err = manager.ValidateToken(token)
+		assert.Error(t, err)
+
+		require.NoError(t, manager.AddVerificationKey(otherPublic))
+		_, err = manager.ValidateToken(token)
+		assert.NoError(t, err)
+	})
+
+	t.Run("Rejects tokens whose algorithm doesn't match the key", func(t *testing.T) {
+		privateKey, publicKey := generateKey(t, "RSA")
+		manager, err := NewJWTManagerWithKey(privateKey, 15*time.Minute, 7*24*time.Hour)
+		require.NoError(t, err)
+
+		// HS256 signed with the public key, which is no secret
+		forged := jwt.NewWithClaims(jwt.SigningMethodHS256, Claims{UserID: 1, Role: RoleAdmin})
+		forged.Header["kid"] = manager.KeyID()
+		token, err := forged.SignedString([]byte(publicKey))
+		require.NoError(t, err)
+
+		_, err = manager.ValidateToken(token)
+		assert.ErrorIs(t, err, ErrInvalidToken)
+	})
+
+	t.Run("Accepts HMAC tokens without a key ID", func(t *testing.T) {
+		manager := NewJWTManager("test-secret", 15*time.Minute, 7*24*time.Hour)
+		legacy := jwt.NewWithClaims(jwt.SigningMethodHS256, Claims{
+			UserID:           1,
+			RegisteredClaims: jwt.RegisteredClaims{ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute))},
+		})
+		token, err := legacy.SignedString([]byte("test-secret"))
+		require.NoError(t, err)
+
+		_, err = manager.ValidateToken(token)
+		assert.NoError(t, err)
+	})
+}
+
+func TestJWKS(t *testing.T) {
+	t.Run("HMAC secrets are not published", func(t *testing.T) {
+		manager := NewJWTManager("test-secret", 15*time.Minute, 7*24*time.Hour)
+		assert.Empty(t, manager.JWKS().Keys)
+	})
+
+	t.Run("Publishes public keys that verify tokens", func(t *testing.T) {
+		privateKey, _ := generateKey(t, "RSA")
+		manager, err := NewJWTManagerWithKey(privateKey, 15*time.Minute, 7*24*time.Hour)
+		require.NoError(t, err)
+
+		keys := manager.JWKS().Keys
+		require.Len(t, keys, 1)
+		jwk := keys[0]
+		assert.Equal(t, "RSA", jwk.KeyType)
+		assert.Equal(t, "RS256", jwk.Algorithm)
+		assert.Equal(t, "sig", jwk.Use)
+		assert.Equal(t, manager.KeyID(), jwk.KeyID)
+
+		// Rebuild the public key from the JWK as another service would
+		n, err := base64.RawURLEncoding.DecodeString(jwk.N)
+		require.NoError(t, err)
+		e, err := base64.RawURLEncoding.DecodeString(jwk.E)
+		require.NoError(t, err)
+		public := &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+
+		token, err := manager.GenerateToken(&models.User{ID: 1})
+		require.NoError(t, err)
+		_, err = jwt.ParseWithClaims(token, &Claims{}, func(*jwt.Token) (interface{}, error) {
+			return public, nil
+		})
+		assert.NoError(t, err)
+	})
+
+	t.Run("Key IDs are RFC 7638 thumbprints", func(t *testing.T) {
+		// Example from RFC 7638 section 3.1
+		jwk := &JWK{
+			KeyType: "RSA",
+			E:       "AQAB",
+			N: "0vx7agoebGcQSuuPiLJXZptN9nndrQmbXEps2aiAFbWhM78LhWx4cbbfAAtVT86zwu1RK7aPFFxuhDR1L6tSoc_BJECPebWKRXjBZCiFV4n3oknjhMs" +
+				"tn64tZ_2W-5JsGY4Hc5n9yBXArwl93lqt7_RN5w6Cf0h4QyQ5v-65YGjQR0_FDW2QvzqY368QQMicAtaSqzs8KJZgnYb9c7d0zgdAZHzu6qMQvRL5hajrn1n9" +
+				"1CbOpbISD08qNLyrdkt-bFTWhAI4vMQFh6WeZu0fM4lFd2NcRwr3XPksINHaQ-G_xBniIqbw0Ls1jF44-csFCur-kEgU8awapJzKnqDKgw",
+		}
+		assert.Equal(t, "NzbLsXh8uDCcd-6MNwXF4W_7noWXFZAfHkxZsRGC9Xs", thumbprint(jwk))
+	})
+}
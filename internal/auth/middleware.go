@@ -5,38 +5,53 @@ import (
 	"strings"
 
 	"github.com/gin-gonic/gin"
+	"github.com/padminisys/flintroute/internal/apierror"
 )
 
-// AuthMiddleware creates a middleware for JWT authentication
-func AuthMiddleware(jwtManager *JWTManager) gin.HandlerFunc {
+// AuthMiddleware creates a middleware for JWT authentication. Tokens on the
+// denylist are rejected; denylist may be nil.
+func AuthMiddleware(jwtManager *JWTManager, denylist *Denylist) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization header required"})
-			c.Abort()
+			apierror.Respond(c, http.StatusUnauthorized, apierror.CodeUnauthorized, "Authorization header required")
 			return
 		}
 
 		// Extract token from "Bearer <token>"
 		parts := strings.SplitN(authHeader, " ", 2)
 		if len(parts) != 2 || parts[0] != "Bearer" {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid authorization header format"})
-			c.Abort()
+			apierror.Respond(c, http.StatusUnauthorized, apierror.CodeUnauthorized, "Invalid authorization header format")
 			return
 		}
 
 		token := parts[1]
 		claims, err := jwtManager.ValidateToken(token)
 		if err != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired token"})
-			c.Abort()
+			apierror.Respond(c, http.StatusUnauthorized, apierror.CodeInvalidToken, "Invalid or expired token")
 			return
 		}
+		if denylist != nil {
+			revoked, err := denylist.IsRevoked(c.Request.Context(), claims)
+			if err != nil {
+				// Without the denylist a revoked token could get through
+				apierror.Respond(c, http.StatusServiceUnavailable, apierror.CodeStoreUnavailable, "Unable to check token revocation")
+				return
+			}
+			if revoked {
+				apierror.Respond(c, http.StatusUnauthorized, apierror.CodeInvalidToken, "Token has been revoked")
+				return
+			}
+		}
 
 		// Store claims in context
 		c.Set("user_id", claims.UserID)
 		c.Set("username", claims.Username)
 		c.Set("role", claims.Role)
+		if claims.Act != nil {
+			c.Set("impersonator", claims.Act.Subject)
+			c.Set("impersonator_id", claims.Act.UserID)
+		}
 
 		c.Next()
 	}
@@ -44,17 +59,51 @@ func AuthMiddleware(jwtManager *JWTManager) gin.HandlerFunc {
 
 // AdminMiddleware ensures the user has admin role
 func AdminMiddleware() gin.HandlerFunc {
+	return RequireRole(RoleAdmin)
+}
+
+// RequireRole ensures the user has at least the given role
+func RequireRole(required string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !hasContextRole(c, required) {
+			respondForbidden(c, required)
+			return
+		}
+		c.Next()
+	}
+}
+
+// RequireRoles ensures the user has at least the read role for GET, HEAD
+// and OPTIONS requests and at least the write role for any other method
+func RequireRoles(read, write string) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		role, exists := c.Get("role")
-		if !exists || role != "admin" {
-			c.JSON(http.StatusForbidden, gin.H{"error": "Admin access required"})
-			c.Abort()
+		required := write
+		switch c.Request.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			required = read
+		}
+
+		if !hasContextRole(c, required) {
+			respondForbidden(c, required)
 			return
 		}
 		c.Next()
 	}
 }
 
+// hasContextRole reports whether the authenticated user's role grants at
+// least the access of required
+func hasContextRole(c *gin.Context, required string) bool {
+	role, _ := GetRole(c)
+	return HasRole(role, required)
+}
+
+// respondForbidden rejects a request that needs the required role
+func respondForbidden(c *gin.Context, required string) {
+	message := strings.ToUpper(required[:1]) + required[1:] + " access required"
+	apierror.Respond(c, http.StatusForbidden, apierror.CodeForbidden, message)
+}
+
 // GetUserID extracts user ID from context
 func GetUserID(c *gin.Context) (uint, bool) {
 	userID, exists := c.Get("user_id")
@@ -75,6 +124,18 @@ func GetUsername(c *gin.Context) (string, bool) {
 	return name, ok
 }
 
+// GetImpersonator extracts the username of the admin impersonating the
+// user from context; ok is false unless the request uses an impersonation
+// token
+func GetImpersonator(c *gin.Context) (string, bool) {
+	impersonator, exists := c.Get("impersonator")
+	if !exists {
+		return "", false
+	}
+	name, ok := impersonator.(string)
+	return name, ok
+}
+
 // GetRole extracts role from context
 func GetRole(c *gin.Context) (string, bool) {
 	role, exists := c.Get("role")
@@ -83,4 +144,4 @@ func GetRole(c *gin.Context) (string, bool) {
 	}
 	r, ok := role.(string)
 	return r, ok
-}
\ No newline at end of file
+}
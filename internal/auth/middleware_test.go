@@ -7,7 +7,7 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
-	"github.com/padminisys/flintroute/internal/models"
+	"github.com/padminisys/flintroute/pkg/models"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -21,7 +21,7 @@ func TestAuthMiddleware(t *testing.T) {
 	router := setupTestRouter()
 
 	// Protected endpoint
-	router.GET("/protected", AuthMiddleware(manager), func(c *gin.Context) {
+	router.GET("/protected", AuthMiddleware(manager, nil), func(c *gin.Context) {
 		c.JSON(http.StatusOK, gin.H{"message": "success"})
 	})
 
@@ -105,7 +105,7 @@ func TestAuthMiddleware(t *testing.T) {
 		token, _ := manager.GenerateToken(user)
 
 		router := setupTestRouter()
-		router.GET("/check-context", AuthMiddleware(manager), func(c *gin.Context) {
+		router.GET("/check-context", AuthMiddleware(manager, nil), func(c *gin.Context) {
 			userID, exists := c.Get("user_id")
 			assert.True(t, exists)
 			assert.Equal(t, uint(42), userID)
@@ -260,7 +260,7 @@ func TestMiddlewareChaining(t *testing.T) {
 	router := setupTestRouter()
 
 	// Chain auth and admin middleware
-	router.GET("/admin-protected", AuthMiddleware(manager), AdminMiddleware(), func(c *gin.Context) {
+	router.GET("/admin-protected", AuthMiddleware(manager, nil), AdminMiddleware(), func(c *gin.Context) {
 		c.JSON(http.StatusOK, gin.H{"message": "admin success"})
 	})
 
@@ -298,4 +298,51 @@ func TestMiddlewareChaining(t *testing.T) {
 
 		assert.Equal(t, http.StatusUnauthorized, w.Code)
 	})
-}
\ No newline at end of file
+}
+func TestHasRole(t *testing.T) {
+	assert.True(t, HasRole(RoleAdmin, RoleOperator))
+	assert.True(t, HasRole(RoleOperator, RoleOperator))
+	assert.True(t, HasRole(RoleOperator, RoleUser))
+	assert.False(t, HasRole(RoleUser, RoleOperator))
+	assert.False(t, HasRole(RoleOperator, RoleAdmin))
+	assert.False(t, HasRole("guest", RoleUser))
+	assert.False(t, HasRole("", RoleUser))
+}
+
+func TestRequireRoles(t *testing.T) {
+	router := setupTestRouter()
+
+	setRole := func(c *gin.Context) {
+		c.Set("role", c.Query("role"))
+		c.Next()
+	}
+	ok := func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"message": "ok"})
+	}
+	router.GET("/peers", setRole, RequireRoles(RoleUser, RoleOperator), ok)
+	router.POST("/peers", setRole, RequireRoles(RoleUser, RoleOperator), ok)
+
+	tests := []struct {
+		method string
+		role   string
+		want   int
+	}{
+		{http.MethodGet, RoleUser, http.StatusOK},
+		{http.MethodGet, RoleAdmin, http.StatusOK},
+		{http.MethodGet, "", http.StatusForbidden},
+		{http.MethodPost, RoleUser, http.StatusForbidden},
+		{http.MethodPost, RoleOperator, http.StatusOK},
+		{http.MethodPost, RoleAdmin, http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, "/peers?role="+tt.role, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, tt.want, w.Code, "%s as %q", tt.method, tt.role)
+		if tt.want == http.StatusForbidden && tt.method == http.MethodPost {
+			assert.Contains(t, w.Body.String(), "Operator access required")
+		}
+	}
+}
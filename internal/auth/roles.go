@@ -0,0 +1,23 @@
+package auth
+
+// User roles, from least to most privileged. Each role can do everything
+// the roles before it can.
+const (
+	RoleUser     = "user"     // read-only access
+	RoleOperator = "operator" // changes peers, policies and configuration
+	RoleAdmin    = "admin"    // administration, such as pausing monitoring
+)
+
+// roleRanks orders the roles by privilege
+var roleRanks = map[string]int{
+	RoleUser:     1,
+	RoleOperator: 2,
+	RoleAdmin:    3,
+}
+
+// HasRole reports whether role grants at least the access of required.
+// Unknown roles grant nothing.
+func HasRole(role, required string) bool {
+	rank, ok := roleRanks[role]
+	return ok && rank >= roleRanks[required]
+}
@@ -0,0 +1,62 @@
+package bgp
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/padminisys/flintroute/internal/requestid"
+	"github.com/padminisys/flintroute/pkg/models"
+	"go.uber.org/zap"
+)
+
+// Peer actions that can be scheduled for a maintenance window
+const (
+	PeerActionEnable   = "enable"
+	PeerActionShutdown = "shutdown"
+)
+
+// RunPeerAction enables or shuts down a peer
+func (s *Service) RunPeerAction(ctx context.Context, id uint, action string) (*models.BGPPeer, error) {
+	var enabled bool
+	switch action {
+	case PeerActionEnable:
+		enabled = true
+	case PeerActionShutdown:
+	default:
+		return nil, fmt.Errorf("%w: unknown peer action %q", ErrInvalidPeer, action)
+	}
+
+	if err := s.PatchPeer(ctx, id, &PeerPatch{Enabled: &enabled}); err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("Ran scheduled BGP peer action", zap.Uint("id", id), zap.String("action", action), requestid.Field(ctx))
+
+	return s.GetPeer(ctx, id)
+}
+
+// AnnouncePeerAction alerts that action will run on a peer at runAt
+func (s *Service) AnnouncePeerAction(ctx context.Context, id uint, action string, runAt time.Time) {
+	peer, err := s.GetPeer(ctx, id)
+	if err != nil {
+		s.logger.Warn("Failed to announce scheduled peer action", zap.Uint("id", id), zap.Error(err))
+		return
+	}
+
+	severity := "info"
+	verb := "enabled"
+	if action == PeerActionShutdown {
+		severity = "warning"
+		verb = "shut down"
+	}
+
+	alert := models.Alert{
+		Type:     "peer_action_scheduled",
+		Severity: severity,
+		Message:  fmt.Sprintf("BGP peer %s (%s) will be %s at %s", peer.Name, peer.IPAddress, verb, runAt.UTC().Format(time.RFC3339)),
+		PeerID:   &peer.ID,
+		Peer:     peer,
+	}
+	s.raiseAlert(ctx, &alert, false)
+}
@@ -0,0 +1,48 @@
+package bgp
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/padminisys/flintroute/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPeerActions(t *testing.T) {
+	ctx := context.Background()
+	service := setupTestService(t, ConsistencyEventual)
+
+	peer := newTestPeer("10.0.0.1", true)
+	require.NoError(t, service.CreatePeer(ctx, peer))
+
+	t.Run("Announce an upcoming action", func(t *testing.T) {
+		runAt := time.Date(2026, 10, 18, 22, 0, 0, 0, time.UTC)
+		service.AnnouncePeerAction(ctx, peer.ID, PeerActionShutdown, runAt)
+
+		var alert models.Alert
+		require.NoError(t, service.db.Where("type = ?", "peer_action_scheduled").First(&alert).Error)
+		assert.Equal(t, "warning", alert.Severity)
+		assert.Equal(t, peer.ID, *alert.PeerID)
+		assert.Contains(t, alert.Message, "will be shut down at 2026-10-18T22:00:00Z")
+	})
+
+	t.Run("Shut down and enable a peer", func(t *testing.T) {
+		updated, err := service.RunPeerAction(ctx, peer.ID, PeerActionShutdown)
+		require.NoError(t, err)
+		assert.False(t, updated.Enabled)
+
+		updated, err = service.RunPeerAction(ctx, peer.ID, PeerActionEnable)
+		require.NoError(t, err)
+		assert.True(t, updated.Enabled)
+	})
+
+	t.Run("Reject unknown actions and peers", func(t *testing.T) {
+		_, err := service.RunPeerAction(ctx, peer.ID, "restart")
+		assert.ErrorIs(t, err, ErrInvalidPeer)
+
+		_, err = service.RunPeerAction(ctx, 999, PeerActionEnable)
+		assert.ErrorIs(t, err, ErrPeerNotFound)
+	})
+}
@@ -0,0 +1,135 @@
+package bgp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/padminisys/flintroute/internal/frrconf"
+	"github.com/padminisys/flintroute/internal/requestid"
+	"github.com/padminisys/flintroute/internal/tenancy"
+	"github.com/padminisys/flintroute/internal/webhooks"
+	"github.com/padminisys/flintroute/pkg/models"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// reasonNotInFRR is why a selected address FRR has no neighbor for isn't
+// adopted
+const reasonNotInFRR = "FRR's default BGP instance has no such neighbor"
+
+// AdoptableNeighbor is a neighbor FRR runs that FlintRoute doesn't manage
+type AdoptableNeighbor struct {
+	IPAddress string `json:"ip_address"`
+	// Peer is the peer the neighbor would be stored as, with its current
+	// settings
+	Peer *models.BGPPeer `json:"peer"`
+	// Reason is why the neighbor can't be adopted; empty when it can
+	Reason string `json:"reason,omitempty"`
+}
+
+// AdoptReport is the result of adopting FRR neighbors as peers
+type AdoptReport struct {
+	AdoptedAt time.Time `json:"adopted_at"`
+	ASN       uint32    `json:"asn"`
+	// Adopted are the peers created for the selected neighbors
+	Adopted []*models.BGPPeer `json:"adopted"`
+	// Skipped are the selected neighbors that couldn't be adopted
+	Skipped []*ImportItem `json:"skipped"`
+	// Candidates are the neighbors FlintRoute still doesn't manage
+	Candidates []*AdoptableNeighbor `json:"candidates"`
+}
+
+// AdoptNeighbors creates peers for the neighbors of FRR's default BGP
+// instance with the given IP addresses, capturing their current settings,
+// so that an existing router can be brought under FlintRoute a few
+// neighbors at a time. FRR already runs them, so nothing is applied to FRR.
+// The report lists the neighbors left to adopt; without addresses nothing
+// is adopted.
+func (s *Service) AdoptNeighbors(ctx context.Context, addresses []string) (*AdoptReport, error) {
+	running, err := s.frrClient.GetRunningConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+	parsed := frrconf.Parse(running)
+	if parsed.BGP == nil {
+		return nil, ErrNoBGPInstance
+	}
+	router, err := s.RouterASN(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	selected := make(map[string]bool, len(addresses))
+	for _, address := range addresses {
+		selected[address] = true
+	}
+	report := &AdoptReport{
+		AdoptedAt:  time.Now(),
+		ASN:        parsed.BGP.ASN,
+		Adopted:    []*models.BGPPeer{},
+		Skipped:    []*ImportItem{},
+		Candidates: []*AdoptableNeighbor{},
+	}
+
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for _, neighbor := range parsed.BGP.Peers() {
+			adopt := selected[neighbor.Name]
+			delete(selected, neighbor.Name)
+
+			var peer *models.BGPPeer
+			var err error
+			if router != 0 && parsed.BGP.ASN != router {
+				err = fmt.Errorf("%w: the instance's AS %d differs from the router's AS %d", ErrASNMismatch, parsed.BGP.ASN, router)
+			} else {
+				peer, err = s.importPeer(tx, parsed.BGP.ASN, neighbor, tenancy.ID(ctx), !adopt)
+			}
+
+			if adopt && err != nil {
+				report.Skipped = append(report.Skipped, &ImportItem{Kind: ImportKindPeer, Name: neighbor.Name, Reason: err.Error()})
+			}
+			switch {
+			case errors.Is(err, ErrPeerExists):
+				// Already managed
+			case adopt && err == nil:
+				report.Adopted = append(report.Adopted, peer)
+			default:
+				candidate := &AdoptableNeighbor{IPAddress: neighbor.Name, Peer: peer}
+				if err != nil {
+					candidate.Peer = importedPeer(parsed.BGP.ASN, neighbor)
+					candidate.Reason = err.Error()
+				}
+				candidate.Peer.HasPassword = candidate.Peer.Password != ""
+				report.Candidates = append(report.Candidates, candidate)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to adopt neighbors: %w", err)
+	}
+	for _, address := range addresses {
+		if selected[address] {
+			delete(selected, address)
+			report.Skipped = append(report.Skipped, &ImportItem{Kind: ImportKindPeer, Name: address, Reason: reasonNotInFRR})
+		}
+	}
+
+	if len(report.Adopted) > 0 {
+		s.peersChanged()
+		for _, peer := range report.Adopted {
+			s.wsHub.BroadcastPeerUpdate(ctx, peer)
+			s.config.Webhooks.Publish(ctx, webhooks.EventPeerCreated, peer)
+		}
+	}
+
+	s.logger.Info("Adopted FRR neighbors",
+		zap.Int("adopted", len(report.Adopted)),
+		zap.Int("skipped", len(report.Skipped)),
+		zap.Int("candidates", len(report.Candidates)),
+		requestid.Field(ctx),
+	)
+
+	return report, nil
+}
@@ -0,0 +1,103 @@
+package bgp
+
+import (
+	"context"
+	"testing"
+
+	"github.com/padminisys/flintroute/internal/frr"
+	"github.com/padminisys/flintroute/internal/repository"
+	"github.com/padminisys/flintroute/internal/tenancy"
+	"github.com/padminisys/flintroute/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAdoptNeighbors(t *testing.T) {
+	ctx := context.Background()
+	service := setupTestService(t, ConsistencyEventual)
+	client := frr.NewMockClient()
+	client.On("GetRunningConfig", mock.Anything).Return(importRunningConfig, nil)
+	service.frrClient = client
+	require.NoError(t, repository.WritePeer(service.db.DB, newTestPeer("10.0.0.4", true)))
+
+	candidates := func(report *AdoptReport) map[string]*AdoptableNeighbor {
+		byIP := make(map[string]*AdoptableNeighbor)
+		for _, candidate := range report.Candidates {
+			byIP[candidate.IPAddress] = candidate
+		}
+		return byIP
+	}
+
+	t.Run("Lists the neighbors FlintRoute doesn't manage", func(t *testing.T) {
+		report, err := service.AdoptNeighbors(ctx, nil)
+		require.NoError(t, err)
+		assert.Empty(t, report.Adopted)
+		assert.Equal(t, uint32(65001), report.ASN)
+
+		byIP := candidates(report)
+		require.Len(t, byIP, 3, "10.0.0.4 is managed")
+		assert.Empty(t, byIP["10.0.0.2"].Reason)
+		assert.Equal(t, "Customer B", byIP["10.0.0.2"].Peer.Name)
+		assert.True(t, byIP["10.0.0.2"].Peer.HasPassword)
+		assert.Contains(t, byIP["10.0.0.3"].Reason, "keepalive must be less than holdtime")
+
+		peers, err := service.ListPeers(ctx)
+		require.NoError(t, err)
+		assert.Len(t, peers, 1, "nothing is adopted")
+	})
+
+	t.Run("Adopts the selected neighbors with their settings", func(t *testing.T) {
+		report, err := service.AdoptNeighbors(ctx, []string{"10.0.0.2", "10.0.0.3", "10.0.0.4", "10.0.0.9"})
+		require.NoError(t, err)
+		require.Len(t, report.Adopted, 1)
+		adopted := report.Adopted[0]
+		assert.Equal(t, "10.0.0.2", adopted.IPAddress)
+		assert.Equal(t, models.PeerSyncSynced, adopted.SyncStatus)
+
+		skipped := make(map[string]string)
+		for _, item := range report.Skipped {
+			skipped[item.Name] = item.Reason
+		}
+		assert.Len(t, skipped, 3)
+		assert.Contains(t, skipped["10.0.0.3"], "keepalive must be less than holdtime")
+		assert.Contains(t, skipped["10.0.0.4"], "already exists")
+		assert.Equal(t, reasonNotInFRR, skipped["10.0.0.9"])
+
+		byIP := candidates(report)
+		assert.Len(t, byIP, 2, "10.0.0.1 and 10.0.0.3 are left")
+		assert.NotContains(t, byIP, "10.0.0.2")
+
+		stored, err := service.GetPeer(ctx, adopted.ID)
+		require.NoError(t, err)
+		assert.Equal(t, uint32(65002), stored.RemoteASN)
+		assert.Equal(t, "CUSTOMER-IN", stored.RouteMapIn)
+		assert.Equal(t, uint32(64999), stored.LocalAS)
+		password, err := service.config.PasswordCipher.Decrypt(stored.Password)
+		require.NoError(t, err)
+		assert.Equal(t, "s3cret", password)
+		client.AssertNotCalled(t, "AddBGPPeer", mock.Anything, mock.Anything)
+		client.AssertNotCalled(t, "UpdateBGPPeer", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Adopts into the request's tenant", func(t *testing.T) {
+		report, err := service.AdoptNeighbors(tenancy.WithTenant(ctx, 3), []string{"10.0.0.1"})
+		require.NoError(t, err)
+		require.Len(t, report.Adopted, 1)
+
+		var stored models.BGPPeer
+		require.NoError(t, service.db.First(&stored, report.Adopted[0].ID).Error)
+		require.NotNil(t, stored.TenantID)
+		assert.Equal(t, uint(3), *stored.TenantID)
+		assert.Equal(t, map[string]string{PeerGroupTag: "UPSTREAM"}, report.Adopted[0].Tags.Map())
+	})
+
+	t.Run("Fails without a BGP instance", func(t *testing.T) {
+		empty := frr.NewMockClient()
+		empty.On("GetRunningConfig", mock.Anything).Return("frr version 9.1\n", nil)
+		service.frrClient = empty
+
+		_, err := service.AdoptNeighbors(ctx, []string{"10.0.0.1"})
+		assert.ErrorIs(t, err, ErrNoBGPInstance)
+	})
+}
@@ -0,0 +1,330 @@
+package bgp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/padminisys/flintroute/internal/tenancy"
+	"github.com/padminisys/flintroute/pkg/models"
+	"go.uber.org/zap"
+)
+
+// Alert types raised for sudden changes in the number of prefixes a peer
+// sends
+const (
+	AlertRouteLeakSuspected = "route_leak_suspected"
+	AlertPrefixWithdrawal   = "prefix_withdrawal"
+)
+
+// TopTalkerMetrics are the session counters peers can be ranked by
+var TopTalkerMetrics = []string{"prefixes_received", "prefixes_sent", "messages_received", "messages_sent"}
+
+// ErrInvalidMetric is returned when peers are ranked by an unknown counter
+var ErrInvalidMetric = errors.New("invalid metric")
+
+// AnomalyPolicy configures prefix count anomaly detection
+type AnomalyPolicy struct {
+	// Window is how far back a peer's received prefix count is compared.
+	// Defaults to an hour.
+	Window time.Duration
+	// ThresholdPercent is the change within Window, up or down, that
+	// raises an alert. Defaults to 50.
+	ThresholdPercent float64
+	// MinPrefixes skips peers that received fewer prefixes at the start of
+	// Window
+	MinPrefixes int
+}
+
+// withDefaults fills in unset fields
+func (p AnomalyPolicy) withDefaults() AnomalyPolicy {
+	if p.Window <= 0 {
+		p.Window = time.Hour
+	}
+	if p.ThresholdPercent <= 0 {
+		p.ThresholdPercent = 50
+	}
+	return p
+}
+
+// PrefixAnomaly is a sudden change in the number of prefixes a peer sends
+type PrefixAnomaly struct {
+	PeerID    uint   `json:"peer_id"`
+	Name      string `json:"name"`
+	IPAddress string `json:"ip_address"`
+	// Kind is the alert type raised, route_leak_suspected or
+	// prefix_withdrawal
+	Kind string `json:"kind"`
+	// Baseline is the prefix count at Since, Current the count now
+	Baseline     int       `json:"baseline"`
+	Current      int       `json:"current"`
+	Delta        int       `json:"delta"`
+	DeltaPercent float64   `json:"delta_percent"`
+	Since        time.Time `json:"since"`
+
+	tenantID *uint // the peer's tenant
+}
+
+// AnomalyReport is the result of a prefix count analysis
+type AnomalyReport struct {
+	AnalyzedAt time.Time `json:"analyzed_at"`
+	Window     string    `json:"window"`
+	// Peers is how many established sessions were analyzed
+	Peers     int              `json:"peers"`
+	Anomalies []*PrefixAnomaly `json:"anomalies"`
+
+	tenantPeers map[uint]int // sessions analyzed per tenant
+}
+
+// forTenant returns the part of the report covering the peers of a tenant
+func (r *AnomalyReport) forTenant(id uint) *AnomalyReport {
+	report := &AnomalyReport{
+		AnalyzedAt: r.AnalyzedAt,
+		Window:     r.Window,
+		Peers:      r.tenantPeers[id],
+		Anomalies:  []*PrefixAnomaly{},
+	}
+	for _, anomaly := range r.Anomalies {
+		if anomaly.tenantID != nil && *anomaly.tenantID == id {
+			report.Anomalies = append(report.Anomalies, anomaly)
+		}
+	}
+	return report
+}
+
+// TopTalker is a peer's session counters, with the change in prefixes
+// received over the anomaly window
+type TopTalker struct {
+	PeerID           uint   `json:"peer_id"`
+	Name             string `json:"name"`
+	IPAddress        string `json:"ip_address"`
+	State            string `json:"state"`
+	PrefixesReceived int    `json:"prefixes_received"`
+	PrefixesSent     int    `json:"prefixes_sent"`
+	MessagesReceived int64  `json:"messages_received"`
+	MessagesSent     int64  `json:"messages_sent"`
+	PrefixChange     int    `json:"prefix_change"`
+}
+
+// AnalyzePrefixes compares the received prefix count of every established
+// session with its count a window ago, and raises a route_leak_suspected
+// alert for a rise of at least the threshold and a prefix_withdrawal alert
+// for a drop. Samples no analysis needs anymore are pruned.
+func (s *Service) AnalyzePrefixes(ctx context.Context) (*AnomalyReport, error) {
+	policy := s.config.Anomalies.withDefaults()
+	now := time.Now()
+	since := now.Add(-policy.Window)
+
+	var sessions []*models.BGPSession
+	if err := s.db.WithContext(ctx).Preload("Peer").Where("state = ?", "Established").Order("peer_id").Find(&sessions).Error; err != nil {
+		return nil, err
+	}
+
+	report := &AnomalyReport{
+		AnalyzedAt:  now,
+		Window:      policy.Window.String(),
+		Anomalies:   []*PrefixAnomaly{},
+		tenantPeers: make(map[uint]int),
+	}
+	for _, session := range sessions {
+		if !session.Peer.Enabled {
+			continue
+		}
+		report.Peers++
+		if id := session.Peer.TenantID; id != nil {
+			report.tenantPeers[*id]++
+		}
+
+		baseline, err := s.prefixBaseline(ctx, session.PeerID, since)
+		if err != nil {
+			return nil, err
+		}
+		if baseline == nil || baseline.PrefixesReceived < max(policy.MinPrefixes, 1) {
+			continue
+		}
+
+		delta := session.PrefixesReceived - baseline.PrefixesReceived
+		percent := float64(delta) * 100 / float64(baseline.PrefixesReceived)
+		if math.Abs(percent) < policy.ThresholdPercent {
+			continue
+		}
+
+		anomaly := &PrefixAnomaly{
+			PeerID:       session.PeerID,
+			Name:         session.Peer.Name,
+			IPAddress:    session.Peer.IPAddress,
+			Kind:         AlertPrefixWithdrawal,
+			Baseline:     baseline.PrefixesReceived,
+			Current:      session.PrefixesReceived,
+			Delta:        delta,
+			DeltaPercent: math.Round(percent*10) / 10,
+			Since:        baseline.CreatedAt,
+			tenantID:     session.Peer.TenantID,
+		}
+		if delta > 0 {
+			anomaly.Kind = AlertRouteLeakSuspected
+		}
+		report.Anomalies = append(report.Anomalies, anomaly)
+		s.createAnomalyAlert(ctx, &session.Peer, anomaly, policy.Window)
+	}
+
+	if err := s.pruneSamples(ctx, since); err != nil {
+		s.logger.Warn("Failed to prune prefix samples", zap.Error(err))
+	}
+
+	s.anomalyMu.Lock()
+	s.lastAnomalies = report
+	s.anomalyMu.Unlock()
+
+	return report, nil
+}
+
+// LastAnomalyReport returns the report of the most recent prefix analysis,
+// limited to the peers of the tenant ctx acts for, if any
+func (s *Service) LastAnomalyReport(ctx context.Context) *AnomalyReport {
+	s.anomalyMu.Lock()
+	defer s.anomalyMu.Unlock()
+
+	id, ok := tenancy.FromContext(ctx)
+	if !ok || s.lastAnomalies == nil {
+		return s.lastAnomalies
+	}
+	return s.lastAnomalies.forTenant(id)
+}
+
+// TopTalkers returns up to limit sessions ranked by metric, one of
+// TopTalkerMetrics, highest first
+func (s *Service) TopTalkers(ctx context.Context, metric string, limit int) ([]*TopTalker, error) {
+	if !slices.Contains(TopTalkerMetrics, metric) {
+		return nil, fmt.Errorf("%w %q: must be one of %s", ErrInvalidMetric, metric, strings.Join(TopTalkerMetrics, ", "))
+	}
+
+	var sessions []*models.BGPSession
+	err := s.db.WithContext(ctx).Scopes(tenancy.PeerScope(ctx, "peer_id")).Preload("Peer").
+		Order(metric + " DESC").Order("peer_id").
+		Limit(limit).
+		Find(&sessions).Error
+	if err != nil {
+		return nil, err
+	}
+
+	since := time.Now().Add(-s.config.Anomalies.withDefaults().Window)
+	talkers := make([]*TopTalker, len(sessions))
+	for i, session := range sessions {
+		talkers[i] = &TopTalker{
+			PeerID:           session.PeerID,
+			Name:             session.Peer.Name,
+			IPAddress:        session.Peer.IPAddress,
+			State:            session.State,
+			PrefixesReceived: session.PrefixesReceived,
+			PrefixesSent:     session.PrefixesSent,
+			MessagesReceived: session.MessagesReceived,
+			MessagesSent:     session.MessagesSent,
+		}
+		baseline, err := s.prefixBaseline(ctx, session.PeerID, since)
+		if err != nil {
+			return nil, err
+		}
+		if baseline != nil {
+			talkers[i].PrefixChange = session.PrefixesReceived - baseline.PrefixesReceived
+		}
+	}
+	return talkers, nil
+}
+
+// StartAnomalyDetector analyzes prefix counts every interval until ctx is
+// cancelled
+func (s *Service) StartAnomalyDetector(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	policy := s.config.Anomalies.withDefaults()
+	s.logger.Info("Started prefix anomaly detection",
+		zap.Duration("interval", interval),
+		zap.Duration("window", policy.Window),
+		zap.Float64("threshold_percent", policy.ThresholdPercent),
+	)
+
+	for {
+		select {
+		case <-ctx.Done():
+			s.logger.Info("Stopped prefix anomaly detection")
+			return
+		case <-ticker.C:
+			if _, err := s.AnalyzePrefixes(ctx); err != nil {
+				s.logger.Error("Failed to analyze prefix counts", zap.Error(err))
+			}
+		}
+	}
+}
+
+// prefixBaseline returns a peer's prefix counts as of since: its last
+// sample before then or, for a peer first sampled later, its first sample.
+// It returns nil for a peer without samples.
+func (s *Service) prefixBaseline(ctx context.Context, peerID uint, since time.Time) (*models.PrefixSample, error) {
+	var samples []models.PrefixSample
+	err := s.db.WithContext(ctx).
+		Where("peer_id = ? AND created_at <= ?", peerID, since).
+		Order("created_at DESC").Order("id DESC").
+		Limit(1).
+		Find(&samples).Error
+	if err == nil && len(samples) == 0 {
+		err = s.db.WithContext(ctx).
+			Where("peer_id = ?", peerID).
+			Order("created_at").Order("id").
+			Limit(1).
+			Find(&samples).Error
+	}
+	if err != nil || len(samples) == 0 {
+		return nil, err
+	}
+	return &samples[0], nil
+}
+
+// pruneSamples deletes samples taken before cutoff, except each peer's
+// latest one, which remains its baseline while its counts don't change
+func (s *Service) pruneSamples(ctx context.Context, cutoff time.Time) error {
+	db := s.db.WithContext(ctx)
+	latest := db.Model(&models.PrefixSample{}).
+		Select("MAX(id)").
+		Where("created_at < ?", cutoff).
+		Group("peer_id")
+	return db.Where("created_at < ? AND id NOT IN (?)", cutoff, latest).
+		Delete(&models.PrefixSample{}).Error
+}
+
+// createAnomalyAlert raises an alert for a sudden prefix count change
+func (s *Service) createAnomalyAlert(ctx context.Context, peer *models.BGPPeer, anomaly *PrefixAnomaly, window time.Duration) {
+	alert := models.Alert{
+		Type:     anomaly.Kind,
+		Severity: "warning",
+		Message: fmt.Sprintf("BGP peer %s (%s) withdrew %.0f%% of its prefixes within %s",
+			peer.Name, peer.IPAddress, -anomaly.DeltaPercent, window),
+		Details: fmt.Sprintf("%d prefixes received, down from %d (%d, %.1f%%)",
+			anomaly.Current, anomaly.Baseline, anomaly.Delta, anomaly.DeltaPercent),
+		PeerID: &peer.ID,
+		Peer:   peer,
+	}
+	if anomaly.Kind == AlertRouteLeakSuspected {
+		alert.Severity = "critical"
+		alert.Message = fmt.Sprintf("Possible route leak from BGP peer %s (%s): prefixes received rose %.0f%% within %s",
+			peer.Name, peer.IPAddress, anomaly.DeltaPercent, window)
+		alert.Details = fmt.Sprintf("%d prefixes received, up from %d (+%d, +%.1f%%)",
+			anomaly.Current, anomaly.Baseline, anomaly.Delta, anomaly.DeltaPercent)
+	}
+	if !s.raiseAlert(ctx, &alert, true) {
+		return
+	}
+
+	s.logger.Warn("Detected prefix count anomaly",
+		zap.String("peer", peer.Name),
+		zap.String("kind", anomaly.Kind),
+		zap.Int("baseline", anomaly.Baseline),
+		zap.Int("current", anomaly.Current),
+		zap.Float64("delta_percent", anomaly.DeltaPercent),
+	)
+}
@@ -0,0 +1,159 @@
+package bgp
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/padminisys/flintroute/internal/alerts"
+	"github.com/padminisys/flintroute/internal/tenancy"
+	"github.com/padminisys/flintroute/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// createSampledPeer stores an established peer now receiving current
+// prefixes, sampled with each of counts one hour apart up to an hour ago
+func createSampledPeer(t *testing.T, service *Service, ip string, current int, counts ...int) *models.BGPPeer {
+	t.Helper()
+
+	peer := newTestPeer(ip, true)
+	require.NoError(t, service.db.Create(peer).Error)
+	require.NoError(t, service.db.Create(&models.BGPSession{
+		PeerID:           peer.ID,
+		State:            "Established",
+		PrefixesReceived: current,
+		MessagesReceived: int64(current) * 10,
+	}).Error)
+
+	now := time.Now()
+	for i, count := range counts {
+		require.NoError(t, service.db.Create(&models.PrefixSample{
+			CreatedAt:        now.Add(-time.Duration(len(counts)-i) * time.Hour),
+			PeerID:           peer.ID,
+			PrefixesReceived: count,
+		}).Error)
+	}
+	require.NoError(t, service.db.Create(&models.PrefixSample{PeerID: peer.ID, PrefixesReceived: current}).Error)
+	return peer
+}
+
+func TestAnalyzePrefixes(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Raises alerts for spikes and drops", func(t *testing.T) {
+		service := setupTestService(t, ConsistencyEventual)
+		service.config.Anomalies = AnomalyPolicy{Window: 30 * time.Minute, ThresholdPercent: 50, MinPrefixes: 10}
+
+		leaking := createSampledPeer(t, service, "192.0.2.1", 300, 90, 100)
+		withdrawing := createSampledPeer(t, service, "192.0.2.2", 20, 100)
+		createSampledPeer(t, service, "192.0.2.3", 120, 100)
+		createSampledPeer(t, service, "192.0.2.4", 50, 5)
+
+		report, err := service.AnalyzePrefixes(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, 4, report.Peers)
+		assert.Equal(t, "30m0s", report.Window)
+		require.Len(t, report.Anomalies, 2)
+
+		leak := report.Anomalies[0]
+		assert.Equal(t, AlertRouteLeakSuspected, leak.Kind)
+		assert.Equal(t, leaking.ID, leak.PeerID)
+		assert.Equal(t, 100, leak.Baseline)
+		assert.Equal(t, 200, leak.Delta)
+		assert.Equal(t, 200.0, leak.DeltaPercent)
+
+		withdrawal := report.Anomalies[1]
+		assert.Equal(t, AlertPrefixWithdrawal, withdrawal.Kind)
+		assert.Equal(t, -80.0, withdrawal.DeltaPercent)
+
+		var alert models.Alert
+		require.NoError(t, service.db.Where("type = ?", AlertRouteLeakSuspected).First(&alert).Error)
+		assert.Equal(t, "critical", alert.Severity)
+		assert.Equal(t, leaking.ID, *alert.PeerID)
+		assert.Contains(t, alert.Details, "300 prefixes received, up from 100 (+200, +200.0%)")
+
+		var withdrawalAlert models.Alert
+		require.NoError(t, service.db.Where("type = ?", AlertPrefixWithdrawal).First(&withdrawalAlert).Error)
+		assert.Equal(t, "warning", withdrawalAlert.Severity)
+		assert.Equal(t, withdrawing.ID, *withdrawalAlert.PeerID)
+		assert.Contains(t, withdrawalAlert.Message, "withdrew 80% of its prefixes")
+
+		assert.Equal(t, report, service.LastAnomalyReport(ctx))
+	})
+
+	t.Run("Reports a tenant's anomalies", func(t *testing.T) {
+		service := setupTestService(t, ConsistencyEventual)
+		service.config.Anomalies = AnomalyPolicy{Window: 30 * time.Minute, ThresholdPercent: 50, MinPrefixes: 10}
+
+		owned := createSampledPeer(t, service, "192.0.2.1", 300, 100)
+		require.NoError(t, service.db.Model(owned).Update("tenant_id", 3).Error)
+		createSampledPeer(t, service, "192.0.2.2", 20, 100)
+		createSampledPeer(t, service, "192.0.2.3", 100, 100)
+
+		_, err := service.AnalyzePrefixes(ctx)
+		require.NoError(t, err)
+
+		report := service.LastAnomalyReport(tenancy.WithTenant(ctx, 3))
+		assert.Equal(t, 1, report.Peers)
+		require.Len(t, report.Anomalies, 1)
+		assert.Equal(t, owned.ID, report.Anomalies[0].PeerID)
+
+		report = service.LastAnomalyReport(tenancy.WithTenant(ctx, 4))
+		assert.Equal(t, 0, report.Peers)
+		assert.Empty(t, report.Anomalies)
+		assert.Len(t, service.LastAnomalyReport(ctx).Anomalies, 2)
+	})
+
+	t.Run("Repeats fold into the open alert", func(t *testing.T) {
+		service := setupTestService(t, ConsistencyEventual)
+		service.config.Anomalies = AnomalyPolicy{Window: 30 * time.Minute}
+		service.config.Alerts = alerts.NewDeduplicator(service.db, alerts.DedupPolicy{Window: time.Hour}, service.logger)
+		createSampledPeer(t, service, "192.0.2.1", 300, 100)
+
+		for i := 0; i < 2; i++ {
+			_, err := service.AnalyzePrefixes(ctx)
+			require.NoError(t, err)
+		}
+
+		var raised []models.Alert
+		require.NoError(t, service.db.Where("type = ?", AlertRouteLeakSuspected).Find(&raised).Error)
+		require.Len(t, raised, 1)
+		assert.Equal(t, 2, raised[0].Occurrences)
+	})
+
+	t.Run("Prunes samples but keeps each peer's baseline", func(t *testing.T) {
+		service := setupTestService(t, ConsistencyEventual)
+		service.config.Anomalies = AnomalyPolicy{Window: 30 * time.Minute}
+		peer := createSampledPeer(t, service, "192.0.2.1", 100, 100, 100, 100)
+
+		_, err := service.AnalyzePrefixes(ctx)
+		require.NoError(t, err)
+
+		var samples []models.PrefixSample
+		require.NoError(t, service.db.Where("peer_id = ?", peer.ID).Order("id").Find(&samples).Error)
+		require.Len(t, samples, 2)
+		assert.WithinDuration(t, time.Now().Add(-time.Hour), samples[0].CreatedAt, time.Minute)
+	})
+}
+
+func TestTopTalkers(t *testing.T) {
+	ctx := context.Background()
+	service := setupTestService(t, ConsistencyEventual)
+	createSampledPeer(t, service, "192.0.2.1", 100, 100)
+	busiest := createSampledPeer(t, service, "192.0.2.2", 500, 200)
+
+	talkers, err := service.TopTalkers(ctx, "prefixes_received", 1)
+	require.NoError(t, err)
+	require.Len(t, talkers, 1)
+	assert.Equal(t, busiest.ID, talkers[0].PeerID)
+	assert.Equal(t, 500, talkers[0].PrefixesReceived)
+	assert.Equal(t, 300, talkers[0].PrefixChange)
+
+	talkers, err = service.TopTalkers(ctx, "messages_received", 10)
+	require.NoError(t, err)
+	assert.Len(t, talkers, 2)
+
+	_, err = service.TopTalkers(ctx, "uptime; DROP TABLE bgp_sessions", 10)
+	assert.ErrorIs(t, err, ErrInvalidMetric)
+}
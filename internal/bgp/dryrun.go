@@ -0,0 +1,123 @@
+package bgp
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/padminisys/flintroute/internal/frr"
+	"github.com/padminisys/flintroute/internal/tenancy"
+	"github.com/padminisys/flintroute/pkg/models"
+)
+
+// PeerPlan is what creating or changing a peer would do, worked out
+// without saving or applying anything
+type PeerPlan struct {
+	// Peer is the peer as it would be stored
+	Peer *models.BGPPeer `json:"peer"`
+	// Commands are the vtysh commands that would be applied, with passwords
+	// redacted. Empty when the peer wouldn't be applied to FRR.
+	Commands []string `json:"commands"`
+	// Validated reports whether FRR checked the commands against its
+	// running configuration
+	Validated bool     `json:"validated"`
+	Warnings  []string `json:"warnings,omitempty"`
+}
+
+// PlanCreatePeer returns what CreatePeer would do with peer
+func (s *Service) PlanCreatePeer(ctx context.Context, peer *models.BGPPeer) (*PeerPlan, error) {
+	if err := s.checkLocalASN(ctx, peer); err != nil {
+		return nil, err
+	}
+	if err := ValidatePeer(peer); err != nil {
+		return nil, err
+	}
+	if err := s.checkPeerConflicts(ctx, peer); err != nil {
+		return nil, err
+	}
+	return s.planPeer(ctx, peer, peer.Enabled)
+}
+
+// PlanUpdatePeer returns what UpdatePeer would do
+func (s *Service) PlanUpdatePeer(ctx context.Context, id uint, updates *models.BGPPeer) (*PeerPlan, error) {
+	var peer models.BGPPeer
+	if err := s.db.Scopes(tenancy.Scope(ctx, "bgp_peers")).Preload("Tags").First(&peer, id).Error; err != nil {
+		return nil, ErrPeerNotFound
+	}
+
+	replacePeer(&peer, updates)
+	if err := s.checkLocalASN(ctx, &peer); err != nil {
+		return nil, err
+	}
+	if err := ValidatePeer(&peer); err != nil {
+		return nil, err
+	}
+	return s.planPeer(ctx, &peer, true)
+}
+
+// PlanPatchPeer returns what PatchPeer would do
+func (s *Service) PlanPatchPeer(ctx context.Context, id uint, patch *PeerPatch) (*PeerPlan, error) {
+	var peer models.BGPPeer
+	if err := s.db.Scopes(tenancy.Scope(ctx, "bgp_peers")).Preload("Tags").First(&peer, id).Error; err != nil {
+		return nil, ErrPeerNotFound
+	}
+
+	patch.applyTo(&peer)
+	if err := s.checkLocalASN(ctx, &peer); err != nil {
+		return nil, err
+	}
+	if err := ValidatePeer(&peer); err != nil {
+		return nil, err
+	}
+	return s.planPeer(ctx, &peer, true)
+}
+
+// planPeer renders the FRR changes for a validated peer and has FRR check
+// them. apply is false for peers that wouldn't be applied to FRR. An
+// unreachable FRR is a warning, since the change would still be saved in
+// eventual consistency mode.
+func (s *Service) planPeer(ctx context.Context, peer *models.BGPPeer, apply bool) (*PeerPlan, error) {
+	peer.HasPassword = peer.Password != ""
+	plan := &PeerPlan{Peer: peer, Commands: []string{}}
+
+	if !apply {
+		return plan, nil
+	}
+
+	cfg, err := s.peerConfig(ctx, peer)
+	if err != nil {
+		return nil, err
+	}
+
+	commands, err := s.frrClient.CheckBGPPeer(ctx, cfg)
+	switch {
+	case errors.Is(err, frr.ErrNotConnected):
+		plan.Warnings = append(plan.Warnings,
+			"FRR is unreachable; the commands were not checked and don't remove stale statements")
+		commands = cfg.NeighborCommands()
+	case err != nil:
+		return nil, err
+	default:
+		plan.Validated = true
+	}
+
+	plan.Commands = redactPasswords(commands)
+	return plan, nil
+}
+
+// redactPasswords replaces the passwords in neighbor password statements
+func redactPasswords(commands []string) []string {
+	redacted := make([]string, len(commands))
+	for i, command := range commands {
+		negate, statement := "", command
+		if rest, ok := strings.CutPrefix(command, "no "); ok {
+			negate, statement = "no ", rest
+		}
+		fields := strings.Fields(statement)
+		if len(fields) > 3 && fields[0] == "neighbor" && fields[2] == "password" {
+			command = negate + "neighbor " + fields[1] + " password " + redactedPassword
+		}
+		redacted[i] = command
+	}
+	return redacted
+}
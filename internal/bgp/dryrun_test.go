@@ -0,0 +1,119 @@
+package bgp
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/padminisys/flintroute/internal/frr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPlanPeer(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Create plan is checked by FRR and saves nothing", func(t *testing.T) {
+		service := setupTestService(t, ConsistencyStrict)
+		client := frr.NewMockClient()
+		client.On("CheckBGPPeer", mock.Anything, peerIP("10.0.0.1")).Return([]string{
+			"configure terminal",
+			"router bgp 65001",
+			"neighbor 10.0.0.1 remote-as 65002",
+			"neighbor 10.0.0.1 password s3cret",
+			"end",
+		}, nil)
+		service.frrClient = client
+
+		peer := newTestPeer("10.0.0.1", true)
+		peer.Password = "s3cret"
+		plan, err := service.PlanCreatePeer(ctx, peer)
+		require.NoError(t, err)
+		assert.True(t, plan.Validated)
+		assert.Contains(t, plan.Commands, "neighbor 10.0.0.1 password <redacted>")
+		assert.NotContains(t, fmt.Sprint(plan.Commands), "s3cret")
+		assert.True(t, plan.Peer.HasPassword)
+		assert.Empty(t, plan.Warnings)
+
+		peers, err := service.ListPeers(ctx)
+		require.NoError(t, err)
+		assert.Empty(t, peers)
+	})
+
+	t.Run("Disabled peers are not checked", func(t *testing.T) {
+		service := setupTestService(t, ConsistencyStrict)
+
+		plan, err := service.PlanCreatePeer(ctx, newTestPeer("10.0.0.1", false))
+		require.NoError(t, err)
+		assert.Empty(t, plan.Commands)
+		assert.False(t, plan.Validated)
+	})
+
+	t.Run("Unreachable FRR is a warning", func(t *testing.T) {
+		service := setupTestService(t, ConsistencyEventual)
+
+		plan, err := service.PlanCreatePeer(ctx, newTestPeer("10.0.0.1", true))
+		require.NoError(t, err)
+		assert.False(t, plan.Validated)
+		assert.Equal(t, []string{
+			"neighbor 10.0.0.1 remote-as 65002",
+			"neighbor 10.0.0.1 description Peer 10.0.0.1",
+		}, plan.Commands)
+		require.Len(t, plan.Warnings, 1)
+		assert.Contains(t, plan.Warnings[0], "unreachable")
+	})
+
+	t.Run("Rejects duplicate IPs and ASN mismatches", func(t *testing.T) {
+		service := setupTestService(t, ConsistencyEventual)
+		require.NoError(t, service.CreatePeer(ctx, newTestPeer("10.0.0.1", false)))
+
+		_, err := service.PlanCreatePeer(ctx, newTestPeer("10.0.0.1", false))
+		assert.ErrorIs(t, err, ErrPeerExists)
+		assert.ErrorIs(t, service.CreatePeer(ctx, newTestPeer("10.0.0.1", false)), ErrPeerExists)
+
+		peer := newTestPeer("10.0.0.2", false)
+		peer.ASN = 65009
+		_, err = service.PlanCreatePeer(ctx, peer)
+		assert.ErrorIs(t, err, ErrASNMismatch)
+		assert.Contains(t, err.Error(), "set local_as 65009")
+	})
+
+	t.Run("Update plans validate the merged peer", func(t *testing.T) {
+		service := setupTestService(t, ConsistencyEventual)
+		peer := newTestPeer("10.0.0.1", false)
+		require.NoError(t, service.CreatePeer(ctx, peer))
+
+		keepalive := 30
+		_, err := service.PlanPatchPeer(ctx, peer.ID, &PeerPatch{Keepalive: &keepalive})
+		assert.ErrorIs(t, err, ErrInvalidPeer)
+
+		client := frr.NewMockClient()
+		client.On("CheckBGPPeer", mock.Anything, mock.Anything).Return(nil, frr.ErrConfigRejected)
+		service.frrClient = client
+
+		updates := *peer
+		updates.RouteMapIn = "RM-MISSING"
+		_, err = service.PlanUpdatePeer(ctx, peer.ID, &updates)
+		assert.ErrorIs(t, err, frr.ErrConfigRejected)
+
+		stored, err := service.GetPeer(ctx, peer.ID)
+		require.NoError(t, err)
+		assert.Empty(t, stored.RouteMapIn)
+
+		_, err = service.PlanPatchPeer(ctx, peer.ID+1, &PeerPatch{})
+		assert.ErrorIs(t, err, ErrPeerNotFound)
+	})
+}
+
+func TestRedactPasswords(t *testing.T) {
+	assert.Equal(t, []string{
+		"no neighbor 192.0.2.1 password <redacted>",
+		"neighbor 192.0.2.1 password <redacted>",
+		"neighbor 192.0.2.1 description password rotation",
+	}, redactPasswords([]string{
+		"no neighbor 192.0.2.1 password old secret",
+		"neighbor 192.0.2.1 password new",
+		"neighbor 192.0.2.1 description password rotation",
+	}))
+}
@@ -0,0 +1,202 @@
+package bgp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/padminisys/flintroute/internal/frr"
+	"github.com/padminisys/flintroute/internal/requestid"
+	"github.com/padminisys/flintroute/pkg/models"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrGlobalConfigNotFound is returned when no BGP global configuration
+	// has been set
+	ErrGlobalConfigNotFound = errors.New("BGP global configuration not set")
+	// ErrInvalidGlobalConfig is returned when a BGP global configuration is
+	// rejected before being applied
+	ErrInvalidGlobalConfig = errors.New("invalid BGP global configuration")
+	// ErrASNMismatch is returned when a local ASN differs from the router's:
+	// a global ASN other than existing peers', or a peer's ASN other than
+	// the router's
+	ErrASNMismatch = errors.New("local ASN differs from the router's")
+)
+
+// maxGracefulRestartTime is the longest restart and stale path time FRR
+// accepts, in seconds
+const maxGracefulRestartTime = 4095
+
+// ValidateGlobalConfig checks a BGP global configuration's ASN, router ID
+// and graceful restart timers
+func ValidateGlobalConfig(cfg *models.BGPGlobalConfig) error {
+	var problems []string
+	addf := func(format string, args ...interface{}) {
+		problems = append(problems, fmt.Sprintf(format, args...))
+	}
+
+	if cfg.ASN == 0 {
+		addf("asn is required")
+	}
+	if cfg.RouterID != "" {
+		if ip := net.ParseIP(cfg.RouterID); ip == nil || ip.To4() == nil {
+			addf("router_id must be an IPv4 address")
+		}
+	}
+	for _, timer := range []struct {
+		name  string
+		value int
+	}{
+		{"graceful_restart_time", cfg.RestartTime},
+		{"graceful_restart_stalepath_time", cfg.StalePathTime},
+	} {
+		switch {
+		case timer.value < 0 || timer.value > maxGracefulRestartTime:
+			addf("%s must be between 1 and %d", timer.name, maxGracefulRestartTime)
+		case timer.value > 0 && !cfg.GracefulRestart:
+			addf("%s requires graceful_restart", timer.name)
+		}
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("%w: %s", ErrInvalidGlobalConfig, strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// newGlobalConfig converts the stored global configuration into its FRR
+// configuration
+func newGlobalConfig(cfg *models.BGPGlobalConfig) *frr.BGPGlobalConfig {
+	return &frr.BGPGlobalConfig{
+		ASN:                cfg.ASN,
+		RouterID:           cfg.RouterID,
+		GracefulRestart:    cfg.GracefulRestart,
+		RestartTime:        cfg.RestartTime,
+		StalePathTime:      cfg.StalePathTime,
+		EBGPRequiresPolicy: cfg.EBGPRequiresPolicy,
+		LogNeighborChanges: cfg.LogNeighborChanges,
+		AlwaysCompareMED:   cfg.AlwaysCompareMED,
+		CompareRouterID:    cfg.CompareRouterID,
+		MultipathRelax:     cfg.MultipathRelax,
+		MEDMissingAsWorst:  cfg.MEDMissingAsWorst,
+	}
+}
+
+// GetGlobalConfig retrieves the BGP global configuration
+func (s *Service) GetGlobalConfig(ctx context.Context) (*models.BGPGlobalConfig, error) {
+	var cfg models.BGPGlobalConfig
+	if err := s.db.WithContext(ctx).First(&cfg, models.BGPGlobalConfigID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrGlobalConfigNotFound
+		}
+		return nil, err
+	}
+	return &cfg, nil
+}
+
+// SaveGlobalConfig validates the BGP global configuration, applies it to
+// FRR and stores it. FRR runs a single BGP instance, so the ASN must match
+// the configured local ASN, if any, and the local ASN of every peer. The configuration is only stored once FRR
+// has accepted it.
+func (s *Service) SaveGlobalConfig(ctx context.Context, cfg *models.BGPGlobalConfig) error {
+	if err := ValidateGlobalConfig(cfg); err != nil {
+		return err
+	}
+	if local := s.config.LocalASN; local != 0 && cfg.ASN != local {
+		return fmt.Errorf("%w: asn %d differs from the configured local ASN %d", ErrASNMismatch, cfg.ASN, local)
+	}
+	if err := s.checkPeerASNs(ctx, cfg.ASN); err != nil {
+		return err
+	}
+	if err := s.frrClient.ApplyBGPGlobal(ctx, newGlobalConfig(cfg)); err != nil {
+		return fmt.Errorf("%w: %w", ErrFRRApplyFailed, err)
+	}
+
+	cfg.ID = models.BGPGlobalConfigID
+	existing, err := s.GetGlobalConfig(ctx)
+	switch {
+	case err == nil:
+		cfg.CreatedAt = existing.CreatedAt
+		err = s.db.WithContext(ctx).Save(cfg).Error
+	case errors.Is(err, ErrGlobalConfigNotFound):
+		err = s.db.WithContext(ctx).Create(cfg).Error
+	}
+	if err != nil {
+		return fmt.Errorf("failed to save BGP global configuration: %w", err)
+	}
+
+	s.logger.Info("Saved BGP global configuration",
+		zap.Uint32("asn", cfg.ASN),
+		zap.String("router_id", cfg.RouterID),
+		requestid.Field(ctx),
+	)
+	return nil
+}
+
+// checkPeerASNs returns ErrASNMismatch when a peer has a local ASN other
+// than asn
+func (s *Service) checkPeerASNs(ctx context.Context, asn uint32) error {
+	var peers []models.BGPPeer
+	if err := s.db.WithContext(ctx).Where("asn <> ?", asn).Order("ip_address").Find(&peers).Error; err != nil {
+		return fmt.Errorf("failed to list peers: %w", err)
+	}
+	if len(peers) == 0 {
+		return nil
+	}
+
+	mismatched := make([]string, len(peers))
+	for i, peer := range peers {
+		mismatched[i] = fmt.Sprintf("%s (AS %d)", peer.IPAddress, peer.ASN)
+	}
+	return fmt.Errorf("%w: %s", ErrASNMismatch, strings.Join(mismatched, ", "))
+}
+
+// RouterASN returns the AS number of the router's BGP instance: the
+// configured local ASN, else the global configuration's, else that of the
+// existing peers. It is 0 while none of them is set.
+func (s *Service) RouterASN(ctx context.Context) (uint32, error) {
+	if s.config.LocalASN != 0 {
+		return s.config.LocalASN, nil
+	}
+	cfg, err := s.GetGlobalConfig(ctx)
+	switch {
+	case err == nil:
+		return cfg.ASN, nil
+	case !errors.Is(err, ErrGlobalConfigNotFound):
+		return 0, err
+	}
+
+	var peer models.BGPPeer
+	err = s.db.WithContext(ctx).Select("asn").Order("id").First(&peer).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to look up the router's ASN: %w", err)
+	}
+	return peer.ASN, nil
+}
+
+// checkLocalASN defaults a new peer's ASN to the router's and returns
+// ErrASNMismatch when it is another. FRR would otherwise be asked for a
+// second BGP instance; a peer that should see another AS uses local_as.
+func (s *Service) checkLocalASN(ctx context.Context, peer *models.BGPPeer) error {
+	router, err := s.RouterASN(ctx)
+	if err != nil {
+		return err
+	}
+	switch {
+	case peer.ASN == 0 && router == 0:
+		return fmt.Errorf("%w: asn is required until the router's ASN is known", ErrInvalidPeer)
+	case peer.ASN == 0:
+		peer.ASN = router
+	case router != 0 && peer.ASN != router:
+		return fmt.Errorf("%w: asn %d differs from the router's AS %d; keep asn %d and set local_as %d to present AS %d to this peer",
+			ErrASNMismatch, peer.ASN, router, router, peer.ASN, peer.ASN)
+	}
+	return nil
+}
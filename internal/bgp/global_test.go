@@ -0,0 +1,154 @@
+package bgp
+
+import (
+	"context"
+	"testing"
+
+	"github.com/padminisys/flintroute/internal/frr"
+	"github.com/padminisys/flintroute/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateGlobalConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     models.BGPGlobalConfig
+		problem string
+	}{
+		{"Valid", models.BGPGlobalConfig{ASN: 65001, RouterID: "10.0.0.1", GracefulRestart: true, RestartTime: 120, StalePathTime: 360}, ""},
+		{"Missing ASN", models.BGPGlobalConfig{}, "asn is required"},
+		{"IPv6 router ID", models.BGPGlobalConfig{ASN: 65001, RouterID: "2001:db8::1"}, "router_id must be an IPv4 address"},
+		{"Restart time out of range", models.BGPGlobalConfig{ASN: 65001, GracefulRestart: true, RestartTime: 5000}, "graceful_restart_time must be between 1 and 4095"},
+		{"Timer without graceful restart", models.BGPGlobalConfig{ASN: 65001, StalePathTime: 360}, "graceful_restart_stalepath_time requires graceful_restart"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateGlobalConfig(&tt.cfg)
+			if tt.problem == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorIs(t, err, ErrInvalidGlobalConfig)
+			assert.Contains(t, err.Error(), tt.problem)
+		})
+	}
+}
+
+func TestSaveGlobalConfig(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Applies and stores the configuration", func(t *testing.T) {
+		service := setupTestService(t, ConsistencyEventual)
+		require.NoError(t, service.db.Create(newTestPeer("192.0.2.1", true)).Error)
+
+		client := frr.NewMockClient()
+		client.On("ApplyBGPGlobal", mock.Anything, &frr.BGPGlobalConfig{
+			ASN:                65001,
+			RouterID:           "10.0.0.1",
+			GracefulRestart:    true,
+			EBGPRequiresPolicy: true,
+		}).Return(nil).Once()
+		client.On("ApplyBGPGlobal", mock.Anything, mock.Anything).Return(nil).Once()
+		service.frrClient = client
+
+		_, err := service.GetGlobalConfig(ctx)
+		assert.ErrorIs(t, err, ErrGlobalConfigNotFound)
+
+		require.NoError(t, service.SaveGlobalConfig(ctx, &models.BGPGlobalConfig{
+			ASN:                65001,
+			RouterID:           "10.0.0.1",
+			GracefulRestart:    true,
+			EBGPRequiresPolicy: true,
+		}))
+		require.NoError(t, service.SaveGlobalConfig(ctx, &models.BGPGlobalConfig{ASN: 65001, RouterID: "10.0.0.2"}))
+		client.AssertExpectations(t)
+
+		stored, err := service.GetGlobalConfig(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, "10.0.0.2", stored.RouterID)
+		assert.False(t, stored.GracefulRestart)
+
+		var count int64
+		require.NoError(t, service.db.Model(&models.BGPGlobalConfig{}).Count(&count).Error)
+		assert.Equal(t, int64(1), count)
+	})
+
+	t.Run("Rejects an ASN peers don't use", func(t *testing.T) {
+		service := setupTestService(t, ConsistencyEventual)
+		require.NoError(t, service.db.Create(newTestPeer("192.0.2.1", true)).Error)
+
+		err := service.SaveGlobalConfig(ctx, &models.BGPGlobalConfig{ASN: 65100})
+		assert.ErrorIs(t, err, ErrASNMismatch)
+		assert.Contains(t, err.Error(), "192.0.2.1 (AS 65001)")
+	})
+
+	t.Run("Rejects an ASN other than the configured one", func(t *testing.T) {
+		service := setupTestService(t, ConsistencyEventual)
+		service.config.LocalASN = 65001
+
+		err := service.SaveGlobalConfig(ctx, &models.BGPGlobalConfig{ASN: 65100})
+		assert.ErrorIs(t, err, ErrASNMismatch)
+		assert.Contains(t, err.Error(), "configured local ASN 65001")
+	})
+
+	t.Run("Not stored when FRR apply fails", func(t *testing.T) {
+		service := setupTestService(t, ConsistencyEventual)
+
+		err := service.SaveGlobalConfig(ctx, &models.BGPGlobalConfig{ASN: 65001})
+		assert.ErrorIs(t, err, ErrFRRApplyFailed)
+		assert.ErrorIs(t, err, frr.ErrNotConnected)
+
+		_, err = service.GetGlobalConfig(ctx)
+		assert.ErrorIs(t, err, ErrGlobalConfigNotFound)
+	})
+}
+
+func TestRouterASN(t *testing.T) {
+	ctx := context.Background()
+	service := setupTestService(t, ConsistencyEventual)
+
+	t.Run("Required until known", func(t *testing.T) {
+		asn, err := service.RouterASN(ctx)
+		require.NoError(t, err)
+		assert.Zero(t, asn)
+
+		peer := newTestPeer("192.0.2.1", false)
+		peer.ASN = 0
+		assert.ErrorIs(t, service.CreatePeer(ctx, peer), ErrInvalidPeer)
+	})
+
+	t.Run("Taken from existing peers", func(t *testing.T) {
+		require.NoError(t, service.CreatePeer(ctx, newTestPeer("192.0.2.1", false)))
+
+		peer := newTestPeer("192.0.2.2", false)
+		peer.ASN = 0
+		require.NoError(t, service.CreatePeer(ctx, peer))
+		assert.Equal(t, uint32(65001), peer.ASN)
+	})
+
+	t.Run("Rejects mismatches without local-as", func(t *testing.T) {
+		peer := newTestPeer("192.0.2.3", false)
+		peer.ASN = 65009
+		err := service.CreatePeer(ctx, peer)
+		assert.ErrorIs(t, err, ErrASNMismatch)
+		assert.Contains(t, err.Error(), "keep asn 65001 and set local_as 65009")
+
+		peer = newTestPeer("192.0.2.3", false)
+		peer.LocalAS = 65009
+		peer.LocalASMode = models.LocalASNoPrepend
+		require.NoError(t, service.CreatePeer(ctx, peer))
+	})
+
+	t.Run("Configured ASN wins", func(t *testing.T) {
+		service.config.LocalASN = 65100
+		asn, err := service.RouterASN(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, uint32(65100), asn)
+
+		err = service.CreatePeer(ctx, newTestPeer("192.0.2.4", false))
+		assert.ErrorIs(t, err, ErrASNMismatch)
+	})
+}
@@ -0,0 +1,311 @@
+package bgp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/padminisys/flintroute/internal/frr"
+	"github.com/padminisys/flintroute/internal/frrconf"
+	"github.com/padminisys/flintroute/internal/repository"
+	"github.com/padminisys/flintroute/internal/requestid"
+	"github.com/padminisys/flintroute/pkg/models"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// ErrNoBGPInstance is returned when FRR's running configuration has no
+// default BGP instance to import neighbors from
+var ErrNoBGPInstance = errors.New("FRR has no default BGP instance")
+
+// ImportKindPeer is the kind of imported peers
+const ImportKindPeer = "peer"
+
+// PeerGroupTag is the tag that records which FRR peer-group an imported
+// peer belonged to
+const PeerGroupTag = "peer-group"
+
+// ImportItem is an object of the running configuration, imported or
+// skipped
+type ImportItem struct {
+	Kind   string `json:"kind"` // peer, route-map, prefix-list, community-list, as-path-list
+	Name   string `json:"name"`
+	Reason string `json:"reason,omitempty"`
+}
+
+// ImportReport is the result of importing FRR's running configuration
+type ImportReport struct {
+	DryRun     bool      `json:"dry_run"`
+	ImportedAt time.Time `json:"imported_at"`
+	ASN        uint32    `json:"asn"`
+	// PeerGroups were flattened into their members, which are tagged with
+	// PeerGroupTag
+	PeerGroups []string      `json:"peer_groups"`
+	Imported   []*ImportItem `json:"imported"`
+	Skipped    []*ImportItem `json:"skipped"`
+	// Unsupported are the statements that could not be modeled, in the
+	// order FRR lists them
+	Unsupported []frrconf.Unsupported `json:"unsupported"`
+}
+
+// reasonGlobalStatement is why the default instance's router-level
+// settings are not imported
+const reasonGlobalStatement = "global BGP setting; manage it through the BGP global configuration"
+
+// ImportRunningConfig reads FRR's running configuration into FlintRoute:
+// the default BGP instance's neighbors become peers, and route-maps,
+// prefix-lists, community-lists and as-path access-lists become policies.
+// FRR already runs all of it, so nothing is applied to FRR. Objects
+// FlintRoute already has are skipped. With dryRun nothing is stored.
+func (s *Service) ImportRunningConfig(ctx context.Context, dryRun bool) (*ImportReport, error) {
+	running, err := s.frrClient.GetRunningConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+	parsed := frrconf.Parse(running)
+	if parsed.BGP == nil && len(parsed.PrefixLists)+len(parsed.CommunityLists)+len(parsed.ASPathLists)+len(parsed.RouteMaps) == 0 {
+		return nil, ErrNoBGPInstance
+	}
+
+	report := &ImportReport{
+		DryRun:      dryRun,
+		ImportedAt:  time.Now(),
+		PeerGroups:  []string{},
+		Imported:    []*ImportItem{},
+		Skipped:     []*ImportItem{},
+		Unsupported: append([]frrconf.Unsupported{}, parsed.Unsupported...),
+	}
+	if parsed.BGP != nil {
+		report.ASN = parsed.BGP.ASN
+		for _, n := range parsed.BGP.Neighbors {
+			if n.IsPeerGroup {
+				report.PeerGroups = append(report.PeerGroups, n.Name)
+			}
+		}
+		for _, st := range parsed.BGP.Statements {
+			reason := frrconf.ReasonNotModeled
+			if frr.IsGlobalStatement(st.Text) {
+				reason = reasonGlobalStatement
+			}
+			report.Unsupported = append(report.Unsupported, frrconf.Unsupported{Statement: st, Reason: reason})
+		}
+		sort.SliceStable(report.Unsupported, func(i, j int) bool {
+			return report.Unsupported[i].Line < report.Unsupported[j].Line
+		})
+	}
+
+	router, err := s.RouterASN(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var peers []*models.BGPPeer
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		add := func(item *ImportItem, err error) {
+			if err != nil {
+				item.Reason = err.Error()
+				report.Skipped = append(report.Skipped, item)
+				return
+			}
+			report.Imported = append(report.Imported, item)
+		}
+
+		// Lists before the route-maps that reference them
+		for _, pl := range parsed.PrefixLists {
+			policy := &models.RoutingPolicy{Kind: models.PolicyPrefixList, Name: pl.Name, Config: pl.String()}
+			add(&ImportItem{Kind: policy.Kind, Name: pl.Name}, s.importPolicy(tx, policy.Kind, pl.Name, policy, dryRun))
+		}
+		for _, cl := range parsed.CommunityLists {
+			add(&ImportItem{Kind: models.PolicyCommunityList, Name: cl.Name}, s.importPolicy(tx, models.PolicyCommunityList, cl.Name, importedCommunityList(cl), dryRun))
+		}
+		for _, al := range parsed.ASPathLists {
+			add(&ImportItem{Kind: models.PolicyASPathList, Name: al.Name}, s.importPolicy(tx, models.PolicyASPathList, al.Name, importedASPathList(al), dryRun))
+		}
+		for _, rm := range parsed.RouteMaps {
+			policy := &models.RoutingPolicy{Kind: models.PolicyRouteMap, Name: rm.Name, Config: rm.String()}
+			add(&ImportItem{Kind: policy.Kind, Name: rm.Name}, s.importPolicy(tx, policy.Kind, rm.Name, policy, dryRun))
+		}
+
+		if parsed.BGP == nil {
+			return nil
+		}
+		for _, neighbor := range parsed.BGP.Peers() {
+			if router != 0 && parsed.BGP.ASN != router {
+				add(&ImportItem{Kind: ImportKindPeer, Name: neighbor.Name},
+					fmt.Errorf("%w: the instance's AS %d differs from the router's AS %d", ErrASNMismatch, parsed.BGP.ASN, router))
+				continue
+			}
+			peer, err := s.importPeer(tx, parsed.BGP.ASN, neighbor, nil, dryRun)
+			add(&ImportItem{Kind: ImportKindPeer, Name: neighbor.Name}, err)
+			if err == nil {
+				peers = append(peers, peer)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to import running config: %w", err)
+	}
+
+	if !dryRun && len(peers) > 0 {
+		s.peersChanged()
+		for _, peer := range peers {
+			s.wsHub.BroadcastPeerUpdate(ctx, peer)
+		}
+	}
+
+	s.logger.Info("Imported FRR running config",
+		zap.Bool("dry_run", dryRun),
+		zap.Int("imported", len(report.Imported)),
+		zap.Int("skipped", len(report.Skipped)),
+		zap.Int("unsupported", len(report.Unsupported)),
+		requestid.Field(ctx),
+	)
+
+	return report, nil
+}
+
+// importPeer stores a neighbor of the instance asn as a peer of tenantID,
+// returning why it can't be imported if it can't: ErrPeerExists when
+// FlintRoute already has it
+func (s *Service) importPeer(tx *gorm.DB, asn uint32, neighbor *frrconf.Neighbor, tenantID *uint, dryRun bool) (*models.BGPPeer, error) {
+	if neighbor.RemoteASN == 0 {
+		return nil, errors.New("neighbor has no remote-as with a fixed ASN")
+	}
+
+	var count int64
+	if err := tx.Unscoped().Model(&models.BGPPeer{}).Where("ip_address = ?", neighbor.Name).Count(&count).Error; err != nil {
+		return nil, err
+	}
+	if count > 0 {
+		return nil, ErrPeerExists
+	}
+
+	peer := importedPeer(asn, neighbor)
+	peer.TenantID = tenantID
+	if err := ValidatePeer(peer); err != nil {
+		return nil, err
+	}
+	if dryRun {
+		return peer, nil
+	}
+
+	encrypted, err := s.config.PasswordCipher.Encrypt(peer.Password)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt peer password: %w", err)
+	}
+	peer.Password = encrypted
+	if err := repository.WritePeer(tx, peer); err != nil {
+		return nil, err
+	}
+	return peer, nil
+}
+
+// importedPeer returns the peer a neighbor of the instance asn is stored
+// as. It is named after its description, or its IP address without one.
+func importedPeer(asn uint32, n *frrconf.Neighbor) *models.BGPPeer {
+	name := n.Description
+	if name == "" {
+		name = n.Name
+	}
+	action := ""
+	if n.MaxPrefixWarningOnly {
+		action = models.MaxPrefixWarningOnly
+	}
+	localASMode := ""
+	switch {
+	case n.LocalASReplaceAS:
+		localASMode = models.LocalASReplaceAS
+	case n.LocalASNoPrepend:
+		localASMode = models.LocalASNoPrepend
+	}
+	var tags models.PeerTags
+	if n.PeerGroup != "" {
+		tags = models.NewPeerTags(map[string]string{PeerGroupTag: n.PeerGroup})
+	}
+
+	return &models.BGPPeer{
+		Name:                name,
+		IPAddress:           n.Name,
+		ASN:                 asn,
+		RemoteASN:           n.RemoteASN,
+		Description:         n.Description,
+		Enabled:             !n.Shutdown,
+		Password:            n.Password,
+		Multihop:            n.Multihop,
+		UpdateSource:        n.UpdateSource,
+		RouteMapIn:          n.RouteMapIn,
+		RouteMapOut:         n.RouteMapOut,
+		PrefixListIn:        n.PrefixListIn,
+		PrefixListOut:       n.PrefixListOut,
+		MaxPrefixes:         n.MaxPrefixes,
+		MaxPrefixThreshold:  n.MaxPrefixThreshold,
+		MaxPrefixAction:     action,
+		MaxPrefixRestart:    n.MaxPrefixRestart,
+		Keepalive:           n.Keepalive,
+		HoldTime:            n.HoldTime,
+		ConnectRetry:        n.ConnectRetry,
+		Passive:             n.Passive,
+		TTLSecurityHops:     n.TTLSecurityHops,
+		NextHopSelf:         n.NextHopSelf,
+		SoftReconfigInbound: n.SoftReconfigInbound,
+		RemovePrivateAS:     n.RemovePrivateAS,
+		AllowASIn:           n.AllowASIn,
+		LocalAS:             n.LocalAS,
+		LocalASMode:         localASMode,
+		SyncStatus:          models.PeerSyncSynced,
+		Tags:                tags,
+	}
+}
+
+// importPolicy stores the record of a policy object, returning why it
+// can't be imported if it can't. Community-lists and as-path access-lists
+// are stored as lists; route-maps and prefix-lists as routing policies.
+func (s *Service) importPolicy(tx *gorm.DB, kind, name string, record interface{}, dryRun bool) error {
+	var count int64
+	var err error
+	switch record := record.(type) {
+	case *models.CommunityList:
+		if err := ValidateCommunityList(record); err != nil {
+			return err
+		}
+		err = tx.Model(&models.CommunityList{}).Where("name = ?", name).Count(&count).Error
+	case *models.ASPathList:
+		if err := ValidateASPathList(record); err != nil {
+			return err
+		}
+		err = tx.Model(&models.ASPathList{}).Where("name = ?", name).Count(&count).Error
+	default:
+		err = tx.Model(&models.RoutingPolicy{}).Where("kind = ? AND name = ?", kind, name).Count(&count).Error
+	}
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return fmt.Errorf("a %s with this name already exists", kind)
+	}
+	if dryRun {
+		return nil
+	}
+	return tx.Create(record).Error
+}
+
+// importedCommunityList returns the list a community-list is stored as
+func importedCommunityList(cl *frrconf.CommunityList) *models.CommunityList {
+	list := &models.CommunityList{Name: cl.Name, Type: cl.Type}
+	for _, entry := range cl.Entries {
+		list.Entries = append(list.Entries, models.CommunityListEntry{Seq: entry.Seq, Action: entry.Action, Value: entry.Value})
+	}
+	return list
+}
+
+// importedASPathList returns the list an as-path access-list is stored as
+func importedASPathList(al *frrconf.ASPathList) *models.ASPathList {
+	list := &models.ASPathList{Name: al.Name}
+	for _, entry := range al.Entries {
+		list.Entries = append(list.Entries, models.ASPathListEntry{Seq: entry.Seq, Action: entry.Action, Regex: entry.Value})
+	}
+	return list
+}
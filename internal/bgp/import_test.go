@@ -0,0 +1,149 @@
+package bgp
+
+import (
+	"context"
+	"testing"
+
+	"github.com/padminisys/flintroute/internal/frr"
+	"github.com/padminisys/flintroute/internal/repository"
+	"github.com/padminisys/flintroute/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+const importRunningConfig = `router bgp 65001
+ neighbor UPSTREAM peer-group
+ neighbor UPSTREAM remote-as 65100
+ neighbor 10.0.0.1 peer-group UPSTREAM
+ neighbor 10.0.0.2 remote-as 65002
+ neighbor 10.0.0.2 description Customer B
+ neighbor 10.0.0.2 password s3cret
+ neighbor 10.0.0.2 local-as 64999 no-prepend
+ neighbor 10.0.0.3 remote-as 65003
+ neighbor 10.0.0.3 timers 30 10
+ neighbor 10.0.0.4 remote-as 65004
+ address-family ipv4 unicast
+  neighbor 10.0.0.2 route-map CUSTOMER-IN in
+ exit-address-family
+exit
+!
+ip prefix-list CUSTOMER seq 5 permit 203.0.113.0/24
+bgp as-path access-list PRIVATE seq 5 permit _6451[2-9]_
+bgp community-list standard NO-EXPORT seq 5 permit no-export
+!
+route-map CUSTOMER-IN permit 10
+ match ip address prefix-list CUSTOMER
+exit
+`
+
+func TestImportRunningConfig(t *testing.T) {
+	ctx := context.Background()
+
+	setup := func(t *testing.T) *Service {
+		service := setupTestService(t, ConsistencyEventual)
+		client := frr.NewMockClient()
+		client.On("GetRunningConfig", mock.Anything).Return(importRunningConfig, nil)
+		service.frrClient = client
+
+		// Already managed by FlintRoute
+		existing := newTestPeer("10.0.0.4", true)
+		require.NoError(t, repository.WritePeer(service.db.DB, existing))
+		return service
+	}
+
+	t.Run("Imports peers and policies", func(t *testing.T) {
+		service := setup(t)
+
+		report, err := service.ImportRunningConfig(ctx, false)
+		require.NoError(t, err)
+		assert.False(t, report.DryRun)
+		assert.Equal(t, []string{"UPSTREAM"}, report.PeerGroups)
+		assert.Len(t, report.Imported, 6)
+
+		skipped := make(map[string]string)
+		for _, item := range report.Skipped {
+			skipped[item.Name] = item.Reason
+		}
+		assert.Len(t, skipped, 2)
+		assert.Contains(t, skipped["10.0.0.3"], "keepalive must be less than holdtime")
+		assert.Contains(t, skipped["10.0.0.4"], "already exists")
+
+		peers, err := service.ListPeers(ctx)
+		require.NoError(t, err)
+		require.Len(t, peers, 3)
+
+		member := peers[1]
+		assert.Equal(t, "10.0.0.1", member.IPAddress)
+		assert.Equal(t, uint32(65001), member.ASN)
+		assert.Equal(t, uint32(65100), member.RemoteASN)
+		assert.Equal(t, map[string]string{PeerGroupTag: "UPSTREAM"}, member.Tags.Map())
+
+		customer := peers[2]
+		assert.Equal(t, "Customer B", customer.Name)
+		assert.Equal(t, "CUSTOMER-IN", customer.RouteMapIn)
+		assert.Equal(t, uint32(64999), customer.LocalAS)
+		assert.Equal(t, models.LocalASNoPrepend, customer.LocalASMode)
+		assert.Equal(t, models.PeerSyncSynced, customer.SyncStatus)
+		assert.NotEqual(t, "s3cret", customer.Password)
+		password, err := service.StoredPassword(customer)
+		require.NoError(t, err)
+		assert.Equal(t, "s3cret", password)
+
+		policies, err := service.ListPolicies(ctx)
+		require.NoError(t, err)
+		require.Len(t, policies, 2)
+		assert.Equal(t, "prefix-list", policies[0].Kind)
+		assert.Equal(t, "route-map CUSTOMER-IN permit 10\n match ip address prefix-list CUSTOMER\n", policies[1].Config)
+
+		asPath, err := service.GetASPathList(ctx, "PRIVATE")
+		require.NoError(t, err)
+		assert.Equal(t, []models.ASPathListEntry{{Seq: 5, Action: "permit", Regex: "_6451[2-9]_"}}, asPath.Entries)
+		community, err := service.GetCommunityList(ctx, "NO-EXPORT")
+		require.NoError(t, err)
+		assert.Equal(t, models.CommunityListStandard, community.Type)
+
+		// Importing again finds everything already there
+		report, err = service.ImportRunningConfig(ctx, false)
+		require.NoError(t, err)
+		assert.Empty(t, report.Imported)
+	})
+
+	t.Run("Dry run stores nothing", func(t *testing.T) {
+		service := setup(t)
+
+		report, err := service.ImportRunningConfig(ctx, true)
+		require.NoError(t, err)
+		assert.True(t, report.DryRun)
+		assert.Len(t, report.Imported, 6)
+
+		peers, err := service.ListPeers(ctx)
+		require.NoError(t, err)
+		assert.Len(t, peers, 1)
+		policies, err := service.ListPolicies(ctx)
+		require.NoError(t, err)
+		assert.Empty(t, policies)
+	})
+
+	t.Run("Skips peers of another AS", func(t *testing.T) {
+		service := setup(t)
+		service.config.LocalASN = 65100
+
+		report, err := service.ImportRunningConfig(ctx, true)
+		require.NoError(t, err)
+		assert.Len(t, report.Imported, 4, "only the policies")
+		for _, item := range report.Skipped {
+			assert.Contains(t, item.Reason, "the instance's AS 65001 differs from the router's AS 65100")
+		}
+	})
+
+	t.Run("Nothing to import", func(t *testing.T) {
+		service := setupTestService(t, ConsistencyEventual)
+		client := frr.NewMockClient()
+		client.On("GetRunningConfig", mock.Anything).Return("frr version 9.1\nhostname edge1\n", nil)
+		service.frrClient = client
+
+		_, err := service.ImportRunningConfig(ctx, false)
+		assert.ErrorIs(t, err, ErrNoBGPInstance)
+	})
+}
@@ -0,0 +1,386 @@
+package bgp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/padminisys/flintroute/internal/requestid"
+	"github.com/padminisys/flintroute/pkg/models"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrInvalidPolicy is returned when a community-list or as-path
+	// access-list is rejected before being stored
+	ErrInvalidPolicy = errors.New("invalid routing policy")
+	// ErrPolicyInUse is returned when deleting a list a route-map still
+	// references
+	ErrPolicyInUse = errors.New("routing policy is referenced by a route-map")
+)
+
+// policyNamePattern restricts list names to identifiers FRR doesn't
+// mistake for numbered lists
+var policyNamePattern = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_.-]*$`)
+
+// wellKnownCommunities are the community keywords FRR accepts in standard
+// community-lists
+var wellKnownCommunities = map[string]bool{
+	"internet":          true,
+	"local-AS":          true,
+	"no-advertise":      true,
+	"no-export":         true,
+	"no-peer":           true,
+	"blackhole":         true,
+	"graceful-shutdown": true,
+	"accept-own":        true,
+	"llgr-stale":        true,
+	"no-llgr":           true,
+}
+
+// ValidateCommunityList checks a community-list's name, type and entries
+func ValidateCommunityList(list *models.CommunityList) error {
+	var problems []string
+	addf := func(format string, args ...interface{}) {
+		problems = append(problems, fmt.Sprintf(format, args...))
+	}
+
+	validatePolicyName(addf, list.Name)
+	if list.Type != models.CommunityListStandard && list.Type != models.CommunityListExpanded {
+		addf("type must be %s or %s", models.CommunityListStandard, models.CommunityListExpanded)
+	}
+	if len(list.Entries) == 0 {
+		addf("at least one entry is required")
+	}
+
+	seqs := make(map[int]bool)
+	for _, entry := range list.Entries {
+		validateEntry(addf, entry.Seq, entry.Action, seqs)
+		switch {
+		case strings.TrimSpace(entry.Value) == "":
+			addf("seq %d: value is required", entry.Seq)
+		case list.Type == models.CommunityListStandard:
+			for _, community := range strings.Fields(entry.Value) {
+				if !validCommunity(community) {
+					addf("seq %d: invalid community %q", entry.Seq, community)
+				}
+			}
+		case list.Type == models.CommunityListExpanded:
+			if _, err := regexp.Compile(entry.Value); err != nil {
+				addf("seq %d: invalid regular expression %q", entry.Seq, entry.Value)
+			}
+		}
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("%w: community-list %s: %s", ErrInvalidPolicy, list.Name, strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// ValidateASPathList checks an as-path access-list's name and entries
+func ValidateASPathList(list *models.ASPathList) error {
+	var problems []string
+	addf := func(format string, args ...interface{}) {
+		problems = append(problems, fmt.Sprintf(format, args...))
+	}
+
+	validatePolicyName(addf, list.Name)
+	if len(list.Entries) == 0 {
+		addf("at least one entry is required")
+	}
+
+	seqs := make(map[int]bool)
+	for _, entry := range list.Entries {
+		validateEntry(addf, entry.Seq, entry.Action, seqs)
+		if strings.TrimSpace(entry.Regex) == "" {
+			addf("seq %d: regex is required", entry.Seq)
+		} else if _, err := regexp.Compile(entry.Regex); err != nil {
+			addf("seq %d: invalid regular expression %q", entry.Seq, entry.Regex)
+		}
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("%w: as-path-list %s: %s", ErrInvalidPolicy, list.Name, strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+func validatePolicyName(addf func(string, ...interface{}), name string) {
+	if !policyNamePattern.MatchString(name) {
+		addf("name must start with a letter and contain only letters, digits, '.', '_' and '-'")
+	}
+}
+
+func validateEntry(addf func(string, ...interface{}), seq int, action string, seen map[int]bool) {
+	if seq <= 0 {
+		addf("seq must be positive")
+	} else if seen[seq] {
+		addf("seq %d is used more than once", seq)
+	}
+	seen[seq] = true
+
+	if action != "permit" && action != "deny" {
+		addf("seq %d: action must be permit or deny", seq)
+	}
+}
+
+// validCommunity reports whether s is an AA:NN community or a well-known
+// community keyword
+func validCommunity(s string) bool {
+	if wellKnownCommunities[s] {
+		return true
+	}
+	asn, value, ok := strings.Cut(s, ":")
+	if !ok {
+		return false
+	}
+	for _, part := range []string{asn, value} {
+		if _, err := strconv.ParseUint(part, 10, 16); err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// RenderCommunityList returns the FRR configuration for a community-list
+func RenderCommunityList(list *models.CommunityList) string {
+	entries := append([]models.CommunityListEntry(nil), list.Entries...)
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Seq < entries[j].Seq })
+
+	var b strings.Builder
+	for _, entry := range entries {
+		value := entry.Value
+		if list.Type == models.CommunityListStandard {
+			value = strings.Join(strings.Fields(value), " ")
+		}
+		fmt.Fprintf(&b, "bgp community-list %s %s seq %d %s %s\n", list.Type, list.Name, entry.Seq, entry.Action, value)
+	}
+	return b.String()
+}
+
+// RenderASPathList returns the FRR configuration for an as-path access-list
+func RenderASPathList(list *models.ASPathList) string {
+	entries := append([]models.ASPathListEntry(nil), list.Entries...)
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Seq < entries[j].Seq })
+
+	var b strings.Builder
+	for _, entry := range entries {
+		fmt.Fprintf(&b, "bgp as-path access-list %s seq %d %s %s\n", list.Name, entry.Seq, entry.Action, entry.Regex)
+	}
+	return b.String()
+}
+
+// PolicyRef is a reference from a route-map clause to another policy
+type PolicyRef struct {
+	Kind string
+	Name string
+}
+
+// RouteMapReferences returns the community-lists and as-path access-lists
+// referenced by route-map match and set clauses, given without the leading
+// keyword as in "community CUSTOMERS exact-match"
+func RouteMapReferences(match, set []string) []PolicyRef {
+	var refs []PolicyRef
+	for _, clause := range match {
+		fields := strings.Fields(clause)
+		if len(fields) < 2 {
+			continue
+		}
+		switch fields[0] {
+		case "community":
+			refs = append(refs, PolicyRef{Kind: models.PolicyCommunityList, Name: fields[1]})
+		case "as-path":
+			refs = append(refs, PolicyRef{Kind: models.PolicyASPathList, Name: fields[1]})
+		}
+	}
+	for _, clause := range set {
+		fields := strings.Fields(clause)
+		if len(fields) >= 2 && fields[0] == "comm-list" {
+			refs = append(refs, PolicyRef{Kind: models.PolicyCommunityList, Name: fields[1]})
+		}
+	}
+	return refs
+}
+
+// routeMapConfigReferences returns the references in a rendered route-map
+func routeMapConfigReferences(config string) []PolicyRef {
+	var match, set []string
+	for _, line := range strings.Split(config, "\n") {
+		line = strings.TrimSpace(line)
+		if clause, ok := strings.CutPrefix(line, "match "); ok {
+			match = append(match, clause)
+		} else if clause, ok := strings.CutPrefix(line, "set "); ok {
+			set = append(set, clause)
+		}
+	}
+	return RouteMapReferences(match, set)
+}
+
+// checkUnreferenced returns ErrPolicyInUse if a stored route-map references
+// the policy
+func (s *Service) checkUnreferenced(ctx context.Context, kind, name string) error {
+	var routeMaps []models.RoutingPolicy
+	if err := s.db.WithContext(ctx).Where("kind = ?", models.PolicyRouteMap).Order("name").Find(&routeMaps).Error; err != nil {
+		return fmt.Errorf("failed to list route-maps: %w", err)
+	}
+
+	var users []string
+	for _, routeMap := range routeMaps {
+		for _, ref := range routeMapConfigReferences(routeMap.Config) {
+			if ref.Kind == kind && ref.Name == name {
+				users = append(users, routeMap.Name)
+				break
+			}
+		}
+	}
+	if len(users) > 0 {
+		return fmt.Errorf("%w: %s", ErrPolicyInUse, strings.Join(users, ", "))
+	}
+	return nil
+}
+
+// ListCommunityLists retrieves all community-lists
+func (s *Service) ListCommunityLists(ctx context.Context) ([]*models.CommunityList, error) {
+	var lists []*models.CommunityList
+	if err := s.db.WithContext(ctx).Order("name").Find(&lists).Error; err != nil {
+		return nil, err
+	}
+	return lists, nil
+}
+
+// GetCommunityList retrieves a community-list by name
+func (s *Service) GetCommunityList(ctx context.Context, name string) (*models.CommunityList, error) {
+	var list models.CommunityList
+	if err := s.db.WithContext(ctx).Where("name = ?", name).First(&list).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrPolicyNotFound
+		}
+		return nil, err
+	}
+	return &list, nil
+}
+
+// SaveCommunityList validates a community-list, applies it to FRR and
+// stores it, replacing any existing list of the same name
+func (s *Service) SaveCommunityList(ctx context.Context, list *models.CommunityList) error {
+	if err := ValidateCommunityList(list); err != nil {
+		return err
+	}
+	if err := s.frrClient.ApplyPolicy(ctx, models.PolicyCommunityList, list.Name, RenderCommunityList(list)); err != nil {
+		return fmt.Errorf("%w: %w", ErrFRRApplyFailed, err)
+	}
+
+	existing, err := s.GetCommunityList(ctx, list.Name)
+	switch {
+	case err == nil:
+		list.ID = existing.ID
+		list.CreatedAt = existing.CreatedAt
+		err = s.db.WithContext(ctx).Save(list).Error
+	case errors.Is(err, ErrPolicyNotFound):
+		err = s.db.WithContext(ctx).Create(list).Error
+	}
+	if err != nil {
+		return fmt.Errorf("failed to save community-list: %w", err)
+	}
+
+	s.logger.Info("Saved community-list", zap.String("name", list.Name), requestid.Field(ctx))
+	return nil
+}
+
+// DeleteCommunityList removes a community-list from FRR and the database.
+// Lists still referenced by a route-map are kept.
+func (s *Service) DeleteCommunityList(ctx context.Context, name string) error {
+	list, err := s.GetCommunityList(ctx, name)
+	if err != nil {
+		return err
+	}
+	if err := s.checkUnreferenced(ctx, models.PolicyCommunityList, name); err != nil {
+		return err
+	}
+
+	if err := s.frrClient.RemovePolicy(ctx, models.PolicyCommunityList, name); err != nil {
+		return fmt.Errorf("%w: %w", ErrFRRApplyFailed, err)
+	}
+	if err := s.db.WithContext(ctx).Delete(list).Error; err != nil {
+		return fmt.Errorf("failed to delete community-list: %w", err)
+	}
+
+	s.logger.Info("Deleted community-list", zap.String("name", name), requestid.Field(ctx))
+	return nil
+}
+
+// ListASPathLists retrieves all as-path access-lists
+func (s *Service) ListASPathLists(ctx context.Context) ([]*models.ASPathList, error) {
+	var lists []*models.ASPathList
+	if err := s.db.WithContext(ctx).Order("name").Find(&lists).Error; err != nil {
+		return nil, err
+	}
+	return lists, nil
+}
+
+// GetASPathList retrieves an as-path access-list by name
+func (s *Service) GetASPathList(ctx context.Context, name string) (*models.ASPathList, error) {
+	var list models.ASPathList
+	if err := s.db.WithContext(ctx).Where("name = ?", name).First(&list).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrPolicyNotFound
+		}
+		return nil, err
+	}
+	return &list, nil
+}
+
+// SaveASPathList validates an as-path access-list, applies it to FRR and
+// stores it, replacing any existing list of the same name
+func (s *Service) SaveASPathList(ctx context.Context, list *models.ASPathList) error {
+	if err := ValidateASPathList(list); err != nil {
+		return err
+	}
+	if err := s.frrClient.ApplyPolicy(ctx, models.PolicyASPathList, list.Name, RenderASPathList(list)); err != nil {
+		return fmt.Errorf("%w: %w", ErrFRRApplyFailed, err)
+	}
+
+	existing, err := s.GetASPathList(ctx, list.Name)
+	switch {
+	case err == nil:
+		list.ID = existing.ID
+		list.CreatedAt = existing.CreatedAt
+		err = s.db.WithContext(ctx).Save(list).Error
+	case errors.Is(err, ErrPolicyNotFound):
+		err = s.db.WithContext(ctx).Create(list).Error
+	}
+	if err != nil {
+		return fmt.Errorf("failed to save as-path-list: %w", err)
+	}
+
+	s.logger.Info("Saved as-path-list", zap.String("name", list.Name), requestid.Field(ctx))
+	return nil
+}
+
+// DeleteASPathList removes an as-path access-list from FRR and the
+// database. Lists still referenced by a route-map are kept.
+func (s *Service) DeleteASPathList(ctx context.Context, name string) error {
+	list, err := s.GetASPathList(ctx, name)
+	if err != nil {
+		return err
+	}
+	if err := s.checkUnreferenced(ctx, models.PolicyASPathList, name); err != nil {
+		return err
+	}
+
+	if err := s.frrClient.RemovePolicy(ctx, models.PolicyASPathList, name); err != nil {
+		return fmt.Errorf("%w: %w", ErrFRRApplyFailed, err)
+	}
+	if err := s.db.WithContext(ctx).Delete(list).Error; err != nil {
+		return fmt.Errorf("failed to delete as-path-list: %w", err)
+	}
+
+	s.logger.Info("Deleted as-path-list", zap.String("name", name), requestid.Field(ctx))
+	return nil
+}
@@ -0,0 +1,163 @@
+package bgp
+
+import (
+	"context"
+	"testing"
+
+	"github.com/padminisys/flintroute/internal/frr"
+	"github.com/padminisys/flintroute/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateCommunityList(t *testing.T) {
+	valid := func() *models.CommunityList {
+		return &models.CommunityList{
+			Name: "CL-CUSTOMERS",
+			Type: models.CommunityListStandard,
+			Entries: []models.CommunityListEntry{
+				{Seq: 10, Action: "permit", Value: "65000:100 no-export"},
+				{Seq: 20, Action: "deny", Value: "internet"},
+			},
+		}
+	}
+
+	assert.NoError(t, ValidateCommunityList(valid()))
+
+	tests := []struct {
+		name   string
+		mutate func(*models.CommunityList)
+		want   string
+	}{
+		{"Numeric name", func(l *models.CommunityList) { l.Name = "100" }, "name must start with a letter"},
+		{"Unknown type", func(l *models.CommunityList) { l.Type = "large" }, "type must be standard or expanded"},
+		{"No entries", func(l *models.CommunityList) { l.Entries = nil }, "at least one entry"},
+		{"Invalid community", func(l *models.CommunityList) { l.Entries[0].Value = "65000:100000" }, `invalid community "65000:100000"`},
+		{"Missing value", func(l *models.CommunityList) { l.Entries[0].Value = " " }, "seq 10: value is required"},
+		{"Duplicate seq", func(l *models.CommunityList) { l.Entries[1].Seq = 10 }, "seq 10 is used more than once"},
+		{"Invalid action", func(l *models.CommunityList) { l.Entries[0].Action = "accept" }, "permit or deny"},
+		{"Invalid regex", func(l *models.CommunityList) {
+			l.Type = models.CommunityListExpanded
+			l.Entries[0].Value = "^65000:(1"
+		}, "invalid regular expression"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			list := valid()
+			tt.mutate(list)
+
+			err := ValidateCommunityList(list)
+			assert.ErrorIs(t, err, ErrInvalidPolicy)
+			assert.Contains(t, err.Error(), tt.want)
+		})
+	}
+}
+
+func TestValidateASPathList(t *testing.T) {
+	list := &models.ASPathList{
+		Name:    "AS-CUSTOMERS",
+		Entries: []models.ASPathListEntry{{Seq: 10, Action: "permit", Regex: "^65010_"}},
+	}
+	assert.NoError(t, ValidateASPathList(list))
+
+	list.Entries = append(list.Entries, models.ASPathListEntry{Seq: 20, Action: "deny", Regex: "["})
+	err := ValidateASPathList(list)
+	assert.ErrorIs(t, err, ErrInvalidPolicy)
+	assert.Contains(t, err.Error(), "seq 20: invalid regular expression")
+}
+
+func TestRenderLists(t *testing.T) {
+	t.Run("Community-list", func(t *testing.T) {
+		list := &models.CommunityList{
+			Name: "CL-CUSTOMERS",
+			Type: models.CommunityListStandard,
+			Entries: []models.CommunityListEntry{
+				{Seq: 20, Action: "deny", Value: "internet"},
+				{Seq: 10, Action: "permit", Value: "65000:100   no-export"},
+			},
+		}
+		assert.Equal(t,
+			"bgp community-list standard CL-CUSTOMERS seq 10 permit 65000:100 no-export\n"+
+				"bgp community-list standard CL-CUSTOMERS seq 20 deny internet\n",
+			RenderCommunityList(list))
+	})
+
+	t.Run("As-path access-list", func(t *testing.T) {
+		list := &models.ASPathList{
+			Name:    "AS-CUSTOMERS",
+			Entries: []models.ASPathListEntry{{Seq: 10, Action: "permit", Regex: "^65010_"}},
+		}
+		assert.Equal(t, "bgp as-path access-list AS-CUSTOMERS seq 10 permit ^65010_\n", RenderASPathList(list))
+	})
+}
+
+func TestRouteMapReferences(t *testing.T) {
+	refs := RouteMapReferences(
+		[]string{"community CL-IN exact-match", "as-path AS-IN", "ip address prefix-list PL-IN"},
+		[]string{"comm-list CL-STRIP delete", "local-preference 200"},
+	)
+	assert.Equal(t, []PolicyRef{
+		{Kind: models.PolicyCommunityList, Name: "CL-IN"},
+		{Kind: models.PolicyASPathList, Name: "AS-IN"},
+		{Kind: models.PolicyCommunityList, Name: "CL-STRIP"},
+	}, refs)
+}
+
+func TestSaveCommunityList(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Invalid list is rejected", func(t *testing.T) {
+		service := setupTestService(t, ConsistencyEventual)
+
+		err := service.SaveCommunityList(ctx, &models.CommunityList{Name: "CL", Type: models.CommunityListStandard})
+		assert.ErrorIs(t, err, ErrInvalidPolicy)
+	})
+
+	t.Run("Not stored when FRR apply fails", func(t *testing.T) {
+		service := setupTestService(t, ConsistencyEventual)
+
+		err := service.SaveCommunityList(ctx, &models.CommunityList{
+			Name:    "CL",
+			Type:    models.CommunityListStandard,
+			Entries: []models.CommunityListEntry{{Seq: 10, Action: "permit", Value: "65000:1"}},
+		})
+		assert.ErrorIs(t, err, ErrFRRApplyFailed)
+		assert.ErrorIs(t, err, frr.ErrNotConnected)
+
+		lists, err := service.ListCommunityLists(ctx)
+		require.NoError(t, err)
+		assert.Empty(t, lists)
+	})
+}
+
+func TestDeleteLists(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Missing list", func(t *testing.T) {
+		service := setupTestService(t, ConsistencyEventual)
+
+		assert.ErrorIs(t, service.DeleteCommunityList(ctx, "CL"), ErrPolicyNotFound)
+		assert.ErrorIs(t, service.DeleteASPathList(ctx, "AS"), ErrPolicyNotFound)
+	})
+
+	t.Run("Referenced list is kept", func(t *testing.T) {
+		service := setupTestService(t, ConsistencyEventual)
+		require.NoError(t, service.db.Create(&models.ASPathList{
+			Name:    "AS-IN",
+			Entries: []models.ASPathListEntry{{Seq: 10, Action: "permit", Regex: "^65010_"}},
+		}).Error)
+		require.NoError(t, service.db.Create(&models.RoutingPolicy{
+			Kind:   models.PolicyRouteMap,
+			Name:   "RM-IN",
+			Config: "route-map RM-IN permit 10\n match as-path AS-IN\n",
+		}).Error)
+
+		err := service.DeleteASPathList(ctx, "AS-IN")
+		assert.ErrorIs(t, err, ErrPolicyInUse)
+		assert.Contains(t, err.Error(), "RM-IN")
+
+		_, err = service.GetASPathList(ctx, "AS-IN")
+		assert.NoError(t, err)
+	})
+}
@@ -0,0 +1,105 @@
+package bgp
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/padminisys/flintroute/internal/requestid"
+	"github.com/padminisys/flintroute/internal/webhooks"
+	"github.com/padminisys/flintroute/pkg/models"
+	"go.uber.org/zap"
+)
+
+// StartMaintenance puts a peer under maintenance until until, or until
+// EndMaintenance is called when until is nil. State change alerts and
+// notifications for the peer are suppressed during the window. The peer's
+// FRR configuration is not changed.
+func (s *Service) StartMaintenance(ctx context.Context, id uint, until *time.Time) (*models.BGPPeer, error) {
+	now := time.Now()
+	if until != nil && !until.After(now) {
+		return nil, fmt.Errorf("%w: maintenance end time must be in the future", ErrInvalidPeer)
+	}
+
+	peer, err := s.GetPeer(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	// Extending a running window keeps its start
+	if !peer.InMaintenance(now) {
+		peer.MaintenanceSince = &now
+	}
+	peer.MaintenanceUntil = until
+
+	if err := s.setMaintenance(ctx, peer); err != nil {
+		return nil, err
+	}
+
+	fields := []zap.Field{zap.Uint("id", peer.ID), zap.String("ip", peer.IPAddress), requestid.Field(ctx)}
+	if until != nil {
+		fields = append(fields, zap.Time("until", *until))
+	}
+	s.logger.Info("Started BGP peer maintenance", fields...)
+
+	return peer, nil
+}
+
+// EndMaintenance takes a peer out of maintenance
+func (s *Service) EndMaintenance(ctx context.Context, id uint) (*models.BGPPeer, error) {
+	peer, err := s.GetPeer(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if peer.MaintenanceSince == nil {
+		return peer, nil
+	}
+
+	peer.MaintenanceSince = nil
+	peer.MaintenanceUntil = nil
+	if err := s.setMaintenance(ctx, peer); err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("Ended BGP peer maintenance", zap.Uint("id", peer.ID), requestid.Field(ctx))
+
+	return peer, nil
+}
+
+// ExpireMaintenance takes peers whose maintenance window has passed out of
+// maintenance. It runs with every monitoring poll.
+func (s *Service) ExpireMaintenance(ctx context.Context) error {
+	var peers []*models.BGPPeer
+	if err := s.db.WithContext(ctx).Preload("Tags").
+		Where("maintenance_since IS NOT NULL AND maintenance_until <= ?", time.Now()).
+		Find(&peers).Error; err != nil {
+		return err
+	}
+
+	for _, peer := range peers {
+		peer.MaintenanceSince = nil
+		peer.MaintenanceUntil = nil
+		if err := s.setMaintenance(ctx, peer); err != nil {
+			return err
+		}
+
+		s.logger.Info("BGP peer maintenance window ended", zap.Uint("id", peer.ID), zap.String("ip", peer.IPAddress))
+	}
+
+	return nil
+}
+
+// setMaintenance stores a peer's maintenance window and announces the change
+func (s *Service) setMaintenance(ctx context.Context, peer *models.BGPPeer) error {
+	if err := s.db.Model(peer).Updates(map[string]interface{}{
+		"maintenance_since": peer.MaintenanceSince,
+		"maintenance_until": peer.MaintenanceUntil,
+	}).Error; err != nil {
+		return fmt.Errorf("failed to update peer maintenance: %w", err)
+	}
+	s.peersChanged()
+
+	s.wsHub.BroadcastPeerUpdate(ctx, peer)
+	s.config.Webhooks.Publish(ctx, webhooks.EventPeerUpdated, peer)
+	return nil
+}
@@ -0,0 +1,126 @@
+package bgp
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/padminisys/flintroute/internal/frr"
+	"github.com/padminisys/flintroute/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMaintenance(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Starts and ends maintenance", func(t *testing.T) {
+		service := setupTestService(t, ConsistencyEventual)
+		peer := newTestPeer("192.0.2.1", true)
+		require.NoError(t, service.db.Create(peer).Error)
+
+		until := time.Now().Add(time.Hour)
+		started, err := service.StartMaintenance(ctx, peer.ID, &until)
+		require.NoError(t, err)
+		require.NotNil(t, started.MaintenanceSince)
+		assert.True(t, started.InMaintenance(time.Now()))
+		assert.False(t, started.InMaintenance(until))
+
+		// Extending the window keeps its start
+		extended, err := service.StartMaintenance(ctx, peer.ID, nil)
+		require.NoError(t, err)
+		assert.True(t, started.MaintenanceSince.Equal(*extended.MaintenanceSince))
+		assert.Nil(t, extended.MaintenanceUntil)
+
+		ended, err := service.EndMaintenance(ctx, peer.ID)
+		require.NoError(t, err)
+		assert.Nil(t, ended.MaintenanceSince)
+
+		stored, err := service.GetPeer(ctx, peer.ID)
+		require.NoError(t, err)
+		assert.False(t, stored.InMaintenance(time.Now()))
+	})
+
+	t.Run("Rejects end times in the past", func(t *testing.T) {
+		service := setupTestService(t, ConsistencyEventual)
+		peer := newTestPeer("192.0.2.1", true)
+		require.NoError(t, service.db.Create(peer).Error)
+
+		past := time.Now().Add(-time.Minute)
+		_, err := service.StartMaintenance(ctx, peer.ID, &past)
+		assert.ErrorIs(t, err, ErrInvalidPeer)
+
+		_, err = service.StartMaintenance(ctx, 999, nil)
+		assert.ErrorIs(t, err, ErrPeerNotFound)
+	})
+
+	t.Run("Expires ended windows", func(t *testing.T) {
+		service := setupTestService(t, ConsistencyEventual)
+		since := time.Now().Add(-time.Hour)
+		past := time.Now().Add(-time.Minute)
+		future := time.Now().Add(time.Hour)
+
+		expired := newTestPeer("192.0.2.1", true)
+		expired.MaintenanceSince, expired.MaintenanceUntil = &since, &past
+		require.NoError(t, service.db.Create(expired).Error)
+		open := newTestPeer("192.0.2.2", true)
+		open.MaintenanceSince = &since
+		require.NoError(t, service.db.Create(open).Error)
+		running := newTestPeer("192.0.2.3", true)
+		running.MaintenanceSince, running.MaintenanceUntil = &since, &future
+		require.NoError(t, service.db.Create(running).Error)
+
+		require.NoError(t, service.ExpireMaintenance(ctx))
+
+		stored, err := service.GetPeer(ctx, expired.ID)
+		require.NoError(t, err)
+		assert.Nil(t, stored.MaintenanceSince)
+		assert.Nil(t, stored.MaintenanceUntil)
+
+		for _, peer := range []*models.BGPPeer{open, running} {
+			stored, err := service.GetPeer(ctx, peer.ID)
+			require.NoError(t, err)
+			assert.True(t, stored.InMaintenance(time.Now()), peer.IPAddress)
+		}
+	})
+
+	t.Run("Suppresses state change alerts", func(t *testing.T) {
+		service := setupTestService(t, ConsistencyEventual)
+		peer := newTestPeer("192.0.2.1", true)
+		require.NoError(t, service.db.Create(peer).Error)
+
+		client := frr.NewMockClient()
+		client.On("IsConnected").Return(true)
+		client.On("GetAllBGPSessions", mock.Anything).Return([]*frr.BGPSessionState{{IPAddress: "192.0.2.1", State: "Established"}}, nil).Once()
+		client.On("GetAllBGPSessions", mock.Anything).Return([]*frr.BGPSessionState{{IPAddress: "192.0.2.1", State: "Active"}}, nil).Once()
+		client.On("GetAllBGPSessions", mock.Anything).Return([]*frr.BGPSessionState{{IPAddress: "192.0.2.1", State: "Established"}}, nil).Once()
+		service.frrClient = client
+
+		require.NoError(t, service.UpdateSessionStates(ctx))
+		_, err := service.StartMaintenance(ctx, peer.ID, nil)
+		require.NoError(t, err)
+
+		require.NoError(t, service.UpdateSessionStates(ctx))
+		session, err := service.GetSession(ctx, peer.ID)
+		require.NoError(t, err)
+		assert.Equal(t, "Active", session.State)
+		assert.True(t, session.InMaintenance)
+
+		var count int64
+		require.NoError(t, service.db.Model(&models.Alert{}).Where("type = ?", "peer_down").Count(&count).Error)
+		assert.Zero(t, count)
+
+		// Alerts resume once maintenance ends
+		_, err = service.EndMaintenance(ctx, peer.ID)
+		require.NoError(t, err)
+		require.NoError(t, service.UpdateSessionStates(ctx))
+		require.NoError(t, service.db.Model(&models.Alert{}).Where("type = ?", "peer_up").Count(&count).Error)
+		assert.Equal(t, int64(1), count)
+
+		sessions, err := service.ListSessions(ctx)
+		require.NoError(t, err)
+		require.Len(t, sessions, 1)
+		assert.False(t, sessions[0].InMaintenance)
+	})
+}
@@ -0,0 +1,304 @@
+package bgp
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"time"
+
+	"github.com/padminisys/flintroute/internal/tracing"
+	"github.com/padminisys/flintroute/pkg/models"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+// MonitorConfig controls how often session states are polled from FRR
+type MonitorConfig struct {
+	// Interval is the delay between polls while FRR is reachable
+	Interval time.Duration
+	// Jitter randomizes each delay by up to this fraction of it, so several
+	// FlintRoute instances don't poll in lockstep
+	Jitter float64
+	// MaxBackoff caps the delay while FRR is unreachable. The delay
+	// doubles with every poll that finds FRR unreachable.
+	MaxBackoff time.Duration
+	// AlertAfter is how many cycles in a row must fail, or take longer
+	// than Interval, before an alert is raised; defaults to 3
+	AlertAfter int
+}
+
+// withDefaults fills in unset fields
+func (c MonitorConfig) withDefaults() MonitorConfig {
+	if c.Interval <= 0 {
+		c.Interval = 30 * time.Second
+	}
+	if c.MaxBackoff <= 0 {
+		c.MaxBackoff = 5 * time.Minute
+	}
+	c.MaxBackoff = max(c.MaxBackoff, c.Interval)
+	if c.AlertAfter <= 0 {
+		c.AlertAfter = 3
+	}
+	return c
+}
+
+// delay returns how long to wait before the next poll after failures
+// consecutive polls found FRR unreachable
+func (c MonitorConfig) delay(failures int) time.Duration {
+	d := c.Interval
+	for i := 0; i < failures && d < c.MaxBackoff; i++ {
+		d *= 2
+	}
+	d = min(d, c.MaxBackoff)
+
+	if c.Jitter > 0 {
+		d += time.Duration((rand.Float64()*2 - 1) * c.Jitter * float64(d))
+	}
+	return d
+}
+
+// gapAfter is how long since the last successful poll the next one has to
+// come, at the latest, for session states to count as watched in between:
+// two polls missed, jitter included
+func (c MonitorConfig) gapAfter() time.Duration {
+	return 3 * c.Interval
+}
+
+// MonitorStatus describes the session monitor
+type MonitorStatus struct {
+	Running      bool       `json:"running"`
+	Paused       bool       `json:"paused"`
+	FRRReachable bool       `json:"frr_reachable"`
+	LastPoll     *time.Time `json:"last_poll,omitempty"`
+	NextPoll     *time.Time `json:"next_poll,omitempty"`
+	// UnreachablePolls counts consecutive polls that found FRR unreachable;
+	// the poll interval backs off while it is non-zero
+	UnreachablePolls int `json:"unreachable_polls"`
+	// Stats describes the polling cycles, including those run before the
+	// last restart
+	Stats models.MonitorStats `json:"stats"`
+}
+
+// MonitorStatus returns the session monitor's current state
+func (s *Service) MonitorStatus() MonitorStatus {
+	s.monitorMu.Lock()
+	defer s.monitorMu.Unlock()
+
+	status := s.monitor
+	if status.Paused {
+		status.NextPoll = nil
+	}
+	return status
+}
+
+// PauseMonitoring stops polling FRR until ResumeMonitoring is called, for
+// example during FRR maintenance
+func (s *Service) PauseMonitoring() {
+	s.monitorMu.Lock()
+	defer s.monitorMu.Unlock()
+
+	if !s.monitor.Paused {
+		s.monitor.Paused = true
+		s.logger.Info("Paused BGP session monitoring")
+	}
+}
+
+// ResumeMonitoring resumes polling FRR, starting with an immediate poll
+func (s *Service) ResumeMonitoring() {
+	s.monitorMu.Lock()
+	defer s.monitorMu.Unlock()
+
+	if !s.monitor.Paused {
+		return
+	}
+	s.monitor.Paused = false
+	s.logger.Info("Resumed BGP session monitoring")
+
+	select {
+	case s.monitorWake <- struct{}{}:
+	default:
+	}
+}
+
+// StartMonitoring starts periodic monitoring of BGP sessions. Stored peers
+// are pushed to FRR whenever it becomes reachable again; the caller syncs
+// them at startup.
+func (s *Service) StartMonitoring(ctx context.Context, cfg MonitorConfig) {
+	cfg = cfg.withDefaults()
+
+	s.logger.Info("Started BGP session monitoring",
+		zap.Duration("interval", cfg.Interval),
+		zap.Float64("jitter", cfg.Jitter),
+		zap.Duration("max_backoff", cfg.MaxBackoff),
+	)
+
+	stats := s.loadMonitorStats(ctx)
+	s.monitorMu.Lock()
+	s.monitor.Running = true
+	s.monitor.FRRReachable = s.frrReachable
+	s.monitor.Stats = *stats
+	s.monitorMu.Unlock()
+	defer func() {
+		s.monitorMu.Lock()
+		s.monitor.Running = false
+		s.monitor.NextPoll = nil
+		s.monitorMu.Unlock()
+	}()
+
+	failures := 0
+	for {
+		delay := cfg.delay(failures)
+		next := time.Now().Add(delay)
+		s.monitorMu.Lock()
+		s.monitor.NextPoll = &next
+		s.monitorMu.Unlock()
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			s.logger.Info("Stopped BGP session monitoring")
+			return
+		case <-timer.C:
+		case <-s.monitorWake:
+			timer.Stop()
+		}
+
+		if s.MonitorStatus().Paused {
+			continue
+		}
+
+		started := time.Now()
+		cycle := s.poll(ctx)
+		cycle.duration = time.Since(started)
+
+		if s.frrReachable {
+			failures = 0
+		} else {
+			failures++
+		}
+
+		now := time.Now()
+		lastSuccess := stats.LastSuccessAt
+		recordCycle(stats, cycle, cfg.Interval, now)
+		if len(cycle.errs) == 0 && lastSuccess != nil && now.Sub(*lastSuccess) > cfg.gapAfter() {
+			s.recordMonitoringGap(ctx, *lastSuccess, now)
+		}
+		s.monitorMu.Lock()
+		s.monitor.LastPoll = &now
+		s.monitor.FRRReachable = s.frrReachable
+		s.monitor.UnreachablePolls = failures
+		s.monitor.Stats = *stats
+		s.monitorMu.Unlock()
+
+		if err := s.db.WithContext(ctx).Save(stats).Error; err != nil {
+			s.logger.Error("Failed to save monitoring statistics", zap.Error(err))
+		}
+		s.checkMonitorHealth(ctx, stats, cfg)
+	}
+}
+
+// monitorCycle is the outcome of one poll
+type monitorCycle struct {
+	duration    time.Duration
+	peersPolled int
+	errs        []error
+}
+
+// recordCycle adds a cycle to stats. Cycles taking longer than interval
+// count as slow.
+func recordCycle(stats *models.MonitorStats, cycle monitorCycle, interval time.Duration, now time.Time) {
+	stats.Cycles++
+	stats.LastCycleAt = &now
+	stats.LastDurationMS = cycle.duration.Milliseconds()
+	stats.LastPeersPolled = cycle.peersPolled
+	stats.LastErrors = len(cycle.errs)
+
+	if len(cycle.errs) > 0 {
+		stats.FailedCycles++
+		stats.ConsecutiveFailures++
+		stats.LastError = cycle.errs[len(cycle.errs)-1].Error()
+	} else {
+		stats.ConsecutiveFailures = 0
+		stats.LastError = ""
+		stats.LastSuccessAt = &now
+	}
+
+	if cycle.duration > interval {
+		stats.SlowCycles++
+		stats.ConsecutiveSlow++
+	} else {
+		stats.ConsecutiveSlow = 0
+	}
+}
+
+// recordMonitoringGap records that session states weren't polled between
+// start and end, such as while FlintRoute was down or FRR unreachable
+func (s *Service) recordMonitoringGap(ctx context.Context, start, end time.Time) {
+	s.logger.Info("BGP session states were not polled for a while",
+		zap.Time("since", start),
+		zap.Duration("gap", end.Sub(start)),
+	)
+	if err := s.db.WithContext(ctx).Create(&models.MonitoringGap{Start: start, End: end}).Error; err != nil {
+		s.logger.Error("Failed to record monitoring gap", zap.Error(err))
+	}
+}
+
+// loadMonitorStats returns the persisted monitoring statistics, or empty
+// ones when none were saved or they can't be read
+func (s *Service) loadMonitorStats(ctx context.Context) *models.MonitorStats {
+	var stats models.MonitorStats
+	err := s.db.WithContext(ctx).Order("id").Limit(1).Find(&stats).Error
+	if err != nil {
+		s.logger.Error("Failed to load monitoring statistics", zap.Error(err))
+		return &models.MonitorStats{}
+	}
+	return &stats
+}
+
+// checkMonitorHealth raises an alert once cycles have failed, or taken
+// longer than the poll interval, AlertAfter times in a row. Failures while
+// FRR is unreachable are left to the frr_unreachable alert.
+func (s *Service) checkMonitorHealth(ctx context.Context, stats *models.MonitorStats, cfg MonitorConfig) {
+	if stats.ConsecutiveFailures == cfg.AlertAfter && s.frrReachable {
+		s.raiseAlert(ctx, &models.Alert{
+			Type:     "monitor_failing",
+			Severity: "warning",
+			Message: fmt.Sprintf("BGP session monitoring failed %d times in a row: %s",
+				stats.ConsecutiveFailures, stats.LastError),
+		}, false)
+	}
+	if stats.ConsecutiveSlow == cfg.AlertAfter {
+		s.raiseAlert(ctx, &models.Alert{
+			Type:     "monitor_slow",
+			Severity: "warning",
+			Message: fmt.Sprintf("BGP session monitoring cycles took longer than the %s poll interval %d times in a row, the last %dms",
+				cfg.Interval, stats.ConsecutiveSlow, stats.LastDurationMS),
+		}, false)
+	}
+}
+
+// poll ends expired maintenance windows, retries pending peers and
+// refreshes session states
+func (s *Service) poll(ctx context.Context) monitorCycle {
+	// Each poll is a trace of its own, with the FRR calls and queries it makes
+	ctx, span := tracing.Tracer().Start(ctx, "bgp.monitor.poll", trace.WithNewRoot())
+	defer span.End()
+
+	var cycle monitorCycle
+	if err := s.ExpireMaintenance(ctx); err != nil {
+		s.logger.Error("Failed to expire peer maintenance", zap.Error(err))
+		cycle.errs = append(cycle.errs, err)
+	}
+	if err := s.SyncPendingPeers(ctx); err != nil {
+		s.logger.Error("Failed to sync pending peers", zap.Error(err))
+		cycle.errs = append(cycle.errs, err)
+	}
+	peers, err := s.updateSessionStates(ctx)
+	if err != nil {
+		s.logger.Error("Failed to update session states", zap.Error(err))
+		cycle.errs = append(cycle.errs, err)
+	}
+	cycle.peersPolled = peers
+	return cycle
+}
@@ -0,0 +1,168 @@
+package bgp
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/padminisys/flintroute/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMonitorDelay(t *testing.T) {
+	t.Run("Backs off while FRR is unreachable", func(t *testing.T) {
+		cfg := MonitorConfig{Interval: 30 * time.Second, MaxBackoff: 5 * time.Minute}.withDefaults()
+
+		assert.Equal(t, 30*time.Second, cfg.delay(0))
+		assert.Equal(t, 60*time.Second, cfg.delay(1))
+		assert.Equal(t, 240*time.Second, cfg.delay(3))
+		assert.Equal(t, 5*time.Minute, cfg.delay(4))
+		assert.Equal(t, 5*time.Minute, cfg.delay(100))
+	})
+
+	t.Run("Jitter stays within bounds", func(t *testing.T) {
+		cfg := MonitorConfig{Interval: 10 * time.Second, Jitter: 0.2}.withDefaults()
+
+		for i := 0; i < 100; i++ {
+			d := cfg.delay(0)
+			assert.GreaterOrEqual(t, d, 8*time.Second)
+			assert.LessOrEqual(t, d, 12*time.Second)
+		}
+	})
+
+	t.Run("Defaults", func(t *testing.T) {
+		cfg := MonitorConfig{}.withDefaults()
+		assert.Equal(t, 30*time.Second, cfg.Interval)
+		assert.Equal(t, 5*time.Minute, cfg.MaxBackoff)
+
+		cfg = MonitorConfig{Interval: 10 * time.Minute, MaxBackoff: time.Minute}.withDefaults()
+		assert.Equal(t, 10*time.Minute, cfg.MaxBackoff)
+	})
+}
+
+func TestStartMonitoring(t *testing.T) {
+	service := setupTestService(t, ConsistencyEventual)
+	go service.wsHub.Run()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		service.StartMonitoring(ctx, MonitorConfig{Interval: 10 * time.Millisecond, MaxBackoff: 20 * time.Millisecond})
+		close(done)
+	}()
+
+	require.Eventually(t, func() bool {
+		return service.MonitorStatus().UnreachablePolls >= 2
+	}, time.Second, 5*time.Millisecond)
+
+	status := service.MonitorStatus()
+	assert.True(t, status.Running)
+	assert.False(t, status.FRRReachable)
+	require.NotNil(t, status.LastPoll)
+	assert.NotNil(t, status.NextPoll)
+
+	t.Run("Records and persists cycle statistics", func(t *testing.T) {
+		stats := service.MonitorStatus().Stats
+		assert.GreaterOrEqual(t, stats.Cycles, int64(2))
+		assert.Zero(t, stats.FailedCycles, "there are no peers to poll")
+		assert.NotNil(t, stats.LastSuccessAt)
+
+		var saved models.MonitorStats
+		require.NoError(t, service.db.First(&saved).Error)
+		assert.Positive(t, saved.Cycles)
+	})
+
+	t.Run("Pause stops polling", func(t *testing.T) {
+		service.PauseMonitoring()
+		time.Sleep(30 * time.Millisecond)
+		paused := service.MonitorStatus()
+		assert.True(t, paused.Paused)
+		assert.Nil(t, paused.NextPoll)
+
+		time.Sleep(50 * time.Millisecond)
+		assert.Equal(t, paused.LastPoll, service.MonitorStatus().LastPoll)
+	})
+
+	t.Run("Resume polls again", func(t *testing.T) {
+		last := *service.MonitorStatus().LastPoll
+		service.ResumeMonitoring()
+
+		require.Eventually(t, func() bool {
+			return service.MonitorStatus().LastPoll.After(last)
+		}, time.Second, 5*time.Millisecond)
+		assert.False(t, service.MonitorStatus().Paused)
+
+		var gaps []models.MonitoringGap
+		require.NoError(t, service.db.Find(&gaps).Error)
+		require.Len(t, gaps, 1, "the pause")
+		assert.GreaterOrEqual(t, gaps[0].End.Sub(gaps[0].Start), 80*time.Millisecond)
+	})
+
+	cancel()
+	<-done
+	assert.False(t, service.MonitorStatus().Running)
+
+	t.Run("Statistics survive a restart", func(t *testing.T) {
+		var saved models.MonitorStats
+		require.NoError(t, service.db.First(&saved).Error)
+		assert.Equal(t, saved.Cycles, service.loadMonitorStats(context.Background()).Cycles)
+	})
+}
+
+func TestMonitorHealth(t *testing.T) {
+	ctx := context.Background()
+	cfg := MonitorConfig{Interval: time.Second}.withDefaults()
+	failed := monitorCycle{duration: 2 * time.Second, peersPolled: 4, errs: []error{errors.New("database is locked")}}
+
+	t.Run("Counts failed and slow cycles", func(t *testing.T) {
+		var stats models.MonitorStats
+		now := time.Now()
+		recordCycle(&stats, failed, cfg.Interval, now)
+		recordCycle(&stats, failed, cfg.Interval, now)
+		assert.Equal(t, int64(2), stats.FailedCycles)
+		assert.Equal(t, 2, stats.ConsecutiveSlow)
+		assert.Equal(t, int64(2000), stats.LastDurationMS)
+		assert.Equal(t, 4, stats.LastPeersPolled)
+		assert.Equal(t, "database is locked", stats.LastError)
+		assert.Nil(t, stats.LastSuccessAt)
+
+		recordCycle(&stats, monitorCycle{duration: time.Millisecond}, cfg.Interval, now)
+		assert.Equal(t, int64(3), stats.Cycles)
+		assert.Zero(t, stats.ConsecutiveFailures)
+		assert.Zero(t, stats.ConsecutiveSlow)
+		assert.Empty(t, stats.LastError)
+		assert.Equal(t, &now, stats.LastSuccessAt)
+	})
+
+	t.Run("Alerts once cycles keep failing", func(t *testing.T) {
+		service := setupTestService(t, ConsistencyEventual)
+
+		var stats models.MonitorStats
+		for i := 0; i < cfg.AlertAfter+1; i++ {
+			recordCycle(&stats, failed, cfg.Interval, time.Now())
+			service.checkMonitorHealth(ctx, &stats, cfg)
+		}
+
+		var alerts []models.Alert
+		require.NoError(t, service.db.Order("type").Find(&alerts).Error)
+		require.Len(t, alerts, 2)
+		assert.Equal(t, "monitor_failing", alerts[0].Type)
+		assert.Contains(t, alerts[0].Message, "database is locked")
+		assert.Equal(t, "monitor_slow", alerts[1].Type)
+		assert.Equal(t, 1, alerts[0].Occurrences)
+	})
+
+	t.Run("Leaves FRR outages to the frr_unreachable alert", func(t *testing.T) {
+		service := setupTestService(t, ConsistencyEventual)
+		service.frrReachable = false
+
+		stats := models.MonitorStats{ConsecutiveFailures: cfg.AlertAfter}
+		service.checkMonitorHealth(ctx, &stats, cfg)
+
+		var count int64
+		require.NoError(t, service.db.Model(&models.Alert{}).Count(&count).Error)
+		assert.Zero(t, count)
+	})
+}
@@ -0,0 +1,123 @@
+package bgp
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/padminisys/flintroute/internal/frr"
+	"github.com/padminisys/flintroute/pkg/models"
+)
+
+// ErrInvalidPeer is returned when a peer's options are rejected before
+// being stored
+var ErrInvalidPeer = errors.New("invalid peer configuration")
+
+// Limits FRR accepts for per-neighbor options
+const (
+	maxTimer           = 65535
+	minHoldTime        = 3
+	maxTTLSecurityHops = 254
+	maxAllowASIn       = 10
+	maxPercent         = 100
+)
+
+// ValidatePeer checks a peer's timers and advanced options against the
+// ranges FRR accepts
+func ValidatePeer(peer *models.BGPPeer) error {
+	var problems []string
+	addf := func(format string, args ...interface{}) {
+		problems = append(problems, fmt.Sprintf(format, args...))
+	}
+
+	if peer.Multihop < 0 || peer.Multihop > 255 {
+		addf("multihop must be between 1 and 255")
+	}
+
+	switch {
+	case peer.Keepalive == 0 && peer.HoldTime == 0:
+	case peer.Keepalive == 0 || peer.HoldTime == 0:
+		addf("keepalive and holdtime must be set together")
+	case peer.Keepalive < 0 || peer.Keepalive > maxTimer:
+		addf("keepalive must be between 1 and %d", maxTimer)
+	case peer.HoldTime < minHoldTime || peer.HoldTime > maxTimer:
+		addf("holdtime must be between %d and %d", minHoldTime, maxTimer)
+	case peer.Keepalive >= peer.HoldTime:
+		addf("keepalive must be less than holdtime")
+	}
+
+	if peer.ConnectRetry < 0 || peer.ConnectRetry > maxTimer {
+		addf("connect_retry must be between 1 and %d", maxTimer)
+	}
+
+	if peer.TTLSecurityHops < 0 || peer.TTLSecurityHops > maxTTLSecurityHops {
+		addf("ttl_security_hops must be between 1 and %d", maxTTLSecurityHops)
+	} else if peer.TTLSecurityHops > 0 && peer.Multihop > 1 {
+		addf("ttl_security_hops cannot be combined with multihop")
+	}
+
+	if peer.AllowASIn < 0 || peer.AllowASIn > maxAllowASIn {
+		addf("allowas_in must be between 1 and %d", maxAllowASIn)
+	}
+
+	if peer.LocalAS > 0 {
+		if peer.LocalAS == peer.ASN {
+			addf("local_as must differ from asn")
+		}
+		if peer.RemoteASN == peer.ASN {
+			addf("local_as is only supported for eBGP peers")
+		}
+	}
+	switch peer.LocalASMode {
+	case "":
+	case models.LocalASNoPrepend, models.LocalASReplaceAS:
+		if peer.LocalAS == 0 {
+			addf("local_as_mode requires local_as")
+		}
+	default:
+		addf("local_as_mode must be %s or %s", models.LocalASNoPrepend, models.LocalASReplaceAS)
+	}
+
+	if peer.MaxPrefixThreshold < 0 || peer.MaxPrefixThreshold > maxPercent {
+		addf("max_prefix_threshold must be between 1 and %d", maxPercent)
+	}
+	if peer.MaxPrefixAction != "" && peer.MaxPrefixAction != models.MaxPrefixShutdown && peer.MaxPrefixAction != models.MaxPrefixWarningOnly {
+		addf("max_prefix_action must be %s or %s", models.MaxPrefixShutdown, models.MaxPrefixWarningOnly)
+	}
+	if peer.MaxPrefixRestart < 0 || peer.MaxPrefixRestart > maxTimer {
+		addf("max_prefix_restart must be between 1 and %d", maxTimer)
+	} else if peer.MaxPrefixRestart > 0 && peer.MaxPrefixAction == models.MaxPrefixWarningOnly {
+		addf("max_prefix_restart cannot be combined with %s", models.MaxPrefixWarningOnly)
+	}
+	if peer.MaxPrefixes <= 0 && (peer.MaxPrefixThreshold > 0 || peer.MaxPrefixRestart > 0 || peer.MaxPrefixAction == models.MaxPrefixWarningOnly) {
+		addf("max_prefix_threshold, max_prefix_action and max_prefix_restart require max_prefixes")
+	}
+
+	problems = append(problems, validateTags(peer.Tags)...)
+
+	if len(problems) > 0 {
+		return fmt.Errorf("%w: %s", ErrInvalidPeer, strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// NeighborOptions returns the FRR neighbor options of a peer
+func NeighborOptions(peer *models.BGPPeer) frr.NeighborOptions {
+	return frr.NeighborOptions{
+		Keepalive:            peer.Keepalive,
+		HoldTime:             peer.HoldTime,
+		ConnectRetry:         peer.ConnectRetry,
+		Passive:              peer.Passive,
+		TTLSecurityHops:      peer.TTLSecurityHops,
+		NextHopSelf:          peer.NextHopSelf,
+		SoftReconfigInbound:  peer.SoftReconfigInbound,
+		RemovePrivateAS:      peer.RemovePrivateAS,
+		AllowASIn:            peer.AllowASIn,
+		LocalAS:              peer.LocalAS,
+		LocalASNoPrepend:     peer.LocalASMode != "",
+		LocalASReplaceAS:     peer.LocalASMode == models.LocalASReplaceAS,
+		MaxPrefixThreshold:   peer.MaxPrefixThreshold,
+		MaxPrefixWarningOnly: peer.MaxPrefixAction == models.MaxPrefixWarningOnly,
+		MaxPrefixRestart:     peer.MaxPrefixRestart,
+	}
+}
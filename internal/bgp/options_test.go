@@ -0,0 +1,70 @@
+package bgp
+
+import (
+	"testing"
+
+	"github.com/padminisys/flintroute/pkg/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidatePeer(t *testing.T) {
+	valid := func() *models.BGPPeer {
+		return &models.BGPPeer{
+			IPAddress:           "192.0.2.1",
+			ASN:                 65000,
+			RemoteASN:           65001,
+			LocalAS:             64999,
+			LocalASMode:         models.LocalASReplaceAS,
+			Multihop:            1,
+			Keepalive:           10,
+			HoldTime:            30,
+			ConnectRetry:        5,
+			TTLSecurityHops:     1,
+			SoftReconfigInbound: true,
+			AllowASIn:           3,
+			MaxPrefixes:         1000,
+			MaxPrefixThreshold:  80,
+			MaxPrefixAction:     models.MaxPrefixShutdown,
+			MaxPrefixRestart:    30,
+		}
+	}
+
+	assert.NoError(t, ValidatePeer(valid()))
+	assert.NoError(t, ValidatePeer(&models.BGPPeer{IPAddress: "192.0.2.1"}))
+
+	tests := []struct {
+		name   string
+		mutate func(*models.BGPPeer)
+		want   string
+	}{
+		{"Keepalive without holdtime", func(p *models.BGPPeer) { p.HoldTime = 0 }, "set together"},
+		{"Holdtime too short", func(p *models.BGPPeer) { p.Keepalive, p.HoldTime = 1, 2 }, "holdtime must be between 3 and 65535"},
+		{"Keepalive not below holdtime", func(p *models.BGPPeer) { p.Keepalive = 30 }, "keepalive must be less than holdtime"},
+		{"Negative connect retry", func(p *models.BGPPeer) { p.ConnectRetry = -1 }, "connect_retry"},
+		{"TTL security out of range", func(p *models.BGPPeer) { p.TTLSecurityHops = 255 }, "ttl_security_hops must be between"},
+		{"TTL security with multihop", func(p *models.BGPPeer) { p.Multihop = 2 }, "cannot be combined with multihop"},
+		{"Allowas-in out of range", func(p *models.BGPPeer) { p.AllowASIn = 11 }, "allowas_in must be between 1 and 10"},
+		{"Max-prefix threshold out of range", func(p *models.BGPPeer) { p.MaxPrefixThreshold = 101 }, "max_prefix_threshold must be between"},
+		{"Unknown max-prefix action", func(p *models.BGPPeer) { p.MaxPrefixAction = "drop" }, "max_prefix_action must be"},
+		{"Restart with warning-only", func(p *models.BGPPeer) {
+			p.MaxPrefixAction = models.MaxPrefixWarningOnly
+			p.MaxPrefixRestart = 30
+		}, "cannot be combined with warning-only"},
+		{"Max-prefix options without limit", func(p *models.BGPPeer) { p.MaxPrefixes = 0 }, "require max_prefixes"},
+		{"Local AS equal to ASN", func(p *models.BGPPeer) { p.LocalAS = p.ASN }, "local_as must differ from asn"},
+		{"Local AS for iBGP", func(p *models.BGPPeer) { p.RemoteASN = p.ASN }, "only supported for eBGP peers"},
+		{"Unknown local AS mode", func(p *models.BGPPeer) { p.LocalASMode = "dual-as" }, "local_as_mode must be"},
+		{"Local AS mode without local AS", func(p *models.BGPPeer) { p.LocalAS = 0 }, "local_as_mode requires local_as"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			peer := valid()
+			tt.mutate(peer)
+
+			err := ValidatePeer(peer)
+			assert.ErrorIs(t, err, ErrInvalidPeer)
+			assert.Contains(t, err.Error(), tt.want)
+		})
+	}
+}
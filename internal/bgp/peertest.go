@@ -0,0 +1,96 @@
+package bgp
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/padminisys/flintroute/internal/frr"
+)
+
+// peerTestTimeout bounds each connection attempt of a peer test
+const peerTestTimeout = 5 * time.Second
+
+// PeerTest is the outcome of a pre-flight connection test of a peer
+type PeerTest struct {
+	PeerID    uint      `json:"peer_id"`
+	IPAddress string    `json:"ip_address"`
+	TestedAt  time.Time `json:"tested_at"`
+	// Reachable reports whether the peer's host answered on the BGP port,
+	// accepting or refusing the connection
+	Reachable bool `json:"reachable"`
+	// Ready reports whether the peer accepted a connection the way FRR
+	// will make it, i.e. signed when it has a password
+	Ready bool `json:"ready"`
+	*frr.PeerProbe
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+// TestPeer checks that a peer can be connected to before it is enabled: it
+// opens a TCP connection to the peer's BGP port, and a second one signed
+// with TCP MD5 when the peer has a password. Nothing is configured in FRR.
+func (s *Service) TestPeer(ctx context.Context, id uint) (*PeerTest, error) {
+	peer, err := s.GetPeer(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	cfg, err := s.peerConfig(ctx, peer)
+	if err != nil {
+		return nil, err
+	}
+
+	probe, err := s.frrClient.ProbeBGPPeer(ctx, cfg, peerTestTimeout)
+	if err != nil {
+		return nil, err
+	}
+
+	test := &PeerTest{
+		PeerID:    peer.ID,
+		IPAddress: peer.IPAddress,
+		TestedAt:  time.Now(),
+		PeerProbe: probe,
+	}
+	answered := func(p *frr.TCPProbe) bool {
+		return p != nil && (p.Result == frr.ProbeOpen || p.Result == frr.ProbeRefused)
+	}
+	test.Reachable = answered(&probe.TCP) || answered(probe.MD5)
+	if probe.MD5 != nil {
+		test.Ready = probe.MD5.Result == frr.ProbeOpen
+	} else {
+		test.Ready = probe.TCP.Result == frr.ProbeOpen
+	}
+	test.Warnings = peerTestWarnings(peer.IPAddress, probe)
+
+	return test, nil
+}
+
+// peerTestWarnings explains what a probe suggests is wrong
+func peerTestWarnings(ip string, probe *frr.PeerProbe) []string {
+	var warnings []string
+	if probe.ProbedFrom != frr.ProbedFromRouter {
+		warnings = append(warnings, "the test ran from the FlintRoute host, which may reach the peer differently than the router")
+	}
+
+	switch probe.TCP.Result {
+	case frr.ProbeRefused:
+		warnings = append(warnings, fmt.Sprintf("%s refused connections on port %d; BGP may not be running there or this router is not configured as its neighbor", ip, frr.BGPPort))
+	case frr.ProbeUnreachable:
+		warnings = append(warnings, fmt.Sprintf("no route to %s", ip))
+	case frr.ProbeTimeout:
+		if probe.MD5 == nil || probe.MD5.Result != frr.ProbeOpen {
+			warnings = append(warnings, fmt.Sprintf("%s did not answer on port %d within %s; check routing, firewalls and multihop settings", ip, frr.BGPPort, peerTestTimeout))
+		}
+	}
+
+	if probe.MD5 != nil {
+		switch {
+		case probe.MD5.Result == frr.ProbeUnsupported:
+			warnings = append(warnings, "TCP MD5 signatures could not be tested on this platform")
+		case probe.MD5.Result == frr.ProbeError:
+			warnings = append(warnings, fmt.Sprintf("the TCP MD5 test failed: %s", probe.MD5.Error))
+		case probe.TCP.Result == frr.ProbeOpen && probe.MD5.Result == frr.ProbeTimeout:
+			warnings = append(warnings, fmt.Sprintf("%s accepts unsigned connections but dropped the one signed with the peer's password; it may not have TCP MD5 configured", ip))
+		}
+	}
+	return warnings
+}
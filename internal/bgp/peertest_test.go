@@ -0,0 +1,90 @@
+package bgp
+
+import (
+	"context"
+	"testing"
+
+	"github.com/padminisys/flintroute/internal/frr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTestPeer(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Probes with the peer's password", func(t *testing.T) {
+		service := setupTestService(t, ConsistencyEventual)
+		peer := newTestPeer("192.0.2.1", false)
+		peer.Password = "s3cret"
+		require.NoError(t, service.CreatePeer(ctx, peer))
+
+		client := frr.NewMockClient()
+		client.On("ProbeBGPPeer", mock.Anything, mock.MatchedBy(func(cfg *frr.BGPPeerConfig) bool {
+			return cfg.IPAddress == "192.0.2.1" && cfg.Password == "s3cret"
+		}), peerTestTimeout).Return(&frr.PeerProbe{
+			ProbedFrom: frr.ProbedFromRouter,
+			TCP:        frr.TCPProbe{Result: frr.ProbeOpen, LatencyMS: 1.5},
+			MD5:        &frr.TCPProbe{Result: frr.ProbeOpen, LatencyMS: 1.6},
+		}, nil)
+		service.frrClient = client
+
+		test, err := service.TestPeer(ctx, peer.ID)
+		require.NoError(t, err)
+		assert.Equal(t, peer.ID, test.PeerID)
+		assert.True(t, test.Reachable)
+		assert.True(t, test.Ready)
+		assert.Empty(t, test.Warnings)
+		client.AssertExpectations(t)
+	})
+
+	t.Run("Explains a missing MD5 configuration", func(t *testing.T) {
+		service := setupTestService(t, ConsistencyEventual)
+		peer := newTestPeer("192.0.2.1", false)
+		peer.Password = "s3cret"
+		require.NoError(t, service.CreatePeer(ctx, peer))
+
+		client := frr.NewMockClient()
+		client.On("ProbeBGPPeer", mock.Anything, mock.Anything, peerTestTimeout).Return(&frr.PeerProbe{
+			ProbedFrom: frr.ProbedFromFlintRoute,
+			TCP:        frr.TCPProbe{Result: frr.ProbeOpen, LatencyMS: 1.5},
+			MD5:        &frr.TCPProbe{Result: frr.ProbeTimeout},
+		}, nil)
+		service.frrClient = client
+
+		test, err := service.TestPeer(ctx, peer.ID)
+		require.NoError(t, err)
+		assert.True(t, test.Reachable)
+		assert.False(t, test.Ready)
+		require.Len(t, test.Warnings, 2)
+		assert.Contains(t, test.Warnings[0], "FlintRoute host")
+		assert.Contains(t, test.Warnings[1], "TCP MD5")
+	})
+
+	t.Run("Unreachable peer", func(t *testing.T) {
+		service := setupTestService(t, ConsistencyEventual)
+		peer := newTestPeer("192.0.2.1", false)
+		require.NoError(t, service.CreatePeer(ctx, peer))
+
+		client := frr.NewMockClient()
+		client.On("ProbeBGPPeer", mock.Anything, mock.Anything, peerTestTimeout).Return(&frr.PeerProbe{
+			ProbedFrom: frr.ProbedFromRouter,
+			TCP:        frr.TCPProbe{Result: frr.ProbeTimeout},
+		}, nil)
+		service.frrClient = client
+
+		test, err := service.TestPeer(ctx, peer.ID)
+		require.NoError(t, err)
+		assert.False(t, test.Reachable)
+		assert.False(t, test.Ready)
+		require.Len(t, test.Warnings, 1)
+		assert.Contains(t, test.Warnings[0], "did not answer")
+	})
+
+	t.Run("Unknown peer", func(t *testing.T) {
+		service := setupTestService(t, ConsistencyEventual)
+
+		_, err := service.TestPeer(ctx, 42)
+		assert.ErrorIs(t, err, ErrPeerNotFound)
+	})
+}
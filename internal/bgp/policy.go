@@ -0,0 +1,79 @@
+package bgp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/padminisys/flintroute/internal/requestid"
+	"github.com/padminisys/flintroute/pkg/models"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// ErrPolicyNotFound is returned when a routing policy does not exist
+var ErrPolicyNotFound = errors.New("routing policy not found")
+
+// ListPolicies retrieves all routing policies
+func (s *Service) ListPolicies(ctx context.Context) ([]*models.RoutingPolicy, error) {
+	var policies []*models.RoutingPolicy
+	if err := s.db.Order("kind, name").Find(&policies).Error; err != nil {
+		return nil, err
+	}
+	return policies, nil
+}
+
+// SavePolicy applies a routing policy to FRR and stores it, replacing any
+// existing policy of the same kind and name. The policy is only stored
+// once FRR has accepted it, so a failed apply is retried by the next save.
+func (s *Service) SavePolicy(ctx context.Context, policy *models.RoutingPolicy) error {
+	if err := s.frrClient.ApplyPolicy(ctx, policy.Kind, policy.Name, policy.Config); err != nil {
+		return fmt.Errorf("%w: %w", ErrFRRApplyFailed, err)
+	}
+
+	var existing models.RoutingPolicy
+	err := s.db.Where("kind = ? AND name = ?", policy.Kind, policy.Name).First(&existing).Error
+	switch {
+	case err == nil:
+		policy.ID = existing.ID
+		policy.CreatedAt = existing.CreatedAt
+		err = s.db.Save(policy).Error
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		err = s.db.Create(policy).Error
+	}
+	if err != nil {
+		return fmt.Errorf("failed to save routing policy: %w", err)
+	}
+
+	s.logger.Info("Saved routing policy",
+		zap.String("kind", policy.Kind),
+		zap.String("name", policy.Name),
+		requestid.Field(ctx),
+	)
+
+	return nil
+}
+
+// DeletePolicy removes a routing policy from FRR and the database
+func (s *Service) DeletePolicy(ctx context.Context, kind, name string) error {
+	var policy models.RoutingPolicy
+	if err := s.db.Where("kind = ? AND name = ?", kind, name).First(&policy).Error; err != nil {
+		return ErrPolicyNotFound
+	}
+
+	if err := s.frrClient.RemovePolicy(ctx, kind, name); err != nil {
+		return fmt.Errorf("%w: %w", ErrFRRApplyFailed, err)
+	}
+
+	if err := s.db.Delete(&policy).Error; err != nil {
+		return fmt.Errorf("failed to delete routing policy: %w", err)
+	}
+
+	s.logger.Info("Deleted routing policy",
+		zap.String("kind", kind),
+		zap.String("name", name),
+		requestid.Field(ctx),
+	)
+
+	return nil
+}
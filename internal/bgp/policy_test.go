@@ -0,0 +1,58 @@
+package bgp
+
+import (
+	"context"
+	"testing"
+
+	"github.com/padminisys/flintroute/internal/frr"
+	"github.com/padminisys/flintroute/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSavePolicy(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Not stored when FRR apply fails", func(t *testing.T) {
+		service := setupTestService(t, ConsistencyEventual)
+
+		err := service.SavePolicy(ctx, &models.RoutingPolicy{
+			Kind:   models.PolicyPrefixList,
+			Name:   "PL-IN",
+			Config: "ip prefix-list PL-IN seq 10 permit 10.0.0.0/8\n",
+		})
+		assert.ErrorIs(t, err, ErrFRRApplyFailed)
+		assert.ErrorIs(t, err, frr.ErrNotConnected)
+
+		policies, err := service.ListPolicies(ctx)
+		require.NoError(t, err)
+		assert.Empty(t, policies)
+	})
+}
+
+func TestDeletePolicy(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Missing policy", func(t *testing.T) {
+		service := setupTestService(t, ConsistencyEventual)
+
+		err := service.DeletePolicy(ctx, models.PolicyRouteMap, "RM-IN")
+		assert.ErrorIs(t, err, ErrPolicyNotFound)
+	})
+
+	t.Run("Kept when FRR removal fails", func(t *testing.T) {
+		service := setupTestService(t, ConsistencyEventual)
+		require.NoError(t, service.db.Create(&models.RoutingPolicy{
+			Kind:   models.PolicyRouteMap,
+			Name:   "RM-IN",
+			Config: "route-map RM-IN permit 10\n",
+		}).Error)
+
+		err := service.DeletePolicy(ctx, models.PolicyRouteMap, "RM-IN")
+		assert.ErrorIs(t, err, frr.ErrNotConnected)
+
+		policies, err := service.ListPolicies(ctx)
+		require.NoError(t, err)
+		assert.Len(t, policies, 1)
+	})
+}
@@ -0,0 +1,453 @@
+package bgp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/padminisys/flintroute/internal/frr"
+	"github.com/padminisys/flintroute/internal/repository"
+	"github.com/padminisys/flintroute/internal/tenancy"
+	"github.com/padminisys/flintroute/pkg/models"
+	"go.uber.org/zap"
+)
+
+// Alert types raised for peers becoming unreachable, and reachable again,
+// at the IP layer
+const (
+	AlertPeerUnreachable = "peer_unreachable"
+	AlertPeerReachable   = "peer_reachable"
+)
+
+// Methods of probing peer addresses
+const (
+	// ReachabilityICMP pings peers
+	ReachabilityICMP = "icmp"
+	// ReachabilityTCP connects to peers' BGP port; a refused connection
+	// still shows the peer host is reachable
+	ReachabilityTCP = "tcp"
+)
+
+// What a peer's last probe round suggests is wrong with it
+const (
+	DiagnosisOK = "ok"
+	// DiagnosisDegraded means some probes were lost, fewer than fail a round
+	DiagnosisDegraded = "degraded"
+	// DiagnosisBGPDown means the peer answers probes but its session isn't
+	// established, pointing at BGP configuration rather than the network
+	DiagnosisBGPDown = "bgp_down"
+	// DiagnosisUnreachable means the round failed: the peer is unreachable
+	// at the IP layer
+	DiagnosisUnreachable = "ip_unreachable"
+	// DiagnosisUnknown means the peer couldn't be probed, e.g. because
+	// ICMP isn't permitted on the FlintRoute host
+	DiagnosisUnknown = "unknown"
+)
+
+// maxConcurrentProbes caps how many peers are probed at the same time
+const maxConcurrentProbes = 16
+
+// ReachabilityProbe sends one probe to ip by method, returning the round
+// trip time, or why the probe was lost: a frr.Probe* result such as
+// "timeout" or an error message
+type ReachabilityProbe func(ctx context.Context, method, ip string, timeout time.Duration) (time.Duration, string)
+
+// ReachabilityPolicy configures probing peer addresses independently of BGP
+type ReachabilityPolicy struct {
+	// Method is ReachabilityICMP or ReachabilityTCP. Defaults to ICMP.
+	Method string
+	// Count is how many probes each round sends to a peer. Defaults to 3.
+	Count int
+	// Timeout bounds each probe. Defaults to 2 seconds.
+	Timeout time.Duration
+	// LossThresholdPercent is the share of probes lost that fails a round.
+	// Defaults to 100, failing rounds without any reply.
+	LossThresholdPercent float64
+	// Failures is how many failed rounds in a row raise a peer_unreachable
+	// alert. Defaults to 3.
+	Failures int
+	// Retention is how long samples are kept. Defaults to a day.
+	Retention time.Duration
+	// Probe sends the probes. Defaults to pinging or connecting for real.
+	Probe ReachabilityProbe
+}
+
+// withDefaults fills in unset fields
+func (p ReachabilityPolicy) withDefaults() ReachabilityPolicy {
+	if p.Method != ReachabilityTCP {
+		p.Method = ReachabilityICMP
+	}
+	if p.Count <= 0 {
+		p.Count = 3
+	}
+	if p.Timeout <= 0 {
+		p.Timeout = 2 * time.Second
+	}
+	if p.LossThresholdPercent <= 0 || p.LossThresholdPercent > 100 {
+		p.LossThresholdPercent = 100
+	}
+	if p.Failures <= 0 {
+		p.Failures = 3
+	}
+	if p.Retention <= 0 {
+		p.Retention = 24 * time.Hour
+	}
+	if p.Probe == nil {
+		p.Probe = probeReachability
+	}
+	return p
+}
+
+// PeerReachability is the outcome of a peer's last probe round, alongside
+// its BGP session state
+type PeerReachability struct {
+	PeerID      uint    `json:"peer_id"`
+	Name        string  `json:"name"`
+	IPAddress   string  `json:"ip_address"`
+	Sent        int     `json:"sent"`
+	Received    int     `json:"received"`
+	LossPercent float64 `json:"loss_percent"`
+	AvgMS       float64 `json:"avg_ms"`
+	Error       string  `json:"error,omitempty"`
+	// ConsecutiveFailures counts the failed rounds up to this one
+	ConsecutiveFailures int    `json:"consecutive_failures"`
+	SessionState        string `json:"session_state"`
+	Diagnosis           string `json:"diagnosis"`
+
+	tenantID *uint // the peer's tenant
+}
+
+// ReachabilityReport is the result of a probe round of every enabled peer
+type ReachabilityReport struct {
+	ProbedAt time.Time `json:"probed_at"`
+	Method   string    `json:"method"`
+	// Unreachable is how many peers failed the round
+	Unreachable int                 `json:"unreachable"`
+	Peers       []*PeerReachability `json:"peers"`
+}
+
+// forTenant returns the part of the report covering the peers of a tenant
+func (r *ReachabilityReport) forTenant(id uint) *ReachabilityReport {
+	report := &ReachabilityReport{ProbedAt: r.ProbedAt, Method: r.Method, Peers: []*PeerReachability{}}
+	for _, peer := range r.Peers {
+		if peer.tenantID == nil || *peer.tenantID != id {
+			continue
+		}
+		report.Peers = append(report.Peers, peer)
+		if peer.Diagnosis == DiagnosisUnreachable {
+			report.Unreachable++
+		}
+	}
+	return report
+}
+
+// ProbeReachability probes the address of every enabled peer not under
+// maintenance, stores a sample per peer and raises a peer_unreachable alert
+// for a peer whose rounds failed Failures times in a row, and a
+// peer_reachable alert once it answers again. Samples older than the
+// retention are pruned. Only the peers of the tenant ctx acts for are
+// probed.
+func (s *Service) ProbeReachability(ctx context.Context) (*ReachabilityReport, error) {
+	policy := s.config.Reachability.withDefaults()
+	now := time.Now()
+
+	peers, err := s.ListPeers(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var sessions []models.BGPSession
+	if err := s.db.WithContext(ctx).Scopes(tenancy.PeerScope(ctx, "peer_id")).Find(&sessions).Error; err != nil {
+		return nil, err
+	}
+	states := make(map[uint]string, len(sessions))
+	for _, session := range sessions {
+		states[session.PeerID] = session.State
+	}
+
+	var probed []*models.BGPPeer
+	for _, peer := range peers {
+		if peer.Enabled && !peer.InMaintenance(now) {
+			probed = append(probed, peer)
+		}
+	}
+
+	samples := make([]*models.ReachabilitySample, len(probed))
+	var wg sync.WaitGroup
+	slots := make(chan struct{}, maxConcurrentProbes)
+	for i, peer := range probed {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			slots <- struct{}{}
+			defer func() { <-slots }()
+			samples[i] = probePeerAddress(ctx, policy, peer.IPAddress)
+		}()
+	}
+	wg.Wait()
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	report := &ReachabilityReport{ProbedAt: now, Method: policy.Method, Peers: []*PeerReachability{}}
+	unsupported := false
+	for i, peer := range probed {
+		sample := samples[i]
+		sample.PeerID = peer.ID
+		if err := s.db.WithContext(ctx).Create(sample).Error; err != nil {
+			return nil, fmt.Errorf("failed to store reachability sample: %w", err)
+		}
+
+		failures, previous, err := s.reachabilityFailures(ctx, peer.ID, policy)
+		if err != nil {
+			return nil, err
+		}
+		state := states[peer.ID]
+		if state == "" {
+			state = repository.UnknownState
+		}
+		result := &PeerReachability{
+			PeerID:              peer.ID,
+			Name:                peer.Name,
+			IPAddress:           peer.IPAddress,
+			Sent:                sample.Sent,
+			Received:            sample.Received,
+			LossPercent:         sample.LossPercent,
+			AvgMS:               sample.AvgMS,
+			Error:               sample.Error,
+			ConsecutiveFailures: failures,
+			SessionState:        state,
+			Diagnosis:           diagnose(sample, state, policy),
+			tenantID:            peer.TenantID,
+		}
+		report.Peers = append(report.Peers, result)
+
+		switch {
+		case result.Diagnosis == DiagnosisUnknown:
+			unsupported = true
+		case result.Diagnosis == DiagnosisUnreachable:
+			report.Unreachable++
+			if failures == policy.Failures {
+				s.createReachabilityAlert(ctx, peer, result, policy)
+			}
+		case previous >= policy.Failures:
+			s.createReachabilityAlert(ctx, peer, result, policy)
+		}
+	}
+	if unsupported {
+		s.logger.Warn("Peers couldn't be probed; allow unprivileged ping sockets or CAP_NET_RAW, or probe by TCP",
+			zap.String("method", policy.Method))
+	}
+
+	if err := s.db.WithContext(ctx).Where("created_at < ?", now.Add(-policy.Retention)).
+		Delete(&models.ReachabilitySample{}).Error; err != nil {
+		s.logger.Warn("Failed to prune reachability samples", zap.Error(err))
+	}
+
+	s.reachabilityMu.Lock()
+	if id, ok := tenancy.FromContext(ctx); ok {
+		if s.tenantReachability == nil {
+			s.tenantReachability = make(map[uint]*ReachabilityReport)
+		}
+		s.tenantReachability[id] = report
+	} else {
+		s.lastReachability = report
+	}
+	s.reachabilityMu.Unlock()
+
+	return report, nil
+}
+
+// LastReachabilityReport returns the report of the most recent probe round.
+// For a tenant it is the most recent round of the tenant's peers, or the
+// tenant's part of the most recent round of every peer.
+func (s *Service) LastReachabilityReport(ctx context.Context) *ReachabilityReport {
+	s.reachabilityMu.Lock()
+	defer s.reachabilityMu.Unlock()
+
+	id, ok := tenancy.FromContext(ctx)
+	if !ok {
+		return s.lastReachability
+	}
+	report := s.tenantReachability[id]
+	if s.lastReachability != nil && (report == nil || s.lastReachability.ProbedAt.After(report.ProbedAt)) {
+		report = s.lastReachability.forTenant(id)
+	}
+	return report
+}
+
+// PeerReachabilityHistory returns a peer's reachability samples taken since
+// since, oldest first
+func (s *Service) PeerReachabilityHistory(ctx context.Context, id uint, since time.Time) ([]models.ReachabilitySample, error) {
+	if _, err := s.GetPeer(ctx, id); err != nil {
+		return nil, err
+	}
+
+	samples := []models.ReachabilitySample{}
+	err := s.db.WithContext(ctx).
+		Where("peer_id = ? AND created_at >= ?", id, since).
+		Order("created_at").Order("id").
+		Find(&samples).Error
+	return samples, err
+}
+
+// StartReachabilityMonitor probes peer addresses every interval until ctx
+// is cancelled
+func (s *Service) StartReachabilityMonitor(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	policy := s.config.Reachability.withDefaults()
+	s.logger.Info("Started peer reachability monitoring",
+		zap.Duration("interval", interval),
+		zap.String("method", policy.Method),
+		zap.Int("count", policy.Count),
+	)
+
+	for {
+		select {
+		case <-ctx.Done():
+			s.logger.Info("Stopped peer reachability monitoring")
+			return
+		case <-ticker.C:
+			if _, err := s.ProbeReachability(ctx); err != nil && ctx.Err() == nil {
+				s.logger.Error("Failed to probe peer reachability", zap.Error(err))
+			}
+		}
+	}
+}
+
+// probePeerAddress sends a round of probes to ip, one after another
+func probePeerAddress(ctx context.Context, policy ReachabilityPolicy, ip string) *models.ReachabilitySample {
+	sample := &models.ReachabilitySample{Method: policy.Method, Sent: policy.Count}
+	var total float64
+	for range policy.Count {
+		rtt, failure := policy.Probe(ctx, policy.Method, ip, policy.Timeout)
+		if failure != "" {
+			sample.Error = failure
+			continue
+		}
+		ms := float64(rtt.Microseconds()) / 1000
+		if sample.Received == 0 || ms < sample.MinMS {
+			sample.MinMS = ms
+		}
+		sample.MaxMS = max(sample.MaxMS, ms)
+		total += ms
+		sample.Received++
+	}
+	if sample.Received > 0 {
+		sample.AvgMS = math.Round(total/float64(sample.Received)*1000) / 1000
+	}
+	sample.LossPercent = math.Round(float64(sample.Sent-sample.Received)*1000/float64(sample.Sent)) / 10
+	return sample
+}
+
+// probeReachability sends one probe for real
+func probeReachability(ctx context.Context, method, ip string, timeout time.Duration) (time.Duration, string) {
+	if method == ReachabilityTCP {
+		probe := frr.ProbeTCP(ctx, ip, frr.BGPPort, timeout)
+		if probe.Result == frr.ProbeOpen || probe.Result == frr.ProbeRefused {
+			return time.Duration(probe.LatencyMS * float64(time.Millisecond)), ""
+		}
+		return 0, probe.Result
+	}
+
+	rtt, err := frr.Ping(ctx, ip, timeout)
+	var netErr interface{ Timeout() bool }
+	switch {
+	case err == nil:
+		return rtt, ""
+	case errors.Is(err, frr.ErrPingUnsupported):
+		return 0, frr.ProbeUnsupported
+	case errors.Is(err, syscall.EHOSTUNREACH), errors.Is(err, syscall.ENETUNREACH):
+		return 0, frr.ProbeUnreachable
+	case errors.As(err, &netErr) && netErr.Timeout(), errors.Is(err, context.DeadlineExceeded):
+		return 0, frr.ProbeTimeout
+	default:
+		return 0, err.Error()
+	}
+}
+
+// roundFailed reports whether a sample's round failed. Rounds that
+// couldn't probe at all are inconclusive rather than failed.
+func roundFailed(sample *models.ReachabilitySample, policy ReachabilityPolicy) bool {
+	return sample.Error != frr.ProbeUnsupported && sample.LossPercent >= policy.LossThresholdPercent
+}
+
+// diagnose tells what a peer's latest sample and session state suggest
+func diagnose(sample *models.ReachabilitySample, state string, policy ReachabilityPolicy) string {
+	switch {
+	case sample.Received == 0 && sample.Error == frr.ProbeUnsupported:
+		return DiagnosisUnknown
+	case roundFailed(sample, policy):
+		return DiagnosisUnreachable
+	case state != "Established":
+		return DiagnosisBGPDown
+	case sample.Received < sample.Sent:
+		return DiagnosisDegraded
+	}
+	return DiagnosisOK
+}
+
+// reachabilityFailures counts a peer's failed rounds in a row up to its
+// latest sample and, when the latest succeeded, the failed rounds in a row
+// before it
+func (s *Service) reachabilityFailures(ctx context.Context, peerID uint, policy ReachabilityPolicy) (int, int, error) {
+	var samples []*models.ReachabilitySample
+	if err := s.db.WithContext(ctx).
+		Where("peer_id = ?", peerID).
+		Order("created_at DESC").Order("id DESC").
+		Limit(policy.Failures + 1).
+		Find(&samples).Error; err != nil {
+		return 0, 0, err
+	}
+
+	count := func(samples []*models.ReachabilitySample) int {
+		n := 0
+		for n < len(samples) && roundFailed(samples[n], policy) {
+			n++
+		}
+		return n
+	}
+	failures := count(samples)
+	if failures > 0 || len(samples) == 0 {
+		return failures, 0, nil
+	}
+	return 0, count(samples[1:]), nil
+}
+
+// createReachabilityAlert raises a peer_unreachable alert for a peer that
+// failed enough rounds in a row, or a peer_reachable alert for one that
+// answers again
+func (s *Service) createReachabilityAlert(ctx context.Context, peer *models.BGPPeer, result *PeerReachability, policy ReachabilityPolicy) {
+	alert := models.Alert{
+		Type:     AlertPeerReachable,
+		Severity: "info",
+		Message:  fmt.Sprintf("BGP peer %s (%s) is reachable at the IP layer again", peer.Name, peer.IPAddress),
+		Details: fmt.Sprintf("%d of %d %s probes answered, %.1f ms on average; the BGP session is %s",
+			result.Received, result.Sent, policy.Method, result.AvgMS, result.SessionState),
+		PeerID: &peer.ID,
+		Peer:   peer,
+	}
+	if result.Diagnosis == DiagnosisUnreachable {
+		alert.Type = AlertPeerUnreachable
+		alert.Severity = "critical"
+		alert.Message = fmt.Sprintf("BGP peer %s (%s) is unreachable at the IP layer", peer.Name, peer.IPAddress)
+		alert.Details = fmt.Sprintf("%.0f%% of %s probes lost in %d rounds in a row (last error: %s); the BGP session is %s. "+
+			"Check routing and filtering to the peer before its BGP configuration.",
+			result.LossPercent, policy.Method, result.ConsecutiveFailures, result.Error, result.SessionState)
+	}
+	if !s.raiseAlert(ctx, &alert, true) {
+		return
+	}
+
+	s.logger.Warn("Peer reachability changed",
+		zap.String("peer", peer.Name),
+		zap.String("alert", alert.Type),
+		zap.Float64("loss_percent", result.LossPercent),
+		zap.String("session_state", result.SessionState),
+	)
+}
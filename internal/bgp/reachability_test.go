@@ -0,0 +1,166 @@
+package bgp
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/padminisys/flintroute/internal/frr"
+	"github.com/padminisys/flintroute/internal/tenancy"
+	"github.com/padminisys/flintroute/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeProbes answers probes by IP address: addresses marked down lose every
+// probe, others answer in 2ms
+type fakeProbes struct {
+	mu   sync.Mutex
+	down map[string]string
+}
+
+func (f *fakeProbes) set(ip, failure string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.down[ip] = failure
+}
+
+func (f *fakeProbes) probe(ctx context.Context, method, ip string, timeout time.Duration) (time.Duration, string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if failure := f.down[ip]; failure != "" {
+		return 0, failure
+	}
+	return 2 * time.Millisecond, ""
+}
+
+func TestProbeReachability(t *testing.T) {
+	ctx := context.Background()
+	service := setupTestService(t, ConsistencyEventual)
+	probes := &fakeProbes{down: map[string]string{}}
+	service.config.Reachability = ReachabilityPolicy{Method: ReachabilityTCP, Count: 2, Failures: 2, Probe: probes.probe}
+
+	up := newTestPeer("192.0.2.1", true)
+	idle := newTestPeer("192.0.2.2", true)
+	down := newTestPeer("192.0.2.3", true)
+	disabled := newTestPeer("192.0.2.4", false)
+	for _, peer := range []*models.BGPPeer{up, idle, down, disabled} {
+		require.NoError(t, service.db.Create(peer).Error)
+	}
+	require.NoError(t, service.db.Model(disabled).Update("enabled", false).Error)
+	require.NoError(t, service.db.Create(&models.BGPSession{PeerID: up.ID, State: "Established"}).Error)
+	require.NoError(t, service.db.Create(&models.BGPSession{PeerID: idle.ID, State: "Active"}).Error)
+	probes.set(down.IPAddress, frr.ProbeTimeout)
+
+	alertCount := func(alertType string) int64 {
+		var count int64
+		require.NoError(t, service.db.Model(&models.Alert{}).Where("type = ?", alertType).Count(&count).Error)
+		return count
+	}
+
+	t.Run("Diagnoses peers", func(t *testing.T) {
+		report, err := service.ProbeReachability(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, ReachabilityTCP, report.Method)
+		assert.Equal(t, 1, report.Unreachable)
+		require.Len(t, report.Peers, 3)
+
+		assert.Equal(t, DiagnosisOK, report.Peers[0].Diagnosis)
+		assert.Equal(t, 2, report.Peers[0].Received)
+		assert.Equal(t, 2.0, report.Peers[0].AvgMS)
+		assert.Equal(t, DiagnosisBGPDown, report.Peers[1].Diagnosis)
+		assert.Equal(t, "Active", report.Peers[1].SessionState)
+		assert.Equal(t, DiagnosisUnreachable, report.Peers[2].Diagnosis)
+		assert.Equal(t, 100.0, report.Peers[2].LossPercent)
+		assert.Equal(t, frr.ProbeTimeout, report.Peers[2].Error)
+		assert.Equal(t, "Unknown", report.Peers[2].SessionState)
+		assert.Equal(t, 1, report.Peers[2].ConsecutiveFailures)
+		assert.Same(t, report, service.LastReachabilityReport(ctx))
+
+		assert.Zero(t, alertCount(AlertPeerUnreachable), "one failed round is below the threshold")
+	})
+
+	t.Run("Alerts after failures in a row", func(t *testing.T) {
+		report, err := service.ProbeReachability(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, 2, report.Peers[2].ConsecutiveFailures)
+
+		var alert models.Alert
+		require.NoError(t, service.db.Where("type = ?", AlertPeerUnreachable).First(&alert).Error)
+		assert.Equal(t, "critical", alert.Severity)
+		assert.Equal(t, down.ID, *alert.PeerID)
+		assert.Contains(t, alert.Details, "100% of tcp probes lost in 2 rounds in a row (last error: timeout)")
+
+		_, err = service.ProbeReachability(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), alertCount(AlertPeerUnreachable), "alerts once per outage")
+	})
+
+	t.Run("Alerts on recovery", func(t *testing.T) {
+		probes.set(down.IPAddress, "")
+		report, err := service.ProbeReachability(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, 0, report.Unreachable)
+		assert.Equal(t, int64(1), alertCount(AlertPeerReachable))
+
+		_, err = service.ProbeReachability(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), alertCount(AlertPeerReachable))
+	})
+
+	t.Run("Inconclusive without ICMP", func(t *testing.T) {
+		probes.set(up.IPAddress, frr.ProbeUnsupported)
+		report, err := service.ProbeReachability(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, DiagnosisUnknown, report.Peers[0].Diagnosis)
+		assert.Equal(t, 0, report.Unreachable)
+	})
+
+	t.Run("History", func(t *testing.T) {
+		samples, err := service.PeerReachabilityHistory(ctx, down.ID, time.Now().Add(-time.Hour))
+		require.NoError(t, err)
+		require.Len(t, samples, 6)
+		assert.Equal(t, 0, samples[0].Received)
+		assert.Equal(t, 2, samples[5].Received)
+
+		_, err = service.PeerReachabilityHistory(ctx, 9999, time.Time{})
+		assert.ErrorIs(t, err, ErrPeerNotFound)
+	})
+
+	t.Run("Prunes old samples", func(t *testing.T) {
+		require.NoError(t, service.db.Model(&models.ReachabilitySample{}).
+			Where("peer_id = ?", down.ID).
+			Update("created_at", time.Now().Add(-48*time.Hour)).Error)
+		_, err := service.ProbeReachability(ctx)
+		require.NoError(t, err)
+
+		var count int64
+		require.NoError(t, service.db.Model(&models.ReachabilitySample{}).Where("peer_id = ?", down.ID).Count(&count).Error)
+		assert.Equal(t, int64(1), count)
+	})
+
+	t.Run("Rounds of a tenant's peers", func(t *testing.T) {
+		tenant := uint(7)
+		require.NoError(t, service.db.Model(&models.BGPPeer{}).
+			Where("id IN ?", []uint{up.ID, idle.ID}).Update("tenant_id", tenant).Error)
+		tenantCtx := tenancy.WithTenant(ctx, tenant)
+
+		global, err := service.ProbeReachability(ctx)
+		require.NoError(t, err)
+		require.Len(t, global.Peers, 3)
+		// The tenant sees its part of the round of every peer
+		last := service.LastReachabilityReport(tenantCtx)
+		require.NotNil(t, last)
+		require.Len(t, last.Peers, 2)
+		assert.Equal(t, up.ID, last.Peers[0].PeerID)
+		assert.Equal(t, idle.ID, last.Peers[1].PeerID)
+
+		report, err := service.ProbeReachability(tenantCtx)
+		require.NoError(t, err)
+		require.Len(t, report.Peers, 2, "only the tenant's peers are probed")
+		assert.Same(t, report, service.LastReachabilityReport(tenantCtx))
+		assert.Same(t, global, service.LastReachabilityReport(ctx), "a tenant's round leaves the round of every peer")
+		assert.Empty(t, service.LastReachabilityReport(tenancy.WithTenant(ctx, 8)).Peers)
+	})
+}
@@ -0,0 +1,276 @@
+package bgp
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/padminisys/flintroute/internal/frr"
+	"github.com/padminisys/flintroute/internal/tenancy"
+	"github.com/padminisys/flintroute/pkg/models"
+	"go.uber.org/zap"
+)
+
+// Drift kinds reported by the reconciler
+const (
+	DriftMissing    = "missing"    // enabled in the database, absent from FRR
+	DriftUnknown    = "unknown"    // present in FRR, not managed by FlintRoute
+	DriftMismatched = "mismatched" // present in both, but FRR differs from intent
+)
+
+// DriftEntry describes a single difference between intent and FRR state
+type DriftEntry struct {
+	Kind      string `json:"kind"`
+	PeerID    *uint  `json:"peer_id,omitempty"`
+	IPAddress string `json:"ip_address"`
+	Expected  string `json:"expected,omitempty"`
+	Actual    string `json:"actual,omitempty"`
+	Healed    bool   `json:"healed"`
+}
+
+// DriftReport is the result of comparing stored peers with FRR's configuration
+type DriftReport struct {
+	CheckedAt time.Time     `json:"checked_at"`
+	InSync    bool          `json:"in_sync"`
+	Entries   []*DriftEntry `json:"entries"`
+}
+
+// key identifies a drift entry across reconciliation runs
+func (e *DriftEntry) key() string {
+	return e.Kind + "/" + e.IPAddress
+}
+
+// DetectDrift compares the peers stored in the database with the neighbors
+// present in FRR's running configuration. For a tenant only its peers are
+// compared, and neighbors FlintRoute doesn't manage, which belong to no
+// tenant, aren't reported.
+func (s *Service) DetectDrift(ctx context.Context) (*DriftReport, error) {
+	peers, err := s.ListPeers(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	neighbors, err := s.frrClient.ListBGPNeighbors(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	report := compareWithFRR(peers, neighbors)
+	if _, ok := tenancy.FromContext(ctx); ok {
+		entries := []*DriftEntry{}
+		for _, entry := range report.Entries {
+			if entry.Kind != DriftUnknown {
+				entries = append(entries, entry)
+			}
+		}
+		report.Entries = entries
+		report.InSync = len(entries) == 0
+	}
+	return report, nil
+}
+
+// compareWithFRR builds a drift report from stored peers and FRR neighbors
+func compareWithFRR(peers []*models.BGPPeer, neighbors []*frr.BGPNeighbor) *DriftReport {
+	actual := make(map[string]*frr.BGPNeighbor, len(neighbors))
+	for _, n := range neighbors {
+		actual[n.IPAddress] = n
+	}
+
+	report := &DriftReport{
+		CheckedAt: time.Now(),
+		Entries:   []*DriftEntry{},
+	}
+
+	managed := make(map[string]bool, len(peers))
+	for _, peer := range peers {
+		managed[peer.IPAddress] = true
+		id := peer.ID
+		neighbor, present := actual[peer.IPAddress]
+
+		switch {
+		case peer.Enabled && !present:
+			report.Entries = append(report.Entries, &DriftEntry{
+				Kind:      DriftMissing,
+				PeerID:    &id,
+				IPAddress: peer.IPAddress,
+				Expected:  fmt.Sprintf("remote-as %d", peer.RemoteASN),
+			})
+		case !peer.Enabled && present:
+			report.Entries = append(report.Entries, &DriftEntry{
+				Kind:      DriftMismatched,
+				PeerID:    &id,
+				IPAddress: peer.IPAddress,
+				Expected:  "disabled",
+				Actual:    fmt.Sprintf("remote-as %d", neighbor.RemoteASN),
+			})
+		case present && neighbor.RemoteASN != peer.RemoteASN:
+			report.Entries = append(report.Entries, &DriftEntry{
+				Kind:      DriftMismatched,
+				PeerID:    &id,
+				IPAddress: peer.IPAddress,
+				Expected:  fmt.Sprintf("remote-as %d", peer.RemoteASN),
+				Actual:    fmt.Sprintf("remote-as %d", neighbor.RemoteASN),
+			})
+		case present && neighbor.Description != frrDescription(peer):
+			report.Entries = append(report.Entries, &DriftEntry{
+				Kind:      DriftMismatched,
+				PeerID:    &id,
+				IPAddress: peer.IPAddress,
+				Expected:  fmt.Sprintf("description %q", frrDescription(peer)),
+				Actual:    fmt.Sprintf("description %q", neighbor.Description),
+			})
+		}
+	}
+
+	for _, n := range neighbors {
+		if managed[n.IPAddress] {
+			continue
+		}
+		report.Entries = append(report.Entries, &DriftEntry{
+			Kind:      DriftUnknown,
+			IPAddress: n.IPAddress,
+			Actual:    fmt.Sprintf("remote-as %d", n.RemoteASN),
+		})
+	}
+
+	report.InSync = len(report.Entries) == 0
+	return report
+}
+
+// Reconcile detects drift, raises alerts for newly detected differences and,
+// when auto-heal is enabled, re-applies stored peers to FRR. Neighbors not
+// managed by FlintRoute are only flagged, never removed.
+func (s *Service) Reconcile(ctx context.Context) (*DriftReport, error) {
+	report, err := s.DetectDrift(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if s.config.AutoHeal {
+		for _, entry := range report.Entries {
+			s.healDrift(ctx, entry)
+		}
+	}
+
+	s.driftMu.Lock()
+	previous := s.lastDrift
+	s.lastDrift = report
+	s.driftMu.Unlock()
+
+	s.alertNewDrift(ctx, previous, report.Entries)
+
+	if !report.InSync {
+		s.logger.Warn("Detected drift between database and FRR",
+			zap.Int("entries", len(report.Entries)),
+		)
+	}
+
+	return report, nil
+}
+
+// LastDriftReport returns the report of the most recent reconciliation run
+func (s *Service) LastDriftReport() *DriftReport {
+	s.driftMu.Lock()
+	defer s.driftMu.Unlock()
+	return s.lastDrift
+}
+
+// alertNewDrift raises alerts for entries not present in the previous report,
+// so persistent drift is reported once rather than on every run
+func (s *Service) alertNewDrift(ctx context.Context, previous *DriftReport, entries []*DriftEntry) {
+	seen := make(map[string]bool)
+	if previous != nil {
+		for _, entry := range previous.Entries {
+			seen[entry.key()] = true
+		}
+	}
+
+	for _, entry := range entries {
+		if !seen[entry.key()] {
+			s.createDriftAlert(ctx, entry)
+		}
+	}
+}
+
+// healDrift re-applies the stored intent for a drifted peer
+func (s *Service) healDrift(ctx context.Context, entry *DriftEntry) {
+	if entry.PeerID == nil {
+		return
+	}
+
+	peer, err := s.GetPeer(ctx, *entry.PeerID)
+	if err != nil {
+		s.logger.Error("Failed to load peer for healing", zap.Uint("id", *entry.PeerID), zap.Error(err))
+		return
+	}
+
+	switch {
+	case entry.Kind == DriftMissing:
+		err = s.addToFRR(ctx, peer)
+	case !peer.Enabled:
+		err = s.removeFromFRR(ctx, peer)
+	default:
+		err = s.updateInFRR(ctx, peer)
+	}
+
+	if err != nil {
+		s.logger.Error("Failed to heal drifted peer",
+			zap.String("ip", peer.IPAddress),
+			zap.Error(err),
+		)
+		return
+	}
+
+	entry.Healed = true
+	s.logger.Info("Healed drifted peer",
+		zap.String("ip", peer.IPAddress),
+		zap.String("kind", entry.Kind),
+	)
+}
+
+// createDriftAlert creates an alert for a drift entry
+func (s *Service) createDriftAlert(ctx context.Context, entry *DriftEntry) {
+	var message string
+	switch entry.Kind {
+	case DriftMissing:
+		message = fmt.Sprintf("BGP peer %s is missing from FRR configuration", entry.IPAddress)
+	case DriftUnknown:
+		message = fmt.Sprintf("BGP neighbor %s is configured in FRR but not managed by FlintRoute", entry.IPAddress)
+	default:
+		message = fmt.Sprintf("BGP peer %s differs in FRR: expected %s, found %s", entry.IPAddress, entry.Expected, entry.Actual)
+	}
+	if entry.Healed {
+		message += " (auto-healed)"
+	}
+
+	alert := models.Alert{
+		Type:     "config_drift",
+		Severity: "warning",
+		Message:  message,
+		PeerID:   entry.PeerID,
+	}
+	s.raiseAlert(ctx, &alert, false)
+}
+
+// StartReconciler periodically reconciles stored peers with FRR
+func (s *Service) StartReconciler(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	s.logger.Info("Started FRR reconciler",
+		zap.Duration("interval", interval),
+		zap.Bool("auto_heal", s.config.AutoHeal),
+	)
+
+	for {
+		select {
+		case <-ctx.Done():
+			s.logger.Info("Stopped FRR reconciler")
+			return
+		case <-ticker.C:
+			if _, err := s.Reconcile(ctx); err != nil {
+				s.logger.Error("Failed to reconcile with FRR", zap.Error(err))
+			}
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package bgp
+
+import (
+	"context"
+	"testing"
+
+	"github.com/padminisys/flintroute/internal/frr"
+	"github.com/padminisys/flintroute/internal/tenancy"
+	"github.com/padminisys/flintroute/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompareWithFRR(t *testing.T) {
+	peer := func(id uint, ip string, remoteASN uint32, enabled bool) *models.BGPPeer {
+		p := newTestPeer(ip, enabled)
+		p.ID = id
+		p.RemoteASN = remoteASN
+		return p
+	}
+
+	t.Run("In sync", func(t *testing.T) {
+		report := compareWithFRR(
+			[]*models.BGPPeer{peer(1, "10.0.0.1", 65002, true), peer(2, "10.0.0.2", 65003, false)},
+			[]*frr.BGPNeighbor{{IPAddress: "10.0.0.1", RemoteASN: 65002, Description: "Peer 10.0.0.1"}},
+		)
+
+		assert.True(t, report.InSync)
+		assert.Empty(t, report.Entries)
+	})
+
+	t.Run("Missing from FRR", func(t *testing.T) {
+		report := compareWithFRR([]*models.BGPPeer{peer(1, "10.0.0.1", 65002, true)}, nil)
+
+		require.Len(t, report.Entries, 1)
+		assert.False(t, report.InSync)
+		assert.Equal(t, DriftMissing, report.Entries[0].Kind)
+		require.NotNil(t, report.Entries[0].PeerID)
+		assert.Equal(t, uint(1), *report.Entries[0].PeerID)
+	})
+
+	t.Run("Remote ASN mismatch", func(t *testing.T) {
+		report := compareWithFRR(
+			[]*models.BGPPeer{peer(1, "10.0.0.1", 65002, true)},
+			[]*frr.BGPNeighbor{{IPAddress: "10.0.0.1", RemoteASN: 65099}},
+		)
+
+		require.Len(t, report.Entries, 1)
+		assert.Equal(t, DriftMismatched, report.Entries[0].Kind)
+		assert.Equal(t, "remote-as 65002", report.Entries[0].Expected)
+		assert.Equal(t, "remote-as 65099", report.Entries[0].Actual)
+	})
+
+	t.Run("Description mismatch", func(t *testing.T) {
+		upstream := peer(1, "10.0.0.1", 65002, true)
+		upstream.Description = "Upstream\nTransit"
+		report := compareWithFRR(
+			[]*models.BGPPeer{upstream},
+			[]*frr.BGPNeighbor{{IPAddress: "10.0.0.1", RemoteASN: 65002, Description: "Peer 10.0.0.1"}},
+		)
+
+		require.Len(t, report.Entries, 1)
+		assert.Equal(t, DriftMismatched, report.Entries[0].Kind)
+		assert.Equal(t, `description "Upstream Transit"`, report.Entries[0].Expected)
+		assert.Equal(t, `description "Peer 10.0.0.1"`, report.Entries[0].Actual)
+	})
+
+	t.Run("Disabled peer present in FRR", func(t *testing.T) {
+		report := compareWithFRR(
+			[]*models.BGPPeer{peer(1, "10.0.0.1", 65002, false)},
+			[]*frr.BGPNeighbor{{IPAddress: "10.0.0.1", RemoteASN: 65002}},
+		)
+
+		require.Len(t, report.Entries, 1)
+		assert.Equal(t, DriftMismatched, report.Entries[0].Kind)
+		assert.Equal(t, "disabled", report.Entries[0].Expected)
+	})
+
+	t.Run("Unknown neighbor", func(t *testing.T) {
+		report := compareWithFRR(nil, []*frr.BGPNeighbor{{IPAddress: "192.0.2.1", RemoteASN: 64512}})
+
+		require.Len(t, report.Entries, 1)
+		assert.Equal(t, DriftUnknown, report.Entries[0].Kind)
+		assert.Nil(t, report.Entries[0].PeerID)
+	})
+}
+
+func TestDetectDrift(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Tenants see drift of their peers only", func(t *testing.T) {
+		service := setupTestService(t, ConsistencyEventual)
+		require.NoError(t, service.CreatePeer(tenancy.WithTenant(ctx, 3), newTestPeer("10.0.0.1", true)))
+		require.NoError(t, service.CreatePeer(tenancy.WithTenant(ctx, 4), newTestPeer("10.0.0.2", true)))
+
+		client := frr.NewMockClient()
+		client.On("ListBGPNeighbors", mock.Anything).Return([]*frr.BGPNeighbor{{IPAddress: "192.0.2.1", RemoteASN: 64512}}, nil)
+		service.frrClient = client
+
+		report, err := service.DetectDrift(tenancy.WithTenant(ctx, 3))
+		require.NoError(t, err)
+		require.Len(t, report.Entries, 1)
+		assert.False(t, report.InSync)
+		assert.Equal(t, DriftMissing, report.Entries[0].Kind)
+		assert.Equal(t, "10.0.0.1", report.Entries[0].IPAddress)
+
+		// Across tenants, the unmanaged neighbor is reported too
+		report, err = service.DetectDrift(ctx)
+		require.NoError(t, err)
+		assert.Len(t, report.Entries, 3)
+	})
+}
+
+func TestReconcile(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("FRR unavailable", func(t *testing.T) {
+		service := setupTestService(t, ConsistencyEventual)
+
+		report, err := service.Reconcile(ctx)
+		assert.ErrorIs(t, err, frr.ErrNotConnected)
+		assert.Nil(t, report)
+		assert.Nil(t, service.LastDriftReport())
+	})
+
+	t.Run("Only new drift raises alerts", func(t *testing.T) {
+		service := setupTestService(t, ConsistencyEventual)
+		entry := &DriftEntry{Kind: DriftUnknown, IPAddress: "192.0.2.1", Actual: "remote-as 64512"}
+
+		first := &DriftReport{Entries: []*DriftEntry{entry}}
+		service.alertNewDrift(ctx, nil, first.Entries)
+		service.alertNewDrift(ctx, first, []*DriftEntry{entry})
+
+		var count int64
+		require.NoError(t, service.db.Model(&models.Alert{}).Where("type = ?", "config_drift").Count(&count).Error)
+		assert.Equal(t, int64(1), count)
+	})
+}
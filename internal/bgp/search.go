@@ -0,0 +1,185 @@
+package bgp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/padminisys/flintroute/internal/repository"
+)
+
+// ErrInvalidPeerQuery is returned for a malformed peer search query
+var ErrInvalidPeerQuery = errors.New("invalid peer search query")
+
+// Peer search types, as the repository defines them
+type (
+	PeerFilter       = repository.PeerFilter
+	PeerSearch       = repository.PeerSearch
+	PeerSearchResult = repository.PeerSearchResult
+	PeerFacets       = repository.PeerFacets
+	FacetCount       = repository.FacetCount
+)
+
+// PeerSearchFields are the fields of peer search queries, in the order
+// they are documented
+var PeerSearchFields = []string{
+	repository.PeerFieldName, repository.PeerFieldDescription, repository.PeerFieldIP,
+	repository.PeerFieldASN, repository.PeerFieldLocalASN, repository.PeerFieldState,
+	repository.PeerFieldTag, repository.PeerFieldEnabled, repository.PeerFieldSyncStatus,
+	repository.PeerFieldMaintenance, repository.PeerFieldManagedBy,
+}
+
+// fieldPattern matches the field of a "field:value" term; terms starting
+// with anything else, such as IPv6 addresses, are free text
+var fieldPattern = regexp.MustCompile(`^[a-z_]+$`)
+
+// ParsePeerQuery parses a peer search query into filters. A query is terms
+// separated by spaces, every one of which a peer must match:
+//
+//	asn:65010 state:Established tag:pop=ams name~"transit" -enabled:false
+//
+// "field:value" matches a field's value exactly, case-insensitively, and
+// "field~value" values containing it, for name, description and ip. asn
+// and local_asn take a number or a range such as 64512..65534. A leading
+// "-" negates a term. Other terms are free text, matching words of the
+// name or description by prefix, or the start of the IP address. Values
+// and free text with spaces are quoted.
+func ParsePeerQuery(query string) ([]PeerFilter, error) {
+	terms, err := splitQuery(query)
+	if err != nil {
+		return nil, err
+	}
+
+	filters := make([]PeerFilter, 0, len(terms))
+	for _, term := range terms {
+		filter := PeerFilter{Field: repository.PeerFieldText, Value: term.text}
+		if !term.quoted {
+			if negated, ok := strings.CutPrefix(term.text, "-"); ok && negated != "" {
+				filter.Negate = true
+				filter.Value = unquote(negated)
+			}
+			if i := strings.IndexAny(filter.Value, ":~"); i > 0 && fieldPattern.MatchString(filter.Value[:i]) {
+				filter.Field = filter.Value[:i]
+				filter.Contains = filter.Value[i] == '~'
+				filter.Value = unquote(filter.Value[i+1:])
+				if err := validateFilter(&filter); err != nil {
+					return nil, err
+				}
+			}
+		}
+		filters = append(filters, filter)
+	}
+	return filters, nil
+}
+
+// queryTerm is a term of a query, and whether it was quoted as a whole
+type queryTerm struct {
+	text   string
+	quoted bool
+}
+
+// splitQuery splits a query into terms at spaces outside quotes
+func splitQuery(query string) ([]queryTerm, error) {
+	var terms []queryTerm
+	var term strings.Builder
+	quoted, inQuotes := false, false
+	flush := func() {
+		if term.Len() > 0 || quoted {
+			text := term.String()
+			terms = append(terms, queryTerm{text: unquote(text), quoted: quoted})
+		}
+		term.Reset()
+		quoted = false
+	}
+
+	for _, r := range query {
+		switch {
+		case r == '"':
+			if !inQuotes && term.Len() == 0 {
+				quoted = true
+			}
+			inQuotes = !inQuotes
+			term.WriteRune(r)
+		case !inQuotes && (r == ' ' || r == '\t' || r == '\n'):
+			flush()
+		default:
+			term.WriteRune(r)
+		}
+	}
+	if inQuotes {
+		return nil, fmt.Errorf("%w: unterminated quote", ErrInvalidPeerQuery)
+	}
+	flush()
+	return terms, nil
+}
+
+// unquote drops the quotes around a value
+func unquote(value string) string {
+	if len(value) >= 2 && value[0] == '"' && value[len(value)-1] == '"' {
+		return value[1 : len(value)-1]
+	}
+	return value
+}
+
+// validateFilter checks the value of a "field:value" filter, filling in
+// the AS range of ASN fields
+func validateFilter(filter *PeerFilter) error {
+	invalid := func(format string, args ...interface{}) error {
+		return fmt.Errorf("%w: %s", ErrInvalidPeerQuery, fmt.Sprintf(format, args...))
+	}
+
+	switch filter.Field {
+	case repository.PeerFieldName, repository.PeerFieldDescription, repository.PeerFieldIP:
+	default:
+		if !slices.Contains(PeerSearchFields, filter.Field) {
+			return invalid("unknown field %q; fields are %s", filter.Field, strings.Join(PeerSearchFields, ", "))
+		}
+		if filter.Contains {
+			return invalid("%s doesn't support ~, only name, description and ip do", filter.Field)
+		}
+	}
+	if filter.Value == "" {
+		return invalid("%s needs a value", filter.Field)
+	}
+
+	switch filter.Field {
+	case repository.PeerFieldName, repository.PeerFieldDescription, repository.PeerFieldIP,
+		repository.PeerFieldState, repository.PeerFieldSyncStatus, repository.PeerFieldManagedBy:
+	case repository.PeerFieldASN, repository.PeerFieldLocalASN:
+		from, to, isRange := strings.Cut(filter.Value, "..")
+		if !isRange {
+			to = from
+		}
+		first, err := strconv.ParseUint(from, 10, 32)
+		if err != nil {
+			return invalid("%s must be an AS number or a range such as 64512..65534", filter.Field)
+		}
+		last, err := strconv.ParseUint(to, 10, 32)
+		if err != nil || last < first {
+			return invalid("%s must be an AS number or a range such as 64512..65534", filter.Field)
+		}
+		filter.From, filter.To = uint32(first), uint32(last)
+	case repository.PeerFieldTag:
+		key, _, _ := strings.Cut(filter.Value, "=")
+		if !tagKeyPattern.MatchString(key) {
+			return invalid("tag must be key=value or key, with a valid key")
+		}
+	case repository.PeerFieldEnabled, repository.PeerFieldMaintenance:
+		value, err := strconv.ParseBool(filter.Value)
+		if err != nil {
+			return invalid("%s must be true or false", filter.Field)
+		}
+		filter.Value = strconv.FormatBool(value)
+	}
+	return nil
+}
+
+// SearchPeers returns a page of the peers matching a search, with the
+// total and facets of every match
+func (s *Service) SearchPeers(ctx context.Context, search PeerSearch) (*PeerSearchResult, error) {
+	return s.peers.Search(ctx, search)
+}
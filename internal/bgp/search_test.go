@@ -0,0 +1,62 @@
+package bgp
+
+import (
+	"context"
+	"testing"
+
+	"github.com/padminisys/flintroute/internal/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePeerQuery(t *testing.T) {
+	filters, err := ParsePeerQuery(`asn:65010 state:Established tag:pop=ams name~"transit provider" -enabled:0 ` +
+		`local_asn:64512..65534 "hurricane electric" 2001:db8::1 -fra`)
+	require.NoError(t, err)
+	assert.Equal(t, []PeerFilter{
+		{Field: repository.PeerFieldASN, Value: "65010", From: 65010, To: 65010},
+		{Field: repository.PeerFieldState, Value: "Established"},
+		{Field: repository.PeerFieldTag, Value: "pop=ams"},
+		{Field: repository.PeerFieldName, Value: "transit provider", Contains: true},
+		{Field: repository.PeerFieldEnabled, Value: "false", Negate: true},
+		{Field: repository.PeerFieldLocalASN, Value: "64512..65534", From: 64512, To: 65534},
+		{Field: repository.PeerFieldText, Value: "hurricane electric"},
+		{Field: repository.PeerFieldText, Value: "2001:db8::1"},
+		{Field: repository.PeerFieldText, Value: "fra", Negate: true},
+	}, filters)
+
+	filters, err = ParsePeerQuery("  ")
+	require.NoError(t, err)
+	assert.Empty(t, filters)
+
+	for _, query := range []string{
+		`name~"unterminated`,
+		"color:blue",
+		"asn:AS65010",
+		"asn:65010..64512",
+		"asn:4294967296",
+		"state~Est",
+		"enabled:maybe",
+		`tag:"bad key"`,
+		"tag:=ams",
+		"name:",
+	} {
+		_, err := ParsePeerQuery(query)
+		assert.ErrorIs(t, err, ErrInvalidPeerQuery, query)
+	}
+}
+
+func TestSearchPeers(t *testing.T) {
+	ctx := context.Background()
+	service := setupTestService(t, ConsistencyEventual)
+	require.NoError(t, service.CreatePeer(ctx, newTaggedPeer("10.0.0.1", map[string]string{"pop": "fra1"})))
+	require.NoError(t, service.CreatePeer(ctx, newTaggedPeer("10.0.0.2", map[string]string{"pop": "ams1"})))
+
+	filters, err := ParsePeerQuery("tag:pop=ams1")
+	require.NoError(t, err)
+	result, err := service.SearchPeers(ctx, PeerSearch{Filters: filters, Limit: 10})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), result.Total)
+	require.Len(t, result.Peers, 1)
+	assert.Equal(t, "10.0.0.2", result.Peers[0].IPAddress)
+}
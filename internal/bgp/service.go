@@ -2,109 +2,441 @@ package bgp
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"slices"
+	"strings"
+	"sync"
 	"time"
 
+	"github.com/padminisys/flintroute/internal/alerts"
+	"github.com/padminisys/flintroute/internal/cache"
 	"github.com/padminisys/flintroute/internal/database"
+	"github.com/padminisys/flintroute/internal/encryption"
 	"github.com/padminisys/flintroute/internal/frr"
-	"github.com/padminisys/flintroute/internal/models"
+	"github.com/padminisys/flintroute/internal/frrlog"
+	"github.com/padminisys/flintroute/internal/repository"
+	"github.com/padminisys/flintroute/internal/requestid"
+	"github.com/padminisys/flintroute/internal/secrets"
+	"github.com/padminisys/flintroute/internal/snmp"
+	"github.com/padminisys/flintroute/internal/tenancy"
+	"github.com/padminisys/flintroute/internal/webhooks"
 	"github.com/padminisys/flintroute/internal/websocket"
+	"github.com/padminisys/flintroute/pkg/models"
 	"go.uber.org/zap"
-	"gorm.io/gorm"
+)
+
+var (
+	ErrPeerNotFound    = errors.New("peer not found")
+	ErrPeerExists      = errors.New("a peer with this IP address already exists")
+	ErrSessionNotFound = errors.New("session not found")
+	ErrFRRApplyFailed  = errors.New("failed to apply configuration to FRR")
+)
+
+// ConsistencyMode controls how FRR apply failures affect stored peers
+type ConsistencyMode string
+
+const (
+	// ConsistencyStrict rolls back the database change when FRR rejects it
+	ConsistencyStrict ConsistencyMode = "strict"
+	// ConsistencyEventual keeps the change and marks the peer as pending sync
+	ConsistencyEventual ConsistencyMode = "eventual"
+)
+
+// ServiceConfig holds tunable BGP service behaviour
+type ServiceConfig struct {
+	ConsistencyMode ConsistencyMode
+	// AutoHeal re-applies drifted peers during reconciliation
+	AutoHeal bool
+	// LocalASN is the router's AS number, which new peers must use as their
+	// local ASN. 0 takes it from the global configuration or existing peers.
+	LocalASN uint32
+	// PasswordCipher encrypts peer passwords at rest. Required.
+	PasswordCipher *encryption.Cipher
+	// Secrets resolves peer passwords given as secret:// references.
+	// References are resolved on every FRR apply, so rotations are picked
+	// up the next time the peer is pushed.
+	Secrets *secrets.Resolver
+	// Webhooks receives peer and session lifecycle events. Optional.
+	Webhooks *webhooks.Service
+	// Traps sends SNMP traps for peer and FRR alerts. Optional.
+	Traps *snmp.Sender
+	// Cache holds API responses built from peers and sessions. The service
+	// invalidates CachePeers and CacheSessions whenever they change.
+	// Optional.
+	Cache *cache.Cache
+	// Alerts stores raised alerts, folding repeats together and holding
+	// back notifications during alert storms. Defaults to storing every
+	// alert.
+	Alerts *alerts.Deduplicator
+	// Notifier notifies users of new alerts by their notification
+	// settings. Optional.
+	Notifier *alerts.Notifier
+	// Anomalies configures detecting sudden prefix count changes
+	Anomalies AnomalyPolicy
+	// Reachability configures probing peer addresses at the IP layer
+	Reachability ReachabilityPolicy
+	// SyncBatchSize is how many peers a full sync pushes to FRR in one
+	// commit; 0 or 1 pushes them one at a time
+	SyncBatchSize int
+	// TrashRetention is how long deleted peers stay in the trash before
+	// StartTrashPurger removes them for good; 0 keeps them until purged
+	TrashRetention time.Duration
+	// Peers stores peers. Defaults to the GORM repository on the service's
+	// database.
+	Peers repository.PeerRepo
+	// Sessions stores session states. Defaults to the GORM repository on
+	// the service's database.
+	Sessions repository.SessionRepo
+}
+
+// Cache namespaces invalidated by the service
+const (
+	CachePeers    = "peers"
+	CacheSessions = "sessions"
 )
 
 // Service manages BGP operations
 type Service struct {
 	db        *database.DB
-	frrClient *frr.Client
+	peers     repository.PeerRepo
+	sessions  repository.SessionRepo
+	frrClient frr.FRRClient
 	wsHub     *websocket.Hub
+	config    ServiceConfig
 	logger    *zap.Logger
+
+	driftMu   sync.Mutex
+	lastDrift *DriftReport
+
+	syncMu   sync.Mutex
+	lastSync *PeerSyncReport
+
+	anomalyMu     sync.Mutex
+	lastAnomalies *AnomalyReport
+
+	reachabilityMu     sync.Mutex
+	lastReachability   *ReachabilityReport
+	tenantReachability map[uint]*ReachabilityReport // rounds of a tenant's peers
+
+	monitorMu   sync.Mutex
+	monitor     MonitorStatus
+	monitorWake chan struct{}
+
+	frrReachable bool // last observed FRR reachability, owned by the monitoring loop
 }
 
 // NewService creates a new BGP service
-func NewService(db *database.DB, frrClient *frr.Client, wsHub *websocket.Hub, logger *zap.Logger) *Service {
+func NewService(db *database.DB, frrClient frr.FRRClient, wsHub *websocket.Hub, cfg ServiceConfig, logger *zap.Logger) *Service {
+	if cfg.ConsistencyMode == "" {
+		cfg.ConsistencyMode = ConsistencyEventual
+	}
+	if cfg.Alerts == nil {
+		cfg.Alerts = alerts.NewDeduplicator(db, alerts.DedupPolicy{}, logger)
+	}
+	if cfg.Peers == nil {
+		cfg.Peers = repository.NewPeerRepo(db)
+	}
+	if cfg.Sessions == nil {
+		cfg.Sessions = repository.NewSessionRepo(db)
+	}
+
 	return &Service{
 		db:        db,
+		peers:     cfg.Peers,
+		sessions:  cfg.Sessions,
 		frrClient: frrClient,
 		wsHub:     wsHub,
+		config:    cfg,
 		logger:    logger,
+
+		monitorWake:  make(chan struct{}, 1),
+		frrReachable: true,
 	}
 }
 
-// CreatePeer creates a new BGP peer
-func (s *Service) CreatePeer(ctx context.Context, peer *models.BGPPeer) error {
-	// Save to database
-	if err := s.db.Create(peer).Error; err != nil {
-		return fmt.Errorf("failed to create peer in database: %w", err)
-	}
-
-	// Configure in FRR if enabled
-	if peer.Enabled {
-		config := &frr.BGPPeerConfig{
-			IPAddress:       peer.IPAddress,
-			ASN:             peer.ASN,
-			RemoteASN:       peer.RemoteASN,
-			Password:        peer.Password,
-			Multihop:        peer.Multihop,
-			UpdateSource:    peer.UpdateSource,
-			RouteMapIn:      peer.RouteMapIn,
-			RouteMapOut:     peer.RouteMapOut,
-			PrefixListIn:    peer.PrefixListIn,
-			PrefixListOut:   peer.PrefixListOut,
-			MaxPrefixes:     peer.MaxPrefixes,
-			LocalPreference: peer.LocalPreference,
+// peerConfig converts a stored peer into its FRR configuration,
+// decrypting the peer password and resolving secret references
+func (s *Service) peerConfig(ctx context.Context, peer *models.BGPPeer) (*frr.BGPPeerConfig, error) {
+	password, err := s.config.PasswordCipher.Decrypt(peer.Password)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt password for peer %s: %w", peer.IPAddress, err)
+	}
+
+	if secrets.IsReference(password) {
+		if s.config.Secrets == nil {
+			return nil, fmt.Errorf("peer %s password is a secret reference but no secrets resolver is configured", peer.IPAddress)
+		}
+		if password, err = s.config.Secrets.Resolve(ctx, password); err != nil {
+			return nil, err
 		}
+	}
+
+	return newPeerConfig(peer, password), nil
+}
+
+// newPeerConfig converts a stored peer into its FRR configuration with the
+// given plaintext password
+func newPeerConfig(peer *models.BGPPeer, password string) *frr.BGPPeerConfig {
+	return &frr.BGPPeerConfig{
+		IPAddress:       peer.IPAddress,
+		ASN:             peer.ASN,
+		RemoteASN:       peer.RemoteASN,
+		Description:     frrDescription(peer),
+		Password:        password,
+		Multihop:        peer.Multihop,
+		UpdateSource:    peer.UpdateSource,
+		RouteMapIn:      peer.RouteMapIn,
+		RouteMapOut:     peer.RouteMapOut,
+		PrefixListIn:    peer.PrefixListIn,
+		PrefixListOut:   peer.PrefixListOut,
+		MaxPrefixes:     peer.MaxPrefixes,
+		LocalPreference: peer.LocalPreference,
+		NeighborOptions: NeighborOptions(peer),
+	}
+}
+
+// frrDescription returns the neighbor description FRR is given for a peer,
+// so router CLI users see the label the UI shows: its description, or its
+// name without one, on a single line
+func frrDescription(peer *models.BGPPeer) string {
+	description := peer.Description
+	if description == "" {
+		description = peer.Name
+	}
+	return strings.Join(strings.Fields(description), " ")
+}
+
+// redactedPassword replaces peer passwords in configuration previews
+const redactedPassword = "<redacted>"
+
+// PeerFRRConfig renders the FRR configuration FlintRoute generates for a
+// peer, without applying it. The password is redacted. Disabled peers are
+// not configured in FRR, which a leading comment says.
+func (s *Service) PeerFRRConfig(ctx context.Context, id uint) (string, error) {
+	peer, err := s.GetPeer(ctx, id)
+	if err != nil {
+		return "", err
+	}
+
+	password := ""
+	if peer.Password != "" {
+		password = redactedPassword
+	}
+	config := newPeerConfig(peer, password).Config()
+	if !peer.Enabled {
+		config = "! peer is disabled and not configured in FRR\n" + config
+	}
+	return config, nil
+}
+
+// addToFRR adds a stored peer to FRR
+func (s *Service) addToFRR(ctx context.Context, peer *models.BGPPeer) error {
+	cfg, err := s.peerConfig(ctx, peer)
+	if err != nil {
+		return err
+	}
+	return s.frrClient.AddBGPPeer(frrlog.WithPeer(ctx, peer), cfg)
+}
+
+// updateInFRR pushes a stored peer's configuration to FRR
+func (s *Service) updateInFRR(ctx context.Context, peer *models.BGPPeer) error {
+	cfg, err := s.peerConfig(ctx, peer)
+	if err != nil {
+		return err
+	}
+	return s.frrClient.UpdateBGPPeer(frrlog.WithPeer(ctx, peer), cfg)
+}
+
+// removeFromFRR removes a stored peer from FRR
+func (s *Service) removeFromFRR(ctx context.Context, peer *models.BGPPeer) error {
+	return s.frrClient.RemoveBGPPeer(frrlog.WithPeer(ctx, peer), peer.IPAddress)
+}
+
+// CreatePeer creates a new BGP peer. peer.Password is expected in
+// plaintext and is encrypted before being stored. Its ASN defaults to the
+// router's and must match it.
+func (s *Service) CreatePeer(ctx context.Context, peer *models.BGPPeer) error {
+	if err := s.checkLocalASN(ctx, peer); err != nil {
+		return err
+	}
+	if err := ValidatePeer(peer); err != nil {
+		return err
+	}
+	if err := s.checkPeerConflicts(ctx, peer); err != nil {
+		return err
+	}
+
+	encrypted, err := s.config.PasswordCipher.Encrypt(peer.Password)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt peer password: %w", err)
+	}
+	peer.Password = encrypted
+	peer.SyncStatus = models.PeerSyncSynced
+	peer.SyncError = ""
+	peer.TenantID = tenancy.ID(ctx)
 
-		if err := s.frrClient.AddBGPPeer(ctx, config); err != nil {
-			s.logger.Error("Failed to add peer to FRR", zap.Error(err))
-			// Don't fail the operation, just log the error
+	apply := func() error {
+		if !peer.Enabled {
+			return nil
 		}
+		return s.addToFRR(ctx, peer)
+	}
+
+	if err := s.savePeer(ctx, peer, apply); err != nil {
+		return err
 	}
 
 	// Broadcast update
-	s.wsHub.BroadcastPeerUpdate(peer)
+	s.wsHub.BroadcastPeerUpdate(ctx, peer)
+	s.config.Webhooks.Publish(ctx, webhooks.EventPeerCreated, peer)
 
 	s.logger.Info("Created BGP peer",
 		zap.Uint("id", peer.ID),
 		zap.String("ip", peer.IPAddress),
+		zap.String("sync_status", peer.SyncStatus),
+		requestid.Field(ctx),
 	)
 
 	return nil
 }
 
-// GetPeer retrieves a BGP peer by ID
-func (s *Service) GetPeer(ctx context.Context, id uint) (*models.BGPPeer, error) {
-	var peer models.BGPPeer
-	if err := s.db.First(&peer, id).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
-			return nil, fmt.Errorf("peer not found")
+// checkPeerConflicts returns ErrPeerExists when another peer, in the trash
+// or not, has peer's IP address
+func (s *Service) checkPeerConflicts(ctx context.Context, peer *models.BGPPeer) error {
+	other, err := s.peers.FindByIP(ctx, peer.IPAddress, peer.ID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if other.DeletedAt.Valid {
+		return fmt.Errorf("%w: %s is in the trash as peer %d; restore or purge it", ErrPeerExists, peer.IPAddress, other.ID)
+	}
+	return fmt.Errorf("%w: %s", ErrPeerExists, peer.IPAddress)
+}
+
+// savePeer persists a peer and applies it to FRR according to the
+// configured consistency mode. In strict mode both steps run in a single
+// transaction that is rolled back if FRR rejects the change; in eventual
+// mode the peer is kept and marked as pending sync. Configuration FRR
+// rejects is recorded on peers that are stored.
+func (s *Service) savePeer(ctx context.Context, peer *models.BGPPeer, apply func() error) error {
+	defer s.peersChanged()
+
+	if s.config.ConsistencyMode == ConsistencyStrict {
+		existing := peer.ID != 0
+		err := s.peers.Save(ctx, peer, func() error {
+			if err := apply(); err != nil {
+				s.logger.Error("Failed to apply peer to FRR, rolling back",
+					zap.String("ip", peer.IPAddress),
+					zap.Error(err),
+					requestid.Field(ctx),
+				)
+				return fmt.Errorf("%w: %w", ErrFRRApplyFailed, err)
+			}
+			return nil
+		})
+		if err != nil && existing {
+			s.recordFRRError(ctx, peer, err)
 		}
-		return nil, err
+		return err
+	}
+
+	if err := s.peers.Save(ctx, peer, nil); err != nil {
+		return err
 	}
-	return &peer, nil
+
+	if err := apply(); err != nil {
+		s.logger.Warn("Failed to apply peer to FRR, marking pending sync",
+			zap.String("ip", peer.IPAddress),
+			zap.Error(err),
+			requestid.Field(ctx),
+		)
+		s.setSyncStatus(ctx, peer, models.PeerSyncPending, err.Error())
+		s.recordFRRError(ctx, peer, err)
+	}
+
+	return nil
 }
 
-// ListPeers retrieves all BGP peers
-func (s *Service) ListPeers(ctx context.Context) ([]*models.BGPPeer, error) {
-	var peers []*models.BGPPeer
-	if err := s.db.Find(&peers).Error; err != nil {
-		return nil, err
+// peersChanged invalidates cached responses built from peers. Sessions
+// embed their peer, so they are invalidated too.
+func (s *Service) peersChanged() {
+	s.config.Cache.Invalidate(CachePeers, CacheSessions)
+}
+
+// setSyncStatus records the FRR sync status of a peer
+func (s *Service) setSyncStatus(ctx context.Context, peer *models.BGPPeer, status, syncErr string) {
+	peer.SyncStatus = status
+	peer.SyncError = syncErr
+	defer s.peersChanged()
+
+	if err := s.peers.SetSyncStatus(ctx, peer, status, syncErr); err != nil {
+		s.logger.Error("Failed to update peer sync status",
+			zap.Uint("id", peer.ID),
+			zap.Error(err),
+		)
+	}
+}
+
+// recordFRRError keeps FRR's raw output on a stored peer when err is FRR
+// rejecting its configuration, for inspection once the error is gone
+func (s *Service) recordFRRError(ctx context.Context, peer *models.BGPPeer, err error) {
+	configErr, ok := frr.AsConfigError(err)
+	if !ok {
+		return
+	}
+	now := time.Now()
+	peer.LastFRRError = configErr.Raw
+	peer.LastFRRErrorAt = &now
+
+	if err := s.peers.SetFRRError(ctx, peer, configErr.Raw, now); err != nil {
+		s.logger.Error("Failed to record FRR error of peer",
+			zap.Uint("id", peer.ID),
+			zap.Error(err),
+		)
+	}
+}
+
+// GetPeer retrieves a BGP peer by ID
+func (s *Service) GetPeer(ctx context.Context, id uint) (*models.BGPPeer, error) {
+	peer, err := s.peers.Get(ctx, id)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, ErrPeerNotFound
 	}
-	return peers, nil
+	return peer, err
+}
+
+// ListPeers retrieves all BGP peers, or only those matching every tag
+// selector when selectors are given
+func (s *Service) ListPeers(ctx context.Context, selectors ...TagSelector) ([]*models.BGPPeer, error) {
+	return s.peers.List(ctx, selectors...)
 }
 
 // UpdatePeer updates a BGP peer
 func (s *Service) UpdatePeer(ctx context.Context, id uint, updates *models.BGPPeer) error {
-	var peer models.BGPPeer
-	if err := s.db.First(&peer, id).Error; err != nil {
-		return fmt.Errorf("peer not found")
+	peer, err := s.peers.Get(ctx, id)
+	if err != nil {
+		return ErrPeerNotFound
 	}
 
-	// Update fields
+	replacePeer(peer, updates)
+	if err := s.checkLocalASN(ctx, peer); err != nil {
+		return err
+	}
+
+	return s.applyPeerChange(ctx, peer)
+}
+
+// replacePeer copies every mutable attribute of updates onto peer. Tags are
+// only replaced when updates.Tags is non-nil. updates.TemplateOverrides are
+// added to the overrides of a peer provisioned from a template.
+func replacePeer(peer, updates *models.BGPPeer) {
 	peer.Name = updates.Name
 	peer.Description = updates.Description
 	peer.Enabled = updates.Enabled
-	peer.Password = updates.Password
 	peer.Multihop = updates.Multihop
 	peer.UpdateSource = updates.UpdateSource
 	peer.RouteMapIn = updates.RouteMapIn
@@ -112,158 +444,560 @@ func (s *Service) UpdatePeer(ctx context.Context, id uint, updates *models.BGPPe
 	peer.PrefixListIn = updates.PrefixListIn
 	peer.PrefixListOut = updates.PrefixListOut
 	peer.MaxPrefixes = updates.MaxPrefixes
+	peer.MaxPrefixThreshold = updates.MaxPrefixThreshold
+	peer.MaxPrefixAction = updates.MaxPrefixAction
+	peer.MaxPrefixRestart = updates.MaxPrefixRestart
 	peer.LocalPreference = updates.LocalPreference
+	peer.Keepalive = updates.Keepalive
+	peer.HoldTime = updates.HoldTime
+	peer.ConnectRetry = updates.ConnectRetry
+	peer.Passive = updates.Passive
+	peer.TTLSecurityHops = updates.TTLSecurityHops
+	peer.NextHopSelf = updates.NextHopSelf
+	peer.SoftReconfigInbound = updates.SoftReconfigInbound
+	peer.RemovePrivateAS = updates.RemovePrivateAS
+	peer.AllowASIn = updates.AllowASIn
+	peer.LocalAS = updates.LocalAS
+	peer.LocalASMode = updates.LocalASMode
+	if updates.Tags != nil {
+		peer.Tags = updates.Tags
+	}
+	if peer.TemplateID != nil {
+		peer.TemplateOverrides = addOverrides(peer.TemplateOverrides, updates.TemplateOverrides)
+	}
+}
+
+// PeerPatch holds optional peer attributes for a partial update.
+// Nil fields are left unchanged.
+type PeerPatch struct {
+	Name                *string
+	Description         *string
+	Enabled             *bool
+	Multihop            *int
+	UpdateSource        *string
+	RouteMapIn          *string
+	RouteMapOut         *string
+	PrefixListIn        *string
+	PrefixListOut       *string
+	MaxPrefixes         *int
+	MaxPrefixThreshold  *int
+	MaxPrefixAction     *string
+	MaxPrefixRestart    *int
+	LocalPreference     *int
+	Keepalive           *int
+	HoldTime            *int
+	ConnectRetry        *int
+	Passive             *bool
+	TTLSecurityHops     *int
+	NextHopSelf         *bool
+	SoftReconfigInbound *bool
+	RemovePrivateAS     *bool
+	AllowASIn           *int
+	LocalAS             *uint32
+	LocalASMode         *string
+	ManagedBy           *string
+	// Tags replaces all of the peer's tags when non-nil
+	Tags map[string]string
+	// TemplateOverrides names, as in the API, the settings the update sets
+	// explicitly; they are added to the overrides of a peer provisioned
+	// from a template
+	TemplateOverrides []string
+}
 
-	if err := s.db.Save(&peer).Error; err != nil {
-		return fmt.Errorf("failed to update peer: %w", err)
+// applyTo copies the provided attributes onto peer
+func (p *PeerPatch) applyTo(peer *models.BGPPeer) {
+	setIfPresent(&peer.Name, p.Name)
+	setIfPresent(&peer.Description, p.Description)
+	setIfPresent(&peer.Enabled, p.Enabled)
+	setIfPresent(&peer.Multihop, p.Multihop)
+	setIfPresent(&peer.UpdateSource, p.UpdateSource)
+	setIfPresent(&peer.RouteMapIn, p.RouteMapIn)
+	setIfPresent(&peer.RouteMapOut, p.RouteMapOut)
+	setIfPresent(&peer.PrefixListIn, p.PrefixListIn)
+	setIfPresent(&peer.PrefixListOut, p.PrefixListOut)
+	setIfPresent(&peer.MaxPrefixes, p.MaxPrefixes)
+	setIfPresent(&peer.MaxPrefixThreshold, p.MaxPrefixThreshold)
+	setIfPresent(&peer.MaxPrefixAction, p.MaxPrefixAction)
+	setIfPresent(&peer.MaxPrefixRestart, p.MaxPrefixRestart)
+	setIfPresent(&peer.LocalPreference, p.LocalPreference)
+	setIfPresent(&peer.Keepalive, p.Keepalive)
+	setIfPresent(&peer.HoldTime, p.HoldTime)
+	setIfPresent(&peer.ConnectRetry, p.ConnectRetry)
+	setIfPresent(&peer.Passive, p.Passive)
+	setIfPresent(&peer.TTLSecurityHops, p.TTLSecurityHops)
+	setIfPresent(&peer.NextHopSelf, p.NextHopSelf)
+	setIfPresent(&peer.SoftReconfigInbound, p.SoftReconfigInbound)
+	setIfPresent(&peer.RemovePrivateAS, p.RemovePrivateAS)
+	setIfPresent(&peer.AllowASIn, p.AllowASIn)
+	setIfPresent(&peer.LocalAS, p.LocalAS)
+	setIfPresent(&peer.LocalASMode, p.LocalASMode)
+	setIfPresent(&peer.ManagedBy, p.ManagedBy)
+	if p.Tags != nil {
+		peer.Tags = models.NewPeerTags(p.Tags)
 	}
+	if peer.TemplateID != nil {
+		peer.TemplateOverrides = addOverrides(peer.TemplateOverrides, p.TemplateOverrides)
+	}
+}
 
-	// Update FRR configuration
-	config := &frr.BGPPeerConfig{
-		IPAddress:       peer.IPAddress,
-		ASN:             peer.ASN,
-		RemoteASN:       peer.RemoteASN,
-		Password:        peer.Password,
-		Multihop:        peer.Multihop,
-		UpdateSource:    peer.UpdateSource,
-		RouteMapIn:      peer.RouteMapIn,
-		RouteMapOut:     peer.RouteMapOut,
-		PrefixListIn:    peer.PrefixListIn,
-		PrefixListOut:   peer.PrefixListOut,
-		MaxPrefixes:     peer.MaxPrefixes,
-		LocalPreference: peer.LocalPreference,
+// setIfPresent assigns *value to dst when value is non-nil
+func setIfPresent[T any](dst *T, value *T) {
+	if value != nil {
+		*dst = *value
+	}
+}
+
+// PatchPeer updates only the peer attributes set in patch
+func (s *Service) PatchPeer(ctx context.Context, id uint, patch *PeerPatch) error {
+	peer, err := s.peers.Get(ctx, id)
+	if err != nil {
+		return ErrPeerNotFound
+	}
+
+	patch.applyTo(peer)
+	if err := s.checkLocalASN(ctx, peer); err != nil {
+		return err
+	}
+
+	return s.applyPeerChange(ctx, peer)
+}
+
+// applyPeerChange persists a modified peer and pushes it to FRR
+func (s *Service) applyPeerChange(ctx context.Context, peer *models.BGPPeer) error {
+	if err := ValidatePeer(peer); err != nil {
+		return err
+	}
+
+	peer.SyncStatus = models.PeerSyncSynced
+	peer.SyncError = ""
+
+	apply := func() error {
+		return s.updateInFRR(ctx, peer)
 	}
 
-	if err := s.frrClient.UpdateBGPPeer(ctx, config); err != nil {
-		s.logger.Error("Failed to update peer in FRR", zap.Error(err))
+	if err := s.savePeer(ctx, peer, apply); err != nil {
+		return err
 	}
 
 	// Broadcast update
-	s.wsHub.BroadcastPeerUpdate(&peer)
+	s.wsHub.BroadcastPeerUpdate(ctx, peer)
+	s.config.Webhooks.Publish(ctx, webhooks.EventPeerUpdated, peer)
+
+	s.logger.Info("Updated BGP peer", zap.Uint("id", peer.ID), requestid.Field(ctx))
+
+	return nil
+}
+
+// SetPeerPassword replaces a peer's password and pushes it to FRR.
+// An empty password removes authentication from the session.
+func (s *Service) SetPeerPassword(ctx context.Context, id uint, password string) error {
+	peer, err := s.peers.Get(ctx, id)
+	if err != nil {
+		return ErrPeerNotFound
+	}
+
+	encrypted, err := s.config.PasswordCipher.Encrypt(password)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt peer password: %w", err)
+	}
+	peer.Password = encrypted
+
+	if err := s.applyPeerChange(ctx, peer); err != nil {
+		return err
+	}
+
+	s.logger.Info("Rotated BGP peer password", zap.Uint("id", id), requestid.Field(ctx))
+
+	return nil
+}
+
+// StoredPassword returns a peer's decrypted stored password. Secret
+// references are returned as-is rather than resolved.
+func (s *Service) StoredPassword(peer *models.BGPPeer) (string, error) {
+	return s.config.PasswordCipher.Decrypt(peer.Password)
+}
+
+// EncryptStoredPasswords encrypts peer passwords that were stored in
+// plaintext by earlier versions
+func (s *Service) EncryptStoredPasswords(ctx context.Context) error {
+	peers, err := s.peers.ListWithPassword(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, peer := range peers {
+		if encryption.IsEncrypted(peer.Password) {
+			continue
+		}
+
+		encrypted, err := s.config.PasswordCipher.Encrypt(peer.Password)
+		if err != nil {
+			return fmt.Errorf("failed to encrypt password for peer %s: %w", peer.IPAddress, err)
+		}
+
+		if err := s.peers.SetPassword(ctx, peer, encrypted); err != nil {
+			return fmt.Errorf("failed to store encrypted password: %w", err)
+		}
 
-	s.logger.Info("Updated BGP peer", zap.Uint("id", id))
+		s.logger.Info("Encrypted stored BGP peer password", zap.Uint("id", peer.ID))
+	}
 
 	return nil
 }
 
-// DeletePeer deletes a BGP peer
+// SyncPendingPeers re-applies peers marked as pending sync to FRR
+func (s *Service) SyncPendingPeers(ctx context.Context) error {
+	peers, err := s.peers.ListBySyncStatus(ctx, models.PeerSyncPending)
+	if err != nil {
+		return err
+	}
+
+	for _, peer := range peers {
+		if peer.Enabled {
+			if err := s.addToFRR(ctx, peer); err != nil {
+				s.logger.Debug("Peer still pending sync",
+					zap.String("ip", peer.IPAddress),
+					zap.Error(err),
+				)
+				s.recordFRRError(ctx, peer, err)
+				continue
+			}
+		}
+
+		s.setSyncStatus(ctx, peer, models.PeerSyncSynced, "")
+		s.wsHub.BroadcastPeerUpdate(ctx, peer)
+
+		s.logger.Info("Synced pending BGP peer", zap.Uint("id", peer.ID))
+	}
+
+	return nil
+}
+
+// DeletePeer removes a BGP peer from FRR and moves it to the trash, from
+// which RestorePeer can bring it back with its tags
 func (s *Service) DeletePeer(ctx context.Context, id uint) error {
-	var peer models.BGPPeer
-	if err := s.db.First(&peer, id).Error; err != nil {
-		return fmt.Errorf("peer not found")
+	peer, err := s.peers.Get(ctx, id)
+	if err != nil {
+		return ErrPeerNotFound
 	}
 
 	// Remove from FRR
-	if err := s.frrClient.RemoveBGPPeer(ctx, peer.IPAddress); err != nil {
-		s.logger.Error("Failed to remove peer from FRR", zap.Error(err))
+	if err := s.removeFromFRR(ctx, peer); err != nil {
+		s.logger.Error("Failed to remove peer from FRR", zap.Error(err), requestid.Field(ctx))
 	}
 
-	// Delete from database
-	if err := s.db.Delete(&peer).Error; err != nil {
+	// Move to the trash
+	if err := s.peers.Delete(ctx, peer); err != nil {
 		return fmt.Errorf("failed to delete peer: %w", err)
 	}
+	s.peersChanged()
 
-	s.logger.Info("Deleted BGP peer", zap.Uint("id", id))
+	s.config.Webhooks.Publish(ctx, webhooks.EventPeerDeleted, peer)
+
+	s.logger.Info("Deleted BGP peer", zap.Uint("id", id), requestid.Field(ctx))
 
 	return nil
 }
 
 // GetSession retrieves a BGP session by peer ID
 func (s *Service) GetSession(ctx context.Context, peerID uint) (*models.BGPSession, error) {
-	var session models.BGPSession
-	if err := s.db.Preload("Peer").Where("peer_id = ?", peerID).First(&session).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
-			return nil, fmt.Errorf("session not found")
+	session, err := s.sessions.GetByPeer(ctx, peerID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, ErrSessionNotFound
 		}
 		return nil, err
 	}
-	return &session, nil
+	now := time.Now()
+	session.Uptime = sessionUptime(session, now)
+	session.InMaintenance = session.Peer.InMaintenance(now)
+	return session, nil
 }
 
-// ListSessions retrieves all BGP sessions
-func (s *Service) ListSessions(ctx context.Context) ([]*models.BGPSession, error) {
-	var sessions []*models.BGPSession
-	if err := s.db.Preload("Peer").Find(&sessions).Error; err != nil {
+// ListSessions retrieves all BGP sessions, or only those of peers matching
+// every tag selector when selectors are given
+func (s *Service) ListSessions(ctx context.Context, selectors ...TagSelector) ([]*models.BGPSession, error) {
+	sessions, err := s.sessions.List(ctx, selectors...)
+	if err != nil {
 		return nil, err
 	}
+	now := time.Now()
+	for _, session := range sessions {
+		session.Uptime = sessionUptime(session, now)
+		session.InMaintenance = session.Peer.InMaintenance(now)
+	}
 	return sessions, nil
 }
 
-// UpdateSessionStates updates all BGP session states from FRR
+// UpdateSessionStates refreshes stored BGP session states from FRR. States
+// are fetched in a single call, and only sessions that changed are written,
+// in one transaction, and broadcast.
 func (s *Service) UpdateSessionStates(ctx context.Context) error {
-	// Get all peers
+	_, err := s.updateSessionStates(ctx)
+	return err
+}
+
+// updateSessionStates refreshes session states and returns how many peers
+// were polled
+func (s *Service) updateSessionStates(ctx context.Context) (int, error) {
+	s.checkFRRReachable(ctx)
+
 	peers, err := s.ListPeers(ctx)
 	if err != nil {
-		return err
+		return 0, err
 	}
 
+	enabled := make([]*models.BGPPeer, 0, len(peers))
 	for _, peer := range peers {
-		if !peer.Enabled {
-			continue
+		if peer.Enabled {
+			enabled = append(enabled, peer)
 		}
+	}
+	if len(enabled) == 0 {
+		return 0, nil
+	}
 
-		// Get session state from FRR
-		state, err := s.frrClient.GetBGPSessionState(ctx, peer.IPAddress)
-		if err != nil {
-			s.logger.Error("Failed to get session state",
-				zap.String("ip", peer.IPAddress),
-				zap.Error(err),
-			)
+	states, err := s.frrClient.GetAllBGPSessions(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get session states: %w", err)
+	}
+	stateByIP := make(map[string]*frr.BGPSessionState, len(states))
+	for _, state := range states {
+		stateByIP[state.IPAddress] = state
+	}
+
+	stored, err := s.sessions.ListStored(ctx)
+	if err != nil {
+		return 0, err
+	}
+	sessionByPeer := make(map[uint]*models.BGPSession, len(stored))
+	for _, session := range stored {
+		sessionByPeer[session.PeerID] = session
+	}
+
+	now := time.Now()
+	var changes []*sessionChange
+	for _, peer := range enabled {
+		state, ok := stateByIP[peer.IPAddress]
+		if !ok {
+			s.logger.Debug("No session state for peer", zap.String("ip", peer.IPAddress))
 			continue
 		}
 
-		// Update or create session in database
-		var session models.BGPSession
-		result := s.db.Where("peer_id = ?", peer.ID).First(&session)
-		
-		if result.Error == gorm.ErrRecordNotFound {
-			// Create new session
-			session = models.BGPSession{
-				PeerID:           peer.ID,
-				State:            state.State,
-				Uptime:           state.Uptime,
-				PrefixesReceived: state.PrefixesReceived,
-				PrefixesSent:     state.PrefixesSent,
-				MessagesReceived: state.MessagesReceived,
-				MessagesSent:     state.MessagesSent,
-				LastError:        state.LastError,
-			}
-			if err := s.db.Create(&session).Error; err != nil {
-				s.logger.Error("Failed to create session", zap.Error(err))
+		change := &sessionChange{peer: peer, state: state}
+		session, exists := sessionByPeer[peer.ID]
+		if exists {
+			if !sessionChanged(session, state, now) {
 				continue
 			}
+			change.oldState = session.State
+			change.wasExceeded = maxPrefixExceeded(peer, &frr.BGPSessionState{
+				PrefixesReceived: session.PrefixesReceived,
+				LastError:        session.LastError,
+			})
+			change.sampled = session.PrefixesReceived != state.PrefixesReceived ||
+				session.PrefixesSent != state.PrefixesSent
 		} else {
-			// Update existing session
-			oldState := session.State
-			session.State = state.State
-			session.Uptime = state.Uptime
-			session.PrefixesReceived = state.PrefixesReceived
-			session.PrefixesSent = state.PrefixesSent
-			session.MessagesReceived = state.MessagesReceived
-			session.MessagesSent = state.MessagesSent
-			session.LastError = state.LastError
-
-			if err := s.db.Save(&session).Error; err != nil {
-				s.logger.Error("Failed to update session", zap.Error(err))
-				continue
-			}
+			session = &models.BGPSession{PeerID: peer.ID}
+			change.created = true
+			change.sampled = true
+		}
 
-			// Create alert if state changed
-			if oldState != state.State {
-				s.createStateChangeAlert(peer, oldState, state.State)
+		session.State = state.State
+		session.Uptime = state.Uptime
+		session.PrefixesReceived = state.PrefixesReceived
+		session.PrefixesSent = state.PrefixesSent
+		session.MessagesReceived = state.MessagesReceived
+		session.MessagesSent = state.MessagesSent
+		session.LastError = state.LastError
+		session.RemoteRouterID = state.RemoteRouterID
+		session.BGPVersion = state.BGPVersion
+		session.HoldTime = state.HoldTime
+		session.KeepaliveTime = state.KeepaliveTime
+		session.FourByteASN = state.FourByteASN
+		session.AddressFamilies = state.AddressFamilies
+		session.GracefulRestart = state.GracefulRestart
+		change.session = session
+		changes = append(changes, change)
+	}
+
+	if len(changes) == 0 {
+		return len(enabled), nil
+	}
+
+	updates := make([]repository.SessionUpdate, 0, len(changes))
+	for _, change := range changes {
+		update := repository.SessionUpdate{Session: change.session}
+		if change.sampled {
+			update.Sample = &models.PrefixSample{
+				PeerID:           change.peer.ID,
+				PrefixesReceived: change.state.PrefixesReceived,
+				PrefixesSent:     change.state.PrefixesSent,
 			}
 		}
+		if !change.created && change.oldState != change.state.State {
+			update.Event = &models.SessionEvent{
+				PeerID:        change.peer.ID,
+				IPAddress:     change.peer.IPAddress,
+				OldState:      change.oldState,
+				NewState:      change.state.State,
+				InMaintenance: change.peer.InMaintenance(now),
+			}
+		}
+		updates = append(updates, update)
+	}
+	if err := s.sessions.SaveStates(ctx, updates); err != nil {
+		return len(enabled), fmt.Errorf("failed to save session states: %w", err)
+	}
+	s.config.Cache.Invalidate(CacheSessions)
+
+	for _, change := range changes {
+		peer, state := change.peer, change.state
+
+		// Create alert if state changed. Peers under maintenance are
+		// expected to go down, so they neither alert nor notify.
+		inMaintenance := peer.InMaintenance(now)
+		stateChanged := !change.created && change.oldState != state.State
+		switch {
+		case stateChanged && inMaintenance:
+			s.logger.Info("Suppressed state change alert for peer under maintenance",
+				zap.String("peer", peer.Name),
+				zap.String("old_state", change.oldState),
+				zap.String("new_state", state.State),
+			)
+		case stateChanged:
+			s.createStateChangeAlert(ctx, peer, change.oldState, state.State)
+			s.config.Webhooks.Publish(ctx, webhooks.EventSessionStateChanged, map[string]interface{}{
+				"peer_id":    peer.ID,
+				"ip_address": peer.IPAddress,
+				"old_state":  change.oldState,
+				"new_state":  state.State,
+			})
+		}
+
+		// Alert once when the peer goes over its maximum-prefix limit
+		if !change.wasExceeded && maxPrefixExceeded(peer, state) {
+			s.createMaxPrefixAlert(ctx, peer, state)
+		}
 
 		// Broadcast session update
-		session.Peer = *peer
-		s.wsHub.BroadcastSessionUpdate(&session)
+		change.session.Peer = *peer
+		change.session.InMaintenance = inMaintenance
+		s.wsHub.BroadcastSessionUpdate(ctx, change.session)
 	}
 
-	return nil
+	s.logger.Debug("Updated BGP session states",
+		zap.Int("changed", len(changes)),
+		zap.Int("sessions", len(enabled)),
+	)
+
+	return len(enabled), nil
+}
+
+// sessionChange is a session whose state differs from the stored one
+type sessionChange struct {
+	peer        *models.BGPPeer
+	session     *models.BGPSession
+	state       *frr.BGPSessionState
+	oldState    string
+	wasExceeded bool
+	created     bool
+	// sampled reports whether the prefix counts changed and are recorded
+	sampled bool
+}
+
+// uptimeSlack absorbs the difference between when a session was saved and
+// when FRR sampled its uptime
+const uptimeSlack = 5
+
+// sessionChanged reports whether FRR's state differs from the stored
+// session. Uptime advancing on its own is not a change; a session whose
+// uptime went back was reset between polls.
+func sessionChanged(session *models.BGPSession, state *frr.BGPSessionState, now time.Time) bool {
+	return session.State != state.State ||
+		session.PrefixesReceived != state.PrefixesReceived ||
+		session.PrefixesSent != state.PrefixesSent ||
+		session.MessagesReceived != state.MessagesReceived ||
+		session.MessagesSent != state.MessagesSent ||
+		session.LastError != state.LastError ||
+		session.RemoteRouterID != state.RemoteRouterID ||
+		session.BGPVersion != state.BGPVersion ||
+		session.HoldTime != state.HoldTime ||
+		session.KeepaliveTime != state.KeepaliveTime ||
+		session.FourByteASN != state.FourByteASN ||
+		!slices.Equal(session.AddressFamilies, state.AddressFamilies) ||
+		session.GracefulRestart != state.GracefulRestart ||
+		state.Uptime+uptimeSlack < sessionUptime(session, now)
+}
+
+// sessionUptime returns a stored session's uptime as of now. Sessions are
+// only written when something other than their uptime changes, so an
+// established session's stored uptime is extended by the time since it was
+// saved.
+func sessionUptime(session *models.BGPSession, now time.Time) int64 {
+	if session.State != "Established" || session.UpdatedAt.IsZero() {
+		return session.Uptime
+	}
+	return session.Uptime + int64(now.Sub(session.UpdatedAt).Seconds())
+}
+
+// checkFRRReachable raises a critical alert when FRR becomes unreachable
+// and re-applies every enabled peer once it is reachable again, since FRR
+// may have restarted without its configuration
+func (s *Service) checkFRRReachable(ctx context.Context) {
+	reachable := s.frrClient != nil && s.frrClient.IsConnected()
+	wasReachable := s.frrReachable
+	s.frrReachable = reachable
+
+	if reachable {
+		if !wasReachable {
+			s.logger.Info("FRR is reachable again")
+			s.syncAllPeers(ctx, SyncTriggerReconnect)
+		}
+		return
+	}
+	if !wasReachable {
+		return
+	}
+
+	alert := models.Alert{
+		Type:     "frr_unreachable",
+		Severity: "critical",
+		Message:  "FRR is unreachable",
+	}
+	s.raiseAlert(ctx, &alert, true)
+
+	s.logger.Warn("FRR is unreachable")
+}
+
+// raiseAlert stores alert and notifies WebSocket clients, users and, with
+// trap, SNMP trap receivers. A repeat of an open alert updates it and
+// notifies neither users nor trap receivers; during an alert storm nobody is
+// notified. It reports whether the
+// alert was stored.
+func (s *Service) raiseAlert(ctx context.Context, alert *models.Alert, trap bool) bool {
+	result, err := s.config.Alerts.Raise(ctx, alert)
+	if err != nil {
+		s.logger.Error("Failed to create alert", zap.String("type", alert.Type), zap.Error(err))
+		return false
+	}
+	if result.Suppressed {
+		return true
+	}
+
+	s.wsHub.BroadcastAlert(ctx, alert)
+	if result.Duplicate {
+		return true
+	}
+	s.config.Notifier.Notify(ctx, alert)
+	if trap {
+		s.config.Traps.SendAlert(ctx, alert)
+	}
+	return true
 }
 
 // createStateChangeAlert creates an alert for BGP state changes
-func (s *Service) createStateChangeAlert(peer *models.BGPPeer, oldState, newState string) {
+func (s *Service) createStateChangeAlert(ctx context.Context, peer *models.BGPPeer, oldState, newState string) {
 	severity := "info"
 	alertType := "peer_up"
 
@@ -277,17 +1011,12 @@ func (s *Service) createStateChangeAlert(peer *models.BGPPeer, oldState, newStat
 		Severity: severity,
 		Message:  fmt.Sprintf("BGP peer %s (%s) state changed from %s to %s", peer.Name, peer.IPAddress, oldState, newState),
 		PeerID:   &peer.ID,
+		Peer:     peer,
 	}
-
-	if err := s.db.Create(&alert).Error; err != nil {
-		s.logger.Error("Failed to create alert", zap.Error(err))
+	if !s.raiseAlert(ctx, &alert, true) {
 		return
 	}
 
-	// Broadcast alert
-	alert.Peer = peer
-	s.wsHub.BroadcastAlert(&alert)
-
 	s.logger.Info("Created state change alert",
 		zap.String("peer", peer.Name),
 		zap.String("old_state", oldState),
@@ -295,27 +1024,55 @@ func (s *Service) createStateChangeAlert(peer *models.BGPPeer, oldState, newStat
 	)
 }
 
+// maxPrefixExceeded reports whether a session state shows the peer over its
+// maximum-prefix limit. FRR keeps warning-only sessions up, so the received
+// prefix count is checked as well as the reset reason.
+func maxPrefixExceeded(peer *models.BGPPeer, state *frr.BGPSessionState) bool {
+	if peer.MaxPrefixes <= 0 {
+		return false
+	}
+	return state.PrefixesReceived > peer.MaxPrefixes || state.MaxPrefixExceeded()
+}
+
+// createMaxPrefixAlert creates an alert for a maximum-prefix violation
+func (s *Service) createMaxPrefixAlert(ctx context.Context, peer *models.BGPPeer, state *frr.BGPSessionState) {
+	severity := "critical"
+	details := "FRR shut the session down"
+	if peer.MaxPrefixAction == models.MaxPrefixWarningOnly {
+		severity = "warning"
+		details = "The session was kept up (warning-only)"
+	} else if peer.MaxPrefixRestart > 0 {
+		details += fmt.Sprintf("; it restarts after %d minutes", peer.MaxPrefixRestart)
+	}
+	if state.PrefixesReceived > 0 {
+		details = fmt.Sprintf("%d prefixes received. %s", state.PrefixesReceived, details)
+	}
+
+	alert := models.Alert{
+		Type:     "max_prefix_exceeded",
+		Severity: severity,
+		Message:  fmt.Sprintf("BGP peer %s (%s) exceeded its maximum-prefix limit of %d", peer.Name, peer.IPAddress, peer.MaxPrefixes),
+		Details:  details,
+		PeerID:   &peer.ID,
+		Peer:     peer,
+	}
+	if !s.raiseAlert(ctx, &alert, true) {
+		return
+	}
+
+	s.logger.Warn("Peer exceeded maximum-prefix limit",
+		zap.String("peer", peer.Name),
+		zap.Int("max_prefixes", peer.MaxPrefixes),
+		zap.Int("prefixes_received", state.PrefixesReceived),
+	)
+}
+
 // GetRunningConfig retrieves the current FRR running configuration
 func (s *Service) GetRunningConfig(ctx context.Context) (string, error) {
 	return s.frrClient.GetRunningConfig(ctx)
 }
 
-// StartMonitoring starts periodic monitoring of BGP sessions
-func (s *Service) StartMonitoring(ctx context.Context, interval time.Duration) {
-	ticker := time.NewTicker(interval)
-	defer ticker.Stop()
-
-	s.logger.Info("Started BGP session monitoring", zap.Duration("interval", interval))
-
-	for {
-		select {
-		case <-ctx.Done():
-			s.logger.Info("Stopped BGP session monitoring")
-			return
-		case <-ticker.C:
-			if err := s.UpdateSessionStates(ctx); err != nil {
-				s.logger.Error("Failed to update session states", zap.Error(err))
-			}
-		}
-	}
-}
\ No newline at end of file
+// FRRStatus retrieves FRR's version, daemons and northbound capabilities
+func (s *Service) FRRStatus(ctx context.Context) (*frr.Status, error) {
+	return s.frrClient.GetStatus(ctx)
+}
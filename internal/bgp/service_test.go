@@ -0,0 +1,690 @@
+package bgp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/padminisys/flintroute/internal/cache"
+	"github.com/padminisys/flintroute/internal/encryption"
+	"github.com/padminisys/flintroute/internal/frr"
+	"github.com/padminisys/flintroute/internal/repository"
+	"github.com/padminisys/flintroute/internal/secrets"
+	"github.com/padminisys/flintroute/internal/tenancy"
+	"github.com/padminisys/flintroute/internal/testutil"
+	"github.com/padminisys/flintroute/internal/websocket"
+	"github.com/padminisys/flintroute/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// setupTestService creates a service backed by a disconnected FRR client,
+// so every FRR apply fails with frr.ErrNotConnected
+func setupTestService(t *testing.T, mode ConsistencyMode) *Service {
+	t.Helper()
+
+	logger := zap.NewNop()
+	db := testutil.SetupTestDB(t)
+	t.Cleanup(func() { testutil.CleanupTestDB(t, db) })
+
+	frrClient, err := frr.NewClient("localhost", 50051, logger)
+	require.NoError(t, err)
+
+	passwordCipher, err := encryption.NewCipher(bytes.Repeat([]byte{0x42}, encryption.KeySize))
+	require.NoError(t, err)
+
+	return NewService(db, frrClient, websocket.NewHub(logger), ServiceConfig{
+		ConsistencyMode: mode,
+		PasswordCipher:  passwordCipher,
+	}, logger)
+}
+
+func newTestPeer(ip string, enabled bool) *models.BGPPeer {
+	return &models.BGPPeer{
+		Name:      "Peer " + ip,
+		IPAddress: ip,
+		ASN:       65001,
+		RemoteASN: 65002,
+		Enabled:   enabled,
+	}
+}
+
+func TestCreatePeerStrict(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Roll back when FRR apply fails", func(t *testing.T) {
+		service := setupTestService(t, ConsistencyStrict)
+
+		err := service.CreatePeer(ctx, newTestPeer("10.0.0.1", true))
+		assert.ErrorIs(t, err, ErrFRRApplyFailed)
+		assert.ErrorIs(t, err, frr.ErrNotConnected)
+
+		peers, err := service.ListPeers(ctx)
+		require.NoError(t, err)
+		assert.Empty(t, peers)
+	})
+
+	t.Run("Disabled peer is not applied", func(t *testing.T) {
+		service := setupTestService(t, ConsistencyStrict)
+
+		peer := newTestPeer("10.0.0.2", false)
+		require.NoError(t, service.CreatePeer(ctx, peer))
+		assert.Equal(t, models.PeerSyncSynced, peer.SyncStatus)
+	})
+}
+
+func TestCreatePeerEventual(t *testing.T) {
+	ctx := context.Background()
+	service := setupTestService(t, ConsistencyEventual)
+
+	peer := newTestPeer("10.0.0.1", true)
+	require.NoError(t, service.CreatePeer(ctx, peer))
+	assert.Equal(t, models.PeerSyncPending, peer.SyncStatus)
+
+	stored, err := service.GetPeer(ctx, peer.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.PeerSyncPending, stored.SyncStatus)
+	assert.Contains(t, stored.SyncError, "not connected")
+}
+
+func TestUpdatePeerStrict(t *testing.T) {
+	ctx := context.Background()
+	service := setupTestService(t, ConsistencyStrict)
+
+	peer := newTestPeer("10.0.0.1", false)
+	require.NoError(t, service.CreatePeer(ctx, peer))
+
+	updates := *peer
+	updates.Name = "Renamed"
+	err := service.UpdatePeer(ctx, peer.ID, &updates)
+	assert.ErrorIs(t, err, ErrFRRApplyFailed)
+
+	stored, err := service.GetPeer(ctx, peer.ID)
+	require.NoError(t, err)
+	assert.Equal(t, peer.Name, stored.Name)
+	assert.Empty(t, stored.LastFRRError, "only rejections are recorded")
+}
+
+func TestRecordFRRErrors(t *testing.T) {
+	ctx := context.Background()
+	rejection := &frr.ConfigError{
+		Message: "the statement is missing a value",
+		Command: "neighbor 10.0.0.1 route-map",
+		Raw:     "% Command incomplete: neighbor 10.0.0.1 route-map",
+	}
+
+	t.Run("Strict", func(t *testing.T) {
+		service := setupTestService(t, ConsistencyStrict)
+		client := frr.NewMockClient()
+		client.On("AddBGPPeer", mock.Anything, mock.Anything).Return(rejection)
+		client.On("UpdateBGPPeer", mock.Anything, mock.Anything).Return(rejection)
+		service.frrClient = client
+
+		err := service.CreatePeer(ctx, newTestPeer("10.0.0.1", true))
+		assert.ErrorIs(t, err, frr.ErrConfigRejected)
+
+		peer := newTestPeer("10.0.0.2", false)
+		require.NoError(t, service.CreatePeer(ctx, peer))
+		enabled := true
+		err = service.PatchPeer(ctx, peer.ID, &PeerPatch{Enabled: &enabled})
+		configErr, ok := frr.AsConfigError(err)
+		require.True(t, ok)
+		assert.Equal(t, "neighbor 10.0.0.1 route-map", configErr.Command)
+
+		stored, err := service.GetPeer(ctx, peer.ID)
+		require.NoError(t, err)
+		assert.False(t, stored.Enabled)
+		assert.Equal(t, rejection.Raw, stored.LastFRRError)
+		assert.NotNil(t, stored.LastFRRErrorAt)
+	})
+
+	t.Run("Eventual", func(t *testing.T) {
+		service := setupTestService(t, ConsistencyEventual)
+		client := frr.NewMockClient()
+		client.On("AddBGPPeer", mock.Anything, mock.Anything).Return(rejection)
+		service.frrClient = client
+
+		peer := newTestPeer("10.0.0.1", true)
+		require.NoError(t, service.CreatePeer(ctx, peer))
+		stored, err := service.GetPeer(ctx, peer.ID)
+		require.NoError(t, err)
+		assert.Equal(t, models.PeerSyncPending, stored.SyncStatus)
+		assert.Contains(t, stored.SyncError, "the statement is missing a value")
+		assert.Equal(t, rejection.Raw, stored.LastFRRError)
+	})
+}
+
+func TestPeerChangesInvalidateCache(t *testing.T) {
+	ctx := context.Background()
+	service := setupTestService(t, ConsistencyEventual)
+	service.config.Cache = cache.New(time.Minute)
+
+	list := func() int {
+		value, err := service.config.Cache.GetOrLoad(CachePeers, "", func() (interface{}, error) {
+			peers, err := service.ListPeers(ctx)
+			return len(peers), err
+		})
+		require.NoError(t, err)
+		return value.(int)
+	}
+
+	assert.Equal(t, 0, list())
+	peer := newTestPeer("10.0.0.1", false)
+	require.NoError(t, service.CreatePeer(ctx, peer))
+	assert.Equal(t, 1, list())
+
+	require.NoError(t, service.DeletePeer(ctx, peer.ID))
+	assert.Equal(t, 0, list())
+}
+
+func TestPeerFRRConfig(t *testing.T) {
+	ctx := context.Background()
+	service := setupTestService(t, ConsistencyEventual)
+
+	peer := newTestPeer("10.0.0.1", false)
+	peer.Password = "s3cret"
+	require.NoError(t, service.CreatePeer(ctx, peer))
+
+	config, err := service.PeerFRRConfig(ctx, peer.ID)
+	require.NoError(t, err)
+	assert.Equal(t, `! peer is disabled and not configured in FRR
+router bgp 65001
+ neighbor 10.0.0.1 remote-as 65002
+ neighbor 10.0.0.1 description Peer 10.0.0.1
+ neighbor 10.0.0.1 password <redacted>
+exit
+`, config)
+	assert.NotContains(t, config, "s3cret")
+
+	_, err = service.PeerFRRConfig(ctx, peer.ID+1)
+	assert.ErrorIs(t, err, ErrPeerNotFound)
+}
+
+func TestPatchPeer(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Only provided fields change", func(t *testing.T) {
+		service := setupTestService(t, ConsistencyEventual)
+
+		peer := newTestPeer("10.0.0.1", true)
+		peer.MaxPrefixes = 1000
+		peer.Description = "Upstream"
+		require.NoError(t, service.CreatePeer(ctx, peer))
+
+		description := "Transit"
+		require.NoError(t, service.PatchPeer(ctx, peer.ID, &PeerPatch{Description: &description}))
+
+		stored, err := service.GetPeer(ctx, peer.ID)
+		require.NoError(t, err)
+		assert.Equal(t, "Transit", stored.Description)
+		assert.Equal(t, 1000, stored.MaxPrefixes)
+		assert.Equal(t, peer.Name, stored.Name)
+		assert.True(t, stored.Enabled)
+	})
+
+	t.Run("Explicit zero values are applied", func(t *testing.T) {
+		service := setupTestService(t, ConsistencyEventual)
+
+		peer := newTestPeer("10.0.0.1", true)
+		peer.MaxPrefixes = 1000
+		require.NoError(t, service.CreatePeer(ctx, peer))
+
+		zero := 0
+		disabled := false
+		require.NoError(t, service.PatchPeer(ctx, peer.ID, &PeerPatch{MaxPrefixes: &zero, Enabled: &disabled}))
+
+		stored, err := service.GetPeer(ctx, peer.ID)
+		require.NoError(t, err)
+		assert.Equal(t, 0, stored.MaxPrefixes)
+		assert.False(t, stored.Enabled)
+	})
+
+	t.Run("Invalid options are rejected", func(t *testing.T) {
+		service := setupTestService(t, ConsistencyEventual)
+
+		peer := newTestPeer("10.0.0.1", true)
+		peer.Keepalive = 10
+		peer.HoldTime = 30
+		require.NoError(t, service.CreatePeer(ctx, peer))
+
+		holdTime := 5
+		err := service.PatchPeer(ctx, peer.ID, &PeerPatch{HoldTime: &holdTime})
+		assert.ErrorIs(t, err, ErrInvalidPeer)
+
+		stored, err := service.GetPeer(ctx, peer.ID)
+		require.NoError(t, err)
+		assert.Equal(t, 30, stored.HoldTime)
+	})
+
+	t.Run("Invalid local-as is rejected", func(t *testing.T) {
+		service := setupTestService(t, ConsistencyEventual)
+
+		peer := newTestPeer("10.0.0.1", true)
+		require.NoError(t, service.CreatePeer(ctx, peer))
+
+		// The peer's own AS, and a mode without a local-as
+		localAS := peer.ASN
+		err := service.PatchPeer(ctx, peer.ID, &PeerPatch{LocalAS: &localAS})
+		assert.ErrorIs(t, err, ErrInvalidPeer)
+		mode := models.LocalASReplaceAS
+		err = service.PatchPeer(ctx, peer.ID, &PeerPatch{LocalASMode: &mode})
+		assert.ErrorIs(t, err, ErrInvalidPeer)
+		_, err = service.PlanPatchPeer(ctx, peer.ID, &PeerPatch{LocalASMode: &mode})
+		assert.ErrorIs(t, err, ErrInvalidPeer)
+
+		stored, err := service.GetPeer(ctx, peer.ID)
+		require.NoError(t, err)
+		assert.Zero(t, stored.LocalAS)
+		assert.Empty(t, stored.LocalASMode)
+	})
+
+	t.Run("Peer of another AS than the router's is rejected", func(t *testing.T) {
+		service := setupTestService(t, ConsistencyEventual)
+
+		peer := newTestPeer("10.0.0.1", true)
+		require.NoError(t, service.CreatePeer(ctx, peer))
+		service.config.LocalASN = 65100
+
+		description := "Transit"
+		err := service.PatchPeer(ctx, peer.ID, &PeerPatch{Description: &description})
+		assert.ErrorIs(t, err, ErrASNMismatch)
+		_, err = service.PlanPatchPeer(ctx, peer.ID, &PeerPatch{Description: &description})
+		assert.ErrorIs(t, err, ErrASNMismatch)
+		updates := *peer
+		updates.Description = description
+		assert.ErrorIs(t, service.UpdatePeer(ctx, peer.ID, &updates), ErrASNMismatch)
+
+		// local-as presents the peer's AS while the router keeps its own
+		peer.ASN = 65100
+		require.NoError(t, service.db.Model(peer).Update("asn", peer.ASN).Error)
+		localAS := uint32(65001)
+		mode := models.LocalASNoPrepend
+		require.NoError(t, service.PatchPeer(ctx, peer.ID, &PeerPatch{LocalAS: &localAS, LocalASMode: &mode}))
+	})
+
+	t.Run("Peer not found", func(t *testing.T) {
+		service := setupTestService(t, ConsistencyEventual)
+
+		err := service.PatchPeer(ctx, 999, &PeerPatch{})
+		assert.ErrorIs(t, err, ErrPeerNotFound)
+	})
+}
+
+func TestSyncPendingPeers(t *testing.T) {
+	ctx := context.Background()
+	service := setupTestService(t, ConsistencyEventual)
+
+	enabled := newTestPeer("10.0.0.1", true)
+	require.NoError(t, service.CreatePeer(ctx, enabled))
+
+	// Disabling a peer fails to reach FRR too, leaving it pending
+	disabled := newTestPeer("10.0.0.2", true)
+	require.NoError(t, service.CreatePeer(ctx, disabled))
+	updates := *disabled
+	updates.Enabled = false
+	require.NoError(t, service.UpdatePeer(ctx, disabled.ID, &updates))
+
+	require.NoError(t, service.SyncPendingPeers(ctx))
+
+	stored, err := service.GetPeer(ctx, enabled.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.PeerSyncPending, stored.SyncStatus)
+
+	stored, err = service.GetPeer(ctx, disabled.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.PeerSyncSynced, stored.SyncStatus)
+	assert.Empty(t, stored.SyncError)
+}
+
+func TestPeerPasswords(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Encrypted at rest and not serialized", func(t *testing.T) {
+		service := setupTestService(t, ConsistencyEventual)
+
+		peer := newTestPeer("10.0.0.1", true)
+		peer.Password = "s3cret"
+		require.NoError(t, service.CreatePeer(ctx, peer))
+
+		var raw string
+		require.NoError(t, service.db.Raw("SELECT password FROM bgp_peers WHERE id = ?", peer.ID).Scan(&raw).Error)
+		assert.True(t, encryption.IsEncrypted(raw))
+		assert.NotContains(t, raw, "s3cret")
+
+		stored, err := service.GetPeer(ctx, peer.ID)
+		require.NoError(t, err)
+		assert.True(t, stored.HasPassword)
+
+		body, err := json.Marshal(stored)
+		require.NoError(t, err)
+		assert.NotContains(t, string(body), "s3cret")
+		assert.NotContains(t, string(body), raw)
+
+		cfg, err := service.peerConfig(ctx, stored)
+		require.NoError(t, err)
+		assert.Equal(t, "s3cret", cfg.Password)
+	})
+
+	t.Run("Update keeps password", func(t *testing.T) {
+		service := setupTestService(t, ConsistencyEventual)
+
+		peer := newTestPeer("10.0.0.1", true)
+		peer.Password = "s3cret"
+		require.NoError(t, service.CreatePeer(ctx, peer))
+
+		updates := &models.BGPPeer{Name: "Renamed", Enabled: true}
+		require.NoError(t, service.UpdatePeer(ctx, peer.ID, updates))
+
+		stored, err := service.GetPeer(ctx, peer.ID)
+		require.NoError(t, err)
+		cfg, err := service.peerConfig(ctx, stored)
+		require.NoError(t, err)
+		assert.Equal(t, "s3cret", cfg.Password)
+	})
+
+	t.Run("Rotate and clear", func(t *testing.T) {
+		service := setupTestService(t, ConsistencyEventual)
+
+		peer := newTestPeer("10.0.0.1", true)
+		peer.Password = "old"
+		require.NoError(t, service.CreatePeer(ctx, peer))
+
+		require.NoError(t, service.SetPeerPassword(ctx, peer.ID, "new"))
+		stored, err := service.GetPeer(ctx, peer.ID)
+		require.NoError(t, err)
+		cfg, err := service.peerConfig(ctx, stored)
+		require.NoError(t, err)
+		assert.Equal(t, "new", cfg.Password)
+
+		require.NoError(t, service.SetPeerPassword(ctx, peer.ID, ""))
+		stored, err = service.GetPeer(ctx, peer.ID)
+		require.NoError(t, err)
+		assert.False(t, stored.HasPassword)
+
+		assert.ErrorIs(t, service.SetPeerPassword(ctx, 999, "x"), ErrPeerNotFound)
+	})
+
+	t.Run("Secret reference is resolved on apply", func(t *testing.T) {
+		service := setupTestService(t, ConsistencyEventual)
+
+		peer := newTestPeer("10.0.0.1", true)
+		peer.Password = "secret://env/FLINTROUTE_TEST_PEER_PASSWORD"
+		require.NoError(t, service.CreatePeer(ctx, peer))
+
+		_, err := service.peerConfig(ctx, peer)
+		assert.Error(t, err, "references need a resolver")
+
+		service.config.Secrets = secrets.NewResolver(secrets.NewEnvProvider())
+		t.Setenv("FLINTROUTE_TEST_PEER_PASSWORD", "v1")
+		cfg, err := service.peerConfig(ctx, peer)
+		require.NoError(t, err)
+		assert.Equal(t, "v1", cfg.Password)
+
+		t.Setenv("FLINTROUTE_TEST_PEER_PASSWORD", "v2")
+		cfg, err = service.peerConfig(ctx, peer)
+		require.NoError(t, err)
+		assert.Equal(t, "v2", cfg.Password)
+	})
+
+	t.Run("Legacy plaintext is encrypted", func(t *testing.T) {
+		service := setupTestService(t, ConsistencyEventual)
+
+		peer := newTestPeer("10.0.0.1", true)
+		require.NoError(t, service.CreatePeer(ctx, peer))
+		require.NoError(t, service.db.Model(peer).UpdateColumn("password", "legacy").Error)
+
+		require.NoError(t, service.EncryptStoredPasswords(ctx))
+
+		stored, err := service.GetPeer(ctx, peer.ID)
+		require.NoError(t, err)
+		assert.True(t, encryption.IsEncrypted(stored.Password))
+		cfg, err := service.peerConfig(ctx, stored)
+		require.NoError(t, err)
+		assert.Equal(t, "legacy", cfg.Password)
+	})
+}
+
+func TestUpdateSessionStates(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Alerts once when FRR is unreachable", func(t *testing.T) {
+		service := setupTestService(t, ConsistencyEventual)
+
+		require.NoError(t, service.UpdateSessionStates(ctx))
+		require.NoError(t, service.UpdateSessionStates(ctx))
+
+		var alerts []models.Alert
+		require.NoError(t, service.db.Where("type = ?", "frr_unreachable").Find(&alerts).Error)
+		require.Len(t, alerts, 1)
+		assert.Equal(t, "critical", alerts[0].Severity)
+	})
+
+	t.Run("Detects maximum-prefix violations", func(t *testing.T) {
+		peer := &models.BGPPeer{MaxPrefixes: 100}
+
+		assert.False(t, maxPrefixExceeded(peer, &frr.BGPSessionState{PrefixesReceived: 100}))
+		assert.True(t, maxPrefixExceeded(peer, &frr.BGPSessionState{PrefixesReceived: 101}))
+		assert.True(t, maxPrefixExceeded(peer, &frr.BGPSessionState{LastError: "Maximum number of prefixes reached"}))
+		assert.False(t, maxPrefixExceeded(&models.BGPPeer{}, &frr.BGPSessionState{PrefixesReceived: 101}))
+	})
+
+	t.Run("Maximum-prefix alert severity follows the action", func(t *testing.T) {
+		service := setupTestService(t, ConsistencyEventual)
+		peer := &models.BGPPeer{
+			Name:            "edge",
+			IPAddress:       "192.0.2.1",
+			ASN:             65001,
+			RemoteASN:       65002,
+			MaxPrefixes:     100,
+			MaxPrefixAction: models.MaxPrefixWarningOnly,
+		}
+		require.NoError(t, service.db.Create(peer).Error)
+
+		service.createMaxPrefixAlert(ctx, peer, &frr.BGPSessionState{PrefixesReceived: 150})
+
+		var alert models.Alert
+		require.NoError(t, service.db.Where("type = ?", "max_prefix_exceeded").First(&alert).Error)
+		assert.Equal(t, "warning", alert.Severity)
+		assert.Equal(t, peer.ID, *alert.PeerID)
+		assert.Contains(t, alert.Message, "maximum-prefix limit of 100")
+		assert.Contains(t, alert.Details, "150 prefixes received")
+	})
+	t.Run("Writes and broadcasts only changed sessions", func(t *testing.T) {
+		service := setupTestService(t, ConsistencyEventual)
+		peer := newTestPeer("192.0.2.1", true)
+		require.NoError(t, service.db.Create(peer).Error)
+		require.NoError(t, service.db.Create(newTestPeer("192.0.2.2", false)).Error)
+
+		established := func(uptime int64, prefixes int) []*frr.BGPSessionState {
+			return []*frr.BGPSessionState{{
+				IPAddress:        "192.0.2.1",
+				State:            "Established",
+				Uptime:           uptime,
+				PrefixesReceived: prefixes,
+				RemoteRouterID:   "10.0.0.2",
+				BGPVersion:       4,
+				HoldTime:         90,
+				KeepaliveTime:    30,
+				FourByteASN:      true,
+				AddressFamilies:  []string{"ipv4-unicast", "ipv6-unicast"},
+			}}
+		}
+		client := frr.NewMockClient()
+		client.On("IsConnected").Return(true)
+		client.On("GetAllBGPSessions", mock.Anything).Return(established(100, 10), nil).Once()
+		client.On("GetAllBGPSessions", mock.Anything).Return(established(130, 10), nil).Once()
+		client.On("GetAllBGPSessions", mock.Anything).Return(established(160, 12), nil).Once()
+		client.On("GetAllBGPSessions", mock.Anything).Return([]*frr.BGPSessionState{{IPAddress: "192.0.2.1", State: "Active"}}, nil).Once()
+		service.frrClient = client
+
+		go service.wsHub.Run()
+		broadcasts := func() int {
+			// A cursor ahead of the hub replays its whole history
+			client, replay, _ := service.wsHub.Subscribe(ctx, []string{websocket.TopicSessionUpdate}, math.MaxUint64)
+			service.wsHub.Unsubscribe(client)
+			return len(replay)
+		}
+
+		require.NoError(t, service.UpdateSessionStates(ctx))
+		session, err := service.GetSession(ctx, peer.ID)
+		require.NoError(t, err)
+		assert.Equal(t, 10, session.PrefixesReceived)
+		assert.Equal(t, "10.0.0.2", session.RemoteRouterID)
+		assert.Equal(t, 90, session.HoldTime)
+		assert.True(t, session.FourByteASN)
+		assert.Equal(t, []string{"ipv4-unicast", "ipv6-unicast"}, session.AddressFamilies)
+		assert.Equal(t, 1, broadcasts())
+		created := session.UpdatedAt
+
+		// Only the uptime moved on
+		require.NoError(t, service.UpdateSessionStates(ctx))
+		session, err = service.GetSession(ctx, peer.ID)
+		require.NoError(t, err)
+		assert.Equal(t, created, session.UpdatedAt)
+		assert.Equal(t, 1, broadcasts())
+
+		require.NoError(t, service.UpdateSessionStates(ctx))
+		session, err = service.GetSession(ctx, peer.ID)
+		require.NoError(t, err)
+		assert.Equal(t, 12, session.PrefixesReceived)
+		assert.Equal(t, int64(160), session.Uptime)
+		assert.Equal(t, 2, broadcasts())
+
+		require.NoError(t, service.UpdateSessionStates(ctx))
+		var alert models.Alert
+		require.NoError(t, service.db.Where("type = ?", "peer_down").First(&alert).Error)
+		assert.Equal(t, peer.ID, *alert.PeerID)
+		session, err = service.GetSession(ctx, peer.ID)
+		require.NoError(t, err)
+		assert.Empty(t, session.RemoteRouterID)
+		assert.Empty(t, session.AddressFamilies)
+
+		var count int64
+		require.NoError(t, service.db.Model(&models.BGPSession{}).Count(&count).Error)
+		assert.Equal(t, int64(1), count)
+		assert.Equal(t, 3, broadcasts())
+
+		// Prefix counts are sampled when they change
+		require.NoError(t, service.db.Model(&models.PrefixSample{}).Count(&count).Error)
+		assert.Equal(t, int64(3), count)
+
+		// Only the state change is recorded as an event
+		var events []models.SessionEvent
+		require.NoError(t, service.db.Find(&events).Error)
+		require.Len(t, events, 1)
+		assert.Equal(t, peer.ID, events[0].PeerID)
+		assert.Equal(t, "Established", events[0].OldState)
+		assert.Equal(t, "Active", events[0].NewState)
+		client.AssertNumberOfCalls(t, "GetAllBGPSessions", 4)
+	})
+
+	t.Run("Detects session resets", func(t *testing.T) {
+		now := time.Now()
+		session := &models.BGPSession{State: "Established", Uptime: 100, UpdatedAt: now.Add(-time.Minute)}
+
+		assert.Equal(t, int64(160), sessionUptime(session, now))
+		assert.False(t, sessionChanged(session, &frr.BGPSessionState{State: "Established", Uptime: 158}, now))
+		assert.True(t, sessionChanged(session, &frr.BGPSessionState{State: "Established", Uptime: 20}, now))
+		assert.True(t, sessionChanged(session, &frr.BGPSessionState{State: "Established", Uptime: 160, MessagesSent: 1}, now))
+		assert.True(t, sessionChanged(session, &frr.BGPSessionState{State: "Established", Uptime: 160, AddressFamilies: []string{"ipv4-unicast"}}, now))
+	})
+}
+
+func TestTenantScoping(t *testing.T) {
+	service := setupTestService(t, ConsistencyEventual)
+	first := tenancy.WithTenant(context.Background(), 1)
+	second := tenancy.WithTenant(context.Background(), 2)
+
+	peer := newTestPeer("192.0.2.1", false)
+	require.NoError(t, service.CreatePeer(first, peer))
+	require.NotNil(t, peer.TenantID, "peers belong to the tenant creating them")
+	assert.Equal(t, uint(1), *peer.TenantID)
+	require.NoError(t, service.CreatePeer(second, newTestPeer("192.0.2.2", false)))
+	require.NoError(t, service.db.Create(&models.BGPSession{PeerID: peer.ID, State: "Established"}).Error)
+
+	t.Run("Tenants only see their own peers", func(t *testing.T) {
+		_, err := service.GetPeer(first, peer.ID)
+		assert.NoError(t, err)
+		_, err = service.GetPeer(second, peer.ID)
+		assert.ErrorIs(t, err, ErrPeerNotFound)
+
+		peers, err := service.ListPeers(second)
+		require.NoError(t, err)
+		require.Len(t, peers, 1)
+		assert.Equal(t, "192.0.2.2", peers[0].IPAddress)
+
+		assert.ErrorIs(t, service.PatchPeer(second, peer.ID, &PeerPatch{Description: ptr("taken")}), ErrPeerNotFound)
+		assert.ErrorIs(t, service.DeletePeer(second, peer.ID), ErrPeerNotFound)
+	})
+
+	t.Run("Sessions follow their peer", func(t *testing.T) {
+		sessions, err := service.ListSessions(first)
+		require.NoError(t, err)
+		assert.Len(t, sessions, 1)
+
+		sessions, err = service.ListSessions(second)
+		require.NoError(t, err)
+		assert.Empty(t, sessions)
+	})
+
+	t.Run("A context without a tenant sees every tenant", func(t *testing.T) {
+		peers, err := service.ListPeers(context.Background())
+		require.NoError(t, err)
+		assert.Len(t, peers, 2)
+	})
+}
+
+func TestMockedRepositories(t *testing.T) {
+	ctx := context.Background()
+
+	setup := func(t *testing.T) (*Service, *repository.MockPeerRepo, *repository.MockSessionRepo) {
+		t.Helper()
+		service := setupTestService(t, ConsistencyEventual)
+		peers, sessions := repository.NewMockPeerRepo(), repository.NewMockSessionRepo()
+		service.peers, service.sessions = peers, sessions
+		t.Cleanup(func() {
+			peers.AssertExpectations(t)
+			sessions.AssertExpectations(t)
+		})
+		return service, peers, sessions
+	}
+
+	t.Run("Maps missing records to service errors", func(t *testing.T) {
+		service, peers, sessions := setup(t)
+		peers.On("Get", ctx, uint(1)).Return(nil, repository.ErrNotFound).Once()
+		sessions.On("GetByPeer", ctx, uint(1)).Return(nil, repository.ErrNotFound).Once()
+
+		_, err := service.GetPeer(ctx, 1)
+		assert.ErrorIs(t, err, ErrPeerNotFound)
+		_, err = service.GetSession(ctx, 1)
+		assert.ErrorIs(t, err, ErrSessionNotFound)
+	})
+
+	t.Run("Rejects an IP address held by a peer in the trash", func(t *testing.T) {
+		service, peers, _ := setup(t)
+		trashed := newTestPeer("10.0.0.1", true)
+		trashed.ID = 3
+		trashed.DeletedAt.Valid = true
+		peers.On("FindByIP", ctx, "10.0.0.1", uint(0)).Return(trashed, nil).Once()
+
+		err := service.CreatePeer(ctx, newTestPeer("10.0.0.1", true))
+		assert.ErrorIs(t, err, ErrPeerExists)
+		assert.ErrorContains(t, err, "in the trash as peer 3")
+	})
+
+	t.Run("Marks a peer FRR rejected as pending sync", func(t *testing.T) {
+		service, peers, _ := setup(t)
+		peer := newTestPeer("10.0.0.1", true)
+		peers.On("FindByIP", ctx, "10.0.0.1", uint(0)).Return(nil, repository.ErrNotFound).Once()
+		peers.On("Save", ctx, peer).Return(nil).Once()
+		peers.On("SetSyncStatus", ctx, peer, models.PeerSyncPending, mock.Anything).Return(nil).Once()
+
+		require.NoError(t, service.CreatePeer(ctx, peer))
+		assert.Equal(t, models.PeerSyncPending, peer.SyncStatus)
+	})
+}
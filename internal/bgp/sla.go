@@ -0,0 +1,165 @@
+package bgp
+
+import (
+	"context"
+	"time"
+
+	"github.com/padminisys/flintroute/pkg/models"
+)
+
+// SLAIncident is a period a peer's session was down
+type SLAIncident struct {
+	Start time.Time `json:"start"`
+	// End is nil while the session is still down
+	End *time.Time `json:"end,omitempty"`
+	// Duration is how long the session was down within the report's range,
+	// in seconds
+	Duration int64 `json:"duration"`
+	// State is the state the session went down to, such as "Idle"
+	State string `json:"state"`
+	// Maintenance is set for incidents that began while the peer was under
+	// maintenance; they are planned and left out of availability and MTTR
+	Maintenance bool `json:"maintenance,omitempty"`
+}
+
+// PeerSLA is a peer's availability over the report's range
+type PeerSLA struct {
+	PeerID    uint   `json:"peer_id"`
+	Name      string `json:"name"`
+	IPAddress string `json:"ip_address"`
+	RemoteASN uint32 `json:"remote_asn"`
+	// Monitored is how much of the range the peer existed for, in seconds
+	Monitored int64 `json:"monitored"`
+	// Downtime is the unplanned downtime, in seconds
+	Downtime int64 `json:"downtime"`
+	// MaintenanceDowntime is the downtime during maintenance, in seconds
+	MaintenanceDowntime int64 `json:"maintenance_downtime"`
+	// Availability is the percentage of the monitored time the session was
+	// up, not counting maintenance downtime against it
+	Availability float64 `json:"availability"`
+	// MTTR is the mean duration of the unplanned incidents that ended, in
+	// seconds; 0 without any
+	MTTR      int64          `json:"mttr"`
+	Incidents []*SLAIncident `json:"incidents"`
+}
+
+// SLAReport is the availability of peers' sessions over a range, from
+// their session event history
+type SLAReport struct {
+	GeneratedAt time.Time  `json:"generated_at"`
+	Start       time.Time  `json:"start"`
+	End         time.Time  `json:"end"`
+	Peers       []*PeerSLA `json:"peers"`
+}
+
+// SLAReport reports the availability of peers' sessions over the window
+// ending now, for the peers matching every tag selector. The session
+// events of the window, and the last one before it, tell when each session
+// was up.
+func (s *Service) SLAReport(ctx context.Context, window time.Duration, selectors ...TagSelector) (*SLAReport, error) {
+	end := time.Now()
+	start := end.Add(-window)
+
+	peers, err := s.ListPeers(ctx, selectors...)
+	if err != nil {
+		return nil, err
+	}
+	sessions, err := s.sessions.List(ctx, selectors...)
+	if err != nil {
+		return nil, err
+	}
+	states := make(map[uint]string, len(sessions))
+	for _, session := range sessions {
+		states[session.PeerID] = session.State
+	}
+	events, err := s.sessions.ListEvents(ctx, start)
+	if err != nil {
+		return nil, err
+	}
+	byPeer := make(map[uint][]models.SessionEvent)
+	for _, event := range events {
+		byPeer[event.PeerID] = append(byPeer[event.PeerID], event)
+	}
+
+	report := &SLAReport{GeneratedAt: end, Start: start, End: end, Peers: []*PeerSLA{}}
+	for _, peer := range peers {
+		report.Peers = append(report.Peers, peerSLA(peer, states[peer.ID], byPeer[peer.ID], start, end))
+	}
+	return report, nil
+}
+
+// peerSLA works out a peer's availability between start and end from its
+// events, oldest first, and its current session state. The state before the
+// first event is that of the last event before start or, without one, the
+// state the first event left; without any events it is the current state.
+func peerSLA(peer *models.BGPPeer, state string, events []models.SessionEvent, start, end time.Time) *PeerSLA {
+	sla := &PeerSLA{
+		PeerID:    peer.ID,
+		Name:      peer.Name,
+		IPAddress: peer.IPAddress,
+		RemoteASN: peer.RemoteASN,
+		Incidents: []*SLAIncident{},
+	}
+	if peer.CreatedAt.After(start) {
+		start = peer.CreatedAt
+	}
+	sla.Monitored = int64(end.Sub(start).Seconds())
+	if sla.Monitored <= 0 {
+		sla.Monitored = 0
+		sla.Availability = 100
+		return sla
+	}
+
+	maintenance := false
+	switch {
+	case len(events) > 0 && events[0].CreatedAt.Before(start):
+		state, maintenance = events[0].NewState, events[0].InMaintenance
+		events = events[1:]
+	case len(events) > 0:
+		state = events[0].OldState
+	}
+
+	var incident *SLAIncident
+	down := func(at time.Time, state string, maintenance bool) {
+		incident = &SLAIncident{Start: at, State: state, Maintenance: maintenance}
+		sla.Incidents = append(sla.Incidents, incident)
+	}
+	up := func(at time.Time) {
+		incident.End = &at
+		incident.Duration = int64(at.Sub(incident.Start).Seconds())
+		incident = nil
+	}
+
+	if state != "Established" {
+		down(start, state, maintenance)
+	}
+	for _, event := range events {
+		switch {
+		case event.NewState == "Established" && incident != nil:
+			up(event.CreatedAt)
+		case event.NewState != "Established" && incident == nil:
+			down(event.CreatedAt, event.NewState, event.InMaintenance)
+		}
+	}
+	if incident != nil {
+		incident.Duration = int64(end.Sub(incident.Start).Seconds())
+	}
+
+	var resolved, repair int64
+	for _, incident := range sla.Incidents {
+		if incident.Maintenance {
+			sla.MaintenanceDowntime += incident.Duration
+			continue
+		}
+		sla.Downtime += incident.Duration
+		if incident.End != nil {
+			resolved++
+			repair += incident.Duration
+		}
+	}
+	if resolved > 0 {
+		sla.MTTR = repair / resolved
+	}
+	sla.Availability = 100 * float64(sla.Monitored-sla.Downtime) / float64(sla.Monitored)
+	return sla
+}
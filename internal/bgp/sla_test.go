@@ -0,0 +1,94 @@
+package bgp
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/padminisys/flintroute/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPeerSLA(t *testing.T) {
+	end := time.Date(2024, 3, 31, 0, 0, 0, 0, time.UTC)
+	start := end.Add(-100 * time.Hour)
+	peer := &models.BGPPeer{ID: 1, Name: "edge", IPAddress: "192.0.2.1", CreatedAt: start.Add(-time.Hour)}
+	at := func(hours int) time.Time { return start.Add(time.Duration(hours) * time.Hour) }
+
+	t.Run("Counts unplanned incidents", func(t *testing.T) {
+		sla := peerSLA(peer, "Idle", []models.SessionEvent{
+			{CreatedAt: start.Add(-2 * time.Hour), NewState: "Established"},
+			{CreatedAt: at(10), OldState: "Established", NewState: "Idle"},
+			{CreatedAt: at(12), OldState: "Idle", NewState: "Established"},
+			{CreatedAt: at(50), OldState: "Established", NewState: "Active", InMaintenance: true},
+			{CreatedAt: at(55), OldState: "Active", NewState: "Established", InMaintenance: true},
+			{CreatedAt: at(96), OldState: "Established", NewState: "Idle"},
+		}, start, end)
+
+		require.Len(t, sla.Incidents, 3)
+		assert.Equal(t, int64(2*3600), sla.Incidents[0].Duration)
+		assert.True(t, sla.Incidents[1].Maintenance)
+		assert.Nil(t, sla.Incidents[2].End)
+		assert.Equal(t, int64(6*3600), sla.Downtime)
+		assert.Equal(t, int64(5*3600), sla.MaintenanceDowntime)
+		// Only the incident that ended counts toward MTTR
+		assert.Equal(t, int64(2*3600), sla.MTTR)
+		assert.InDelta(t, 94.0, sla.Availability, 0.001)
+	})
+
+	t.Run("Starts in the state before the range", func(t *testing.T) {
+		sla := peerSLA(peer, "Established", []models.SessionEvent{
+			{CreatedAt: start.Add(-time.Hour), NewState: "Active"},
+			{CreatedAt: at(25), OldState: "Active", NewState: "Established"},
+		}, start, end)
+
+		require.Len(t, sla.Incidents, 1)
+		assert.Equal(t, start, sla.Incidents[0].Start)
+		assert.Equal(t, "Active", sla.Incidents[0].State)
+		assert.InDelta(t, 75.0, sla.Availability, 0.001)
+	})
+
+	t.Run("Uses the current state without events", func(t *testing.T) {
+		assert.Equal(t, 100.0, peerSLA(peer, "Established", nil, start, end).Availability)
+		assert.Equal(t, 0.0, peerSLA(peer, "Idle", nil, start, end).Availability)
+	})
+
+	t.Run("Covers only the time since the peer was created", func(t *testing.T) {
+		created := &models.BGPPeer{ID: 2, CreatedAt: at(50)}
+		sla := peerSLA(created, "Established", []models.SessionEvent{
+			{CreatedAt: at(50), OldState: "", NewState: "Established"},
+		}, start, end)
+		assert.Equal(t, int64(50*3600), sla.Monitored)
+		assert.Equal(t, 100.0, sla.Availability)
+	})
+}
+
+func TestSLAReport(t *testing.T) {
+	ctx := context.Background()
+	service := setupTestService(t, ConsistencyEventual)
+
+	peer := newTaggedPeer("10.0.0.1", map[string]string{"pop": "fra1"})
+	require.NoError(t, service.CreatePeer(ctx, peer))
+	other := newTaggedPeer("10.0.0.2", map[string]string{"pop": "ams1"})
+	require.NoError(t, service.CreatePeer(ctx, other))
+	require.NoError(t, service.db.Create(&models.BGPSession{PeerID: peer.ID, State: "Established"}).Error)
+	require.NoError(t, service.db.Model(peer).UpdateColumn("created_at", time.Now().Add(-48*time.Hour)).Error)
+
+	down := time.Now().Add(-time.Hour)
+	for _, event := range []models.SessionEvent{
+		{CreatedAt: down, PeerID: peer.ID, IPAddress: peer.IPAddress, OldState: "Established", NewState: "Idle"},
+		{CreatedAt: down.Add(30 * time.Minute), PeerID: peer.ID, IPAddress: peer.IPAddress, OldState: "Idle", NewState: "Established"},
+	} {
+		require.NoError(t, service.db.Create(&event).Error)
+	}
+
+	report, err := service.SLAReport(ctx, 24*time.Hour, TagSelector{Key: "pop", Value: "fra1"})
+	require.NoError(t, err)
+	require.Len(t, report.Peers, 1)
+	sla := report.Peers[0]
+	assert.Equal(t, peer.IPAddress, sla.IPAddress)
+	require.Len(t, sla.Incidents, 1)
+	assert.InDelta(t, 1800, sla.MTTR, 1)
+	assert.Less(t, sla.Availability, 100.0)
+}
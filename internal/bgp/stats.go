@@ -0,0 +1,157 @@
+package bgp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/padminisys/flintroute/pkg/models"
+)
+
+// Dimensions session statistics can be aggregated by
+const (
+	// StatsByRemoteASN groups peers by their remote ASN
+	StatsByRemoteASN = "remote_asn"
+	// StatsByPeerGroup groups peers by the FRR peer-group they were
+	// imported from (PeerGroupTag)
+	StatsByPeerGroup = "peer_group"
+	// StatsByTag groups peers by the value of one of their tags
+	StatsByTag = "tag"
+	// StatsByRouter groups peers by the local ASN of the BGP instance they
+	// belong to
+	StatsByRouter = "router"
+)
+
+// StatsDimensions are the dimensions session statistics can be aggregated
+// by
+var StatsDimensions = []string{StatsByRemoteASN, StatsByPeerGroup, StatsByTag, StatsByRouter}
+
+// ErrInvalidDimension is returned when statistics are aggregated by an
+// unknown dimension, or by tag without a tag key
+var ErrInvalidDimension = errors.New("invalid dimension")
+
+// SessionStats are peer and session counts for a group of peers
+type SessionStats struct {
+	// Key is the group's value of the dimension; empty for peers without
+	// one, such as peers that aren't in a peer-group
+	Key         string `json:"key"`
+	Peers       int    `json:"peers"`
+	Enabled     int    `json:"enabled"`
+	Established int    `json:"established"`
+	// EstablishedRatio is Established over Enabled, 0 without enabled
+	// peers
+	EstablishedRatio float64 `json:"established_ratio"`
+	PrefixesReceived int     `json:"prefixes_received"`
+	PrefixesSent     int     `json:"prefixes_sent"`
+	// AverageUptime is the mean uptime of established sessions, in seconds
+	AverageUptime int64 `json:"average_uptime"`
+
+	uptime int64
+}
+
+// StatsReport is the result of aggregating session statistics
+type StatsReport struct {
+	GeneratedAt time.Time `json:"generated_at"`
+	By          string    `json:"by"`
+	TagKey      string    `json:"tag_key,omitempty"`
+	// Total covers every peer
+	Total  *SessionStats   `json:"total"`
+	Groups []*SessionStats `json:"groups"`
+}
+
+// add counts a peer and its session, if it has one
+func (s *SessionStats) add(peer *models.BGPPeer, session *models.BGPSession) {
+	s.Peers++
+	if peer.Enabled {
+		s.Enabled++
+	}
+	if session == nil {
+		return
+	}
+	s.PrefixesReceived += session.PrefixesReceived
+	s.PrefixesSent += session.PrefixesSent
+	if session.State == "Established" {
+		s.Established++
+		s.uptime += session.Uptime
+	}
+}
+
+// finish computes the ratio and average
+func (s *SessionStats) finish() {
+	if s.Enabled > 0 {
+		s.EstablishedRatio = float64(s.Established) / float64(s.Enabled)
+	}
+	if s.Established > 0 {
+		s.AverageUptime = s.uptime / int64(s.Established)
+	}
+}
+
+// SessionStatistics aggregates peer counts, the established ratio, prefix
+// counts and average uptime by dimension, one of StatsDimensions. By tag,
+// tagKey names the tag. Groups are ordered by key, numerically for ASNs.
+func (s *Service) SessionStatistics(ctx context.Context, by, tagKey string) (*StatsReport, error) {
+	var key func(*models.BGPPeer) string
+	switch by {
+	case StatsByRemoteASN:
+		key = func(p *models.BGPPeer) string { return strconv.FormatUint(uint64(p.RemoteASN), 10) }
+	case StatsByRouter:
+		key = func(p *models.BGPPeer) string { return strconv.FormatUint(uint64(p.ASN), 10) }
+	case StatsByPeerGroup:
+		key = func(p *models.BGPPeer) string { return p.Tags.Map()[PeerGroupTag] }
+	case StatsByTag:
+		if tagKey == "" {
+			return nil, fmt.Errorf("%w: aggregating by tag needs a tag key", ErrInvalidDimension)
+		}
+		key = func(p *models.BGPPeer) string { return p.Tags.Map()[tagKey] }
+	default:
+		return nil, fmt.Errorf("%w %q: must be one of %s", ErrInvalidDimension, by, strings.Join(StatsDimensions, ", "))
+	}
+
+	peers, err := s.ListPeers(ctx)
+	if err != nil {
+		return nil, err
+	}
+	sessions, err := s.ListSessions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	byPeer := make(map[uint]*models.BGPSession, len(sessions))
+	for _, session := range sessions {
+		byPeer[session.PeerID] = session
+	}
+
+	report := &StatsReport{GeneratedAt: time.Now(), By: by, Total: &SessionStats{}, Groups: []*SessionStats{}}
+	if by == StatsByTag {
+		report.TagKey = tagKey
+	}
+	groups := make(map[string]*SessionStats)
+	for _, peer := range peers {
+		k := key(peer)
+		group, ok := groups[k]
+		if !ok {
+			group = &SessionStats{Key: k}
+			groups[k] = group
+			report.Groups = append(report.Groups, group)
+		}
+		group.add(peer, byPeer[peer.ID])
+		report.Total.add(peer, byPeer[peer.ID])
+	}
+
+	numeric := by == StatsByRemoteASN || by == StatsByRouter
+	sort.Slice(report.Groups, func(i, j int) bool {
+		a, b := report.Groups[i].Key, report.Groups[j].Key
+		if numeric && len(a) != len(b) {
+			return len(a) < len(b)
+		}
+		return a < b
+	})
+	for _, group := range report.Groups {
+		group.finish()
+	}
+	report.Total.finish()
+	return report, nil
+}
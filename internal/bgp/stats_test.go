@@ -0,0 +1,87 @@
+package bgp
+
+import (
+	"context"
+	"testing"
+
+	"github.com/padminisys/flintroute/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSessionStatistics(t *testing.T) {
+	ctx := context.Background()
+	service := setupTestService(t, ConsistencyEventual)
+
+	addPeer := func(ip string, remoteASN uint32, enabled bool, tags map[string]string, session *models.BGPSession) {
+		peer := newTaggedPeer(ip, tags)
+		peer.RemoteASN = remoteASN
+		peer.Enabled = enabled
+		require.NoError(t, service.CreatePeer(ctx, peer))
+		if session != nil {
+			session.PeerID = peer.ID
+			require.NoError(t, service.db.Create(session).Error)
+		}
+	}
+	addPeer("10.0.0.1", 65100, true, map[string]string{"pop": "fra1", PeerGroupTag: "TRANSIT"},
+		&models.BGPSession{State: "Established", Uptime: 100, PrefixesReceived: 1000, PrefixesSent: 10})
+	addPeer("10.0.0.2", 65100, true, map[string]string{"pop": "ams1", PeerGroupTag: "TRANSIT"},
+		&models.BGPSession{State: "Established", Uptime: 300, PrefixesReceived: 500})
+	addPeer("10.0.0.3", 9000, true, map[string]string{"pop": "fra1"},
+		&models.BGPSession{State: "Active"})
+	addPeer("10.0.0.4", 9000, false, nil, nil)
+
+	t.Run("By remote ASN", func(t *testing.T) {
+		report, err := service.SessionStatistics(ctx, StatsByRemoteASN, "")
+		require.NoError(t, err)
+		require.Len(t, report.Groups, 2)
+
+		small := report.Groups[0]
+		assert.Equal(t, "9000", small.Key)
+		assert.Equal(t, 2, small.Peers)
+		assert.Equal(t, 1, small.Enabled)
+		assert.Zero(t, small.EstablishedRatio)
+		assert.Zero(t, small.AverageUptime)
+
+		transit := report.Groups[1]
+		assert.Equal(t, "65100", transit.Key)
+		assert.Equal(t, 2, transit.Established)
+		assert.Equal(t, 1.0, transit.EstablishedRatio)
+		assert.Equal(t, 1500, transit.PrefixesReceived)
+		assert.Equal(t, 10, transit.PrefixesSent)
+		assert.InDelta(t, 200, transit.AverageUptime, 5)
+
+		assert.Equal(t, 4, report.Total.Peers)
+		assert.InDelta(t, 2.0/3.0, report.Total.EstablishedRatio, 0.001)
+	})
+
+	t.Run("By peer group and tag", func(t *testing.T) {
+		report, err := service.SessionStatistics(ctx, StatsByPeerGroup, "")
+		require.NoError(t, err)
+		require.Len(t, report.Groups, 2)
+		assert.Equal(t, "", report.Groups[0].Key)
+		assert.Equal(t, "TRANSIT", report.Groups[1].Key)
+		assert.Equal(t, 2, report.Groups[1].Peers)
+
+		report, err = service.SessionStatistics(ctx, StatsByTag, "pop")
+		require.NoError(t, err)
+		assert.Equal(t, "pop", report.TagKey)
+		var keys []string
+		for _, group := range report.Groups {
+			keys = append(keys, group.Key)
+		}
+		assert.Equal(t, []string{"", "ams1", "fra1"}, keys)
+
+		report, err = service.SessionStatistics(ctx, StatsByRouter, "")
+		require.NoError(t, err)
+		require.Len(t, report.Groups, 1)
+		assert.Equal(t, "65001", report.Groups[0].Key)
+	})
+
+	t.Run("Rejects unknown dimensions", func(t *testing.T) {
+		_, err := service.SessionStatistics(ctx, "state", "")
+		assert.ErrorIs(t, err, ErrInvalidDimension)
+		_, err = service.SessionStatistics(ctx, StatsByTag, "")
+		assert.ErrorIs(t, err, ErrInvalidDimension)
+	})
+}
@@ -0,0 +1,195 @@
+package bgp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/padminisys/flintroute/internal/frr"
+	"github.com/padminisys/flintroute/internal/frrlog"
+	"github.com/padminisys/flintroute/internal/tenancy"
+	"github.com/padminisys/flintroute/pkg/models"
+	"go.uber.org/zap"
+)
+
+// Reasons a full peer sync runs
+const (
+	SyncTriggerStartup   = "startup"   // FlintRoute started with FRR reachable
+	SyncTriggerReconnect = "reconnect" // FRR became reachable again
+	SyncTriggerRestore   = "restore"   // A database snapshot was restored
+)
+
+// PeerSyncResult is the outcome of applying a single peer to FRR
+type PeerSyncResult struct {
+	PeerID    uint   `json:"peer_id"`
+	IPAddress string `json:"ip_address"`
+	Applied   bool   `json:"applied"`
+	Error     string `json:"error,omitempty"`
+
+	tenantID *uint // the peer's tenant
+}
+
+// PeerSyncReport is the result of applying every enabled peer to FRR
+type PeerSyncReport struct {
+	Trigger  string            `json:"trigger"`
+	SyncedAt time.Time         `json:"synced_at"`
+	Applied  int               `json:"applied"`
+	Failed   int               `json:"failed"`
+	Peers    []*PeerSyncResult `json:"peers"`
+}
+
+// forTenant returns the part of the report covering the peers of a tenant
+func (r *PeerSyncReport) forTenant(id uint) *PeerSyncReport {
+	report := &PeerSyncReport{Trigger: r.Trigger, SyncedAt: r.SyncedAt, Peers: []*PeerSyncResult{}}
+	for _, result := range r.Peers {
+		if result.tenantID == nil || *result.tenantID != id {
+			continue
+		}
+		report.Peers = append(report.Peers, result)
+		if result.Applied {
+			report.Applied++
+		} else {
+			report.Failed++
+		}
+	}
+	return report
+}
+
+// SyncAllPeers pushes every enabled peer to FRR, so a freshly started or
+// restarted FRR ends up with the stored configuration. Peers that fail are
+// marked pending, which makes the monitoring loop retry them, and raise a
+// peer_sync_failed alert.
+func (s *Service) SyncAllPeers(ctx context.Context, trigger string) (*PeerSyncReport, error) {
+	var peers []*models.BGPPeer
+	if err := s.db.Where("enabled = ?", true).Order("id").Find(&peers).Error; err != nil {
+		return nil, err
+	}
+
+	report := &PeerSyncReport{
+		Trigger:  trigger,
+		SyncedAt: time.Now(),
+		Peers:    make([]*PeerSyncResult, 0, len(peers)),
+	}
+
+	errs := s.pushPeers(ctx, peers)
+	for i, peer := range peers {
+		result := &PeerSyncResult{PeerID: peer.ID, IPAddress: peer.IPAddress, tenantID: peer.TenantID}
+		report.Peers = append(report.Peers, result)
+
+		if err := errs[i]; err != nil {
+			result.Error = err.Error()
+			report.Failed++
+
+			s.setSyncStatus(ctx, peer, models.PeerSyncPending, err.Error())
+			s.recordFRRError(ctx, peer, err)
+			s.wsHub.BroadcastPeerUpdate(ctx, peer)
+			s.createSyncFailedAlert(ctx, peer, err)
+
+			s.logger.Warn("Failed to sync BGP peer to FRR",
+				zap.String("ip", peer.IPAddress),
+				zap.String("trigger", trigger),
+				zap.Error(err),
+			)
+			continue
+		}
+
+		result.Applied = true
+		report.Applied++
+
+		if peer.SyncStatus != models.PeerSyncSynced {
+			s.setSyncStatus(ctx, peer, models.PeerSyncSynced, "")
+			s.wsHub.BroadcastPeerUpdate(ctx, peer)
+		}
+	}
+
+	s.syncMu.Lock()
+	s.lastSync = report
+	s.syncMu.Unlock()
+
+	s.logger.Info("Synced BGP peers to FRR",
+		zap.String("trigger", trigger),
+		zap.Int("applied", report.Applied),
+		zap.Int("failed", report.Failed),
+	)
+
+	return report, nil
+}
+
+// pushPeers pushes peers to FRR, SyncBatchSize of them per commit, and
+// returns the error of each. When a batch fails for anything but FRR being
+// unreachable, its peers are pushed again one at a time, so that only those
+// FRR rejects fail.
+func (s *Service) pushPeers(ctx context.Context, peers []*models.BGPPeer) []error {
+	errs := make([]error, len(peers))
+	size := max(s.config.SyncBatchSize, 1)
+	for start := 0; start < len(peers); start += size {
+		end := min(start+size, len(peers))
+		if end-start == 1 {
+			errs[start] = s.updateInFRR(ctx, peers[start])
+			continue
+		}
+
+		var batch []*models.BGPPeer
+		var configs []*frr.BGPPeerConfig
+		for i := start; i < end; i++ {
+			config, err := s.peerConfig(ctx, peers[i])
+			if err != nil {
+				errs[i] = err
+				continue
+			}
+			batch = append(batch, peers[i])
+			configs = append(configs, config)
+		}
+
+		err := frr.UpdateBGPPeers(frrlog.WithPeers(ctx, batch), s.frrClient, configs)
+		if err == nil {
+			continue
+		}
+		s.logger.Warn("Failed to sync a batch of BGP peers to FRR", zap.Int("peers", len(batch)), zap.Error(err))
+		for i := start; i < end; i++ {
+			switch {
+			case errs[i] != nil:
+			case errors.Is(err, frr.ErrNotConnected):
+				errs[i] = err
+			default:
+				errs[i] = s.updateInFRR(ctx, peers[i])
+			}
+		}
+	}
+	return errs
+}
+
+// LastSyncReport returns the report of the most recent full peer sync,
+// limited to the peers of the tenant ctx acts for, if any
+func (s *Service) LastSyncReport(ctx context.Context) *PeerSyncReport {
+	s.syncMu.Lock()
+	defer s.syncMu.Unlock()
+
+	id, ok := tenancy.FromContext(ctx)
+	if !ok || s.lastSync == nil {
+		return s.lastSync
+	}
+	return s.lastSync.forTenant(id)
+}
+
+// syncAllPeers runs a full peer sync from the monitoring loop, logging
+// rather than returning errors
+func (s *Service) syncAllPeers(ctx context.Context, trigger string) {
+	if _, err := s.SyncAllPeers(ctx, trigger); err != nil {
+		s.logger.Error("Failed to sync BGP peers to FRR", zap.String("trigger", trigger), zap.Error(err))
+	}
+}
+
+// createSyncFailedAlert creates an alert for a peer that could not be
+// applied to FRR
+func (s *Service) createSyncFailedAlert(ctx context.Context, peer *models.BGPPeer, err error) {
+	alert := models.Alert{
+		Type:     "peer_sync_failed",
+		Severity: "error",
+		Message:  fmt.Sprintf("BGP peer %s (%s) could not be applied to FRR", peer.Name, peer.IPAddress),
+		Details:  err.Error(),
+		PeerID:   &peer.ID,
+	}
+	s.raiseAlert(ctx, &alert, true)
+}
@@ -0,0 +1,179 @@
+package bgp
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/padminisys/flintroute/internal/frr"
+	"github.com/padminisys/flintroute/internal/tenancy"
+	"github.com/padminisys/flintroute/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func peerIP(ip string) interface{} {
+	return mock.MatchedBy(func(cfg *frr.BGPPeerConfig) bool { return cfg.IPAddress == ip })
+}
+
+// batchingClient is a mock FRR client that commits batches of peers
+type batchingClient struct {
+	*frr.MockClient
+}
+
+func (c batchingClient) UpdateBGPPeers(ctx context.Context, configs []*frr.BGPPeerConfig) error {
+	args := c.Called(ctx, configs)
+	return args.Error(0)
+}
+
+func TestSyncAllPeers(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Reports a tenant's peers", func(t *testing.T) {
+		service := setupTestService(t, ConsistencyEventual)
+		require.NoError(t, service.CreatePeer(ctx, newTestPeer("10.0.0.1", true)))
+		owned := newTestPeer("10.0.0.2", true)
+		require.NoError(t, service.CreatePeer(tenancy.WithTenant(ctx, 3), owned))
+
+		client := frr.NewMockClient()
+		client.On("UpdateBGPPeer", mock.Anything, peerIP("10.0.0.1")).Return(nil)
+		client.On("UpdateBGPPeer", mock.Anything, peerIP("10.0.0.2")).Return(errors.New("unknown route-map"))
+		service.frrClient = client
+		_, err := service.SyncAllPeers(ctx, SyncTriggerStartup)
+		require.NoError(t, err)
+
+		report := service.LastSyncReport(tenancy.WithTenant(ctx, 3))
+		require.Len(t, report.Peers, 1)
+		assert.Equal(t, owned.ID, report.Peers[0].PeerID)
+		assert.Equal(t, 0, report.Applied)
+		assert.Equal(t, 1, report.Failed)
+
+		assert.Empty(t, service.LastSyncReport(tenancy.WithTenant(ctx, 4)).Peers)
+		assert.Len(t, service.LastSyncReport(ctx).Peers, 2)
+	})
+
+	t.Run("Applies enabled peers and reports failures", func(t *testing.T) {
+		service := setupTestService(t, ConsistencyEventual)
+
+		// Created while FRR is unavailable, so both start pending
+		ok := newTestPeer("10.0.0.1", true)
+		require.NoError(t, service.CreatePeer(ctx, ok))
+		rejected := newTestPeer("10.0.0.2", true)
+		require.NoError(t, service.CreatePeer(ctx, rejected))
+		disabled := newTestPeer("10.0.0.3", false)
+		require.NoError(t, service.CreatePeer(ctx, disabled))
+
+		client := frr.NewMockClient()
+		client.On("UpdateBGPPeer", mock.Anything, peerIP("10.0.0.1")).Return(nil)
+		client.On("UpdateBGPPeer", mock.Anything, peerIP("10.0.0.2")).Return(errors.New("unknown route-map"))
+		service.frrClient = client
+
+		report, err := service.SyncAllPeers(ctx, SyncTriggerStartup)
+		require.NoError(t, err)
+		client.AssertNumberOfCalls(t, "UpdateBGPPeer", 2)
+
+		assert.Equal(t, SyncTriggerStartup, report.Trigger)
+		assert.Equal(t, 1, report.Applied)
+		assert.Equal(t, 1, report.Failed)
+		require.Len(t, report.Peers, 2)
+		assert.Equal(t, &PeerSyncResult{PeerID: ok.ID, IPAddress: "10.0.0.1", Applied: true}, report.Peers[0])
+		assert.Equal(t, "unknown route-map", report.Peers[1].Error)
+		assert.Same(t, report, service.LastSyncReport(ctx))
+
+		stored, err := service.GetPeer(ctx, ok.ID)
+		require.NoError(t, err)
+		assert.Equal(t, models.PeerSyncSynced, stored.SyncStatus)
+
+		stored, err = service.GetPeer(ctx, rejected.ID)
+		require.NoError(t, err)
+		assert.Equal(t, models.PeerSyncPending, stored.SyncStatus)
+		assert.Equal(t, "unknown route-map", stored.SyncError)
+
+		var alerts []models.Alert
+		require.NoError(t, service.db.Where("type = ?", "peer_sync_failed").Find(&alerts).Error)
+		require.Len(t, alerts, 1)
+		assert.Equal(t, "error", alerts[0].Severity)
+		assert.Equal(t, rejected.ID, *alerts[0].PeerID)
+		assert.Equal(t, "unknown route-map", alerts[0].Details)
+	})
+
+	t.Run("Runs when FRR reconnects", func(t *testing.T) {
+		service := setupTestService(t, ConsistencyEventual)
+		require.NoError(t, service.CreatePeer(ctx, newTestPeer("10.0.0.1", true)))
+
+		// The first check finds FRR unreachable
+		service.checkFRRReachable(ctx)
+		assert.Nil(t, service.LastSyncReport(ctx))
+
+		client := frr.NewMockClient()
+		client.On("IsConnected").Return(true)
+		client.On("UpdateBGPPeer", mock.Anything, peerIP("10.0.0.1")).Return(nil)
+		service.frrClient = client
+
+		service.checkFRRReachable(ctx)
+		report := service.LastSyncReport(ctx)
+		require.NotNil(t, report)
+		assert.Equal(t, SyncTriggerReconnect, report.Trigger)
+		assert.Equal(t, 1, report.Applied)
+
+		// Staying connected does not sync again
+		service.checkFRRReachable(ctx)
+		client.AssertNumberOfCalls(t, "UpdateBGPPeer", 1)
+	})
+
+	t.Run("Pushes peers in batches", func(t *testing.T) {
+		service := setupTestService(t, ConsistencyEventual)
+		service.config.SyncBatchSize = 2
+		for _, ip := range []string{"10.0.0.1", "10.0.0.2", "10.0.0.3", "10.0.0.4", "10.0.0.5"} {
+			require.NoError(t, service.CreatePeer(ctx, newTestPeer(ip, true)))
+		}
+
+		client := batchingClient{frr.NewMockClient()}
+		batchOf := func(ips ...string) interface{} {
+			return mock.MatchedBy(func(configs []*frr.BGPPeerConfig) bool {
+				if len(configs) != len(ips) {
+					return false
+				}
+				for i, config := range configs {
+					if config.IPAddress != ips[i] {
+						return false
+					}
+				}
+				return true
+			})
+		}
+		client.On("UpdateBGPPeers", mock.Anything, batchOf("10.0.0.1", "10.0.0.2")).Return(nil)
+		// A rejected batch is retried a peer at a time
+		client.On("UpdateBGPPeers", mock.Anything, batchOf("10.0.0.3", "10.0.0.4")).Return(errors.New("unknown route-map"))
+		client.On("UpdateBGPPeer", mock.Anything, peerIP("10.0.0.3")).Return(nil)
+		client.On("UpdateBGPPeer", mock.Anything, peerIP("10.0.0.4")).Return(errors.New("unknown route-map"))
+		client.On("UpdateBGPPeer", mock.Anything, peerIP("10.0.0.5")).Return(nil)
+		service.frrClient = client
+
+		report, err := service.SyncAllPeers(ctx, SyncTriggerStartup)
+		require.NoError(t, err)
+		assert.Equal(t, 4, report.Applied)
+		assert.Equal(t, 1, report.Failed)
+		assert.Equal(t, "10.0.0.4", report.Peers[3].IPAddress)
+		assert.Equal(t, "unknown route-map", report.Peers[3].Error)
+		client.AssertNumberOfCalls(t, "UpdateBGPPeers", 2)
+		client.AssertNumberOfCalls(t, "UpdateBGPPeer", 3)
+	})
+
+	t.Run("Fails a batch when FRR is unreachable", func(t *testing.T) {
+		service := setupTestService(t, ConsistencyEventual)
+		service.config.SyncBatchSize = 10
+		require.NoError(t, service.CreatePeer(ctx, newTestPeer("10.0.0.1", true)))
+		require.NoError(t, service.CreatePeer(ctx, newTestPeer("10.0.0.2", true)))
+
+		client := batchingClient{frr.NewMockClient()}
+		client.On("UpdateBGPPeers", mock.Anything, mock.Anything).Return(frr.ErrNotConnected)
+		service.frrClient = client
+
+		report, err := service.SyncAllPeers(ctx, SyncTriggerStartup)
+		require.NoError(t, err)
+		assert.Equal(t, 2, report.Failed)
+		client.AssertNotCalled(t, "UpdateBGPPeer", mock.Anything, mock.Anything)
+	})
+}
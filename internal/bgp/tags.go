@@ -0,0 +1,122 @@
+package bgp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/padminisys/flintroute/internal/repository"
+	"github.com/padminisys/flintroute/internal/requestid"
+	"github.com/padminisys/flintroute/pkg/models"
+	"go.uber.org/zap"
+)
+
+// ErrInvalidTagSelector is returned for a malformed ?tag= filter
+var ErrInvalidTagSelector = errors.New("invalid tag selector")
+
+// Tag limits
+const (
+	maxTagsPerPeer = 32
+	maxTagValueLen = 255
+)
+
+// tagKeyPattern matches tag keys such as "pop" or "role.transit"
+var tagKeyPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]{0,62}$`)
+
+// validateTags checks a peer's tag keys and values
+func validateTags(tags models.PeerTags) []string {
+	var problems []string
+	if len(tags) > maxTagsPerPeer {
+		problems = append(problems, fmt.Sprintf("a peer can have at most %d tags", maxTagsPerPeer))
+	}
+	for _, tag := range tags {
+		if !tagKeyPattern.MatchString(tag.Key) {
+			problems = append(problems, fmt.Sprintf("tag key %q must be 1-63 letters, digits, '_', '.' or '-'", tag.Key))
+		}
+		if len(tag.Value) > maxTagValueLen {
+			problems = append(problems, fmt.Sprintf("tag %s value must be at most %d characters", tag.Key, maxTagValueLen))
+		}
+	}
+	return problems
+}
+
+// TagSelector matches peers by tag. An empty Value matches any value.
+type TagSelector = repository.TagSelector
+
+// ParseTagSelectors parses selectors of the form "key:value" or "key"
+func ParseTagSelectors(raw []string) ([]TagSelector, error) {
+	selectors := make([]TagSelector, 0, len(raw))
+	for _, r := range raw {
+		key, value, _ := strings.Cut(r, ":")
+		if !tagKeyPattern.MatchString(key) {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidTagSelector, r)
+		}
+		selectors = append(selectors, TagSelector{Key: key, Value: value})
+	}
+	return selectors, nil
+}
+
+// Bulk peer actions
+const (
+	BulkEnable  = "enable"
+	BulkDisable = "disable"
+	BulkDelete  = "delete"
+)
+
+// BulkResult is the outcome of a bulk action on a single peer
+type BulkResult struct {
+	PeerID    uint   `json:"peer_id"`
+	IPAddress string `json:"ip_address"`
+	Error     string `json:"error,omitempty"`
+}
+
+// BulkUpdatePeers enables, disables or deletes every peer matching the
+// selectors. Each peer is changed on its own, so one failing doesn't stop
+// the others; the results report every peer's outcome.
+func (s *Service) BulkUpdatePeers(ctx context.Context, selectors []TagSelector, action string) ([]*BulkResult, error) {
+	if len(selectors) == 0 {
+		return nil, fmt.Errorf("%w: at least one tag is required", ErrInvalidTagSelector)
+	}
+
+	var change func(peer *models.BGPPeer) error
+	switch action {
+	case BulkEnable, BulkDisable:
+		enabled := action == BulkEnable
+		change = func(peer *models.BGPPeer) error {
+			return s.PatchPeer(ctx, peer.ID, &PeerPatch{Enabled: &enabled})
+		}
+	case BulkDelete:
+		change = func(peer *models.BGPPeer) error {
+			return s.DeletePeer(ctx, peer.ID)
+		}
+	default:
+		return nil, fmt.Errorf("unknown bulk action: %s", action)
+	}
+
+	peers, err := s.ListPeers(ctx, selectors...)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]*BulkResult, 0, len(peers))
+	failed := 0
+	for _, peer := range peers {
+		result := &BulkResult{PeerID: peer.ID, IPAddress: peer.IPAddress}
+		if err := change(peer); err != nil {
+			result.Error = err.Error()
+			failed++
+		}
+		results = append(results, result)
+	}
+
+	s.logger.Info("Ran bulk peer action",
+		zap.String("action", action),
+		zap.Int("peers", len(peers)),
+		zap.Int("failed", failed),
+		requestid.Field(ctx),
+	)
+
+	return results, nil
+}
@@ -0,0 +1,170 @@
+package bgp
+
+import (
+	"context"
+	"testing"
+
+	"github.com/padminisys/flintroute/internal/frr"
+	"github.com/padminisys/flintroute/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func newTaggedPeer(ip string, tags map[string]string) *models.BGPPeer {
+	peer := newTestPeer(ip, true)
+	peer.Tags = models.NewPeerTags(tags)
+	return peer
+}
+
+func TestParseTagSelectors(t *testing.T) {
+	selectors, err := ParseTagSelectors([]string{"pop:fra1", "role", "note:a:b"})
+	require.NoError(t, err)
+	assert.Equal(t, []TagSelector{
+		{Key: "pop", Value: "fra1"},
+		{Key: "role"},
+		{Key: "note", Value: "a:b"},
+	}, selectors)
+
+	for _, raw := range []string{"", ":fra1", "bad key:x", "-pop:fra1"} {
+		_, err := ParseTagSelectors([]string{raw})
+		assert.ErrorIs(t, err, ErrInvalidTagSelector, raw)
+	}
+}
+
+func TestPeerTags(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Stores and replaces tags", func(t *testing.T) {
+		service := setupTestService(t, ConsistencyEventual)
+		peer := newTaggedPeer("10.0.0.1", map[string]string{"pop": "fra1", "role": "transit"})
+		require.NoError(t, service.CreatePeer(ctx, peer))
+
+		stored, err := service.GetPeer(ctx, peer.ID)
+		require.NoError(t, err)
+		assert.Equal(t, map[string]string{"pop": "fra1", "role": "transit"}, stored.Tags.Map())
+
+		// Patches without tags keep them
+		name := "renamed"
+		require.NoError(t, service.PatchPeer(ctx, peer.ID, &PeerPatch{Name: &name}))
+		stored, err = service.GetPeer(ctx, peer.ID)
+		require.NoError(t, err)
+		assert.Len(t, stored.Tags, 2)
+
+		require.NoError(t, service.PatchPeer(ctx, peer.ID, &PeerPatch{Tags: map[string]string{"pop": "ams1"}}))
+		stored, err = service.GetPeer(ctx, peer.ID)
+		require.NoError(t, err)
+		assert.Equal(t, map[string]string{"pop": "ams1"}, stored.Tags.Map())
+
+		// Deleted peers keep their tags in the trash until purged
+		require.NoError(t, service.DeletePeer(ctx, peer.ID))
+		var count int64
+		require.NoError(t, service.db.Model(&models.PeerTag{}).Count(&count).Error)
+		assert.Equal(t, int64(1), count)
+
+		_, err = service.PurgeDeletedPeers(ctx, 0)
+		require.NoError(t, err)
+		require.NoError(t, service.db.Model(&models.PeerTag{}).Count(&count).Error)
+		assert.Zero(t, count)
+	})
+
+	t.Run("Rejects invalid tags", func(t *testing.T) {
+		service := setupTestService(t, ConsistencyEventual)
+		err := service.CreatePeer(ctx, newTaggedPeer("10.0.0.1", map[string]string{"bad key": "x"}))
+		assert.ErrorIs(t, err, ErrInvalidPeer)
+	})
+
+	t.Run("Filters peers and sessions by tag", func(t *testing.T) {
+		service := setupTestService(t, ConsistencyEventual)
+		fra := newTaggedPeer("10.0.0.1", map[string]string{"pop": "fra1", "role": "transit"})
+		require.NoError(t, service.CreatePeer(ctx, fra))
+		ams := newTaggedPeer("10.0.0.2", map[string]string{"pop": "ams1", "role": "transit"})
+		require.NoError(t, service.CreatePeer(ctx, ams))
+		require.NoError(t, service.CreatePeer(ctx, newTestPeer("10.0.0.3", true)))
+		require.NoError(t, service.db.Create(&models.BGPSession{PeerID: fra.ID, State: "Established"}).Error)
+		require.NoError(t, service.db.Create(&models.BGPSession{PeerID: ams.ID, State: "Idle"}).Error)
+
+		peers, err := service.ListPeers(ctx, TagSelector{Key: "pop", Value: "fra1"})
+		require.NoError(t, err)
+		require.Len(t, peers, 1)
+		assert.Equal(t, fra.ID, peers[0].ID)
+
+		peers, err = service.ListPeers(ctx, TagSelector{Key: "role"})
+		require.NoError(t, err)
+		assert.Len(t, peers, 2)
+
+		peers, err = service.ListPeers(ctx, TagSelector{Key: "role", Value: "transit"}, TagSelector{Key: "pop", Value: "ams1"})
+		require.NoError(t, err)
+		require.Len(t, peers, 1)
+		assert.Equal(t, ams.ID, peers[0].ID)
+
+		peers, err = service.ListPeers(ctx)
+		require.NoError(t, err)
+		assert.Len(t, peers, 3)
+
+		sessions, err := service.ListSessions(ctx, TagSelector{Key: "pop", Value: "ams1"})
+		require.NoError(t, err)
+		require.Len(t, sessions, 1)
+		assert.Equal(t, ams.ID, sessions[0].PeerID)
+		assert.Equal(t, "ams1", sessions[0].Peer.Tags.Map()["pop"])
+	})
+}
+
+func TestBulkUpdatePeers(t *testing.T) {
+	ctx := context.Background()
+	service := setupTestService(t, ConsistencyEventual)
+
+	fra1 := newTaggedPeer("10.0.0.1", map[string]string{"pop": "fra1"})
+	require.NoError(t, service.CreatePeer(ctx, fra1))
+	fra2 := newTaggedPeer("10.0.0.2", map[string]string{"pop": "fra1"})
+	require.NoError(t, service.CreatePeer(ctx, fra2))
+	ams := newTaggedPeer("10.0.0.3", map[string]string{"pop": "ams1"})
+	require.NoError(t, service.CreatePeer(ctx, ams))
+
+	client := frr.NewMockClient()
+	client.On("UpdateBGPPeer", mock.Anything, mock.Anything).Return(nil)
+	client.On("RemoveBGPPeer", mock.Anything, mock.Anything).Return(nil)
+	service.frrClient = client
+	fra := []TagSelector{{Key: "pop", Value: "fra1"}}
+
+	t.Run("Requires a selector", func(t *testing.T) {
+		_, err := service.BulkUpdatePeers(ctx, nil, BulkDisable)
+		assert.ErrorIs(t, err, ErrInvalidTagSelector)
+	})
+
+	t.Run("Disables matching peers", func(t *testing.T) {
+		results, err := service.BulkUpdatePeers(ctx, fra, BulkDisable)
+		require.NoError(t, err)
+		require.Len(t, results, 2)
+
+		for _, peer := range []*models.BGPPeer{fra1, fra2} {
+			stored, err := service.GetPeer(ctx, peer.ID)
+			require.NoError(t, err)
+			assert.False(t, stored.Enabled)
+			assert.Len(t, stored.Tags, 1)
+		}
+
+		stored, err := service.GetPeer(ctx, ams.ID)
+		require.NoError(t, err)
+		assert.True(t, stored.Enabled)
+	})
+
+	t.Run("Deletes matching peers", func(t *testing.T) {
+		results, err := service.BulkUpdatePeers(ctx, fra, BulkDelete)
+		require.NoError(t, err)
+		assert.Equal(t, []*BulkResult{
+			{PeerID: fra1.ID, IPAddress: "10.0.0.1"},
+			{PeerID: fra2.ID, IPAddress: "10.0.0.2"},
+		}, results)
+
+		peers, err := service.ListPeers(ctx)
+		require.NoError(t, err)
+		require.Len(t, peers, 1)
+		assert.Equal(t, ams.ID, peers[0].ID)
+	})
+
+	t.Run("Rejects unknown actions", func(t *testing.T) {
+		_, err := service.BulkUpdatePeers(ctx, fra, "restart")
+		assert.Error(t, err)
+	})
+}
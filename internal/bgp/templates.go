@@ -0,0 +1,432 @@
+package bgp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/padminisys/flintroute/internal/requestid"
+	"github.com/padminisys/flintroute/pkg/models"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrTemplateNotFound is returned when a peer template does not exist
+	ErrTemplateNotFound = errors.New("peer template not found")
+	// ErrTemplateExists is returned when another template has the name
+	ErrTemplateExists = errors.New("a peer template with this name already exists")
+	// ErrInvalidTemplate is returned when a template is rejected before
+	// being stored
+	ErrInvalidTemplate = errors.New("invalid peer template")
+	// ErrTemplateInUse is returned when deleting a template peers or other
+	// templates still use
+	ErrTemplateInUse = errors.New("peer template is used by peers or other templates")
+)
+
+// maxTemplateDepth bounds how many parents a template can inherit from
+const maxTemplateDepth = 8
+
+// templateFields are the JSON names of the peer settings a template can
+// hold. Setting one on a peer overrides the template's.
+var templateFields = map[string]bool{
+	"asn":                          true,
+	"remote_asn":                   true,
+	"enabled":                      true,
+	"multihop":                     true,
+	"update_source":                true,
+	"route_map_in":                 true,
+	"route_map_out":                true,
+	"prefix_list_in":               true,
+	"prefix_list_out":              true,
+	"max_prefixes":                 true,
+	"max_prefix_threshold":         true,
+	"max_prefix_action":            true,
+	"max_prefix_restart":           true,
+	"local_preference":             true,
+	"keepalive":                    true,
+	"holdtime":                     true,
+	"connect_retry":                true,
+	"passive":                      true,
+	"ttl_security_hops":            true,
+	"next_hop_self":                true,
+	"soft_reconfiguration_inbound": true,
+	"remove_private_as":            true,
+	"allowas_in":                   true,
+	"local_as":                     true,
+	"local_as_mode":                true,
+	"tags":                         true,
+}
+
+// ListTemplates retrieves all peer templates
+func (s *Service) ListTemplates(ctx context.Context) ([]*models.PeerTemplate, error) {
+	var templates []*models.PeerTemplate
+	if err := s.db.WithContext(ctx).Order("name").Find(&templates).Error; err != nil {
+		return nil, err
+	}
+	return templates, nil
+}
+
+// GetTemplate retrieves a peer template by ID
+func (s *Service) GetTemplate(ctx context.Context, id uint) (*models.PeerTemplate, error) {
+	var template models.PeerTemplate
+	if err := s.db.WithContext(ctx).First(&template, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrTemplateNotFound
+		}
+		return nil, err
+	}
+	return &template, nil
+}
+
+// ResolveTemplate returns a template's settings merged with those it
+// inherits from its parents
+func (s *Service) ResolveTemplate(ctx context.Context, id uint) (*models.PeerTemplateSettings, error) {
+	template, err := s.GetTemplate(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return s.resolve(ctx, template)
+}
+
+// resolve merges template's settings with its parents'
+func (s *Service) resolve(ctx context.Context, template *models.PeerTemplate) (*models.PeerTemplateSettings, error) {
+	settings := template.Settings
+	seen := map[uint]bool{template.ID: true}
+	for parentID := template.ParentID; parentID != nil; {
+		if seen[*parentID] || len(seen) > maxTemplateDepth {
+			return nil, fmt.Errorf("%w: template %s: parents are nested too deeply or form a cycle", ErrInvalidTemplate, template.Name)
+		}
+		seen[*parentID] = true
+
+		parent, err := s.GetTemplate(ctx, *parentID)
+		if errors.Is(err, ErrTemplateNotFound) {
+			return nil, fmt.Errorf("%w: template %s: parent template %d not found", ErrInvalidTemplate, template.Name, *parentID)
+		} else if err != nil {
+			return nil, err
+		}
+		inheritSettings(&settings, &parent.Settings)
+		parentID = parent.ParentID
+	}
+	return &settings, nil
+}
+
+// inheritSettings fills the settings unset in settings from parent. Tags
+// are merged, settings' values winning.
+func inheritSettings(settings, parent *models.PeerTemplateSettings) {
+	inherit(&settings.ASN, parent.ASN)
+	inherit(&settings.RemoteASN, parent.RemoteASN)
+	inherit(&settings.Enabled, parent.Enabled)
+	inherit(&settings.Multihop, parent.Multihop)
+	inherit(&settings.UpdateSource, parent.UpdateSource)
+	inherit(&settings.RouteMapIn, parent.RouteMapIn)
+	inherit(&settings.RouteMapOut, parent.RouteMapOut)
+	inherit(&settings.PrefixListIn, parent.PrefixListIn)
+	inherit(&settings.PrefixListOut, parent.PrefixListOut)
+	inherit(&settings.MaxPrefixes, parent.MaxPrefixes)
+	inherit(&settings.MaxPrefixThreshold, parent.MaxPrefixThreshold)
+	inherit(&settings.MaxPrefixAction, parent.MaxPrefixAction)
+	inherit(&settings.MaxPrefixRestart, parent.MaxPrefixRestart)
+	inherit(&settings.LocalPreference, parent.LocalPreference)
+	inherit(&settings.Keepalive, parent.Keepalive)
+	inherit(&settings.HoldTime, parent.HoldTime)
+	inherit(&settings.ConnectRetry, parent.ConnectRetry)
+	inherit(&settings.Passive, parent.Passive)
+	inherit(&settings.TTLSecurityHops, parent.TTLSecurityHops)
+	inherit(&settings.NextHopSelf, parent.NextHopSelf)
+	inherit(&settings.SoftReconfigInbound, parent.SoftReconfigInbound)
+	inherit(&settings.RemovePrivateAS, parent.RemovePrivateAS)
+	inherit(&settings.AllowASIn, parent.AllowASIn)
+	inherit(&settings.LocalAS, parent.LocalAS)
+	inherit(&settings.LocalASMode, parent.LocalASMode)
+	if len(parent.Tags) > 0 {
+		tags := make(map[string]string, len(parent.Tags)+len(settings.Tags))
+		for k, v := range parent.Tags {
+			tags[k] = v
+		}
+		for k, v := range settings.Tags {
+			tags[k] = v
+		}
+		settings.Tags = tags
+	}
+}
+
+// inherit sets *dst to value when dst is unset
+func inherit[T any](dst **T, value *T) {
+	if *dst == nil {
+		*dst = value
+	}
+}
+
+// templatePatch returns the settings as a peer patch, leaving out the
+// overridden ones
+func templatePatch(settings *models.PeerTemplateSettings, overridden map[string]bool) *PeerPatch {
+	patch := &PeerPatch{
+		Enabled:             unlessOverridden(overridden, "enabled", settings.Enabled),
+		Multihop:            unlessOverridden(overridden, "multihop", settings.Multihop),
+		UpdateSource:        unlessOverridden(overridden, "update_source", settings.UpdateSource),
+		RouteMapIn:          unlessOverridden(overridden, "route_map_in", settings.RouteMapIn),
+		RouteMapOut:         unlessOverridden(overridden, "route_map_out", settings.RouteMapOut),
+		PrefixListIn:        unlessOverridden(overridden, "prefix_list_in", settings.PrefixListIn),
+		PrefixListOut:       unlessOverridden(overridden, "prefix_list_out", settings.PrefixListOut),
+		MaxPrefixes:         unlessOverridden(overridden, "max_prefixes", settings.MaxPrefixes),
+		MaxPrefixThreshold:  unlessOverridden(overridden, "max_prefix_threshold", settings.MaxPrefixThreshold),
+		MaxPrefixAction:     unlessOverridden(overridden, "max_prefix_action", settings.MaxPrefixAction),
+		MaxPrefixRestart:    unlessOverridden(overridden, "max_prefix_restart", settings.MaxPrefixRestart),
+		LocalPreference:     unlessOverridden(overridden, "local_preference", settings.LocalPreference),
+		Keepalive:           unlessOverridden(overridden, "keepalive", settings.Keepalive),
+		HoldTime:            unlessOverridden(overridden, "holdtime", settings.HoldTime),
+		ConnectRetry:        unlessOverridden(overridden, "connect_retry", settings.ConnectRetry),
+		Passive:             unlessOverridden(overridden, "passive", settings.Passive),
+		TTLSecurityHops:     unlessOverridden(overridden, "ttl_security_hops", settings.TTLSecurityHops),
+		NextHopSelf:         unlessOverridden(overridden, "next_hop_self", settings.NextHopSelf),
+		SoftReconfigInbound: unlessOverridden(overridden, "soft_reconfiguration_inbound", settings.SoftReconfigInbound),
+		RemovePrivateAS:     unlessOverridden(overridden, "remove_private_as", settings.RemovePrivateAS),
+		AllowASIn:           unlessOverridden(overridden, "allowas_in", settings.AllowASIn),
+		LocalAS:             unlessOverridden(overridden, "local_as", settings.LocalAS),
+		LocalASMode:         unlessOverridden(overridden, "local_as_mode", settings.LocalASMode),
+	}
+	if !overridden["tags"] {
+		patch.Tags = settings.Tags
+	}
+	return patch
+}
+
+// unlessOverridden returns value unless field is overridden
+func unlessOverridden[T any](overridden map[string]bool, field string, value *T) *T {
+	if overridden[field] {
+		return nil
+	}
+	return value
+}
+
+// addOverrides returns overrides with the template fields among fields
+// added, sorted
+func addOverrides(overrides, fields []string) []string {
+	set := make(map[string]bool, len(overrides)+len(fields))
+	for _, field := range overrides {
+		set[field] = true
+	}
+	for _, field := range fields {
+		if templateFields[field] {
+			set[field] = true
+		}
+	}
+	if len(set) == 0 {
+		return nil
+	}
+
+	merged := make([]string, 0, len(set))
+	for field := range set {
+		merged = append(merged, field)
+	}
+	sort.Strings(merged)
+	return merged
+}
+
+// overriddenSet returns a peer's template overrides as a set
+func overriddenSet(peer *models.BGPPeer) map[string]bool {
+	set := make(map[string]bool, len(peer.TemplateOverrides))
+	for _, field := range peer.TemplateOverrides {
+		set[field] = true
+	}
+	return set
+}
+
+// ApplyTemplate provisions peer from a template: the template's settings
+// replace peer's, except the fields, named as in the API, the caller set
+// explicitly. Those are recorded as the peer's overrides.
+func (s *Service) ApplyTemplate(ctx context.Context, peer *models.BGPPeer, templateID uint, fields []string) error {
+	settings, err := s.ResolveTemplate(ctx, templateID)
+	if err != nil {
+		return err
+	}
+
+	peer.TemplateID = &templateID
+	peer.TemplateOverrides = addOverrides(nil, fields)
+	overridden := overriddenSet(peer)
+	if settings.ASN != nil && !overridden["asn"] {
+		peer.ASN = *settings.ASN
+	}
+	if settings.RemoteASN != nil && !overridden["remote_asn"] {
+		peer.RemoteASN = *settings.RemoteASN
+	}
+	templatePatch(settings, overridden).applyTo(peer)
+	return nil
+}
+
+// validateTemplate checks a template's name, parent and settings as its
+// peers would inherit them
+func (s *Service) validateTemplate(ctx context.Context, template *models.PeerTemplate) error {
+	if strings.TrimSpace(template.Name) == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalidTemplate)
+	}
+	if template.ParentID != nil && template.ID != 0 && *template.ParentID == template.ID {
+		return fmt.Errorf("%w: template %s cannot be its own parent", ErrInvalidTemplate, template.Name)
+	}
+
+	var existing models.PeerTemplate
+	err := s.db.WithContext(ctx).Where("name = ? AND id <> ?", template.Name, template.ID).First(&existing).Error
+	if err == nil {
+		return ErrTemplateExists
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+
+	settings, err := s.resolve(ctx, template)
+	if err != nil {
+		return err
+	}
+	peer := &models.BGPPeer{}
+	templatePatch(settings, nil).applyTo(peer)
+	if err := ValidatePeer(peer); err != nil {
+		return fmt.Errorf("%w: template %s: %s", ErrInvalidTemplate, template.Name,
+			strings.TrimPrefix(err.Error(), ErrInvalidPeer.Error()+": "))
+	}
+	return nil
+}
+
+// CreateTemplate validates and stores a new peer template
+func (s *Service) CreateTemplate(ctx context.Context, template *models.PeerTemplate) error {
+	template.ID = 0
+	if err := s.validateTemplate(ctx, template); err != nil {
+		return err
+	}
+	if err := s.db.WithContext(ctx).Create(template).Error; err != nil {
+		return fmt.Errorf("failed to create peer template: %w", err)
+	}
+
+	s.logger.Info("Created peer template",
+		zap.Uint("template_id", template.ID),
+		zap.String("name", template.Name),
+		requestid.Field(ctx),
+	)
+
+	return nil
+}
+
+// UpdateTemplate replaces a peer template's name, description, parent and
+// settings. Peers provisioned from it keep their settings until the
+// template is synced.
+func (s *Service) UpdateTemplate(ctx context.Context, id uint, updates *models.PeerTemplate) (*models.PeerTemplate, error) {
+	template, err := s.GetTemplate(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	template.Name = updates.Name
+	template.Description = updates.Description
+	template.ParentID = updates.ParentID
+	template.Settings = updates.Settings
+	if err := s.validateTemplate(ctx, template); err != nil {
+		return nil, err
+	}
+	if err := s.db.WithContext(ctx).Save(template).Error; err != nil {
+		return nil, fmt.Errorf("failed to update peer template: %w", err)
+	}
+
+	s.logger.Info("Updated peer template",
+		zap.Uint("template_id", template.ID),
+		zap.String("name", template.Name),
+		requestid.Field(ctx),
+	)
+
+	return template, nil
+}
+
+// DeleteTemplate removes a peer template no peer or template uses. Peers
+// in the trash are detached from it.
+func (s *Service) DeleteTemplate(ctx context.Context, id uint) error {
+	template, err := s.GetTemplate(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	var peers, children int64
+	if err := s.db.WithContext(ctx).Model(&models.BGPPeer{}).Where("template_id = ?", id).Count(&peers).Error; err != nil {
+		return err
+	}
+	if err := s.db.WithContext(ctx).Model(&models.PeerTemplate{}).Where("parent_id = ?", id).Count(&children).Error; err != nil {
+		return err
+	}
+	if peers > 0 || children > 0 {
+		return fmt.Errorf("%w: %d peers and %d templates use %s", ErrTemplateInUse, peers, children, template.Name)
+	}
+
+	if err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Model(&models.BGPPeer{}).Where("template_id = ?", id).
+			Update("template_id", nil).Error; err != nil {
+			return err
+		}
+		return tx.Delete(template).Error
+	}); err != nil {
+		return fmt.Errorf("failed to delete peer template: %w", err)
+	}
+
+	s.logger.Info("Deleted peer template",
+		zap.Uint("template_id", id),
+		zap.String("name", template.Name),
+		requestid.Field(ctx),
+	)
+
+	return nil
+}
+
+// SyncTemplate reapplies a template to the peers provisioned from it or
+// from the templates inheriting from it. Each peer keeps its overrides, as
+// well as its ASNs, which can't change once created. One peer failing
+// doesn't stop the others; the results report every peer's outcome.
+func (s *Service) SyncTemplate(ctx context.Context, id uint) ([]*BulkResult, error) {
+	if _, err := s.GetTemplate(ctx, id); err != nil {
+		return nil, err
+	}
+
+	var all []*models.PeerTemplate
+	if err := s.db.WithContext(ctx).Find(&all).Error; err != nil {
+		return nil, err
+	}
+	ids := []uint{id}
+	for i := 0; i < len(ids); i++ {
+		for _, t := range all {
+			if t.ParentID != nil && *t.ParentID == ids[i] {
+				ids = append(ids, t.ID)
+			}
+		}
+	}
+
+	var peers []*models.BGPPeer
+	if err := s.db.WithContext(ctx).Where("template_id IN ?", ids).Order("id").Find(&peers).Error; err != nil {
+		return nil, err
+	}
+
+	settings := make(map[uint]*models.PeerTemplateSettings)
+	results := make([]*BulkResult, 0, len(peers))
+	failed := 0
+	for _, peer := range peers {
+		result := &BulkResult{PeerID: peer.ID, IPAddress: peer.IPAddress}
+		resolved, ok := settings[*peer.TemplateID]
+		if !ok {
+			var err error
+			if resolved, err = s.ResolveTemplate(ctx, *peer.TemplateID); err != nil {
+				return nil, err
+			}
+			settings[*peer.TemplateID] = resolved
+		}
+		if err := s.PatchPeer(ctx, peer.ID, templatePatch(resolved, overriddenSet(peer))); err != nil {
+			result.Error = err.Error()
+			failed++
+		}
+		results = append(results, result)
+	}
+
+	s.logger.Info("Synced peer template",
+		zap.Uint("template_id", id),
+		zap.Int("peers", len(peers)),
+		zap.Int("failed", failed),
+		requestid.Field(ctx),
+	)
+
+	return results, nil
+}
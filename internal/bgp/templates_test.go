@@ -0,0 +1,120 @@
+package bgp
+
+import (
+	"context"
+	"testing"
+
+	"github.com/padminisys/flintroute/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func ptr[T any](v T) *T { return &v }
+
+func TestPeerTemplates(t *testing.T) {
+	ctx := context.Background()
+	service := setupTestService(t, ConsistencyEventual)
+
+	ixp := &models.PeerTemplate{
+		Name: "ixp",
+		Settings: models.PeerTemplateSettings{
+			ASN:             ptr(uint32(65001)),
+			RouteMapIn:      ptr("IXP-IN"),
+			MaxPrefixes:     ptr(1000),
+			MaxPrefixAction: ptr(models.MaxPrefixWarningOnly),
+			Keepalive:       ptr(30),
+			HoldTime:        ptr(90),
+			Tags:            map[string]string{"role": "ixp"},
+		},
+	}
+	require.NoError(t, service.CreateTemplate(ctx, ixp))
+
+	decix := &models.PeerTemplate{
+		Name:     "decix",
+		ParentID: &ixp.ID,
+		Settings: models.PeerTemplateSettings{
+			MaxPrefixes: ptr(5000),
+			Tags:        map[string]string{"pop": "fra1"},
+		},
+	}
+	require.NoError(t, service.CreateTemplate(ctx, decix))
+
+	t.Run("Children inherit their parents' settings", func(t *testing.T) {
+		settings, err := service.ResolveTemplate(ctx, decix.ID)
+		require.NoError(t, err)
+		assert.Equal(t, uint32(65001), *settings.ASN)
+		assert.Equal(t, "IXP-IN", *settings.RouteMapIn)
+		assert.Equal(t, 5000, *settings.MaxPrefixes)
+		assert.Equal(t, map[string]string{"role": "ixp", "pop": "fra1"}, settings.Tags)
+		assert.Nil(t, settings.RemoteASN)
+	})
+
+	t.Run("Reject invalid templates", func(t *testing.T) {
+		err := service.CreateTemplate(ctx, &models.PeerTemplate{Name: "ixp"})
+		assert.ErrorIs(t, err, ErrTemplateExists)
+
+		err = service.CreateTemplate(ctx, &models.PeerTemplate{Name: "bad", Settings: models.PeerTemplateSettings{Keepalive: ptr(30)}})
+		assert.ErrorIs(t, err, ErrInvalidTemplate)
+
+		missing := uint(999)
+		err = service.CreateTemplate(ctx, &models.PeerTemplate{Name: "orphan", ParentID: &missing})
+		assert.ErrorIs(t, err, ErrInvalidTemplate)
+
+		_, err = service.UpdateTemplate(ctx, ixp.ID, &models.PeerTemplate{Name: "ixp", ParentID: &decix.ID})
+		assert.ErrorIs(t, err, ErrInvalidTemplate)
+	})
+
+	peer := &models.BGPPeer{Name: "Peer 10.0.0.1", IPAddress: "10.0.0.1", RemoteASN: 65100, LocalPreference: 200}
+	require.NoError(t, service.ApplyTemplate(ctx, peer, decix.ID, []string{"name", "ip_address", "remote_asn", "local_preference"}))
+	require.NoError(t, service.CreatePeer(ctx, peer))
+
+	t.Run("Peers are provisioned from the template", func(t *testing.T) {
+		stored, err := service.GetPeer(ctx, peer.ID)
+		require.NoError(t, err)
+		assert.Equal(t, decix.ID, *stored.TemplateID)
+		assert.Equal(t, []string{"local_preference", "remote_asn"}, stored.TemplateOverrides)
+		assert.Equal(t, uint32(65001), stored.ASN)
+		assert.Equal(t, uint32(65100), stored.RemoteASN)
+		assert.Equal(t, 5000, stored.MaxPrefixes)
+		assert.Equal(t, 90, stored.HoldTime)
+		assert.Equal(t, 200, stored.LocalPreference)
+		assert.Equal(t, map[string]string{"role": "ixp", "pop": "fra1"}, stored.Tags.Map())
+	})
+
+	t.Run("Syncing keeps overrides", func(t *testing.T) {
+		require.NoError(t, service.PatchPeer(ctx, peer.ID, &PeerPatch{
+			RouteMapIn:        ptr("CUSTOM-IN"),
+			TemplateOverrides: []string{"route_map_in"},
+		}))
+
+		ixp.Settings.RouteMapIn = ptr("IXP-IN-V2")
+		ixp.Settings.LocalPreference = ptr(150)
+		ixp.Settings.ConnectRetry = ptr(10)
+		_, err := service.UpdateTemplate(ctx, ixp.ID, ixp)
+		require.NoError(t, err)
+
+		results, err := service.SyncTemplate(ctx, ixp.ID)
+		require.NoError(t, err)
+		require.Len(t, results, 1)
+		assert.Equal(t, peer.ID, results[0].PeerID)
+		assert.Empty(t, results[0].Error)
+
+		stored, err := service.GetPeer(ctx, peer.ID)
+		require.NoError(t, err)
+		assert.Equal(t, "CUSTOM-IN", stored.RouteMapIn)
+		assert.Equal(t, 200, stored.LocalPreference)
+		assert.Equal(t, 10, stored.ConnectRetry)
+		assert.Equal(t, []string{"local_preference", "remote_asn", "route_map_in"}, stored.TemplateOverrides)
+	})
+
+	t.Run("Templates in use can't be deleted", func(t *testing.T) {
+		assert.ErrorIs(t, service.DeleteTemplate(ctx, ixp.ID), ErrTemplateInUse)
+		assert.ErrorIs(t, service.DeleteTemplate(ctx, decix.ID), ErrTemplateInUse)
+
+		require.NoError(t, service.DeletePeer(ctx, peer.ID))
+		require.NoError(t, service.DeleteTemplate(ctx, decix.ID))
+		require.NoError(t, service.DeleteTemplate(ctx, ixp.ID))
+		_, err := service.GetTemplate(ctx, ixp.ID)
+		assert.ErrorIs(t, err, ErrTemplateNotFound)
+	})
+}
@@ -0,0 +1,235 @@
+package bgp
+
+import (
+	"context"
+	"time"
+
+	"github.com/padminisys/flintroute/pkg/models"
+)
+
+// StateUnknown is the state of a session in a timeline while it wasn't
+// monitored
+const StateUnknown = "unknown"
+
+// StateSegment is a period a session stayed in one state
+type StateSegment struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+	State string    `json:"state"`
+}
+
+// StateBucket summarizes a session's states over one bucket of a timeline
+type StateBucket struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+	// States is how long the session spent in each state within the
+	// bucket, in seconds. Time before the peer was created is left out.
+	States map[string]int64 `json:"states"`
+	// State is the state the session spent the most time in; empty for
+	// buckets before the peer was created
+	State string `json:"state"`
+	// Transitions counts the state changes within the bucket
+	Transitions int `json:"transitions"`
+}
+
+// StateTimeline is a peer's session states over a range, from its session
+// event history, for swimlane charts. Periods the session states weren't
+// polled have the state "unknown" and are listed as gaps.
+type StateTimeline struct {
+	PeerID    uint      `json:"peer_id"`
+	IPAddress string    `json:"ip_address"`
+	Start     time.Time `json:"start"`
+	End       time.Time `json:"end"`
+	// Bucket is the length of each bucket, in seconds
+	Bucket      int64                  `json:"bucket"`
+	Transitions []models.SessionEvent  `json:"transitions"`
+	Segments    []*StateSegment        `json:"segments"`
+	Buckets     []*StateBucket         `json:"buckets"`
+	Gaps        []models.MonitoringGap `json:"gaps"`
+}
+
+// PeerStateTimeline returns a peer's session states between start and end,
+// bucketed by bucket. The state before the first event of the range is
+// that of the last event before it or, without one, the state the first
+// event left; without any events it is the current state.
+func (s *Service) PeerStateTimeline(ctx context.Context, id uint, start, end time.Time, bucket time.Duration) (*StateTimeline, error) {
+	peer, err := s.GetPeer(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	var events []models.SessionEvent
+	err = s.db.WithContext(ctx).
+		Where("peer_id = ? AND created_at < ?", id, start).
+		Order("created_at DESC").Order("id DESC").
+		Limit(1).
+		Find(&events).Error
+	if err != nil {
+		return nil, err
+	}
+	var recent []models.SessionEvent
+	err = s.db.WithContext(ctx).
+		Where("peer_id = ? AND created_at >= ? AND created_at < ?", id, start, end).
+		Order("created_at").Order("id").
+		Find(&recent).Error
+	if err != nil {
+		return nil, err
+	}
+	events = append(events, recent...)
+
+	gaps := []models.MonitoringGap{}
+	err = s.db.WithContext(ctx).
+		Where(`"start" < ? AND "end" > ?`, end, start).
+		Order(`"start"`).
+		Find(&gaps).Error
+	if err != nil {
+		return nil, err
+	}
+
+	state := ""
+	if len(events) == 0 {
+		session, err := s.sessions.GetByPeer(ctx, id)
+		if err == nil {
+			state = session.State
+		}
+	}
+
+	timeline := &StateTimeline{
+		PeerID:      peer.ID,
+		IPAddress:   peer.IPAddress,
+		Start:       start,
+		End:         end,
+		Bucket:      int64(bucket.Seconds()),
+		Transitions: recent,
+		Gaps:        gaps,
+	}
+	if timeline.Transitions == nil {
+		timeline.Transitions = []models.SessionEvent{}
+	}
+	from := start
+	if peer.CreatedAt.After(from) {
+		from = peer.CreatedAt
+	}
+	timeline.Segments = stateSegments(events, gaps, state, from, end)
+	timeline.Buckets = stateBuckets(timeline.Segments, recent, start, end, bucket)
+	return timeline, nil
+}
+
+// stateSegments splits the range from start to end into the periods the
+// session stayed in one state, from its events, oldest first, and the
+// monitoring gaps. state is the current state, used without events.
+func stateSegments(events []models.SessionEvent, gaps []models.MonitoringGap, state string, start, end time.Time) []*StateSegment {
+	segments := []*StateSegment{}
+	if !start.Before(end) {
+		return segments
+	}
+
+	switch {
+	case len(events) > 0 && events[0].CreatedAt.Before(start):
+		state = events[0].NewState
+		events = events[1:]
+	case len(events) > 0:
+		state = events[0].OldState
+	}
+	if state == "" {
+		state = StateUnknown
+	}
+
+	at := start
+	for _, event := range events {
+		if event.CreatedAt.After(at) {
+			segments = append(segments, &StateSegment{Start: at, End: event.CreatedAt, State: state})
+			at = event.CreatedAt
+		}
+		state = event.NewState
+	}
+	segments = append(segments, &StateSegment{Start: at, End: end, State: state})
+
+	for _, gap := range gaps {
+		segments = overlayGap(segments, gap)
+	}
+	return mergeSegments(segments)
+}
+
+// overlayGap marks the part of segments within gap as unknown
+func overlayGap(segments []*StateSegment, gap models.MonitoringGap) []*StateSegment {
+	overlaid := make([]*StateSegment, 0, len(segments)+2)
+	for _, segment := range segments {
+		if !gap.Start.Before(segment.End) || !gap.End.After(segment.Start) {
+			overlaid = append(overlaid, segment)
+			continue
+		}
+		if gap.Start.After(segment.Start) {
+			overlaid = append(overlaid, &StateSegment{Start: segment.Start, End: gap.Start, State: segment.State})
+		}
+		overlaid = append(overlaid, &StateSegment{
+			Start: laterOf(segment.Start, gap.Start),
+			End:   earlierOf(segment.End, gap.End),
+			State: StateUnknown,
+		})
+		if gap.End.Before(segment.End) {
+			overlaid = append(overlaid, &StateSegment{Start: gap.End, End: segment.End, State: segment.State})
+		}
+	}
+	return overlaid
+}
+
+// mergeSegments joins adjacent segments in the same state
+func mergeSegments(segments []*StateSegment) []*StateSegment {
+	merged := make([]*StateSegment, 0, len(segments))
+	for _, segment := range segments {
+		if n := len(merged); n > 0 && merged[n-1].State == segment.State {
+			merged[n-1].End = segment.End
+			continue
+		}
+		merged = append(merged, segment)
+	}
+	return merged
+}
+
+// stateBuckets sums up the segments and events over consecutive buckets
+// from start to end; the last bucket may be shorter
+func stateBuckets(segments []*StateSegment, events []models.SessionEvent, start, end time.Time, bucket time.Duration) []*StateBucket {
+	buckets := []*StateBucket{}
+	for from := start; from.Before(end); from = from.Add(bucket) {
+		b := &StateBucket{Start: from, End: earlierOf(from.Add(bucket), end), States: map[string]int64{}}
+
+		durations := map[string]time.Duration{}
+		var longest time.Duration
+		for _, segment := range segments {
+			overlap := earlierOf(segment.End, b.End).Sub(laterOf(segment.Start, b.Start))
+			if overlap <= 0 {
+				continue
+			}
+			durations[segment.State] += overlap
+			if durations[segment.State] > longest {
+				longest = durations[segment.State]
+				b.State = segment.State
+			}
+		}
+		for state, d := range durations {
+			b.States[state] = int64(d.Seconds())
+		}
+		for _, event := range events {
+			if !event.CreatedAt.Before(b.Start) && event.CreatedAt.Before(b.End) {
+				b.Transitions++
+			}
+		}
+		buckets = append(buckets, b)
+	}
+	return buckets
+}
+
+func earlierOf(a, b time.Time) time.Time {
+	if a.Before(b) {
+		return a
+	}
+	return b
+}
+
+func laterOf(a, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+	return b
+}
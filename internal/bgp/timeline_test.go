@@ -0,0 +1,121 @@
+package bgp
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/padminisys/flintroute/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStateSegments(t *testing.T) {
+	start := time.Date(2024, 3, 30, 0, 0, 0, 0, time.UTC)
+	end := start.Add(24 * time.Hour)
+	at := func(hours int) time.Time { return start.Add(time.Duration(hours) * time.Hour) }
+
+	t.Run("Follows the events", func(t *testing.T) {
+		segments := stateSegments([]models.SessionEvent{
+			{CreatedAt: start.Add(-time.Hour), NewState: "Established"},
+			{CreatedAt: at(6), OldState: "Established", NewState: "Idle"},
+			{CreatedAt: at(7), OldState: "Idle", NewState: "Established"},
+		}, nil, "", start, end)
+
+		assert.Equal(t, []*StateSegment{
+			{Start: start, End: at(6), State: "Established"},
+			{Start: at(6), End: at(7), State: "Idle"},
+			{Start: at(7), End: end, State: "Established"},
+		}, segments)
+	})
+
+	t.Run("Marks gaps unknown", func(t *testing.T) {
+		segments := stateSegments([]models.SessionEvent{
+			{CreatedAt: at(6), OldState: "Established", NewState: "Idle"},
+			{CreatedAt: at(12), OldState: "Idle", NewState: "Established"},
+		}, []models.MonitoringGap{
+			{Start: at(10), End: at(12)},
+			{Start: at(20), End: end.Add(time.Hour)},
+		}, "", start, end)
+
+		assert.Equal(t, []*StateSegment{
+			{Start: start, End: at(6), State: "Established"},
+			{Start: at(6), End: at(10), State: "Idle"},
+			{Start: at(10), End: at(12), State: StateUnknown},
+			{Start: at(12), End: at(20), State: "Established"},
+			{Start: at(20), End: end, State: StateUnknown},
+		}, segments)
+	})
+
+	t.Run("Uses the current state without events", func(t *testing.T) {
+		assert.Equal(t, []*StateSegment{{Start: start, End: end, State: "Active"}}, stateSegments(nil, nil, "Active", start, end))
+		assert.Equal(t, []*StateSegment{{Start: start, End: end, State: StateUnknown}}, stateSegments(nil, nil, "", start, end))
+	})
+}
+
+func TestStateBuckets(t *testing.T) {
+	start := time.Date(2024, 3, 30, 0, 0, 0, 0, time.UTC)
+	at := func(minutes int) time.Time { return start.Add(time.Duration(minutes) * time.Minute) }
+
+	buckets := stateBuckets([]*StateSegment{
+		{Start: at(30), End: at(80), State: "Established"},
+		{Start: at(80), End: at(100), State: "Idle"},
+		{Start: at(100), End: at(150), State: "Established"},
+	}, []models.SessionEvent{
+		{CreatedAt: at(80), NewState: "Idle"},
+		{CreatedAt: at(100), NewState: "Established"},
+	}, start, at(150), time.Hour)
+
+	require.Len(t, buckets, 3)
+	assert.Equal(t, map[string]int64{"Established": 30 * 60}, buckets[0].States, "the peer was created at 0:30")
+	assert.Equal(t, "Established", buckets[0].State)
+	assert.Zero(t, buckets[0].Transitions)
+	assert.Equal(t, map[string]int64{"Established": 40 * 60, "Idle": 20 * 60}, buckets[1].States)
+	assert.Equal(t, 2, buckets[1].Transitions)
+	assert.Equal(t, at(150), buckets[2].End, "the last bucket is cut short")
+	assert.Equal(t, map[string]int64{"Established": 30 * 60}, buckets[2].States)
+}
+
+func TestPeerStateTimeline(t *testing.T) {
+	ctx := context.Background()
+	service := setupTestService(t, ConsistencyEventual)
+
+	peer := newTestPeer("10.0.0.1", true)
+	require.NoError(t, service.CreatePeer(ctx, peer))
+	require.NoError(t, service.db.Model(peer).UpdateColumn("created_at", time.Now().Add(-48*time.Hour)).Error)
+	require.NoError(t, service.db.Create(&models.BGPSession{PeerID: peer.ID, State: "Established"}).Error)
+
+	end := time.Now()
+	start := end.Add(-24 * time.Hour)
+	down := end.Add(-3 * time.Hour)
+	for _, event := range []models.SessionEvent{
+		{CreatedAt: start.Add(-time.Hour), PeerID: peer.ID, OldState: "Idle", NewState: "Established"},
+		{CreatedAt: down, PeerID: peer.ID, OldState: "Established", NewState: "Idle"},
+		{CreatedAt: down.Add(time.Hour), PeerID: peer.ID, OldState: "Idle", NewState: "Established"},
+		{CreatedAt: down, PeerID: peer.ID + 1, OldState: "Established", NewState: "Idle"},
+	} {
+		require.NoError(t, service.db.Create(&event).Error)
+	}
+	require.NoError(t, service.db.Create(&models.MonitoringGap{Start: start.Add(-2 * time.Hour), End: start.Add(2 * time.Hour)}).Error)
+	require.NoError(t, service.db.Create(&models.MonitoringGap{Start: end.Add(-30 * 24 * time.Hour), End: end.Add(-29 * 24 * time.Hour)}).Error)
+
+	timeline, err := service.PeerStateTimeline(ctx, peer.ID, start, end, 6*time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, int64(6*3600), timeline.Bucket)
+	assert.Len(t, timeline.Transitions, 2, "only the peer's, within the range")
+	require.Len(t, timeline.Gaps, 1)
+
+	require.Len(t, timeline.Segments, 4)
+	assert.Equal(t, StateUnknown, timeline.Segments[0].State)
+	assert.Equal(t, "Established", timeline.Segments[1].State)
+	assert.Equal(t, "Idle", timeline.Segments[2].State)
+	assert.Equal(t, "Established", timeline.Segments[3].State)
+
+	require.Len(t, timeline.Buckets, 4)
+	assert.Equal(t, int64(2*3600), timeline.Buckets[0].States[StateUnknown])
+	assert.Equal(t, "Established", timeline.Buckets[0].State)
+	assert.Equal(t, 2, timeline.Buckets[3].Transitions)
+
+	_, err = service.PeerStateTimeline(ctx, 999, start, end, time.Hour)
+	assert.ErrorIs(t, err, ErrPeerNotFound)
+}
@@ -0,0 +1,154 @@
+package bgp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/padminisys/flintroute/internal/requestid"
+	"github.com/padminisys/flintroute/internal/tenancy"
+	"github.com/padminisys/flintroute/internal/webhooks"
+	"github.com/padminisys/flintroute/pkg/models"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// ErrPeerNotInTrash is returned when restoring a peer that isn't deleted
+var ErrPeerNotInTrash = errors.New("peer is not in the trash")
+
+// DeletedPeer is a peer in the trash
+type DeletedPeer struct {
+	*models.BGPPeer
+	DeletedAt time.Time `json:"deleted_at"`
+	// PurgeAt is when the peer is removed for good; nil when the trash is
+	// kept until purged by hand
+	PurgeAt *time.Time `json:"purge_at,omitempty"`
+}
+
+// ListDeletedPeers returns the peers in the trash, most recently deleted
+// first
+func (s *Service) ListDeletedPeers(ctx context.Context) ([]*DeletedPeer, error) {
+	var peers []*models.BGPPeer
+	if err := s.db.WithContext(ctx).Unscoped().Scopes(tenancy.Scope(ctx, "bgp_peers")).Preload("Tags").
+		Where("deleted_at IS NOT NULL").
+		Order("deleted_at DESC").
+		Find(&peers).Error; err != nil {
+		return nil, err
+	}
+
+	deleted := make([]*DeletedPeer, len(peers))
+	for i, peer := range peers {
+		deleted[i] = &DeletedPeer{BGPPeer: peer, DeletedAt: peer.DeletedAt.Time}
+		if s.config.TrashRetention > 0 {
+			purgeAt := peer.DeletedAt.Time.Add(s.config.TrashRetention)
+			deleted[i].PurgeAt = &purgeAt
+		}
+	}
+	return deleted, nil
+}
+
+// RestorePeer takes a peer out of the trash and, when it is enabled,
+// applies it to FRR according to the consistency mode
+func (s *Service) RestorePeer(ctx context.Context, id uint) (*models.BGPPeer, error) {
+	var peer models.BGPPeer
+	if err := s.db.WithContext(ctx).Unscoped().Scopes(tenancy.Scope(ctx, "bgp_peers")).Preload("Tags").First(&peer, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrPeerNotFound
+		}
+		return nil, err
+	}
+	if !peer.DeletedAt.Valid {
+		return nil, ErrPeerNotInTrash
+	}
+
+	peer.DeletedAt = gorm.DeletedAt{}
+	peer.SyncStatus = models.PeerSyncSynced
+	peer.SyncError = ""
+	apply := func() error {
+		if !peer.Enabled {
+			return nil
+		}
+		return s.addToFRR(ctx, &peer)
+	}
+	if err := s.savePeer(ctx, &peer, apply); err != nil {
+		return nil, err
+	}
+
+	s.wsHub.BroadcastPeerUpdate(ctx, &peer)
+	s.config.Webhooks.Publish(ctx, webhooks.EventPeerRestored, &peer)
+
+	s.logger.Info("Restored BGP peer",
+		zap.Uint("id", peer.ID),
+		zap.String("ip", peer.IPAddress),
+		zap.String("sync_status", peer.SyncStatus),
+		requestid.Field(ctx),
+	)
+
+	return &peer, nil
+}
+
+// PurgeDeletedPeers permanently removes the peers deleted more than
+// olderThan ago, with their tags, sessions and prefix samples. An olderThan
+// of 0 empties the trash.
+func (s *Service) PurgeDeletedPeers(ctx context.Context, olderThan time.Duration) ([]*models.BGPPeer, error) {
+	var peers []*models.BGPPeer
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Scopes(tenancy.Scope(ctx, "bgp_peers")).
+			Where("deleted_at IS NOT NULL AND deleted_at <= ?", time.Now().Add(-olderThan)).
+			Find(&peers).Error; err != nil {
+			return err
+		}
+		if len(peers) == 0 {
+			return nil
+		}
+
+		ids := make([]uint, len(peers))
+		for i, peer := range peers {
+			ids[i] = peer.ID
+		}
+		for _, model := range []interface{}{&models.PeerTag{}, &models.BGPSession{}, &models.PrefixSample{}, &models.ReachabilitySample{}, &models.SessionEvent{}} {
+			if err := tx.Where("peer_id IN ?", ids).Delete(model).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Unscoped().Delete(&models.BGPPeer{}, ids).Error
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to purge deleted peers: %w", err)
+	}
+
+	if len(peers) > 0 {
+		s.config.Cache.Invalidate(CacheSessions)
+		s.logger.Info("Purged deleted BGP peers",
+			zap.Int("count", len(peers)),
+			zap.Duration("older_than", olderThan),
+			requestid.Field(ctx),
+		)
+	}
+	return peers, nil
+}
+
+// StartTrashPurger purges peers deleted longer than the trash retention
+// ago every interval until ctx is cancelled
+func (s *Service) StartTrashPurger(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	s.logger.Info("Started peer trash purger",
+		zap.Duration("interval", interval),
+		zap.Duration("retention", s.config.TrashRetention),
+	)
+
+	for {
+		select {
+		case <-ctx.Done():
+			s.logger.Info("Stopped peer trash purger")
+			return
+		case <-ticker.C:
+			if _, err := s.PurgeDeletedPeers(ctx, s.config.TrashRetention); err != nil {
+				s.logger.Error("Failed to purge deleted peers", zap.Error(err))
+			}
+		}
+	}
+}
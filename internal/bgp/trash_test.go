@@ -0,0 +1,91 @@
+package bgp
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/padminisys/flintroute/internal/frr"
+	"github.com/padminisys/flintroute/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPeerTrash(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Deleted peers can be listed and restored", func(t *testing.T) {
+		service := setupTestService(t, ConsistencyEventual)
+		service.config.TrashRetention = 24 * time.Hour
+		peer := newTaggedPeer("10.0.0.1", map[string]string{"pop": "fra1"})
+		require.NoError(t, service.CreatePeer(ctx, peer))
+		require.NoError(t, service.DeletePeer(ctx, peer.ID))
+
+		_, err := service.GetPeer(ctx, peer.ID)
+		assert.ErrorIs(t, err, ErrPeerNotFound)
+
+		deleted, err := service.ListDeletedPeers(ctx)
+		require.NoError(t, err)
+		require.Len(t, deleted, 1)
+		assert.Equal(t, peer.ID, deleted[0].ID)
+		require.NotNil(t, deleted[0].PurgeAt)
+		assert.Equal(t, deleted[0].DeletedAt.Add(24*time.Hour), *deleted[0].PurgeAt)
+
+		// The IP address stays taken while the peer is in the trash
+		err = service.CreatePeer(ctx, newTestPeer("10.0.0.1", true))
+		assert.ErrorIs(t, err, ErrPeerExists)
+		assert.Contains(t, err.Error(), "in the trash")
+
+		client := frr.NewMockClient()
+		client.On("AddBGPPeer", mock.Anything, peerIP("10.0.0.1")).Return(nil)
+		service.frrClient = client
+
+		restored, err := service.RestorePeer(ctx, peer.ID)
+		require.NoError(t, err)
+		assert.Equal(t, models.PeerSyncSynced, restored.SyncStatus)
+		client.AssertExpectations(t)
+
+		stored, err := service.GetPeer(ctx, peer.ID)
+		require.NoError(t, err)
+		assert.Equal(t, map[string]string{"pop": "fra1"}, stored.Tags.Map())
+		deleted, err = service.ListDeletedPeers(ctx)
+		require.NoError(t, err)
+		assert.Empty(t, deleted)
+
+		_, err = service.RestorePeer(ctx, peer.ID)
+		assert.ErrorIs(t, err, ErrPeerNotInTrash)
+		_, err = service.RestorePeer(ctx, peer.ID+1)
+		assert.ErrorIs(t, err, ErrPeerNotFound)
+	})
+
+	t.Run("Purge honours the retention", func(t *testing.T) {
+		service := setupTestService(t, ConsistencyEventual)
+		old := newTestPeer("10.0.0.1", false)
+		require.NoError(t, service.CreatePeer(ctx, old))
+		require.NoError(t, service.db.Create(&models.BGPSession{PeerID: old.ID, State: "Idle"}).Error)
+		require.NoError(t, service.DeletePeer(ctx, old.ID))
+		require.NoError(t, service.db.Unscoped().Model(old).Update("deleted_at", time.Now().Add(-48*time.Hour)).Error)
+
+		recent := newTestPeer("10.0.0.2", false)
+		require.NoError(t, service.CreatePeer(ctx, recent))
+		require.NoError(t, service.DeletePeer(ctx, recent.ID))
+
+		purged, err := service.PurgeDeletedPeers(ctx, 24*time.Hour)
+		require.NoError(t, err)
+		require.Len(t, purged, 1)
+		assert.Equal(t, old.ID, purged[0].ID)
+
+		var sessions int64
+		require.NoError(t, service.db.Model(&models.BGPSession{}).Count(&sessions).Error)
+		assert.Zero(t, sessions)
+
+		// Its IP address is free again
+		require.NoError(t, service.CreatePeer(ctx, newTestPeer("10.0.0.1", false)))
+
+		deleted, err := service.ListDeletedPeers(ctx)
+		require.NoError(t, err)
+		require.Len(t, deleted, 1)
+		assert.Nil(t, deleted[0].PurgeAt)
+	})
+}
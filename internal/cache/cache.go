@@ -0,0 +1,151 @@
+package cache
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Cache is an in-memory cache of values grouped into namespaces. Entries
+// expire after a TTL, and a namespace is invalidated as a whole when the
+// data behind it changes. A nil Cache caches nothing.
+type Cache struct {
+	ttl time.Duration
+
+	mu         sync.Mutex
+	namespaces map[string]*namespace
+}
+
+// namespace holds the entries and counters of one namespace
+type namespace struct {
+	entries map[string]entry
+	// generation is bumped on every invalidation, so that a value loaded
+	// before an invalidation isn't stored after it
+	generation    uint64
+	hits          uint64
+	misses        uint64
+	invalidations uint64
+}
+
+// entry is a cached value
+type entry struct {
+	value     interface{}
+	expiresAt time.Time
+}
+
+// Stats reports the activity of a namespace
+type Stats struct {
+	Namespace     string  `json:"namespace"`
+	Entries       int     `json:"entries"`
+	Hits          uint64  `json:"hits"`
+	Misses        uint64  `json:"misses"`
+	HitRatio      float64 `json:"hit_ratio"`
+	Invalidations uint64  `json:"invalidations"`
+}
+
+// New creates a cache whose entries expire after ttl
+func New(ttl time.Duration) *Cache {
+	return &Cache{
+		ttl:        ttl,
+		namespaces: make(map[string]*namespace),
+	}
+}
+
+// TTL returns how long entries are kept
+func (c *Cache) TTL() time.Duration {
+	if c == nil {
+		return 0
+	}
+	return c.ttl
+}
+
+// GetOrLoad returns the cached value for key, or calls load and caches its
+// result. Errors are not cached.
+func (c *Cache) GetOrLoad(name, key string, load func() (interface{}, error)) (interface{}, error) {
+	if c == nil {
+		return load()
+	}
+
+	c.mu.Lock()
+	ns := c.namespace(name)
+	if e, ok := ns.entries[key]; ok && time.Now().Before(e.expiresAt) {
+		ns.hits++
+		c.mu.Unlock()
+		return e.value, nil
+	}
+	ns.misses++
+	generation := ns.generation
+	c.mu.Unlock()
+
+	value, err := load()
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if ns.generation == generation {
+		ns.entries[key] = entry{value: value, expiresAt: time.Now().Add(c.ttl)}
+	}
+	return value, nil
+}
+
+// Invalidate drops every entry of the given namespaces
+func (c *Cache) Invalidate(names ...string) {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, name := range names {
+		ns := c.namespace(name)
+		ns.entries = make(map[string]entry)
+		ns.generation++
+		ns.invalidations++
+	}
+}
+
+// Stats returns the activity of every namespace, sorted by name
+func (c *Cache) Stats() []Stats {
+	stats := []Stats{}
+	if c == nil {
+		return stats
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := time.Now()
+	for name, ns := range c.namespaces {
+		s := Stats{
+			Namespace:     name,
+			Hits:          ns.hits,
+			Misses:        ns.misses,
+			Invalidations: ns.invalidations,
+		}
+		for key, e := range ns.entries {
+			if now.Before(e.expiresAt) {
+				s.Entries++
+			} else {
+				delete(ns.entries, key)
+			}
+		}
+		if total := ns.hits + ns.misses; total > 0 {
+			s.HitRatio = float64(ns.hits) / float64(total)
+		}
+		stats = append(stats, s)
+	}
+	sort.Slice(stats, func(i, j int) bool { return stats[i].Namespace < stats[j].Namespace })
+	return stats
+}
+
+// namespace returns the named namespace, creating it if needed. c.mu must
+// be held.
+func (c *Cache) namespace(name string) *namespace {
+	ns, ok := c.namespaces[name]
+	if !ok {
+		ns = &namespace{entries: make(map[string]entry)}
+		c.namespaces[name] = ns
+	}
+	return ns
+}
@@ -0,0 +1,125 @@
+package cache
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// counter returns a load function that counts its calls
+func counter(calls *int) func() (interface{}, error) {
+	return func() (interface{}, error) {
+		*calls++
+		return *calls, nil
+	}
+}
+
+func TestGetOrLoad(t *testing.T) {
+	t.Run("Caches loaded values", func(t *testing.T) {
+		c := New(time.Minute)
+		calls := 0
+
+		value, err := c.GetOrLoad("peers", "", counter(&calls))
+		require.NoError(t, err)
+		assert.Equal(t, 1, value)
+
+		value, err = c.GetOrLoad("peers", "", counter(&calls))
+		require.NoError(t, err)
+		assert.Equal(t, 1, value)
+		assert.Equal(t, 1, calls)
+
+		// Keys are cached separately
+		value, err = c.GetOrLoad("peers", "tag=edge", counter(&calls))
+		require.NoError(t, err)
+		assert.Equal(t, 2, value)
+	})
+
+	t.Run("Entries expire after the TTL", func(t *testing.T) {
+		c := New(10 * time.Millisecond)
+		calls := 0
+
+		_, err := c.GetOrLoad("peers", "", counter(&calls))
+		require.NoError(t, err)
+		time.Sleep(20 * time.Millisecond)
+		value, err := c.GetOrLoad("peers", "", counter(&calls))
+		require.NoError(t, err)
+		assert.Equal(t, 2, value)
+	})
+
+	t.Run("Errors are not cached", func(t *testing.T) {
+		c := New(time.Minute)
+		loadErr := errors.New("database is locked")
+
+		_, err := c.GetOrLoad("peers", "", func() (interface{}, error) { return nil, loadErr })
+		assert.ErrorIs(t, err, loadErr)
+
+		calls := 0
+		value, err := c.GetOrLoad("peers", "", counter(&calls))
+		require.NoError(t, err)
+		assert.Equal(t, 1, value)
+	})
+
+	t.Run("Nil cache always loads", func(t *testing.T) {
+		var c *Cache
+		calls := 0
+
+		c.GetOrLoad("peers", "", counter(&calls))
+		c.GetOrLoad("peers", "", counter(&calls))
+		assert.Equal(t, 2, calls)
+		assert.Empty(t, c.Stats())
+		assert.Zero(t, c.TTL())
+		c.Invalidate("peers")
+	})
+}
+
+func TestInvalidate(t *testing.T) {
+	t.Run("Drops the namespace's entries", func(t *testing.T) {
+		c := New(time.Minute)
+		peers, sessions := 0, 0
+
+		c.GetOrLoad("peers", "", counter(&peers))
+		c.GetOrLoad("sessions", "", counter(&sessions))
+		c.Invalidate("peers")
+
+		c.GetOrLoad("peers", "", counter(&peers))
+		c.GetOrLoad("sessions", "", counter(&sessions))
+		assert.Equal(t, 2, peers)
+		assert.Equal(t, 1, sessions)
+	})
+
+	t.Run("Values loaded across an invalidation are not stored", func(t *testing.T) {
+		c := New(time.Minute)
+		calls := 0
+
+		value, err := c.GetOrLoad("peers", "", func() (interface{}, error) {
+			// The data changes while it is being loaded
+			c.Invalidate("peers")
+			return "stale", nil
+		})
+		require.NoError(t, err)
+		assert.Equal(t, "stale", value)
+
+		value, err = c.GetOrLoad("peers", "", counter(&calls))
+		require.NoError(t, err)
+		assert.Equal(t, 1, value)
+	})
+}
+
+func TestStats(t *testing.T) {
+	c := New(time.Minute)
+	calls := 0
+
+	c.GetOrLoad("sessions", "", counter(&calls))
+	c.GetOrLoad("peers", "", counter(&calls))
+	c.GetOrLoad("peers", "", counter(&calls))
+	c.GetOrLoad("peers", "", counter(&calls))
+	c.Invalidate("peers")
+
+	stats := c.Stats()
+	require.Len(t, stats, 2)
+	assert.Equal(t, Stats{Namespace: "peers", Entries: 0, Hits: 2, Misses: 1, HitRatio: 2.0 / 3, Invalidations: 1}, stats[0])
+	assert.Equal(t, Stats{Namespace: "sessions", Entries: 1, Hits: 0, Misses: 1, HitRatio: 0}, stats[1])
+}
@@ -0,0 +1,320 @@
+// Package changes implements the two-person rule for configuration
+// changes. A change is stored as a pending change request and only applied
+// once an admin other than the requester approves it.
+package changes
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/padminisys/flintroute/internal/alerts"
+	"github.com/padminisys/flintroute/internal/database"
+	"github.com/padminisys/flintroute/internal/encryption"
+	"github.com/padminisys/flintroute/internal/requestid"
+	"github.com/padminisys/flintroute/internal/tenancy"
+	"github.com/padminisys/flintroute/internal/webhooks"
+	"github.com/padminisys/flintroute/internal/websocket"
+	"github.com/padminisys/flintroute/pkg/models"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrChangeNotFound   = errors.New("change request not found")
+	ErrNotPending       = errors.New("change request is no longer pending")
+	ErrSelfReview       = errors.New("change requests must be reviewed by someone other than the requester")
+	ErrUnknownOperation = errors.New("unknown change operation")
+)
+
+// Applier applies an approved change and returns its result, which is
+// stored as JSON. secret is the decrypted secret submitted with the change.
+type Applier func(ctx context.Context, change *models.ChangeRequest, secret string) (interface{}, error)
+
+// Service stores change requests and applies them once approved
+type Service struct {
+	db       *database.DB
+	cipher   *encryption.Cipher
+	hub      *websocket.Hub
+	webhooks *webhooks.Service
+	mailer   *alerts.Mailer
+	logger   *zap.Logger
+
+	mu       sync.RWMutex
+	appliers map[string]Applier
+}
+
+// NewService creates a change request service. Secrets submitted with
+// changes are encrypted at rest with cipher. With a mailer, the admins who
+// can approve a change request are emailed about it.
+func NewService(db *database.DB, cipher *encryption.Cipher, hub *websocket.Hub, webhookService *webhooks.Service, mailer *alerts.Mailer, logger *zap.Logger) *Service {
+	return &Service{
+		db:       db,
+		cipher:   cipher,
+		hub:      hub,
+		webhooks: webhookService,
+		mailer:   mailer,
+		logger:   logger,
+		appliers: make(map[string]Applier),
+	}
+}
+
+// Register sets the applier for an operation
+func (s *Service) Register(operation string, apply Applier) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.appliers[operation] = apply
+}
+
+func (s *Service) applier(operation string) (Applier, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	apply, ok := s.appliers[operation]
+	return apply, ok
+}
+
+// Submit stores a pending change request for operation with payload
+// encoded as JSON, for the tenant ctx acts for. secret, which may be empty,
+// is kept encrypted and handed to the applier on approval.
+func (s *Service) Submit(ctx context.Context, operation, summary string, payload interface{}, secret string, requestedBy uint) (*models.ChangeRequest, error) {
+	if _, ok := s.applier(operation); !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownOperation, operation)
+	}
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode change payload: %w", err)
+	}
+	if secret != "" {
+		if secret, err = s.cipher.Encrypt(secret); err != nil {
+			return nil, fmt.Errorf("failed to encrypt change secret: %w", err)
+		}
+	}
+
+	change := &models.ChangeRequest{
+		Operation:   operation,
+		Status:      models.ChangePending,
+		Summary:     summary,
+		Payload:     data,
+		Secret:      secret,
+		RequestedBy: requestedBy,
+		TenantID:    tenancy.ID(ctx),
+	}
+	if err := s.db.WithContext(ctx).Create(change).Error; err != nil {
+		return nil, fmt.Errorf("failed to store change request: %w", err)
+	}
+
+	s.logger.Info("Change request submitted",
+		zap.Uint("change_id", change.ID),
+		zap.String("operation", operation),
+		zap.Uint("requested_by", requestedBy),
+		requestid.Field(ctx),
+	)
+	s.notify(ctx, webhooks.EventChangeRequested, change)
+	s.emailApprovers(ctx, change)
+	return change, nil
+}
+
+// List returns change requests of the tenant ctx acts for, newest first,
+// optionally only those with the given status
+func (s *Service) List(ctx context.Context, status string) ([]*models.ChangeRequest, error) {
+	query := s.db.WithContext(ctx).Scopes(tenancy.Scope(ctx, "change_requests")).Order("id DESC")
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	var changes []*models.ChangeRequest
+	if err := query.Find(&changes).Error; err != nil {
+		return nil, err
+	}
+	return changes, nil
+}
+
+// Get returns a change request by ID. Changes of other tenants than the one
+// ctx acts for are not found.
+func (s *Service) Get(ctx context.Context, id uint) (*models.ChangeRequest, error) {
+	var change models.ChangeRequest
+	if err := s.db.WithContext(ctx).Scopes(tenancy.Scope(ctx, "change_requests")).First(&change, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrChangeNotFound
+		}
+		return nil, err
+	}
+	return &change, nil
+}
+
+// Approve approves a pending change request on behalf of reviewer and
+// applies it. The change ends up applied, or failed with the applier's
+// error; only failures to record the review are returned.
+func (s *Service) Approve(ctx context.Context, id, reviewer uint, comment string) (*models.ChangeRequest, error) {
+	change, err := s.review(ctx, id, reviewer, comment, models.ChangeApplied)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := s.apply(ctx, change)
+	var data []byte
+	if err == nil {
+		if data, err = json.Marshal(result); err != nil {
+			err = fmt.Errorf("failed to encode change result: %w", err)
+		}
+	}
+	if err != nil {
+		change.Status = models.ChangeFailed
+		change.Error = err.Error()
+		s.logger.Warn("Approved change failed to apply",
+			zap.Uint("change_id", change.ID),
+			zap.String("operation", change.Operation),
+			zap.Error(err),
+			requestid.Field(ctx),
+		)
+	} else {
+		change.Result = data
+		s.logger.Info("Approved change applied",
+			zap.Uint("change_id", change.ID),
+			zap.String("operation", change.Operation),
+			zap.Uint("reviewed_by", reviewer),
+			requestid.Field(ctx),
+		)
+	}
+
+	if err := s.db.WithContext(ctx).Model(change).Select("status", "result", "error").Updates(change).Error; err != nil {
+		return nil, fmt.Errorf("failed to record change result: %w", err)
+	}
+	s.notify(ctx, webhooks.EventChangeReviewed, change)
+	return change, nil
+}
+
+// Reject rejects a pending change request on behalf of reviewer
+func (s *Service) Reject(ctx context.Context, id, reviewer uint, comment string) (*models.ChangeRequest, error) {
+	change, err := s.review(ctx, id, reviewer, comment, models.ChangeRejected)
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("Change request rejected",
+		zap.Uint("change_id", change.ID),
+		zap.String("operation", change.Operation),
+		zap.Uint("reviewed_by", reviewer),
+		requestid.Field(ctx),
+	)
+	s.notify(ctx, webhooks.EventChangeReviewed, change)
+	return change, nil
+}
+
+// review records reviewer's decision on a pending change. The conditional
+// update makes sure concurrent reviews can't both succeed.
+func (s *Service) review(ctx context.Context, id, reviewer uint, comment, status string) (*models.ChangeRequest, error) {
+	change, err := s.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if change.Status != models.ChangePending {
+		return nil, ErrNotPending
+	}
+	if change.RequestedBy == reviewer {
+		return nil, ErrSelfReview
+	}
+
+	now := time.Now()
+	result := s.db.WithContext(ctx).Model(&models.ChangeRequest{}).
+		Where("id = ? AND status = ?", id, models.ChangePending).
+		Updates(map[string]interface{}{
+			"status":      status,
+			"reviewed_by": reviewer,
+			"reviewed_at": now,
+			"comment":     comment,
+		})
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to record review: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrNotPending
+	}
+
+	change.Status = status
+	change.ReviewedBy = &reviewer
+	change.ReviewedAt = &now
+	change.Comment = comment
+	return change, nil
+}
+
+// apply calls the change's applier acting for the change's tenant, turning
+// panics into errors
+func (s *Service) apply(ctx context.Context, change *models.ChangeRequest) (result interface{}, err error) {
+	apply, ok := s.applier(change.Operation)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownOperation, change.Operation)
+	}
+	if change.TenantID != nil {
+		ctx = tenancy.WithTenant(ctx, *change.TenantID)
+	}
+	secret, err := s.cipher.Decrypt(change.Secret)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt change secret: %w", err)
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("change panicked: %v", r)
+		}
+	}()
+	return apply(ctx, change, secret)
+}
+
+// notify tells WebSocket clients and webhook subscribers about a change
+func (s *Service) notify(ctx context.Context, event string, change *models.ChangeRequest) {
+	s.hub.BroadcastChangeUpdate(ctx, change)
+	s.webhooks.Publish(ctx, event, change)
+}
+
+// emailApprovers emails the active admins other than the requester that
+// change awaits their approval, in the background
+func (s *Service) emailApprovers(ctx context.Context, change *models.ChangeRequest) {
+	if s.mailer == nil {
+		return
+	}
+	changeCopy := *change
+	go func() {
+		if err := s.sendApprovalRequest(context.WithoutCancel(ctx), &changeCopy); err != nil {
+			s.logger.Error("Failed to email approval request", zap.Uint("change_id", change.ID), zap.Error(err))
+		}
+	}()
+}
+
+// sendApprovalRequest emails the change_requested template to the admins
+// who can approve change
+func (s *Service) sendApprovalRequest(ctx context.Context, change *models.ChangeRequest) error {
+	to, requestedBy, err := s.approvers(ctx, change)
+	if err != nil || len(to) == 0 {
+		return err
+	}
+	return s.mailer.SendTemplate(to, alerts.TemplateChangeRequested, &alerts.ChangeRequestEmail{
+		Change:      change,
+		RequestedBy: requestedBy,
+	})
+}
+
+// approvers returns the email addresses of the active admins other than the
+// requester of change, and the requester's username
+func (s *Service) approvers(ctx context.Context, change *models.ChangeRequest) ([]string, string, error) {
+	var admins []models.User
+	if err := s.db.WithContext(ctx).
+		Where("role = ? AND active = ? AND email <> '' AND id <> ?", "admin", true, change.RequestedBy).
+		Order("id").Find(&admins).Error; err != nil {
+		return nil, "", fmt.Errorf("failed to list admins: %w", err)
+	}
+	var requester models.User
+	if err := s.db.WithContext(ctx).Select("username").First(&requester, change.RequestedBy).Error; err != nil {
+		return nil, "", fmt.Errorf("failed to load requester: %w", err)
+	}
+
+	to := make([]string, len(admins))
+	for i, admin := range admins {
+		to[i] = admin.Email
+	}
+	return to, requester.Username, nil
+}
@@ -0,0 +1,200 @@
+package changes
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/padminisys/flintroute/internal/encryption"
+	"github.com/padminisys/flintroute/internal/testutil"
+	"github.com/padminisys/flintroute/internal/websocket"
+	"github.com/padminisys/flintroute/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func setupTestService(t *testing.T) *Service {
+	t.Helper()
+
+	logger := zap.NewNop()
+	db := testutil.SetupTestDB(t)
+	t.Cleanup(func() { testutil.CleanupTestDB(t, db) })
+
+	cipher, err := encryption.NewCipher(bytes.Repeat([]byte{0x42}, encryption.KeySize))
+	require.NoError(t, err)
+
+	hub := websocket.NewHub(logger)
+	go hub.Run()
+	return NewService(db, cipher, hub, nil, nil, logger)
+}
+
+func TestSubmit(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Unknown operation", func(t *testing.T) {
+		service := setupTestService(t)
+
+		_, err := service.Submit(ctx, "peer.explode", "", nil, "", 1)
+		assert.ErrorIs(t, err, ErrUnknownOperation)
+	})
+
+	t.Run("Stores a pending change with an encrypted secret", func(t *testing.T) {
+		service := setupTestService(t)
+		service.Register("peer.create", func(ctx context.Context, change *models.ChangeRequest, secret string) (interface{}, error) {
+			return nil, nil
+		})
+
+		change, err := service.Submit(ctx, "peer.create", "Create peer 10.0.0.1", map[string]string{"ip_address": "10.0.0.1"}, "s3cret", 1)
+		require.NoError(t, err)
+
+		stored, err := service.Get(ctx, change.ID)
+		require.NoError(t, err)
+		assert.Equal(t, models.ChangePending, stored.Status)
+		assert.JSONEq(t, `{"ip_address":"10.0.0.1"}`, string(stored.Payload))
+		assert.Equal(t, uint(1), stored.RequestedBy)
+		assert.True(t, encryption.IsEncrypted(stored.Secret))
+	})
+
+	t.Run("Missing change", func(t *testing.T) {
+		service := setupTestService(t)
+
+		_, err := service.Get(ctx, 42)
+		assert.ErrorIs(t, err, ErrChangeNotFound)
+	})
+}
+
+func TestApprovers(t *testing.T) {
+	ctx := context.Background()
+	service := setupTestService(t)
+	db := service.db
+
+	users := []models.User{
+		{Username: "alice", PasswordHash: "x", Email: "alice@example.com", Role: "admin", Active: true},
+		{Username: "bob", PasswordHash: "x", Email: "bob@example.com", Role: "admin", Active: true},
+		{Username: "carol", PasswordHash: "x", Email: "carol@example.com", Role: "admin", Active: true},
+		{Username: "dave", PasswordHash: "x", Email: "dave@example.com", Role: "operator", Active: true},
+		{Username: "erin", PasswordHash: "x", Role: "admin", Active: true},
+	}
+	for i := range users {
+		require.NoError(t, db.Create(&users[i]).Error)
+	}
+	require.NoError(t, db.Model(&users[2]).Update("active", false).Error)
+	require.NoError(t, db.Model(&models.User{}).Where("username = ?", "admin").Update("active", false).Error)
+
+	to, requestedBy, err := service.approvers(ctx, &models.ChangeRequest{RequestedBy: users[0].ID})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"bob@example.com"}, to)
+	assert.Equal(t, "alice", requestedBy)
+}
+
+func TestApprove(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Applies the change", func(t *testing.T) {
+		service := setupTestService(t)
+		var gotSecret string
+		service.Register("peer.create", func(ctx context.Context, change *models.ChangeRequest, secret string) (interface{}, error) {
+			gotSecret = secret
+			return map[string]int{"peer_id": 7}, nil
+		})
+		change, err := service.Submit(ctx, "peer.create", "", nil, "s3cret", 1)
+		require.NoError(t, err)
+
+		approved, err := service.Approve(ctx, change.ID, 2, "looks good")
+		require.NoError(t, err)
+		assert.Equal(t, models.ChangeApplied, approved.Status)
+		assert.Equal(t, "s3cret", gotSecret)
+
+		stored, err := service.Get(ctx, change.ID)
+		require.NoError(t, err)
+		assert.Equal(t, models.ChangeApplied, stored.Status)
+		assert.JSONEq(t, `{"peer_id":7}`, string(stored.Result))
+		assert.Equal(t, uint(2), *stored.ReviewedBy)
+		assert.NotNil(t, stored.ReviewedAt)
+		assert.Equal(t, "looks good", stored.Comment)
+	})
+
+	t.Run("Records failures to apply", func(t *testing.T) {
+		service := setupTestService(t)
+		service.Register("peer.delete", func(ctx context.Context, change *models.ChangeRequest, secret string) (interface{}, error) {
+			return nil, errors.New("peer not found")
+		})
+		change, err := service.Submit(ctx, "peer.delete", "", nil, "", 1)
+		require.NoError(t, err)
+
+		approved, err := service.Approve(ctx, change.ID, 2, "")
+		require.NoError(t, err)
+		assert.Equal(t, models.ChangeFailed, approved.Status)
+
+		stored, err := service.Get(ctx, change.ID)
+		require.NoError(t, err)
+		assert.Equal(t, models.ChangeFailed, stored.Status)
+		assert.Equal(t, "peer not found", stored.Error)
+	})
+
+	t.Run("Requesters can't approve their own changes", func(t *testing.T) {
+		service := setupTestService(t)
+		applied := false
+		service.Register("peer.delete", func(ctx context.Context, change *models.ChangeRequest, secret string) (interface{}, error) {
+			applied = true
+			return nil, nil
+		})
+		change, err := service.Submit(ctx, "peer.delete", "", nil, "", 1)
+		require.NoError(t, err)
+
+		_, err = service.Approve(ctx, change.ID, 1, "")
+		assert.ErrorIs(t, err, ErrSelfReview)
+		assert.False(t, applied)
+
+		stored, err := service.Get(ctx, change.ID)
+		require.NoError(t, err)
+		assert.Equal(t, models.ChangePending, stored.Status)
+	})
+
+	t.Run("Changes are reviewed only once", func(t *testing.T) {
+		service := setupTestService(t)
+		calls := 0
+		service.Register("peer.delete", func(ctx context.Context, change *models.ChangeRequest, secret string) (interface{}, error) {
+			calls++
+			return nil, nil
+		})
+		change, err := service.Submit(ctx, "peer.delete", "", nil, "", 1)
+		require.NoError(t, err)
+
+		_, err = service.Approve(ctx, change.ID, 2, "")
+		require.NoError(t, err)
+		_, err = service.Approve(ctx, change.ID, 3, "")
+		assert.ErrorIs(t, err, ErrNotPending)
+		_, err = service.Reject(ctx, change.ID, 3, "")
+		assert.ErrorIs(t, err, ErrNotPending)
+		assert.Equal(t, 1, calls)
+	})
+}
+
+func TestReject(t *testing.T) {
+	ctx := context.Background()
+	service := setupTestService(t)
+	applied := false
+	service.Register("config.restore", func(ctx context.Context, change *models.ChangeRequest, secret string) (interface{}, error) {
+		applied = true
+		return nil, nil
+	})
+	change, err := service.Submit(ctx, "config.restore", "", nil, "", 1)
+	require.NoError(t, err)
+
+	rejected, err := service.Reject(ctx, change.ID, 2, "wrong version")
+	require.NoError(t, err)
+	assert.Equal(t, models.ChangeRejected, rejected.Status)
+	assert.False(t, applied)
+
+	pending, err := service.List(ctx, models.ChangePending)
+	require.NoError(t, err)
+	assert.Empty(t, pending)
+
+	all, err := service.List(ctx, "")
+	require.NoError(t, err)
+	require.Len(t, all, 1)
+	assert.Equal(t, "wrong version", all[0].Comment)
+}
@@ -2,6 +2,7 @@ package config
 
 import (
 	"fmt"
+	"net/url"
 	"os"
 
 	"github.com/spf13/viper"
@@ -9,34 +10,607 @@ import (
 
 // Config represents the application configuration
 type Config struct {
-	Server   ServerConfig   `mapstructure:"server"`
-	Database DatabaseConfig `mapstructure:"database"`
-	FRR      FRRConfig      `mapstructure:"frr"`
-	Auth     AuthConfig     `mapstructure:"auth"`
+	Server         ServerConfig         `mapstructure:"server"`
+	Database       DatabaseConfig       `mapstructure:"database"`
+	FRR            FRRConfig            `mapstructure:"frr"`
+	Peers          PeersConfig          `mapstructure:"peers"`
+	PeeringDB      PeeringDBConfig      `mapstructure:"peeringdb"`
+	Auth           AuthConfig           `mapstructure:"auth"`
+	Secrets        SecretsConfig        `mapstructure:"secrets"`
+	GitOps         GitOpsConfig         `mapstructure:"gitops"`
+	Webhooks       WebhooksConfig       `mapstructure:"webhooks"`
+	Alertmanager   AlertmanagerConfig   `mapstructure:"alertmanager"`
+	SNMP           SNMPConfig           `mapstructure:"snmp"`
+	Alerts         AlertsConfig         `mapstructure:"alerts"`
+	ConfigVersions ConfigVersionsConfig `mapstructure:"config_versions"`
+	Jobs           JobsConfig           `mapstructure:"jobs"`
+	Cache          CacheConfig          `mapstructure:"cache"`
+	Approvals      ApprovalsConfig      `mapstructure:"approvals"`
+	Tenancy        TenancyConfig        `mapstructure:"tenancy"`
+	Logging        LoggingConfig        `mapstructure:"logging"`
+	WebSocket      WebSocketConfig      `mapstructure:"websocket"`
+	HA             HAConfig             `mapstructure:"ha"`
+	Store          StoreConfig          `mapstructure:"store"`
 }
 
 // ServerConfig represents HTTP server configuration
 type ServerConfig struct {
 	Host string `mapstructure:"host"`
 	Port int    `mapstructure:"port"`
+	// ReadOnly starts the API rejecting changes, e.g. on a standby
+	// instance; admins can turn it off at runtime
+	ReadOnly bool `mapstructure:"read_only"`
+	// IdempotencyWindow is how long responses to POSTs sent with an
+	// Idempotency-Key are kept for replay; "0" ignores the header
+	IdempotencyWindow string `mapstructure:"idempotency_window"`
+	// RequestQuota is how many API requests per hour each user may make,
+	// unless admins set the user's own quota; 0 is unlimited
+	RequestQuota int `mapstructure:"request_quota"`
+	// GRPCPort serves the gRPC API on Host; 0 disables it
+	GRPCPort int `mapstructure:"grpc_port"`
 }
 
 // DatabaseConfig represents database configuration
 type DatabaseConfig struct {
+	// Driver is sqlite, the default, or postgres. Instances running with
+	// HA must share a postgres database.
+	Driver string `mapstructure:"driver"`
+	// Path is the SQLite database file
 	Path string `mapstructure:"path"`
+	// DSN is the Postgres connection string, such as
+	// "postgres://flintroute:secret@db:5432/flintroute?sslmode=require"
+	DSN string `mapstructure:"dsn"`
+	// EncryptionKey is a base64-encoded 32-byte key used to encrypt BGP
+	// peer passwords at rest. If empty, the key is read from (or generated
+	// into) EncryptionKeyFile.
+	EncryptionKey     string `mapstructure:"encryption_key"`
+	EncryptionKeyFile string `mapstructure:"encryption_key_file"`
+	// Snapshots configures where admins' snapshots of the database are kept
+	Snapshots SnapshotsConfig `mapstructure:"snapshots"`
 }
 
-// FRRConfig represents FRR gRPC configuration
+// SnapshotsConfig selects where database snapshots are kept
+type SnapshotsConfig struct {
+	Backend string   `mapstructure:"backend"` // filesystem or s3
+	Dir     string   `mapstructure:"dir"`     // filesystem backend directory
+	S3      S3Config `mapstructure:"s3"`
+}
+
+// FRRConfig represents FRR connection configuration
 type FRRConfig struct {
-	GRPCHost string `mapstructure:"grpc_host"`
-	GRPCPort int    `mapstructure:"grpc_port"`
+	// Transport selects how FlintRoute talks to FRR: "grpc" uses the
+	// northbound gRPC API, "vtysh" runs vtysh for builds without it
+	Transport string      `mapstructure:"transport"`
+	GRPCHost  string      `mapstructure:"grpc_host"`
+	GRPCPort  int         `mapstructure:"grpc_port"`
+	Vtysh     VtyshConfig `mapstructure:"vtysh"`
+	// ConsistencyMode controls how FRR apply failures are handled:
+	// "strict" rolls back the database change, "eventual" marks the peer
+	// as pending sync and retries in the background
+	ConsistencyMode string `mapstructure:"consistency_mode"`
+	// ReconcileInterval is how often stored peers are compared with FRR's
+	// running configuration; "0" disables the reconciler
+	ReconcileInterval string `mapstructure:"reconcile_interval"`
+	// AutoHeal re-applies peers that have drifted from the stored intent
+	AutoHeal bool `mapstructure:"auto_heal"`
+	// OutOfBandInterval is how often FRR's running configuration is
+	// checked for changes made outside FlintRoute; "0" disables the check
+	OutOfBandInterval string `mapstructure:"out_of_band_interval"`
+	// PollInterval is how often BGP session states are polled from FRR
+	PollInterval string `mapstructure:"poll_interval"`
+	// PollJitter randomizes each poll interval by up to this fraction of it
+	PollJitter float64 `mapstructure:"poll_jitter"`
+	// PollMaxBackoff caps the poll interval while FRR is unreachable; the
+	// interval doubles with every poll that finds FRR unreachable
+	PollMaxBackoff string `mapstructure:"poll_max_backoff"`
+	// StartupTimeout bounds how long startup waits for FRR before the API
+	// accepts changes without it; peers are synced once FRR connects
+	StartupTimeout string `mapstructure:"startup_timeout"`
+	// LocalASN is the router's AS number. Peers must use it as their local
+	// ASN, presenting a different AS with local_as instead. 0 takes it from
+	// the BGP global configuration or existing peers.
+	LocalASN uint32 `mapstructure:"local_asn"`
+	// Demo replaces FRR with a simulator, whatever the transport
+	Demo DemoConfig `mapstructure:"demo"`
+	// Limits protects FRR from bursts of operations
+	Limits FRRLimitsConfig `mapstructure:"limits"`
+}
+
+// FRRLimitsConfig paces and caps the operations FlintRoute sends FRR. Zero
+// values disable each limit.
+type FRRLimitsConfig struct {
+	// Rate caps configuration changes per second
+	Rate float64 `mapstructure:"rate"`
+	// Burst is how many changes may be sent at once above the rate
+	Burst int `mapstructure:"burst"`
+	// MaxConcurrent caps the operations in flight at once, shared by API
+	// requests, the session monitor and the reconciler
+	MaxConcurrent int `mapstructure:"max_concurrent"`
+	// BatchSize is how many peers a full sync pushes in one commit
+	BatchSize int `mapstructure:"batch_size"`
+	// FailureThreshold is how many operations in a row failing to reach
+	// FRR open the circuit breaker, which fails operations without sending
+	// them for OpenDuration
+	FailureThreshold int    `mapstructure:"failure_threshold"`
+	OpenDuration     string `mapstructure:"open_duration"`
+}
+
+// DemoConfig configures demo mode, where peers' sessions are simulated
+// instead of configured in FRR, for evaluating FlintRoute without a router
+type DemoConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	// SessionStateDelay is how long a simulated session spends in each
+	// state on its way to Established
+	SessionStateDelay string `mapstructure:"session_state_delay"`
+	// FlapInterval is how long a simulated session stays up on average
+	// before it flaps; "0" disables flaps
+	FlapInterval string `mapstructure:"flap_interval"`
+}
+
+// PeersConfig configures how deleted peers are kept
+type PeersConfig struct {
+	// TrashRetentionDays is how many days deleted peers stay in the trash
+	// before they are purged for good; 0 keeps them until purged by hand
+	TrashRetentionDays int    `mapstructure:"trash_retention_days"`
+	PurgeInterval      string `mapstructure:"purge_interval"`
+	// Reachability probes peer addresses independently of BGP
+	Reachability PeerReachabilityConfig `mapstructure:"reachability"`
+}
+
+// PeerReachabilityConfig configures probing peer addresses at the IP layer,
+// to tell network failures from BGP misconfiguration
+type PeerReachabilityConfig struct {
+	// Interval is how often every enabled peer is probed; "0" disables it
+	Interval string `mapstructure:"interval"`
+	// Method is "icmp" to ping peers or "tcp" to connect to their BGP port
+	Method string `mapstructure:"method"`
+	// Count is how many probes each round sends to a peer
+	Count   int    `mapstructure:"count"`
+	Timeout string `mapstructure:"timeout"`
+	// LossThreshold is the percentage of probes lost that fails a round
+	LossThreshold float64 `mapstructure:"loss_threshold"`
+	// Failures is how many failed rounds in a row raise a peer_unreachable
+	// alert
+	Failures int `mapstructure:"failures"`
+	// Retention is how long samples are kept
+	Retention string `mapstructure:"retention"`
+}
+
+// PeeringDBConfig configures the PeeringDB lookups that pre-fill and check
+// peers
+type PeeringDBConfig struct {
+	// URL is the PeeringDB API; empty disables lookups
+	URL string `mapstructure:"url"`
+	// APIKey raises PeeringDB's anonymous rate limits; it can be a secret
+	// reference
+	APIKey  string `mapstructure:"api_key"`
+	Timeout string `mapstructure:"timeout"`
+	// CacheTTL is how long a network looked up is reused
+	CacheTTL string `mapstructure:"cache_ttl"`
+}
+
+// VtyshConfig configures the vtysh FRR transport
+type VtyshConfig struct {
+	Path string `mapstructure:"path"`
+	// SocketDir is the directory holding the daemons' VTY sockets; empty
+	// uses the vtysh default
+	SocketDir string `mapstructure:"socket_dir"`
+	// Timeout bounds each vtysh invocation
+	Timeout string `mapstructure:"timeout"`
 }
 
 // AuthConfig represents authentication configuration
 type AuthConfig struct {
-	JWTSecret     string `mapstructure:"jwt_secret"`
-	TokenExpiry   string `mapstructure:"token_expiry"`
-	RefreshExpiry string `mapstructure:"refresh_expiry"`
+	// JWTSecret signs tokens with HS256 unless SigningKey is set
+	JWTSecret string `mapstructure:"jwt_secret"`
+	// SigningKey is a PEM RSA or EC private key, or a secret reference to
+	// one, that signs tokens with RS256 or ES256 instead of JWTSecret
+	SigningKey string `mapstructure:"signing_key"`
+	// VerificationKeys are PEM public keys or certificates whose tokens are
+	// still accepted, e.g. the previous signing key during a rotation
+	VerificationKeys []string `mapstructure:"verification_keys"`
+	TokenExpiry      string   `mapstructure:"token_expiry"`
+	RefreshExpiry    string   `mapstructure:"refresh_expiry"`
+	// Impersonation lets admins act as other users for troubleshooting
+	Impersonation ImpersonationConfig `mapstructure:"impersonation"`
+	// APIKeys authenticate gRPC clients, such as automation, without a
+	// login
+	APIKeys []APIKeyConfig `mapstructure:"api_keys"`
+}
+
+// APIKeyConfig is a key that authenticates gRPC calls as a user, with the
+// user's role
+type APIKeyConfig struct {
+	Name string `mapstructure:"name"`
+	// Key is sent by clients as x-api-key metadata; it can be a secret
+	// reference
+	Key      string `mapstructure:"key"`
+	Username string `mapstructure:"username"`
+}
+
+// ImpersonationConfig configures admins impersonating users
+type ImpersonationConfig struct {
+	// Enabled allows admins to get tokens acting as other users; turn it
+	// off in hardened environments
+	Enabled bool `mapstructure:"enabled"`
+	// TokenExpiry is how long an impersonation token is valid
+	TokenExpiry string `mapstructure:"token_expiry"`
+}
+
+// SecretsConfig configures the providers used to resolve secret://
+// references in configuration values and BGP peer passwords
+type SecretsConfig struct {
+	// RefreshInterval is how often referenced secrets are re-read to pick
+	// up rotations; "0" disables rotation
+	RefreshInterval string            `mapstructure:"refresh_interval"`
+	File            FileSecretsConfig `mapstructure:"file"`
+	Vault           VaultConfig       `mapstructure:"vault"`
+}
+
+// FileSecretsConfig configures the file secrets provider
+type FileSecretsConfig struct {
+	BaseDir string `mapstructure:"base_dir"`
+}
+
+// VaultConfig configures the HashiCorp Vault secrets provider
+type VaultConfig struct {
+	Address string `mapstructure:"address"`
+	Token   string `mapstructure:"token"`
+	Mount   string `mapstructure:"mount"`
+}
+
+// GitOpsConfig configures syncing peer and policy definitions from a Git
+// repository
+type GitOpsConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	// RepoURL may be a secret:// reference when it embeds credentials
+	RepoURL string `mapstructure:"repo_url"`
+	Branch  string `mapstructure:"branch"`
+	// Path is the directory within the repository holding definitions
+	Path        string `mapstructure:"path"`
+	CheckoutDir string `mapstructure:"checkout_dir"`
+	// Interval is how often the repository is polled; "0" only syncs on
+	// webhook pushes and manual requests
+	Interval string `mapstructure:"interval"`
+	// WebhookSecret enables the push webhook endpoint
+	WebhookSecret string `mapstructure:"webhook_secret"`
+	// Prune deletes GitOps-managed peers and policies that were removed
+	// from the repository
+	Prune bool `mapstructure:"prune"`
+}
+
+// WebhooksConfig configures delivery of lifecycle event webhooks
+type WebhooksConfig struct {
+	// MaxAttempts is how many times a delivery is tried before it is
+	// marked as failed
+	MaxAttempts int    `mapstructure:"max_attempts"`
+	Timeout     string `mapstructure:"timeout"`
+	// RetryBackoff is the delay before the first retry; it doubles with
+	// every further attempt
+	RetryBackoff string `mapstructure:"retry_backoff"`
+}
+
+// AlertmanagerConfig configures exchanging alerts with Prometheus
+// Alertmanager
+type AlertmanagerConfig struct {
+	// ReceiverToken enables the Alertmanager webhook receiver endpoint,
+	// authenticated by this bearer token
+	ReceiverToken string `mapstructure:"receiver_token"`
+	// URLs are the Alertmanager instances FlintRoute alerts are pushed to;
+	// empty disables pushing
+	URLs         []string `mapstructure:"urls"`
+	PushInterval string   `mapstructure:"push_interval"`
+	// Router is the router label of pushed alerts; defaults to the hostname
+	Router string `mapstructure:"router"`
+	// ExternalURL is the generator URL of pushed alerts
+	ExternalURL string `mapstructure:"external_url"`
+	// Labels are added to every pushed alert
+	Labels map[string]string `mapstructure:"labels"`
+}
+
+// SNMPConfig configures SNMP traps for alerts
+type SNMPConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	// Targets are trap receivers as host or host:port (default port 162)
+	Targets []string `mapstructure:"targets"`
+	// Version is "2c" or "3"
+	Version string `mapstructure:"version"`
+	// Community may be a secret:// reference
+	Community string       `mapstructure:"community"`
+	V3        SNMPv3Config `mapstructure:"v3"`
+	// Severities lists the alert severities that send traps
+	Severities []string `mapstructure:"severities"`
+	// EnterpriseOID roots the FlintRoute MIB
+	EnterpriseOID string `mapstructure:"enterprise_oid"`
+	// Router is sent with every trap; defaults to the hostname
+	Router  string `mapstructure:"router"`
+	Timeout string `mapstructure:"timeout"`
+	Retries int    `mapstructure:"retries"`
+}
+
+// SNMPv3Config holds SNMPv3 credentials. Passphrases may be secret://
+// references.
+type SNMPv3Config struct {
+	Username       string `mapstructure:"username"`
+	SecurityLevel  string `mapstructure:"security_level"` // noAuthNoPriv, authNoPriv, authPriv
+	AuthProtocol   string `mapstructure:"auth_protocol"`
+	AuthPassphrase string `mapstructure:"auth_passphrase"`
+	PrivProtocol   string `mapstructure:"priv_protocol"`
+	PrivPassphrase string `mapstructure:"priv_passphrase"`
+	// EngineID is the hex-encoded engine ID of traps; derived when empty
+	EngineID string `mapstructure:"engine_id"`
+}
+
+// AlertsConfig configures alert housekeeping
+type AlertsConfig struct {
+	Retention AlertRetentionConfig `mapstructure:"retention"`
+	Dedup     AlertDedupConfig     `mapstructure:"dedup"`
+	Digest    AlertDigestConfig    `mapstructure:"digest"`
+	Anomalies AlertAnomaliesConfig `mapstructure:"anomalies"`
+	// Notifications sends alerts to users by their notification settings
+	Notifications AlertNotificationsConfig `mapstructure:"notifications"`
+	// Templates customizes the emails of notifications, digests and
+	// approval requests
+	Templates EmailTemplatesConfig `mapstructure:"templates"`
+}
+
+// EmailTemplatesConfig configures the templates emails are rendered from
+type EmailTemplatesConfig struct {
+	// Dir holds templates replacing the built-in ones of the same file
+	// name; empty uses the built-in templates
+	Dir      string              `mapstructure:"dir"`
+	Branding EmailBrandingConfig `mapstructure:"branding"`
+}
+
+// EmailBrandingConfig is what emails show of the deployment. Empty fields
+// keep the defaults.
+type EmailBrandingConfig struct {
+	// Name replaces "FlintRoute" in subjects and headers
+	Name string `mapstructure:"name"`
+	// URL links to the web UI
+	URL string `mapstructure:"url"`
+	// LogoURL is an image shown in HTML headers in place of Name
+	LogoURL string `mapstructure:"logo_url"`
+	// Color is the accent color of HTML emails, such as "#1f6feb"
+	Color  string `mapstructure:"color"`
+	Footer string `mapstructure:"footer"`
+}
+
+// AlertAnomaliesConfig configures detecting sudden changes in the number of
+// prefixes a peer sends
+type AlertAnomaliesConfig struct {
+	// Interval is how often prefix counts are analyzed; "0" disables it
+	Interval string `mapstructure:"interval"`
+	// Window is how far back a peer's prefix count is compared
+	Window string `mapstructure:"window"`
+	// ThresholdPercent is the change within Window, up or down, that
+	// raises an alert
+	ThresholdPercent float64 `mapstructure:"threshold_percent"`
+	// MinPrefixes skips peers that sent fewer prefixes at the start of
+	// Window, whose counts swing by large percentages
+	MinPrefixes int `mapstructure:"min_prefixes"`
+}
+
+// AlertDedupConfig configures folding repeated alerts together, keyed by
+// alert type and peer
+type AlertDedupConfig struct {
+	// Window counts a repeat of an open alert last seen within it on that
+	// alert instead of raising a new one; "0" disables deduplication
+	Window string `mapstructure:"window"`
+	// StormThreshold stops notifying an alert type and peer once it occurred
+	// more than this many times within StormWindow; 0 disables it
+	StormThreshold int    `mapstructure:"storm_threshold"`
+	StormWindow    string `mapstructure:"storm_window"`
+}
+
+// AlertDigestConfig configures periodic summaries of recent alerts, sent as
+// alert.digest webhook events and optionally by email
+type AlertDigestConfig struct {
+	Enabled  bool                   `mapstructure:"enabled"`
+	Interval string                 `mapstructure:"interval"`
+	Email    AlertDigestEmailConfig `mapstructure:"email"`
+}
+
+// AlertDigestEmailConfig configures emailing digests. An empty SMTPHost
+// disables email.
+type AlertDigestEmailConfig struct {
+	SMTPHost string `mapstructure:"smtp_host"`
+	SMTPPort int    `mapstructure:"smtp_port"`
+	Username string `mapstructure:"username"`
+	// Password may be a secret:// reference
+	Password string   `mapstructure:"password"`
+	From     string   `mapstructure:"from"`
+	To       []string `mapstructure:"to"`
+}
+
+// AlertNotificationsConfig configures the channels of per-user alert
+// notifications
+type AlertNotificationsConfig struct {
+	Email AlertNotificationEmailConfig `mapstructure:"email"`
+}
+
+// AlertNotificationEmailConfig configures the SMTP server for email
+// notifications, sent to each user's address. An empty SMTPHost disables
+// email.
+type AlertNotificationEmailConfig struct {
+	SMTPHost string `mapstructure:"smtp_host"`
+	SMTPPort int    `mapstructure:"smtp_port"`
+	Username string `mapstructure:"username"`
+	// Password may be a secret:// reference
+	Password string `mapstructure:"password"`
+	From     string `mapstructure:"from"`
+}
+
+// AlertRetentionConfig configures archiving and purging old alerts. A value
+// of 0 days disables that step.
+type AlertRetentionConfig struct {
+	// ArchiveAfterDays archives acknowledged or resolved alerts this many
+	// days after they were raised
+	ArchiveAfterDays int `mapstructure:"archive_after_days"`
+	// DeleteAfterDays permanently deletes alerts of any state this many
+	// days after they were raised
+	DeleteAfterDays int `mapstructure:"delete_after_days"`
+	// ExportDir receives an NDJSON file of the alerts before each purge;
+	// empty skips the export
+	ExportDir string `mapstructure:"export_dir"`
+	Interval  string `mapstructure:"interval"`
+}
+
+// ConfigVersionsConfig configures how configuration backups are stored and
+// pruned. A limit of 0 disables it.
+type ConfigVersionsConfig struct {
+	// MaxVersions keeps only this many of the newest versions
+	MaxVersions int `mapstructure:"max_versions"`
+	// MaxAgeDays deletes versions older than this many days; the newest
+	// version is always kept
+	MaxAgeDays int `mapstructure:"max_age_days"`
+	// Compress gzips new versions before storing them
+	Compress      bool                `mapstructure:"compress"`
+	PruneInterval string              `mapstructure:"prune_interval"`
+	Storage       ConfigStorageConfig `mapstructure:"storage"`
+	Restore       RestoreConfig       `mapstructure:"restore"`
+}
+
+// RestoreConfig configures how restores are verified. After a version is
+// applied, the sessions that were established are watched for VerifyWindow;
+// when more than MaxSessionsDown of them go down, the configuration from
+// before the restore is applied again. A window of "0" skips verification.
+type RestoreConfig struct {
+	VerifyWindow    string `mapstructure:"verify_window"`
+	CheckInterval   string `mapstructure:"check_interval"`
+	MaxSessionsDown int    `mapstructure:"max_sessions_down"`
+}
+
+// ConfigStorageConfig selects where configuration text is kept. With the
+// filesystem or s3 backend only metadata stays in the database.
+type ConfigStorageConfig struct {
+	Backend string `mapstructure:"backend"` // database, filesystem or s3
+	// MinSize keeps configs smaller than this many bytes in the database
+	MinSize int      `mapstructure:"min_size"`
+	Dir     string   `mapstructure:"dir"` // filesystem backend directory
+	S3      S3Config `mapstructure:"s3"`
+}
+
+// S3Config configures an S3-compatible object store. The secret access key
+// may be a secret:// reference.
+type S3Config struct {
+	// Endpoint defaults to AWS for the region; set it for MinIO and others
+	Endpoint        string `mapstructure:"endpoint"`
+	Region          string `mapstructure:"region"`
+	Bucket          string `mapstructure:"bucket"`
+	Prefix          string `mapstructure:"prefix"`
+	AccessKeyID     string `mapstructure:"access_key_id"`
+	SecretAccessKey string `mapstructure:"secret_access_key"`
+	// UsePathStyle addresses objects as endpoint/bucket/key instead of
+	// bucket.endpoint/key
+	UsePathStyle bool `mapstructure:"use_path_style"`
+}
+
+// JobsConfig configures the background job queue that runs config
+// restores, reconciliation, GitOps syncs and scheduled peer actions
+type JobsConfig struct {
+	// Workers is how many jobs run at the same time
+	Workers int `mapstructure:"workers"`
+	// AnnounceBefore is how long before a scheduled peer action runs it is
+	// announced with an alert
+	AnnounceBefore string `mapstructure:"announce_before"`
+}
+
+// CacheConfig configures the in-memory cache of peer and session list
+// responses
+type CacheConfig struct {
+	// TTL bounds how long a cached response is served; changes made
+	// through FlintRoute invalidate it sooner. "0" disables the cache.
+	TTL string `mapstructure:"ttl"`
+}
+
+// ApprovalsConfig configures the two-person rule for configuration changes
+type ApprovalsConfig struct {
+	// Enabled holds peer creations, peer deletions and config restores as
+	// change requests until a second admin approves them
+	Enabled bool `mapstructure:"enabled"`
+}
+
+// TenancyConfig configures multi-tenancy
+type TenancyConfig struct {
+	// Enabled scopes peers, alerts and config versions to the tenant a
+	// request acts for; users other than admins only see their tenants'
+	Enabled bool `mapstructure:"enabled"`
+}
+
+// LoggingConfig configures the application log
+type LoggingConfig struct {
+	// Level is debug, info, warn or error; admins can change it at runtime
+	Level  string `mapstructure:"level"`
+	Format string `mapstructure:"format"` // json or console
+	// File receives the log instead of stdout when set, rotated when it
+	// reaches MaxSizeMB
+	File       string `mapstructure:"file"`
+	MaxSizeMB  int    `mapstructure:"max_size_mb"`
+	MaxBackups int    `mapstructure:"max_backups"`
+	// MaxAgeDays deletes rotated files older than this many days; 0 keeps
+	// them until MaxBackups is reached
+	MaxAgeDays int  `mapstructure:"max_age_days"`
+	Compress   bool `mapstructure:"compress"` // gzip rotated files
+	// RequestSampleRate is the fraction of successful HTTP requests logged,
+	// from 0 to 1. Failed and slow requests are always logged.
+	RequestSampleRate float64 `mapstructure:"request_sample_rate"`
+}
+
+// WebSocketConfig configures how WebSocket and SSE event streams treat
+// clients that read slower than events are published
+type WebSocketConfig struct {
+	// SendBuffer is how many events are queued for each client
+	SendBuffer int `mapstructure:"send_buffer"`
+	// SlowClientPolicy is what happens to an event for a client whose
+	// queue is full: "disconnect" drops the client, which can reconnect
+	// and resume; "drop" discards the event and keeps the client
+	SlowClientPolicy string `mapstructure:"slow_client_policy"`
+	// MaxDropped disconnects a client under the drop policy once this many
+	// of its events were discarded; 0 never does
+	MaxDropped int `mapstructure:"max_dropped"`
+	// EventRetention is how many broadcast events are kept in the database
+	// for clients resuming with last_seq and for the event history
+	EventRetention int `mapstructure:"event_retention"`
+}
+
+// HAConfig configures active/standby operation of several instances
+// sharing a database
+type HAConfig struct {
+	// Enabled elects a leader through a lease in the database. Only the
+	// leader monitors sessions, reconciles and applies FRR changes.
+	Enabled bool `mapstructure:"enabled"`
+	// Identity names this instance in the lease; defaults to the host name
+	Identity string `mapstructure:"identity"`
+	// LeaseDuration is how long a standby waits for a failed leader's
+	// lease to expire before taking over
+	LeaseDuration string `mapstructure:"lease_duration"`
+	// RenewInterval is how often the leader renews its lease and standbys
+	// try to take it; it must be well under LeaseDuration
+	RenewInterval string `mapstructure:"renew_interval"`
+}
+
+// StoreConfig selects where state that instances must share is kept:
+// revoked access tokens, request quota counters and the buffer of recent
+// events replayed to reconnecting clients
+type StoreConfig struct {
+	// Backend is "memory", for a single instance, or "redis" for several
+	// instances behind a load balancer
+	Backend string      `mapstructure:"backend"`
+	Redis   RedisConfig `mapstructure:"redis"`
+}
+
+// RedisConfig configures the connection to Redis. Password may be a
+// secret:// reference.
+type RedisConfig struct {
+	Address  string `mapstructure:"address"`
+	Username string `mapstructure:"username"`
+	Password string `mapstructure:"password"`
+	DB       int    `mapstructure:"db"`
+	// KeyPrefix is put in front of every key, so several deployments can
+	// share a Redis server
+	KeyPrefix string `mapstructure:"key_prefix"`
+	TLS       bool   `mapstructure:"tls"`
 }
 
 // Load loads configuration from file or environment variables
@@ -46,12 +620,125 @@ func Load() (*Config, error) {
 	// Set default values
 	v.SetDefault("server.host", "0.0.0.0")
 	v.SetDefault("server.port", 8080)
+	v.SetDefault("server.read_only", false)
+	v.SetDefault("server.idempotency_window", "24h")
+	v.SetDefault("server.request_quota", 0)
+	v.SetDefault("server.grpc_port", 0)
+	v.SetDefault("database.driver", "sqlite")
 	v.SetDefault("database.path", "./data/flintroute.db")
+	v.SetDefault("database.dsn", "")
+	v.SetDefault("database.encryption_key_file", "./data/encryption.key")
+	v.SetDefault("database.snapshots.backend", "filesystem")
+	v.SetDefault("database.snapshots.dir", "./data/snapshots")
+	v.SetDefault("frr.transport", "grpc")
 	v.SetDefault("frr.grpc_host", "localhost")
 	v.SetDefault("frr.grpc_port", 50051)
+	v.SetDefault("frr.consistency_mode", "eventual")
+	v.SetDefault("frr.reconcile_interval", "5m")
+	v.SetDefault("frr.auto_heal", false)
+	v.SetDefault("frr.out_of_band_interval", "1m")
+	v.SetDefault("frr.poll_interval", "30s")
+	v.SetDefault("frr.poll_jitter", 0.1)
+	v.SetDefault("frr.poll_max_backoff", "5m")
+	v.SetDefault("frr.startup_timeout", "1m")
+	v.SetDefault("frr.local_asn", 0)
+	v.SetDefault("frr.limits.rate", 20)
+	v.SetDefault("frr.limits.burst", 20)
+	v.SetDefault("frr.limits.max_concurrent", 4)
+	v.SetDefault("frr.limits.batch_size", 50)
+	v.SetDefault("frr.limits.failure_threshold", 5)
+	v.SetDefault("frr.limits.open_duration", "30s")
+	v.SetDefault("frr.demo.enabled", false)
+	v.SetDefault("frr.demo.session_state_delay", "2s")
+	v.SetDefault("frr.demo.flap_interval", "30m")
+	v.SetDefault("frr.vtysh.path", "vtysh")
+	v.SetDefault("frr.vtysh.timeout", "10s")
 	v.SetDefault("auth.jwt_secret", "changeme-in-production")
 	v.SetDefault("auth.token_expiry", "15m")
 	v.SetDefault("auth.refresh_expiry", "168h") // 7 days
+	v.SetDefault("auth.impersonation.enabled", true)
+	v.SetDefault("auth.impersonation.token_expiry", "10m")
+	v.SetDefault("secrets.refresh_interval", "5m")
+	v.SetDefault("secrets.file.base_dir", "/run/secrets")
+	v.SetDefault("secrets.vault.mount", "secret")
+	v.SetDefault("gitops.enabled", false)
+	v.SetDefault("gitops.branch", "main")
+	v.SetDefault("gitops.path", ".")
+	v.SetDefault("gitops.checkout_dir", "./data/gitops")
+	v.SetDefault("gitops.interval", "5m")
+	v.SetDefault("gitops.prune", true)
+	v.SetDefault("webhooks.max_attempts", 5)
+	v.SetDefault("webhooks.timeout", "10s")
+	v.SetDefault("webhooks.retry_backoff", "30s")
+	v.SetDefault("alertmanager.push_interval", "1m")
+	v.SetDefault("snmp.enabled", false)
+	v.SetDefault("snmp.version", "2c")
+	v.SetDefault("snmp.community", "public")
+	v.SetDefault("snmp.v3.security_level", "authPriv")
+	v.SetDefault("snmp.v3.auth_protocol", "SHA")
+	v.SetDefault("snmp.v3.priv_protocol", "AES")
+	v.SetDefault("snmp.severities", []string{"warning", "error", "critical"})
+	v.SetDefault("snmp.enterprise_oid", "1.3.6.1.4.1.99999")
+	v.SetDefault("snmp.timeout", "5s")
+	v.SetDefault("snmp.retries", 1)
+	v.SetDefault("alerts.retention.archive_after_days", 30)
+	v.SetDefault("alerts.retention.delete_after_days", 0)
+	v.SetDefault("alerts.retention.interval", "1h")
+	v.SetDefault("alerts.dedup.window", "1h")
+	v.SetDefault("alerts.dedup.storm_threshold", 20)
+	v.SetDefault("alerts.dedup.storm_window", "5m")
+	v.SetDefault("alerts.digest.enabled", false)
+	v.SetDefault("alerts.digest.interval", "1h")
+	v.SetDefault("alerts.digest.email.smtp_port", 587)
+	v.SetDefault("alerts.anomalies.interval", "5m")
+	v.SetDefault("alerts.anomalies.window", "1h")
+	v.SetDefault("alerts.anomalies.threshold_percent", 50)
+	v.SetDefault("alerts.anomalies.min_prefixes", 10)
+	v.SetDefault("alerts.notifications.email.smtp_port", 587)
+	v.SetDefault("peers.trash_retention_days", 30)
+	v.SetDefault("peers.purge_interval", "1h")
+	v.SetDefault("peers.reachability.interval", "0")
+	v.SetDefault("peers.reachability.method", "icmp")
+	v.SetDefault("peers.reachability.count", 3)
+	v.SetDefault("peers.reachability.timeout", "2s")
+	v.SetDefault("peers.reachability.loss_threshold", 100)
+	v.SetDefault("peers.reachability.failures", 3)
+	v.SetDefault("peers.reachability.retention", "24h")
+	v.SetDefault("peeringdb.url", "https://www.peeringdb.com/api")
+	v.SetDefault("peeringdb.timeout", "10s")
+	v.SetDefault("peeringdb.cache_ttl", "1h")
+	v.SetDefault("config_versions.max_versions", 0)
+	v.SetDefault("config_versions.max_age_days", 0)
+	v.SetDefault("config_versions.compress", false)
+	v.SetDefault("config_versions.prune_interval", "1h")
+	v.SetDefault("config_versions.storage.backend", "database")
+	v.SetDefault("config_versions.restore.verify_window", "2m")
+	v.SetDefault("config_versions.restore.check_interval", "10s")
+	v.SetDefault("config_versions.restore.max_sessions_down", 0)
+	v.SetDefault("config_versions.storage.min_size", 0)
+	v.SetDefault("jobs.workers", 2)
+	v.SetDefault("jobs.announce_before", "15m")
+	v.SetDefault("cache.ttl", "30s")
+	v.SetDefault("approvals.enabled", false)
+	v.SetDefault("tenancy.enabled", false)
+	v.SetDefault("logging.level", "info")
+	v.SetDefault("logging.format", "json")
+	v.SetDefault("logging.max_size_mb", 100)
+	v.SetDefault("logging.max_backups", 5)
+	v.SetDefault("logging.max_age_days", 0)
+	v.SetDefault("logging.compress", false)
+	v.SetDefault("logging.request_sample_rate", 1.0)
+	v.SetDefault("websocket.send_buffer", 256)
+	v.SetDefault("websocket.slow_client_policy", "disconnect")
+	v.SetDefault("websocket.max_dropped", 0)
+	v.SetDefault("websocket.event_retention", 10000)
+	v.SetDefault("ha.enabled", false)
+	v.SetDefault("ha.identity", "")
+	v.SetDefault("ha.lease_duration", "15s")
+	v.SetDefault("ha.renew_interval", "5s")
+	v.SetDefault("store.backend", "memory")
+	v.SetDefault("store.redis.db", 0)
+	v.SetDefault("store.redis.key_prefix", "flintroute:")
 
 	// Set config file name and paths
 	v.SetConfigName("config")
@@ -62,16 +749,172 @@ func Load() (*Config, error) {
 	// Enable environment variable override
 	v.SetEnvPrefix("FLINTROUTE")
 	v.AutomaticEnv()
-	
+
 	// Explicitly bind environment variables for nested keys
 	v.BindEnv("server.host", "FLINTROUTE_SERVER_HOST")
 	v.BindEnv("server.port", "FLINTROUTE_SERVER_PORT")
+	v.BindEnv("server.read_only", "FLINTROUTE_SERVER_READ_ONLY")
+	v.BindEnv("server.idempotency_window", "FLINTROUTE_SERVER_IDEMPOTENCY_WINDOW")
+	v.BindEnv("server.request_quota", "FLINTROUTE_SERVER_REQUEST_QUOTA")
+	v.BindEnv("server.grpc_port", "FLINTROUTE_SERVER_GRPC_PORT")
+	v.BindEnv("database.driver", "FLINTROUTE_DATABASE_DRIVER")
 	v.BindEnv("database.path", "FLINTROUTE_DATABASE_PATH")
+	v.BindEnv("database.dsn", "FLINTROUTE_DATABASE_DSN")
+	v.BindEnv("database.encryption_key", "FLINTROUTE_DATABASE_ENCRYPTION_KEY")
+	v.BindEnv("database.encryption_key_file", "FLINTROUTE_DATABASE_ENCRYPTION_KEY_FILE")
+	v.BindEnv("database.snapshots.backend", "FLINTROUTE_DATABASE_SNAPSHOTS_BACKEND")
+	v.BindEnv("database.snapshots.dir", "FLINTROUTE_DATABASE_SNAPSHOTS_DIR")
+	v.BindEnv("database.snapshots.s3.endpoint", "FLINTROUTE_DATABASE_SNAPSHOTS_S3_ENDPOINT")
+	v.BindEnv("database.snapshots.s3.region", "FLINTROUTE_DATABASE_SNAPSHOTS_S3_REGION", "AWS_REGION")
+	v.BindEnv("database.snapshots.s3.bucket", "FLINTROUTE_DATABASE_SNAPSHOTS_S3_BUCKET")
+	v.BindEnv("database.snapshots.s3.prefix", "FLINTROUTE_DATABASE_SNAPSHOTS_S3_PREFIX")
+	v.BindEnv("database.snapshots.s3.access_key_id", "FLINTROUTE_DATABASE_SNAPSHOTS_S3_ACCESS_KEY_ID", "AWS_ACCESS_KEY_ID")
+	v.BindEnv("database.snapshots.s3.secret_access_key", "FLINTROUTE_DATABASE_SNAPSHOTS_S3_SECRET_ACCESS_KEY", "AWS_SECRET_ACCESS_KEY")
+	v.BindEnv("frr.transport", "FLINTROUTE_FRR_TRANSPORT")
 	v.BindEnv("frr.grpc_host", "FLINTROUTE_FRR_GRPC_HOST")
 	v.BindEnv("frr.grpc_port", "FLINTROUTE_FRR_GRPC_PORT")
+	v.BindEnv("frr.consistency_mode", "FLINTROUTE_FRR_CONSISTENCY_MODE")
+	v.BindEnv("frr.reconcile_interval", "FLINTROUTE_FRR_RECONCILE_INTERVAL")
+	v.BindEnv("frr.auto_heal", "FLINTROUTE_FRR_AUTO_HEAL")
+	v.BindEnv("frr.out_of_band_interval", "FLINTROUTE_FRR_OUT_OF_BAND_INTERVAL")
+	v.BindEnv("frr.poll_interval", "FLINTROUTE_FRR_POLL_INTERVAL")
+	v.BindEnv("frr.poll_jitter", "FLINTROUTE_FRR_POLL_JITTER")
+	v.BindEnv("frr.poll_max_backoff", "FLINTROUTE_FRR_POLL_MAX_BACKOFF")
+	v.BindEnv("frr.startup_timeout", "FLINTROUTE_FRR_STARTUP_TIMEOUT")
+	v.BindEnv("frr.local_asn", "FLINTROUTE_FRR_LOCAL_ASN")
+	v.BindEnv("frr.limits.rate", "FLINTROUTE_FRR_LIMITS_RATE")
+	v.BindEnv("frr.limits.burst", "FLINTROUTE_FRR_LIMITS_BURST")
+	v.BindEnv("frr.limits.max_concurrent", "FLINTROUTE_FRR_LIMITS_MAX_CONCURRENT")
+	v.BindEnv("frr.limits.batch_size", "FLINTROUTE_FRR_LIMITS_BATCH_SIZE")
+	v.BindEnv("frr.limits.failure_threshold", "FLINTROUTE_FRR_LIMITS_FAILURE_THRESHOLD")
+	v.BindEnv("frr.limits.open_duration", "FLINTROUTE_FRR_LIMITS_OPEN_DURATION")
+	v.BindEnv("frr.demo.enabled", "FLINTROUTE_FRR_DEMO_ENABLED")
+	v.BindEnv("frr.demo.session_state_delay", "FLINTROUTE_FRR_DEMO_SESSION_STATE_DELAY")
+	v.BindEnv("frr.demo.flap_interval", "FLINTROUTE_FRR_DEMO_FLAP_INTERVAL")
+	v.BindEnv("frr.vtysh.path", "FLINTROUTE_FRR_VTYSH_PATH")
+	v.BindEnv("frr.vtysh.socket_dir", "FLINTROUTE_FRR_VTYSH_SOCKET_DIR")
 	v.BindEnv("auth.jwt_secret", "FLINTROUTE_AUTH_JWT_SECRET")
+	v.BindEnv("auth.signing_key", "FLINTROUTE_AUTH_SIGNING_KEY")
 	v.BindEnv("auth.token_expiry", "FLINTROUTE_AUTH_TOKEN_EXPIRY")
 	v.BindEnv("auth.refresh_expiry", "FLINTROUTE_AUTH_REFRESH_EXPIRY")
+	v.BindEnv("auth.impersonation.enabled", "FLINTROUTE_AUTH_IMPERSONATION_ENABLED")
+	v.BindEnv("auth.impersonation.token_expiry", "FLINTROUTE_AUTH_IMPERSONATION_TOKEN_EXPIRY")
+	v.BindEnv("secrets.refresh_interval", "FLINTROUTE_SECRETS_REFRESH_INTERVAL")
+	v.BindEnv("secrets.file.base_dir", "FLINTROUTE_SECRETS_FILE_BASE_DIR")
+	v.BindEnv("secrets.vault.address", "FLINTROUTE_SECRETS_VAULT_ADDRESS", "VAULT_ADDR")
+	v.BindEnv("secrets.vault.token", "FLINTROUTE_SECRETS_VAULT_TOKEN", "VAULT_TOKEN")
+	v.BindEnv("secrets.vault.mount", "FLINTROUTE_SECRETS_VAULT_MOUNT")
+	v.BindEnv("gitops.enabled", "FLINTROUTE_GITOPS_ENABLED")
+	v.BindEnv("gitops.repo_url", "FLINTROUTE_GITOPS_REPO_URL")
+	v.BindEnv("gitops.branch", "FLINTROUTE_GITOPS_BRANCH")
+	v.BindEnv("gitops.path", "FLINTROUTE_GITOPS_PATH")
+	v.BindEnv("gitops.checkout_dir", "FLINTROUTE_GITOPS_CHECKOUT_DIR")
+	v.BindEnv("gitops.interval", "FLINTROUTE_GITOPS_INTERVAL")
+	v.BindEnv("gitops.webhook_secret", "FLINTROUTE_GITOPS_WEBHOOK_SECRET")
+	v.BindEnv("gitops.prune", "FLINTROUTE_GITOPS_PRUNE")
+	v.BindEnv("webhooks.max_attempts", "FLINTROUTE_WEBHOOKS_MAX_ATTEMPTS")
+	v.BindEnv("webhooks.timeout", "FLINTROUTE_WEBHOOKS_TIMEOUT")
+	v.BindEnv("webhooks.retry_backoff", "FLINTROUTE_WEBHOOKS_RETRY_BACKOFF")
+	v.BindEnv("alertmanager.receiver_token", "FLINTROUTE_ALERTMANAGER_RECEIVER_TOKEN")
+	v.BindEnv("alertmanager.urls", "FLINTROUTE_ALERTMANAGER_URLS")
+	v.BindEnv("alertmanager.push_interval", "FLINTROUTE_ALERTMANAGER_PUSH_INTERVAL")
+	v.BindEnv("alertmanager.router", "FLINTROUTE_ALERTMANAGER_ROUTER")
+	v.BindEnv("alertmanager.external_url", "FLINTROUTE_ALERTMANAGER_EXTERNAL_URL")
+	v.BindEnv("snmp.enabled", "FLINTROUTE_SNMP_ENABLED")
+	v.BindEnv("snmp.targets", "FLINTROUTE_SNMP_TARGETS")
+	v.BindEnv("snmp.version", "FLINTROUTE_SNMP_VERSION")
+	v.BindEnv("snmp.community", "FLINTROUTE_SNMP_COMMUNITY")
+	v.BindEnv("snmp.v3.username", "FLINTROUTE_SNMP_V3_USERNAME")
+	v.BindEnv("snmp.v3.auth_passphrase", "FLINTROUTE_SNMP_V3_AUTH_PASSPHRASE")
+	v.BindEnv("snmp.v3.priv_passphrase", "FLINTROUTE_SNMP_V3_PRIV_PASSPHRASE")
+	v.BindEnv("snmp.severities", "FLINTROUTE_SNMP_SEVERITIES")
+	v.BindEnv("alerts.retention.archive_after_days", "FLINTROUTE_ALERTS_RETENTION_ARCHIVE_AFTER_DAYS")
+	v.BindEnv("alerts.retention.delete_after_days", "FLINTROUTE_ALERTS_RETENTION_DELETE_AFTER_DAYS")
+	v.BindEnv("alerts.retention.export_dir", "FLINTROUTE_ALERTS_RETENTION_EXPORT_DIR")
+	v.BindEnv("alerts.retention.interval", "FLINTROUTE_ALERTS_RETENTION_INTERVAL")
+	v.BindEnv("alerts.dedup.window", "FLINTROUTE_ALERTS_DEDUP_WINDOW")
+	v.BindEnv("alerts.dedup.storm_threshold", "FLINTROUTE_ALERTS_DEDUP_STORM_THRESHOLD")
+	v.BindEnv("alerts.dedup.storm_window", "FLINTROUTE_ALERTS_DEDUP_STORM_WINDOW")
+	v.BindEnv("alerts.digest.enabled", "FLINTROUTE_ALERTS_DIGEST_ENABLED")
+	v.BindEnv("alerts.digest.interval", "FLINTROUTE_ALERTS_DIGEST_INTERVAL")
+	v.BindEnv("alerts.digest.email.smtp_host", "FLINTROUTE_ALERTS_DIGEST_EMAIL_SMTP_HOST")
+	v.BindEnv("alerts.digest.email.smtp_port", "FLINTROUTE_ALERTS_DIGEST_EMAIL_SMTP_PORT")
+	v.BindEnv("alerts.digest.email.username", "FLINTROUTE_ALERTS_DIGEST_EMAIL_USERNAME")
+	v.BindEnv("alerts.digest.email.password", "FLINTROUTE_ALERTS_DIGEST_EMAIL_PASSWORD")
+	v.BindEnv("alerts.digest.email.from", "FLINTROUTE_ALERTS_DIGEST_EMAIL_FROM")
+	v.BindEnv("alerts.digest.email.to", "FLINTROUTE_ALERTS_DIGEST_EMAIL_TO")
+	v.BindEnv("alerts.anomalies.interval", "FLINTROUTE_ALERTS_ANOMALIES_INTERVAL")
+	v.BindEnv("alerts.anomalies.window", "FLINTROUTE_ALERTS_ANOMALIES_WINDOW")
+	v.BindEnv("alerts.anomalies.threshold_percent", "FLINTROUTE_ALERTS_ANOMALIES_THRESHOLD_PERCENT")
+	v.BindEnv("alerts.anomalies.min_prefixes", "FLINTROUTE_ALERTS_ANOMALIES_MIN_PREFIXES")
+	v.BindEnv("alerts.notifications.email.smtp_host", "FLINTROUTE_ALERTS_NOTIFICATIONS_EMAIL_SMTP_HOST")
+	v.BindEnv("alerts.notifications.email.smtp_port", "FLINTROUTE_ALERTS_NOTIFICATIONS_EMAIL_SMTP_PORT")
+	v.BindEnv("alerts.notifications.email.username", "FLINTROUTE_ALERTS_NOTIFICATIONS_EMAIL_USERNAME")
+	v.BindEnv("alerts.notifications.email.password", "FLINTROUTE_ALERTS_NOTIFICATIONS_EMAIL_PASSWORD")
+	v.BindEnv("alerts.notifications.email.from", "FLINTROUTE_ALERTS_NOTIFICATIONS_EMAIL_FROM")
+	v.BindEnv("alerts.templates.dir", "FLINTROUTE_ALERTS_TEMPLATES_DIR")
+	v.BindEnv("alerts.templates.branding.name", "FLINTROUTE_ALERTS_TEMPLATES_BRANDING_NAME")
+	v.BindEnv("alerts.templates.branding.url", "FLINTROUTE_ALERTS_TEMPLATES_BRANDING_URL")
+	v.BindEnv("alerts.templates.branding.logo_url", "FLINTROUTE_ALERTS_TEMPLATES_BRANDING_LOGO_URL")
+	v.BindEnv("alerts.templates.branding.color", "FLINTROUTE_ALERTS_TEMPLATES_BRANDING_COLOR")
+	v.BindEnv("alerts.templates.branding.footer", "FLINTROUTE_ALERTS_TEMPLATES_BRANDING_FOOTER")
+	v.BindEnv("peers.trash_retention_days", "FLINTROUTE_PEERS_TRASH_RETENTION_DAYS")
+	v.BindEnv("peers.purge_interval", "FLINTROUTE_PEERS_PURGE_INTERVAL")
+	v.BindEnv("peers.reachability.interval", "FLINTROUTE_PEERS_REACHABILITY_INTERVAL")
+	v.BindEnv("peers.reachability.method", "FLINTROUTE_PEERS_REACHABILITY_METHOD")
+	v.BindEnv("peers.reachability.count", "FLINTROUTE_PEERS_REACHABILITY_COUNT")
+	v.BindEnv("peers.reachability.timeout", "FLINTROUTE_PEERS_REACHABILITY_TIMEOUT")
+	v.BindEnv("peers.reachability.loss_threshold", "FLINTROUTE_PEERS_REACHABILITY_LOSS_THRESHOLD")
+	v.BindEnv("peers.reachability.failures", "FLINTROUTE_PEERS_REACHABILITY_FAILURES")
+	v.BindEnv("peers.reachability.retention", "FLINTROUTE_PEERS_REACHABILITY_RETENTION")
+	v.BindEnv("peeringdb.url", "FLINTROUTE_PEERINGDB_URL")
+	v.BindEnv("peeringdb.api_key", "FLINTROUTE_PEERINGDB_API_KEY")
+	v.BindEnv("peeringdb.timeout", "FLINTROUTE_PEERINGDB_TIMEOUT")
+	v.BindEnv("peeringdb.cache_ttl", "FLINTROUTE_PEERINGDB_CACHE_TTL")
+	v.BindEnv("config_versions.max_versions", "FLINTROUTE_CONFIG_VERSIONS_MAX_VERSIONS")
+	v.BindEnv("config_versions.max_age_days", "FLINTROUTE_CONFIG_VERSIONS_MAX_AGE_DAYS")
+	v.BindEnv("config_versions.compress", "FLINTROUTE_CONFIG_VERSIONS_COMPRESS")
+	v.BindEnv("config_versions.prune_interval", "FLINTROUTE_CONFIG_VERSIONS_PRUNE_INTERVAL")
+	v.BindEnv("config_versions.storage.backend", "FLINTROUTE_CONFIG_VERSIONS_STORAGE_BACKEND")
+	v.BindEnv("config_versions.restore.verify_window", "FLINTROUTE_CONFIG_VERSIONS_RESTORE_VERIFY_WINDOW")
+	v.BindEnv("config_versions.restore.check_interval", "FLINTROUTE_CONFIG_VERSIONS_RESTORE_CHECK_INTERVAL")
+	v.BindEnv("config_versions.restore.max_sessions_down", "FLINTROUTE_CONFIG_VERSIONS_RESTORE_MAX_SESSIONS_DOWN")
+	v.BindEnv("config_versions.storage.min_size", "FLINTROUTE_CONFIG_VERSIONS_STORAGE_MIN_SIZE")
+	v.BindEnv("config_versions.storage.dir", "FLINTROUTE_CONFIG_VERSIONS_STORAGE_DIR")
+	v.BindEnv("config_versions.storage.s3.endpoint", "FLINTROUTE_CONFIG_VERSIONS_STORAGE_S3_ENDPOINT")
+	v.BindEnv("config_versions.storage.s3.region", "FLINTROUTE_CONFIG_VERSIONS_STORAGE_S3_REGION", "AWS_REGION")
+	v.BindEnv("config_versions.storage.s3.bucket", "FLINTROUTE_CONFIG_VERSIONS_STORAGE_S3_BUCKET")
+	v.BindEnv("config_versions.storage.s3.prefix", "FLINTROUTE_CONFIG_VERSIONS_STORAGE_S3_PREFIX")
+	v.BindEnv("config_versions.storage.s3.access_key_id", "FLINTROUTE_CONFIG_VERSIONS_STORAGE_S3_ACCESS_KEY_ID", "AWS_ACCESS_KEY_ID")
+	v.BindEnv("config_versions.storage.s3.secret_access_key", "FLINTROUTE_CONFIG_VERSIONS_STORAGE_S3_SECRET_ACCESS_KEY", "AWS_SECRET_ACCESS_KEY")
+	v.BindEnv("jobs.workers", "FLINTROUTE_JOBS_WORKERS")
+	v.BindEnv("jobs.announce_before", "FLINTROUTE_JOBS_ANNOUNCE_BEFORE")
+	v.BindEnv("cache.ttl", "FLINTROUTE_CACHE_TTL")
+	v.BindEnv("approvals.enabled", "FLINTROUTE_APPROVALS_ENABLED")
+	v.BindEnv("tenancy.enabled", "FLINTROUTE_TENANCY_ENABLED")
+	v.BindEnv("logging.level", "FLINTROUTE_LOGGING_LEVEL")
+	v.BindEnv("logging.format", "FLINTROUTE_LOGGING_FORMAT")
+	v.BindEnv("logging.file", "FLINTROUTE_LOGGING_FILE")
+	v.BindEnv("logging.max_size_mb", "FLINTROUTE_LOGGING_MAX_SIZE_MB")
+	v.BindEnv("logging.max_backups", "FLINTROUTE_LOGGING_MAX_BACKUPS")
+	v.BindEnv("logging.max_age_days", "FLINTROUTE_LOGGING_MAX_AGE_DAYS")
+	v.BindEnv("logging.compress", "FLINTROUTE_LOGGING_COMPRESS")
+	v.BindEnv("logging.request_sample_rate", "FLINTROUTE_LOGGING_REQUEST_SAMPLE_RATE")
+	v.BindEnv("websocket.send_buffer", "FLINTROUTE_WEBSOCKET_SEND_BUFFER")
+	v.BindEnv("websocket.slow_client_policy", "FLINTROUTE_WEBSOCKET_SLOW_CLIENT_POLICY")
+	v.BindEnv("websocket.max_dropped", "FLINTROUTE_WEBSOCKET_MAX_DROPPED")
+	v.BindEnv("websocket.event_retention", "FLINTROUTE_WEBSOCKET_EVENT_RETENTION")
+	v.BindEnv("ha.enabled", "FLINTROUTE_HA_ENABLED")
+	v.BindEnv("ha.identity", "FLINTROUTE_HA_IDENTITY")
+	v.BindEnv("ha.lease_duration", "FLINTROUTE_HA_LEASE_DURATION")
+	v.BindEnv("ha.renew_interval", "FLINTROUTE_HA_RENEW_INTERVAL")
+	v.BindEnv("store.backend", "FLINTROUTE_STORE_BACKEND")
+	v.BindEnv("store.redis.address", "FLINTROUTE_STORE_REDIS_ADDRESS")
+	v.BindEnv("store.redis.username", "FLINTROUTE_STORE_REDIS_USERNAME")
+	v.BindEnv("store.redis.password", "FLINTROUTE_STORE_REDIS_PASSWORD")
+	v.BindEnv("store.redis.db", "FLINTROUTE_STORE_REDIS_DB")
+	v.BindEnv("store.redis.key_prefix", "FLINTROUTE_STORE_REDIS_KEY_PREFIX")
+	v.BindEnv("store.redis.tls", "FLINTROUTE_STORE_REDIS_TLS")
 
 	// Read config file if it exists
 	if err := v.ReadInConfig(); err != nil {
@@ -99,14 +942,218 @@ func validate(cfg *Config) error {
 	if cfg.Server.Port < 1 || cfg.Server.Port > 65535 {
 		return fmt.Errorf("invalid server port: %d", cfg.Server.Port)
 	}
+	if cfg.Server.RequestQuota < 0 {
+		return fmt.Errorf("invalid server.request_quota: %d", cfg.Server.RequestQuota)
+	}
+	if cfg.Server.GRPCPort < 0 || cfg.Server.GRPCPort > 65535 || (cfg.Server.GRPCPort != 0 && cfg.Server.GRPCPort == cfg.Server.Port) {
+		return fmt.Errorf("invalid server.grpc_port: %d", cfg.Server.GRPCPort)
+	}
+	names := make(map[string]bool, len(cfg.Auth.APIKeys))
+	for _, key := range cfg.Auth.APIKeys {
+		if key.Name == "" || key.Key == "" || key.Username == "" {
+			return fmt.Errorf("auth.api_keys entries need a name, key and username")
+		}
+		if names[key.Name] {
+			return fmt.Errorf("duplicate auth.api_keys name: %s", key.Name)
+		}
+		names[key.Name] = true
+	}
+
+	switch cfg.FRR.Transport {
+	case "", "grpc", "vtysh":
+	default:
+		return fmt.Errorf("invalid FRR transport: %s", cfg.FRR.Transport)
+	}
 
 	if cfg.FRR.GRPCPort < 1 || cfg.FRR.GRPCPort > 65535 {
 		return fmt.Errorf("invalid FRR gRPC port: %d", cfg.FRR.GRPCPort)
 	}
 
-	if cfg.Auth.JWTSecret == "" || cfg.Auth.JWTSecret == "changeme-in-production" {
+	switch cfg.FRR.ConsistencyMode {
+	case "", "strict", "eventual":
+	default:
+		return fmt.Errorf("invalid FRR consistency mode: %s", cfg.FRR.ConsistencyMode)
+	}
+
+	if cfg.FRR.PollJitter < 0 || cfg.FRR.PollJitter >= 1 {
+		return fmt.Errorf("invalid FRR poll jitter: %v", cfg.FRR.PollJitter)
+	}
+
+	if cfg.Jobs.Workers < 0 {
+		return fmt.Errorf("invalid job workers: %d", cfg.Jobs.Workers)
+	}
+
+	if cfg.Webhooks.MaxAttempts < 0 {
+		return fmt.Errorf("invalid webhook max attempts: %d", cfg.Webhooks.MaxAttempts)
+	}
+
+	for _, raw := range cfg.Alertmanager.URLs {
+		u, err := url.Parse(raw)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("invalid Alertmanager URL: %s", raw)
+		}
+	}
+
+	if cfg.SNMP.Enabled {
+		if len(cfg.SNMP.Targets) == 0 {
+			return fmt.Errorf("snmp.targets is required when SNMP traps are enabled")
+		}
+		switch cfg.SNMP.Version {
+		case "2c":
+		case "3":
+			if cfg.SNMP.V3.Username == "" {
+				return fmt.Errorf("snmp.v3.username is required for SNMPv3")
+			}
+		default:
+			return fmt.Errorf("invalid SNMP version: %s", cfg.SNMP.Version)
+		}
+	}
+
+	if cfg.Alerts.Retention.ArchiveAfterDays < 0 {
+		return fmt.Errorf("invalid alert archive_after_days: %d", cfg.Alerts.Retention.ArchiveAfterDays)
+	}
+	if cfg.Alerts.Retention.DeleteAfterDays < 0 {
+		return fmt.Errorf("invalid alert delete_after_days: %d", cfg.Alerts.Retention.DeleteAfterDays)
+	}
+	if cfg.Alerts.Dedup.StormThreshold < 0 {
+		return fmt.Errorf("invalid alerts.dedup.storm_threshold: %d", cfg.Alerts.Dedup.StormThreshold)
+	}
+	if email := cfg.Alerts.Digest.Email; cfg.Alerts.Digest.Enabled && email.SMTPHost != "" && (email.From == "" || len(email.To) == 0) {
+		return fmt.Errorf("alerts.digest.email.from and to are required when smtp_host is set")
+	}
+	if cfg.Alerts.Anomalies.ThresholdPercent < 0 {
+		return fmt.Errorf("invalid alerts.anomalies.threshold_percent: %g", cfg.Alerts.Anomalies.ThresholdPercent)
+	}
+	if cfg.Alerts.Anomalies.MinPrefixes < 0 {
+		return fmt.Errorf("invalid alerts.anomalies.min_prefixes: %d", cfg.Alerts.Anomalies.MinPrefixes)
+	}
+	if email := cfg.Alerts.Notifications.Email; email.SMTPHost != "" && email.From == "" {
+		return fmt.Errorf("alerts.notifications.email.from is required when smtp_host is set")
+	}
+
+	if cfg.Peers.TrashRetentionDays < 0 {
+		return fmt.Errorf("invalid peers.trash_retention_days: %d", cfg.Peers.TrashRetentionDays)
+	}
+	if reach := cfg.Peers.Reachability; reach.Method != "" && reach.Method != "icmp" && reach.Method != "tcp" {
+		return fmt.Errorf("invalid peers.reachability.method: %s (must be icmp or tcp)", reach.Method)
+	}
+	if reach := cfg.Peers.Reachability; reach.LossThreshold < 0 || reach.LossThreshold > 100 {
+		return fmt.Errorf("invalid peers.reachability.loss_threshold: %g", reach.LossThreshold)
+	}
+	if cfg.Peers.Reachability.Count < 0 {
+		return fmt.Errorf("invalid peers.reachability.count: %d", cfg.Peers.Reachability.Count)
+	}
+	if cfg.Peers.Reachability.Failures < 0 {
+		return fmt.Errorf("invalid peers.reachability.failures: %d", cfg.Peers.Reachability.Failures)
+	}
+
+	if raw := cfg.PeeringDB.URL; raw != "" {
+		u, err := url.Parse(raw)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("invalid peeringdb.url: %s", raw)
+		}
+	}
+
+	if cfg.ConfigVersions.MaxVersions < 0 {
+		return fmt.Errorf("invalid config_versions.max_versions: %d", cfg.ConfigVersions.MaxVersions)
+	}
+	if cfg.ConfigVersions.MaxAgeDays < 0 {
+		return fmt.Errorf("invalid config_versions.max_age_days: %d", cfg.ConfigVersions.MaxAgeDays)
+	}
+	if cfg.ConfigVersions.Restore.MaxSessionsDown < 0 {
+		return fmt.Errorf("invalid config_versions.restore.max_sessions_down: %d", cfg.ConfigVersions.Restore.MaxSessionsDown)
+	}
+	switch cfg.ConfigVersions.Storage.Backend {
+	case "", "database":
+	case "filesystem":
+		if cfg.ConfigVersions.Storage.Dir == "" {
+			return fmt.Errorf("config_versions.storage.dir is required for the filesystem backend")
+		}
+	case "s3":
+		if cfg.ConfigVersions.Storage.S3.Bucket == "" || cfg.ConfigVersions.Storage.S3.Region == "" {
+			return fmt.Errorf("config_versions.storage.s3.bucket and region are required for the s3 backend")
+		}
+	default:
+		return fmt.Errorf("invalid config_versions.storage.backend: %s", cfg.ConfigVersions.Storage.Backend)
+	}
+	switch cfg.Database.Driver {
+	case "", "sqlite":
+	case "postgres":
+		if cfg.Database.DSN == "" {
+			return fmt.Errorf("database.dsn is required for the postgres driver")
+		}
+	default:
+		return fmt.Errorf("invalid database.driver: %s", cfg.Database.Driver)
+	}
+	switch cfg.Database.Snapshots.Backend {
+	case "", "filesystem":
+	case "s3":
+		if cfg.Database.Snapshots.S3.Bucket == "" || cfg.Database.Snapshots.S3.Region == "" {
+			return fmt.Errorf("database.snapshots.s3.bucket and region are required for the s3 backend")
+		}
+	default:
+		return fmt.Errorf("invalid database.snapshots.backend: %s", cfg.Database.Snapshots.Backend)
+	}
+
+	switch cfg.Logging.Level {
+	case "", "debug", "info", "warn", "error":
+	default:
+		return fmt.Errorf("invalid log level: %s", cfg.Logging.Level)
+	}
+	switch cfg.Logging.Format {
+	case "", "json", "console":
+	default:
+		return fmt.Errorf("invalid log format: %s", cfg.Logging.Format)
+	}
+	if cfg.Logging.MaxSizeMB < 0 || cfg.Logging.MaxBackups < 0 || cfg.Logging.MaxAgeDays < 0 {
+		return fmt.Errorf("invalid log rotation: max_size_mb, max_backups and max_age_days must not be negative")
+	}
+	if cfg.Logging.RequestSampleRate < 0 || cfg.Logging.RequestSampleRate > 1 {
+		return fmt.Errorf("invalid log request sample rate: %v", cfg.Logging.RequestSampleRate)
+	}
+
+	if cfg.WebSocket.SendBuffer < 0 {
+		return fmt.Errorf("invalid websocket.send_buffer: %d", cfg.WebSocket.SendBuffer)
+	}
+	switch cfg.WebSocket.SlowClientPolicy {
+	case "", "disconnect", "drop":
+	default:
+		return fmt.Errorf("invalid websocket.slow_client_policy: %s", cfg.WebSocket.SlowClientPolicy)
+	}
+	if cfg.WebSocket.MaxDropped < 0 {
+		return fmt.Errorf("invalid websocket.max_dropped: %d", cfg.WebSocket.MaxDropped)
+	}
+	if cfg.WebSocket.EventRetention < 0 {
+		return fmt.Errorf("invalid websocket.event_retention: %d", cfg.WebSocket.EventRetention)
+	}
+
+	switch cfg.Store.Backend {
+	case "", "memory":
+	case "redis":
+		if cfg.Store.Redis.Address == "" {
+			return fmt.Errorf("store.redis.address is required for the redis backend")
+		}
+		if cfg.Store.Redis.DB < 0 {
+			return fmt.Errorf("invalid store.redis.db: %d", cfg.Store.Redis.DB)
+		}
+	default:
+		return fmt.Errorf("invalid store.backend: %s", cfg.Store.Backend)
+	}
+
+	// The HA lease lives in a database the instances share over the
+	// network. Instances sharing a SQLite file on a network filesystem
+	// corrupt it.
+	if cfg.HA.Enabled && cfg.Database.Driver != "postgres" {
+		return fmt.Errorf("ha.enabled requires database.driver postgres; a SQLite file must not be shared between instances")
+	}
+
+	if cfg.GitOps.Enabled && cfg.GitOps.RepoURL == "" {
+		return fmt.Errorf("gitops.repo_url is required when GitOps is enabled")
+	}
+
+	if cfg.Auth.SigningKey == "" && (cfg.Auth.JWTSecret == "" || cfg.Auth.JWTSecret == "changeme-in-production") {
 		fmt.Fprintf(os.Stderr, "WARNING: Using default JWT secret. Please set a secure secret in production!\n")
 	}
 
 	return nil
-}
\ No newline at end of file
+}
@@ -23,12 +23,85 @@ func TestLoad(t *testing.T) {
 		// Check default values
 		assert.Equal(t, "0.0.0.0", cfg.Server.Host)
 		assert.Equal(t, 8080, cfg.Server.Port)
+		assert.False(t, cfg.Server.ReadOnly)
+		assert.Equal(t, "sqlite", cfg.Database.Driver)
 		assert.Equal(t, "./data/flintroute.db", cfg.Database.Path)
+		assert.Equal(t, "./data/encryption.key", cfg.Database.EncryptionKeyFile)
+		assert.Empty(t, cfg.Database.EncryptionKey)
 		assert.Equal(t, "localhost", cfg.FRR.GRPCHost)
 		assert.Equal(t, 50051, cfg.FRR.GRPCPort)
+		assert.Equal(t, "eventual", cfg.FRR.ConsistencyMode)
+		assert.Equal(t, "5m", cfg.FRR.ReconcileInterval)
+		assert.False(t, cfg.FRR.AutoHeal)
+		assert.Equal(t, "1m", cfg.FRR.OutOfBandInterval)
+		assert.Equal(t, "grpc", cfg.FRR.Transport)
+		assert.Equal(t, "vtysh", cfg.FRR.Vtysh.Path)
+		assert.Equal(t, "30s", cfg.FRR.PollInterval)
+		assert.Equal(t, 0.1, cfg.FRR.PollJitter)
+		assert.Equal(t, "5m", cfg.FRR.PollMaxBackoff)
+		assert.Equal(t, "1m", cfg.FRR.StartupTimeout)
+		assert.Equal(t, 20.0, cfg.FRR.Limits.Rate)
+		assert.Equal(t, 4, cfg.FRR.Limits.MaxConcurrent)
+		assert.Equal(t, 50, cfg.FRR.Limits.BatchSize)
+		assert.Equal(t, 5, cfg.FRR.Limits.FailureThreshold)
+		assert.Equal(t, "30s", cfg.FRR.Limits.OpenDuration)
+		assert.False(t, cfg.FRR.Demo.Enabled)
+		assert.Equal(t, "30m", cfg.FRR.Demo.FlapInterval)
 		assert.Equal(t, "changeme-in-production", cfg.Auth.JWTSecret)
 		assert.Equal(t, "15m", cfg.Auth.TokenExpiry)
 		assert.Equal(t, "168h", cfg.Auth.RefreshExpiry)
+		assert.True(t, cfg.Auth.Impersonation.Enabled)
+		assert.Equal(t, "10m", cfg.Auth.Impersonation.TokenExpiry)
+		assert.Equal(t, "5m", cfg.Secrets.RefreshInterval)
+		assert.Equal(t, "/run/secrets", cfg.Secrets.File.BaseDir)
+		assert.Equal(t, "secret", cfg.Secrets.Vault.Mount)
+		assert.False(t, cfg.GitOps.Enabled)
+		assert.Equal(t, "main", cfg.GitOps.Branch)
+		assert.Equal(t, ".", cfg.GitOps.Path)
+		assert.Equal(t, "./data/gitops", cfg.GitOps.CheckoutDir)
+		assert.Equal(t, "5m", cfg.GitOps.Interval)
+		assert.True(t, cfg.GitOps.Prune)
+		assert.Equal(t, 5, cfg.Webhooks.MaxAttempts)
+		assert.Equal(t, "10s", cfg.Webhooks.Timeout)
+		assert.Equal(t, "30s", cfg.Webhooks.RetryBackoff)
+		assert.Equal(t, "1m", cfg.Alertmanager.PushInterval)
+		assert.Empty(t, cfg.Alertmanager.URLs)
+		assert.False(t, cfg.SNMP.Enabled)
+		assert.Equal(t, "2c", cfg.SNMP.Version)
+		assert.Equal(t, []string{"warning", "error", "critical"}, cfg.SNMP.Severities)
+		assert.Equal(t, 30, cfg.Alerts.Retention.ArchiveAfterDays)
+		assert.Equal(t, 0, cfg.Alerts.Retention.DeleteAfterDays)
+		assert.Equal(t, "1h", cfg.Alerts.Retention.Interval)
+		assert.Equal(t, "1h", cfg.Alerts.Dedup.Window)
+		assert.Equal(t, 20, cfg.Alerts.Dedup.StormThreshold)
+		assert.False(t, cfg.Alerts.Digest.Enabled)
+		assert.Equal(t, 587, cfg.Alerts.Digest.Email.SMTPPort)
+		assert.Equal(t, "5m", cfg.Alerts.Anomalies.Interval)
+		assert.Equal(t, 50.0, cfg.Alerts.Anomalies.ThresholdPercent)
+		assert.Equal(t, 10, cfg.Alerts.Anomalies.MinPrefixes)
+		assert.Equal(t, 0, cfg.ConfigVersions.MaxVersions)
+		assert.False(t, cfg.ConfigVersions.Compress)
+		assert.Equal(t, "1h", cfg.ConfigVersions.PruneInterval)
+		assert.Equal(t, "database", cfg.ConfigVersions.Storage.Backend)
+		assert.Equal(t, "2m", cfg.ConfigVersions.Restore.VerifyWindow)
+		assert.Equal(t, 0, cfg.ConfigVersions.Restore.MaxSessionsDown)
+		assert.Equal(t, "filesystem", cfg.Database.Snapshots.Backend)
+		assert.Equal(t, "./data/snapshots", cfg.Database.Snapshots.Dir)
+		assert.Equal(t, "info", cfg.Logging.Level)
+		assert.Equal(t, "json", cfg.Logging.Format)
+		assert.Empty(t, cfg.Logging.File)
+		assert.Equal(t, 100, cfg.Logging.MaxSizeMB)
+		assert.Equal(t, 5, cfg.Logging.MaxBackups)
+		assert.Equal(t, 1.0, cfg.Logging.RequestSampleRate)
+		assert.Equal(t, 256, cfg.WebSocket.SendBuffer)
+		assert.Equal(t, "disconnect", cfg.WebSocket.SlowClientPolicy)
+		assert.Equal(t, 10000, cfg.WebSocket.EventRetention)
+		assert.False(t, cfg.HA.Enabled)
+		assert.Equal(t, "15s", cfg.HA.LeaseDuration)
+		assert.Equal(t, "5s", cfg.HA.RenewInterval)
+		assert.Equal(t, "memory", cfg.Store.Backend)
+		assert.Equal(t, "flintroute:", cfg.Store.Redis.KeyPrefix)
+		assert.False(t, cfg.Tenancy.Enabled)
 	})
 
 	t.Run("Load from config file", func(t *testing.T) {
@@ -79,9 +152,11 @@ auth:
 		// Set environment variables
 		os.Setenv("FLINTROUTE_SERVER_PORT", "7070")
 		os.Setenv("FLINTROUTE_AUTH_JWT_SECRET", "env-secret")
+		os.Setenv("FLINTROUTE_SERVER_READ_ONLY", "true")
 		defer func() {
 			os.Unsetenv("FLINTROUTE_SERVER_PORT")
 			os.Unsetenv("FLINTROUTE_AUTH_JWT_SECRET")
+			os.Unsetenv("FLINTROUTE_SERVER_READ_ONLY")
 		}()
 
 		cfg, err := Load()
@@ -90,6 +165,7 @@ auth:
 
 		assert.Equal(t, 7070, cfg.Server.Port)
 		assert.Equal(t, "env-secret", cfg.Auth.JWTSecret)
+		assert.True(t, cfg.Server.ReadOnly)
 	})
 
 	t.Run("Invalid YAML file", func(t *testing.T) {
@@ -210,6 +286,393 @@ func TestValidate(t *testing.T) {
 		assert.Contains(t, err.Error(), "invalid FRR gRPC port")
 	})
 
+	t.Run("Invalid FRR consistency mode", func(t *testing.T) {
+		cfg := &Config{
+			Server: ServerConfig{
+				Port: 8080,
+			},
+			FRR: FRRConfig{
+				GRPCPort:        50051,
+				ConsistencyMode: "sometimes",
+			},
+			Auth: AuthConfig{
+				JWTSecret: "secret",
+			},
+		}
+
+		err := validate(cfg)
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "invalid FRR consistency mode")
+	})
+
+	t.Run("Invalid FRR transport", func(t *testing.T) {
+		cfg := &Config{
+			Server: ServerConfig{
+				Port: 8080,
+			},
+			FRR: FRRConfig{
+				Transport: "netconf",
+				GRPCPort:  50051,
+			},
+			Auth: AuthConfig{
+				JWTSecret: "secret",
+			},
+		}
+
+		err := validate(cfg)
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "invalid FRR transport")
+	})
+
+	t.Run("Invalid FRR poll jitter", func(t *testing.T) {
+		cfg := &Config{
+			Server: ServerConfig{
+				Port: 8080,
+			},
+			FRR: FRRConfig{
+				GRPCPort:   50051,
+				PollJitter: 1.5,
+			},
+			Auth: AuthConfig{
+				JWTSecret: "secret",
+			},
+		}
+
+		err := validate(cfg)
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "invalid FRR poll jitter")
+	})
+
+	t.Run("Invalid logging settings", func(t *testing.T) {
+		for name, tc := range map[string]struct {
+			logging LoggingConfig
+			err     string
+		}{
+			"level":       {LoggingConfig{Level: "trace"}, "invalid log level"},
+			"format":      {LoggingConfig{Format: "logfmt"}, "invalid log format"},
+			"rotation":    {LoggingConfig{MaxBackups: -1}, "invalid log rotation"},
+			"sample rate": {LoggingConfig{RequestSampleRate: 1.5}, "invalid log request sample rate"},
+		} {
+			cfg := &Config{
+				Server:  ServerConfig{Port: 8080},
+				FRR:     FRRConfig{GRPCPort: 50051},
+				Auth:    AuthConfig{JWTSecret: "secret"},
+				Logging: tc.logging,
+			}
+
+			err := validate(cfg)
+			if assert.Error(t, err, name) {
+				assert.Contains(t, err.Error(), tc.err, name)
+			}
+		}
+	})
+
+	t.Run("Invalid gRPC settings", func(t *testing.T) {
+		for name, tc := range map[string]struct {
+			grpcPort int
+			apiKeys  []APIKeyConfig
+			err      string
+		}{
+			"port":          {grpcPort: 70000, err: "invalid server.grpc_port"},
+			"same port":     {grpcPort: 8080, err: "invalid server.grpc_port"},
+			"key":           {apiKeys: []APIKeyConfig{{Name: "ci", Username: "automation"}}, err: "need a name, key and username"},
+			"duplicate key": {apiKeys: []APIKeyConfig{{Name: "ci", Key: "a", Username: "automation"}, {Name: "ci", Key: "b", Username: "noc"}}, err: "duplicate auth.api_keys name"},
+		} {
+			cfg := &Config{
+				Server: ServerConfig{Port: 8080, GRPCPort: tc.grpcPort},
+				FRR:    FRRConfig{GRPCPort: 50051},
+				Auth:   AuthConfig{JWTSecret: "secret", APIKeys: tc.apiKeys},
+			}
+
+			err := validate(cfg)
+			if assert.Error(t, err, name) {
+				assert.Contains(t, err.Error(), tc.err, name)
+			}
+		}
+	})
+
+	t.Run("Invalid store settings", func(t *testing.T) {
+		for name, tc := range map[string]struct {
+			store StoreConfig
+			err   string
+		}{
+			"backend":    {StoreConfig{Backend: "etcd"}, "invalid store.backend"},
+			"no address": {StoreConfig{Backend: "redis"}, "store.redis.address is required"},
+			"db":         {StoreConfig{Backend: "redis", Redis: RedisConfig{Address: "localhost:6379", DB: -1}}, "invalid store.redis.db"},
+		} {
+			cfg := &Config{
+				Server: ServerConfig{Port: 8080},
+				FRR:    FRRConfig{GRPCPort: 50051},
+				Auth:   AuthConfig{JWTSecret: "secret"},
+				Store:  tc.store,
+			}
+
+			err := validate(cfg)
+			if assert.Error(t, err, name) {
+				assert.Contains(t, err.Error(), tc.err, name)
+			}
+		}
+	})
+
+	t.Run("Invalid websocket settings", func(t *testing.T) {
+		for name, tc := range map[string]struct {
+			websocket WebSocketConfig
+			err       string
+		}{
+			"send buffer": {WebSocketConfig{SendBuffer: -1}, "invalid websocket.send_buffer"},
+			"policy":      {WebSocketConfig{SlowClientPolicy: "block"}, "invalid websocket.slow_client_policy"},
+			"max dropped": {WebSocketConfig{MaxDropped: -1}, "invalid websocket.max_dropped"},
+			"retention":   {WebSocketConfig{EventRetention: -1}, "invalid websocket.event_retention"},
+		} {
+			cfg := &Config{
+				Server:    ServerConfig{Port: 8080},
+				FRR:       FRRConfig{GRPCPort: 50051},
+				Auth:      AuthConfig{JWTSecret: "secret"},
+				WebSocket: tc.websocket,
+			}
+
+			err := validate(cfg)
+			if assert.Error(t, err, name) {
+				assert.Contains(t, err.Error(), tc.err, name)
+			}
+		}
+	})
+
+	t.Run("Invalid alert dedup, digest and anomaly settings", func(t *testing.T) {
+		for name, tc := range map[string]struct {
+			alerts AlertsConfig
+			err    string
+		}{
+			"storm threshold": {AlertsConfig{Dedup: AlertDedupConfig{StormThreshold: -1}}, "invalid alerts.dedup.storm_threshold"},
+			"email recipients": {
+				AlertsConfig{Digest: AlertDigestConfig{Enabled: true, Email: AlertDigestEmailConfig{SMTPHost: "smtp.example.com", From: "flintroute@example.com"}}},
+				"alerts.digest.email.from and to are required",
+			},
+			"anomaly threshold": {AlertsConfig{Anomalies: AlertAnomaliesConfig{ThresholdPercent: -5}}, "invalid alerts.anomalies.threshold_percent"},
+			"anomaly minimum":   {AlertsConfig{Anomalies: AlertAnomaliesConfig{MinPrefixes: -1}}, "invalid alerts.anomalies.min_prefixes"},
+		} {
+			cfg := &Config{
+				Server: ServerConfig{Port: 8080},
+				FRR:    FRRConfig{GRPCPort: 50051},
+				Auth:   AuthConfig{JWTSecret: "secret"},
+				Alerts: tc.alerts,
+			}
+
+			err := validate(cfg)
+			if assert.Error(t, err, name) {
+				assert.Contains(t, err.Error(), tc.err, name)
+			}
+		}
+	})
+
+	t.Run("GitOps without repository URL", func(t *testing.T) {
+		cfg := &Config{
+			Server: ServerConfig{
+				Port: 8080,
+			},
+			FRR: FRRConfig{
+				GRPCPort: 50051,
+			},
+			Auth: AuthConfig{
+				JWTSecret: "secret",
+			},
+			GitOps: GitOpsConfig{
+				Enabled: true,
+			},
+		}
+
+		err := validate(cfg)
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "gitops.repo_url is required")
+	})
+
+	t.Run("HA on SQLite", func(t *testing.T) {
+		cfg := &Config{
+			Server: ServerConfig{
+				Port: 8080,
+			},
+			FRR: FRRConfig{
+				GRPCPort: 50051,
+			},
+			Auth: AuthConfig{
+				JWTSecret: "secret",
+			},
+			HA: HAConfig{
+				Enabled: true,
+			},
+		}
+
+		err := validate(cfg)
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "ha.enabled requires database.driver postgres")
+
+		cfg.Database = DatabaseConfig{Driver: "postgres", DSN: "postgres://flintroute@db/flintroute"}
+		assert.NoError(t, validate(cfg))
+	})
+
+	t.Run("Postgres without a DSN", func(t *testing.T) {
+		cfg := &Config{
+			Server: ServerConfig{
+				Port: 8080,
+			},
+			FRR: FRRConfig{
+				GRPCPort: 50051,
+			},
+			Auth: AuthConfig{
+				JWTSecret: "secret",
+			},
+			Database: DatabaseConfig{
+				Driver: "postgres",
+			},
+		}
+
+		err := validate(cfg)
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "database.dsn is required")
+
+		cfg.Database.Driver = "mysql"
+		assert.ErrorContains(t, validate(cfg), "invalid database.driver: mysql")
+	})
+
+	t.Run("Invalid Alertmanager URL", func(t *testing.T) {
+		cfg := &Config{
+			Server: ServerConfig{
+				Port: 8080,
+			},
+			FRR: FRRConfig{
+				GRPCPort: 50051,
+			},
+			Auth: AuthConfig{
+				JWTSecret: "secret",
+			},
+			Alertmanager: AlertmanagerConfig{
+				URLs: []string{"alertmanager:9093"},
+			},
+		}
+
+		err := validate(cfg)
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "invalid Alertmanager URL")
+	})
+
+	t.Run("SNMP enabled without targets", func(t *testing.T) {
+		cfg := &Config{
+			Server: ServerConfig{
+				Port: 8080,
+			},
+			FRR: FRRConfig{
+				GRPCPort: 50051,
+			},
+			Auth: AuthConfig{
+				JWTSecret: "secret",
+			},
+			SNMP: SNMPConfig{
+				Enabled: true,
+				Version: "2c",
+			},
+		}
+
+		err := validate(cfg)
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "snmp.targets is required")
+	})
+
+	t.Run("SNMPv3 without username", func(t *testing.T) {
+		cfg := &Config{
+			Server: ServerConfig{
+				Port: 8080,
+			},
+			FRR: FRRConfig{
+				GRPCPort: 50051,
+			},
+			Auth: AuthConfig{
+				JWTSecret: "secret",
+			},
+			SNMP: SNMPConfig{
+				Enabled: true,
+				Targets: []string{"192.0.2.10"},
+				Version: "3",
+			},
+		}
+
+		err := validate(cfg)
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "snmp.v3.username is required")
+	})
+
+	t.Run("Negative alert retention", func(t *testing.T) {
+		cfg := &Config{
+			Server: ServerConfig{
+				Port: 8080,
+			},
+			FRR: FRRConfig{
+				GRPCPort: 50051,
+			},
+			Auth: AuthConfig{
+				JWTSecret: "secret",
+			},
+			Alerts: AlertsConfig{
+				Retention: AlertRetentionConfig{DeleteAfterDays: -1},
+			},
+		}
+
+		err := validate(cfg)
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "invalid alert delete_after_days")
+	})
+
+	t.Run("Config storage backend without settings", func(t *testing.T) {
+		cfg := &Config{
+			Server: ServerConfig{
+				Port: 8080,
+			},
+			FRR: FRRConfig{
+				GRPCPort: 50051,
+			},
+			Auth: AuthConfig{
+				JWTSecret: "secret",
+			},
+			ConfigVersions: ConfigVersionsConfig{
+				Storage: ConfigStorageConfig{Backend: "s3", S3: S3Config{Bucket: "backups"}},
+			},
+		}
+
+		err := validate(cfg)
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "bucket and region are required")
+
+		cfg.ConfigVersions.Storage = ConfigStorageConfig{Backend: "tape"}
+		err = validate(cfg)
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "invalid config_versions.storage.backend")
+	})
+
+	t.Run("Snapshot backend without settings", func(t *testing.T) {
+		cfg := &Config{
+			Server: ServerConfig{
+				Port: 8080,
+			},
+			FRR: FRRConfig{
+				GRPCPort: 50051,
+			},
+			Auth: AuthConfig{
+				JWTSecret: "secret",
+			},
+			Database: DatabaseConfig{
+				Snapshots: SnapshotsConfig{Backend: "s3", S3: S3Config{Region: "eu-west-1"}},
+			},
+		}
+
+		err := validate(cfg)
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "database.snapshots.s3.bucket and region are required")
+
+		cfg.Database.Snapshots = SnapshotsConfig{Backend: "tape"}
+		err = validate(cfg)
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "invalid database.snapshots.backend")
+	})
+
 	t.Run("Warning for default JWT secret", func(t *testing.T) {
 		cfg := &Config{
 			Server: ServerConfig{
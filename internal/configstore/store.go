@@ -0,0 +1,255 @@
+// Package configstore stores configuration versions, optionally compressed
+// or offloaded to an external blob store, and prunes old versions.
+package configstore
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/padminisys/flintroute/internal/database"
+	"github.com/padminisys/flintroute/pkg/models"
+	"go.uber.org/zap"
+)
+
+// blobKeyPrefix namespaces config version blobs in the store
+const blobKeyPrefix = "config-versions/"
+
+// Options controls how versions are stored and pruned. Zero limits disable
+// pruning. Pinned versions are never pruned and don't count towards the
+// limits.
+type Options struct {
+	// Compress gzips new versions
+	Compress bool
+	// Blobs holds the text of versions of at least MinBlobSize bytes; nil
+	// keeps everything in the database
+	Blobs       BlobStore
+	MinBlobSize int
+	// MaxVersions keeps only this many of the newest versions
+	MaxVersions int
+	// MaxAge deletes versions older than this; the newest version is
+	// always kept
+	MaxAge time.Duration
+}
+
+// Store saves and loads config versions
+type Store struct {
+	db      *database.DB
+	options Options
+	logger  *zap.Logger
+}
+
+// New creates a config version store
+func New(db *database.DB, opts Options, logger *zap.Logger) *Store {
+	return &Store{
+		db:      db,
+		options: opts,
+		logger:  logger,
+	}
+}
+
+// Save creates version, compressing and offloading its Config as
+// configured. On return version.Config still holds the plain text.
+func (s *Store) Save(ctx context.Context, version *models.ConfigVersion) error {
+	config := version.Config
+	version.Size = int64(len(config))
+	version.Encoding = ""
+	version.StorageKey = ""
+
+	data := []byte(config)
+	if s.options.Compress {
+		compressed, err := compress(data)
+		if err != nil {
+			return err
+		}
+		data = compressed
+		version.Encoding = models.ConfigEncodingGzip
+	}
+
+	if s.options.Blobs != nil && len(config) >= s.options.MinBlobSize {
+		key := blobKeyPrefix + version.Hash + ".conf"
+		if version.Encoding == models.ConfigEncodingGzip {
+			key += ".gz"
+		}
+		if err := s.options.Blobs.Put(ctx, key, data); err != nil {
+			return fmt.Errorf("failed to store config in %s: %w", s.options.Blobs.Name(), err)
+		}
+		version.StorageKey = key
+		version.Config = ""
+	} else if version.Encoding == models.ConfigEncodingGzip {
+		version.Config = base64.StdEncoding.EncodeToString(data)
+	}
+
+	err := s.db.WithContext(ctx).Create(version).Error
+	version.Config = config
+	if err != nil {
+		if version.StorageKey != "" {
+			s.deleteBlob(ctx, version.StorageKey)
+		}
+		return fmt.Errorf("failed to create config version: %w", err)
+	}
+
+	if s.options.MaxVersions > 0 {
+		if _, err := s.Prune(ctx, time.Now()); err != nil {
+			s.logger.Error("Failed to prune config versions", zap.Error(err))
+		}
+	}
+
+	return nil
+}
+
+// Load replaces version.Config with the plain configuration text
+func (s *Store) Load(ctx context.Context, version *models.ConfigVersion) error {
+	var data []byte
+	switch {
+	case version.StorageKey != "":
+		if s.options.Blobs == nil {
+			return fmt.Errorf("config version %d is stored externally but no storage backend is configured", version.ID)
+		}
+		blob, err := s.options.Blobs.Get(ctx, version.StorageKey)
+		if err != nil {
+			return fmt.Errorf("failed to load config version %d: %w", version.ID, err)
+		}
+		data = blob
+	case version.Encoding == models.ConfigEncodingGzip:
+		decoded, err := base64.StdEncoding.DecodeString(version.Config)
+		if err != nil {
+			return fmt.Errorf("failed to decode config version %d: %w", version.ID, err)
+		}
+		data = decoded
+	default:
+		return nil
+	}
+
+	if version.Encoding == models.ConfigEncodingGzip {
+		decompressed, err := decompress(data)
+		if err != nil {
+			return fmt.Errorf("failed to decompress config version %d: %w", version.ID, err)
+		}
+		data = decompressed
+	}
+
+	version.Config = string(data)
+	return nil
+}
+
+// LoadAll loads the configuration text of every version
+func (s *Store) LoadAll(ctx context.Context, versions []models.ConfigVersion) error {
+	for i := range versions {
+		if err := s.Load(ctx, &versions[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Prune deletes unpinned versions beyond MaxVersions or older than MaxAge,
+// along with their blobs, and returns how many were deleted
+func (s *Store) Prune(ctx context.Context, now time.Time) (int, error) {
+	if s.options.MaxVersions <= 0 && s.options.MaxAge <= 0 {
+		return 0, nil
+	}
+
+	// Newest first; the first version is never pruned
+	var versions []models.ConfigVersion
+	if err := s.db.WithContext(ctx).Select("id", "created_at", "storage_key").
+		Where("pinned = ?", false).
+		Order("created_at DESC, id DESC").
+		Find(&versions).Error; err != nil {
+		return 0, fmt.Errorf("failed to list config versions: %w", err)
+	}
+
+	var ids []uint
+	var keys []string
+	for i, version := range versions {
+		if i == 0 {
+			continue
+		}
+		tooMany := s.options.MaxVersions > 0 && i >= s.options.MaxVersions
+		tooOld := s.options.MaxAge > 0 && version.CreatedAt.Before(now.Add(-s.options.MaxAge))
+		if tooMany || tooOld {
+			ids = append(ids, version.ID)
+			if version.StorageKey != "" {
+				keys = append(keys, version.StorageKey)
+			}
+		}
+	}
+	if len(ids) == 0 {
+		return 0, nil
+	}
+
+	if err := s.db.WithContext(ctx).Delete(&models.ConfigVersion{}, ids).Error; err != nil {
+		return 0, fmt.Errorf("failed to delete config versions: %w", err)
+	}
+
+	// Orphaned blobs are harmless, so failures are only logged
+	for _, key := range keys {
+		s.deleteBlob(ctx, key)
+	}
+
+	return len(ids), nil
+}
+
+// deleteBlob removes a blob, logging failures
+func (s *Store) deleteBlob(ctx context.Context, key string) {
+	if err := s.options.Blobs.Delete(ctx, key); err != nil {
+		s.logger.Warn("Failed to delete config blob",
+			zap.String("key", key),
+			zap.String("backend", s.options.Blobs.Name()),
+			zap.Error(err),
+		)
+	}
+}
+
+// Start prunes versions every interval until ctx is cancelled
+func (s *Store) Start(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	s.logger.Info("Started config version pruning",
+		zap.Int("max_versions", s.options.MaxVersions),
+		zap.Duration("max_age", s.options.MaxAge),
+		zap.Duration("interval", interval),
+	)
+
+	for {
+		deleted, err := s.Prune(ctx, time.Now())
+		if err != nil {
+			s.logger.Error("Failed to prune config versions", zap.Error(err))
+		} else if deleted > 0 {
+			s.logger.Info("Pruned config versions", zap.Int("deleted", deleted))
+		}
+
+		select {
+		case <-ctx.Done():
+			s.logger.Info("Stopped config version pruning")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func compress(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	if _, err := w.Write(data); err != nil {
+		return nil, fmt.Errorf("failed to compress config: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return nil, fmt.Errorf("failed to compress config: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+func decompress(data []byte) ([]byte, error) {
+	r, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return io.ReadAll(r)
+}
@@ -0,0 +1,186 @@
+package configstore
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/padminisys/flintroute/internal/database"
+	"github.com/padminisys/flintroute/internal/testutil"
+	"github.com/padminisys/flintroute/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func newVersion(config string) *models.ConfigVersion {
+	return &models.ConfigVersion{
+		Description: "test",
+		Config:      config,
+		Hash:        fmt.Sprintf("%x", sha256.Sum256([]byte(config))),
+		CreatedBy:   1,
+	}
+}
+
+// reload fetches a version as stored and loads its text through store
+func reload(t *testing.T, db *database.DB, store *Store, id uint) *models.ConfigVersion {
+	t.Helper()
+	var version models.ConfigVersion
+	require.NoError(t, db.First(&version, id).Error)
+	require.NoError(t, store.Load(context.Background(), &version))
+	return &version
+}
+
+func TestSave(t *testing.T) {
+	ctx := context.Background()
+	config := strings.Repeat("router bgp 65001\n neighbor 192.0.2.1 remote-as 65002\n", 50)
+
+	t.Run("Stores plain text by default", func(t *testing.T) {
+		db := testutil.SetupTestDB(t)
+		store := New(db, Options{}, zap.NewNop())
+
+		version := newVersion(config)
+		require.NoError(t, store.Save(ctx, version))
+		assert.Equal(t, int64(len(config)), version.Size)
+
+		var raw models.ConfigVersion
+		require.NoError(t, db.First(&raw, version.ID).Error)
+		assert.Equal(t, config, raw.Config)
+		assert.Empty(t, raw.Encoding)
+	})
+
+	t.Run("Compresses the config column", func(t *testing.T) {
+		db := testutil.SetupTestDB(t)
+		store := New(db, Options{Compress: true}, zap.NewNop())
+
+		version := newVersion(config)
+		require.NoError(t, store.Save(ctx, version))
+		assert.Equal(t, config, version.Config)
+
+		var raw models.ConfigVersion
+		require.NoError(t, db.First(&raw, version.ID).Error)
+		assert.Equal(t, models.ConfigEncodingGzip, raw.Encoding)
+		assert.Less(t, len(raw.Config), len(config))
+
+		assert.Equal(t, config, reload(t, db, store, version.ID).Config)
+	})
+
+	t.Run("Offloads large configs to the blob store", func(t *testing.T) {
+		db := testutil.SetupTestDB(t)
+		dir := t.TempDir()
+		store := New(db, Options{Compress: true, Blobs: NewFilesystemStore(dir), MinBlobSize: 100}, zap.NewNop())
+
+		large := newVersion(config)
+		require.NoError(t, store.Save(ctx, large))
+		small := newVersion("hostname edge-1\n")
+		require.NoError(t, store.Save(ctx, small))
+
+		var raw models.ConfigVersion
+		require.NoError(t, db.First(&raw, large.ID).Error)
+		assert.Empty(t, raw.Config)
+		assert.Equal(t, "config-versions/"+large.Hash+".conf.gz", raw.StorageKey)
+		assert.FileExists(t, filepath.Join(dir, "config-versions", large.Hash+".conf.gz"))
+
+		var rawSmall models.ConfigVersion
+		require.NoError(t, db.First(&rawSmall, small.ID).Error)
+		assert.Empty(t, rawSmall.StorageKey)
+
+		assert.Equal(t, config, reload(t, db, store, large.ID).Config)
+		assert.Equal(t, "hostname edge-1\n", reload(t, db, store, small.ID).Config)
+	})
+
+	t.Run("Fails to load a missing blob", func(t *testing.T) {
+		db := testutil.SetupTestDB(t)
+		dir := t.TempDir()
+		store := New(db, Options{Blobs: NewFilesystemStore(dir)}, zap.NewNop())
+
+		version := newVersion(config)
+		require.NoError(t, store.Save(ctx, version))
+		require.NoError(t, os.RemoveAll(filepath.Join(dir, "config-versions")))
+
+		var raw models.ConfigVersion
+		require.NoError(t, db.First(&raw, version.ID).Error)
+		assert.ErrorIs(t, store.Load(ctx, &raw), ErrBlobNotFound)
+	})
+}
+
+func TestPrune(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Keeps the newest versions", func(t *testing.T) {
+		db := testutil.SetupTestDB(t)
+		dir := t.TempDir()
+		store := New(db, Options{Blobs: NewFilesystemStore(dir), MaxVersions: 2}, zap.NewNop())
+
+		var versions []*models.ConfigVersion
+		for i := 0; i < 4; i++ {
+			version := newVersion(fmt.Sprintf("! version %d\n", i))
+			require.NoError(t, store.Save(ctx, version))
+			versions = append(versions, version)
+		}
+
+		var ids []uint
+		require.NoError(t, db.Model(&models.ConfigVersion{}).Order("id").Pluck("id", &ids).Error)
+		assert.Equal(t, []uint{versions[2].ID, versions[3].ID}, ids)
+		assert.NoFileExists(t, filepath.Join(dir, versions[0].StorageKey))
+		assert.FileExists(t, filepath.Join(dir, versions[3].StorageKey))
+	})
+
+	t.Run("Deletes old versions but never the newest", func(t *testing.T) {
+		db := testutil.SetupTestDB(t)
+		store := New(db, Options{MaxAge: 24 * time.Hour}, zap.NewNop())
+
+		for i := 0; i < 3; i++ {
+			version := newVersion(fmt.Sprintf("! version %d\n", i))
+			require.NoError(t, store.Save(ctx, version))
+		}
+
+		// Everything is old, but the newest version survives
+		deleted, err := store.Prune(ctx, time.Now().Add(48*time.Hour))
+		require.NoError(t, err)
+		assert.Equal(t, 2, deleted)
+
+		var count int64
+		require.NoError(t, db.Model(&models.ConfigVersion{}).Count(&count).Error)
+		assert.Equal(t, int64(1), count)
+	})
+
+	t.Run("Never deletes pinned versions", func(t *testing.T) {
+		db := testutil.SetupTestDB(t)
+		store := New(db, Options{MaxVersions: 1}, zap.NewNop())
+
+		golden := newVersion("! golden\n")
+		golden.Pinned = true
+		require.NoError(t, store.Save(ctx, golden))
+		for i := 0; i < 3; i++ {
+			require.NoError(t, store.Save(ctx, newVersion(fmt.Sprintf("! version %d\n", i))))
+		}
+
+		// The pinned version survives and doesn't use up MaxVersions
+		var configs []string
+		require.NoError(t, db.Model(&models.ConfigVersion{}).Order("id").Pluck("config", &configs).Error)
+		assert.Equal(t, []string{"! golden\n", "! version 2\n"}, configs)
+	})
+}
+
+func TestFilesystemStore(t *testing.T) {
+	ctx := context.Background()
+	store := NewFilesystemStore(t.TempDir())
+
+	require.NoError(t, store.Put(ctx, "a/b.conf", []byte("data")))
+	data, err := store.Get(ctx, "a/b.conf")
+	require.NoError(t, err)
+	assert.Equal(t, "data", string(data))
+
+	require.NoError(t, store.Delete(ctx, "a/b.conf"))
+	require.NoError(t, store.Delete(ctx, "a/b.conf"))
+	_, err = store.Get(ctx, "a/b.conf")
+	assert.ErrorIs(t, err, ErrBlobNotFound)
+
+	assert.Error(t, store.Put(ctx, "../escape", []byte("data")))
+}
@@ -2,12 +2,17 @@ package database
 
 import (
 	"fmt"
+	"net/url"
 	"os"
 	"path/filepath"
+	"strconv"
+	"time"
 
-	"github.com/padminisys/flintroute/internal/models"
+	"github.com/padminisys/flintroute/internal/tracing"
+	"github.com/padminisys/flintroute/pkg/models"
 	"go.uber.org/zap"
 	"golang.org/x/crypto/bcrypt"
+	"gorm.io/driver/postgres"
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
@@ -19,34 +24,60 @@ type DB struct {
 	logger *zap.Logger
 }
 
-// Initialize creates and initializes the database
-func Initialize(dbPath string, log *zap.Logger) (*DB, error) {
-	// Create directory if it doesn't exist
-	dir := filepath.Dir(dbPath)
-	if err := os.MkdirAll(dir, 0755); err != nil {
-		return nil, fmt.Errorf("failed to create database directory: %w", err)
+// Database drivers
+const (
+	// DriverSQLite keeps the database in a local file. It is the default.
+	DriverSQLite = "sqlite"
+	// DriverPostgres connects to a Postgres server, which several
+	// FlintRoute instances can share
+	DriverPostgres = "postgres"
+)
+
+// Options tunes the database connection
+type Options struct {
+	// Driver is DriverSQLite, when empty, or DriverPostgres
+	Driver string
+	// JournalMode is the SQLite journal mode. WAL lets readers proceed
+	// while a write is in progress.
+	JournalMode string
+	// BusyTimeout is how long a connection waits on a locked database
+	// before failing with "database is locked"
+	BusyTimeout time.Duration
+	// MaxOpenConns caps the connection pool. SQLite allows a single writer,
+	// so one connection serialises writes in-process instead of having
+	// them contend for the lock. Postgres pools are left uncapped.
+	MaxOpenConns int
+}
+
+// DefaultOptions returns the options Initialize uses
+func DefaultOptions() Options {
+	return Options{
+		Driver:       DriverSQLite,
+		JournalMode:  "WAL",
+		BusyTimeout:  5 * time.Second,
+		MaxOpenConns: 1,
 	}
+}
 
-	// Configure GORM logger
-	gormLogger := logger.Default.LogMode(logger.Silent)
+// Initialize creates and initializes the database with DefaultOptions,
+// applying any pending schema migrations. It refuses to start on a database
+// migrated by a newer build.
+func Initialize(dbPath string, log *zap.Logger) (*DB, error) {
+	return InitializeWithOptions(dbPath, DefaultOptions(), log)
+}
 
-	// Open database connection
-	db, err := gorm.Open(sqlite.Open(dbPath), &gorm.Config{
-		Logger: gormLogger,
-	})
+// InitializeWithOptions is Initialize with explicit connection options.
+// source is the SQLite file, or the Postgres connection string.
+func InitializeWithOptions(source string, opts Options, log *zap.Logger) (*DB, error) {
+	db, err := open(source, opts)
 	if err != nil {
-		return nil, fmt.Errorf("failed to open database: %w", err)
+		return nil, err
 	}
 
-	// Auto-migrate models
-	if err := db.AutoMigrate(
-		&models.User{},
-		&models.BGPPeer{},
-		&models.BGPSession{},
-		&models.ConfigVersion{},
-		&models.Alert{},
-		&models.RefreshToken{},
-	); err != nil {
+	if err := migrate(db); err != nil {
+		if sqlDB, dbErr := db.DB(); dbErr == nil {
+			sqlDB.Close()
+		}
 		return nil, fmt.Errorf("failed to migrate database: %w", err)
 	}
 
@@ -60,11 +91,82 @@ func Initialize(dbPath string, log *zap.Logger) (*DB, error) {
 		return nil, fmt.Errorf("failed to create default user: %w", err)
 	}
 
-	log.Info("Database initialized successfully", zap.String("path", dbPath))
+	if db.Dialector.Name() == DriverPostgres {
+		// The connection string may hold a password
+		log.Info("Database initialized successfully", zap.String("driver", DriverPostgres))
+	} else {
+		log.Info("Database initialized successfully",
+			zap.String("path", source),
+			zap.String("journal_mode", opts.JournalMode),
+			zap.Duration("busy_timeout", opts.BusyTimeout),
+		)
+	}
 
 	return database, nil
 }
 
+// open opens the database at source without migrating it
+func open(source string, opts Options) (*gorm.DB, error) {
+	var dialector gorm.Dialector
+	switch opts.Driver {
+	case "", DriverSQLite:
+		// Create directory if it doesn't exist
+		dir := filepath.Dir(source)
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return nil, fmt.Errorf("failed to create database directory: %w", err)
+		}
+		dialector = sqlite.Open(dsn(source, opts))
+	case DriverPostgres:
+		dialector = postgres.Open(source)
+		opts.MaxOpenConns = 0
+	default:
+		return nil, fmt.Errorf("unknown database driver %q", opts.Driver)
+	}
+
+	// Configure GORM logger
+	gormLogger := logger.Default.LogMode(logger.Silent)
+
+	// Open database connection
+	db, err := gorm.Open(dialector, &gorm.Config{
+		Logger: gormLogger,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+
+	if err := db.Use(tracing.GORMPlugin()); err != nil {
+		return nil, fmt.Errorf("failed to set up database tracing: %w", err)
+	}
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+	if opts.MaxOpenConns > 0 {
+		sqlDB.SetMaxOpenConns(opts.MaxOpenConns)
+		sqlDB.SetMaxIdleConns(opts.MaxOpenConns)
+	}
+	// Keep connections open so per-connection pragmas aren't re-applied
+	sqlDB.SetConnMaxLifetime(0)
+
+	return db, nil
+}
+
+// dsn builds the go-sqlite3 connection string. Transactions take the write
+// lock when they begin (_txlock=immediate) so a read transaction never has
+// to be upgraded, which SQLite fails with SQLITE_BUSY without waiting.
+func dsn(dbPath string, opts Options) string {
+	params := url.Values{}
+	if opts.JournalMode != "" {
+		params.Set("_journal_mode", opts.JournalMode)
+	}
+	if opts.BusyTimeout > 0 {
+		params.Set("_busy_timeout", strconv.FormatInt(opts.BusyTimeout.Milliseconds(), 10))
+	}
+	params.Set("_txlock", "immediate")
+	return dbPath + "?" + params.Encode()
+}
+
 // createDefaultUser creates a default admin user if no users exist
 func (db *DB) createDefaultUser() error {
 	var count int64
@@ -116,4 +218,4 @@ func (db *DB) Close() error {
 		return err
 	}
 	return sqlDB.Close()
-}
\ No newline at end of file
+}
@@ -5,7 +5,7 @@ import (
 	"path/filepath"
 	"testing"
 
-	"github.com/padminisys/flintroute/internal/models"
+	"github.com/padminisys/flintroute/pkg/models"
 	"github.com/stretchr/testify/assert"
 	"go.uber.org/zap"
 	"golang.org/x/crypto/bcrypt"
@@ -136,6 +136,15 @@ func TestInitialize(t *testing.T) {
 			assert.Nil(t, db)
 		}
 	})
+
+	t.Run("Unknown driver", func(t *testing.T) {
+		opts := DefaultOptions()
+		opts.Driver = "mysql"
+
+		db, err := InitializeWithOptions(filepath.Join(t.TempDir(), "test.db"), opts, logger)
+		assert.ErrorContains(t, err, `unknown database driver "mysql"`)
+		assert.Nil(t, db)
+	})
 }
 
 func TestCreateDefaultUser(t *testing.T) {
@@ -351,4 +360,4 @@ func TestDatabaseConcurrency(t *testing.T) {
 		assert.NoError(t, err)
 		assert.GreaterOrEqual(t, count, int64(10))
 	})
-}
\ No newline at end of file
+}
@@ -0,0 +1,130 @@
+package database
+
+import (
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"gorm.io/gorm"
+)
+
+// MigrateUsage describes the migrate subcommand
+const MigrateUsage = `Usage: flintroute migrate [command]
+
+Commands:
+  up          Apply all pending migrations (default)
+  down        Roll back the latest applied migration
+  to <id>     Migrate up or roll back to the given migration
+  status      List migrations and whether they are applied
+  version     Print the current schema version
+`
+
+// RunMigrateCommand implements the "flintroute migrate" subcommand against
+// the SQLite database at dbPath. It never creates the default admin user, so
+// it can prepare a schema before the server first starts.
+func RunMigrateCommand(dbPath string, args []string, out io.Writer) error {
+	return RunMigrateCommandWithOptions(dbPath, DefaultOptions(), args, out)
+}
+
+// RunMigrateCommandWithOptions is RunMigrateCommand against the database
+// at source, the SQLite file or the Postgres connection string
+func RunMigrateCommandWithOptions(source string, opts Options, args []string, out io.Writer) error {
+	command := "up"
+	if len(args) > 0 {
+		command = args[0]
+	}
+
+	db, err := open(source, opts)
+	if err != nil {
+		return err
+	}
+	if sqlDB, err := db.DB(); err == nil {
+		defer sqlDB.Close()
+	}
+
+	switch command {
+	case "up":
+		if err := migrate(db); err != nil {
+			return err
+		}
+		return printVersion(db, out)
+
+	case "down":
+		if err := checkSchemaVersion(db); err != nil {
+			return err
+		}
+		if err := newMigrator(db).RollbackLast(); err != nil {
+			return fmt.Errorf("failed to roll back: %w", err)
+		}
+		return printVersion(db, out)
+
+	case "to":
+		if len(args) != 2 {
+			return fmt.Errorf("migrate to requires a migration ID\n\n%s", MigrateUsage)
+		}
+		if err := migrateTo(db, args[1]); err != nil {
+			return err
+		}
+		return printVersion(db, out)
+
+	case "status":
+		statuses, err := MigrationStatuses(db)
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "MIGRATION\tSTATUS")
+		for _, status := range statuses {
+			state := "pending"
+			switch {
+			case status.Unknown:
+				state = "unknown"
+			case status.Applied:
+				state = "applied"
+			}
+			fmt.Fprintf(w, "%s\t%s\n", status.ID, state)
+		}
+		return w.Flush()
+
+	case "version":
+		return printVersion(db, out)
+
+	default:
+		return fmt.Errorf("unknown migrate command %q\n\n%s", command, MigrateUsage)
+	}
+}
+
+// migrateTo applies migrations up to id, or rolls back those after it if id
+// is already applied
+func migrateTo(db *gorm.DB, id string) error {
+	if err := checkSchemaVersion(db); err != nil {
+		return err
+	}
+
+	statuses, err := MigrationStatuses(db)
+	if err != nil {
+		return err
+	}
+	for _, status := range statuses {
+		if status.ID != id {
+			continue
+		}
+		if status.Applied {
+			return newMigrator(db).RollbackTo(id)
+		}
+		return newMigrator(db).MigrateTo(id)
+	}
+	return fmt.Errorf("unknown migration %q", id)
+}
+
+func printVersion(db *gorm.DB, out io.Writer) error {
+	version, err := SchemaVersion(db)
+	if err != nil {
+		return err
+	}
+	if version == "" {
+		version = "none"
+	}
+	_, err = fmt.Fprintf(out, "Schema version: %s\n", version)
+	return err
+}
@@ -0,0 +1,992 @@
+package database
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/go-gormigrate/gormigrate/v2"
+	"github.com/padminisys/flintroute/pkg/models"
+	"gorm.io/gorm"
+)
+
+// schemaVersionTable records the IDs of applied migrations
+const schemaVersionTable = "schema_version"
+
+// ErrUnknownSchemaVersion is returned when the database has migrations this
+// build doesn't know about, i.e. it was migrated by a newer FlintRoute
+var ErrUnknownSchemaVersion = errors.New("unknown database schema version")
+
+// migrations lists every schema change in the order it is applied. IDs are
+// never reused or reordered; a change to an existing table gets a new
+// migration rather than an edit to an old one.
+var migrations = []*gormigrate.Migration{
+	{
+		// Baseline: the tables previously created by AutoMigrate. Existing
+		// databases without a schema_version table adopt it in place since
+		// AutoMigrate leaves matching tables untouched.
+		ID: "0001_initial_schema",
+		Migrate: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(baselineTables...)
+		},
+		Rollback: func(tx *gorm.DB) error {
+			for i := len(baselineTables) - 1; i >= 0; i-- {
+				if err := tx.Migrator().DropTable(baselineTables[i]); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	},
+	{
+		ID: "0002_alert_archived_at",
+		Migrate: func(tx *gorm.DB) error {
+			if !tx.Migrator().HasColumn(&models.Alert{}, "ArchivedAt") {
+				if err := tx.Migrator().AddColumn(&models.Alert{}, "ArchivedAt"); err != nil {
+					return err
+				}
+			}
+			if !tx.Migrator().HasIndex(&models.Alert{}, "ArchivedAt") {
+				return tx.Migrator().CreateIndex(&models.Alert{}, "ArchivedAt")
+			}
+			return nil
+		},
+		Rollback: func(tx *gorm.DB) error {
+			if err := tx.Migrator().DropIndex(&models.Alert{}, "ArchivedAt"); err != nil {
+				return err
+			}
+			return tx.Migrator().DropColumn(&models.Alert{}, "ArchivedAt")
+		},
+	},
+	{
+		ID: "0003_config_version_storage",
+		Migrate: func(tx *gorm.DB) error {
+			for _, field := range []string{"Size", "Encoding", "StorageKey"} {
+				if !tx.Migrator().HasColumn(&models.ConfigVersion{}, field) {
+					if err := tx.Migrator().AddColumn(&models.ConfigVersion{}, field); err != nil {
+						return err
+					}
+				}
+			}
+			// Existing versions are plain text held in the config column
+			size := "length(CAST(config AS BLOB))"
+			if tx.Dialector.Name() == DriverPostgres {
+				size = "octet_length(config)"
+			}
+			return tx.Exec("UPDATE config_versions SET size = " + size + " WHERE size = 0 OR size IS NULL").Error
+		},
+		Rollback: func(tx *gorm.DB) error {
+			for _, field := range []string{"StorageKey", "Encoding", "Size"} {
+				if err := tx.Migrator().DropColumn(&models.ConfigVersion{}, field); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	},
+	{
+		ID: "0004_peer_timers_and_options",
+		Migrate: func(tx *gorm.DB) error {
+			for _, field := range peerOptionFields {
+				if !tx.Migrator().HasColumn(&models.BGPPeer{}, field) {
+					if err := tx.Migrator().AddColumn(&models.BGPPeer{}, field); err != nil {
+						return err
+					}
+				}
+			}
+			return nil
+		},
+		Rollback: func(tx *gorm.DB) error {
+			for i := len(peerOptionFields) - 1; i >= 0; i-- {
+				if err := tx.Migrator().DropColumn(&models.BGPPeer{}, peerOptionFields[i]); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	},
+	{
+		ID: "0005_community_and_as_path_lists",
+		Migrate: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&models.CommunityList{}, &models.ASPathList{})
+		},
+		Rollback: func(tx *gorm.DB) error {
+			if err := tx.Migrator().DropTable(&models.ASPathList{}); err != nil {
+				return err
+			}
+			return tx.Migrator().DropTable(&models.CommunityList{})
+		},
+	},
+	{
+		ID: "0006_peer_max_prefix_options",
+		Migrate: func(tx *gorm.DB) error {
+			for _, field := range peerMaxPrefixFields {
+				if !tx.Migrator().HasColumn(&models.BGPPeer{}, field) {
+					if err := tx.Migrator().AddColumn(&models.BGPPeer{}, field); err != nil {
+						return err
+					}
+				}
+			}
+			return nil
+		},
+		Rollback: func(tx *gorm.DB) error {
+			for i := len(peerMaxPrefixFields) - 1; i >= 0; i-- {
+				if err := tx.Migrator().DropColumn(&models.BGPPeer{}, peerMaxPrefixFields[i]); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	},
+	{
+		ID: "0007_jobs",
+		Migrate: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&models.Job{})
+		},
+		Rollback: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&models.Job{})
+		},
+	},
+	{
+		ID: "0008_peer_tags",
+		Migrate: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&models.PeerTag{})
+		},
+		Rollback: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&models.PeerTag{})
+		},
+	},
+	{
+		ID: "0009_peer_maintenance",
+		Migrate: func(tx *gorm.DB) error {
+			for _, field := range peerMaintenanceFields {
+				if !tx.Migrator().HasColumn(&models.BGPPeer{}, field) {
+					if err := tx.Migrator().AddColumn(&models.BGPPeer{}, field); err != nil {
+						return err
+					}
+				}
+			}
+			return nil
+		},
+		Rollback: func(tx *gorm.DB) error {
+			for i := len(peerMaintenanceFields) - 1; i >= 0; i-- {
+				if err := tx.Migrator().DropColumn(&models.BGPPeer{}, peerMaintenanceFields[i]); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	},
+	{
+		ID: "0010_user_profile",
+		Migrate: func(tx *gorm.DB) error {
+			if !tx.Migrator().HasColumn(&models.User{}, "Preferences") {
+				if err := tx.Migrator().AddColumn(&models.User{}, "Preferences"); err != nil {
+					return err
+				}
+			}
+			for _, field := range refreshTokenClientFields {
+				if !tx.Migrator().HasColumn(&models.RefreshToken{}, field) {
+					if err := tx.Migrator().AddColumn(&models.RefreshToken{}, field); err != nil {
+						return err
+					}
+				}
+			}
+			return nil
+		},
+		Rollback: func(tx *gorm.DB) error {
+			for i := len(refreshTokenClientFields) - 1; i >= 0; i-- {
+				if err := tx.Migrator().DropColumn(&models.RefreshToken{}, refreshTokenClientFields[i]); err != nil {
+					return err
+				}
+			}
+			return tx.Migrator().DropColumn(&models.User{}, "Preferences")
+		},
+	},
+	{
+		ID: "0011_revoked_tokens",
+		Migrate: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&models.RevokedToken{})
+		},
+		Rollback: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&models.RevokedToken{})
+		},
+	},
+	{
+		ID: "0012_change_requests",
+		Migrate: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&models.ChangeRequest{})
+		},
+		Rollback: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&models.ChangeRequest{})
+		},
+	},
+	{
+		ID: "0013_job_trace_parent",
+		Migrate: func(tx *gorm.DB) error {
+			if !tx.Migrator().HasColumn(&models.Job{}, "TraceParent") {
+				return tx.Migrator().AddColumn(&models.Job{}, "TraceParent")
+			}
+			return nil
+		},
+		Rollback: func(tx *gorm.DB) error {
+			return tx.Migrator().DropColumn(&models.Job{}, "TraceParent")
+		},
+	},
+	{
+		ID: "0014_bgp_global_config",
+		Migrate: func(tx *gorm.DB) error {
+			if err := tx.AutoMigrate(&models.BGPGlobalConfig{}); err != nil {
+				return err
+			}
+			if !tx.Migrator().HasColumn(&models.ConfigVersion{}, "BGPGlobal") {
+				return tx.Migrator().AddColumn(&models.ConfigVersion{}, "BGPGlobal")
+			}
+			return nil
+		},
+		Rollback: func(tx *gorm.DB) error {
+			if err := tx.Migrator().DropColumn(&models.ConfigVersion{}, "BGPGlobal"); err != nil {
+				return err
+			}
+			return tx.Migrator().DropTable(&models.BGPGlobalConfig{})
+		},
+	},
+	{
+		ID: "0015_stream_events",
+		Migrate: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&models.StreamEvent{})
+		},
+		Rollback: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&models.StreamEvent{})
+		},
+	},
+	{
+		ID: "0016_alert_dedup",
+		Migrate: func(tx *gorm.DB) error {
+			for _, field := range alertDedupFields {
+				if !tx.Migrator().HasColumn(&models.Alert{}, field) {
+					if err := tx.Migrator().AddColumn(&models.Alert{}, field); err != nil {
+						return err
+					}
+				}
+			}
+			if !tx.Migrator().HasIndex(&models.Alert{}, "LastSeenAt") {
+				if err := tx.Migrator().CreateIndex(&models.Alert{}, "LastSeenAt"); err != nil {
+					return err
+				}
+			}
+			// Existing alerts were seen once, when raised
+			return tx.Exec("UPDATE alerts SET first_seen_at = created_at, last_seen_at = created_at WHERE first_seen_at IS NULL").Error
+		},
+		Rollback: func(tx *gorm.DB) error {
+			if err := tx.Migrator().DropIndex(&models.Alert{}, "LastSeenAt"); err != nil {
+				return err
+			}
+			for i := len(alertDedupFields) - 1; i >= 0; i-- {
+				if err := tx.Migrator().DropColumn(&models.Alert{}, alertDedupFields[i]); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	},
+	{
+		ID: "0017_session_details",
+		Migrate: func(tx *gorm.DB) error {
+			for _, field := range sessionDetailFields {
+				if !tx.Migrator().HasColumn(&models.BGPSession{}, field) {
+					if err := tx.Migrator().AddColumn(&models.BGPSession{}, field); err != nil {
+						return err
+					}
+				}
+			}
+			return nil
+		},
+		Rollback: func(tx *gorm.DB) error {
+			for i := len(sessionDetailFields) - 1; i >= 0; i-- {
+				if err := tx.Migrator().DropColumn(&models.BGPSession{}, sessionDetailFields[i]); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	},
+	{
+		ID: "0018_prefix_samples",
+		Migrate: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&models.PrefixSample{})
+		},
+		Rollback: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&models.PrefixSample{})
+		},
+	},
+	{
+		ID: "0019_leader_leases",
+		Migrate: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&models.LeaderLease{})
+		},
+		Rollback: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&models.LeaderLease{})
+		},
+	},
+	{
+		ID: "0020_database_snapshots",
+		Migrate: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&models.DatabaseSnapshot{}, &models.AuditEntry{})
+		},
+		Rollback: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&models.AuditEntry{}, &models.DatabaseSnapshot{})
+		},
+	},
+	{
+		ID: "0021_idempotency_keys",
+		Migrate: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&models.IdempotencyKey{})
+		},
+		Rollback: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&models.IdempotencyKey{})
+		},
+	},
+	{
+		ID: "0022_notification_settings",
+		Migrate: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&models.NotificationSettings{})
+		},
+		Rollback: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&models.NotificationSettings{})
+		},
+	},
+	{
+		ID: "0023_user_request_quota",
+		Migrate: func(tx *gorm.DB) error {
+			if tx.Migrator().HasColumn(&models.User{}, "RequestQuota") {
+				return nil
+			}
+			return tx.Migrator().AddColumn(&models.User{}, "RequestQuota")
+		},
+		Rollback: func(tx *gorm.DB) error {
+			return tx.Migrator().DropColumn(&models.User{}, "RequestQuota")
+		},
+	},
+	{
+		ID: "0024_peer_templates",
+		Migrate: func(tx *gorm.DB) error {
+			if err := tx.AutoMigrate(&models.PeerTemplate{}); err != nil {
+				return err
+			}
+			for _, field := range peerTemplateFields {
+				if !tx.Migrator().HasColumn(&models.BGPPeer{}, field) {
+					if err := tx.Migrator().AddColumn(&models.BGPPeer{}, field); err != nil {
+						return err
+					}
+				}
+			}
+			return nil
+		},
+		Rollback: func(tx *gorm.DB) error {
+			for i := len(peerTemplateFields) - 1; i >= 0; i-- {
+				if err := tx.Migrator().DropColumn(&models.BGPPeer{}, peerTemplateFields[i]); err != nil {
+					return err
+				}
+			}
+			return tx.Migrator().DropTable(&models.PeerTemplate{})
+		},
+	},
+	{
+		ID: "0025_scheduled_jobs",
+		Migrate: func(tx *gorm.DB) error {
+			for _, field := range scheduledJobFields {
+				if !tx.Migrator().HasColumn(&models.Job{}, field) {
+					if err := tx.Migrator().AddColumn(&models.Job{}, field); err != nil {
+						return err
+					}
+				}
+			}
+			if !tx.Migrator().HasIndex(&models.Job{}, "RunAt") {
+				return tx.Migrator().CreateIndex(&models.Job{}, "RunAt")
+			}
+			return nil
+		},
+		Rollback: func(tx *gorm.DB) error {
+			if err := tx.Migrator().DropIndex(&models.Job{}, "RunAt"); err != nil {
+				return err
+			}
+			for i := len(scheduledJobFields) - 1; i >= 0; i-- {
+				if err := tx.Migrator().DropColumn(&models.Job{}, scheduledJobFields[i]); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	},
+	{
+		ID: "0026_session_events",
+		Migrate: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&models.SessionEvent{})
+		},
+		Rollback: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&models.SessionEvent{})
+		},
+	},
+	{
+		ID: "0027_tenants",
+		Migrate: func(tx *gorm.DB) error {
+			if err := tx.AutoMigrate(&models.Tenant{}, &models.TenantMember{}); err != nil {
+				return err
+			}
+			for _, model := range tenantOwnedModels {
+				if !tx.Migrator().HasColumn(model, "TenantID") {
+					if err := tx.Migrator().AddColumn(model, "TenantID"); err != nil {
+						return err
+					}
+				}
+			}
+			for _, model := range []interface{}{&models.BGPPeer{}, &models.Alert{}} {
+				if !tx.Migrator().HasIndex(model, "TenantID") {
+					if err := tx.Migrator().CreateIndex(model, "TenantID"); err != nil {
+						return err
+					}
+				}
+			}
+			// Tenants may back up the same configuration, so hashes are
+			// unique per tenant. NULLs are distinct in a unique index, so
+			// versions outside any tenant keep their own index on the hash.
+			if tx.Migrator().HasIndex(&models.ConfigVersion{}, "idx_config_versions_hash") {
+				if err := tx.Migrator().DropIndex(&models.ConfigVersion{}, "idx_config_versions_hash"); err != nil {
+					return err
+				}
+			}
+			for _, index := range []string{"idx_config_versions_hash", "idx_config_versions_tenant_hash"} {
+				if tx.Migrator().HasIndex(&models.ConfigVersion{}, index) {
+					continue
+				}
+				if err := tx.Migrator().CreateIndex(&models.ConfigVersion{}, index); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+		Rollback: func(tx *gorm.DB) error {
+			for _, index := range []string{"idx_config_versions_hash", "idx_config_versions_tenant_hash"} {
+				if err := tx.Migrator().DropIndex(&models.ConfigVersion{}, index); err != nil {
+					return err
+				}
+			}
+			if err := tx.Exec("CREATE UNIQUE INDEX idx_config_versions_hash ON config_versions (hash)").Error; err != nil {
+				return err
+			}
+			for _, model := range tenantOwnedModels {
+				if err := tx.Migrator().DropColumn(model, "TenantID"); err != nil {
+					return err
+				}
+			}
+			return tx.Migrator().DropTable(&models.TenantMember{}, &models.Tenant{})
+		},
+	},
+	{
+		ID: "0028_monitor_stats",
+		Migrate: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&models.MonitorStats{})
+		},
+		Rollback: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&models.MonitorStats{})
+		},
+	},
+	{
+		ID: "0029_config_version_annotations",
+		Migrate: func(tx *gorm.DB) error {
+			for _, field := range configVersionAnnotationFields {
+				if !tx.Migrator().HasColumn(&models.ConfigVersion{}, field) {
+					if err := tx.Migrator().AddColumn(&models.ConfigVersion{}, field); err != nil {
+						return err
+					}
+				}
+			}
+			if !tx.Migrator().HasIndex(&models.ConfigVersion{}, "Pinned") {
+				return tx.Migrator().CreateIndex(&models.ConfigVersion{}, "Pinned")
+			}
+			return nil
+		},
+		Rollback: func(tx *gorm.DB) error {
+			if err := tx.Migrator().DropIndex(&models.ConfigVersion{}, "Pinned"); err != nil {
+				return err
+			}
+			for i := len(configVersionAnnotationFields) - 1; i >= 0; i-- {
+				if err := tx.Migrator().DropColumn(&models.ConfigVersion{}, configVersionAnnotationFields[i]); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	},
+	{
+		ID: "0030_peer_search",
+		Migrate: func(tx *gorm.DB) error {
+			for _, index := range peerSearchIndexes {
+				if !tx.Migrator().HasIndex(index.model, index.field) {
+					if err := tx.Migrator().CreateIndex(index.model, index.field); err != nil {
+						return err
+					}
+				}
+			}
+			if tx.Dialector.Name() != DriverSQLite {
+				// The index is an SQLite virtual table; other databases
+				// search the peers' columns
+				return nil
+			}
+			for _, statement := range peerSearchSchema {
+				if err := tx.Exec(statement).Error; err != nil {
+					return err
+				}
+			}
+			return tx.Exec("INSERT INTO peer_search (docid, name, description) SELECT id, name, description FROM bgp_peers").Error
+		},
+		Rollback: func(tx *gorm.DB) error {
+			if tx.Dialector.Name() == DriverSQLite {
+				for _, trigger := range []string{"peer_search_insert", "peer_search_update", "peer_search_delete"} {
+					if err := tx.Exec("DROP TRIGGER IF EXISTS " + trigger).Error; err != nil {
+						return err
+					}
+				}
+				if err := tx.Exec("DROP TABLE IF EXISTS peer_search").Error; err != nil {
+					return err
+				}
+			}
+			for _, index := range peerSearchIndexes {
+				if err := tx.Migrator().DropIndex(index.model, index.field); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	},
+	{
+		ID: "0031_reachability_samples",
+		Migrate: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&models.ReachabilitySample{})
+		},
+		Rollback: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&models.ReachabilitySample{})
+		},
+	},
+	{
+		ID: "0032_peer_local_as",
+		Migrate: func(tx *gorm.DB) error {
+			for _, field := range peerLocalASFields {
+				if !tx.Migrator().HasColumn(&models.BGPPeer{}, field) {
+					if err := tx.Migrator().AddColumn(&models.BGPPeer{}, field); err != nil {
+						return err
+					}
+				}
+			}
+			return nil
+		},
+		Rollback: func(tx *gorm.DB) error {
+			for i := len(peerLocalASFields) - 1; i >= 0; i-- {
+				if err := tx.Migrator().DropColumn(&models.BGPPeer{}, peerLocalASFields[i]); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	},
+	{
+		ID: "0033_peer_frr_error",
+		Migrate: func(tx *gorm.DB) error {
+			for _, field := range peerFRRErrorFields {
+				if !tx.Migrator().HasColumn(&models.BGPPeer{}, field) {
+					if err := tx.Migrator().AddColumn(&models.BGPPeer{}, field); err != nil {
+						return err
+					}
+				}
+			}
+			return nil
+		},
+		Rollback: func(tx *gorm.DB) error {
+			for i := len(peerFRRErrorFields) - 1; i >= 0; i-- {
+				if err := tx.Migrator().DropColumn(&models.BGPPeer{}, peerFRRErrorFields[i]); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	},
+	{
+		ID: "0034_frr_transactions",
+		Migrate: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&models.FRRTransaction{})
+		},
+		Rollback: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&models.FRRTransaction{})
+		},
+	},
+	{
+		ID: "0035_monitoring_gaps",
+		Migrate: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&models.MonitoringGap{})
+		},
+		Rollback: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&models.MonitoringGap{})
+		},
+	},
+	{
+		ID: "0036_keyset_indexes",
+		Migrate: func(tx *gorm.DB) error {
+			for _, index := range keysetIndexes {
+				if tx.Migrator().HasIndex(index.model, index.name) {
+					continue
+				}
+				if err := tx.Migrator().CreateIndex(index.model, index.name); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+		Rollback: func(tx *gorm.DB) error {
+			for _, index := range keysetIndexes {
+				if err := tx.Migrator().DropIndex(index.model, index.name); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	},
+	{
+		ID: "0037_idempotency_key_tenant",
+		Migrate: func(tx *gorm.DB) error {
+			if !tx.Migrator().HasColumn(&models.IdempotencyKey{}, "Tenant") {
+				if err := tx.Migrator().AddColumn(&models.IdempotencyKey{}, "Tenant"); err != nil {
+					return err
+				}
+			}
+			// Keys are unique per user and tenant rather than per user
+			if err := tx.Migrator().DropIndex(&models.IdempotencyKey{}, "idx_idempotency_keys_user_key"); err != nil {
+				return err
+			}
+			return tx.Migrator().CreateIndex(&models.IdempotencyKey{}, "idx_idempotency_keys_user_key")
+		},
+		Rollback: func(tx *gorm.DB) error {
+			if err := tx.Where("tenant <> ''").Delete(&models.IdempotencyKey{}).Error; err != nil {
+				return err
+			}
+			if err := tx.Migrator().DropIndex(&models.IdempotencyKey{}, "idx_idempotency_keys_user_key"); err != nil {
+				return err
+			}
+			if err := tx.Migrator().DropColumn(&models.IdempotencyKey{}, "Tenant"); err != nil {
+				return err
+			}
+			return tx.Exec("CREATE UNIQUE INDEX idx_idempotency_keys_user_key ON idempotency_keys (user_id, key)").Error
+		},
+	},
+	{
+		ID: "0038_stream_event_tenant",
+		Migrate: func(tx *gorm.DB) error {
+			// Events kept from before have no tenant, so only clients
+			// acting across tenants replay them
+			if !tx.Migrator().HasColumn(&models.StreamEvent{}, "TenantID") {
+				if err := tx.Migrator().AddColumn(&models.StreamEvent{}, "TenantID"); err != nil {
+					return err
+				}
+			}
+			if !tx.Migrator().HasIndex(&models.StreamEvent{}, "TenantID") {
+				return tx.Migrator().CreateIndex(&models.StreamEvent{}, "TenantID")
+			}
+			return nil
+		},
+		Rollback: func(tx *gorm.DB) error {
+			if err := tx.Migrator().DropIndex(&models.StreamEvent{}, "TenantID"); err != nil {
+				return err
+			}
+			return tx.Migrator().DropColumn(&models.StreamEvent{}, "TenantID")
+		},
+	},
+	{
+		ID: "0039_job_tenant",
+		Migrate: func(tx *gorm.DB) error {
+			if !tx.Migrator().HasColumn(&models.Job{}, "TenantID") {
+				if err := tx.Migrator().AddColumn(&models.Job{}, "TenantID"); err != nil {
+					return err
+				}
+			}
+			if !tx.Migrator().HasIndex(&models.Job{}, "TenantID") {
+				if err := tx.Migrator().CreateIndex(&models.Job{}, "TenantID"); err != nil {
+					return err
+				}
+			}
+			// Reachability probes carried their tenant in the payload
+			var jobs []models.Job
+			if err := tx.Select("id", "payload").Where("payload LIKE ?", `%"tenant_id"%`).Find(&jobs).Error; err != nil {
+				return err
+			}
+			for _, job := range jobs {
+				var payload struct {
+					TenantID *uint `json:"tenant_id"`
+				}
+				if json.Unmarshal([]byte(job.Payload), &payload) != nil || payload.TenantID == nil {
+					continue
+				}
+				if err := tx.Model(&models.Job{}).Where("id = ?", job.ID).Update("tenant_id", *payload.TenantID).Error; err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+		Rollback: func(tx *gorm.DB) error {
+			if err := tx.Migrator().DropIndex(&models.Job{}, "TenantID"); err != nil {
+				return err
+			}
+			return tx.Migrator().DropColumn(&models.Job{}, "TenantID")
+		},
+	},
+	{
+		ID: "0040_change_request_tenant",
+		Migrate: func(tx *gorm.DB) error {
+			if !tx.Migrator().HasColumn(&models.ChangeRequest{}, "TenantID") {
+				if err := tx.Migrator().AddColumn(&models.ChangeRequest{}, "TenantID"); err != nil {
+					return err
+				}
+			}
+			if !tx.Migrator().HasIndex(&models.ChangeRequest{}, "TenantID") {
+				if err := tx.Migrator().CreateIndex(&models.ChangeRequest{}, "TenantID"); err != nil {
+					return err
+				}
+			}
+			// Peer creations carried their tenant in the payload; deletions
+			// and restores belong to the tenant of their peer or version
+			var changes []struct {
+				ID        uint
+				Operation string
+				Payload   string
+			}
+			if err := tx.Table("change_requests").Select("id", "operation", "payload").Where("tenant_id IS NULL").Find(&changes).Error; err != nil {
+				return err
+			}
+			for _, change := range changes {
+				var payload struct {
+					TenantID  *uint `json:"tenant_id"`
+					PeerID    uint  `json:"peer_id"`
+					VersionID uint  `json:"version_id"`
+				}
+				if json.Unmarshal([]byte(change.Payload), &payload) != nil {
+					continue
+				}
+				tenant := payload.TenantID
+				switch change.Operation {
+				case "peer.delete":
+					var peer models.BGPPeer
+					if tx.Unscoped().Select("tenant_id").Where("id = ?", payload.PeerID).Take(&peer).Error == nil {
+						tenant = peer.TenantID
+					}
+				case "config.restore":
+					var version models.ConfigVersion
+					if tx.Select("tenant_id").Where("id = ?", payload.VersionID).Take(&version).Error == nil {
+						tenant = version.TenantID
+					}
+				}
+				if tenant == nil {
+					continue
+				}
+				if err := tx.Model(&models.ChangeRequest{}).Where("id = ?", change.ID).Update("tenant_id", *tenant).Error; err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+		Rollback: func(tx *gorm.DB) error {
+			if err := tx.Migrator().DropIndex(&models.ChangeRequest{}, "TenantID"); err != nil {
+				return err
+			}
+			return tx.Migrator().DropColumn(&models.ChangeRequest{}, "TenantID")
+		},
+	},
+}
+
+// keysetIndexes are the indexes added by 0036 for listing alerts and audit
+// entries a page at a time, newest first
+var keysetIndexes = []struct {
+	model interface{}
+	name  string
+}{
+	{&models.Alert{}, "idx_alerts_created_at_id"},
+	{&models.AuditEntry{}, "idx_audit_entries_created_at_id"},
+}
+
+// peerSearchIndexes are the indexes added by 0030 for columns peers are
+// searched by
+var peerSearchIndexes = []struct {
+	model interface{}
+	field string
+}{
+	{&models.BGPPeer{}, "Name"},
+	{&models.BGPPeer{}, "RemoteASN"},
+	{&models.BGPSession{}, "State"},
+}
+
+// peerSearchSchema is the full-text index of peer names and descriptions
+// added by 0030, kept in step with bgp_peers by triggers. Rows are keyed by
+// peer ID; peers in the trash keep theirs, and searches leave them out.
+var peerSearchSchema = []string{
+	"CREATE VIRTUAL TABLE IF NOT EXISTS peer_search USING fts4(name, description)",
+	`CREATE TRIGGER IF NOT EXISTS peer_search_insert AFTER INSERT ON bgp_peers BEGIN
+		INSERT INTO peer_search (docid, name, description) VALUES (new.id, new.name, new.description);
+	END`,
+	`CREATE TRIGGER IF NOT EXISTS peer_search_update AFTER UPDATE OF name, description ON bgp_peers BEGIN
+		UPDATE peer_search SET name = new.name, description = new.description WHERE docid = new.id;
+	END`,
+	`CREATE TRIGGER IF NOT EXISTS peer_search_delete AFTER DELETE ON bgp_peers BEGIN
+		DELETE FROM peer_search WHERE docid = old.id;
+	END`,
+}
+
+// configVersionAnnotationFields are the ConfigVersion columns added by 0029
+var configVersionAnnotationFields = []string{"Name", "Labels", "Pinned"}
+
+// peerOptionFields are the BGPPeer columns added by 0004
+var peerOptionFields = []string{
+	"Keepalive", "HoldTime", "ConnectRetry", "Passive", "TTLSecurityHops",
+	"NextHopSelf", "SoftReconfigInbound", "RemovePrivateAS", "AllowASIn",
+}
+
+// peerLocalASFields are the BGPPeer columns added by 0032
+var peerLocalASFields = []string{"LocalAS", "LocalASMode"}
+
+// peerFRRErrorFields are the BGPPeer columns added by 0033
+var peerFRRErrorFields = []string{"LastFRRError", "LastFRRErrorAt"}
+
+// peerMaxPrefixFields are the BGPPeer columns added by 0006
+var peerMaxPrefixFields = []string{"MaxPrefixThreshold", "MaxPrefixAction", "MaxPrefixRestart"}
+
+// alertDedupFields are the Alert columns added by 0016
+var alertDedupFields = []string{"Occurrences", "FirstSeenAt", "LastSeenAt", "Suppressed"}
+
+// sessionDetailFields are the BGPSession columns added by 0017
+var sessionDetailFields = []string{
+	"RemoteRouterID", "BGPVersion", "HoldTime", "KeepaliveTime",
+	"FourByteASN", "AddressFamilies", "GracefulRestart",
+}
+
+// peerMaintenanceFields are the BGPPeer columns added by 0009
+var peerMaintenanceFields = []string{"MaintenanceSince", "MaintenanceUntil"}
+
+// peerTemplateFields are the BGPPeer columns added by 0024
+var peerTemplateFields = []string{"TemplateID", "TemplateOverrides"}
+
+// scheduledJobFields are the Job columns added by 0025
+var scheduledJobFields = []string{"RunAt", "AnnouncedAt"}
+
+// tenantOwnedModels are the models given a TenantID column by 0027
+var tenantOwnedModels = []interface{}{&models.BGPPeer{}, &models.Alert{}, &models.ConfigVersion{}}
+
+// refreshTokenClientFields are the RefreshToken columns added by 0010
+var refreshTokenClientFields = []string{"UserAgent", "ClientIP"}
+
+var baselineTables = []interface{}{
+	&models.User{},
+	&models.BGPPeer{},
+	&models.BGPSession{},
+	&models.ConfigVersion{},
+	&models.Alert{},
+	&models.RefreshToken{},
+	&models.RoutingPolicy{},
+	&models.WebhookSubscription{},
+	&models.WebhookDelivery{},
+}
+
+// MigrationStatus describes one migration and whether it has been applied
+type MigrationStatus struct {
+	ID      string
+	Applied bool
+	// Unknown marks an applied migration this build doesn't define
+	Unknown bool
+}
+
+func newMigrator(db *gorm.DB) *gormigrate.Gormigrate {
+	return gormigrate.New(db, &gormigrate.Options{
+		TableName:                 schemaVersionTable,
+		IDColumnName:              "id",
+		IDColumnSize:              255,
+		UseTransaction:            true,
+		ValidateUnknownMigrations: true,
+	}, migrations)
+}
+
+// migrate applies pending migrations after checking the database isn't ahead
+// of this build
+func migrate(db *gorm.DB) error {
+	if err := checkSchemaVersion(db); err != nil {
+		return err
+	}
+	return newMigrator(db).Migrate()
+}
+
+// appliedMigrations returns the IDs recorded in schema_version
+func appliedMigrations(db *gorm.DB) ([]string, error) {
+	if !db.Migrator().HasTable(schemaVersionTable) {
+		return nil, nil
+	}
+
+	var ids []string
+	if err := db.Table(schemaVersionTable).Order("id").Pluck("id", &ids).Error; err != nil {
+		return nil, fmt.Errorf("failed to read schema version: %w", err)
+	}
+	return ids, nil
+}
+
+// checkSchemaVersion refuses databases with migrations unknown to this build
+func checkSchemaVersion(db *gorm.DB) error {
+	statuses, err := MigrationStatuses(db)
+	if err != nil {
+		return err
+	}
+
+	for _, status := range statuses {
+		if status.Unknown {
+			return fmt.Errorf("%w: %s was applied by a newer FlintRoute; upgrade FlintRoute or restore a backup", ErrUnknownSchemaVersion, status.ID)
+		}
+	}
+	return nil
+}
+
+// MigrationStatuses lists the known migrations in order, followed by any
+// applied migrations this build doesn't define
+func MigrationStatuses(db *gorm.DB) ([]MigrationStatus, error) {
+	applied, err := appliedMigrations(db)
+	if err != nil {
+		return nil, err
+	}
+
+	appliedSet := make(map[string]bool, len(applied))
+	for _, id := range applied {
+		appliedSet[id] = true
+	}
+
+	statuses := make([]MigrationStatus, 0, len(migrations))
+	known := make(map[string]bool, len(migrations))
+	for _, m := range migrations {
+		known[m.ID] = true
+		statuses = append(statuses, MigrationStatus{ID: m.ID, Applied: appliedSet[m.ID]})
+	}
+	for _, id := range applied {
+		if !known[id] {
+			statuses = append(statuses, MigrationStatus{ID: id, Applied: true, Unknown: true})
+		}
+	}
+	return statuses, nil
+}
+
+// SchemaVersion returns the ID of the latest applied migration, or "" if
+// none have been applied
+func SchemaVersion(db *gorm.DB) (string, error) {
+	statuses, err := MigrationStatuses(db)
+	if err != nil {
+		return "", err
+	}
+
+	version := ""
+	for _, status := range statuses {
+		if status.Applied {
+			version = status.ID
+		}
+	}
+	return version, nil
+}
@@ -0,0 +1,193 @@
+package database
+
+import (
+	"bytes"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/padminisys/flintroute/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+func TestMigrations(t *testing.T) {
+	logger := zap.NewNop()
+
+	t.Run("Fresh database is at the latest version", func(t *testing.T) {
+		db, err := Initialize(filepath.Join(t.TempDir(), "test.db"), logger)
+		require.NoError(t, err)
+		defer db.Close()
+
+		version, err := SchemaVersion(db.DB)
+		require.NoError(t, err)
+		assert.Equal(t, migrations[len(migrations)-1].ID, version)
+	})
+
+	t.Run("Creates every model's table and columns", func(t *testing.T) {
+		db, err := Initialize(filepath.Join(t.TempDir(), "test.db"), logger)
+		require.NoError(t, err)
+		defer db.Close()
+
+		for _, model := range models.All() {
+			stmt := &gorm.Statement{DB: db.DB}
+			require.NoError(t, stmt.Parse(model))
+			require.True(t, db.Migrator().HasTable(model), "table %s", stmt.Schema.Table)
+			for _, field := range stmt.Schema.Fields {
+				if field.DBName == "" {
+					continue
+				}
+				assert.True(t, db.Migrator().HasColumn(model, field.DBName), "column %s.%s", stmt.Schema.Table, field.DBName)
+			}
+		}
+	})
+
+	t.Run("Adopts database created by AutoMigrate", func(t *testing.T) {
+		dbPath := filepath.Join(t.TempDir(), "test.db")
+		legacy, err := open(dbPath, DefaultOptions())
+		require.NoError(t, err)
+		require.NoError(t, legacy.AutoMigrate(&models.User{}, &models.BGPPeer{}))
+		require.NoError(t, legacy.Create(&models.BGPPeer{Name: "Legacy", IPAddress: "10.0.0.1", ASN: 65001, RemoteASN: 65002}).Error)
+		sqlDB, _ := legacy.DB()
+		sqlDB.Close()
+
+		db, err := Initialize(dbPath, logger)
+		require.NoError(t, err)
+		defer db.Close()
+
+		var peers []models.BGPPeer
+		require.NoError(t, db.Find(&peers).Error)
+		assert.Len(t, peers, 1)
+		assert.True(t, db.Migrator().HasTable(schemaVersionTable))
+	})
+
+	t.Run("Refuses unknown schema version", func(t *testing.T) {
+		dbPath := filepath.Join(t.TempDir(), "test.db")
+		db, err := Initialize(dbPath, logger)
+		require.NoError(t, err)
+		require.NoError(t, db.Exec("INSERT INTO schema_version (id) VALUES (?)", "9999_from_the_future").Error)
+		db.Close()
+
+		_, err = Initialize(dbPath, logger)
+		assert.True(t, errors.Is(err, ErrUnknownSchemaVersion))
+
+		var out bytes.Buffer
+		err = RunMigrateCommand(dbPath, []string{"up"}, &out)
+		assert.True(t, errors.Is(err, ErrUnknownSchemaVersion))
+	})
+
+	t.Run("Moves job tenants out of payloads", func(t *testing.T) {
+		dbPath := filepath.Join(t.TempDir(), "test.db")
+		var out bytes.Buffer
+		require.NoError(t, RunMigrateCommand(dbPath, []string{"to", "0038_stream_event_tenant"}, &out))
+		legacy, err := open(dbPath, DefaultOptions())
+		require.NoError(t, err)
+		for _, payload := range []string{`{"tenant_id":7}`, `null`} {
+			require.NoError(t, legacy.Exec("INSERT INTO jobs (type, status, payload, progress) VALUES (?, ?, ?, 0)",
+				"bgp.probe_reachability", models.JobQueued, payload).Error)
+		}
+		sqlDB, _ := legacy.DB()
+		sqlDB.Close()
+
+		db, err := Initialize(dbPath, logger)
+		require.NoError(t, err)
+		defer db.Close()
+
+		var jobs []models.Job
+		require.NoError(t, db.Order("id").Find(&jobs).Error)
+		require.Len(t, jobs, 2)
+		require.NotNil(t, jobs[0].TenantID)
+		assert.Equal(t, uint(7), *jobs[0].TenantID)
+		assert.Nil(t, jobs[1].TenantID)
+	})
+
+	t.Run("Gives change requests their tenant", func(t *testing.T) {
+		dbPath := filepath.Join(t.TempDir(), "test.db")
+		var out bytes.Buffer
+		require.NoError(t, RunMigrateCommand(dbPath, []string{"to", "0039_job_tenant"}, &out))
+		legacy, err := open(dbPath, DefaultOptions())
+		require.NoError(t, err)
+		require.NoError(t, legacy.Exec("INSERT INTO bgp_peers (name, ip_address, asn, remote_asn, tenant_id) VALUES ('acme', '10.0.0.1', 65001, 65002, 3)").Error)
+		require.NoError(t, legacy.Exec("INSERT INTO config_versions (config, hash, tenant_id) VALUES ('!', 'abc', 4)").Error)
+		for _, change := range []struct{ operation, payload string }{
+			{"peer.create", `{"name":"new","tenant_id":2}`},
+			{"peer.delete", `{"peer_id":1}`},
+			{"config.restore", `{"version_id":1}`},
+			{"peer.create", `{"name":"untenanted"}`},
+		} {
+			require.NoError(t, legacy.Exec("INSERT INTO change_requests (operation, status, payload, requested_by) VALUES (?, 'pending', ?, 1)",
+				change.operation, []byte(change.payload)).Error)
+		}
+		sqlDB, _ := legacy.DB()
+		sqlDB.Close()
+
+		db, err := Initialize(dbPath, logger)
+		require.NoError(t, err)
+		defer db.Close()
+
+		var changes []models.ChangeRequest
+		require.NoError(t, db.Order("id").Find(&changes).Error)
+		require.Len(t, changes, 4)
+		for i, want := range []uint{2, 3, 4} {
+			require.NotNil(t, changes[i].TenantID, changes[i].Operation)
+			assert.Equal(t, want, *changes[i].TenantID, changes[i].Operation)
+		}
+		assert.Nil(t, changes[3].TenantID)
+	})
+}
+
+func TestRunMigrateCommand(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "test.db")
+	latest := migrations[len(migrations)-1].ID
+
+	t.Run("Status before migrating", func(t *testing.T) {
+		var out bytes.Buffer
+		require.NoError(t, RunMigrateCommand(dbPath, []string{"status"}, &out))
+		assert.Contains(t, out.String(), "0001_initial_schema")
+		assert.Contains(t, out.String(), "pending")
+	})
+
+	t.Run("Up applies all migrations", func(t *testing.T) {
+		var out bytes.Buffer
+		require.NoError(t, RunMigrateCommand(dbPath, nil, &out))
+		assert.Equal(t, "Schema version: "+latest+"\n", out.String())
+
+		db, err := open(dbPath, DefaultOptions())
+		require.NoError(t, err)
+		assert.True(t, db.Migrator().HasTable(&models.BGPPeer{}))
+		sqlDB, _ := db.DB()
+		sqlDB.Close()
+	})
+
+	t.Run("Down rolls back the latest migration", func(t *testing.T) {
+		var out bytes.Buffer
+		require.NoError(t, RunMigrateCommand(dbPath, []string{"down"}, &out))
+
+		db, err := open(dbPath, DefaultOptions())
+		require.NoError(t, err)
+		defer func() {
+			sqlDB, _ := db.DB()
+			sqlDB.Close()
+		}()
+		version, err := SchemaVersion(db)
+		require.NoError(t, err)
+		assert.NotEqual(t, latest, version)
+		if len(migrations) == 1 {
+			assert.False(t, db.Migrator().HasTable(&models.BGPPeer{}))
+		}
+	})
+
+	t.Run("To migrates to a specific version", func(t *testing.T) {
+		var out bytes.Buffer
+		require.NoError(t, RunMigrateCommand(dbPath, []string{"to", latest}, &out))
+		assert.Equal(t, "Schema version: "+latest+"\n", out.String())
+	})
+
+	t.Run("Rejects unknown commands", func(t *testing.T) {
+		var out bytes.Buffer
+		assert.Error(t, RunMigrateCommand(dbPath, []string{"sideways"}, &out))
+		assert.Error(t, RunMigrateCommand(dbPath, []string{"to", "0000_nope"}, &out))
+	})
+}
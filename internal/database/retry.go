@@ -0,0 +1,62 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/mattn/go-sqlite3"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// Retry limits for writes that find the database locked after the busy
+// timeout, e.g. while another process holds a long write transaction
+const (
+	busyRetries      = 5
+	busyRetryBackoff = 50 * time.Millisecond
+)
+
+// IsBusy reports whether err is SQLite's "database is locked" (SQLITE_BUSY)
+// or "database table is locked" (SQLITE_LOCKED)
+func IsBusy(err error) bool {
+	var sqliteErr sqlite3.Error
+	if errors.As(err, &sqliteErr) {
+		return sqliteErr.Code == sqlite3.ErrBusy || sqliteErr.Code == sqlite3.ErrLocked
+	}
+	return false
+}
+
+// WithRetry runs fn, retrying with exponential backoff while it fails
+// because the database is locked. fn must be safe to run more than once.
+func (db *DB) WithRetry(ctx context.Context, fn func(*gorm.DB) error) error {
+	backoff := busyRetryBackoff
+	for attempt := 1; ; attempt++ {
+		err := fn(db.DB.WithContext(ctx))
+		if !IsBusy(err) || attempt > busyRetries {
+			return err
+		}
+
+		db.logger.Debug("Database busy, retrying",
+			zap.Int("attempt", attempt),
+			zap.Duration("backoff", backoff),
+		)
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+		backoff *= 2
+	}
+}
+
+// TransactionWithRetry runs fn in a transaction, retrying the whole
+// transaction while the database is locked
+func (db *DB) TransactionWithRetry(ctx context.Context, fn func(tx *gorm.DB) error) error {
+	return db.WithRetry(ctx, func(conn *gorm.DB) error {
+		return conn.Transaction(fn)
+	})
+}
@@ -0,0 +1,116 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/mattn/go-sqlite3"
+	"github.com/padminisys/flintroute/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+func TestSQLiteOptions(t *testing.T) {
+	db, err := Initialize(filepath.Join(t.TempDir(), "test.db"), zap.NewNop())
+	require.NoError(t, err)
+	defer db.Close()
+
+	t.Run("Uses WAL journal mode", func(t *testing.T) {
+		var mode string
+		require.NoError(t, db.Raw("PRAGMA journal_mode").Scan(&mode).Error)
+		assert.Equal(t, "wal", mode)
+	})
+
+	t.Run("Sets busy timeout", func(t *testing.T) {
+		var timeout int
+		require.NoError(t, db.Raw("PRAGMA busy_timeout").Scan(&timeout).Error)
+		assert.Equal(t, 5000, timeout)
+	})
+
+	t.Run("Concurrent writes succeed", func(t *testing.T) {
+		var wg sync.WaitGroup
+		errs := make(chan error, 20)
+		for i := 0; i < 20; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				errs <- db.TransactionWithRetry(context.Background(), func(tx *gorm.DB) error {
+					return tx.Create(&models.Alert{Type: "test", Severity: "info", Message: fmt.Sprintf("alert %d", i)}).Error
+				})
+			}(i)
+		}
+		wg.Wait()
+		close(errs)
+
+		for err := range errs {
+			assert.NoError(t, err)
+		}
+		var count int64
+		require.NoError(t, db.Model(&models.Alert{}).Count(&count).Error)
+		assert.Equal(t, int64(20), count)
+	})
+}
+
+func TestWithRetry(t *testing.T) {
+	db, err := Initialize(filepath.Join(t.TempDir(), "test.db"), zap.NewNop())
+	require.NoError(t, err)
+	defer db.Close()
+	busy := sqlite3.Error{Code: sqlite3.ErrBusy}
+
+	t.Run("Retries while busy", func(t *testing.T) {
+		calls := 0
+		err := db.WithRetry(context.Background(), func(*gorm.DB) error {
+			calls++
+			if calls < 3 {
+				return busy
+			}
+			return nil
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, 3, calls)
+	})
+
+	t.Run("Gives up after the retry limit", func(t *testing.T) {
+		calls := 0
+		err := db.WithRetry(context.Background(), func(*gorm.DB) error {
+			calls++
+			return busy
+		})
+		assert.True(t, IsBusy(err))
+		assert.Equal(t, busyRetries+1, calls)
+	})
+
+	t.Run("Does not retry other errors", func(t *testing.T) {
+		calls := 0
+		err := db.WithRetry(context.Background(), func(*gorm.DB) error {
+			calls++
+			return errors.New("constraint failed")
+		})
+		assert.Error(t, err)
+		assert.Equal(t, 1, calls)
+	})
+
+	t.Run("Stops when the context is cancelled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		calls := 0
+		err := db.WithRetry(ctx, func(*gorm.DB) error {
+			calls++
+			cancel()
+			return busy
+		})
+		assert.True(t, IsBusy(err))
+		assert.Equal(t, 1, calls)
+	})
+}
+
+func TestIsBusy(t *testing.T) {
+	assert.True(t, IsBusy(fmt.Errorf("wrapped: %w", sqlite3.Error{Code: sqlite3.ErrLocked})))
+	assert.False(t, IsBusy(sqlite3.Error{Code: sqlite3.ErrConstraint}))
+	assert.False(t, IsBusy(nil))
+}
@@ -0,0 +1,129 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/padminisys/flintroute/pkg/models"
+	"gorm.io/gorm"
+)
+
+// ErrInvalidSnapshot is returned when a snapshot is not an intact
+// FlintRoute database
+var ErrInvalidSnapshot = errors.New("invalid snapshot")
+
+// ErrSnapshotsUnsupported is returned when snapshotting or restoring a
+// database whose driver has no snapshot support. Snapshots are taken with
+// SQLite's VACUUM INTO; other databases, such as Postgres, are backed up
+// with their own tools.
+var ErrSnapshotsUnsupported = errors.New("snapshots are only supported on SQLite")
+
+// checkSnapshotDriver returns ErrSnapshotsUnsupported unless the database
+// is SQLite
+func (db *DB) checkSnapshotDriver() error {
+	if name := db.Dialector.Name(); name != DriverSQLite {
+		return fmt.Errorf("%w: unsupported driver %s", ErrSnapshotsUnsupported, name)
+	}
+	return nil
+}
+
+// Snapshot writes a consistent copy of the database to path, which must not
+// exist yet. Writes may go on while it runs.
+func (db *DB) Snapshot(ctx context.Context, path string) error {
+	if err := db.checkSnapshotDriver(); err != nil {
+		return err
+	}
+	if err := db.WithContext(ctx).Exec("VACUUM INTO ?", path).Error; err != nil {
+		return fmt.Errorf("failed to snapshot database: %w", err)
+	}
+	return nil
+}
+
+// Restore replaces the rows of every table with those of the snapshot at
+// path, except the tables named in keep, in a single transaction. The
+// snapshot is checked for integrity and migrated to this build's schema
+// first; one migrated by a newer FlintRoute is refused.
+func (db *DB) Restore(ctx context.Context, path string, keep ...string) error {
+	if err := db.checkSnapshotDriver(); err != nil {
+		return err
+	}
+	if err := prepareSnapshot(path); err != nil {
+		return err
+	}
+
+	tables := make([]string, 0, len(models.All()))
+	for _, model := range models.All() {
+		stmt := &gorm.Statement{DB: db.DB}
+		if err := stmt.Parse(model); err != nil {
+			return err
+		}
+		if !slices.Contains(keep, stmt.Schema.Table) {
+			tables = append(tables, stmt.Schema.Table)
+		}
+	}
+
+	return db.WithContext(ctx).Connection(func(conn *gorm.DB) error {
+		if err := conn.Exec("ATTACH DATABASE ? AS snapshot", path).Error; err != nil {
+			return fmt.Errorf("failed to attach snapshot: %w", err)
+		}
+		defer conn.Exec("DETACH DATABASE snapshot")
+
+		return conn.Transaction(func(tx *gorm.DB) error {
+			// Children before the tables they refer to
+			for i := len(tables) - 1; i >= 0; i-- {
+				if err := tx.Exec(fmt.Sprintf("DELETE FROM main.%q", tables[i])).Error; err != nil {
+					return fmt.Errorf("failed to clear %s: %w", tables[i], err)
+				}
+			}
+			for _, table := range tables {
+				columns, err := tx.Migrator().ColumnTypes(table)
+				if err != nil {
+					return err
+				}
+				names := make([]string, len(columns))
+				for i, column := range columns {
+					names[i] = fmt.Sprintf("%q", column.Name())
+				}
+				list := strings.Join(names, ", ")
+				err = tx.Exec(fmt.Sprintf("INSERT INTO main.%q (%s) SELECT %s FROM snapshot.%q", table, list, list, table)).Error
+				if err != nil {
+					return fmt.Errorf("failed to restore %s: %w", table, err)
+				}
+			}
+			return nil
+		})
+	})
+}
+
+// prepareSnapshot checks the snapshot at path and migrates it to this
+// build's schema, so that its tables match the live ones
+func prepareSnapshot(path string) error {
+	snap, err := open(path, Options{})
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrInvalidSnapshot, err)
+	}
+	defer func() {
+		if sqlDB, err := snap.DB(); err == nil {
+			sqlDB.Close()
+		}
+	}()
+
+	var result string
+	if err := snap.Raw("PRAGMA integrity_check").Scan(&result).Error; err != nil {
+		return fmt.Errorf("%w: %s", ErrInvalidSnapshot, err)
+	}
+	if result != "ok" {
+		return fmt.Errorf("%w: integrity check failed: %s", ErrInvalidSnapshot, result)
+	}
+	if !snap.Migrator().HasTable(schemaVersionTable) {
+		return fmt.Errorf("%w: not a FlintRoute database", ErrInvalidSnapshot)
+	}
+
+	if err := migrate(snap); err != nil {
+		return fmt.Errorf("failed to migrate snapshot: %w", err)
+	}
+	return nil
+}
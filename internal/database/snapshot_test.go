@@ -0,0 +1,88 @@
+package database
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/padminisys/flintroute/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+func TestSnapshotRestore(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	db, err := Initialize(filepath.Join(dir, "flintroute.db"), zap.NewNop())
+	require.NoError(t, err)
+	defer db.Close()
+
+	require.NoError(t, db.Create(&models.BGPPeer{Name: "kept", IPAddress: "192.0.2.1", ASN: 65000, RemoteASN: 65001}).Error)
+	snapshot := filepath.Join(dir, "snapshot.db")
+	require.NoError(t, db.Snapshot(ctx, snapshot))
+
+	// Changes after the snapshot
+	require.NoError(t, db.Create(&models.BGPPeer{Name: "added", IPAddress: "192.0.2.2", ASN: 65000, RemoteASN: 65002}).Error)
+	require.NoError(t, db.Create(&models.Job{Type: "test", Status: models.JobQueued}).Error)
+
+	t.Run("Restores all but the kept tables", func(t *testing.T) {
+		require.NoError(t, db.Restore(ctx, snapshot, "jobs"))
+
+		var peers []models.BGPPeer
+		require.NoError(t, db.Find(&peers).Error)
+		require.Len(t, peers, 1)
+		assert.Equal(t, "kept", peers[0].Name)
+
+		var jobs int64
+		require.NoError(t, db.Model(&models.Job{}).Count(&jobs).Error)
+		assert.Equal(t, int64(1), jobs)
+
+		var users int64
+		require.NoError(t, db.Model(&models.User{}).Count(&users).Error)
+		assert.Equal(t, int64(1), users)
+	})
+
+	t.Run("Refuses a file that isn't a database", func(t *testing.T) {
+		garbage := filepath.Join(dir, "garbage.db")
+		require.NoError(t, os.WriteFile(garbage, []byte("not a database at all, just some text padding it out"), 0600))
+
+		err := db.Restore(ctx, garbage)
+		assert.ErrorIs(t, err, ErrInvalidSnapshot)
+	})
+
+	t.Run("Refuses a newer schema", func(t *testing.T) {
+		newer := filepath.Join(dir, "newer.db")
+		require.NoError(t, db.Snapshot(ctx, newer))
+		snap, err := open(newer, Options{})
+		require.NoError(t, err)
+		require.NoError(t, snap.Exec("INSERT INTO schema_version (id) VALUES ('9999_future')").Error)
+		sqlDB, _ := snap.DB()
+		sqlDB.Close()
+
+		err = db.Restore(ctx, newer)
+		assert.ErrorIs(t, err, ErrUnknownSchemaVersion)
+	})
+
+	t.Run("Refuses other drivers", func(t *testing.T) {
+		postgres := &DB{DB: db.Session(&gorm.Session{NewDB: true})}
+		postgres.Config = &gorm.Config{Dialector: namedDialector{Dialector: db.Dialector, name: "postgres"}}
+
+		err := postgres.Snapshot(ctx, filepath.Join(dir, "postgres.db"))
+		assert.ErrorIs(t, err, ErrSnapshotsUnsupported)
+		assert.Contains(t, err.Error(), "unsupported driver postgres")
+		assert.ErrorIs(t, postgres.Restore(ctx, snapshot), ErrSnapshotsUnsupported)
+	})
+}
+
+// namedDialector is a dialector reporting another driver's name
+type namedDialector struct {
+	gorm.Dialector
+	name string
+}
+
+func (d namedDialector) Name() string {
+	return d.name
+}
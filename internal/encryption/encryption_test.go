@@ -0,0 +1,103 @@
+package encryption
+
+import (
+	"bytes"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testKey() []byte {
+	return bytes.Repeat([]byte{0x42}, KeySize)
+}
+
+func TestNewCipher(t *testing.T) {
+	t.Run("Valid key", func(t *testing.T) {
+		c, err := NewCipher(testKey())
+		assert.NoError(t, err)
+		assert.NotNil(t, c)
+	})
+
+	t.Run("Short key", func(t *testing.T) {
+		_, err := NewCipher([]byte("too-short"))
+		assert.ErrorIs(t, err, ErrInvalidKey)
+	})
+}
+
+func TestEncryptDecrypt(t *testing.T) {
+	c, err := NewCipher(testKey())
+	require.NoError(t, err)
+
+	t.Run("Round trip", func(t *testing.T) {
+		encrypted, err := c.Encrypt("s3cret")
+		require.NoError(t, err)
+		assert.True(t, IsEncrypted(encrypted))
+		assert.NotContains(t, encrypted, "s3cret")
+
+		decrypted, err := c.Decrypt(encrypted)
+		require.NoError(t, err)
+		assert.Equal(t, "s3cret", decrypted)
+	})
+
+	t.Run("Nonce is random", func(t *testing.T) {
+		first, _ := c.Encrypt("s3cret")
+		second, _ := c.Encrypt("s3cret")
+		assert.NotEqual(t, first, second)
+	})
+
+	t.Run("Empty value", func(t *testing.T) {
+		encrypted, err := c.Encrypt("")
+		require.NoError(t, err)
+		assert.Empty(t, encrypted)
+	})
+
+	t.Run("Legacy plaintext", func(t *testing.T) {
+		decrypted, err := c.Decrypt("plain")
+		require.NoError(t, err)
+		assert.Equal(t, "plain", decrypted)
+	})
+
+	t.Run("Wrong key", func(t *testing.T) {
+		encrypted, _ := c.Encrypt("s3cret")
+		other, err := NewCipher(bytes.Repeat([]byte{0x01}, KeySize))
+		require.NoError(t, err)
+
+		_, err = other.Decrypt(encrypted)
+		assert.ErrorIs(t, err, ErrInvalidCiphertext)
+	})
+
+	t.Run("Corrupted value", func(t *testing.T) {
+		_, err := c.Decrypt(prefix + "not-base64!")
+		assert.ErrorIs(t, err, ErrInvalidCiphertext)
+	})
+}
+
+func TestLoadOrCreateKey(t *testing.T) {
+	t.Run("Creates and reuses key", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "keys", "encryption.key")
+
+		key, err := LoadOrCreateKey(path)
+		require.NoError(t, err)
+		assert.Len(t, key, KeySize)
+
+		info, err := os.Stat(path)
+		require.NoError(t, err)
+		assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+		again, err := LoadOrCreateKey(path)
+		require.NoError(t, err)
+		assert.Equal(t, key, again)
+	})
+
+	t.Run("Invalid key file", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "encryption.key")
+		require.NoError(t, os.WriteFile(path, []byte(base64.StdEncoding.EncodeToString([]byte("short"))), 0600))
+
+		_, err := LoadOrCreateKey(path)
+		assert.ErrorIs(t, err, ErrInvalidKey)
+	})
+}
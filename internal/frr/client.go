@@ -2,16 +2,83 @@ package frr
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strings"
+	"sync"
 	"time"
 
+	"github.com/padminisys/flintroute/internal/frrconf"
+	"github.com/padminisys/flintroute/internal/requestid"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
 	"go.uber.org/zap"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials/insecure"
 )
 
+var (
+	// ErrNotConnected is returned when an operation requires an FRR connection
+	ErrNotConnected = errors.New("not connected to FRR")
+	// ErrConfigRejected is returned when FRR finds a candidate configuration
+	// invalid
+	ErrConfigRejected = errors.New("FRR rejected the configuration")
+)
+
+// FRRClient is the set of FRR operations FlintRoute uses. Client talks to
+// FRR's gRPC northbound; VtyshClient shells out to vtysh for builds without
+// it.
+type FRRClient interface {
+	Connect(ctx context.Context) error
+	Close() error
+	IsConnected() bool
+	AddBGPPeer(ctx context.Context, config *BGPPeerConfig) error
+	RemoveBGPPeer(ctx context.Context, ipAddress string) error
+	UpdateBGPPeer(ctx context.Context, config *BGPPeerConfig) error
+	CheckBGPPeer(ctx context.Context, config *BGPPeerConfig) ([]string, error)
+	ApplyBGPGlobal(ctx context.Context, config *BGPGlobalConfig) error
+	ApplyPolicy(ctx context.Context, kind, name, config string) error
+	RemovePolicy(ctx context.Context, kind, name string) error
+	GetBGPSessionState(ctx context.Context, ipAddress string) (*BGPSessionState, error)
+	GetAllBGPSessions(ctx context.Context) ([]*BGPSessionState, error)
+	GetRunningConfig(ctx context.Context) (string, error)
+	GetStatus(ctx context.Context) (*Status, error)
+	ListBGPNeighbors(ctx context.Context) ([]*BGPNeighbor, error)
+	ProbeBGPPeer(ctx context.Context, config *BGPPeerConfig, timeout time.Duration) (*PeerProbe, error)
+}
+
+// PeerBatcher is implemented by clients that apply the configuration of
+// several peers in a single commit
+type PeerBatcher interface {
+	// UpdateBGPPeers adds or updates the peers as UpdateBGPPeer does. When
+	// it fails, some of them may have been applied.
+	UpdateBGPPeers(ctx context.Context, configs []*BGPPeerConfig) error
+}
+
+// UpdateBGPPeers adds or updates peers through client, in a single commit
+// when it is a PeerBatcher and one at a time otherwise
+func UpdateBGPPeers(ctx context.Context, client FRRClient, configs []*BGPPeerConfig) error {
+	if batcher, ok := client.(PeerBatcher); ok {
+		return batcher.UpdateBGPPeers(ctx, configs)
+	}
+	for _, config := range configs {
+		if err := client.UpdateBGPPeer(ctx, config); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+var (
+	_ FRRClient = (*Client)(nil)
+	_ FRRClient = (*VtyshClient)(nil)
+	_ FRRClient = (*MockClient)(nil)
+
+	_ PeerBatcher = (*VtyshClient)(nil)
+)
+
 // Client represents an FRR gRPC client
 type Client struct {
+	mu     sync.RWMutex // guards conn, which Connect sets while others check it
 	conn   *grpc.ClientConn
 	logger *zap.Logger
 	host   string
@@ -30,25 +97,30 @@ func NewClient(host string, port int, logger *zap.Logger) (*Client, error) {
 // Connect establishes connection to FRR gRPC server
 func (c *Client) Connect(ctx context.Context) error {
 	addr := fmt.Sprintf("%s:%d", c.host, c.port)
-	
+
 	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
 	defer cancel()
 
 	conn, err := grpc.DialContext(ctx, addr,
 		grpc.WithTransportCredentials(insecure.NewCredentials()),
 		grpc.WithBlock(),
+		grpc.WithStatsHandler(otelgrpc.NewClientHandler()),
 	)
 	if err != nil {
 		return fmt.Errorf("failed to connect to FRR gRPC server: %w", err)
 	}
 
+	c.mu.Lock()
 	c.conn = conn
+	c.mu.Unlock()
 	c.logger.Info("Connected to FRR gRPC server", zap.String("address", addr))
 	return nil
 }
 
 // Close closes the gRPC connection
 func (c *Client) Close() error {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
 	if c.conn != nil {
 		return c.conn.Close()
 	}
@@ -57,14 +129,18 @@ func (c *Client) Close() error {
 
 // IsConnected checks if the client is connected
 func (c *Client) IsConnected() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
 	return c.conn != nil
 }
 
 // BGPPeerConfig represents BGP peer configuration for FRR
 type BGPPeerConfig struct {
-	IPAddress       string
-	ASN             uint32
-	RemoteASN       uint32
+	IPAddress string
+	ASN       uint32
+	RemoteASN uint32
+	// Description labels the neighbor for router CLI users
+	Description     string
 	Password        string
 	Multihop        int
 	UpdateSource    string
@@ -74,6 +150,7 @@ type BGPPeerConfig struct {
 	PrefixListOut   string
 	MaxPrefixes     int
 	LocalPreference int
+	NeighborOptions
 }
 
 // BGPSessionState represents BGP session state from FRR
@@ -86,12 +163,32 @@ type BGPSessionState struct {
 	MessagesReceived int64
 	MessagesSent     int64
 	LastError        string
+
+	// Negotiated with the peer; only set while the session is established
+	RemoteRouterID string
+	BGPVersion     int
+	HoldTime       int // seconds
+	KeepaliveTime  int // seconds
+	FourByteASN    bool
+	// AddressFamilies are the multiprotocol AFI/SAFIs both sides advertised,
+	// e.g. "ipv4-unicast"
+	AddressFamilies []string
+	GracefulRestart bool
+}
+
+// MaxPrefixExceeded reports whether FRR last reset the session because the
+// peer exceeded its maximum-prefix limit
+func (s *BGPSessionState) MaxPrefixExceeded() bool {
+	reason := strings.ToLower(s.LastError)
+	return strings.Contains(reason, "maximum number of prefixes") ||
+		strings.Contains(reason, "received prefix count") ||
+		strings.Contains(reason, "max prefix received")
 }
 
 // AddBGPPeer adds a BGP peer to FRR configuration
 func (c *Client) AddBGPPeer(ctx context.Context, config *BGPPeerConfig) error {
 	if !c.IsConnected() {
-		return fmt.Errorf("not connected to FRR gRPC server")
+		return ErrNotConnected
 	}
 
 	// TODO: Implement actual gRPC call to FRR
@@ -99,6 +196,9 @@ func (c *Client) AddBGPPeer(ctx context.Context, config *BGPPeerConfig) error {
 	c.logger.Info("Adding BGP peer",
 		zap.String("ip", config.IPAddress),
 		zap.Uint32("remote_asn", config.RemoteASN),
+		zap.Strings("options", config.Commands(config.IPAddress)),
+		zap.String("maximum_prefix", config.MaximumPrefixCommand(config.IPAddress, config.MaxPrefixes)),
+		requestid.Field(ctx),
 	)
 
 	return nil
@@ -107,11 +207,11 @@ func (c *Client) AddBGPPeer(ctx context.Context, config *BGPPeerConfig) error {
 // RemoveBGPPeer removes a BGP peer from FRR configuration
 func (c *Client) RemoveBGPPeer(ctx context.Context, ipAddress string) error {
 	if !c.IsConnected() {
-		return fmt.Errorf("not connected to FRR gRPC server")
+		return ErrNotConnected
 	}
 
 	// TODO: Implement actual gRPC call to FRR
-	c.logger.Info("Removing BGP peer", zap.String("ip", ipAddress))
+	c.logger.Info("Removing BGP peer", zap.String("ip", ipAddress), requestid.Field(ctx))
 
 	return nil
 }
@@ -119,13 +219,77 @@ func (c *Client) RemoveBGPPeer(ctx context.Context, ipAddress string) error {
 // UpdateBGPPeer updates a BGP peer configuration
 func (c *Client) UpdateBGPPeer(ctx context.Context, config *BGPPeerConfig) error {
 	if !c.IsConnected() {
-		return fmt.Errorf("not connected to FRR gRPC server")
+		return ErrNotConnected
 	}
 
 	// TODO: Implement actual gRPC call to FRR
 	c.logger.Info("Updating BGP peer",
 		zap.String("ip", config.IPAddress),
 		zap.Uint32("remote_asn", config.RemoteASN),
+		zap.Strings("options", config.Commands(config.IPAddress)),
+		zap.String("maximum_prefix", config.MaximumPrefixCommand(config.IPAddress, config.MaxPrefixes)),
+		requestid.Field(ctx),
+	)
+
+	return nil
+}
+
+// CheckBGPPeer returns the commands that would configure a BGP peer,
+// without applying them
+func (c *Client) CheckBGPPeer(ctx context.Context, config *BGPPeerConfig) ([]string, error) {
+	running, err := c.GetRunningConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// TODO: Validate the candidate configuration through the northbound
+	return peerCommands(config, running), nil
+}
+
+// ApplyBGPGlobal applies the settings of the default BGP instance
+func (c *Client) ApplyBGPGlobal(ctx context.Context, config *BGPGlobalConfig) error {
+	if !c.IsConnected() {
+		return ErrNotConnected
+	}
+
+	// TODO: Implement actual gRPC call to FRR
+	c.logger.Info("Applying BGP global configuration",
+		zap.Uint32("asn", config.ASN),
+		zap.String("router_id", config.RouterID),
+		requestid.Field(ctx),
+	)
+
+	return nil
+}
+
+// ApplyPolicy replaces a route-map, prefix-list, community-list or as-path
+// access-list in FRR with the given configuration text
+func (c *Client) ApplyPolicy(ctx context.Context, kind, name, config string) error {
+	if !c.IsConnected() {
+		return ErrNotConnected
+	}
+
+	// TODO: Implement actual gRPC call to FRR
+	c.logger.Info("Applying routing policy",
+		zap.String("kind", kind),
+		zap.String("name", name),
+		requestid.Field(ctx),
+	)
+
+	return nil
+}
+
+// RemovePolicy removes a routing policy object from FRR
+func (c *Client) RemovePolicy(ctx context.Context, kind, name string) error {
+	if !c.IsConnected() {
+		return ErrNotConnected
+	}
+
+	// TODO: Implement actual gRPC call to FRR
+	c.logger.Info("Removing routing policy",
+		zap.String("kind", kind),
+		zap.String("name", name),
+		requestid.Field(ctx),
 	)
 
 	return nil
@@ -134,7 +298,7 @@ func (c *Client) UpdateBGPPeer(ctx context.Context, config *BGPPeerConfig) error
 // GetBGPSessionState retrieves BGP session state for a peer
 func (c *Client) GetBGPSessionState(ctx context.Context, ipAddress string) (*BGPSessionState, error) {
 	if !c.IsConnected() {
-		return nil, fmt.Errorf("not connected to FRR gRPC server")
+		return nil, ErrNotConnected
 	}
 
 	// TODO: Implement actual gRPC call to FRR
@@ -150,13 +314,18 @@ func (c *Client) GetBGPSessionState(ctx context.Context, ipAddress string) (*BGP
 		MessagesReceived: 1000,
 		MessagesSent:     900,
 		LastError:        "",
+		BGPVersion:       4,
+		HoldTime:         180,
+		KeepaliveTime:    60,
+		FourByteASN:      true,
+		AddressFamilies:  []string{"ipv4-unicast"},
 	}, nil
 }
 
 // GetAllBGPSessions retrieves all BGP session states
 func (c *Client) GetAllBGPSessions(ctx context.Context) ([]*BGPSessionState, error) {
 	if !c.IsConnected() {
-		return nil, fmt.Errorf("not connected to FRR gRPC server")
+		return nil, ErrNotConnected
 	}
 
 	// TODO: Implement actual gRPC call to FRR
@@ -168,11 +337,73 @@ func (c *Client) GetAllBGPSessions(ctx context.Context) ([]*BGPSessionState, err
 // GetRunningConfig retrieves the current FRR running configuration
 func (c *Client) GetRunningConfig(ctx context.Context) (string, error) {
 	if !c.IsConnected() {
-		return "", fmt.Errorf("not connected to FRR gRPC server")
+		return "", ErrNotConnected
 	}
 
 	// TODO: Implement actual gRPC call to FRR
-	c.logger.Debug("Getting running configuration")
+	c.logger.Debug("Getting running configuration", requestid.Field(ctx))
 
 	return "! FRR Configuration\n", nil
-}
\ No newline at end of file
+}
+
+// GetStatus retrieves FRR's version, daemons and northbound capabilities
+func (c *Client) GetStatus(ctx context.Context) (*Status, error) {
+	if !c.IsConnected() {
+		return nil, ErrNotConnected
+	}
+
+	// TODO: Read the version and supported modules from the northbound
+	// Capabilities RPC. For now, only report bgpd, whose northbound this
+	// client talks to.
+	c.logger.Debug("Getting FRR status", requestid.Field(ctx))
+
+	return newStatus("", []string{"bgpd"}), nil
+}
+
+// ProbeBGPPeer checks that a peer's BGP port can be reached, without
+// configuring it
+func (c *Client) ProbeBGPPeer(ctx context.Context, config *BGPPeerConfig, timeout time.Duration) (*PeerProbe, error) {
+	// TODO: Have the router probe the peer through the northbound; until
+	// then the probe runs from FlintRoute's host
+	c.logger.Debug("Probing BGP peer", zap.String("ip", config.IPAddress), requestid.Field(ctx))
+	return probePeer(ctx, config, ProbedFromFlintRoute, timeout), nil
+}
+
+// BGPNeighbor represents a neighbor present in FRR's BGP configuration
+type BGPNeighbor struct {
+	IPAddress   string
+	RemoteASN   uint32
+	Description string
+}
+
+// ListBGPNeighbors returns the neighbors configured in FRR's running config
+func (c *Client) ListBGPNeighbors(ctx context.Context) ([]*BGPNeighbor, error) {
+	config, err := c.GetRunningConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return ParseBGPNeighbors(config), nil
+}
+
+// ParseBGPNeighbors returns the neighbors of the default BGP instance in
+// FRR configuration text that have an IP address and a remote-as, members
+// of a peer-group taking the group's. Interface neighbors are ignored.
+func ParseBGPNeighbors(config string) []*BGPNeighbor {
+	r := frrconf.Parse(config).BGP
+	if r == nil {
+		return nil
+	}
+
+	var neighbors []*BGPNeighbor
+	for _, peer := range r.Peers() {
+		if peer.RemoteASN == 0 {
+			continue
+		}
+		neighbors = append(neighbors, &BGPNeighbor{
+			IPAddress:   peer.Name,
+			RemoteASN:   peer.RemoteASN,
+			Description: peer.Description,
+		})
+	}
+	return neighbors
+}
@@ -82,6 +82,63 @@ func TestBGPPeerConfig(t *testing.T) {
 		assert.Equal(t, 1000, config.MaxPrefixes)
 		assert.Equal(t, 100, config.LocalPreference)
 	})
+
+	t.Run("Render neighbor options", func(t *testing.T) {
+		options := &NeighborOptions{
+			Keepalive:           10,
+			HoldTime:            30,
+			ConnectRetry:        5,
+			Passive:             true,
+			TTLSecurityHops:     1,
+			NextHopSelf:         true,
+			SoftReconfigInbound: true,
+			RemovePrivateAS:     true,
+			AllowASIn:           2,
+			LocalAS:             64999,
+			LocalASNoPrepend:    true,
+		}
+
+		assert.Equal(t, []string{
+			"neighbor 192.0.2.1 local-as 64999 no-prepend",
+			"neighbor 192.0.2.1 timers 10 30",
+			"neighbor 192.0.2.1 timers connect 5",
+			"neighbor 192.0.2.1 passive",
+			"neighbor 192.0.2.1 ttl-security hops 1",
+			"neighbor 192.0.2.1 next-hop-self",
+			"neighbor 192.0.2.1 soft-reconfiguration inbound",
+			"neighbor 192.0.2.1 remove-private-AS",
+			"neighbor 192.0.2.1 allowas-in 2",
+		}, options.Commands("192.0.2.1"))
+
+		assert.Empty(t, (&NeighborOptions{}).Commands("192.0.2.1"))
+	})
+
+	t.Run("Render maximum-prefix", func(t *testing.T) {
+		assert.Equal(t, "neighbor 192.0.2.1 maximum-prefix 1000",
+			(&NeighborOptions{}).MaximumPrefixCommand("192.0.2.1", 1000))
+		assert.Equal(t, "neighbor 192.0.2.1 maximum-prefix 1000 80 warning-only",
+			(&NeighborOptions{MaxPrefixThreshold: 80, MaxPrefixWarningOnly: true}).MaximumPrefixCommand("192.0.2.1", 1000))
+		assert.Equal(t, "neighbor 192.0.2.1 maximum-prefix 1000 restart 30",
+			(&NeighborOptions{MaxPrefixRestart: 30}).MaximumPrefixCommand("192.0.2.1", 1000))
+		assert.Empty(t, (&NeighborOptions{MaxPrefixThreshold: 80}).MaximumPrefixCommand("192.0.2.1", 0))
+	})
+
+	t.Run("Render router bgp block", func(t *testing.T) {
+		config := &BGPPeerConfig{
+			IPAddress:       "192.0.2.1",
+			ASN:             65001,
+			RemoteASN:       65002,
+			RouteMapIn:      "RM-IN",
+			NeighborOptions: NeighborOptions{NextHopSelf: true},
+		}
+
+		assert.Equal(t, `router bgp 65001
+ neighbor 192.0.2.1 remote-as 65002
+ neighbor 192.0.2.1 route-map RM-IN in
+ neighbor 192.0.2.1 next-hop-self
+exit
+`, config.Config())
+	})
 }
 
 func TestBGPSessionState(t *testing.T) {
@@ -116,6 +173,16 @@ func TestBGPSessionState(t *testing.T) {
 
 		assert.Equal(t, "Idle", state.State)
 		assert.Equal(t, "Connection refused", state.LastError)
+		assert.False(t, state.MaxPrefixExceeded())
+	})
+
+	t.Run("Session reset by maximum-prefix", func(t *testing.T) {
+		state := &BGPSessionState{
+			State:     "Idle",
+			LastError: "Maximum number of prefixes reached",
+		}
+
+		assert.True(t, state.MaxPrefixExceeded())
 	})
 }
 
@@ -175,6 +242,25 @@ func TestUpdateBGPPeer(t *testing.T) {
 	// Note: Testing with actual connection requires a running FRR gRPC server
 }
 
+func TestApplyPolicy(t *testing.T) {
+	logger := zap.NewNop()
+	ctx := context.Background()
+
+	t.Run("Apply policy without connection", func(t *testing.T) {
+		client, _ := NewClient("localhost", 50051, logger)
+
+		err := client.ApplyPolicy(ctx, "prefix-list", "PL-IN", "ip prefix-list PL-IN seq 10 permit 10.0.0.0/8\n")
+		assert.ErrorIs(t, err, ErrNotConnected)
+	})
+
+	t.Run("Remove policy without connection", func(t *testing.T) {
+		client, _ := NewClient("localhost", 50051, logger)
+
+		err := client.RemovePolicy(ctx, "prefix-list", "PL-IN")
+		assert.ErrorIs(t, err, ErrNotConnected)
+	})
+}
+
 func TestGetBGPSessionState(t *testing.T) {
 	logger := zap.NewNop()
 	ctx := context.Background()
@@ -297,4 +383,36 @@ func TestConnectTimeout(t *testing.T) {
 		err := client.Connect(ctx)
 		assert.Error(t, err)
 	})
-}
\ No newline at end of file
+}
+func TestParseBGPNeighbors(t *testing.T) {
+	t.Run("Parse neighbors from running config", func(t *testing.T) {
+		config := `!
+router bgp 65001
+ bgp router-id 10.0.0.1
+ neighbor 192.168.1.1 remote-as 65002
+ neighbor 2001:db8::1 remote-as 65003
+ neighbor 192.168.1.1 description upstream
+ neighbor PEERS peer-group
+ neighbor PEERS remote-as 65004
+ neighbor eth0 interface remote-as external
+!
+`
+		neighbors := ParseBGPNeighbors(config)
+		assert.Len(t, neighbors, 2)
+		assert.Equal(t, "192.168.1.1", neighbors[0].IPAddress)
+		assert.Equal(t, uint32(65002), neighbors[0].RemoteASN)
+		assert.Equal(t, "upstream", neighbors[0].Description)
+		assert.Equal(t, "2001:db8::1", neighbors[1].IPAddress)
+		assert.Equal(t, uint32(65003), neighbors[1].RemoteASN)
+	})
+
+	t.Run("Empty config", func(t *testing.T) {
+		assert.Empty(t, ParseBGPNeighbors("! FRR Configuration\n"))
+	})
+
+	t.Run("List neighbors when not connected", func(t *testing.T) {
+		client, _ := NewClient("localhost", 50051, zap.NewNop())
+		_, err := client.ListBGPNeighbors(context.Background())
+		assert.ErrorIs(t, err, ErrNotConnected)
+	})
+}
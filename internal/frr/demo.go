@@ -0,0 +1,455 @@
+package frr
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"net/netip"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/padminisys/flintroute/internal/frrconf"
+	"github.com/padminisys/flintroute/internal/requestid"
+	"github.com/padminisys/flintroute/pkg/bgpsim"
+	"go.uber.org/zap"
+)
+
+// demoTick is how often the demo client simulates traffic and flaps
+const demoTick = 5 * time.Second
+
+// DemoOptions configures the simulation of a DemoClient
+type DemoOptions struct {
+	// SessionStateDelay is how long a session spends in each state on its
+	// way to Established
+	SessionStateDelay time.Duration
+	// FlapInterval is how long each session stays up on average before it
+	// flaps; 0 disables flaps
+	FlapInterval time.Duration
+}
+
+// DemoClient stands in for FRR in demo mode, so that FlintRoute can be
+// evaluated without a router. Peers it is given establish sessions,
+// exchange messages, advertise routes and now and then flap, as simulated
+// by bgpsim; nothing is configured anywhere.
+type DemoClient struct {
+	state   *bgpsim.BGPState
+	options DemoOptions
+	logger  *zap.Logger
+
+	mu sync.Mutex // guards everything below
+	// stop ends the simulation loop; nil while disconnected
+	stop      chan struct{}
+	startedAt time.Time
+	global    *BGPGlobalConfig
+	peers     map[string]*BGPPeerConfig
+	policies  map[string]string // by kind and name
+	// networks counts the peers given routes, to give each its own
+	networks int
+}
+
+var _ FRRClient = (*DemoClient)(nil)
+
+// NewDemoClient creates a demo FRR client
+func NewDemoClient(opts DemoOptions, logger *zap.Logger) *DemoClient {
+	return &DemoClient{
+		state:    bgpsim.NewBGPState(),
+		options:  opts,
+		logger:   logger,
+		peers:    make(map[string]*BGPPeerConfig),
+		policies: make(map[string]string),
+	}
+}
+
+// Connect starts the simulation
+func (c *DemoClient) Connect(ctx context.Context) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.stop == nil {
+		c.stop = make(chan struct{})
+		c.startedAt = time.Now()
+		go c.run(c.stop)
+		c.logger.Warn("Demo mode: FRR is simulated and no router is configured")
+	}
+	return nil
+}
+
+// Close stops the simulation. Simulated sessions keep their state.
+func (c *DemoClient) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.stop != nil {
+		close(c.stop)
+		c.stop = nil
+	}
+	return nil
+}
+
+// IsConnected reports whether the simulation runs
+func (c *DemoClient) IsConnected() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.stop != nil
+}
+
+// run simulates traffic on established sessions and flaps them, every
+// demoTick until stop is closed
+func (c *DemoClient) run(stop chan struct{}) {
+	ticker := time.NewTicker(demoTick)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			c.tick()
+		}
+	}
+}
+
+// tick advances the simulation by demoTick
+func (c *DemoClient) tick() {
+	flapChance := 0.0
+	if c.options.FlapInterval > 0 {
+		flapChance = float64(demoTick) / float64(c.options.FlapInterval)
+	}
+
+	for _, session := range c.state.GetAllSessions() {
+		if session.State != bgpsim.StateEstablished {
+			continue
+		}
+		// Keepalives, and the odd update
+		c.state.IncrementSessionCounters(session.IPAddress, 1+rand.Int64N(10), 1+rand.Int64N(5))
+
+		if rand.Float64() < flapChance && !c.state.IsPinned(session.IPAddress) {
+			c.logger.Info("Demo mode: flapping session", zap.String("ip", session.IPAddress))
+			c.state.SimulateFlap(session.IPAddress, 1, 0, c.options.SessionStateDelay)
+		}
+	}
+}
+
+// AddBGPPeer adds a simulated peer, whose session then establishes
+func (c *DemoClient) AddBGPPeer(ctx context.Context, config *BGPPeerConfig) error {
+	if !c.IsConnected() {
+		return ErrNotConnected
+	}
+	if _, err := netip.ParseAddr(config.IPAddress); err != nil {
+		return vtyshConfigError("% Malformed address: " + config.IPAddress)
+	}
+
+	c.logger.Info("Demo mode: adding BGP peer", zap.String("ip", config.IPAddress), requestid.Field(ctx))
+	if _, err := c.state.GetPeer(config.IPAddress); err == nil {
+		return c.UpdateBGPPeer(ctx, config)
+	}
+
+	if err := c.state.AddPeer(demoPeerState(config)); err != nil {
+		return err
+	}
+	peer := *config
+	c.mu.Lock()
+	c.peers[config.IPAddress] = &peer
+	c.networks++
+	network := c.networks
+	c.mu.Unlock()
+
+	if err := c.state.GenerateRoutes(config.IPAddress, demoNetwork(config.IPAddress, network), demoPrefixCount(config.MaxPrefixes)); err != nil {
+		c.logger.Warn("Demo mode: failed to generate routes", zap.String("ip", config.IPAddress), zap.Error(err))
+	}
+	c.state.SimulateSessionEstablishment(config.IPAddress, c.options.SessionStateDelay)
+	return nil
+}
+
+// RemoveBGPPeer removes a simulated peer and its session
+func (c *DemoClient) RemoveBGPPeer(ctx context.Context, ipAddress string) error {
+	if !c.IsConnected() {
+		return ErrNotConnected
+	}
+
+	c.logger.Info("Demo mode: removing BGP peer", zap.String("ip", ipAddress), requestid.Field(ctx))
+	c.mu.Lock()
+	delete(c.peers, ipAddress)
+	c.mu.Unlock()
+	// Like "no neighbor", removing a peer that isn't there is no error
+	c.state.RemovePeer(ipAddress)
+	return nil
+}
+
+// UpdateBGPPeer updates a simulated peer. Changing its remote AS or
+// password resets its session, as it would in FRR.
+func (c *DemoClient) UpdateBGPPeer(ctx context.Context, config *BGPPeerConfig) error {
+	if !c.IsConnected() {
+		return ErrNotConnected
+	}
+
+	existing, err := c.state.GetPeer(config.IPAddress)
+	if err != nil {
+		return c.AddBGPPeer(ctx, config)
+	}
+
+	c.logger.Info("Demo mode: updating BGP peer", zap.String("ip", config.IPAddress), requestid.Field(ctx))
+	if err := c.state.UpdatePeer(demoPeerState(config)); err != nil {
+		return err
+	}
+	peer := *config
+	c.mu.Lock()
+	c.peers[config.IPAddress] = &peer
+	c.mu.Unlock()
+
+	if (existing.RemoteASN != config.RemoteASN || existing.Password != config.Password) && !c.state.IsPinned(config.IPAddress) {
+		c.state.SimulateFlap(config.IPAddress, 1, 0, c.options.SessionStateDelay)
+	}
+	return nil
+}
+
+// CheckBGPPeer returns the commands that would configure a BGP peer,
+// without applying them
+func (c *DemoClient) CheckBGPPeer(ctx context.Context, config *BGPPeerConfig) ([]string, error) {
+	running, err := c.GetRunningConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return peerCommands(config, running), nil
+}
+
+// ApplyBGPGlobal records the settings of the simulated BGP instance
+func (c *DemoClient) ApplyBGPGlobal(ctx context.Context, config *BGPGlobalConfig) error {
+	if !c.IsConnected() {
+		return ErrNotConnected
+	}
+
+	global := *config
+	c.mu.Lock()
+	c.global = &global
+	c.mu.Unlock()
+	return nil
+}
+
+// ApplyPolicy records a routing policy object for the running
+// configuration
+func (c *DemoClient) ApplyPolicy(ctx context.Context, kind, name, config string) error {
+	if !c.IsConnected() {
+		return ErrNotConnected
+	}
+
+	c.mu.Lock()
+	c.policies[kind+" "+name] = config
+	c.mu.Unlock()
+	return nil
+}
+
+// RemovePolicy forgets a routing policy object
+func (c *DemoClient) RemovePolicy(ctx context.Context, kind, name string) error {
+	if !c.IsConnected() {
+		return ErrNotConnected
+	}
+
+	c.mu.Lock()
+	delete(c.policies, kind+" "+name)
+	c.mu.Unlock()
+	return nil
+}
+
+// GetBGPSessionState returns a simulated peer's session state
+func (c *DemoClient) GetBGPSessionState(ctx context.Context, ipAddress string) (*BGPSessionState, error) {
+	if !c.IsConnected() {
+		return nil, ErrNotConnected
+	}
+
+	session, err := c.state.GetSessionState(ipAddress)
+	if err != nil {
+		return nil, fmt.Errorf("BGP neighbor %s not found in FRR", ipAddress)
+	}
+	return demoSessionState(session), nil
+}
+
+// GetAllBGPSessions returns the session states of every simulated peer
+func (c *DemoClient) GetAllBGPSessions(ctx context.Context) ([]*BGPSessionState, error) {
+	if !c.IsConnected() {
+		return nil, ErrNotConnected
+	}
+
+	sessions := c.state.GetAllSessions()
+	states := make([]*BGPSessionState, 0, len(sessions))
+	for _, session := range sessions {
+		states = append(states, demoSessionState(session))
+	}
+	sort.Slice(states, func(i, j int) bool { return states[i].IPAddress < states[j].IPAddress })
+	return states, nil
+}
+
+// GetRunningConfig renders the simulated peers, BGP settings and policies
+// as FRR would show them
+func (c *DemoClient) GetRunningConfig(ctx context.Context) (string, error) {
+	if !c.IsConnected() {
+		return "", ErrNotConnected
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var b strings.Builder
+	b.WriteString("! FRR Configuration (simulated in demo mode)\n!\n")
+
+	var asn uint32
+	var statements []frrconf.Statement
+	if c.global != nil {
+		asn = c.global.ASN
+		for _, command := range c.global.Commands() {
+			statements = append(statements, frrconf.Statement{Text: command})
+		}
+	}
+	addresses := make([]string, 0, len(c.peers))
+	for address, peer := range c.peers {
+		addresses = append(addresses, address)
+		if asn == 0 {
+			asn = peer.ASN
+		}
+	}
+	sort.Strings(addresses)
+	if asn != 0 {
+		r := &frrconf.Router{ASN: asn, Statements: statements}
+		for _, address := range addresses {
+			r.Neighbors = append(r.Neighbors, c.peers[address].Neighbor())
+		}
+		b.WriteString(r.String())
+		b.WriteString("!\n")
+	}
+
+	names := make([]string, 0, len(c.policies))
+	for name := range c.policies {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		b.WriteString(strings.TrimSuffix(c.policies[name], "\n") + "\n!\n")
+	}
+	return b.String(), nil
+}
+
+// GetStatus reports the simulated FRR as running bgpd, zebra and bfdd. Its
+// version is unknown.
+func (c *DemoClient) GetStatus(ctx context.Context) (*Status, error) {
+	if !c.IsConnected() {
+		return nil, ErrNotConnected
+	}
+
+	c.mu.Lock()
+	startedAt := c.startedAt
+	c.mu.Unlock()
+
+	status := newStatus("", Daemons)
+	status.Uptime = int64(time.Since(startedAt).Seconds())
+	return status, nil
+}
+
+// ListBGPNeighbors returns the simulated peers
+func (c *DemoClient) ListBGPNeighbors(ctx context.Context) ([]*BGPNeighbor, error) {
+	config, err := c.GetRunningConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return ParseBGPNeighbors(config), nil
+}
+
+// ProbeBGPPeer reports every peer's BGP port as open, after a plausible
+// round trip
+func (c *DemoClient) ProbeBGPPeer(ctx context.Context, config *BGPPeerConfig, timeout time.Duration) (*PeerProbe, error) {
+	probe := func() TCPProbe {
+		return TCPProbe{Result: ProbeOpen, LatencyMS: 1 + rand.Float64()*20}
+	}
+
+	result := &PeerProbe{ProbedFrom: ProbedFromRouter, TCP: probe()}
+	if config.Password != "" {
+		md5 := probe()
+		result.MD5 = &md5
+	}
+	return result, nil
+}
+
+// PinSessionState keeps a simulated peer's session in state, with
+// lastError as the reason it last went down, until UnpinSessionState
+func (c *DemoClient) PinSessionState(ipAddress, state, lastError string) error {
+	return c.state.PinState(ipAddress, state, lastError)
+}
+
+// UnpinSessionState lets a simulated peer's session move again, and
+// establishes it when it is down
+func (c *DemoClient) UnpinSessionState(ipAddress string) error {
+	if err := c.state.UnpinState(ipAddress); err != nil {
+		return err
+	}
+	if session, err := c.state.GetSessionState(ipAddress); err == nil && session.State != bgpsim.StateEstablished {
+		c.state.SimulateSessionEstablishment(ipAddress, c.options.SessionStateDelay)
+	}
+	return nil
+}
+
+// demoPeerState converts a peer's configuration for the simulation
+func demoPeerState(config *BGPPeerConfig) *bgpsim.PeerState {
+	return &bgpsim.PeerState{
+		IPAddress:       config.IPAddress,
+		ASN:             config.ASN,
+		RemoteASN:       config.RemoteASN,
+		Password:        config.Password,
+		Multihop:        int32(config.Multihop),
+		UpdateSource:    config.UpdateSource,
+		RouteMapIn:      config.RouteMapIn,
+		RouteMapOut:     config.RouteMapOut,
+		PrefixListIn:    config.PrefixListIn,
+		PrefixListOut:   config.PrefixListOut,
+		MaxPrefixes:     int32(config.MaxPrefixes),
+		LocalPreference: int32(config.LocalPreference),
+	}
+}
+
+// demoNetwork returns the first prefix the nth simulated peer advertises,
+// in the family of its address. Peers' prefixes don't overlap as long as
+// they advertise fewer than 256.
+func demoNetwork(ipAddress string, n int) string {
+	if addr, err := netip.ParseAddr(ipAddress); err == nil && addr.Is6() && !addr.Is4In6() {
+		return fmt.Sprintf("2001:db8:%x::/48", n%0x10000)
+	}
+	return fmt.Sprintf("10.%d.0.0/24", n%256)
+}
+
+// demoPrefixCount returns how many prefixes a simulated peer advertises,
+// staying below its maximum-prefix limit
+func demoPrefixCount(maxPrefixes int) int {
+	count := 20 + rand.IntN(230)
+	if limit := maxPrefixes * 8 / 10; maxPrefixes > 0 && count > limit {
+		count = max(limit, 1)
+	}
+	return count
+}
+
+// demoSessionState converts a simulated session, with what would be
+// negotiated while it is established
+func demoSessionState(session *bgpsim.SessionState) *BGPSessionState {
+	state := &BGPSessionState{
+		IPAddress:        session.IPAddress,
+		State:            session.State,
+		Uptime:           session.Uptime,
+		PrefixesReceived: int(session.PrefixesReceived),
+		PrefixesSent:     int(session.PrefixesSent),
+		MessagesReceived: session.MessagesReceived,
+		MessagesSent:     session.MessagesSent,
+		LastError:        session.LastError,
+	}
+	if session.State == bgpsim.StateEstablished {
+		state.BGPVersion = 4
+		state.HoldTime = 180
+		state.KeepaliveTime = 60
+		state.FourByteASN = true
+		state.AddressFamilies = []string{"ipv4-unicast"}
+		if addr, err := netip.ParseAddr(session.IPAddress); err == nil && addr.Is4() {
+			state.RemoteRouterID = session.IPAddress
+		} else {
+			state.AddressFamilies = []string{"ipv6-unicast"}
+		}
+	}
+	return state
+}
@@ -0,0 +1,100 @@
+package frr
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestDemoClient(t *testing.T) {
+	ctx := context.Background()
+	client := NewDemoClient(DemoOptions{}, zap.NewNop())
+	peer := &BGPPeerConfig{IPAddress: "192.0.2.1", ASN: 65001, RemoteASN: 65002, MaxPrefixes: 50}
+
+	assert.ErrorIs(t, client.AddBGPPeer(ctx, peer), ErrNotConnected)
+	require.NoError(t, client.Connect(ctx))
+	defer client.Close()
+	assert.True(t, client.IsConnected())
+
+	established := func() bool {
+		state, err := client.GetBGPSessionState(ctx, "192.0.2.1")
+		return err == nil && state.State == "Established"
+	}
+
+	t.Run("Peers establish sessions", func(t *testing.T) {
+		require.NoError(t, client.AddBGPPeer(ctx, peer))
+		require.Eventually(t, established, time.Second, time.Millisecond)
+
+		state, err := client.GetBGPSessionState(ctx, "192.0.2.1")
+		require.NoError(t, err)
+		assert.Positive(t, state.PrefixesReceived)
+		assert.LessOrEqual(t, state.PrefixesReceived, 40, "stays below the maximum-prefix limit")
+		assert.Equal(t, []string{"ipv4-unicast"}, state.AddressFamilies)
+
+		sessions, err := client.GetAllBGPSessions(ctx)
+		require.NoError(t, err)
+		assert.Len(t, sessions, 1)
+
+		_, err = client.GetBGPSessionState(ctx, "192.0.2.9")
+		assert.Error(t, err)
+	})
+
+	t.Run("Running configuration has the peers", func(t *testing.T) {
+		require.NoError(t, client.ApplyBGPGlobal(ctx, &BGPGlobalConfig{ASN: 65001, RouterID: "10.0.0.1"}))
+		require.NoError(t, client.ApplyPolicy(ctx, "route-map", "IMPORT", "route-map IMPORT permit 10\n"))
+
+		running, err := client.GetRunningConfig(ctx)
+		require.NoError(t, err)
+		assert.Contains(t, running, "router bgp 65001\n bgp router-id 10.0.0.1\n")
+		assert.Contains(t, running, " neighbor 192.0.2.1 remote-as 65002\n")
+		assert.Contains(t, running, "route-map IMPORT permit 10\n")
+
+		neighbors, err := client.ListBGPNeighbors(ctx)
+		require.NoError(t, err)
+		require.Len(t, neighbors, 1)
+		assert.Equal(t, uint32(65002), neighbors[0].RemoteASN)
+
+		commands, err := client.CheckBGPPeer(ctx, &BGPPeerConfig{IPAddress: "192.0.2.1", ASN: 65001, RemoteASN: 65003})
+		require.NoError(t, err)
+		assert.Contains(t, commands, "neighbor 192.0.2.1 remote-as 65003")
+	})
+
+	t.Run("Pinned sessions keep their state", func(t *testing.T) {
+		require.NoError(t, client.PinSessionState("192.0.2.1", "Idle", "Hold Timer Expired"))
+		require.NoError(t, client.UpdateBGPPeer(ctx, &BGPPeerConfig{IPAddress: "192.0.2.1", ASN: 65001, RemoteASN: 65003}))
+
+		state, err := client.GetBGPSessionState(ctx, "192.0.2.1")
+		require.NoError(t, err)
+		assert.Equal(t, "Idle", state.State)
+		assert.Equal(t, "Hold Timer Expired", state.LastError)
+
+		require.NoError(t, client.UnpinSessionState("192.0.2.1"))
+		require.Eventually(t, established, time.Second, time.Millisecond)
+	})
+
+	t.Run("Malformed addresses are rejected", func(t *testing.T) {
+		err := client.AddBGPPeer(ctx, &BGPPeerConfig{IPAddress: "192.0.2.300", ASN: 65001, RemoteASN: 65002})
+		configErr, ok := AsConfigError(err)
+		require.True(t, ok)
+		assert.Equal(t, "the address is not a valid IP address", configErr.Message)
+	})
+
+	t.Run("Removed peers lose their session", func(t *testing.T) {
+		require.NoError(t, client.RemoveBGPPeer(ctx, "192.0.2.1"))
+		require.NoError(t, client.RemoveBGPPeer(ctx, "192.0.2.1"))
+
+		sessions, err := client.GetAllBGPSessions(ctx)
+		require.NoError(t, err)
+		assert.Empty(t, sessions)
+	})
+
+	t.Run("Status reports the daemons running", func(t *testing.T) {
+		status, err := client.GetStatus(ctx)
+		require.NoError(t, err)
+		assert.True(t, status.Running("bgpd"))
+	})
+}
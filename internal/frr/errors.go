@@ -0,0 +1,152 @@
+package frr
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ConfigError is FRR's rejection of configuration, translated into what a
+// user can act on. It matches ErrConfigRejected.
+type ConfigError struct {
+	// Message says what is wrong, in terms of FlintRoute's settings where
+	// FRR's message can be recognised
+	Message string `json:"message"`
+	// Command is the statement FRR rejected, when it said which
+	Command string `json:"command,omitempty"`
+	// Line is the line of the rejected statement in a checked
+	// configuration file, starting at 1
+	Line int `json:"line,omitempty"`
+	// Path is the northbound data path of the rejected node
+	Path string `json:"path,omitempty"`
+	// Raw is FRR's error output as received
+	Raw string `json:"raw"`
+}
+
+func (e *ConfigError) Error() string {
+	message := ErrConfigRejected.Error() + ": " + e.Message
+	switch {
+	case e.Line > 0 && e.Command != "":
+		message += fmt.Sprintf(" (line %d: %s)", e.Line, e.Command)
+	case e.Command != "":
+		message += fmt.Sprintf(" (%s)", e.Command)
+	case e.Path != "":
+		message += fmt.Sprintf(" (%s)", e.Path)
+	}
+	return message
+}
+
+// Unwrap makes a ConfigError match ErrConfigRejected
+func (e *ConfigError) Unwrap() error {
+	return ErrConfigRejected
+}
+
+// AsConfigError returns the ConfigError in err's chain, if any
+func AsConfigError(err error) (*ConfigError, bool) {
+	var configErr *ConfigError
+	ok := errors.As(err, &configErr)
+	return configErr, ok
+}
+
+var (
+	// vtyshLinePattern matches the errors vtysh reports for a configuration
+	// file, e.g. "line 12: % Unknown command[4]: neighbor 192.0.2.1 bogus"
+	vtyshLinePattern = regexp.MustCompile(`^line (\d+): (.*)$`)
+	// vtyshCommandPattern matches the statement vtysh quotes after a
+	// parser error, with or without its error code
+	vtyshCommandPattern = regexp.MustCompile(`^% (Unknown command|Command incomplete|Ambiguous command)(?:\[\d+\])?: (.+)$`)
+	// runningASPattern matches bgpd's refusal to start a second instance
+	runningASPattern = regexp.MustCompile(`BGP is already running; AS is (\d+)`)
+	// xpathPattern matches a northbound data path in an error message
+	xpathPattern = regexp.MustCompile(`/frr-[a-z0-9-]+:(?:[^\s\[\](),]|\[[^\]]*\])+`)
+	// pathLabelPattern matches what introduces the path at the end of an
+	// error message once the path is removed, e.g. " (YANG path: "
+	pathLabelPattern = regexp.MustCompile(`(?i)[\s,;:(]*(?:\b(?:yang\s+)?x?path\b)?:?\s*$`)
+)
+
+// frrMessages translates error messages FRR prints, by the text they
+// contain, into what to change
+var frrMessages = []struct {
+	contains string
+	message  string
+}{
+	{"Unknown command", "this FRR version doesn't support the statement"},
+	{"Command incomplete", "the statement is missing a value"},
+	{"Ambiguous command", "the statement is ambiguous"},
+	{"Invalid input detected", "the statement has an invalid value"},
+	{"Specify remote-as or peer-group commands first", "the neighbor has no remote-as; set remote_asn"},
+	{"Create the peer-group or interface first", "the peer-group or interface the neighbor refers to doesn't exist in FRR"},
+	{"Malformed address", "the address is not a valid IP address"},
+	{"Invalid AS number", "the AS number is out of range"},
+}
+
+// translate returns an actionable message for an error message of FRR's,
+// or the message itself without vtysh's "% " marker
+func translate(raw string) string {
+	if match := runningASPattern.FindStringSubmatch(raw); match != nil {
+		return fmt.Sprintf("FRR already runs BGP as AS %s; use it as the peer's asn and set local_as to present another", match[1])
+	}
+	for _, m := range frrMessages {
+		if strings.Contains(raw, m.contains) {
+			return m.message
+		}
+	}
+	return strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(raw), "%"))
+}
+
+// vtyshConfigError parses vtysh's output for configuration it rejected.
+// Its first error is the one reported.
+func vtyshConfigError(output string) *ConfigError {
+	e := &ConfigError{Raw: strings.TrimSpace(output)}
+	for _, line := range strings.Split(e.Raw, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		if match := vtyshLinePattern.FindStringSubmatch(line); match != nil {
+			e.Line, _ = strconv.Atoi(match[1])
+			line = match[2]
+		}
+		if match := vtyshCommandPattern.FindStringSubmatch(line); match != nil {
+			e.Command = match[2]
+		}
+		e.Message = translate(line)
+		break
+	}
+	if e.Message == "" {
+		e.Message = "FRR gave no reason"
+	}
+	return e
+}
+
+// GRPCError translates an error of an FRR northbound gRPC call. Rejected
+// configuration becomes a ConfigError, with the data path FRR named, and
+// an unreachable FRR wraps ErrNotConnected. Other errors are returned as
+// they are.
+func GRPCError(err error) error {
+	st, ok := status.FromError(err)
+	if !ok || err == nil {
+		return err
+	}
+
+	switch st.Code() {
+	case codes.Unavailable, codes.DeadlineExceeded, codes.Canceled:
+		return fmt.Errorf("%w: %s", ErrNotConnected, st.Message())
+	case codes.InvalidArgument, codes.FailedPrecondition, codes.AlreadyExists, codes.NotFound, codes.Aborted:
+		e := &ConfigError{Raw: st.Message(), Path: xpathPattern.FindString(st.Message())}
+		reason := st.Message()
+		if e.Path != "" {
+			// Drop the path, and its label, from the reason
+			reason = strings.TrimRight(strings.Replace(reason, e.Path, "", 1), " )")
+			reason = pathLabelPattern.ReplaceAllString(reason, "")
+		}
+		e.Message = translate(reason)
+		return e
+	}
+	return err
+}
@@ -0,0 +1,86 @@
+package frr
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestVtyshConfigError(t *testing.T) {
+	tests := []struct {
+		name   string
+		output string
+		want   ConfigError
+	}{
+		{
+			name:   "Unknown command in a file",
+			output: "line 12: % Unknown command[4]: neighbor 192.0.2.1 route-map\nline 13: % Unknown command[4]: exit\n",
+			want: ConfigError{
+				Message: "this FRR version doesn't support the statement",
+				Command: "neighbor 192.0.2.1 route-map",
+				Line:    12,
+			},
+		},
+		{
+			name:   "Incomplete command",
+			output: "% Command incomplete: neighbor 192.0.2.1 route-map",
+			want:   ConfigError{Message: "the statement is missing a value", Command: "neighbor 192.0.2.1 route-map"},
+		},
+		{
+			name:   "Second instance",
+			output: "% BGP is already running; AS is 65001",
+			want:   ConfigError{Message: "FRR already runs BGP as AS 65001; use it as the peer's asn and set local_as to present another"},
+		},
+		{
+			name:   "Unrecognised message",
+			output: "% Peer-group does not exist",
+			want:   ConfigError{Message: "Peer-group does not exist"},
+		},
+		{
+			name: "No output",
+			want: ConfigError{Message: "FRR gave no reason"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := vtyshConfigError(tt.output)
+			assert.Equal(t, tt.want.Message, err.Message)
+			assert.Equal(t, tt.want.Command, err.Command)
+			assert.Equal(t, tt.want.Line, err.Line)
+			assert.NotEmpty(t, err.Error())
+			assert.ErrorIs(t, err, ErrConfigRejected)
+		})
+	}
+
+	assert.Equal(t, "FRR rejected the configuration: the statement is missing a value (line 3: neighbor 192.0.2.1 timers)",
+		vtyshConfigError("line 3: % Command incomplete[4]: neighbor 192.0.2.1 timers").Error())
+}
+
+func TestGRPCError(t *testing.T) {
+	path := "/frr-routing:routing/control-plane-protocols/control-plane-protocol[type='frr-bgp:bgp'][name='bgp'][vrf='default']/frr-bgp:bgp/neighbors/neighbor[remote-address='192.0.2.1']/neighbor-remote-as/remote-as-type"
+
+	err := GRPCError(status.Error(codes.InvalidArgument, "Invalid input detected (YANG path: "+path+")"))
+	configErr, ok := AsConfigError(err)
+	require.True(t, ok)
+	assert.Equal(t, path, configErr.Path)
+	assert.Equal(t, "the statement has an invalid value", configErr.Message)
+	assert.Contains(t, configErr.Raw, "YANG path")
+
+	err = GRPCError(status.Error(codes.FailedPrecondition, "neighbor has no remote AS, path: "+path))
+	configErr, ok = AsConfigError(err)
+	require.True(t, ok)
+	assert.Equal(t, "neighbor has no remote AS", configErr.Message)
+
+	assert.ErrorIs(t, GRPCError(status.Error(codes.Unavailable, "connection refused")), ErrNotConnected)
+
+	internal := status.Error(codes.Internal, "boom")
+	assert.Equal(t, internal, GRPCError(internal))
+	plain := errors.New("plain")
+	assert.Equal(t, plain, GRPCError(plain))
+	assert.NoError(t, GRPCError(nil))
+}
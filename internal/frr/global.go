@@ -0,0 +1,159 @@
+package frr
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/padminisys/flintroute/internal/frrconf"
+)
+
+// BGPGlobalConfig holds the settings of FRR's default BGP instance that
+// apply to every neighbor. Zero timers keep FRR's defaults.
+type BGPGlobalConfig struct {
+	ASN      uint32
+	RouterID string
+	// GracefulRestart enables graceful restart (RFC 4724). RestartTime and
+	// StalePathTime are in seconds.
+	GracefulRestart    bool
+	RestartTime        int
+	StalePathTime      int
+	EBGPRequiresPolicy bool
+	LogNeighborChanges bool
+	// Best path selection
+	AlwaysCompareMED  bool
+	CompareRouterID   bool
+	MultipathRelax    bool
+	MEDMissingAsWorst bool
+}
+
+// globalStatements are the router-level statements FlintRoute manages, as
+// FRR shows them in its running configuration. Statements taking an
+// argument end with a space.
+var globalStatements = []string{
+	"bgp router-id ",
+	"bgp graceful-restart",
+	"bgp graceful-restart restart-time ",
+	"bgp graceful-restart stalepath-time ",
+	"bgp always-compare-med",
+	"bgp bestpath compare-routerid",
+	"bgp bestpath as-path multipath-relax",
+	"bgp bestpath med missing-as-worst",
+}
+
+// Commands renders the settings as statements of the "router bgp" block.
+// ebgp-requires-policy and log-neighbor-changes are always set explicitly,
+// since FRR's defaults for them depend on its version and profile.
+func (c *BGPGlobalConfig) Commands() []string {
+	var commands []string
+	add := func(format string, args ...interface{}) {
+		commands = append(commands, fmt.Sprintf(format, args...))
+	}
+	toggle := func(on bool, statement string) {
+		if on {
+			add("%s", statement)
+		} else {
+			add("no %s", statement)
+		}
+	}
+
+	if c.RouterID != "" {
+		add("bgp router-id %s", c.RouterID)
+	}
+	if c.GracefulRestart {
+		add("bgp graceful-restart")
+		if c.RestartTime > 0 {
+			add("bgp graceful-restart restart-time %d", c.RestartTime)
+		}
+		if c.StalePathTime > 0 {
+			add("bgp graceful-restart stalepath-time %d", c.StalePathTime)
+		}
+	}
+	toggle(c.EBGPRequiresPolicy, "bgp ebgp-requires-policy")
+	toggle(c.LogNeighborChanges, "bgp log-neighbor-changes")
+	if c.AlwaysCompareMED {
+		add("bgp always-compare-med")
+	}
+	if c.CompareRouterID {
+		add("bgp bestpath compare-routerid")
+	}
+	if c.MultipathRelax {
+		add("bgp bestpath as-path multipath-relax")
+	}
+	if c.MEDMissingAsWorst {
+		add("bgp bestpath med missing-as-worst")
+	}
+	return commands
+}
+
+// Config renders the settings as a "router bgp" block in FRR's
+// configuration syntax
+func (c *BGPGlobalConfig) Config() string {
+	r := &frrconf.Router{ASN: c.ASN}
+	for _, command := range c.Commands() {
+		r.Statements = append(r.Statements, frrconf.Statement{Text: command})
+	}
+	return r.String()
+}
+
+// IsGlobalStatement reports whether a router-level statement, as FRR shows
+// it, is one BGPGlobalConfig manages
+func IsGlobalStatement(statement string) bool {
+	statement = strings.TrimPrefix(statement, "no ")
+	if statement == "bgp ebgp-requires-policy" || statement == "bgp log-neighbor-changes" {
+		return true
+	}
+	for _, global := range globalStatements {
+		if statement == global || strings.HasSuffix(global, " ") && strings.HasPrefix(statement, global) {
+			return true
+		}
+	}
+	return false
+}
+
+// globalCommands returns the vtysh commands that apply config. Managed
+// statements in running that config no longer has are removed first. A
+// default instance with another ASN is removed with all its neighbors,
+// since FRR can't renumber it.
+func globalCommands(config *BGPGlobalConfig, running string) []string {
+	wanted := config.Commands()
+	keep := make(map[string]bool, len(wanted))
+	for _, command := range wanted {
+		keep[command] = true
+	}
+
+	commands := []string{"configure terminal"}
+	asn, lines := configuredGlobalLines(running)
+	if asn != 0 && asn != config.ASN {
+		commands = append(commands, fmt.Sprintf("no router bgp %d", asn))
+		lines = nil
+	}
+	commands = append(commands, fmt.Sprintf("router bgp %d", config.ASN))
+	for _, line := range lines {
+		if !keep[line] {
+			commands = append(commands, "no "+line)
+		}
+	}
+
+	commands = append(commands, wanted...)
+	return append(commands, "end")
+}
+
+// configuredGlobalLines returns the ASN of the default BGP instance in
+// config, or 0 when there is none, and its statements FlintRoute manages
+func configuredGlobalLines(config string) (uint32, []string) {
+	r := frrconf.Parse(config).BGP
+	if r == nil {
+		return 0, nil
+	}
+
+	var lines []string
+	for _, st := range r.Statements {
+		for _, statement := range globalStatements {
+			if st.Text == statement || strings.HasSuffix(statement, " ") && strings.HasPrefix(st.Text, statement) {
+				lines = append(lines, st.Text)
+				break
+			}
+		}
+	}
+	return r.ASN, lines
+}
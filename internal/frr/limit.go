@@ -0,0 +1,301 @@
+package frr
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"golang.org/x/time/rate"
+)
+
+// ErrCircuitOpen is returned without reaching FRR while the circuit breaker
+// of a LimitedClient is open. It wraps ErrNotConnected, as FRR was found
+// unreachable.
+var ErrCircuitOpen = fmt.Errorf("%w: circuit breaker open", ErrNotConnected)
+
+// Circuit breaker states
+const (
+	CircuitClosed   = "closed"    // operations are sent to FRR
+	CircuitOpen     = "open"      // operations fail with ErrCircuitOpen
+	CircuitHalfOpen = "half_open" // one operation checks whether FRR is back
+)
+
+// LimitOptions configures a LimitedClient. Zero values disable each limit.
+type LimitOptions struct {
+	// Rate caps configuration changes per second. A batch of peers counts
+	// as one change.
+	Rate float64
+	// Burst is how many changes may be sent at once above the rate;
+	// defaults to 1
+	Burst int
+	// MaxConcurrent caps the operations, changes or reads, in flight at
+	// once, whoever sends them
+	MaxConcurrent int
+	// FailureThreshold is how many operations in a row failing to reach
+	// FRR open the circuit breaker
+	FailureThreshold int
+	// OpenDuration is how long the circuit breaker stays open before an
+	// operation is let through to check FRR; defaults to 30 seconds
+	OpenDuration time.Duration
+}
+
+// LimitStats describes a LimitedClient's current state
+type LimitStats struct {
+	Circuit string `json:"circuit"`
+	// InFlight is the number of operations being sent to FRR
+	InFlight int `json:"in_flight"`
+	// Throttled counts the changes that waited for the rate limit
+	Throttled uint64 `json:"throttled"`
+	// Rejected counts the operations failed by the open circuit breaker
+	Rejected uint64 `json:"rejected"`
+}
+
+// LimitedClient protects FRR from bursts of operations, such as those of a
+// bulk import: it paces configuration changes, caps the operations in
+// flight, shared by API requests, the session monitor and the reconciler,
+// and stops sending operations for a while once FRR keeps failing to
+// answer.
+type LimitedClient struct {
+	FRRClient
+	options LimitOptions
+	logger  *zap.Logger
+	rate    *rate.Limiter
+	slots   chan struct{}
+
+	mu          sync.Mutex // guards everything below
+	inFlight    int
+	failures    int
+	circuit     string
+	openedUntil time.Time
+	throttled   uint64
+	rejected    uint64
+}
+
+var _ PeerBatcher = (*LimitedClient)(nil)
+
+// NewLimitedClient wraps client with the limits in opts
+func NewLimitedClient(client FRRClient, opts LimitOptions, logger *zap.Logger) *LimitedClient {
+	if opts.Burst < 1 {
+		opts.Burst = 1
+	}
+	if opts.OpenDuration <= 0 {
+		opts.OpenDuration = 30 * time.Second
+	}
+
+	c := &LimitedClient{FRRClient: client, options: opts, logger: logger, circuit: CircuitClosed}
+	if opts.Rate > 0 {
+		c.rate = rate.NewLimiter(rate.Limit(opts.Rate), opts.Burst)
+	}
+	if opts.MaxConcurrent > 0 {
+		c.slots = make(chan struct{}, opts.MaxConcurrent)
+	}
+	return c
+}
+
+// Stats returns the client's current state
+func (c *LimitedClient) Stats() LimitStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return LimitStats{Circuit: c.circuit, InFlight: c.inFlight, Throttled: c.throttled, Rejected: c.rejected}
+}
+
+// do sends an operation to FRR within the limits. Changes wait for the
+// rate limit; every operation waits for a free slot, then for the circuit
+// breaker.
+func (c *LimitedClient) do(ctx context.Context, change bool, operation func() error) error {
+	if change && c.rate != nil {
+		reservation := c.rate.Reserve()
+		if delay := reservation.Delay(); delay > 0 {
+			c.mu.Lock()
+			c.throttled++
+			c.mu.Unlock()
+
+			timer := time.NewTimer(delay)
+			select {
+			case <-timer.C:
+			case <-ctx.Done():
+				timer.Stop()
+				reservation.Cancel()
+				return ctx.Err()
+			}
+		}
+	}
+
+	if c.slots != nil {
+		select {
+		case c.slots <- struct{}{}:
+			defer func() { <-c.slots }()
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	if err := c.allow(); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	c.inFlight++
+	c.mu.Unlock()
+
+	err := operation()
+
+	c.mu.Lock()
+	c.inFlight--
+	c.mu.Unlock()
+	c.record(err)
+	return err
+}
+
+// allow fails operations while the circuit breaker is open. Once it has
+// been open for OpenDuration, a single operation is let through.
+func (c *LimitedClient) allow() error {
+	if c.options.FailureThreshold <= 0 {
+		return nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	switch {
+	case c.circuit == CircuitClosed:
+		return nil
+	case c.circuit == CircuitOpen && !time.Now().Before(c.openedUntil):
+		c.circuit = CircuitHalfOpen
+		c.logger.Info("Checking whether FRR answers again")
+		return nil
+	default:
+		c.rejected++
+		return ErrCircuitOpen
+	}
+}
+
+// record counts failures to reach FRR, opening the circuit breaker after
+// FailureThreshold of them in a row. Any other outcome, including
+// configuration FRR rejects, closes it.
+func (c *LimitedClient) record(err error) {
+	if c.options.FailureThreshold <= 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err == nil || !errors.Is(err, ErrNotConnected) {
+		if c.circuit != CircuitClosed {
+			c.logger.Info("FRR answers again, closed the circuit breaker")
+		}
+		c.failures = 0
+		c.circuit = CircuitClosed
+		return
+	}
+
+	c.failures++
+	if c.circuit == CircuitHalfOpen || c.failures >= c.options.FailureThreshold {
+		if c.circuit == CircuitClosed {
+			c.logger.Warn("FRR keeps failing to answer, opened the circuit breaker",
+				zap.Int("failures", c.failures),
+				zap.Duration("open_for", c.options.OpenDuration),
+				zap.Error(err),
+			)
+		}
+		c.circuit = CircuitOpen
+		c.openedUntil = time.Now().Add(c.options.OpenDuration)
+	}
+}
+
+func (c *LimitedClient) AddBGPPeer(ctx context.Context, config *BGPPeerConfig) error {
+	return c.do(ctx, true, func() error { return c.FRRClient.AddBGPPeer(ctx, config) })
+}
+
+func (c *LimitedClient) RemoveBGPPeer(ctx context.Context, ipAddress string) error {
+	return c.do(ctx, true, func() error { return c.FRRClient.RemoveBGPPeer(ctx, ipAddress) })
+}
+
+func (c *LimitedClient) UpdateBGPPeer(ctx context.Context, config *BGPPeerConfig) error {
+	return c.do(ctx, true, func() error { return c.FRRClient.UpdateBGPPeer(ctx, config) })
+}
+
+// UpdateBGPPeers applies the peers as one change when the wrapped client
+// commits them together, and as a change per peer otherwise
+func (c *LimitedClient) UpdateBGPPeers(ctx context.Context, configs []*BGPPeerConfig) error {
+	batcher, ok := c.FRRClient.(PeerBatcher)
+	if !ok {
+		for _, config := range configs {
+			if err := c.UpdateBGPPeer(ctx, config); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return c.do(ctx, true, func() error { return batcher.UpdateBGPPeers(ctx, configs) })
+}
+
+func (c *LimitedClient) CheckBGPPeer(ctx context.Context, config *BGPPeerConfig) (commands []string, err error) {
+	err = c.do(ctx, false, func() error {
+		commands, err = c.FRRClient.CheckBGPPeer(ctx, config)
+		return err
+	})
+	return commands, err
+}
+
+func (c *LimitedClient) ApplyBGPGlobal(ctx context.Context, config *BGPGlobalConfig) error {
+	return c.do(ctx, true, func() error { return c.FRRClient.ApplyBGPGlobal(ctx, config) })
+}
+
+func (c *LimitedClient) ApplyPolicy(ctx context.Context, kind, name, config string) error {
+	return c.do(ctx, true, func() error { return c.FRRClient.ApplyPolicy(ctx, kind, name, config) })
+}
+
+func (c *LimitedClient) RemovePolicy(ctx context.Context, kind, name string) error {
+	return c.do(ctx, true, func() error { return c.FRRClient.RemovePolicy(ctx, kind, name) })
+}
+
+func (c *LimitedClient) GetBGPSessionState(ctx context.Context, ipAddress string) (state *BGPSessionState, err error) {
+	err = c.do(ctx, false, func() error {
+		state, err = c.FRRClient.GetBGPSessionState(ctx, ipAddress)
+		return err
+	})
+	return state, err
+}
+
+func (c *LimitedClient) GetAllBGPSessions(ctx context.Context) (sessions []*BGPSessionState, err error) {
+	err = c.do(ctx, false, func() error {
+		sessions, err = c.FRRClient.GetAllBGPSessions(ctx)
+		return err
+	})
+	return sessions, err
+}
+
+func (c *LimitedClient) GetRunningConfig(ctx context.Context) (config string, err error) {
+	err = c.do(ctx, false, func() error {
+		config, err = c.FRRClient.GetRunningConfig(ctx)
+		return err
+	})
+	return config, err
+}
+
+func (c *LimitedClient) GetStatus(ctx context.Context) (status *Status, err error) {
+	err = c.do(ctx, false, func() error {
+		status, err = c.FRRClient.GetStatus(ctx)
+		return err
+	})
+	return status, err
+}
+
+func (c *LimitedClient) ListBGPNeighbors(ctx context.Context) (neighbors []*BGPNeighbor, err error) {
+	err = c.do(ctx, false, func() error {
+		neighbors, err = c.FRRClient.ListBGPNeighbors(ctx)
+		return err
+	})
+	return neighbors, err
+}
+
+func (c *LimitedClient) ProbeBGPPeer(ctx context.Context, config *BGPPeerConfig, timeout time.Duration) (probe *PeerProbe, err error) {
+	err = c.do(ctx, false, func() error {
+		probe, err = c.FRRClient.ProbeBGPPeer(ctx, config, timeout)
+		return err
+	})
+	return probe, err
+}
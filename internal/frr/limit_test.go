@@ -0,0 +1,147 @@
+package frr
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestLimitedClient(t *testing.T) {
+	ctx := context.Background()
+	peer := &BGPPeerConfig{IPAddress: "192.0.2.1", ASN: 65001, RemoteASN: 65002}
+
+	t.Run("Paces changes but not reads", func(t *testing.T) {
+		mockClient := NewMockClient()
+		mockClient.On("AddBGPPeer", mock.Anything, peer).Return(nil)
+		mockClient.On("GetRunningConfig", mock.Anything).Return("", nil)
+		client := NewLimitedClient(mockClient, LimitOptions{Rate: 20, Burst: 1}, zap.NewNop())
+
+		start := time.Now()
+		for i := 0; i < 3; i++ {
+			require.NoError(t, client.AddBGPPeer(ctx, peer))
+			_, err := client.GetRunningConfig(ctx)
+			require.NoError(t, err)
+		}
+		assert.GreaterOrEqual(t, time.Since(start), 90*time.Millisecond)
+		assert.Equal(t, uint64(2), client.Stats().Throttled)
+	})
+
+	t.Run("Canceled while throttled", func(t *testing.T) {
+		mockClient := NewMockClient()
+		mockClient.On("AddBGPPeer", mock.Anything, peer).Return(nil).Once()
+		client := NewLimitedClient(mockClient, LimitOptions{Rate: 0.1, Burst: 1}, zap.NewNop())
+
+		require.NoError(t, client.AddBGPPeer(ctx, peer))
+		canceled, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+		defer cancel()
+		assert.ErrorIs(t, client.AddBGPPeer(canceled, peer), context.DeadlineExceeded)
+		mockClient.AssertExpectations(t)
+	})
+
+	t.Run("Caps operations in flight", func(t *testing.T) {
+		release := make(chan struct{})
+		mockClient := NewMockClient()
+		mockClient.On("GetAllBGPSessions", mock.Anything).Run(func(mock.Arguments) { <-release }).Return([]*BGPSessionState{}, nil)
+		client := NewLimitedClient(mockClient, LimitOptions{MaxConcurrent: 2}, zap.NewNop())
+
+		var wg sync.WaitGroup
+		for i := 0; i < 3; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_, err := client.GetAllBGPSessions(ctx)
+				assert.NoError(t, err)
+			}()
+		}
+		require.Eventually(t, func() bool { return client.Stats().InFlight == 2 }, time.Second, time.Millisecond)
+		time.Sleep(10 * time.Millisecond)
+		assert.Equal(t, 2, client.Stats().InFlight)
+
+		close(release)
+		wg.Wait()
+		assert.Equal(t, 0, client.Stats().InFlight)
+		mockClient.AssertNumberOfCalls(t, "GetAllBGPSessions", 3)
+	})
+
+	t.Run("Circuit breaker", func(t *testing.T) {
+		unreachable := NewMockClient()
+		unreachable.On("GetRunningConfig", mock.Anything).Return("", ErrNotConnected).Times(2)
+		unreachable.On("GetRunningConfig", mock.Anything).Return("router bgp 65001\n", nil).Once()
+		client := NewLimitedClient(unreachable, LimitOptions{FailureThreshold: 2, OpenDuration: 20 * time.Millisecond}, zap.NewNop())
+
+		for i := 0; i < 2; i++ {
+			_, err := client.GetRunningConfig(ctx)
+			assert.ErrorIs(t, err, ErrNotConnected)
+		}
+		assert.Equal(t, CircuitOpen, client.Stats().Circuit)
+
+		_, err := client.GetRunningConfig(ctx)
+		assert.ErrorIs(t, err, ErrCircuitOpen)
+		assert.ErrorIs(t, err, ErrNotConnected)
+		assert.Equal(t, uint64(1), client.Stats().Rejected)
+		unreachable.AssertNumberOfCalls(t, "GetRunningConfig", 2)
+
+		time.Sleep(25 * time.Millisecond)
+		config, err := client.GetRunningConfig(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, "router bgp 65001\n", config)
+		assert.Equal(t, CircuitClosed, client.Stats().Circuit)
+	})
+
+	t.Run("Circuit breaker reopens when the check fails", func(t *testing.T) {
+		unreachable := NewMockClient()
+		unreachable.On("GetStatus", mock.Anything).Return(nil, ErrNotConnected)
+		client := NewLimitedClient(unreachable, LimitOptions{FailureThreshold: 1, OpenDuration: 10 * time.Millisecond}, zap.NewNop())
+
+		_, err := client.GetStatus(ctx)
+		assert.ErrorIs(t, err, ErrNotConnected)
+		time.Sleep(15 * time.Millisecond)
+		_, err = client.GetStatus(ctx)
+		assert.NotErrorIs(t, err, ErrCircuitOpen)
+		assert.Equal(t, CircuitOpen, client.Stats().Circuit)
+		_, err = client.GetStatus(ctx)
+		assert.ErrorIs(t, err, ErrCircuitOpen)
+	})
+
+	t.Run("Rejected configuration keeps the circuit closed", func(t *testing.T) {
+		mockClient := NewMockClient()
+		mockClient.On("UpdateBGPPeer", mock.Anything, peer).Return(errors.New("% Unknown command"))
+		client := NewLimitedClient(mockClient, LimitOptions{FailureThreshold: 1}, zap.NewNop())
+
+		for i := 0; i < 3; i++ {
+			assert.Error(t, client.UpdateBGPPeer(ctx, peer))
+		}
+		assert.Equal(t, CircuitClosed, client.Stats().Circuit)
+	})
+
+	t.Run("Batch counts as one change", func(t *testing.T) {
+		fake := &fakeVtysh{outputs: map[string]string{"show running-config": vtyshRunningConfig}}
+		client := NewLimitedClient(newTestVtyshClient(fake), LimitOptions{Rate: 0.1, Burst: 1}, zap.NewNop())
+
+		timeout, cancel := context.WithTimeout(ctx, time.Second)
+		defer cancel()
+		require.NoError(t, client.UpdateBGPPeers(timeout, []*BGPPeerConfig{
+			peer,
+			{IPAddress: "192.0.2.2", ASN: 65001, RemoteASN: 65003},
+		}))
+		assert.Zero(t, client.Stats().Throttled)
+	})
+
+	t.Run("Batch without a batcher counts a change per peer", func(t *testing.T) {
+		other := &BGPPeerConfig{IPAddress: "192.0.2.2", ASN: 65001, RemoteASN: 65003}
+		mockClient := NewMockClient()
+		mockClient.On("UpdateBGPPeer", mock.Anything, mock.Anything).Return(nil)
+		client := NewLimitedClient(mockClient, LimitOptions{Rate: 50, Burst: 1}, zap.NewNop())
+
+		require.NoError(t, client.UpdateBGPPeers(ctx, []*BGPPeerConfig{peer, other}))
+		assert.Equal(t, uint64(1), client.Stats().Throttled)
+		mockClient.AssertNumberOfCalls(t, "UpdateBGPPeer", 2)
+	})
+}
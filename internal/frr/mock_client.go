@@ -2,6 +2,7 @@ package frr
 
 import (
 	"context"
+	"time"
 
 	"github.com/stretchr/testify/mock"
 )
@@ -52,6 +53,33 @@ func (m *MockClient) UpdateBGPPeer(ctx context.Context, config *BGPPeerConfig) e
 	return args.Error(0)
 }
 
+// CheckBGPPeer mocks the CheckBGPPeer method
+func (m *MockClient) CheckBGPPeer(ctx context.Context, config *BGPPeerConfig) ([]string, error) {
+	args := m.Called(ctx, config)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]string), args.Error(1)
+}
+
+// ApplyBGPGlobal mocks the ApplyBGPGlobal method
+func (m *MockClient) ApplyBGPGlobal(ctx context.Context, config *BGPGlobalConfig) error {
+	args := m.Called(ctx, config)
+	return args.Error(0)
+}
+
+// ApplyPolicy mocks the ApplyPolicy method
+func (m *MockClient) ApplyPolicy(ctx context.Context, kind, name, config string) error {
+	args := m.Called(ctx, kind, name, config)
+	return args.Error(0)
+}
+
+// RemovePolicy mocks the RemovePolicy method
+func (m *MockClient) RemovePolicy(ctx context.Context, kind, name string) error {
+	args := m.Called(ctx, kind, name)
+	return args.Error(0)
+}
+
 // GetBGPSessionState mocks the GetBGPSessionState method
 func (m *MockClient) GetBGPSessionState(ctx context.Context, ipAddress string) (*BGPSessionState, error) {
 	args := m.Called(ctx, ipAddress)
@@ -74,4 +102,31 @@ func (m *MockClient) GetAllBGPSessions(ctx context.Context) ([]*BGPSessionState,
 func (m *MockClient) GetRunningConfig(ctx context.Context) (string, error) {
 	args := m.Called(ctx)
 	return args.String(0), args.Error(1)
-}
\ No newline at end of file
+}
+
+// GetStatus mocks the GetStatus method
+func (m *MockClient) GetStatus(ctx context.Context) (*Status, error) {
+	args := m.Called(ctx)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*Status), args.Error(1)
+}
+
+// ProbeBGPPeer mocks the ProbeBGPPeer method
+func (m *MockClient) ProbeBGPPeer(ctx context.Context, config *BGPPeerConfig, timeout time.Duration) (*PeerProbe, error) {
+	args := m.Called(ctx, config, timeout)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*PeerProbe), args.Error(1)
+}
+
+// ListBGPNeighbors mocks the ListBGPNeighbors method
+func (m *MockClient) ListBGPNeighbors(ctx context.Context) ([]*BGPNeighbor, error) {
+	args := m.Called(ctx)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*BGPNeighbor), args.Error(1)
+}
@@ -0,0 +1,94 @@
+package frr
+
+import "github.com/padminisys/flintroute/internal/frrconf"
+
+// NeighborOptions holds per-neighbor BGP timers and behaviour. Zero values
+// keep FRR's defaults.
+type NeighborOptions struct {
+	// Keepalive and HoldTime are in seconds and only applied together
+	Keepalive           int
+	HoldTime            int
+	ConnectRetry        int
+	Passive             bool
+	TTLSecurityHops     int
+	NextHopSelf         bool
+	SoftReconfigInbound bool
+	RemovePrivateAS     bool
+	AllowASIn           int
+	// LocalAS is presented to the neighbor instead of the router's AS.
+	// LocalASReplaceAS requires LocalASNoPrepend.
+	LocalAS          uint32
+	LocalASNoPrepend bool
+	LocalASReplaceAS bool
+	// MaxPrefixThreshold is the percentage of the maximum-prefix limit
+	// that logs a warning. With MaxPrefixWarningOnly the session is kept
+	// when the limit is exceeded; otherwise it is shut down and, with
+	// MaxPrefixRestart, restarted after that many minutes.
+	MaxPrefixThreshold   int
+	MaxPrefixWarningOnly bool
+	MaxPrefixRestart     int
+}
+
+// neighbor returns the options as ip's frrconf settings
+func (o *NeighborOptions) neighbor(ip string) *frrconf.Neighbor {
+	return &frrconf.Neighbor{
+		Name:                 ip,
+		Keepalive:            o.Keepalive,
+		HoldTime:             o.HoldTime,
+		ConnectRetry:         o.ConnectRetry,
+		Passive:              o.Passive,
+		TTLSecurityHops:      o.TTLSecurityHops,
+		NextHopSelf:          o.NextHopSelf,
+		SoftReconfigInbound:  o.SoftReconfigInbound,
+		RemovePrivateAS:      o.RemovePrivateAS,
+		AllowASIn:            o.AllowASIn,
+		LocalAS:              o.LocalAS,
+		LocalASNoPrepend:     o.LocalASNoPrepend,
+		LocalASReplaceAS:     o.LocalASReplaceAS,
+		MaxPrefixThreshold:   o.MaxPrefixThreshold,
+		MaxPrefixWarningOnly: o.MaxPrefixWarningOnly,
+		MaxPrefixRestart:     o.MaxPrefixRestart,
+	}
+}
+
+// Commands renders the options as FRR "neighbor" statements for ip
+func (o *NeighborOptions) Commands(ip string) []string {
+	return o.neighbor(ip).OptionCommands()
+}
+
+// MaximumPrefixCommand renders the "neighbor maximum-prefix" statement for
+// ip, or "" when limit is 0
+func (o *NeighborOptions) MaximumPrefixCommand(ip string, limit int) string {
+	n := o.neighbor(ip)
+	n.MaxPrefixes = limit
+	return n.MaximumPrefixCommand()
+}
+
+// Neighbor returns the peer's settings as an frrconf neighbor
+func (c *BGPPeerConfig) Neighbor() *frrconf.Neighbor {
+	n := c.NeighborOptions.neighbor(c.IPAddress)
+	n.RemoteASN = c.RemoteASN
+	n.Description = c.Description
+	n.Password = c.Password
+	n.Multihop = c.Multihop
+	n.UpdateSource = c.UpdateSource
+	n.RouteMapIn = c.RouteMapIn
+	n.RouteMapOut = c.RouteMapOut
+	n.PrefixListIn = c.PrefixListIn
+	n.PrefixListOut = c.PrefixListOut
+	n.MaxPrefixes = c.MaxPrefixes
+	return n
+}
+
+// NeighborCommands renders the peer as FRR "neighbor" statements, starting
+// with remote-as
+func (c *BGPPeerConfig) NeighborCommands() []string {
+	return c.Neighbor().Commands()
+}
+
+// Config renders the peer as a "router bgp" block in FRR's configuration
+// syntax
+func (c *BGPPeerConfig) Config() string {
+	r := &frrconf.Router{ASN: c.ASN, Neighbors: []*frrconf.Neighbor{c.Neighbor()}}
+	return r.String()
+}
@@ -0,0 +1,119 @@
+package frr
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"golang.org/x/net/icmp"
+	"golang.org/x/net/ipv4"
+	"golang.org/x/net/ipv6"
+)
+
+// ErrPingUnsupported is returned when neither unprivileged ping sockets
+// (net.ipv4.ping_group_range) nor raw sockets (CAP_NET_RAW) are available
+var ErrPingUnsupported = errors.New("ICMP echo is not permitted on this host")
+
+// Protocol numbers of ICMP messages, as icmp.ParseMessage takes them
+const (
+	protocolICMP     = 1
+	protocolIPv6ICMP = 58
+)
+
+// Ping sends one ICMP echo request to ip and waits up to timeout for the
+// reply, returning the round trip time. It prefers unprivileged ping
+// sockets and falls back to raw sockets. A reply not received in time is
+// an error matching os.ErrDeadlineExceeded.
+func Ping(ctx context.Context, ip string, timeout time.Duration) (time.Duration, error) {
+	addr := net.ParseIP(ip)
+	if addr == nil {
+		return 0, fmt.Errorf("invalid IP address %q", ip)
+	}
+
+	protocol, echo, reply := protocolICMP, icmp.Type(ipv4.ICMPTypeEcho), icmp.Type(ipv4.ICMPTypeEchoReply)
+	networks := [][2]string{{"udp4", "0.0.0.0"}, {"ip4:icmp", "0.0.0.0"}}
+	if addr.To4() == nil {
+		protocol, echo, reply = protocolIPv6ICMP, ipv6.ICMPTypeEchoRequest, ipv6.ICMPTypeEchoReply
+		networks = [][2]string{{"udp6", "::"}, {"ip6:ipv6-icmp", "::"}}
+	}
+
+	var conn *icmp.PacketConn
+	var dst net.Addr
+	for _, network := range networks {
+		c, err := icmp.ListenPacket(network[0], network[1])
+		if err != nil {
+			continue
+		}
+		conn = c
+		if strings.HasPrefix(network[0], "udp") {
+			dst = &net.UDPAddr{IP: addr}
+		} else {
+			dst = &net.IPAddr{IP: addr}
+		}
+		break
+	}
+	if conn == nil {
+		return 0, ErrPingUnsupported
+	}
+	defer conn.Close()
+
+	// Unprivileged sockets replace the identifier with their port, so
+	// replies are matched by sequence number and payload
+	var token [10]byte
+	if _, err := rand.Read(token[:]); err != nil {
+		return 0, err
+	}
+	id := int(binary.BigEndian.Uint16(token[0:2]))
+	seq := int(binary.BigEndian.Uint16(token[2:4]))
+	payload := append([]byte("flintroute"), token[4:]...)
+	message, err := (&icmp.Message{
+		Type: echo,
+		Body: &icmp.Echo{ID: id, Seq: seq, Data: payload},
+	}).Marshal(nil)
+	if err != nil {
+		return 0, err
+	}
+
+	deadline := time.Now().Add(timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	if err := conn.SetDeadline(deadline); err != nil {
+		return 0, err
+	}
+	stop := context.AfterFunc(ctx, func() { conn.SetDeadline(time.Now()) })
+	defer stop()
+
+	start := time.Now()
+	if _, err := conn.WriteTo(message, dst); err != nil {
+		return 0, err
+	}
+	buf := make([]byte, 1500)
+	for {
+		n, _, err := conn.ReadFrom(buf)
+		if err != nil {
+			if ctx.Err() != nil {
+				return 0, ctx.Err()
+			}
+			return 0, err
+		}
+		received, err := icmp.ParseMessage(protocol, buf[:n])
+		if err != nil || received.Type != reply {
+			continue
+		}
+		if body, ok := received.Body.(*icmp.Echo); ok && body.Seq == seq && string(body.Data) == string(payload) {
+			return time.Since(start), nil
+		}
+	}
+}
+
+// ProbeTCP makes one connection attempt to port on ip, from whatever local
+// address the kernel picks
+func ProbeTCP(ctx context.Context, ip string, port int, timeout time.Duration) TCPProbe {
+	return probeTCP(ctx, ip, port, nil, "", timeout)
+}
@@ -0,0 +1,129 @@
+package frr
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// BGPPort is the TCP port BGP speakers listen on
+const BGPPort = 179
+
+// Where a peer probe ran from
+const (
+	// ProbedFromRouter means the probe ran on the FRR host, as FRR would
+	// connect
+	ProbedFromRouter = "router"
+	// ProbedFromFlintRoute means the probe ran on the FlintRoute host,
+	// which may not be the router
+	ProbedFromFlintRoute = "flintroute"
+)
+
+// Outcomes of a TCP probe
+const (
+	// ProbeOpen means the peer accepted the connection
+	ProbeOpen = "open"
+	// ProbeRefused means the peer host answered but nothing accepted
+	// connections on the BGP port
+	ProbeRefused = "refused"
+	// ProbeTimeout means nothing answered in time, e.g. a firewall drops
+	// the SYNs or an MD5 signature is missing or unexpected
+	ProbeTimeout = "timeout"
+	// ProbeUnreachable means there is no route to the peer
+	ProbeUnreachable = "unreachable"
+	// ProbeUnsupported means the probe couldn't run on this platform
+	ProbeUnsupported = "unsupported"
+	// ProbeError is any other failure
+	ProbeError = "error"
+)
+
+// PeerProbe is the outcome of pre-flight connection attempts to a peer's
+// BGP port
+type PeerProbe struct {
+	ProbedFrom string `json:"probed_from"`
+	// SourceAddress is the local address connections were made from, when
+	// the peer has an update source address
+	SourceAddress string `json:"source_address,omitempty"`
+	// TCP is a plain connection attempt
+	TCP TCPProbe `json:"tcp"`
+	// MD5 is an attempt signed with the peer's password, only made for
+	// peers with one
+	MD5 *TCPProbe `json:"md5,omitempty"`
+}
+
+// TCPProbe is the outcome of one connection attempt
+type TCPProbe struct {
+	Result string `json:"result"`
+	// LatencyMS is how long the peer took to accept or refuse the connection
+	LatencyMS float64 `json:"latency_ms,omitempty"`
+	Error     string  `json:"error,omitempty"`
+}
+
+// probePeer connects to the BGP port of config's peer and closes the
+// connection straight away, once plainly and, for peers with a password,
+// once with a TCP MD5 signature. Both attempts run at the same time and give
+// up after timeout.
+func probePeer(ctx context.Context, config *BGPPeerConfig, probedFrom string, timeout time.Duration) *PeerProbe {
+	result := &PeerProbe{ProbedFrom: probedFrom}
+	var source net.IP
+	if ip := net.ParseIP(config.UpdateSource); ip != nil {
+		source = ip
+		result.SourceAddress = ip.String()
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		result.TCP = probeTCP(ctx, config.IPAddress, BGPPort, source, "", timeout)
+	}()
+	if config.Password != "" {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			md5 := probeTCP(ctx, config.IPAddress, BGPPort, source, config.Password, timeout)
+			result.MD5 = &md5
+		}()
+	}
+	wg.Wait()
+	return result
+}
+
+// probeTCP makes one connection attempt to port on ip, signed with password
+// when it isn't empty
+func probeTCP(ctx context.Context, ip string, port int, source net.IP, password string, timeout time.Duration) TCPProbe {
+	dialer := net.Dialer{Timeout: timeout}
+	if source != nil {
+		dialer.LocalAddr = &net.TCPAddr{IP: source}
+	}
+	if password != "" {
+		if !md5Supported {
+			return TCPProbe{Result: ProbeUnsupported, Error: "TCP MD5 signatures are not supported on this platform"}
+		}
+		dialer.Control = md5Control(ip, password)
+	}
+
+	start := time.Now()
+	conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(ip, strconv.Itoa(port)))
+	latency := float64(time.Since(start).Microseconds()) / 1000
+	if err == nil {
+		conn.Close()
+		return TCPProbe{Result: ProbeOpen, LatencyMS: latency}
+	}
+
+	var netErr net.Error
+	switch {
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return TCPProbe{Result: ProbeRefused, LatencyMS: latency}
+	case errors.Is(err, syscall.EHOSTUNREACH), errors.Is(err, syscall.ENETUNREACH):
+		return TCPProbe{Result: ProbeUnreachable, Error: err.Error()}
+	case errors.As(err, &netErr) && netErr.Timeout(), errors.Is(err, context.DeadlineExceeded):
+		return TCPProbe{Result: ProbeTimeout}
+	default:
+		return TCPProbe{Result: ProbeError, Error: err.Error()}
+	}
+}
@@ -0,0 +1,48 @@
+package frr
+
+import (
+	"fmt"
+	"net"
+	"syscall"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// md5Supported reports whether probes can sign connections with TCP MD5
+const md5Supported = true
+
+// md5Control returns a dialer control function that signs the connection to
+// peer with password (RFC 2385), as FRR does for neighbors with a password
+func md5Control(peer, password string) func(network, address string, c syscall.RawConn) error {
+	return func(network, address string, c syscall.RawConn) error {
+		var sig unix.TCPMD5Sig
+		if len(password) > len(sig.Key) {
+			return fmt.Errorf("password is longer than %d characters", len(sig.Key))
+		}
+		sig.Keylen = uint16(copy(sig.Key[:], password))
+
+		ip := net.ParseIP(peer)
+		if ip4 := ip.To4(); ip4 != nil {
+			addr := (*unix.RawSockaddrInet4)(unsafe.Pointer(&sig.Addr))
+			addr.Family = unix.AF_INET
+			copy(addr.Addr[:], ip4)
+		} else {
+			addr := (*unix.RawSockaddrInet6)(unsafe.Pointer(&sig.Addr))
+			addr.Family = unix.AF_INET6
+			copy(addr.Addr[:], ip.To16())
+		}
+
+		var sockErr error
+		err := c.Control(func(fd uintptr) {
+			sockErr = unix.SetsockoptTCPMD5Sig(int(fd), unix.IPPROTO_TCP, unix.TCP_MD5SIG, &sig)
+		})
+		if err != nil {
+			return err
+		}
+		if sockErr != nil {
+			return fmt.Errorf("failed to set TCP MD5 signature: %w", sockErr)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,53 @@
+package frr
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProbeTCPMD5(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Listener without the password drops signed connections", func(t *testing.T) {
+		_, port := listen(t)
+
+		probe := probeTCP(ctx, "127.0.0.1", port, nil, "secret", 200*time.Millisecond)
+		if probe.Result == ProbeError {
+			t.Skipf("TCP MD5 signatures unavailable: %s", probe.Error)
+		}
+		assert.Equal(t, ProbeTimeout, probe.Result)
+	})
+
+	t.Run("Listener with the password accepts them", func(t *testing.T) {
+		listener, port := listen(t)
+		raw, err := listener.(*net.TCPListener).SyscallConn()
+		require.NoError(t, err)
+		// The listener expects connections from 127.0.0.1 to be signed
+		if err := md5Control("127.0.0.1", "secret")("tcp", "", raw); err != nil {
+			t.Skipf("TCP MD5 signatures unavailable on listeners: %s", err)
+		}
+
+		probe := probeTCP(ctx, "127.0.0.1", port, nil, "secret", time.Second)
+		assert.Equal(t, ProbeOpen, probe.Result)
+
+		probe = probeTCP(ctx, "127.0.0.1", port, nil, "", 200*time.Millisecond)
+		assert.Equal(t, ProbeTimeout, probe.Result)
+	})
+
+	t.Run("Password too long", func(t *testing.T) {
+		_, port := listen(t)
+		long := make([]byte, 81)
+		for i := range long {
+			long[i] = 'x'
+		}
+
+		probe := probeTCP(ctx, "127.0.0.1", port, nil, string(long), time.Second)
+		assert.Equal(t, ProbeError, probe.Result)
+		assert.Contains(t, probe.Error, "longer than 80")
+	})
+}
@@ -0,0 +1,13 @@
+//go:build !linux
+
+package frr
+
+import "syscall"
+
+// md5Supported reports whether probes can sign connections with TCP MD5
+const md5Supported = false
+
+// md5Control is never called where TCP MD5 signatures aren't supported
+func md5Control(peer, password string) func(network, address string, c syscall.RawConn) error {
+	return nil
+}
@@ -0,0 +1,91 @@
+package frr
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// listen returns a loopback listener accepting connections until the test
+// ends, and its port
+func listen(t *testing.T) (net.Listener, int) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+	return listener, listener.Addr().(*net.TCPAddr).Port
+}
+
+func TestProbeTCP(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Open port", func(t *testing.T) {
+		_, port := listen(t)
+
+		probe := probeTCP(ctx, "127.0.0.1", port, net.ParseIP("127.0.0.1"), "", time.Second)
+		assert.Equal(t, ProbeOpen, probe.Result)
+		assert.Empty(t, probe.Error)
+	})
+
+	t.Run("Closed port", func(t *testing.T) {
+		listener, port := listen(t)
+		listener.Close()
+
+		probe := probeTCP(ctx, "127.0.0.1", port, nil, "", time.Second)
+		assert.Equal(t, ProbeRefused, probe.Result)
+	})
+
+	t.Run("Source address that isn't local", func(t *testing.T) {
+		_, port := listen(t)
+
+		probe := probeTCP(ctx, "127.0.0.1", port, net.ParseIP("192.0.2.1"), "", time.Second)
+		assert.Equal(t, ProbeError, probe.Result)
+		assert.NotEmpty(t, probe.Error)
+	})
+}
+
+func TestProbePeer(t *testing.T) {
+	// Nothing listens on the BGP port of the documentation address, and
+	// the sandbox may have no route to it at all
+	probe := probePeer(context.Background(), &BGPPeerConfig{
+		IPAddress:    "192.0.2.1",
+		UpdateSource: "lo",
+		Password:     "secret",
+	}, ProbedFromRouter, 100*time.Millisecond)
+
+	assert.Equal(t, ProbedFromRouter, probe.ProbedFrom)
+	assert.Empty(t, probe.SourceAddress)
+	assert.NotEqual(t, ProbeOpen, probe.TCP.Result)
+	require.NotNil(t, probe.MD5)
+	assert.NotEqual(t, ProbeOpen, probe.MD5.Result)
+
+	probe = probePeer(context.Background(), &BGPPeerConfig{IPAddress: "192.0.2.1"}, ProbedFromFlintRoute, 100*time.Millisecond)
+	assert.Nil(t, probe.MD5)
+}
+
+func TestPing(t *testing.T) {
+	ctx := context.Background()
+
+	rtt, err := Ping(ctx, "127.0.0.1", time.Second)
+	if errors.Is(err, ErrPingUnsupported) {
+		t.Skip("ICMP echo is not permitted here")
+	}
+	require.NoError(t, err)
+	assert.Positive(t, rtt)
+
+	_, err = Ping(ctx, "not-an-ip", time.Second)
+	assert.Error(t, err)
+}
@@ -0,0 +1,127 @@
+package frr
+
+import (
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Daemons are the FRR daemons a status always reports, running or not
+var Daemons = []string{"bgpd", "zebra", "bfdd"}
+
+// Status describes the FRR instance FlintRoute manages
+type Status struct {
+	// Version is FRR's version, such as "9.1"; empty when unknown
+	Version string `json:"version,omitempty"`
+	// Uptime is how long FRR has run, in seconds; 0 when the transport
+	// doesn't report it
+	Uptime int64 `json:"uptime,omitempty"`
+	// Daemons lists the daemons FRR runs, and those of Daemons it doesn't,
+	// sorted by name
+	Daemons []DaemonStatus `json:"daemons"`
+	// Capabilities are the YANG modules the northbound supports; empty
+	// when the transport doesn't report them
+	Capabilities []string `json:"capabilities,omitempty"`
+}
+
+// DaemonStatus is whether an FRR daemon is running
+type DaemonStatus struct {
+	Name    string `json:"name"`
+	Running bool   `json:"running"`
+}
+
+// Running reports whether the named daemon is running
+func (s *Status) Running(name string) bool {
+	for _, daemon := range s.Daemons {
+		if daemon.Name == name {
+			return daemon.Running
+		}
+	}
+	return false
+}
+
+// AtLeast reports whether FRR's version is minimum or newer. An unknown
+// version is never new enough.
+func (s *Status) AtLeast(minimum string) bool {
+	if s.Version == "" {
+		return false
+	}
+	return CompareVersions(s.Version, minimum) >= 0
+}
+
+// CompareVersions compares two dotted FRR versions numerically, returning
+// -1, 0 or 1. Missing components count as 0 and suffixes such as "-dev"
+// are ignored, so "9.1-dev" equals "9.1.0".
+func CompareVersions(a, b string) int {
+	as, bs := versionParts(a), versionParts(b)
+	for len(as) < len(bs) {
+		as = append(as, 0)
+	}
+	for len(bs) < len(as) {
+		bs = append(bs, 0)
+	}
+	for i := range as {
+		switch {
+		case as[i] < bs[i]:
+			return -1
+		case as[i] > bs[i]:
+			return 1
+		}
+	}
+	return 0
+}
+
+// versionParts returns the numeric components of a version
+func versionParts(version string) []int {
+	if i := strings.IndexAny(version, "-+ "); i >= 0 {
+		version = version[:i]
+	}
+	var parts []int
+	for _, field := range strings.Split(version, ".") {
+		n, err := strconv.Atoi(field)
+		if err != nil {
+			break
+		}
+		parts = append(parts, n)
+	}
+	return parts
+}
+
+// newStatus returns a status with the given daemons running, along with
+// those of Daemons that aren't
+func newStatus(version string, running []string) *Status {
+	names := make(map[string]bool, len(running)+len(Daemons))
+	for _, name := range Daemons {
+		names[name] = false
+	}
+	for _, name := range running {
+		names[name] = true
+	}
+
+	status := &Status{Version: version, Daemons: make([]DaemonStatus, 0, len(names))}
+	for name, isRunning := range names {
+		status.Daemons = append(status.Daemons, DaemonStatus{Name: name, Running: isRunning})
+	}
+	sort.Slice(status.Daemons, func(i, j int) bool { return status.Daemons[i].Name < status.Daemons[j].Name })
+	return status
+}
+
+// versionPattern matches the version in "show version" output, such as
+// "FRRouting 9.1 (router) on Linux(6.1.0)."
+var versionPattern = regexp.MustCompile(`(?m)^FRRouting (\S+)`)
+
+// parseVersion returns the version from "show version" output
+func parseVersion(output string) string {
+	match := versionPattern.FindStringSubmatch(output)
+	if match == nil {
+		return ""
+	}
+	return match[1]
+}
+
+// parseDaemons returns the daemons listed by "show daemons", which prints
+// the names of those vtysh is connected to on one line
+func parseDaemons(output string) []string {
+	return strings.Fields(output)
+}
@@ -0,0 +1,19 @@
+package frr
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompareVersions(t *testing.T) {
+	assert.Equal(t, 0, CompareVersions("9.1", "9.1.0"))
+	assert.Equal(t, 0, CompareVersions("9.1-dev", "9.1"))
+	assert.Equal(t, -1, CompareVersions("8.4.4", "8.5"))
+	assert.Equal(t, 1, CompareVersions("10.0", "9.1.2"))
+
+	status := &Status{Version: "8.5.1"}
+	assert.True(t, status.AtLeast("8.5"))
+	assert.False(t, status.AtLeast("9.0"))
+	assert.False(t, (&Status{}).AtLeast("7.0"), "an unknown version is never new enough")
+}
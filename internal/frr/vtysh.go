@@ -0,0 +1,576 @@
+package frr
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/padminisys/flintroute/internal/frrconf"
+	"github.com/padminisys/flintroute/internal/requestid"
+	"github.com/padminisys/flintroute/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+// VtyshOptions configures the vtysh transport
+type VtyshOptions struct {
+	// Path is the vtysh binary; defaults to "vtysh"
+	Path string
+	// SocketDir is the directory holding the daemons' VTY sockets, passed
+	// to vtysh as --vty_socket; empty uses the vtysh default
+	SocketDir string
+	// Timeout bounds each vtysh invocation; defaults to 10 seconds
+	Timeout time.Duration
+}
+
+// VtyshClient talks to FRR by running vtysh, for FRR builds that ship
+// without the gRPC northbound. Configuration is applied with "configure
+// terminal" command sequences and state is read from "show ... json".
+type VtyshClient struct {
+	options VtyshOptions
+	logger  *zap.Logger
+	// run executes vtysh and returns its output
+	run func(ctx context.Context, name string, args ...string) ([]byte, error)
+
+	mu        sync.RWMutex
+	connected bool
+}
+
+// NewVtyshClient creates a vtysh FRR client
+func NewVtyshClient(opts VtyshOptions, logger *zap.Logger) *VtyshClient {
+	if opts.Path == "" {
+		opts.Path = "vtysh"
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = 10 * time.Second
+	}
+	return &VtyshClient{
+		options: opts,
+		logger:  logger,
+		run:     runVtysh,
+	}
+}
+
+// runVtysh runs vtysh, appending its stderr to the output on failure
+func runVtysh(ctx context.Context, name string, args ...string) ([]byte, error) {
+	output, err := exec.CommandContext(ctx, name, args...).Output()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		output = append(output, exitErr.Stderr...)
+	}
+	return output, err
+}
+
+// exec runs commands in a single vtysh invocation. Failures to run vtysh
+// or reach the daemons wrap ErrNotConnected and mark the client
+// disconnected; any successful run marks it connected again. Configuration
+// FRR rejects is returned as a ConfigError.
+func (c *VtyshClient) exec(ctx context.Context, commands ...string) (_ string, err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "frr.vtysh",
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attribute.Int("frr.vtysh.commands", len(commands))),
+	)
+	defer func() {
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+	}()
+
+	ctx, cancel := context.WithTimeout(ctx, c.options.Timeout)
+	defer cancel()
+
+	var args []string
+	if c.options.SocketDir != "" {
+		args = append(args, "--vty_socket", c.options.SocketDir)
+	}
+	for _, command := range commands {
+		args = append(args, "-c", command)
+	}
+
+	output, err := c.run(ctx, c.options.Path, args...)
+	if err != nil {
+		message := strings.TrimSpace(string(output))
+		if message == "" {
+			message = err.Error()
+		}
+
+		var exitErr *exec.ExitError
+		if !errors.As(err, &exitErr) || ctx.Err() != nil || strings.Contains(message, "failed to connect to any daemons") {
+			c.setConnected(false)
+			return "", fmt.Errorf("%w: vtysh: %s", ErrNotConnected, message)
+		}
+		if len(commands) > 0 && commands[0] == "configure terminal" {
+			return "", vtyshConfigError(message)
+		}
+		return "", fmt.Errorf("vtysh: %s", message)
+	}
+
+	c.setConnected(true)
+	return string(output), nil
+}
+
+func (c *VtyshClient) setConnected(connected bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.connected = connected
+}
+
+// Connect checks that vtysh can reach the FRR daemons
+func (c *VtyshClient) Connect(ctx context.Context) error {
+	if _, err := c.exec(ctx, "show version"); err != nil {
+		return fmt.Errorf("failed to reach FRR through vtysh: %w", err)
+	}
+
+	c.logger.Info("Connected to FRR through vtysh", zap.String("path", c.options.Path))
+	return nil
+}
+
+// Close marks the client disconnected; vtysh holds no connection
+func (c *VtyshClient) Close() error {
+	c.setConnected(false)
+	return nil
+}
+
+// IsConnected reports whether the last vtysh invocation reached FRR
+func (c *VtyshClient) IsConnected() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.connected
+}
+
+// AddBGPPeer adds a BGP peer to FRR configuration
+func (c *VtyshClient) AddBGPPeer(ctx context.Context, config *BGPPeerConfig) error {
+	c.logger.Info("Adding BGP peer",
+		zap.String("ip", config.IPAddress),
+		zap.Uint32("remote_asn", config.RemoteASN),
+		requestid.Field(ctx),
+	)
+
+	_, err := c.exec(ctx, peerCommands(config, "")...)
+	return err
+}
+
+// RemoveBGPPeer removes a BGP peer from FRR configuration
+func (c *VtyshClient) RemoveBGPPeer(ctx context.Context, ipAddress string) error {
+	c.logger.Info("Removing BGP peer", zap.String("ip", ipAddress), requestid.Field(ctx))
+
+	_, err := c.exec(ctx, "configure terminal", "router bgp", "no neighbor "+ipAddress, "end")
+	return err
+}
+
+// UpdateBGPPeer updates a BGP peer configuration. Statements FRR has for
+// the neighbor that the new configuration no longer contains are removed.
+func (c *VtyshClient) UpdateBGPPeer(ctx context.Context, config *BGPPeerConfig) error {
+	running, err := c.GetRunningConfig(ctx)
+	if err != nil {
+		return err
+	}
+
+	c.logger.Info("Updating BGP peer",
+		zap.String("ip", config.IPAddress),
+		zap.Uint32("remote_asn", config.RemoteASN),
+		requestid.Field(ctx),
+	)
+
+	_, err = c.exec(ctx, peerCommands(config, running)...)
+	return err
+}
+
+// UpdateBGPPeers adds or updates peers in a single vtysh invocation, so
+// that FRR commits their neighbor statements together
+func (c *VtyshClient) UpdateBGPPeers(ctx context.Context, configs []*BGPPeerConfig) error {
+	running, err := c.GetRunningConfig(ctx)
+	if err != nil {
+		return err
+	}
+
+	c.logger.Info("Updating BGP peers", zap.Int("peers", len(configs)), requestid.Field(ctx))
+
+	commands := []string{"configure terminal"}
+	for _, config := range configs {
+		// Without "configure terminal" and "end", as for a dry run
+		peer := peerCommands(config, running)
+		commands = append(commands, peer[1:len(peer)-1]...)
+	}
+	_, err = c.exec(ctx, append(commands, "end")...)
+	return err
+}
+
+// CheckBGPPeer returns the commands UpdateBGPPeer would run for config,
+// once vtysh has checked them in dry-run mode on top of the running
+// configuration. Nothing is applied.
+func (c *VtyshClient) CheckBGPPeer(ctx context.Context, config *BGPPeerConfig) ([]string, error) {
+	running, err := c.GetRunningConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	commands := peerCommands(config, running)
+	// Without "configure terminal" and "end" the commands are valid
+	// configuration file lines
+	candidate := running + "\n" + strings.Join(commands[1:len(commands)-1], "\n") + "\n"
+	if err := c.dryRun(ctx, candidate); err != nil {
+		return nil, err
+	}
+	return commands, nil
+}
+
+// dryRun has vtysh check a configuration file without applying it
+func (c *VtyshClient) dryRun(ctx context.Context, config string) error {
+	file, err := os.CreateTemp("", "flintroute-*.conf")
+	if err != nil {
+		return fmt.Errorf("failed to write candidate config: %w", err)
+	}
+	defer os.Remove(file.Name())
+	_, err = file.WriteString(config)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write candidate config: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, c.options.Timeout)
+	defer cancel()
+
+	output, err := c.run(ctx, c.options.Path, "--dryrun", "--inputfile", file.Name())
+	if err != nil {
+		var exitErr *exec.ExitError
+		if !errors.As(err, &exitErr) || ctx.Err() != nil {
+			return fmt.Errorf("%w: vtysh: %v", ErrNotConnected, err)
+		}
+		message := strings.TrimSpace(string(output))
+		if message == "" {
+			message = err.Error()
+		}
+		return vtyshConfigError(message)
+	}
+	return nil
+}
+
+// ApplyBGPGlobal applies the settings of the default BGP instance.
+// Managed statements FRR has that config no longer contains are removed.
+func (c *VtyshClient) ApplyBGPGlobal(ctx context.Context, config *BGPGlobalConfig) error {
+	running, err := c.GetRunningConfig(ctx)
+	if err != nil {
+		return err
+	}
+
+	c.logger.Info("Applying BGP global configuration",
+		zap.Uint32("asn", config.ASN),
+		zap.String("router_id", config.RouterID),
+		requestid.Field(ctx),
+	)
+
+	_, err = c.exec(ctx, globalCommands(config, running)...)
+	return err
+}
+
+// ApplyPolicy replaces a route-map, prefix-list, community-list or as-path
+// access-list in FRR with the given configuration text
+func (c *VtyshClient) ApplyPolicy(ctx context.Context, kind, name, config string) error {
+	running, err := c.GetRunningConfig(ctx)
+	if err != nil {
+		return err
+	}
+	removal, err := policyRemovalCommands(kind, name, running)
+	if err != nil {
+		return err
+	}
+
+	c.logger.Info("Applying routing policy",
+		zap.String("kind", kind),
+		zap.String("name", name),
+		requestid.Field(ctx),
+	)
+
+	commands := append([]string{"configure terminal"}, removal...)
+	for _, line := range strings.Split(config, "\n") {
+		if line = strings.TrimSpace(line); line != "" && line != "!" {
+			commands = append(commands, line)
+		}
+	}
+	_, err = c.exec(ctx, append(commands, "end")...)
+	return err
+}
+
+// RemovePolicy removes a routing policy object from FRR. Objects FRR does
+// not have are ignored.
+func (c *VtyshClient) RemovePolicy(ctx context.Context, kind, name string) error {
+	running, err := c.GetRunningConfig(ctx)
+	if err != nil {
+		return err
+	}
+	removal, err := policyRemovalCommands(kind, name, running)
+	if err != nil || len(removal) == 0 {
+		return err
+	}
+
+	c.logger.Info("Removing routing policy",
+		zap.String("kind", kind),
+		zap.String("name", name),
+		requestid.Field(ctx),
+	)
+
+	commands := append([]string{"configure terminal"}, removal...)
+	_, err = c.exec(ctx, append(commands, "end")...)
+	return err
+}
+
+// GetBGPSessionState retrieves BGP session state for a peer
+func (c *VtyshClient) GetBGPSessionState(ctx context.Context, ipAddress string) (*BGPSessionState, error) {
+	neighbors, err := c.showNeighbors(ctx, "show bgp neighbors "+ipAddress+" json")
+	if err != nil {
+		return nil, err
+	}
+
+	neighbor, ok := neighbors[ipAddress]
+	if !ok {
+		return nil, fmt.Errorf("BGP neighbor %s not found in FRR", ipAddress)
+	}
+	return neighbor.state(ipAddress), nil
+}
+
+// GetAllBGPSessions retrieves all BGP session states
+func (c *VtyshClient) GetAllBGPSessions(ctx context.Context) ([]*BGPSessionState, error) {
+	neighbors, err := c.showNeighbors(ctx, "show bgp neighbors json")
+	if err != nil {
+		return nil, err
+	}
+
+	states := make([]*BGPSessionState, 0, len(neighbors))
+	for ip, neighbor := range neighbors {
+		states = append(states, neighbor.state(ip))
+	}
+	sort.Slice(states, func(i, j int) bool { return states[i].IPAddress < states[j].IPAddress })
+	return states, nil
+}
+
+// GetRunningConfig retrieves the current FRR running configuration
+func (c *VtyshClient) GetRunningConfig(ctx context.Context) (string, error) {
+	c.logger.Debug("Getting running configuration", requestid.Field(ctx))
+	return c.exec(ctx, "show running-config")
+}
+
+// GetStatus retrieves FRR's version and the daemons vtysh reaches. vtysh
+// reports neither FRR's uptime nor the northbound capabilities.
+func (c *VtyshClient) GetStatus(ctx context.Context) (*Status, error) {
+	version, err := c.exec(ctx, "show version")
+	if err != nil {
+		return nil, err
+	}
+	daemons, err := c.exec(ctx, "show daemons")
+	if err != nil {
+		return nil, err
+	}
+	return newStatus(parseVersion(version), parseDaemons(daemons)), nil
+}
+
+// ListBGPNeighbors returns the neighbors configured in FRR's running config
+func (c *VtyshClient) ListBGPNeighbors(ctx context.Context) ([]*BGPNeighbor, error) {
+	config, err := c.GetRunningConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return ParseBGPNeighbors(config), nil
+}
+
+// ProbeBGPPeer checks that a peer's BGP port can be reached, without
+// configuring it. vtysh runs on the router, so the probe does too.
+func (c *VtyshClient) ProbeBGPPeer(ctx context.Context, config *BGPPeerConfig, timeout time.Duration) (*PeerProbe, error) {
+	c.logger.Debug("Probing BGP peer", zap.String("ip", config.IPAddress), requestid.Field(ctx))
+	return probePeer(ctx, config, ProbedFromRouter, timeout), nil
+}
+
+// capabilityNegotiated is how FRR reports a capability both sides advertised
+const capabilityNegotiated = "advertisedAndReceived"
+
+// vtyshNeighbor is the part of a "show bgp neighbors json" entry that
+// FlintRoute reads
+type vtyshNeighbor struct {
+	BGPState               string `json:"bgpState"`
+	BGPTimerUpMsec         int64  `json:"bgpTimerUpMsec"`
+	LastResetDueTo         string `json:"lastResetDueTo"`
+	LastNotificationReason string `json:"lastNotificationReason"`
+	RemoteRouterID         string `json:"remoteRouterId"`
+	BGPVersion             int    `json:"bgpVersion"`
+	HoldTimeMsecs          int    `json:"bgpTimerHoldTimeMsecs"`          // negotiated
+	KeepaliveMsecs         int    `json:"bgpTimerKeepAliveIntervalMsecs"` // negotiated
+	NeighborCapabilities   struct {
+		FourByteAS      string `json:"4byteAs"`
+		GracefulRestart string `json:"gracefulRestart"`
+		// Keyed by AFI/SAFI, then by advertised, received or both
+		MultiprotocolExtensions map[string]map[string]bool `json:"multiprotocolExtensions"`
+	} `json:"neighborCapabilities"`
+	MessageStats struct {
+		TotalSent int64 `json:"totalSent"`
+		TotalRecv int64 `json:"totalRecv"`
+	} `json:"messageStats"`
+	AddressFamilyInfo map[string]struct {
+		AcceptedPrefixCounter int `json:"acceptedPrefixCounter"`
+		SentPrefixCounter     int `json:"sentPrefixCounter"`
+	} `json:"addressFamilyInfo"`
+}
+
+// showNeighbors runs a "show bgp neighbors ... json" command and returns
+// the entries keyed by neighbor address. Interface and peer-group entries
+// are skipped.
+func (c *VtyshClient) showNeighbors(ctx context.Context, command string) (map[string]*vtyshNeighbor, error) {
+	output, err := c.exec(ctx, command)
+	if err != nil {
+		return nil, err
+	}
+	return parseNeighborsJSON([]byte(output))
+}
+
+// parseNeighborsJSON parses "show bgp neighbors json" output
+func parseNeighborsJSON(data []byte) (map[string]*vtyshNeighbor, error) {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("failed to parse vtysh neighbor output: %w", err)
+	}
+
+	neighbors := make(map[string]*vtyshNeighbor, len(raw))
+	for key, value := range raw {
+		if net.ParseIP(key) == nil {
+			continue
+		}
+		var neighbor vtyshNeighbor
+		if err := json.Unmarshal(value, &neighbor); err != nil {
+			return nil, fmt.Errorf("failed to parse vtysh state for neighbor %s: %w", key, err)
+		}
+		neighbors[key] = &neighbor
+	}
+	return neighbors, nil
+}
+
+// state converts the entry to a session state. The last reset reason is
+// only reported while the session is down.
+func (n *vtyshNeighbor) state(ip string) *BGPSessionState {
+	state := &BGPSessionState{
+		IPAddress:        ip,
+		State:            n.BGPState,
+		MessagesReceived: n.MessageStats.TotalRecv,
+		MessagesSent:     n.MessageStats.TotalSent,
+	}
+	for _, af := range n.AddressFamilyInfo {
+		state.PrefixesReceived += af.AcceptedPrefixCounter
+		state.PrefixesSent += af.SentPrefixCounter
+	}
+
+	if n.BGPState == "Established" {
+		state.Uptime = n.BGPTimerUpMsec / 1000
+		n.negotiated(state)
+	} else if n.LastNotificationReason != "" {
+		state.LastError = n.LastNotificationReason
+	} else {
+		state.LastError = n.LastResetDueTo
+	}
+	return state
+}
+
+// negotiated fills in what the established session negotiated: timers,
+// router ID, version and capabilities
+func (n *vtyshNeighbor) negotiated(state *BGPSessionState) {
+	caps := n.NeighborCapabilities
+	state.RemoteRouterID = n.RemoteRouterID
+	state.BGPVersion = n.BGPVersion
+	state.HoldTime = n.HoldTimeMsecs / 1000
+	state.KeepaliveTime = n.KeepaliveMsecs / 1000
+	state.FourByteASN = caps.FourByteAS == capabilityNegotiated
+	state.GracefulRestart = caps.GracefulRestart == capabilityNegotiated
+
+	for af, status := range caps.MultiprotocolExtensions {
+		if status[capabilityNegotiated] {
+			state.AddressFamilies = append(state.AddressFamilies, addressFamilyName(af))
+		}
+	}
+	sort.Strings(state.AddressFamilies)
+}
+
+// addressFamilyName turns FRR's JSON name of an AFI/SAFI into a hyphenated
+// one, e.g. "ipv4LabeledUnicast" into "ipv4-labeled-unicast"
+func addressFamilyName(af string) string {
+	var b strings.Builder
+	for i, r := range af {
+		if unicode.IsUpper(r) {
+			if i > 0 {
+				b.WriteByte('-')
+			}
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return strings.Replace(b.String(), "l2-vpn", "l2vpn", 1)
+}
+
+// peerCommands returns the vtysh commands that configure a peer. Neighbor
+// statements in running that the peer no longer has are removed first;
+// remote-as and activate are left to FRR.
+func peerCommands(config *BGPPeerConfig, running string) []string {
+	wanted := config.NeighborCommands()
+	keep := make(map[string]bool, len(wanted))
+	for _, command := range wanted {
+		keep[command] = true
+	}
+
+	commands := []string{"configure terminal", fmt.Sprintf("router bgp %d", config.ASN)}
+	global, ipv4 := configuredNeighborLines(running, config.IPAddress)
+	for _, line := range global {
+		if !keep[line] {
+			commands = append(commands, "no "+line)
+		}
+	}
+	var staleIPv4 []string
+	for _, line := range ipv4 {
+		if !keep[line] {
+			staleIPv4 = append(staleIPv4, "no "+line)
+		}
+	}
+	if len(staleIPv4) > 0 {
+		commands = append(commands, "address-family ipv4 unicast")
+		commands = append(commands, staleIPv4...)
+		commands = append(commands, "exit-address-family")
+	}
+
+	commands = append(commands, wanted...)
+	return append(commands, "end")
+}
+
+// configuredNeighborLines returns ip's neighbor statements from the default
+// BGP instance in config, split into those at router level and those under
+// address-family ipv4 unicast
+func configuredNeighborLines(config, ip string) (global, ipv4 []string) {
+	r := frrconf.Parse(config).BGP
+	if r == nil {
+		return nil, nil
+	}
+	if n := r.Neighbor(ip); n != nil {
+		return n.Lines(frrconf.IPv4Unicast)
+	}
+	return nil, nil
+}
+
+// policyRemovalCommands returns the commands that delete every statement of
+// the named policy present in running
+func policyRemovalCommands(kind, name, running string) ([]string, error) {
+	switch kind {
+	case frrconf.KindRouteMap, frrconf.KindPrefixList, frrconf.KindCommunityList, frrconf.KindASPathList:
+	default:
+		return nil, fmt.Errorf("unsupported policy kind %q", kind)
+	}
+	return frrconf.Parse(running).RemovalCommands(kind, name), nil
+}
@@ -0,0 +1,423 @@
+package frr
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+const vtyshRunningConfig = `frr version 9.1
+!
+route-map RM-IN permit 10
+ match ip address prefix-list PL-IN
+exit
+!
+ip prefix-list PL-IN seq 10 permit 10.0.0.0/8 le 24
+!
+router bgp 65001
+ neighbor 192.0.2.1 remote-as 65002
+ neighbor 192.0.2.1 password old-secret
+ neighbor 192.0.2.1 timers 10 30
+ neighbor 192.0.2.2 remote-as 65003
+ !
+ address-family ipv4 unicast
+  neighbor 192.0.2.1 activate
+  neighbor 192.0.2.1 route-map RM-OLD in
+  neighbor 192.0.2.1 soft-reconfiguration inbound
+ exit-address-family
+exit
+!
+`
+
+const vtyshNeighborsJSON = `{
+  "192.0.2.1": {
+    "remoteAs": 65002,
+    "bgpState": "Established",
+    "bgpTimerUpMsec": 3600500,
+    "lastResetDueTo": "Max Prefix received",
+    "remoteRouterId": "10.0.0.2",
+    "bgpVersion": 4,
+    "bgpTimerHoldTimeMsecs": 90000,
+    "bgpTimerKeepAliveIntervalMsecs": 30000,
+    "neighborCapabilities": {
+      "4byteAs": "advertisedAndReceived",
+      "gracefulRestart": "advertised",
+      "multiprotocolExtensions": {
+        "ipv6Unicast": {"advertisedAndReceived": true},
+        "ipv4Unicast": {"advertisedAndReceived": true},
+        "l2VpnEvpn": {"advertised": true}
+      }
+    },
+    "messageStats": {"totalSent": 900, "totalRecv": 1000},
+    "addressFamilyInfo": {
+      "ipv4Unicast": {"acceptedPrefixCounter": 100, "sentPrefixCounter": 50},
+      "ipv6Unicast": {"acceptedPrefixCounter": 20, "sentPrefixCounter": 5}
+    }
+  },
+  "192.0.2.2": {
+    "remoteAs": 65003,
+    "bgpState": "Idle",
+    "lastResetDueTo": "Max Prefix received",
+    "bgpVersion": 4,
+    "bgpTimerHoldTimeMsecs": 180000,
+    "messageStats": {"totalSent": 1, "totalRecv": 2}
+  },
+  "swp1": {"bgpState": "Active"}
+}`
+
+// fakeVtysh records vtysh invocations and answers them from outputs,
+// keyed by the first command. Dry runs record the checked file and fail
+// with dryRunErr.
+type fakeVtysh struct {
+	calls     [][]string
+	outputs   map[string]string
+	err       error
+	dryRuns   []string
+	dryRunErr error
+}
+
+func (f *fakeVtysh) run(ctx context.Context, name string, args ...string) ([]byte, error) {
+	var commands []string
+	for i := 0; i+1 < len(args); i++ {
+		switch args[i] {
+		case "-c":
+			commands = append(commands, args[i+1])
+		case "--inputfile":
+			config, err := os.ReadFile(args[i+1])
+			if err != nil {
+				return nil, err
+			}
+			f.dryRuns = append(f.dryRuns, string(config))
+			if f.dryRunErr != nil {
+				return []byte("line 27: % Unknown command: neighbor 192.0.2.1 bogus"), f.dryRunErr
+			}
+			return nil, nil
+		}
+	}
+	f.calls = append(f.calls, commands)
+	if f.err != nil {
+		return []byte("Exiting: failed to connect to any daemons."), f.err
+	}
+	return []byte(f.outputs[commands[0]]), nil
+}
+
+func newTestVtyshClient(fake *fakeVtysh) *VtyshClient {
+	client := NewVtyshClient(VtyshOptions{}, zap.NewNop())
+	client.run = fake.run
+	return client
+}
+
+func TestVtyshClient(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Connect marks the client connected", func(t *testing.T) {
+		client := newTestVtyshClient(&fakeVtysh{})
+		assert.False(t, client.IsConnected())
+
+		require.NoError(t, client.Connect(ctx))
+		assert.True(t, client.IsConnected())
+
+		require.NoError(t, client.Close())
+		assert.False(t, client.IsConnected())
+	})
+
+	t.Run("Unreachable daemons", func(t *testing.T) {
+		client := newTestVtyshClient(&fakeVtysh{err: &exec.ExitError{}})
+
+		err := client.Connect(ctx)
+		assert.ErrorIs(t, err, ErrNotConnected)
+		assert.Contains(t, err.Error(), "failed to connect to any daemons")
+		assert.False(t, client.IsConnected())
+	})
+
+	t.Run("Status", func(t *testing.T) {
+		client := newTestVtyshClient(&fakeVtysh{outputs: map[string]string{
+			"show version": "FRRouting 9.1 (router) on Linux(6.1.0).\nCopyright 1996-2005 Kunihiro Ishiguro, et al.\n",
+			"show daemons": " zebra bgpd staticd watchfrr\n",
+		}})
+
+		status, err := client.GetStatus(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, "9.1", status.Version)
+		assert.Equal(t, []DaemonStatus{
+			{Name: "bfdd"},
+			{Name: "bgpd", Running: true},
+			{Name: "staticd", Running: true},
+			{Name: "watchfrr", Running: true},
+			{Name: "zebra", Running: true},
+		}, status.Daemons)
+		assert.Empty(t, status.Capabilities)
+	})
+
+	t.Run("Missing vtysh binary", func(t *testing.T) {
+		client := newTestVtyshClient(&fakeVtysh{err: exec.ErrNotFound})
+
+		_, err := client.GetRunningConfig(ctx)
+		assert.ErrorIs(t, err, ErrNotConnected)
+	})
+
+	t.Run("Add peer", func(t *testing.T) {
+		fake := &fakeVtysh{}
+		client := newTestVtyshClient(fake)
+
+		require.NoError(t, client.AddBGPPeer(ctx, &BGPPeerConfig{
+			IPAddress:   "192.0.2.1",
+			ASN:         65001,
+			RemoteASN:   65002,
+			RouteMapIn:  "RM-IN",
+			MaxPrefixes: 1000,
+		}))
+		assert.Equal(t, [][]string{{
+			"configure terminal",
+			"router bgp 65001",
+			"neighbor 192.0.2.1 remote-as 65002",
+			"neighbor 192.0.2.1 route-map RM-IN in",
+			"neighbor 192.0.2.1 maximum-prefix 1000",
+			"end",
+		}}, fake.calls)
+	})
+
+	t.Run("Update peer removes stale statements", func(t *testing.T) {
+		fake := &fakeVtysh{outputs: map[string]string{"show running-config": vtyshRunningConfig}}
+		client := newTestVtyshClient(fake)
+
+		require.NoError(t, client.UpdateBGPPeer(ctx, &BGPPeerConfig{
+			IPAddress:  "192.0.2.1",
+			ASN:        65001,
+			RemoteASN:  65002,
+			RouteMapIn: "RM-IN",
+			NeighborOptions: NeighborOptions{
+				Keepalive:           10,
+				HoldTime:            30,
+				SoftReconfigInbound: true,
+			},
+		}))
+		require.Len(t, fake.calls, 2)
+		assert.Equal(t, []string{
+			"configure terminal",
+			"router bgp 65001",
+			"no neighbor 192.0.2.1 password old-secret",
+			"address-family ipv4 unicast",
+			"no neighbor 192.0.2.1 route-map RM-OLD in",
+			"exit-address-family",
+			"neighbor 192.0.2.1 remote-as 65002",
+			"neighbor 192.0.2.1 route-map RM-IN in",
+			"neighbor 192.0.2.1 timers 10 30",
+			"neighbor 192.0.2.1 soft-reconfiguration inbound",
+			"end",
+		}, fake.calls[1])
+	})
+
+	t.Run("Update peers commits them together", func(t *testing.T) {
+		fake := &fakeVtysh{outputs: map[string]string{"show running-config": vtyshRunningConfig}}
+		client := newTestVtyshClient(fake)
+
+		require.NoError(t, client.UpdateBGPPeers(ctx, []*BGPPeerConfig{
+			{IPAddress: "192.0.2.2", ASN: 65001, RemoteASN: 65003},
+			{IPAddress: "192.0.2.3", ASN: 65001, RemoteASN: 65004},
+		}))
+		require.Len(t, fake.calls, 2, "one running config read and one commit")
+		commands := fake.calls[1]
+		assert.Equal(t, "configure terminal", commands[0])
+		assert.Equal(t, "end", commands[len(commands)-1])
+		assert.Contains(t, commands, "neighbor 192.0.2.2 remote-as 65003")
+		assert.Contains(t, commands, "neighbor 192.0.2.3 remote-as 65004")
+		assert.NotContains(t, commands[1:len(commands)-1], "end")
+	})
+
+	t.Run("Check peer dry-runs the change without applying it", func(t *testing.T) {
+		fake := &fakeVtysh{outputs: map[string]string{"show running-config": vtyshRunningConfig}}
+		client := newTestVtyshClient(fake)
+
+		commands, err := client.CheckBGPPeer(ctx, &BGPPeerConfig{IPAddress: "192.0.2.1", ASN: 65001, RemoteASN: 65002})
+		require.NoError(t, err)
+		assert.Equal(t, []string{
+			"configure terminal",
+			"router bgp 65001",
+			"no neighbor 192.0.2.1 password old-secret",
+			"no neighbor 192.0.2.1 timers 10 30",
+			"address-family ipv4 unicast",
+			"no neighbor 192.0.2.1 route-map RM-OLD in",
+			"no neighbor 192.0.2.1 soft-reconfiguration inbound",
+			"exit-address-family",
+			"neighbor 192.0.2.1 remote-as 65002",
+			"end",
+		}, commands)
+		assert.Len(t, fake.calls, 1)
+		require.Len(t, fake.dryRuns, 1)
+		assert.True(t, strings.HasPrefix(fake.dryRuns[0], vtyshRunningConfig))
+		assert.True(t, strings.HasSuffix(fake.dryRuns[0], "\nneighbor 192.0.2.1 remote-as 65002\n"))
+	})
+
+	t.Run("Check peer reports FRR's objections", func(t *testing.T) {
+		fake := &fakeVtysh{
+			outputs:   map[string]string{"show running-config": vtyshRunningConfig},
+			dryRunErr: &exec.ExitError{},
+		}
+		client := newTestVtyshClient(fake)
+
+		_, err := client.CheckBGPPeer(ctx, &BGPPeerConfig{IPAddress: "192.0.2.1", ASN: 65001, RemoteASN: 65002})
+		assert.ErrorIs(t, err, ErrConfigRejected)
+		configErr, ok := AsConfigError(err)
+		require.True(t, ok)
+		assert.Equal(t, 27, configErr.Line)
+		assert.Equal(t, "neighbor 192.0.2.1 bogus", configErr.Command)
+		assert.Equal(t, "line 27: % Unknown command: neighbor 192.0.2.1 bogus", configErr.Raw)
+	})
+
+	t.Run("Remove peer", func(t *testing.T) {
+		fake := &fakeVtysh{}
+		client := newTestVtyshClient(fake)
+
+		require.NoError(t, client.RemoveBGPPeer(ctx, "192.0.2.1"))
+		assert.Equal(t, [][]string{{"configure terminal", "router bgp", "no neighbor 192.0.2.1", "end"}}, fake.calls)
+	})
+
+	t.Run("Apply policy replaces the existing definition", func(t *testing.T) {
+		fake := &fakeVtysh{outputs: map[string]string{"show running-config": vtyshRunningConfig}}
+		client := newTestVtyshClient(fake)
+
+		require.NoError(t, client.ApplyPolicy(ctx, "route-map", "RM-IN",
+			"route-map RM-IN permit 10\n match ip address prefix-list PL-IN\n set local-preference 200\n"))
+		require.Len(t, fake.calls, 2)
+		assert.Equal(t, []string{
+			"configure terminal",
+			"no route-map RM-IN",
+			"route-map RM-IN permit 10",
+			"match ip address prefix-list PL-IN",
+			"set local-preference 200",
+			"end",
+		}, fake.calls[1])
+	})
+
+	t.Run("Remove missing policy", func(t *testing.T) {
+		fake := &fakeVtysh{outputs: map[string]string{"show running-config": vtyshRunningConfig}}
+		client := newTestVtyshClient(fake)
+
+		require.NoError(t, client.RemovePolicy(ctx, "as-path-list", "AS-IN"))
+		assert.Len(t, fake.calls, 1)
+
+		require.NoError(t, client.RemovePolicy(ctx, "prefix-list", "PL-IN"))
+		assert.Equal(t, []string{"configure terminal", "no ip prefix-list PL-IN", "end"}, fake.calls[2])
+
+		assert.Error(t, client.RemovePolicy(ctx, "access-list", "ACL"))
+	})
+
+	t.Run("Apply global settings removes stale statements", func(t *testing.T) {
+		running := strings.Replace(vtyshRunningConfig, "router bgp 65001\n",
+			"router bgp 65001\n bgp router-id 10.0.0.1\n bgp graceful-restart\n bgp graceful-restart restart-time 90\n bgp bestpath compare-routerid\n", 1)
+		fake := &fakeVtysh{outputs: map[string]string{"show running-config": running}}
+		client := newTestVtyshClient(fake)
+
+		require.NoError(t, client.ApplyBGPGlobal(ctx, &BGPGlobalConfig{
+			ASN:             65001,
+			RouterID:        "10.0.0.2",
+			GracefulRestart: true,
+			StalePathTime:   300,
+			MultipathRelax:  true,
+		}))
+		require.Len(t, fake.calls, 2)
+		assert.Equal(t, []string{
+			"configure terminal",
+			"router bgp 65001",
+			"no bgp router-id 10.0.0.1",
+			"no bgp graceful-restart restart-time 90",
+			"no bgp bestpath compare-routerid",
+			"bgp router-id 10.0.0.2",
+			"bgp graceful-restart",
+			"bgp graceful-restart stalepath-time 300",
+			"no bgp ebgp-requires-policy",
+			"no bgp log-neighbor-changes",
+			"bgp bestpath as-path multipath-relax",
+			"end",
+		}, fake.calls[1])
+	})
+
+	t.Run("Apply global settings with another ASN replaces the instance", func(t *testing.T) {
+		fake := &fakeVtysh{outputs: map[string]string{"show running-config": vtyshRunningConfig}}
+		client := newTestVtyshClient(fake)
+
+		require.NoError(t, client.ApplyBGPGlobal(ctx, &BGPGlobalConfig{ASN: 65010, EBGPRequiresPolicy: true, LogNeighborChanges: true}))
+		require.Len(t, fake.calls, 2)
+		assert.Equal(t, []string{
+			"configure terminal",
+			"no router bgp 65001",
+			"router bgp 65010",
+			"bgp ebgp-requires-policy",
+			"bgp log-neighbor-changes",
+			"end",
+		}, fake.calls[1])
+	})
+
+	t.Run("Session states", func(t *testing.T) {
+		fake := &fakeVtysh{outputs: map[string]string{
+			"show bgp neighbors json":           vtyshNeighborsJSON,
+			"show bgp neighbors 192.0.2.1 json": vtyshNeighborsJSON,
+		}}
+		client := newTestVtyshClient(fake)
+
+		state, err := client.GetBGPSessionState(ctx, "192.0.2.1")
+		require.NoError(t, err)
+		assert.Equal(t, &BGPSessionState{
+			IPAddress:        "192.0.2.1",
+			State:            "Established",
+			Uptime:           3600,
+			PrefixesReceived: 120,
+			PrefixesSent:     55,
+			MessagesReceived: 1000,
+			MessagesSent:     900,
+			RemoteRouterID:   "10.0.0.2",
+			BGPVersion:       4,
+			HoldTime:         90,
+			KeepaliveTime:    30,
+			FourByteASN:      true,
+			AddressFamilies:  []string{"ipv4-unicast", "ipv6-unicast"},
+		}, state)
+
+		states, err := client.GetAllBGPSessions(ctx)
+		require.NoError(t, err)
+		require.Len(t, states, 2)
+		assert.Equal(t, "192.0.2.2", states[1].IPAddress)
+		assert.Equal(t, "Idle", states[1].State)
+		assert.True(t, states[1].MaxPrefixExceeded())
+		assert.Zero(t, states[1].HoldTime, "timers are only negotiated once established")
+
+		_, err = client.GetBGPSessionState(ctx, "192.0.2.9")
+		assert.Error(t, err)
+	})
+
+	t.Run("Command failure keeps the client connected", func(t *testing.T) {
+		client := newTestVtyshClient(&fakeVtysh{})
+		require.NoError(t, client.Connect(ctx))
+		client.run = func(ctx context.Context, name string, args ...string) ([]byte, error) {
+			return []byte("% Unknown command: neighbor"), &exec.ExitError{}
+		}
+
+		err := client.RemoveBGPPeer(ctx, "192.0.2.1")
+		require.Error(t, err)
+		assert.False(t, errors.Is(err, ErrNotConnected))
+		assert.ErrorIs(t, err, ErrConfigRejected)
+		assert.True(t, client.IsConnected())
+
+		_, err = client.GetBGPSessionState(ctx, "192.0.2.1")
+		assert.True(t, strings.HasPrefix(err.Error(), "vtysh: % Unknown command"))
+	})
+}
+
+func TestAddressFamilyName(t *testing.T) {
+	for af, want := range map[string]string{
+		"ipv4Unicast":        "ipv4-unicast",
+		"ipv6LabeledUnicast": "ipv6-labeled-unicast",
+		"ipv4Flowspec":       "ipv4-flowspec",
+		"l2VpnEvpn":          "l2vpn-evpn",
+	} {
+		assert.Equal(t, want, addressFamilyName(af), af)
+	}
+}
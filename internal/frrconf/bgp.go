@@ -0,0 +1,480 @@
+package frrconf
+
+import (
+	"fmt"
+	"net/netip"
+	"strconv"
+	"strings"
+)
+
+// Router is a "router bgp" block
+type Router struct {
+	ASN uint32 `json:"asn"`
+	// Statements are the router-level statements other than neighbor
+	// ones, such as "bgp router-id 10.0.0.1"
+	Statements []Statement `json:"statements,omitempty"`
+	// Neighbors are the neighbors and peer-groups, in the order they are
+	// first configured
+	Neighbors []*Neighbor `json:"neighbors,omitempty"`
+}
+
+// Neighbor is a neighbor or peer-group. Its settings are those configured
+// on it directly; see Router.Peers for neighbors with their peer-group's
+// settings. Zero values are left unset.
+type Neighbor struct {
+	// Name is the neighbor's IP address or the peer-group's name
+	Name string `json:"name"`
+	// IsPeerGroup is set for a peer-group
+	IsPeerGroup bool `json:"is_peer_group,omitempty"`
+	// PeerGroup is the peer-group the neighbor is a member of
+	PeerGroup string `json:"peer_group,omitempty"`
+
+	RemoteASN           uint32 `json:"remote_asn,omitempty"`
+	Description         string `json:"description,omitempty"`
+	Password            string `json:"password,omitempty"`
+	Shutdown            bool   `json:"shutdown,omitempty"`
+	Multihop            int    `json:"multihop,omitempty"`
+	UpdateSource        string `json:"update_source,omitempty"`
+	RouteMapIn          string `json:"route_map_in,omitempty"`
+	RouteMapOut         string `json:"route_map_out,omitempty"`
+	PrefixListIn        string `json:"prefix_list_in,omitempty"`
+	PrefixListOut       string `json:"prefix_list_out,omitempty"`
+	MaxPrefixes         int    `json:"max_prefixes,omitempty"`
+	Keepalive           int    `json:"keepalive,omitempty"`
+	HoldTime            int    `json:"holdtime,omitempty"`
+	ConnectRetry        int    `json:"connect_retry,omitempty"`
+	Passive             bool   `json:"passive,omitempty"`
+	TTLSecurityHops     int    `json:"ttl_security_hops,omitempty"`
+	NextHopSelf         bool   `json:"next_hop_self,omitempty"`
+	SoftReconfigInbound bool   `json:"soft_reconfiguration_inbound,omitempty"`
+	RemovePrivateAS     bool   `json:"remove_private_as,omitempty"`
+	AllowASIn           int    `json:"allowas_in,omitempty"`
+	// LocalAS is presented to the neighbor instead of the instance's AS.
+	// LocalASReplaceAS requires LocalASNoPrepend.
+	LocalAS          uint32 `json:"local_as,omitempty"`
+	LocalASNoPrepend bool   `json:"local_as_no_prepend,omitempty"`
+	LocalASReplaceAS bool   `json:"local_as_replace_as,omitempty"`
+	// MaxPrefixThreshold is the percentage of MaxPrefixes that logs a
+	// warning. With MaxPrefixWarningOnly the session is kept when the
+	// limit is exceeded; otherwise it is shut down and, with
+	// MaxPrefixRestart, restarted after that many minutes.
+	MaxPrefixThreshold   int  `json:"max_prefix_threshold,omitempty"`
+	MaxPrefixWarningOnly bool `json:"max_prefix_warning_only,omitempty"`
+	MaxPrefixRestart     int  `json:"max_prefix_restart,omitempty"`
+
+	// Statements are the neighbor's statements as FRR shows them
+	Statements []Statement `json:"statements"`
+}
+
+// neighbor returns the neighbor or peer-group named name, adding it if
+// needed
+func (r *Router) neighbor(name string) *Neighbor {
+	if n := r.Neighbor(name); n != nil {
+		return n
+	}
+	n := &Neighbor{Name: name}
+	r.Neighbors = append(r.Neighbors, n)
+	return n
+}
+
+// Neighbor returns the neighbor or peer-group named name, or nil
+func (r *Router) Neighbor(name string) *Neighbor {
+	for _, n := range r.Neighbors {
+		if n.Name == name {
+			return n
+		}
+	}
+	return nil
+}
+
+// check applies the neighbors' statements once every peer-group is known,
+// reporting those that aren't modeled
+func (r *Router) check(unsupported func(Statement, string)) {
+	for _, n := range r.Neighbors {
+		for _, st := range n.Statements {
+			if fields := neighborFields(st); len(fields) == 1 && fields[0] == "peer-group" {
+				n.IsPeerGroup = true
+			}
+		}
+	}
+
+	for _, n := range r.Neighbors {
+		if _, err := netip.ParseAddr(n.Name); err != nil && !n.IsPeerGroup {
+			for _, st := range n.Statements {
+				unsupported(st, ReasonNeighborName)
+			}
+			continue
+		}
+		for _, st := range n.Statements {
+			fields := neighborFields(st)
+			if len(fields) == 2 && fields[0] == "peer-group" {
+				if group := r.Neighbor(fields[1]); group == nil || !group.IsPeerGroup {
+					unsupported(st, ReasonMalformed)
+					continue
+				}
+			}
+			if !n.apply(fields, r.ASN) {
+				unsupported(st, ReasonNeighborSetting)
+			}
+		}
+	}
+}
+
+// Peers returns the neighbors with an IP address, members of a peer-group
+// with the group's settings beneath their own
+func (r *Router) Peers() []*Neighbor {
+	var peers []*Neighbor
+	for _, n := range r.Neighbors {
+		if _, err := netip.ParseAddr(n.Name); err != nil || n.IsPeerGroup {
+			continue
+		}
+		peer := &Neighbor{Name: n.Name, Statements: n.Statements}
+		if group := r.Neighbor(n.PeerGroup); n.PeerGroup != "" && group != nil {
+			for _, st := range group.Statements {
+				if fields := neighborFields(st); fields[0] != "peer-group" {
+					peer.apply(fields, r.ASN)
+				}
+			}
+		}
+		for _, st := range n.Statements {
+			peer.apply(neighborFields(st), r.ASN)
+		}
+		peers = append(peers, peer)
+	}
+	return peers
+}
+
+// Lines returns the neighbor's statements under the router and under the
+// address family af, leaving out remote-as and activate, which the other
+// statements depend on
+func (n *Neighbor) Lines(af string) (router, family []string) {
+	for _, st := range n.Statements {
+		switch neighborFields(st)[0] {
+		case "remote-as", "activate":
+			continue
+		}
+		if st.AddressFamily == "" {
+			router = append(router, st.Text)
+		} else if st.AddressFamily == af {
+			family = append(family, st.Text)
+		}
+	}
+	return router, family
+}
+
+// neighborFields returns the fields of a neighbor statement after the
+// neighbor's name
+func neighborFields(st Statement) []string {
+	return strings.Fields(st.Text)[2:]
+}
+
+// apply sets what a neighbor statement configures, given its fields after
+// the neighbor's name. It reports whether the statement was understood.
+// "remote-as internal" is resolved against the instance's asn.
+func (n *Neighbor) apply(fields []string, asn uint32) bool {
+	arg := func(i int) (int, bool) {
+		if len(fields) <= i {
+			return 0, false
+		}
+		v, err := strconv.Atoi(fields[i])
+		return v, err == nil
+	}
+
+	switch fields[0] {
+	case "activate":
+		return len(fields) == 1
+	case "peer-group":
+		if len(fields) == 2 {
+			n.PeerGroup = fields[1]
+		}
+		return len(fields) <= 2
+	case "remote-as":
+		if len(fields) != 2 {
+			return false
+		}
+		if fields[1] == "internal" {
+			n.RemoteASN = asn
+			return true
+		}
+		v, err := strconv.ParseUint(fields[1], 10, 32)
+		if err != nil {
+			return false
+		}
+		n.RemoteASN = uint32(v)
+		return true
+	case "description":
+		n.Description = strings.Join(fields[1:], " ")
+		return len(fields) > 1
+	case "password":
+		if len(fields) != 2 {
+			return false
+		}
+		n.Password = fields[1]
+		return true
+	case "shutdown":
+		n.Shutdown = true
+		return true
+	case "ebgp-multihop":
+		if len(fields) == 1 {
+			n.Multihop = 255
+			return true
+		}
+		v, ok := arg(1)
+		n.Multihop = v
+		return ok
+	case "update-source":
+		if len(fields) != 2 {
+			return false
+		}
+		n.UpdateSource = fields[1]
+		return true
+	case "timers":
+		if len(fields) == 3 && fields[1] == "connect" {
+			v, ok := arg(2)
+			n.ConnectRetry = v
+			return ok
+		}
+		keepalive, ok1 := arg(1)
+		holdTime, ok2 := arg(2)
+		if len(fields) != 3 || !ok1 || !ok2 {
+			return false
+		}
+		n.Keepalive, n.HoldTime = keepalive, holdTime
+		return true
+	case "passive":
+		n.Passive = true
+		return len(fields) == 1
+	case "ttl-security":
+		v, ok := arg(2)
+		n.TTLSecurityHops = v
+		return len(fields) == 3 && fields[1] == "hops" && ok
+	case "route-map", "prefix-list":
+		if len(fields) != 3 {
+			return false
+		}
+		switch fields[0] + " " + fields[2] {
+		case "route-map in":
+			n.RouteMapIn = fields[1]
+		case "route-map out":
+			n.RouteMapOut = fields[1]
+		case "prefix-list in":
+			n.PrefixListIn = fields[1]
+		case "prefix-list out":
+			n.PrefixListOut = fields[1]
+		default:
+			return false
+		}
+		return true
+	case "next-hop-self":
+		n.NextHopSelf = true
+		return len(fields) == 1
+	case "soft-reconfiguration":
+		n.SoftReconfigInbound = true
+		return len(fields) == 2 && fields[1] == "inbound"
+	case "remove-private-AS":
+		n.RemovePrivateAS = true
+		return len(fields) == 1
+	case "allowas-in":
+		if len(fields) == 1 {
+			n.AllowASIn = 3
+			return true
+		}
+		v, ok := arg(1)
+		n.AllowASIn = v
+		return ok
+	case "local-as":
+		return n.applyLocalAS(fields[1:])
+	case "maximum-prefix":
+		return n.applyMaximumPrefix(fields[1:])
+	}
+	return false
+}
+
+// applyLocalAS parses "local-as ASN [no-prepend [replace-as]]". dual-as is
+// not supported.
+func (n *Neighbor) applyLocalAS(fields []string) bool {
+	if len(fields) == 0 || len(fields) > 3 {
+		return false
+	}
+	v, err := strconv.ParseUint(fields[0], 10, 32)
+	if err != nil {
+		return false
+	}
+	n.LocalAS = uint32(v)
+	if len(fields) > 1 {
+		if fields[1] != "no-prepend" {
+			return false
+		}
+		n.LocalASNoPrepend = true
+	}
+	if len(fields) > 2 {
+		if fields[2] != "replace-as" {
+			return false
+		}
+		n.LocalASReplaceAS = true
+	}
+	return true
+}
+
+// applyMaximumPrefix parses "maximum-prefix LIMIT [THRESHOLD]
+// [warning-only|restart MINUTES]"
+func (n *Neighbor) applyMaximumPrefix(fields []string) bool {
+	if len(fields) == 0 {
+		return false
+	}
+	limit, err := strconv.Atoi(fields[0])
+	if err != nil {
+		return false
+	}
+	n.MaxPrefixes = limit
+	fields = fields[1:]
+	if len(fields) > 0 {
+		if threshold, err := strconv.Atoi(fields[0]); err == nil {
+			n.MaxPrefixThreshold = threshold
+			fields = fields[1:]
+		}
+	}
+	switch {
+	case len(fields) == 0:
+		return true
+	case len(fields) == 1 && fields[0] == "warning-only":
+		n.MaxPrefixWarningOnly = true
+		return true
+	case len(fields) == 2 && fields[0] == "restart":
+		restart, err := strconv.Atoi(fields[1])
+		n.MaxPrefixRestart = restart
+		return err == nil
+	}
+	return false
+}
+
+// Commands renders the neighbor's settings as "neighbor" statements,
+// starting with remote-as. A peer-group is declared first.
+func (n *Neighbor) Commands() []string {
+	var commands []string
+	add := func(format string, args ...interface{}) {
+		commands = append(commands, fmt.Sprintf("neighbor %s "+format, append([]interface{}{n.Name}, args...)...))
+	}
+
+	if n.IsPeerGroup {
+		add("peer-group")
+	}
+	if n.RemoteASN != 0 {
+		add("remote-as %d", n.RemoteASN)
+	}
+	if n.PeerGroup != "" {
+		add("peer-group %s", n.PeerGroup)
+	}
+	if n.Description != "" {
+		add("description %s", n.Description)
+	}
+	if n.Password != "" {
+		add("password %s", n.Password)
+	}
+	if n.Multihop > 1 {
+		add("ebgp-multihop %d", n.Multihop)
+	}
+	if n.UpdateSource != "" {
+		add("update-source %s", n.UpdateSource)
+	}
+	if n.RouteMapIn != "" {
+		add("route-map %s in", n.RouteMapIn)
+	}
+	if n.RouteMapOut != "" {
+		add("route-map %s out", n.RouteMapOut)
+	}
+	if n.PrefixListIn != "" {
+		add("prefix-list %s in", n.PrefixListIn)
+	}
+	if n.PrefixListOut != "" {
+		add("prefix-list %s out", n.PrefixListOut)
+	}
+	if command := n.MaximumPrefixCommand(); command != "" {
+		commands = append(commands, command)
+	}
+	commands = append(commands, n.OptionCommands()...)
+	if n.Shutdown {
+		add("shutdown")
+	}
+	return commands
+}
+
+// MaximumPrefixCommand renders the "maximum-prefix" statement, or "" when
+// MaxPrefixes is 0
+func (n *Neighbor) MaximumPrefixCommand() string {
+	if n.MaxPrefixes <= 0 {
+		return ""
+	}
+
+	command := fmt.Sprintf("neighbor %s maximum-prefix %d", n.Name, n.MaxPrefixes)
+	if n.MaxPrefixThreshold > 0 {
+		command += fmt.Sprintf(" %d", n.MaxPrefixThreshold)
+	}
+	if n.MaxPrefixWarningOnly {
+		command += " warning-only"
+	} else if n.MaxPrefixRestart > 0 {
+		command += fmt.Sprintf(" restart %d", n.MaxPrefixRestart)
+	}
+	return command
+}
+
+// OptionCommands renders the neighbor's timers and behaviour
+func (n *Neighbor) OptionCommands() []string {
+	var commands []string
+	add := func(format string, args ...interface{}) {
+		commands = append(commands, fmt.Sprintf("neighbor %s "+format, append([]interface{}{n.Name}, args...)...))
+	}
+
+	if n.LocalAS > 0 {
+		command := fmt.Sprintf("local-as %d", n.LocalAS)
+		if n.LocalASNoPrepend {
+			command += " no-prepend"
+			if n.LocalASReplaceAS {
+				command += " replace-as"
+			}
+		}
+		add("%s", command)
+	}
+	if n.Keepalive > 0 && n.HoldTime > 0 {
+		add("timers %d %d", n.Keepalive, n.HoldTime)
+	}
+	if n.ConnectRetry > 0 {
+		add("timers connect %d", n.ConnectRetry)
+	}
+	if n.Passive {
+		add("passive")
+	}
+	if n.TTLSecurityHops > 0 {
+		add("ttl-security hops %d", n.TTLSecurityHops)
+	}
+	if n.NextHopSelf {
+		add("next-hop-self")
+	}
+	if n.SoftReconfigInbound {
+		add("soft-reconfiguration inbound")
+	}
+	if n.RemovePrivateAS {
+		add("remove-private-AS")
+	}
+	if n.AllowASIn > 0 {
+		add("allowas-in %d", n.AllowASIn)
+	}
+	return commands
+}
+
+// String renders the instance as a "router bgp" block. Neighbor settings
+// are rendered at router level, where FRR accepts them for IPv4 unicast.
+func (r *Router) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "router bgp %d\n", r.ASN)
+	for _, st := range r.Statements {
+		b.WriteString(" " + st.Text + "\n")
+	}
+	for _, n := range r.Neighbors {
+		for _, command := range n.Commands() {
+			b.WriteString(" " + command + "\n")
+		}
+	}
+	b.WriteString("exit\n")
+	return b.String()
+}
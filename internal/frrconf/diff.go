@@ -0,0 +1,129 @@
+package frrconf
+
+import "strings"
+
+// Changes to a neighbor between two configurations
+const (
+	NeighborAdded   = "added"
+	NeighborRemoved = "removed"
+	NeighborChanged = "changed"
+)
+
+// NeighborChange is a neighbor configured differently by two configurations
+type NeighborChange struct {
+	// Name is the neighbor's IP address
+	Name   string `json:"name"`
+	Change string `json:"change"`
+	// Lines are the neighbor's statements that differ, as Diff returns them
+	Lines []string `json:"lines"`
+}
+
+// Diff compares two configurations line by line. Lines only in from are
+// returned prefixed with "- " and lines only in to with "+ ", in the order
+// they appear; unchanged lines are left out.
+func Diff(from, to string) []string {
+	return diffLines(splitLines(from), splitLines(to))
+}
+
+// ChangedNeighbors returns the neighbors of the default BGP instance that
+// to adds, removes or configures differently than from. Peer-group
+// settings are compared as the neighbors inherit them.
+func ChangedNeighbors(from, to *Config) []NeighborChange {
+	before, after := neighborCommands(from), neighborCommands(to)
+
+	var changes []NeighborChange
+	for _, name := range before.names {
+		commands, ok := after.commands[name]
+		if !ok {
+			changes = append(changes, NeighborChange{Name: name, Change: NeighborRemoved, Lines: diffLines(before.commands[name], nil)})
+			continue
+		}
+		if lines := diffLines(before.commands[name], commands); len(lines) > 0 {
+			changes = append(changes, NeighborChange{Name: name, Change: NeighborChanged, Lines: lines})
+		}
+	}
+	for _, name := range after.names {
+		if _, ok := before.commands[name]; !ok {
+			changes = append(changes, NeighborChange{Name: name, Change: NeighborAdded, Lines: diffLines(nil, after.commands[name])})
+		}
+	}
+	return changes
+}
+
+// neighbors holds the commands of a configuration's neighbors, by name
+type neighbors struct {
+	names    []string
+	commands map[string][]string
+}
+
+// neighborCommands renders the commands of the peers of c's BGP instance
+func neighborCommands(c *Config) neighbors {
+	n := neighbors{commands: make(map[string][]string)}
+	if c == nil || c.BGP == nil {
+		return n
+	}
+	for _, peer := range c.BGP.Peers() {
+		n.names = append(n.names, peer.Name)
+		n.commands[peer.Name] = peer.Commands()
+	}
+	return n
+}
+
+// splitLines splits configuration text into lines, without trailing
+// whitespace or a final empty line
+func splitLines(text string) []string {
+	text = strings.TrimRight(text, "\n")
+	if text == "" {
+		return nil
+	}
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " \t\r")
+	}
+	return lines
+}
+
+// diffLines returns the lines removed from a and added in b, keeping the
+// longest common subsequence of lines unchanged
+func diffLines(a, b []string) []string {
+	// Lines shared at the start and the end need no table
+	for len(a) > 0 && len(b) > 0 && a[0] == b[0] {
+		a, b = a[1:], b[1:]
+	}
+	for len(a) > 0 && len(b) > 0 && a[len(a)-1] == b[len(b)-1] {
+		a, b = a[:len(a)-1], b[:len(b)-1]
+	}
+
+	// common[i][j] is the length of the longest common subsequence of
+	// a[i:] and b[j:]
+	common := make([][]int, len(a)+1)
+	for i := range common {
+		common[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				common[i][j] = common[i+1][j+1] + 1
+			} else {
+				common[i][j] = max(common[i+1][j], common[i][j+1])
+			}
+		}
+	}
+
+	var lines []string
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			i++
+			j++
+		case j == len(b) || (i < len(a) && common[i+1][j] >= common[i][j+1]):
+			lines = append(lines, "- "+a[i])
+			i++
+		default:
+			lines = append(lines, "+ "+b[j])
+			j++
+		}
+	}
+	return lines
+}
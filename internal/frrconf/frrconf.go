@@ -0,0 +1,250 @@
+// Package frrconf parses and renders the FRR configuration dialect shown by
+// "show running-config": the default BGP instance with its neighbors and
+// peer-groups, prefix-lists, community-lists, as-path access-lists and
+// route-maps. Everything else is reported as unsupported rather than
+// guessed at.
+package frrconf
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Config is a parsed FRR configuration
+type Config struct {
+	// BGP is the default BGP instance; nil when there is none
+	BGP            *Router          `json:"bgp,omitempty"`
+	PrefixLists    []*PrefixList    `json:"prefix_lists,omitempty"`
+	CommunityLists []*CommunityList `json:"community_lists,omitempty"`
+	ASPathLists    []*ASPathList    `json:"as_path_lists,omitempty"`
+	RouteMaps      []*RouteMap      `json:"route_maps,omitempty"`
+	// Unsupported are the statements the package does not model
+	Unsupported []Unsupported `json:"unsupported,omitempty"`
+}
+
+// Statement is a configuration statement as FRR shows it. Line is 1-based.
+type Statement struct {
+	Line int `json:"line"`
+	// AddressFamily is the address family the statement is configured
+	// under, such as "ipv4 unicast"; empty at router level
+	AddressFamily string `json:"address_family,omitempty"`
+	Text          string `json:"text"`
+}
+
+// Unsupported is a statement the package does not model, and why
+type Unsupported struct {
+	Statement
+	Reason string `json:"reason"`
+}
+
+// Reasons statements are unsupported
+const (
+	ReasonNotModeled      = "not modeled by FlintRoute"
+	ReasonVRF             = "only the default BGP instance is supported"
+	ReasonAddressFamily   = "only the IPv4 and IPv6 unicast address families are supported"
+	ReasonNeighborSetting = "neighbor setting not modeled by FlintRoute"
+	ReasonNeighborName    = "only neighbors with an IP address are supported"
+	ReasonMalformed       = "malformed statement"
+)
+
+// Address families whose neighbor statements are modeled
+const (
+	IPv4Unicast = "ipv4 unicast"
+	IPv6Unicast = "ipv6 unicast"
+)
+
+// ignoredStatements are top-level statements that hold nothing to model
+var ignoredStatements = []string{
+	"Building configuration",
+	"Current configuration",
+	"frr version ",
+	"frr defaults ",
+	"hostname ",
+	"log ",
+	"service ",
+	"line vty",
+	"end",
+	"exit",
+}
+
+// Parse parses FRR configuration text
+func Parse(text string) *Config {
+	p := &parser{config: &Config{}}
+	for i, line := range strings.Split(text, "\n") {
+		p.line(i+1, line)
+	}
+	if p.config.BGP != nil {
+		p.config.BGP.check(p.unsupported)
+	}
+	return p.config
+}
+
+// parser holds the state of Parse between lines
+type parser struct {
+	config *Config
+	// block is the top-level block being read: "bgp", "route-map" or
+	// "skip" for blocks that aren't modeled
+	block    string
+	af       string
+	routeMap *RouteMapEntry
+}
+
+// unsupported records a statement that isn't modeled
+func (p *parser) unsupported(st Statement, reason string) {
+	p.config.Unsupported = append(p.config.Unsupported, Unsupported{Statement: st, Reason: reason})
+}
+
+// line parses one line of configuration
+func (p *parser) line(n int, line string) {
+	text := strings.TrimSpace(line)
+	if text == "" || text == "!" {
+		return
+	}
+	st := Statement{Line: n, Text: text}
+	fields := strings.Fields(text)
+
+	if !strings.HasPrefix(line, " ") {
+		p.block, p.af, p.routeMap = "", "", nil
+		p.topLevel(st, fields)
+		return
+	}
+
+	switch p.block {
+	case "route-map":
+		if text != "exit" {
+			p.routeMap.add(text)
+		}
+	case "bgp":
+		p.router(st, fields)
+	}
+}
+
+// topLevel parses an unindented statement
+func (p *parser) topLevel(st Statement, fields []string) {
+	c := p.config
+	ok := false
+	switch {
+	case fields[0] == "router" && len(fields) >= 3 && fields[1] == "bgp":
+		asn, err := strconv.ParseUint(fields[2], 10, 32)
+		switch {
+		case len(fields) > 3:
+			p.block = "skip"
+			p.unsupported(st, ReasonVRF)
+		case err != nil || c.BGP != nil:
+			p.block = "skip"
+			p.unsupported(st, ReasonMalformed)
+		default:
+			p.block = "bgp"
+			c.BGP = &Router{ASN: uint32(asn)}
+		}
+		return
+	case fields[0] == "route-map":
+		ok = p.routeMapHeader(fields)
+	case (fields[0] == "ip" || fields[0] == "ipv6") && len(fields) >= 3 && fields[1] == "prefix-list":
+		ok = p.prefixList(fields)
+	case len(fields) >= 3 && fields[0] == "bgp" && fields[1] == "community-list":
+		ok = p.communityList(fields)
+	case len(fields) >= 4 && fields[0] == "bgp" && fields[1] == "as-path" && fields[2] == "access-list":
+		ok = p.asPathList(fields)
+	case ignored(st.Text):
+		p.block = "skip"
+		return
+	default:
+		p.block = "skip"
+		p.unsupported(st, ReasonNotModeled)
+		return
+	}
+	if !ok {
+		p.block = "skip"
+		p.unsupported(st, ReasonMalformed)
+	}
+}
+
+// router parses a statement of the default BGP instance
+func (p *parser) router(st Statement, fields []string) {
+	r := p.config.BGP
+	switch {
+	case st.Text == "exit" || st.Text == "exit-address-family":
+		p.af = ""
+	case fields[0] == "address-family":
+		p.af = strings.TrimPrefix(st.Text, "address-family ")
+		if p.af != IPv4Unicast && p.af != IPv6Unicast {
+			p.unsupported(st, ReasonAddressFamily)
+			p.af = "skip"
+		}
+	case p.af == "skip":
+	case fields[0] == "neighbor" && len(fields) >= 3:
+		st.AddressFamily = p.af
+		r.neighbor(fields[1]).Statements = append(r.neighbor(fields[1]).Statements, st)
+	case p.af == "":
+		r.Statements = append(r.Statements, st)
+	default:
+		st.AddressFamily = p.af
+		p.unsupported(st, ReasonNotModeled)
+	}
+}
+
+// ignored reports whether a top-level statement holds nothing to model
+func ignored(statement string) bool {
+	for _, prefix := range ignoredStatements {
+		if statement == strings.TrimSpace(prefix) || strings.HasPrefix(statement, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// String renders the configuration. Unsupported statements are left out.
+func (c *Config) String() string {
+	var b strings.Builder
+	if c.BGP != nil {
+		b.WriteString(c.BGP.String())
+		b.WriteString("!\n")
+	}
+	for _, pl := range c.PrefixLists {
+		b.WriteString(pl.String())
+	}
+	for _, cl := range c.CommunityLists {
+		b.WriteString(cl.String())
+	}
+	for _, al := range c.ASPathLists {
+		b.WriteString(al.String())
+	}
+	if len(c.PrefixLists)+len(c.CommunityLists)+len(c.ASPathLists) > 0 {
+		b.WriteString("!\n")
+	}
+	for _, rm := range c.RouteMaps {
+		b.WriteString(rm.String())
+		b.WriteString("!\n")
+	}
+	return b.String()
+}
+
+// RemovalCommands returns the statements that delete the policy object
+// of kind named name, or none when the configuration doesn't define it
+func (c *Config) RemovalCommands(kind, name string) []string {
+	switch kind {
+	case KindRouteMap:
+		if rm := findRouteMap(c.RouteMaps, name); rm != nil {
+			return []string{"no route-map " + name}
+		}
+	case KindPrefixList:
+		if pl := findPrefixList(c.PrefixLists, name); pl != nil {
+			var commands []string
+			for _, family := range pl.families() {
+				commands = append(commands, fmt.Sprintf("no %s prefix-list %s", family, name))
+			}
+			return commands
+		}
+	case KindCommunityList:
+		if cl := findCommunityList(c.CommunityLists, name); cl != nil {
+			return []string{fmt.Sprintf("no bgp community-list %s %s", cl.Type, name)}
+		}
+	case KindASPathList:
+		if al := findASPathList(c.ASPathLists, name); al != nil {
+			return []string{"no bgp as-path access-list " + name}
+		}
+	}
+	return nil
+}
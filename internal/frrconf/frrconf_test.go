@@ -0,0 +1,181 @@
+package frrconf
+
+import (
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// golden compares got with testdata/name, rewriting it with -update
+func golden(t *testing.T, name, got string) {
+	t.Helper()
+	path := filepath.Join("testdata", name)
+	if *update {
+		require.NoError(t, os.WriteFile(path, []byte(got), 0o644))
+	}
+	want, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, string(want), got)
+}
+
+func TestParseGolden(t *testing.T) {
+	inputs, err := filepath.Glob(filepath.Join("testdata", "*.conf"))
+	require.NoError(t, err)
+	require.NotEmpty(t, inputs)
+
+	for _, input := range inputs {
+		name := strings.TrimSuffix(filepath.Base(input), ".conf")
+		t.Run(name, func(t *testing.T) {
+			text, err := os.ReadFile(input)
+			require.NoError(t, err)
+			config := Parse(string(text))
+
+			parsed, err := json.MarshalIndent(config, "", "  ")
+			require.NoError(t, err)
+			golden(t, name+".json", string(parsed)+"\n")
+
+			rendered := config.String()
+			golden(t, name+".golden", rendered)
+
+			// Rendering what was rendered changes nothing
+			reparsed := Parse(rendered)
+			assert.Empty(t, reparsed.Unsupported)
+			assert.Equal(t, rendered, reparsed.String())
+		})
+	}
+}
+
+func TestParse(t *testing.T) {
+	text, err := os.ReadFile(filepath.Join("testdata", "running.conf"))
+	require.NoError(t, err)
+	config := Parse(string(text))
+
+	t.Run("Peers", func(t *testing.T) {
+		require.NotNil(t, config.BGP)
+		peers := config.BGP.Peers()
+		require.Len(t, peers, 3)
+
+		member := peers[0]
+		assert.Equal(t, "192.0.2.1", member.Name)
+		assert.Equal(t, "TRANSIT", member.PeerGroup)
+		assert.Equal(t, uint32(65100), member.RemoteASN)
+		assert.Equal(t, 10, member.Keepalive)
+		assert.Equal(t, "TRANSIT-IN", member.RouteMapIn)
+		assert.Equal(t, "Transit A", member.Description)
+
+		assert.Equal(t, 1000, peers[1].MaxPrefixes)
+		assert.Equal(t, 80, peers[1].MaxPrefixThreshold)
+		assert.Equal(t, 5, peers[1].MaxPrefixRestart)
+		assert.Equal(t, uint32(65000), peers[2].RemoteASN)
+	})
+
+	t.Run("Lines", func(t *testing.T) {
+		router, family := config.BGP.Neighbor("192.0.2.2").Lines(IPv4Unicast)
+		assert.Equal(t, []string{
+			"neighbor 192.0.2.2 password s3cret",
+			"neighbor 192.0.2.2 ebgp-multihop 2",
+			"neighbor 192.0.2.2 shutdown",
+			"neighbor 192.0.2.2 bfd",
+		}, router)
+		assert.Equal(t, []string{
+			"neighbor 192.0.2.2 prefix-list CUSTOMER out",
+			"neighbor 192.0.2.2 maximum-prefix 1000 80 restart 5",
+			"neighbor 192.0.2.2 soft-reconfiguration inbound",
+		}, family)
+	})
+
+	t.Run("Unsupported", func(t *testing.T) {
+		var texts []string
+		for _, u := range config.Unsupported {
+			texts = append(texts, u.Text)
+		}
+		assert.Equal(t, []string{
+			"interface eth0",
+			"network 203.0.113.0/24",
+			"address-family l2vpn evpn",
+			"router bgp 65000 vrf customers",
+			"ip prefix-list BROKEN permit",
+			"neighbor 192.0.2.2 bfd",
+			"neighbor eth1 interface remote-as external",
+		}, texts)
+		assert.Equal(t, 9, config.Unsupported[0].Line)
+		assert.Equal(t, IPv4Unicast, config.Unsupported[1].AddressFamily)
+	})
+
+	t.Run("RemovalCommands", func(t *testing.T) {
+		assert.Equal(t, []string{"no ip prefix-list CUSTOMER", "no ipv6 prefix-list CUSTOMER"}, config.RemovalCommands(KindPrefixList, "CUSTOMER"))
+		assert.Equal(t, []string{"no bgp community-list expanded REGIONS"}, config.RemovalCommands(KindCommunityList, "REGIONS"))
+		assert.Equal(t, []string{"no bgp as-path access-list PRIVATE"}, config.RemovalCommands(KindASPathList, "PRIVATE"))
+		assert.Equal(t, []string{"no route-map TRANSIT-IN"}, config.RemovalCommands(KindRouteMap, "TRANSIT-IN"))
+		assert.Empty(t, config.RemovalCommands(KindRouteMap, "MISSING"))
+	})
+}
+
+func TestNeighborCommands(t *testing.T) {
+	n := &Neighbor{
+		Name:                 "192.0.2.1",
+		RemoteASN:            65001,
+		Password:             "s3cret",
+		Multihop:             2,
+		MaxPrefixes:          100,
+		MaxPrefixWarningOnly: true,
+		Keepalive:            10,
+		HoldTime:             30,
+		LocalAS:              64999,
+		LocalASNoPrepend:     true,
+		LocalASReplaceAS:     true,
+		Shutdown:             true,
+	}
+	assert.Equal(t, []string{
+		"neighbor 192.0.2.1 remote-as 65001",
+		"neighbor 192.0.2.1 password s3cret",
+		"neighbor 192.0.2.1 ebgp-multihop 2",
+		"neighbor 192.0.2.1 maximum-prefix 100 warning-only",
+		"neighbor 192.0.2.1 local-as 64999 no-prepend replace-as",
+		"neighbor 192.0.2.1 timers 10 30",
+		"neighbor 192.0.2.1 shutdown",
+	}, n.Commands())
+
+	// Every rendered statement parses back to the same settings
+	text := "router bgp 65000\n"
+	for _, command := range n.Commands() {
+		text += " " + command + "\n"
+	}
+	parsed := Parse(text)
+	assert.Empty(t, parsed.Unsupported)
+	assert.Equal(t, n.Commands(), parsed.BGP.Neighbors[0].Commands())
+}
+
+func TestDiff(t *testing.T) {
+	from := "router bgp 65000\n neighbor 192.0.2.1 remote-as 65001\n neighbor 192.0.2.2 remote-as 65002\nexit\n"
+	to := "router bgp 65000\n neighbor 192.0.2.1 remote-as 65001\n neighbor 192.0.2.3 remote-as 65003\n neighbor 192.0.2.3 shutdown\nexit\n"
+
+	assert.Equal(t, []string{
+		"-  neighbor 192.0.2.2 remote-as 65002",
+		"+  neighbor 192.0.2.3 remote-as 65003",
+		"+  neighbor 192.0.2.3 shutdown",
+	}, Diff(from, to))
+	assert.Empty(t, Diff(from, from+"\n"))
+
+	t.Run("Reports changed neighbors", func(t *testing.T) {
+		from := Parse("router bgp 65000\n neighbor UPSTREAM peer-group\n neighbor UPSTREAM remote-as 65001\n" +
+			" neighbor 192.0.2.1 peer-group UPSTREAM\n neighbor 192.0.2.2 remote-as 65002\n neighbor 192.0.2.4 remote-as 65004\nexit\n")
+		to := Parse("router bgp 65000\n neighbor UPSTREAM peer-group\n neighbor UPSTREAM remote-as 65001\n neighbor UPSTREAM shutdown\n" +
+			" neighbor 192.0.2.1 peer-group UPSTREAM\n neighbor 192.0.2.3 remote-as 65003\n neighbor 192.0.2.4 remote-as 65004\nexit\n")
+
+		assert.Equal(t, []NeighborChange{
+			{Name: "192.0.2.1", Change: NeighborChanged, Lines: []string{"+ neighbor 192.0.2.1 shutdown"}},
+			{Name: "192.0.2.2", Change: NeighborRemoved, Lines: []string{"- neighbor 192.0.2.2 remote-as 65002"}},
+			{Name: "192.0.2.3", Change: NeighborAdded, Lines: []string{"+ neighbor 192.0.2.3 remote-as 65003"}},
+		}, ChangedNeighbors(from, to))
+		assert.Empty(t, ChangedNeighbors(to, to))
+	})
+}
@@ -0,0 +1,345 @@
+package frrconf
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Policy object kinds, as FlintRoute names them
+const (
+	KindRouteMap      = "route-map"
+	KindPrefixList    = "prefix-list"
+	KindCommunityList = "community-list"
+	KindASPathList    = "as-path-list"
+)
+
+// RouteMap is a route-map and its entries
+type RouteMap struct {
+	Name    string           `json:"name"`
+	Entries []*RouteMapEntry `json:"entries"`
+}
+
+// RouteMapEntry is one sequence of a route-map
+type RouteMapEntry struct {
+	Action string   `json:"action"` // permit, deny
+	Seq    int      `json:"seq"`
+	Match  []string `json:"match,omitempty"` // clauses without "match "
+	Set    []string `json:"set,omitempty"`   // clauses without "set "
+	// Other holds the remaining statements, such as "call" and "on-match"
+	Other []string `json:"other,omitempty"`
+}
+
+// PrefixList is an IPv4 and/or IPv6 prefix-list
+type PrefixList struct {
+	Name    string             `json:"name"`
+	Entries []*PrefixListEntry `json:"entries"`
+}
+
+// PrefixListEntry is one sequence of a prefix-list
+type PrefixListEntry struct {
+	Family string `json:"family"` // ip, ipv6
+	Seq    int    `json:"seq"`
+	Action string `json:"action"` // permit, deny
+	Prefix string `json:"prefix"` // a prefix or "any"
+	GE     int    `json:"ge,omitempty"`
+	LE     int    `json:"le,omitempty"`
+}
+
+// CommunityList is a standard or expanded community-list
+type CommunityList struct {
+	Name    string      `json:"name"`
+	Type    string      `json:"type"` // standard, expanded
+	Entries []ListEntry `json:"entries"`
+}
+
+// ASPathList is an as-path access-list
+type ASPathList struct {
+	Name    string      `json:"name"`
+	Entries []ListEntry `json:"entries"`
+}
+
+// ListEntry is one sequence of a community-list or as-path access-list.
+// Value holds communities or a regular expression.
+type ListEntry struct {
+	Seq    int    `json:"seq"`
+	Action string `json:"action"` // permit, deny
+	Value  string `json:"value"`
+}
+
+// routeMapHeader parses "route-map NAME permit|deny SEQ", starting a
+// route-map entry
+func (p *parser) routeMapHeader(fields []string) bool {
+	if len(fields) != 4 || !isAction(fields[2]) {
+		return false
+	}
+	seq, err := strconv.Atoi(fields[3])
+	if err != nil {
+		return false
+	}
+
+	entry := &RouteMapEntry{Action: fields[2], Seq: seq}
+	rm := findRouteMap(p.config.RouteMaps, fields[1])
+	if rm == nil {
+		rm = &RouteMap{Name: fields[1]}
+		p.config.RouteMaps = append(p.config.RouteMaps, rm)
+	}
+	rm.Entries = append(rm.Entries, entry)
+	p.block, p.routeMap = "route-map", entry
+	return true
+}
+
+// add adds a statement of the entry's block
+func (e *RouteMapEntry) add(text string) {
+	if clause, ok := strings.CutPrefix(text, "match "); ok {
+		e.Match = append(e.Match, clause)
+	} else if clause, ok := strings.CutPrefix(text, "set "); ok {
+		e.Set = append(e.Set, clause)
+	} else {
+		e.Other = append(e.Other, text)
+	}
+}
+
+// prefixList parses "ip|ipv6 prefix-list NAME [seq N] permit|deny
+// PREFIX|any [ge N] [le N]"
+func (p *parser) prefixList(fields []string) bool {
+	family, name := fields[0], fields[2]
+	fields = fields[3:]
+	pl := findPrefixList(p.config.PrefixLists, name)
+
+	seq := 5
+	if pl != nil {
+		seq = (len(pl.Entries) + 1) * 5
+	}
+	if len(fields) >= 2 && fields[0] == "seq" {
+		n, err := strconv.Atoi(fields[1])
+		if err != nil {
+			return false
+		}
+		seq, fields = n, fields[2:]
+	}
+	if len(fields) < 2 || !isAction(fields[0]) {
+		return false
+	}
+	entry := &PrefixListEntry{Family: family, Seq: seq, Action: fields[0], Prefix: fields[1]}
+	for rest := fields[2:]; len(rest) > 0; rest = rest[2:] {
+		if len(rest) < 2 {
+			return false
+		}
+		n, err := strconv.Atoi(rest[1])
+		if err != nil {
+			return false
+		}
+		switch rest[0] {
+		case "ge":
+			entry.GE = n
+		case "le":
+			entry.LE = n
+		default:
+			return false
+		}
+	}
+
+	if pl == nil {
+		pl = &PrefixList{Name: name}
+		p.config.PrefixLists = append(p.config.PrefixLists, pl)
+	}
+	pl.Entries = append(pl.Entries, entry)
+	return true
+}
+
+// communityList parses "bgp community-list standard|expanded NAME [seq N]
+// permit|deny VALUE"
+func (p *parser) communityList(fields []string) bool {
+	if len(fields) < 5 || (fields[2] != "standard" && fields[2] != "expanded") {
+		return false
+	}
+	cl := findCommunityList(p.config.CommunityLists, fields[3])
+	if cl != nil && cl.Type != fields[2] {
+		return false
+	}
+	var entries []ListEntry
+	if cl != nil {
+		entries = cl.Entries
+	}
+	entries, ok := addListEntry(entries, fields[4:])
+	if !ok {
+		return false
+	}
+
+	if cl == nil {
+		cl = &CommunityList{Name: fields[3], Type: fields[2]}
+		p.config.CommunityLists = append(p.config.CommunityLists, cl)
+	}
+	cl.Entries = entries
+	return true
+}
+
+// asPathList parses "bgp as-path access-list NAME [seq N] permit|deny
+// REGEX"
+func (p *parser) asPathList(fields []string) bool {
+	al := findASPathList(p.config.ASPathLists, fields[3])
+	var entries []ListEntry
+	if al != nil {
+		entries = al.Entries
+	}
+	entries, ok := addListEntry(entries, fields[4:])
+	if !ok {
+		return false
+	}
+
+	if al == nil {
+		al = &ASPathList{Name: fields[3]}
+		p.config.ASPathLists = append(p.config.ASPathLists, al)
+	}
+	al.Entries = entries
+	return true
+}
+
+// addListEntry parses "[seq N] permit|deny VALUE..." and appends it to
+// entries. Entries without a sequence number are numbered in steps of 5,
+// as FRR does.
+func addListEntry(entries []ListEntry, fields []string) ([]ListEntry, bool) {
+	seq := (len(entries) + 1) * 5
+	if len(fields) >= 2 && fields[0] == "seq" {
+		n, err := strconv.Atoi(fields[1])
+		if err != nil {
+			return entries, false
+		}
+		seq, fields = n, fields[2:]
+	}
+	if len(fields) < 2 || !isAction(fields[0]) {
+		return entries, false
+	}
+	return append(entries, ListEntry{Seq: seq, Action: fields[0], Value: strings.Join(fields[1:], " ")}), true
+}
+
+// isAction reports whether s is a permit or deny action
+func isAction(s string) bool {
+	return s == "permit" || s == "deny"
+}
+
+// String renders the route-map, entries in sequence order
+func (rm *RouteMap) String() string {
+	entries := append([]*RouteMapEntry(nil), rm.Entries...)
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].Seq < entries[j].Seq })
+
+	var b strings.Builder
+	for _, entry := range entries {
+		fmt.Fprintf(&b, "route-map %s %s %d\n", rm.Name, entry.Action, entry.Seq)
+		for _, match := range entry.Match {
+			fmt.Fprintf(&b, " match %s\n", match)
+		}
+		for _, set := range entry.Set {
+			fmt.Fprintf(&b, " set %s\n", set)
+		}
+		for _, other := range entry.Other {
+			fmt.Fprintf(&b, " %s\n", other)
+		}
+	}
+	return b.String()
+}
+
+// String renders the prefix-list, entries in sequence order
+func (pl *PrefixList) String() string {
+	entries := append([]*PrefixListEntry(nil), pl.Entries...)
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].Seq < entries[j].Seq })
+
+	var b strings.Builder
+	for _, entry := range entries {
+		fmt.Fprintf(&b, "%s prefix-list %s seq %d %s %s", entry.Family, pl.Name, entry.Seq, entry.Action, entry.Prefix)
+		if entry.GE != 0 {
+			fmt.Fprintf(&b, " ge %d", entry.GE)
+		}
+		if entry.LE != 0 {
+			fmt.Fprintf(&b, " le %d", entry.LE)
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// families returns the address families the prefix-list has entries for
+func (pl *PrefixList) families() []string {
+	var families []string
+	for _, family := range []string{"ip", "ipv6"} {
+		for _, entry := range pl.Entries {
+			if entry.Family == family {
+				families = append(families, family)
+				break
+			}
+		}
+	}
+	return families
+}
+
+// String renders the community-list, entries in sequence order. Standard
+// communities are separated by single spaces.
+func (cl *CommunityList) String() string {
+	var b strings.Builder
+	for _, entry := range sortedEntries(cl.Entries) {
+		value := entry.Value
+		if cl.Type == "standard" {
+			value = strings.Join(strings.Fields(value), " ")
+		}
+		fmt.Fprintf(&b, "bgp community-list %s %s seq %d %s %s\n", cl.Type, cl.Name, entry.Seq, entry.Action, value)
+	}
+	return b.String()
+}
+
+// String renders the as-path access-list, entries in sequence order
+func (al *ASPathList) String() string {
+	var b strings.Builder
+	for _, entry := range sortedEntries(al.Entries) {
+		fmt.Fprintf(&b, "bgp as-path access-list %s seq %d %s %s\n", al.Name, entry.Seq, entry.Action, entry.Value)
+	}
+	return b.String()
+}
+
+// sortedEntries returns a copy of entries in sequence order
+func sortedEntries(entries []ListEntry) []ListEntry {
+	sorted := append([]ListEntry(nil), entries...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Seq < sorted[j].Seq })
+	return sorted
+}
+
+// findRouteMap returns the route-map named name, or nil
+func findRouteMap(list []*RouteMap, name string) *RouteMap {
+	for _, rm := range list {
+		if rm.Name == name {
+			return rm
+		}
+	}
+	return nil
+}
+
+// findPrefixList returns the prefix-list named name, or nil
+func findPrefixList(list []*PrefixList, name string) *PrefixList {
+	for _, pl := range list {
+		if pl.Name == name {
+			return pl
+		}
+	}
+	return nil
+}
+
+// findCommunityList returns the community-list named name, or nil
+func findCommunityList(list []*CommunityList, name string) *CommunityList {
+	for _, cl := range list {
+		if cl.Name == name {
+			return cl
+		}
+	}
+	return nil
+}
+
+// findASPathList returns the as-path access-list named name, or nil
+func findASPathList(list []*ASPathList, name string) *ASPathList {
+	for _, al := range list {
+		if al.Name == name {
+			return al
+		}
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+package frrlog
+
+import (
+	"context"
+
+	"github.com/padminisys/flintroute/pkg/models"
+)
+
+// initiatorKey is the type of the context key holding the initiator
+type initiatorKey struct{}
+
+// initiator is who or what a change to FRR is made for, beyond the request
+// ID, job and tenant the context carries anyway
+type initiator struct {
+	userID          *uint
+	username        string
+	peerID          *uint
+	tenantID        *uint
+	configVersionID *uint
+	// batch holds the stored peers of a batch, by IP address
+	batch map[string]*models.BGPPeer
+}
+
+func withInitiator(ctx context.Context, update func(*initiator)) context.Context {
+	i, _ := ctx.Value(initiatorKey{}).(initiator)
+	update(&i)
+	return context.WithValue(ctx, initiatorKey{}, i)
+}
+
+// WithUser returns a copy of ctx whose changes are made by the user
+func WithUser(ctx context.Context, id uint, username string) context.Context {
+	return withInitiator(ctx, func(i *initiator) {
+		i.userID = &id
+		i.username = username
+	})
+}
+
+// WithPeer returns a copy of ctx whose changes are made to peer, and are
+// recorded for its tenant. Peers not stored yet are left out.
+func WithPeer(ctx context.Context, peer *models.BGPPeer) context.Context {
+	if peer.ID == 0 {
+		return ctx
+	}
+	id := peer.ID
+	return withInitiator(ctx, func(i *initiator) {
+		i.peerID = &id
+		i.tenantID = peer.TenantID
+	})
+}
+
+// WithPeers returns a copy of ctx whose batches of changes are made to
+// peers, each recorded as WithPeer would. Peers not stored yet are left
+// out.
+func WithPeers(ctx context.Context, peers []*models.BGPPeer) context.Context {
+	batch := make(map[string]*models.BGPPeer, len(peers))
+	for _, peer := range peers {
+		if peer.ID != 0 {
+			batch[peer.IPAddress] = peer
+		}
+	}
+	return withInitiator(ctx, func(i *initiator) { i.batch = batch })
+}
+
+// WithConfigVersion returns a copy of ctx whose changes restore, or roll
+// back to, config version id
+func WithConfigVersion(ctx context.Context, id uint) context.Context {
+	return withInitiator(ctx, func(i *initiator) { i.configVersionID = &id })
+}
@@ -0,0 +1,380 @@
+// Package frrlog records every change FlintRoute pushes to FRR as an
+// FRRTransaction: the configuration pushed, how FRR's running
+// configuration changed, whether FRR took it, how long it took and the
+// user or job that made it. A context acting for a tenant only sees that
+// tenant's transactions.
+package frrlog
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/padminisys/flintroute/internal/database"
+	"github.com/padminisys/flintroute/internal/frr"
+	"github.com/padminisys/flintroute/internal/frrconf"
+	"github.com/padminisys/flintroute/internal/jobs"
+	"github.com/padminisys/flintroute/internal/requestid"
+	"github.com/padminisys/flintroute/internal/tenancy"
+	"github.com/padminisys/flintroute/pkg/models"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// Operations recorded
+const (
+	OpPeerAdd      = "peer.add"
+	OpPeerUpdate   = "peer.update"
+	OpPeerRemove   = "peer.remove"
+	OpGlobalApply  = "global.apply"
+	OpPolicyApply  = "policy.apply"
+	OpPolicyRemove = "policy.remove"
+	// OpOutOfBand is a change made outside FlintRoute, such as through
+	// vtysh
+	OpOutOfBand = "out_of_band"
+)
+
+// ErrTransactionNotFound is returned for a transaction that doesn't exist,
+// or belongs to another tenant
+var ErrTransactionNotFound = errors.New("FRR transaction not found")
+
+// redactedPassword replaces peer passwords in recorded configuration
+const redactedPassword = "<redacted>"
+
+// passwordLine matches neighbor password statements, with any "- " or
+// "+ " diff prefix
+var passwordLine = regexp.MustCompile(`^((?:[-+] )?\s*(?:no )?neighbor \S+ password )\S+`)
+
+// Query selects transactions, newest first
+type Query struct {
+	PeerID          *uint
+	ConfigVersionID *uint
+	JobID           *uint
+	Username        string
+	Operation       string
+	Result          string
+	// Before continues from a previous page, listing transactions with
+	// lower IDs
+	Before uint
+	Limit  int
+}
+
+// Log records the changes made through the FRR clients it wraps
+type Log struct {
+	db     *database.DB
+	logger *zap.Logger
+
+	// changeMu serializes changes and out-of-band checks, so that each
+	// transaction's delta holds only its own change
+	changeMu sync.Mutex
+	// head is the running configuration FlintRoute last saw, once known
+	head      runningConfig
+	headKnown bool
+
+	mu      sync.Mutex
+	queue   []*models.FRRTransaction // waiting to be stored
+	storing bool                     // whether store is running
+	// pending counts the transactions queued or being stored
+	pending sync.WaitGroup
+}
+
+// NewLog creates an FRR transaction log
+func NewLog(db *database.DB, logger *zap.Logger) *Log {
+	return &Log{db: db, logger: logger}
+}
+
+// Wrap returns client recording its changes in the log
+func (l *Log) Wrap(client frr.FRRClient) frr.FRRClient {
+	return &recordingClient{FRRClient: client, log: l}
+}
+
+// Wait waits until the transactions recorded so far are stored
+func (l *Log) Wait() {
+	l.pending.Wait()
+}
+
+// List returns the transactions matching q
+func (l *Log) List(ctx context.Context, q Query) ([]models.FRRTransaction, error) {
+	query := l.db.WithContext(ctx).Scopes(tenancy.Scope(ctx, "frr_transactions"))
+	if q.PeerID != nil {
+		query = query.Where("peer_id = ?", *q.PeerID)
+	}
+	if q.ConfigVersionID != nil {
+		query = query.Where("config_version_id = ?", *q.ConfigVersionID)
+	}
+	if q.JobID != nil {
+		query = query.Where("job_id = ?", *q.JobID)
+	}
+	if q.Username != "" {
+		query = query.Where("username = ?", q.Username)
+	}
+	if q.Operation != "" {
+		query = query.Where("operation = ?", q.Operation)
+	}
+	if q.Result != "" {
+		query = query.Where("result = ?", q.Result)
+	}
+	return l.find(query, q)
+}
+
+// ListForVersion returns the transactions made while restoring or rolling
+// back to version, and those that produced its configuration
+func (l *Log) ListForVersion(ctx context.Context, version *models.ConfigVersion, q Query) ([]models.FRRTransaction, error) {
+	query := l.db.WithContext(ctx).Scopes(tenancy.Scope(ctx, "frr_transactions")).
+		Where("config_version_id = ? OR commit_id = ?", version.ID, version.Hash)
+	return l.find(query, q)
+}
+
+func (l *Log) find(query *gorm.DB, q Query) ([]models.FRRTransaction, error) {
+	if q.Before > 0 {
+		query = query.Where("id < ?", q.Before)
+	}
+	if q.Limit > 0 {
+		query = query.Limit(q.Limit)
+	}
+
+	var transactions []models.FRRTransaction
+	if err := query.Order("id DESC").Find(&transactions).Error; err != nil {
+		return nil, fmt.Errorf("failed to list FRR transactions: %w", err)
+	}
+	return transactions, nil
+}
+
+// Get returns a transaction by ID
+func (l *Log) Get(ctx context.Context, id uint) (*models.FRRTransaction, error) {
+	var transaction models.FRRTransaction
+	err := l.db.WithContext(ctx).Scopes(tenancy.Scope(ctx, "frr_transactions")).First(&transaction, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrTransactionNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get FRR transaction: %w", err)
+	}
+	return &transaction, nil
+}
+
+// record stores a transaction in the background. In strict consistency
+// mode peers are pushed to FRR while their database transaction holds
+// SQLite's write lock, so storing it in line would wait on that lock.
+// Transactions are stored in the order they were made.
+func (l *Log) record(ctx context.Context, transaction *models.FRRTransaction) {
+	initiator, _ := ctx.Value(initiatorKey{}).(initiator)
+	transaction.PeerID = initiator.peerID
+	transaction.ConfigVersionID = initiator.configVersionID
+	transaction.UserID = initiator.userID
+	transaction.Username = initiator.username
+	if job, ok := jobs.FromContext(ctx); ok {
+		transaction.JobID = &job.ID
+		if transaction.UserID == nil {
+			transaction.UserID = job.CreatedBy
+		}
+	}
+	transaction.RequestID = requestid.FromContext(ctx)
+	transaction.TenantID = tenancy.ID(ctx)
+	if initiator.peerID != nil {
+		transaction.TenantID = initiator.tenantID
+	}
+
+	l.pending.Add(1)
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.queue = append(l.queue, transaction)
+	if !l.storing {
+		l.storing = true
+		go l.store()
+	}
+}
+
+// store stores the queued transactions until none are left
+func (l *Log) store() {
+	for {
+		l.mu.Lock()
+		if len(l.queue) == 0 {
+			l.storing = false
+			l.mu.Unlock()
+			return
+		}
+		transaction := l.queue[0]
+		l.queue = l.queue[1:]
+		l.mu.Unlock()
+
+		if transaction.UserID != nil && transaction.Username == "" {
+			var user models.User
+			if err := l.db.Select("username").First(&user, *transaction.UserID).Error; err == nil {
+				transaction.Username = user.Username
+			}
+		}
+		if err := l.db.Create(transaction).Error; err != nil {
+			l.logger.Error("Failed to record FRR transaction",
+				zap.String("operation", transaction.Operation),
+				zap.String("target", transaction.Target),
+				zap.Error(err),
+			)
+		}
+		l.pending.Done()
+	}
+}
+
+// recordingClient records the changes made through an FRR client
+type recordingClient struct {
+	frr.FRRClient
+	log *Log
+}
+
+var _ frr.PeerBatcher = (*recordingClient)(nil)
+
+func (c *recordingClient) AddBGPPeer(ctx context.Context, config *frr.BGPPeerConfig) error {
+	return c.change(ctx, OpPeerAdd, config.IPAddress, peerConfig(config), func() error {
+		return c.FRRClient.AddBGPPeer(ctx, config)
+	})
+}
+
+func (c *recordingClient) UpdateBGPPeer(ctx context.Context, config *frr.BGPPeerConfig) error {
+	return c.change(ctx, OpPeerUpdate, config.IPAddress, peerConfig(config), func() error {
+		return c.FRRClient.UpdateBGPPeer(ctx, config)
+	})
+}
+
+func (c *recordingClient) RemoveBGPPeer(ctx context.Context, ipAddress string) error {
+	return c.change(ctx, OpPeerRemove, ipAddress, "", func() error {
+		return c.FRRClient.RemoveBGPPeer(ctx, ipAddress)
+	})
+}
+
+func (c *recordingClient) ApplyBGPGlobal(ctx context.Context, config *frr.BGPGlobalConfig) error {
+	return c.change(ctx, OpGlobalApply, "", config.Config(), func() error {
+		return c.FRRClient.ApplyBGPGlobal(ctx, config)
+	})
+}
+
+func (c *recordingClient) ApplyPolicy(ctx context.Context, kind, name, config string) error {
+	return c.change(ctx, OpPolicyApply, kind+" "+name, config, func() error {
+		return c.FRRClient.ApplyPolicy(ctx, kind, name, config)
+	})
+}
+
+func (c *recordingClient) RemovePolicy(ctx context.Context, kind, name string) error {
+	return c.change(ctx, OpPolicyRemove, kind+" "+name, "", func() error {
+		return c.FRRClient.RemovePolicy(ctx, kind, name)
+	})
+}
+
+// UpdateBGPPeers records a batch of peers pushed in one commit as a
+// transaction per peer. They share the commit IDs, and each delta holds the
+// peer's own statements.
+func (c *recordingClient) UpdateBGPPeers(ctx context.Context, configs []*frr.BGPPeerConfig) error {
+	c.log.changeMu.Lock()
+	defer c.log.changeMu.Unlock()
+
+	o := c.observe(ctx, func() error { return frr.UpdateBGPPeers(ctx, c.FRRClient, configs) })
+
+	changed := make(map[string][]string)
+	if o.read {
+		for _, change := range frrconf.ChangedNeighbors(frrconf.Parse(o.before), frrconf.Parse(o.after)) {
+			changed[change.Name] = change.Lines
+		}
+	}
+	initiator, _ := ctx.Value(initiatorKey{}).(initiator)
+	for _, config := range configs {
+		transaction := o.transaction(OpPeerUpdate, config.IPAddress, peerConfig(config), changed[config.IPAddress])
+		peerCtx := ctx
+		if peer, ok := initiator.batch[config.IPAddress]; ok {
+			peerCtx = WithPeer(ctx, peer)
+		}
+		c.log.record(peerCtx, transaction)
+	}
+	return o.err
+}
+
+// change makes a change through apply and records it
+func (c *recordingClient) change(ctx context.Context, operation, target, config string, apply func() error) error {
+	c.log.changeMu.Lock()
+	defer c.log.changeMu.Unlock()
+
+	o := c.observe(ctx, apply)
+	var delta []string
+	if o.read {
+		delta = frrconf.Diff(o.before, o.after)
+	}
+	c.log.record(ctx, o.transaction(operation, target, config, delta))
+	return o.err
+}
+
+// observation is how a change through the client went
+type observation struct {
+	start    time.Time
+	duration time.Duration
+	err      error
+	// before and after are FRR's running configuration around the change,
+	// when read is set
+	before, after string
+	read          bool
+}
+
+// observe makes a change through apply, reading FRR's running
+// configuration around it. changeMu must be held.
+func (c *recordingClient) observe(ctx context.Context, apply func() error) observation {
+	before, beforeErr := c.FRRClient.GetRunningConfig(ctx)
+	o := observation{start: time.Now()}
+	o.err = apply()
+	o.duration = time.Since(o.start)
+
+	if beforeErr == nil {
+		if after, err := c.FRRClient.GetRunningConfig(ctx); err == nil {
+			o.before, o.after, o.read = before, after, true
+			c.log.setHead(after)
+		}
+	}
+	return o
+}
+
+// transaction builds the transaction recording the change. The delta and
+// commit IDs are left empty when FRR's running configuration couldn't be
+// read.
+func (o observation) transaction(operation, target, config string, delta []string) *models.FRRTransaction {
+	transaction := &models.FRRTransaction{
+		CreatedAt:  o.start,
+		Operation:  operation,
+		Target:     target,
+		Config:     config,
+		Delta:      append([]string{}, redact(delta)...),
+		Result:     models.FRRTransactionSucceeded,
+		DurationMS: o.duration.Milliseconds(),
+	}
+	if o.err != nil {
+		transaction.Result = models.FRRTransactionFailed
+		transaction.Error = o.err.Error()
+	}
+	if o.read {
+		transaction.ParentCommitID = commitID(o.before)
+		transaction.CommitID = commitID(o.after)
+	}
+	return transaction
+}
+
+// peerConfig renders a peer's configuration with its password redacted
+func peerConfig(config *frr.BGPPeerConfig) string {
+	redacted := *config
+	if redacted.Password != "" {
+		redacted.Password = redactedPassword
+	}
+	return redacted.Config()
+}
+
+// commitID identifies a running configuration the way config versions'
+// hashes do
+func commitID(config string) string {
+	return fmt.Sprintf("%x", sha256.Sum256([]byte(config)))
+}
+
+// redact replaces the passwords in neighbor password lines
+func redact(lines []string) []string {
+	for i, line := range lines {
+		lines[i] = passwordLine.ReplaceAllString(line, "${1}"+redactedPassword)
+	}
+	return lines
+}
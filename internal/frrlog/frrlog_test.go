@@ -0,0 +1,216 @@
+package frrlog
+
+import (
+	"context"
+	"testing"
+
+	"github.com/padminisys/flintroute/internal/frr"
+	"github.com/padminisys/flintroute/internal/jobs"
+	"github.com/padminisys/flintroute/internal/requestid"
+	"github.com/padminisys/flintroute/internal/tenancy"
+	"github.com/padminisys/flintroute/internal/testutil"
+	"github.com/padminisys/flintroute/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLog(t *testing.T) {
+	db := testutil.SetupTestDB(t)
+	logger := testutil.CreateTestLogger()
+	log := NewLog(db, logger)
+
+	demo := frr.NewDemoClient(frr.DemoOptions{}, logger)
+	require.NoError(t, demo.Connect(context.Background()))
+	defer demo.Close()
+	client := log.Wrap(demo)
+
+	user := &models.User{Username: "operator", Email: "operator@example.com", PasswordHash: "x", Role: "operator"}
+	require.NoError(t, db.Create(user).Error)
+	tenantID := uint(7)
+	peer := &models.BGPPeer{ID: 3, IPAddress: "192.0.2.1", TenantID: &tenantID}
+	config := &frr.BGPPeerConfig{IPAddress: "192.0.2.1", ASN: 65000, RemoteASN: 65001, Password: "s3cret"}
+
+	t.Run("Records changes with their delta and initiator", func(t *testing.T) {
+		before, err := demo.GetRunningConfig(context.Background())
+		require.NoError(t, err)
+
+		ctx := requestid.NewContext(context.Background(), "req-1")
+		ctx = WithPeer(WithUser(ctx, user.ID, user.Username), peer)
+		require.NoError(t, client.AddBGPPeer(ctx, config))
+		log.Wait()
+
+		after, err := demo.GetRunningConfig(context.Background())
+		require.NoError(t, err)
+
+		transactions, err := log.List(context.Background(), Query{})
+		require.NoError(t, err)
+		require.Len(t, transactions, 1)
+		tx := transactions[0]
+		assert.Equal(t, OpPeerAdd, tx.Operation)
+		assert.Equal(t, "192.0.2.1", tx.Target)
+		assert.Equal(t, models.FRRTransactionSucceeded, tx.Result)
+		assert.Equal(t, commitID(before), tx.ParentCommitID)
+		assert.Equal(t, commitID(after), tx.CommitID)
+		assert.Contains(t, tx.Delta, "+  neighbor 192.0.2.1 remote-as 65001")
+		assert.Contains(t, tx.Delta, "+  neighbor 192.0.2.1 password <redacted>")
+		assert.Contains(t, tx.Config, "neighbor 192.0.2.1 password <redacted>")
+		assert.NotContains(t, tx.Config, "s3cret")
+		assert.Equal(t, "s3cret", config.Password, "the pushed configuration is left alone")
+		assert.Equal(t, &peer.ID, tx.PeerID)
+		assert.Equal(t, &tenantID, tx.TenantID)
+		assert.Equal(t, &user.ID, tx.UserID)
+		assert.Equal(t, "operator", tx.Username)
+		assert.Equal(t, "req-1", tx.RequestID)
+	})
+
+	t.Run("Records jobs and their creator", func(t *testing.T) {
+		job := &models.Job{ID: 12, Type: "config_restore", CreatedBy: &user.ID}
+		ctx := WithConfigVersion(jobs.NewContext(context.Background(), job), 4)
+		require.NoError(t, client.ApplyBGPGlobal(ctx, &frr.BGPGlobalConfig{ASN: 65000, RouterID: "192.0.2.254"}))
+		log.Wait()
+
+		versionID := uint(4)
+		transactions, err := log.List(context.Background(), Query{ConfigVersionID: &versionID})
+		require.NoError(t, err)
+		require.Len(t, transactions, 1)
+		tx := transactions[0]
+		assert.Equal(t, OpGlobalApply, tx.Operation)
+		assert.Equal(t, &job.ID, tx.JobID)
+		assert.Equal(t, &user.ID, tx.UserID)
+		assert.Equal(t, "operator", tx.Username, "looked up from the job's creator")
+		assert.Contains(t, tx.Delta, "+  bgp router-id 192.0.2.254")
+
+		found, err := log.ListForVersion(context.Background(), &models.ConfigVersion{ID: 99, Hash: tx.CommitID}, Query{})
+		require.NoError(t, err)
+		require.Len(t, found, 1, "found by the configuration it produced")
+		assert.Equal(t, tx.ID, found[0].ID)
+	})
+
+	t.Run("Records failed changes", func(t *testing.T) {
+		failing := frr.NewMockClient()
+		failing.On("GetRunningConfig", mock.Anything).Return("", frr.ErrNotConnected)
+		failing.On("RemoveBGPPeer", mock.Anything, "192.0.2.9").Return(frr.ErrNotConnected)
+
+		err := log.Wrap(failing).RemoveBGPPeer(context.Background(), "192.0.2.9")
+		assert.ErrorIs(t, err, frr.ErrNotConnected)
+		log.Wait()
+
+		transactions, err := log.List(context.Background(), Query{Result: models.FRRTransactionFailed})
+		require.NoError(t, err)
+		require.Len(t, transactions, 1)
+		assert.Equal(t, OpPeerRemove, transactions[0].Operation)
+		assert.Equal(t, frr.ErrNotConnected.Error(), transactions[0].Error)
+		assert.Empty(t, transactions[0].Delta)
+		assert.Empty(t, transactions[0].CommitID)
+	})
+
+	t.Run("Lists newest first and by tenant", func(t *testing.T) {
+		transactions, err := log.List(context.Background(), Query{Limit: 2})
+		require.NoError(t, err)
+		require.Len(t, transactions, 2)
+		assert.Equal(t, OpPeerRemove, transactions[0].Operation)
+		assert.Equal(t, OpGlobalApply, transactions[1].Operation)
+
+		transactions, err = log.List(context.Background(), Query{Before: transactions[1].ID})
+		require.NoError(t, err)
+		require.Len(t, transactions, 1)
+		assert.Equal(t, OpPeerAdd, transactions[0].Operation)
+
+		transactions, err = log.List(tenancy.WithTenant(context.Background(), tenantID), Query{})
+		require.NoError(t, err)
+		require.Len(t, transactions, 1)
+		assert.Equal(t, OpPeerAdd, transactions[0].Operation)
+
+		_, err = log.Get(tenancy.WithTenant(context.Background(), 8), transactions[0].ID)
+		assert.ErrorIs(t, err, ErrTransactionNotFound)
+	})
+
+	t.Run("Records a batch as a transaction per peer", func(t *testing.T) {
+		other := &models.BGPPeer{ID: 4, IPAddress: "192.0.2.2"}
+		ctx := WithPeers(context.Background(), []*models.BGPPeer{peer, other})
+		require.NoError(t, frr.UpdateBGPPeers(ctx, client, []*frr.BGPPeerConfig{
+			{IPAddress: "192.0.2.1", ASN: 65000, RemoteASN: 65011},
+			{IPAddress: "192.0.2.2", ASN: 65000, RemoteASN: 65012},
+		}))
+		log.Wait()
+
+		transactions, err := log.List(context.Background(), Query{Operation: OpPeerUpdate})
+		require.NoError(t, err)
+		require.Len(t, transactions, 2)
+		assert.Equal(t, transactions[0].CommitID, transactions[1].CommitID, "one commit")
+		for _, tx := range transactions {
+			require.NotNil(t, tx.PeerID)
+			ip := map[uint]string{peer.ID: "192.0.2.1", other.ID: "192.0.2.2"}[*tx.PeerID]
+			assert.Equal(t, ip, tx.Target)
+			require.NotEmpty(t, tx.Delta)
+			for _, line := range tx.Delta {
+				assert.Contains(t, line, "neighbor "+ip+" ", "only the peer's own lines")
+			}
+		}
+	})
+}
+
+func TestDetectOutOfBand(t *testing.T) {
+	db := testutil.SetupTestDB(t)
+	logger := testutil.CreateTestLogger()
+	log := NewLog(db, logger)
+
+	demo := frr.NewDemoClient(frr.DemoOptions{}, logger)
+	require.NoError(t, demo.Connect(context.Background()))
+	defer demo.Close()
+	client := log.Wrap(demo)
+	ctx := context.Background()
+
+	t.Run("Takes the running configuration as FlintRoute's at first", func(t *testing.T) {
+		transaction, err := log.DetectOutOfBand(ctx, client)
+		require.NoError(t, err)
+		assert.Nil(t, transaction)
+	})
+
+	t.Run("Ignores FlintRoute's own changes", func(t *testing.T) {
+		require.NoError(t, client.AddBGPPeer(ctx, &frr.BGPPeerConfig{IPAddress: "192.0.2.1", ASN: 65000, RemoteASN: 65001}))
+
+		transaction, err := log.DetectOutOfBand(ctx, client)
+		require.NoError(t, err)
+		assert.Nil(t, transaction)
+	})
+
+	t.Run("Records changes made outside FlintRoute", func(t *testing.T) {
+		log.Wait()
+		last, err := log.List(ctx, Query{Limit: 1})
+		require.NoError(t, err)
+		require.Len(t, last, 1)
+
+		require.NoError(t, demo.AddBGPPeer(ctx, &frr.BGPPeerConfig{IPAddress: "192.0.2.2", ASN: 65000, RemoteASN: 65002, Password: "s3cret"}))
+		running, err := demo.GetRunningConfig(ctx)
+		require.NoError(t, err)
+
+		transaction, err := log.DetectOutOfBand(ctx, client)
+		require.NoError(t, err)
+		require.NotNil(t, transaction)
+		assert.NotZero(t, transaction.ID, "stored")
+		assert.Equal(t, OpOutOfBand, transaction.Operation)
+		assert.Equal(t, last[0].CommitID, transaction.ParentCommitID)
+		assert.Equal(t, commitID(running), transaction.CommitID)
+		assert.Contains(t, transaction.Delta, "+  neighbor 192.0.2.2 remote-as 65002")
+		assert.Contains(t, transaction.Delta, "+  neighbor 192.0.2.2 password <redacted>")
+
+		transaction, err = log.DetectOutOfBand(ctx, client)
+		require.NoError(t, err)
+		assert.Nil(t, transaction, "reported once")
+	})
+
+	t.Run("Continues from the last transaction after a restart", func(t *testing.T) {
+		last, err := log.List(ctx, Query{Limit: 1})
+		require.NoError(t, err)
+		require.Len(t, last, 1)
+		require.NoError(t, demo.RemoveBGPPeer(ctx, "192.0.2.2"))
+
+		transaction, err := NewLog(db, logger).DetectOutOfBand(ctx, client)
+		require.NoError(t, err)
+		require.NotNil(t, transaction)
+		assert.Equal(t, last[0].CommitID, transaction.ParentCommitID)
+		assert.Empty(t, transaction.Delta, "the configuration before it is unknown")
+	})
+}
@@ -0,0 +1,94 @@
+package frrlog
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/padminisys/flintroute/internal/frr"
+	"github.com/padminisys/flintroute/internal/frrconf"
+	"github.com/padminisys/flintroute/pkg/models"
+	"gorm.io/gorm"
+)
+
+// runningConfig is a running configuration FlintRoute saw, by commit ID.
+// Its text is empty when only the commit ID is known, as for the last
+// transaction recorded before FlintRoute started.
+type runningConfig struct {
+	commitID string
+	config   string
+}
+
+// setHead records config as the running configuration FlintRoute last
+// saw. changeMu must be held.
+func (l *Log) setHead(config string) {
+	l.head = runningConfig{commitID: commitID(config), config: config}
+	l.headKnown = true
+}
+
+// DetectOutOfBand compares FRR's running configuration, read through
+// client, with the one FlintRoute last produced. When it has changed
+// outside FlintRoute, the change is recorded as an OpOutOfBand transaction
+// and returned; otherwise the transaction is nil.
+//
+// The first check takes the commit ID of the last transaction recorded.
+// Without one, the running configuration is taken as FlintRoute's, and the
+// delta of a change made while FlintRoute wasn't running is left empty, as
+// the configuration before it is unknown.
+func (l *Log) DetectOutOfBand(ctx context.Context, client frr.FRRClient) (*models.FRRTransaction, error) {
+	l.changeMu.Lock()
+	defer l.changeMu.Unlock()
+
+	running, err := client.GetRunningConfig(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get running config: %w", err)
+	}
+	if !l.headKnown {
+		last, err := l.lastCommitID(ctx)
+		if err != nil {
+			return nil, err
+		}
+		l.head = runningConfig{commitID: last}
+		l.headKnown = true
+	}
+
+	previous := l.head
+	l.setHead(running)
+	if previous.commitID == "" || previous.commitID == l.head.commitID {
+		return nil, nil
+	}
+
+	transaction := &models.FRRTransaction{
+		CreatedAt:      time.Now(),
+		Operation:      OpOutOfBand,
+		Delta:          []string{},
+		CommitID:       l.head.commitID,
+		ParentCommitID: previous.commitID,
+		Result:         models.FRRTransactionSucceeded,
+	}
+	if previous.config != "" {
+		transaction.Delta = append(transaction.Delta, redact(frrconf.Diff(previous.config, running))...)
+	}
+
+	// The transaction is returned stored, with its ID
+	l.record(ctx, transaction)
+	l.Wait()
+	return transaction, nil
+}
+
+// lastCommitID returns the commit ID of the last transaction recorded with
+// one, in any tenant
+func (l *Log) lastCommitID(ctx context.Context) (string, error) {
+	l.Wait()
+
+	var transaction models.FRRTransaction
+	err := l.db.WithContext(ctx).Select("commit_id").Where("commit_id <> ''").Order("id DESC").First(&transaction).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to get last FRR transaction: %w", err)
+	}
+	return transaction.CommitID, nil
+}
@@ -0,0 +1,560 @@
+package gitops
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/padminisys/flintroute/internal/bgp"
+	"github.com/padminisys/flintroute/internal/secrets"
+	"github.com/padminisys/flintroute/pkg/models"
+	"gopkg.in/yaml.v3"
+)
+
+// ErrInvalidDefinitions is returned when the repository contents cannot be
+// parsed or fail validation
+var ErrInvalidDefinitions = errors.New("invalid GitOps definitions")
+
+// Definitions is the desired state declared in a GitOps repository
+type Definitions struct {
+	Peers          []PeerDefinition          `yaml:"peers" json:"peers"`
+	RouteMaps      []RouteMapDefinition      `yaml:"route_maps" json:"route_maps"`
+	PrefixLists    []PrefixListDefinition    `yaml:"prefix_lists" json:"prefix_lists"`
+	CommunityLists []CommunityListDefinition `yaml:"community_lists" json:"community_lists"`
+	ASPathLists    []ASPathListDefinition    `yaml:"as_path_lists" json:"as_path_lists"`
+}
+
+// PeerDefinition declares a BGP peer. Password must be empty or a
+// secret:// reference; plaintext passwords are rejected so they never end
+// up in Git history.
+type PeerDefinition struct {
+	Name                string `yaml:"name" json:"name"`
+	IPAddress           string `yaml:"ip_address" json:"ip_address"`
+	ASN                 uint32 `yaml:"asn" json:"asn"`
+	RemoteASN           uint32 `yaml:"remote_asn" json:"remote_asn"`
+	Description         string `yaml:"description" json:"description,omitempty"`
+	Enabled             *bool  `yaml:"enabled" json:"enabled,omitempty"`
+	Password            string `yaml:"password" json:"password,omitempty"`
+	Multihop            int    `yaml:"multihop" json:"multihop,omitempty"`
+	UpdateSource        string `yaml:"update_source" json:"update_source,omitempty"`
+	RouteMapIn          string `yaml:"route_map_in" json:"route_map_in,omitempty"`
+	RouteMapOut         string `yaml:"route_map_out" json:"route_map_out,omitempty"`
+	PrefixListIn        string `yaml:"prefix_list_in" json:"prefix_list_in,omitempty"`
+	PrefixListOut       string `yaml:"prefix_list_out" json:"prefix_list_out,omitempty"`
+	MaxPrefixes         int    `yaml:"max_prefixes" json:"max_prefixes,omitempty"`
+	MaxPrefixThreshold  int    `yaml:"max_prefix_threshold" json:"max_prefix_threshold,omitempty"`
+	MaxPrefixAction     string `yaml:"max_prefix_action" json:"max_prefix_action,omitempty"`
+	MaxPrefixRestart    int    `yaml:"max_prefix_restart" json:"max_prefix_restart,omitempty"`
+	LocalPreference     int    `yaml:"local_preference" json:"local_preference,omitempty"`
+	Keepalive           int    `yaml:"keepalive" json:"keepalive,omitempty"`
+	HoldTime            int    `yaml:"holdtime" json:"holdtime,omitempty"`
+	ConnectRetry        int    `yaml:"connect_retry" json:"connect_retry,omitempty"`
+	Passive             bool   `yaml:"passive" json:"passive,omitempty"`
+	TTLSecurityHops     int    `yaml:"ttl_security_hops" json:"ttl_security_hops,omitempty"`
+	NextHopSelf         bool   `yaml:"next_hop_self" json:"next_hop_self,omitempty"`
+	SoftReconfigInbound bool   `yaml:"soft_reconfiguration_inbound" json:"soft_reconfiguration_inbound,omitempty"`
+	RemovePrivateAS     bool   `yaml:"remove_private_as" json:"remove_private_as,omitempty"`
+	AllowASIn           int    `yaml:"allowas_in" json:"allowas_in,omitempty"`
+	LocalAS             uint32 `yaml:"local_as" json:"local_as,omitempty"`
+	LocalASMode         string `yaml:"local_as_mode" json:"local_as_mode,omitempty"`
+}
+
+// RouteMapDefinition declares an FRR route-map
+type RouteMapDefinition struct {
+	Name    string          `yaml:"name" json:"name"`
+	Entries []RouteMapEntry `yaml:"entries" json:"entries"`
+}
+
+// RouteMapEntry is a single sequence of a route-map. Match and Set hold
+// FRR clauses without the leading keyword, e.g. "ip address prefix-list PL".
+type RouteMapEntry struct {
+	Seq    int      `yaml:"seq" json:"seq"`
+	Action string   `yaml:"action" json:"action"` // permit, deny
+	Match  []string `yaml:"match" json:"match,omitempty"`
+	Set    []string `yaml:"set" json:"set,omitempty"`
+}
+
+// PrefixListDefinition declares an FRR prefix-list. All entries must be of
+// the same address family.
+type PrefixListDefinition struct {
+	Name    string            `yaml:"name" json:"name"`
+	Entries []PrefixListEntry `yaml:"entries" json:"entries"`
+}
+
+// PrefixListEntry is a single sequence of a prefix-list
+type PrefixListEntry struct {
+	Seq    int    `yaml:"seq" json:"seq"`
+	Action string `yaml:"action" json:"action"` // permit, deny
+	Prefix string `yaml:"prefix" json:"prefix"`
+	GE     int    `yaml:"ge" json:"ge,omitempty"`
+	LE     int    `yaml:"le" json:"le,omitempty"`
+}
+
+// CommunityListDefinition declares an FRR community-list. Type defaults to
+// standard.
+type CommunityListDefinition struct {
+	Name    string               `yaml:"name" json:"name"`
+	Type    string               `yaml:"type" json:"type,omitempty"` // standard, expanded
+	Entries []CommunityListEntry `yaml:"entries" json:"entries"`
+}
+
+// CommunityListEntry is a single sequence of a community-list. Value holds
+// space-separated communities for standard lists and a regular expression
+// for expanded lists.
+type CommunityListEntry struct {
+	Seq    int    `yaml:"seq" json:"seq"`
+	Action string `yaml:"action" json:"action"` // permit, deny
+	Value  string `yaml:"value" json:"value"`
+}
+
+// ASPathListDefinition declares an FRR as-path access-list
+type ASPathListDefinition struct {
+	Name    string            `yaml:"name" json:"name"`
+	Entries []ASPathListEntry `yaml:"entries" json:"entries"`
+}
+
+// ASPathListEntry is a single sequence of an as-path access-list
+type ASPathListEntry struct {
+	Seq    int    `yaml:"seq" json:"seq"`
+	Action string `yaml:"action" json:"action"` // permit, deny
+	Regex  string `yaml:"regex" json:"regex"`
+}
+
+// LoadDefinitions reads and merges every .yaml and .yml file below dir.
+// Hidden files and directories are skipped. The merged definitions are
+// validated before being returned.
+func LoadDefinitions(dir string) (*Definitions, error) {
+	var files []string
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if path != dir && strings.HasPrefix(d.Name(), ".") {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.IsDir() && (strings.HasSuffix(path, ".yaml") || strings.HasSuffix(path, ".yml")) {
+			files = append(files, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read definitions directory: %w", err)
+	}
+	sort.Strings(files)
+
+	defs := &Definitions{}
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", file, err)
+		}
+		if err := defs.decode(data); err != nil {
+			rel, _ := filepath.Rel(dir, file)
+			return nil, fmt.Errorf("%w: %s: %v", ErrInvalidDefinitions, rel, err)
+		}
+	}
+
+	if err := defs.Validate(); err != nil {
+		return nil, err
+	}
+	return defs, nil
+}
+
+// decode appends the documents in data to the definitions
+func (d *Definitions) decode(data []byte) error {
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	for {
+		var doc Definitions
+		if err := decoder.Decode(&doc); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+		d.Peers = append(d.Peers, doc.Peers...)
+		d.RouteMaps = append(d.RouteMaps, doc.RouteMaps...)
+		d.PrefixLists = append(d.PrefixLists, doc.PrefixLists...)
+		d.CommunityLists = append(d.CommunityLists, doc.CommunityLists...)
+		d.ASPathLists = append(d.ASPathLists, doc.ASPathLists...)
+	}
+}
+
+// Validate checks the definitions for missing fields, duplicates and
+// references to undefined policies
+func (d *Definitions) Validate() error {
+	var problems []string
+	addf := func(format string, args ...interface{}) {
+		problems = append(problems, fmt.Sprintf(format, args...))
+	}
+
+	prefixLists := make(map[string]bool)
+	for _, pl := range d.PrefixLists {
+		if pl.Name == "" {
+			addf("prefix-list without a name")
+			continue
+		}
+		if prefixLists[pl.Name] {
+			addf("prefix-list %s is defined more than once", pl.Name)
+		}
+		prefixLists[pl.Name] = true
+		if len(pl.Entries) == 0 {
+			addf("prefix-list %s has no entries", pl.Name)
+		}
+
+		seqs := make(map[int]bool)
+		var family string
+		for _, entry := range pl.Entries {
+			validateSeq(addf, "prefix-list "+pl.Name, entry.Seq, entry.Action, seqs)
+			prefix, err := netip.ParsePrefix(entry.Prefix)
+			if err != nil {
+				addf("prefix-list %s seq %d: invalid prefix %q", pl.Name, entry.Seq, entry.Prefix)
+				continue
+			}
+			entryFamily := prefixFamily(prefix)
+			if family != "" && family != entryFamily {
+				addf("prefix-list %s mixes IPv4 and IPv6 prefixes", pl.Name)
+			}
+			family = entryFamily
+			if entry.GE != 0 && (entry.GE < prefix.Bits() || entry.GE > prefix.Addr().BitLen()) {
+				addf("prefix-list %s seq %d: ge %d out of range", pl.Name, entry.Seq, entry.GE)
+			}
+			if entry.LE != 0 && (entry.LE < max(prefix.Bits(), entry.GE) || entry.LE > prefix.Addr().BitLen()) {
+				addf("prefix-list %s seq %d: le %d out of range", pl.Name, entry.Seq, entry.LE)
+			}
+		}
+	}
+
+	lists := map[string]map[string]bool{
+		models.PolicyCommunityList: make(map[string]bool),
+		models.PolicyASPathList:    make(map[string]bool),
+	}
+	for i := range d.CommunityLists {
+		list := d.CommunityLists[i].list()
+		if lists[models.PolicyCommunityList][list.Name] {
+			addf("community-list %s is defined more than once", list.Name)
+		}
+		lists[models.PolicyCommunityList][list.Name] = true
+		if err := bgp.ValidateCommunityList(list); err != nil {
+			addf("%v", err)
+		}
+	}
+	for i := range d.ASPathLists {
+		list := d.ASPathLists[i].list()
+		if lists[models.PolicyASPathList][list.Name] {
+			addf("as-path-list %s is defined more than once", list.Name)
+		}
+		lists[models.PolicyASPathList][list.Name] = true
+		if err := bgp.ValidateASPathList(list); err != nil {
+			addf("%v", err)
+		}
+	}
+
+	routeMaps := make(map[string]bool)
+	for _, rm := range d.RouteMaps {
+		if rm.Name == "" {
+			addf("route-map without a name")
+			continue
+		}
+		if routeMaps[rm.Name] {
+			addf("route-map %s is defined more than once", rm.Name)
+		}
+		routeMaps[rm.Name] = true
+		if len(rm.Entries) == 0 {
+			addf("route-map %s has no entries", rm.Name)
+		}
+
+		seqs := make(map[int]bool)
+		for _, entry := range rm.Entries {
+			validateSeq(addf, "route-map "+rm.Name, entry.Seq, entry.Action, seqs)
+			for _, ref := range bgp.RouteMapReferences(entry.Match, entry.Set) {
+				if !lists[ref.Kind][ref.Name] {
+					addf("route-map %s seq %d references undefined %s %s", rm.Name, entry.Seq, ref.Kind, ref.Name)
+				}
+			}
+		}
+	}
+
+	ips := make(map[string]bool)
+	for _, peer := range d.Peers {
+		if net.ParseIP(peer.IPAddress) == nil {
+			addf("peer %q: invalid ip_address %q", peer.Name, peer.IPAddress)
+			continue
+		}
+		if ips[peer.IPAddress] {
+			addf("peer %s is defined more than once", peer.IPAddress)
+		}
+		ips[peer.IPAddress] = true
+
+		if peer.Name == "" {
+			addf("peer %s: name is required", peer.IPAddress)
+		}
+		if peer.ASN == 0 || peer.RemoteASN == 0 {
+			addf("peer %s: asn and remote_asn are required", peer.IPAddress)
+		}
+		if peer.Password != "" && !secrets.IsReference(peer.Password) {
+			addf("peer %s: password must be a %s reference", peer.IPAddress, secrets.Scheme)
+		}
+		if err := bgp.ValidatePeer(peer.peer()); err != nil {
+			addf("peer %s: %v", peer.IPAddress, err)
+		}
+		if peer.MaxPrefixes < 0 || peer.LocalPreference < 0 {
+			addf("peer %s: max_prefixes and local_preference must not be negative", peer.IPAddress)
+		}
+		for _, ref := range []string{peer.RouteMapIn, peer.RouteMapOut} {
+			if ref != "" && !routeMaps[ref] {
+				addf("peer %s references undefined route-map %s", peer.IPAddress, ref)
+			}
+		}
+		for _, ref := range []string{peer.PrefixListIn, peer.PrefixListOut} {
+			if ref != "" && !prefixLists[ref] {
+				addf("peer %s references undefined prefix-list %s", peer.IPAddress, ref)
+			}
+		}
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("%w: %s", ErrInvalidDefinitions, strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// validateSeq checks a policy entry's sequence number and action
+func validateSeq(addf func(string, ...interface{}), owner string, seq int, action string, seen map[int]bool) {
+	if seq <= 0 {
+		addf("%s: seq must be positive", owner)
+	} else if seen[seq] {
+		addf("%s: seq %d is used more than once", owner, seq)
+	}
+	seen[seq] = true
+
+	if action != "permit" && action != "deny" {
+		addf("%s seq %d: action must be permit or deny", owner, seq)
+	}
+}
+
+// prefixFamily returns the FRR prefix-list keyword for a prefix
+func prefixFamily(prefix netip.Prefix) string {
+	if prefix.Addr().Is4() {
+		return "ip"
+	}
+	return "ipv6"
+}
+
+// Render returns the FRR configuration for the route-map
+func (rm *RouteMapDefinition) Render() string {
+	entries := append([]RouteMapEntry(nil), rm.Entries...)
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Seq < entries[j].Seq })
+
+	var b strings.Builder
+	for _, entry := range entries {
+		fmt.Fprintf(&b, "route-map %s %s %d\n", rm.Name, entry.Action, entry.Seq)
+		for _, match := range entry.Match {
+			fmt.Fprintf(&b, " match %s\n", match)
+		}
+		for _, set := range entry.Set {
+			fmt.Fprintf(&b, " set %s\n", set)
+		}
+	}
+	return b.String()
+}
+
+// Render returns the FRR configuration for the prefix-list
+func (pl *PrefixListDefinition) Render() string {
+	entries := append([]PrefixListEntry(nil), pl.Entries...)
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Seq < entries[j].Seq })
+
+	var b strings.Builder
+	for _, entry := range entries {
+		prefix, err := netip.ParsePrefix(entry.Prefix)
+		if err != nil {
+			continue
+		}
+		fmt.Fprintf(&b, "%s prefix-list %s seq %d %s %s", prefixFamily(prefix), pl.Name, entry.Seq, entry.Action, prefix)
+		if entry.GE != 0 {
+			fmt.Fprintf(&b, " ge %d", entry.GE)
+		}
+		if entry.LE != 0 {
+			fmt.Fprintf(&b, " le %d", entry.LE)
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// list returns the stored representation of the definition
+func (cl *CommunityListDefinition) list() *models.CommunityList {
+	listType := cl.Type
+	if listType == "" {
+		listType = models.CommunityListStandard
+	}
+	entries := make([]models.CommunityListEntry, len(cl.Entries))
+	for i, entry := range cl.Entries {
+		entries[i] = models.CommunityListEntry{Seq: entry.Seq, Action: entry.Action, Value: entry.Value}
+	}
+	return &models.CommunityList{
+		Name:      cl.Name,
+		Type:      listType,
+		Entries:   entries,
+		ManagedBy: models.ManagedByGitOps,
+	}
+}
+
+// list returns the stored representation of the definition
+func (al *ASPathListDefinition) list() *models.ASPathList {
+	entries := make([]models.ASPathListEntry, len(al.Entries))
+	for i, entry := range al.Entries {
+		entries[i] = models.ASPathListEntry{Seq: entry.Seq, Action: entry.Action, Regex: entry.Regex}
+	}
+	return &models.ASPathList{
+		Name:      al.Name,
+		Entries:   entries,
+		ManagedBy: models.ManagedByGitOps,
+	}
+}
+
+// Policies returns every policy object as a routing policy, in the order
+// they must be created: route-maps come last since they reference the
+// lists
+func (d *Definitions) Policies() []*models.RoutingPolicy {
+	var policies []*models.RoutingPolicy
+	for i := range d.PrefixLists {
+		policies = append(policies, &models.RoutingPolicy{
+			Kind:      models.PolicyPrefixList,
+			Name:      d.PrefixLists[i].Name,
+			Config:    d.PrefixLists[i].Render(),
+			ManagedBy: models.ManagedByGitOps,
+		})
+	}
+	for i := range d.CommunityLists {
+		policies = append(policies, &models.RoutingPolicy{
+			Kind:      models.PolicyCommunityList,
+			Name:      d.CommunityLists[i].Name,
+			Config:    bgp.RenderCommunityList(d.CommunityLists[i].list()),
+			ManagedBy: models.ManagedByGitOps,
+		})
+	}
+	for i := range d.ASPathLists {
+		policies = append(policies, &models.RoutingPolicy{
+			Kind:      models.PolicyASPathList,
+			Name:      d.ASPathLists[i].Name,
+			Config:    bgp.RenderASPathList(d.ASPathLists[i].list()),
+			ManagedBy: models.ManagedByGitOps,
+		})
+	}
+	for i := range d.RouteMaps {
+		policies = append(policies, &models.RoutingPolicy{
+			Kind:      models.PolicyRouteMap,
+			Name:      d.RouteMaps[i].Name,
+			Config:    d.RouteMaps[i].Render(),
+			ManagedBy: models.ManagedByGitOps,
+		})
+	}
+	return policies
+}
+
+// peer returns the stored representation of the definition, applying the
+// same defaults as the API
+func (p *PeerDefinition) peer() *models.BGPPeer {
+	enabled := true
+	if p.Enabled != nil {
+		enabled = *p.Enabled
+	}
+	multihop := p.Multihop
+	if multihop == 0 {
+		multihop = 1
+	}
+
+	return &models.BGPPeer{
+		Name:                p.Name,
+		IPAddress:           p.IPAddress,
+		ASN:                 p.ASN,
+		RemoteASN:           p.RemoteASN,
+		Description:         p.Description,
+		Enabled:             enabled,
+		Password:            p.Password,
+		Multihop:            multihop,
+		UpdateSource:        p.UpdateSource,
+		RouteMapIn:          p.RouteMapIn,
+		RouteMapOut:         p.RouteMapOut,
+		PrefixListIn:        p.PrefixListIn,
+		PrefixListOut:       p.PrefixListOut,
+		MaxPrefixes:         p.MaxPrefixes,
+		MaxPrefixThreshold:  p.MaxPrefixThreshold,
+		MaxPrefixAction:     p.MaxPrefixAction,
+		MaxPrefixRestart:    p.MaxPrefixRestart,
+		LocalPreference:     p.LocalPreference,
+		Keepalive:           p.Keepalive,
+		HoldTime:            p.HoldTime,
+		ConnectRetry:        p.ConnectRetry,
+		Passive:             p.Passive,
+		TTLSecurityHops:     p.TTLSecurityHops,
+		NextHopSelf:         p.NextHopSelf,
+		SoftReconfigInbound: p.SoftReconfigInbound,
+		RemovePrivateAS:     p.RemovePrivateAS,
+		AllowASIn:           p.AllowASIn,
+		LocalAS:             p.LocalAS,
+		LocalASMode:         p.LocalASMode,
+		ManagedBy:           models.ManagedByGitOps,
+	}
+}
+
+// Render returns the FRR configuration for all definitions. It is stored
+// in config versions so a synced commit can be inspected later.
+func (d *Definitions) Render() string {
+	var b strings.Builder
+	for _, policy := range d.Policies() {
+		b.WriteString(policy.Config)
+		b.WriteString("!\n")
+	}
+
+	peers := append([]PeerDefinition(nil), d.Peers...)
+	sort.Slice(peers, func(i, j int) bool { return peers[i].IPAddress < peers[j].IPAddress })
+	for _, def := range peers {
+		peer := def.peer()
+		fmt.Fprintf(&b, "neighbor %s remote-as %d\n", peer.IPAddress, peer.RemoteASN)
+		if peer.Description != "" {
+			fmt.Fprintf(&b, "neighbor %s description %s\n", peer.IPAddress, peer.Description)
+		}
+		if !peer.Enabled {
+			fmt.Fprintf(&b, "neighbor %s shutdown\n", peer.IPAddress)
+		}
+		if peer.Multihop > 1 {
+			fmt.Fprintf(&b, "neighbor %s ebgp-multihop %d\n", peer.IPAddress, peer.Multihop)
+		}
+		if peer.UpdateSource != "" {
+			fmt.Fprintf(&b, "neighbor %s update-source %s\n", peer.IPAddress, peer.UpdateSource)
+		}
+		if peer.RouteMapIn != "" {
+			fmt.Fprintf(&b, "neighbor %s route-map %s in\n", peer.IPAddress, peer.RouteMapIn)
+		}
+		if peer.RouteMapOut != "" {
+			fmt.Fprintf(&b, "neighbor %s route-map %s out\n", peer.IPAddress, peer.RouteMapOut)
+		}
+		if peer.PrefixListIn != "" {
+			fmt.Fprintf(&b, "neighbor %s prefix-list %s in\n", peer.IPAddress, peer.PrefixListIn)
+		}
+		if peer.PrefixListOut != "" {
+			fmt.Fprintf(&b, "neighbor %s prefix-list %s out\n", peer.IPAddress, peer.PrefixListOut)
+		}
+		options := bgp.NeighborOptions(peer)
+		if command := options.MaximumPrefixCommand(peer.IPAddress, peer.MaxPrefixes); command != "" {
+			b.WriteString(command + "\n")
+		}
+		for _, command := range options.Commands(peer.IPAddress) {
+			b.WriteString(command + "\n")
+		}
+	}
+	return b.String()
+}
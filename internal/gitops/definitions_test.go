@@ -0,0 +1,243 @@
+package gitops
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/padminisys/flintroute/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const policyDefinitions = `
+prefix_lists:
+  - name: PL-IN
+    entries:
+      - seq: 20
+        action: deny
+        prefix: 0.0.0.0/0
+      - seq: 10
+        action: permit
+        prefix: 10.0.0.0/8
+        le: 24
+route_maps:
+  - name: RM-IN
+    entries:
+      - seq: 10
+        action: permit
+        match:
+          - ip address prefix-list PL-IN
+        set:
+          - local-preference 200
+`
+
+const listDefinitions = `
+community_lists:
+  - name: CL-BLACKHOLE
+    entries:
+      - seq: 10
+        action: permit
+        value: 65000:666  blackhole
+as_path_lists:
+  - name: AS-CUSTOMERS
+    entries:
+      - seq: 10
+        action: permit
+        regex: ^65010(_65010)*$
+route_maps:
+  - name: RM-CUSTOMERS
+    entries:
+      - seq: 10
+        action: permit
+        match:
+          - as-path AS-CUSTOMERS
+          - community CL-BLACKHOLE
+        set:
+          - comm-list CL-BLACKHOLE delete
+`
+
+const peerDefinitions = `
+peers:
+  - name: upstream-a
+    ip_address: 192.0.2.1
+    asn: 65001
+    remote_asn: 65002
+    password: secret://env/UPSTREAM_A_PASSWORD
+    route_map_in: RM-IN
+    prefix_list_in: PL-IN
+    keepalive: 10
+    holdtime: 30
+    soft_reconfiguration_inbound: true
+    max_prefixes: 1000
+    max_prefix_threshold: 80
+    max_prefix_action: warning-only
+`
+
+func writeDefinitions(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	}
+}
+
+func TestLoadDefinitions(t *testing.T) {
+	t.Run("Merge files", func(t *testing.T) {
+		dir := t.TempDir()
+		writeDefinitions(t, dir, map[string]string{
+			"policies.yaml":      policyDefinitions,
+			"peers/upstream.yml": peerDefinitions,
+			"README.md":          "not a definition",
+			".hidden/skip.yaml":  "invalid: [",
+		})
+
+		defs, err := LoadDefinitions(dir)
+		require.NoError(t, err)
+		assert.Len(t, defs.Peers, 1)
+		assert.Len(t, defs.RouteMaps, 1)
+		assert.Len(t, defs.PrefixLists, 1)
+	})
+
+	t.Run("Multiple documents", func(t *testing.T) {
+		dir := t.TempDir()
+		writeDefinitions(t, dir, map[string]string{
+			"all.yaml": policyDefinitions + "---\n" + peerDefinitions,
+		})
+
+		defs, err := LoadDefinitions(dir)
+		require.NoError(t, err)
+		assert.Len(t, defs.Peers, 1)
+	})
+
+	t.Run("Unknown fields", func(t *testing.T) {
+		dir := t.TempDir()
+		writeDefinitions(t, dir, map[string]string{"peers.yaml": "peers:\n  - ip: 192.0.2.1\n"})
+
+		_, err := LoadDefinitions(dir)
+		assert.ErrorIs(t, err, ErrInvalidDefinitions)
+		assert.Contains(t, err.Error(), "peers.yaml")
+	})
+}
+
+func TestValidate(t *testing.T) {
+	valid := func() *Definitions {
+		return &Definitions{
+			Peers: []PeerDefinition{{Name: "a", IPAddress: "192.0.2.1", ASN: 65001, RemoteASN: 65002}},
+			PrefixLists: []PrefixListDefinition{{
+				Name:    "PL-IN",
+				Entries: []PrefixListEntry{{Seq: 10, Action: "permit", Prefix: "10.0.0.0/8", LE: 24}},
+			}},
+		}
+	}
+
+	require.NoError(t, valid().Validate())
+
+	tests := []struct {
+		name   string
+		mutate func(*Definitions)
+		want   string
+	}{
+		{"Invalid IP", func(d *Definitions) { d.Peers[0].IPAddress = "not-an-ip" }, "invalid ip_address"},
+		{"Duplicate peer", func(d *Definitions) { d.Peers = append(d.Peers, d.Peers[0]) }, "more than once"},
+		{"Missing ASN", func(d *Definitions) { d.Peers[0].RemoteASN = 0 }, "asn and remote_asn are required"},
+		{"Plaintext password", func(d *Definitions) { d.Peers[0].Password = "hunter2" }, "secret:// reference"},
+		{"Undefined route-map", func(d *Definitions) { d.Peers[0].RouteMapIn = "RM-MISSING" }, "undefined route-map"},
+		{"Undefined prefix-list", func(d *Definitions) { d.Peers[0].PrefixListOut = "PL-MISSING" }, "undefined prefix-list"},
+		{"Holdtime without keepalive", func(d *Definitions) { d.Peers[0].HoldTime = 90 }, "keepalive and holdtime must be set together"},
+		{"TTL security with multihop", func(d *Definitions) {
+			d.Peers[0].TTLSecurityHops = 1
+			d.Peers[0].Multihop = 2
+		}, "cannot be combined with multihop"},
+		{"Invalid action", func(d *Definitions) { d.PrefixLists[0].Entries[0].Action = "accept" }, "permit or deny"},
+		{"Invalid prefix", func(d *Definitions) { d.PrefixLists[0].Entries[0].Prefix = "10.0.0.0" }, "invalid prefix"},
+		{"Out of range le", func(d *Definitions) { d.PrefixLists[0].Entries[0].LE = 33 }, "le 33 out of range"},
+		{"Mixed families", func(d *Definitions) {
+			d.PrefixLists[0].Entries = append(d.PrefixLists[0].Entries, PrefixListEntry{Seq: 20, Action: "permit", Prefix: "2001:db8::/32"})
+		}, "mixes IPv4 and IPv6"},
+		{"Invalid community", func(d *Definitions) {
+			d.CommunityLists = []CommunityListDefinition{{Name: "CL", Entries: []CommunityListEntry{{Seq: 10, Action: "permit", Value: "65000:70000"}}}}
+		}, `invalid community "65000:70000"`},
+		{"Undefined community-list", func(d *Definitions) {
+			d.RouteMaps = []RouteMapDefinition{{Name: "RM", Entries: []RouteMapEntry{{Seq: 10, Action: "permit", Match: []string{"community CL-MISSING"}}}}}
+		}, "route-map RM seq 10 references undefined community-list CL-MISSING"},
+		{"Undefined as-path-list", func(d *Definitions) {
+			d.RouteMaps = []RouteMapDefinition{{Name: "RM", Entries: []RouteMapEntry{{Seq: 10, Action: "permit", Match: []string{"as-path AS-MISSING"}}}}}
+		}, "references undefined as-path-list AS-MISSING"},
+		{"Duplicate seq", func(d *Definitions) {
+			d.PrefixLists[0].Entries = append(d.PrefixLists[0].Entries, PrefixListEntry{Seq: 10, Action: "deny", Prefix: "0.0.0.0/0"})
+		}, "seq 10 is used more than once"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defs := valid()
+			tt.mutate(defs)
+
+			err := defs.Validate()
+			assert.ErrorIs(t, err, ErrInvalidDefinitions)
+			assert.Contains(t, err.Error(), tt.want)
+		})
+	}
+}
+
+func TestRender(t *testing.T) {
+	dir := t.TempDir()
+	writeDefinitions(t, dir, map[string]string{"all.yaml": policyDefinitions + "---\n" + peerDefinitions})
+	defs, err := LoadDefinitions(dir)
+	require.NoError(t, err)
+
+	t.Run("Prefix-list", func(t *testing.T) {
+		assert.Equal(t,
+			"ip prefix-list PL-IN seq 10 permit 10.0.0.0/8 le 24\n"+
+				"ip prefix-list PL-IN seq 20 deny 0.0.0.0/0\n",
+			defs.PrefixLists[0].Render())
+	})
+
+	t.Run("IPv6 prefix-list", func(t *testing.T) {
+		pl := PrefixListDefinition{Name: "PL6", Entries: []PrefixListEntry{{Seq: 5, Action: "permit", Prefix: "2001:db8::/32", GE: 48}}}
+		assert.Equal(t, "ipv6 prefix-list PL6 seq 5 permit 2001:db8::/32 ge 48\n", pl.Render())
+	})
+
+	t.Run("Route-map", func(t *testing.T) {
+		assert.Equal(t,
+			"route-map RM-IN permit 10\n"+
+				" match ip address prefix-list PL-IN\n"+
+				" set local-preference 200\n",
+			defs.RouteMaps[0].Render())
+	})
+
+	t.Run("Policies", func(t *testing.T) {
+		policies := defs.Policies()
+		require.Len(t, policies, 2)
+		assert.Equal(t, models.PolicyPrefixList, policies[0].Kind)
+		assert.Equal(t, models.PolicyRouteMap, policies[1].Kind)
+		assert.Equal(t, models.ManagedByGitOps, policies[0].ManagedBy)
+	})
+
+	t.Run("Community and as-path lists", func(t *testing.T) {
+		dir := t.TempDir()
+		writeDefinitions(t, dir, map[string]string{"lists.yaml": listDefinitions})
+		defs, err := LoadDefinitions(dir)
+		require.NoError(t, err)
+
+		policies := defs.Policies()
+		require.Len(t, policies, 3)
+		assert.Equal(t, models.PolicyCommunityList, policies[0].Kind)
+		assert.Equal(t, "bgp community-list standard CL-BLACKHOLE seq 10 permit 65000:666 blackhole\n", policies[0].Config)
+		assert.Equal(t, models.PolicyASPathList, policies[1].Kind)
+		assert.Equal(t, "bgp as-path access-list AS-CUSTOMERS seq 10 permit ^65010(_65010)*$\n", policies[1].Config)
+		assert.Equal(t, models.PolicyRouteMap, policies[2].Kind)
+	})
+
+	t.Run("Full configuration", func(t *testing.T) {
+		config := defs.Render()
+		assert.Contains(t, config, "neighbor 192.0.2.1 remote-as 65002\n")
+		assert.Contains(t, config, "neighbor 192.0.2.1 route-map RM-IN in\n")
+		assert.Contains(t, config, "neighbor 192.0.2.1 timers 10 30\n")
+		assert.Contains(t, config, "neighbor 192.0.2.1 soft-reconfiguration inbound\n")
+		assert.Contains(t, config, "neighbor 192.0.2.1 maximum-prefix 1000 80 warning-only\n")
+		assert.NotContains(t, config, "UPSTREAM_A_PASSWORD")
+	})
+}
@@ -0,0 +1,366 @@
+package gitops
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/padminisys/flintroute/internal/bgp"
+	"github.com/padminisys/flintroute/pkg/models"
+)
+
+// ErrConflict is returned when the definitions cannot be applied without
+// manual intervention, e.g. a peer's ASN changed
+var ErrConflict = errors.New("GitOps definitions conflict with stored state")
+
+// Action is the operation a change performs
+type Action string
+
+// Plan actions
+const (
+	ActionCreate Action = "create"
+	ActionUpdate Action = "update"
+	ActionDelete Action = "delete"
+)
+
+// KindPeer is the change kind for BGP peers. Policy changes use the
+// routing policy kinds.
+const KindPeer = "peer"
+
+// Change is a single planned operation. Name is the IP address for peers.
+type Change struct {
+	Action Action   `json:"action"`
+	Kind   string   `json:"kind"` // peer, route-map, prefix-list, community-list, as-path-list
+	Name   string   `json:"name"`
+	Fields []string `json:"fields,omitempty"` // attributes changed by an update
+
+	peerID        uint
+	peer          *models.BGPPeer
+	policy        *models.RoutingPolicy
+	communityList *models.CommunityList
+	asPathList    *models.ASPathList
+}
+
+// String describes the change for logs and errors
+func (c *Change) String() string {
+	return fmt.Sprintf("%s %s %s", c.Action, c.Kind, c.Name)
+}
+
+// Plan is the set of changes needed to bring the database in line with a
+// commit of the definitions repository
+type Plan struct {
+	CommitSHA string    `json:"commit_sha"`
+	Changes   []*Change `json:"changes"`
+	Conflicts []string  `json:"conflicts,omitempty"`
+}
+
+// computePlan diffs the definitions against the stored peers and policies.
+// When prune is set, GitOps-managed objects missing from the definitions
+// are deleted; objects created through the API are never deleted.
+func computePlan(ctx context.Context, service *bgp.Service, defs *Definitions, prune bool) (*Plan, error) {
+	plan := &Plan{Changes: []*Change{}}
+
+	policies, err := service.ListPolicies(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list routing policies: %w", err)
+	}
+	listPolicies, err := storedListPolicies(ctx, service)
+	if err != nil {
+		return nil, err
+	}
+	existingPolicies := make(map[string]*models.RoutingPolicy, len(policies)+len(listPolicies))
+	for _, policy := range append(policies, listPolicies...) {
+		existingPolicies[policy.Kind+"/"+policy.Name] = policy
+	}
+
+	communityLists := make(map[string]*models.CommunityList, len(defs.CommunityLists))
+	for i := range defs.CommunityLists {
+		communityLists[defs.CommunityLists[i].Name] = defs.CommunityLists[i].list()
+	}
+	asPathLists := make(map[string]*models.ASPathList, len(defs.ASPathLists))
+	for i := range defs.ASPathLists {
+		asPathLists[defs.ASPathLists[i].Name] = defs.ASPathLists[i].list()
+	}
+	policyChange := func(action Action, policy *models.RoutingPolicy, fields []string) *Change {
+		change := &Change{Action: action, Kind: policy.Kind, Name: policy.Name, Fields: fields, policy: policy}
+		switch policy.Kind {
+		case models.PolicyCommunityList:
+			change.communityList = communityLists[policy.Name]
+		case models.PolicyASPathList:
+			change.asPathList = asPathLists[policy.Name]
+		}
+		return change
+	}
+
+	var policyDeletes []*Change
+	desiredPolicies := make(map[string]bool)
+	for _, policy := range defs.Policies() {
+		key := policy.Kind + "/" + policy.Name
+		desiredPolicies[key] = true
+
+		existing, ok := existingPolicies[key]
+		if !ok {
+			plan.Changes = append(plan.Changes, policyChange(ActionCreate, policy, nil))
+			continue
+		}
+
+		var fields []string
+		if existing.Config != policy.Config {
+			fields = append(fields, "config")
+		}
+		if existing.ManagedBy != policy.ManagedBy {
+			fields = append(fields, "managed_by")
+		}
+		if len(fields) > 0 {
+			plan.Changes = append(plan.Changes, policyChange(ActionUpdate, policy, fields))
+		}
+	}
+	if prune {
+		for key, policy := range existingPolicies {
+			if !desiredPolicies[key] && policy.ManagedBy == models.ManagedByGitOps {
+				policyDeletes = append(policyDeletes, &Change{Action: ActionDelete, Kind: policy.Kind, Name: policy.Name})
+			}
+		}
+	}
+
+	peers, err := service.ListPeers(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list peers: %w", err)
+	}
+	existingPeers := make(map[string]*models.BGPPeer, len(peers))
+	for _, peer := range peers {
+		existingPeers[peer.IPAddress] = peer
+	}
+
+	desiredPeers := make(map[string]bool)
+	for i := range defs.Peers {
+		desired := defs.Peers[i].peer()
+		desiredPeers[desired.IPAddress] = true
+
+		existing, ok := existingPeers[desired.IPAddress]
+		if !ok {
+			plan.Changes = append(plan.Changes, &Change{Action: ActionCreate, Kind: KindPeer, Name: desired.IPAddress, peer: desired})
+			continue
+		}
+
+		if existing.ASN != desired.ASN || existing.RemoteASN != desired.RemoteASN {
+			plan.Conflicts = append(plan.Conflicts, fmt.Sprintf(
+				"peer %s: asn and remote_asn cannot be changed; delete the peer before re-adding it", desired.IPAddress))
+			continue
+		}
+
+		fields := peerDiff(existing, desired)
+		password, err := service.StoredPassword(existing)
+		if err != nil {
+			return nil, err
+		}
+		if password != desired.Password {
+			fields = append(fields, "password")
+		}
+		if len(fields) > 0 {
+			plan.Changes = append(plan.Changes, &Change{
+				Action: ActionUpdate,
+				Kind:   KindPeer,
+				Name:   desired.IPAddress,
+				Fields: fields,
+				peerID: existing.ID,
+				peer:   desired,
+			})
+		}
+	}
+	if prune {
+		var peerDeletes []*Change
+		for ip, peer := range existingPeers {
+			if !desiredPeers[ip] && peer.ManagedBy == models.ManagedByGitOps {
+				peerDeletes = append(peerDeletes, &Change{Action: ActionDelete, Kind: KindPeer, Name: ip, peerID: peer.ID})
+			}
+		}
+		sortChanges(peerDeletes)
+		plan.Changes = append(plan.Changes, peerDeletes...)
+	}
+
+	// Policies are removed last so no peer references them in between, and
+	// route-maps before the lists they reference
+	sortChanges(policyDeletes)
+	sort.SliceStable(policyDeletes, func(i, j int) bool {
+		return policyDeletes[i].Kind == models.PolicyRouteMap && policyDeletes[j].Kind != models.PolicyRouteMap
+	})
+	plan.Changes = append(plan.Changes, policyDeletes...)
+
+	return plan, nil
+}
+
+// storedListPolicies returns the stored community-lists and as-path
+// access-lists as routing policies so they diff like other policies
+func storedListPolicies(ctx context.Context, service *bgp.Service) ([]*models.RoutingPolicy, error) {
+	communityLists, err := service.ListCommunityLists(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list community-lists: %w", err)
+	}
+	asPathLists, err := service.ListASPathLists(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list as-path-lists: %w", err)
+	}
+
+	var policies []*models.RoutingPolicy
+	for _, list := range communityLists {
+		policies = append(policies, &models.RoutingPolicy{
+			Kind:      models.PolicyCommunityList,
+			Name:      list.Name,
+			Config:    bgp.RenderCommunityList(list),
+			ManagedBy: list.ManagedBy,
+		})
+	}
+	for _, list := range asPathLists {
+		policies = append(policies, &models.RoutingPolicy{
+			Kind:      models.PolicyASPathList,
+			Name:      list.Name,
+			Config:    bgp.RenderASPathList(list),
+			ManagedBy: list.ManagedBy,
+		})
+	}
+	return policies, nil
+}
+
+// sortChanges orders changes by kind and name for stable plans
+func sortChanges(changes []*Change) {
+	sort.Slice(changes, func(i, j int) bool {
+		if changes[i].Kind != changes[j].Kind {
+			return changes[i].Kind < changes[j].Kind
+		}
+		return changes[i].Name < changes[j].Name
+	})
+}
+
+// peerDiff returns the names of the attributes that differ between the
+// stored and desired peer
+func peerDiff(current, desired *models.BGPPeer) []string {
+	var fields []string
+	add := func(name string, changed bool) {
+		if changed {
+			fields = append(fields, name)
+		}
+	}
+
+	add("name", current.Name != desired.Name)
+	add("description", current.Description != desired.Description)
+	add("enabled", current.Enabled != desired.Enabled)
+	add("multihop", current.Multihop != desired.Multihop)
+	add("update_source", current.UpdateSource != desired.UpdateSource)
+	add("route_map_in", current.RouteMapIn != desired.RouteMapIn)
+	add("route_map_out", current.RouteMapOut != desired.RouteMapOut)
+	add("prefix_list_in", current.PrefixListIn != desired.PrefixListIn)
+	add("prefix_list_out", current.PrefixListOut != desired.PrefixListOut)
+	add("max_prefixes", current.MaxPrefixes != desired.MaxPrefixes)
+	add("max_prefix_threshold", current.MaxPrefixThreshold != desired.MaxPrefixThreshold)
+	add("max_prefix_action", current.MaxPrefixAction != desired.MaxPrefixAction)
+	add("max_prefix_restart", current.MaxPrefixRestart != desired.MaxPrefixRestart)
+	add("local_preference", current.LocalPreference != desired.LocalPreference)
+	add("keepalive", current.Keepalive != desired.Keepalive)
+	add("holdtime", current.HoldTime != desired.HoldTime)
+	add("connect_retry", current.ConnectRetry != desired.ConnectRetry)
+	add("passive", current.Passive != desired.Passive)
+	add("ttl_security_hops", current.TTLSecurityHops != desired.TTLSecurityHops)
+	add("next_hop_self", current.NextHopSelf != desired.NextHopSelf)
+	add("soft_reconfiguration_inbound", current.SoftReconfigInbound != desired.SoftReconfigInbound)
+	add("remove_private_as", current.RemovePrivateAS != desired.RemovePrivateAS)
+	add("allowas_in", current.AllowASIn != desired.AllowASIn)
+	add("local_as", current.LocalAS != desired.LocalAS)
+	add("local_as_mode", current.LocalASMode != desired.LocalASMode)
+	add("managed_by", current.ManagedBy != desired.ManagedBy)
+	return fields
+}
+
+// applyPlan executes the plan's changes in order, stopping at the first
+// failure. It returns the changes that were applied.
+func applyPlan(ctx context.Context, service *bgp.Service, plan *Plan) ([]*Change, error) {
+	applied := []*Change{}
+	for _, change := range plan.Changes {
+		if err := applyChange(ctx, service, change); err != nil {
+			return applied, fmt.Errorf("failed to %s: %w", change, err)
+		}
+		applied = append(applied, change)
+	}
+	return applied, nil
+}
+
+// applyChange executes a single change through the BGP service
+func applyChange(ctx context.Context, service *bgp.Service, change *Change) error {
+	if change.Kind != KindPeer {
+		return applyPolicyChange(ctx, service, change)
+	}
+
+	switch change.Action {
+	case ActionCreate:
+		return service.CreatePeer(ctx, change.peer)
+	case ActionDelete:
+		return service.DeletePeer(ctx, change.peerID)
+	}
+
+	passwordChanged := false
+	for _, field := range change.Fields {
+		if field == "password" {
+			passwordChanged = true
+		}
+	}
+	if len(change.Fields) > 1 || !passwordChanged {
+		peer := change.peer
+		patch := &bgp.PeerPatch{
+			Name:                &peer.Name,
+			Description:         &peer.Description,
+			Enabled:             &peer.Enabled,
+			Multihop:            &peer.Multihop,
+			UpdateSource:        &peer.UpdateSource,
+			RouteMapIn:          &peer.RouteMapIn,
+			RouteMapOut:         &peer.RouteMapOut,
+			PrefixListIn:        &peer.PrefixListIn,
+			PrefixListOut:       &peer.PrefixListOut,
+			MaxPrefixes:         &peer.MaxPrefixes,
+			MaxPrefixThreshold:  &peer.MaxPrefixThreshold,
+			MaxPrefixAction:     &peer.MaxPrefixAction,
+			MaxPrefixRestart:    &peer.MaxPrefixRestart,
+			LocalPreference:     &peer.LocalPreference,
+			Keepalive:           &peer.Keepalive,
+			HoldTime:            &peer.HoldTime,
+			ConnectRetry:        &peer.ConnectRetry,
+			Passive:             &peer.Passive,
+			TTLSecurityHops:     &peer.TTLSecurityHops,
+			NextHopSelf:         &peer.NextHopSelf,
+			SoftReconfigInbound: &peer.SoftReconfigInbound,
+			RemovePrivateAS:     &peer.RemovePrivateAS,
+			AllowASIn:           &peer.AllowASIn,
+			LocalAS:             &peer.LocalAS,
+			LocalASMode:         &peer.LocalASMode,
+			ManagedBy:           &peer.ManagedBy,
+		}
+		if err := service.PatchPeer(ctx, change.peerID, patch); err != nil {
+			return err
+		}
+	}
+	if passwordChanged {
+		return service.SetPeerPassword(ctx, change.peerID, change.peer.Password)
+	}
+	return nil
+}
+
+// applyPolicyChange executes a change to a policy object
+func applyPolicyChange(ctx context.Context, service *bgp.Service, change *Change) error {
+	switch change.Kind {
+	case models.PolicyCommunityList:
+		if change.Action == ActionDelete {
+			return service.DeleteCommunityList(ctx, change.Name)
+		}
+		return service.SaveCommunityList(ctx, change.communityList)
+	case models.PolicyASPathList:
+		if change.Action == ActionDelete {
+			return service.DeleteASPathList(ctx, change.Name)
+		}
+		return service.SaveASPathList(ctx, change.asPathList)
+	}
+
+	if change.Action == ActionDelete {
+		return service.DeletePolicy(ctx, change.Kind, change.Name)
+	}
+	return service.SavePolicy(ctx, change.policy)
+}
@@ -0,0 +1,94 @@
+package gitops
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// ErrFetchFailed is returned when the repository cannot be fetched
+var ErrFetchFailed = errors.New("failed to fetch GitOps repository")
+
+// Source provides a local checkout of the definitions repository
+type Source interface {
+	// Fetch updates the checkout and returns its directory and the commit
+	// SHA it is at
+	Fetch(ctx context.Context) (dir, commit string, err error)
+}
+
+// GitSource keeps a shallow clone of a single branch using the git CLI.
+// Credentials are taken from the URL or the environment (SSH agent,
+// credential helpers), as with any git invocation.
+type GitSource struct {
+	url    string
+	branch string
+	dir    string
+}
+
+// NewGitSource creates a source that clones url at branch into dir
+func NewGitSource(url, branch, dir string) *GitSource {
+	return &GitSource{url: url, branch: branch, dir: dir}
+}
+
+// Fetch clones the repository on first use and fast-forwards it to the
+// remote branch head afterwards. Local modifications are discarded.
+func (g *GitSource) Fetch(ctx context.Context) (string, string, error) {
+	if _, err := os.Stat(filepath.Join(g.dir, ".git")); err == nil {
+		if err := g.git(ctx, "-C", g.dir, "fetch", "--depth", "1", "origin", g.branch); err != nil {
+			return "", "", err
+		}
+		if err := g.git(ctx, "-C", g.dir, "reset", "--hard", "FETCH_HEAD"); err != nil {
+			return "", "", err
+		}
+		if err := g.git(ctx, "-C", g.dir, "clean", "-fdx"); err != nil {
+			return "", "", err
+		}
+	} else {
+		if err := os.MkdirAll(filepath.Dir(g.dir), 0755); err != nil {
+			return "", "", fmt.Errorf("%w: %v", ErrFetchFailed, err)
+		}
+		if err := g.git(ctx, "clone", "--depth", "1", "--single-branch", "--branch", g.branch, g.url, g.dir); err != nil {
+			return "", "", err
+		}
+	}
+
+	commit, err := g.output(ctx, "-C", g.dir, "rev-parse", "HEAD")
+	if err != nil {
+		return "", "", err
+	}
+	return g.dir, commit, nil
+}
+
+// git runs a git command, discarding its output
+func (g *GitSource) git(ctx context.Context, args ...string) error {
+	_, err := g.output(ctx, args...)
+	return err
+}
+
+// output runs a git command and returns its trimmed stdout
+func (g *GitSource) output(ctx context.Context, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		msg := strings.TrimSpace(stderr.String())
+		if msg == "" {
+			msg = err.Error()
+		}
+		subcommand := args[0]
+		if subcommand == "-C" {
+			subcommand = args[2]
+		}
+		return "", fmt.Errorf("%w: git %s: %s", ErrFetchFailed, subcommand, msg)
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}
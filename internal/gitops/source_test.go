@@ -0,0 +1,76 @@
+package gitops
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// initRepo creates a local repository with a single commit on main
+func initRepo(t *testing.T) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	dir := t.TempDir()
+	runGit(t, dir, "init", "--initial-branch", "main")
+	commitFile(t, dir, "peers.yaml", peerDefinitions)
+	return dir
+}
+
+// commitFile writes a file into the repository and commits it
+func commitFile(t *testing.T, repo, name, content string) {
+	t.Helper()
+	require.NoError(t, os.WriteFile(filepath.Join(repo, name), []byte(content), 0644))
+	runGit(t, repo, "add", name)
+	runGit(t, repo, "-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "-m", "Update "+name)
+}
+
+func runGit(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	out, err := exec.Command("git", append([]string{"-C", dir}, args...)...).CombinedOutput()
+	require.NoError(t, err, string(out))
+	return string(out)
+}
+
+func TestGitSource(t *testing.T) {
+	ctx := context.Background()
+	repo := initRepo(t)
+	checkout := filepath.Join(t.TempDir(), "checkout")
+	source := NewGitSource(repo, "main", checkout)
+
+	t.Run("Clone", func(t *testing.T) {
+		dir, commit, err := source.Fetch(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, checkout, dir)
+		assert.Len(t, commit, 40)
+		assert.FileExists(t, filepath.Join(dir, "peers.yaml"))
+	})
+
+	t.Run("Fetch new commits", func(t *testing.T) {
+		_, before, err := source.Fetch(ctx)
+		require.NoError(t, err)
+
+		commitFile(t, repo, "policies.yaml", policyDefinitions)
+		require.NoError(t, os.WriteFile(filepath.Join(checkout, "local.yaml"), []byte("peers: []\n"), 0644))
+
+		dir, after, err := source.Fetch(ctx)
+		require.NoError(t, err)
+		assert.NotEqual(t, before, after)
+		assert.FileExists(t, filepath.Join(dir, "policies.yaml"))
+		assert.NoFileExists(t, filepath.Join(dir, "local.yaml"))
+	})
+
+	t.Run("Missing branch", func(t *testing.T) {
+		source := NewGitSource(repo, "does-not-exist", filepath.Join(t.TempDir(), "checkout"))
+
+		_, _, err := source.Fetch(ctx)
+		assert.ErrorIs(t, err, ErrFetchFailed)
+	})
+}
@@ -0,0 +1,255 @@
+package gitops
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/padminisys/flintroute/internal/bgp"
+	"github.com/padminisys/flintroute/internal/configstore"
+	"github.com/padminisys/flintroute/internal/database"
+	"github.com/padminisys/flintroute/pkg/models"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// Options configures a Syncer
+type Options struct {
+	// Path is the directory within the repository holding definitions
+	Path string
+	// Prune deletes GitOps-managed peers and policies that were removed
+	// from the repository
+	Prune bool
+	// Versions stores the config version recorded for each sync; nil
+	// stores it uncompressed in the database
+	Versions *configstore.Store
+}
+
+// Result describes a completed or failed sync
+type Result struct {
+	CommitSHA string                `json:"commit_sha"`
+	Plan      *Plan                 `json:"plan"`
+	Applied   []*Change             `json:"applied"`
+	Version   *models.ConfigVersion `json:"version,omitempty"`
+}
+
+// Status reports the outcome of the most recent sync
+type Status struct {
+	LastSyncAt *time.Time `json:"last_sync_at,omitempty"`
+	CommitSHA  string     `json:"commit_sha,omitempty"` // last successfully synced commit
+	LastError  string     `json:"last_error,omitempty"`
+	LastResult *Result    `json:"last_result,omitempty"`
+}
+
+// Syncer applies peer and policy definitions from a Git repository
+type Syncer struct {
+	source  Source
+	service *bgp.Service
+	db      *database.DB
+	options Options
+	logger  *zap.Logger
+
+	mu      sync.Mutex // serializes fetches and syncs
+	trigger chan struct{}
+
+	statusMu sync.RWMutex
+	status   Status
+}
+
+// NewSyncer creates a new GitOps syncer
+func NewSyncer(source Source, service *bgp.Service, db *database.DB, opts Options, logger *zap.Logger) *Syncer {
+	if opts.Path == "" {
+		opts.Path = "."
+	}
+	if opts.Versions == nil {
+		opts.Versions = configstore.New(db, configstore.Options{}, logger)
+	}
+
+	return &Syncer{
+		source:  source,
+		service: service,
+		db:      db,
+		options: opts,
+		logger:  logger,
+		trigger: make(chan struct{}, 1),
+	}
+}
+
+// Plan fetches the repository and returns the changes a sync would make
+// without applying them
+func (s *Syncer) Plan(ctx context.Context) (*Plan, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	plan, _, err := s.plan(ctx)
+	return plan, err
+}
+
+// plan fetches and loads the definitions and diffs them against the database
+func (s *Syncer) plan(ctx context.Context) (*Plan, *Definitions, error) {
+	dir, commit, err := s.source.Fetch(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	defs, err := LoadDefinitions(filepath.Join(dir, s.options.Path))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	plan, err := computePlan(ctx, s.service, defs, s.options.Prune)
+	if err != nil {
+		return nil, nil, err
+	}
+	plan.CommitSHA = commit
+
+	return plan, defs, nil
+}
+
+// Sync fetches the repository, applies the planned changes and records the
+// synced commit as a config version. userID is recorded as the version
+// creator and is zero for automatic syncs.
+func (s *Syncer) Sync(ctx context.Context, userID uint) (*Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	result, err := s.sync(ctx, userID)
+	s.recordStatus(result, err)
+
+	if err != nil {
+		s.logger.Error("GitOps sync failed", zap.Error(err))
+		return result, err
+	}
+
+	s.logger.Info("GitOps sync complete",
+		zap.String("commit", result.CommitSHA),
+		zap.Int("changes", len(result.Applied)),
+	)
+	return result, nil
+}
+
+// sync performs a sync while holding the sync lock
+func (s *Syncer) sync(ctx context.Context, userID uint) (*Result, error) {
+	plan, defs, err := s.plan(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &Result{CommitSHA: plan.CommitSHA, Plan: plan, Applied: []*Change{}}
+	if len(plan.Conflicts) > 0 {
+		return result, fmt.Errorf("%w: %s", ErrConflict, strings.Join(plan.Conflicts, "; "))
+	}
+
+	result.Applied, err = applyPlan(ctx, s.service, plan)
+	if err != nil {
+		return result, err
+	}
+
+	result.Version, err = s.recordVersion(ctx, plan.CommitSHA, defs, userID)
+	if err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
+
+// recordVersion stores the synced definitions as a config version tagged
+// with the commit SHA. A commit is only recorded once.
+func (s *Syncer) recordVersion(ctx context.Context, commit string, defs *Definitions, userID uint) (*models.ConfigVersion, error) {
+	var version models.ConfigVersion
+	err := s.db.Where("commit_sha = ?", commit).First(&version).Error
+	if err == nil {
+		if err := s.options.Versions.Load(ctx, &version); err != nil {
+			return nil, err
+		}
+		return &version, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("failed to look up config version: %w", err)
+	}
+
+	config := fmt.Sprintf("! Synced from commit %s\n!\n%s", commit, defs.Render())
+	version = models.ConfigVersion{
+		Description: "GitOps sync of commit " + shortSHA(commit),
+		Config:      config,
+		Hash:        fmt.Sprintf("%x", sha256.Sum256([]byte(config))),
+		CreatedBy:   userID,
+		CommitSHA:   commit,
+	}
+	if err := s.options.Versions.Save(ctx, &version); err != nil {
+		return nil, err
+	}
+
+	return &version, nil
+}
+
+// recordStatus updates the status after a sync attempt
+func (s *Syncer) recordStatus(result *Result, err error) {
+	s.statusMu.Lock()
+	defer s.statusMu.Unlock()
+
+	now := time.Now()
+	s.status.LastSyncAt = &now
+	s.status.LastResult = result
+	s.status.LastError = ""
+	if err != nil {
+		s.status.LastError = err.Error()
+		return
+	}
+	s.status.CommitSHA = result.CommitSHA
+}
+
+// Status returns the outcome of the most recent sync
+func (s *Syncer) Status() Status {
+	s.statusMu.RLock()
+	defer s.statusMu.RUnlock()
+	return s.status
+}
+
+// Trigger schedules a sync on the running sync loop. Triggers received
+// while a sync is already scheduled are coalesced.
+func (s *Syncer) Trigger() {
+	select {
+	case s.trigger <- struct{}{}:
+	default:
+	}
+}
+
+// Start syncs immediately and then every interval, or when triggered.
+// An interval of zero only syncs on triggers.
+func (s *Syncer) Start(ctx context.Context, interval time.Duration) {
+	var tick <-chan time.Time
+	if interval > 0 {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	s.logger.Info("Started GitOps sync", zap.Duration("interval", interval))
+
+	s.Sync(ctx, 0)
+	for {
+		select {
+		case <-ctx.Done():
+			s.logger.Info("Stopped GitOps sync")
+			return
+		case <-tick:
+			s.Sync(ctx, 0)
+		case <-s.trigger:
+			s.Sync(ctx, 0)
+		}
+	}
+}
+
+// shortSHA abbreviates a commit SHA for display
+func shortSHA(commit string) string {
+	if len(commit) > 12 {
+		return commit[:12]
+	}
+	return commit
+}
@@ -0,0 +1,254 @@
+package gitops
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/padminisys/flintroute/internal/bgp"
+	"github.com/padminisys/flintroute/internal/database"
+	"github.com/padminisys/flintroute/internal/encryption"
+	"github.com/padminisys/flintroute/internal/frr"
+	"github.com/padminisys/flintroute/internal/testutil"
+	"github.com/padminisys/flintroute/internal/websocket"
+	"github.com/padminisys/flintroute/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// fakeSource serves definitions from a local directory at a fixed commit
+type fakeSource struct {
+	dir    string
+	commit string
+}
+
+func (f *fakeSource) Fetch(ctx context.Context) (string, string, error) {
+	return f.dir, f.commit, nil
+}
+
+// setupTestSyncer creates a syncer backed by a disconnected FRR client, so
+// peers are stored as pending sync and policy applies fail
+func setupTestSyncer(t *testing.T, files map[string]string) (*Syncer, *fakeSource, *bgp.Service, *database.DB) {
+	t.Helper()
+
+	logger := zap.NewNop()
+	db := testutil.SetupTestDB(t)
+	t.Cleanup(func() { testutil.CleanupTestDB(t, db) })
+
+	frrClient, err := frr.NewClient("localhost", 50051, logger)
+	require.NoError(t, err)
+
+	passwordCipher, err := encryption.NewCipher(bytes.Repeat([]byte{0x42}, encryption.KeySize))
+	require.NoError(t, err)
+
+	service := bgp.NewService(db, frrClient, websocket.NewHub(logger), bgp.ServiceConfig{
+		PasswordCipher: passwordCipher,
+	}, logger)
+
+	source := &fakeSource{dir: t.TempDir(), commit: "1111111111111111111111111111111111111111"}
+	writeDefinitions(t, source.dir, files)
+
+	syncer := NewSyncer(source, service, db, Options{Prune: true}, logger)
+	return syncer, source, service, db
+}
+
+const twoPeers = `
+peers:
+  - name: upstream-a
+    ip_address: 192.0.2.1
+    asn: 65001
+    remote_asn: 65002
+    password: secret://env/UPSTREAM_A_PASSWORD
+  - name: upstream-b
+    ip_address: 192.0.2.2
+    asn: 65001
+    remote_asn: 65003
+    enabled: false
+`
+
+func TestSync(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Create peers and record version", func(t *testing.T) {
+		syncer, source, service, _ := setupTestSyncer(t, map[string]string{"peers.yaml": twoPeers})
+
+		result, err := syncer.Sync(ctx, 0)
+		require.NoError(t, err)
+		assert.Equal(t, source.commit, result.CommitSHA)
+		require.Len(t, result.Applied, 2)
+		assert.Equal(t, ActionCreate, result.Applied[0].Action)
+
+		require.NotNil(t, result.Version)
+		assert.Equal(t, source.commit, result.Version.CommitSHA)
+		assert.Contains(t, result.Version.Config, "neighbor 192.0.2.1 remote-as 65002")
+		assert.Contains(t, result.Version.Description, "111111111111")
+
+		peers, err := service.ListPeers(ctx)
+		require.NoError(t, err)
+		require.Len(t, peers, 2)
+		for _, peer := range peers {
+			assert.Equal(t, models.ManagedByGitOps, peer.ManagedBy)
+		}
+		assert.True(t, peers[0].HasPassword)
+		assert.False(t, peers[1].Enabled)
+
+		status := syncer.Status()
+		assert.Equal(t, source.commit, status.CommitSHA)
+		assert.Empty(t, status.LastError)
+		assert.NotNil(t, status.LastSyncAt)
+	})
+
+	t.Run("Second sync is a no-op", func(t *testing.T) {
+		syncer, _, _, db := setupTestSyncer(t, map[string]string{"peers.yaml": twoPeers})
+
+		first, err := syncer.Sync(ctx, 0)
+		require.NoError(t, err)
+
+		plan, err := syncer.Plan(ctx)
+		require.NoError(t, err)
+		assert.Empty(t, plan.Changes)
+
+		second, err := syncer.Sync(ctx, 0)
+		require.NoError(t, err)
+		assert.Empty(t, second.Applied)
+		assert.Equal(t, first.Version.ID, second.Version.ID)
+
+		var count int64
+		db.Model(&models.ConfigVersion{}).Count(&count)
+		assert.Equal(t, int64(1), count)
+	})
+
+	t.Run("Update and prune", func(t *testing.T) {
+		syncer, source, service, _ := setupTestSyncer(t, map[string]string{"peers.yaml": twoPeers})
+		_, err := syncer.Sync(ctx, 0)
+		require.NoError(t, err)
+
+		// An API-managed peer is never pruned
+		require.NoError(t, service.CreatePeer(ctx, &models.BGPPeer{
+			Name: "manual", IPAddress: "192.0.2.9", ASN: 65001, RemoteASN: 65009, Enabled: true,
+		}))
+
+		source.commit = "2222222222222222222222222222222222222222"
+		writeDefinitions(t, source.dir, map[string]string{"peers.yaml": `
+peers:
+  - name: upstream-a
+    ip_address: 192.0.2.1
+    asn: 65001
+    remote_asn: 65002
+    description: Primary transit
+    password: secret://env/UPSTREAM_A_PASSWORD_V2
+`})
+
+		plan, err := syncer.Plan(ctx)
+		require.NoError(t, err)
+		require.Len(t, plan.Changes, 2)
+		assert.Equal(t, ActionUpdate, plan.Changes[0].Action)
+		assert.Equal(t, []string{"description", "password"}, plan.Changes[0].Fields)
+		assert.Equal(t, ActionDelete, plan.Changes[1].Action)
+		assert.Equal(t, "192.0.2.2", plan.Changes[1].Name)
+
+		result, err := syncer.Sync(ctx, 0)
+		require.NoError(t, err)
+		assert.Len(t, result.Applied, 2)
+		assert.Equal(t, source.commit, result.Version.CommitSHA)
+
+		peers, err := service.ListPeers(ctx)
+		require.NoError(t, err)
+		require.Len(t, peers, 2)
+		assert.Equal(t, "Primary transit", peers[0].Description)
+		password, err := service.StoredPassword(peers[0])
+		require.NoError(t, err)
+		assert.Equal(t, "secret://env/UPSTREAM_A_PASSWORD_V2", password)
+		assert.Equal(t, "192.0.2.9", peers[1].IPAddress)
+	})
+
+	t.Run("Adopt API-managed peer", func(t *testing.T) {
+		syncer, _, service, _ := setupTestSyncer(t, map[string]string{"peers.yaml": twoPeers})
+		require.NoError(t, service.CreatePeer(ctx, &models.BGPPeer{
+			Name: "upstream-b", IPAddress: "192.0.2.2", ASN: 65001, RemoteASN: 65003, Multihop: 1,
+		}))
+
+		plan, err := syncer.Plan(ctx)
+		require.NoError(t, err)
+		require.Len(t, plan.Changes, 2)
+		assert.Equal(t, ActionUpdate, plan.Changes[1].Action)
+		assert.Equal(t, []string{"managed_by"}, plan.Changes[1].Fields)
+	})
+
+	t.Run("ASN change is a conflict", func(t *testing.T) {
+		syncer, _, service, _ := setupTestSyncer(t, map[string]string{"peers.yaml": twoPeers})
+		require.NoError(t, service.CreatePeer(ctx, &models.BGPPeer{
+			Name: "upstream-a", IPAddress: "192.0.2.1", ASN: 65001, RemoteASN: 65999, Enabled: true,
+		}))
+
+		result, err := syncer.Sync(ctx, 0)
+		assert.ErrorIs(t, err, ErrConflict)
+		require.NotNil(t, result)
+		assert.Empty(t, result.Applied)
+		assert.Nil(t, result.Version)
+		assert.Contains(t, syncer.Status().LastError, "192.0.2.1")
+	})
+
+	t.Run("Invalid definitions", func(t *testing.T) {
+		syncer, _, _, _ := setupTestSyncer(t, map[string]string{"peers.yaml": "peers:\n  - ip_address: nope\n"})
+
+		_, err := syncer.Sync(ctx, 0)
+		assert.ErrorIs(t, err, ErrInvalidDefinitions)
+		assert.Empty(t, syncer.Status().CommitSHA)
+	})
+
+	t.Run("Route-maps are deleted before the lists they reference", func(t *testing.T) {
+		syncer, _, _, db := setupTestSyncer(t, map[string]string{"peers.yaml": twoPeers})
+		require.NoError(t, db.Create(&models.RoutingPolicy{
+			Kind:      models.PolicyRouteMap,
+			Name:      "RM-OLD",
+			Config:    "route-map RM-OLD permit 10\n match community CL-OLD\n",
+			ManagedBy: models.ManagedByGitOps,
+		}).Error)
+		require.NoError(t, db.Create(&models.CommunityList{
+			Name:      "CL-OLD",
+			Type:      models.CommunityListStandard,
+			Entries:   []models.CommunityListEntry{{Seq: 10, Action: "permit", Value: "65000:1"}},
+			ManagedBy: models.ManagedByGitOps,
+		}).Error)
+
+		plan, err := syncer.Plan(ctx)
+		require.NoError(t, err)
+		require.Len(t, plan.Changes, 4)
+		assert.Equal(t, "delete route-map RM-OLD", plan.Changes[2].String())
+		assert.Equal(t, "delete community-list CL-OLD", plan.Changes[3].String())
+	})
+
+	t.Run("Policy apply failure stops the sync", func(t *testing.T) {
+		syncer, _, service, _ := setupTestSyncer(t, map[string]string{
+			"policies.yaml": policyDefinitions,
+			"peers.yaml":    peerDefinitions,
+		})
+
+		plan, err := syncer.Plan(ctx)
+		require.NoError(t, err)
+		require.Len(t, plan.Changes, 3)
+		assert.Equal(t, models.PolicyPrefixList, plan.Changes[0].Kind)
+		assert.Equal(t, models.PolicyRouteMap, plan.Changes[1].Kind)
+		assert.Equal(t, KindPeer, plan.Changes[2].Kind)
+
+		result, err := syncer.Sync(ctx, 0)
+		assert.ErrorIs(t, err, bgp.ErrFRRApplyFailed)
+		assert.Contains(t, err.Error(), "create prefix-list PL-IN")
+		assert.Empty(t, result.Applied)
+		assert.Nil(t, result.Version)
+
+		peers, err := service.ListPeers(ctx)
+		require.NoError(t, err)
+		assert.Empty(t, peers)
+	})
+}
+
+func TestTrigger(t *testing.T) {
+	syncer, _, _, _ := setupTestSyncer(t, map[string]string{"peers.yaml": twoPeers})
+
+	syncer.Trigger()
+	syncer.Trigger()
+	assert.Len(t, syncer.trigger, 1)
+}
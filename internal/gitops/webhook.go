@@ -0,0 +1,54 @@
+package gitops
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+// VerifyWebhook checks a push webhook against the shared secret. GitHub
+// and Gitea style HMAC-SHA256 body signatures and GitLab style tokens are
+// accepted.
+func VerifyWebhook(secret string, header http.Header, body []byte) bool {
+	if secret == "" {
+		return false
+	}
+
+	if token := header.Get("X-Gitlab-Token"); token != "" {
+		return subtle.ConstantTimeCompare([]byte(token), []byte(secret)) == 1
+	}
+
+	signature := header.Get("X-Hub-Signature-256")
+	if signature != "" {
+		signature = strings.TrimPrefix(signature, "sha256=")
+	} else {
+		signature = header.Get("X-Gitea-Signature")
+	}
+	if signature == "" {
+		return false
+	}
+
+	expected, err := hex.DecodeString(signature)
+	if err != nil {
+		return false
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hmac.Equal(mac.Sum(nil), expected)
+}
+
+// PushedBranch returns the branch a push webhook payload refers to, or
+// an empty string when the payload carries no branch ref
+func PushedBranch(body []byte) string {
+	var payload struct {
+		Ref string `json:"ref"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return ""
+	}
+	return strings.TrimPrefix(payload.Ref, "refs/heads/")
+}
@@ -0,0 +1,47 @@
+package gitops
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVerifyWebhook(t *testing.T) {
+	body := []byte(`{"ref":"refs/heads/main"}`)
+	mac := hmac.New(sha256.New, []byte("s3cret"))
+	mac.Write(body)
+	signature := hex.EncodeToString(mac.Sum(nil))
+
+	tests := []struct {
+		name   string
+		secret string
+		header http.Header
+		want   bool
+	}{
+		{"GitHub signature", "s3cret", http.Header{"X-Hub-Signature-256": {"sha256=" + signature}}, true},
+		{"Gitea signature", "s3cret", http.Header{"X-Gitea-Signature": {signature}}, true},
+		{"GitLab token", "s3cret", http.Header{"X-Gitlab-Token": {"s3cret"}}, true},
+		{"Wrong signature", "other", http.Header{"X-Hub-Signature-256": {"sha256=" + signature}}, false},
+		{"Wrong token", "s3cret", http.Header{"X-Gitlab-Token": {"guess"}}, false},
+		{"Malformed signature", "s3cret", http.Header{"X-Hub-Signature-256": {"sha256=zz"}}, false},
+		{"Unsigned", "s3cret", http.Header{}, false},
+		{"No secret configured", "", http.Header{"X-Gitlab-Token": {""}}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, VerifyWebhook(tt.secret, tt.header, body))
+		})
+	}
+}
+
+func TestPushedBranch(t *testing.T) {
+	assert.Equal(t, "main", PushedBranch([]byte(`{"ref":"refs/heads/main"}`)))
+	assert.Equal(t, "refs/tags/v1.0.0", PushedBranch([]byte(`{"ref":"refs/tags/v1.0.0"}`)))
+	assert.Equal(t, "", PushedBranch([]byte(`{}`)))
+	assert.Equal(t, "", PushedBranch([]byte(`not json`)))
+}
@@ -0,0 +1,454 @@
+// Package jobs runs long-running operations such as config restores and
+// reconciliation in the background. Jobs are stored in the database, picked
+// up by a pool of workers and report their progress over WebSocket. Jobs can
+// be scheduled to run at a later time and cancelled until they start.
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/padminisys/flintroute/internal/database"
+	"github.com/padminisys/flintroute/internal/requestid"
+	"github.com/padminisys/flintroute/internal/tenancy"
+	"github.com/padminisys/flintroute/internal/tracing"
+	"github.com/padminisys/flintroute/internal/websocket"
+	"github.com/padminisys/flintroute/pkg/models"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrJobNotFound       = errors.New("job not found")
+	ErrUnknownJobType    = errors.New("unknown job type")
+	ErrJobNotCancellable = errors.New("only queued jobs can be cancelled")
+)
+
+// Progress reports how far a running job has got, as a percentage and a
+// short description of the current step
+type Progress func(percent int, message string)
+
+// Handler runs a job and returns its result, which is stored as JSON
+type Handler func(ctx context.Context, job *models.Job, progress Progress) (interface{}, error)
+
+// contextKey is the type of the context key holding the running job
+type contextKey struct{}
+
+// NewContext returns a copy of ctx running job
+func NewContext(ctx context.Context, job *models.Job) context.Context {
+	return context.WithValue(ctx, contextKey{}, job)
+}
+
+// FromContext returns the job ctx runs, if any. Handlers are called with
+// their job in ctx.
+func FromContext(ctx context.Context) (*models.Job, bool) {
+	job, ok := ctx.Value(contextKey{}).(*models.Job)
+	return job, ok
+}
+
+// Announcer is called once for a scheduled job when it is about to run
+type Announcer func(ctx context.Context, job *models.Job)
+
+// announcement is an announcer and how long before a job's run time it is
+// called
+type announcement struct {
+	lead      time.Duration
+	announcer Announcer
+}
+
+// Queue stores jobs and runs them with a pool of workers
+type Queue struct {
+	db      *database.DB
+	hub     *websocket.Hub
+	workers int
+	logger  *zap.Logger
+
+	mu            sync.RWMutex
+	handlers      map[string]Handler
+	announcements map[string]announcement
+
+	wake chan struct{}
+}
+
+// NewQueue creates a job queue run by the given number of workers
+func NewQueue(db *database.DB, hub *websocket.Hub, workers int, logger *zap.Logger) *Queue {
+	if workers <= 0 {
+		workers = 2
+	}
+	return &Queue{
+		db:            db,
+		hub:           hub,
+		workers:       workers,
+		logger:        logger,
+		handlers:      make(map[string]Handler),
+		announcements: make(map[string]announcement),
+		wake:          make(chan struct{}, 1),
+	}
+}
+
+// Announce sets the announcer called lead before scheduled jobs of jobType
+// run. Jobs scheduled less than lead ahead are announced straight away.
+func (q *Queue) Announce(jobType string, lead time.Duration, announcer Announcer) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.announcements[jobType] = announcement{lead: lead, announcer: announcer}
+}
+
+// Register sets the handler for a job type
+func (q *Queue) Register(jobType string, handler Handler) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.handlers[jobType] = handler
+}
+
+func (q *Queue) handler(jobType string) (Handler, bool) {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+	handler, ok := q.handlers[jobType]
+	return handler, ok
+}
+
+// Enqueue stores a queued job of jobType with payload encoded as JSON. The
+// request ID carried by ctx is kept for the job's logs and events, and the
+// tenant ctx acts for owns the job.
+func (q *Queue) Enqueue(ctx context.Context, jobType string, payload interface{}, createdBy *uint) (*models.Job, error) {
+	return q.enqueue(ctx, jobType, payload, nil, createdBy)
+}
+
+// Schedule stores a job of jobType that is held back until runAt
+func (q *Queue) Schedule(ctx context.Context, jobType string, payload interface{}, runAt time.Time, createdBy *uint) (*models.Job, error) {
+	return q.enqueue(ctx, jobType, payload, &runAt, createdBy)
+}
+
+func (q *Queue) enqueue(ctx context.Context, jobType string, payload interface{}, runAt *time.Time, createdBy *uint) (*models.Job, error) {
+	if _, ok := q.handler(jobType); !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownJobType, jobType)
+	}
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode job payload: %w", err)
+	}
+
+	job := &models.Job{
+		Type:      jobType,
+		Status:    models.JobQueued,
+		Payload:   string(data),
+		CreatedBy: createdBy,
+		RunAt:     runAt,
+		RequestID: requestid.FromContext(ctx),
+		TenantID:  tenancy.ID(ctx),
+		// The worker continues the request's trace
+		TraceParent: tracing.Inject(ctx),
+	}
+	if err := q.db.WithContext(ctx).Create(job).Error; err != nil {
+		return nil, fmt.Errorf("failed to queue job: %w", err)
+	}
+
+	fields := []zap.Field{zap.Uint("job_id", job.ID), zap.String("type", jobType), requestid.Field(ctx)}
+	if runAt != nil {
+		fields = append(fields, zap.Time("run_at", *runAt))
+	}
+	q.logger.Info("Queued job", fields...)
+	q.hub.BroadcastJobUpdate(ctx, job)
+	q.notify()
+	return job, nil
+}
+
+// Cancel cancels a queued job, such as a scheduled job that has not run yet
+func (q *Queue) Cancel(ctx context.Context, id uint) (*models.Job, error) {
+	now := time.Now()
+	result := q.db.WithContext(ctx).Model(&models.Job{}).Scopes(tenancy.Scope(ctx, "jobs")).
+		Where("id = ? AND status = ?", id, models.JobQueued).
+		Updates(map[string]interface{}{"status": models.JobCancelled, "finished_at": now})
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to cancel job: %w", result.Error)
+	}
+
+	job, err := q.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrJobNotCancellable
+	}
+
+	q.logger.Info("Cancelled job", zap.Uint("job_id", job.ID), zap.String("type", job.Type), requestid.Field(ctx))
+	q.hub.BroadcastJobUpdate(ctx, job)
+	return job, nil
+}
+
+// Scheduled returns the scheduled jobs of jobType that have not run yet,
+// soonest first
+func (q *Queue) Scheduled(ctx context.Context, jobType string) ([]*models.Job, error) {
+	var scheduled []*models.Job
+	err := q.db.WithContext(ctx).
+		Where("type = ? AND status = ? AND run_at IS NOT NULL", jobType, models.JobQueued).
+		Order("run_at, id").
+		Find(&scheduled).Error
+	return scheduled, err
+}
+
+// Get returns a job by ID. Jobs of other tenants than the one ctx acts for
+// are not found.
+func (q *Queue) Get(ctx context.Context, id uint) (*models.Job, error) {
+	var job models.Job
+	if err := q.db.WithContext(ctx).Scopes(tenancy.Scope(ctx, "jobs")).First(&job, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrJobNotFound
+		}
+		return nil, err
+	}
+	return &job, nil
+}
+
+// notify wakes a worker without blocking
+func (q *Queue) notify() {
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+}
+
+// claim marks the oldest queued job that is due as running and returns it,
+// or nil when no job is due. Workers race for jobs; the conditional update
+// makes sure only one of them wins.
+func (q *Queue) claim(ctx context.Context) (*models.Job, error) {
+	for {
+		var job models.Job
+		err := q.db.WithContext(ctx).
+			Where("status = ? AND (run_at IS NULL OR run_at <= ?)", models.JobQueued, time.Now()).
+			Order("id").First(&job).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		now := time.Now()
+		result := q.db.WithContext(ctx).Model(&models.Job{}).
+			Where("id = ? AND status = ?", job.ID, models.JobQueued).
+			Updates(map[string]interface{}{"status": models.JobRunning, "started_at": now})
+		if result.Error != nil {
+			return nil, result.Error
+		}
+		if result.RowsAffected == 1 {
+			job.Status = models.JobRunning
+			job.StartedAt = &now
+			return &job, nil
+		}
+	}
+}
+
+// announceDue calls the announcers of scheduled jobs due to run within
+// their lead time. Like claim, the conditional update makes sure each job
+// is announced once.
+func (q *Queue) announceDue(ctx context.Context) error {
+	q.mu.RLock()
+	announcements := make(map[string]announcement, len(q.announcements))
+	for jobType, a := range q.announcements {
+		announcements[jobType] = a
+	}
+	q.mu.RUnlock()
+
+	for jobType, a := range announcements {
+		now := time.Now()
+		var due []*models.Job
+		if err := q.db.WithContext(ctx).
+			Where("type = ? AND status = ? AND announced_at IS NULL AND run_at <= ?", jobType, models.JobQueued, now.Add(a.lead)).
+			Order("run_at, id").
+			Find(&due).Error; err != nil {
+			return err
+		}
+
+		for _, job := range due {
+			result := q.db.WithContext(ctx).Model(&models.Job{}).
+				Where("id = ? AND announced_at IS NULL", job.ID).
+				Update("announced_at", now)
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected == 0 {
+				continue
+			}
+			job.AnnouncedAt = &now
+
+			jobCtx := requestid.NewContext(ctx, job.RequestID)
+			a.announcer(jobCtx, job)
+			q.hub.BroadcastJobUpdate(jobCtx, job)
+		}
+	}
+	return nil
+}
+
+// RunNext runs the oldest queued job and reports whether there was one
+func (q *Queue) RunNext(ctx context.Context) (bool, error) {
+	job, err := q.claim(ctx)
+	if err != nil || job == nil {
+		return false, err
+	}
+
+	ctx = requestid.NewContext(ctx, job.RequestID)
+	ctx = NewContext(ctx, job)
+	ctx, span := tracing.Tracer().Start(tracing.Extract(ctx, job.TraceParent), "job "+job.Type,
+		trace.WithAttributes(attribute.Int("job.id", int(job.ID)), attribute.String("job.type", job.Type)),
+	)
+	defer span.End()
+
+	q.run(ctx, job)
+	if job.Status == models.JobFailed {
+		span.SetStatus(codes.Error, job.Error)
+	}
+	return true, nil
+}
+
+// run executes a claimed job and records its outcome
+func (q *Queue) run(ctx context.Context, job *models.Job) {
+	q.logger.Info("Running job",
+		zap.Uint("job_id", job.ID),
+		zap.String("type", job.Type),
+		requestid.Field(ctx),
+	)
+	q.hub.BroadcastJobUpdate(ctx, job)
+
+	progress := func(percent int, message string) {
+		job.Progress = min(max(percent, 0), 100)
+		job.Message = message
+		if err := q.db.Model(job).Updates(map[string]interface{}{"progress": job.Progress, "message": message}).Error; err != nil {
+			q.logger.Error("Failed to record job progress", zap.Uint("job_id", job.ID), zap.Error(err))
+		}
+		q.hub.BroadcastJobUpdate(ctx, job)
+	}
+
+	result, err := q.execute(ctx, job, progress)
+
+	var data []byte
+	if err == nil {
+		if data, err = json.Marshal(result); err != nil {
+			err = fmt.Errorf("failed to encode job result: %w", err)
+		}
+	}
+
+	now := time.Now()
+	job.FinishedAt = &now
+	if err != nil {
+		job.Status = models.JobFailed
+		job.Error = err.Error()
+		q.logger.Warn("Job failed",
+			zap.Uint("job_id", job.ID),
+			zap.String("type", job.Type),
+			zap.Error(err),
+			requestid.Field(ctx),
+		)
+	} else {
+		job.Status = models.JobSucceeded
+		job.Progress = 100
+		job.Result = data
+		q.logger.Info("Job succeeded",
+			zap.Uint("job_id", job.ID),
+			zap.String("type", job.Type),
+			zap.Duration("duration", now.Sub(*job.StartedAt)),
+			requestid.Field(ctx),
+		)
+	}
+
+	if err := q.db.Model(job).Select("status", "progress", "error", "result", "finished_at").Updates(job).Error; err != nil {
+		q.logger.Error("Failed to record job result", zap.Uint("job_id", job.ID), zap.Error(err))
+	}
+	q.hub.BroadcastJobUpdate(ctx, job)
+}
+
+// execute calls the job's handler, turning panics into errors so a bad job
+// cannot take a worker down
+func (q *Queue) execute(ctx context.Context, job *models.Job, progress Progress) (result interface{}, err error) {
+	handler, ok := q.handler(job.Type)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownJobType, job.Type)
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("job panicked: %v", r)
+		}
+	}()
+	return handler(ctx, job, progress)
+}
+
+// failInterrupted marks jobs left running by a previous process as failed.
+// They are not retried because a half-applied restore or sync should be
+// looked at before it is run again.
+func (q *Queue) failInterrupted(ctx context.Context) error {
+	result := q.db.WithContext(ctx).Model(&models.Job{}).
+		Where("status = ?", models.JobRunning).
+		Updates(map[string]interface{}{
+			"status":      models.JobFailed,
+			"error":       "interrupted by a FlintRoute restart",
+			"finished_at": time.Now(),
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected > 0 {
+		q.logger.Warn("Marked interrupted jobs as failed", zap.Int64("count", result.RowsAffected))
+	}
+	return nil
+}
+
+// Start runs queued jobs with the configured number of workers until ctx
+// is cancelled. Workers poll every interval, which bounds how late
+// scheduled jobs start, and are woken when a job is queued.
+func (q *Queue) Start(ctx context.Context, interval time.Duration) {
+	if err := q.failInterrupted(ctx); err != nil {
+		q.logger.Error("Failed to fail interrupted jobs", zap.Error(err))
+	}
+
+	q.logger.Info("Started job workers", zap.Int("workers", q.workers))
+
+	var wg sync.WaitGroup
+	for i := 0; i < q.workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			q.work(ctx, interval)
+		}()
+	}
+	wg.Wait()
+
+	q.logger.Info("Stopped job workers")
+}
+
+// work runs jobs until the queue is empty, then waits for a wake-up
+func (q *Queue) work(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := q.announceDue(ctx); err != nil {
+			q.logger.Error("Failed to announce scheduled jobs", zap.Error(err))
+		}
+		for {
+			ran, err := q.RunNext(ctx)
+			if err != nil {
+				q.logger.Error("Failed to claim job", zap.Error(err))
+			}
+			if !ran {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-q.wake:
+		}
+	}
+}
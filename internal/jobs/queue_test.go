@@ -0,0 +1,263 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/padminisys/flintroute/internal/requestid"
+	"github.com/padminisys/flintroute/internal/testutil"
+	"github.com/padminisys/flintroute/internal/tracing"
+	"github.com/padminisys/flintroute/internal/websocket"
+	"github.com/padminisys/flintroute/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+func setupTestQueue(t *testing.T) *Queue {
+	logger := zap.NewNop()
+	hub := websocket.NewHub(logger)
+	go hub.Run()
+	return NewQueue(testutil.SetupTestDB(t), hub, 1, logger)
+}
+
+func TestEnqueue(t *testing.T) {
+	ctx := requestid.NewContext(context.Background(), "req-1")
+
+	t.Run("Unknown job type", func(t *testing.T) {
+		queue := setupTestQueue(t)
+
+		_, err := queue.Enqueue(ctx, "unknown", nil, nil)
+		assert.ErrorIs(t, err, ErrUnknownJobType)
+	})
+
+	t.Run("Stores a queued job", func(t *testing.T) {
+		queue := setupTestQueue(t)
+		queue.Register("test", func(ctx context.Context, job *models.Job, progress Progress) (interface{}, error) {
+			return nil, nil
+		})
+		userID := uint(1)
+
+		job, err := queue.Enqueue(ctx, "test", map[string]int{"version_id": 3}, &userID)
+		require.NoError(t, err)
+
+		stored, err := queue.Get(ctx, job.ID)
+		require.NoError(t, err)
+		assert.Equal(t, models.JobQueued, stored.Status)
+		assert.JSONEq(t, `{"version_id":3}`, stored.Payload)
+		assert.Equal(t, "req-1", stored.RequestID)
+		assert.Equal(t, userID, *stored.CreatedBy)
+	})
+
+	t.Run("Missing job", func(t *testing.T) {
+		queue := setupTestQueue(t)
+
+		_, err := queue.Get(ctx, 42)
+		assert.ErrorIs(t, err, ErrJobNotFound)
+	})
+}
+
+func TestRunNext(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Empty queue", func(t *testing.T) {
+		queue := setupTestQueue(t)
+
+		ran, err := queue.RunNext(ctx)
+		require.NoError(t, err)
+		assert.False(t, ran)
+	})
+
+	t.Run("Records progress and result", func(t *testing.T) {
+		queue := setupTestQueue(t)
+		var seen []int
+		queue.Register("test", func(ctx context.Context, job *models.Job, progress Progress) (interface{}, error) {
+			assert.Equal(t, "req-2", requestid.FromContext(ctx))
+			running, ok := FromContext(ctx)
+			require.True(t, ok)
+			assert.Equal(t, job.ID, running.ID)
+			progress(50, "Halfway")
+			stored, err := queue.Get(ctx, job.ID)
+			require.NoError(t, err)
+			seen = append(seen, stored.Progress)
+			return map[string]bool{"in_sync": true}, nil
+		})
+
+		job, err := queue.Enqueue(requestid.NewContext(ctx, "req-2"), "test", nil, nil)
+		require.NoError(t, err)
+
+		ran, err := queue.RunNext(ctx)
+		require.NoError(t, err)
+		assert.True(t, ran)
+		assert.Equal(t, []int{50}, seen)
+
+		stored, err := queue.Get(ctx, job.ID)
+		require.NoError(t, err)
+		assert.Equal(t, models.JobSucceeded, stored.Status)
+		assert.Equal(t, 100, stored.Progress)
+		assert.JSONEq(t, `{"in_sync":true}`, string(stored.Result))
+		assert.NotNil(t, stored.StartedAt)
+		assert.NotNil(t, stored.FinishedAt)
+		assert.True(t, stored.Done())
+	})
+
+	t.Run("Records failures and panics", func(t *testing.T) {
+		queue := setupTestQueue(t)
+		queue.Register("fail", func(ctx context.Context, job *models.Job, progress Progress) (interface{}, error) {
+			return nil, errors.New("FRR is unavailable")
+		})
+		queue.Register("panic", func(ctx context.Context, job *models.Job, progress Progress) (interface{}, error) {
+			panic("boom")
+		})
+
+		failed, err := queue.Enqueue(ctx, "fail", nil, nil)
+		require.NoError(t, err)
+		panicked, err := queue.Enqueue(ctx, "panic", nil, nil)
+		require.NoError(t, err)
+
+		for i := 0; i < 2; i++ {
+			_, err := queue.RunNext(ctx)
+			require.NoError(t, err)
+		}
+
+		stored, err := queue.Get(ctx, failed.ID)
+		require.NoError(t, err)
+		assert.Equal(t, models.JobFailed, stored.Status)
+		assert.Equal(t, "FRR is unavailable", stored.Error)
+
+		stored, err = queue.Get(ctx, panicked.ID)
+		require.NoError(t, err)
+		assert.Equal(t, models.JobFailed, stored.Status)
+		assert.Contains(t, stored.Error, "boom")
+	})
+
+	t.Run("Continues the trace of the request", func(t *testing.T) {
+		recorder := tracetest.NewSpanRecorder()
+		previous := otel.GetTracerProvider()
+		otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+		defer otel.SetTracerProvider(previous)
+
+		queue := setupTestQueue(t)
+		var traceID trace.TraceID
+		queue.Register("test", func(ctx context.Context, job *models.Job, progress Progress) (interface{}, error) {
+			traceID = trace.SpanContextFromContext(ctx).TraceID()
+			return nil, nil
+		})
+
+		requestCtx, span := tracing.Tracer().Start(ctx, "request")
+		_, err := queue.Enqueue(requestCtx, "test", nil, nil)
+		require.NoError(t, err)
+		span.End()
+
+		_, err = queue.RunNext(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, span.SpanContext().TraceID(), traceID)
+
+		var names []string
+		for _, s := range recorder.Ended() {
+			names = append(names, s.Name())
+		}
+		assert.Contains(t, names, "job test")
+	})
+
+	t.Run("Interrupted jobs fail", func(t *testing.T) {
+		queue := setupTestQueue(t)
+		job := models.Job{Type: "test", Status: models.JobRunning}
+		require.NoError(t, queue.db.Create(&job).Error)
+
+		require.NoError(t, queue.failInterrupted(ctx))
+
+		stored, err := queue.Get(ctx, job.ID)
+		require.NoError(t, err)
+		assert.Equal(t, models.JobFailed, stored.Status)
+		assert.Contains(t, stored.Error, "restart")
+	})
+}
+
+func TestSchedule(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Holds jobs back until they are due", func(t *testing.T) {
+		queue := setupTestQueue(t)
+		queue.Register("test", func(ctx context.Context, job *models.Job, progress Progress) (interface{}, error) {
+			return nil, nil
+		})
+
+		later, err := queue.Schedule(ctx, "test", nil, time.Now().Add(time.Hour), nil)
+		require.NoError(t, err)
+		due, err := queue.Schedule(ctx, "test", nil, time.Now().Add(-time.Second), nil)
+		require.NoError(t, err)
+
+		ran, err := queue.RunNext(ctx)
+		require.NoError(t, err)
+		assert.True(t, ran)
+		ran, err = queue.RunNext(ctx)
+		require.NoError(t, err)
+		assert.False(t, ran)
+
+		stored, err := queue.Get(ctx, due.ID)
+		require.NoError(t, err)
+		assert.Equal(t, models.JobSucceeded, stored.Status)
+
+		scheduled, err := queue.Scheduled(ctx, "test")
+		require.NoError(t, err)
+		require.Len(t, scheduled, 1)
+		assert.Equal(t, later.ID, scheduled[0].ID)
+	})
+
+	t.Run("Announces jobs once within their lead time", func(t *testing.T) {
+		queue := setupTestQueue(t)
+		queue.Register("test", func(ctx context.Context, job *models.Job, progress Progress) (interface{}, error) {
+			return nil, nil
+		})
+		var announced []uint
+		queue.Announce("test", 15*time.Minute, func(ctx context.Context, job *models.Job) {
+			announced = append(announced, job.ID)
+		})
+
+		soon, err := queue.Schedule(ctx, "test", nil, time.Now().Add(10*time.Minute), nil)
+		require.NoError(t, err)
+		_, err = queue.Schedule(ctx, "test", nil, time.Now().Add(time.Hour), nil)
+		require.NoError(t, err)
+		_, err = queue.Enqueue(ctx, "test", nil, nil)
+		require.NoError(t, err)
+
+		require.NoError(t, queue.announceDue(ctx))
+		require.NoError(t, queue.announceDue(ctx))
+		assert.Equal(t, []uint{soon.ID}, announced)
+
+		stored, err := queue.Get(ctx, soon.ID)
+		require.NoError(t, err)
+		assert.NotNil(t, stored.AnnouncedAt)
+	})
+
+	t.Run("Cancels queued jobs only", func(t *testing.T) {
+		queue := setupTestQueue(t)
+		queue.Register("test", func(ctx context.Context, job *models.Job, progress Progress) (interface{}, error) {
+			return nil, nil
+		})
+
+		job, err := queue.Schedule(ctx, "test", nil, time.Now().Add(time.Hour), nil)
+		require.NoError(t, err)
+
+		cancelled, err := queue.Cancel(ctx, job.ID)
+		require.NoError(t, err)
+		assert.Equal(t, models.JobCancelled, cancelled.Status)
+		assert.True(t, cancelled.Done())
+
+		_, err = queue.Cancel(ctx, job.ID)
+		assert.ErrorIs(t, err, ErrJobNotCancellable)
+		_, err = queue.Cancel(ctx, 42)
+		assert.ErrorIs(t, err, ErrJobNotFound)
+
+		scheduled, err := queue.Scheduled(ctx, "test")
+		require.NoError(t, err)
+		assert.Empty(t, scheduled)
+	})
+}
@@ -0,0 +1,223 @@
+// Package leader elects one of several FlintRoute instances sharing a
+// database as the leader, through a lease row the leader keeps renewing.
+// Only the leader monitors sessions, reconciles and applies FRR changes;
+// the others stand by and take over once the lease expires.
+package leader
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/padminisys/flintroute/internal/database"
+	"github.com/padminisys/flintroute/pkg/models"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// LeaseName is the name of the lease instances compete for
+const LeaseName = "flintroute"
+
+// Config configures leader election
+type Config struct {
+	// Identity names this instance in the lease. It must be unique among
+	// the instances sharing the database.
+	Identity string
+	// LeaseDuration is how long a lease lasts without being renewed, and
+	// so how long a standby waits before taking over from a failed leader.
+	// Defaults to 15 seconds.
+	LeaseDuration time.Duration
+	// RenewInterval is how often the leader renews its lease and standbys
+	// try to take it. Defaults to a third of LeaseDuration.
+	RenewInterval time.Duration
+}
+
+// withDefaults fills in unset fields
+func (c Config) withDefaults() Config {
+	if c.LeaseDuration <= 0 {
+		c.LeaseDuration = 15 * time.Second
+	}
+	if c.RenewInterval <= 0 || c.RenewInterval >= c.LeaseDuration {
+		c.RenewInterval = c.LeaseDuration / 3
+	}
+	return c
+}
+
+// Status is this instance's view of the leadership
+type Status struct {
+	// Enabled is false when leader election is off and this instance
+	// always leads
+	Enabled  bool   `json:"enabled"`
+	Identity string `json:"identity"`
+	Leader   bool   `json:"leader"`
+	// Holder is the instance holding the lease, empty when nobody does
+	Holder     string     `json:"holder,omitempty"`
+	AcquiredAt *time.Time `json:"acquired_at,omitempty"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	// Elections counts the times this instance became leader
+	Elections int `json:"elections"`
+}
+
+// Elector competes for the lease on behalf of this instance
+type Elector struct {
+	db     *database.DB
+	config Config
+	logger *zap.Logger
+
+	mu     sync.RWMutex
+	status Status
+}
+
+// NewElector creates an elector for the instance named by config.Identity
+func NewElector(db *database.DB, config Config, logger *zap.Logger) *Elector {
+	config = config.withDefaults()
+	return &Elector{
+		db:     db,
+		config: config,
+		logger: logger,
+		status: Status{Enabled: true, Identity: config.Identity},
+	}
+}
+
+// Status returns a copy of this instance's view of the leadership
+func (e *Elector) Status() Status {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.status
+}
+
+// IsLeader reports whether this instance holds the lease
+func (e *Elector) IsLeader() bool {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.status.Leader
+}
+
+// Run competes for the lease every renew interval until ctx is cancelled.
+// Each time this instance becomes leader, lead is started with a context
+// that is cancelled once it loses the lease. On return the lease is
+// released so that a standby takes over right away.
+func (e *Elector) Run(ctx context.Context, lead func(ctx context.Context)) {
+	ticker := time.NewTicker(e.config.RenewInterval)
+	defer ticker.Stop()
+
+	e.logger.Info("Started leader election",
+		zap.String("identity", e.config.Identity),
+		zap.Duration("lease_duration", e.config.LeaseDuration),
+		zap.Duration("renew_interval", e.config.RenewInterval),
+	)
+
+	var cancel context.CancelFunc
+	stepDown := func(reason string) {
+		if cancel == nil {
+			return
+		}
+		cancel()
+		cancel = nil
+		e.mu.Lock()
+		e.status.Leader = false
+		e.mu.Unlock()
+		e.logger.Warn("Lost leadership", zap.String("identity", e.config.Identity), zap.String("reason", reason))
+	}
+
+	for {
+		leading, err := e.tryAcquire(ctx)
+		switch {
+		case err != nil && ctx.Err() == nil:
+			e.logger.Warn("Failed to renew leader lease", zap.Error(err))
+			// Without a renewal the lease may expire before the next
+			// attempt, and a standby take over
+			if status := e.Status(); status.Leader && status.ExpiresAt != nil &&
+				time.Now().Add(e.config.RenewInterval).After(*status.ExpiresAt) {
+				stepDown("lease could not be renewed")
+			}
+		case leading && cancel == nil:
+			cancel = e.startLeading(ctx, lead)
+		case !leading && err == nil:
+			stepDown("lease taken over by " + e.Status().Holder)
+		}
+
+		select {
+		case <-ctx.Done():
+			stepDown("shutting down")
+			e.release()
+			e.logger.Info("Stopped leader election")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// startLeading runs lead with a context that is cancelled by the returned
+// function
+func (e *Elector) startLeading(ctx context.Context, lead func(ctx context.Context)) context.CancelFunc {
+	leaderCtx, cancel := context.WithCancel(ctx)
+	e.logger.Info("Became leader", zap.String("identity", e.config.Identity))
+	go lead(leaderCtx)
+	return cancel
+}
+
+// tryAcquire renews the lease when this instance holds it, or takes it
+// when it has expired, and reports whether this instance now leads
+func (e *Elector) tryAcquire(ctx context.Context) (bool, error) {
+	db := e.db.WithContext(ctx)
+	now := time.Now()
+	expires := now.Add(e.config.LeaseDuration)
+	identity := e.config.Identity
+
+	result := db.Model(&models.LeaderLease{}).
+		Where("name = ? AND (holder = ? OR expires_at < ?)", LeaseName, identity, now).
+		Updates(map[string]interface{}{
+			"acquired_at": gorm.Expr("CASE WHEN holder = ? THEN acquired_at ELSE ? END", identity, now),
+			"holder":      identity,
+			"renewed_at":  now,
+			"expires_at":  expires,
+		})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	if result.RowsAffected == 0 {
+		// Nobody has held the lease yet, or another instance holds it
+		result = db.Clauses(clause.OnConflict{DoNothing: true}).Create(&models.LeaderLease{
+			Name:       LeaseName,
+			Holder:     identity,
+			AcquiredAt: now,
+			RenewedAt:  now,
+			ExpiresAt:  expires,
+		})
+		if result.Error != nil {
+			return false, result.Error
+		}
+	}
+
+	var lease models.LeaderLease
+	if err := db.Where("name = ?", LeaseName).First(&lease).Error; err != nil {
+		return false, err
+	}
+	leading := lease.Holder == identity
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if leading && !e.status.Leader {
+		e.status.Elections++
+	}
+	e.status.Leader = leading
+	e.status.Holder = lease.Holder
+	e.status.AcquiredAt = &lease.AcquiredAt
+	e.status.ExpiresAt = &lease.ExpiresAt
+	return leading, nil
+}
+
+// release expires the lease if this instance holds it
+func (e *Elector) release() {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	err := e.db.WithContext(ctx).Model(&models.LeaderLease{}).
+		Where("name = ? AND holder = ?", LeaseName, e.config.Identity).
+		Update("expires_at", time.Now()).Error
+	if err != nil {
+		e.logger.Warn("Failed to release leader lease", zap.Error(err))
+	}
+}
@@ -0,0 +1,96 @@
+package leader
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/padminisys/flintroute/internal/testutil"
+	"github.com/padminisys/flintroute/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestTryAcquire(t *testing.T) {
+	ctx := context.Background()
+	db := testutil.SetupTestDB(t)
+	first := NewElector(db, Config{Identity: "a", LeaseDuration: time.Hour}, zap.NewNop())
+	second := NewElector(db, Config{Identity: "b", LeaseDuration: time.Hour}, zap.NewNop())
+
+	t.Run("First instance takes the lease", func(t *testing.T) {
+		leading, err := first.tryAcquire(ctx)
+		require.NoError(t, err)
+		assert.True(t, leading)
+
+		leading, err = second.tryAcquire(ctx)
+		require.NoError(t, err)
+		assert.False(t, leading)
+
+		status := second.Status()
+		assert.True(t, status.Enabled)
+		assert.Equal(t, "b", status.Identity)
+		assert.Equal(t, "a", status.Holder)
+		assert.Equal(t, 1, first.Status().Elections)
+	})
+
+	t.Run("Renewing keeps the acquisition time", func(t *testing.T) {
+		acquired := *first.Status().AcquiredAt
+		leading, err := first.tryAcquire(ctx)
+		require.NoError(t, err)
+		assert.True(t, leading)
+
+		var lease models.LeaderLease
+		require.NoError(t, db.First(&lease, "name = ?", LeaseName).Error)
+		assert.True(t, lease.AcquiredAt.Equal(acquired))
+		assert.True(t, lease.RenewedAt.After(acquired))
+		assert.Equal(t, 1, first.Status().Elections)
+	})
+
+	t.Run("Standby takes over an expired lease", func(t *testing.T) {
+		require.NoError(t, db.Model(&models.LeaderLease{}).Where("name = ?", LeaseName).
+			Update("expires_at", time.Now().Add(-time.Second)).Error)
+
+		leading, err := second.tryAcquire(ctx)
+		require.NoError(t, err)
+		assert.True(t, leading)
+
+		leading, err = first.tryAcquire(ctx)
+		require.NoError(t, err)
+		assert.False(t, leading)
+		assert.Equal(t, "b", first.Status().Holder)
+	})
+}
+
+func TestRun(t *testing.T) {
+	db := testutil.SetupTestDB(t)
+	config := Config{Identity: "a", LeaseDuration: time.Minute, RenewInterval: 10 * time.Millisecond}
+	elector := NewElector(db, config, zap.NewNop())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	led := make(chan context.Context, 1)
+	done := make(chan struct{})
+	go func() {
+		elector.Run(ctx, func(ctx context.Context) { led <- ctx })
+		close(done)
+	}()
+
+	var leaderCtx context.Context
+	select {
+	case leaderCtx = <-led:
+	case <-time.After(5 * time.Second):
+		t.Fatal("instance never became leader")
+	}
+	assert.True(t, elector.IsLeader())
+
+	cancel()
+	<-done
+	assert.Error(t, leaderCtx.Err())
+	assert.False(t, elector.IsLeader())
+
+	// The lease was released, so another instance takes over right away
+	standby := NewElector(db, Config{Identity: "b", LeaseDuration: time.Minute}, zap.NewNop())
+	leading, err := standby.tryAcquire(context.Background())
+	require.NoError(t, err)
+	assert.True(t, leading)
+}
@@ -0,0 +1,91 @@
+package logging
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/padminisys/flintroute/internal/config"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+// Logger is the application logger. Its level can be changed while it runs.
+type Logger struct {
+	*zap.Logger
+	// Level is the minimum level logged, shared by every child logger
+	Level zap.AtomicLevel
+
+	file *lumberjack.Logger
+}
+
+// New builds the application logger from cfg: JSON or console lines on
+// stdout, or in a file rotated by size
+func New(cfg config.LoggingConfig) (*Logger, error) {
+	level, err := ParseLevel(cfg.Level)
+	if err != nil {
+		return nil, err
+	}
+
+	encoderConfig := zap.NewProductionEncoderConfig()
+	encoderConfig.TimeKey = "timestamp"
+	encoderConfig.EncodeTime = zapcore.ISO8601TimeEncoder
+
+	var encoder zapcore.Encoder
+	switch cfg.Format {
+	case "", "json":
+		encoder = zapcore.NewJSONEncoder(encoderConfig)
+	case "console":
+		encoderConfig.EncodeLevel = zapcore.CapitalLevelEncoder
+		encoder = zapcore.NewConsoleEncoder(encoderConfig)
+	default:
+		return nil, fmt.Errorf("invalid log format: %s", cfg.Format)
+	}
+
+	l := &Logger{Level: zap.NewAtomicLevelAt(level)}
+	var out io.Writer = os.Stdout
+	if cfg.File != "" {
+		if err := os.MkdirAll(filepath.Dir(cfg.File), 0755); err != nil {
+			return nil, fmt.Errorf("failed to create log directory: %w", err)
+		}
+		l.file = &lumberjack.Logger{
+			Filename:   cfg.File,
+			MaxSize:    cfg.MaxSizeMB,
+			MaxBackups: cfg.MaxBackups,
+			MaxAge:     cfg.MaxAgeDays,
+			Compress:   cfg.Compress,
+		}
+		out = l.file
+	}
+
+	core := zapcore.NewCore(encoder, zapcore.AddSync(out), l.Level)
+	l.Logger = zap.New(core, zap.AddCaller(), zap.AddStacktrace(zapcore.ErrorLevel))
+	return l, nil
+}
+
+// ParseLevel parses debug, info, warn or error; empty means info
+func ParseLevel(s string) (zapcore.Level, error) {
+	switch s {
+	case "", "info":
+		return zapcore.InfoLevel, nil
+	case "debug":
+		return zapcore.DebugLevel, nil
+	case "warn":
+		return zapcore.WarnLevel, nil
+	case "error":
+		return zapcore.ErrorLevel, nil
+	default:
+		return zapcore.InfoLevel, fmt.Errorf("invalid log level: %s (must be debug, info, warn or error)", s)
+	}
+}
+
+// Close flushes the log and closes the log file, if any
+func (l *Logger) Close() error {
+	l.Logger.Sync()
+	if l.file != nil {
+		return l.file.Close()
+	}
+	return nil
+}
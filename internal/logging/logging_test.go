@@ -0,0 +1,62 @@
+package logging
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/padminisys/flintroute/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestNew(t *testing.T) {
+	t.Run("Writes JSON to the log file", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "logs", "flintroute.log")
+		logger, err := New(config.LoggingConfig{Level: "warn", File: path, MaxSizeMB: 1})
+		require.NoError(t, err)
+
+		logger.Info("dropped")
+		logger.Warn("kept")
+		require.NoError(t, logger.Close())
+
+		content, err := os.ReadFile(path)
+		require.NoError(t, err)
+		lines := strings.Split(strings.TrimSpace(string(content)), "\n")
+		require.Len(t, lines, 1)
+
+		var entry map[string]interface{}
+		require.NoError(t, json.Unmarshal([]byte(lines[0]), &entry))
+		assert.Equal(t, "kept", entry["msg"])
+		assert.Equal(t, "warn", entry["level"])
+		assert.Contains(t, entry, "timestamp")
+	})
+
+	t.Run("Level changes at runtime", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "flintroute.log")
+		logger, err := New(config.LoggingConfig{Format: "console", File: path})
+		require.NoError(t, err)
+
+		logger.Debug("dropped")
+		logger.Level.SetLevel(zapcore.DebugLevel)
+		logger.Named("bgp").Debug("kept")
+		require.NoError(t, logger.Close())
+
+		content, err := os.ReadFile(path)
+		require.NoError(t, err)
+		assert.NotContains(t, string(content), "dropped")
+		assert.Contains(t, string(content), "DEBUG")
+		assert.Contains(t, string(content), "kept")
+	})
+
+	t.Run("Rejects invalid settings", func(t *testing.T) {
+		_, err := New(config.LoggingConfig{Level: "trace"})
+		assert.Error(t, err)
+
+		_, err = New(config.LoggingConfig{Format: "logfmt"})
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,279 @@
+// Package peeringdb looks up networks in PeeringDB to pre-fill and check
+// BGP peers: their contacts, the prefix counts they announce and their
+// addresses on IXP peering LANs.
+package peeringdb
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+var (
+	// ErrNotFound is returned when PeeringDB has no network for an ASN
+	ErrNotFound = errors.New("ASN not found in PeeringDB")
+	// ErrMismatch is returned when a peer's ASN or address disagrees with
+	// PeeringDB
+	ErrMismatch = errors.New("peer does not match PeeringDB")
+	// ErrUnavailable is returned when PeeringDB can't be queried
+	ErrUnavailable = errors.New("PeeringDB is unavailable")
+)
+
+// Network is a network registered in PeeringDB
+type Network struct {
+	ASN     uint32 `json:"asn"`
+	Name    string `json:"name"`
+	AKA     string `json:"aka,omitempty"`
+	Website string `json:"website,omitempty"`
+	// IRRASSet is the network's IRR as-set, for building prefix filters
+	IRRASSet string `json:"irr_as_set,omitempty"`
+	Type     string `json:"type,omitempty"`   // e.g. NSP, Content, Cable/DSL/ISP
+	Policy   string `json:"policy,omitempty"` // general peering policy: Open, Selective, Restrictive, No
+	// MaxPrefixesIPv4 and MaxPrefixesIPv6 are the prefix counts the network
+	// recommends configuring as maximum-prefix limits
+	MaxPrefixesIPv4 int       `json:"max_prefixes_ipv4"`
+	MaxPrefixesIPv6 int       `json:"max_prefixes_ipv6"`
+	Contacts        []Contact `json:"contacts"`
+	IXLANs          []IXLAN   `json:"ix_lans"`
+	FetchedAt       time.Time `json:"fetched_at"`
+}
+
+// Contact is a publicly visible point of contact of a network
+type Contact struct {
+	Role  string `json:"role"` // e.g. NOC, Policy, Technical, Abuse
+	Name  string `json:"name,omitempty"`
+	Email string `json:"email,omitempty"`
+	Phone string `json:"phone,omitempty"`
+	URL   string `json:"url,omitempty"`
+}
+
+// IXLAN is a network's presence on an IXP peering LAN
+type IXLAN struct {
+	IXID        int    `json:"ix_id"`
+	Name        string `json:"name"`
+	IPv4        string `json:"ipv4,omitempty"`
+	IPv6        string `json:"ipv6,omitempty"`
+	Speed       int    `json:"speed"` // Mbit/s
+	RouteServer bool   `json:"route_server"`
+}
+
+// MaxPrefixes returns the recommended maximum-prefix limit for a session
+// to ip: the IPv6 count for IPv6 addresses and the IPv4 count otherwise
+func (n *Network) MaxPrefixes(ip string) int {
+	if addr, err := netip.ParseAddr(ip); err == nil && addr.Is6() && !addr.Is4In6() {
+		return n.MaxPrefixesIPv6
+	}
+	return n.MaxPrefixesIPv4
+}
+
+// apiNetwork is a PeeringDB net object as returned with depth=2
+type apiNetwork struct {
+	ASN           uint32 `json:"asn"`
+	Name          string `json:"name"`
+	AKA           string `json:"aka"`
+	Website       string `json:"website"`
+	IRRASSet      string `json:"irr_as_set"`
+	InfoType      string `json:"info_type"`
+	PolicyGeneral string `json:"policy_general"`
+	InfoPrefixes4 int    `json:"info_prefixes4"`
+	InfoPrefixes6 int    `json:"info_prefixes6"`
+	PocSet        []struct {
+		Role    string `json:"role"`
+		Name    string `json:"name"`
+		Email   string `json:"email"`
+		Phone   string `json:"phone"`
+		URL     string `json:"url"`
+		Visible string `json:"visible"`
+	} `json:"poc_set"`
+	NetIXLANSet []apiNetIXLAN `json:"netixlan_set"`
+}
+
+// apiNetIXLAN is a PeeringDB netixlan object
+type apiNetIXLAN struct {
+	ASN      uint32 `json:"asn"`
+	IXID     int    `json:"ix_id"`
+	Name     string `json:"name"`
+	IPAddr4  string `json:"ipaddr4"`
+	IPAddr6  string `json:"ipaddr6"`
+	Speed    int    `json:"speed"`
+	IsRSPeer bool   `json:"is_rs_peer"`
+}
+
+// cachedNetwork is a lookup result; a nil network caches a miss
+type cachedNetwork struct {
+	network   *Network
+	fetchedAt time.Time
+}
+
+// Client queries the PeeringDB API, caching networks for cacheTTL
+type Client struct {
+	baseURL    string
+	apiKey     string
+	cacheTTL   time.Duration
+	httpClient *http.Client
+	logger     *zap.Logger
+
+	mu    sync.Mutex
+	cache map[uint32]cachedNetwork
+}
+
+// NewClient creates a PeeringDB client for the API at baseURL, e.g.
+// https://www.peeringdb.com/api. apiKey is optional; without it PeeringDB
+// applies its anonymous rate limits.
+func NewClient(baseURL, apiKey string, timeout, cacheTTL time.Duration, logger *zap.Logger) *Client {
+	return &Client{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		apiKey:     apiKey,
+		cacheTTL:   cacheTTL,
+		httpClient: &http.Client{Timeout: timeout},
+		logger:     logger,
+		cache:      make(map[uint32]cachedNetwork),
+	}
+}
+
+// Lookup returns the network registered for asn
+func (c *Client) Lookup(ctx context.Context, asn uint32) (*Network, error) {
+	c.mu.Lock()
+	cached, ok := c.cache[asn]
+	c.mu.Unlock()
+	if ok && time.Since(cached.fetchedAt) < c.cacheTTL {
+		if cached.network == nil {
+			return nil, fmt.Errorf("%w: AS%d", ErrNotFound, asn)
+		}
+		return cached.network, nil
+	}
+
+	var nets []apiNetwork
+	if err := c.get(ctx, "net", url.Values{"asn": {fmt.Sprint(asn)}, "depth": {"2"}}, &nets); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	var network *Network
+	if len(nets) > 0 {
+		network = convertNetwork(&nets[0], now)
+	}
+
+	c.mu.Lock()
+	c.cache[asn] = cachedNetwork{network: network, fetchedAt: now}
+	c.mu.Unlock()
+
+	if network == nil {
+		return nil, fmt.Errorf("%w: AS%d", ErrNotFound, asn)
+	}
+	return network, nil
+}
+
+// Validate checks a peer against PeeringDB: remoteASN must be registered,
+// and when ip is registered on an IXP peering LAN it must belong to
+// remoteASN
+func (c *Client) Validate(ctx context.Context, remoteASN uint32, ip string) error {
+	if _, err := c.Lookup(ctx, remoteASN); errors.Is(err, ErrNotFound) {
+		return fmt.Errorf("%w: AS%d is not registered in PeeringDB", ErrMismatch, remoteASN)
+	} else if err != nil {
+		return err
+	}
+
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return nil
+	}
+	field := "ipaddr4"
+	if addr.Is6() && !addr.Is4In6() {
+		field = "ipaddr6"
+	}
+
+	var lans []apiNetIXLAN
+	if err := c.get(ctx, "netixlan", url.Values{field: {addr.Unmap().String()}}, &lans); err != nil {
+		return err
+	}
+	for _, lan := range lans {
+		if lan.ASN != remoteASN {
+			return fmt.Errorf("%w: %s is registered to AS%d at %s", ErrMismatch, ip, lan.ASN, lan.Name)
+		}
+	}
+	return nil
+}
+
+// get queries a PeeringDB object type and decodes the data of the response
+// into v
+func (c *Client) get(ctx context.Context, object string, query url.Values, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+"/"+object+"?"+query.Encode(), nil)
+	if err != nil {
+		return fmt.Errorf("failed to create PeeringDB request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	if c.apiKey != "" {
+		req.Header.Set("Authorization", "Api-Key "+c.apiKey)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrUnavailable, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%w: PeeringDB returned %s for %s", ErrUnavailable, resp.Status, object)
+	}
+
+	var body struct {
+		Data json.RawMessage `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return fmt.Errorf("%w: failed to decode PeeringDB response: %w", ErrUnavailable, err)
+	}
+	if err := json.Unmarshal(body.Data, v); err != nil {
+		return fmt.Errorf("%w: failed to decode PeeringDB %s: %w", ErrUnavailable, object, err)
+	}
+
+	c.logger.Debug("Queried PeeringDB", zap.String("object", object), zap.String("query", query.Encode()))
+	return nil
+}
+
+// convertNetwork converts a PeeringDB net object, keeping only public
+// contacts
+func convertNetwork(net *apiNetwork, fetchedAt time.Time) *Network {
+	network := &Network{
+		ASN:             net.ASN,
+		Name:            net.Name,
+		AKA:             net.AKA,
+		Website:         net.Website,
+		IRRASSet:        net.IRRASSet,
+		Type:            net.InfoType,
+		Policy:          net.PolicyGeneral,
+		MaxPrefixesIPv4: net.InfoPrefixes4,
+		MaxPrefixesIPv6: net.InfoPrefixes6,
+		Contacts:        []Contact{},
+		IXLANs:          []IXLAN{},
+		FetchedAt:       fetchedAt,
+	}
+	for _, poc := range net.PocSet {
+		if poc.Visible != "" && poc.Visible != "Public" {
+			continue
+		}
+		network.Contacts = append(network.Contacts, Contact{
+			Role: poc.Role, Name: poc.Name, Email: poc.Email, Phone: poc.Phone, URL: poc.URL,
+		})
+	}
+	for _, lan := range net.NetIXLANSet {
+		network.IXLANs = append(network.IXLANs, IXLAN{
+			IXID:        lan.IXID,
+			Name:        lan.Name,
+			IPv4:        lan.IPAddr4,
+			IPv6:        lan.IPAddr6,
+			Speed:       lan.Speed,
+			RouteServer: lan.IsRSPeer,
+		})
+	}
+	return network
+}
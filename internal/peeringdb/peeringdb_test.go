@@ -0,0 +1,115 @@
+package peeringdb
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+const testNetwork = `{"data": [{
+	"asn": 64510, "name": "Example Networks", "irr_as_set": "AS-EXAMPLE",
+	"info_type": "NSP", "policy_general": "Open",
+	"info_prefixes4": 1200, "info_prefixes6": 300,
+	"poc_set": [
+		{"role": "NOC", "name": "NOC", "email": "noc@example.net", "visible": "Public"},
+		{"role": "Policy", "name": "Peering", "email": "peering@example.net", "visible": "Users"}
+	],
+	"netixlan_set": [
+		{"asn": 64510, "ix_id": 31, "name": "DE-CIX Frankfurt", "ipaddr4": "80.81.192.10", "ipaddr6": "2001:7f8::fbfe:0:1", "speed": 100000, "is_rs_peer": true}
+	]
+}]}`
+
+func newTestClient(t *testing.T, handler http.HandlerFunc) *Client {
+	t.Helper()
+
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	return NewClient(server.URL+"/api/", "secret-key", 5*time.Second, time.Hour, zap.NewNop())
+}
+
+func TestLookup(t *testing.T) {
+	ctx := context.Background()
+	var queries atomic.Int32
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		queries.Add(1)
+		assert.Equal(t, "/api/net", r.URL.Path)
+		assert.Equal(t, "Api-Key secret-key", r.Header.Get("Authorization"))
+		if r.URL.Query().Get("asn") != "64510" {
+			w.Write([]byte(`{"data": []}`))
+			return
+		}
+		assert.Equal(t, "2", r.URL.Query().Get("depth"))
+		w.Write([]byte(testNetwork))
+	})
+
+	network, err := client.Lookup(ctx, 64510)
+	require.NoError(t, err)
+	assert.Equal(t, "Example Networks", network.Name)
+	assert.Equal(t, "AS-EXAMPLE", network.IRRASSet)
+	assert.Equal(t, 1200, network.MaxPrefixes("80.81.192.10"))
+	assert.Equal(t, 300, network.MaxPrefixes("2001:7f8::fbfe:0:1"))
+	require.Len(t, network.Contacts, 1, "only public contacts are kept")
+	assert.Equal(t, "noc@example.net", network.Contacts[0].Email)
+	require.Len(t, network.IXLANs, 1)
+	assert.Equal(t, IXLAN{IXID: 31, Name: "DE-CIX Frankfurt", IPv4: "80.81.192.10", IPv6: "2001:7f8::fbfe:0:1", Speed: 100000, RouteServer: true}, network.IXLANs[0])
+
+	// Lookups are cached, misses included
+	_, err = client.Lookup(ctx, 64510)
+	require.NoError(t, err)
+	_, err = client.Lookup(ctx, 64999)
+	assert.ErrorIs(t, err, ErrNotFound)
+	_, err = client.Lookup(ctx, 64999)
+	assert.ErrorIs(t, err, ErrNotFound)
+	assert.EqualValues(t, 2, queries.Load())
+}
+
+func TestLookupUnavailable(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTooManyRequests)
+	})
+
+	_, err := client.Lookup(context.Background(), 64510)
+	assert.ErrorIs(t, err, ErrUnavailable)
+}
+
+func TestValidate(t *testing.T) {
+	ctx := context.Background()
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/net":
+			if r.URL.Query().Get("asn") == "64510" {
+				w.Write([]byte(testNetwork))
+				return
+			}
+			w.Write([]byte(`{"data": []}`))
+		case "/api/netixlan":
+			switch {
+			case r.URL.Query().Get("ipaddr4") == "80.81.192.10":
+				w.Write([]byte(`{"data": [{"asn": 64510, "name": "DE-CIX Frankfurt"}]}`))
+			case r.URL.Query().Get("ipaddr4") == "80.81.192.20":
+				w.Write([]byte(`{"data": [{"asn": 64520, "name": "DE-CIX Frankfurt"}]}`))
+			case r.URL.Query().Get("ipaddr6") == "2001:db8::1":
+				w.Write([]byte(`{"data": []}`))
+			default:
+				t.Errorf("unexpected query %s", r.URL.RawQuery)
+			}
+		}
+	})
+
+	assert.NoError(t, client.Validate(ctx, 64510, "80.81.192.10"))
+	assert.NoError(t, client.Validate(ctx, 64510, "2001:db8::1"), "addresses off IXP LANs only need a registered ASN")
+
+	err := client.Validate(ctx, 64510, "80.81.192.20")
+	assert.ErrorIs(t, err, ErrMismatch)
+	assert.Contains(t, err.Error(), "AS64520")
+
+	assert.ErrorIs(t, client.Validate(ctx, 64999, "192.0.2.1"), ErrMismatch)
+}
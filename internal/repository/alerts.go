@@ -0,0 +1,174 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/padminisys/flintroute/internal/database"
+	"github.com/padminisys/flintroute/internal/tenancy"
+	"github.com/padminisys/flintroute/pkg/models"
+	"gorm.io/gorm"
+)
+
+// AlertRepo stores alerts
+type AlertRepo interface {
+	// Get returns an alert, or ErrNotFound
+	Get(ctx context.Context, id uint) (*models.Alert, error)
+	// List returns the alerts matching filter with their peer, the peer's
+	// tags and the acknowledging user, newest first
+	List(ctx context.Context, filter AlertFilter) ([]models.Alert, error)
+	// ListPage returns a page of the alerts matching filter, newest first,
+	// and the cursor of the next page, nil on the last page
+	ListPage(ctx context.Context, filter AlertFilter, page PageQuery) ([]models.Alert, *Cursor, error)
+	// Each calls fn with the alerts matching filter and their peer, in
+	// batches of up to size alerts, stopping at fn's first error
+	Each(ctx context.Context, filter AlertFilter, size int, fn func([]models.Alert) error) error
+	// Create stores a new alert
+	Create(ctx context.Context, alert *models.Alert) error
+	// Save stores an existing alert
+	Save(ctx context.Context, alert *models.Alert) error
+	// Acknowledge acknowledges the unacknowledged alerts matching filter as
+	// userID, returning how many it acknowledged
+	Acknowledge(ctx context.Context, filter AlertFilter, userID uint, at time.Time) (int64, error)
+	// CountBySeverity counts the alerts matching filter by severity and
+	// acknowledgement
+	CountBySeverity(ctx context.Context, filter AlertFilter) ([]AlertCount, error)
+}
+
+// AlertFilter selects alerts. Empty fields match every alert; archived
+// alerts only match when Archived is set.
+type AlertFilter struct {
+	Archived     bool
+	Acknowledged *bool
+	Severity     string
+	Source       string
+	Type         string
+	PeerID       *uint
+	// Before matches alerts raised before it
+	Before *time.Time
+	// Tags matches alerts of peers matching every selector
+	Tags []TagSelector
+}
+
+// AlertCount counts the alerts of one severity and acknowledgement
+type AlertCount struct {
+	Severity     string
+	Acknowledged bool
+	Count        int64
+}
+
+// gormAlertRepo is the GORM implementation of AlertRepo
+type gormAlertRepo struct {
+	db *database.DB
+}
+
+// NewAlertRepo returns an AlertRepo storing alerts in db
+func NewAlertRepo(db *database.DB) AlertRepo {
+	return &gormAlertRepo{db: db}
+}
+
+// query returns the query selecting the alerts matching filter
+func (r *gormAlertRepo) query(ctx context.Context, filter AlertFilter) *gorm.DB {
+	query := r.db.WithContext(ctx).Model(&models.Alert{}).Scopes(tenancy.Scope(ctx, "alerts"))
+
+	if filter.Archived {
+		query = query.Where("archived_at IS NOT NULL")
+	} else {
+		query = query.Where("archived_at IS NULL")
+	}
+	if filter.Acknowledged != nil {
+		query = query.Where("acknowledged = ?", *filter.Acknowledged)
+	}
+	if filter.Severity != "" {
+		query = query.Where("severity = ?", filter.Severity)
+	}
+	if filter.Source != "" {
+		query = query.Where("source = ?", filter.Source)
+	}
+	if filter.Type != "" {
+		query = query.Where("type = ?", filter.Type)
+	}
+	if filter.PeerID != nil {
+		query = query.Where("peer_id = ?", *filter.PeerID)
+	}
+	if filter.Before != nil {
+		query = query.Where("created_at < ?", *filter.Before)
+	}
+	if len(filter.Tags) > 0 {
+		query = query.Where("peer_id IN (?)", TaggedPeerIDs(r.db.DB, filter.Tags))
+	}
+	return query
+}
+
+// Get returns an alert, or ErrNotFound
+func (r *gormAlertRepo) Get(ctx context.Context, id uint) (*models.Alert, error) {
+	var alert models.Alert
+	if err := r.db.WithContext(ctx).Scopes(tenancy.Scope(ctx, "alerts")).First(&alert, id).Error; err != nil {
+		return nil, notFound(err)
+	}
+	return &alert, nil
+}
+
+// List returns the alerts matching filter, newest first
+func (r *gormAlertRepo) List(ctx context.Context, filter AlertFilter) ([]models.Alert, error) {
+	var alerts []models.Alert
+	if err := r.query(ctx, filter).Preload("Peer.Tags").Preload("User").Order("created_at DESC").Find(&alerts).Error; err != nil {
+		return nil, err
+	}
+	return alerts, nil
+}
+
+// ListPage returns a page of the alerts matching filter, newest first
+func (r *gormAlertRepo) ListPage(ctx context.Context, filter AlertFilter, page PageQuery) ([]models.Alert, *Cursor, error) {
+	var alerts []models.Alert
+	if err := r.query(ctx, filter).Scopes(Keyset(page)).Preload("Peer.Tags").Preload("User").Find(&alerts).Error; err != nil {
+		return nil, nil, err
+	}
+	alerts, next := NextPage(alerts, page.Limit, func(alert *models.Alert) Cursor {
+		return Cursor{CreatedAt: alert.CreatedAt, ID: alert.ID}
+	})
+	return alerts, next, nil
+}
+
+// Each calls fn with the alerts matching filter in batches
+func (r *gormAlertRepo) Each(ctx context.Context, filter AlertFilter, size int, fn func([]models.Alert) error) error {
+	var batch []models.Alert
+	return r.query(ctx, filter).Preload("Peer").FindInBatches(&batch, size, func(_ *gorm.DB, _ int) error {
+		return fn(batch)
+	}).Error
+}
+
+// Create stores a new alert
+func (r *gormAlertRepo) Create(ctx context.Context, alert *models.Alert) error {
+	return r.db.WithContext(ctx).Create(alert).Error
+}
+
+// Save stores an existing alert
+func (r *gormAlertRepo) Save(ctx context.Context, alert *models.Alert) error {
+	return r.db.WithContext(ctx).Save(alert).Error
+}
+
+// Acknowledge acknowledges the unacknowledged alerts matching filter
+func (r *gormAlertRepo) Acknowledge(ctx context.Context, filter AlertFilter, userID uint, at time.Time) (int64, error) {
+	unacknowledged := false
+	filter.Acknowledged = &unacknowledged
+
+	result := r.query(ctx, filter).Updates(map[string]interface{}{
+		"acknowledged":    true,
+		"acknowledged_at": at,
+		"acknowledged_by": userID,
+	})
+	return result.RowsAffected, result.Error
+}
+
+// CountBySeverity counts the alerts matching filter by severity and
+// acknowledgement
+func (r *gormAlertRepo) CountBySeverity(ctx context.Context, filter AlertFilter) ([]AlertCount, error) {
+	var counts []AlertCount
+	if err := r.query(ctx, filter).Select("severity, acknowledged, COUNT(*) AS count").
+		Group("severity, acknowledged").
+		Scan(&counts).Error; err != nil {
+		return nil, err
+	}
+	return counts, nil
+}
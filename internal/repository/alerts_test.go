@@ -0,0 +1,156 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/padminisys/flintroute/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAlertRepo(t *testing.T) {
+	ctx := context.Background()
+
+	setup := func(t *testing.T) (AlertRepo, *models.BGPPeer) {
+		db := setupTestDB(t)
+		peer := newTestPeer("192.0.2.1")
+		peer.Tags = models.PeerTags{{Key: "role", Value: "transit"}}
+		require.NoError(t, NewPeerRepo(db).Save(ctx, peer, nil))
+
+		alerts := NewAlertRepo(db)
+		archivedAt := time.Now()
+		for _, alert := range []*models.Alert{
+			{Type: "peer_down", Severity: "critical", Message: "down", PeerID: &peer.ID},
+			{Type: "peer_up", Severity: "info", Message: "up", PeerID: &peer.ID, Acknowledged: true},
+			{Type: "config_change", Severity: "info", Message: "changed", Source: "alertmanager"},
+			{Type: "peer_down", Severity: "critical", Message: "old", ArchivedAt: &archivedAt},
+		} {
+			require.NoError(t, alerts.Create(ctx, alert))
+		}
+		return alerts, peer
+	}
+
+	t.Run("Lists alerts by filter", func(t *testing.T) {
+		alerts, peer := setup(t)
+
+		listed, err := alerts.List(ctx, AlertFilter{})
+		require.NoError(t, err)
+		assert.Len(t, listed, 3)
+
+		listed, err = alerts.List(ctx, AlertFilter{Archived: true})
+		require.NoError(t, err)
+		require.Len(t, listed, 1)
+		assert.Equal(t, "old", listed[0].Message)
+
+		unacknowledged := false
+		listed, err = alerts.List(ctx, AlertFilter{Acknowledged: &unacknowledged, Severity: "critical"})
+		require.NoError(t, err)
+		require.Len(t, listed, 1)
+		assert.Equal(t, "down", listed[0].Message)
+
+		listed, err = alerts.List(ctx, AlertFilter{Tags: []TagSelector{{Key: "role", Value: "transit"}}})
+		require.NoError(t, err)
+		require.Len(t, listed, 2)
+		require.NotNil(t, listed[0].Peer)
+		assert.Equal(t, peer.IPAddress, listed[0].Peer.IPAddress)
+	})
+
+	t.Run("Lists alerts a page at a time", func(t *testing.T) {
+		alerts, _ := setup(t)
+		// Alerts raised at the same time are ordered by ID
+		at := time.Now()
+		for _, message := range []string{"same time", "same time, later ID"} {
+			require.NoError(t, alerts.Create(ctx, &models.Alert{Type: "peer_down", Severity: "warning", Message: message, CreatedAt: at}))
+		}
+
+		var messages []string
+		page := PageQuery{Limit: 2}
+		for pages := 1; ; pages++ {
+			listed, next, err := alerts.ListPage(ctx, AlertFilter{}, page)
+			require.NoError(t, err)
+			for _, alert := range listed {
+				messages = append(messages, alert.Message)
+			}
+			if next == nil {
+				assert.Equal(t, 3, pages)
+				break
+			}
+			page.Cursor, err = ParseCursor(next.String())
+			require.NoError(t, err)
+		}
+		assert.Equal(t, []string{"same time, later ID", "same time", "changed", "up", "down"}, messages)
+
+		_, err := ParseCursor("not-a-cursor")
+		assert.ErrorIs(t, err, ErrInvalidCursor)
+	})
+
+	t.Run("Pages across changes of the local zone", func(t *testing.T) {
+		local := time.Local
+		t.Cleanup(func() { time.Local = local })
+		ist := time.FixedZone("IST", 5*60*60+30*60)
+		pst := time.FixedZone("PST", -8*60*60)
+
+		db := setupTestDB(t)
+		alerts := NewAlertRepo(db)
+		created := map[uint]bool{}
+		for _, zone := range []*time.Location{ist, ist, pst, pst} {
+			time.Local = zone
+			alert := &models.Alert{Type: "peer_down", Severity: "warning", Message: zone.String()}
+			require.NoError(t, alerts.Create(ctx, alert))
+			created[alert.ID] = true
+		}
+
+		// Each page is requested in another zone than the alerts were
+		// raised in
+		listed := map[uint]int{}
+		page := PageQuery{Limit: 1}
+		for _, zone := range []*time.Location{time.UTC, pst, ist, time.UTC, pst} {
+			time.Local = zone
+			alerts, next, err := alerts.ListPage(ctx, AlertFilter{}, page)
+			require.NoError(t, err)
+			for _, alert := range alerts {
+				listed[alert.ID]++
+			}
+			if next == nil {
+				break
+			}
+			page.Cursor, err = ParseCursor(next.String())
+			require.NoError(t, err)
+		}
+		require.Len(t, listed, len(created))
+		for id, count := range listed {
+			assert.True(t, created[id])
+			assert.Equal(t, 1, count, "alert %d", id)
+		}
+	})
+
+	t.Run("Visits alerts in batches", func(t *testing.T) {
+		alerts, _ := setup(t)
+
+		var batches, visited int
+		require.NoError(t, alerts.Each(ctx, AlertFilter{}, 2, func(batch []models.Alert) error {
+			batches++
+			visited += len(batch)
+			return nil
+		}))
+		assert.Equal(t, 2, batches)
+		assert.Equal(t, 3, visited)
+	})
+
+	t.Run("Acknowledges matching alerts", func(t *testing.T) {
+		alerts, _ := setup(t)
+
+		acknowledged, err := alerts.Acknowledge(ctx, AlertFilter{Severity: "info"}, 1, time.Now())
+		require.NoError(t, err)
+		assert.EqualValues(t, 1, acknowledged)
+
+		counts, err := alerts.CountBySeverity(ctx, AlertFilter{})
+		require.NoError(t, err)
+		assert.ElementsMatch(t, []AlertCount{
+			{Severity: "critical", Acknowledged: false, Count: 1},
+			{Severity: "info", Acknowledged: true, Count: 2},
+		}, counts)
+	})
+}
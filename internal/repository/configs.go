@@ -0,0 +1,68 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/padminisys/flintroute/internal/database"
+	"github.com/padminisys/flintroute/internal/tenancy"
+	"github.com/padminisys/flintroute/pkg/models"
+)
+
+// ConfigRepo looks up configuration versions. Their content is loaded and
+// stored by the config version store, which may keep it outside the
+// database.
+type ConfigRepo interface {
+	// List returns every version with the user who created it, newest
+	// first
+	List(ctx context.Context) ([]models.ConfigVersion, error)
+	// Get returns a version, or ErrNotFound
+	Get(ctx context.Context, id uint) (*models.ConfigVersion, error)
+	// FindByHash returns the version of a configuration by its hash, or
+	// ErrNotFound
+	FindByHash(ctx context.Context, hash string) (*models.ConfigVersion, error)
+	// Annotate stores a version's name, labels and pinning
+	Annotate(ctx context.Context, version *models.ConfigVersion) error
+}
+
+// gormConfigRepo is the GORM implementation of ConfigRepo
+type gormConfigRepo struct {
+	db *database.DB
+}
+
+// NewConfigRepo returns a ConfigRepo looking up versions in db
+func NewConfigRepo(db *database.DB) ConfigRepo {
+	return &gormConfigRepo{db: db}
+}
+
+// List returns every version, newest first
+func (r *gormConfigRepo) List(ctx context.Context) ([]models.ConfigVersion, error) {
+	var versions []models.ConfigVersion
+	if err := r.db.WithContext(ctx).Scopes(tenancy.Scope(ctx, "config_versions")).Preload("User").Order("created_at DESC").Find(&versions).Error; err != nil {
+		return nil, err
+	}
+	return versions, nil
+}
+
+// Get returns a version, or ErrNotFound
+func (r *gormConfigRepo) Get(ctx context.Context, id uint) (*models.ConfigVersion, error) {
+	var version models.ConfigVersion
+	if err := r.db.WithContext(ctx).Scopes(tenancy.Scope(ctx, "config_versions")).First(&version, id).Error; err != nil {
+		return nil, notFound(err)
+	}
+	return &version, nil
+}
+
+// FindByHash returns the version of a configuration by its hash, or
+// ErrNotFound
+func (r *gormConfigRepo) FindByHash(ctx context.Context, hash string) (*models.ConfigVersion, error) {
+	var version models.ConfigVersion
+	if err := r.db.WithContext(ctx).Scopes(tenancy.Scope(ctx, "config_versions")).Where("hash = ?", hash).First(&version).Error; err != nil {
+		return nil, notFound(err)
+	}
+	return &version, nil
+}
+
+// Annotate stores a version's name, labels and pinning
+func (r *gormConfigRepo) Annotate(ctx context.Context, version *models.ConfigVersion) error {
+	return r.db.WithContext(ctx).Model(version).Select("name", "labels", "pinned").Updates(version).Error
+}
@@ -0,0 +1,97 @@
+package repository
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ErrInvalidCursor is returned for a page cursor not issued by a listing
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Cursor is the position of a record in a listing ordered newest first, by
+// creation time and then ID. Unlike an offset it stays put as records are
+// added, and the page after it is found through an index however deep it
+// is.
+//
+// SQLite stores timestamps as text in the zone they were written in and
+// compares them as text, so CreatedAt keeps the record's zone offset. The
+// cursor then binds to the record's stored value whatever the process's
+// local zone is when the next page is requested.
+type Cursor struct {
+	CreatedAt time.Time
+	ID        uint
+}
+
+// PageQuery selects up to Limit records after Cursor, or the first Limit
+// records without one
+type PageQuery struct {
+	Cursor *Cursor
+	Limit  int
+}
+
+// String encodes the cursor as an opaque string
+func (c Cursor) String() string {
+	_, offset := c.CreatedAt.Zone()
+	raw := fmt.Sprintf("%d.%d.%d", c.CreatedAt.UnixNano(), offset, c.ID)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// ParseCursor parses a cursor encoded by Cursor.String
+func ParseCursor(s string) (*Cursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	parts := strings.Split(string(raw), ".")
+	if len(parts) != 3 {
+		return nil, ErrInvalidCursor
+	}
+	nanos, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	offset, err := strconv.Atoi(parts[1])
+	if err != nil || offset < -24*60*60 || offset > 24*60*60 {
+		return nil, ErrInvalidCursor
+	}
+	id, err := strconv.ParseUint(parts[2], 10, 64)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	// Never the local zone, which may differ from the record's
+	createdAt := time.Unix(0, nanos).UTC()
+	if offset != 0 {
+		createdAt = createdAt.In(time.FixedZone("", offset))
+	}
+	return &Cursor{CreatedAt: createdAt, ID: uint(id)}, nil
+}
+
+// Keyset scopes a query to page, newest first. It selects one record more
+// than the page holds, which NextPage uses to tell whether another page
+// follows.
+func Keyset(page PageQuery) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if cur := page.Cursor; cur != nil {
+			db = db.Where("(created_at < ? OR (created_at = ? AND id < ?))", cur.CreatedAt, cur.CreatedAt, cur.ID)
+		}
+		return db.Order("created_at DESC, id DESC").Limit(page.Limit + 1)
+	}
+}
+
+// NextPage trims records selected with Keyset to the page's limit, and
+// returns the cursor of the next page, or nil on the last page. position
+// returns a record's cursor.
+func NextPage[T any](records []T, limit int, position func(*T) Cursor) ([]T, *Cursor) {
+	if len(records) <= limit {
+		return records, nil
+	}
+	records = records[:limit]
+	next := position(&records[limit-1])
+	return records, &next
+}
@@ -0,0 +1,334 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/padminisys/flintroute/pkg/models"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockPeerRepo is a mock implementation of PeerRepo for testing
+type MockPeerRepo struct {
+	mock.Mock
+}
+
+// NewMockPeerRepo creates a new mock peer repository
+func NewMockPeerRepo() *MockPeerRepo {
+	return &MockPeerRepo{}
+}
+
+// Get mocks the Get method
+func (m *MockPeerRepo) Get(ctx context.Context, id uint) (*models.BGPPeer, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.BGPPeer), args.Error(1)
+}
+
+// List mocks the List method
+func (m *MockPeerRepo) List(ctx context.Context, selectors ...TagSelector) ([]*models.BGPPeer, error) {
+	args := m.Called(ctx, selectors)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*models.BGPPeer), args.Error(1)
+}
+
+// Search mocks the Search method
+func (m *MockPeerRepo) Search(ctx context.Context, search PeerSearch) (*PeerSearchResult, error) {
+	args := m.Called(ctx, search)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*PeerSearchResult), args.Error(1)
+}
+
+// ListBySyncStatus mocks the ListBySyncStatus method
+func (m *MockPeerRepo) ListBySyncStatus(ctx context.Context, status string) ([]*models.BGPPeer, error) {
+	args := m.Called(ctx, status)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*models.BGPPeer), args.Error(1)
+}
+
+// ListWithPassword mocks the ListWithPassword method
+func (m *MockPeerRepo) ListWithPassword(ctx context.Context) ([]*models.BGPPeer, error) {
+	args := m.Called(ctx)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*models.BGPPeer), args.Error(1)
+}
+
+// FindByIP mocks the FindByIP method
+func (m *MockPeerRepo) FindByIP(ctx context.Context, ip string, exceptID uint) (*models.BGPPeer, error) {
+	args := m.Called(ctx, ip, exceptID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.BGPPeer), args.Error(1)
+}
+
+// Save mocks the Save method. When the mocked save succeeds, confirm is
+// run as the real repository would.
+func (m *MockPeerRepo) Save(ctx context.Context, peer *models.BGPPeer, confirm func() error) error {
+	args := m.Called(ctx, peer)
+	if err := args.Error(0); err != nil {
+		return err
+	}
+	if confirm != nil {
+		return confirm()
+	}
+	return nil
+}
+
+// SetSyncStatus mocks the SetSyncStatus method
+func (m *MockPeerRepo) SetSyncStatus(ctx context.Context, peer *models.BGPPeer, status, syncErr string) error {
+	args := m.Called(ctx, peer, status, syncErr)
+	return args.Error(0)
+}
+
+// SetFRRError mocks the SetFRRError method
+func (m *MockPeerRepo) SetFRRError(ctx context.Context, peer *models.BGPPeer, raw string, at time.Time) error {
+	args := m.Called(ctx, peer, raw, at)
+	return args.Error(0)
+}
+
+// SetPassword mocks the SetPassword method
+func (m *MockPeerRepo) SetPassword(ctx context.Context, peer *models.BGPPeer, password string) error {
+	args := m.Called(ctx, peer, password)
+	return args.Error(0)
+}
+
+// Delete mocks the Delete method
+func (m *MockPeerRepo) Delete(ctx context.Context, peer *models.BGPPeer) error {
+	args := m.Called(ctx, peer)
+	return args.Error(0)
+}
+
+// MockSessionRepo is a mock implementation of SessionRepo for testing
+type MockSessionRepo struct {
+	mock.Mock
+}
+
+// NewMockSessionRepo creates a new mock session repository
+func NewMockSessionRepo() *MockSessionRepo {
+	return &MockSessionRepo{}
+}
+
+// GetByPeer mocks the GetByPeer method
+func (m *MockSessionRepo) GetByPeer(ctx context.Context, peerID uint) (*models.BGPSession, error) {
+	args := m.Called(ctx, peerID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.BGPSession), args.Error(1)
+}
+
+// List mocks the List method
+func (m *MockSessionRepo) List(ctx context.Context, selectors ...TagSelector) ([]*models.BGPSession, error) {
+	args := m.Called(ctx, selectors)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*models.BGPSession), args.Error(1)
+}
+
+// ListStored mocks the ListStored method
+func (m *MockSessionRepo) ListStored(ctx context.Context) ([]*models.BGPSession, error) {
+	args := m.Called(ctx)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*models.BGPSession), args.Error(1)
+}
+
+// SaveStates mocks the SaveStates method
+func (m *MockSessionRepo) SaveStates(ctx context.Context, updates []SessionUpdate) error {
+	args := m.Called(ctx, updates)
+	return args.Error(0)
+}
+
+// ListEvents mocks the ListEvents method
+func (m *MockSessionRepo) ListEvents(ctx context.Context, since time.Time) ([]models.SessionEvent, error) {
+	args := m.Called(ctx, since)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.SessionEvent), args.Error(1)
+}
+
+// MockAlertRepo is a mock implementation of AlertRepo for testing
+type MockAlertRepo struct {
+	mock.Mock
+}
+
+// NewMockAlertRepo creates a new mock alert repository
+func NewMockAlertRepo() *MockAlertRepo {
+	return &MockAlertRepo{}
+}
+
+// Get mocks the Get method
+func (m *MockAlertRepo) Get(ctx context.Context, id uint) (*models.Alert, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Alert), args.Error(1)
+}
+
+// List mocks the List method
+func (m *MockAlertRepo) List(ctx context.Context, filter AlertFilter) ([]models.Alert, error) {
+	args := m.Called(ctx, filter)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.Alert), args.Error(1)
+}
+
+// ListPage mocks the ListPage method
+func (m *MockAlertRepo) ListPage(ctx context.Context, filter AlertFilter, page PageQuery) ([]models.Alert, *Cursor, error) {
+	args := m.Called(ctx, filter, page)
+	var next *Cursor
+	if args.Get(1) != nil {
+		next = args.Get(1).(*Cursor)
+	}
+	if args.Get(0) == nil {
+		return nil, next, args.Error(2)
+	}
+	return args.Get(0).([]models.Alert), next, args.Error(2)
+}
+
+// Each mocks the Each method. The mocked alerts are passed to fn in one
+// batch.
+func (m *MockAlertRepo) Each(ctx context.Context, filter AlertFilter, size int, fn func([]models.Alert) error) error {
+	args := m.Called(ctx, filter, size)
+	if args.Get(0) != nil {
+		if err := fn(args.Get(0).([]models.Alert)); err != nil {
+			return err
+		}
+	}
+	return args.Error(1)
+}
+
+// Create mocks the Create method
+func (m *MockAlertRepo) Create(ctx context.Context, alert *models.Alert) error {
+	args := m.Called(ctx, alert)
+	return args.Error(0)
+}
+
+// Save mocks the Save method
+func (m *MockAlertRepo) Save(ctx context.Context, alert *models.Alert) error {
+	args := m.Called(ctx, alert)
+	return args.Error(0)
+}
+
+// Acknowledge mocks the Acknowledge method
+func (m *MockAlertRepo) Acknowledge(ctx context.Context, filter AlertFilter, userID uint, at time.Time) (int64, error) {
+	args := m.Called(ctx, filter, userID, at)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+// CountBySeverity mocks the CountBySeverity method
+func (m *MockAlertRepo) CountBySeverity(ctx context.Context, filter AlertFilter) ([]AlertCount, error) {
+	args := m.Called(ctx, filter)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]AlertCount), args.Error(1)
+}
+
+// MockUserRepo is a mock implementation of UserRepo for testing
+type MockUserRepo struct {
+	mock.Mock
+}
+
+// NewMockUserRepo creates a new mock user repository
+func NewMockUserRepo() *MockUserRepo {
+	return &MockUserRepo{}
+}
+
+// GetByID mocks the GetByID method
+func (m *MockUserRepo) GetByID(ctx context.Context, id uint) (*models.User, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.User), args.Error(1)
+}
+
+// GetByUsername mocks the GetByUsername method
+func (m *MockUserRepo) GetByUsername(ctx context.Context, username string) (*models.User, error) {
+	args := m.Called(ctx, username)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.User), args.Error(1)
+}
+
+// ListByIDs mocks the ListByIDs method
+func (m *MockUserRepo) ListByIDs(ctx context.Context, ids []uint) ([]models.User, error) {
+	args := m.Called(ctx, ids)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.User), args.Error(1)
+}
+
+// EmailTaken mocks the EmailTaken method
+func (m *MockUserRepo) EmailTaken(ctx context.Context, email string, exceptID uint) (bool, error) {
+	args := m.Called(ctx, email, exceptID)
+	return args.Bool(0), args.Error(1)
+}
+
+// Update mocks the Update method
+func (m *MockUserRepo) Update(ctx context.Context, user *models.User, column string, value interface{}) error {
+	args := m.Called(ctx, user, column, value)
+	return args.Error(0)
+}
+
+// MockConfigRepo is a mock implementation of ConfigRepo for testing
+type MockConfigRepo struct {
+	mock.Mock
+}
+
+// NewMockConfigRepo creates a new mock config version repository
+func NewMockConfigRepo() *MockConfigRepo {
+	return &MockConfigRepo{}
+}
+
+// List mocks the List method
+func (m *MockConfigRepo) List(ctx context.Context) ([]models.ConfigVersion, error) {
+	args := m.Called(ctx)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.ConfigVersion), args.Error(1)
+}
+
+// Get mocks the Get method
+func (m *MockConfigRepo) Get(ctx context.Context, id uint) (*models.ConfigVersion, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.ConfigVersion), args.Error(1)
+}
+
+// FindByHash mocks the FindByHash method
+func (m *MockConfigRepo) FindByHash(ctx context.Context, hash string) (*models.ConfigVersion, error) {
+	args := m.Called(ctx, hash)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.ConfigVersion), args.Error(1)
+}
+
+// Annotate mocks the Annotate method
+func (m *MockConfigRepo) Annotate(ctx context.Context, version *models.ConfigVersion) error {
+	args := m.Called(ctx, version)
+	return args.Error(0)
+}
@@ -0,0 +1,206 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/padminisys/flintroute/internal/database"
+	"github.com/padminisys/flintroute/internal/tenancy"
+	"github.com/padminisys/flintroute/pkg/models"
+	"gorm.io/gorm"
+)
+
+// PeerRepo stores BGP peers and their tags
+type PeerRepo interface {
+	// Get returns a peer with its tags, or ErrNotFound
+	Get(ctx context.Context, id uint) (*models.BGPPeer, error)
+	// List returns every peer with its tags, or only those matching every
+	// tag selector when selectors are given
+	List(ctx context.Context, selectors ...TagSelector) ([]*models.BGPPeer, error)
+	// Search returns a page of the peers matching a search, with the total
+	// and facets of every match
+	Search(ctx context.Context, search PeerSearch) (*PeerSearchResult, error)
+	// ListBySyncStatus returns the peers of every tenant with a sync status
+	ListBySyncStatus(ctx context.Context, status string) ([]*models.BGPPeer, error)
+	// ListWithPassword returns the peers of every tenant that have a
+	// password
+	ListWithPassword(ctx context.Context) ([]*models.BGPPeer, error)
+	// FindByIP returns the peer other than exceptID with an IP address,
+	// including one in the trash, or ErrNotFound
+	FindByIP(ctx context.Context, ip string, exceptID uint) (*models.BGPPeer, error)
+	// Save inserts a new peer or saves an existing one, as WritePeer does.
+	// When confirm is non-nil it runs once the peer is written, and its
+	// error rolls the write back.
+	Save(ctx context.Context, peer *models.BGPPeer, confirm func() error) error
+	// SetSyncStatus records a peer's FRR sync status
+	SetSyncStatus(ctx context.Context, peer *models.BGPPeer, status, syncErr string) error
+	// SetFRRError records FRR's raw output for configuration of the peer
+	// it rejected, without touching its update time
+	SetFRRError(ctx context.Context, peer *models.BGPPeer, raw string, at time.Time) error
+	// SetPassword stores a peer's password as given, without touching
+	// its update time
+	SetPassword(ctx context.Context, peer *models.BGPPeer, password string) error
+	// Delete moves a peer to the trash, dropping its prefix history
+	Delete(ctx context.Context, peer *models.BGPPeer) error
+}
+
+// gormPeerRepo is the GORM implementation of PeerRepo
+type gormPeerRepo struct {
+	db *database.DB
+}
+
+// NewPeerRepo returns a PeerRepo storing peers in db
+func NewPeerRepo(db *database.DB) PeerRepo {
+	return &gormPeerRepo{db: db}
+}
+
+// Get returns a peer with its tags, or ErrNotFound
+func (r *gormPeerRepo) Get(ctx context.Context, id uint) (*models.BGPPeer, error) {
+	var peer models.BGPPeer
+	if err := r.db.WithContext(ctx).Scopes(tenancy.Scope(ctx, "bgp_peers")).Preload("Tags").First(&peer, id).Error; err != nil {
+		return nil, notFound(err)
+	}
+	return &peer, nil
+}
+
+// List returns every peer with its tags, or only those matching every tag
+// selector when selectors are given
+func (r *gormPeerRepo) List(ctx context.Context, selectors ...TagSelector) ([]*models.BGPPeer, error) {
+	query := r.db.WithContext(ctx).Scopes(tenancy.Scope(ctx, "bgp_peers")).Preload("Tags")
+	if len(selectors) > 0 {
+		query = query.Where("id IN (?)", TaggedPeerIDs(r.db.DB, selectors))
+	}
+
+	var peers []*models.BGPPeer
+	if err := query.Find(&peers).Error; err != nil {
+		return nil, err
+	}
+	return peers, nil
+}
+
+// ListBySyncStatus returns the peers of every tenant with a sync status
+func (r *gormPeerRepo) ListBySyncStatus(ctx context.Context, status string) ([]*models.BGPPeer, error) {
+	var peers []*models.BGPPeer
+	if err := r.db.WithContext(ctx).Where("sync_status = ?", status).Find(&peers).Error; err != nil {
+		return nil, err
+	}
+	return peers, nil
+}
+
+// ListWithPassword returns the peers of every tenant that have a password
+func (r *gormPeerRepo) ListWithPassword(ctx context.Context) ([]*models.BGPPeer, error) {
+	var peers []*models.BGPPeer
+	if err := r.db.WithContext(ctx).Where("password <> ''").Find(&peers).Error; err != nil {
+		return nil, err
+	}
+	return peers, nil
+}
+
+// FindByIP returns the peer other than exceptID with an IP address,
+// including one in the trash, or ErrNotFound
+func (r *gormPeerRepo) FindByIP(ctx context.Context, ip string, exceptID uint) (*models.BGPPeer, error) {
+	var peer models.BGPPeer
+	if err := r.db.WithContext(ctx).Unscoped().
+		Where("ip_address = ? AND id <> ?", ip, exceptID).
+		First(&peer).Error; err != nil {
+		return nil, notFound(err)
+	}
+	return &peer, nil
+}
+
+// Save inserts a new peer or saves an existing one, running confirm in the
+// same transaction
+func (r *gormPeerRepo) Save(ctx context.Context, peer *models.BGPPeer, confirm func() error) error {
+	if confirm == nil {
+		return WritePeer(r.db.WithContext(ctx), peer)
+	}
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := WritePeer(tx, peer); err != nil {
+			return err
+		}
+		return confirm()
+	})
+}
+
+// SetSyncStatus records a peer's FRR sync status
+func (r *gormPeerRepo) SetSyncStatus(ctx context.Context, peer *models.BGPPeer, status, syncErr string) error {
+	return r.db.WithContext(ctx).Model(peer).Updates(map[string]interface{}{
+		"sync_status": status,
+		"sync_error":  syncErr,
+	}).Error
+}
+
+// SetFRRError records FRR's raw output for rejected configuration
+func (r *gormPeerRepo) SetFRRError(ctx context.Context, peer *models.BGPPeer, raw string, at time.Time) error {
+	return r.db.WithContext(ctx).Model(peer).UpdateColumns(map[string]interface{}{
+		"last_frr_error":    raw,
+		"last_frr_error_at": at,
+	}).Error
+}
+
+// SetPassword stores a peer's password as given
+func (r *gormPeerRepo) SetPassword(ctx context.Context, peer *models.BGPPeer, password string) error {
+	return r.db.WithContext(ctx).Model(peer).UpdateColumn("password", password).Error
+}
+
+// Delete moves a peer to the trash, dropping its prefix and reachability
+// history
+func (r *gormPeerRepo) Delete(ctx context.Context, peer *models.BGPPeer) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for _, model := range []interface{}{&models.PrefixSample{}, &models.ReachabilitySample{}} {
+			if err := tx.Where("peer_id = ?", peer.ID).Delete(model).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Delete(peer).Error
+	})
+}
+
+// WritePeer inserts a new peer or saves an existing one, restoring it when
+// it is in the trash and peer.DeletedAt is cleared. The peer's tags are
+// replaced unless peer.Tags is nil. It is exported for writes that share a
+// transaction with other changes, such as imports.
+func WritePeer(db *gorm.DB, peer *models.BGPPeer) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if peer.ID == 0 {
+			enabled := peer.Enabled
+			if err := tx.Omit("Tags").Create(peer).Error; err != nil {
+				return fmt.Errorf("failed to create peer in database: %w", err)
+			}
+			// GORM skips zero values for columns with defaults, so a disabled
+			// peer would otherwise be stored (and returned) as enabled
+			if !enabled {
+				if err := tx.Model(peer).Update("enabled", false).Error; err != nil {
+					return fmt.Errorf("failed to create peer in database: %w", err)
+				}
+			}
+		} else if err := tx.Unscoped().Omit("Tags").Save(peer).Error; err != nil {
+			return fmt.Errorf("failed to update peer: %w", err)
+		}
+
+		if peer.Tags == nil {
+			return nil
+		}
+		return writeTags(tx, peer)
+	})
+}
+
+// writeTags replaces a peer's stored tags with peer.Tags
+func writeTags(db *gorm.DB, peer *models.BGPPeer) error {
+	if err := db.Where("peer_id = ?", peer.ID).Delete(&models.PeerTag{}).Error; err != nil {
+		return fmt.Errorf("failed to update peer tags: %w", err)
+	}
+	if len(peer.Tags) == 0 {
+		return nil
+	}
+
+	for i := range peer.Tags {
+		peer.Tags[i].ID = 0
+		peer.Tags[i].PeerID = peer.ID
+	}
+	if err := db.Create(&peer.Tags).Error; err != nil {
+		return fmt.Errorf("failed to update peer tags: %w", err)
+	}
+	return nil
+}
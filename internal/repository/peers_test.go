@@ -0,0 +1,155 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/padminisys/flintroute/internal/database"
+	"github.com/padminisys/flintroute/internal/tenancy"
+	"github.com/padminisys/flintroute/internal/testutil"
+	"github.com/padminisys/flintroute/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupTestDB(t *testing.T) *database.DB {
+	t.Helper()
+	db := testutil.SetupTestDB(t)
+	t.Cleanup(func() { testutil.CleanupTestDB(t, db) })
+	return db
+}
+
+func newTestPeer(ip string) *models.BGPPeer {
+	return &models.BGPPeer{
+		Name:      "Peer " + ip,
+		IPAddress: ip,
+		ASN:       65001,
+		RemoteASN: 65002,
+		Enabled:   true,
+	}
+}
+
+func TestPeerRepo(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Saves and gets a peer with its tags", func(t *testing.T) {
+		peers := NewPeerRepo(setupTestDB(t))
+
+		peer := newTestPeer("192.0.2.1")
+		peer.Tags = models.PeerTags{{Key: "role", Value: "transit"}}
+		require.NoError(t, peers.Save(ctx, peer, nil))
+
+		stored, err := peers.Get(ctx, peer.ID)
+		require.NoError(t, err)
+		assert.Equal(t, "192.0.2.1", stored.IPAddress)
+		require.Len(t, stored.Tags, 1)
+		assert.Equal(t, "transit", stored.Tags[0].Value)
+
+		_, err = peers.Get(ctx, peer.ID+1)
+		assert.ErrorIs(t, err, ErrNotFound)
+	})
+
+	t.Run("Stores a disabled peer as disabled", func(t *testing.T) {
+		peers := NewPeerRepo(setupTestDB(t))
+
+		peer := newTestPeer("192.0.2.1")
+		peer.Enabled = false
+		require.NoError(t, peers.Save(ctx, peer, nil))
+
+		stored, err := peers.Get(ctx, peer.ID)
+		require.NoError(t, err)
+		assert.False(t, stored.Enabled)
+	})
+
+	t.Run("Rolls the save back when confirm fails", func(t *testing.T) {
+		peers := NewPeerRepo(setupTestDB(t))
+
+		rejected := errors.New("rejected")
+		err := peers.Save(ctx, newTestPeer("192.0.2.1"), func() error { return rejected })
+		assert.ErrorIs(t, err, rejected)
+
+		stored, err := peers.List(ctx)
+		require.NoError(t, err)
+		assert.Empty(t, stored)
+	})
+
+	t.Run("Lists peers by tag", func(t *testing.T) {
+		peers := NewPeerRepo(setupTestDB(t))
+
+		transit := newTestPeer("192.0.2.1")
+		transit.Tags = models.PeerTags{{Key: "role", Value: "transit"}, {Key: "pop", Value: "fra"}}
+		require.NoError(t, peers.Save(ctx, transit, nil))
+		ixp := newTestPeer("192.0.2.2")
+		ixp.Tags = models.PeerTags{{Key: "role", Value: "ixp"}, {Key: "pop", Value: "fra"}}
+		require.NoError(t, peers.Save(ctx, ixp, nil))
+
+		listed, err := peers.List(ctx, TagSelector{Key: "pop"})
+		require.NoError(t, err)
+		assert.Len(t, listed, 2)
+
+		listed, err = peers.List(ctx, TagSelector{Key: "pop", Value: "fra"}, TagSelector{Key: "role", Value: "ixp"})
+		require.NoError(t, err)
+		require.Len(t, listed, 1)
+		assert.Equal(t, ixp.ID, listed[0].ID)
+	})
+
+	t.Run("Scopes peers to the tenant", func(t *testing.T) {
+		peers := NewPeerRepo(setupTestDB(t))
+
+		tenant := uint(7)
+		owned := newTestPeer("192.0.2.1")
+		owned.TenantID = &tenant
+		require.NoError(t, peers.Save(ctx, owned, nil))
+		other := newTestPeer("192.0.2.2")
+		require.NoError(t, peers.Save(ctx, other, nil))
+
+		tenantCtx := tenancy.WithTenant(ctx, tenant)
+		listed, err := peers.List(tenantCtx)
+		require.NoError(t, err)
+		require.Len(t, listed, 1)
+		assert.Equal(t, owned.ID, listed[0].ID)
+
+		_, err = peers.Get(tenantCtx, other.ID)
+		assert.ErrorIs(t, err, ErrNotFound)
+	})
+
+	t.Run("Finds peers by IP address in the trash", func(t *testing.T) {
+		peers := NewPeerRepo(setupTestDB(t))
+
+		peer := newTestPeer("192.0.2.1")
+		require.NoError(t, peers.Save(ctx, peer, nil))
+		require.NoError(t, peers.Delete(ctx, peer))
+
+		_, err := peers.Get(ctx, peer.ID)
+		assert.ErrorIs(t, err, ErrNotFound)
+
+		found, err := peers.FindByIP(ctx, "192.0.2.1", 0)
+		require.NoError(t, err)
+		assert.Equal(t, peer.ID, found.ID)
+		assert.True(t, found.DeletedAt.Valid)
+
+		_, err = peers.FindByIP(ctx, "192.0.2.1", peer.ID)
+		assert.ErrorIs(t, err, ErrNotFound)
+	})
+
+	t.Run("Records sync status and passwords", func(t *testing.T) {
+		peers := NewPeerRepo(setupTestDB(t))
+
+		peer := newTestPeer("192.0.2.1")
+		require.NoError(t, peers.Save(ctx, peer, nil))
+		require.NoError(t, peers.Save(ctx, newTestPeer("192.0.2.2"), nil))
+
+		require.NoError(t, peers.SetSyncStatus(ctx, peer, models.PeerSyncPending, "unreachable"))
+		pending, err := peers.ListBySyncStatus(ctx, models.PeerSyncPending)
+		require.NoError(t, err)
+		require.Len(t, pending, 1)
+		assert.Equal(t, "unreachable", pending[0].SyncError)
+
+		require.NoError(t, peers.SetPassword(ctx, peer, "secret"))
+		withPassword, err := peers.ListWithPassword(ctx)
+		require.NoError(t, err)
+		require.Len(t, withPassword, 1)
+		assert.Equal(t, peer.ID, withPassword[0].ID)
+	})
+}
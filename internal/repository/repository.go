@@ -0,0 +1,68 @@
+// Package repository stores and loads FlintRoute's models, so services and
+// handlers work against small interfaces rather than GORM queries. Each
+// repository has a GORM implementation, used in production, and a mock for
+// tests.
+//
+// Repositories scope their queries to the tenant of the context they are
+// given, as described in the tenancy package.
+package repository
+
+import (
+	"errors"
+
+	"github.com/padminisys/flintroute/internal/database"
+	"github.com/padminisys/flintroute/pkg/models"
+	"gorm.io/gorm"
+)
+
+// ErrNotFound is returned when the requested record doesn't exist, or
+// belongs to another tenant
+var ErrNotFound = errors.New("record not found")
+
+// Repositories groups the repositories FlintRoute's services use
+type Repositories struct {
+	Peers    PeerRepo
+	Sessions SessionRepo
+	Alerts   AlertRepo
+	Users    UserRepo
+	Configs  ConfigRepo
+}
+
+// New returns the GORM implementations of every repository on db
+func New(db *database.DB) Repositories {
+	return Repositories{
+		Peers:    NewPeerRepo(db),
+		Sessions: NewSessionRepo(db),
+		Alerts:   NewAlertRepo(db),
+		Users:    NewUserRepo(db),
+		Configs:  NewConfigRepo(db),
+	}
+}
+
+// TagSelector matches peers by tag. An empty Value matches any value.
+type TagSelector struct {
+	Key   string
+	Value string
+}
+
+// TaggedPeerIDs returns a subquery selecting the IDs of peers matching
+// every selector
+func TaggedPeerIDs(db *gorm.DB, selectors []TagSelector) *gorm.DB {
+	query := db.Model(&models.BGPPeer{}).Select("id")
+	for _, selector := range selectors {
+		tagged := db.Model(&models.PeerTag{}).Select("peer_id").Where("key = ?", selector.Key)
+		if selector.Value != "" {
+			tagged = tagged.Where("value = ?", selector.Value)
+		}
+		query = query.Where("id IN (?)", tagged)
+	}
+	return query
+}
+
+// notFound translates GORM's missing record error into ErrNotFound
+func notFound(err error) error {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrNotFound
+	}
+	return err
+}
@@ -0,0 +1,248 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/padminisys/flintroute/internal/database"
+	"github.com/padminisys/flintroute/internal/tenancy"
+	"github.com/padminisys/flintroute/pkg/models"
+	"gorm.io/gorm"
+)
+
+// Fields a peer search filters on
+const (
+	// PeerFieldText matches words in the name or description, by prefix, or
+	// the start of the IP address. Without SQLite's full-text index, as on
+	// Postgres, it matches names and descriptions containing the text.
+	PeerFieldText        = "text"
+	PeerFieldName        = "name"
+	PeerFieldDescription = "description"
+	PeerFieldIP          = "ip"
+	// PeerFieldASN is the remote AS, between From and To
+	PeerFieldASN = "asn"
+	// PeerFieldLocalASN is the local AS, between From and To
+	PeerFieldLocalASN = "local_asn"
+	// PeerFieldState is the session state; "Unknown" matches peers without
+	// a session
+	PeerFieldState = "state"
+	// PeerFieldTag is a tag, as "key=value" or "key" for any value
+	PeerFieldTag         = "tag"
+	PeerFieldEnabled     = "enabled"
+	PeerFieldSyncStatus  = "sync"
+	PeerFieldMaintenance = "maintenance"
+	PeerFieldManagedBy   = "managed_by"
+)
+
+// UnknownState is the session state of peers without a session
+const UnknownState = "Unknown"
+
+// maxFacetValues caps the ASN and tag facets
+const maxFacetValues = 20
+
+// PeerFilter is a condition of a peer search
+type PeerFilter struct {
+	Field string
+	Value string
+	// Contains matches values containing Value rather than equal to it,
+	// for the name, description and IP fields
+	Contains bool
+	// From and To bound the AS numbers of ASN fields
+	From, To uint32
+	// Negate matches the peers not meeting the condition
+	Negate bool
+}
+
+// PeerSearch finds the peers matching every filter, a page at a time
+type PeerSearch struct {
+	Filters []PeerFilter
+	Limit   int
+	Offset  int
+}
+
+// FacetCount is how many matching peers have a value
+type FacetCount struct {
+	Value string `json:"value"`
+	Count int64  `json:"count"`
+}
+
+// PeerFacets count the peers matching a search by the values of the fields
+// UIs filter on, most common first. ASN and tag facets hold the top 20.
+type PeerFacets struct {
+	State      []FacetCount `json:"state"`
+	ASN        []FacetCount `json:"asn"`
+	Tag        []FacetCount `json:"tag"`
+	Enabled    []FacetCount `json:"enabled"`
+	SyncStatus []FacetCount `json:"sync_status"`
+}
+
+// PeerSearchResult is a page of the peers matching a search, with the
+// total and facets of every match
+type PeerSearchResult struct {
+	Peers  []*models.BGPPeer `json:"peers"`
+	Total  int64             `json:"total"`
+	Facets PeerFacets        `json:"facets"`
+}
+
+// Search returns a page of the peers matching search, ordered by name, with
+// the facets of all of them
+func (r *gormPeerRepo) Search(ctx context.Context, search PeerSearch) (*PeerSearchResult, error) {
+	matching := func() (*gorm.DB, error) {
+		query := r.db.WithContext(ctx).Model(&models.BGPPeer{}).Scopes(tenancy.Scope(ctx, "bgp_peers"))
+		for _, filter := range search.Filters {
+			condition, args, err := filterCondition(filter, r.db.Dialector.Name())
+			if err != nil {
+				return nil, err
+			}
+			if filter.Negate {
+				query = query.Not(condition, args...)
+			} else {
+				query = query.Where(condition, args...)
+			}
+		}
+		return query, nil
+	}
+
+	query, err := matching()
+	if err != nil {
+		return nil, err
+	}
+	result := &PeerSearchResult{Peers: []*models.BGPPeer{}}
+	if err := query.Count(&result.Total).Error; err != nil {
+		return nil, err
+	}
+
+	query, _ = matching()
+	query = query.Preload("Tags").Order("bgp_peers.name, bgp_peers.ip_address")
+	if search.Limit > 0 {
+		query = query.Limit(search.Limit)
+	}
+	if search.Offset > 0 {
+		query = query.Offset(search.Offset)
+	}
+	if err := query.Find(&result.Peers).Error; err != nil {
+		return nil, err
+	}
+
+	ids, _ := matching()
+	ids = ids.Select("bgp_peers.id")
+	db := r.db.WithContext(ctx)
+	facets := []struct {
+		counts *[]FacetCount
+		query  *gorm.DB
+	}{
+		{&result.Facets.State, db.Table("bgp_peers").
+			Select("COALESCE(bgp_sessions.state, ?) AS value, COUNT(*) AS count", UnknownState).
+			Joins("LEFT JOIN bgp_sessions ON bgp_sessions.peer_id = bgp_peers.id").
+			Where("bgp_peers.id IN (?)", ids).
+			Group("value")},
+		{&result.Facets.ASN, db.Table("bgp_peers").
+			Select("CAST(remote_asn AS TEXT) AS value, COUNT(*) AS count").
+			Where("id IN (?)", ids).
+			Group("remote_asn").Limit(maxFacetValues)},
+		{&result.Facets.Tag, db.Table("peer_tags").
+			Select("key || '=' || value AS value, COUNT(*) AS count").
+			Where("peer_id IN (?)", ids).
+			Group("key, peer_tags.value").Limit(maxFacetValues)},
+		{&result.Facets.Enabled, db.Table("bgp_peers").
+			Select("CASE WHEN enabled THEN 'true' ELSE 'false' END AS value, COUNT(*) AS count").
+			Where("id IN (?)", ids).
+			Group("enabled")},
+		{&result.Facets.SyncStatus, db.Table("bgp_peers").
+			Select("sync_status AS value, COUNT(*) AS count").
+			Where("id IN (?)", ids).
+			Group("sync_status")},
+	}
+	for _, facet := range facets {
+		*facet.counts = []FacetCount{}
+		if err := facet.query.Order("count DESC, value").Scan(facet.counts).Error; err != nil {
+			return nil, fmt.Errorf("failed to count peer facets: %w", err)
+		}
+	}
+	return result, nil
+}
+
+// filterCondition returns the SQL condition of a search filter on a
+// database of the given driver. Text compares ignoring case.
+func filterCondition(filter PeerFilter, driver string) (string, []interface{}, error) {
+	text := func(column string) (string, []interface{}) {
+		if filter.Contains {
+			return "LOWER(" + column + `) LIKE LOWER(?) ESCAPE '\'`, []interface{}{"%" + escapeLike(filter.Value) + "%"}
+		}
+		return "LOWER(" + column + ") = LOWER(?)", []interface{}{filter.Value}
+	}
+
+	switch filter.Field {
+	case PeerFieldText:
+		prefix := escapeLike(filter.Value) + "%"
+		if driver != database.DriverSQLite {
+			contains := "%" + escapeLike(filter.Value) + "%"
+			return `(LOWER(bgp_peers.name) LIKE LOWER(?) ESCAPE '\' OR LOWER(bgp_peers.description) LIKE LOWER(?) ESCAPE '\' OR bgp_peers.ip_address LIKE ? ESCAPE '\')`,
+				[]interface{}{contains, contains, prefix}, nil
+		}
+		if match := ftsQuery(filter.Value); match != "" {
+			return `(bgp_peers.id IN (SELECT docid FROM peer_search WHERE peer_search MATCH ?) OR bgp_peers.ip_address LIKE ? ESCAPE '\')`,
+				[]interface{}{match, prefix}, nil
+		}
+		return `bgp_peers.ip_address LIKE ? ESCAPE '\'`, []interface{}{prefix}, nil
+	case PeerFieldName:
+		condition, args := text("bgp_peers.name")
+		return condition, args, nil
+	case PeerFieldDescription:
+		condition, args := text("bgp_peers.description")
+		return condition, args, nil
+	case PeerFieldIP:
+		condition, args := text("bgp_peers.ip_address")
+		return condition, args, nil
+	case PeerFieldASN:
+		return "bgp_peers.remote_asn BETWEEN ? AND ?", []interface{}{filter.From, filter.To}, nil
+	case PeerFieldLocalASN:
+		return "bgp_peers.asn BETWEEN ? AND ?", []interface{}{filter.From, filter.To}, nil
+	case PeerFieldState:
+		if strings.EqualFold(filter.Value, UnknownState) {
+			return "bgp_peers.id NOT IN (SELECT peer_id FROM bgp_sessions)", nil, nil
+		}
+		return "bgp_peers.id IN (SELECT peer_id FROM bgp_sessions WHERE LOWER(state) = LOWER(?))", []interface{}{filter.Value}, nil
+	case PeerFieldTag:
+		key, value, hasValue := strings.Cut(filter.Value, "=")
+		if !hasValue {
+			return "bgp_peers.id IN (SELECT peer_id FROM peer_tags WHERE key = ?)", []interface{}{key}, nil
+		}
+		return "bgp_peers.id IN (SELECT peer_id FROM peer_tags WHERE key = ? AND value = ?)", []interface{}{key, value}, nil
+	case PeerFieldEnabled:
+		return "bgp_peers.enabled = ?", []interface{}{filter.Value == "true"}, nil
+	case PeerFieldSyncStatus:
+		return "LOWER(bgp_peers.sync_status) = LOWER(?)", []interface{}{filter.Value}, nil
+	case PeerFieldMaintenance:
+		if filter.Value == "true" {
+			return "bgp_peers.maintenance_since IS NOT NULL", nil, nil
+		}
+		return "bgp_peers.maintenance_since IS NULL", nil, nil
+	case PeerFieldManagedBy:
+		return "LOWER(bgp_peers.managed_by) = LOWER(?)", []interface{}{filter.Value}, nil
+	}
+	return "", nil, fmt.Errorf("unknown peer search field %q", filter.Field)
+}
+
+// ftsQuery turns free text into a full-text query matching every word by
+// prefix, or a phrase for text of several words. The words are split as
+// the index's tokenizer splits them, so the query holds no operators.
+func ftsQuery(text string) string {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	switch len(words) {
+	case 0:
+		return ""
+	case 1:
+		return words[0] + "*"
+	}
+	return `"` + strings.Join(words, " ") + `*"`
+}
+
+// escapeLike escapes the wildcards of a LIKE pattern, for ESCAPE '\'
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
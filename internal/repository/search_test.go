@@ -0,0 +1,128 @@
+package repository
+
+import (
+	"context"
+	"testing"
+
+	"github.com/padminisys/flintroute/internal/database"
+	"github.com/padminisys/flintroute/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPeerSearch(t *testing.T) {
+	ctx := context.Background()
+	db := setupTestDB(t)
+	peers := NewPeerRepo(db)
+
+	transit := newTestPeer("192.0.2.1")
+	transit.Name = "Transit AMS"
+	transit.Description = "Hurricane Electric transit"
+	transit.RemoteASN = 65010
+	transit.Tags = models.PeerTags{{Key: "pop", Value: "ams"}}
+	ix := newTestPeer("192.0.2.2")
+	ix.Name = "IX Peer"
+	ix.Description = "Route server"
+	ix.RemoteASN = 65020
+	ix.Enabled = false
+	ix.Tags = models.PeerTags{{Key: "pop", Value: "fra"}}
+	customer := newTestPeer("2001:db8::1")
+	customer.Name = "Customer"
+	customer.RemoteASN = 64512
+	customer.Tags = models.PeerTags{{Key: "pop", Value: "ams"}}
+	for _, peer := range []*models.BGPPeer{transit, ix, customer} {
+		require.NoError(t, peers.Save(ctx, peer, nil))
+	}
+	require.NoError(t, db.Create(&models.BGPSession{PeerID: transit.ID, State: "Established"}).Error)
+	require.NoError(t, db.Create(&models.BGPSession{PeerID: ix.ID, State: "Idle"}).Error)
+
+	search := func(t *testing.T, filters ...PeerFilter) []string {
+		t.Helper()
+		result, err := peers.Search(ctx, PeerSearch{Filters: filters})
+		require.NoError(t, err)
+		names := []string{}
+		for _, peer := range result.Peers {
+			names = append(names, peer.Name)
+		}
+		assert.Equal(t, int64(len(names)), result.Total)
+		return names
+	}
+
+	t.Run("Filters", func(t *testing.T) {
+		tests := []struct {
+			name    string
+			filters []PeerFilter
+			want    []string
+		}{
+			{"Free text by word prefix", []PeerFilter{{Field: PeerFieldText, Value: "hurr"}}, []string{"Transit AMS"}},
+			{"Free text phrase", []PeerFilter{{Field: PeerFieldText, Value: "route serv"}}, []string{"IX Peer"}},
+			{"Free text IP prefix", []PeerFilter{{Field: PeerFieldText, Value: "192.0.2."}}, []string{"IX Peer", "Transit AMS"}},
+			{"ASN", []PeerFilter{{Field: PeerFieldASN, From: 65010, To: 65010}}, []string{"Transit AMS"}},
+			{"ASN range", []PeerFilter{{Field: PeerFieldASN, From: 64512, To: 65010}}, []string{"Customer", "Transit AMS"}},
+			{"State", []PeerFilter{{Field: PeerFieldState, Value: "established"}}, []string{"Transit AMS"}},
+			{"Unknown state", []PeerFilter{{Field: PeerFieldState, Value: "Unknown"}}, []string{"Customer"}},
+			{"Tag", []PeerFilter{{Field: PeerFieldTag, Value: "pop=ams"}}, []string{"Customer", "Transit AMS"}},
+			{"Tag key", []PeerFilter{{Field: PeerFieldTag, Value: "pop"}}, []string{"Customer", "IX Peer", "Transit AMS"}},
+			{"Name contains", []PeerFilter{{Field: PeerFieldName, Value: "peer", Contains: true}}, []string{"IX Peer"}},
+			{"Name contains escapes wildcards", []PeerFilter{{Field: PeerFieldName, Value: "%", Contains: true}}, []string{}},
+			{"Negated", []PeerFilter{{Field: PeerFieldTag, Value: "pop=ams", Negate: true}}, []string{"IX Peer"}},
+			{"Enabled", []PeerFilter{{Field: PeerFieldEnabled, Value: "false"}}, []string{"IX Peer"}},
+			{"Every filter", []PeerFilter{
+				{Field: PeerFieldTag, Value: "pop=ams"},
+				{Field: PeerFieldState, Value: "Established"},
+			}, []string{"Transit AMS"}},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				assert.Equal(t, tt.want, search(t, tt.filters...))
+			})
+		}
+	})
+
+	t.Run("Free text without the full-text index", func(t *testing.T) {
+		tests := []struct {
+			text string
+			want []string
+		}{
+			{"ELECTRIC", []string{"Transit AMS"}},
+			{"route serv", []string{"IX Peer"}},
+			{"192.0.2.", []string{"IX Peer", "Transit AMS"}},
+			{"2.1", []string{}},
+			{"100%", []string{}},
+		}
+		for _, tt := range tests {
+			condition, args, err := filterCondition(PeerFilter{Field: PeerFieldText, Value: tt.text}, database.DriverPostgres)
+			require.NoError(t, err)
+			assert.NotContains(t, condition, "peer_search")
+
+			names := []string{}
+			require.NoError(t, db.Model(&models.BGPPeer{}).Where(condition, args...).Order("name").Pluck("name", &names).Error)
+			assert.Equal(t, tt.want, names, tt.text)
+		}
+	})
+
+	t.Run("Facets count every match", func(t *testing.T) {
+		result, err := peers.Search(ctx, PeerSearch{Limit: 1, Offset: 1})
+		require.NoError(t, err)
+
+		assert.Equal(t, int64(3), result.Total)
+		require.Len(t, result.Peers, 1)
+		assert.Equal(t, "IX Peer", result.Peers[0].Name)
+		assert.Equal(t, []FacetCount{{"Established", 1}, {"Idle", 1}, {"Unknown", 1}}, result.Facets.State)
+		assert.Equal(t, []FacetCount{{"pop=ams", 2}, {"pop=fra", 1}}, result.Facets.Tag)
+		assert.Equal(t, []FacetCount{{"true", 2}, {"false", 1}}, result.Facets.Enabled)
+		assert.Equal(t, []FacetCount{{"64512", 1}, {"65010", 1}, {"65020", 1}}, result.Facets.ASN)
+		assert.Equal(t, []FacetCount{{"synced", 3}}, result.Facets.SyncStatus)
+	})
+
+	t.Run("Index follows changes", func(t *testing.T) {
+		transit.Name = "Backbone"
+		transit.Description = "Upstream"
+		require.NoError(t, peers.Save(ctx, transit, nil))
+		assert.Equal(t, []string{}, search(t, PeerFilter{Field: PeerFieldText, Value: "hurricane"}))
+		assert.Equal(t, []string{"Backbone"}, search(t, PeerFilter{Field: PeerFieldText, Value: "upstream"}))
+
+		require.NoError(t, peers.Delete(ctx, ix))
+		assert.Equal(t, []string{}, search(t, PeerFilter{Field: PeerFieldText, Value: "route"}))
+	})
+}
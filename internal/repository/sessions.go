@@ -0,0 +1,140 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/padminisys/flintroute/internal/database"
+	"github.com/padminisys/flintroute/internal/tenancy"
+	"github.com/padminisys/flintroute/pkg/models"
+	"gorm.io/gorm"
+)
+
+// SessionRepo stores BGP session states and their history
+type SessionRepo interface {
+	// GetByPeer returns a peer's session with the peer and its tags, or
+	// ErrNotFound
+	GetByPeer(ctx context.Context, peerID uint) (*models.BGPSession, error)
+	// List returns every session with its peer and the peer's tags, or only
+	// those of peers matching every tag selector when selectors are given
+	List(ctx context.Context, selectors ...TagSelector) ([]*models.BGPSession, error)
+	// ListStored returns the sessions of every tenant, without their peers
+	ListStored(ctx context.Context) ([]*models.BGPSession, error)
+	// SaveStates writes changed sessions, and the prefix samples and state
+	// change events recorded with them, in one transaction
+	SaveStates(ctx context.Context, updates []SessionUpdate) error
+	// ListEvents returns the state change events since since, oldest
+	// first, preceded by each peer's last event before since
+	ListEvents(ctx context.Context, since time.Time) ([]models.SessionEvent, error)
+}
+
+// SessionUpdate is a changed session to save
+type SessionUpdate struct {
+	Session *models.BGPSession
+	// Sample records the session's prefix counts in its peer's prefix
+	// history; nil when they didn't change
+	Sample *models.PrefixSample
+	// Event records a change of state; nil when the state didn't change
+	Event *models.SessionEvent
+}
+
+// sessionEventRetention is how many session events are kept; older ones
+// are deleted every sessionEventPruneInterval events
+const (
+	sessionEventRetention     = 10000
+	sessionEventPruneInterval = 100
+)
+
+// gormSessionRepo is the GORM implementation of SessionRepo
+type gormSessionRepo struct {
+	db *database.DB
+}
+
+// NewSessionRepo returns a SessionRepo storing sessions in db
+func NewSessionRepo(db *database.DB) SessionRepo {
+	return &gormSessionRepo{db: db}
+}
+
+// GetByPeer returns a peer's session, or ErrNotFound
+func (r *gormSessionRepo) GetByPeer(ctx context.Context, peerID uint) (*models.BGPSession, error) {
+	var session models.BGPSession
+	if err := r.db.WithContext(ctx).Scopes(tenancy.PeerScope(ctx, "peer_id")).Preload("Peer.Tags").Where("peer_id = ?", peerID).First(&session).Error; err != nil {
+		return nil, notFound(err)
+	}
+	return &session, nil
+}
+
+// List returns every session, or only those of peers matching every tag
+// selector when selectors are given
+func (r *gormSessionRepo) List(ctx context.Context, selectors ...TagSelector) ([]*models.BGPSession, error) {
+	query := r.db.WithContext(ctx).Scopes(tenancy.PeerScope(ctx, "peer_id")).Preload("Peer.Tags")
+	if len(selectors) > 0 {
+		query = query.Where("peer_id IN (?)", TaggedPeerIDs(r.db.DB, selectors))
+	}
+
+	var sessions []*models.BGPSession
+	if err := query.Find(&sessions).Error; err != nil {
+		return nil, err
+	}
+	return sessions, nil
+}
+
+// ListStored returns the sessions of every tenant, without their peers
+func (r *gormSessionRepo) ListStored(ctx context.Context) ([]*models.BGPSession, error) {
+	var sessions []*models.BGPSession
+	if err := r.db.WithContext(ctx).Find(&sessions).Error; err != nil {
+		return nil, err
+	}
+	return sessions, nil
+}
+
+// SaveStates writes changed sessions with their samples and events, retrying
+// when the database is busy
+func (r *gormSessionRepo) SaveStates(ctx context.Context, updates []SessionUpdate) error {
+	return r.db.TransactionWithRetry(ctx, func(tx *gorm.DB) error {
+		for _, update := range updates {
+			if err := tx.Save(update.Session).Error; err != nil {
+				return err
+			}
+			if update.Sample != nil {
+				if err := tx.Create(update.Sample).Error; err != nil {
+					return err
+				}
+			}
+			if update.Event != nil {
+				if err := recordSessionEvent(tx, update.Event); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+}
+
+// ListEvents returns the events since since, preceded by each peer's last
+// event before it, which holds the state the peer started in
+func (r *gormSessionRepo) ListEvents(ctx context.Context, since time.Time) ([]models.SessionEvent, error) {
+	var events []models.SessionEvent
+	last := r.db.Model(&models.SessionEvent{}).Select("MAX(id)").Where("created_at < ?", since).Group("peer_id")
+	if err := r.db.WithContext(ctx).Scopes(tenancy.PeerScope(ctx, "peer_id")).Where("id IN (?)", last).Order("created_at, id").Find(&events).Error; err != nil {
+		return nil, err
+	}
+
+	var recent []models.SessionEvent
+	if err := r.db.WithContext(ctx).Scopes(tenancy.PeerScope(ctx, "peer_id")).Where("created_at >= ?", since).Order("created_at, id").Find(&recent).Error; err != nil {
+		return nil, err
+	}
+	return append(events, recent...), nil
+}
+
+// recordSessionEvent stores a session's state change
+func recordSessionEvent(tx *gorm.DB, event *models.SessionEvent) error {
+	if err := tx.Create(event).Error; err != nil {
+		return err
+	}
+
+	if event.ID%sessionEventPruneInterval == 0 && event.ID > sessionEventRetention {
+		return tx.Where("id <= ?", event.ID-sessionEventRetention).Delete(&models.SessionEvent{}).Error
+	}
+	return nil
+}
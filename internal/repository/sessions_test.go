@@ -0,0 +1,98 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/padminisys/flintroute/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSessionRepo(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Saves states with their samples and events", func(t *testing.T) {
+		db := setupTestDB(t)
+		peers, sessions := NewPeerRepo(db), NewSessionRepo(db)
+
+		peer := newTestPeer("192.0.2.1")
+		peer.Tags = models.PeerTags{{Key: "role", Value: "transit"}}
+		require.NoError(t, peers.Save(ctx, peer, nil))
+
+		session := &models.BGPSession{PeerID: peer.ID, State: "Established", PrefixesReceived: 10}
+		require.NoError(t, sessions.SaveStates(ctx, []SessionUpdate{{
+			Session: session,
+			Sample:  &models.PrefixSample{PeerID: peer.ID, PrefixesReceived: 10},
+			Event:   &models.SessionEvent{PeerID: peer.ID, IPAddress: peer.IPAddress, OldState: "Active", NewState: "Established"},
+		}}))
+
+		stored, err := sessions.GetByPeer(ctx, peer.ID)
+		require.NoError(t, err)
+		assert.Equal(t, "Established", stored.State)
+		assert.Equal(t, "192.0.2.1", stored.Peer.IPAddress)
+		require.Len(t, stored.Peer.Tags, 1)
+
+		var samples, events int64
+		require.NoError(t, db.Model(&models.PrefixSample{}).Count(&samples).Error)
+		require.NoError(t, db.Model(&models.SessionEvent{}).Count(&events).Error)
+		assert.EqualValues(t, 1, samples)
+		assert.EqualValues(t, 1, events)
+
+		// Saving again updates the session rather than adding one
+		session.State = "Idle"
+		require.NoError(t, sessions.SaveStates(ctx, []SessionUpdate{{Session: session}}))
+		all, err := sessions.ListStored(ctx)
+		require.NoError(t, err)
+		require.Len(t, all, 1)
+		assert.Equal(t, "Idle", all[0].State)
+	})
+
+	t.Run("Lists sessions by their peer's tags", func(t *testing.T) {
+		db := setupTestDB(t)
+		peers, sessions := NewPeerRepo(db), NewSessionRepo(db)
+
+		var updates []SessionUpdate
+		for ip, role := range map[string]string{"192.0.2.1": "transit", "192.0.2.2": "ixp"} {
+			peer := newTestPeer(ip)
+			peer.Tags = models.PeerTags{{Key: "role", Value: role}}
+			require.NoError(t, peers.Save(ctx, peer, nil))
+			updates = append(updates, SessionUpdate{Session: &models.BGPSession{PeerID: peer.ID, State: "Established"}})
+		}
+		require.NoError(t, sessions.SaveStates(ctx, updates))
+
+		listed, err := sessions.List(ctx, TagSelector{Key: "role", Value: "ixp"})
+		require.NoError(t, err)
+		require.Len(t, listed, 1)
+		assert.Equal(t, "192.0.2.2", listed[0].Peer.IPAddress)
+	})
+
+	t.Run("Lists events with the state before them", func(t *testing.T) {
+		db := setupTestDB(t)
+		sessions := NewSessionRepo(db)
+
+		now := time.Now()
+		for _, event := range []models.SessionEvent{
+			{CreatedAt: now.Add(-3 * time.Hour), PeerID: 1, IPAddress: "192.0.2.1", NewState: "Established"},
+			{CreatedAt: now.Add(-2 * time.Hour), PeerID: 1, IPAddress: "192.0.2.1", NewState: "Idle"},
+			{CreatedAt: now.Add(-2 * time.Hour), PeerID: 2, IPAddress: "192.0.2.2", NewState: "Established"},
+			{CreatedAt: now.Add(-time.Minute), PeerID: 1, IPAddress: "192.0.2.1", NewState: "Established"},
+		} {
+			require.NoError(t, db.Create(&event).Error)
+		}
+
+		events, err := sessions.ListEvents(ctx, now.Add(-time.Hour))
+		require.NoError(t, err)
+		require.Len(t, events, 3)
+		assert.Equal(t, "Idle", events[0].NewState)
+		assert.Equal(t, uint(2), events[1].PeerID)
+		assert.Equal(t, "Established", events[2].NewState)
+		assert.Equal(t, uint(1), events[2].PeerID)
+	})
+
+	t.Run("Reports a missing session", func(t *testing.T) {
+		_, err := NewSessionRepo(setupTestDB(t)).GetByPeer(ctx, 1)
+		assert.ErrorIs(t, err, ErrNotFound)
+	})
+}
@@ -0,0 +1,75 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/padminisys/flintroute/internal/database"
+	"github.com/padminisys/flintroute/pkg/models"
+)
+
+// UserRepo stores users
+type UserRepo interface {
+	// GetByID returns a user, or ErrNotFound
+	GetByID(ctx context.Context, id uint) (*models.User, error)
+	// GetByUsername returns the user with a username, or ErrNotFound
+	GetByUsername(ctx context.Context, username string) (*models.User, error)
+	// ListByIDs returns the users with the given IDs that exist
+	ListByIDs(ctx context.Context, ids []uint) ([]models.User, error)
+	// EmailTaken reports whether a user other than exceptID has an email
+	// address
+	EmailTaken(ctx context.Context, email string, exceptID uint) (bool, error)
+	// Update stores a single column of a user
+	Update(ctx context.Context, user *models.User, column string, value interface{}) error
+}
+
+// gormUserRepo is the GORM implementation of UserRepo
+type gormUserRepo struct {
+	db *database.DB
+}
+
+// NewUserRepo returns a UserRepo storing users in db
+func NewUserRepo(db *database.DB) UserRepo {
+	return &gormUserRepo{db: db}
+}
+
+// GetByID returns a user, or ErrNotFound
+func (r *gormUserRepo) GetByID(ctx context.Context, id uint) (*models.User, error) {
+	var user models.User
+	if err := r.db.WithContext(ctx).First(&user, id).Error; err != nil {
+		return nil, notFound(err)
+	}
+	return &user, nil
+}
+
+// GetByUsername returns the user with a username, or ErrNotFound
+func (r *gormUserRepo) GetByUsername(ctx context.Context, username string) (*models.User, error) {
+	var user models.User
+	if err := r.db.WithContext(ctx).Where("username = ?", username).First(&user).Error; err != nil {
+		return nil, notFound(err)
+	}
+	return &user, nil
+}
+
+// ListByIDs returns the users with the given IDs that exist
+func (r *gormUserRepo) ListByIDs(ctx context.Context, ids []uint) ([]models.User, error) {
+	var users []models.User
+	if err := r.db.WithContext(ctx).Where("id IN ?", ids).Find(&users).Error; err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+// EmailTaken reports whether a user other than exceptID has an email
+// address
+func (r *gormUserRepo) EmailTaken(ctx context.Context, email string, exceptID uint) (bool, error) {
+	var taken int64
+	if err := r.db.WithContext(ctx).Model(&models.User{}).Where("email = ? AND id <> ?", email, exceptID).Count(&taken).Error; err != nil {
+		return false, err
+	}
+	return taken > 0, nil
+}
+
+// Update stores a single column of a user
+func (r *gormUserRepo) Update(ctx context.Context, user *models.User, column string, value interface{}) error {
+	return r.db.WithContext(ctx).Model(user).Update(column, value).Error
+}
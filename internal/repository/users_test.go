@@ -0,0 +1,56 @@
+package repository
+
+import (
+	"context"
+	"testing"
+
+	"github.com/padminisys/flintroute/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUserRepo(t *testing.T) {
+	ctx := context.Background()
+	db := setupTestDB(t)
+	users := NewUserRepo(db)
+
+	alice := &models.User{Username: "alice", Email: "alice@example.com", PasswordHash: "x", Role: "user", Active: true}
+	require.NoError(t, db.Create(alice).Error)
+	bob := &models.User{Username: "bob", Email: "bob@example.com", PasswordHash: "x", Role: "user", Active: true}
+	require.NoError(t, db.Create(bob).Error)
+
+	t.Run("Gets users by ID and username", func(t *testing.T) {
+		user, err := users.GetByUsername(ctx, "alice")
+		require.NoError(t, err)
+		assert.Equal(t, alice.ID, user.ID)
+
+		user, err = users.GetByID(ctx, bob.ID)
+		require.NoError(t, err)
+		assert.Equal(t, "bob", user.Username)
+
+		_, err = users.GetByUsername(ctx, "carol")
+		assert.ErrorIs(t, err, ErrNotFound)
+
+		listed, err := users.ListByIDs(ctx, []uint{alice.ID, bob.ID, bob.ID + 100})
+		require.NoError(t, err)
+		assert.Len(t, listed, 2)
+	})
+
+	t.Run("Checks whether an email is taken", func(t *testing.T) {
+		taken, err := users.EmailTaken(ctx, "bob@example.com", alice.ID)
+		require.NoError(t, err)
+		assert.True(t, taken)
+
+		taken, err = users.EmailTaken(ctx, "bob@example.com", bob.ID)
+		require.NoError(t, err)
+		assert.False(t, taken)
+	})
+
+	t.Run("Updates a column", func(t *testing.T) {
+		require.NoError(t, users.Update(ctx, bob, "active", false))
+
+		user, err := users.GetByID(ctx, bob.ID)
+		require.NoError(t, err)
+		assert.False(t, user.Active)
+	})
+}
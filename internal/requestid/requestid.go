@@ -0,0 +1,52 @@
+package requestid
+
+import (
+	"context"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// Header is the HTTP header used to propagate request IDs
+const Header = "X-Request-ID"
+
+// maxLength bounds client-supplied request IDs
+const maxLength = 128
+
+type contextKey struct{}
+
+// NewContext returns a copy of ctx carrying the request ID
+func NewContext(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, contextKey{}, id)
+}
+
+// FromContext returns the request ID carried by ctx, or an empty string
+func FromContext(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	id, _ := ctx.Value(contextKey{}).(string)
+	return id
+}
+
+// Field returns a zap field with the request ID carried by ctx
+func Field(ctx context.Context) zap.Field {
+	return zap.String("request_id", FromContext(ctx))
+}
+
+// Middleware assigns a request ID to every request, reusing the incoming
+// X-Request-ID header when present, and echoes it in the response
+func Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(Header)
+		if id == "" || len(id) > maxLength {
+			id = uuid.New().String()
+		}
+
+		c.Request = c.Request.WithContext(NewContext(c.Request.Context(), id))
+		c.Header(Header, id)
+
+		c.Next()
+	}
+}
@@ -0,0 +1,57 @@
+package requestid
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	var seen string
+	router := gin.New()
+	router.Use(Middleware())
+	router.GET("/test", func(c *gin.Context) {
+		seen = FromContext(c.Request.Context())
+		c.Status(http.StatusOK)
+	})
+
+	t.Run("Generate request ID", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/test", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.NotEmpty(t, seen)
+		assert.Equal(t, seen, w.Header().Get(Header))
+	})
+
+	t.Run("Propagate incoming request ID", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/test", nil)
+		req.Header.Set(Header, "abc-123")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, "abc-123", seen)
+		assert.Equal(t, "abc-123", w.Header().Get(Header))
+	})
+
+	t.Run("Replace oversized request ID", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/test", nil)
+		req.Header.Set(Header, strings.Repeat("x", maxLength+1))
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Len(t, seen, 36)
+	})
+}
+
+func TestFromContext(t *testing.T) {
+	assert.Equal(t, "", FromContext(context.Background()))
+	assert.Equal(t, "id-1", FromContext(NewContext(context.Background(), "id-1")))
+}
@@ -0,0 +1,67 @@
+package secrets
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// EnvProvider reads secrets from environment variables.
+// The reference path is the variable name: secret://env/FLINTROUTE_JWT_SECRET
+type EnvProvider struct{}
+
+// NewEnvProvider creates a new environment variable provider
+func NewEnvProvider() *EnvProvider {
+	return &EnvProvider{}
+}
+
+// Name returns the provider name
+func (p *EnvProvider) Name() string {
+	return "env"
+}
+
+// Get returns the value of the environment variable named path
+func (p *EnvProvider) Get(ctx context.Context, path string) (string, error) {
+	value, ok := os.LookupEnv(path)
+	if !ok {
+		return "", fmt.Errorf("%w: environment variable %s is not set", ErrSecretNotFound, path)
+	}
+	return value, nil
+}
+
+// FileProvider reads secrets from files, such as mounted Docker or
+// Kubernetes secrets. Relative paths are resolved against BaseDir:
+// secret://file/jwt_secret or secret://file//run/secrets/jwt_secret
+type FileProvider struct {
+	baseDir string
+}
+
+// NewFileProvider creates a new file provider rooted at baseDir
+func NewFileProvider(baseDir string) *FileProvider {
+	return &FileProvider{baseDir: baseDir}
+}
+
+// Name returns the provider name
+func (p *FileProvider) Name() string {
+	return "file"
+}
+
+// Get returns the contents of the file at path with surrounding whitespace trimmed
+func (p *FileProvider) Get(ctx context.Context, path string) (string, error) {
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(p.baseDir, path)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return "", fmt.Errorf("%w: %s", ErrSecretNotFound, path)
+		}
+		return "", fmt.Errorf("failed to read secret file: %w", err)
+	}
+
+	return strings.TrimSpace(string(data)), nil
+}
@@ -0,0 +1,112 @@
+package secrets
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Scheme prefixes configuration values that reference a secret,
+// e.g. secret://vault/flintroute/auth#jwt_secret
+const Scheme = "secret://"
+
+var (
+	ErrInvalidReference = errors.New("invalid secret reference")
+	ErrUnknownProvider  = errors.New("unknown secret provider")
+	ErrSecretNotFound   = errors.New("secret not found")
+)
+
+// Provider fetches secret values from a backing store
+type Provider interface {
+	// Name returns the provider name used in secret references
+	Name() string
+	// Get returns the secret stored at path
+	Get(ctx context.Context, path string) (string, error)
+}
+
+// IsReference reports whether value is a secret reference
+func IsReference(value string) bool {
+	return strings.HasPrefix(value, Scheme)
+}
+
+// ParseReference splits a secret://provider/path reference
+func ParseReference(ref string) (provider, path string, err error) {
+	if !IsReference(ref) {
+		return "", "", fmt.Errorf("%w: %q", ErrInvalidReference, ref)
+	}
+
+	provider, path, ok := strings.Cut(strings.TrimPrefix(ref, Scheme), "/")
+	if !ok || provider == "" || path == "" {
+		return "", "", fmt.Errorf("%w: %q", ErrInvalidReference, ref)
+	}
+
+	return provider, path, nil
+}
+
+// Resolver resolves secret references using registered providers
+type Resolver struct {
+	providers map[string]Provider
+}
+
+// NewResolver creates a resolver for the given providers
+func NewResolver(providers ...Provider) *Resolver {
+	r := &Resolver{providers: make(map[string]Provider, len(providers))}
+	for _, p := range providers {
+		r.providers[p.Name()] = p
+	}
+	return r
+}
+
+// Resolve returns the secret a reference points to. Values that are not
+// references are returned unchanged.
+func (r *Resolver) Resolve(ctx context.Context, value string) (string, error) {
+	if !IsReference(value) {
+		return value, nil
+	}
+
+	name, path, err := ParseReference(value)
+	if err != nil {
+		return "", err
+	}
+
+	provider, ok := r.providers[name]
+	if !ok {
+		return "", fmt.Errorf("%w: %q", ErrUnknownProvider, name)
+	}
+
+	secret, err := provider.Get(ctx, path)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve %s: %w", value, err)
+	}
+
+	return secret, nil
+}
+
+// Watch re-resolves ref every interval and calls onChange when the secret
+// value differs from current. Resolution errors are passed to onError and
+// the previous value is kept. Watch blocks until ctx is cancelled.
+func (r *Resolver) Watch(ctx context.Context, ref, current string, interval time.Duration, onChange func(string), onError func(error)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			value, err := r.Resolve(ctx, ref)
+			if err != nil {
+				if onError != nil {
+					onError(err)
+				}
+				continue
+			}
+			if value != current {
+				current = value
+				onChange(value)
+			}
+		}
+	}
+}
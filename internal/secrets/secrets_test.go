@@ -0,0 +1,148 @@
+package secrets
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// staticProvider serves secrets from a map for tests
+type staticProvider struct {
+	mu     sync.Mutex
+	values map[string]string
+}
+
+func (p *staticProvider) Name() string { return "static" }
+
+func (p *staticProvider) Get(ctx context.Context, path string) (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	value, ok := p.values[path]
+	if !ok {
+		return "", ErrSecretNotFound
+	}
+	return value, nil
+}
+
+func (p *staticProvider) set(path, value string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.values[path] = value
+}
+
+func TestParseReference(t *testing.T) {
+	t.Run("Valid reference", func(t *testing.T) {
+		provider, path, err := ParseReference("secret://vault/flintroute/auth#jwt")
+		require.NoError(t, err)
+		assert.Equal(t, "vault", provider)
+		assert.Equal(t, "flintroute/auth#jwt", path)
+	})
+
+	t.Run("Absolute file path", func(t *testing.T) {
+		provider, path, err := ParseReference("secret://file//run/secrets/jwt")
+		require.NoError(t, err)
+		assert.Equal(t, "file", provider)
+		assert.Equal(t, "/run/secrets/jwt", path)
+	})
+
+	t.Run("Invalid references", func(t *testing.T) {
+		for _, ref := range []string{"plain", "secret://", "secret://env", "secret://env/", "secret:///path"} {
+			_, _, err := ParseReference(ref)
+			assert.ErrorIs(t, err, ErrInvalidReference, ref)
+		}
+	})
+}
+
+func TestResolver(t *testing.T) {
+	ctx := context.Background()
+	resolver := NewResolver(&staticProvider{values: map[string]string{"jwt": "s3cret"}})
+
+	t.Run("Plain value passes through", func(t *testing.T) {
+		value, err := resolver.Resolve(ctx, "not-a-reference")
+		require.NoError(t, err)
+		assert.Equal(t, "not-a-reference", value)
+	})
+
+	t.Run("Resolves reference", func(t *testing.T) {
+		value, err := resolver.Resolve(ctx, "secret://static/jwt")
+		require.NoError(t, err)
+		assert.Equal(t, "s3cret", value)
+	})
+
+	t.Run("Unknown provider", func(t *testing.T) {
+		_, err := resolver.Resolve(ctx, "secret://kms/jwt")
+		assert.ErrorIs(t, err, ErrUnknownProvider)
+	})
+
+	t.Run("Missing secret", func(t *testing.T) {
+		_, err := resolver.Resolve(ctx, "secret://static/missing")
+		assert.ErrorIs(t, err, ErrSecretNotFound)
+	})
+}
+
+func TestResolverWatch(t *testing.T) {
+	provider := &staticProvider{values: map[string]string{"jwt": "v1"}}
+	resolver := NewResolver(provider)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	changes := make(chan string, 1)
+	go resolver.Watch(ctx, "secret://static/jwt", "v1", 10*time.Millisecond, func(v string) {
+		changes <- v
+	}, nil)
+
+	provider.set("jwt", "v2")
+
+	select {
+	case value := <-changes:
+		assert.Equal(t, "v2", value)
+	case <-time.After(time.Second):
+		t.Fatal("rotation was not detected")
+	}
+}
+
+func TestEnvProvider(t *testing.T) {
+	ctx := context.Background()
+	provider := NewEnvProvider()
+
+	t.Setenv("FLINTROUTE_TEST_SECRET", "from-env")
+
+	value, err := provider.Get(ctx, "FLINTROUTE_TEST_SECRET")
+	require.NoError(t, err)
+	assert.Equal(t, "from-env", value)
+
+	_, err = provider.Get(ctx, "FLINTROUTE_TEST_SECRET_UNSET")
+	assert.ErrorIs(t, err, ErrSecretNotFound)
+}
+
+func TestFileProvider(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "jwt"), []byte("from-file\n"), 0600))
+
+	provider := NewFileProvider(dir)
+
+	t.Run("Relative path", func(t *testing.T) {
+		value, err := provider.Get(ctx, "jwt")
+		require.NoError(t, err)
+		assert.Equal(t, "from-file", value)
+	})
+
+	t.Run("Absolute path", func(t *testing.T) {
+		value, err := NewFileProvider("/nonexistent").Get(ctx, filepath.Join(dir, "jwt"))
+		require.NoError(t, err)
+		assert.Equal(t, "from-file", value)
+	})
+
+	t.Run("Missing file", func(t *testing.T) {
+		_, err := provider.Get(ctx, "missing")
+		assert.ErrorIs(t, err, ErrSecretNotFound)
+	})
+}
@@ -0,0 +1,95 @@
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// defaultVaultKey is the field read when a reference does not name one
+const defaultVaultKey = "value"
+
+// VaultProvider reads secrets from a HashiCorp Vault KV version 2 engine.
+// References name the secret path and, optionally, a field:
+// secret://vault/flintroute/auth#jwt_secret
+type VaultProvider struct {
+	address    string
+	token      string
+	mount      string
+	httpClient *http.Client
+}
+
+// NewVaultProvider creates a new Vault provider for the KV v2 engine at mount
+func NewVaultProvider(address, token, mount string) *VaultProvider {
+	if mount == "" {
+		mount = "secret"
+	}
+
+	return &VaultProvider{
+		address:    strings.TrimRight(address, "/"),
+		token:      token,
+		mount:      strings.Trim(mount, "/"),
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Name returns the provider name
+func (p *VaultProvider) Name() string {
+	return "vault"
+}
+
+// vaultKVResponse is the KV v2 read response
+type vaultKVResponse struct {
+	Data struct {
+		Data map[string]interface{} `json:"data"`
+	} `json:"data"`
+}
+
+// Get returns a field of the KV secret at path
+func (p *VaultProvider) Get(ctx context.Context, path string) (string, error) {
+	path, key, _ := strings.Cut(path, "#")
+	if key == "" {
+		key = defaultVaultKey
+	}
+
+	url := fmt.Sprintf("%s/v1/%s/data/%s", p.address, p.mount, strings.TrimLeft(path, "/"))
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to create Vault request: %w", err)
+	}
+	req.Header.Set("X-Vault-Token", p.token)
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to read secret from Vault: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return "", fmt.Errorf("%w: vault path %s", ErrSecretNotFound, path)
+	default:
+		return "", fmt.Errorf("vault returned HTTP %d for %s", resp.StatusCode, path)
+	}
+
+	var kv vaultKVResponse
+	if err := json.NewDecoder(resp.Body).Decode(&kv); err != nil {
+		return "", fmt.Errorf("failed to decode Vault response: %w", err)
+	}
+
+	value, ok := kv.Data.Data[key]
+	if !ok {
+		return "", fmt.Errorf("%w: vault path %s has no field %q", ErrSecretNotFound, path, key)
+	}
+
+	str, ok := value.(string)
+	if !ok {
+		return "", fmt.Errorf("vault field %q at %s is not a string", key, path)
+	}
+
+	return str, nil
+}
@@ -0,0 +1,70 @@
+package secrets
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestVault(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != "test-token" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+
+		switch r.URL.Path {
+		case "/v1/kv/data/flintroute/auth":
+			w.Write([]byte(`{"data":{"data":{"jwt_secret":"from-vault","value":"default","port":8080}}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func TestVaultProvider(t *testing.T) {
+	ctx := context.Background()
+	vault := newTestVault(t)
+	provider := NewVaultProvider(vault.URL+"/", "test-token", "kv")
+
+	t.Run("Named field", func(t *testing.T) {
+		value, err := provider.Get(ctx, "flintroute/auth#jwt_secret")
+		require.NoError(t, err)
+		assert.Equal(t, "from-vault", value)
+	})
+
+	t.Run("Default field", func(t *testing.T) {
+		value, err := provider.Get(ctx, "flintroute/auth")
+		require.NoError(t, err)
+		assert.Equal(t, "default", value)
+	})
+
+	t.Run("Missing field", func(t *testing.T) {
+		_, err := provider.Get(ctx, "flintroute/auth#missing")
+		assert.ErrorIs(t, err, ErrSecretNotFound)
+	})
+
+	t.Run("Non-string field", func(t *testing.T) {
+		_, err := provider.Get(ctx, "flintroute/auth#port")
+		assert.Error(t, err)
+	})
+
+	t.Run("Missing path", func(t *testing.T) {
+		_, err := provider.Get(ctx, "flintroute/other")
+		assert.ErrorIs(t, err, ErrSecretNotFound)
+	})
+
+	t.Run("Permission denied", func(t *testing.T) {
+		_, err := NewVaultProvider(vault.URL, "bad-token", "kv").Get(ctx, "flintroute/auth")
+		assert.ErrorContains(t, err, "HTTP 403")
+	})
+}
@@ -0,0 +1,249 @@
+// Package snapshots backs up FlintRoute's own database: peers, policies,
+// users, alerts and every other table, as opposed to the FRR configuration
+// versions kept by configstore. Snapshots are kept in a blob store and
+// recorded in the database; restoring one is audited.
+package snapshots
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/padminisys/flintroute/internal/configstore"
+	"github.com/padminisys/flintroute/internal/database"
+	"github.com/padminisys/flintroute/internal/repository"
+	"github.com/padminisys/flintroute/pkg/models"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// Audit actions recorded for snapshots
+const (
+	ActionCreate  = "database.snapshot"
+	ActionRestore = "database.restore"
+	ActionDelete  = "database.snapshot_delete"
+)
+
+var (
+	ErrSnapshotNotFound = errors.New("snapshot not found")
+	// ErrChecksumMismatch is returned when a stored snapshot no longer
+	// matches the checksum taken when it was created
+	ErrChecksumMismatch = errors.New("snapshot checksum mismatch")
+)
+
+// preservedTables are left as they are by a restore: the record of
+// snapshots and of the restore itself, sign-in sessions and revoked tokens
+// (so that nothing revoked since comes back), background jobs, the event
+// stream clients resume from and HA leadership
+var preservedTables = []string{
+	"database_snapshots",
+	"audit_entries",
+	"refresh_tokens",
+	"revoked_tokens",
+	"jobs",
+	"stream_events",
+	"leader_leases",
+}
+
+// RestoreResult describes a completed restore
+type RestoreResult struct {
+	Snapshot *models.DatabaseSnapshot `json:"snapshot"`
+	// Backup is the snapshot taken right before restoring, to undo it
+	Backup     *models.DatabaseSnapshot `json:"backup"`
+	RestoredAt time.Time                `json:"restored_at"`
+}
+
+// Store creates, lists and restores database snapshots
+type Store struct {
+	db     *database.DB
+	blobs  configstore.BlobStore
+	logger *zap.Logger
+}
+
+// New creates a snapshot store keeping snapshots in blobs
+func New(db *database.DB, blobs configstore.BlobStore, logger *zap.Logger) *Store {
+	return &Store{db: db, blobs: blobs, logger: logger}
+}
+
+// Create snapshots the database
+func (s *Store) Create(ctx context.Context, note, username string) (*models.DatabaseSnapshot, error) {
+	dir, err := os.MkdirTemp("", "flintroute-snapshot-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create snapshot: %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "snapshot.db")
+	if err := s.db.Snapshot(ctx, path); err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read snapshot: %w", err)
+	}
+
+	version, err := database.SchemaVersion(s.db.DB)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now().UTC()
+	sum := sha256.Sum256(data)
+	snapshot := &models.DatabaseSnapshot{
+		CreatedAt:     now,
+		Key:           "flintroute-" + now.Format("20060102-150405.000000") + ".db",
+		Size:          int64(len(data)),
+		Checksum:      hex.EncodeToString(sum[:]),
+		SchemaVersion: version,
+		Note:          note,
+		CreatedBy:     username,
+	}
+	if err := s.blobs.Put(ctx, snapshot.Key, data); err != nil {
+		return nil, fmt.Errorf("failed to store snapshot: %w", err)
+	}
+
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(snapshot).Error; err != nil {
+			return err
+		}
+		return audit(tx, username, ActionCreate, snapshot, note)
+	})
+	if err != nil {
+		s.blobs.Delete(ctx, snapshot.Key)
+		return nil, err
+	}
+
+	s.logger.Info("Created database snapshot",
+		zap.Uint("id", snapshot.ID),
+		zap.String("key", snapshot.Key),
+		zap.Int64("size", snapshot.Size),
+		zap.String("backend", s.blobs.Name()),
+		zap.String("username", username),
+	)
+	return snapshot, nil
+}
+
+// List returns every snapshot, newest first
+func (s *Store) List(ctx context.Context) ([]models.DatabaseSnapshot, error) {
+	var snapshots []models.DatabaseSnapshot
+	err := s.db.WithContext(ctx).Order("created_at DESC").Order("id DESC").Find(&snapshots).Error
+	return snapshots, err
+}
+
+// Get returns a snapshot's metadata
+func (s *Store) Get(ctx context.Context, id uint) (*models.DatabaseSnapshot, error) {
+	var snapshot models.DatabaseSnapshot
+	if err := s.db.WithContext(ctx).First(&snapshot, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrSnapshotNotFound
+		}
+		return nil, err
+	}
+	return &snapshot, nil
+}
+
+// Read returns a snapshot's metadata and contents, after checking them
+// against its checksum
+func (s *Store) Read(ctx context.Context, id uint) (*models.DatabaseSnapshot, []byte, error) {
+	snapshot, err := s.Get(ctx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	data, err := s.blobs.Get(ctx, snapshot.Key)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read snapshot: %w", err)
+	}
+	sum := sha256.Sum256(data)
+	if hex.EncodeToString(sum[:]) != snapshot.Checksum {
+		return nil, nil, fmt.Errorf("%w: %s", ErrChecksumMismatch, snapshot.Key)
+	}
+	return snapshot, data, nil
+}
+
+// Restore replaces the database's contents with a snapshot's. The current
+// contents are snapshotted first, so a restore can be undone by restoring
+// that backup. Tables holding operational state are kept; see
+// preservedTables.
+func (s *Store) Restore(ctx context.Context, id uint, username string) (*RestoreResult, error) {
+	snapshot, data, err := s.Read(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	dir, err := os.MkdirTemp("", "flintroute-restore-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to restore snapshot: %w", err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "restore.db")
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		return nil, fmt.Errorf("failed to restore snapshot: %w", err)
+	}
+
+	backup, err := s.Create(ctx, fmt.Sprintf("Before restoring snapshot %d", snapshot.ID), username)
+	if err != nil {
+		return nil, fmt.Errorf("failed to back up database before restoring: %w", err)
+	}
+
+	if err := s.db.Restore(ctx, path, preservedTables...); err != nil {
+		return nil, err
+	}
+
+	result := &RestoreResult{Snapshot: snapshot, Backup: backup, RestoredAt: time.Now()}
+	if err := audit(s.db.WithContext(ctx), username, ActionRestore, snapshot,
+		fmt.Sprintf("backup snapshot %d", backup.ID)); err != nil {
+		s.logger.Error("Failed to record database restore", zap.Error(err))
+	}
+
+	s.logger.Warn("Restored database snapshot",
+		zap.Uint("id", snapshot.ID),
+		zap.String("key", snapshot.Key),
+		zap.Uint("backup_id", backup.ID),
+		zap.String("username", username),
+	)
+	return result, nil
+}
+
+// Delete removes a snapshot
+func (s *Store) Delete(ctx context.Context, id uint, username string) error {
+	snapshot, err := s.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := s.blobs.Delete(ctx, snapshot.Key); err != nil {
+		return err
+	}
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(snapshot).Error; err != nil {
+			return err
+		}
+		return audit(tx, username, ActionDelete, snapshot, "")
+	})
+}
+
+// AuditLog returns a page of the audit log, newest first, and the cursor of
+// the next page, nil on the last page
+func (s *Store) AuditLog(ctx context.Context, page repository.PageQuery) ([]models.AuditEntry, *repository.Cursor, error) {
+	var entries []models.AuditEntry
+	if err := s.db.WithContext(ctx).Scopes(repository.Keyset(page)).Find(&entries).Error; err != nil {
+		return nil, nil, err
+	}
+	entries, next := repository.NextPage(entries, page.Limit, func(entry *models.AuditEntry) repository.Cursor {
+		return repository.Cursor{CreatedAt: entry.CreatedAt, ID: entry.ID}
+	})
+	return entries, next, nil
+}
+
+// audit records an action on a snapshot
+func audit(tx *gorm.DB, username, action string, snapshot *models.DatabaseSnapshot, detail string) error {
+	return tx.Create(&models.AuditEntry{
+		Username: username,
+		Action:   action,
+		Target:   fmt.Sprintf("snapshot %d (%s)", snapshot.ID, snapshot.Key),
+		Detail:   detail,
+	}).Error
+}
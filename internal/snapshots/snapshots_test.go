@@ -0,0 +1,109 @@
+package snapshots
+
+import (
+	"context"
+	"testing"
+
+	"github.com/padminisys/flintroute/internal/configstore"
+	"github.com/padminisys/flintroute/internal/repository"
+	"github.com/padminisys/flintroute/internal/testutil"
+	"github.com/padminisys/flintroute/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func setupTestStore(t *testing.T) *Store {
+	t.Helper()
+	return New(testutil.SetupTestDB(t), configstore.NewFilesystemStore(t.TempDir()), zap.NewNop())
+}
+
+func TestCreate(t *testing.T) {
+	ctx := context.Background()
+	store := setupTestStore(t)
+
+	snapshot, err := store.Create(ctx, "before upgrade", "admin")
+	require.NoError(t, err)
+	assert.NotZero(t, snapshot.ID)
+	assert.Positive(t, snapshot.Size)
+	assert.Len(t, snapshot.Checksum, 64)
+	assert.NotEmpty(t, snapshot.SchemaVersion)
+	assert.Equal(t, "admin", snapshot.CreatedBy)
+
+	_, data, err := store.Read(ctx, snapshot.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "SQLite format 3\x00", string(data[:16]))
+
+	snapshots, err := store.List(ctx)
+	require.NoError(t, err)
+	require.Len(t, snapshots, 1)
+
+	entries, _, err := store.AuditLog(ctx, repository.PageQuery{Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, ActionCreate, entries[0].Action)
+	assert.Equal(t, "before upgrade", entries[0].Detail)
+}
+
+func TestRestore(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Restores the snapshot after backing up", func(t *testing.T) {
+		store := setupTestStore(t)
+		require.NoError(t, store.db.Create(&models.BGPPeer{Name: "kept", IPAddress: "192.0.2.1", ASN: 65000, RemoteASN: 65001}).Error)
+		snapshot, err := store.Create(ctx, "", "admin")
+		require.NoError(t, err)
+		require.NoError(t, store.db.Create(&models.BGPPeer{Name: "added", IPAddress: "192.0.2.2", ASN: 65000, RemoteASN: 65002}).Error)
+
+		result, err := store.Restore(ctx, snapshot.ID, "admin")
+		require.NoError(t, err)
+		assert.Equal(t, snapshot.ID, result.Snapshot.ID)
+		assert.Contains(t, result.Backup.Note, "Before restoring snapshot")
+
+		var peers []models.BGPPeer
+		require.NoError(t, store.db.Find(&peers).Error)
+		require.Len(t, peers, 1)
+		assert.Equal(t, "kept", peers[0].Name)
+
+		// The backup taken before restoring survives the restore
+		snapshots, err := store.List(ctx)
+		require.NoError(t, err)
+		assert.Len(t, snapshots, 2)
+
+		entries, _, err := store.AuditLog(ctx, repository.PageQuery{Limit: 1})
+		require.NoError(t, err)
+		require.Len(t, entries, 1)
+		assert.Equal(t, ActionRestore, entries[0].Action)
+		assert.Equal(t, "admin", entries[0].Username)
+	})
+
+	t.Run("Refuses a tampered snapshot", func(t *testing.T) {
+		store := setupTestStore(t)
+		snapshot, err := store.Create(ctx, "", "admin")
+		require.NoError(t, err)
+		require.NoError(t, store.blobs.Put(ctx, snapshot.Key, []byte("tampered")))
+
+		_, err = store.Restore(ctx, snapshot.ID, "admin")
+		assert.ErrorIs(t, err, ErrChecksumMismatch)
+	})
+
+	t.Run("Unknown snapshot", func(t *testing.T) {
+		store := setupTestStore(t)
+		_, err := store.Restore(ctx, 42, "admin")
+		assert.ErrorIs(t, err, ErrSnapshotNotFound)
+	})
+}
+
+func TestDelete(t *testing.T) {
+	ctx := context.Background()
+	store := setupTestStore(t)
+	snapshot, err := store.Create(ctx, "", "admin")
+	require.NoError(t, err)
+
+	require.NoError(t, store.Delete(ctx, snapshot.ID, "admin"))
+
+	_, err = store.Get(ctx, snapshot.ID)
+	assert.ErrorIs(t, err, ErrSnapshotNotFound)
+	_, err = store.blobs.Get(ctx, snapshot.Key)
+	assert.ErrorIs(t, err, configstore.ErrBlobNotFound)
+}
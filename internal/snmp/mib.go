@@ -0,0 +1,47 @@
+package snmp
+
+// DefaultEnterpriseOID is the root of the FlintRoute MIB unless configured
+// otherwise. FlintRoute has no registered Private Enterprise Number, so
+// deployments that need globally unique OIDs should root the MIB under
+// their own PEN and edit mibs/FLINTROUTE-MIB.txt to match.
+const DefaultEnterpriseOID = "1.3.6.1.4.1.99999"
+
+// OIDs relative to the enterprise OID. Notifications live under .1.0 so
+// they can be translated to SNMPv1 traps.
+const (
+	notificationsArc = ".1.0"
+	objectsArc       = ".2"
+)
+
+// Notification numbers under flintrouteNotifications
+const (
+	notificationPeerDown       = 1
+	notificationPeerUp         = 2
+	notificationFRRUnreachable = 3
+	notificationConfigRestored = 4
+	notificationMaxPrefix      = 5
+)
+
+// Object numbers under flintrouteObjects, sent as trap varbinds
+const (
+	objectAlertSeverity = 1
+	objectAlertMessage  = 2
+	objectRouter        = 3
+	objectPeerAddress   = 4
+	objectPeerRemoteAS  = 5
+	objectPeerName      = 6
+	objectAlertID       = 7
+)
+
+// notifications maps alert types to the notification they are sent as.
+// Other alert types do not send traps.
+var notifications = map[string]int{
+	"peer_down":           notificationPeerDown,
+	"peer_up":             notificationPeerUp,
+	"frr_unreachable":     notificationFRRUnreachable,
+	"config_restored":     notificationConfigRestored,
+	"max_prefix_exceeded": notificationMaxPrefix,
+}
+
+// snmpTrapOID is the varbind carrying a v2 trap's notification OID
+const snmpTrapOID = "1.3.6.1.6.3.1.1.4.1.0"
@@ -0,0 +1,328 @@
+// Package snmp sends SNMP traps for FlintRoute alerts, for NOCs that only
+// consume SNMP. Peer down/up, FRR unreachable, config restored and
+// maximum-prefix alerts are sent as notifications of the FlintRoute MIB.
+package snmp
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gosnmp/gosnmp"
+	"github.com/padminisys/flintroute/pkg/models"
+	"go.uber.org/zap"
+)
+
+// defaultPort is the standard SNMP trap port
+const defaultPort = 162
+
+// Config configures trap destinations and credentials
+type Config struct {
+	// Targets are trap receivers as host or host:port
+	Targets []string
+	// Version is "2c" or "3"
+	Version   string
+	Community string
+	V3        V3Config
+	// Severities lists the alert severities that send traps
+	Severities []string
+	// EnterpriseOID roots the FlintRoute MIB; defaults to DefaultEnterpriseOID
+	EnterpriseOID string
+	// Router is sent with every trap; defaults to the hostname
+	Router  string
+	Timeout time.Duration
+	Retries int
+}
+
+// V3Config holds SNMPv3 user-based security credentials
+type V3Config struct {
+	Username string
+	// SecurityLevel is noAuthNoPriv, authNoPriv or authPriv
+	SecurityLevel  string
+	AuthProtocol   string // MD5, SHA, SHA224, SHA256, SHA384, SHA512
+	AuthPassphrase string
+	PrivProtocol   string // DES, AES, AES192, AES256, AES192C, AES256C
+	PrivPassphrase string
+	// EngineID is the hex-encoded authoritative engine ID of traps. It is
+	// derived from the enterprise number and router name when empty.
+	EngineID string
+}
+
+var authProtocols = map[string]gosnmp.SnmpV3AuthProtocol{
+	"MD5":    gosnmp.MD5,
+	"SHA":    gosnmp.SHA,
+	"SHA224": gosnmp.SHA224,
+	"SHA256": gosnmp.SHA256,
+	"SHA384": gosnmp.SHA384,
+	"SHA512": gosnmp.SHA512,
+}
+
+var privProtocols = map[string]gosnmp.SnmpV3PrivProtocol{
+	"DES":     gosnmp.DES,
+	"AES":     gosnmp.AES,
+	"AES192":  gosnmp.AES192,
+	"AES256":  gosnmp.AES256,
+	"AES192C": gosnmp.AES192C,
+	"AES256C": gosnmp.AES256C,
+}
+
+var securityLevels = map[string]gosnmp.SnmpV3MsgFlags{
+	"noauthnopriv": gosnmp.NoAuthNoPriv,
+	"authnopriv":   gosnmp.AuthNoPriv,
+	"authpriv":     gosnmp.AuthPriv,
+}
+
+// target is a resolved trap destination
+type target struct {
+	host string
+	port uint16
+}
+
+// Sender sends traps for alerts to the configured receivers
+type Sender struct {
+	config     Config
+	targets    []target
+	severities map[string]bool
+	version    gosnmp.SnmpVersion
+	msgFlags   gosnmp.SnmpV3MsgFlags
+	usm        *gosnmp.UsmSecurityParameters
+	started    time.Time // engine time of v3 traps counts from here
+	logger     *zap.Logger
+}
+
+// NewSender validates the configuration and creates a trap sender
+func NewSender(cfg Config, logger *zap.Logger) (*Sender, error) {
+	if cfg.EnterpriseOID == "" {
+		cfg.EnterpriseOID = DefaultEnterpriseOID
+	}
+	cfg.EnterpriseOID = strings.TrimPrefix(cfg.EnterpriseOID, ".")
+	if cfg.Router == "" {
+		cfg.Router, _ = os.Hostname()
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 5 * time.Second
+	}
+
+	s := &Sender{
+		config:     cfg,
+		severities: make(map[string]bool, len(cfg.Severities)),
+		started:    time.Now(),
+		logger:     logger,
+	}
+	for _, severity := range cfg.Severities {
+		s.severities[strings.ToLower(severity)] = true
+	}
+
+	if len(cfg.Targets) == 0 {
+		return nil, fmt.Errorf("at least one SNMP trap target is required")
+	}
+	for _, raw := range cfg.Targets {
+		t, err := parseTarget(raw)
+		if err != nil {
+			return nil, err
+		}
+		s.targets = append(s.targets, t)
+	}
+
+	switch cfg.Version {
+	case "", "2c":
+		s.version = gosnmp.Version2c
+	case "3":
+		s.version = gosnmp.Version3
+		if err := s.setupV3(); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unsupported SNMP version: %s", cfg.Version)
+	}
+
+	return s, nil
+}
+
+// parseTarget splits a host or host:port trap target
+func parseTarget(raw string) (target, error) {
+	host, portStr, err := net.SplitHostPort(raw)
+	if err != nil {
+		// No port given; bracketed IPv6 addresses are unwrapped
+		return target{host: strings.Trim(raw, "[]"), port: defaultPort}, nil
+	}
+	port, err := strconv.ParseUint(portStr, 10, 16)
+	if err != nil || port == 0 || host == "" {
+		return target{}, fmt.Errorf("invalid SNMP trap target: %s", raw)
+	}
+	return target{host: host, port: uint16(port)}, nil
+}
+
+// setupV3 builds the user-based security parameters for v3 traps
+func (s *Sender) setupV3() error {
+	v3 := s.config.V3
+	if v3.Username == "" {
+		return fmt.Errorf("SNMPv3 username is required")
+	}
+
+	level := v3.SecurityLevel
+	if level == "" {
+		level = "authPriv"
+	}
+	flags, ok := securityLevels[strings.ToLower(level)]
+	if !ok {
+		return fmt.Errorf("invalid SNMPv3 security level: %s", level)
+	}
+	s.msgFlags = flags
+
+	usm := &gosnmp.UsmSecurityParameters{
+		UserName:               v3.Username,
+		AuthenticationProtocol: gosnmp.NoAuth,
+		PrivacyProtocol:        gosnmp.NoPriv,
+	}
+	if flags&gosnmp.AuthNoPriv != 0 {
+		protocol, ok := authProtocols[strings.ToUpper(v3.AuthProtocol)]
+		if !ok {
+			return fmt.Errorf("invalid SNMPv3 auth protocol: %s", v3.AuthProtocol)
+		}
+		if v3.AuthPassphrase == "" {
+			return fmt.Errorf("SNMPv3 auth passphrase is required for %s", level)
+		}
+		usm.AuthenticationProtocol = protocol
+		usm.AuthenticationPassphrase = v3.AuthPassphrase
+	}
+	if flags&gosnmp.AuthPriv == gosnmp.AuthPriv {
+		protocol, ok := privProtocols[strings.ToUpper(v3.PrivProtocol)]
+		if !ok {
+			return fmt.Errorf("invalid SNMPv3 privacy protocol: %s", v3.PrivProtocol)
+		}
+		if v3.PrivPassphrase == "" {
+			return fmt.Errorf("SNMPv3 privacy passphrase is required for %s", level)
+		}
+		usm.PrivacyProtocol = protocol
+		usm.PrivacyPassphrase = v3.PrivPassphrase
+	}
+
+	engineID, err := s.engineID()
+	if err != nil {
+		return err
+	}
+	usm.AuthoritativeEngineID = engineID
+	usm.AuthoritativeEngineBoots = 1
+	s.usm = usm
+
+	return nil
+}
+
+// engineID returns the configured engine ID, or derives an RFC 3411 text
+// format engine ID from the enterprise number and router name
+func (s *Sender) engineID() (string, error) {
+	if s.config.V3.EngineID != "" {
+		id, err := hex.DecodeString(strings.TrimPrefix(s.config.V3.EngineID, "0x"))
+		if err != nil || len(id) < 5 || len(id) > 32 {
+			return "", fmt.Errorf("invalid SNMPv3 engine ID: %s", s.config.V3.EngineID)
+		}
+		return string(id), nil
+	}
+
+	arcs := strings.Split(s.config.EnterpriseOID, ".")
+	pen, err := strconv.ParseUint(arcs[len(arcs)-1], 10, 31)
+	if err != nil {
+		return "", fmt.Errorf("invalid enterprise OID: %s", s.config.EnterpriseOID)
+	}
+	text := s.config.Router
+	if len(text) > 27 {
+		text = text[:27]
+	}
+	id := []byte{byte(pen>>24) | 0x80, byte(pen >> 16), byte(pen >> 8), byte(pen), 4}
+	return string(append(id, text...)), nil
+}
+
+// SendAlert sends a trap for the alert to every receiver when its type has
+// a notification and its severity is enabled. Failures are logged. Sending
+// on a nil sender is a no-op.
+func (s *Sender) SendAlert(ctx context.Context, alert *models.Alert) {
+	if s == nil {
+		return
+	}
+
+	trap, ok := s.Trap(alert)
+	if !ok {
+		return
+	}
+
+	for _, t := range s.targets {
+		if err := s.send(t, trap); err != nil {
+			s.logger.Warn("Failed to send SNMP trap",
+				zap.String("target", net.JoinHostPort(t.host, strconv.Itoa(int(t.port)))),
+				zap.String("alert_type", alert.Type),
+				zap.Error(err),
+			)
+		}
+	}
+}
+
+// Trap builds the trap for an alert. It reports false when the alert's
+// type has no notification or its severity is not enabled.
+func (s *Sender) Trap(alert *models.Alert) (gosnmp.SnmpTrap, bool) {
+	notification, ok := notifications[alert.Type]
+	if !ok || !s.severities[alert.Severity] {
+		return gosnmp.SnmpTrap{}, false
+	}
+
+	oid := func(arc string, n int) string {
+		return "." + s.config.EnterpriseOID + arc + "." + strconv.Itoa(n)
+	}
+	str := func(n int, value string) gosnmp.SnmpPDU {
+		return gosnmp.SnmpPDU{Name: oid(objectsArc, n), Type: gosnmp.OctetString, Value: value}
+	}
+	gauge := func(n int, value uint) gosnmp.SnmpPDU {
+		return gosnmp.SnmpPDU{Name: oid(objectsArc, n), Type: gosnmp.Gauge32, Value: value}
+	}
+
+	vars := []gosnmp.SnmpPDU{
+		{Name: "." + snmpTrapOID, Type: gosnmp.ObjectIdentifier, Value: oid(notificationsArc, notification)},
+		gauge(objectAlertID, alert.ID),
+		str(objectAlertSeverity, alert.Severity),
+		str(objectAlertMessage, alert.Message),
+		str(objectRouter, s.config.Router),
+	}
+	if alert.Peer != nil {
+		vars = append(vars,
+			str(objectPeerAddress, alert.Peer.IPAddress),
+			gauge(objectPeerRemoteAS, uint(alert.Peer.RemoteASN)),
+			str(objectPeerName, alert.Peer.Name),
+		)
+	}
+
+	return gosnmp.SnmpTrap{Variables: vars}, true
+}
+
+// send delivers a trap to a single receiver
+func (s *Sender) send(t target, trap gosnmp.SnmpTrap) error {
+	client := &gosnmp.GoSNMP{
+		Target:    t.host,
+		Port:      t.port,
+		Transport: "udp",
+		Version:   s.version,
+		Community: s.config.Community,
+		Timeout:   s.config.Timeout,
+		Retries:   s.config.Retries,
+		MaxOids:   gosnmp.MaxOids,
+	}
+	if s.version == gosnmp.Version3 {
+		client.SecurityModel = gosnmp.UserSecurityModel
+		client.MsgFlags = s.msgFlags
+		usm := s.usm.Copy().(*gosnmp.UsmSecurityParameters)
+		usm.AuthoritativeEngineTime = uint32(time.Since(s.started).Seconds())
+		client.SecurityParameters = usm
+	}
+
+	if err := client.Connect(); err != nil {
+		return err
+	}
+	defer client.Conn.Close()
+
+	_, err := client.SendTrap(trap)
+	return err
+}
@@ -0,0 +1,226 @@
+package snmp
+
+import (
+	"context"
+	"net"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gosnmp/gosnmp"
+	"github.com/padminisys/flintroute/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// startListener runs a trap receiver on a random local port and returns
+// its address and the channel received traps are sent to
+func startListener(t *testing.T, params *gosnmp.GoSNMP) (string, <-chan *gosnmp.SnmpPacket) {
+	t.Helper()
+
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := conn.LocalAddr().String()
+	conn.Close()
+
+	traps := make(chan *gosnmp.SnmpPacket, 10)
+	listener := gosnmp.NewTrapListener()
+	listener.Params = params
+	listener.OnNewTrap = func(packet *gosnmp.SnmpPacket, _ *net.UDPAddr) {
+		traps <- packet
+	}
+
+	go listener.Listen(addr)
+	t.Cleanup(listener.Close)
+	select {
+	case <-listener.Listening():
+	case <-time.After(time.Second):
+		t.Fatal("trap listener did not start")
+	}
+
+	return addr, traps
+}
+
+func peerDownAlert() *models.Alert {
+	return &models.Alert{
+		ID:       7,
+		Type:     "peer_down",
+		Severity: "warning",
+		Message:  "BGP peer transit (192.0.2.1) state changed from Established to Idle",
+		Peer:     &models.BGPPeer{Name: "transit", IPAddress: "192.0.2.1", RemoteASN: 64500},
+	}
+}
+
+// varbinds indexes a trap's varbinds by OID
+func varbinds(packet *gosnmp.SnmpPacket) map[string]interface{} {
+	values := make(map[string]interface{}, len(packet.Variables))
+	for _, v := range packet.Variables {
+		if b, ok := v.Value.([]byte); ok {
+			values[v.Name] = string(b)
+			continue
+		}
+		values[v.Name] = v.Value
+	}
+	return values
+}
+
+func TestNewSender(t *testing.T) {
+	logger := zap.NewNop()
+
+	t.Run("Requires targets", func(t *testing.T) {
+		_, err := NewSender(Config{}, logger)
+		assert.Error(t, err)
+	})
+
+	t.Run("Defaults trap port", func(t *testing.T) {
+		s, err := NewSender(Config{Targets: []string{"nms.example.com", "[2001:db8::1]", "192.0.2.10:1162"}}, logger)
+		require.NoError(t, err)
+		assert.Equal(t, []target{
+			{host: "nms.example.com", port: 162},
+			{host: "2001:db8::1", port: 162},
+			{host: "192.0.2.10", port: 1162},
+		}, s.targets)
+	})
+
+	t.Run("Rejects invalid v3 settings", func(t *testing.T) {
+		for _, v3 := range []V3Config{
+			{},
+			{Username: "nms", SecurityLevel: "secret"},
+			{Username: "nms", SecurityLevel: "authNoPriv", AuthProtocol: "SHA1024", AuthPassphrase: "authpass"},
+			{Username: "nms", SecurityLevel: "authPriv", AuthProtocol: "SHA", AuthPassphrase: "authpass", PrivProtocol: "AES"},
+			{Username: "nms", SecurityLevel: "noAuthNoPriv", EngineID: "zz"},
+		} {
+			_, err := NewSender(Config{Targets: []string{"nms"}, Version: "3", V3: v3}, logger)
+			assert.Error(t, err, "%+v", v3)
+		}
+	})
+
+	t.Run("Derives engine ID", func(t *testing.T) {
+		s, err := NewSender(Config{
+			Targets: []string{"nms"},
+			Version: "3",
+			Router:  "edge-1",
+			V3:      V3Config{Username: "nms", SecurityLevel: "noAuthNoPriv"},
+		}, logger)
+		require.NoError(t, err)
+		assert.Equal(t, "\x80\x01\x86\x9f\x04edge-1", s.usm.AuthoritativeEngineID)
+	})
+}
+
+func TestTrap(t *testing.T) {
+	s, err := NewSender(Config{
+		Targets:    []string{"nms"},
+		Severities: []string{"warning", "CRITICAL"},
+		Router:     "edge-1",
+	}, zap.NewNop())
+	require.NoError(t, err)
+
+	t.Run("Peer down", func(t *testing.T) {
+		trap, ok := s.Trap(peerDownAlert())
+		require.True(t, ok)
+
+		values := varbinds(&gosnmp.SnmpPacket{Variables: trap.Variables})
+		assert.Equal(t, ".1.3.6.1.4.1.99999.1.0.1", values[".1.3.6.1.6.3.1.1.4.1.0"])
+		assert.Equal(t, "warning", values[".1.3.6.1.4.1.99999.2.1"])
+		assert.Equal(t, "edge-1", values[".1.3.6.1.4.1.99999.2.3"])
+		assert.Equal(t, "192.0.2.1", values[".1.3.6.1.4.1.99999.2.4"])
+		assert.Equal(t, uint(64500), values[".1.3.6.1.4.1.99999.2.5"])
+		assert.Equal(t, uint(7), values[".1.3.6.1.4.1.99999.2.7"])
+	})
+
+	t.Run("Severity not enabled", func(t *testing.T) {
+		alert := peerDownAlert()
+		alert.Type = "peer_up"
+		alert.Severity = "info"
+		_, ok := s.Trap(alert)
+		assert.False(t, ok)
+	})
+
+	t.Run("Alert type without notification", func(t *testing.T) {
+		alert := peerDownAlert()
+		alert.Type = "config_drift"
+		_, ok := s.Trap(alert)
+		assert.False(t, ok)
+	})
+}
+
+func TestSendAlert(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("SNMPv2c", func(t *testing.T) {
+		addr, traps := startListener(t, &gosnmp.GoSNMP{Version: gosnmp.Version2c, Community: "flint", Logger: gosnmp.NewLogger(nil)})
+
+		s, err := NewSender(Config{
+			Targets:    []string{addr},
+			Version:    "2c",
+			Community:  "flint",
+			Severities: []string{"warning"},
+		}, zap.NewNop())
+		require.NoError(t, err)
+
+		s.SendAlert(ctx, peerDownAlert())
+
+		select {
+		case packet := <-traps:
+			assert.Equal(t, "flint", packet.Community)
+			values := varbinds(packet)
+			assert.Equal(t, ".1.3.6.1.4.1.99999.1.0.1", values[".1.3.6.1.6.3.1.1.4.1.0"])
+			assert.Equal(t, "192.0.2.1", values[".1.3.6.1.4.1.99999.2.4"])
+		case <-time.After(2 * time.Second):
+			t.Fatal("no trap received")
+		}
+	})
+
+	t.Run("SNMPv3 authPriv", func(t *testing.T) {
+		sender, err := NewSender(Config{
+			Targets:    []string{"127.0.0.1"},
+			Version:    "3",
+			Router:     "edge-1",
+			Severities: []string{"warning"},
+			V3: V3Config{
+				Username:       "nms",
+				SecurityLevel:  "authPriv",
+				AuthProtocol:   "SHA",
+				AuthPassphrase: "authpassphrase",
+				PrivProtocol:   "AES",
+				PrivPassphrase: "privpassphrase",
+			},
+		}, zap.NewNop())
+		require.NoError(t, err)
+
+		addr, traps := startListener(t, &gosnmp.GoSNMP{
+			Version:       gosnmp.Version3,
+			SecurityModel: gosnmp.UserSecurityModel,
+			MsgFlags:      gosnmp.AuthPriv,
+			SecurityParameters: &gosnmp.UsmSecurityParameters{
+				UserName:                 "nms",
+				AuthoritativeEngineID:    sender.usm.AuthoritativeEngineID,
+				AuthenticationProtocol:   gosnmp.SHA,
+				AuthenticationPassphrase: "authpassphrase",
+				PrivacyProtocol:          gosnmp.AES,
+				PrivacyPassphrase:        "privpassphrase",
+			},
+			Logger: gosnmp.NewLogger(nil),
+		})
+		host, port, _ := net.SplitHostPort(addr)
+		portNum, _ := strconv.Atoi(port)
+		sender.targets = []target{{host: host, port: uint16(portNum)}}
+
+		sender.SendAlert(ctx, peerDownAlert())
+
+		select {
+		case packet := <-traps:
+			values := varbinds(packet)
+			assert.Equal(t, ".1.3.6.1.4.1.99999.1.0.1", values[".1.3.6.1.6.3.1.1.4.1.0"])
+			assert.Equal(t, "edge-1", values[".1.3.6.1.4.1.99999.2.3"])
+		case <-time.After(2 * time.Second):
+			t.Fatal("no trap received")
+		}
+	})
+
+	t.Run("Nil sender is a no-op", func(t *testing.T) {
+		var s *Sender
+		assert.NotPanics(t, func() { s.SendAlert(ctx, peerDownAlert()) })
+	})
+}
@@ -0,0 +1,593 @@
+// Package state dumps FlintRoute's declarative state, the intent an
+// operator configured, as a single versioned YAML document and loads such
+// documents back, so the configuration can move between instances or be
+// kept as code. Secrets are never part of a document.
+package state
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/padminisys/flintroute/internal/alerts"
+	authpkg "github.com/padminisys/flintroute/internal/auth"
+	"github.com/padminisys/flintroute/internal/bgp"
+	"github.com/padminisys/flintroute/internal/secrets"
+	"github.com/padminisys/flintroute/pkg/models"
+	"gopkg.in/yaml.v3"
+)
+
+// Version is the document version this release writes and reads
+const Version = 1
+
+// ErrInvalidDocument is returned when a document cannot be parsed or fails
+// validation
+var ErrInvalidDocument = errors.New("invalid state document")
+
+// Document is the declarative state of an instance. Lists are complete:
+// loading a document with pruning removes what it doesn't list, except
+// users.
+type Document struct {
+	Version              int                   `json:"version"`
+	ExportedAt           *time.Time            `json:"exported_at,omitempty"` // informational; ignored on import
+	Global               *Global               `json:"global,omitempty"`
+	PeerTemplates        []Template            `json:"peer_templates"`
+	Peers                []Peer                `json:"peers"`
+	RouteMaps            []Policy              `json:"route_maps"`
+	PrefixLists          []Policy              `json:"prefix_lists"`
+	CommunityLists       []CommunityList       `json:"community_lists"`
+	ASPathLists          []ASPathList          `json:"as_path_lists"`
+	Users                []User                `json:"users"`
+	Webhooks             []Webhook             `json:"webhooks"`
+	NotificationDefaults *NotificationDefaults `json:"notification_defaults,omitempty"`
+}
+
+// Global is the BGP global configuration, named as in the API
+type Global struct {
+	ASN                uint32 `json:"asn"`
+	RouterID           string `json:"router_id,omitempty"`
+	GracefulRestart    bool   `json:"graceful_restart,omitempty"`
+	RestartTime        int    `json:"graceful_restart_time,omitempty"`
+	StalePathTime      int    `json:"graceful_restart_stalepath_time,omitempty"`
+	EBGPRequiresPolicy bool   `json:"ebgp_requires_policy,omitempty"`
+	LogNeighborChanges bool   `json:"log_neighbor_changes,omitempty"`
+	AlwaysCompareMED   bool   `json:"always_compare_med,omitempty"`
+	CompareRouterID    bool   `json:"bestpath_compare_router_id,omitempty"`
+	MultipathRelax     bool   `json:"bestpath_multipath_relax,omitempty"`
+	MEDMissingAsWorst  bool   `json:"bestpath_med_missing_as_worst,omitempty"`
+}
+
+// Template is a peer template. Parent names another template of the
+// document.
+type Template struct {
+	Name        string                      `json:"name"`
+	Description string                      `json:"description,omitempty"`
+	Parent      string                      `json:"parent,omitempty"`
+	Settings    models.PeerTemplateSettings `json:"settings"`
+}
+
+// Peer is a BGP peer. Password is only set for peers whose password is a
+// secret:// reference; other passwords are left out and left alone.
+// Template and TemplateOverrides are only set when the peer is created.
+type Peer struct {
+	Name                string            `json:"name"`
+	IPAddress           string            `json:"ip_address"`
+	ASN                 uint32            `json:"asn"`
+	RemoteASN           uint32            `json:"remote_asn"`
+	Description         string            `json:"description,omitempty"`
+	Enabled             *bool             `json:"enabled,omitempty"` // defaults to true
+	Password            string            `json:"password,omitempty"`
+	Multihop            int               `json:"multihop,omitempty"`
+	UpdateSource        string            `json:"update_source,omitempty"`
+	RouteMapIn          string            `json:"route_map_in,omitempty"`
+	RouteMapOut         string            `json:"route_map_out,omitempty"`
+	PrefixListIn        string            `json:"prefix_list_in,omitempty"`
+	PrefixListOut       string            `json:"prefix_list_out,omitempty"`
+	MaxPrefixes         int               `json:"max_prefixes,omitempty"`
+	MaxPrefixThreshold  int               `json:"max_prefix_threshold,omitempty"`
+	MaxPrefixAction     string            `json:"max_prefix_action,omitempty"`
+	MaxPrefixRestart    int               `json:"max_prefix_restart,omitempty"`
+	LocalPreference     int               `json:"local_preference,omitempty"`
+	Keepalive           int               `json:"keepalive,omitempty"`
+	HoldTime            int               `json:"holdtime,omitempty"`
+	ConnectRetry        int               `json:"connect_retry,omitempty"`
+	Passive             bool              `json:"passive,omitempty"`
+	TTLSecurityHops     int               `json:"ttl_security_hops,omitempty"`
+	NextHopSelf         bool              `json:"next_hop_self,omitempty"`
+	SoftReconfigInbound bool              `json:"soft_reconfiguration_inbound,omitempty"`
+	RemovePrivateAS     bool              `json:"remove_private_as,omitempty"`
+	AllowASIn           int               `json:"allowas_in,omitempty"`
+	LocalAS             uint32            `json:"local_as,omitempty"`
+	LocalASMode         string            `json:"local_as_mode,omitempty"`
+	Tags                map[string]string `json:"tags,omitempty"`
+	Template            string            `json:"template,omitempty"`
+	TemplateOverrides   []string          `json:"template_overrides,omitempty"`
+}
+
+// Policy is a route-map or prefix-list as its FRR configuration
+type Policy struct {
+	Name   string `json:"name"`
+	Config string `json:"config"`
+}
+
+// CommunityList is a BGP community-list
+type CommunityList struct {
+	Name    string                      `json:"name"`
+	Type    string                      `json:"type,omitempty"` // standard (default), expanded
+	Entries []models.CommunityListEntry `json:"entries"`
+}
+
+// ASPathList is a BGP as-path access-list
+type ASPathList struct {
+	Name    string                   `json:"name"`
+	Entries []models.ASPathListEntry `json:"entries"`
+}
+
+// User is a user account without its password or second factor
+type User struct {
+	Username     string `json:"username"`
+	Email        string `json:"email,omitempty"`
+	Role         string `json:"role"`
+	Active       *bool  `json:"active,omitempty"` // defaults to true
+	RequestQuota *int   `json:"request_quota,omitempty"`
+}
+
+// Webhook is a webhook subscription without its signing secret. The URL
+// identifies it.
+type Webhook struct {
+	URL         string   `json:"url"`
+	Description string   `json:"description,omitempty"`
+	Events      []string `json:"events"`
+	Active      *bool    `json:"active,omitempty"` // defaults to true
+}
+
+// NotificationDefaults are the notification settings of users without
+// their own
+type NotificationDefaults struct {
+	Rules            []models.NotificationRule `json:"rules"`
+	QuietHours       []models.QuietHours       `json:"quiet_hours,omitempty"`
+	QuietHoursBypass []string                  `json:"quiet_hours_bypass,omitempty"`
+	Timezone         string                    `json:"timezone,omitempty"`
+	WebhookURL       string                    `json:"webhook_url,omitempty"`
+}
+
+// Marshal encodes a document as YAML, with keys in the order of the Go
+// types
+func Marshal(doc *Document) ([]byte, error) {
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+
+	// JSON is YAML: decoding it into a node keeps the key order, and
+	// clearing the styles turns flow mappings into blocks
+	var node yaml.Node
+	if err := yaml.Unmarshal(data, &node); err != nil {
+		return nil, err
+	}
+	clearStyle(&node)
+
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&node); err != nil {
+		return nil, err
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// clearStyle resets the style of node and its children to YAML's default
+func clearStyle(node *yaml.Node) {
+	node.Style = 0
+	for _, child := range node.Content {
+		clearStyle(child)
+	}
+}
+
+// Parse decodes and validates a YAML or JSON document. Unknown fields are
+// rejected so typos don't go unnoticed.
+func Parse(data []byte) (*Document, error) {
+	var raw interface{}
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidDocument, err)
+	}
+	if raw == nil {
+		return nil, fmt.Errorf("%w: the document is empty", ErrInvalidDocument)
+	}
+	data, err := json.Marshal(raw)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidDocument, err)
+	}
+
+	var doc Document
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidDocument, err)
+	}
+
+	if err := doc.Validate(); err != nil {
+		return nil, err
+	}
+	return &doc, nil
+}
+
+// Validate checks the document's version, required fields, duplicates and
+// references between its objects
+func (d *Document) Validate() error {
+	if d.Version != Version {
+		return fmt.Errorf("%w: unsupported version %d; this release reads version %d", ErrInvalidDocument, d.Version, Version)
+	}
+
+	var problems []string
+	addf := func(format string, args ...interface{}) {
+		problems = append(problems, fmt.Sprintf(format, args...))
+	}
+
+	if d.Global != nil {
+		if err := bgp.ValidateGlobalConfig(d.Global.config()); err != nil {
+			addf("global: %s", strings.TrimPrefix(err.Error(), bgp.ErrInvalidGlobalConfig.Error()+": "))
+		}
+	}
+
+	templates := make(map[string]bool)
+	for _, template := range d.PeerTemplates {
+		if template.Name == "" {
+			addf("peer template without a name")
+			continue
+		}
+		if templates[template.Name] {
+			addf("peer template %s is defined more than once", template.Name)
+		}
+		templates[template.Name] = true
+	}
+	for _, template := range d.PeerTemplates {
+		if template.Parent == template.Name && template.Name != "" {
+			addf("peer template %s is its own parent", template.Name)
+		} else if template.Parent != "" && !templates[template.Parent] {
+			addf("peer template %s references undefined parent %s", template.Name, template.Parent)
+		}
+	}
+	if len(problems) == 0 && templateDepths(d.PeerTemplates) == nil {
+		addf("peer template parents form a cycle")
+	}
+
+	policies := map[string]map[string]bool{
+		models.PolicyRouteMap:   make(map[string]bool),
+		models.PolicyPrefixList: make(map[string]bool),
+	}
+	for kind, list := range map[string][]Policy{models.PolicyRouteMap: d.RouteMaps, models.PolicyPrefixList: d.PrefixLists} {
+		for _, policy := range list {
+			switch {
+			case policy.Name == "":
+				addf("%s without a name", kind)
+			case policies[kind][policy.Name]:
+				addf("%s %s is defined more than once", kind, policy.Name)
+			case strings.TrimSpace(policy.Config) == "":
+				addf("%s %s has no config", kind, policy.Name)
+			}
+			policies[kind][policy.Name] = true
+		}
+	}
+
+	communityLists := make(map[string]bool)
+	for i := range d.CommunityLists {
+		list := d.CommunityLists[i].list()
+		if communityLists[list.Name] {
+			addf("community-list %s is defined more than once", list.Name)
+		}
+		communityLists[list.Name] = true
+		if err := bgp.ValidateCommunityList(list); err != nil {
+			addf("%v", err)
+		}
+	}
+	asPathLists := make(map[string]bool)
+	for i := range d.ASPathLists {
+		list := d.ASPathLists[i].list()
+		if asPathLists[list.Name] {
+			addf("as-path-list %s is defined more than once", list.Name)
+		}
+		asPathLists[list.Name] = true
+		if err := bgp.ValidateASPathList(list); err != nil {
+			addf("%v", err)
+		}
+	}
+
+	ips := make(map[string]bool)
+	for i := range d.Peers {
+		peer := &d.Peers[i]
+		if net.ParseIP(peer.IPAddress) == nil {
+			addf("peer %q: invalid ip_address %q", peer.Name, peer.IPAddress)
+			continue
+		}
+		if ips[peer.IPAddress] {
+			addf("peer %s is defined more than once", peer.IPAddress)
+		}
+		ips[peer.IPAddress] = true
+
+		if peer.Name == "" {
+			addf("peer %s: name is required", peer.IPAddress)
+		}
+		if peer.RemoteASN == 0 {
+			addf("peer %s: remote_asn is required", peer.IPAddress)
+		}
+		if peer.Password != "" && !secrets.IsReference(peer.Password) {
+			addf("peer %s: password must be a %s reference", peer.IPAddress, secrets.Scheme)
+		}
+		if err := bgp.ValidatePeer(peer.model()); err != nil {
+			addf("peer %s: %s", peer.IPAddress, strings.TrimPrefix(err.Error(), bgp.ErrInvalidPeer.Error()+": "))
+		}
+		if peer.Template != "" && !templates[peer.Template] {
+			addf("peer %s references undefined peer template %s", peer.IPAddress, peer.Template)
+		}
+		for _, ref := range []string{peer.RouteMapIn, peer.RouteMapOut} {
+			if ref != "" && !policies[models.PolicyRouteMap][ref] {
+				addf("peer %s references undefined route-map %s", peer.IPAddress, ref)
+			}
+		}
+		for _, ref := range []string{peer.PrefixListIn, peer.PrefixListOut} {
+			if ref != "" && !policies[models.PolicyPrefixList][ref] {
+				addf("peer %s references undefined prefix-list %s", peer.IPAddress, ref)
+			}
+		}
+	}
+
+	usernames := make(map[string]bool)
+	for _, user := range d.Users {
+		if user.Username == "" {
+			addf("user without a username")
+			continue
+		}
+		if usernames[user.Username] {
+			addf("user %s is defined more than once", user.Username)
+		}
+		usernames[user.Username] = true
+		if user.Role != authpkg.RoleAdmin && user.Role != authpkg.RoleOperator && user.Role != authpkg.RoleUser {
+			addf("user %s: role must be admin, operator or user", user.Username)
+		}
+		if user.RequestQuota != nil && *user.RequestQuota < 0 {
+			addf("user %s: request_quota must not be negative", user.Username)
+		}
+	}
+
+	urls := make(map[string]bool)
+	for _, webhook := range d.Webhooks {
+		if webhook.URL == "" {
+			addf("webhook without a url")
+			continue
+		}
+		if urls[webhook.URL] {
+			addf("webhook %s is defined more than once", webhook.URL)
+		}
+		urls[webhook.URL] = true
+		if len(webhook.Events) == 0 {
+			addf("webhook %s: at least one event is required", webhook.URL)
+		}
+	}
+
+	if d.NotificationDefaults != nil {
+		if err := alerts.ValidateSettings(d.NotificationDefaults.settings()); err != nil {
+			addf("notification_defaults: %s", strings.TrimPrefix(err.Error(), alerts.ErrInvalidSettings.Error()+": "))
+		}
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("%w: %s", ErrInvalidDocument, strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// setDefaults fills in the fields a document may leave out
+func (d *Document) setDefaults() {
+	for i := range d.Peers {
+		if d.Peers[i].Enabled == nil {
+			d.Peers[i].Enabled = boolPtr(true)
+		}
+		// Peers are stored with FRR's default of a single hop
+		if d.Peers[i].Multihop == 0 {
+			d.Peers[i].Multihop = 1
+		}
+	}
+	for i := range d.CommunityLists {
+		if d.CommunityLists[i].Type == "" {
+			d.CommunityLists[i].Type = models.CommunityListStandard
+		}
+	}
+	for i := range d.Users {
+		if d.Users[i].Active == nil {
+			d.Users[i].Active = boolPtr(true)
+		}
+	}
+	for i := range d.Webhooks {
+		if d.Webhooks[i].Active == nil {
+			d.Webhooks[i].Active = boolPtr(true)
+		}
+	}
+}
+
+// templateDepths returns how many ancestors each template has, by name, or
+// nil when parents form a cycle
+func templateDepths(templates []Template) map[string]int {
+	parents := make(map[string]string, len(templates))
+	for _, template := range templates {
+		parents[template.Name] = template.Parent
+	}
+
+	depths := make(map[string]int, len(templates))
+	for name := range parents {
+		depth := 0
+		for parent := parents[name]; parent != ""; parent = parents[parent] {
+			depth++
+			if depth > len(parents) {
+				return nil
+			}
+		}
+		depths[name] = depth
+	}
+	return depths
+}
+
+// boolPtr returns a pointer to b
+func boolPtr(b bool) *bool {
+	return &b
+}
+
+// config returns the stored representation of the global configuration
+func (g *Global) config() *models.BGPGlobalConfig {
+	return &models.BGPGlobalConfig{
+		ASN:                g.ASN,
+		RouterID:           g.RouterID,
+		GracefulRestart:    g.GracefulRestart,
+		RestartTime:        g.RestartTime,
+		StalePathTime:      g.StalePathTime,
+		EBGPRequiresPolicy: g.EBGPRequiresPolicy,
+		LogNeighborChanges: g.LogNeighborChanges,
+		AlwaysCompareMED:   g.AlwaysCompareMED,
+		CompareRouterID:    g.CompareRouterID,
+		MultipathRelax:     g.MultipathRelax,
+		MEDMissingAsWorst:  g.MEDMissingAsWorst,
+	}
+}
+
+// newGlobal returns the document representation of the global
+// configuration
+func newGlobal(cfg *models.BGPGlobalConfig) *Global {
+	return &Global{
+		ASN:                cfg.ASN,
+		RouterID:           cfg.RouterID,
+		GracefulRestart:    cfg.GracefulRestart,
+		RestartTime:        cfg.RestartTime,
+		StalePathTime:      cfg.StalePathTime,
+		EBGPRequiresPolicy: cfg.EBGPRequiresPolicy,
+		LogNeighborChanges: cfg.LogNeighborChanges,
+		AlwaysCompareMED:   cfg.AlwaysCompareMED,
+		CompareRouterID:    cfg.CompareRouterID,
+		MultipathRelax:     cfg.MultipathRelax,
+		MEDMissingAsWorst:  cfg.MEDMissingAsWorst,
+	}
+}
+
+// model returns the stored representation of the peer, without its
+// template
+func (p *Peer) model() *models.BGPPeer {
+	return &models.BGPPeer{
+		Name:                p.Name,
+		IPAddress:           p.IPAddress,
+		ASN:                 p.ASN,
+		RemoteASN:           p.RemoteASN,
+		Description:         p.Description,
+		Enabled:             p.Enabled == nil || *p.Enabled,
+		Password:            p.Password,
+		Multihop:            p.Multihop,
+		UpdateSource:        p.UpdateSource,
+		RouteMapIn:          p.RouteMapIn,
+		RouteMapOut:         p.RouteMapOut,
+		PrefixListIn:        p.PrefixListIn,
+		PrefixListOut:       p.PrefixListOut,
+		MaxPrefixes:         p.MaxPrefixes,
+		MaxPrefixThreshold:  p.MaxPrefixThreshold,
+		MaxPrefixAction:     p.MaxPrefixAction,
+		MaxPrefixRestart:    p.MaxPrefixRestart,
+		LocalPreference:     p.LocalPreference,
+		Keepalive:           p.Keepalive,
+		HoldTime:            p.HoldTime,
+		ConnectRetry:        p.ConnectRetry,
+		Passive:             p.Passive,
+		TTLSecurityHops:     p.TTLSecurityHops,
+		NextHopSelf:         p.NextHopSelf,
+		SoftReconfigInbound: p.SoftReconfigInbound,
+		RemovePrivateAS:     p.RemovePrivateAS,
+		AllowASIn:           p.AllowASIn,
+		LocalAS:             p.LocalAS,
+		LocalASMode:         p.LocalASMode,
+		Tags:                models.NewPeerTags(p.Tags),
+		TemplateOverrides:   p.TemplateOverrides,
+	}
+}
+
+// newPeer returns the document representation of a stored peer. password
+// is its stored password, which is only kept when it is a secret
+// reference.
+func newPeer(peer *models.BGPPeer, password, template string) Peer {
+	if !secrets.IsReference(password) {
+		password = ""
+	}
+	return Peer{
+		Name:                peer.Name,
+		IPAddress:           peer.IPAddress,
+		ASN:                 peer.ASN,
+		RemoteASN:           peer.RemoteASN,
+		Description:         peer.Description,
+		Enabled:             boolPtr(peer.Enabled),
+		Password:            password,
+		Multihop:            peer.Multihop,
+		UpdateSource:        peer.UpdateSource,
+		RouteMapIn:          peer.RouteMapIn,
+		RouteMapOut:         peer.RouteMapOut,
+		PrefixListIn:        peer.PrefixListIn,
+		PrefixListOut:       peer.PrefixListOut,
+		MaxPrefixes:         peer.MaxPrefixes,
+		MaxPrefixThreshold:  peer.MaxPrefixThreshold,
+		MaxPrefixAction:     peer.MaxPrefixAction,
+		MaxPrefixRestart:    peer.MaxPrefixRestart,
+		LocalPreference:     peer.LocalPreference,
+		Keepalive:           peer.Keepalive,
+		HoldTime:            peer.HoldTime,
+		ConnectRetry:        peer.ConnectRetry,
+		Passive:             peer.Passive,
+		TTLSecurityHops:     peer.TTLSecurityHops,
+		NextHopSelf:         peer.NextHopSelf,
+		SoftReconfigInbound: peer.SoftReconfigInbound,
+		RemovePrivateAS:     peer.RemovePrivateAS,
+		AllowASIn:           peer.AllowASIn,
+		LocalAS:             peer.LocalAS,
+		LocalASMode:         peer.LocalASMode,
+		Tags:                peer.Tags.Map(),
+		Template:            template,
+		TemplateOverrides:   peer.TemplateOverrides,
+	}
+}
+
+// list returns the stored representation of the community-list
+func (cl *CommunityList) list() *models.CommunityList {
+	listType := cl.Type
+	if listType == "" {
+		listType = models.CommunityListStandard
+	}
+	return &models.CommunityList{Name: cl.Name, Type: listType, Entries: cl.Entries}
+}
+
+// list returns the stored representation of the as-path access-list
+func (al *ASPathList) list() *models.ASPathList {
+	return &models.ASPathList{Name: al.Name, Entries: al.Entries}
+}
+
+// settings returns the stored representation of the notification defaults
+func (n *NotificationDefaults) settings() *models.NotificationSettings {
+	return &models.NotificationSettings{
+		Rules:            n.Rules,
+		QuietHours:       n.QuietHours,
+		QuietHoursBypass: n.QuietHoursBypass,
+		Timezone:         n.Timezone,
+		WebhookURL:       n.WebhookURL,
+	}
+}
+
+// newNotificationDefaults returns the document representation of stored
+// notification settings
+func newNotificationDefaults(settings *models.NotificationSettings) *NotificationDefaults {
+	return &NotificationDefaults{
+		Rules:            settings.Rules,
+		QuietHours:       settings.QuietHours,
+		QuietHoursBypass: settings.QuietHoursBypass,
+		Timezone:         settings.Timezone,
+		WebhookURL:       settings.WebhookURL,
+	}
+}
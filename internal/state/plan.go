@@ -0,0 +1,611 @@
+package state
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/padminisys/flintroute/internal/bgp"
+	"github.com/padminisys/flintroute/internal/requestid"
+	"github.com/padminisys/flintroute/internal/webhooks"
+	"github.com/padminisys/flintroute/pkg/models"
+	"go.uber.org/zap"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// ErrConflict is returned when a document cannot be applied without manual
+// intervention, e.g. a peer's ASN changed
+var ErrConflict = errors.New("state document conflicts with stored state")
+
+// Action is the operation a change performs
+type Action string
+
+// Change actions
+const (
+	ActionCreate Action = "create"
+	ActionUpdate Action = "update"
+	ActionDelete Action = "delete"
+)
+
+// Change kinds besides the routing policy kinds
+const (
+	KindGlobal               = "global"
+	KindPeerTemplate         = "peer-template"
+	KindPeer                 = "peer"
+	KindUser                 = "user"
+	KindWebhook              = "webhook"
+	KindNotificationDefaults = "notification-defaults"
+)
+
+// defaultName names the changes to singletons: the BGP global
+// configuration and the notification defaults
+const defaultName = "default"
+
+// Change is a single operation of an import. Name is the IP address for
+// peers, the username for users and the URL for webhooks.
+type Change struct {
+	Action Action   `json:"action"`
+	Kind   string   `json:"kind"`
+	Name   string   `json:"name"`
+	Fields []string `json:"fields,omitempty"` // attributes changed by an update
+
+	apply func(ctx context.Context, result *Result) error
+}
+
+// String describes the change for logs and errors
+func (c *Change) String() string {
+	return fmt.Sprintf("%s %s %s", c.Action, c.Kind, c.Name)
+}
+
+// Credential is a secret generated for an object an import created: the
+// password of a user or the signing secret of a webhook. It is only ever
+// returned by the import.
+type Credential struct {
+	Kind   string `json:"kind"` // user, webhook
+	Name   string `json:"name"`
+	Secret string `json:"secret"`
+}
+
+// Result is the outcome of an import: the changes it made, or would make
+// in a dry run
+type Result struct {
+	DryRun      bool         `json:"dry_run"`
+	Changes     []*Change    `json:"changes"`
+	Conflicts   []string     `json:"conflicts,omitempty"`
+	Credentials []Credential `json:"credentials,omitempty"`
+}
+
+// ImportOptions control an import
+type ImportOptions struct {
+	// DryRun only computes the changes
+	DryRun bool
+	// Prune deletes the peers, templates, policies and webhooks the
+	// document doesn't list. Users are never deleted.
+	Prune bool
+	// UserID is the user importing, recorded as the creator of webhooks
+	UserID uint
+}
+
+// Import brings the stored state in line with doc. Changes are applied in
+// dependency order, stopping at the first failure; the result then holds
+// the changes applied before it. Nothing is applied when there are
+// conflicts.
+func (s *Service) Import(ctx context.Context, doc *Document, opts ImportOptions) (*Result, error) {
+	doc.setDefaults()
+	changes, conflicts, err := s.plan(ctx, doc, opts.Prune, opts.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &Result{DryRun: opts.DryRun, Changes: changes, Conflicts: conflicts}
+	if opts.DryRun {
+		return result, nil
+	}
+	if len(conflicts) > 0 {
+		return result, fmt.Errorf("%w: %s", ErrConflict, strings.Join(conflicts, "; "))
+	}
+
+	applied := []*Change{}
+	for _, change := range changes {
+		if err := change.apply(ctx, result); err != nil {
+			result.Changes = applied
+			return result, fmt.Errorf("failed to %s: %w", change, err)
+		}
+		applied = append(applied, change)
+	}
+
+	s.logger.Info("Imported state document",
+		zap.Int("changes", len(applied)),
+		zap.Bool("prune", opts.Prune),
+		requestid.Field(ctx),
+	)
+
+	return result, nil
+}
+
+// plan diffs doc against the stored state. Lists and prefix-lists are
+// saved before the route-maps referencing them, templates before their
+// children and the peers using them, and deletes come last, in reverse.
+func (s *Service) plan(ctx context.Context, doc *Document, prune bool, userID uint) ([]*Change, []string, error) {
+	current, err := s.Export(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var changes, deletes []*Change
+	var conflicts []string
+
+	if doc.Global != nil {
+		desired := doc.Global
+		var change *Change
+		if current.Global == nil {
+			change = &Change{Action: ActionCreate, Kind: KindGlobal, Name: defaultName}
+		} else if fields := diffFields(*current.Global, *desired); len(fields) > 0 {
+			change = &Change{Action: ActionUpdate, Kind: KindGlobal, Name: defaultName, Fields: fields}
+		}
+		if change != nil {
+			change.apply = func(ctx context.Context, _ *Result) error {
+				return s.bgp.SaveGlobalConfig(ctx, desired.config())
+			}
+			changes = append(changes, change)
+		}
+	}
+
+	communityChanges, communityDeletes := planByName(models.PolicyCommunityList, current.CommunityLists, doc.CommunityLists, prune,
+		func(list CommunityList) string { return list.Name },
+		func(list CommunityList) func(context.Context, *Result) error {
+			return func(ctx context.Context, _ *Result) error { return s.bgp.SaveCommunityList(ctx, list.list()) }
+		},
+		func(name string) func(context.Context, *Result) error {
+			return func(ctx context.Context, _ *Result) error { return s.bgp.DeleteCommunityList(ctx, name) }
+		})
+	asPathChanges, asPathDeletes := planByName(models.PolicyASPathList, current.ASPathLists, doc.ASPathLists, prune,
+		func(list ASPathList) string { return list.Name },
+		func(list ASPathList) func(context.Context, *Result) error {
+			return func(ctx context.Context, _ *Result) error { return s.bgp.SaveASPathList(ctx, list.list()) }
+		},
+		func(name string) func(context.Context, *Result) error {
+			return func(ctx context.Context, _ *Result) error { return s.bgp.DeleteASPathList(ctx, name) }
+		})
+	policyChanges := func(kind string, currentPolicies, desiredPolicies []Policy) ([]*Change, []*Change) {
+		return planByName(kind, currentPolicies, desiredPolicies, prune,
+			func(policy Policy) string { return policy.Name },
+			func(policy Policy) func(context.Context, *Result) error {
+				return func(ctx context.Context, _ *Result) error {
+					return s.bgp.SavePolicy(ctx, &models.RoutingPolicy{Kind: kind, Name: policy.Name, Config: policy.Config})
+				}
+			},
+			func(name string) func(context.Context, *Result) error {
+				return func(ctx context.Context, _ *Result) error { return s.bgp.DeletePolicy(ctx, kind, name) }
+			})
+	}
+	prefixListChanges, prefixListDeletes := policyChanges(models.PolicyPrefixList, current.PrefixLists, doc.PrefixLists)
+	routeMapChanges, routeMapDeletes := policyChanges(models.PolicyRouteMap, current.RouteMaps, doc.RouteMaps)
+	changes = append(changes, communityChanges...)
+	changes = append(changes, asPathChanges...)
+	changes = append(changes, prefixListChanges...)
+	changes = append(changes, routeMapChanges...)
+
+	templateChanges, templateDeletes, err := s.planTemplates(ctx, current.PeerTemplates, doc.PeerTemplates, prune)
+	if err != nil {
+		return nil, nil, err
+	}
+	changes = append(changes, templateChanges...)
+
+	peerChanges, peerDeletes, peerConflicts, err := s.planPeers(ctx, doc.Peers, prune)
+	if err != nil {
+		return nil, nil, err
+	}
+	changes = append(changes, peerChanges...)
+	conflicts = append(conflicts, peerConflicts...)
+
+	userChanges, err := s.planUsers(ctx, current.Users, doc.Users)
+	if err != nil {
+		return nil, nil, err
+	}
+	changes = append(changes, userChanges...)
+
+	webhookChanges, webhookDeletes, err := s.planWebhooks(ctx, doc.Webhooks, prune, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+	changes = append(changes, webhookChanges...)
+
+	if doc.NotificationDefaults != nil {
+		desired := doc.NotificationDefaults
+		var change *Change
+		if current.NotificationDefaults == nil {
+			change = &Change{Action: ActionCreate, Kind: KindNotificationDefaults, Name: defaultName}
+		} else if fields := diffFields(*current.NotificationDefaults, *desired); len(fields) > 0 {
+			change = &Change{Action: ActionUpdate, Kind: KindNotificationDefaults, Name: defaultName, Fields: fields}
+		}
+		if change != nil {
+			change.apply = func(ctx context.Context, _ *Result) error {
+				return s.notifier.SetDefaults(ctx, desired.settings())
+			}
+			changes = append(changes, change)
+		}
+	}
+
+	for _, group := range [][]*Change{webhookDeletes, peerDeletes, templateDeletes, routeMapDeletes, prefixListDeletes, communityDeletes, asPathDeletes} {
+		deletes = append(deletes, group...)
+	}
+	return append(changes, deletes...), conflicts, nil
+}
+
+// planByName diffs objects identified by their name. save applies a
+// desired object and remove deletes a stored one; stored objects missing
+// from desired are only deleted when pruning.
+func planByName[T any](kind string, current, desired []T, prune bool, name func(T) string,
+	save func(T) func(context.Context, *Result) error, remove func(string) func(context.Context, *Result) error) ([]*Change, []*Change) {
+	stored := make(map[string]T, len(current))
+	for _, object := range current {
+		stored[name(object)] = object
+	}
+
+	var changes, deletes []*Change
+	wanted := make(map[string]bool, len(desired))
+	for _, object := range desired {
+		wanted[name(object)] = true
+		existing, ok := stored[name(object)]
+		if !ok {
+			changes = append(changes, &Change{Action: ActionCreate, Kind: kind, Name: name(object), apply: save(object)})
+		} else if fields := diffFields(existing, object, "name"); len(fields) > 0 {
+			changes = append(changes, &Change{Action: ActionUpdate, Kind: kind, Name: name(object), Fields: fields, apply: save(object)})
+		}
+	}
+	if prune {
+		for _, object := range current {
+			if !wanted[name(object)] {
+				deletes = append(deletes, &Change{Action: ActionDelete, Kind: kind, Name: name(object), apply: remove(name(object))})
+			}
+		}
+	}
+	return changes, deletes
+}
+
+// planTemplates diffs the peer templates. Parents are created before their
+// children and deleted after them.
+func (s *Service) planTemplates(ctx context.Context, current, desired []Template, prune bool) ([]*Change, []*Change, error) {
+	stored, err := s.bgp.ListTemplates(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to list peer templates: %w", err)
+	}
+	ids := make(map[string]uint, len(stored))
+	for _, template := range stored {
+		ids[template.Name] = template.ID
+	}
+
+	depths := templateDepths(desired)
+	ordered := append([]Template(nil), desired...)
+	sort.SliceStable(ordered, func(i, j int) bool { return depths[ordered[i].Name] < depths[ordered[j].Name] })
+
+	save := func(template Template) func(context.Context, *Result) error {
+		return func(ctx context.Context, _ *Result) error {
+			parentID, err := s.templateID(ctx, template.Parent)
+			if err != nil {
+				return err
+			}
+			model := &models.PeerTemplate{
+				Name:        template.Name,
+				Description: template.Description,
+				ParentID:    parentID,
+				Settings:    template.Settings,
+			}
+			id, err := s.templateID(ctx, template.Name)
+			if err != nil || id == nil {
+				return s.bgp.CreateTemplate(ctx, model)
+			}
+			_, err = s.bgp.UpdateTemplate(ctx, *id, model)
+			return err
+		}
+	}
+	changes, deletes := planByName(KindPeerTemplate, current, ordered, prune,
+		func(template Template) string { return template.Name },
+		save,
+		func(name string) func(context.Context, *Result) error {
+			return func(ctx context.Context, _ *Result) error { return s.bgp.DeleteTemplate(ctx, ids[name]) }
+		})
+
+	currentDepths := templateDepths(current)
+	sort.SliceStable(deletes, func(i, j int) bool { return currentDepths[deletes[i].Name] > currentDepths[deletes[j].Name] })
+	return changes, deletes, nil
+}
+
+// templateID returns the ID of the stored template with a name, or nil for
+// an empty name or a template that doesn't exist yet
+func (s *Service) templateID(ctx context.Context, name string) (*uint, error) {
+	if name == "" {
+		return nil, nil
+	}
+	templates, err := s.bgp.ListTemplates(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list peer templates: %w", err)
+	}
+	for _, template := range templates {
+		if template.Name == name {
+			return &template.ID, nil
+		}
+	}
+	return nil, nil
+}
+
+// planPeers diffs the peers by IP address. Changing a peer's ASNs is a
+// conflict, as through the API.
+func (s *Service) planPeers(ctx context.Context, desired []Peer, prune bool) ([]*Change, []*Change, []string, error) {
+	stored, err := s.bgp.ListPeers(ctx)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to list peers: %w", err)
+	}
+	existing := make(map[string]*models.BGPPeer, len(stored))
+	for _, peer := range stored {
+		existing[peer.IPAddress] = peer
+	}
+
+	var changes, deletes []*Change
+	var conflicts []string
+	wanted := make(map[string]bool, len(desired))
+	for i := range desired {
+		peer := desired[i]
+		wanted[peer.IPAddress] = true
+
+		current, ok := existing[peer.IPAddress]
+		if !ok {
+			changes = append(changes, &Change{Action: ActionCreate, Kind: KindPeer, Name: peer.IPAddress,
+				apply: func(ctx context.Context, _ *Result) error {
+					model := peer.model()
+					templateID, err := s.templateID(ctx, peer.Template)
+					if err != nil {
+						return err
+					}
+					model.TemplateID = templateID
+					return s.bgp.CreatePeer(ctx, model)
+				}})
+			continue
+		}
+
+		if current.ASN != peer.ASN || current.RemoteASN != peer.RemoteASN {
+			conflicts = append(conflicts, fmt.Sprintf(
+				"peer %s: asn and remote_asn cannot be changed; delete the peer before re-adding it", peer.IPAddress))
+			continue
+		}
+
+		password, err := s.bgp.StoredPassword(current)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		fields := diffFields(newPeer(current, password, ""), peer,
+			"ip_address", "asn", "remote_asn", "password", "template", "template_overrides")
+		passwordChanged := peer.Password != "" && peer.Password != password
+		if passwordChanged {
+			fields = append(fields, "password")
+		}
+		if len(fields) == 0 {
+			continue
+		}
+
+		id := current.ID
+		patchFields := len(fields) > 1 || !passwordChanged
+		changes = append(changes, &Change{Action: ActionUpdate, Kind: KindPeer, Name: peer.IPAddress, Fields: fields,
+			apply: func(ctx context.Context, _ *Result) error {
+				if patchFields {
+					if err := s.bgp.PatchPeer(ctx, id, peerPatch(peer.model())); err != nil {
+						return err
+					}
+				}
+				if passwordChanged {
+					return s.bgp.SetPeerPassword(ctx, id, peer.Password)
+				}
+				return nil
+			}})
+	}
+
+	if prune {
+		for _, peer := range stored {
+			if !wanted[peer.IPAddress] {
+				id := peer.ID
+				deletes = append(deletes, &Change{Action: ActionDelete, Kind: KindPeer, Name: peer.IPAddress,
+					apply: func(ctx context.Context, _ *Result) error { return s.bgp.DeletePeer(ctx, id) }})
+			}
+		}
+		sort.Slice(deletes, func(i, j int) bool { return deletes[i].Name < deletes[j].Name })
+	}
+	return changes, deletes, conflicts, nil
+}
+
+// peerPatch returns a patch setting every attribute of peer, including its
+// tags, but not its password
+func peerPatch(peer *models.BGPPeer) *bgp.PeerPatch {
+	tags := peer.Tags.Map()
+	if tags == nil {
+		tags = map[string]string{}
+	}
+	return &bgp.PeerPatch{
+		Name:                &peer.Name,
+		Description:         &peer.Description,
+		Enabled:             &peer.Enabled,
+		Multihop:            &peer.Multihop,
+		UpdateSource:        &peer.UpdateSource,
+		RouteMapIn:          &peer.RouteMapIn,
+		RouteMapOut:         &peer.RouteMapOut,
+		PrefixListIn:        &peer.PrefixListIn,
+		PrefixListOut:       &peer.PrefixListOut,
+		MaxPrefixes:         &peer.MaxPrefixes,
+		MaxPrefixThreshold:  &peer.MaxPrefixThreshold,
+		MaxPrefixAction:     &peer.MaxPrefixAction,
+		MaxPrefixRestart:    &peer.MaxPrefixRestart,
+		LocalPreference:     &peer.LocalPreference,
+		Keepalive:           &peer.Keepalive,
+		HoldTime:            &peer.HoldTime,
+		ConnectRetry:        &peer.ConnectRetry,
+		Passive:             &peer.Passive,
+		TTLSecurityHops:     &peer.TTLSecurityHops,
+		NextHopSelf:         &peer.NextHopSelf,
+		SoftReconfigInbound: &peer.SoftReconfigInbound,
+		RemovePrivateAS:     &peer.RemovePrivateAS,
+		AllowASIn:           &peer.AllowASIn,
+		LocalAS:             &peer.LocalAS,
+		LocalASMode:         &peer.LocalASMode,
+		Tags:                tags,
+	}
+}
+
+// planUsers diffs the users by username. Users created by an import get a
+// random password, returned as a credential.
+func (s *Service) planUsers(ctx context.Context, current, desired []User) ([]*Change, error) {
+	stored, err := s.listUsers(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ids := make(map[string]uint, len(stored))
+	for _, user := range stored {
+		ids[user.Username] = user.ID
+	}
+
+	changes, _ := planByName(KindUser, current, desired, false,
+		func(user User) string { return user.Username },
+		func(user User) func(context.Context, *Result) error {
+			return func(ctx context.Context, result *Result) error {
+				if id, ok := ids[user.Username]; ok {
+					return s.db.WithContext(ctx).Model(&models.User{ID: id}).Updates(map[string]interface{}{
+						"email":         user.Email,
+						"role":          user.Role,
+						"active":        *user.Active,
+						"request_quota": user.RequestQuota,
+					}).Error
+				}
+				return s.createUser(ctx, user, result)
+			}
+		}, nil)
+	return changes, nil
+}
+
+// createUser creates a user with a random password
+func (s *Service) createUser(ctx context.Context, user User, result *Result) error {
+	password, err := randomSecret()
+	if err != nil {
+		return err
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return fmt.Errorf("failed to hash password: %w", err)
+	}
+
+	model := &models.User{
+		Username:     user.Username,
+		PasswordHash: string(hash),
+		Email:        user.Email,
+		Role:         user.Role,
+		Active:       *user.Active,
+		RequestQuota: user.RequestQuota,
+	}
+	if err := s.db.WithContext(ctx).Create(model).Error; err != nil {
+		return fmt.Errorf("failed to create user: %w", err)
+	}
+	// GORM skips zero values for columns with defaults
+	if !model.Active {
+		if err := s.db.WithContext(ctx).Model(model).Update("active", false).Error; err != nil {
+			return fmt.Errorf("failed to create user: %w", err)
+		}
+	}
+
+	result.Credentials = append(result.Credentials, Credential{Kind: KindUser, Name: user.Username, Secret: password})
+	return nil
+}
+
+// randomSecret returns a random password
+func randomSecret() (string, error) {
+	b := make([]byte, 18)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// planWebhooks diffs the webhook subscriptions by URL. Webhooks created
+// by an import get a generated signing secret, returned as a credential,
+// and are recorded as created by userID.
+func (s *Service) planWebhooks(ctx context.Context, desired []Webhook, prune bool, userID uint) ([]*Change, []*Change, error) {
+	stored, err := s.webhooks.ListSubscriptions(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to list webhooks: %w", err)
+	}
+	ids := make(map[string]uint, len(stored))
+	current := make([]Webhook, 0, len(stored))
+	for _, sub := range stored {
+		if _, ok := ids[sub.URL]; !ok {
+			current = append(current, newWebhook(sub))
+		}
+		ids[sub.URL] = sub.ID
+	}
+
+	changes, deletes := planByName(KindWebhook, current, desired, prune,
+		func(webhook Webhook) string { return webhook.URL },
+		func(webhook Webhook) func(context.Context, *Result) error {
+			return func(ctx context.Context, result *Result) error {
+				sub := &models.WebhookSubscription{
+					URL:         webhook.URL,
+					Description: webhook.Description,
+					Events:      webhook.Events,
+					Active:      *webhook.Active,
+				}
+				if id, ok := ids[webhook.URL]; ok {
+					_, err := s.webhooks.UpdateSubscription(ctx, id, sub, nil)
+					return err
+				}
+
+				secret, err := webhooks.GenerateSecret()
+				if err != nil {
+					return err
+				}
+				sub.CreatedBy = userID
+				if err := s.webhooks.CreateSubscription(ctx, sub, secret); err != nil {
+					return err
+				}
+				result.Credentials = append(result.Credentials, Credential{Kind: KindWebhook, Name: webhook.URL, Secret: secret})
+				return nil
+			}
+		},
+		func(url string) func(context.Context, *Result) error {
+			return func(ctx context.Context, _ *Result) error { return s.webhooks.DeleteSubscription(ctx, ids[url]) }
+		})
+	sort.Slice(deletes, func(i, j int) bool { return deletes[i].Name < deletes[j].Name })
+	return changes, deletes, nil
+}
+
+// diffFields returns the JSON names of the fields that differ between
+// current and desired, two values of the same struct type. Empty and
+// missing lists count as equal, and the fields named in skip are ignored.
+func diffFields(current, desired interface{}, skip ...string) []string {
+	currentValue, desiredValue := reflect.ValueOf(current), reflect.ValueOf(desired)
+	var fields []string
+	for i := 0; i < currentValue.NumField(); i++ {
+		name, _, _ := strings.Cut(currentValue.Type().Field(i).Tag.Get("json"), ",")
+		if slices.Contains(skip, name) {
+			continue
+		}
+		a, b := currentValue.Field(i), desiredValue.Field(i)
+		if isEmpty(a) && isEmpty(b) {
+			continue
+		}
+		if !reflect.DeepEqual(a.Interface(), b.Interface()) {
+			fields = append(fields, name)
+		}
+	}
+	return fields
+}
+
+// isEmpty reports whether a value is zero or an empty list or map
+func isEmpty(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Slice, reflect.Map:
+		return v.Len() == 0
+	}
+	return v.IsZero()
+}
@@ -0,0 +1,194 @@
+package state
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/padminisys/flintroute/internal/alerts"
+	"github.com/padminisys/flintroute/internal/bgp"
+	"github.com/padminisys/flintroute/internal/database"
+	"github.com/padminisys/flintroute/internal/webhooks"
+	"github.com/padminisys/flintroute/pkg/models"
+	"go.uber.org/zap"
+)
+
+// Service exports the state of an instance and imports documents into it.
+// Imports go through the same services as the API, so FRR is configured
+// and events are published as for API changes.
+type Service struct {
+	db       *database.DB
+	bgp      *bgp.Service
+	webhooks *webhooks.Service
+	notifier *alerts.Notifier
+	logger   *zap.Logger
+}
+
+// NewService creates a state service
+func NewService(db *database.DB, bgpService *bgp.Service, webhookService *webhooks.Service, notifier *alerts.Notifier, logger *zap.Logger) *Service {
+	return &Service{
+		db:       db,
+		bgp:      bgpService,
+		webhooks: webhookService,
+		notifier: notifier,
+		logger:   logger,
+	}
+}
+
+// Export returns the instance's current state. Objects are sorted by name
+// so exports of the same state are identical.
+func (s *Service) Export(ctx context.Context) (*Document, error) {
+	now := time.Now().UTC()
+	doc := &Document{
+		Version:        Version,
+		ExportedAt:     &now,
+		PeerTemplates:  []Template{},
+		Peers:          []Peer{},
+		RouteMaps:      []Policy{},
+		PrefixLists:    []Policy{},
+		CommunityLists: []CommunityList{},
+		ASPathLists:    []ASPathList{},
+		Users:          []User{},
+		Webhooks:       []Webhook{},
+	}
+
+	global, err := s.bgp.GetGlobalConfig(ctx)
+	switch {
+	case err == nil:
+		doc.Global = newGlobal(global)
+	case !errors.Is(err, bgp.ErrGlobalConfigNotFound):
+		return nil, fmt.Errorf("failed to get BGP global configuration: %w", err)
+	}
+
+	templates, err := s.bgp.ListTemplates(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list peer templates: %w", err)
+	}
+	templateNames := make(map[uint]string, len(templates))
+	for _, template := range templates {
+		templateNames[template.ID] = template.Name
+	}
+	for _, template := range templates {
+		doc.PeerTemplates = append(doc.PeerTemplates, newTemplate(template, templateNames))
+	}
+
+	peers, err := s.bgp.ListPeers(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list peers: %w", err)
+	}
+	sort.Slice(peers, func(i, j int) bool { return peers[i].IPAddress < peers[j].IPAddress })
+	for _, peer := range peers {
+		password, err := s.bgp.StoredPassword(peer)
+		if err != nil {
+			return nil, err
+		}
+		doc.Peers = append(doc.Peers, newPeer(peer, password, templateName(peer.TemplateID, templateNames)))
+	}
+
+	policies, err := s.bgp.ListPolicies(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list routing policies: %w", err)
+	}
+	for _, policy := range policies {
+		switch policy.Kind {
+		case models.PolicyRouteMap:
+			doc.RouteMaps = append(doc.RouteMaps, Policy{Name: policy.Name, Config: policy.Config})
+		case models.PolicyPrefixList:
+			doc.PrefixLists = append(doc.PrefixLists, Policy{Name: policy.Name, Config: policy.Config})
+		}
+	}
+
+	communityLists, err := s.bgp.ListCommunityLists(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list community-lists: %w", err)
+	}
+	for _, list := range communityLists {
+		doc.CommunityLists = append(doc.CommunityLists, CommunityList{Name: list.Name, Type: list.Type, Entries: list.Entries})
+	}
+	asPathLists, err := s.bgp.ListASPathLists(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list as-path-lists: %w", err)
+	}
+	for _, list := range asPathLists {
+		doc.ASPathLists = append(doc.ASPathLists, ASPathList{Name: list.Name, Entries: list.Entries})
+	}
+
+	users, err := s.listUsers(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, user := range users {
+		doc.Users = append(doc.Users, newUser(user))
+	}
+
+	subs, err := s.webhooks.ListSubscriptions(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list webhooks: %w", err)
+	}
+	sort.Slice(subs, func(i, j int) bool { return subs[i].URL < subs[j].URL })
+	for _, sub := range subs {
+		doc.Webhooks = append(doc.Webhooks, newWebhook(sub))
+	}
+
+	defaults, err := s.notifier.Defaults(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if defaults.ID != 0 {
+		doc.NotificationDefaults = newNotificationDefaults(defaults)
+	}
+
+	return doc, nil
+}
+
+// listUsers returns every user, sorted by username
+func (s *Service) listUsers(ctx context.Context) ([]*models.User, error) {
+	var users []*models.User
+	if err := s.db.WithContext(ctx).Order("username").Find(&users).Error; err != nil {
+		return nil, fmt.Errorf("failed to list users: %w", err)
+	}
+	return users, nil
+}
+
+// newTemplate returns the document representation of a stored template
+func newTemplate(template *models.PeerTemplate, names map[uint]string) Template {
+	return Template{
+		Name:        template.Name,
+		Description: template.Description,
+		Parent:      templateName(template.ParentID, names),
+		Settings:    template.Settings,
+	}
+}
+
+// templateName returns the name of the template with an ID, or an empty
+// name for nil
+func templateName(id *uint, names map[uint]string) string {
+	if id == nil {
+		return ""
+	}
+	return names[*id]
+}
+
+// newUser returns the document representation of a user
+func newUser(user *models.User) User {
+	return User{
+		Username:     user.Username,
+		Email:        user.Email,
+		Role:         user.Role,
+		Active:       boolPtr(user.Active),
+		RequestQuota: user.RequestQuota,
+	}
+}
+
+// newWebhook returns the document representation of a webhook
+// subscription
+func newWebhook(sub *models.WebhookSubscription) Webhook {
+	return Webhook{
+		URL:         sub.URL,
+		Description: sub.Description,
+		Events:      sub.Events,
+		Active:      boolPtr(sub.Active),
+	}
+}
@@ -0,0 +1,293 @@
+package state
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/padminisys/flintroute/internal/alerts"
+	"github.com/padminisys/flintroute/internal/bgp"
+	"github.com/padminisys/flintroute/internal/encryption"
+	"github.com/padminisys/flintroute/internal/frr"
+	"github.com/padminisys/flintroute/internal/testutil"
+	"github.com/padminisys/flintroute/internal/webhooks"
+	"github.com/padminisys/flintroute/internal/websocket"
+	"github.com/padminisys/flintroute/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// setupTestService creates a state service for a fresh instance, with FRR
+// simulated so every change applies
+func setupTestService(t *testing.T) (*Service, *bgp.Service) {
+	t.Helper()
+
+	logger := zap.NewNop()
+	db := testutil.SetupTestDB(t)
+	t.Cleanup(func() { testutil.CleanupTestDB(t, db) })
+
+	frrClient := frr.NewDemoClient(frr.DemoOptions{}, logger)
+	require.NoError(t, frrClient.Connect(context.Background()))
+	t.Cleanup(func() { frrClient.Close() })
+
+	cipher, err := encryption.NewCipher(bytes.Repeat([]byte{0x42}, encryption.KeySize))
+	require.NoError(t, err)
+
+	bgpService := bgp.NewService(db, frrClient, websocket.NewHub(logger), bgp.ServiceConfig{PasswordCipher: cipher}, logger)
+	webhookService := webhooks.NewService(db, cipher, webhooks.Config{}, logger)
+	return NewService(db, bgpService, webhookService, alerts.NewNotifier(db, nil, logger), logger), bgpService
+}
+
+const sourceState = `
+version: 1
+global:
+  asn: 65001
+  router_id: 10.0.0.1
+peer_templates:
+  - name: transit-v4
+    parent: transit
+    settings:
+      max_prefixes: 1000000
+  - name: transit
+    settings:
+      keepalive: 10
+      holdtime: 30
+peers:
+  - name: upstream-a
+    ip_address: 192.0.2.1
+    asn: 65001
+    remote_asn: 65002
+    password: secret://env/UPSTREAM_A_PASSWORD
+    route_map_in: IMPORT
+    tags:
+      role: transit
+    template: transit-v4
+  - name: upstream-b
+    ip_address: 192.0.2.2
+    asn: 65001
+    remote_asn: 65003
+    enabled: false
+route_maps:
+  - name: IMPORT
+    config: |
+      route-map IMPORT permit 10
+       match ip address prefix-list CUSTOMERS
+prefix_lists:
+  - name: CUSTOMERS
+    config: |
+      ip prefix-list CUSTOMERS seq 10 permit 203.0.113.0/24
+community_lists:
+  - name: BLACKHOLE
+    entries:
+      - seq: 10
+        action: permit
+        value: "65535:666"
+as_path_lists: []
+users:
+  - username: admin
+    email: admin@flintroute.local
+    role: admin
+  - username: noc
+    email: noc@example.com
+    role: operator
+webhooks:
+  - url: https://hooks.example.com/flintroute
+    events: ["peer.*"]
+notification_defaults:
+  rules:
+    - severities: [critical]
+      channels: [email]
+`
+
+func TestImport(t *testing.T) {
+	ctx := context.Background()
+	doc, err := Parse([]byte(sourceState))
+	require.NoError(t, err)
+
+	service, bgpService := setupTestService(t)
+
+	t.Run("Dry run lists the changes", func(t *testing.T) {
+		result, err := service.Import(ctx, doc, ImportOptions{DryRun: true})
+		require.NoError(t, err)
+		assert.True(t, result.DryRun)
+
+		var names []string
+		for _, change := range result.Changes {
+			assert.Equal(t, ActionCreate, change.Action, change.String())
+			names = append(names, change.Kind+" "+change.Name)
+		}
+		assert.Equal(t, []string{
+			"global default",
+			"community-list BLACKHOLE",
+			"prefix-list CUSTOMERS",
+			"route-map IMPORT",
+			"peer-template transit",
+			"peer-template transit-v4",
+			"peer 192.0.2.1",
+			"peer 192.0.2.2",
+			"user noc",
+			"webhook https://hooks.example.com/flintroute",
+			"notification-defaults default",
+		}, names, "the stored admin is unchanged")
+
+		peers, err := bgpService.ListPeers(ctx)
+		require.NoError(t, err)
+		assert.Empty(t, peers)
+	})
+
+	t.Run("Apply creates everything", func(t *testing.T) {
+		result, err := service.Import(ctx, doc, ImportOptions{UserID: 1})
+		require.NoError(t, err)
+		assert.Len(t, result.Changes, 11)
+		require.Len(t, result.Credentials, 2)
+		assert.Equal(t, KindUser, result.Credentials[0].Kind)
+		assert.NotEmpty(t, result.Credentials[0].Secret)
+
+		peers, err := bgpService.ListPeers(ctx)
+		require.NoError(t, err)
+		require.Len(t, peers, 2)
+		assert.True(t, peers[0].HasPassword)
+		assert.Equal(t, "transit", peers[0].Tags.Map()["role"])
+		assert.NotNil(t, peers[0].TemplateID)
+		assert.False(t, peers[1].Enabled)
+	})
+
+	t.Run("Export gives back the document", func(t *testing.T) {
+		exported, err := service.Export(ctx)
+		require.NoError(t, err)
+		data, err := Marshal(exported)
+		require.NoError(t, err)
+		assert.Contains(t, string(data), "version: 1\n")
+		assert.Contains(t, string(data), "password: secret://env/UPSTREAM_A_PASSWORD\n")
+		assert.NotContains(t, string(data), "password_hash")
+
+		reloaded, err := Parse(data)
+		require.NoError(t, err)
+		result, err := service.Import(ctx, reloaded, ImportOptions{DryRun: true})
+		require.NoError(t, err)
+		assert.Empty(t, result.Changes)
+	})
+
+	t.Run("Updates name the changed fields", func(t *testing.T) {
+		changed, err := Parse([]byte(sourceState))
+		require.NoError(t, err)
+		changed.Peers[1].Description = "backup transit"
+		changed.Peers[1].Tags = map[string]string{"role": "backup"}
+
+		result, err := service.Import(ctx, changed, ImportOptions{})
+		require.NoError(t, err)
+		require.Len(t, result.Changes, 1)
+		assert.Equal(t, ActionUpdate, result.Changes[0].Action)
+		assert.Equal(t, []string{"description", "tags"}, result.Changes[0].Fields)
+
+		peer, err := bgpService.GetPeer(ctx, 2)
+		require.NoError(t, err)
+		assert.Equal(t, "backup transit", peer.Description)
+	})
+
+	t.Run("ASN changes conflict", func(t *testing.T) {
+		changed, err := Parse([]byte(sourceState))
+		require.NoError(t, err)
+		changed.Peers[0].RemoteASN = 65009
+
+		result, err := service.Import(ctx, changed, ImportOptions{})
+		assert.ErrorIs(t, err, ErrConflict)
+		require.Len(t, result.Conflicts, 1)
+		assert.Contains(t, result.Conflicts[0], "192.0.2.1")
+	})
+
+	t.Run("Pruning deletes what the document leaves out", func(t *testing.T) {
+		pruned, err := Parse([]byte(sourceState))
+		require.NoError(t, err)
+		pruned.Peers = pruned.Peers[:1]
+		pruned.Webhooks = nil
+		pruned.Users = nil
+
+		result, err := service.Import(ctx, pruned, ImportOptions{DryRun: true})
+		require.NoError(t, err)
+		assert.Empty(t, result.Changes, "nothing is deleted without pruning")
+
+		result, err = service.Import(ctx, pruned, ImportOptions{Prune: true})
+		require.NoError(t, err)
+		var deleted []string
+		for _, change := range result.Changes {
+			if change.Action == ActionDelete {
+				deleted = append(deleted, change.Kind+" "+change.Name)
+			}
+		}
+		assert.Equal(t, []string{"webhook https://hooks.example.com/flintroute", "peer 192.0.2.2"}, deleted, "users are never deleted")
+
+		exported, err := service.Export(ctx)
+		require.NoError(t, err)
+		assert.Len(t, exported.Peers, 1)
+		assert.Len(t, exported.Users, 2)
+	})
+}
+
+func TestParse(t *testing.T) {
+	t.Run("Unknown fields are rejected", func(t *testing.T) {
+		_, err := Parse([]byte("version: 1\npeers:\n  - name: a\n    ip_address: 192.0.2.1\n    remote_asn: 65002\n    colour: blue\n"))
+		assert.ErrorIs(t, err, ErrInvalidDocument)
+		assert.Contains(t, err.Error(), "colour")
+	})
+
+	t.Run("Versions other than the current are rejected", func(t *testing.T) {
+		_, err := Parse([]byte("version: 2\n"))
+		assert.ErrorIs(t, err, ErrInvalidDocument)
+		assert.Contains(t, err.Error(), "unsupported version 2")
+	})
+
+	t.Run("References and secrets are checked", func(t *testing.T) {
+		_, err := Parse([]byte(`
+version: 1
+peer_templates:
+  - name: a
+    parent: b
+  - name: b
+    parent: a
+peers:
+  - name: upstream
+    ip_address: 192.0.2.1
+    remote_asn: 65002
+    password: hunter2
+    prefix_list_in: MISSING
+users:
+  - username: noc
+    role: superuser
+`))
+		require.ErrorIs(t, err, ErrInvalidDocument)
+		for _, problem := range []string{
+			"peer template parents form a cycle",
+			"peer 192.0.2.1: password must be a secret:// reference",
+			"peer 192.0.2.1 references undefined prefix-list MISSING",
+			"user noc: role must be admin, operator or user",
+		} {
+			assert.Contains(t, err.Error(), problem)
+		}
+	})
+
+	t.Run("JSON documents are accepted", func(t *testing.T) {
+		doc, err := Parse([]byte(`{"version": 1, "users": [{"username": "noc", "role": "user"}]}`))
+		require.NoError(t, err)
+		assert.Equal(t, "noc", doc.Users[0].Username)
+	})
+
+	t.Run("Empty documents are rejected", func(t *testing.T) {
+		_, err := Parse([]byte("\n"))
+		assert.ErrorIs(t, err, ErrInvalidDocument)
+	})
+}
+
+func TestDiffFields(t *testing.T) {
+	current := Webhook{URL: "https://a.example.com", Events: []string{"*"}, Active: boolPtr(true)}
+	desired := current
+	assert.Empty(t, diffFields(current, desired))
+
+	desired.Description = "alerts"
+	desired.Active = boolPtr(false)
+	assert.Equal(t, []string{"description", "active"}, diffFields(current, desired))
+
+	assert.Empty(t, diffFields(Peer{Tags: map[string]string{}}, Peer{}), "empty and missing maps are equal")
+	assert.Equal(t, models.CommunityListStandard, (&CommunityList{Name: "C"}).list().Type)
+}
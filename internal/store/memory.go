@@ -0,0 +1,158 @@
+package store
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// sweepInterval is how many writes pass between removals of expired keys
+const sweepInterval = 1000
+
+// Memory is a Store kept in process memory, for a single instance
+type Memory struct {
+	mu      sync.Mutex
+	entries map[string]*memoryEntry
+	writes  int
+}
+
+// memoryEntry is the value or list held by a key
+type memoryEntry struct {
+	value     []byte
+	list      [][]byte
+	expiresAt time.Time // zero when the key doesn't expire
+}
+
+// NewMemory creates an empty in-memory store
+func NewMemory() *Memory {
+	return &Memory{entries: make(map[string]*memoryEntry)}
+}
+
+// Get implements Store
+func (m *Memory) Get(ctx context.Context, key string) ([]byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	e := m.entry(key, time.Now())
+	if e == nil || e.value == nil {
+		return nil, ErrNotFound
+	}
+	return append([]byte(nil), e.value...), nil
+}
+
+// Set implements Store
+func (m *Memory) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	m.entries[key] = &memoryEntry{value: append([]byte{}, value...), expiresAt: expiry(now, ttl)}
+	m.wrote(now)
+	return nil
+}
+
+// Delete implements Store
+func (m *Memory) Delete(ctx context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.entries, key)
+	return nil
+}
+
+// Incr implements Store
+func (m *Memory) Incr(ctx context.Context, key string, ttl time.Duration) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	e := m.entry(key, now)
+	if e == nil {
+		e = &memoryEntry{expiresAt: expiry(now, ttl)}
+		m.entries[key] = e
+	}
+	var count int64
+	if e.value != nil {
+		var err error
+		if count, err = strconv.ParseInt(string(e.value), 10, 64); err != nil {
+			return 0, err
+		}
+	}
+	count++
+	e.value = strconv.AppendInt(nil, count, 10)
+	m.wrote(now)
+	return count, nil
+}
+
+// Push implements Store
+func (m *Memory) Push(ctx context.Context, key string, value []byte, limit int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	e := m.entry(key, now)
+	if e == nil {
+		e = &memoryEntry{}
+		m.entries[key] = e
+	}
+	e.list = append(e.list, append([]byte(nil), value...))
+	if limit > 0 && len(e.list) > limit {
+		e.list = append([][]byte(nil), e.list[len(e.list)-limit:]...)
+	}
+	m.wrote(now)
+	return nil
+}
+
+// Range implements Store
+func (m *Memory) Range(ctx context.Context, key string) ([][]byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	e := m.entry(key, time.Now())
+	if e == nil {
+		return nil, nil
+	}
+	return append([][]byte(nil), e.list...), nil
+}
+
+// Close implements Store
+func (m *Memory) Close() error {
+	return nil
+}
+
+// entry returns the unexpired entry of key, or nil. m.mu must be held.
+func (m *Memory) entry(key string, now time.Time) *memoryEntry {
+	e, ok := m.entries[key]
+	if !ok {
+		return nil
+	}
+	if !e.expiresAt.IsZero() && !now.Before(e.expiresAt) {
+		delete(m.entries, key)
+		return nil
+	}
+	return e
+}
+
+// wrote counts a write and removes expired keys every sweepInterval
+// writes, so keys that are never read again don't pile up. m.mu must be
+// held.
+func (m *Memory) wrote(now time.Time) {
+	m.writes++
+	if m.writes%sweepInterval != 0 {
+		return
+	}
+	for key, e := range m.entries {
+		if !e.expiresAt.IsZero() && !now.Before(e.expiresAt) {
+			delete(m.entries, key)
+		}
+	}
+}
+
+// expiry returns when a key written at now with ttl expires
+func expiry(now time.Time, ttl time.Duration) time.Time {
+	if ttl <= 0 {
+		return time.Time{}
+	}
+	return now.Add(ttl)
+}
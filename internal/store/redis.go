@@ -0,0 +1,122 @@
+package store
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// RedisConfig configures the Redis store
+type RedisConfig struct {
+	// Address is host:port of the Redis server
+	Address  string
+	Username string
+	Password string
+	DB       int
+	// KeyPrefix is put in front of every key, so several deployments can
+	// share a Redis server
+	KeyPrefix string
+	TLS       bool
+}
+
+// Redis is a Store kept in Redis, shared by every instance using the same
+// server and key prefix
+type Redis struct {
+	client *redis.Client
+	prefix string
+}
+
+// NewRedis connects to the Redis server described by cfg and checks that
+// it answers
+func NewRedis(cfg RedisConfig) (*Redis, error) {
+	if cfg.Address == "" {
+		return nil, errors.New("redis address is required")
+	}
+	opts := &redis.Options{
+		Addr:     cfg.Address,
+		Username: cfg.Username,
+		Password: cfg.Password,
+		DB:       cfg.DB,
+	}
+	if cfg.TLS {
+		opts.TLSConfig = &tls.Config{MinVersion: tls.VersionTLS12}
+	}
+	r := &Redis{client: redis.NewClient(opts), prefix: cfg.KeyPrefix}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := r.client.Ping(ctx).Err(); err != nil {
+		r.client.Close()
+		return nil, fmt.Errorf("failed to connect to redis at %s: %w", cfg.Address, err)
+	}
+	return r, nil
+}
+
+// Get implements Store
+func (r *Redis) Get(ctx context.Context, key string) ([]byte, error) {
+	value, err := r.client.Get(ctx, r.prefix+key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, ErrNotFound
+	}
+	return value, err
+}
+
+// Set implements Store
+func (r *Redis) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	return r.client.Set(ctx, r.prefix+key, value, max(ttl, 0)).Err()
+}
+
+// Delete implements Store
+func (r *Redis) Delete(ctx context.Context, key string) error {
+	return r.client.Del(ctx, r.prefix+key).Err()
+}
+
+// Incr implements Store
+func (r *Redis) Incr(ctx context.Context, key string, ttl time.Duration) (int64, error) {
+	key = r.prefix + key
+	count, err := r.client.Incr(ctx, key).Result()
+	if err != nil {
+		return 0, err
+	}
+	if count == 1 && ttl > 0 {
+		if err := r.client.Expire(ctx, key, ttl).Err(); err != nil {
+			return 0, err
+		}
+	}
+	return count, nil
+}
+
+// Push implements Store
+func (r *Redis) Push(ctx context.Context, key string, value []byte, limit int) error {
+	key = r.prefix + key
+	_, err := r.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.RPush(ctx, key, value)
+		if limit > 0 {
+			pipe.LTrim(ctx, key, int64(-limit), -1)
+		}
+		return nil
+	})
+	return err
+}
+
+// Range implements Store
+func (r *Redis) Range(ctx context.Context, key string) ([][]byte, error) {
+	values, err := r.client.LRange(ctx, r.prefix+key, 0, -1).Result()
+	if err != nil {
+		return nil, err
+	}
+	list := make([][]byte, len(values))
+	for i, value := range values {
+		list[i] = []byte(value)
+	}
+	return list, nil
+}
+
+// Close implements Store
+func (r *Redis) Close() error {
+	return r.client.Close()
+}
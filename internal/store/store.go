@@ -0,0 +1,59 @@
+// Package store holds short-lived state that instances of FlintRoute
+// running side by side must agree on, such as revoked access tokens,
+// request quota counters and the buffer of recent events. A single
+// instance keeps it in memory; several instances share it through Redis.
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Backends
+const (
+	BackendMemory = "memory"
+	BackendRedis  = "redis"
+)
+
+// ErrNotFound is returned by Get for a missing or expired key
+var ErrNotFound = errors.New("key not found")
+
+// Store is a key/value store with expiring keys. A key holds a value, a
+// counter or a list, depending on the methods used with it.
+type Store interface {
+	// Get returns the value of key
+	Get(ctx context.Context, key string) ([]byte, error)
+	// Set stores value under key, expiring after ttl; 0 keeps it
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	// Delete removes key
+	Delete(ctx context.Context, key string) error
+	// Incr adds one to the counter at key and returns the new count. A
+	// counter created by the call expires after ttl; 0 keeps it.
+	Incr(ctx context.Context, key string, ttl time.Duration) (int64, error)
+	// Push appends value to the list at key, keeping its last limit values
+	Push(ctx context.Context, key string, value []byte, limit int) error
+	// Range returns the values of the list at key, oldest first
+	Range(ctx context.Context, key string) ([][]byte, error)
+	// Close releases the store's connections
+	Close() error
+}
+
+// Config selects and configures a store
+type Config struct {
+	// Backend is BackendMemory (the default) or BackendRedis
+	Backend string
+	Redis   RedisConfig
+}
+
+// New creates the store selected by cfg
+func New(cfg Config) (Store, error) {
+	switch cfg.Backend {
+	case "", BackendMemory:
+		return NewMemory(), nil
+	case BackendRedis:
+		return NewRedis(cfg.Redis)
+	}
+	return nil, fmt.Errorf("unknown store backend %q", cfg.Backend)
+}
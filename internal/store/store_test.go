@@ -0,0 +1,117 @@
+package store
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// backends returns a fresh store of each backend, and a function moving
+// its clock forward so keys expire
+func backends(t *testing.T) map[string]func() (Store, func(time.Duration)) {
+	return map[string]func() (Store, func(time.Duration)){
+		"memory": func() (Store, func(time.Duration)) {
+			m := NewMemory()
+			return m, func(d time.Duration) {
+				m.mu.Lock()
+				defer m.mu.Unlock()
+				for _, e := range m.entries {
+					if !e.expiresAt.IsZero() {
+						e.expiresAt = e.expiresAt.Add(-d)
+					}
+				}
+			}
+		},
+		"redis": func() (Store, func(time.Duration)) {
+			server := miniredis.RunT(t)
+			r, err := NewRedis(RedisConfig{Address: server.Addr(), KeyPrefix: "flintroute:"})
+			require.NoError(t, err)
+			t.Cleanup(func() { r.Close() })
+			return r, server.FastForward
+		},
+	}
+}
+
+func TestStore(t *testing.T) {
+	ctx := context.Background()
+
+	for name, open := range backends(t) {
+		t.Run(name, func(t *testing.T) {
+			t.Run("Sets values until they expire", func(t *testing.T) {
+				s, advance := open()
+
+				_, err := s.Get(ctx, "token")
+				assert.ErrorIs(t, err, ErrNotFound)
+
+				require.NoError(t, s.Set(ctx, "token", []byte("a"), time.Minute))
+				require.NoError(t, s.Set(ctx, "kept", []byte("b"), 0))
+				value, err := s.Get(ctx, "token")
+				require.NoError(t, err)
+				assert.Equal(t, []byte("a"), value)
+
+				advance(2 * time.Minute)
+				_, err = s.Get(ctx, "token")
+				assert.ErrorIs(t, err, ErrNotFound)
+				value, err = s.Get(ctx, "kept")
+				require.NoError(t, err)
+				assert.Equal(t, []byte("b"), value)
+
+				require.NoError(t, s.Delete(ctx, "kept"))
+				_, err = s.Get(ctx, "kept")
+				assert.ErrorIs(t, err, ErrNotFound)
+			})
+
+			t.Run("Counts from the first increment's expiry", func(t *testing.T) {
+				s, advance := open()
+
+				for want := int64(1); want <= 3; want++ {
+					count, err := s.Incr(ctx, "quota", time.Hour)
+					require.NoError(t, err)
+					assert.Equal(t, want, count)
+					advance(20 * time.Minute)
+				}
+
+				// The third increment didn't extend the counter's life
+				advance(time.Minute)
+				count, err := s.Incr(ctx, "quota", time.Hour)
+				require.NoError(t, err)
+				assert.Equal(t, int64(1), count)
+
+				value, err := s.Get(ctx, "quota")
+				require.NoError(t, err)
+				assert.Equal(t, []byte("1"), value)
+			})
+
+			t.Run("Keeps the last values of a list", func(t *testing.T) {
+				s, _ := open()
+
+				list, err := s.Range(ctx, "events")
+				require.NoError(t, err)
+				assert.Empty(t, list)
+
+				for _, value := range []string{"1", "2", "3", "4"} {
+					require.NoError(t, s.Push(ctx, "events", []byte(value), 3))
+				}
+				list, err = s.Range(ctx, "events")
+				require.NoError(t, err)
+				assert.Equal(t, [][]byte{[]byte("2"), []byte("3"), []byte("4")}, list)
+			})
+		})
+	}
+}
+
+func TestNew(t *testing.T) {
+	s, err := New(Config{})
+	require.NoError(t, err)
+	assert.IsType(t, &Memory{}, s)
+
+	_, err = New(Config{Backend: BackendRedis})
+	assert.Error(t, err, "an address is required")
+
+	_, err = New(Config{Backend: "etcd"})
+	assert.Error(t, err)
+}
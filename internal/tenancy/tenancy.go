@@ -0,0 +1,60 @@
+// Package tenancy carries the tenant a request acts for and scopes queries
+// to it. Peers, alerts, config versions, jobs and change requests belong to
+// a tenant; a context without a tenant, such as a background task's or a
+// global admin's, sees every tenant.
+package tenancy
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+)
+
+// contextKey is the type of the context key holding the tenant ID
+type contextKey struct{}
+
+// WithTenant returns a copy of ctx acting for tenant id
+func WithTenant(ctx context.Context, id uint) context.Context {
+	return context.WithValue(ctx, contextKey{}, id)
+}
+
+// FromContext returns the tenant ctx acts for, if any
+func FromContext(ctx context.Context) (uint, bool) {
+	id, ok := ctx.Value(contextKey{}).(uint)
+	return id, ok
+}
+
+// ID returns the tenant to record on an object created in ctx; nil when
+// ctx doesn't act for a tenant
+func ID(ctx context.Context) *uint {
+	id, ok := FromContext(ctx)
+	if !ok {
+		return nil
+	}
+	return &id
+}
+
+// Scope restricts a query on table to the tenant of ctx. It leaves the
+// query alone when ctx doesn't act for a tenant.
+func Scope(ctx context.Context, table string) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		id, ok := FromContext(ctx)
+		if !ok {
+			return db
+		}
+		return db.Where(table+".tenant_id = ?", id)
+	}
+}
+
+// PeerScope restricts a query to rows whose column refers to a peer of the
+// tenant of ctx, for tables such as sessions that belong to a peer. It
+// leaves the query alone when ctx doesn't act for a tenant.
+func PeerScope(ctx context.Context, column string) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		id, ok := FromContext(ctx)
+		if !ok {
+			return db
+		}
+		return db.Where(column+" IN (SELECT id FROM bgp_peers WHERE tenant_id = ?)", id)
+	}
+}
@@ -6,7 +6,7 @@ import (
 	"testing"
 
 	"github.com/padminisys/flintroute/internal/database"
-	"github.com/padminisys/flintroute/internal/models"
+	"github.com/padminisys/flintroute/pkg/models"
 	"go.uber.org/zap"
 	"golang.org/x/crypto/bcrypt"
 	"gorm.io/driver/sqlite"
@@ -107,13 +107,21 @@ func SetupInMemoryDB(t *testing.T) *gorm.DB {
 	if err := db.AutoMigrate(
 		&models.User{},
 		&models.BGPPeer{},
+		&models.PeerTag{},
 		&models.BGPSession{},
 		&models.ConfigVersion{},
 		&models.Alert{},
 		&models.RefreshToken{},
+		&models.RoutingPolicy{},
+		&models.CommunityList{},
+		&models.ASPathList{},
+		&models.WebhookSubscription{},
+		&models.WebhookDelivery{},
+		&models.Job{},
+		&models.ChangeRequest{},
 	); err != nil {
 		t.Fatalf("Failed to migrate database: %v", err)
 	}
 
 	return db
-}
\ No newline at end of file
+}
@@ -0,0 +1,79 @@
+package tracing
+
+import (
+	"errors"
+
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.34.0"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+)
+
+// gormSpanKey is where a statement's span is kept between callbacks
+const gormSpanKey = "tracing:span"
+
+// GORMPlugin traces database statements. Only statements run with a context
+// carrying a span, i.e. db.WithContext(ctx) from a traced request or job,
+// get a span, so that background housekeeping doesn't flood the traces.
+func GORMPlugin() gorm.Plugin {
+	return gormPlugin{}
+}
+
+type gormPlugin struct{}
+
+func (gormPlugin) Name() string {
+	return "tracing"
+}
+
+func (gormPlugin) Initialize(db *gorm.DB) error {
+	cb := db.Callback()
+	return errors.Join(
+		cb.Create().Before("*").Register("tracing:before_create", startGORMSpan("create")),
+		cb.Create().After("*").Register("tracing:after_create", endGORMSpan),
+		cb.Query().Before("*").Register("tracing:before_query", startGORMSpan("query")),
+		cb.Query().After("*").Register("tracing:after_query", endGORMSpan),
+		cb.Update().Before("*").Register("tracing:before_update", startGORMSpan("update")),
+		cb.Update().After("*").Register("tracing:after_update", endGORMSpan),
+		cb.Delete().Before("*").Register("tracing:before_delete", startGORMSpan("delete")),
+		cb.Delete().After("*").Register("tracing:after_delete", endGORMSpan),
+		cb.Row().Before("*").Register("tracing:before_row", startGORMSpan("row")),
+		cb.Row().After("*").Register("tracing:after_row", endGORMSpan),
+		cb.Raw().Before("*").Register("tracing:before_raw", startGORMSpan("raw")),
+		cb.Raw().After("*").Register("tracing:after_raw", endGORMSpan),
+	)
+}
+
+// startGORMSpan starts the span of a statement
+func startGORMSpan(operation string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		ctx := db.Statement.Context
+		if ctx == nil || !trace.SpanContextFromContext(ctx).IsValid() {
+			return
+		}
+		_, span := Tracer().Start(ctx, "gorm."+operation,
+			trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(semconv.DBSystemNameSQLite, semconv.DBOperationName(operation)),
+		)
+		db.InstanceSet(gormSpanKey, span)
+	}
+}
+
+// endGORMSpan ends the span of a statement with its SQL and outcome
+func endGORMSpan(db *gorm.DB) {
+	value, ok := db.InstanceGet(gormSpanKey)
+	if !ok {
+		return
+	}
+	span := value.(trace.Span)
+	defer span.End()
+
+	if table := db.Statement.Table; table != "" {
+		span.SetAttributes(semconv.DBCollectionName(table))
+	}
+	// The SQL has placeholders, never the values bound to them
+	span.SetAttributes(semconv.DBQueryText(db.Statement.SQL.String()))
+	if err := db.Error; err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+}
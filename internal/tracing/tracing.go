@@ -0,0 +1,151 @@
+// Package tracing sets up OpenTelemetry tracing. The exporter is configured
+// with the standard OTEL_ environment variables; without them, spans are
+// created by a no-op tracer and cost next to nothing.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.34.0"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+// ServiceName is the service.name of FlintRoute spans unless OTEL_SERVICE_NAME
+// overrides it
+const ServiceName = "flintroute"
+
+// instrumentationName names the tracer of FlintRoute's own spans
+const instrumentationName = "github.com/padminisys/flintroute"
+
+// Tracer returns the tracer FlintRoute's own spans are started with
+func Tracer() trace.Tracer {
+	return otel.Tracer(instrumentationName)
+}
+
+// Setup installs the global tracer provider and W3C trace context
+// propagation. OTEL_TRACES_EXPORTER picks the exporter:
+//
+//   - otlp: OTLP over HTTP, configured with OTEL_EXPORTER_OTLP_ENDPOINT,
+//     OTEL_EXPORTER_OTLP_HEADERS and the other OTEL_EXPORTER_OTLP_ variables
+//   - console: pretty-printed JSON on stdout, for debugging
+//   - none: no export
+//
+// When it is unset, otlp is used if an OTLP endpoint is set and none
+// otherwise. OTEL_SDK_DISABLED=true disables tracing. Sampling follows
+// OTEL_TRACES_SAMPLER and resource attributes OTEL_SERVICE_NAME and
+// OTEL_RESOURCE_ATTRIBUTES.
+//
+// The returned function flushes pending spans and must be called on
+// shutdown.
+func Setup(ctx context.Context, logger *zap.Logger) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+
+	exporterName := exporterFromEnv()
+	var exporter sdktrace.SpanExporter
+	var err error
+	switch exporterName {
+	case "none":
+		logger.Info("Tracing disabled")
+		return func(context.Context) error { return nil }, nil
+	case "otlp":
+		if protocol := otlpProtocol(); protocol != "http/protobuf" {
+			return nil, fmt.Errorf("unsupported OTLP protocol %q (only http/protobuf is supported)", protocol)
+		}
+		exporter, err = otlptracehttp.New(ctx)
+	case "console":
+		exporter, err = stdouttrace.New(stdouttrace.WithPrettyPrint())
+	default:
+		return nil, fmt.Errorf("unsupported OTEL_TRACES_EXPORTER %q (must be otlp, console or none)", exporterName)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %s trace exporter: %w", exporterName, err)
+	}
+
+	// Attributes from the environment come last so that they win
+	res, err := resource.New(ctx,
+		resource.WithTelemetrySDK(),
+		resource.WithHost(),
+		resource.WithAttributes(semconv.ServiceName(ServiceName)),
+		resource.WithFromEnv(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to detect trace resource: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+	)
+	otel.SetTracerProvider(provider)
+	otel.SetErrorHandler(otel.ErrorHandlerFunc(func(err error) {
+		logger.Warn("Tracing error", zap.Error(err))
+	}))
+
+	logger.Info("Tracing enabled", zap.String("exporter", exporterName))
+	return provider.Shutdown, nil
+}
+
+// exporterFromEnv returns the exporter named by the OTEL_ variables
+func exporterFromEnv() string {
+	if strings.EqualFold(os.Getenv("OTEL_SDK_DISABLED"), "true") {
+		return "none"
+	}
+	if name := strings.TrimSpace(os.Getenv("OTEL_TRACES_EXPORTER")); name != "" {
+		return strings.ToLower(name)
+	}
+	if os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT") != "" || os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT") != "" {
+		return "otlp"
+	}
+	return "none"
+}
+
+// otlpProtocol returns the OTLP protocol of traces, http/protobuf by default
+func otlpProtocol() string {
+	for _, key := range []string{"OTEL_EXPORTER_OTLP_TRACES_PROTOCOL", "OTEL_EXPORTER_OTLP_PROTOCOL"} {
+		if protocol := os.Getenv(key); protocol != "" {
+			return protocol
+		}
+	}
+	return "http/protobuf"
+}
+
+// Field returns a zap field with the trace ID of the span carried by ctx, so
+// that log lines can be matched with their trace. It is skipped when there
+// is no sampled span.
+func Field(ctx context.Context) zap.Field {
+	spanContext := trace.SpanContextFromContext(ctx)
+	if !spanContext.IsSampled() {
+		return zap.Skip()
+	}
+	return zap.String("trace_id", spanContext.TraceID().String())
+}
+
+// Inject returns the W3C traceparent of the span carried by ctx, or an
+// empty string, for work handed over through the database
+func Inject(ctx context.Context) string {
+	carrier := propagation.MapCarrier{}
+	propagation.TraceContext{}.Inject(ctx, carrier)
+	return carrier.Get("traceparent")
+}
+
+// Extract returns ctx carrying the remote span of traceparent, so that spans
+// started from it continue the trace Inject was called in
+func Extract(ctx context.Context, traceparent string) context.Context {
+	if traceparent == "" {
+		return ctx
+	}
+	return propagation.TraceContext{}.Extract(ctx, propagation.MapCarrier{"traceparent": traceparent})
+}
@@ -0,0 +1,124 @@
+package tracing
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// recordSpans installs a tracer provider recording every span for the test
+func recordSpans(t *testing.T) *tracetest.SpanRecorder {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(provider)
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+	return recorder
+}
+
+func TestExporterFromEnv(t *testing.T) {
+	t.Run("Disabled without an endpoint", func(t *testing.T) {
+		t.Setenv("OTEL_TRACES_EXPORTER", "")
+		t.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", "")
+		t.Setenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT", "")
+		assert.Equal(t, "none", exporterFromEnv())
+	})
+
+	t.Run("OTLP with an endpoint", func(t *testing.T) {
+		t.Setenv("OTEL_TRACES_EXPORTER", "")
+		t.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", "http://collector:4318")
+		assert.Equal(t, "otlp", exporterFromEnv())
+	})
+
+	t.Run("Explicit exporter", func(t *testing.T) {
+		t.Setenv("OTEL_TRACES_EXPORTER", "Console")
+		assert.Equal(t, "console", exporterFromEnv())
+	})
+
+	t.Run("SDK disabled", func(t *testing.T) {
+		t.Setenv("OTEL_SDK_DISABLED", "true")
+		t.Setenv("OTEL_TRACES_EXPORTER", "otlp")
+		assert.Equal(t, "none", exporterFromEnv())
+	})
+}
+
+func TestSetup(t *testing.T) {
+	t.Run("Rejects unknown exporters", func(t *testing.T) {
+		t.Setenv("OTEL_TRACES_EXPORTER", "zipkin")
+		_, err := Setup(context.Background(), zap.NewNop())
+		assert.Error(t, err)
+	})
+
+	t.Run("Rejects OTLP over gRPC", func(t *testing.T) {
+		t.Setenv("OTEL_TRACES_EXPORTER", "otlp")
+		t.Setenv("OTEL_EXPORTER_OTLP_PROTOCOL", "grpc")
+		_, err := Setup(context.Background(), zap.NewNop())
+		assert.Error(t, err)
+	})
+}
+
+func TestInjectExtract(t *testing.T) {
+	recordSpans(t)
+
+	ctx, span := Tracer().Start(context.Background(), "request")
+	traceparent := Inject(ctx)
+	span.End()
+	require.NotEmpty(t, traceparent)
+
+	_, child := Tracer().Start(Extract(context.Background(), traceparent), "job")
+	defer child.End()
+	assert.Equal(t, span.SpanContext().TraceID(), child.SpanContext().TraceID())
+
+	assert.Empty(t, Inject(context.Background()))
+	assert.Equal(t, context.Background(), Extract(context.Background(), ""))
+}
+
+func TestGORMPlugin(t *testing.T) {
+	recorder := recordSpans(t)
+
+	type Peer struct {
+		ID   uint
+		Name string
+	}
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	require.NoError(t, err)
+	require.NoError(t, db.Use(GORMPlugin()))
+	require.NoError(t, db.AutoMigrate(&Peer{}))
+
+	// Without a span in the context nothing is recorded
+	require.NoError(t, db.Create(&Peer{Name: "untraced"}).Error)
+	assert.Empty(t, recorder.Ended())
+
+	ctx, span := Tracer().Start(context.Background(), "request")
+	require.NoError(t, db.WithContext(ctx).Create(&Peer{Name: "traced"}).Error)
+	var peer Peer
+	err = db.WithContext(ctx).Where("name = ?", "missing").First(&peer).Error
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+	span.End()
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 3)
+	assert.Equal(t, "gorm.create", spans[0].Name())
+	assert.Equal(t, "gorm.query", spans[1].Name())
+	assert.Equal(t, span.SpanContext().SpanID(), spans[0].Parent().SpanID())
+	assert.Equal(t, trace.SpanKindClient, spans[0].SpanKind())
+
+	attributes := map[string]string{}
+	for _, kv := range spans[1].Attributes() {
+		attributes[string(kv.Key)] = kv.Value.Emit()
+	}
+	assert.Equal(t, "peers", attributes["db.collection.name"])
+	assert.Contains(t, attributes["db.query.text"], "WHERE name = ?")
+	// A missing record is not an error
+	assert.Empty(t, spans[1].Events())
+}
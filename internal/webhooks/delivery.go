@@ -0,0 +1,311 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/padminisys/flintroute/internal/requestid"
+	"github.com/padminisys/flintroute/pkg/models"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// Headers set on every delivery
+const (
+	HeaderEvent     = "X-FlintRoute-Event"
+	HeaderDelivery  = "X-FlintRoute-Delivery"
+	HeaderSignature = "X-FlintRoute-Signature-256"
+)
+
+// deliveryBatchSize limits how many due deliveries are sent per pass
+const deliveryBatchSize = 100
+
+// Event is the JSON body delivered to webhook endpoints
+type Event struct {
+	ID        string      `json:"id"`
+	Type      string      `json:"type"`
+	CreatedAt time.Time   `json:"created_at"`
+	RequestID string      `json:"request_id,omitempty"`
+	Data      interface{} `json:"data"`
+}
+
+// Sign returns the signature header value for a payload: the hex-encoded
+// HMAC-SHA256 of the body, prefixed with "sha256="
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Publish queues an event for every active subscription whose filters
+// match it. Delivery happens in the background; failures are logged and
+// never returned to the caller. Publishing on a nil service is a no-op.
+func (s *Service) Publish(ctx context.Context, event string, data interface{}) {
+	if s == nil {
+		return
+	}
+
+	var subs []*models.WebhookSubscription
+	if err := s.db.Where("active = ?", true).Find(&subs).Error; err != nil {
+		s.logger.Error("Failed to load webhook subscriptions", zap.Error(err))
+		return
+	}
+
+	var matched []*models.WebhookSubscription
+	for _, sub := range subs {
+		if Matches(sub.Events, event) {
+			matched = append(matched, sub)
+		}
+	}
+	if len(matched) == 0 {
+		return
+	}
+
+	if _, err := s.enqueue(ctx, matched, event, data); err != nil {
+		s.logger.Error("Failed to queue webhook event",
+			zap.String("event", event),
+			zap.Error(err),
+			requestid.Field(ctx),
+		)
+	}
+}
+
+// Ping queues a ping event for a single subscription, regardless of its
+// filters, so the endpoint can be tested
+func (s *Service) Ping(ctx context.Context, id uint) (*models.WebhookDelivery, error) {
+	sub, err := s.GetSubscription(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	deliveries, err := s.enqueue(ctx, []*models.WebhookSubscription{sub}, EventPing, map[string]interface{}{"subscription_id": sub.ID})
+	if err != nil {
+		return nil, err
+	}
+	return deliveries[0], nil
+}
+
+// enqueue stores a pending delivery of the event for each subscription
+func (s *Service) enqueue(ctx context.Context, subs []*models.WebhookSubscription, event string, data interface{}) ([]*models.WebhookDelivery, error) {
+	id, err := newEventID()
+	if err != nil {
+		return nil, err
+	}
+
+	payload, err := json.Marshal(Event{
+		ID:        id,
+		Type:      event,
+		CreatedAt: time.Now().UTC(),
+		RequestID: requestid.FromContext(ctx),
+		Data:      data,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode webhook event: %w", err)
+	}
+
+	now := time.Now()
+	deliveries := make([]*models.WebhookDelivery, 0, len(subs))
+	for _, sub := range subs {
+		delivery := &models.WebhookDelivery{
+			SubscriptionID: sub.ID,
+			EventID:        id,
+			Event:          event,
+			Payload:        string(payload),
+			Status:         models.DeliveryPending,
+			NextAttemptAt:  &now,
+		}
+		if err := s.db.Create(delivery).Error; err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, delivery)
+	}
+
+	s.notify()
+	return deliveries, nil
+}
+
+// Redeliver queues a new delivery with the same payload as an earlier one
+func (s *Service) Redeliver(ctx context.Context, deliveryID uint) (*models.WebhookDelivery, error) {
+	var original models.WebhookDelivery
+	if err := s.db.First(&original, deliveryID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrDeliveryNotFound
+		}
+		return nil, err
+	}
+	if _, err := s.GetSubscription(ctx, original.SubscriptionID); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	delivery := models.WebhookDelivery{
+		SubscriptionID: original.SubscriptionID,
+		EventID:        original.EventID,
+		Event:          original.Event,
+		Payload:        original.Payload,
+		Status:         models.DeliveryPending,
+		NextAttemptAt:  &now,
+	}
+	if err := s.db.Create(&delivery).Error; err != nil {
+		return nil, fmt.Errorf("failed to queue redelivery: %w", err)
+	}
+
+	s.notify()
+	return &delivery, nil
+}
+
+// newEventID returns a random identifier shared by all deliveries of an event
+func newEventID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// notify wakes the delivery loop without blocking
+func (s *Service) notify() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// DeliverDue sends every pending delivery whose next attempt is due and
+// returns how many were attempted
+func (s *Service) DeliverDue(ctx context.Context) (int, error) {
+	var deliveries []*models.WebhookDelivery
+	if err := s.db.Where("status = ? AND next_attempt_at <= ?", models.DeliveryPending, time.Now()).
+		Order("id").Limit(deliveryBatchSize).Find(&deliveries).Error; err != nil {
+		return 0, err
+	}
+
+	for _, delivery := range deliveries {
+		s.attempt(ctx, delivery)
+	}
+	return len(deliveries), nil
+}
+
+// attempt sends a delivery once and records the outcome, scheduling a
+// retry with exponential backoff until MaxAttempts is reached
+func (s *Service) attempt(ctx context.Context, delivery *models.WebhookDelivery) {
+	var sub models.WebhookSubscription
+	if err := s.db.First(&sub, delivery.SubscriptionID).Error; err != nil {
+		s.finish(delivery, models.DeliveryFailed, 0, "subscription deleted")
+		return
+	}
+
+	delivery.Attempts++
+	status, err := s.send(ctx, &sub, delivery)
+	if err == nil {
+		s.finish(delivery, models.DeliverySucceeded, status, "")
+		s.logger.Debug("Delivered webhook",
+			zap.Uint("subscription_id", sub.ID),
+			zap.String("event", delivery.Event),
+		)
+		return
+	}
+
+	if delivery.Attempts >= s.config.MaxAttempts {
+		s.finish(delivery, models.DeliveryFailed, status, err.Error())
+		s.logger.Warn("Webhook delivery failed permanently",
+			zap.Uint("subscription_id", sub.ID),
+			zap.String("event", delivery.Event),
+			zap.Int("attempts", delivery.Attempts),
+			zap.Error(err),
+		)
+		return
+	}
+
+	next := time.Now().Add(s.config.RetryBackoff << (delivery.Attempts - 1))
+	delivery.ResponseStatus = status
+	delivery.LastError = err.Error()
+	delivery.NextAttemptAt = &next
+	if err := s.db.Save(delivery).Error; err != nil {
+		s.logger.Error("Failed to record webhook delivery", zap.Error(err))
+	}
+}
+
+// finish records a delivery's final state
+func (s *Service) finish(delivery *models.WebhookDelivery, status string, responseStatus int, lastError string) {
+	delivery.Status = status
+	delivery.ResponseStatus = responseStatus
+	delivery.LastError = lastError
+	delivery.NextAttemptAt = nil
+	if status == models.DeliverySucceeded {
+		now := time.Now()
+		delivery.DeliveredAt = &now
+	}
+
+	if err := s.db.Save(delivery).Error; err != nil {
+		s.logger.Error("Failed to record webhook delivery", zap.Error(err))
+	}
+}
+
+// send posts a delivery's payload, returning the response status. Any
+// non-2xx response is an error.
+func (s *Service) send(ctx context.Context, sub *models.WebhookSubscription, delivery *models.WebhookDelivery) (int, error) {
+	secret, err := s.cipher.Decrypt(sub.Secret)
+	if err != nil {
+		return 0, fmt.Errorf("failed to decrypt webhook secret: %w", err)
+	}
+
+	body := []byte(delivery.Payload)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, sub.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "FlintRoute-Webhooks")
+	req.Header.Set(HeaderEvent, delivery.Event)
+	req.Header.Set(HeaderDelivery, delivery.EventID)
+	req.Header.Set(HeaderSignature, Sign(secret, body))
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("endpoint returned %s", resp.Status)
+	}
+	return resp.StatusCode, nil
+}
+
+// Start delivers due webhooks every interval and whenever new events are
+// queued
+func (s *Service) Start(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	s.logger.Info("Started webhook delivery",
+		zap.Int("max_attempts", s.config.MaxAttempts),
+		zap.Duration("retry_backoff", s.config.RetryBackoff),
+	)
+
+	for {
+		select {
+		case <-ctx.Done():
+			s.logger.Info("Stopped webhook delivery")
+			return
+		case <-ticker.C:
+		case <-s.wake:
+		}
+
+		if _, err := s.DeliverDue(ctx); err != nil {
+			s.logger.Error("Failed to deliver webhooks", zap.Error(err))
+		}
+	}
+}
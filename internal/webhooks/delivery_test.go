@@ -0,0 +1,183 @@
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/padminisys/flintroute/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSign(t *testing.T) {
+	// Reference value from `printf '{}' | openssl dgst -sha256 -hmac s3cret`
+	assert.Equal(t,
+		"sha256=adbde1ce40c89c14215687d5d762a47df6dfaefcfad61e2e86718ffc8498571b",
+		Sign("s3cret", []byte("{}")))
+}
+
+func TestPublish(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Queues matching active subscriptions only", func(t *testing.T) {
+		s := setupTestService(t)
+		peers := createTestSubscription(t, s, "https://hooks.example.com/peers", "peer.*")
+		createTestSubscription(t, s, "https://hooks.example.com/config", "config.*")
+		inactive := &models.WebhookSubscription{URL: "https://hooks.example.com/off", Events: []string{"*"}}
+		require.NoError(t, s.CreateSubscription(ctx, inactive, "s3cret"))
+
+		s.Publish(ctx, EventPeerCreated, map[string]interface{}{"id": 7})
+
+		var deliveries []*models.WebhookDelivery
+		require.NoError(t, s.db.Find(&deliveries).Error)
+		require.Len(t, deliveries, 1)
+		assert.Equal(t, peers.ID, deliveries[0].SubscriptionID)
+		assert.Equal(t, models.DeliveryPending, deliveries[0].Status)
+
+		var event Event
+		require.NoError(t, json.Unmarshal([]byte(deliveries[0].Payload), &event))
+		assert.Equal(t, EventPeerCreated, event.Type)
+		assert.Equal(t, deliveries[0].EventID, event.ID)
+	})
+
+	t.Run("Nil service is a no-op", func(t *testing.T) {
+		var s *Service
+		assert.NotPanics(t, func() { s.Publish(ctx, EventPeerCreated, nil) })
+	})
+}
+
+func TestDeliverDue(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Signs and delivers events", func(t *testing.T) {
+		var received atomic.Int32
+		endpoint := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, _ := io.ReadAll(r.Body)
+			assert.Equal(t, Sign("s3cret", body), r.Header.Get(HeaderSignature))
+			assert.Equal(t, EventPeerDeleted, r.Header.Get(HeaderEvent))
+			assert.NotEmpty(t, r.Header.Get(HeaderDelivery))
+			received.Add(1)
+		}))
+		defer endpoint.Close()
+
+		s := setupTestService(t)
+		sub := createTestSubscription(t, s, endpoint.URL, EventPeerDeleted)
+		s.Publish(ctx, EventPeerDeleted, map[string]interface{}{"id": 1})
+
+		n, err := s.DeliverDue(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, 1, n)
+		assert.Equal(t, int32(1), received.Load())
+
+		deliveries, err := s.ListDeliveries(ctx, sub.ID, models.DeliverySucceeded, 10)
+		require.NoError(t, err)
+		require.Len(t, deliveries, 1)
+		assert.Equal(t, 1, deliveries[0].Attempts)
+		assert.Equal(t, http.StatusOK, deliveries[0].ResponseStatus)
+		assert.NotNil(t, deliveries[0].DeliveredAt)
+		assert.Nil(t, deliveries[0].NextAttemptAt)
+	})
+
+	t.Run("Retries until the endpoint succeeds", func(t *testing.T) {
+		var calls atomic.Int32
+		endpoint := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if calls.Add(1) == 1 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+			}
+		}))
+		defer endpoint.Close()
+
+		s := setupTestService(t)
+		sub := createTestSubscription(t, s, endpoint.URL, "*")
+		s.Publish(ctx, EventConfigRestored, nil)
+
+		_, err := s.DeliverDue(ctx)
+		require.NoError(t, err)
+
+		deliveries, err := s.ListDeliveries(ctx, sub.ID, "", 10)
+		require.NoError(t, err)
+		require.Len(t, deliveries, 1)
+		assert.Equal(t, models.DeliveryPending, deliveries[0].Status)
+		assert.Equal(t, http.StatusServiceUnavailable, deliveries[0].ResponseStatus)
+		assert.NotEmpty(t, deliveries[0].LastError)
+
+		require.Eventually(t, func() bool {
+			s.DeliverDue(ctx)
+			deliveries, err = s.ListDeliveries(ctx, sub.ID, models.DeliverySucceeded, 10)
+			return err == nil && len(deliveries) == 1
+		}, time.Second, 5*time.Millisecond)
+		assert.Equal(t, 2, deliveries[0].Attempts)
+	})
+
+	t.Run("Fails after max attempts", func(t *testing.T) {
+		var calls atomic.Int32
+		endpoint := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			calls.Add(1)
+			w.WriteHeader(http.StatusInternalServerError)
+		}))
+		defer endpoint.Close()
+
+		s := setupTestService(t)
+		sub := createTestSubscription(t, s, endpoint.URL, "*")
+		s.Publish(ctx, EventPeerUpdated, nil)
+
+		require.Eventually(t, func() bool {
+			s.DeliverDue(ctx)
+			deliveries, err := s.ListDeliveries(ctx, sub.ID, models.DeliveryFailed, 10)
+			return err == nil && len(deliveries) == 1
+		}, time.Second, 5*time.Millisecond)
+		assert.Equal(t, int32(3), calls.Load())
+	})
+}
+
+func TestPing(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Ignores event filters", func(t *testing.T) {
+		s := setupTestService(t)
+		sub := createTestSubscription(t, s, "https://hooks.example.com", EventPeerCreated)
+
+		delivery, err := s.Ping(ctx, sub.ID)
+		require.NoError(t, err)
+		assert.Equal(t, EventPing, delivery.Event)
+		assert.Equal(t, sub.ID, delivery.SubscriptionID)
+	})
+
+	t.Run("Missing subscription", func(t *testing.T) {
+		s := setupTestService(t)
+
+		_, err := s.Ping(ctx, 99)
+		assert.ErrorIs(t, err, ErrSubscriptionNotFound)
+	})
+}
+
+func TestRedeliver(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Queues the same payload again", func(t *testing.T) {
+		s := setupTestService(t)
+		sub := createTestSubscription(t, s, "https://hooks.example.com", "*")
+		original, err := s.Ping(ctx, sub.ID)
+		require.NoError(t, err)
+
+		redelivery, err := s.Redeliver(ctx, original.ID)
+		require.NoError(t, err)
+		assert.NotEqual(t, original.ID, redelivery.ID)
+		assert.Equal(t, original.EventID, redelivery.EventID)
+		assert.Equal(t, original.Payload, redelivery.Payload)
+		assert.Equal(t, models.DeliveryPending, redelivery.Status)
+	})
+
+	t.Run("Missing delivery", func(t *testing.T) {
+		s := setupTestService(t)
+
+		_, err := s.Redeliver(ctx, 99)
+		assert.ErrorIs(t, err, ErrDeliveryNotFound)
+	})
+}
@@ -0,0 +1,290 @@
+package webhooks
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/padminisys/flintroute/internal/database"
+	"github.com/padminisys/flintroute/internal/encryption"
+	"github.com/padminisys/flintroute/pkg/models"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// Lifecycle events delivered to webhook subscriptions
+const (
+	EventPeerCreated         = "peer.created"
+	EventPeerUpdated         = "peer.updated"
+	EventPeerDeleted         = "peer.deleted"
+	EventPeerRestored        = "peer.restored"
+	EventSessionStateChanged = "session.state_changed"
+	EventConfigBackedUp      = "config.backed_up"
+	EventConfigRestored      = "config.restored"
+	EventChangeRequested     = "change.requested"
+	EventChangeReviewed      = "change.reviewed"
+	EventAlertDigest         = "alert.digest"
+	EventPing                = "ping"
+)
+
+// Events lists the events subscriptions can filter on
+var Events = []string{
+	EventPeerCreated,
+	EventPeerUpdated,
+	EventPeerDeleted,
+	EventPeerRestored,
+	EventSessionStateChanged,
+	EventConfigBackedUp,
+	EventConfigRestored,
+	EventChangeRequested,
+	EventChangeReviewed,
+	EventAlertDigest,
+}
+
+var (
+	ErrSubscriptionNotFound = errors.New("webhook subscription not found")
+	ErrDeliveryNotFound     = errors.New("webhook delivery not found")
+	ErrInvalidSubscription  = errors.New("invalid webhook subscription")
+)
+
+// Config holds tunable delivery behaviour
+type Config struct {
+	// MaxAttempts is how many times a delivery is tried before it is
+	// marked as failed
+	MaxAttempts int
+	// Timeout bounds a single delivery request
+	Timeout time.Duration
+	// RetryBackoff is the delay before the first retry; it doubles with
+	// every further attempt
+	RetryBackoff time.Duration
+}
+
+// Service manages webhook subscriptions and delivers events to them
+type Service struct {
+	db         *database.DB
+	cipher     *encryption.Cipher
+	httpClient *http.Client
+	config     Config
+	logger     *zap.Logger
+
+	wake chan struct{}
+}
+
+// NewService creates a new webhook service. Subscription secrets are
+// encrypted at rest with cipher.
+func NewService(db *database.DB, cipher *encryption.Cipher, cfg Config, logger *zap.Logger) *Service {
+	if cfg.MaxAttempts <= 0 {
+		cfg.MaxAttempts = 5
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 10 * time.Second
+	}
+	if cfg.RetryBackoff <= 0 {
+		cfg.RetryBackoff = 30 * time.Second
+	}
+
+	return &Service{
+		db:         db,
+		cipher:     cipher,
+		httpClient: &http.Client{Timeout: cfg.Timeout},
+		config:     cfg,
+		logger:     logger,
+		wake:       make(chan struct{}, 1),
+	}
+}
+
+// Matches reports whether an event name matches a subscription filter.
+// Filters are exact event names, "prefix.*" or "*".
+func Matches(filters []string, event string) bool {
+	for _, filter := range filters {
+		switch {
+		case filter == "*" || filter == event:
+			return true
+		case strings.HasSuffix(filter, ".*") && strings.HasPrefix(event, strings.TrimSuffix(filter, "*")):
+			return true
+		}
+	}
+	return false
+}
+
+// validate checks a subscription's URL and event filters
+func validate(sub *models.WebhookSubscription) error {
+	u, err := url.Parse(sub.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("%w: url must be an absolute http or https URL", ErrInvalidSubscription)
+	}
+
+	if len(sub.Events) == 0 {
+		return fmt.Errorf("%w: at least one event is required", ErrInvalidSubscription)
+	}
+	for _, filter := range sub.Events {
+		if filter == "*" {
+			continue
+		}
+		known := false
+		for _, event := range Events {
+			if Matches([]string{filter}, event) {
+				known = true
+				break
+			}
+		}
+		if !known {
+			return fmt.Errorf("%w: unknown event %q", ErrInvalidSubscription, filter)
+		}
+	}
+	return nil
+}
+
+// GenerateSecret returns a random signing secret
+func GenerateSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// ListSubscriptions retrieves all webhook subscriptions
+func (s *Service) ListSubscriptions(ctx context.Context) ([]*models.WebhookSubscription, error) {
+	var subs []*models.WebhookSubscription
+	if err := s.db.Order("id").Find(&subs).Error; err != nil {
+		return nil, err
+	}
+	return subs, nil
+}
+
+// GetSubscription retrieves a webhook subscription by ID
+func (s *Service) GetSubscription(ctx context.Context, id uint) (*models.WebhookSubscription, error) {
+	var sub models.WebhookSubscription
+	if err := s.db.First(&sub, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrSubscriptionNotFound
+		}
+		return nil, err
+	}
+	return &sub, nil
+}
+
+// CreateSubscription stores a new subscription. secret is expected in
+// plaintext and is encrypted before being stored.
+func (s *Service) CreateSubscription(ctx context.Context, sub *models.WebhookSubscription, secret string) error {
+	if err := validate(sub); err != nil {
+		return err
+	}
+	if err := s.setSecret(sub, secret); err != nil {
+		return err
+	}
+
+	active := sub.Active
+	if err := s.db.Create(sub).Error; err != nil {
+		return fmt.Errorf("failed to create webhook subscription: %w", err)
+	}
+	// GORM skips zero values for columns with defaults
+	if !active {
+		if err := s.db.Model(sub).Update("active", false).Error; err != nil {
+			return fmt.Errorf("failed to create webhook subscription: %w", err)
+		}
+	}
+
+	s.logger.Info("Created webhook subscription",
+		zap.Uint("id", sub.ID),
+		zap.String("url", sub.URL),
+		zap.Strings("events", sub.Events),
+	)
+
+	return nil
+}
+
+// UpdateSubscription replaces a subscription's URL, description, events
+// and active flag. A non-nil secret rotates the signing secret.
+func (s *Service) UpdateSubscription(ctx context.Context, id uint, updates *models.WebhookSubscription, secret *string) (*models.WebhookSubscription, error) {
+	sub, err := s.GetSubscription(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	sub.URL = updates.URL
+	sub.Description = updates.Description
+	sub.Events = updates.Events
+	sub.Active = updates.Active
+	if err := validate(sub); err != nil {
+		return nil, err
+	}
+	if secret != nil {
+		if err := s.setSecret(sub, *secret); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.db.Save(sub).Error; err != nil {
+		return nil, fmt.Errorf("failed to update webhook subscription: %w", err)
+	}
+
+	s.logger.Info("Updated webhook subscription", zap.Uint("id", id))
+
+	return sub, nil
+}
+
+// DeleteSubscription deletes a subscription. Its pending deliveries are
+// abandoned.
+func (s *Service) DeleteSubscription(ctx context.Context, id uint) error {
+	sub, err := s.GetSubscription(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if err := s.db.Delete(sub).Error; err != nil {
+		return fmt.Errorf("failed to delete webhook subscription: %w", err)
+	}
+
+	s.db.Model(&models.WebhookDelivery{}).
+		Where("subscription_id = ? AND status = ?", id, models.DeliveryPending).
+		Updates(map[string]interface{}{
+			"status":          models.DeliveryFailed,
+			"last_error":      "subscription deleted",
+			"next_attempt_at": nil,
+		})
+
+	s.logger.Info("Deleted webhook subscription", zap.Uint("id", id))
+
+	return nil
+}
+
+// ListDeliveries retrieves the most recent deliveries for a subscription,
+// optionally filtered by status
+func (s *Service) ListDeliveries(ctx context.Context, subscriptionID uint, status string, limit int) ([]*models.WebhookDelivery, error) {
+	if _, err := s.GetSubscription(ctx, subscriptionID); err != nil {
+		return nil, err
+	}
+
+	query := s.db.Where("subscription_id = ?", subscriptionID).Order("id DESC").Limit(limit)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	var deliveries []*models.WebhookDelivery
+	if err := query.Find(&deliveries).Error; err != nil {
+		return nil, err
+	}
+	return deliveries, nil
+}
+
+// setSecret encrypts and assigns a subscription's signing secret
+func (s *Service) setSecret(sub *models.WebhookSubscription, secret string) error {
+	if secret == "" {
+		return fmt.Errorf("%w: secret must not be empty", ErrInvalidSubscription)
+	}
+
+	encrypted, err := s.cipher.Encrypt(secret)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt webhook secret: %w", err)
+	}
+	sub.Secret = encrypted
+	return nil
+}
@@ -0,0 +1,167 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/padminisys/flintroute/internal/encryption"
+	"github.com/padminisys/flintroute/internal/testutil"
+	"github.com/padminisys/flintroute/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func setupTestService(t *testing.T) *Service {
+	t.Helper()
+
+	cipher, err := encryption.NewCipher(bytes.Repeat([]byte{0x42}, encryption.KeySize))
+	require.NoError(t, err)
+
+	return NewService(testutil.SetupTestDB(t), cipher, Config{
+		MaxAttempts:  3,
+		Timeout:      time.Second,
+		RetryBackoff: time.Millisecond,
+	}, zap.NewNop())
+}
+
+func createTestSubscription(t *testing.T, s *Service, url string, events ...string) *models.WebhookSubscription {
+	t.Helper()
+
+	sub := &models.WebhookSubscription{URL: url, Events: events, Active: true}
+	require.NoError(t, s.CreateSubscription(context.Background(), sub, "s3cret"))
+	return sub
+}
+
+func TestMatches(t *testing.T) {
+	tests := []struct {
+		name    string
+		filters []string
+		event   string
+		want    bool
+	}{
+		{"Exact match", []string{EventPeerCreated}, EventPeerCreated, true},
+		{"Exact mismatch", []string{EventPeerCreated}, EventPeerDeleted, false},
+		{"Prefix wildcard", []string{"peer.*"}, EventPeerDeleted, true},
+		{"Prefix wildcard mismatch", []string{"peer.*"}, EventConfigRestored, false},
+		{"Match all", []string{"*"}, EventSessionStateChanged, true},
+		{"Any filter matches", []string{"config.*", EventPeerUpdated}, EventPeerUpdated, true},
+		{"No filters", nil, EventPeerCreated, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, Matches(tt.filters, tt.event))
+		})
+	}
+}
+
+func TestCreateSubscription(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Encrypts secret", func(t *testing.T) {
+		s := setupTestService(t)
+		sub := createTestSubscription(t, s, "https://hooks.example.com/flintroute", "peer.*")
+
+		stored, err := s.GetSubscription(ctx, sub.ID)
+		require.NoError(t, err)
+		assert.True(t, encryption.IsEncrypted(stored.Secret))
+		assert.Equal(t, []string{"peer.*"}, stored.Events)
+		assert.True(t, stored.Active)
+	})
+
+	t.Run("Stores inactive subscription", func(t *testing.T) {
+		s := setupTestService(t)
+		sub := &models.WebhookSubscription{URL: "https://hooks.example.com", Events: []string{"*"}}
+		require.NoError(t, s.CreateSubscription(ctx, sub, "s3cret"))
+
+		stored, err := s.GetSubscription(ctx, sub.ID)
+		require.NoError(t, err)
+		assert.False(t, stored.Active)
+	})
+
+	t.Run("Rejects invalid subscriptions", func(t *testing.T) {
+		s := setupTestService(t)
+
+		for _, sub := range []*models.WebhookSubscription{
+			{URL: "ftp://hooks.example.com", Events: []string{"*"}},
+			{URL: "/relative", Events: []string{"*"}},
+			{URL: "https://hooks.example.com"},
+			{URL: "https://hooks.example.com", Events: []string{"peer.renamed"}},
+			{URL: "https://hooks.example.com", Events: []string{"route.*"}},
+		} {
+			err := s.CreateSubscription(ctx, sub, "s3cret")
+			assert.ErrorIs(t, err, ErrInvalidSubscription, sub.URL)
+		}
+
+		err := s.CreateSubscription(ctx, &models.WebhookSubscription{URL: "https://hooks.example.com", Events: []string{"*"}}, "")
+		assert.ErrorIs(t, err, ErrInvalidSubscription)
+	})
+}
+
+func TestUpdateSubscription(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Keeps secret when not given", func(t *testing.T) {
+		s := setupTestService(t)
+		sub := createTestSubscription(t, s, "https://hooks.example.com", "*")
+
+		updated, err := s.UpdateSubscription(ctx, sub.ID, &models.WebhookSubscription{
+			URL:    "https://hooks.example.com/v2",
+			Events: []string{EventConfigRestored},
+		}, nil)
+		require.NoError(t, err)
+		assert.Equal(t, "https://hooks.example.com/v2", updated.URL)
+		assert.False(t, updated.Active)
+
+		secret, err := s.cipher.Decrypt(updated.Secret)
+		require.NoError(t, err)
+		assert.Equal(t, "s3cret", secret)
+	})
+
+	t.Run("Rotates secret", func(t *testing.T) {
+		s := setupTestService(t)
+		sub := createTestSubscription(t, s, "https://hooks.example.com", "*")
+
+		rotated := "n3w"
+		updated, err := s.UpdateSubscription(ctx, sub.ID, &models.WebhookSubscription{
+			URL:    sub.URL,
+			Events: sub.Events,
+			Active: true,
+		}, &rotated)
+		require.NoError(t, err)
+
+		secret, err := s.cipher.Decrypt(updated.Secret)
+		require.NoError(t, err)
+		assert.Equal(t, "n3w", secret)
+	})
+
+	t.Run("Missing subscription", func(t *testing.T) {
+		s := setupTestService(t)
+
+		_, err := s.UpdateSubscription(ctx, 99, &models.WebhookSubscription{}, nil)
+		assert.ErrorIs(t, err, ErrSubscriptionNotFound)
+	})
+}
+
+func TestDeleteSubscription(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Abandons pending deliveries", func(t *testing.T) {
+		s := setupTestService(t)
+		sub := createTestSubscription(t, s, "https://hooks.example.com", "*")
+		s.Publish(ctx, EventPeerCreated, map[string]interface{}{"id": 1})
+
+		require.NoError(t, s.DeleteSubscription(ctx, sub.ID))
+
+		var delivery models.WebhookDelivery
+		require.NoError(t, s.db.Where("subscription_id = ?", sub.ID).First(&delivery).Error)
+		assert.Equal(t, models.DeliveryFailed, delivery.Status)
+		assert.Equal(t, "subscription deleted", delivery.LastError)
+
+		_, err := s.GetSubscription(ctx, sub.ID)
+		assert.ErrorIs(t, err, ErrSubscriptionNotFound)
+	})
+}
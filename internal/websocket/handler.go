@@ -2,11 +2,12 @@ package websocket
 
 import (
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
-	"github.com/google/uuid"
 	"github.com/gorilla/websocket"
+	"github.com/padminisys/flintroute/internal/apierror"
 	"go.uber.org/zap"
 )
 
@@ -34,24 +35,42 @@ var upgrader = websocket.Upgrader{
 	},
 }
 
-// HandleWebSocket handles WebSocket connections
+// HandleWebSocket handles WebSocket connections. A client reconnecting with
+// ?last_seq= (the ID of the last message it received) first gets the
+// messages it missed. Requests acting for a tenant only get its events.
 func (h *Hub) HandleWebSocket(c *gin.Context) {
+	topics, err := ParseTopics(c.Query("topics"))
+	if err != nil {
+		apierror.Respond(c, http.StatusBadRequest, apierror.CodeValidationFailed, err.Error())
+		return
+	}
+
+	var lastSeq uint64
+	if raw := c.Query("last_seq"); raw != "" {
+		lastSeq, err = strconv.ParseUint(raw, 10, 64)
+		if err != nil {
+			apierror.Respond(c, http.StatusBadRequest, apierror.CodeValidationFailed, "last_seq must be a sequence number")
+			return
+		}
+	}
+
 	conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
 	if err != nil {
 		h.logger.Error("Failed to upgrade WebSocket connection", zap.Error(err))
 		return
 	}
 
-	client := &Client{
-		hub:  h,
-		send: make(chan []byte, 256),
-		id:   uuid.New().String(),
+	client, replay, cursor := h.subscribe(c.Request.Context(), "websocket", topics, lastSeq)
+	if lastSeq > 0 {
+		h.logger.Info("WebSocket client resumed",
+			zap.String("client_id", client.id),
+			zap.Uint64("last_seq", lastSeq),
+			zap.Int("replayed", len(replay)),
+		)
 	}
 
-	client.hub.register <- client
-
 	// Start goroutines for reading and writing
-	go client.writePump(conn)
+	go client.writePump(conn, replay, cursor)
 	go client.readPump(conn)
 }
 
@@ -82,14 +101,22 @@ func (c *Client) readPump(conn *websocket.Conn) {
 	}
 }
 
-// writePump pumps messages from the hub to the WebSocket connection
-func (c *Client) writePump(conn *websocket.Conn) {
+// writePump pumps messages from the hub to the WebSocket connection, after
+// the replayed ones. Live messages up to cursor were part of the replay.
+func (c *Client) writePump(conn *websocket.Conn, replay []*Event, cursor uint64) {
 	ticker := time.NewTicker(pingPeriod)
 	defer func() {
 		ticker.Stop()
 		conn.Close()
 	}()
 
+	for _, message := range replay {
+		conn.SetWriteDeadline(time.Now().Add(writeWait))
+		if err := conn.WriteMessage(websocket.TextMessage, message.Data); err != nil {
+			return
+		}
+	}
+
 	for {
 		select {
 		case message, ok := <-c.send:
@@ -99,18 +126,28 @@ func (c *Client) writePump(conn *websocket.Conn) {
 				conn.WriteMessage(websocket.CloseMessage, []byte{})
 				return
 			}
+			if message.ID <= cursor {
+				continue
+			}
 
 			w, err := conn.NextWriter(websocket.TextMessage)
 			if err != nil {
 				return
 			}
-			w.Write(message)
+			w.Write(message.Data)
 
 			// Add queued messages to the current WebSocket message
 			n := len(c.send)
 			for i := 0; i < n; i++ {
+				queued, ok := <-c.send
+				if !ok {
+					break
+				}
+				if queued.ID <= cursor {
+					continue
+				}
 				w.Write([]byte{'\n'})
-				w.Write(<-c.send)
+				w.Write(queued.Data)
 			}
 
 			if err := w.Close(); err != nil {
@@ -0,0 +1,229 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/padminisys/flintroute/internal/apierror"
+	"github.com/padminisys/flintroute/internal/tenancy"
+	"github.com/padminisys/flintroute/pkg/models"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// defaultRetention is the number of events kept in the database
+const defaultRetention = 10000
+
+// pruneInterval is how many events are persisted between deletions of
+// those past the retention
+const pruneInterval = 100
+
+// Persist stores broadcast events in db, keeping the last retention of
+// them (defaultRetention when 0), so clients can catch up after being
+// offline for longer than the in-memory buffer covers. Sequence numbers
+// continue from the last stored event, across restarts.
+func (h *Hub) Persist(db *gorm.DB, retention int) error {
+	if retention <= 0 {
+		retention = defaultRetention
+	}
+
+	var rows []models.StreamEvent
+	if err := db.Order("id DESC").Limit(historySize).Find(&rows).Error; err != nil {
+		return err
+	}
+
+	h.historyMu.Lock()
+	defer h.historyMu.Unlock()
+
+	h.db = db
+	h.retention = retention
+	if len(rows) > 0 && rows[0].ID > h.lastID {
+		h.lastID = rows[0].ID
+		h.history = make([]*Event, len(rows))
+		for i, row := range rows {
+			h.history[len(rows)-1-i] = newStoredEvent(&row)
+		}
+	}
+	return nil
+}
+
+func newStoredEvent(row *models.StreamEvent) *Event {
+	return &Event{ID: row.ID, Type: row.Type, TenantID: row.TenantID, Data: []byte(row.Data)}
+}
+
+// persistLocked stores event, deleting events past the retention every
+// pruneInterval events. Failures are logged: live clients still get the
+// event. h.historyMu must be held.
+func (h *Hub) persistLocked(ctx context.Context, event *Event) {
+	if h.db == nil {
+		return
+	}
+	// The event outlives the request that caused it
+	db := h.db.WithContext(context.WithoutCancel(ctx))
+
+	row := &models.StreamEvent{ID: event.ID, Type: event.Type, TenantID: event.TenantID, Data: string(event.Data)}
+	if err := db.Create(row).Error; err != nil {
+		h.logger.Warn("Failed to persist event", zap.Uint64("event_id", event.ID), zap.Error(err))
+		return
+	}
+
+	if event.ID%pruneInterval == 0 && event.ID > uint64(h.retention) {
+		if err := db.Where("id <= ?", event.ID-uint64(h.retention)).Delete(&models.StreamEvent{}).Error; err != nil {
+			h.logger.Warn("Failed to prune events", zap.Error(err))
+		}
+	}
+}
+
+// missedLocked returns the events after lastEventID the client wants, and
+// the sequence number of the latest event. The buffer of recent events is
+// used when it reaches back far enough, the database otherwise. A
+// lastEventID ahead of the hub (e.g. after the database was reset) gets the
+// whole buffer. h.historyMu must be held.
+func (h *Hub) missedLocked(client *Client, lastEventID uint64) ([]*Event, uint64) {
+	ctx := context.Background()
+	buffer, lastID := h.bufferedLocked(ctx)
+	if lastEventID == 0 {
+		return nil, lastID
+	}
+	if lastEventID > lastID {
+		return filterEvents(buffer, client, 0), lastID
+	}
+	if h.db == nil || len(buffer) == 0 || buffer[0].ID <= lastEventID+1 {
+		return filterEvents(buffer, client, lastEventID), lastID
+	}
+
+	events, err := h.storedEvents(ctx, client, lastEventID, lastID, 0)
+	if err != nil {
+		h.logger.Warn("Failed to load missed events", zap.Uint64("last_event_id", lastEventID), zap.Error(err))
+		return filterEvents(buffer, client, lastEventID), lastID
+	}
+	return events, lastID
+}
+
+// filterEvents returns the events after since the client wants
+func filterEvents(events []*Event, client *Client, since uint64) []*Event {
+	var filtered []*Event
+	for _, event := range events {
+		if event.ID > since && client.wants(event) {
+			filtered = append(filtered, event)
+		}
+	}
+	return filtered
+}
+
+// storedEvents loads the persisted events in (since, until] the client
+// wants, oldest first, at most limit of them when limit > 0
+func (h *Hub) storedEvents(ctx context.Context, client *Client, since, until uint64, limit int) ([]*Event, error) {
+	query := h.db.WithContext(ctx).Where("id > ? AND id <= ?", since, until).Order("id")
+	if len(client.topics) > 0 {
+		types := make([]string, 0, len(client.topics))
+		for topic := range client.topics {
+			types = append(types, topic)
+		}
+		query = query.Where("type IN ?", types)
+	}
+	if client.tenant != nil {
+		query = query.Where("tenant_id = ?", *client.tenant)
+	}
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+
+	var rows []models.StreamEvent
+	if err := query.Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	events := make([]*Event, len(rows))
+	for i := range rows {
+		events[i] = newStoredEvent(&rows[i])
+	}
+	return events, nil
+}
+
+// History returns up to limit events after since for the given topics (all
+// topics when empty), oldest first, and the sequence number of the latest
+// event. Only the events of the tenant ctx acts for, if any, are returned.
+// Without persistence only the buffer of recent events is searched.
+func (h *Hub) History(ctx context.Context, topics []string, since uint64, limit int) ([]*Event, uint64, error) {
+	filter := &Client{topics: topicSet(topics), tenant: tenancy.ID(ctx)}
+
+	h.historyMu.Lock()
+	buffer, lastID := h.bufferedLocked(ctx)
+	db := h.db
+	var events []*Event
+	if db == nil {
+		events = filterEvents(buffer, filter, since)
+	}
+	h.historyMu.Unlock()
+
+	if db == nil {
+		if len(events) > limit {
+			events = events[:limit]
+		}
+		return events, lastID, nil
+	}
+
+	events, err := h.storedEvents(ctx, filter, since, lastID, limit)
+	if err != nil {
+		return nil, 0, err
+	}
+	return events, lastID, nil
+}
+
+// maxHistoryLimit is the most events GET /events/history returns at once
+const maxHistoryLimit = 1000
+
+// HandleHistory lists past events after ?since= (a sequence number, 0 for
+// the oldest kept), filtered by ?topics= like the live streams. Events are
+// the same JSON messages the streams send. has_more reports that another
+// page starts after the last event returned.
+func (h *Hub) HandleHistory(c *gin.Context) {
+	topics, err := ParseTopics(c.Query("topics"))
+	if err != nil {
+		apierror.Respond(c, http.StatusBadRequest, apierror.CodeValidationFailed, err.Error())
+		return
+	}
+
+	var since uint64
+	if raw := c.Query("since"); raw != "" {
+		since, err = strconv.ParseUint(raw, 10, 64)
+		if err != nil {
+			apierror.Respond(c, http.StatusBadRequest, apierror.CodeValidationFailed, "since must be a sequence number")
+			return
+		}
+	}
+
+	limit := 100
+	if raw := c.Query("limit"); raw != "" {
+		limit, err = strconv.Atoi(raw)
+		if err != nil || limit < 1 || limit > maxHistoryLimit {
+			apierror.Respond(c, http.StatusBadRequest, apierror.CodeValidationFailed, "limit must be between 1 and 1000")
+			return
+		}
+	}
+
+	// One more than asked tells whether there is another page
+	events, lastID, err := h.History(c.Request.Context(), topics, since, limit+1)
+	if err != nil {
+		h.logger.Error("Failed to load event history", zap.Error(err))
+		apierror.Respond(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to load event history")
+		return
+	}
+	hasMore := len(events) > limit
+	if hasMore {
+		events = events[:limit]
+	}
+
+	messages := make([]json.RawMessage, len(events))
+	for i, event := range events {
+		messages[i] = event.Data
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"events":   messages,
+		"last_seq": lastID,
+		"has_more": hasMore,
+	})
+}
@@ -0,0 +1,249 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	gorillaws "github.com/gorilla/websocket"
+	"github.com/padminisys/flintroute/internal/tenancy"
+	"github.com/padminisys/flintroute/internal/testutil"
+	"github.com/padminisys/flintroute/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// historyResponse is the body of GET /events/history
+type historyResponse struct {
+	Events  []Message `json:"events"`
+	LastSeq uint64    `json:"last_seq"`
+	HasMore bool      `json:"has_more"`
+}
+
+func getHistory(t *testing.T, hub *Hub, query string) (int, historyResponse) {
+	t.Helper()
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/events/history", hub.HandleHistory)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/events/history"+query, nil))
+
+	var resp historyResponse
+	if w.Code == http.StatusOK {
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	}
+	return w.Code, resp
+}
+
+func TestPersist(t *testing.T) {
+	logger := zap.NewNop()
+	ctx := context.Background()
+
+	t.Run("Sequence continues after a restart", func(t *testing.T) {
+		db := testutil.SetupTestDB(t)
+
+		hub := NewHub(logger)
+		go hub.Run()
+		require.NoError(t, hub.Persist(db.DB, 0))
+		require.NoError(t, hub.BroadcastAlert(ctx, "a"))
+		require.NoError(t, hub.BroadcastPeerUpdate(ctx, "b"))
+
+		restarted := NewHub(logger)
+		go restarted.Run()
+		require.NoError(t, restarted.Persist(db.DB, 0))
+		require.NoError(t, restarted.BroadcastAlert(ctx, "c"))
+
+		var rows []models.StreamEvent
+		require.NoError(t, db.Order("id").Find(&rows).Error)
+		require.Len(t, rows, 3)
+		assert.Equal(t, uint64(3), rows[2].ID)
+		assert.Equal(t, TopicPeerUpdate, rows[1].Type)
+
+		client, replay, cursor := restarted.Subscribe(ctx, []string{TopicAlert}, 1)
+		defer restarted.Unsubscribe(client)
+		require.Len(t, replay, 1)
+		assert.Equal(t, uint64(3), replay[0].ID)
+		assert.Equal(t, uint64(3), cursor)
+	})
+
+	t.Run("Replays from the database beyond the buffer", func(t *testing.T) {
+		db := testutil.SetupTestDB(t)
+		hub := NewHub(logger)
+		go hub.Run()
+		require.NoError(t, hub.Persist(db.DB, 0))
+
+		for i := 0; i < historySize+10; i++ {
+			require.NoError(t, hub.BroadcastAlert(ctx, i))
+		}
+
+		client, replay, _ := hub.Subscribe(ctx, nil, 5)
+		defer hub.Unsubscribe(client)
+		require.Len(t, replay, historySize+5)
+		assert.Equal(t, uint64(6), replay[0].ID)
+	})
+
+	t.Run("Prunes events past the retention", func(t *testing.T) {
+		db := testutil.SetupTestDB(t)
+		hub := NewHub(logger)
+		go hub.Run()
+		require.NoError(t, hub.Persist(db.DB, 50))
+
+		for i := 0; i < pruneInterval; i++ {
+			require.NoError(t, hub.BroadcastAlert(ctx, i))
+		}
+
+		var count int64
+		require.NoError(t, db.Model(&models.StreamEvent{}).Count(&count).Error)
+		assert.Equal(t, int64(50), count)
+	})
+
+	t.Run("Clients acting for a tenant get only its events", func(t *testing.T) {
+		db := testutil.SetupTestDB(t)
+		hub := NewHub(logger)
+		go hub.Run()
+		require.NoError(t, hub.Persist(db.DB, 0))
+
+		acme, globex := uint(1), uint(2)
+		acmeCtx := tenancy.WithTenant(ctx, acme)
+		for _, alert := range []*models.Alert{
+			{Message: "instance"},
+			{Message: "acme", TenantID: &acme},
+			{Message: "globex", TenantID: &globex},
+			{Message: "instance"},
+		} {
+			require.NoError(t, hub.BroadcastAlert(ctx, alert))
+		}
+
+		ids := func(events []*Event) []uint64 {
+			var ids []uint64
+			for _, event := range events {
+				ids = append(ids, event.ID)
+			}
+			return ids
+		}
+		client, replay, cursor := hub.Subscribe(acmeCtx, nil, 1)
+		defer hub.Unsubscribe(client)
+		assert.Equal(t, []uint64{2}, ids(replay))
+
+		all, replay, _ := hub.Subscribe(ctx, nil, 1)
+		defer hub.Unsubscribe(all)
+		assert.Equal(t, []uint64{2, 3, 4}, ids(replay))
+
+		events, _, err := hub.History(tenancy.WithTenant(ctx, globex), nil, 0, 10)
+		require.NoError(t, err)
+		assert.Equal(t, []uint64{3}, ids(events))
+
+		require.NoError(t, hub.BroadcastAlert(ctx, &models.Alert{Message: "globex", TenantID: &globex}))
+		require.NoError(t, hub.BroadcastAlert(ctx, &models.Alert{Message: "acme", TenantID: &acme}))
+		// Live events up to the cursor were replayed
+		for {
+			select {
+			case event := <-client.send:
+				if event.ID <= cursor {
+					continue
+				}
+				assert.Equal(t, uint64(6), event.ID)
+				assert.Contains(t, string(event.Data), `"tenant_id":1`)
+			case <-time.After(time.Second):
+				t.Fatal("the tenant's event wasn't delivered")
+			}
+			break
+		}
+	})
+}
+
+func TestHandleHistory(t *testing.T) {
+	logger := zap.NewNop()
+	ctx := context.Background()
+
+	hub := NewHub(logger)
+	go hub.Run()
+	require.NoError(t, hub.Persist(testutil.SetupTestDB(t).DB, 0))
+	for i := 0; i < 3; i++ {
+		require.NoError(t, hub.BroadcastAlert(ctx, i))
+		require.NoError(t, hub.BroadcastSessionUpdate(ctx, i))
+	}
+
+	t.Run("Pages through events", func(t *testing.T) {
+		code, resp := getHistory(t, hub, "?limit=4")
+		require.Equal(t, http.StatusOK, code)
+		require.Len(t, resp.Events, 4)
+		assert.Equal(t, uint64(1), resp.Events[0].ID)
+		assert.False(t, resp.Events[0].Time.IsZero())
+		assert.True(t, resp.HasMore)
+		assert.Equal(t, uint64(6), resp.LastSeq)
+
+		code, resp = getHistory(t, hub, "?limit=4&since=4")
+		require.Equal(t, http.StatusOK, code)
+		require.Len(t, resp.Events, 2)
+		assert.Equal(t, uint64(5), resp.Events[0].ID)
+		assert.False(t, resp.HasMore)
+	})
+
+	t.Run("Filters by topic", func(t *testing.T) {
+		code, resp := getHistory(t, hub, "?topics=session_update&since=2")
+		require.Equal(t, http.StatusOK, code)
+		require.Len(t, resp.Events, 2)
+		for _, msg := range resp.Events {
+			assert.Equal(t, TopicSessionUpdate, msg.Type)
+		}
+	})
+
+	t.Run("Falls back to the buffer without persistence", func(t *testing.T) {
+		memory := NewHub(logger)
+		go memory.Run()
+		require.NoError(t, memory.BroadcastAlert(ctx, "a"))
+
+		code, resp := getHistory(t, memory, "")
+		require.Equal(t, http.StatusOK, code)
+		assert.Len(t, resp.Events, 1)
+	})
+
+	t.Run("Rejects invalid parameters", func(t *testing.T) {
+		for _, query := range []string{"?limit=0", "?limit=1001", "?since=-1", "?topics=bogus"} {
+			code, _ := getHistory(t, hub, query)
+			assert.Equal(t, http.StatusBadRequest, code, query)
+		}
+	})
+}
+
+func TestHandleWebSocketResume(t *testing.T) {
+	hub := NewHub(zap.NewNop())
+	go hub.Run()
+	for i := 0; i < 3; i++ {
+		require.NoError(t, hub.BroadcastAlert(context.Background(), i))
+	}
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/ws", hub.HandleWebSocket)
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	conn, _, err := gorillaws.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http")+"/ws?last_seq=1", nil)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	read := func() uint64 {
+		conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+		_, data, err := conn.ReadMessage()
+		require.NoError(t, err)
+		var msg Message
+		require.NoError(t, json.Unmarshal(data, &msg))
+		return msg.ID
+	}
+	assert.Equal(t, uint64(2), read())
+	assert.Equal(t, uint64(3), read())
+
+	waitForClients(t, hub, 1)
+	require.NoError(t, hub.BroadcastAlert(context.Background(), 3))
+	assert.Equal(t, uint64(4), read())
+}
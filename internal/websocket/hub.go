@@ -1,43 +1,194 @@
 package websocket
 
 import (
+	"context"
 	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
 	"sync"
+	"time"
 
+	"github.com/google/uuid"
+	"github.com/padminisys/flintroute/internal/requestid"
+	"github.com/padminisys/flintroute/internal/store"
+	"github.com/padminisys/flintroute/internal/tenancy"
 	"go.uber.org/zap"
+	"gorm.io/gorm"
 )
 
-// Message represents a WebSocket message
+// Event topics clients can subscribe to
+const (
+	TopicSessionUpdate = "session_update"
+	TopicAlert         = "alert"
+	TopicPeerUpdate    = "peer_update"
+	TopicJobUpdate     = "job_update"
+	TopicChangeUpdate  = "change_update"
+)
+
+// historySize is the number of recent events kept for Last-Event-ID resume
+const historySize = 256
+
+var knownTopics = map[string]bool{
+	TopicSessionUpdate: true,
+	TopicAlert:         true,
+	TopicPeerUpdate:    true,
+	TopicJobUpdate:     true,
+	TopicChangeUpdate:  true,
+}
+
+// Message represents a WebSocket message. ID is its sequence number, which
+// increases by one for every message broadcast. TenantID is the tenant the
+// payload belongs to, if any.
 type Message struct {
-	Type    string      `json:"type"`
-	Payload interface{} `json:"payload"`
+	ID        uint64      `json:"id"`
+	Type      string      `json:"type"`
+	Time      time.Time   `json:"time"`
+	Payload   interface{} `json:"payload"`
+	RequestID string      `json:"request_id,omitempty"`
+	TenantID  *uint       `json:"tenant_id,omitempty"`
+}
+
+// Event is a broadcast message together with its sequence ID
+type Event struct {
+	ID       uint64
+	Type     string
+	TenantID *uint
+	Data     []byte
+}
+
+// TenantOwned is implemented by payloads that belong to a tenant, such as
+// peers, sessions and alerts. Clients acting for a tenant only receive the
+// events of its payloads; events of other payloads go to clients acting
+// across tenants.
+type TenantOwned interface {
+	OwnerTenantID() *uint
+}
+
+// payloadTenant returns the tenant payload belongs to, if any
+func payloadTenant(payload interface{}) *uint {
+	if owned, ok := payload.(TenantOwned); ok {
+		return owned.OwnerTenantID()
+	}
+	return nil
 }
 
-// Client represents a WebSocket client
+// Slow client policies
+const (
+	// SlowClientDisconnect drops a client whose send buffer is full. Clients
+	// reconnect and resume from their last event without a gap.
+	SlowClientDisconnect = "disconnect"
+	// SlowClientDrop discards the event for that client only and keeps it
+	// connected, up to Options.MaxDropped events
+	SlowClientDrop = "drop"
+)
+
+// defaultSendBuffer is the number of events queued for each client
+const defaultSendBuffer = 256
+
+// Options configures how the hub treats clients that fall behind
+type Options struct {
+	// SendBuffer is how many events are queued for each client
+	SendBuffer int
+	// SlowClientPolicy is SlowClientDisconnect (the default) or
+	// SlowClientDrop
+	SlowClientPolicy string
+	// MaxDropped disconnects a client under SlowClientDrop once this many
+	// of its events were discarded; 0 never does
+	MaxDropped int
+}
+
+// Client represents a WebSocket or SSE client
 type Client struct {
-	hub  *Hub
-	send chan []byte
-	id   string
+	hub         *Hub
+	send        chan *Event
+	id          string
+	kind        string // websocket or sse
+	topics      map[string]bool
+	tenant      *uint // the tenant the client acts for; nil for every tenant
+	connectedAt time.Time
+	dropped     uint64 // events discarded because send was full, guarded by hub.mu
+}
+
+// wants reports whether the client subscribed to the event's type and may
+// see it. A client without topics receives every type, and one acting for a
+// tenant only the events of that tenant.
+func (c *Client) wants(event *Event) bool {
+	if len(c.topics) > 0 && !c.topics[event.Type] {
+		return false
+	}
+	return c.tenant == nil || (event.TenantID != nil && *event.TenantID == *c.tenant)
 }
 
 // Hub maintains active WebSocket connections
 type Hub struct {
 	clients    map[*Client]bool
-	broadcast  chan []byte
+	broadcast  chan *Event
 	register   chan *Client
 	unregister chan *Client
 	logger     *zap.Logger
-	mu         sync.RWMutex
+
+	// mu guards clients, their drop counters, options and the totals
+	mu              sync.RWMutex
+	options         Options
+	dropped         uint64
+	slowDisconnects uint64
+
+	// historyMu serialises ID assignment so events enter the broadcast
+	// channel in ID order and history snapshots line up with registration
+	historyMu sync.Mutex
+	lastID    uint64
+	history   []*Event
+	// db stores events for replay beyond history when set by Persist
+	db        *gorm.DB
+	retention int
+	// store shares sequence numbers and history with other instances when
+	// set by Share
+	store store.Store
 }
 
 // NewHub creates a new WebSocket hub
 func NewHub(logger *zap.Logger) *Hub {
 	return &Hub{
 		clients:    make(map[*Client]bool),
-		broadcast:  make(chan []byte, 256),
+		broadcast:  make(chan *Event, 256),
 		register:   make(chan *Client),
 		unregister: make(chan *Client),
 		logger:     logger,
+		options:    Options{SendBuffer: defaultSendBuffer, SlowClientPolicy: SlowClientDisconnect},
+	}
+}
+
+// SetOptions changes how the hub treats slow clients. Zero fields keep the
+// defaults. The send buffer size applies to clients connecting afterwards.
+func (h *Hub) SetOptions(opts Options) {
+	if opts.SendBuffer <= 0 {
+		opts.SendBuffer = defaultSendBuffer
+	}
+	if opts.SlowClientPolicy == "" {
+		opts.SlowClientPolicy = SlowClientDisconnect
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.options = opts
+}
+
+// newClient creates a client of the given kind with the configured send
+// buffer, acting for the tenant of ctx, if any
+func (h *Hub) newClient(ctx context.Context, kind string, topics []string) *Client {
+	h.mu.RLock()
+	size := h.options.SendBuffer
+	h.mu.RUnlock()
+
+	return &Client{
+		hub:         h,
+		send:        make(chan *Event, size),
+		id:          uuid.New().String(),
+		kind:        kind,
+		topics:      topicSet(topics),
+		tenant:      tenancy.ID(ctx),
+		connectedAt: time.Now(),
 	}
 }
 
@@ -49,38 +200,88 @@ func (h *Hub) Run() {
 			h.mu.Lock()
 			h.clients[client] = true
 			h.mu.Unlock()
-			h.logger.Info("WebSocket client connected", zap.String("client_id", client.id))
+			h.logger.Info("WebSocket client connected", zap.String("client_id", client.id), zap.String("kind", client.kind))
 
 		case client := <-h.unregister:
 			h.mu.Lock()
-			if _, ok := h.clients[client]; ok {
-				delete(h.clients, client)
-				close(client.send)
+			if h.removeLocked(client) {
 				h.logger.Info("WebSocket client disconnected", zap.String("client_id", client.id))
 			}
 			h.mu.Unlock()
 
-		case message := <-h.broadcast:
-			h.mu.RLock()
-			for client := range h.clients {
-				select {
-				case client.send <- message:
-				default:
-					// Client's send channel is full, close it
-					close(client.send)
-					delete(h.clients, client)
-				}
-			}
-			h.mu.RUnlock()
+		case event := <-h.broadcast:
+			h.mu.Lock()
+			h.deliverLocked(event)
+			h.mu.Unlock()
 		}
 	}
 }
 
-// Broadcast sends a message to all connected clients
-func (h *Hub) Broadcast(msgType string, payload interface{}) error {
+// deliverLocked queues event for every client that wants it, applying the
+// slow client policy to clients whose buffer is full. h.mu must be held
+// for writing, since slow clients are removed.
+func (h *Hub) deliverLocked(event *Event) {
+	for client := range h.clients {
+		if !client.wants(event) {
+			continue
+		}
+		select {
+		case client.send <- event:
+			continue
+		default:
+		}
+
+		client.dropped++
+		h.dropped++
+		if h.options.SlowClientPolicy == SlowClientDrop &&
+			(h.options.MaxDropped == 0 || client.dropped < uint64(h.options.MaxDropped)) {
+			h.logger.Debug("Dropped event for slow client",
+				zap.String("client_id", client.id),
+				zap.Uint64("event_id", event.ID),
+				zap.Uint64("dropped", client.dropped),
+			)
+			continue
+		}
+
+		h.removeLocked(client)
+		h.slowDisconnects++
+		h.logger.Warn("Disconnected slow client",
+			zap.String("client_id", client.id),
+			zap.String("kind", client.kind),
+			zap.Uint64("dropped", client.dropped),
+		)
+	}
+}
+
+// removeLocked forgets client and closes its send channel, which ends its
+// stream. It reports whether the client was still registered. h.mu must be
+// held for writing.
+func (h *Hub) removeLocked(client *Client) bool {
+	if _, ok := h.clients[client]; !ok {
+		return false
+	}
+	delete(h.clients, client)
+	close(client.send)
+	return true
+}
+
+// Broadcast sends a message to all connected clients. The request ID carried
+// by ctx, if any, is attached so clients can correlate events with API calls.
+func (h *Hub) Broadcast(ctx context.Context, msgType string, payload interface{}) error {
+	h.historyMu.Lock()
+	defer h.historyMu.Unlock()
+
+	id, err := h.nextIDLocked(ctx)
+	if err != nil {
+		return err
+	}
 	msg := Message{
-		Type:    msgType,
-		Payload: payload,
+		ID:        id,
+		Type:      msgType,
+		Time:      time.Now().UTC(),
+		Payload:   payload,
+		RequestID: requestid.FromContext(ctx),
+		TenantID:  payloadTenant(payload),
 	}
 
 	data, err := json.Marshal(msg)
@@ -88,23 +289,95 @@ func (h *Hub) Broadcast(msgType string, payload interface{}) error {
 		return err
 	}
 
-	h.broadcast <- data
+	event := &Event{ID: msg.ID, Type: msgType, TenantID: msg.TenantID, Data: data}
+	h.lastID = event.ID
+	h.bufferLocked(ctx, event)
+	h.persistLocked(ctx, event)
+
+	h.broadcast <- event
 	return nil
 }
 
+// Subscribe registers a client for the given topics (all topics when empty)
+// and returns the events after lastEventID it should replay first, from the
+// buffer or, when persisted, the database. Live events with an ID at or
+// below the returned cursor are already covered by the replay and must be
+// skipped. A lastEventID of zero replays nothing; one ahead of the hub
+// (e.g. after a server restart without persistence) replays the whole
+// buffer. The client only gets the events of the tenant ctx acts for, if
+// any.
+func (h *Hub) Subscribe(ctx context.Context, topics []string, lastEventID uint64) (*Client, []*Event, uint64) {
+	return h.subscribe(ctx, "sse", topics, lastEventID)
+}
+
+func (h *Hub) subscribe(ctx context.Context, kind string, topics []string, lastEventID uint64) (*Client, []*Event, uint64) {
+	client := h.newClient(ctx, kind, topics)
+
+	h.historyMu.Lock()
+	defer h.historyMu.Unlock()
+
+	replay, lastID := h.missedLocked(client, lastEventID)
+	h.register <- client
+	return client, replay, lastID
+}
+
+// Unsubscribe removes a client registered with Subscribe
+func (h *Hub) Unsubscribe(client *Client) {
+	h.unregister <- client
+}
+
+// ParseTopics parses a comma-separated topic list such as
+// "session_update,alert". An empty string selects all topics.
+func ParseTopics(raw string) ([]string, error) {
+	var topics []string
+	for _, topic := range strings.Split(raw, ",") {
+		topic = strings.TrimSpace(topic)
+		if topic == "" {
+			continue
+		}
+		if !knownTopics[topic] {
+			return nil, fmt.Errorf("unknown topic %q", topic)
+		}
+		topics = append(topics, topic)
+	}
+	return topics, nil
+}
+
+func topicSet(topics []string) map[string]bool {
+	if len(topics) == 0 {
+		return nil
+	}
+	set := make(map[string]bool, len(topics))
+	for _, topic := range topics {
+		set[topic] = true
+	}
+	return set
+}
+
 // BroadcastSessionUpdate sends a BGP session update to all clients
-func (h *Hub) BroadcastSessionUpdate(session interface{}) error {
-	return h.Broadcast("session_update", session)
+func (h *Hub) BroadcastSessionUpdate(ctx context.Context, session interface{}) error {
+	return h.Broadcast(ctx, TopicSessionUpdate, session)
 }
 
 // BroadcastAlert sends an alert to all clients
-func (h *Hub) BroadcastAlert(alert interface{}) error {
-	return h.Broadcast("alert", alert)
+func (h *Hub) BroadcastAlert(ctx context.Context, alert interface{}) error {
+	return h.Broadcast(ctx, TopicAlert, alert)
 }
 
 // BroadcastPeerUpdate sends a peer update to all clients
-func (h *Hub) BroadcastPeerUpdate(peer interface{}) error {
-	return h.Broadcast("peer_update", peer)
+func (h *Hub) BroadcastPeerUpdate(ctx context.Context, peer interface{}) error {
+	return h.Broadcast(ctx, TopicPeerUpdate, peer)
+}
+
+// BroadcastJobUpdate sends a background job's status and progress to all
+// clients
+func (h *Hub) BroadcastJobUpdate(ctx context.Context, job interface{}) error {
+	return h.Broadcast(ctx, TopicJobUpdate, job)
+}
+
+// BroadcastChangeUpdate sends a change request's status to all clients
+func (h *Hub) BroadcastChangeUpdate(ctx context.Context, change interface{}) error {
+	return h.Broadcast(ctx, TopicChangeUpdate, change)
 }
 
 // ClientCount returns the number of connected clients
@@ -112,4 +385,63 @@ func (h *Hub) ClientCount() int {
 	h.mu.RLock()
 	defer h.mu.RUnlock()
 	return len(h.clients)
-}
\ No newline at end of file
+}
+
+// Stats is a snapshot of the hub's clients and of the events it could not
+// deliver
+type Stats struct {
+	SendBuffer       int    `json:"send_buffer"`
+	SlowClientPolicy string `json:"slow_client_policy"`
+	MaxDropped       int    `json:"max_dropped"`
+	// Dropped counts events discarded for slow clients since the start,
+	// including those of clients since disconnected
+	Dropped         uint64        `json:"dropped"`
+	SlowDisconnects uint64        `json:"slow_disconnects"`
+	Clients         []ClientStats `json:"clients"`
+}
+
+// ClientStats describes one connected client
+type ClientStats struct {
+	ID          string    `json:"id"`
+	Kind        string    `json:"kind"`
+	ConnectedAt time.Time `json:"connected_at"`
+	Topics      []string  `json:"topics,omitempty"`
+	// Queued is how many events wait in the client's send buffer
+	Queued  int    `json:"queued"`
+	Dropped uint64 `json:"dropped"`
+}
+
+// Stats returns the hub's current clients and drop counters, clients
+// sorted by connection time
+func (h *Hub) Stats() Stats {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	stats := Stats{
+		SendBuffer:       h.options.SendBuffer,
+		SlowClientPolicy: h.options.SlowClientPolicy,
+		MaxDropped:       h.options.MaxDropped,
+		Dropped:          h.dropped,
+		SlowDisconnects:  h.slowDisconnects,
+		Clients:          make([]ClientStats, 0, len(h.clients)),
+	}
+	for client := range h.clients {
+		topics := make([]string, 0, len(client.topics))
+		for topic := range client.topics {
+			topics = append(topics, topic)
+		}
+		sort.Strings(topics)
+		stats.Clients = append(stats.Clients, ClientStats{
+			ID:          client.id,
+			Kind:        client.kind,
+			ConnectedAt: client.connectedAt,
+			Topics:      topics,
+			Queued:      len(client.send),
+			Dropped:     client.dropped,
+		})
+	}
+	sort.Slice(stats.Clients, func(i, j int) bool {
+		return stats.Clients[i].ConnectedAt.Before(stats.Clients[j].ConnectedAt)
+	})
+	return stats
+}
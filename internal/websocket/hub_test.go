@@ -1,10 +1,15 @@
 package websocket
 
 import (
+	"context"
 	"encoding/json"
+	"sync"
 	"testing"
+	"time"
 
+	"github.com/padminisys/flintroute/internal/requestid"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"go.uber.org/zap"
 )
 
@@ -40,20 +45,32 @@ func TestBroadcast(t *testing.T) {
 	t.Run("Broadcast message", func(t *testing.T) {
 		hub := NewHub(logger)
 
-		err := hub.Broadcast("test_type", map[string]string{"key": "value"})
+		err := hub.Broadcast(context.Background(), "test_type", map[string]string{"key": "value"})
 		assert.NoError(t, err)
 	})
 
 	// Note: Broadcast with running hub requires complex synchronization
 	// Tested via integration tests
 
+	t.Run("Broadcast includes request ID", func(t *testing.T) {
+		hub := NewHub(logger)
+
+		ctx := requestid.NewContext(context.Background(), "req-42")
+		err := hub.Broadcast(ctx, "test_type", "payload")
+		assert.NoError(t, err)
+
+		var msg Message
+		assert.NoError(t, json.Unmarshal((<-hub.broadcast).Data, &msg))
+		assert.Equal(t, "req-42", msg.RequestID)
+	})
+
 	t.Run("Broadcast with invalid payload", func(t *testing.T) {
 		hub := NewHub(logger)
 
 		// Create a payload that cannot be marshaled to JSON
 		invalidPayload := make(chan int)
 
-		err := hub.Broadcast("test_type", invalidPayload)
+		err := hub.Broadcast(context.Background(), "test_type", invalidPayload)
 		assert.Error(t, err)
 	})
 }
@@ -68,7 +85,7 @@ func TestBroadcastSessionUpdate(t *testing.T) {
 			"state":   "Established",
 		}
 
-		err := hub.BroadcastSessionUpdate(session)
+		err := hub.BroadcastSessionUpdate(context.Background(), session)
 		assert.NoError(t, err)
 	})
 }
@@ -84,7 +101,7 @@ func TestBroadcastAlert(t *testing.T) {
 			"message":  "Peer is down",
 		}
 
-		err := hub.BroadcastAlert(alert)
+		err := hub.BroadcastAlert(context.Background(), alert)
 		assert.NoError(t, err)
 	})
 }
@@ -100,7 +117,7 @@ func TestBroadcastPeerUpdate(t *testing.T) {
 			"enabled":    true,
 		}
 
-		err := hub.BroadcastPeerUpdate(peer)
+		err := hub.BroadcastPeerUpdate(context.Background(), peer)
 		assert.NoError(t, err)
 	})
 }
@@ -148,7 +165,7 @@ func TestClient(t *testing.T) {
 	t.Run("Create client", func(t *testing.T) {
 		client := &Client{
 			hub:  hub,
-			send: make(chan []byte, 256),
+			send: make(chan *Event, 256),
 			id:   "test-client",
 		}
 
@@ -168,4 +185,93 @@ func TestConcurrentOperations(t *testing.T) {
 	// Note: Concurrent operations with hub.Run() are complex
 	// Better tested in integration tests
 	t.Skip("Concurrent operations are better suited for integration tests")
-}
\ No newline at end of file
+}
+
+func TestSlowClients(t *testing.T) {
+	logger := zap.NewNop()
+
+	// connect registers a client without running the hub
+	connect := func(hub *Hub) *Client {
+		client := hub.newClient(context.Background(), "websocket", nil)
+		hub.clients[client] = true
+		return client
+	}
+	event := func(id uint64) *Event {
+		return &Event{ID: id, Type: TopicAlert}
+	}
+
+	t.Run("Disconnects a full client by default", func(t *testing.T) {
+		hub := NewHub(logger)
+		hub.SetOptions(Options{SendBuffer: 1})
+		client := connect(hub)
+
+		hub.deliverLocked(event(1))
+		hub.deliverLocked(event(2))
+
+		assert.Equal(t, 0, hub.ClientCount())
+		_, open := <-client.send
+		assert.True(t, open, "queued event is still delivered")
+		_, open = <-client.send
+		assert.False(t, open)
+
+		stats := hub.Stats()
+		assert.Equal(t, uint64(1), stats.Dropped)
+		assert.Equal(t, uint64(1), stats.SlowDisconnects)
+	})
+
+	t.Run("Drop policy keeps the client and counts drops", func(t *testing.T) {
+		hub := NewHub(logger)
+		hub.SetOptions(Options{SendBuffer: 1, SlowClientPolicy: SlowClientDrop})
+		client := connect(hub)
+
+		for i := uint64(1); i <= 3; i++ {
+			hub.deliverLocked(event(i))
+		}
+
+		stats := hub.Stats()
+		require.Len(t, stats.Clients, 1)
+		assert.Equal(t, client.id, stats.Clients[0].ID)
+		assert.Equal(t, 1, stats.Clients[0].Queued)
+		assert.Equal(t, uint64(2), stats.Clients[0].Dropped)
+		assert.Equal(t, uint64(2), stats.Dropped)
+		assert.Zero(t, stats.SlowDisconnects)
+		assert.Equal(t, uint64(1), (<-client.send).ID)
+	})
+
+	t.Run("Drop policy disconnects after MaxDropped", func(t *testing.T) {
+		hub := NewHub(logger)
+		hub.SetOptions(Options{SendBuffer: 1, SlowClientPolicy: SlowClientDrop, MaxDropped: 2})
+		connect(hub)
+
+		hub.deliverLocked(event(1))
+		hub.deliverLocked(event(2))
+		assert.Equal(t, 1, hub.ClientCount())
+		hub.deliverLocked(event(3))
+		assert.Equal(t, 0, hub.ClientCount())
+		assert.Equal(t, uint64(1), hub.Stats().SlowDisconnects)
+	})
+
+	// Run with -race: clients may be disconnected while broadcasting
+	t.Run("Concurrent broadcasts and disconnects", func(t *testing.T) {
+		hub := NewHub(logger)
+		hub.SetOptions(Options{SendBuffer: 1})
+		go hub.Run()
+
+		var wg sync.WaitGroup
+		for i := 0; i < 4; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				client, _, _ := hub.Subscribe(context.Background(), nil, 0)
+				for j := 0; j < 20; j++ {
+					assert.NoError(t, hub.BroadcastAlert(context.Background(), j))
+					_ = hub.Stats()
+				}
+				hub.Unsubscribe(client)
+			}()
+		}
+		wg.Wait()
+
+		assert.Eventually(t, func() bool { return hub.ClientCount() == 0 }, 2*time.Second, 10*time.Millisecond)
+	})
+}
@@ -0,0 +1,118 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/padminisys/flintroute/internal/store"
+	"go.uber.org/zap"
+)
+
+// Store keys of the shared sequence number and replay buffer
+const (
+	sequenceKey = "events:sequence"
+	bufferKey   = "events:buffer"
+)
+
+// Share numbers events from a counter in st and keeps the buffer of recent
+// events there, so that instances sharing st number events in one sequence
+// and a client can resume on any of them. Each instance still streams only
+// the events it broadcasts. Call it after Persist, so the sequence doesn't
+// fall behind the stored events.
+func (h *Hub) Share(st store.Store) error {
+	ctx := context.Background()
+
+	h.historyMu.Lock()
+	defer h.historyMu.Unlock()
+
+	value, err := st.Get(ctx, sequenceKey)
+	var sequence uint64
+	switch {
+	case errors.Is(err, store.ErrNotFound):
+	case err != nil:
+		return fmt.Errorf("failed to read event sequence: %w", err)
+	default:
+		if sequence, err = strconv.ParseUint(string(value), 10, 64); err != nil {
+			return fmt.Errorf("invalid event sequence: %w", err)
+		}
+	}
+	if sequence < h.lastID {
+		if err := st.Set(ctx, sequenceKey, strconv.AppendUint(nil, h.lastID, 10), 0); err != nil {
+			return fmt.Errorf("failed to set event sequence: %w", err)
+		}
+	}
+
+	h.store = st
+	return nil
+}
+
+// nextIDLocked returns the sequence number of the next event.
+// h.historyMu must be held.
+func (h *Hub) nextIDLocked(ctx context.Context) (uint64, error) {
+	if h.store == nil {
+		return h.lastID + 1, nil
+	}
+	id, err := h.store.Incr(ctx, sequenceKey, 0)
+	if err != nil {
+		return 0, fmt.Errorf("failed to number event: %w", err)
+	}
+	return uint64(id), nil
+}
+
+// bufferLocked adds event to the buffer of recent events. Failures to
+// share it are logged: live clients still get the event. h.historyMu must
+// be held.
+func (h *Hub) bufferLocked(ctx context.Context, event *Event) {
+	h.history = append(h.history, event)
+	if len(h.history) > historySize {
+		h.history = h.history[len(h.history)-historySize:]
+	}
+
+	if h.store == nil {
+		return
+	}
+	if err := h.store.Push(context.WithoutCancel(ctx), bufferKey, event.Data, historySize); err != nil {
+		h.logger.Warn("Failed to share event", zap.Uint64("event_id", event.ID), zap.Error(err))
+	}
+}
+
+// bufferedLocked returns the buffer of recent events, oldest first, and the
+// sequence number of the latest event. Once shared, the buffer holds every
+// instance's events; if the store fails, this instance's are returned.
+// h.historyMu must be held.
+func (h *Hub) bufferedLocked(ctx context.Context) ([]*Event, uint64) {
+	if h.store == nil {
+		return h.history, h.lastID
+	}
+
+	values, err := h.store.Range(ctx, bufferKey)
+	if err != nil {
+		h.logger.Warn("Failed to load shared events", zap.Error(err))
+		return h.history, h.lastID
+	}
+
+	events := make([]*Event, 0, len(values))
+	for _, data := range values {
+		var msg struct {
+			ID       uint64 `json:"id"`
+			Type     string `json:"type"`
+			TenantID *uint  `json:"tenant_id"`
+		}
+		if err := json.Unmarshal(data, &msg); err != nil {
+			continue
+		}
+		events = append(events, &Event{ID: msg.ID, Type: msg.Type, TenantID: msg.TenantID, Data: data})
+	}
+	// Instances may push events slightly out of order
+	sort.Slice(events, func(i, j int) bool { return events[i].ID < events[j].ID })
+
+	lastID := h.lastID
+	if n := len(events); n > 0 && events[n-1].ID > lastID {
+		lastID = events[n-1].ID
+	}
+	return events, lastID
+}
@@ -0,0 +1,83 @@
+package websocket
+
+import (
+	"context"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/padminisys/flintroute/internal/store"
+	"github.com/padminisys/flintroute/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestShare(t *testing.T) {
+	logger := zap.NewNop()
+	ctx := context.Background()
+
+	t.Run("Instances number events in one sequence and resume each other's", func(t *testing.T) {
+		shared := store.NewMemory()
+		first, second := NewHub(logger), NewHub(logger)
+		go first.Run()
+		go second.Run()
+		require.NoError(t, first.Share(shared))
+		require.NoError(t, second.Share(shared))
+
+		require.NoError(t, first.BroadcastAlert(ctx, "a"))
+		require.NoError(t, second.BroadcastPeerUpdate(ctx, "b"))
+		require.NoError(t, first.BroadcastAlert(ctx, "c"))
+
+		// A client of the first instance reconnects to the second
+		client, replay, cursor := second.Subscribe(ctx, []string{TopicAlert}, 1)
+		defer second.Unsubscribe(client)
+		require.Len(t, replay, 1)
+		assert.Equal(t, uint64(3), replay[0].ID)
+		assert.Contains(t, string(replay[0].Data), `"payload":"c"`)
+		assert.Equal(t, uint64(3), cursor)
+
+		events, lastID, err := second.History(ctx, nil, 0, 10)
+		require.NoError(t, err)
+		assert.Len(t, events, 3)
+		assert.Equal(t, uint64(3), lastID)
+	})
+
+	t.Run("Sequence continues from persisted events", func(t *testing.T) {
+		db := testutil.SetupTestDB(t)
+		hub := NewHub(logger)
+		go hub.Run()
+		require.NoError(t, hub.Persist(db.DB, 0))
+		require.NoError(t, hub.BroadcastAlert(ctx, "a"))
+		require.NoError(t, hub.BroadcastAlert(ctx, "b"))
+
+		restarted := NewHub(logger)
+		go restarted.Run()
+		require.NoError(t, restarted.Persist(db.DB, 0))
+		require.NoError(t, restarted.Share(store.NewMemory()))
+		require.NoError(t, restarted.BroadcastAlert(ctx, "c"))
+
+		_, lastID, err := restarted.History(ctx, nil, 0, 10)
+		require.NoError(t, err)
+		assert.Equal(t, uint64(3), lastID)
+	})
+
+	t.Run("Falls back to the local buffer when the store fails", func(t *testing.T) {
+		server := miniredis.RunT(t)
+		shared, err := store.NewRedis(store.RedisConfig{Address: server.Addr()})
+		require.NoError(t, err)
+		defer shared.Close()
+
+		hub := NewHub(logger)
+		go hub.Run()
+		require.NoError(t, hub.Share(shared))
+		require.NoError(t, hub.BroadcastAlert(ctx, "a"))
+
+		server.Close()
+		assert.Error(t, hub.BroadcastAlert(ctx, "b"), "events can't be numbered")
+
+		client, replay, cursor := hub.Subscribe(ctx, nil, 100)
+		defer hub.Unsubscribe(client)
+		require.Len(t, replay, 1)
+		assert.Equal(t, uint64(1), cursor)
+	})
+}
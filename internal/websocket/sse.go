@@ -0,0 +1,116 @@
+package websocket
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/padminisys/flintroute/internal/apierror"
+	"go.uber.org/zap"
+)
+
+// sseHeartbeat is how often an idle SSE stream sends a comment line so
+// proxies and clients can tell the connection is still alive
+var sseHeartbeat = 15 * time.Second
+
+// sseRetry is the reconnect delay suggested to EventSource clients
+const sseRetry = 3 * time.Second
+
+// HandleSSE streams hub events as Server-Sent Events. It accepts the same
+// ?topics= filter as the WebSocket endpoint and resumes from the
+// Last-Event-ID header (or ?last_event_id= or ?last_seq=) using the hub's
+// event history. Requests acting for a tenant only get its events.
+func (h *Hub) HandleSSE(c *gin.Context) {
+	topics, err := ParseTopics(c.Query("topics"))
+	if err != nil {
+		apierror.Respond(c, http.StatusBadRequest, apierror.CodeValidationFailed, err.Error())
+		return
+	}
+
+	lastEventID := c.GetHeader("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = c.Query("last_event_id")
+	}
+	if lastEventID == "" {
+		lastEventID = c.Query("last_seq")
+	}
+	var resumeFrom uint64
+	if lastEventID != "" {
+		resumeFrom, err = strconv.ParseUint(lastEventID, 10, 64)
+		if err != nil {
+			apierror.Respond(c, http.StatusBadRequest, apierror.CodeValidationFailed, "Invalid Last-Event-ID")
+			return
+		}
+	}
+
+	client, replay, cursor := h.Subscribe(c.Request.Context(), topics, resumeFrom)
+	defer h.Unsubscribe(client)
+
+	h.logger.Info("SSE client connected",
+		zap.String("client_id", client.id),
+		zap.Uint64("last_event_id", resumeFrom),
+		zap.Int("replayed", len(replay)),
+	)
+
+	w := c.Writer
+	rc := http.NewResponseController(w)
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	// flush extends the write deadline past the server's WriteTimeout,
+	// which would otherwise end the stream
+	flush := func() bool {
+		rc.SetWriteDeadline(time.Now().Add(writeWait))
+		return rc.Flush() == nil
+	}
+
+	fmt.Fprintf(w, "retry: %d\n\n", sseRetry.Milliseconds())
+	for _, event := range replay {
+		writeSSEEvent(w, event)
+	}
+	if !flush() {
+		return
+	}
+
+	ticker := time.NewTicker(sseHeartbeat)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+
+		case event, ok := <-client.send:
+			if !ok {
+				// The hub dropped the client because it fell behind
+				return
+			}
+			if event.ID <= cursor {
+				continue
+			}
+			rc.SetWriteDeadline(time.Now().Add(writeWait))
+			writeSSEEvent(w, event)
+			if !flush() {
+				return
+			}
+
+		case <-ticker.C:
+			rc.SetWriteDeadline(time.Now().Add(writeWait))
+			fmt.Fprint(w, ": heartbeat\n\n")
+			if !flush() {
+				return
+			}
+		}
+	}
+}
+
+// writeSSEEvent writes one event in text/event-stream format. The data is
+// the same JSON message WebSocket clients receive.
+func writeSSEEvent(w gin.ResponseWriter, event *Event) {
+	fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", event.ID, event.Type, event.Data)
+}
@@ -0,0 +1,229 @@
+package websocket
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+type sseFrame struct {
+	retry   string
+	id      string
+	event   string
+	data    string
+	comment string
+}
+
+// openSSE starts an SSE request against a running hub and returns a channel
+// of parsed frames. The stream is closed when the test ends.
+func openSSE(t *testing.T, hub *Hub, query string, header http.Header) <-chan sseFrame {
+	t.Helper()
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/events", hub.HandleSSE)
+	server := httptest.NewServer(router)
+	t.Cleanup(server.Close)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"/events"+query, nil)
+	require.NoError(t, err)
+	for k, v := range header {
+		req.Header[k] = v
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	t.Cleanup(func() { resp.Body.Close() })
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	frames := make(chan sseFrame, 16)
+	go func() {
+		defer close(frames)
+		scanner := bufio.NewScanner(resp.Body)
+		var frame sseFrame
+		for scanner.Scan() {
+			line := scanner.Text()
+			switch {
+			case line == "":
+				if frame != (sseFrame{}) {
+					frames <- frame
+				}
+				frame = sseFrame{}
+			case strings.HasPrefix(line, ":"):
+				frame.comment = strings.TrimSpace(line[1:])
+			case strings.HasPrefix(line, "retry: "):
+				frame.retry = line[len("retry: "):]
+			case strings.HasPrefix(line, "id: "):
+				frame.id = line[len("id: "):]
+			case strings.HasPrefix(line, "event: "):
+				frame.event = line[len("event: "):]
+			case strings.HasPrefix(line, "data: "):
+				frame.data = line[len("data: "):]
+			}
+		}
+	}()
+
+	// The first frame is the retry hint
+	assert.Equal(t, "3000", nextFrame(t, frames).retry)
+	return frames
+}
+
+func nextFrame(t *testing.T, frames <-chan sseFrame) sseFrame {
+	t.Helper()
+	select {
+	case frame := <-frames:
+		return frame
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for SSE frame")
+		return sseFrame{}
+	}
+}
+
+func waitForClients(t *testing.T, hub *Hub, n int) {
+	t.Helper()
+	assert.Eventually(t, func() bool { return hub.ClientCount() == n }, 2*time.Second, 10*time.Millisecond)
+}
+
+func TestHandleSSE(t *testing.T) {
+	logger := zap.NewNop()
+
+	t.Run("Streams broadcast events", func(t *testing.T) {
+		hub := NewHub(logger)
+		go hub.Run()
+
+		frames := openSSE(t, hub, "", nil)
+		waitForClients(t, hub, 1)
+
+		require.NoError(t, hub.BroadcastAlert(context.Background(), map[string]string{"message": "peer down"}))
+
+		frame := nextFrame(t, frames)
+		assert.Equal(t, "1", frame.id)
+		assert.Equal(t, TopicAlert, frame.event)
+
+		var msg Message
+		require.NoError(t, json.Unmarshal([]byte(frame.data), &msg))
+		assert.Equal(t, uint64(1), msg.ID)
+		assert.Equal(t, TopicAlert, msg.Type)
+	})
+
+	t.Run("Filters by topic", func(t *testing.T) {
+		hub := NewHub(logger)
+		go hub.Run()
+
+		frames := openSSE(t, hub, "?topics=peer_update", nil)
+		waitForClients(t, hub, 1)
+
+		require.NoError(t, hub.BroadcastAlert(context.Background(), "alert"))
+		require.NoError(t, hub.BroadcastPeerUpdate(context.Background(), "peer"))
+
+		frame := nextFrame(t, frames)
+		assert.Equal(t, TopicPeerUpdate, frame.event)
+		assert.Equal(t, "2", frame.id)
+	})
+
+	t.Run("Resumes from Last-Event-ID", func(t *testing.T) {
+		hub := NewHub(logger)
+		go hub.Run()
+
+		for i := 0; i < 3; i++ {
+			require.NoError(t, hub.BroadcastSessionUpdate(context.Background(), i))
+		}
+
+		frames := openSSE(t, hub, "", http.Header{"Last-Event-Id": {"1"}})
+		assert.Equal(t, "2", nextFrame(t, frames).id)
+		assert.Equal(t, "3", nextFrame(t, frames).id)
+
+		waitForClients(t, hub, 1)
+		require.NoError(t, hub.BroadcastSessionUpdate(context.Background(), 3))
+		assert.Equal(t, "4", nextFrame(t, frames).id)
+	})
+
+	t.Run("Sends heartbeats", func(t *testing.T) {
+		old := sseHeartbeat
+		sseHeartbeat = 20 * time.Millisecond
+		defer func() { sseHeartbeat = old }()
+
+		hub := NewHub(logger)
+		go hub.Run()
+
+		frames := openSSE(t, hub, "", nil)
+		assert.Equal(t, "heartbeat", nextFrame(t, frames).comment)
+	})
+
+	t.Run("Rejects unknown topics", func(t *testing.T) {
+		hub := NewHub(logger)
+
+		gin.SetMode(gin.TestMode)
+		router := gin.New()
+		router.GET("/events", hub.HandleSSE)
+
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/events?topics=bogus", nil)
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
+
+func TestParseTopics(t *testing.T) {
+	t.Run("Empty selects all", func(t *testing.T) {
+		topics, err := ParseTopics("")
+		assert.NoError(t, err)
+		assert.Empty(t, topics)
+	})
+
+	t.Run("Comma separated", func(t *testing.T) {
+		topics, err := ParseTopics("alert, session_update")
+		assert.NoError(t, err)
+		assert.Equal(t, []string{TopicAlert, TopicSessionUpdate}, topics)
+	})
+
+	t.Run("Unknown topic", func(t *testing.T) {
+		_, err := ParseTopics("alert,bogus")
+		assert.Error(t, err)
+	})
+}
+
+func TestSubscribeReplay(t *testing.T) {
+	logger := zap.NewNop()
+
+	t.Run("Replays whole buffer when ahead of the hub", func(t *testing.T) {
+		hub := NewHub(logger)
+		go hub.Run()
+
+		require.NoError(t, hub.BroadcastAlert(context.Background(), "a"))
+		require.NoError(t, hub.BroadcastAlert(context.Background(), "b"))
+
+		client, replay, cursor := hub.Subscribe(context.Background(), nil, 100)
+		defer hub.Unsubscribe(client)
+		assert.Len(t, replay, 2)
+		assert.Equal(t, uint64(2), cursor)
+	})
+
+	t.Run("History is bounded", func(t *testing.T) {
+		hub := NewHub(logger)
+		go hub.Run()
+
+		for i := 0; i < historySize+10; i++ {
+			require.NoError(t, hub.Broadcast(context.Background(), "test_type", i))
+		}
+
+		hub.historyMu.Lock()
+		defer hub.historyMu.Unlock()
+		assert.Len(t, hub.history, historySize)
+		assert.Equal(t, uint64(11), hub.history[0].ID)
+	})
+}
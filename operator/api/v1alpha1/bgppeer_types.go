@@ -0,0 +1,76 @@
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Condition types reported on BGPPeer resources
+const (
+	// ConditionReady is true when the peer exists in FlintRoute and matches the spec
+	ConditionReady = "Ready"
+	// ConditionSynced is true when FlintRoute has applied the peer to FRR
+	ConditionSynced = "Synced"
+)
+
+// Finalizer ensures the FlintRoute peer is removed before the resource is deleted
+const Finalizer = "flintroute.padminisys.io/finalizer"
+
+// SecretKeySelector selects a key of a Secret in the resource's namespace
+type SecretKeySelector struct {
+	Name string `json:"name"`
+	Key  string `json:"key"`
+}
+
+// BGPPeerSpec is the desired state of a BGP peer
+type BGPPeerSpec struct {
+	// DisplayName is the peer name in FlintRoute; defaults to metadata.name
+	DisplayName string `json:"displayName,omitempty"`
+	IPAddress   string `json:"ipAddress"`
+	ASN         uint32 `json:"asn"`
+	RemoteASN   uint32 `json:"remoteASN"`
+	Description string `json:"description,omitempty"`
+	// Enabled defaults to true
+	Enabled *bool `json:"enabled,omitempty"`
+	// PasswordSecretRef references the TCP MD5 password for the session
+	PasswordSecretRef *SecretKeySelector `json:"passwordSecretRef,omitempty"`
+	Multihop          int                `json:"multihop,omitempty"`
+	UpdateSource      string             `json:"updateSource,omitempty"`
+	RouteMapIn        string             `json:"routeMapIn,omitempty"`
+	RouteMapOut       string             `json:"routeMapOut,omitempty"`
+	PrefixListIn      string             `json:"prefixListIn,omitempty"`
+	PrefixListOut     string             `json:"prefixListOut,omitempty"`
+	MaxPrefixes       int                `json:"maxPrefixes,omitempty"`
+	LocalPreference   int                `json:"localPreference,omitempty"`
+}
+
+// BGPPeerStatus is the observed state of a BGP peer
+type BGPPeerStatus struct {
+	// PeerID is the FlintRoute peer managed by this resource
+	PeerID uint `json:"peerID,omitempty"`
+	// SyncStatus mirrors the FlintRoute peer sync status (synced, pending)
+	SyncStatus string `json:"syncStatus,omitempty"`
+	// PasswordVersion identifies the Secret version last pushed to FlintRoute
+	PasswordVersion    string             `json:"passwordVersion,omitempty"`
+	ObservedGeneration int64              `json:"observedGeneration,omitempty"`
+	Conditions         []metav1.Condition `json:"conditions,omitempty"`
+}
+
+// BGPPeer declares a BGP peer managed through FlintRoute
+type BGPPeer struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   BGPPeerSpec   `json:"spec,omitempty"`
+	Status BGPPeerStatus `json:"status,omitempty"`
+}
+
+// BGPPeerList is a list of BGPPeer resources
+type BGPPeerList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []BGPPeer `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&BGPPeer{}, &BGPPeerList{})
+}
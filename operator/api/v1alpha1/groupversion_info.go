@@ -0,0 +1,18 @@
+// Package v1alpha1 contains the FlintRoute custom resource definitions
+package v1alpha1
+
+import (
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/scheme"
+)
+
+var (
+	// GroupVersion is the API group and version of FlintRoute resources
+	GroupVersion = schema.GroupVersion{Group: "flintroute.padminisys.io", Version: "v1alpha1"}
+
+	// SchemeBuilder registers FlintRoute types with a runtime scheme
+	SchemeBuilder = &scheme.Builder{GroupVersion: GroupVersion}
+
+	// AddToScheme adds FlintRoute types to a scheme
+	AddToScheme = SchemeBuilder.AddToScheme
+)
@@ -0,0 +1,112 @@
+// Command flintroute-operator reconciles BGPPeer custom resources into FlintRoute
+package main
+
+import (
+	"context"
+	"flag"
+	"os"
+	"time"
+
+	"github.com/padminisys/flintroute/operator/api/v1alpha1"
+	"github.com/padminisys/flintroute/operator/internal/controller"
+	"github.com/padminisys/flintroute/pkg/client"
+	"go.uber.org/zap"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/healthz"
+	ctrlzap "sigs.k8s.io/controller-runtime/pkg/log/zap"
+	metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
+)
+
+func main() {
+	var (
+		apiURL         string
+		metricsAddr    string
+		probeAddr      string
+		leaderElect    bool
+		resyncInterval time.Duration
+	)
+	flag.StringVar(&apiURL, "flintroute-url", envOr("FLINTROUTE_URL", "http://localhost:8080"), "FlintRoute API base URL")
+	flag.StringVar(&metricsAddr, "metrics-bind-address", ":8080", "Address the metrics endpoint binds to")
+	flag.StringVar(&probeAddr, "health-probe-bind-address", ":8081", "Address the health probe endpoint binds to")
+	flag.BoolVar(&leaderElect, "leader-elect", false, "Enable leader election for high availability")
+	flag.DurationVar(&resyncInterval, "resync-interval", 5*time.Minute, "How often peers are re-checked against FlintRoute")
+	opts := ctrlzap.Options{}
+	opts.BindFlags(flag.CommandLine)
+	flag.Parse()
+
+	ctrl.SetLogger(ctrlzap.New(ctrlzap.UseFlagOptions(&opts)))
+	setupLog := ctrl.Log.WithName("setup")
+
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		setupLog.Error(err, "Failed to register Kubernetes types")
+		os.Exit(1)
+	}
+	if err := v1alpha1.AddToScheme(scheme); err != nil {
+		setupLog.Error(err, "Failed to register FlintRoute types")
+		os.Exit(1)
+	}
+
+	sdkLogger, err := zap.NewProduction()
+	if err != nil {
+		setupLog.Error(err, "Failed to create SDK logger")
+		os.Exit(1)
+	}
+
+	api := &sessionClient{
+		APIClient: client.NewAPIClient(apiURL, sdkLogger),
+		username:  os.Getenv("FLINTROUTE_USERNAME"),
+		password:  os.Getenv("FLINTROUTE_PASSWORD"),
+	}
+	if err := api.login(context.Background()); err != nil {
+		setupLog.Error(err, "Failed to log in to FlintRoute", "url", apiURL)
+		os.Exit(1)
+	}
+
+	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
+		Scheme:                 scheme,
+		Metrics:                metricsserver.Options{BindAddress: metricsAddr},
+		HealthProbeBindAddress: probeAddr,
+		LeaderElection:         leaderElect,
+		LeaderElectionID:       "flintroute-operator.padminisys.io",
+	})
+	if err != nil {
+		setupLog.Error(err, "Failed to create manager")
+		os.Exit(1)
+	}
+
+	reconciler := &controller.BGPPeerReconciler{
+		Client:         mgr.GetClient(),
+		FlintRoute:     api,
+		ResyncInterval: resyncInterval,
+	}
+	if err := reconciler.SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "Failed to set up BGPPeer controller")
+		os.Exit(1)
+	}
+
+	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
+		setupLog.Error(err, "Failed to set up health check")
+		os.Exit(1)
+	}
+	if err := mgr.AddReadyzCheck("readyz", healthz.Ping); err != nil {
+		setupLog.Error(err, "Failed to set up ready check")
+		os.Exit(1)
+	}
+
+	setupLog.Info("Starting FlintRoute operator", "url", apiURL)
+	if err := mgr.Start(ctrl.SetupSignalHandler()); err != nil {
+		setupLog.Error(err, "Manager exited with error")
+		os.Exit(1)
+	}
+}
+
+// envOr returns the environment variable key, or fallback if unset
+func envOr(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
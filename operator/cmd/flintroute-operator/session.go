@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"sync"
+
+	"github.com/padminisys/flintroute/pkg/client"
+)
+
+// sessionClient wraps the SDK client and logs in again when the session
+// expires, e.g. after the refresh token lifetime or a JWT secret rotation
+type sessionClient struct {
+	*client.APIClient
+	username string
+	password string
+	mu       sync.Mutex
+}
+
+// login authenticates the SDK client
+func (c *sessionClient) login(ctx context.Context) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_, err := c.Login(ctx, c.username, c.password)
+	return err
+}
+
+// withSession runs fn, logging in again and retrying once on 401
+func withSession[T any](ctx context.Context, c *sessionClient, fn func() (T, error)) (T, error) {
+	result, err := fn()
+	if err == nil || !client.IsUnauthorized(err) {
+		return result, err
+	}
+
+	if loginErr := c.login(ctx); loginErr != nil {
+		return result, err
+	}
+	return fn()
+}
+
+// CreatePeer creates a BGP peer
+func (c *sessionClient) CreatePeer(ctx context.Context, peer *client.PeerRequest) (*client.Peer, error) {
+	return withSession(ctx, c, func() (*client.Peer, error) { return c.APIClient.CreatePeer(ctx, peer) })
+}
+
+// GetPeer gets a BGP peer
+func (c *sessionClient) GetPeer(ctx context.Context, id uint) (*client.Peer, error) {
+	return withSession(ctx, c, func() (*client.Peer, error) { return c.APIClient.GetPeer(ctx, id) })
+}
+
+// ListPeers lists BGP peers
+func (c *sessionClient) ListPeers(ctx context.Context) ([]*client.Peer, error) {
+	return withSession(ctx, c, func() ([]*client.Peer, error) { return c.APIClient.ListPeers(ctx) })
+}
+
+// UpdatePeer replaces a BGP peer
+func (c *sessionClient) UpdatePeer(ctx context.Context, id uint, updates *client.PeerRequest) (*client.Peer, error) {
+	return withSession(ctx, c, func() (*client.Peer, error) { return c.APIClient.UpdatePeer(ctx, id, updates) })
+}
+
+// SetPeerPassword rotates a BGP peer password
+func (c *sessionClient) SetPeerPassword(ctx context.Context, id uint, password string) (*client.Peer, error) {
+	return withSession(ctx, c, func() (*client.Peer, error) { return c.APIClient.SetPeerPassword(ctx, id, password) })
+}
+
+// DeletePeer deletes a BGP peer
+func (c *sessionClient) DeletePeer(ctx context.Context, id uint) error {
+	_, err := withSession(ctx, c, func() (struct{}, error) { return struct{}{}, c.APIClient.DeletePeer(ctx, id) })
+	return err
+}
@@ -0,0 +1,302 @@
+// Package controller reconciles BGPPeer resources into FlintRoute
+package controller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/padminisys/flintroute/operator/api/v1alpha1"
+	"github.com/padminisys/flintroute/pkg/client"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// Condition reasons
+const (
+	ReasonReconciled   = "Reconciled"
+	ReasonAPIError     = "FlintRouteError"
+	ReasonSecretError  = "SecretError"
+	ReasonSyncPending  = "SyncPending"
+	ReasonSyncComplete = "Synced"
+)
+
+// pendingRequeue is how soon peers pending FRR sync are checked again
+const pendingRequeue = 30 * time.Second
+
+// PeerAPI is the subset of the FlintRoute SDK used by the reconciler
+type PeerAPI interface {
+	CreatePeer(ctx context.Context, peer *client.PeerRequest) (*client.Peer, error)
+	GetPeer(ctx context.Context, id uint) (*client.Peer, error)
+	ListPeers(ctx context.Context) ([]*client.Peer, error)
+	UpdatePeer(ctx context.Context, id uint, updates *client.PeerRequest) (*client.Peer, error)
+	SetPeerPassword(ctx context.Context, id uint, password string) (*client.Peer, error)
+	DeletePeer(ctx context.Context, id uint) error
+}
+
+// BGPPeerReconciler reconciles BGPPeer resources into FlintRoute
+type BGPPeerReconciler struct {
+	ctrlclient.Client
+	FlintRoute PeerAPI
+	// ResyncInterval re-checks peers periodically to undo out-of-band changes
+	ResyncInterval time.Duration
+}
+
+// SetupWithManager registers the reconciler with a manager. Changes to a
+// referenced password Secret trigger reconciliation of the peers using it.
+func (r *BGPPeerReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&v1alpha1.BGPPeer{}).
+		Watches(&corev1.Secret{}, handler.EnqueueRequestsFromMapFunc(r.peersForSecret)).
+		Complete(r)
+}
+
+// peersForSecret maps a Secret to the BGPPeers that reference it
+func (r *BGPPeerReconciler) peersForSecret(ctx context.Context, obj ctrlclient.Object) []reconcile.Request {
+	var peers v1alpha1.BGPPeerList
+	if err := r.List(ctx, &peers, ctrlclient.InNamespace(obj.GetNamespace())); err != nil {
+		log.FromContext(ctx).Error(err, "Failed to list BGPPeers for Secret", "secret", obj.GetName())
+		return nil
+	}
+
+	var requests []reconcile.Request
+	for _, peer := range peers.Items {
+		ref := peer.Spec.PasswordSecretRef
+		if ref != nil && ref.Name == obj.GetName() {
+			requests = append(requests, reconcile.Request{
+				NamespacedName: types.NamespacedName{Namespace: peer.Namespace, Name: peer.Name},
+			})
+		}
+	}
+	return requests
+}
+
+// Reconcile brings the FlintRoute peer in line with a BGPPeer resource
+func (r *BGPPeerReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+
+	var resource v1alpha1.BGPPeer
+	if err := r.Get(ctx, req.NamespacedName, &resource); err != nil {
+		return ctrl.Result{}, ctrlclient.IgnoreNotFound(err)
+	}
+
+	if !resource.DeletionTimestamp.IsZero() {
+		return ctrl.Result{}, r.finalize(ctx, &resource)
+	}
+
+	if controllerutil.AddFinalizer(&resource, v1alpha1.Finalizer) {
+		if err := r.Update(ctx, &resource); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	password, passwordVersion, err := r.password(ctx, &resource)
+	if err != nil {
+		r.setCondition(&resource, v1alpha1.ConditionReady, metav1.ConditionFalse, ReasonSecretError, err.Error())
+		return ctrl.Result{}, r.updateStatus(ctx, &resource, err)
+	}
+
+	peer, err := r.apply(ctx, &resource, password, passwordVersion)
+	if err != nil {
+		logger.Error(err, "Failed to reconcile peer with FlintRoute")
+		r.setCondition(&resource, v1alpha1.ConditionReady, metav1.ConditionFalse, ReasonAPIError, err.Error())
+		return ctrl.Result{}, r.updateStatus(ctx, &resource, err)
+	}
+
+	resource.Status.PeerID = peer.ID
+	resource.Status.SyncStatus = peer.SyncStatus
+	resource.Status.PasswordVersion = passwordVersion
+	resource.Status.ObservedGeneration = resource.Generation
+	r.setCondition(&resource, v1alpha1.ConditionReady, metav1.ConditionTrue, ReasonReconciled, "Peer matches the desired configuration")
+
+	result := ctrl.Result{RequeueAfter: r.ResyncInterval}
+	if peer.SyncStatus == "pending" {
+		r.setCondition(&resource, v1alpha1.ConditionSynced, metav1.ConditionFalse, ReasonSyncPending, peer.SyncError)
+		result.RequeueAfter = pendingRequeue
+	} else {
+		r.setCondition(&resource, v1alpha1.ConditionSynced, metav1.ConditionTrue, ReasonSyncComplete, "Peer is applied to FRR")
+	}
+
+	return result, r.updateStatus(ctx, &resource, nil)
+}
+
+// apply creates, adopts or updates the FlintRoute peer for resource
+func (r *BGPPeerReconciler) apply(ctx context.Context, resource *v1alpha1.BGPPeer, password, passwordVersion string) (*client.Peer, error) {
+	desired := desiredPeer(resource)
+
+	current, err := r.findPeer(ctx, resource)
+	if err != nil {
+		return nil, err
+	}
+
+	if current == nil {
+		desired.Password = password
+		peer, err := r.FlintRoute.CreatePeer(ctx, desired)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create peer: %w", err)
+		}
+		log.FromContext(ctx).Info("Created FlintRoute peer", "peerID", peer.ID)
+		return peer, nil
+	}
+
+	peer := current
+	if !matches(current, desired) {
+		if peer, err = r.FlintRoute.UpdatePeer(ctx, current.ID, desired); err != nil {
+			return nil, fmt.Errorf("failed to update peer: %w", err)
+		}
+		log.FromContext(ctx).Info("Updated FlintRoute peer", "peerID", peer.ID)
+	}
+
+	if passwordVersion != resource.Status.PasswordVersion {
+		if peer, err = r.FlintRoute.SetPeerPassword(ctx, current.ID, password); err != nil {
+			return nil, fmt.Errorf("failed to set peer password: %w", err)
+		}
+		log.FromContext(ctx).Info("Rotated FlintRoute peer password", "peerID", peer.ID)
+	}
+
+	return peer, nil
+}
+
+// findPeer returns the FlintRoute peer managed by resource, adopting an
+// existing peer with the same IP address if none is recorded yet
+func (r *BGPPeerReconciler) findPeer(ctx context.Context, resource *v1alpha1.BGPPeer) (*client.Peer, error) {
+	if id := resource.Status.PeerID; id != 0 {
+		peer, err := r.FlintRoute.GetPeer(ctx, id)
+		if err == nil && peer.IPAddress == resource.Spec.IPAddress {
+			return peer, nil
+		}
+		if err != nil && !client.IsNotFound(err) {
+			return nil, fmt.Errorf("failed to get peer: %w", err)
+		}
+	}
+
+	peers, err := r.FlintRoute.ListPeers(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list peers: %w", err)
+	}
+
+	for _, peer := range peers {
+		if peer.IPAddress == resource.Spec.IPAddress {
+			return peer, nil
+		}
+	}
+	return nil, nil
+}
+
+// finalize deletes the FlintRoute peer and releases the resource
+func (r *BGPPeerReconciler) finalize(ctx context.Context, resource *v1alpha1.BGPPeer) error {
+	if !controllerutil.ContainsFinalizer(resource, v1alpha1.Finalizer) {
+		return nil
+	}
+
+	if id := resource.Status.PeerID; id != 0 {
+		if err := r.FlintRoute.DeletePeer(ctx, id); err != nil && !client.IsNotFound(err) {
+			return fmt.Errorf("failed to delete peer: %w", err)
+		}
+		log.FromContext(ctx).Info("Deleted FlintRoute peer", "peerID", id)
+	}
+
+	controllerutil.RemoveFinalizer(resource, v1alpha1.Finalizer)
+	return r.Update(ctx, resource)
+}
+
+// password reads the referenced password Secret. The returned version
+// changes whenever the reference or the Secret content changes.
+func (r *BGPPeerReconciler) password(ctx context.Context, resource *v1alpha1.BGPPeer) (string, string, error) {
+	ref := resource.Spec.PasswordSecretRef
+	if ref == nil {
+		return "", "", nil
+	}
+
+	var secret corev1.Secret
+	key := types.NamespacedName{Namespace: resource.Namespace, Name: ref.Name}
+	if err := r.Get(ctx, key, &secret); err != nil {
+		if apierrors.IsNotFound(err) {
+			return "", "", fmt.Errorf("password secret %q not found", ref.Name)
+		}
+		return "", "", err
+	}
+
+	value, ok := secret.Data[ref.Key]
+	if !ok {
+		return "", "", fmt.Errorf("password secret %q has no key %q", ref.Name, ref.Key)
+	}
+
+	return string(value), fmt.Sprintf("%s/%s@%s", ref.Name, ref.Key, secret.ResourceVersion), nil
+}
+
+// setCondition records a status condition for the current generation
+func (r *BGPPeerReconciler) setCondition(resource *v1alpha1.BGPPeer, conditionType string, status metav1.ConditionStatus, reason, message string) {
+	meta.SetStatusCondition(&resource.Status.Conditions, metav1.Condition{
+		Type:               conditionType,
+		Status:             status,
+		Reason:             reason,
+		Message:            message,
+		ObservedGeneration: resource.Generation,
+	})
+}
+
+// updateStatus writes the resource status, returning reconcileErr so that
+// failed reconciliations are retried with backoff
+func (r *BGPPeerReconciler) updateStatus(ctx context.Context, resource *v1alpha1.BGPPeer, reconcileErr error) error {
+	if err := r.Status().Update(ctx, resource); err != nil {
+		return err
+	}
+	return reconcileErr
+}
+
+// desiredPeer converts a BGPPeer spec into an SDK peer request
+func desiredPeer(resource *v1alpha1.BGPPeer) *client.PeerRequest {
+	spec := resource.Spec
+
+	name := spec.DisplayName
+	if name == "" {
+		name = resource.Name
+	}
+
+	enabled := true
+	if spec.Enabled != nil {
+		enabled = *spec.Enabled
+	}
+
+	return &client.PeerRequest{
+		Name:            name,
+		IPAddress:       spec.IPAddress,
+		ASN:             spec.ASN,
+		RemoteASN:       spec.RemoteASN,
+		Description:     spec.Description,
+		Enabled:         enabled,
+		Multihop:        spec.Multihop,
+		UpdateSource:    spec.UpdateSource,
+		RouteMapIn:      spec.RouteMapIn,
+		RouteMapOut:     spec.RouteMapOut,
+		PrefixListIn:    spec.PrefixListIn,
+		PrefixListOut:   spec.PrefixListOut,
+		MaxPrefixes:     spec.MaxPrefixes,
+		LocalPreference: spec.LocalPreference,
+	}
+}
+
+// matches reports whether the mutable attributes of peer equal desired
+func matches(peer *client.Peer, desired *client.PeerRequest) bool {
+	return peer.Name == desired.Name &&
+		peer.Description == desired.Description &&
+		peer.Enabled == desired.Enabled &&
+		peer.Multihop == desired.Multihop &&
+		peer.UpdateSource == desired.UpdateSource &&
+		peer.RouteMapIn == desired.RouteMapIn &&
+		peer.RouteMapOut == desired.RouteMapOut &&
+		peer.PrefixListIn == desired.PrefixListIn &&
+		peer.PrefixListOut == desired.PrefixListOut &&
+		peer.MaxPrefixes == desired.MaxPrefixes &&
+		peer.LocalPreference == desired.LocalPreference
+}
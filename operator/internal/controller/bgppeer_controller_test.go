@@ -0,0 +1,255 @@
+package controller
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/padminisys/flintroute/operator/api/v1alpha1"
+	"github.com/padminisys/flintroute/pkg/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+// fakeFlintRoute is an in-memory PeerAPI
+type fakeFlintRoute struct {
+	peers     map[uint]*client.Peer
+	passwords map[uint]string
+	nextID    uint
+	updates   int
+}
+
+func newFakeFlintRoute() *fakeFlintRoute {
+	return &fakeFlintRoute{peers: map[uint]*client.Peer{}, passwords: map[uint]string{}, nextID: 1}
+}
+
+func notFound() error {
+	return &client.APIError{StatusCode: http.StatusNotFound, Code: client.CodePeerNotFound}
+}
+
+func (f *fakeFlintRoute) CreatePeer(ctx context.Context, req *client.PeerRequest) (*client.Peer, error) {
+	peer := &client.Peer{ID: f.nextID, SyncStatus: "synced"}
+	f.nextID++
+	f.write(peer, req)
+	f.peers[peer.ID] = peer
+	f.passwords[peer.ID] = req.Password
+	return peer, nil
+}
+
+func (f *fakeFlintRoute) GetPeer(ctx context.Context, id uint) (*client.Peer, error) {
+	peer, ok := f.peers[id]
+	if !ok {
+		return nil, notFound()
+	}
+	return peer, nil
+}
+
+func (f *fakeFlintRoute) ListPeers(ctx context.Context) ([]*client.Peer, error) {
+	var peers []*client.Peer
+	for _, p := range f.peers {
+		peers = append(peers, p)
+	}
+	return peers, nil
+}
+
+func (f *fakeFlintRoute) UpdatePeer(ctx context.Context, id uint, req *client.PeerRequest) (*client.Peer, error) {
+	peer, ok := f.peers[id]
+	if !ok {
+		return nil, notFound()
+	}
+	f.updates++
+	f.write(peer, req)
+	return peer, nil
+}
+
+func (f *fakeFlintRoute) SetPeerPassword(ctx context.Context, id uint, password string) (*client.Peer, error) {
+	peer, ok := f.peers[id]
+	if !ok {
+		return nil, notFound()
+	}
+	f.passwords[id] = password
+	return peer, nil
+}
+
+func (f *fakeFlintRoute) DeletePeer(ctx context.Context, id uint) error {
+	if _, ok := f.peers[id]; !ok {
+		return notFound()
+	}
+	delete(f.peers, id)
+	return nil
+}
+
+func (f *fakeFlintRoute) write(peer *client.Peer, req *client.PeerRequest) {
+	peer.Name = req.Name
+	peer.IPAddress = req.IPAddress
+	peer.ASN = req.ASN
+	peer.RemoteASN = req.RemoteASN
+	peer.Description = req.Description
+	peer.Enabled = req.Enabled
+	peer.Multihop = req.Multihop
+	peer.MaxPrefixes = req.MaxPrefixes
+	peer.HasPassword = req.Password != ""
+}
+
+func setupReconciler(t *testing.T, objects ...ctrlclient.Object) (*BGPPeerReconciler, *fakeFlintRoute) {
+	t.Helper()
+
+	scheme := runtime.NewScheme()
+	require.NoError(t, clientgoscheme.AddToScheme(scheme))
+	require.NoError(t, v1alpha1.AddToScheme(scheme))
+
+	k8s := fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithObjects(objects...).
+		WithStatusSubresource(&v1alpha1.BGPPeer{}).
+		Build()
+
+	api := newFakeFlintRoute()
+	return &BGPPeerReconciler{Client: k8s, FlintRoute: api, ResyncInterval: time.Minute}, api
+}
+
+func newResource() *v1alpha1.BGPPeer {
+	return &v1alpha1.BGPPeer{
+		ObjectMeta: metav1.ObjectMeta{Name: "upstream-a", Namespace: "default", Generation: 1},
+		Spec: v1alpha1.BGPPeerSpec{
+			IPAddress:   "192.0.2.1",
+			ASN:         65001,
+			RemoteASN:   65002,
+			Multihop:    1,
+			MaxPrefixes: 1000,
+			PasswordSecretRef: &v1alpha1.SecretKeySelector{
+				Name: "upstream-a-auth",
+				Key:  "password",
+			},
+		},
+	}
+}
+
+func newSecret(password string) *corev1.Secret {
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "upstream-a-auth", Namespace: "default"},
+		Data:       map[string][]byte{"password": []byte(password)},
+	}
+}
+
+func reconcileResource(t *testing.T, r *BGPPeerReconciler) (ctrl.Result, *v1alpha1.BGPPeer) {
+	t.Helper()
+
+	key := types.NamespacedName{Namespace: "default", Name: "upstream-a"}
+	result, err := r.Reconcile(context.Background(), ctrl.Request{NamespacedName: key})
+	require.NoError(t, err)
+
+	var resource v1alpha1.BGPPeer
+	require.NoError(t, r.Get(context.Background(), key, &resource))
+	return result, &resource
+}
+
+func TestReconcileCreatesPeer(t *testing.T) {
+	r, api := setupReconciler(t, newResource(), newSecret("s3cret"))
+
+	result, resource := reconcileResource(t, r)
+
+	require.Len(t, api.peers, 1)
+	peer := api.peers[resource.Status.PeerID]
+	require.NotNil(t, peer)
+	assert.Equal(t, "upstream-a", peer.Name)
+	assert.True(t, peer.Enabled, "enabled defaults to true")
+	assert.Equal(t, "s3cret", api.passwords[peer.ID])
+
+	assert.Contains(t, resource.Finalizers, v1alpha1.Finalizer)
+	assert.True(t, meta.IsStatusConditionTrue(resource.Status.Conditions, v1alpha1.ConditionReady))
+	assert.True(t, meta.IsStatusConditionTrue(resource.Status.Conditions, v1alpha1.ConditionSynced))
+	assert.Equal(t, int64(1), resource.Status.ObservedGeneration)
+	assert.Equal(t, time.Minute, result.RequeueAfter)
+
+	t.Run("Second pass is a no-op", func(t *testing.T) {
+		reconcileResource(t, r)
+		assert.Len(t, api.peers, 1)
+		assert.Zero(t, api.updates)
+	})
+}
+
+func TestReconcileAdoptsAndUpdates(t *testing.T) {
+	r, api := setupReconciler(t, newResource(), newSecret("s3cret"))
+	existing, _ := api.CreatePeer(context.Background(), &client.PeerRequest{
+		Name:      "legacy",
+		IPAddress: "192.0.2.1",
+		Enabled:   true,
+	})
+
+	_, resource := reconcileResource(t, r)
+
+	assert.Equal(t, existing.ID, resource.Status.PeerID)
+	assert.Len(t, api.peers, 1)
+	assert.Equal(t, "upstream-a", api.peers[existing.ID].Name)
+	assert.Equal(t, 1000, api.peers[existing.ID].MaxPrefixes)
+	assert.Equal(t, "s3cret", api.passwords[existing.ID])
+}
+
+func TestReconcileRotatesPassword(t *testing.T) {
+	secret := newSecret("old")
+	r, api := setupReconciler(t, newResource(), secret)
+
+	_, resource := reconcileResource(t, r)
+	id := resource.Status.PeerID
+
+	secret.Data["password"] = []byte("new")
+	require.NoError(t, r.Update(context.Background(), secret))
+
+	reconcileResource(t, r)
+	assert.Equal(t, "new", api.passwords[id])
+}
+
+func TestReconcileMissingSecret(t *testing.T) {
+	r, api := setupReconciler(t, newResource())
+
+	key := types.NamespacedName{Namespace: "default", Name: "upstream-a"}
+	_, err := r.Reconcile(context.Background(), ctrl.Request{NamespacedName: key})
+	assert.Error(t, err)
+	assert.Empty(t, api.peers)
+
+	var resource v1alpha1.BGPPeer
+	require.NoError(t, r.Get(context.Background(), key, &resource))
+	cond := meta.FindStatusCondition(resource.Status.Conditions, v1alpha1.ConditionReady)
+	require.NotNil(t, cond)
+	assert.Equal(t, metav1.ConditionFalse, cond.Status)
+	assert.Equal(t, ReasonSecretError, cond.Reason)
+}
+
+func TestReconcileDeletesPeer(t *testing.T) {
+	r, api := setupReconciler(t, newResource(), newSecret("s3cret"))
+	_, resource := reconcileResource(t, r)
+	require.Len(t, api.peers, 1)
+
+	require.NoError(t, r.Delete(context.Background(), resource))
+
+	key := types.NamespacedName{Namespace: "default", Name: "upstream-a"}
+	_, err := r.Reconcile(context.Background(), ctrl.Request{NamespacedName: key})
+	require.NoError(t, err)
+
+	assert.Empty(t, api.peers)
+	err = r.Get(context.Background(), key, &v1alpha1.BGPPeer{})
+	assert.True(t, apierrors.IsNotFound(err), "resource should be gone once the finalizer is removed")
+}
+
+func TestPeersForSecret(t *testing.T) {
+	other := newResource()
+	other.Name = "unrelated"
+	other.Spec.PasswordSecretRef = nil
+	r, _ := setupReconciler(t, newResource(), other)
+
+	requests := r.peersForSecret(context.Background(), newSecret("x"))
+	require.Len(t, requests, 1)
+	assert.Equal(t, "upstream-a", requests[0].Name)
+}
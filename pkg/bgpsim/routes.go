@@ -0,0 +1,373 @@
+package bgpsim
+
+import (
+	"fmt"
+	"net/netip"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Route origins
+const (
+	OriginIGP        = "igp"
+	OriginEGP        = "egp"
+	OriginIncomplete = "incomplete"
+)
+
+// Address families
+const (
+	FamilyIPv4 = "ipv4"
+	FamilyIPv6 = "ipv6"
+)
+
+// RouteEntry is a prefix advertised by a peer, as held in the RIB
+type RouteEntry struct {
+	Prefix      string    `json:"prefix"`
+	Family      string    `json:"family"`
+	Peer        string    `json:"peer"`
+	NextHop     string    `json:"next_hop"`
+	ASPath      []uint32  `json:"as_path"`
+	Origin      string    `json:"origin"`
+	LocalPref   uint32    `json:"local_pref"`
+	MED         uint32    `json:"med"`
+	Communities []string  `json:"communities,omitempty"`
+	Best        bool      `json:"best"`
+	ReceivedAt  time.Time `json:"received_at"`
+}
+
+// RouteFilter selects routes from the RIB. Empty fields match every route.
+type RouteFilter struct {
+	Peer     string
+	Prefix   string
+	Family   string
+	BestOnly bool
+}
+
+// defaultLocalPref is the local preference of routes injected without one
+const defaultLocalPref = 100
+
+// InjectRoutes adds routes advertised by a peer, replacing routes it already
+// advertised for the same prefixes. Routes only show up in the RIB while the
+// peer's session is established.
+func (s *BGPState) InjectRoutes(peerIP string, routes []RouteEntry) error {
+	peer, err := s.GetPeer(peerIP)
+	if err != nil {
+		return err
+	}
+
+	normalized := make([]*RouteEntry, 0, len(routes))
+	now := time.Now()
+	for _, route := range routes {
+		prefix, err := netip.ParsePrefix(route.Prefix)
+		if err != nil {
+			return fmt.Errorf("invalid prefix %q: %w", route.Prefix, err)
+		}
+		prefix = prefix.Masked()
+
+		entry := route
+		entry.Prefix = prefix.String()
+		entry.Family = prefixFamily(prefix)
+		entry.Peer = peerIP
+		entry.Best = false
+		entry.ReceivedAt = now
+		if entry.NextHop == "" {
+			entry.NextHop = peerIP
+		} else if _, err := netip.ParseAddr(entry.NextHop); err != nil {
+			return fmt.Errorf("invalid next hop %q for %s: %w", route.NextHop, entry.Prefix, err)
+		}
+		if len(entry.ASPath) == 0 {
+			entry.ASPath = []uint32{peer.RemoteASN}
+		}
+		switch entry.Origin {
+		case "":
+			entry.Origin = OriginIGP
+		case OriginIGP, OriginEGP, OriginIncomplete:
+		default:
+			return fmt.Errorf("invalid origin %q for %s", route.Origin, entry.Prefix)
+		}
+		if entry.LocalPref == 0 {
+			entry.LocalPref = defaultLocalPref
+		}
+		normalized = append(normalized, &entry)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.peers[peerIP]; !exists {
+		return fmt.Errorf("peer %s not found", peerIP)
+	}
+	table, exists := s.routes[peerIP]
+	if !exists {
+		table = make(map[string]*RouteEntry)
+		s.routes[peerIP] = table
+	}
+	for _, entry := range normalized {
+		table[entry.Prefix] = entry
+	}
+	s.updatePrefixCountLocked(peerIP)
+
+	return nil
+}
+
+// GenerateRoutes injects count consecutive prefixes advertised by a peer,
+// starting at start and stepping by its size, e.g. 10.100.0.0/24,
+// 10.100.1.0/24, ...
+func (s *BGPState) GenerateRoutes(peerIP, start string, count int) error {
+	prefix, err := netip.ParsePrefix(start)
+	if err != nil {
+		return fmt.Errorf("invalid start prefix %q: %w", start, err)
+	}
+	prefix = prefix.Masked()
+
+	routes := make([]RouteEntry, 0, count)
+	for i := 0; i < count; i++ {
+		routes = append(routes, RouteEntry{Prefix: prefix.String()})
+		next, ok := nextPrefix(prefix)
+		if !ok && i < count-1 {
+			return fmt.Errorf("address space exhausted after %d prefixes from %s", i+1, start)
+		}
+		prefix = next
+	}
+
+	return s.InjectRoutes(peerIP, routes)
+}
+
+// WithdrawRoutes removes the given prefixes advertised by a peer, or every
+// prefix it advertised when prefixes is empty. It returns the number of
+// routes removed.
+func (s *BGPState) WithdrawRoutes(peerIP string, prefixes []string) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.peers[peerIP]; !exists {
+		return 0, fmt.Errorf("peer %s not found", peerIP)
+	}
+
+	table := s.routes[peerIP]
+	if len(prefixes) == 0 {
+		// Keep an empty table so that the session reports no prefixes rather
+		// than the simulated default
+		s.routes[peerIP] = make(map[string]*RouteEntry)
+		s.updatePrefixCountLocked(peerIP)
+		return len(table), nil
+	}
+
+	removed := 0
+	for _, p := range prefixes {
+		prefix, err := netip.ParsePrefix(p)
+		if err != nil {
+			return removed, fmt.Errorf("invalid prefix %q: %w", p, err)
+		}
+		key := prefix.Masked().String()
+		if _, exists := table[key]; exists {
+			delete(table, key)
+			removed++
+		}
+	}
+	s.updatePrefixCountLocked(peerIP)
+
+	return removed, nil
+}
+
+// GetRoutes returns the routes in the RIB matching filter, sorted by prefix
+// and then peer. Only routes from established sessions are in the RIB, and
+// for each prefix the best of them is marked.
+func (s *BGPState) GetRoutes(filter RouteFilter) ([]*RouteEntry, error) {
+	var prefixFilter string
+	if filter.Prefix != "" {
+		prefix, err := netip.ParsePrefix(filter.Prefix)
+		if err != nil {
+			return nil, fmt.Errorf("invalid prefix %q: %w", filter.Prefix, err)
+		}
+		prefixFilter = prefix.Masked().String()
+	}
+	switch filter.Family {
+	case "", FamilyIPv4, FamilyIPv6:
+	default:
+		return nil, fmt.Errorf("invalid family %q (must be %s or %s)", filter.Family, FamilyIPv4, FamilyIPv6)
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	// Group the candidate paths by prefix so that the best one can be picked
+	// even when filtering by peer
+	paths := make(map[string][]*RouteEntry)
+	for peerIP, table := range s.routes {
+		session, exists := s.sessions[peerIP]
+		if !exists || session.State != StateEstablished {
+			continue
+		}
+		for prefix, route := range table {
+			if prefixFilter != "" && prefix != prefixFilter {
+				continue
+			}
+			if filter.Family != "" && route.Family != filter.Family {
+				continue
+			}
+			routeCopy := *route
+			paths[prefix] = append(paths[prefix], &routeCopy)
+		}
+	}
+
+	routes := make([]*RouteEntry, 0, len(paths))
+	for _, candidates := range paths {
+		best := candidates[0]
+		for _, route := range candidates[1:] {
+			if betterPath(route, best) {
+				best = route
+			}
+		}
+		best.Best = true
+
+		for _, route := range candidates {
+			if filter.Peer != "" && route.Peer != filter.Peer {
+				continue
+			}
+			if filter.BestOnly && !route.Best {
+				continue
+			}
+			routes = append(routes, route)
+		}
+	}
+
+	sort.Slice(routes, func(i, j int) bool {
+		if routes[i].Prefix != routes[j].Prefix {
+			return comparePrefixes(routes[i].Prefix, routes[j].Prefix) < 0
+		}
+		return comparePeers(routes[i].Peer, routes[j].Peer) < 0
+	})
+
+	return routes, nil
+}
+
+// GetRouteCount returns the number of routes injected for every peer,
+// established or not
+func (s *BGPState) GetRouteCount() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	count := 0
+	for _, table := range s.routes {
+		count += len(table)
+	}
+	return count
+}
+
+// receivedPrefixCountLocked returns the number of prefixes an established
+// session reports receiving: the injected routes, or the simulated default
+// when none were injected. The caller must hold s.mu.
+func (s *BGPState) receivedPrefixCountLocked(peerIP string) int32 {
+	if table, exists := s.routes[peerIP]; exists {
+		return int32(len(table))
+	}
+	return 100
+}
+
+// updatePrefixCountLocked refreshes an established session's received prefix
+// count after its routes changed. The caller must hold s.mu.
+func (s *BGPState) updatePrefixCountLocked(peerIP string) {
+	if session, exists := s.sessions[peerIP]; exists && session.State == StateEstablished {
+		session.PrefixesReceived = s.receivedPrefixCountLocked(peerIP)
+	}
+}
+
+// betterPath reports whether a is preferred over b: higher local preference,
+// then shorter AS path, then lower origin, then lower MED, then the lower
+// peer address
+func betterPath(a, b *RouteEntry) bool {
+	if a.LocalPref != b.LocalPref {
+		return a.LocalPref > b.LocalPref
+	}
+	if len(a.ASPath) != len(b.ASPath) {
+		return len(a.ASPath) < len(b.ASPath)
+	}
+	if originRank(a.Origin) != originRank(b.Origin) {
+		return originRank(a.Origin) < originRank(b.Origin)
+	}
+	if a.MED != b.MED {
+		return a.MED < b.MED
+	}
+	return comparePeers(a.Peer, b.Peer) < 0
+}
+
+// originRank orders origins from most to least preferred
+func originRank(origin string) int {
+	switch origin {
+	case OriginIGP:
+		return 0
+	case OriginEGP:
+		return 1
+	default:
+		return 2
+	}
+}
+
+// prefixFamily returns the address family of a prefix
+func prefixFamily(prefix netip.Prefix) string {
+	if prefix.Addr().Is4() {
+		return FamilyIPv4
+	}
+	return FamilyIPv6
+}
+
+// nextPrefix returns the prefix of the same size following prefix, reporting
+// false when there is none
+func nextPrefix(prefix netip.Prefix) (netip.Prefix, bool) {
+	addr := prefix.Addr().As16()
+	bits := prefix.Bits()
+	if prefix.Addr().Is4() {
+		bits += 96
+	}
+	if bits == 0 {
+		return netip.Prefix{}, false
+	}
+
+	// Add one at the last bit of the network part, carrying upwards
+	bit := bits - 1
+	for i := bit / 8; i >= 0; i-- {
+		inc := byte(1)
+		if i == bit/8 {
+			inc = 1 << (7 - bit%8)
+		}
+		sum := addr[i] + inc
+		carry := sum < addr[i]
+		addr[i] = sum
+		if !carry {
+			next := netip.AddrFrom16(addr)
+			if prefix.Addr().Is4() {
+				if i < 12 {
+					return netip.Prefix{}, false
+				}
+				next = next.Unmap()
+			}
+			return netip.PrefixFrom(next, prefix.Bits()), true
+		}
+	}
+	return netip.Prefix{}, false
+}
+
+// comparePrefixes orders prefixes by address and then length
+func comparePrefixes(a, b string) int {
+	pa, errA := netip.ParsePrefix(a)
+	pb, errB := netip.ParsePrefix(b)
+	if errA != nil || errB != nil {
+		return strings.Compare(a, b)
+	}
+	if c := pa.Addr().Compare(pb.Addr()); c != 0 {
+		return c
+	}
+	return pa.Bits() - pb.Bits()
+}
+
+// comparePeers orders peers by address
+func comparePeers(a, b string) int {
+	pa, errA := netip.ParseAddr(a)
+	pb, errB := netip.ParseAddr(b)
+	if errA != nil || errB != nil {
+		return strings.Compare(a, b)
+	}
+	return pa.Compare(pb)
+}
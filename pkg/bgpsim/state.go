@@ -1,4 +1,7 @@
-package main
+// Package bgpsim simulates BGP peers, their sessions and the routes they
+// advertise, for stand-ins of FRR: the functional tests' mock FRR server
+// and FlintRoute's demo mode.
+package bgpsim
 
 import (
 	"fmt"
@@ -11,6 +14,16 @@ type BGPState struct {
 	mu       sync.RWMutex
 	peers    map[string]*PeerState
 	sessions map[string]*SessionState
+	holds    map[string]*stateHold
+	routes   map[string]map[string]*RouteEntry // by peer, then prefix
+	// pinned sessions keep their state until unpinned
+	pinned map[string]bool
+}
+
+// stateHold stops a session's establishment at a state until released
+type stateHold struct {
+	state    string
+	released chan struct{}
 }
 
 // PeerState represents the configuration state of a BGP peer
@@ -59,6 +72,9 @@ func NewBGPState() *BGPState {
 	return &BGPState{
 		peers:    make(map[string]*PeerState),
 		sessions: make(map[string]*SessionState),
+		holds:    make(map[string]*stateHold),
+		routes:   make(map[string]map[string]*RouteEntry),
+		pinned:   make(map[string]bool),
 	}
 }
 
@@ -105,6 +121,9 @@ func (s *BGPState) RemovePeer(ipAddress string) error {
 
 	delete(s.peers, ipAddress)
 	delete(s.sessions, ipAddress)
+	delete(s.routes, ipAddress)
+	delete(s.pinned, ipAddress)
+	s.releaseLocked(ipAddress, nil)
 
 	return nil
 }
@@ -222,27 +241,189 @@ func (s *BGPState) UpdateSessionState(ipAddress, state string) error {
 
 // SimulateSessionEstablishment simulates the BGP session establishment process
 func (s *BGPState) SimulateSessionEstablishment(ipAddress string, delay time.Duration) {
+	go s.establish(ipAddress, delay)
+}
+
+// establish walks a session through the establishment states, stopping at a
+// held state until the hold is released. It returns early when the peer is
+// removed or its session pinned.
+func (s *BGPState) establish(ipAddress string, delay time.Duration) {
 	states := []string{StateConnect, StateActive, StateOpenSent, StateOpenConfirm, StateEstablished}
 
+	for _, state := range states {
+		time.Sleep(delay)
+		s.mu.Lock()
+		session, exists := s.sessions[ipAddress]
+		if !exists || s.pinned[ipAddress] {
+			s.mu.Unlock()
+			return
+		}
+		s.setStateLocked(session, state)
+		hold := s.holds[ipAddress]
+		s.mu.Unlock()
+
+		if hold != nil && hold.state == state {
+			<-hold.released
+		}
+	}
+}
+
+// SimulateFlap drops a session to Idle and re-establishes it count times,
+// waiting interval between flaps
+func (s *BGPState) SimulateFlap(ipAddress string, count int, interval, delay time.Duration) error {
+	s.mu.RLock()
+	_, exists := s.sessions[ipAddress]
+	s.mu.RUnlock()
+	if !exists {
+		return fmt.Errorf("session for peer %s not found", ipAddress)
+	}
+
 	go func() {
-		for _, state := range states {
-			time.Sleep(delay)
+		for i := 0; i < count; i++ {
+			if i > 0 {
+				time.Sleep(interval)
+			}
 			s.mu.Lock()
-			if session, exists := s.sessions[ipAddress]; exists {
-				session.State = state
-				session.StateChangedAt = time.Now()
-
-				// Simulate some traffic when established
-				if state == StateEstablished {
-					session.PrefixesReceived = 100
-					session.PrefixesSent = 50
-					session.MessagesReceived = 1000
-					session.MessagesSent = 900
-				}
+			session, exists := s.sessions[ipAddress]
+			if !exists || s.pinned[ipAddress] {
+				s.mu.Unlock()
+				return
 			}
+			session.State = StateIdle
+			session.StateChangedAt = time.Now()
+			session.Uptime = 0
+			session.PrefixesReceived = 0
+			session.LastError = "simulated session flap"
 			s.mu.Unlock()
+
+			s.establish(ipAddress, delay)
 		}
 	}()
+
+	return nil
+}
+
+// setStateLocked moves a session to state, simulating some traffic when it
+// is established. The caller must hold s.mu.
+func (s *BGPState) setStateLocked(session *SessionState, state string) {
+	session.State = state
+	session.StateChangedAt = time.Now()
+
+	if state == StateEstablished {
+		session.PrefixesReceived = s.receivedPrefixCountLocked(session.IPAddress)
+		session.PrefixesSent = 50
+		session.MessagesReceived = 1000
+		session.MessagesSent = 900
+	} else {
+		session.Uptime = 0
+		session.PrefixesReceived = 0
+	}
+}
+
+// PinState puts a session in state, with lastError as the reason it last
+// went down, and keeps it there until UnpinState is called: establishment
+// and flaps leave it alone.
+func (s *BGPState) PinState(ipAddress, state, lastError string) error {
+	switch state {
+	case StateIdle, StateConnect, StateActive, StateOpenSent, StateOpenConfirm, StateEstablished:
+	default:
+		return fmt.Errorf("invalid state to pin: %s", state)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	session, exists := s.sessions[ipAddress]
+	if !exists {
+		return fmt.Errorf("session for peer %s not found", ipAddress)
+	}
+
+	s.pinned[ipAddress] = true
+	// A held establishment would wait for a state it no longer gets to
+	s.releaseLocked(ipAddress, nil)
+	if session.State != state {
+		s.setStateLocked(session, state)
+	}
+	session.LastError = lastError
+
+	return nil
+}
+
+// UnpinState lets a pinned session change state again. It stays where it
+// is until it is established or flapped.
+func (s *BGPState) UnpinState(ipAddress string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.pinned[ipAddress] {
+		return fmt.Errorf("no state pinned for peer %s", ipAddress)
+	}
+	delete(s.pinned, ipAddress)
+
+	return nil
+}
+
+// IsPinned reports whether a session's state is pinned
+func (s *BGPState) IsPinned(ipAddress string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.pinned[ipAddress]
+}
+
+// HoldState stops the session's next establishment at state until
+// ReleaseState is called or, when duration is positive, duration has passed.
+// A hold replaces any earlier one for the session.
+func (s *BGPState) HoldState(ipAddress, state string, duration time.Duration) error {
+	switch state {
+	case StateConnect, StateActive, StateOpenSent, StateOpenConfirm, StateEstablished:
+	default:
+		return fmt.Errorf("invalid state to hold: %s", state)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.sessions[ipAddress]; !exists {
+		return fmt.Errorf("session for peer %s not found", ipAddress)
+	}
+
+	s.releaseLocked(ipAddress, nil)
+	hold := &stateHold{state: state, released: make(chan struct{})}
+	s.holds[ipAddress] = hold
+	if duration > 0 {
+		time.AfterFunc(duration, func() {
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			s.releaseLocked(ipAddress, hold)
+		})
+	}
+
+	return nil
+}
+
+// ReleaseState releases the session's hold, letting its establishment
+// continue
+func (s *BGPState) ReleaseState(ipAddress string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.holds[ipAddress]; !exists {
+		return fmt.Errorf("no state held for peer %s", ipAddress)
+	}
+	s.releaseLocked(ipAddress, nil)
+
+	return nil
+}
+
+// releaseLocked releases the session's hold, only if it is hold when hold is
+// not nil. The caller must hold s.mu.
+func (s *BGPState) releaseLocked(ipAddress string, hold *stateHold) {
+	current, exists := s.holds[ipAddress]
+	if !exists || (hold != nil && current != hold) {
+		return
+	}
+	delete(s.holds, ipAddress)
+	close(current.released)
 }
 
 // IncrementSessionCounters increments message counters for a session
@@ -297,4 +478,4 @@ func (s *BGPState) GetEstablishedSessionCount() int {
 		}
 	}
 	return count
-}
\ No newline at end of file
+}
@@ -0,0 +1,91 @@
+package bgpsim
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func established(s *BGPState, ip string) func() bool {
+	return func() bool {
+		session, err := s.GetSessionState(ip)
+		return err == nil && session.State == StateEstablished
+	}
+}
+
+func TestSessionEstablishment(t *testing.T) {
+	s := NewBGPState()
+	require.NoError(t, s.AddPeer(&PeerState{IPAddress: "192.0.2.1", RemoteASN: 65002}))
+	assert.Error(t, s.AddPeer(&PeerState{IPAddress: "192.0.2.1"}))
+
+	session, err := s.GetSessionState("192.0.2.1")
+	require.NoError(t, err)
+	assert.Equal(t, StateIdle, session.State)
+
+	s.SimulateSessionEstablishment("192.0.2.1", 0)
+	require.Eventually(t, established(s, "192.0.2.1"), time.Second, time.Millisecond)
+	session, _ = s.GetSessionState("192.0.2.1")
+	assert.Equal(t, int32(100), session.PrefixesReceived)
+	assert.Equal(t, 1, s.GetEstablishedSessionCount())
+
+	require.NoError(t, s.RemovePeer("192.0.2.1"))
+	assert.Empty(t, s.GetAllSessions())
+}
+
+func TestPinState(t *testing.T) {
+	s := NewBGPState()
+	require.NoError(t, s.AddPeer(&PeerState{IPAddress: "192.0.2.1", RemoteASN: 65002}))
+	s.SimulateSessionEstablishment("192.0.2.1", 0)
+	require.Eventually(t, established(s, "192.0.2.1"), time.Second, time.Millisecond)
+
+	require.NoError(t, s.PinState("192.0.2.1", StateActive, "Hold Timer Expired"))
+	assert.True(t, s.IsPinned("192.0.2.1"))
+	session, _ := s.GetSessionState("192.0.2.1")
+	assert.Equal(t, StateActive, session.State)
+	assert.Equal(t, "Hold Timer Expired", session.LastError)
+	assert.Zero(t, session.PrefixesReceived)
+
+	// Neither establishment nor flaps move a pinned session
+	s.establish("192.0.2.1", 0)
+	require.NoError(t, s.SimulateFlap("192.0.2.1", 1, 0, 0))
+	time.Sleep(10 * time.Millisecond)
+	session, _ = s.GetSessionState("192.0.2.1")
+	assert.Equal(t, StateActive, session.State)
+
+	require.NoError(t, s.UnpinState("192.0.2.1"))
+	assert.Error(t, s.UnpinState("192.0.2.1"))
+	s.SimulateSessionEstablishment("192.0.2.1", 0)
+	require.Eventually(t, established(s, "192.0.2.1"), time.Second, time.Millisecond)
+
+	assert.Error(t, s.PinState("192.0.2.1", "Down", ""))
+	assert.Error(t, s.PinState("192.0.2.9", StateIdle, ""))
+}
+
+func TestGetRoutes(t *testing.T) {
+	s := NewBGPState()
+	for _, ip := range []string{"192.0.2.1", "192.0.2.2"} {
+		require.NoError(t, s.AddPeer(&PeerState{IPAddress: ip, RemoteASN: 65002}))
+		s.SimulateSessionEstablishment(ip, 0)
+		require.Eventually(t, established(s, ip), time.Second, time.Millisecond)
+	}
+
+	require.NoError(t, s.GenerateRoutes("192.0.2.1", "10.1.0.0/24", 3))
+	require.NoError(t, s.InjectRoutes("192.0.2.2", []RouteEntry{{Prefix: "10.1.1.0/24", LocalPref: 200}}))
+	assert.Equal(t, 4, s.GetRouteCount())
+
+	routes, err := s.GetRoutes(RouteFilter{Prefix: "10.1.1.0/24", BestOnly: true})
+	require.NoError(t, err)
+	require.Len(t, routes, 1)
+	assert.Equal(t, "192.0.2.2", routes[0].Peer)
+
+	session, _ := s.GetSessionState("192.0.2.1")
+	assert.Equal(t, int32(3), session.PrefixesReceived)
+
+	removed, err := s.WithdrawRoutes("192.0.2.1", nil)
+	require.NoError(t, err)
+	assert.Equal(t, 3, removed)
+	session, _ = s.GetSessionState("192.0.2.1")
+	assert.Zero(t, session.PrefixesReceived)
+}
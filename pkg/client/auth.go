@@ -1,6 +1,7 @@
 package client
 
 import (
+	"context"
 	"fmt"
 	"sync"
 	"time"
@@ -26,40 +27,45 @@ func NewTokenManager(client *APIClient) *TokenManager {
 func (tm *TokenManager) SetTokens(accessToken, refreshToken string, expiresIn int64) {
 	tm.mu.Lock()
 	defer tm.mu.Unlock()
-	
+
 	tm.accessToken = accessToken
 	tm.refreshToken = refreshToken
 	tm.expiresAt = time.Now().Add(time.Duration(expiresIn) * time.Second)
 }
 
-// GetAccessToken returns the current access token, refreshing if necessary
-func (tm *TokenManager) GetAccessToken() (string, error) {
+// GetAccessToken returns the current access token, refreshing if necessary.
+// A refresh is bound to ctx and aborts if ctx is cancelled.
+func (tm *TokenManager) GetAccessToken(ctx context.Context) (string, error) {
 	tm.mu.RLock()
-	
+
 	// Check if token is still valid (with 30 second buffer)
 	if time.Now().Add(30 * time.Second).Before(tm.expiresAt) {
 		token := tm.accessToken
 		tm.mu.RUnlock()
 		return token, nil
 	}
-	
+
 	refreshToken := tm.refreshToken
 	tm.mu.RUnlock()
-	
+
 	// Token is expired or about to expire, refresh it
 	if refreshToken == "" {
 		return "", fmt.Errorf("no refresh token available")
 	}
-	
+
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+
 	// Refresh the token
-	response, err := tm.client.RefreshToken(refreshToken)
+	response, err := tm.client.RefreshToken(ctx, refreshToken)
 	if err != nil {
 		return "", fmt.Errorf("failed to refresh token: %w", err)
 	}
-	
+
 	// Update tokens
 	tm.SetTokens(response.AccessToken, response.RefreshToken, response.ExpiresIn)
-	
+
 	return response.AccessToken, nil
 }
 
@@ -74,25 +80,34 @@ func (tm *TokenManager) GetRefreshToken() string {
 func (tm *TokenManager) Clear() {
 	tm.mu.Lock()
 	defer tm.mu.Unlock()
-	
+
 	tm.accessToken = ""
 	tm.refreshToken = ""
 	tm.expiresAt = time.Time{}
 }
 
+// expire marks the access token as expired so the next GetAccessToken
+// refreshes it
+func (tm *TokenManager) expire() {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+
+	tm.expiresAt = time.Time{}
+}
+
 // IsAuthenticated returns true if we have valid tokens
 func (tm *TokenManager) IsAuthenticated() bool {
 	tm.mu.RLock()
 	defer tm.mu.RUnlock()
-	
+
 	return tm.accessToken != "" && time.Now().Before(tm.expiresAt)
 }
 
 // GetAuthorizationHeader returns the Authorization header value
-func (tm *TokenManager) GetAuthorizationHeader() (string, error) {
-	token, err := tm.GetAccessToken()
+func (tm *TokenManager) GetAuthorizationHeader(ctx context.Context) (string, error) {
+	token, err := tm.GetAccessToken(ctx)
 	if err != nil {
 		return "", err
 	}
 	return fmt.Sprintf("Bearer %s", token), nil
-}
\ No newline at end of file
+}
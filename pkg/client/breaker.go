@@ -0,0 +1,115 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is returned without contacting the server while the
+// client's circuit breaker is open
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+// CircuitState is the state of a client's circuit breaker
+type CircuitState string
+
+// Circuit breaker states
+const (
+	// CircuitClosed lets calls through
+	CircuitClosed CircuitState = "closed"
+	// CircuitOpen fails calls at once until the cooldown has passed
+	CircuitOpen CircuitState = "open"
+	// CircuitHalfOpen lets a single trial call through after the cooldown
+	CircuitHalfOpen CircuitState = "half-open"
+)
+
+// circuitBreaker counts consecutive failed attempts and opens once there
+// are threshold of them
+type circuitBreaker struct {
+	threshold int
+	cooldown  time.Duration
+
+	mu       sync.Mutex
+	state    CircuitState
+	failures int
+	openedAt time.Time
+	// trial is set while the half-open trial call is in flight
+	trial bool
+}
+
+// newCircuitBreaker returns a closed circuit breaker
+func newCircuitBreaker(threshold int, cooldown time.Duration) *circuitBreaker {
+	return &circuitBreaker{threshold: threshold, cooldown: cooldown, state: CircuitClosed}
+}
+
+// State returns the breaker's state, open turning half-open once the
+// cooldown has passed
+func (b *circuitBreaker) State() CircuitState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.current()
+}
+
+// current returns the state; b.mu must be held
+func (b *circuitBreaker) current() CircuitState {
+	if b.state == CircuitOpen && time.Since(b.openedAt) >= b.cooldown {
+		b.state = CircuitHalfOpen
+	}
+	return b.state
+}
+
+// allow reports whether an attempt may go ahead, and whether it is the
+// trial attempt of a half-open circuit. Only one trial is let through
+// until its outcome is recorded.
+func (b *circuitBreaker) allow() (ok, trial bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.current() {
+	case CircuitOpen:
+		return false, false
+	case CircuitHalfOpen:
+		if b.trial {
+			return false, false
+		}
+		b.trial = true
+		return true, true
+	}
+	return true, false
+}
+
+// record records the outcome of an attempt allow let through. Attempts
+// abandoned by the caller's context count neither way.
+func (b *circuitBreaker) record(ctx context.Context, trial bool, resp *http.Response, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if trial {
+		b.trial = false
+	}
+
+	failed := err != nil || resp.StatusCode >= http.StatusInternalServerError
+	switch {
+	case err != nil && ctx.Err() != nil:
+		return
+	case !failed:
+		b.state = CircuitClosed
+		b.failures = 0
+	case trial || b.state != CircuitClosed:
+		b.open()
+	default:
+		b.failures++
+		if b.failures >= b.threshold {
+			b.open()
+		}
+	}
+}
+
+// open opens the circuit for a cooldown; b.mu must be held
+func (b *circuitBreaker) open() {
+	b.state = CircuitOpen
+	b.openedAt = time.Now()
+	b.failures = 0
+}
@@ -0,0 +1,2868 @@
+// Package client provides a Go SDK for the FlintRoute REST API.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"gopkg.in/yaml.v3"
+)
+
+// APIClient is a client for the FlintRoute REST API
+type APIClient struct {
+	baseURL        string
+	httpClient     *http.Client
+	tokenManager   *TokenManager
+	retryPolicy    RetryPolicy
+	requestTimeout time.Duration
+	logger         *zap.Logger
+
+	autoIdempotencyKeys bool
+	breaker             *circuitBreaker
+	middleware          []Middleware
+
+	rateLimitMu sync.Mutex
+	rateLimit   *RequestQuota
+}
+
+// NewAPIClient creates a new API client
+func NewAPIClient(baseURL string, logger *zap.Logger, opts ...Option) *APIClient {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+
+	client := &APIClient{
+		baseURL: baseURL,
+		httpClient: &http.Client{
+			Timeout: 30 * time.Second,
+		},
+		retryPolicy: DefaultRetryPolicy(),
+		logger:      logger,
+	}
+	client.tokenManager = NewTokenManager(client)
+
+	for _, opt := range opts {
+		opt(client)
+	}
+
+	return client
+}
+
+// SetTimeout sets the HTTP client timeout
+func (c *APIClient) SetTimeout(timeout time.Duration) {
+	c.httpClient.Timeout = timeout
+}
+
+// doRequest performs an HTTP request with automatic authentication and retries
+func (c *APIClient) doRequest(ctx context.Context, method, path string, body interface{}, authenticated bool) (*http.Response, error) {
+	var payload []byte
+	if body != nil {
+		jsonData, err := json.Marshal(body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal request body: %w", err)
+		}
+		payload = jsonData
+		c.logger.Debug("Request body", zap.String("body", string(jsonData)))
+	}
+
+	if method == http.MethodPost && authenticated && c.autoIdempotencyKeys && idempotencyKey(ctx) == "" {
+		ctx = WithIdempotencyKey(ctx, newIdempotencyKey())
+	}
+
+	attempts := max(c.retryPolicy.MaxAttempts, 1)
+	repeatable := isIdempotent(method) || (method == http.MethodPost && idempotencyKey(ctx) != "")
+
+	var resp *http.Response
+	var err error
+	for attempt := 1; attempt <= attempts; attempt++ {
+		if attempt > 1 {
+			delay, ok := c.retryPolicy.retryDelay(attempt-1, resp)
+			if !ok {
+				break
+			}
+			if resp != nil {
+				resp.Body.Close()
+			}
+			if err := sleep(ctx, delay); err != nil {
+				return nil, err
+			}
+			c.logger.Debug("Retrying request",
+				zap.String("method", method),
+				zap.String("path", path),
+				zap.Int("attempt", attempt),
+			)
+		}
+
+		resp, err = c.attempt(ctx, method, path, payload, authenticated)
+		if err == nil {
+			c.trackRateLimit(resp.Header)
+		}
+		if errors.Is(err, ErrCircuitOpen) || !c.retryPolicy.shouldRetry(resp, err, repeatable) {
+			break
+		}
+	}
+
+	return resp, err
+}
+
+// attempt sends a request through the circuit breaker, if there is one
+func (c *APIClient) attempt(ctx context.Context, method, path string, payload []byte, authenticated bool) (*http.Response, error) {
+	if c.breaker == nil {
+		return c.send(ctx, method, path, payload, authenticated)
+	}
+
+	ok, trial := c.breaker.allow()
+	if !ok {
+		return nil, fmt.Errorf("%s %s: %w", method, path, ErrCircuitOpen)
+	}
+	resp, err := c.send(ctx, method, path, payload, authenticated)
+	c.breaker.record(ctx, trial, resp, err)
+	return resp, err
+}
+
+// CircuitState returns the state of the client's circuit breaker; it is
+// always closed without one
+func (c *APIClient) CircuitState() CircuitState {
+	if c.breaker == nil {
+		return CircuitClosed
+	}
+	return c.breaker.State()
+}
+
+// RateLimit returns the caller's request quota, as of the latest response
+// carrying X-RateLimit headers. It reports false until one has been
+// received; the server only sends them to users with a quota.
+func (c *APIClient) RateLimit() (RequestQuota, bool) {
+	c.rateLimitMu.Lock()
+	defer c.rateLimitMu.Unlock()
+
+	if c.rateLimit == nil {
+		return RequestQuota{}, false
+	}
+	return *c.rateLimit, true
+}
+
+// trackRateLimit keeps the quota from a response's X-RateLimit headers
+func (c *APIClient) trackRateLimit(header http.Header) {
+	limit, err := strconv.Atoi(header.Get("X-RateLimit-Limit"))
+	if err != nil {
+		return
+	}
+	remaining, _ := strconv.Atoi(header.Get("X-RateLimit-Remaining"))
+	reset, _ := strconv.ParseInt(header.Get("X-RateLimit-Reset"), 10, 64)
+
+	c.rateLimitMu.Lock()
+	defer c.rateLimitMu.Unlock()
+	c.rateLimit = &RequestQuota{RequestsPerHour: limit, Remaining: remaining, ResetAt: time.Unix(reset, 0)}
+}
+
+// send performs a single HTTP round trip
+func (c *APIClient) send(ctx context.Context, method, path string, payload []byte, authenticated bool) (*http.Response, error) {
+	timeout := c.requestTimeout
+	if override, ok := callTimeout(ctx); ok {
+		timeout = override
+	}
+
+	cancel := context.CancelFunc(func() {})
+	if timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+	}
+
+	resp, err := c.roundTrip(ctx, method, path, payload, authenticated)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	// Keep the attempt context alive until the caller has read the body
+	resp.Body = &cancelOnClose{ReadCloser: resp.Body, cancel: cancel}
+	return resp, nil
+}
+
+// roundTrip builds and executes the HTTP request
+func (c *APIClient) roundTrip(ctx context.Context, method, path string, payload []byte, authenticated bool) (*http.Response, error) {
+	var bodyReader io.Reader
+	if payload != nil {
+		bodyReader = bytes.NewReader(payload)
+	}
+
+	fullURL := c.baseURL + path
+	req, err := http.NewRequestWithContext(ctx, method, fullURL, bodyReader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	if id := requestID(ctx); id != "" {
+		req.Header.Set("X-Request-ID", id)
+	}
+	if key := idempotencyKey(ctx); key != "" && method == http.MethodPost {
+		req.Header.Set("Idempotency-Key", key)
+	}
+	if id, ok := tenant(ctx); ok {
+		req.Header.Set("X-Tenant-ID", strconv.FormatUint(uint64(id), 10))
+	}
+
+	// Add authentication if required
+	if authenticated {
+		authHeader, err := c.tokenManager.GetAuthorizationHeader(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get authorization header: %w", err)
+		}
+		req.Header.Set("Authorization", authHeader)
+	}
+
+	c.logger.Debug("Making request",
+		zap.String("method", method),
+		zap.String("url", fullURL),
+		zap.Bool("authenticated", authenticated),
+	)
+
+	do := RoundTripFunc(c.httpClient.Do)
+	for i := len(c.middleware) - 1; i >= 0; i-- {
+		do = c.middleware[i](do)
+	}
+	resp, err := do(req)
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+
+	c.logger.Debug("Response received",
+		zap.Int("status", resp.StatusCode),
+		zap.String("status_text", resp.Status),
+	)
+
+	return resp, nil
+}
+
+// parseResponse parses the response body into the target struct
+func (c *APIClient) parseResponse(resp *http.Response, target interface{}) error {
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	c.logger.Debug("Response body", zap.String("body", string(body)))
+
+	if resp.StatusCode >= 400 {
+		apiErr := newAPIError(resp.StatusCode, body)
+		if apiErr.RequestID == "" {
+			apiErr.RequestID = resp.Header.Get("X-Request-ID")
+		}
+		apiErr.RetryAfter, _ = parseRetryAfter(resp.Header.Get("Retry-After"))
+		return apiErr
+	}
+
+	// Changes held for approval are accepted as change requests
+	if resp.StatusCode == http.StatusAccepted && strings.HasPrefix(resp.Header.Get("Location"), "/api/v1/changes/") {
+		var change ChangeRequest
+		if err := json.Unmarshal(body, &change); err != nil {
+			return fmt.Errorf("failed to parse response: %w", err)
+		}
+		return &PendingApprovalError{Change: &change}
+	}
+
+	if target != nil {
+		if err := json.Unmarshal(body, target); err != nil {
+			return fmt.Errorf("failed to parse response: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// Login authenticates with the API
+func (c *APIClient) Login(ctx context.Context, username, password string) (*LoginResponse, error) {
+	req := LoginRequest{
+		Username: username,
+		Password: password,
+	}
+
+	resp, err := c.doRequest(ctx, "POST", "/api/v1/auth/login", req, false)
+	if err != nil {
+		return nil, err
+	}
+
+	var loginResp LoginResponse
+	if err := c.parseResponse(resp, &loginResp); err != nil {
+		return nil, err
+	}
+
+	// Store tokens
+	c.tokenManager.SetTokens(loginResp.AccessToken, loginResp.RefreshToken, loginResp.ExpiresIn)
+
+	c.logger.Info("Logged in successfully", zap.String("username", username))
+
+	return &loginResp, nil
+}
+
+// RefreshToken refreshes the access token
+func (c *APIClient) RefreshToken(ctx context.Context, refreshToken string) (*TokenResponse, error) {
+	req := RefreshRequest{
+		RefreshToken: refreshToken,
+	}
+
+	resp, err := c.doRequest(ctx, "POST", "/api/v1/auth/refresh", req, false)
+	if err != nil {
+		return nil, err
+	}
+
+	var tokenResp TokenResponse
+	if err := c.parseResponse(resp, &tokenResp); err != nil {
+		return nil, err
+	}
+
+	c.logger.Info("Token refreshed successfully")
+
+	return &tokenResp, nil
+}
+
+// Logout logs out from the API
+func (c *APIClient) Logout(ctx context.Context) error {
+	resp, err := c.doRequest(ctx, "POST", "/api/v1/auth/logout", nil, true)
+	if err != nil {
+		return err
+	}
+
+	var msgResp MessageResponse
+	if err := c.parseResponse(resp, &msgResp); err != nil {
+		return err
+	}
+
+	// Clear tokens
+	c.tokenManager.Clear()
+
+	c.logger.Info("Logged out successfully")
+
+	return nil
+}
+
+// GetProfile gets the current user's profile
+func (c *APIClient) GetProfile(ctx context.Context) (*Profile, error) {
+	resp, err := c.doRequest(ctx, "GET", "/api/v1/me", nil, true)
+	if err != nil {
+		return nil, err
+	}
+
+	var profile Profile
+	if err := c.parseResponse(resp, &profile); err != nil {
+		return nil, err
+	}
+
+	return &profile, nil
+}
+
+// UpdateProfile changes the current user's email address
+func (c *APIClient) UpdateProfile(ctx context.Context, email string) (*Profile, error) {
+	resp, err := c.doRequest(ctx, "PUT", "/api/v1/me", &UpdateProfileRequest{Email: email}, true)
+	if err != nil {
+		return nil, err
+	}
+
+	var profile Profile
+	if err := c.parseResponse(resp, &profile); err != nil {
+		return nil, err
+	}
+
+	c.logger.Info("Profile updated")
+
+	return &profile, nil
+}
+
+// ChangePassword changes the current user's password. All of the user's
+// sessions are revoked, including this client's refresh token, so it has
+// to log in again once its access token expires.
+func (c *APIClient) ChangePassword(ctx context.Context, currentPassword, newPassword string) error {
+	req := &ChangePasswordRequest{CurrentPassword: currentPassword, NewPassword: newPassword}
+	resp, err := c.doRequest(ctx, "PUT", "/api/v1/me/password", req, true)
+	if err != nil {
+		return err
+	}
+
+	var msgResp MessageResponse
+	if err := c.parseResponse(resp, &msgResp); err != nil {
+		return err
+	}
+
+	c.logger.Info("Password changed")
+
+	return nil
+}
+
+// ListLoginSessions lists the current user's active sessions
+func (c *APIClient) ListLoginSessions(ctx context.Context) ([]*LoginSession, error) {
+	resp, err := c.doRequest(ctx, "GET", "/api/v1/me/sessions", nil, true)
+	if err != nil {
+		return nil, err
+	}
+
+	var sessionsResp LoginSessionsResponse
+	if err := c.parseResponse(resp, &sessionsResp); err != nil {
+		return nil, err
+	}
+
+	return sessionsResp.Sessions, nil
+}
+
+// RevokeLoginSession revokes one of the current user's sessions
+func (c *APIClient) RevokeLoginSession(ctx context.Context, id uint) error {
+	path := fmt.Sprintf("/api/v1/me/sessions/%d", id)
+	resp, err := c.doRequest(ctx, "DELETE", path, nil, true)
+	if err != nil {
+		return err
+	}
+
+	var msgResp MessageResponse
+	if err := c.parseResponse(resp, &msgResp); err != nil {
+		return err
+	}
+
+	c.logger.Info("Session revoked", zap.Uint("id", id))
+
+	return nil
+}
+
+// GetPreferences decodes the current user's preferences into target
+func (c *APIClient) GetPreferences(ctx context.Context, target interface{}) error {
+	resp, err := c.doRequest(ctx, "GET", "/api/v1/me/preferences", nil, true)
+	if err != nil {
+		return err
+	}
+
+	return c.parseResponse(resp, target)
+}
+
+// UpdatePreferences replaces the current user's preferences.
+// preferences must encode as a JSON object.
+func (c *APIClient) UpdatePreferences(ctx context.Context, preferences interface{}) error {
+	resp, err := c.doRequest(ctx, "PUT", "/api/v1/me/preferences", preferences, true)
+	if err != nil {
+		return err
+	}
+
+	var stored json.RawMessage
+	return c.parseResponse(resp, &stored)
+}
+
+// GetNotificationSettings gets the current user's alert notification
+// settings, or the defaults when the user has none
+func (c *APIClient) GetNotificationSettings(ctx context.Context) (*NotificationSettings, error) {
+	return c.notificationSettings(ctx, "GET", "/api/v1/me/notifications", nil)
+}
+
+// UpdateNotificationSettings replaces the current user's alert
+// notification settings
+func (c *APIClient) UpdateNotificationSettings(ctx context.Context, settings *NotificationSettings) (*NotificationSettings, error) {
+	return c.notificationSettings(ctx, "PUT", "/api/v1/me/notifications", settings)
+}
+
+// ResetNotificationSettings removes the current user's alert notification
+// settings, so the defaults apply again
+func (c *APIClient) ResetNotificationSettings(ctx context.Context) error {
+	resp, err := c.doRequest(ctx, "DELETE", "/api/v1/me/notifications", nil, true)
+	if err != nil {
+		return err
+	}
+
+	var msgResp MessageResponse
+	return c.parseResponse(resp, &msgResp)
+}
+
+// GetNotificationDefaults gets the alert notification settings of users
+// without their own. Requires the admin role.
+func (c *APIClient) GetNotificationDefaults(ctx context.Context) (*NotificationSettings, error) {
+	return c.notificationSettings(ctx, "GET", "/api/v1/admin/notifications/defaults", nil)
+}
+
+// UpdateNotificationDefaults replaces the alert notification settings of
+// users without their own. Requires the admin role.
+func (c *APIClient) UpdateNotificationDefaults(ctx context.Context, settings *NotificationSettings) (*NotificationSettings, error) {
+	return c.notificationSettings(ctx, "PUT", "/api/v1/admin/notifications/defaults", settings)
+}
+
+// SendTestEmail sends an email template rendered with sample data, to
+// check SMTP settings, templates and branding. An empty to sends it to the
+// admin's own address. Requires the admin role.
+func (c *APIClient) SendTestEmail(ctx context.Context, template, to string) (*TestEmail, error) {
+	resp, err := c.doRequest(ctx, "POST", "/api/v1/admin/email/test", &TestEmailRequest{Template: template, To: to}, true)
+	if err != nil {
+		return nil, err
+	}
+
+	var email TestEmail
+	if err := c.parseResponse(resp, &email); err != nil {
+		return nil, err
+	}
+
+	return &email, nil
+}
+
+// notificationSettings sends a notification settings request
+func (c *APIClient) notificationSettings(ctx context.Context, method, path string, body interface{}) (*NotificationSettings, error) {
+	resp, err := c.doRequest(ctx, method, path, body, true)
+	if err != nil {
+		return nil, err
+	}
+
+	var settings NotificationSettings
+	if err := c.parseResponse(resp, &settings); err != nil {
+		return nil, err
+	}
+
+	return &settings, nil
+}
+
+// GetUsage gets the current user's API usage over the last hours, 1 to 24;
+// 0 reports all 24
+func (c *APIClient) GetUsage(ctx context.Context, hours int) (*Usage, error) {
+	resp, err := c.doRequest(ctx, "GET", usagePath("/api/v1/usage", hours), nil, true)
+	if err != nil {
+		return nil, err
+	}
+
+	var usage Usage
+	if err := c.parseResponse(resp, &usage); err != nil {
+		return nil, err
+	}
+
+	return &usage, nil
+}
+
+// ListUsage lists the API usage of every user who made requests in the
+// last hours, busiest first. Requires the admin role.
+func (c *APIClient) ListUsage(ctx context.Context, hours int) (*UsageListResponse, error) {
+	resp, err := c.doRequest(ctx, "GET", usagePath("/api/v1/admin/usage", hours), nil, true)
+	if err != nil {
+		return nil, err
+	}
+
+	var usage UsageListResponse
+	if err := c.parseResponse(resp, &usage); err != nil {
+		return nil, err
+	}
+
+	return &usage, nil
+}
+
+// SetRequestQuota sets how many API requests per hour a user may make. nil
+// uses the server default; 0 is unlimited. Requires the admin role.
+func (c *APIClient) SetRequestQuota(ctx context.Context, userID uint, requestsPerHour *int) error {
+	path := fmt.Sprintf("/api/v1/admin/users/%d/quota", userID)
+	resp, err := c.doRequest(ctx, "PUT", path, &SetRequestQuotaRequest{RequestsPerHour: requestsPerHour}, true)
+	if err != nil {
+		return err
+	}
+
+	var profile Profile
+	if err := c.parseResponse(resp, &profile); err != nil {
+		return err
+	}
+
+	c.logger.Info("Request quota set", zap.Uint("user_id", userID))
+
+	return nil
+}
+
+// Impersonate gets a short-lived token acting as another user, for
+// troubleshooting what they see, and returns a client using it. The
+// returned client shares this client's HTTP client and options; it can't
+// refresh the token, so impersonate again once it expires. The reason is
+// kept in the audit log. Requires the admin role.
+func (c *APIClient) Impersonate(ctx context.Context, userID uint, reason string) (*APIClient, *ImpersonateResponse, error) {
+	path := fmt.Sprintf("/api/v1/admin/impersonate/%d", userID)
+	resp, err := c.doRequest(ctx, "POST", path, &ImpersonateRequest{Reason: reason}, true)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var impersonation ImpersonateResponse
+	if err := c.parseResponse(resp, &impersonation); err != nil {
+		return nil, nil, err
+	}
+
+	impersonated := &APIClient{
+		baseURL:        c.baseURL,
+		httpClient:     c.httpClient,
+		retryPolicy:    c.retryPolicy,
+		requestTimeout: c.requestTimeout,
+		logger:         c.logger,
+
+		autoIdempotencyKeys: c.autoIdempotencyKeys,
+		breaker:             c.breaker,
+		middleware:          c.middleware,
+	}
+	impersonated.tokenManager = NewTokenManager(impersonated)
+	impersonated.tokenManager.SetTokens(impersonation.AccessToken, "", impersonation.ExpiresIn)
+
+	c.logger.Info("Impersonating user", zap.String("username", impersonation.User.Username))
+
+	return impersonated, &impersonation, nil
+}
+
+// usagePath adds the hours parameter to a usage path
+func usagePath(path string, hours int) string {
+	if hours > 0 {
+		path += "?hours=" + strconv.Itoa(hours)
+	}
+	return path
+}
+
+// ListMyTenants lists the tenants you are a member of, with your role in
+// each
+func (c *APIClient) ListMyTenants(ctx context.Context) ([]*TenantMember, error) {
+	resp, err := c.doRequest(ctx, "GET", "/api/v1/me/tenants", nil, true)
+	if err != nil {
+		return nil, err
+	}
+
+	var membershipsResp TenantMembershipsResponse
+	if err := c.parseResponse(resp, &membershipsResp); err != nil {
+		return nil, err
+	}
+
+	return membershipsResp.Tenants, nil
+}
+
+// ListTenants lists all tenants. Requires the admin role.
+func (c *APIClient) ListTenants(ctx context.Context) ([]*Tenant, error) {
+	resp, err := c.doRequest(ctx, "GET", "/api/v1/admin/tenants", nil, true)
+	if err != nil {
+		return nil, err
+	}
+
+	var tenantsResp TenantsResponse
+	if err := c.parseResponse(resp, &tenantsResp); err != nil {
+		return nil, err
+	}
+
+	return tenantsResp.Tenants, nil
+}
+
+// GetTenant retrieves a tenant by ID. Requires the admin role.
+func (c *APIClient) GetTenant(ctx context.Context, id uint) (*Tenant, error) {
+	resp, err := c.doRequest(ctx, "GET", fmt.Sprintf("/api/v1/admin/tenants/%d", id), nil, true)
+	if err != nil {
+		return nil, err
+	}
+
+	var tenant Tenant
+	if err := c.parseResponse(resp, &tenant); err != nil {
+		return nil, err
+	}
+
+	return &tenant, nil
+}
+
+// CreateTenant creates a tenant. Names that are taken are rejected with
+// CodeTenantExists. Requires the admin role.
+func (c *APIClient) CreateTenant(ctx context.Context, tenant *TenantRequest) (*Tenant, error) {
+	resp, err := c.doRequest(ctx, "POST", "/api/v1/admin/tenants", tenant, true)
+	if err != nil {
+		return nil, err
+	}
+
+	var created Tenant
+	if err := c.parseResponse(resp, &created); err != nil {
+		return nil, err
+	}
+
+	c.logger.Info("Tenant created", zap.Uint("id", created.ID), zap.String("name", created.Name))
+
+	return &created, nil
+}
+
+// UpdateTenant renames a tenant or changes its description. Requires the
+// admin role.
+func (c *APIClient) UpdateTenant(ctx context.Context, id uint, tenant *TenantRequest) (*Tenant, error) {
+	resp, err := c.doRequest(ctx, "PUT", fmt.Sprintf("/api/v1/admin/tenants/%d", id), tenant, true)
+	if err != nil {
+		return nil, err
+	}
+
+	var updated Tenant
+	if err := c.parseResponse(resp, &updated); err != nil {
+		return nil, err
+	}
+
+	c.logger.Info("Tenant updated", zap.Uint("id", id))
+
+	return &updated, nil
+}
+
+// DeleteTenant deletes a tenant and its memberships. Tenants that still
+// have peers are rejected with CodeTenantInUse. Requires the admin role.
+func (c *APIClient) DeleteTenant(ctx context.Context, id uint) error {
+	resp, err := c.doRequest(ctx, "DELETE", fmt.Sprintf("/api/v1/admin/tenants/%d", id), nil, true)
+	if err != nil {
+		return err
+	}
+
+	var msgResp MessageResponse
+	if err := c.parseResponse(resp, &msgResp); err != nil {
+		return err
+	}
+
+	c.logger.Info("Tenant deleted", zap.Uint("id", id))
+
+	return nil
+}
+
+// ListTenantMembers lists a tenant's members. Requires the admin role.
+func (c *APIClient) ListTenantMembers(ctx context.Context, id uint) ([]*TenantMember, error) {
+	resp, err := c.doRequest(ctx, "GET", fmt.Sprintf("/api/v1/admin/tenants/%d/members", id), nil, true)
+	if err != nil {
+		return nil, err
+	}
+
+	var membersResp TenantMembersResponse
+	if err := c.parseResponse(resp, &membersResp); err != nil {
+		return nil, err
+	}
+
+	return membersResp.Members, nil
+}
+
+// SetTenantMember adds a user to a tenant with the given role (user,
+// operator), or changes their role. Requires the admin role.
+func (c *APIClient) SetTenantMember(ctx context.Context, id, userID uint, role string) (*TenantMember, error) {
+	path := fmt.Sprintf("/api/v1/admin/tenants/%d/members/%d", id, userID)
+	resp, err := c.doRequest(ctx, "PUT", path, &TenantMemberRequest{Role: role}, true)
+	if err != nil {
+		return nil, err
+	}
+
+	var member TenantMember
+	if err := c.parseResponse(resp, &member); err != nil {
+		return nil, err
+	}
+
+	c.logger.Info("Tenant member set", zap.Uint("tenant_id", id), zap.Uint("user_id", userID))
+
+	return &member, nil
+}
+
+// RemoveTenantMember removes a user from a tenant. Requires the admin role.
+func (c *APIClient) RemoveTenantMember(ctx context.Context, id, userID uint) error {
+	path := fmt.Sprintf("/api/v1/admin/tenants/%d/members/%d", id, userID)
+	resp, err := c.doRequest(ctx, "DELETE", path, nil, true)
+	if err != nil {
+		return err
+	}
+
+	var msgResp MessageResponse
+	if err := c.parseResponse(resp, &msgResp); err != nil {
+		return err
+	}
+
+	c.logger.Info("Tenant member removed", zap.Uint("tenant_id", id), zap.Uint("user_id", userID))
+
+	return nil
+}
+
+// CreatePeer creates a new BGP peer
+func (c *APIClient) CreatePeer(ctx context.Context, peer *PeerRequest) (*Peer, error) {
+	resp, err := c.doRequest(ctx, "POST", "/api/v1/bgp/peers", peer, true)
+	if err != nil {
+		return nil, err
+	}
+
+	var createdPeer Peer
+	if err := c.parseResponse(resp, &createdPeer); err != nil {
+		return nil, err
+	}
+
+	c.logger.Info("Peer created", zap.Uint("id", createdPeer.ID), zap.String("name", createdPeer.Name))
+
+	return &createdPeer, nil
+}
+
+// PlanCreatePeer validates a new BGP peer and returns what creating it
+// would do, without creating it
+func (c *APIClient) PlanCreatePeer(ctx context.Context, peer *PeerRequest) (*PeerPlan, error) {
+	return c.planPeer(ctx, "POST", "/api/v1/bgp/peers", peer)
+}
+
+// PlanUpdatePeer returns what UpdatePeer would do, without changing the peer
+func (c *APIClient) PlanUpdatePeer(ctx context.Context, id uint, updates *PeerRequest) (*PeerPlan, error) {
+	return c.planPeer(ctx, "PUT", fmt.Sprintf("/api/v1/bgp/peers/%d", id), updates)
+}
+
+// PlanPatchPeer returns what PatchPeer would do, without changing the peer
+func (c *APIClient) PlanPatchPeer(ctx context.Context, id uint, patch *PeerPatchRequest) (*PeerPlan, error) {
+	return c.planPeer(ctx, "PATCH", fmt.Sprintf("/api/v1/bgp/peers/%d", id), patch)
+}
+
+// planPeer sends a peer request as a dry run
+func (c *APIClient) planPeer(ctx context.Context, method, path string, body interface{}) (*PeerPlan, error) {
+	resp, err := c.doRequest(ctx, method, path+"?dry_run=true", body, true)
+	if err != nil {
+		return nil, err
+	}
+
+	var plan PeerPlan
+	if err := c.parseResponse(resp, &plan); err != nil {
+		return nil, err
+	}
+	return &plan, nil
+}
+
+// ListPeers lists BGP peers. When tag selectors ("key:value", or "key" for
+// any value) are given, only peers matching all of them are listed.
+func (c *APIClient) ListPeers(ctx context.Context, tags ...string) ([]*Peer, error) {
+	resp, err := c.doRequest(ctx, "GET", withTags("/api/v1/bgp/peers", tags), nil, true)
+	if err != nil {
+		return nil, err
+	}
+
+	var peersResp PeersResponse
+	if err := c.parseResponse(resp, &peersResp); err != nil {
+		return nil, err
+	}
+
+	c.logger.Debug("Peers listed", zap.Int("count", len(peersResp.Peers)))
+
+	return peersResp.Peers, nil
+}
+
+// SearchPeers returns a page of the peers matching a search query, with
+// facet counts of every match for filtering further
+func (c *APIClient) SearchPeers(ctx context.Context, params *PeerSearchParams) (*PeerSearchResult, error) {
+	path := "/api/v1/bgp/peers/search"
+	query := url.Values{}
+	if params != nil {
+		if params.Query != "" {
+			query.Set("q", params.Query)
+		}
+		if params.Limit > 0 {
+			query.Set("limit", strconv.Itoa(params.Limit))
+		}
+		if params.Offset > 0 {
+			query.Set("offset", strconv.Itoa(params.Offset))
+		}
+	}
+	if len(query) > 0 {
+		path += "?" + query.Encode()
+	}
+
+	resp, err := c.doRequest(ctx, "GET", path, nil, true)
+	if err != nil {
+		return nil, err
+	}
+
+	var result PeerSearchResult
+	if err := c.parseResponse(resp, &result); err != nil {
+		return nil, err
+	}
+
+	c.logger.Debug("Peers searched", zap.Int64("total", result.Total))
+
+	return &result, nil
+}
+
+// BulkPeers enables, disables or deletes every peer matching all tag
+// selectors. A peer that fails doesn't stop the others; check each result's
+// Error.
+func (c *APIClient) BulkPeers(ctx context.Context, tags []string, action string) ([]*BulkPeerResult, error) {
+	req := &BulkPeersRequest{Tags: tags, Action: action}
+	resp, err := c.doRequest(ctx, "POST", "/api/v1/bgp/peers/bulk", req, true)
+	if err != nil {
+		return nil, err
+	}
+
+	var bulkResp BulkPeersResponse
+	if err := c.parseResponse(resp, &bulkResp); err != nil {
+		return nil, err
+	}
+
+	c.logger.Info("Bulk peer action run", zap.String("action", action), zap.Int("count", len(bulkResp.Peers)))
+
+	return bulkResp.Peers, nil
+}
+
+// withTags adds ?tag= selectors to path
+func withTags(path string, tags []string) string {
+	if len(tags) == 0 {
+		return path
+	}
+	return path + "?" + url.Values{"tag": tags}.Encode()
+}
+
+// GetPeer gets a specific BGP peer
+func (c *APIClient) GetPeer(ctx context.Context, id uint) (*Peer, error) {
+	path := fmt.Sprintf("/api/v1/bgp/peers/%d", id)
+	resp, err := c.doRequest(ctx, "GET", path, nil, true)
+	if err != nil {
+		return nil, err
+	}
+
+	var peer Peer
+	if err := c.parseResponse(resp, &peer); err != nil {
+		return nil, err
+	}
+
+	c.logger.Debug("Peer retrieved", zap.Uint("id", id))
+
+	return &peer, nil
+}
+
+// GetPeerFRRConfig gets the FRR configuration generated for a BGP peer,
+// with its password redacted
+func (c *APIClient) GetPeerFRRConfig(ctx context.Context, id uint) (string, error) {
+	return c.getText(ctx, fmt.Sprintf("/api/v1/bgp/peers/%d/frr-config", id))
+}
+
+// TestPeer checks that a BGP peer can be connected to, e.g. before
+// enabling it. Nothing is configured in FRR.
+func (c *APIClient) TestPeer(ctx context.Context, id uint) (*PeerTest, error) {
+	path := fmt.Sprintf("/api/v1/bgp/peers/%d/test", id)
+	resp, err := c.doRequest(ctx, "POST", path, nil, true)
+	if err != nil {
+		return nil, err
+	}
+
+	var test PeerTest
+	if err := c.parseResponse(resp, &test); err != nil {
+		return nil, err
+	}
+
+	c.logger.Debug("Peer tested", zap.Uint("id", id), zap.Bool("ready", test.Ready))
+
+	return &test, nil
+}
+
+// UpdatePeer replaces all mutable attributes of a BGP peer
+func (c *APIClient) UpdatePeer(ctx context.Context, id uint, updates *PeerRequest) (*Peer, error) {
+	path := fmt.Sprintf("/api/v1/bgp/peers/%d", id)
+	resp, err := c.doRequest(ctx, "PUT", path, updates, true)
+	if err != nil {
+		return nil, err
+	}
+
+	var peer Peer
+	if err := c.parseResponse(resp, &peer); err != nil {
+		return nil, err
+	}
+
+	c.logger.Info("Peer updated", zap.Uint("id", id))
+
+	return &peer, nil
+}
+
+// PatchPeer updates only the attributes set in patch
+func (c *APIClient) PatchPeer(ctx context.Context, id uint, patch *PeerPatchRequest) (*Peer, error) {
+	path := fmt.Sprintf("/api/v1/bgp/peers/%d", id)
+	resp, err := c.doRequest(ctx, "PATCH", path, patch, true)
+	if err != nil {
+		return nil, err
+	}
+
+	var peer Peer
+	if err := c.parseResponse(resp, &peer); err != nil {
+		return nil, err
+	}
+
+	c.logger.Info("Peer patched", zap.Uint("id", id))
+
+	return &peer, nil
+}
+
+// SetPeerPassword rotates a BGP peer's password. An empty password
+// removes authentication from the session.
+func (c *APIClient) SetPeerPassword(ctx context.Context, id uint, password string) (*Peer, error) {
+	path := fmt.Sprintf("/api/v1/bgp/peers/%d/password", id)
+	resp, err := c.doRequest(ctx, "PUT", path, &PeerPasswordRequest{Password: password}, true)
+	if err != nil {
+		return nil, err
+	}
+
+	var peer Peer
+	if err := c.parseResponse(resp, &peer); err != nil {
+		return nil, err
+	}
+
+	c.logger.Info("Peer password rotated", zap.Uint("id", id))
+
+	return &peer, nil
+}
+
+// StartPeerMaintenance puts a BGP peer under maintenance until until, or
+// until EndPeerMaintenance when until is nil. State change alerts and
+// notifications for the peer are suppressed meanwhile.
+func (c *APIClient) StartPeerMaintenance(ctx context.Context, id uint, until *time.Time) (*Peer, error) {
+	path := fmt.Sprintf("/api/v1/bgp/peers/%d/maintenance", id)
+	resp, err := c.doRequest(ctx, "POST", path, &MaintenanceRequest{Until: until}, true)
+	if err != nil {
+		return nil, err
+	}
+
+	var peer Peer
+	if err := c.parseResponse(resp, &peer); err != nil {
+		return nil, err
+	}
+
+	c.logger.Info("Peer maintenance started", zap.Uint("id", id))
+
+	return &peer, nil
+}
+
+// EndPeerMaintenance takes a BGP peer out of maintenance
+func (c *APIClient) EndPeerMaintenance(ctx context.Context, id uint) (*Peer, error) {
+	path := fmt.Sprintf("/api/v1/bgp/peers/%d/maintenance", id)
+	resp, err := c.doRequest(ctx, "DELETE", path, nil, true)
+	if err != nil {
+		return nil, err
+	}
+
+	var peer Peer
+	if err := c.parseResponse(resp, &peer); err != nil {
+		return nil, err
+	}
+
+	c.logger.Info("Peer maintenance ended", zap.Uint("id", id))
+
+	return &peer, nil
+}
+
+// PinPeerDemoState keeps a BGP peer's simulated session in state, with
+// lastError as the reason it last went down, until UnpinPeerDemoState. It
+// fails with CodeDemoDisabled unless the server runs in demo mode.
+func (c *APIClient) PinPeerDemoState(ctx context.Context, id uint, state, lastError string) error {
+	path := fmt.Sprintf("/api/v1/bgp/peers/%d/demo-state", id)
+	resp, err := c.doRequest(ctx, "PUT", path, &DemoStateRequest{State: state, LastError: lastError}, true)
+	if err != nil {
+		return err
+	}
+	if err := c.parseResponse(resp, nil); err != nil {
+		return err
+	}
+
+	c.logger.Info("Peer demo state pinned", zap.Uint("id", id), zap.String("state", state))
+
+	return nil
+}
+
+// UnpinPeerDemoState lets a BGP peer's simulated session change state
+// again
+func (c *APIClient) UnpinPeerDemoState(ctx context.Context, id uint) error {
+	path := fmt.Sprintf("/api/v1/bgp/peers/%d/demo-state", id)
+	resp, err := c.doRequest(ctx, "DELETE", path, nil, true)
+	if err != nil {
+		return err
+	}
+	if err := c.parseResponse(resp, nil); err != nil {
+		return err
+	}
+
+	c.logger.Info("Peer demo state unpinned", zap.Uint("id", id))
+
+	return nil
+}
+
+// SchedulePeerAction schedules a BGP peer to be enabled or shut down at
+// runAt. The action is announced with an alert shortly before it runs.
+func (c *APIClient) SchedulePeerAction(ctx context.Context, id uint, action string, runAt time.Time) (*ScheduledPeerAction, error) {
+	path := fmt.Sprintf("/api/v1/bgp/peers/%d/schedule", id)
+	resp, err := c.doRequest(ctx, "POST", path, &SchedulePeerActionRequest{Action: action, RunAt: runAt}, true)
+	if err != nil {
+		return nil, err
+	}
+
+	var scheduled ScheduledPeerAction
+	if err := c.parseResponse(resp, &scheduled); err != nil {
+		return nil, err
+	}
+
+	c.logger.Info("Peer action scheduled", zap.Uint("id", id), zap.String("action", action), zap.Time("run_at", runAt))
+
+	return &scheduled, nil
+}
+
+// ListScheduledPeerActions lists the scheduled actions of every peer that
+// have not run yet, soonest first
+func (c *APIClient) ListScheduledPeerActions(ctx context.Context) ([]*ScheduledPeerAction, error) {
+	return c.listScheduledPeerActions(ctx, "/api/v1/bgp/peers/schedule")
+}
+
+// ListPeerScheduledActions lists a BGP peer's scheduled actions that have
+// not run yet, soonest first
+func (c *APIClient) ListPeerScheduledActions(ctx context.Context, id uint) ([]*ScheduledPeerAction, error) {
+	return c.listScheduledPeerActions(ctx, fmt.Sprintf("/api/v1/bgp/peers/%d/schedule", id))
+}
+
+func (c *APIClient) listScheduledPeerActions(ctx context.Context, path string) ([]*ScheduledPeerAction, error) {
+	resp, err := c.doRequest(ctx, "GET", path, nil, true)
+	if err != nil {
+		return nil, err
+	}
+
+	var actionsResp ScheduledPeerActionsResponse
+	if err := c.parseResponse(resp, &actionsResp); err != nil {
+		return nil, err
+	}
+
+	return actionsResp.Actions, nil
+}
+
+// CancelPeerAction cancels a BGP peer's scheduled action. Actions that have
+// started are rejected with CodeJobNotCancellable.
+func (c *APIClient) CancelPeerAction(ctx context.Context, id, actionID uint) (*ScheduledPeerAction, error) {
+	path := fmt.Sprintf("/api/v1/bgp/peers/%d/schedule/%d", id, actionID)
+	resp, err := c.doRequest(ctx, "DELETE", path, nil, true)
+	if err != nil {
+		return nil, err
+	}
+
+	var cancelled ScheduledPeerAction
+	if err := c.parseResponse(resp, &cancelled); err != nil {
+		return nil, err
+	}
+
+	c.logger.Info("Peer action cancelled", zap.Uint("id", id), zap.Uint("action_id", actionID))
+
+	return &cancelled, nil
+}
+
+// DeletePeer deletes a BGP peer
+func (c *APIClient) DeletePeer(ctx context.Context, id uint) error {
+	path := fmt.Sprintf("/api/v1/bgp/peers/%d", id)
+	resp, err := c.doRequest(ctx, "DELETE", path, nil, true)
+	if err != nil {
+		return err
+	}
+
+	var msgResp MessageResponse
+	if err := c.parseResponse(resp, &msgResp); err != nil {
+		return err
+	}
+
+	c.logger.Info("Peer deleted", zap.Uint("id", id))
+
+	return nil
+}
+
+// ListDeletedPeers lists the peers in the trash, most recently deleted first
+func (c *APIClient) ListDeletedPeers(ctx context.Context) ([]*DeletedPeer, error) {
+	resp, err := c.doRequest(ctx, "GET", "/api/v1/bgp/peers/trash", nil, true)
+	if err != nil {
+		return nil, err
+	}
+
+	var peersResp DeletedPeersResponse
+	if err := c.parseResponse(resp, &peersResp); err != nil {
+		return nil, err
+	}
+
+	c.logger.Debug("Deleted peers listed", zap.Int("count", len(peersResp.Peers)))
+
+	return peersResp.Peers, nil
+}
+
+// RestorePeer takes a BGP peer out of the trash and re-applies it to FRR
+func (c *APIClient) RestorePeer(ctx context.Context, id uint) (*Peer, error) {
+	path := fmt.Sprintf("/api/v1/bgp/peers/%d/restore", id)
+	resp, err := c.doRequest(ctx, "POST", path, nil, true)
+	if err != nil {
+		return nil, err
+	}
+
+	var peer Peer
+	if err := c.parseResponse(resp, &peer); err != nil {
+		return nil, err
+	}
+
+	c.logger.Info("Peer restored", zap.Uint("id", id))
+
+	return &peer, nil
+}
+
+// PurgeDeletedPeers permanently removes the peers deleted more than
+// olderThan ago. An olderThan of 0 empties the trash.
+func (c *APIClient) PurgeDeletedPeers(ctx context.Context, olderThan time.Duration) ([]*Peer, error) {
+	path := "/api/v1/bgp/peers/trash/purge"
+	if olderThan > 0 {
+		path += "?" + url.Values{"older_than": {olderThan.String()}}.Encode()
+	}
+	resp, err := c.doRequest(ctx, "POST", path, nil, true)
+	if err != nil {
+		return nil, err
+	}
+
+	var purgeResp PurgeResponse
+	if err := c.parseResponse(resp, &purgeResp); err != nil {
+		return nil, err
+	}
+
+	c.logger.Info("Deleted peers purged", zap.Int("count", purgeResp.Purged))
+
+	return purgeResp.Peers, nil
+}
+
+// ListSessions lists BGP sessions, optionally only those of peers matching
+// all tag selectors
+func (c *APIClient) ListSessions(ctx context.Context, tags ...string) ([]*Session, error) {
+	resp, err := c.doRequest(ctx, "GET", withTags("/api/v1/bgp/sessions", tags), nil, true)
+	if err != nil {
+		return nil, err
+	}
+
+	var sessionsResp SessionsResponse
+	if err := c.parseResponse(resp, &sessionsResp); err != nil {
+		return nil, err
+	}
+
+	c.logger.Debug("Sessions listed", zap.Int("count", len(sessionsResp.Sessions)))
+
+	return sessionsResp.Sessions, nil
+}
+
+// GetSession gets a specific BGP session
+func (c *APIClient) GetSession(ctx context.Context, id uint) (*Session, error) {
+	path := fmt.Sprintf("/api/v1/bgp/sessions/%d", id)
+	resp, err := c.doRequest(ctx, "GET", path, nil, true)
+	if err != nil {
+		return nil, err
+	}
+
+	var session Session
+	if err := c.parseResponse(resp, &session); err != nil {
+		return nil, err
+	}
+
+	c.logger.Debug("Session retrieved", zap.Uint("id", id))
+
+	return &session, nil
+}
+
+// GetDrift compares stored peers with FRR's running configuration
+func (c *APIClient) GetDrift(ctx context.Context) (*DriftReport, error) {
+	resp, err := c.doRequest(ctx, "GET", "/api/v1/bgp/drift", nil, true)
+	if err != nil {
+		return nil, err
+	}
+
+	var report DriftReport
+	if err := c.parseResponse(resp, &report); err != nil {
+		return nil, err
+	}
+
+	c.logger.Debug("Drift detected", zap.Int("entries", len(report.Entries)))
+
+	return &report, nil
+}
+
+// Reconcile runs a reconciliation pass between stored peers and FRR. The
+// pass runs as a background job; Reconcile waits for it to finish.
+func (c *APIClient) Reconcile(ctx context.Context) (*DriftReport, error) {
+	var report DriftReport
+	if err := c.runJob(ctx, "/api/v1/bgp/reconcile", nil, &report); err != nil {
+		return nil, err
+	}
+
+	c.logger.Info("Reconciliation completed", zap.Bool("in_sync", report.InSync))
+
+	return &report, nil
+}
+
+// GetPeerSync gets the result of the last full peer sync
+func (c *APIClient) GetPeerSync(ctx context.Context) (*PeerSyncReport, error) {
+	resp, err := c.doRequest(ctx, "GET", "/api/v1/bgp/sync", nil, true)
+	if err != nil {
+		return nil, err
+	}
+
+	var report PeerSyncReport
+	if err := c.parseResponse(resp, &report); err != nil {
+		return nil, err
+	}
+
+	return &report, nil
+}
+
+// GetTopTalkers ranks peers by a session counter: prefixes_received,
+// prefixes_sent, messages_received or messages_sent. Zero values use the
+// server's defaults.
+func (c *APIClient) GetTopTalkers(ctx context.Context, by string, limit int) ([]*TopTalker, error) {
+	query := url.Values{}
+	if by != "" {
+		query.Set("by", by)
+	}
+	if limit > 0 {
+		query.Set("limit", strconv.Itoa(limit))
+	}
+	path := "/api/v1/bgp/top-talkers"
+	if len(query) > 0 {
+		path += "?" + query.Encode()
+	}
+
+	resp, err := c.doRequest(ctx, "GET", path, nil, true)
+	if err != nil {
+		return nil, err
+	}
+
+	var talkersResp struct {
+		TopTalkers []*TopTalker `json:"top_talkers"`
+	}
+	if err := c.parseResponse(resp, &talkersResp); err != nil {
+		return nil, err
+	}
+
+	return talkersResp.TopTalkers, nil
+}
+
+// GetSessionStats aggregates session statistics by remote_asn (the
+// server's default when by is empty), peer_group, tag or router. By tag,
+// tagKey names the tag.
+func (c *APIClient) GetSessionStats(ctx context.Context, by, tagKey string) (*SessionStatsReport, error) {
+	query := url.Values{}
+	if by != "" {
+		query.Set("by", by)
+	}
+	if tagKey != "" {
+		query.Set("tag_key", tagKey)
+	}
+	path := "/api/v1/bgp/stats"
+	if len(query) > 0 {
+		path += "?" + query.Encode()
+	}
+
+	resp, err := c.doRequest(ctx, "GET", path, nil, true)
+	if err != nil {
+		return nil, err
+	}
+
+	var report SessionStatsReport
+	if err := c.parseResponse(resp, &report); err != nil {
+		return nil, err
+	}
+
+	return &report, nil
+}
+
+// GetSLAReport reports the session availability of peers over a range
+// such as "30d" (the default when empty), optionally only of peers matching
+// all tag selectors
+func (c *APIClient) GetSLAReport(ctx context.Context, rng string, tags ...string) (*SLAReport, error) {
+	resp, err := c.doRequest(ctx, "GET", "/api/v1/reports/sla?"+slaQuery(rng, tags).Encode(), nil, true)
+	if err != nil {
+		return nil, err
+	}
+
+	var report SLAReport
+	if err := c.parseResponse(resp, &report); err != nil {
+		return nil, err
+	}
+
+	return &report, nil
+}
+
+// ExportSLAReport streams the SLA report over rng as CSV, one row per
+// peer. The caller must close the returned body.
+func (c *APIClient) ExportSLAReport(ctx context.Context, rng string, tags ...string) (io.ReadCloser, error) {
+	query := slaQuery(rng, tags)
+	query.Set("format", ExportCSV)
+	return c.export(ctx, "/api/v1/reports/sla?"+query.Encode())
+}
+
+// slaQuery encodes the range and tag selectors of an SLA report
+func slaQuery(rng string, tags []string) url.Values {
+	query := url.Values{}
+	if rng != "" {
+		query.Set("range", rng)
+	}
+	if len(tags) > 0 {
+		query["tag"] = tags
+	}
+	return query
+}
+
+// GetAnomalies gets the result of the last prefix count analysis
+func (c *APIClient) GetAnomalies(ctx context.Context) (*AnomalyReport, error) {
+	resp, err := c.doRequest(ctx, "GET", "/api/v1/bgp/anomalies", nil, true)
+	if err != nil {
+		return nil, err
+	}
+
+	var report AnomalyReport
+	if err := c.parseResponse(resp, &report); err != nil {
+		return nil, err
+	}
+
+	return &report, nil
+}
+
+// AnalyzePrefixes compares every peer's prefix count with its count a
+// window ago, raising alerts for sudden changes. The analysis runs as a
+// background job; AnalyzePrefixes waits for it to finish.
+func (c *APIClient) AnalyzePrefixes(ctx context.Context) (*AnomalyReport, error) {
+	var report AnomalyReport
+	if err := c.runJob(ctx, "/api/v1/bgp/anomalies/analyze", nil, &report); err != nil {
+		return nil, err
+	}
+
+	c.logger.Info("Prefix analysis completed", zap.Int("anomalies", len(report.Anomalies)))
+
+	return &report, nil
+}
+
+// GetReachability gets the result of the last peer reachability probe
+// round
+func (c *APIClient) GetReachability(ctx context.Context) (*ReachabilityReport, error) {
+	resp, err := c.doRequest(ctx, "GET", "/api/v1/bgp/reachability", nil, true)
+	if err != nil {
+		return nil, err
+	}
+
+	var report ReachabilityReport
+	if err := c.parseResponse(resp, &report); err != nil {
+		return nil, err
+	}
+
+	return &report, nil
+}
+
+// ProbeReachability pings, or connects to port 179 of, every enabled peer,
+// raising alerts for peers unreachable at the IP layer. The probes run as a
+// background job; ProbeReachability waits for it to finish.
+func (c *APIClient) ProbeReachability(ctx context.Context) (*ReachabilityReport, error) {
+	var report ReachabilityReport
+	if err := c.runJob(ctx, "/api/v1/bgp/reachability/probe", nil, &report); err != nil {
+		return nil, err
+	}
+
+	c.logger.Info("Reachability probe completed", zap.Int("unreachable", report.Unreachable))
+
+	return &report, nil
+}
+
+// GetPeerReachability gets a peer's reachability samples over window, such
+// as "6h"; empty uses the last hour
+func (c *APIClient) GetPeerReachability(ctx context.Context, id uint, window string) (*PeerReachabilityHistory, error) {
+	path := fmt.Sprintf("/api/v1/bgp/peers/%d/reachability", id)
+	if window != "" {
+		path += "?" + url.Values{"window": {window}}.Encode()
+	}
+	resp, err := c.doRequest(ctx, "GET", path, nil, true)
+	if err != nil {
+		return nil, err
+	}
+
+	var history PeerReachabilityHistory
+	if err := c.parseResponse(resp, &history); err != nil {
+		return nil, err
+	}
+
+	return &history, nil
+}
+
+// GetPeerStateTimeline gets a peer's session states over rng, such as "7d",
+// in buckets of bucket, such as "6h"; empty values use the last day and 48
+// buckets
+func (c *APIClient) GetPeerStateTimeline(ctx context.Context, id uint, rng, bucket string) (*StateTimeline, error) {
+	query := url.Values{}
+	if rng != "" {
+		query.Set("range", rng)
+	}
+	if bucket != "" {
+		query.Set("bucket", bucket)
+	}
+	path := fmt.Sprintf("/api/v1/bgp/peers/%d/state-timeline", id)
+	if len(query) > 0 {
+		path += "?" + query.Encode()
+	}
+	resp, err := c.doRequest(ctx, "GET", path, nil, true)
+	if err != nil {
+		return nil, err
+	}
+
+	var timeline StateTimeline
+	if err := c.parseResponse(resp, &timeline); err != nil {
+		return nil, err
+	}
+
+	return &timeline, nil
+}
+
+// GetLeader gets which instance leads. Changes sent to a standby fail with
+// CodeNotLeader.
+func (c *APIClient) GetLeader(ctx context.Context) (*Leadership, error) {
+	resp, err := c.doRequest(ctx, "GET", "/api/v1/leader", nil, true)
+	if err != nil {
+		return nil, err
+	}
+
+	var leadership Leadership
+	if err := c.parseResponse(resp, &leadership); err != nil {
+		return nil, err
+	}
+
+	return &leadership, nil
+}
+
+// GetFRRStatus gets FRR's version, daemons and northbound capabilities,
+// for instance to check a feature's minimum FRR version with AtLeast. It
+// fails with CodeFRRUnavailable while FRR can't be reached.
+func (c *APIClient) GetFRRStatus(ctx context.Context) (*FRRStatus, error) {
+	resp, err := c.doRequest(ctx, "GET", "/api/v1/frr/status", nil, true)
+	if err != nil {
+		return nil, err
+	}
+
+	var status FRRStatus
+	if err := c.parseResponse(resp, &status); err != nil {
+		return nil, err
+	}
+
+	return &status, nil
+}
+
+// ListFRRTransactions lists the changes FlintRoute pushed to FRR, newest
+// first. Pass the page's NextBefore in params to get the next page.
+func (c *APIClient) ListFRRTransactions(ctx context.Context, params *FRRTransactionQueryParams) (*FRRTransactionPage, error) {
+	path := "/api/v1/frr/transactions"
+	query := url.Values{}
+	if params != nil {
+		for name, id := range map[string]uint{
+			"peer_id":           params.PeerID,
+			"config_version_id": params.ConfigVersionID,
+			"job_id":            params.JobID,
+			"before":            params.Before,
+		} {
+			if id > 0 {
+				query.Set(name, strconv.FormatUint(uint64(id), 10))
+			}
+		}
+		for name, value := range map[string]string{
+			"user":      params.User,
+			"operation": params.Operation,
+			"result":    params.Result,
+		} {
+			if value != "" {
+				query.Set(name, value)
+			}
+		}
+		if params.Limit > 0 {
+			query.Set("limit", strconv.Itoa(params.Limit))
+		}
+	}
+	if len(query) > 0 {
+		path += "?" + query.Encode()
+	}
+
+	resp, err := c.doRequest(ctx, "GET", path, nil, true)
+	if err != nil {
+		return nil, err
+	}
+
+	var page FRRTransactionPage
+	if err := c.parseResponse(resp, &page); err != nil {
+		return nil, err
+	}
+
+	return &page, nil
+}
+
+// GetFRRTransaction gets a change FlintRoute pushed to FRR
+func (c *APIClient) GetFRRTransaction(ctx context.Context, id uint) (*FRRTransaction, error) {
+	resp, err := c.doRequest(ctx, "GET", fmt.Sprintf("/api/v1/frr/transactions/%d", id), nil, true)
+	if err != nil {
+		return nil, err
+	}
+
+	var transaction FRRTransaction
+	if err := c.parseResponse(resp, &transaction); err != nil {
+		return nil, err
+	}
+
+	return &transaction, nil
+}
+
+// BackupFRRTransaction backs up the configuration an out-of-band change
+// left FRR running as a config version. It fails with CodeConfigChanged
+// once FRR's running configuration has changed again.
+func (c *APIClient) BackupFRRTransaction(ctx context.Context, id uint) (*ConfigVersion, error) {
+	resp, err := c.doRequest(ctx, "POST", fmt.Sprintf("/api/v1/frr/transactions/%d/backup", id), nil, true)
+	if err != nil {
+		return nil, err
+	}
+
+	var version ConfigVersion
+	if err := c.parseResponse(resp, &version); err != nil {
+		return nil, err
+	}
+
+	c.logger.Info("Out-of-band change backed up", zap.Uint("version_id", version.ID))
+
+	return &version, nil
+}
+
+// GetBGPGlobal gets the BGP global configuration
+func (c *APIClient) GetBGPGlobal(ctx context.Context) (*BGPGlobal, error) {
+	resp, err := c.doRequest(ctx, "GET", "/api/v1/bgp/global", nil, true)
+	if err != nil {
+		return nil, err
+	}
+
+	var global BGPGlobal
+	if err := c.parseResponse(resp, &global); err != nil {
+		return nil, err
+	}
+
+	return &global, nil
+}
+
+// UpdateBGPGlobal replaces the BGP global configuration and applies it to
+// FRR. The ASN must match the local ASN of every peer.
+func (c *APIClient) UpdateBGPGlobal(ctx context.Context, global *BGPGlobalRequest) (*BGPGlobal, error) {
+	resp, err := c.doRequest(ctx, "PUT", "/api/v1/bgp/global", global, true)
+	if err != nil {
+		return nil, err
+	}
+
+	var updated BGPGlobal
+	if err := c.parseResponse(resp, &updated); err != nil {
+		return nil, err
+	}
+
+	c.logger.Info("BGP global configuration updated", zap.Uint32("asn", updated.ASN))
+
+	return &updated, nil
+}
+
+// ListCommunityLists lists all community-lists
+func (c *APIClient) ListCommunityLists(ctx context.Context) ([]*CommunityList, error) {
+	resp, err := c.doRequest(ctx, "GET", "/api/v1/bgp/community-lists", nil, true)
+	if err != nil {
+		return nil, err
+	}
+
+	var listsResp CommunityListsResponse
+	if err := c.parseResponse(resp, &listsResp); err != nil {
+		return nil, err
+	}
+
+	return listsResp.CommunityLists, nil
+}
+
+// GetCommunityList retrieves a community-list by name
+func (c *APIClient) GetCommunityList(ctx context.Context, name string) (*CommunityList, error) {
+	resp, err := c.doRequest(ctx, "GET", "/api/v1/bgp/community-lists/"+url.PathEscape(name), nil, true)
+	if err != nil {
+		return nil, err
+	}
+
+	var list CommunityList
+	if err := c.parseResponse(resp, &list); err != nil {
+		return nil, err
+	}
+
+	return &list, nil
+}
+
+// CreateCommunityList creates a community-list
+func (c *APIClient) CreateCommunityList(ctx context.Context, list *CommunityListRequest) (*CommunityList, error) {
+	resp, err := c.doRequest(ctx, "POST", "/api/v1/bgp/community-lists", list, true)
+	if err != nil {
+		return nil, err
+	}
+
+	var created CommunityList
+	if err := c.parseResponse(resp, &created); err != nil {
+		return nil, err
+	}
+
+	c.logger.Info("Community-list created", zap.String("name", created.Name))
+
+	return &created, nil
+}
+
+// UpdateCommunityList replaces a community-list's type and entries
+func (c *APIClient) UpdateCommunityList(ctx context.Context, name string, list *CommunityListRequest) (*CommunityList, error) {
+	resp, err := c.doRequest(ctx, "PUT", "/api/v1/bgp/community-lists/"+url.PathEscape(name), list, true)
+	if err != nil {
+		return nil, err
+	}
+
+	var updated CommunityList
+	if err := c.parseResponse(resp, &updated); err != nil {
+		return nil, err
+	}
+
+	c.logger.Info("Community-list updated", zap.String("name", name))
+
+	return &updated, nil
+}
+
+// DeleteCommunityList deletes a community-list. Lists referenced by a
+// route-map are rejected with CodePolicyInUse.
+func (c *APIClient) DeleteCommunityList(ctx context.Context, name string) error {
+	resp, err := c.doRequest(ctx, "DELETE", "/api/v1/bgp/community-lists/"+url.PathEscape(name), nil, true)
+	if err != nil {
+		return err
+	}
+
+	var msgResp MessageResponse
+	if err := c.parseResponse(resp, &msgResp); err != nil {
+		return err
+	}
+
+	c.logger.Info("Community-list deleted", zap.String("name", name))
+
+	return nil
+}
+
+// ListPeerTemplates lists all peer templates
+func (c *APIClient) ListPeerTemplates(ctx context.Context) ([]*PeerTemplate, error) {
+	resp, err := c.doRequest(ctx, "GET", "/api/v1/bgp/peer-templates", nil, true)
+	if err != nil {
+		return nil, err
+	}
+
+	var templatesResp PeerTemplatesResponse
+	if err := c.parseResponse(resp, &templatesResp); err != nil {
+		return nil, err
+	}
+
+	return templatesResp.Templates, nil
+}
+
+// GetPeerTemplate retrieves a peer template and its resolved settings
+func (c *APIClient) GetPeerTemplate(ctx context.Context, id uint) (*PeerTemplate, error) {
+	resp, err := c.doRequest(ctx, "GET", fmt.Sprintf("/api/v1/bgp/peer-templates/%d", id), nil, true)
+	if err != nil {
+		return nil, err
+	}
+
+	var template PeerTemplate
+	if err := c.parseResponse(resp, &template); err != nil {
+		return nil, err
+	}
+
+	return &template, nil
+}
+
+// CreatePeerTemplate creates a peer template
+func (c *APIClient) CreatePeerTemplate(ctx context.Context, template *PeerTemplateRequest) (*PeerTemplate, error) {
+	resp, err := c.doRequest(ctx, "POST", "/api/v1/bgp/peer-templates", template, true)
+	if err != nil {
+		return nil, err
+	}
+
+	var created PeerTemplate
+	if err := c.parseResponse(resp, &created); err != nil {
+		return nil, err
+	}
+
+	c.logger.Info("Peer template created", zap.Uint("id", created.ID), zap.String("name", created.Name))
+
+	return &created, nil
+}
+
+// UpdatePeerTemplate replaces a peer template. Its peers keep their
+// settings until SyncPeerTemplate is called.
+func (c *APIClient) UpdatePeerTemplate(ctx context.Context, id uint, template *PeerTemplateRequest) (*PeerTemplate, error) {
+	resp, err := c.doRequest(ctx, "PUT", fmt.Sprintf("/api/v1/bgp/peer-templates/%d", id), template, true)
+	if err != nil {
+		return nil, err
+	}
+
+	var updated PeerTemplate
+	if err := c.parseResponse(resp, &updated); err != nil {
+		return nil, err
+	}
+
+	c.logger.Info("Peer template updated", zap.Uint("id", id))
+
+	return &updated, nil
+}
+
+// DeletePeerTemplate deletes a peer template. Templates peers or other
+// templates use are rejected with CodeTemplateInUse.
+func (c *APIClient) DeletePeerTemplate(ctx context.Context, id uint) error {
+	resp, err := c.doRequest(ctx, "DELETE", fmt.Sprintf("/api/v1/bgp/peer-templates/%d", id), nil, true)
+	if err != nil {
+		return err
+	}
+
+	var msgResp MessageResponse
+	if err := c.parseResponse(resp, &msgResp); err != nil {
+		return err
+	}
+
+	c.logger.Info("Peer template deleted", zap.Uint("id", id))
+
+	return nil
+}
+
+// SyncPeerTemplate reapplies a template to the peers provisioned from it
+// or from the templates inheriting from it, keeping each peer's overrides
+func (c *APIClient) SyncPeerTemplate(ctx context.Context, id uint) ([]*BulkPeerResult, error) {
+	resp, err := c.doRequest(ctx, "POST", fmt.Sprintf("/api/v1/bgp/peer-templates/%d/sync", id), nil, true)
+	if err != nil {
+		return nil, err
+	}
+
+	var syncResp SyncPeerTemplateResponse
+	if err := c.parseResponse(resp, &syncResp); err != nil {
+		return nil, err
+	}
+
+	return syncResp.Results, nil
+}
+
+// CreatePeerFromTemplate creates a BGP peer from a template
+func (c *APIClient) CreatePeerFromTemplate(ctx context.Context, peer *TemplatePeerRequest) (*Peer, error) {
+	resp, err := c.doRequest(ctx, "POST", "/api/v1/bgp/peers", peer, true)
+	if err != nil {
+		return nil, err
+	}
+
+	var createdPeer Peer
+	if err := c.parseResponse(resp, &createdPeer); err != nil {
+		return nil, err
+	}
+
+	c.logger.Info("Peer created", zap.Uint("id", createdPeer.ID), zap.String("name", createdPeer.Name), zap.Uint("template_id", peer.TemplateID))
+
+	return &createdPeer, nil
+}
+
+// LookupPeeringDB looks up the network registered in PeeringDB for asn, to
+// pre-fill a peer's contacts, prefix limits and IXP LAN addresses
+func (c *APIClient) LookupPeeringDB(ctx context.Context, asn uint32) (*PeeringDBNetwork, error) {
+	resp, err := c.doRequest(ctx, "GET", fmt.Sprintf("/api/v1/peeringdb/asn/%d", asn), nil, true)
+	if err != nil {
+		return nil, err
+	}
+
+	var network PeeringDBNetwork
+	if err := c.parseResponse(resp, &network); err != nil {
+		return nil, err
+	}
+
+	return &network, nil
+}
+
+// CreateValidatedPeer creates a BGP peer after checking its remote ASN and
+// address against PeeringDB. Peers that don't match are rejected with
+// CodePeeringDBMismatch.
+func (c *APIClient) CreateValidatedPeer(ctx context.Context, peer *PeerRequest) (*Peer, error) {
+	resp, err := c.doRequest(ctx, "POST", "/api/v1/bgp/peers?validate_asn=true", peer, true)
+	if err != nil {
+		return nil, err
+	}
+
+	var createdPeer Peer
+	if err := c.parseResponse(resp, &createdPeer); err != nil {
+		return nil, err
+	}
+
+	c.logger.Info("Peer created", zap.Uint("id", createdPeer.ID), zap.String("name", createdPeer.Name))
+
+	return &createdPeer, nil
+}
+
+// ListASPathLists lists all as-path access-lists
+func (c *APIClient) ListASPathLists(ctx context.Context) ([]*ASPathList, error) {
+	resp, err := c.doRequest(ctx, "GET", "/api/v1/bgp/as-path-lists", nil, true)
+	if err != nil {
+		return nil, err
+	}
+
+	var listsResp ASPathListsResponse
+	if err := c.parseResponse(resp, &listsResp); err != nil {
+		return nil, err
+	}
+
+	return listsResp.ASPathLists, nil
+}
+
+// GetASPathList retrieves an as-path access-list by name
+func (c *APIClient) GetASPathList(ctx context.Context, name string) (*ASPathList, error) {
+	resp, err := c.doRequest(ctx, "GET", "/api/v1/bgp/as-path-lists/"+url.PathEscape(name), nil, true)
+	if err != nil {
+		return nil, err
+	}
+
+	var list ASPathList
+	if err := c.parseResponse(resp, &list); err != nil {
+		return nil, err
+	}
+
+	return &list, nil
+}
+
+// CreateASPathList creates an as-path access-list
+func (c *APIClient) CreateASPathList(ctx context.Context, list *ASPathListRequest) (*ASPathList, error) {
+	resp, err := c.doRequest(ctx, "POST", "/api/v1/bgp/as-path-lists", list, true)
+	if err != nil {
+		return nil, err
+	}
+
+	var created ASPathList
+	if err := c.parseResponse(resp, &created); err != nil {
+		return nil, err
+	}
+
+	c.logger.Info("As-path-list created", zap.String("name", created.Name))
+
+	return &created, nil
+}
+
+// UpdateASPathList replaces an as-path access-list's entries
+func (c *APIClient) UpdateASPathList(ctx context.Context, name string, list *ASPathListRequest) (*ASPathList, error) {
+	resp, err := c.doRequest(ctx, "PUT", "/api/v1/bgp/as-path-lists/"+url.PathEscape(name), list, true)
+	if err != nil {
+		return nil, err
+	}
+
+	var updated ASPathList
+	if err := c.parseResponse(resp, &updated); err != nil {
+		return nil, err
+	}
+
+	c.logger.Info("As-path-list updated", zap.String("name", name))
+
+	return &updated, nil
+}
+
+// DeleteASPathList deletes an as-path access-list. Lists referenced by a
+// route-map are rejected with CodePolicyInUse.
+func (c *APIClient) DeleteASPathList(ctx context.Context, name string) error {
+	resp, err := c.doRequest(ctx, "DELETE", "/api/v1/bgp/as-path-lists/"+url.PathEscape(name), nil, true)
+	if err != nil {
+		return err
+	}
+
+	var msgResp MessageResponse
+	if err := c.parseResponse(resp, &msgResp); err != nil {
+		return err
+	}
+
+	c.logger.Info("As-path-list deleted", zap.String("name", name))
+
+	return nil
+}
+
+// GetGitOpsStatus retrieves the outcome of the most recent GitOps sync
+func (c *APIClient) GetGitOpsStatus(ctx context.Context) (*GitOpsStatus, error) {
+	resp, err := c.doRequest(ctx, "GET", "/api/v1/gitops/status", nil, true)
+	if err != nil {
+		return nil, err
+	}
+
+	var status GitOpsStatus
+	if err := c.parseResponse(resp, &status); err != nil {
+		return nil, err
+	}
+
+	return &status, nil
+}
+
+// PlanGitOpsSync previews the changes a GitOps sync would make
+func (c *APIClient) PlanGitOpsSync(ctx context.Context) (*GitOpsPlan, error) {
+	resp, err := c.doRequest(ctx, "GET", "/api/v1/gitops/plan", nil, true)
+	if err != nil {
+		return nil, err
+	}
+
+	var plan GitOpsPlan
+	if err := c.parseResponse(resp, &plan); err != nil {
+		return nil, err
+	}
+
+	c.logger.Debug("GitOps plan computed", zap.Int("changes", len(plan.Changes)))
+
+	return &plan, nil
+}
+
+// SyncGitOps fetches the GitOps repository and applies its definitions. The
+// sync runs as a background job; SyncGitOps waits for it to finish.
+func (c *APIClient) SyncGitOps(ctx context.Context) (*GitOpsResult, error) {
+	var result GitOpsResult
+	if err := c.runJob(ctx, "/api/v1/gitops/sync", nil, &result); err != nil {
+		return nil, err
+	}
+
+	c.logger.Info("GitOps sync completed",
+		zap.String("commit", result.CommitSHA),
+		zap.Int("changes", len(result.Applied)),
+	)
+
+	return &result, nil
+}
+
+// ListConfigVersions lists all configuration versions
+func (c *APIClient) ListConfigVersions(ctx context.Context) ([]*ConfigVersion, error) {
+	resp, err := c.doRequest(ctx, "GET", "/api/v1/config/versions", nil, true)
+	if err != nil {
+		return nil, err
+	}
+
+	var versionsResp ConfigVersionsResponse
+	if err := c.parseResponse(resp, &versionsResp); err != nil {
+		return nil, err
+	}
+
+	c.logger.Debug("Config versions listed", zap.Int("count", len(versionsResp.Versions)))
+
+	return versionsResp.Versions, nil
+}
+
+// GetRunningConfig gets FRR's running configuration
+func (c *APIClient) GetRunningConfig(ctx context.Context) (string, error) {
+	return c.getText(ctx, "/api/v1/config/running")
+}
+
+// getText gets a plain text response
+func (c *APIClient) getText(ctx context.Context, path string) (string, error) {
+	resp, err := c.doRequest(ctx, "GET", path, nil, true)
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode >= 400 {
+		return "", c.parseResponse(resp, nil)
+	}
+	defer resp.Body.Close()
+
+	config, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	return string(config), nil
+}
+
+// BackupConfig creates a configuration backup
+func (c *APIClient) BackupConfig(ctx context.Context, description string) (*ConfigVersion, error) {
+	req := BackupConfigRequest{
+		Description: description,
+	}
+
+	resp, err := c.doRequest(ctx, "POST", "/api/v1/config/backup", req, true)
+	if err != nil {
+		return nil, err
+	}
+
+	var version ConfigVersion
+	if err := c.parseResponse(resp, &version); err != nil {
+		return nil, err
+	}
+
+	c.logger.Info("Config backed up", zap.Uint("version_id", version.ID))
+
+	return &version, nil
+}
+
+// UpdateConfigVersion renames, relabels or pins a configuration version
+func (c *APIClient) UpdateConfigVersion(ctx context.Context, id uint, update *ConfigVersionUpdate) (*ConfigVersion, error) {
+	path := fmt.Sprintf("/api/v1/config/versions/%d", id)
+	resp, err := c.doRequest(ctx, "PATCH", path, update, true)
+	if err != nil {
+		return nil, err
+	}
+
+	var version ConfigVersion
+	if err := c.parseResponse(resp, &version); err != nil {
+		return nil, err
+	}
+
+	c.logger.Info("Config version updated", zap.Uint("version_id", version.ID))
+
+	return &version, nil
+}
+
+// PreviewRestore reports what restoring a configuration version would
+// change: the diff against FRR's running configuration and the peers
+// affected
+func (c *APIClient) PreviewRestore(ctx context.Context, id uint) (*RestorePreview, error) {
+	path := fmt.Sprintf("/api/v1/config/restore/%d/preview", id)
+	resp, err := c.doRequest(ctx, "GET", path, nil, true)
+	if err != nil {
+		return nil, err
+	}
+
+	var preview RestorePreview
+	if err := c.parseResponse(resp, &preview); err != nil {
+		return nil, err
+	}
+
+	return &preview, nil
+}
+
+// RestoreConfig restores a configuration version. The restore runs as a
+// background job; RestoreConfig waits for it to finish.
+func (c *APIClient) RestoreConfig(ctx context.Context, id uint) error {
+	_, err := c.RestoreConfigWithOptions(ctx, id, nil)
+	return err
+}
+
+// RestoreConfigWithOptions restores a configuration version, verifying it
+// as opts sets, and waits for the restore to finish. A restore rolled back
+// because sessions went down is not an error; check the result's Outcome.
+func (c *APIClient) RestoreConfigWithOptions(ctx context.Context, id uint, opts *RestoreOptions) (*RestoreResult, error) {
+	var body interface{}
+	if opts != nil {
+		body = opts
+	}
+
+	path := fmt.Sprintf("/api/v1/config/restore/%d", id)
+	var result RestoreResult
+	if err := c.runJob(ctx, path, body, &result); err != nil {
+		return nil, err
+	}
+
+	c.logger.Info("Config restored", zap.Uint("version_id", id), zap.String("outcome", result.Outcome))
+
+	return &result, nil
+}
+
+// ImportRunningConfig imports FRR's running configuration into
+// FlintRoute's peers and policies. With dryRun the report describes what
+// would be imported, without storing anything.
+func (c *APIClient) ImportRunningConfig(ctx context.Context, dryRun bool) (*ImportReport, error) {
+	path := "/api/v1/config/import-running"
+	if dryRun {
+		path += "?dry_run=true"
+	}
+	resp, err := c.doRequest(ctx, "POST", path, nil, true)
+	if err != nil {
+		return nil, err
+	}
+
+	var report ImportReport
+	if err := c.parseResponse(resp, &report); err != nil {
+		return nil, err
+	}
+
+	c.logger.Info("Running config imported",
+		zap.Bool("dry_run", dryRun),
+		zap.Int("imported", len(report.Imported)),
+		zap.Int("skipped", len(report.Skipped)),
+	)
+
+	return &report, nil
+}
+
+// AdoptNeighbors creates peers for the given neighbors of FRR's default BGP
+// instance, with their current settings, without changing FRR. Without
+// neighbors nothing is adopted; the report lists the candidates.
+func (c *APIClient) AdoptNeighbors(ctx context.Context, neighbors ...string) (*AdoptReport, error) {
+	req := map[string][]string{"neighbors": neighbors}
+	resp, err := c.doRequest(ctx, "POST", "/api/v1/bgp/peers/adopt", req, true)
+	if err != nil {
+		return nil, err
+	}
+
+	var report AdoptReport
+	if err := c.parseResponse(resp, &report); err != nil {
+		return nil, err
+	}
+
+	c.logger.Info("Neighbors adopted",
+		zap.Int("adopted", len(report.Adopted)),
+		zap.Int("candidates", len(report.Candidates)),
+	)
+
+	return &report, nil
+}
+
+// ExportState downloads the instance's declarative state as a YAML
+// document: global settings, templates, peers, policies, users, webhooks
+// and notification defaults. Secrets are left out.
+func (c *APIClient) ExportState(ctx context.Context) ([]byte, error) {
+	body, err := c.export(ctx, "/api/v1/state")
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	doc, err := io.ReadAll(body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read state: %w", err)
+	}
+	return doc, nil
+}
+
+// ImportState loads a YAML or JSON state document. With dryRun the result
+// lists the changes without making them; with prune objects the document
+// leaves out are deleted. Users and webhooks the import creates get
+// generated secrets, returned once in the result's credentials.
+func (c *APIClient) ImportState(ctx context.Context, doc []byte, dryRun, prune bool) (*StateImportResult, error) {
+	var body interface{}
+	if err := yaml.Unmarshal(doc, &body); err != nil {
+		return nil, fmt.Errorf("failed to parse state document: %w", err)
+	}
+
+	query := url.Values{}
+	if dryRun {
+		query.Set("dry_run", "true")
+	}
+	if prune {
+		query.Set("prune", "true")
+	}
+	path := "/api/v1/state"
+	if len(query) > 0 {
+		path += "?" + query.Encode()
+	}
+	resp, err := c.doRequest(ctx, "POST", path, body, true)
+	if err != nil {
+		return nil, err
+	}
+
+	var result StateImportResult
+	if err := c.parseResponse(resp, &result); err != nil {
+		return nil, err
+	}
+
+	c.logger.Info("State imported",
+		zap.Bool("dry_run", dryRun),
+		zap.Int("changes", len(result.Changes)),
+	)
+
+	return &result, nil
+}
+
+// ListSnapshots lists the database snapshots, newest first
+func (c *APIClient) ListSnapshots(ctx context.Context) ([]*DatabaseSnapshot, error) {
+	resp, err := c.doRequest(ctx, "GET", "/api/v1/admin/database/snapshots", nil, true)
+	if err != nil {
+		return nil, err
+	}
+
+	var snapshotsResp SnapshotsResponse
+	if err := c.parseResponse(resp, &snapshotsResp); err != nil {
+		return nil, err
+	}
+
+	return snapshotsResp.Snapshots, nil
+}
+
+// CreateSnapshot snapshots FlintRoute's database
+func (c *APIClient) CreateSnapshot(ctx context.Context, note string) (*DatabaseSnapshot, error) {
+	req := CreateSnapshotRequest{Note: note}
+	resp, err := c.doRequest(ctx, "POST", "/api/v1/admin/database/snapshots", req, true)
+	if err != nil {
+		return nil, err
+	}
+
+	var snapshot DatabaseSnapshot
+	if err := c.parseResponse(resp, &snapshot); err != nil {
+		return nil, err
+	}
+
+	c.logger.Info("Database snapshot created", zap.Uint("id", snapshot.ID))
+
+	return &snapshot, nil
+}
+
+// DownloadSnapshot streams a database snapshot as a SQLite file. The
+// caller must close the returned body.
+func (c *APIClient) DownloadSnapshot(ctx context.Context, id uint) (io.ReadCloser, error) {
+	return c.export(ctx, fmt.Sprintf("/api/v1/admin/database/snapshots/%d", id))
+}
+
+// RestoreSnapshot replaces FlintRoute's database with a snapshot's
+// contents. The server snapshots the current database first; the result
+// names that backup.
+func (c *APIClient) RestoreSnapshot(ctx context.Context, id uint) (*RestoreSnapshotResult, error) {
+	path := fmt.Sprintf("/api/v1/admin/database/restore/%d", id)
+	resp, err := c.doRequest(ctx, "POST", path, RestoreSnapshotRequest{Confirm: true}, true)
+	if err != nil {
+		return nil, err
+	}
+
+	var result RestoreSnapshotResult
+	if err := c.parseResponse(resp, &result); err != nil {
+		return nil, err
+	}
+
+	c.logger.Info("Database snapshot restored", zap.Uint("id", id))
+
+	return &result, nil
+}
+
+// DeleteSnapshot deletes a database snapshot
+func (c *APIClient) DeleteSnapshot(ctx context.Context, id uint) error {
+	path := fmt.Sprintf("/api/v1/admin/database/snapshots/%d", id)
+	resp, err := c.doRequest(ctx, "DELETE", path, nil, true)
+	if err != nil {
+		return err
+	}
+
+	var msgResp MessageResponse
+	if err := c.parseResponse(resp, &msgResp); err != nil {
+		return err
+	}
+
+	c.logger.Info("Database snapshot deleted", zap.Uint("id", id))
+
+	return nil
+}
+
+// ListAuditEntries lists up to limit audit entries, newest first. A limit
+// of 0 uses the server's default.
+func (c *APIClient) ListAuditEntries(ctx context.Context, limit int) ([]*AuditEntry, error) {
+	page, err := c.ListAuditEntryPage(ctx, PageParams{Limit: limit})
+	if err != nil {
+		return nil, err
+	}
+	return page.Entries, nil
+}
+
+// ListAuditEntryPage lists a page of audit entries, newest first. Pass the
+// page's NextCursor in page to get the next one.
+func (c *APIClient) ListAuditEntryPage(ctx context.Context, page PageParams) (*AuditEntriesResponse, error) {
+	path := "/api/v1/admin/audit"
+	if query := pageQuery(url.Values{}, page); len(query) > 0 {
+		path += "?" + query.Encode()
+	}
+	resp, err := c.doRequest(ctx, "GET", path, nil, true)
+	if err != nil {
+		return nil, err
+	}
+
+	var entriesResp AuditEntriesResponse
+	if err := c.parseResponse(resp, &entriesResp); err != nil {
+		return nil, err
+	}
+
+	return &entriesResp, nil
+}
+
+// ListActivity lists a page of recent audit entries, alerts, session events
+// and config versions, newest first. Pass the page's NextCursor in params
+// to get the next page.
+func (c *APIClient) ListActivity(ctx context.Context, params *ActivityQueryParams) (*ActivityPage, error) {
+	path := "/api/v1/activity"
+	query := url.Values{}
+	if params != nil {
+		if len(params.Types) > 0 {
+			query.Set("types", strings.Join(params.Types, ","))
+		}
+		if params.Cursor != "" {
+			query.Set("cursor", params.Cursor)
+		}
+		if params.Limit > 0 {
+			query.Set("limit", strconv.Itoa(params.Limit))
+		}
+	}
+	if len(query) > 0 {
+		path += "?" + query.Encode()
+	}
+
+	resp, err := c.doRequest(ctx, "GET", path, nil, true)
+	if err != nil {
+		return nil, err
+	}
+
+	var page ActivityPage
+	if err := c.parseResponse(resp, &page); err != nil {
+		return nil, err
+	}
+
+	return &page, nil
+}
+
+// GetJob gets a background job's status, progress and result
+func (c *APIClient) GetJob(ctx context.Context, id uint) (*Job, error) {
+	path := fmt.Sprintf("/api/v1/jobs/%d", id)
+	resp, err := c.doRequest(ctx, "GET", path, nil, true)
+	if err != nil {
+		return nil, err
+	}
+
+	var job Job
+	if err := c.parseResponse(resp, &job); err != nil {
+		return nil, err
+	}
+
+	return &job, nil
+}
+
+// WaitForJob polls a background job every interval until it finishes or ctx
+// is done. A failed job is returned together with an error wrapping
+// ErrJobFailed, and a cancelled one with ErrJobCancelled.
+func (c *APIClient) WaitForJob(ctx context.Context, id uint, interval time.Duration) (*Job, error) {
+	if interval <= 0 {
+		interval = 500 * time.Millisecond
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		job, err := c.GetJob(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		if job.Status == JobFailed {
+			return job, fmt.Errorf("%w: %s", ErrJobFailed, job.Error)
+		}
+		if job.Status == JobCancelled {
+			return job, ErrJobCancelled
+		}
+		if job.Done() {
+			return job, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return job, ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// runJob queues a background job with a POST of body to path, waits for it
+// and decodes its result into target, if given
+func (c *APIClient) runJob(ctx context.Context, path string, body, target interface{}) error {
+	resp, err := c.doRequest(ctx, "POST", path, body, true)
+	if err != nil {
+		return err
+	}
+
+	var job Job
+	if err := c.parseResponse(resp, &job); err != nil {
+		return err
+	}
+
+	c.logger.Debug("Job queued", zap.Uint("job_id", job.ID), zap.String("type", job.Type))
+
+	done, err := c.WaitForJob(ctx, job.ID, 0)
+	if err != nil {
+		return err
+	}
+
+	if target != nil && len(done.Result) > 0 {
+		if err := json.Unmarshal(done.Result, target); err != nil {
+			return fmt.Errorf("failed to parse job result: %w", err)
+		}
+	}
+	return nil
+}
+
+// ListChanges lists change requests held for approval, newest first. An
+// empty status lists every change request.
+func (c *APIClient) ListChanges(ctx context.Context, status string) ([]*ChangeRequest, error) {
+	path := "/api/v1/changes"
+	if status != "" {
+		path += "?status=" + url.QueryEscape(status)
+	}
+
+	resp, err := c.doRequest(ctx, "GET", path, nil, true)
+	if err != nil {
+		return nil, err
+	}
+
+	var list []*ChangeRequest
+	if err := c.parseResponse(resp, &list); err != nil {
+		return nil, err
+	}
+
+	return list, nil
+}
+
+// GetChange gets a change request
+func (c *APIClient) GetChange(ctx context.Context, id uint) (*ChangeRequest, error) {
+	path := fmt.Sprintf("/api/v1/changes/%d", id)
+	resp, err := c.doRequest(ctx, "GET", path, nil, true)
+	if err != nil {
+		return nil, err
+	}
+
+	var change ChangeRequest
+	if err := c.parseResponse(resp, &change); err != nil {
+		return nil, err
+	}
+
+	return &change, nil
+}
+
+// ApproveChange approves a change request, which applies it. A change that
+// fails to apply is returned with status "failed" and its error.
+func (c *APIClient) ApproveChange(ctx context.Context, id uint, comment string) (*ChangeRequest, error) {
+	return c.reviewChange(ctx, id, "approve", comment)
+}
+
+// RejectChange rejects a change request
+func (c *APIClient) RejectChange(ctx context.Context, id uint, comment string) (*ChangeRequest, error) {
+	return c.reviewChange(ctx, id, "reject", comment)
+}
+
+// reviewChange approves or rejects a change request
+func (c *APIClient) reviewChange(ctx context.Context, id uint, action, comment string) (*ChangeRequest, error) {
+	path := fmt.Sprintf("/api/v1/changes/%d/%s", id, action)
+	resp, err := c.doRequest(ctx, "POST", path, ReviewChangeRequest{Comment: comment}, true)
+	if err != nil {
+		return nil, err
+	}
+
+	var change ChangeRequest
+	if err := c.parseResponse(resp, &change); err != nil {
+		return nil, err
+	}
+
+	c.logger.Info("Change request reviewed", zap.Uint("change_id", id), zap.String("status", change.Status))
+
+	return &change, nil
+}
+
+// ListAlerts lists alerts with optional filters
+func (c *APIClient) ListAlerts(ctx context.Context, params *AlertQueryParams) ([]*Alert, error) {
+	path := "/api/v1/alerts"
+	if query := alertQuery(params); len(query) > 0 {
+		path += "?" + query.Encode()
+	}
+
+	resp, err := c.doRequest(ctx, "GET", path, nil, true)
+	if err != nil {
+		return nil, err
+	}
+
+	var alertsResp AlertsResponse
+	if err := c.parseResponse(resp, &alertsResp); err != nil {
+		return nil, err
+	}
+
+	c.logger.Debug("Alerts listed", zap.Int("count", len(alertsResp.Alerts)))
+
+	return alertsResp.Alerts, nil
+}
+
+// ListAlertPage lists a page of the alerts matching params, newest first.
+// Pass the page's NextCursor in page to get the next one.
+func (c *APIClient) ListAlertPage(ctx context.Context, params *AlertQueryParams, page PageParams) (*AlertsResponse, error) {
+	if page.Limit <= 0 {
+		page.Limit = defaultPageLimit
+	}
+	path := "/api/v1/alerts?" + pageQuery(alertQuery(params), page).Encode()
+
+	resp, err := c.doRequest(ctx, "GET", path, nil, true)
+	if err != nil {
+		return nil, err
+	}
+
+	var alertsResp AlertsResponse
+	if err := c.parseResponse(resp, &alertsResp); err != nil {
+		return nil, err
+	}
+
+	return &alertsResp, nil
+}
+
+// ExportAlerts streams the alerts matching params in format (ExportCSV or
+// ExportNDJSON), oldest first. The caller must close the returned body.
+func (c *APIClient) ExportAlerts(ctx context.Context, format string, params *AlertQueryParams) (io.ReadCloser, error) {
+	query := alertQuery(params)
+	query.Set("format", format)
+	return c.export(ctx, "/api/v1/alerts/export?"+query.Encode())
+}
+
+// ExportSessions streams the BGP sessions in format (ExportCSV or
+// ExportNDJSON), optionally only those of peers matching all tag selectors.
+// The caller must close the returned body.
+func (c *APIClient) ExportSessions(ctx context.Context, format string, tags ...string) (io.ReadCloser, error) {
+	query := url.Values{"format": {format}}
+	if len(tags) > 0 {
+		query["tag"] = tags
+	}
+	return c.export(ctx, "/api/v1/bgp/sessions/export?"+query.Encode())
+}
+
+// export gets a streamed export, leaving its body to the caller
+func (c *APIClient) export(ctx context.Context, path string) (io.ReadCloser, error) {
+	resp, err := c.doRequest(ctx, "GET", path, nil, true)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 400 {
+		return nil, c.parseResponse(resp, nil)
+	}
+	return resp.Body, nil
+}
+
+// alertQuery encodes alert filters as query parameters
+func alertQuery(params *AlertQueryParams) url.Values {
+	query := url.Values{}
+	if params != nil {
+		if params.Acknowledged != nil {
+			if *params.Acknowledged {
+				query.Set("acknowledged", "true")
+			} else {
+				query.Set("acknowledged", "false")
+			}
+		}
+		if params.Severity != "" {
+			query.Set("severity", params.Severity)
+		}
+		if params.Source != "" {
+			query.Set("source", params.Source)
+		}
+		if params.Archived != nil && *params.Archived {
+			query.Set("archived", "true")
+		}
+		for _, tag := range params.Tags {
+			query.Add("tag", tag)
+		}
+	}
+	return query
+}
+
+// AcknowledgeAlert acknowledges an alert
+func (c *APIClient) AcknowledgeAlert(ctx context.Context, id uint) error {
+	path := fmt.Sprintf("/api/v1/alerts/%d/acknowledge", id)
+	resp, err := c.doRequest(ctx, "POST", path, nil, true)
+	if err != nil {
+		return err
+	}
+
+	var alert Alert
+	if err := c.parseResponse(resp, &alert); err != nil {
+		return err
+	}
+
+	c.logger.Info("Alert acknowledged", zap.Uint("id", id))
+
+	return nil
+}
+
+// AcknowledgeAllAlerts acknowledges every unacknowledged alert matching req
+// and returns how many were acknowledged. A nil req matches all alerts.
+func (c *APIClient) AcknowledgeAllAlerts(ctx context.Context, req *AcknowledgeAlertsRequest) (int64, error) {
+	if req == nil {
+		req = &AcknowledgeAlertsRequest{}
+	}
+
+	resp, err := c.doRequest(ctx, "POST", "/api/v1/alerts/acknowledge-all", req, true)
+	if err != nil {
+		return 0, err
+	}
+
+	var ackResp struct {
+		Acknowledged int64 `json:"acknowledged"`
+	}
+	if err := c.parseResponse(resp, &ackResp); err != nil {
+		return 0, err
+	}
+
+	c.logger.Info("Alerts acknowledged", zap.Int64("count", ackResp.Acknowledged))
+
+	return ackResp.Acknowledged, nil
+}
+
+// GetAlertSummary counts unarchived alerts by severity
+func (c *APIClient) GetAlertSummary(ctx context.Context) (*AlertSummary, error) {
+	resp, err := c.doRequest(ctx, "GET", "/api/v1/alerts/summary", nil, true)
+	if err != nil {
+		return nil, err
+	}
+
+	var summary AlertSummary
+	if err := c.parseResponse(resp, &summary); err != nil {
+		return nil, err
+	}
+
+	return &summary, nil
+}
+
+// ExportAlertmanagerAlerts lists unacknowledged FlintRoute alerts in
+// Alertmanager format
+func (c *APIClient) ExportAlertmanagerAlerts(ctx context.Context) ([]*AlertmanagerAlert, error) {
+	resp, err := c.doRequest(ctx, "GET", "/api/v1/alertmanager/alerts", nil, true)
+	if err != nil {
+		return nil, err
+	}
+
+	var alerts []*AlertmanagerAlert
+	if err := c.parseResponse(resp, &alerts); err != nil {
+		return nil, err
+	}
+
+	return alerts, nil
+}
+
+// ListWebhooks lists all webhook subscriptions
+func (c *APIClient) ListWebhooks(ctx context.Context) ([]*Webhook, error) {
+	resp, err := c.doRequest(ctx, "GET", "/api/v1/webhooks", nil, true)
+	if err != nil {
+		return nil, err
+	}
+
+	var webhooksResp WebhooksResponse
+	if err := c.parseResponse(resp, &webhooksResp); err != nil {
+		return nil, err
+	}
+
+	c.logger.Debug("Webhooks listed", zap.Int("count", len(webhooksResp.Webhooks)))
+
+	return webhooksResp.Webhooks, nil
+}
+
+// CreateWebhook creates a webhook subscription. The returned webhook
+// carries the signing secret, which is not returned again.
+func (c *APIClient) CreateWebhook(ctx context.Context, webhook *WebhookRequest) (*Webhook, error) {
+	resp, err := c.doRequest(ctx, "POST", "/api/v1/webhooks", webhook, true)
+	if err != nil {
+		return nil, err
+	}
+
+	var created Webhook
+	if err := c.parseResponse(resp, &created); err != nil {
+		return nil, err
+	}
+
+	c.logger.Info("Webhook created", zap.Uint("id", created.ID), zap.String("url", created.URL))
+
+	return &created, nil
+}
+
+// GetWebhook retrieves a webhook subscription by ID
+func (c *APIClient) GetWebhook(ctx context.Context, id uint) (*Webhook, error) {
+	path := fmt.Sprintf("/api/v1/webhooks/%d", id)
+	resp, err := c.doRequest(ctx, "GET", path, nil, true)
+	if err != nil {
+		return nil, err
+	}
+
+	var webhook Webhook
+	if err := c.parseResponse(resp, &webhook); err != nil {
+		return nil, err
+	}
+
+	return &webhook, nil
+}
+
+// UpdateWebhook replaces a webhook subscription
+func (c *APIClient) UpdateWebhook(ctx context.Context, id uint, webhook *WebhookRequest) (*Webhook, error) {
+	path := fmt.Sprintf("/api/v1/webhooks/%d", id)
+	resp, err := c.doRequest(ctx, "PUT", path, webhook, true)
+	if err != nil {
+		return nil, err
+	}
+
+	var updated Webhook
+	if err := c.parseResponse(resp, &updated); err != nil {
+		return nil, err
+	}
+
+	c.logger.Info("Webhook updated", zap.Uint("id", id))
+
+	return &updated, nil
+}
+
+// DeleteWebhook deletes a webhook subscription
+func (c *APIClient) DeleteWebhook(ctx context.Context, id uint) error {
+	path := fmt.Sprintf("/api/v1/webhooks/%d", id)
+	resp, err := c.doRequest(ctx, "DELETE", path, nil, true)
+	if err != nil {
+		return err
+	}
+
+	var msgResp MessageResponse
+	if err := c.parseResponse(resp, &msgResp); err != nil {
+		return err
+	}
+
+	c.logger.Info("Webhook deleted", zap.Uint("id", id))
+
+	return nil
+}
+
+// PingWebhook queues a ping event for a webhook subscription
+func (c *APIClient) PingWebhook(ctx context.Context, id uint) (*WebhookDelivery, error) {
+	path := fmt.Sprintf("/api/v1/webhooks/%d/ping", id)
+	resp, err := c.doRequest(ctx, "POST", path, nil, true)
+	if err != nil {
+		return nil, err
+	}
+
+	var delivery WebhookDelivery
+	if err := c.parseResponse(resp, &delivery); err != nil {
+		return nil, err
+	}
+
+	return &delivery, nil
+}
+
+// ListWebhookDeliveries lists the most recent deliveries for a webhook
+// subscription. status and limit are optional.
+func (c *APIClient) ListWebhookDeliveries(ctx context.Context, id uint, status string, limit int) ([]*WebhookDelivery, error) {
+	path := fmt.Sprintf("/api/v1/webhooks/%d/deliveries", id)
+
+	query := url.Values{}
+	if status != "" {
+		query.Set("status", status)
+	}
+	if limit > 0 {
+		query.Set("limit", fmt.Sprintf("%d", limit))
+	}
+	if len(query) > 0 {
+		path += "?" + query.Encode()
+	}
+
+	resp, err := c.doRequest(ctx, "GET", path, nil, true)
+	if err != nil {
+		return nil, err
+	}
+
+	var deliveriesResp WebhookDeliveriesResponse
+	if err := c.parseResponse(resp, &deliveriesResp); err != nil {
+		return nil, err
+	}
+
+	return deliveriesResp.Deliveries, nil
+}
+
+// RedeliverWebhook queues a new delivery of an earlier delivery's payload
+func (c *APIClient) RedeliverWebhook(ctx context.Context, deliveryID uint) (*WebhookDelivery, error) {
+	path := fmt.Sprintf("/api/v1/webhooks/deliveries/%d/redeliver", deliveryID)
+	resp, err := c.doRequest(ctx, "POST", path, nil, true)
+	if err != nil {
+		return nil, err
+	}
+
+	var delivery WebhookDelivery
+	if err := c.parseResponse(resp, &delivery); err != nil {
+		return nil, err
+	}
+
+	c.logger.Info("Webhook redelivery queued", zap.Uint("delivery_id", deliveryID))
+
+	return &delivery, nil
+}
+
+// HealthCheck performs a health check
+func (c *APIClient) HealthCheck(ctx context.Context) error {
+	resp, err := c.doRequest(ctx, "GET", "/health", nil, false)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return fmt.Errorf("health check failed with status %d", resp.StatusCode)
+	}
+
+	resp.Body.Close()
+	c.logger.Debug("Health check passed")
+
+	return nil
+}
+
+// IsAuthenticated returns true if the client is authenticated
+func (c *APIClient) IsAuthenticated() bool {
+	return c.tokenManager.IsAuthenticated()
+}
@@ -0,0 +1,1516 @@
+package client
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestServer(t *testing.T, handler http.HandlerFunc) (*APIClient, *httptest.Server) {
+	t.Helper()
+
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	return NewAPIClient(server.URL, nil), server
+}
+
+func TestLogin(t *testing.T) {
+	t.Run("Stores tokens on success", func(t *testing.T) {
+		client, _ := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "/api/v1/auth/login", r.URL.Path)
+			json.NewEncoder(w).Encode(LoginResponse{
+				AccessToken:  "access",
+				RefreshToken: "refresh",
+				ExpiresIn:    900,
+				User:         UserInfo{ID: 1, Username: "admin"},
+			})
+		})
+
+		resp, err := client.Login(context.Background(), "admin", "admin")
+		require.NoError(t, err)
+		assert.Equal(t, "access", resp.AccessToken)
+		assert.True(t, client.IsAuthenticated())
+	})
+
+	t.Run("Returns typed error on failure", func(t *testing.T) {
+		client, _ := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusUnauthorized)
+			json.NewEncoder(w).Encode(ErrorResponse{Error: "Invalid credentials"})
+		})
+
+		_, err := client.Login(context.Background(), "admin", "wrong")
+		require.Error(t, err)
+		assert.True(t, IsUnauthorized(err))
+
+		apiErr, ok := AsAPIError(err)
+		require.True(t, ok)
+		assert.Equal(t, "Invalid credentials", apiErr.Message)
+		assert.False(t, client.IsAuthenticated())
+	})
+}
+
+func TestAuthenticatedRequest(t *testing.T) {
+	client, _ := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v1/auth/login":
+			json.NewEncoder(w).Encode(LoginResponse{AccessToken: "access", RefreshToken: "refresh", ExpiresIn: 900})
+		case "/api/v1/bgp/peers/42":
+			assert.Equal(t, "Bearer access", r.Header.Get("Authorization"))
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(ErrorResponse{Error: "Peer not found", Code: CodePeerNotFound, RequestID: "req-1"})
+		}
+	})
+
+	_, err := client.Login(context.Background(), "admin", "admin")
+	require.NoError(t, err)
+
+	_, err = client.GetPeer(context.Background(), 42)
+	assert.True(t, IsNotFound(err))
+	assert.True(t, HasCode(err, CodePeerNotFound))
+
+	apiErr, ok := AsAPIError(err)
+	require.True(t, ok)
+	assert.Equal(t, "req-1", apiErr.RequestID)
+}
+
+func TestFieldErrors(t *testing.T) {
+	fieldErrors := []FieldError{{Field: "remote_asn", Message: "required"}}
+	for name, resp := range map[string]ErrorResponse{
+		"errors":  {Error: "Invalid request", Code: CodeValidationFailed, Errors: fieldErrors},
+		"details": {Error: "Invalid request", Code: CodeValidationFailed, Details: fieldErrors},
+	} {
+		t.Run("Reads "+name, func(t *testing.T) {
+			client, _ := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusBadRequest)
+				json.NewEncoder(w).Encode(resp)
+			})
+
+			_, err := client.Login(context.Background(), "admin", "")
+			apiErr, ok := AsAPIError(err)
+			require.True(t, ok)
+			assert.Equal(t, fieldErrors, apiErr.Details)
+		})
+	}
+}
+
+func TestFRRDetail(t *testing.T) {
+	detail := &FRRDetail{Command: "neighbor 192.0.2.1 route-map", Line: 12, Raw: "line 12: % Unknown command[4]: neighbor 192.0.2.1 route-map"}
+	client, _ := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "FRR rejected the configuration", Code: CodeFRRValidation, FRR: detail})
+	})
+
+	_, err := client.Login(context.Background(), "admin", "admin")
+	assert.True(t, HasCode(err, CodeFRRValidation))
+	apiErr, ok := AsAPIError(err)
+	require.True(t, ok)
+	assert.Equal(t, detail, apiErr.FRR)
+}
+
+func TestRetryPolicy(t *testing.T) {
+	policy := RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond, MaxBackoff: 5 * time.Millisecond}
+
+	t.Run("Retries idempotent requests", func(t *testing.T) {
+		var calls int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if atomic.AddInt32(&calls, 1) < 3 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+		}))
+		defer server.Close()
+
+		client := NewAPIClient(server.URL, nil, WithRetryPolicy(policy))
+		assert.NoError(t, client.HealthCheck(context.Background()))
+		assert.Equal(t, int32(3), atomic.LoadInt32(&calls))
+	})
+
+	t.Run("Does not retry non-idempotent requests", func(t *testing.T) {
+		var calls int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&calls, 1)
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}))
+		defer server.Close()
+
+		client := NewAPIClient(server.URL, nil, WithRetryPolicy(policy))
+		_, err := client.Login(context.Background(), "admin", "admin")
+		assert.Error(t, err)
+		assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+	})
+
+	t.Run("Retries POSTs with an idempotency key", func(t *testing.T) {
+		var calls int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "login-1", r.Header.Get("Idempotency-Key"))
+			if atomic.AddInt32(&calls, 1) < 3 {
+				w.WriteHeader(http.StatusBadGateway)
+				return
+			}
+			json.NewEncoder(w).Encode(LoginResponse{AccessToken: "access", RefreshToken: "refresh", ExpiresIn: 900})
+		}))
+		defer server.Close()
+
+		client := NewAPIClient(server.URL, nil, WithRetryPolicy(policy))
+		_, err := client.Login(WithIdempotencyKey(context.Background(), "login-1"), "admin", "admin")
+		assert.NoError(t, err)
+		assert.Equal(t, int32(3), atomic.LoadInt32(&calls))
+	})
+
+	t.Run("Backoff is capped", func(t *testing.T) {
+		assert.Equal(t, time.Millisecond, policy.backoff(1))
+		assert.Equal(t, 2*time.Millisecond, policy.backoff(2))
+		assert.Equal(t, 5*time.Millisecond, policy.backoff(10))
+	})
+
+	t.Run("Retries 429 for any method after Retry-After", func(t *testing.T) {
+		var calls int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if atomic.AddInt32(&calls, 1) == 1 {
+				w.Header().Set("Retry-After", "0")
+				w.WriteHeader(http.StatusTooManyRequests)
+				return
+			}
+			json.NewEncoder(w).Encode(LoginResponse{AccessToken: "access", RefreshToken: "refresh", ExpiresIn: 900})
+		}))
+		defer server.Close()
+
+		client := NewAPIClient(server.URL, nil, WithRetryPolicy(policy))
+		_, err := client.Login(context.Background(), "admin", "admin")
+		assert.NoError(t, err)
+		assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+	})
+
+	t.Run("Gives up when Retry-After is too long", func(t *testing.T) {
+		var calls int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&calls, 1)
+			w.Header().Set("Retry-After", "3600")
+			w.WriteHeader(http.StatusTooManyRequests)
+			json.NewEncoder(w).Encode(ErrorResponse{Error: "Request quota exceeded", Code: CodeQuotaExceeded})
+		}))
+		defer server.Close()
+
+		client := NewAPIClient(server.URL, nil, WithRetryPolicy(policy))
+		_, err := client.Login(context.Background(), "admin", "admin")
+		assert.True(t, IsRateLimited(err))
+		apiErr, ok := AsAPIError(err)
+		require.True(t, ok)
+		assert.Equal(t, time.Hour, apiErr.RetryAfter)
+		assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+	})
+
+	t.Run("Retries custom statuses", func(t *testing.T) {
+		var calls int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if atomic.AddInt32(&calls, 1) < 3 {
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+		}))
+		defer server.Close()
+
+		custom := policy
+		custom.RetryStatuses = []int{http.StatusInternalServerError}
+		client := NewAPIClient(server.URL, nil, WithRetryPolicy(custom))
+		assert.NoError(t, client.HealthCheck(context.Background()))
+		assert.Equal(t, int32(3), atomic.LoadInt32(&calls))
+	})
+
+	t.Run("Jitter shortens backoff", func(t *testing.T) {
+		jittered := RetryPolicy{InitialBackoff: 100 * time.Millisecond, Jitter: 0.5}
+		for i := 0; i < 20; i++ {
+			delay := jittered.delay(1)
+			assert.GreaterOrEqual(t, delay, 50*time.Millisecond)
+			assert.LessOrEqual(t, delay, 100*time.Millisecond)
+		}
+	})
+
+	t.Run("Parses Retry-After", func(t *testing.T) {
+		delay, ok := parseRetryAfter("7")
+		assert.True(t, ok)
+		assert.Equal(t, 7*time.Second, delay)
+
+		delay, ok = parseRetryAfter(time.Now().Add(time.Minute).UTC().Format(http.TimeFormat))
+		assert.True(t, ok)
+		assert.InDelta(t, time.Minute.Seconds(), delay.Seconds(), 2)
+
+		_, ok = parseRetryAfter("soon")
+		assert.False(t, ok)
+	})
+}
+
+func TestAutoIdempotencyKeys(t *testing.T) {
+	var keys []string
+	var calls int32
+	client, _ := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method + " " + r.URL.Path {
+		case "POST /api/v1/auth/login":
+			assert.Empty(t, r.Header.Get("Idempotency-Key"))
+			json.NewEncoder(w).Encode(LoginResponse{AccessToken: "access", RefreshToken: "refresh", ExpiresIn: 900})
+		case "POST /api/v1/bgp/peers":
+			keys = append(keys, r.Header.Get("Idempotency-Key"))
+			if atomic.AddInt32(&calls, 1) == 1 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			json.NewEncoder(w).Encode(Peer{ID: 1})
+		}
+	})
+	WithAutoIdempotencyKeys()(client)
+	WithRetryPolicy(RetryPolicy{MaxAttempts: 2, InitialBackoff: time.Millisecond})(client)
+
+	_, err := client.Login(context.Background(), "admin", "admin")
+	require.NoError(t, err)
+
+	_, err = client.CreatePeer(context.Background(), &PeerRequest{})
+	require.NoError(t, err)
+	_, err = client.CreatePeer(WithIdempotencyKey(context.Background(), "peer-1"), &PeerRequest{})
+	require.NoError(t, err)
+
+	require.Len(t, keys, 3)
+	assert.Len(t, keys[0], 32)
+	assert.Equal(t, keys[0], keys[1], "retries keep the key")
+	assert.Equal(t, "peer-1", keys[2])
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	var healthy atomic.Bool
+	var calls int32
+	client, _ := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		if !healthy.Load() {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+	WithCircuitBreaker(2, 50*time.Millisecond)(client)
+
+	assert.Error(t, client.HealthCheck(context.Background()))
+	assert.Equal(t, CircuitClosed, client.CircuitState())
+	assert.Error(t, client.HealthCheck(context.Background()))
+	assert.Equal(t, CircuitOpen, client.CircuitState())
+
+	err := client.HealthCheck(context.Background())
+	assert.ErrorIs(t, err, ErrCircuitOpen)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&calls), "open circuit fails fast")
+
+	t.Run("Trial failure reopens", func(t *testing.T) {
+		time.Sleep(60 * time.Millisecond)
+		assert.Equal(t, CircuitHalfOpen, client.CircuitState())
+		assert.Error(t, client.HealthCheck(context.Background()))
+		assert.Equal(t, CircuitOpen, client.CircuitState())
+	})
+
+	t.Run("Trial success closes", func(t *testing.T) {
+		healthy.Store(true)
+		time.Sleep(60 * time.Millisecond)
+		assert.NoError(t, client.HealthCheck(context.Background()))
+		assert.Equal(t, CircuitClosed, client.CircuitState())
+	})
+}
+
+func TestMiddleware(t *testing.T) {
+	client, _ := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "outer,inner", r.Header.Get("X-Trace"))
+		w.WriteHeader(http.StatusOK)
+	})
+
+	var order []string
+	trace := func(name string) Middleware {
+		return func(next RoundTripFunc) RoundTripFunc {
+			return func(req *http.Request) (*http.Response, error) {
+				order = append(order, name)
+				trace := name
+				if outer := req.Header.Get("X-Trace"); outer != "" {
+					trace = outer + "," + name
+				}
+				req.Header.Set("X-Trace", trace)
+				return next(req)
+			}
+		}
+	}
+	WithMiddleware(trace("outer"), trace("inner"))(client)
+
+	require.NoError(t, client.HealthCheck(context.Background()))
+	assert.Equal(t, []string{"outer", "inner"}, order)
+
+	t.Run("Middleware can answer requests", func(t *testing.T) {
+		stub := NewAPIClient("http://127.0.0.1:0", nil, WithMiddleware(func(next RoundTripFunc) RoundTripFunc {
+			return func(req *http.Request) (*http.Response, error) {
+				return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader("")), Header: http.Header{}}, nil
+			}
+		}))
+		assert.NoError(t, stub.HealthCheck(context.Background()))
+	})
+}
+
+func TestRateLimit(t *testing.T) {
+	reset := time.Now().Add(time.Hour).Truncate(time.Second)
+	client, _ := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/health" {
+			w.Header().Set("X-RateLimit-Limit", "100")
+			w.Header().Set("X-RateLimit-Remaining", "42")
+			w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(reset.Unix(), 10))
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+
+	_, ok := client.RateLimit()
+	assert.False(t, ok)
+
+	require.NoError(t, client.HealthCheck(context.Background()))
+	quota, ok := client.RateLimit()
+	require.True(t, ok)
+	assert.Equal(t, 100, quota.RequestsPerHour)
+	assert.Equal(t, 42, quota.Remaining)
+	assert.True(t, reset.Equal(quota.ResetAt))
+}
+
+func TestContextCancellation(t *testing.T) {
+	client, _ := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(100 * time.Millisecond)
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := client.HealthCheck(ctx)
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestRequestTimeout(t *testing.T) {
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(200 * time.Millisecond):
+		case <-r.Context().Done():
+		}
+	}
+
+	t.Run("Client-wide request timeout", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(handler))
+		defer server.Close()
+
+		client := NewAPIClient(server.URL, nil, WithRequestTimeout(20*time.Millisecond))
+		err := client.HealthCheck(context.Background())
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+	})
+
+	t.Run("Per-call override", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(handler))
+		defer server.Close()
+
+		client := NewAPIClient(server.URL, nil, WithRequestTimeout(time.Second))
+		ctx := WithCallTimeout(context.Background(), 20*time.Millisecond)
+		err := client.HealthCheck(ctx)
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+	})
+}
+
+func TestTokenRefreshHonorsContext(t *testing.T) {
+	var refreshed int32
+	client, _ := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/v1/auth/refresh" {
+			atomic.AddInt32(&refreshed, 1)
+		}
+		json.NewEncoder(w).Encode(PeersResponse{})
+	})
+
+	// Expired access token forces a refresh on the next authenticated call
+	client.tokenManager.SetTokens("stale", "refresh", 0)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := client.ListPeers(ctx)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, int32(0), atomic.LoadInt32(&refreshed))
+}
+
+func TestRequestIDPropagation(t *testing.T) {
+	client, _ := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Request-ID", r.Header.Get("X-Request-ID"))
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+
+	ctx := WithRequestID(context.Background(), "trace-1")
+	_, err := client.Login(ctx, "admin", "admin")
+
+	apiErr, ok := AsAPIError(err)
+	require.True(t, ok)
+	assert.Equal(t, "trace-1", apiErr.RequestID)
+}
+
+func TestVerifyWebhookSignature(t *testing.T) {
+	body := []byte(`{"id":"1","type":"ping"}`)
+	mac := hmac.New(sha256.New, []byte("s3cret"))
+	mac.Write(body)
+	signature := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+
+	assert.True(t, VerifyWebhookSignature("s3cret", body, signature))
+	assert.False(t, VerifyWebhookSignature("other", body, signature))
+	assert.False(t, VerifyWebhookSignature("s3cret", []byte(`{}`), signature))
+	assert.False(t, VerifyWebhookSignature("s3cret", body, strings.TrimPrefix(signature, "sha256=")))
+	assert.False(t, VerifyWebhookSignature("", body, signature))
+}
+
+func TestAlertBulkOperations(t *testing.T) {
+	client, _ := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v1/auth/login":
+			json.NewEncoder(w).Encode(LoginResponse{AccessToken: "access", RefreshToken: "refresh", ExpiresIn: 900})
+		case "/api/v1/alerts/acknowledge-all":
+			var req AcknowledgeAlertsRequest
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+			assert.Equal(t, "critical", req.Severity)
+			json.NewEncoder(w).Encode(map[string]int64{"acknowledged": 3})
+		case "/api/v1/alerts/summary":
+			json.NewEncoder(w).Encode(AlertSummary{
+				Total:          4,
+				Unacknowledged: 1,
+				BySeverity:     map[string]AlertSeverityCount{"warning": {Total: 4, Unacknowledged: 1}},
+			})
+		case "/api/v1/alerts":
+			assert.Equal(t, "true", r.URL.Query().Get("archived"))
+			assert.Equal(t, []string{"pop:fra1"}, r.URL.Query()["tag"])
+			json.NewEncoder(w).Encode(AlertsResponse{Alerts: []*Alert{{ID: 1}}})
+		}
+	})
+
+	_, err := client.Login(context.Background(), "admin", "admin")
+	require.NoError(t, err)
+
+	count, err := client.AcknowledgeAllAlerts(context.Background(), &AcknowledgeAlertsRequest{Severity: "critical"})
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), count)
+
+	summary, err := client.GetAlertSummary(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), summary.BySeverity["warning"].Unacknowledged)
+
+	archived := true
+	alerts, err := client.ListAlerts(context.Background(), &AlertQueryParams{Archived: &archived, Tags: []string{"pop:fra1"}})
+	require.NoError(t, err)
+	assert.Len(t, alerts, 1)
+}
+
+func TestExports(t *testing.T) {
+	client, _ := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v1/auth/login":
+			json.NewEncoder(w).Encode(LoginResponse{AccessToken: "access", RefreshToken: "refresh", ExpiresIn: 900})
+		case "/api/v1/alerts/export":
+			assert.Equal(t, ExportCSV, r.URL.Query().Get("format"))
+			assert.Equal(t, "critical", r.URL.Query().Get("severity"))
+			w.Header().Set("Content-Type", "text/csv")
+			io.WriteString(w, "id,severity\n1,critical\n")
+		case "/api/v1/bgp/sessions/export":
+			if r.URL.Query().Get("format") != ExportNDJSON {
+				w.WriteHeader(http.StatusBadRequest)
+				json.NewEncoder(w).Encode(map[string]string{"code": "VALIDATION_FAILED", "error": "Unknown export format"})
+				return
+			}
+			assert.Equal(t, []string{"pop:fra1"}, r.URL.Query()["tag"])
+			io.WriteString(w, `{"id":1}`+"\n")
+		}
+	})
+
+	_, err := client.Login(context.Background(), "admin", "admin")
+	require.NoError(t, err)
+
+	body, err := client.ExportAlerts(context.Background(), ExportCSV, &AlertQueryParams{Severity: "critical"})
+	require.NoError(t, err)
+	data, err := io.ReadAll(body)
+	body.Close()
+	require.NoError(t, err)
+	assert.Equal(t, "id,severity\n1,critical\n", string(data))
+
+	body, err = client.ExportSessions(context.Background(), ExportNDJSON, "pop:fra1")
+	require.NoError(t, err)
+	data, err = io.ReadAll(body)
+	body.Close()
+	require.NoError(t, err)
+	assert.Equal(t, `{"id":1}`+"\n", string(data))
+
+	_, err = client.ExportSessions(context.Background(), "xlsx")
+	assert.Error(t, err)
+}
+
+func TestPolicyLists(t *testing.T) {
+	client, _ := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method + " " + r.URL.Path {
+		case "POST /api/v1/auth/login":
+			json.NewEncoder(w).Encode(LoginResponse{AccessToken: "access", RefreshToken: "refresh", ExpiresIn: 900})
+		case "POST /api/v1/bgp/community-lists":
+			var req CommunityListRequest
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+			w.WriteHeader(http.StatusCreated)
+			json.NewEncoder(w).Encode(CommunityList{ID: 1, Name: req.Name, Type: "standard", Entries: req.Entries})
+		case "DELETE /api/v1/bgp/as-path-lists/AS-IN":
+			w.WriteHeader(http.StatusConflict)
+			json.NewEncoder(w).Encode(map[string]string{"code": "POLICY_IN_USE", "error": "routing policy is referenced by a route-map: RM-IN"})
+		}
+	})
+
+	_, err := client.Login(context.Background(), "admin", "admin")
+	require.NoError(t, err)
+
+	list, err := client.CreateCommunityList(context.Background(), &CommunityListRequest{
+		Name:    "CL-IN",
+		Entries: []CommunityListEntry{{Seq: 10, Action: "permit", Value: "65000:100"}},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "CL-IN", list.Name)
+	assert.Equal(t, "65000:100", list.Entries[0].Value)
+
+	err = client.DeleteASPathList(context.Background(), "AS-IN")
+	assert.True(t, HasCode(err, CodePolicyInUse))
+}
+
+func TestBGPGlobal(t *testing.T) {
+	client, _ := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method + " " + r.URL.Path {
+		case "POST /api/v1/auth/login":
+			json.NewEncoder(w).Encode(LoginResponse{AccessToken: "access", RefreshToken: "refresh", ExpiresIn: 900})
+		case "PUT /api/v1/bgp/global":
+			var req map[string]interface{}
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+			assert.Equal(t, map[string]interface{}{"asn": 65001.0, "graceful_restart": true, "ebgp_requires_policy": false}, req)
+			w.Write([]byte(`{"asn":65001,"graceful_restart":true,"ebgp_requires_policy":false}`))
+		case "GET /api/v1/bgp/global":
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(map[string]string{"code": "NOT_FOUND", "error": "BGP global configuration not set"})
+		}
+	})
+
+	_, err := client.Login(context.Background(), "admin", "admin")
+	require.NoError(t, err)
+
+	requirePolicy := false
+	global, err := client.UpdateBGPGlobal(context.Background(), &BGPGlobalRequest{
+		ASN:                65001,
+		GracefulRestart:    true,
+		EBGPRequiresPolicy: &requirePolicy,
+	})
+	require.NoError(t, err)
+	assert.True(t, global.GracefulRestart)
+	assert.False(t, global.EBGPRequiresPolicy)
+
+	_, err = client.GetBGPGlobal(context.Background())
+	assert.True(t, HasCode(err, CodeNotFound))
+}
+
+func TestJobs(t *testing.T) {
+	var polls atomic.Int32
+	client, _ := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method + " " + r.URL.Path {
+		case "POST /api/v1/auth/login":
+			json.NewEncoder(w).Encode(LoginResponse{AccessToken: "access", RefreshToken: "refresh", ExpiresIn: 900})
+		case "POST /api/v1/bgp/reconcile":
+			w.WriteHeader(http.StatusAccepted)
+			json.NewEncoder(w).Encode(Job{ID: 1, Type: "bgp.reconcile", Status: JobQueued})
+		case "GET /api/v1/jobs/1":
+			if polls.Add(1) == 1 {
+				json.NewEncoder(w).Encode(Job{ID: 1, Status: JobRunning, Progress: 10})
+				return
+			}
+			json.NewEncoder(w).Encode(Job{ID: 1, Status: JobSucceeded, Progress: 100, Result: json.RawMessage(`{"in_sync":true}`)})
+		case "POST /api/v1/config/restore/3":
+			w.WriteHeader(http.StatusAccepted)
+			json.NewEncoder(w).Encode(Job{ID: 2, Type: "config.restore", Status: JobQueued})
+		case "GET /api/v1/jobs/2":
+			json.NewEncoder(w).Encode(Job{ID: 2, Status: JobFailed, Error: "failed to get config version 3"})
+		}
+	})
+
+	_, err := client.Login(context.Background(), "admin", "admin")
+	require.NoError(t, err)
+
+	t.Run("Waits for the job result", func(t *testing.T) {
+		report, err := client.Reconcile(context.Background())
+		require.NoError(t, err)
+		assert.True(t, report.InSync)
+		assert.Equal(t, int32(2), polls.Load())
+	})
+
+	t.Run("Failed job", func(t *testing.T) {
+		err := client.RestoreConfig(context.Background(), 3)
+		assert.ErrorIs(t, err, ErrJobFailed)
+		assert.Contains(t, err.Error(), "failed to get config version 3")
+	})
+}
+
+func TestRestorePreviewAndVerification(t *testing.T) {
+	client, _ := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method + " " + r.URL.Path {
+		case "POST /api/v1/auth/login":
+			json.NewEncoder(w).Encode(LoginResponse{AccessToken: "access", RefreshToken: "refresh", ExpiresIn: 900})
+		case "GET /api/v1/config/restore/4/preview":
+			json.NewEncoder(w).Encode(RestorePreview{
+				VersionID: 4,
+				Diff:      []string{"+  neighbor 192.0.2.2 remote-as 65002"},
+				Peers:     []RestorePeerCheck{{Name: "192.0.2.2", Change: "added"}},
+			})
+		case "POST /api/v1/config/restore/4":
+			var opts RestoreOptions
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&opts))
+			assert.Equal(t, "5m", opts.VerifyWindow)
+			w.WriteHeader(http.StatusAccepted)
+			json.NewEncoder(w).Encode(Job{ID: 3, Type: "config.restore", Status: JobQueued})
+		case "GET /api/v1/jobs/3":
+			json.NewEncoder(w).Encode(Job{ID: 3, Status: JobSucceeded, Progress: 100,
+				Result: json.RawMessage(`{"version_id":4,"outcome":"rolled_back","sessions_down":["192.0.2.1"]}`)})
+		}
+	})
+
+	_, err := client.Login(context.Background(), "admin", "admin")
+	require.NoError(t, err)
+
+	preview, err := client.PreviewRestore(context.Background(), 4)
+	require.NoError(t, err)
+	require.Len(t, preview.Peers, 1)
+	assert.Equal(t, "added", preview.Peers[0].Change)
+
+	result, err := client.RestoreConfigWithOptions(context.Background(), 4, &RestoreOptions{VerifyWindow: "5m"})
+	require.NoError(t, err)
+	assert.Equal(t, RestoreRolledBack, result.Outcome)
+	assert.Equal(t, []string{"192.0.2.1"}, result.SessionsDown)
+}
+
+func TestSLAReport(t *testing.T) {
+	client, _ := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method + " " + r.URL.Path {
+		case "POST /api/v1/auth/login":
+			json.NewEncoder(w).Encode(LoginResponse{AccessToken: "access", RefreshToken: "refresh", ExpiresIn: 900})
+		case "GET /api/v1/reports/sla":
+			assert.Equal(t, "7d", r.URL.Query().Get("range"))
+			assert.Equal(t, []string{"pop:fra1"}, r.URL.Query()["tag"])
+			if r.URL.Query().Get("format") == ExportCSV {
+				w.Write([]byte("peer_id,name\n1,edge\n"))
+				return
+			}
+			json.NewEncoder(w).Encode(SLAReport{Peers: []*PeerSLA{{PeerID: 1, Availability: 99.5, MTTR: 120}}})
+		}
+	})
+
+	_, err := client.Login(context.Background(), "admin", "admin")
+	require.NoError(t, err)
+
+	report, err := client.GetSLAReport(context.Background(), "7d", "pop:fra1")
+	require.NoError(t, err)
+	require.Len(t, report.Peers, 1)
+	assert.Equal(t, 99.5, report.Peers[0].Availability)
+
+	body, err := client.ExportSLAReport(context.Background(), "7d", "pop:fra1")
+	require.NoError(t, err)
+	defer body.Close()
+	data, err := io.ReadAll(body)
+	require.NoError(t, err)
+	assert.Equal(t, "peer_id,name\n1,edge\n", string(data))
+}
+
+func TestChanges(t *testing.T) {
+	client, _ := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method + " " + r.URL.Path {
+		case "POST /api/v1/auth/login":
+			json.NewEncoder(w).Encode(LoginResponse{AccessToken: "access", RefreshToken: "refresh", ExpiresIn: 900})
+		case "DELETE /api/v1/bgp/peers/4":
+			w.Header().Set("Location", "/api/v1/changes/7")
+			w.WriteHeader(http.StatusAccepted)
+			json.NewEncoder(w).Encode(ChangeRequest{ID: 7, Operation: "peer.delete", Status: ChangePending})
+		case "GET /api/v1/changes":
+			assert.Equal(t, ChangePending, r.URL.Query().Get("status"))
+			json.NewEncoder(w).Encode([]*ChangeRequest{{ID: 7, Status: ChangePending}})
+		case "POST /api/v1/changes/7/approve":
+			var req ReviewChangeRequest
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+			assert.Equal(t, "ok", req.Comment)
+			json.NewEncoder(w).Encode(ChangeRequest{ID: 7, Status: ChangeApplied, Comment: req.Comment})
+		}
+	})
+
+	_, err := client.Login(context.Background(), "admin", "admin")
+	require.NoError(t, err)
+
+	err = client.DeletePeer(context.Background(), 4)
+	assert.ErrorIs(t, err, ErrPendingApproval)
+	var pending *PendingApprovalError
+	require.ErrorAs(t, err, &pending)
+	assert.Equal(t, uint(7), pending.Change.ID)
+
+	list, err := client.ListChanges(context.Background(), ChangePending)
+	require.NoError(t, err)
+	require.Len(t, list, 1)
+
+	change, err := client.ApproveChange(context.Background(), 7, "ok")
+	require.NoError(t, err)
+	assert.Equal(t, ChangeApplied, change.Status)
+}
+
+func TestPeerTags(t *testing.T) {
+	client, _ := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v1/auth/login":
+			json.NewEncoder(w).Encode(LoginResponse{AccessToken: "access", RefreshToken: "refresh", ExpiresIn: 900})
+		case "/api/v1/bgp/peers":
+			assert.Equal(t, []string{"pop:fra1", "role"}, r.URL.Query()["tag"])
+			json.NewEncoder(w).Encode(PeersResponse{Peers: []*Peer{{ID: 1, Tags: map[string]string{"pop": "fra1", "role": "transit"}}}})
+		case "/api/v1/bgp/peers/bulk":
+			var req BulkPeersRequest
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+			assert.Equal(t, BulkPeersRequest{Tags: []string{"pop:fra1"}, Action: BulkDisable}, req)
+			json.NewEncoder(w).Encode(BulkPeersResponse{Action: req.Action, Peers: []*BulkPeerResult{{PeerID: 1, IPAddress: "192.0.2.1"}}})
+		}
+	})
+
+	_, err := client.Login(context.Background(), "admin", "admin")
+	require.NoError(t, err)
+
+	peers, err := client.ListPeers(context.Background(), "pop:fra1", "role")
+	require.NoError(t, err)
+	require.Len(t, peers, 1)
+	assert.Equal(t, "transit", peers[0].Tags["role"])
+
+	results, err := client.BulkPeers(context.Background(), []string{"pop:fra1"}, BulkDisable)
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.Empty(t, results[0].Error)
+}
+
+func TestPeerTest(t *testing.T) {
+	client, _ := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method + " " + r.URL.Path {
+		case "POST /api/v1/auth/login":
+			json.NewEncoder(w).Encode(LoginResponse{AccessToken: "access", RefreshToken: "refresh", ExpiresIn: 900})
+		case "POST /api/v1/bgp/peers/3/test":
+			w.Write([]byte(`{"peer_id":3,"ip_address":"192.0.2.1","reachable":true,"ready":false,"probed_from":"router",` +
+				`"tcp":{"result":"open","latency_ms":1.2},"md5":{"result":"timeout"},"warnings":["no md5"]}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+
+	_, err := client.Login(context.Background(), "admin", "admin")
+	require.NoError(t, err)
+
+	test, err := client.TestPeer(context.Background(), 3)
+	require.NoError(t, err)
+	assert.True(t, test.Reachable)
+	assert.False(t, test.Ready)
+	assert.Equal(t, ProbeOpen, test.TCP.Result)
+	assert.Equal(t, 1.2, test.TCP.LatencyMS)
+	require.NotNil(t, test.MD5)
+	assert.Equal(t, ProbeTimeout, test.MD5.Result)
+	assert.Equal(t, []string{"no md5"}, test.Warnings)
+}
+
+func TestProfile(t *testing.T) {
+	client, _ := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method + " " + r.URL.Path {
+		case "POST /api/v1/auth/login":
+			json.NewEncoder(w).Encode(LoginResponse{AccessToken: "access", RefreshToken: "refresh", ExpiresIn: 900})
+		case "PUT /api/v1/me":
+			var req UpdateProfileRequest
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+			json.NewEncoder(w).Encode(Profile{ID: 1, Username: "admin", Email: req.Email})
+		case "GET /api/v1/me/sessions":
+			json.NewEncoder(w).Encode(LoginSessionsResponse{Sessions: []*LoginSession{{ID: 7, ClientIP: "192.0.2.10"}}})
+		case "DELETE /api/v1/me/sessions/7":
+			json.NewEncoder(w).Encode(MessageResponse{Message: "Session revoked"})
+		case "PUT /api/v1/me/preferences":
+			w.Header().Set("Content-Type", "application/json")
+			io.Copy(w, r.Body)
+		case "GET /api/v1/me/notifications":
+			json.NewEncoder(w).Encode(NotificationSettings{Rules: []NotificationRule{{Channels: []string{"email"}}}, Inherited: true})
+		case "PUT /api/v1/me/notifications":
+			w.Header().Set("Content-Type", "application/json")
+			io.Copy(w, r.Body)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+
+	_, err := client.Login(context.Background(), "admin", "admin")
+	require.NoError(t, err)
+
+	profile, err := client.UpdateProfile(context.Background(), "noc@example.com")
+	require.NoError(t, err)
+	assert.Equal(t, "noc@example.com", profile.Email)
+
+	sessions, err := client.ListLoginSessions(context.Background())
+	require.NoError(t, err)
+	require.Len(t, sessions, 1)
+	require.NoError(t, client.RevokeLoginSession(context.Background(), sessions[0].ID))
+
+	require.NoError(t, client.UpdatePreferences(context.Background(), map[string]string{"theme": "dark"}))
+
+	notifications, err := client.GetNotificationSettings(context.Background())
+	require.NoError(t, err)
+	assert.True(t, notifications.Inherited)
+
+	notifications, err = client.UpdateNotificationSettings(context.Background(), &NotificationSettings{
+		Rules:    []NotificationRule{{Severities: []string{"critical"}, Channels: []string{"email"}}},
+		Timezone: "Europe/Berlin",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "Europe/Berlin", notifications.Timezone)
+}
+
+func TestSendTestEmail(t *testing.T) {
+	client, _ := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method + " " + r.URL.Path {
+		case "POST /api/v1/auth/login":
+			json.NewEncoder(w).Encode(LoginResponse{AccessToken: "access", RefreshToken: "refresh", ExpiresIn: 900})
+		case "POST /api/v1/admin/email/test":
+			var req TestEmailRequest
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+			if req.Template != EmailTemplateAlert {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				json.NewEncoder(w).Encode(ErrorResponse{Error: "Email is disabled", Code: CodeEmailUnavailable})
+				return
+			}
+			json.NewEncoder(w).Encode(TestEmail{Template: req.Template, To: req.To, Subject: "FlintRoute critical: test"})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+
+	_, err := client.Login(context.Background(), "admin", "admin")
+	require.NoError(t, err)
+
+	email, err := client.SendTestEmail(context.Background(), EmailTemplateAlert, "noc@example.com")
+	require.NoError(t, err)
+	assert.Equal(t, "noc@example.com", email.To)
+	assert.Equal(t, "FlintRoute critical: test", email.Subject)
+
+	_, err = client.SendTestEmail(context.Background(), EmailTemplateDigest, "")
+	var apiErr *APIError
+	require.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, CodeEmailUnavailable, apiErr.Code)
+}
+
+func TestImpersonate(t *testing.T) {
+	client, _ := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method + " " + r.URL.Path {
+		case "POST /api/v1/auth/login":
+			json.NewEncoder(w).Encode(LoginResponse{AccessToken: "access", RefreshToken: "refresh", ExpiresIn: 900})
+		case "POST /api/v1/admin/impersonate/2":
+			assert.Equal(t, "Bearer access", r.Header.Get("Authorization"))
+			var req ImpersonateRequest
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+			assert.Equal(t, "Ticket 4711", req.Reason)
+			json.NewEncoder(w).Encode(ImpersonateResponse{
+				AccessToken:  "impersonation",
+				ExpiresIn:    600,
+				User:         UserInfo{ID: 2, Username: "bob", Role: "operator"},
+				Impersonator: UserInfo{ID: 1, Username: "admin", Role: "admin"},
+			})
+		case "GET /api/v1/me":
+			assert.Equal(t, "Bearer impersonation", r.Header.Get("Authorization"))
+			json.NewEncoder(w).Encode(Profile{ID: 2, Username: "bob"})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+
+	_, err := client.Login(context.Background(), "admin", "admin")
+	require.NoError(t, err)
+
+	bob, impersonation, err := client.Impersonate(context.Background(), 2, "Ticket 4711")
+	require.NoError(t, err)
+	assert.Equal(t, "admin", impersonation.Impersonator.Username)
+
+	profile, err := bob.GetProfile(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "bob", profile.Username)
+}
+
+func TestUsage(t *testing.T) {
+	client, _ := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method + " " + r.URL.Path {
+		case "POST /api/v1/auth/login":
+			json.NewEncoder(w).Encode(LoginResponse{AccessToken: "access", RefreshToken: "refresh", ExpiresIn: 900})
+		case "GET /api/v1/usage":
+			assert.Equal(t, "6", r.URL.Query().Get("hours"))
+			json.NewEncoder(w).Encode(Usage{
+				UserID:   1,
+				Username: "admin",
+				Quota:    &RequestQuota{RequestsPerHour: 100, Remaining: 58},
+				Total:    UsageStats{Requests: 42, AvgLatencyMS: 3.5},
+			})
+		case "PUT /api/v1/admin/users/2/quota":
+			var req SetRequestQuotaRequest
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+			require.NotNil(t, req.RequestsPerHour)
+			json.NewEncoder(w).Encode(Profile{ID: 2, Username: "noc", RequestQuota: req.RequestsPerHour})
+		case "GET /api/v1/admin/usage":
+			w.Header().Set("Retry-After", "120")
+			w.WriteHeader(http.StatusTooManyRequests)
+			json.NewEncoder(w).Encode(ErrorResponse{Error: "Request quota of 100 per hour exceeded", Code: CodeQuotaExceeded})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+
+	_, err := client.Login(context.Background(), "admin", "admin")
+	require.NoError(t, err)
+
+	usage, err := client.GetUsage(context.Background(), 6)
+	require.NoError(t, err)
+	assert.EqualValues(t, 42, usage.Total.Requests)
+	require.NotNil(t, usage.Quota)
+	assert.Equal(t, 58, usage.Quota.Remaining)
+
+	quota := 500
+	require.NoError(t, client.SetRequestQuota(context.Background(), 2, &quota))
+
+	_, err = client.ListUsage(context.Background(), 0)
+	assert.True(t, HasCode(err, CodeQuotaExceeded))
+}
+
+func TestTenants(t *testing.T) {
+	client, _ := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method + " " + r.URL.Path {
+		case "POST /api/v1/auth/login":
+			json.NewEncoder(w).Encode(LoginResponse{AccessToken: "access", RefreshToken: "refresh", ExpiresIn: 900})
+		case "POST /api/v1/admin/tenants":
+			var req TenantRequest
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+			if req.Name == "taken" {
+				w.WriteHeader(http.StatusConflict)
+				json.NewEncoder(w).Encode(ErrorResponse{Error: "A tenant named taken already exists", Code: CodeTenantExists})
+				return
+			}
+			w.WriteHeader(http.StatusCreated)
+			json.NewEncoder(w).Encode(Tenant{ID: 2, Name: req.Name})
+		case "PUT /api/v1/admin/tenants/2/members/5":
+			var req TenantMemberRequest
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+			json.NewEncoder(w).Encode(TenantMember{ID: 1, TenantID: 2, UserID: 5, Role: req.Role})
+		case "GET /api/v1/bgp/peers":
+			if r.Header.Get("X-Tenant-ID") != "2" {
+				w.WriteHeader(http.StatusBadRequest)
+				json.NewEncoder(w).Encode(ErrorResponse{Error: "Select a tenant", Code: CodeTenantRequired})
+				return
+			}
+			tenantID := uint(2)
+			json.NewEncoder(w).Encode(PeersResponse{Peers: []*Peer{{ID: 1, TenantID: &tenantID}}})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+
+	ctx := context.Background()
+	_, err := client.Login(ctx, "admin", "admin")
+	require.NoError(t, err)
+
+	tenant, err := client.CreateTenant(ctx, &TenantRequest{Name: "customer-a"})
+	require.NoError(t, err)
+	assert.Equal(t, uint(2), tenant.ID)
+	_, err = client.CreateTenant(ctx, &TenantRequest{Name: "taken"})
+	assert.True(t, HasCode(err, CodeTenantExists))
+
+	member, err := client.SetTenantMember(ctx, tenant.ID, 5, "operator")
+	require.NoError(t, err)
+	assert.Equal(t, "operator", member.Role)
+
+	_, err = client.ListPeers(ctx)
+	assert.True(t, HasCode(err, CodeTenantRequired))
+	peers, err := client.ListPeers(WithTenant(ctx, tenant.ID))
+	require.NoError(t, err)
+	require.Len(t, peers, 1)
+	assert.Equal(t, &tenant.ID, peers[0].TenantID)
+}
+
+func TestFRRStatus(t *testing.T) {
+	client, _ := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method + " " + r.URL.Path {
+		case "POST /api/v1/auth/login":
+			json.NewEncoder(w).Encode(LoginResponse{AccessToken: "access", RefreshToken: "refresh", ExpiresIn: 900})
+		case "GET /api/v1/frr/status":
+			w.Write([]byte(`{"version":"9.1-dev","daemons":[{"name":"bfdd","running":false},{"name":"bgpd","running":true}]}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+
+	_, err := client.Login(context.Background(), "admin", "admin")
+	require.NoError(t, err)
+
+	status, err := client.GetFRRStatus(context.Background())
+	require.NoError(t, err)
+	assert.True(t, status.Running("bgpd"))
+	assert.False(t, status.Running("bfdd"))
+	assert.True(t, status.AtLeast("9.1"))
+	assert.True(t, status.AtLeast("8.4.2"))
+	assert.False(t, status.AtLeast("9.2"))
+	assert.False(t, status.AtLeast("10"))
+}
+
+func TestListFRRTransactions(t *testing.T) {
+	client, _ := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method + " " + r.URL.Path {
+		case "POST /api/v1/auth/login":
+			json.NewEncoder(w).Encode(LoginResponse{AccessToken: "access", RefreshToken: "refresh", ExpiresIn: 900})
+		case "GET /api/v1/frr/transactions":
+			assert.Equal(t, "before=42&limit=1&peer_id=3&result=failed", r.URL.RawQuery)
+			w.Write([]byte(`{"transactions":[{"id":41,"operation":"peer.update","target":"192.0.2.1","delta":["+  neighbor 192.0.2.1 description b"],"result":"failed","peer_id":3}],"next_before":41}`))
+		case "GET /api/v1/frr/transactions/9":
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"error":"FRR transaction not found","code":"FRR_TRANSACTION_NOT_FOUND"}`))
+		case "POST /api/v1/frr/transactions/43/backup":
+			w.WriteHeader(http.StatusCreated)
+			json.NewEncoder(w).Encode(ConfigVersion{ID: 5, Labels: []string{"out-of-band"}})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+
+	_, err := client.Login(context.Background(), "admin", "admin")
+	require.NoError(t, err)
+
+	page, err := client.ListFRRTransactions(context.Background(), &FRRTransactionQueryParams{PeerID: 3, Result: "failed", Before: 42, Limit: 1})
+	require.NoError(t, err)
+	require.Len(t, page.Transactions, 1)
+	assert.Equal(t, "peer.update", page.Transactions[0].Operation)
+	assert.Equal(t, []string{"+  neighbor 192.0.2.1 description b"}, page.Transactions[0].Delta)
+	assert.Equal(t, uint(41), page.NextBefore)
+
+	_, err = client.GetFRRTransaction(context.Background(), 9)
+	var apiErr *APIError
+	require.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, CodeFRRTxNotFound, apiErr.Code)
+
+	version, err := client.BackupFRRTransaction(context.Background(), 43)
+	require.NoError(t, err)
+	assert.Equal(t, uint(5), version.ID)
+}
+
+func TestUpdateConfigVersion(t *testing.T) {
+	client, _ := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method + " " + r.URL.Path {
+		case "POST /api/v1/auth/login":
+			json.NewEncoder(w).Encode(LoginResponse{AccessToken: "access", RefreshToken: "refresh", ExpiresIn: 900})
+		case "PATCH /api/v1/config/versions/4":
+			var body map[string]interface{}
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+			// Only the fields being changed are sent
+			assert.Equal(t, map[string]interface{}{"labels": []interface{}{"golden"}, "pinned": false}, body)
+			json.NewEncoder(w).Encode(ConfigVersion{ID: 4, Name: "nightly", Labels: []string{"golden"}})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+
+	_, err := client.Login(context.Background(), "admin", "admin")
+	require.NoError(t, err)
+
+	labels, pinned := []string{"golden"}, false
+	version, err := client.UpdateConfigVersion(context.Background(), 4, &ConfigVersionUpdate{Labels: &labels, Pinned: &pinned})
+	require.NoError(t, err)
+	assert.Equal(t, "nightly", version.Name)
+	assert.Equal(t, []string{"golden"}, version.Labels)
+	assert.False(t, version.Pinned)
+}
+
+func TestPeerTemplates(t *testing.T) {
+	client, _ := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method + " " + r.URL.Path {
+		case "POST /api/v1/auth/login":
+			json.NewEncoder(w).Encode(LoginResponse{AccessToken: "access", RefreshToken: "refresh", ExpiresIn: 900})
+		case "POST /api/v1/bgp/peer-templates":
+			var req PeerTemplateRequest
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+			json.NewEncoder(w).Encode(PeerTemplate{ID: 3, Name: req.Name, Settings: req.Settings, Resolved: &req.Settings})
+		case "POST /api/v1/bgp/peers":
+			var body map[string]interface{}
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+			// Only the settings the caller set are sent, so the rest come
+			// from the template
+			assert.Equal(t, map[string]interface{}{
+				"template_id": float64(3), "name": "acme", "ip_address": "192.0.2.10", "remote_asn": float64(64510),
+			}, body)
+			templateID := uint(3)
+			json.NewEncoder(w).Encode(Peer{ID: 7, Name: "acme", ASN: 65000, RemoteASN: 64510, TemplateID: &templateID, TemplateOverrides: []string{"remote_asn"}})
+		case "POST /api/v1/bgp/peer-templates/3/sync":
+			json.NewEncoder(w).Encode(SyncPeerTemplateResponse{Results: []*BulkPeerResult{{PeerID: 7, IPAddress: "192.0.2.10"}}})
+		case "DELETE /api/v1/bgp/peer-templates/3":
+			w.WriteHeader(http.StatusConflict)
+			json.NewEncoder(w).Encode(ErrorResponse{Error: "peer template is used by peers or other templates", Code: CodeTemplateInUse})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+
+	_, err := client.Login(context.Background(), "admin", "admin")
+	require.NoError(t, err)
+
+	asn := uint32(65000)
+	template, err := client.CreatePeerTemplate(context.Background(), &PeerTemplateRequest{
+		Name:     "customers",
+		Settings: PeerTemplateSettings{ASN: &asn},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, uint(3), template.ID)
+
+	remoteASN := uint32(64510)
+	peer, err := client.CreatePeerFromTemplate(context.Background(), &TemplatePeerRequest{
+		TemplateID:           template.ID,
+		Name:                 "acme",
+		IPAddress:            "192.0.2.10",
+		PeerTemplateSettings: PeerTemplateSettings{RemoteASN: &remoteASN},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"remote_asn"}, peer.TemplateOverrides)
+
+	results, err := client.SyncPeerTemplate(context.Background(), template.ID)
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.Equal(t, uint(7), results[0].PeerID)
+
+	err = client.DeletePeerTemplate(context.Background(), template.ID)
+	assert.True(t, HasCode(err, CodeTemplateInUse))
+}
+
+func TestPeeringDB(t *testing.T) {
+	client, _ := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method + " " + r.URL.Path {
+		case "POST /api/v1/auth/login":
+			json.NewEncoder(w).Encode(LoginResponse{AccessToken: "access", RefreshToken: "refresh", ExpiresIn: 900})
+		case "GET /api/v1/peeringdb/asn/64510":
+			json.NewEncoder(w).Encode(PeeringDBNetwork{
+				ASN: 64510, Name: "Example Networks", MaxPrefixesIPv4: 1200,
+				IXLANs: []PeeringDBIXLAN{{IXID: 31, Name: "DE-CIX Frankfurt", IPv4: "80.81.192.10"}},
+			})
+		case "POST /api/v1/bgp/peers":
+			assert.Equal(t, "true", r.URL.Query().Get("validate_asn"))
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			json.NewEncoder(w).Encode(ErrorResponse{Error: "80.81.192.20 is registered to AS64520 at DE-CIX Frankfurt", Code: CodePeeringDBMismatch})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+
+	_, err := client.Login(context.Background(), "admin", "admin")
+	require.NoError(t, err)
+
+	network, err := client.LookupPeeringDB(context.Background(), 64510)
+	require.NoError(t, err)
+	assert.Equal(t, 1200, network.MaxPrefixesIPv4)
+	require.Len(t, network.IXLANs, 1)
+	assert.Equal(t, "80.81.192.10", network.IXLANs[0].IPv4)
+
+	_, err = client.CreateValidatedPeer(context.Background(), &PeerRequest{
+		Name: "example", IPAddress: "80.81.192.20", ASN: 65000, RemoteASN: 64510,
+	})
+	assert.True(t, HasCode(err, CodePeeringDBMismatch))
+}
+
+func TestScheduledPeerActions(t *testing.T) {
+	runAt := time.Date(2026, 10, 18, 22, 0, 0, 0, time.UTC)
+	client, _ := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method + " " + r.URL.Path {
+		case "POST /api/v1/auth/login":
+			json.NewEncoder(w).Encode(LoginResponse{AccessToken: "access", RefreshToken: "refresh", ExpiresIn: 900})
+		case "POST /api/v1/bgp/peers/7/schedule":
+			var req SchedulePeerActionRequest
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+			assert.Equal(t, PeerActionShutdown, req.Action)
+			assert.True(t, runAt.Equal(req.RunAt))
+			w.WriteHeader(http.StatusCreated)
+			json.NewEncoder(w).Encode(ScheduledPeerAction{Job: Job{ID: 12, Status: JobQueued, RunAt: &req.RunAt}, PeerID: 7, Action: req.Action})
+		case "GET /api/v1/bgp/peers/schedule":
+			json.NewEncoder(w).Encode(ScheduledPeerActionsResponse{Actions: []*ScheduledPeerAction{{Job: Job{ID: 12}, PeerID: 7, Action: PeerActionShutdown}}})
+		case "DELETE /api/v1/bgp/peers/7/schedule/12":
+			w.WriteHeader(http.StatusConflict)
+			json.NewEncoder(w).Encode(ErrorResponse{Error: "Scheduled action is running and can no longer be cancelled", Code: CodeJobNotCancellable})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+
+	_, err := client.Login(context.Background(), "admin", "admin")
+	require.NoError(t, err)
+
+	scheduled, err := client.SchedulePeerAction(context.Background(), 7, PeerActionShutdown, runAt)
+	require.NoError(t, err)
+	assert.Equal(t, uint(12), scheduled.ID)
+	assert.Equal(t, uint(7), scheduled.PeerID)
+
+	actions, err := client.ListScheduledPeerActions(context.Background())
+	require.NoError(t, err)
+	require.Len(t, actions, 1)
+	assert.Equal(t, PeerActionShutdown, actions[0].Action)
+
+	_, err = client.CancelPeerAction(context.Background(), 7, 12)
+	assert.True(t, HasCode(err, CodeJobNotCancellable))
+}
+
+func TestListActivity(t *testing.T) {
+	client, _ := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method + " " + r.URL.Path {
+		case "POST /api/v1/auth/login":
+			json.NewEncoder(w).Encode(LoginResponse{AccessToken: "access", RefreshToken: "refresh", ExpiresIn: 900})
+		case "GET /api/v1/activity":
+			assert.Equal(t, "alert,session", r.URL.Query().Get("types"))
+			if r.URL.Query().Get("cursor") == "" {
+				assert.Equal(t, "1", r.URL.Query().Get("limit"))
+				json.NewEncoder(w).Encode(ActivityPage{Items: []*ActivityItem{{Type: ActivityAlert, ID: 3, Summary: "Peer down"}}, NextCursor: "next"})
+				return
+			}
+			assert.Equal(t, "next", r.URL.Query().Get("cursor"))
+			json.NewEncoder(w).Encode(ActivityPage{Items: []*ActivityItem{{Type: ActivitySession, ID: 9}}})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+
+	_, err := client.Login(context.Background(), "admin", "admin")
+	require.NoError(t, err)
+
+	params := &ActivityQueryParams{Types: []string{ActivityAlert, ActivitySession}, Limit: 1}
+	page, err := client.ListActivity(context.Background(), params)
+	require.NoError(t, err)
+	require.Len(t, page.Items, 1)
+	assert.Equal(t, "Peer down", page.Items[0].Summary)
+
+	params.Cursor = page.NextCursor
+	params.Limit = 0
+	page, err = client.ListActivity(context.Background(), params)
+	require.NoError(t, err)
+	require.Len(t, page.Items, 1)
+	assert.Equal(t, ActivitySession, page.Items[0].Type)
+	assert.Empty(t, page.NextCursor)
+}
+
+func TestAlertPages(t *testing.T) {
+	client, _ := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method + " " + r.URL.Path {
+		case "POST /api/v1/auth/login":
+			json.NewEncoder(w).Encode(LoginResponse{AccessToken: "access", RefreshToken: "refresh", ExpiresIn: 900})
+		case "GET /api/v1/alerts":
+			assert.Equal(t, "critical", r.URL.Query().Get("severity"))
+			assert.Equal(t, "100", r.URL.Query().Get("limit"))
+			switch r.URL.Query().Get("cursor") {
+			case "":
+				json.NewEncoder(w).Encode(AlertsResponse{Alerts: []*Alert{{ID: 3}, {ID: 2}}, NextCursor: "next"})
+			case "next":
+				json.NewEncoder(w).Encode(AlertsResponse{Alerts: []*Alert{{ID: 1}}})
+			default:
+				w.WriteHeader(http.StatusBadRequest)
+				json.NewEncoder(w).Encode(ErrorResponse{Error: "Invalid cursor", Code: "VALIDATION_FAILED"})
+			}
+		case "GET /api/v1/admin/audit":
+			w.WriteHeader(http.StatusForbidden)
+			json.NewEncoder(w).Encode(ErrorResponse{Error: "Forbidden", Code: "FORBIDDEN"})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+
+	_, err := client.Login(context.Background(), "admin", "admin")
+	require.NoError(t, err)
+	params := &AlertQueryParams{Severity: "critical"}
+
+	t.Run("Iterates over every page", func(t *testing.T) {
+		var ids []uint
+		for alert, err := range client.Alerts(context.Background(), params) {
+			require.NoError(t, err)
+			ids = append(ids, alert.ID)
+		}
+		assert.Equal(t, []uint{3, 2, 1}, ids)
+	})
+
+	t.Run("Stops when the caller does", func(t *testing.T) {
+		var ids []uint
+		for alert, err := range client.Alerts(context.Background(), params) {
+			require.NoError(t, err)
+			ids = append(ids, alert.ID)
+			break
+		}
+		assert.Equal(t, []uint{3}, ids)
+	})
+
+	t.Run("Yields the error of a page", func(t *testing.T) {
+		_, err := client.ListAlertPage(context.Background(), params, PageParams{Cursor: "stale"})
+		assert.True(t, IsBadRequest(err))
+
+		var errs []error
+		for entry, err := range client.AuditEntries(context.Background()) {
+			assert.Nil(t, entry)
+			errs = append(errs, err)
+		}
+		require.Len(t, errs, 1)
+		assert.True(t, IsForbidden(errs[0]))
+	})
+}
+
+func TestSearchPeers(t *testing.T) {
+	client, _ := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method + " " + r.URL.Path {
+		case "POST /api/v1/auth/login":
+			json.NewEncoder(w).Encode(LoginResponse{AccessToken: "access", RefreshToken: "refresh", ExpiresIn: 900})
+		case "GET /api/v1/bgp/peers/search":
+			assert.Equal(t, `asn:65010 name~"transit"`, r.URL.Query().Get("q"))
+			assert.Equal(t, "25", r.URL.Query().Get("limit"))
+			assert.Equal(t, "50", r.URL.Query().Get("offset"))
+			json.NewEncoder(w).Encode(PeerSearchResult{
+				Query:  r.URL.Query().Get("q"),
+				Total:  51,
+				Peers:  []*Peer{{ID: 7, Name: "Transit AMS"}},
+				Facets: PeerFacets{State: []FacetCount{{Value: "Established", Count: 51}}},
+			})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+
+	_, err := client.Login(context.Background(), "admin", "admin")
+	require.NoError(t, err)
+
+	result, err := client.SearchPeers(context.Background(), &PeerSearchParams{Query: `asn:65010 name~"transit"`, Limit: 25, Offset: 50})
+	require.NoError(t, err)
+	assert.Equal(t, int64(51), result.Total)
+	require.Len(t, result.Peers, 1)
+	assert.Equal(t, "Transit AMS", result.Peers[0].Name)
+	assert.Equal(t, []FacetCount{{Value: "Established", Count: 51}}, result.Facets.State)
+}
+
+func TestReachability(t *testing.T) {
+	client, _ := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method + " " + r.URL.Path {
+		case "POST /api/v1/auth/login":
+			json.NewEncoder(w).Encode(LoginResponse{AccessToken: "access", RefreshToken: "refresh", ExpiresIn: 900})
+		case "POST /api/v1/bgp/reachability/probe":
+			w.WriteHeader(http.StatusAccepted)
+			json.NewEncoder(w).Encode(Job{ID: 1, Type: "bgp.probe_reachability", Status: JobQueued})
+		case "GET /api/v1/jobs/1":
+			json.NewEncoder(w).Encode(Job{ID: 1, Status: JobSucceeded, Progress: 100,
+				Result: json.RawMessage(`{"method":"tcp","unreachable":1,"peers":[{"peer_id":7,"diagnosis":"ip_unreachable"}]}`)})
+		case "GET /api/v1/bgp/peers/7/reachability":
+			assert.Equal(t, "6h", r.URL.Query().Get("window"))
+			json.NewEncoder(w).Encode(PeerReachabilityHistory{PeerID: 7, Window: "6h0m0s",
+				Samples: []*ReachabilitySample{{PeerID: 7, Sent: 3, Received: 0, LossPercent: 100, Error: "timeout"}}})
+		case "GET /api/v1/bgp/peers/7/state-timeline":
+			assert.Equal(t, "7d", r.URL.Query().Get("range"))
+			assert.Equal(t, "1d", r.URL.Query().Get("bucket"))
+			json.NewEncoder(w).Encode(StateTimeline{PeerID: 7, Bucket: 86400,
+				Buckets: []*StateBucket{{State: "Idle", States: map[string]int64{"Idle": 86400}}}})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+
+	_, err := client.Login(context.Background(), "admin", "admin")
+	require.NoError(t, err)
+
+	report, err := client.ProbeReachability(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, report.Unreachable)
+	require.Len(t, report.Peers, 1)
+	assert.Equal(t, "ip_unreachable", report.Peers[0].Diagnosis)
+
+	history, err := client.GetPeerReachability(context.Background(), 7, "6h")
+	require.NoError(t, err)
+	require.Len(t, history.Samples, 1)
+	assert.Equal(t, "timeout", history.Samples[0].Error)
+
+	timeline, err := client.GetPeerStateTimeline(context.Background(), 7, "7d", "1d")
+	require.NoError(t, err)
+	require.Len(t, timeline.Buckets, 1)
+	assert.Equal(t, int64(86400), timeline.Buckets[0].States["Idle"])
+
+	_, err = client.GetReachability(context.Background())
+	assert.Error(t, err)
+}
+
+func TestAdoptNeighbors(t *testing.T) {
+	client, _ := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method + " " + r.URL.Path {
+		case "POST /api/v1/auth/login":
+			json.NewEncoder(w).Encode(LoginResponse{AccessToken: "access", RefreshToken: "refresh", ExpiresIn: 900})
+		case "POST /api/v1/bgp/peers/adopt":
+			var req struct {
+				Neighbors []string `json:"neighbors"`
+			}
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+			assert.Equal(t, []string{"192.0.2.7"}, req.Neighbors)
+			json.NewEncoder(w).Encode(AdoptReport{
+				Adopted:    []*Peer{{ID: 9, IPAddress: "192.0.2.7"}},
+				Candidates: []*AdoptableNeighbor{{IPAddress: "192.0.2.8", Reason: "neighbor has no remote-as with a fixed ASN"}},
+			})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+
+	_, err := client.Login(context.Background(), "admin", "admin")
+	require.NoError(t, err)
+
+	report, err := client.AdoptNeighbors(context.Background(), "192.0.2.7")
+	require.NoError(t, err)
+	require.Len(t, report.Adopted, 1)
+	assert.Equal(t, uint(9), report.Adopted[0].ID)
+	require.Len(t, report.Candidates, 1)
+	assert.NotEmpty(t, report.Candidates[0].Reason)
+}
+
+func TestState(t *testing.T) {
+	client, _ := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method + " " + r.URL.Path {
+		case "POST /api/v1/auth/login":
+			json.NewEncoder(w).Encode(LoginResponse{AccessToken: "access", RefreshToken: "refresh", ExpiresIn: 900})
+		case "GET /api/v1/state":
+			w.Header().Set("Content-Type", "application/yaml")
+			w.Write([]byte("version: 1\n"))
+		case "POST /api/v1/state":
+			var doc map[string]interface{}
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&doc))
+			assert.Equal(t, float64(1), doc["version"], "YAML documents are sent as JSON")
+			if r.URL.Query().Get("prune") == "true" {
+				w.WriteHeader(http.StatusConflict)
+				json.NewEncoder(w).Encode(map[string]string{"code": "STATE_CONFLICT", "error": "state conflicts with the instance"})
+				return
+			}
+			json.NewEncoder(w).Encode(StateImportResult{
+				DryRun:  r.URL.Query().Get("dry_run") == "true",
+				Changes: []*StateChange{{Action: "create", Kind: "peer", Name: "192.0.2.1"}},
+			})
+		}
+	})
+
+	_, err := client.Login(context.Background(), "admin", "admin")
+	require.NoError(t, err)
+
+	doc, err := client.ExportState(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "version: 1\n", string(doc))
+
+	result, err := client.ImportState(context.Background(), doc, true, false)
+	require.NoError(t, err)
+	assert.True(t, result.DryRun)
+	require.Len(t, result.Changes, 1)
+	assert.Equal(t, "peer", result.Changes[0].Kind)
+
+	_, err = client.ImportState(context.Background(), doc, false, true)
+	var apiErr *APIError
+	require.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, CodeStateConflict, apiErr.Code)
+}
@@ -0,0 +1,199 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// ErrorCode is a machine-readable error identifier returned by the API
+type ErrorCode string
+
+// Error codes returned by the API
+const (
+	CodeValidationFailed   ErrorCode = "VALIDATION_FAILED"
+	CodeInvalidID          ErrorCode = "INVALID_ID"
+	CodeUnauthorized       ErrorCode = "UNAUTHORIZED"
+	CodeInvalidCredentials ErrorCode = "INVALID_CREDENTIALS"
+	CodeAccountDisabled    ErrorCode = "ACCOUNT_DISABLED"
+	CodeInvalidToken       ErrorCode = "INVALID_TOKEN"
+	CodeForbidden          ErrorCode = "FORBIDDEN"
+	CodeNotFound           ErrorCode = "NOT_FOUND"
+	CodePeerNotFound       ErrorCode = "PEER_NOT_FOUND"
+	CodePeerExists         ErrorCode = "PEER_EXISTS"
+	CodePeerNotDeleted     ErrorCode = "PEER_NOT_DELETED"
+	CodeSessionNotFound    ErrorCode = "SESSION_NOT_FOUND"
+	CodeVersionNotFound    ErrorCode = "VERSION_NOT_FOUND"
+	CodeSnapshotNotFound   ErrorCode = "SNAPSHOT_NOT_FOUND"
+	CodeInvalidSnapshot    ErrorCode = "INVALID_SNAPSHOT"
+	CodeUnsupportedDriver  ErrorCode = "UNSUPPORTED_DRIVER"
+	CodeAlertNotFound      ErrorCode = "ALERT_NOT_FOUND"
+	CodeWebhookNotFound    ErrorCode = "WEBHOOK_NOT_FOUND"
+	CodeDeliveryNotFound   ErrorCode = "DELIVERY_NOT_FOUND"
+	CodePolicyNotFound     ErrorCode = "POLICY_NOT_FOUND"
+	CodePolicyExists       ErrorCode = "POLICY_EXISTS"
+	CodePolicyInUse        ErrorCode = "POLICY_IN_USE"
+	CodeTemplateNotFound   ErrorCode = "TEMPLATE_NOT_FOUND"
+	CodeTemplateExists     ErrorCode = "TEMPLATE_EXISTS"
+	CodeTemplateInUse      ErrorCode = "TEMPLATE_IN_USE"
+	CodeASNMismatch        ErrorCode = "ASN_MISMATCH"
+	CodeJobNotFound        ErrorCode = "JOB_NOT_FOUND"
+	CodeJobNotCancellable  ErrorCode = "JOB_NOT_CANCELLABLE"
+	CodeFRRTxNotFound      ErrorCode = "FRR_TRANSACTION_NOT_FOUND"
+	CodeChangeNotFound     ErrorCode = "CHANGE_NOT_FOUND"
+	CodeChangeNotPending   ErrorCode = "CHANGE_NOT_PENDING"
+	CodeSelfReview         ErrorCode = "SELF_REVIEW"
+	CodeIdempotencyReused  ErrorCode = "IDEMPOTENCY_KEY_REUSED"
+	CodeIdempotencyInUse   ErrorCode = "IDEMPOTENCY_KEY_IN_USE"
+	CodeFRRUnavailable     ErrorCode = "FRR_UNAVAILABLE"
+	CodeFRRApplyFailed     ErrorCode = "FRR_APPLY_FAILED"
+	CodeFRRConfigRejected  ErrorCode = "FRR_CONFIG_REJECTED"
+	CodeFRRValidation      ErrorCode = "FRR_VALIDATION"
+	CodeConfigChanged      ErrorCode = "CONFIG_CHANGED"
+	CodeInvalidSignature   ErrorCode = "INVALID_SIGNATURE"
+	CodeGitOpsFetchFailed  ErrorCode = "GITOPS_FETCH_FAILED"
+	CodeGitOpsInvalid      ErrorCode = "GITOPS_INVALID_DEFINITIONS"
+	CodeGitOpsConflict     ErrorCode = "GITOPS_CONFLICT"
+	CodeInvalidState       ErrorCode = "INVALID_STATE"
+	CodeStateConflict      ErrorCode = "STATE_CONFLICT"
+	CodeEmailInUse         ErrorCode = "EMAIL_IN_USE"
+	CodeStarting           ErrorCode = "STARTING"
+	CodeReadOnly           ErrorCode = "READ_ONLY"
+	CodeNotLeader          ErrorCode = "NOT_LEADER"
+	CodeQuotaExceeded      ErrorCode = "QUOTA_EXCEEDED"
+	CodeASNNotFound        ErrorCode = "ASN_NOT_FOUND"
+	CodePeeringDBMismatch  ErrorCode = "PEERINGDB_MISMATCH"
+	CodePeeringDBDown      ErrorCode = "PEERINGDB_UNAVAILABLE"
+	CodeStoreUnavailable   ErrorCode = "STORE_UNAVAILABLE"
+	CodeTenantNotFound     ErrorCode = "TENANT_NOT_FOUND"
+	CodeTenantExists       ErrorCode = "TENANT_EXISTS"
+	CodeTenantInUse        ErrorCode = "TENANT_IN_USE"
+	CodeTenantRequired     ErrorCode = "TENANT_REQUIRED"
+	CodeNotTenantMember    ErrorCode = "NOT_TENANT_MEMBER"
+	CodeEmailUnavailable   ErrorCode = "EMAIL_UNAVAILABLE"
+	CodeDemoDisabled       ErrorCode = "DEMO_MODE_DISABLED"
+	CodeInternal           ErrorCode = "INTERNAL_ERROR"
+)
+
+// ErrJobFailed is returned when a background job finishes unsuccessfully
+var ErrJobFailed = errors.New("job failed")
+
+// ErrJobCancelled is returned when a background job is cancelled before it
+// runs
+var ErrJobCancelled = errors.New("job cancelled")
+
+// ErrPendingApproval is returned when the server holds a change for approval
+// by a second admin instead of applying it
+var ErrPendingApproval = errors.New("change is pending approval")
+
+// PendingApprovalError carries the change request a change is held as. It
+// wraps ErrPendingApproval.
+type PendingApprovalError struct {
+	Change *ChangeRequest
+}
+
+// Error implements the error interface
+func (e *PendingApprovalError) Error() string {
+	return fmt.Sprintf("%s: change request %d", ErrPendingApproval, e.Change.ID)
+}
+
+// Unwrap returns ErrPendingApproval
+func (e *PendingApprovalError) Unwrap() error {
+	return ErrPendingApproval
+}
+
+// APIError represents a non-successful response from the FlintRoute API
+type APIError struct {
+	StatusCode int
+	Code       ErrorCode
+	Message    string
+	Details    []FieldError
+	RequestID  string
+	Body       string
+	// RetryAfter is how long the server asked to wait before trying again,
+	// from the Retry-After header of 429 and 503 responses
+	RetryAfter time.Duration
+	// FRR locates the configuration FRR rejected, for FRR_VALIDATION and
+	// FRR_CONFIG_REJECTED responses
+	FRR *FRRDetail
+}
+
+// Error implements the error interface
+func (e *APIError) Error() string {
+	if e.Code != "" {
+		return fmt.Sprintf("HTTP %d %s: %s", e.StatusCode, e.Code, e.Message)
+	}
+	return fmt.Sprintf("HTTP %d: %s", e.StatusCode, e.Message)
+}
+
+// newAPIError builds an APIError from a response status and body
+func newAPIError(statusCode int, body []byte) *APIError {
+	apiErr := &APIError{
+		StatusCode: statusCode,
+		Body:       string(body),
+	}
+
+	var errResp ErrorResponse
+	if err := json.Unmarshal(body, &errResp); err != nil || errResp.Error == "" {
+		apiErr.Message = string(body)
+	} else {
+		apiErr.Message = errResp.Error
+		apiErr.Code = errResp.Code
+		apiErr.Details = errResp.Errors
+		if apiErr.Details == nil {
+			apiErr.Details = errResp.Details
+		}
+		apiErr.RequestID = errResp.RequestID
+		apiErr.FRR = errResp.FRR
+	}
+
+	return apiErr
+}
+
+// AsAPIError extracts an APIError from err, if present
+func AsAPIError(err error) (*APIError, bool) {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr, true
+	}
+	return nil, false
+}
+
+// HasCode reports whether err is an APIError with the given error code
+func HasCode(err error, code ErrorCode) bool {
+	apiErr, ok := AsAPIError(err)
+	return ok && apiErr.Code == code
+}
+
+// IsNotFound reports whether err is an API 404 response
+func IsNotFound(err error) bool {
+	return hasStatus(err, http.StatusNotFound)
+}
+
+// IsUnauthorized reports whether err is an API 401 response
+func IsUnauthorized(err error) bool {
+	return hasStatus(err, http.StatusUnauthorized)
+}
+
+// IsForbidden reports whether err is an API 403 response
+func IsForbidden(err error) bool {
+	return hasStatus(err, http.StatusForbidden)
+}
+
+// IsBadRequest reports whether err is an API 400 response
+func IsBadRequest(err error) bool {
+	return hasStatus(err, http.StatusBadRequest)
+}
+
+// IsRateLimited reports whether err is an API 429 response
+func IsRateLimited(err error) bool {
+	return hasStatus(err, http.StatusTooManyRequests)
+}
+
+// hasStatus reports whether err is an APIError with the given status code
+func hasStatus(err error, statusCode int) bool {
+	apiErr, ok := AsAPIError(err)
+	return ok && apiErr.StatusCode == statusCode
+}
@@ -0,0 +1,313 @@
+package client
+
+import (
+	"context"
+	cryptorand "crypto/rand"
+	"encoding/hex"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+)
+
+// Option configures an APIClient
+type Option func(*APIClient)
+
+// WithHTTPClient sets the underlying HTTP client
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *APIClient) {
+		if httpClient != nil {
+			c.httpClient = httpClient
+		}
+	}
+}
+
+// WithTimeout sets the HTTP client timeout
+func WithTimeout(timeout time.Duration) Option {
+	return func(c *APIClient) {
+		c.httpClient.Timeout = timeout
+	}
+}
+
+// WithRequestTimeout bounds every request attempt, independently of the
+// deadline carried by the caller's context
+func WithRequestTimeout(timeout time.Duration) Option {
+	return func(c *APIClient) {
+		c.requestTimeout = timeout
+	}
+}
+
+// WithRetryPolicy sets the retry policy used for idempotent requests
+func WithRetryPolicy(policy RetryPolicy) Option {
+	return func(c *APIClient) {
+		c.retryPolicy = policy
+	}
+}
+
+// WithAutoIdempotencyKeys makes the client send a random Idempotency-Key
+// with every authenticated POST made without one from WithIdempotencyKey.
+// The key is kept across the retries of a call, so POSTs are retried under
+// the retry policy without the server repeating the change.
+func WithAutoIdempotencyKeys() Option {
+	return func(c *APIClient) {
+		c.autoIdempotencyKeys = true
+	}
+}
+
+// WithCircuitBreaker stops the client hammering a degraded server. After
+// threshold consecutive failed attempts, transport errors or 5xx responses,
+// calls fail at once with ErrCircuitOpen for cooldown. A single call is
+// then let through: the circuit closes again if it succeeds and stays open
+// for another cooldown if it fails. A threshold of 0 or less disables it.
+func WithCircuitBreaker(threshold int, cooldown time.Duration) Option {
+	return func(c *APIClient) {
+		c.breaker = nil
+		if threshold > 0 {
+			c.breaker = newCircuitBreaker(threshold, cooldown)
+		}
+	}
+}
+
+// WithMiddleware wraps each HTTP attempt in the given middleware, the first
+// outermost, for logging, metrics, tracing or custom headers. Middleware
+// sees every retry attempt, with the headers and authentication already set.
+func WithMiddleware(middleware ...Middleware) Option {
+	return func(c *APIClient) {
+		c.middleware = append(c.middleware, middleware...)
+	}
+}
+
+// RoundTripFunc performs a single HTTP request
+type RoundTripFunc func(*http.Request) (*http.Response, error)
+
+// Middleware wraps the round trip of requests made by the client. It calls
+// next to carry on with the request, or returns without calling it to
+// answer the request itself.
+type Middleware func(next RoundTripFunc) RoundTripFunc
+
+// RetryPolicy controls how failed requests are retried. Idempotent
+// requests, and POSTs with an idempotency key, are retried on transport
+// errors and the RetryStatuses. 429 responses are retried whatever the
+// method, as the server turned the request away without running it.
+type RetryPolicy struct {
+	MaxAttempts    int
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	// RetryStatuses are the response statuses retried; nil retries 429,
+	// 502, 503 and 504
+	RetryStatuses []int
+	// Jitter shortens each backoff by a random fraction of it, up to
+	// Jitter (0 to 1), so clients that failed together don't retry in
+	// lockstep
+	Jitter float64
+	// MaxRetryAfter is the longest Retry-After waited for before a retry.
+	// Responses asking to wait longer are returned to the caller at once.
+	// 0 uses MaxBackoff.
+	MaxRetryAfter time.Duration
+}
+
+// DefaultRetryPolicy returns the policy used when none is configured
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts:    1,
+		InitialBackoff: 200 * time.Millisecond,
+		MaxBackoff:     2 * time.Second,
+		Jitter:         0.2,
+		MaxRetryAfter:  30 * time.Second,
+	}
+}
+
+// backoff returns the delay before the given retry (1-based)
+func (p RetryPolicy) backoff(retry int) time.Duration {
+	delay := p.InitialBackoff
+	for i := 1; i < retry; i++ {
+		delay *= 2
+		if p.MaxBackoff > 0 && delay >= p.MaxBackoff {
+			return p.MaxBackoff
+		}
+	}
+	return delay
+}
+
+// delay returns the backoff of the given retry with jitter applied
+func (p RetryPolicy) delay(retry int) time.Duration {
+	delay := p.backoff(retry)
+	if p.Jitter > 0 && delay > 0 {
+		delay -= time.Duration(rand.Float64() * min(p.Jitter, 1) * float64(delay))
+	}
+	return delay
+}
+
+// retryDelay returns how long to wait before retrying after resp: the
+// backoff of the given retry, or the response's Retry-After when that is
+// longer. It reports false when Retry-After asks for longer than the policy
+// waits.
+func (p RetryPolicy) retryDelay(retry int, resp *http.Response) (time.Duration, bool) {
+	delay := p.delay(retry)
+	if resp == nil {
+		return delay, true
+	}
+	retryAfter, ok := parseRetryAfter(resp.Header.Get("Retry-After"))
+	if !ok {
+		return delay, true
+	}
+	limit := p.MaxRetryAfter
+	if limit <= 0 {
+		limit = p.MaxBackoff
+	}
+	if retryAfter > limit {
+		return 0, false
+	}
+	return max(delay, retryAfter), true
+}
+
+// wait blocks for the backoff of the given retry or until ctx is done
+func (p RetryPolicy) wait(ctx context.Context, retry int) error {
+	return sleep(ctx, p.delay(retry))
+}
+
+// retryable reports whether a response status is worth retrying
+func (p RetryPolicy) retryable(status int) bool {
+	if p.RetryStatuses == nil {
+		switch status {
+		case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+			return true
+		}
+		return false
+	}
+	return slices.Contains(p.RetryStatuses, status)
+}
+
+// shouldRetry reports whether a request outcome is worth retrying. Requests
+// that aren't safe to repeat are only retried after a 429.
+func (p RetryPolicy) shouldRetry(resp *http.Response, err error, repeatable bool) bool {
+	if err != nil {
+		return repeatable
+	}
+	if !p.retryable(resp.StatusCode) {
+		return false
+	}
+	return repeatable || resp.StatusCode == http.StatusTooManyRequests
+}
+
+// sleep blocks for d or until ctx is done
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// parseRetryAfter parses a Retry-After header, in seconds or as an HTTP
+// date
+func parseRetryAfter(value string) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return time.Duration(max(seconds, 0)) * time.Second, true
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		return max(time.Until(at), 0), true
+	}
+	return 0, false
+}
+
+// isIdempotent reports whether requests with this method are safe to retry
+func isIdempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPut, http.MethodDelete, http.MethodOptions:
+		return true
+	}
+	return false
+}
+
+// newIdempotencyKey returns a random idempotency key
+func newIdempotencyKey() string {
+	key := make([]byte, 16)
+	if _, err := cryptorand.Read(key); err != nil {
+		// crypto/rand doesn't fail on supported platforms
+		panic(err)
+	}
+	return hex.EncodeToString(key)
+}
+
+type callTimeoutKey struct{}
+
+type requestIDKey struct{}
+
+type idempotencyKeyKey struct{}
+
+type tenantKey struct{}
+
+// WithRequestID returns a context that sends the given X-Request-ID with
+// calls made with it, so they can be correlated with server-side logs
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// requestID returns the request ID stored in ctx, if any
+func requestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// WithIdempotencyKey returns a context that sends the given Idempotency-Key
+// with POSTs made with it. The server replays the response to the first
+// request with the key instead of repeating it, so such POSTs are retried
+// under the retry policy like idempotent requests. Use a new key for each
+// distinct operation.
+func WithIdempotencyKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, idempotencyKeyKey{}, key)
+}
+
+// idempotencyKey returns the idempotency key stored in ctx, if any
+func idempotencyKey(ctx context.Context) string {
+	key, _ := ctx.Value(idempotencyKeyKey{}).(string)
+	return key
+}
+
+// WithTenant returns a context that sends the given X-Tenant-ID with calls
+// made with it, acting for that tenant. Members of a single tenant don't
+// need it; admins without it see every tenant.
+func WithTenant(ctx context.Context, id uint) context.Context {
+	return context.WithValue(ctx, tenantKey{}, id)
+}
+
+// tenant returns the tenant stored in ctx, if any
+func tenant(ctx context.Context) (uint, bool) {
+	id, ok := ctx.Value(tenantKey{}).(uint)
+	return id, ok
+}
+
+// WithCallTimeout returns a context that overrides the client's request
+// timeout for calls made with it. Each retry attempt gets the full timeout.
+func WithCallTimeout(ctx context.Context, timeout time.Duration) context.Context {
+	return context.WithValue(ctx, callTimeoutKey{}, timeout)
+}
+
+// callTimeout returns the per-call timeout override stored in ctx, if any
+func callTimeout(ctx context.Context) (time.Duration, bool) {
+	timeout, ok := ctx.Value(callTimeoutKey{}).(time.Duration)
+	return timeout, ok
+}
+
+// cancelOnClose releases a request context once the response body is closed
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+// Close closes the body and cancels the request context
+func (b *cancelOnClose) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
@@ -0,0 +1,71 @@
+package client
+
+import (
+	"context"
+	"iter"
+	"net/url"
+	"strconv"
+)
+
+// defaultPageLimit is the server's default number of items per page
+const defaultPageLimit = 100
+
+// Alerts iterates over the alerts matching params, newest first, fetching
+// them a page at a time. Iteration stops after yielding an error.
+func (c *APIClient) Alerts(ctx context.Context, params *AlertQueryParams) iter.Seq2[*Alert, error] {
+	return paginate(func(cursor string) ([]*Alert, string, error) {
+		page, err := c.ListAlertPage(ctx, params, PageParams{Cursor: cursor})
+		if err != nil {
+			return nil, "", err
+		}
+		return page.Alerts, page.NextCursor, nil
+	})
+}
+
+// AuditEntries iterates over the audit log, newest first, fetching it a
+// page at a time. Iteration stops after yielding an error.
+func (c *APIClient) AuditEntries(ctx context.Context) iter.Seq2[*AuditEntry, error] {
+	return paginate(func(cursor string) ([]*AuditEntry, string, error) {
+		page, err := c.ListAuditEntryPage(ctx, PageParams{Cursor: cursor})
+		if err != nil {
+			return nil, "", err
+		}
+		return page.Entries, page.NextCursor, nil
+	})
+}
+
+// paginate iterates over the items of the pages fetch returns, following
+// each page's cursor to the next until the last page
+func paginate[T any](fetch func(cursor string) (items []T, next string, err error)) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		cursor := ""
+		for {
+			items, next, err := fetch(cursor)
+			if err != nil {
+				var zero T
+				yield(zero, err)
+				return
+			}
+			for _, item := range items {
+				if !yield(item, nil) {
+					return
+				}
+			}
+			if next == "" {
+				return
+			}
+			cursor = next
+		}
+	}
+}
+
+// pageQuery adds the parameters selecting page to query
+func pageQuery(query url.Values, page PageParams) url.Values {
+	if page.Cursor != "" {
+		query.Set("cursor", page.Cursor)
+	}
+	if page.Limit > 0 {
+		query.Set("limit", strconv.Itoa(page.Limit))
+	}
+	return query
+}
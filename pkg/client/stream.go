@@ -0,0 +1,416 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"go.uber.org/zap"
+)
+
+// Message types pushed over the live update stream
+const (
+	MessageSessionUpdate = "session_update"
+	MessageAlert         = "alert"
+	MessagePeerUpdate    = "peer_update"
+)
+
+// ErrSubscriptionClosed is returned when waiting on a closed subscription
+var ErrSubscriptionClosed = errors.New("subscription closed")
+
+// errStreamDial marks connection failures worth reconnecting after
+var errStreamDial = errors.New("websocket dial failed")
+
+// StreamMessage is a live update received over the WebSocket. ID is its
+// sequence number.
+type StreamMessage struct {
+	ID        uint64          `json:"id"`
+	Type      string          `json:"type"`
+	Time      time.Time       `json:"time"`
+	Payload   json.RawMessage `json:"payload"`
+	RequestID string          `json:"request_id,omitempty"`
+}
+
+// Session decodes a session_update payload
+func (m *StreamMessage) Session() (*Session, error) {
+	var session Session
+	if err := m.decode(MessageSessionUpdate, &session); err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// Alert decodes an alert payload
+func (m *StreamMessage) Alert() (*Alert, error) {
+	var alert Alert
+	if err := m.decode(MessageAlert, &alert); err != nil {
+		return nil, err
+	}
+	return &alert, nil
+}
+
+// Peer decodes a peer_update payload
+func (m *StreamMessage) Peer() (*Peer, error) {
+	var peer Peer
+	if err := m.decode(MessagePeerUpdate, &peer); err != nil {
+		return nil, err
+	}
+	return &peer, nil
+}
+
+func (m *StreamMessage) decode(msgType string, target interface{}) error {
+	if m.Type != msgType {
+		return fmt.Errorf("message is %q, not %q", m.Type, msgType)
+	}
+	if err := json.Unmarshal(m.Payload, target); err != nil {
+		return fmt.Errorf("failed to parse %s payload: %w", msgType, err)
+	}
+	return nil
+}
+
+// Subscription is a live update stream. It reconnects with backoff when the
+// connection drops, refreshing the access token as needed, and resumes from
+// the last message received so the server replays those broadcast while
+// disconnected.
+type Subscription struct {
+	client   *APIClient
+	topics   []string
+	messages chan *StreamMessage
+	cancel   context.CancelFunc
+	done     chan struct{}
+
+	mu      sync.Mutex
+	conn    *websocket.Conn
+	err     error
+	lastSeq uint64
+}
+
+// Subscribe opens a WebSocket to /api/v1/ws receiving the given message
+// types, or all types when none are given. The first connection is made
+// before Subscribe returns so bad credentials or topics fail fast. The
+// subscription ends when ctx is cancelled or Close is called.
+func (c *APIClient) Subscribe(ctx context.Context, topics ...string) (*Subscription, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	sub := &Subscription{
+		client:   c,
+		topics:   topics,
+		messages: make(chan *StreamMessage, 64),
+		cancel:   cancel,
+		done:     make(chan struct{}),
+	}
+
+	conn, err := sub.dial(ctx)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	go sub.run(ctx, conn)
+	return sub, nil
+}
+
+// Messages returns the channel of received messages. It is closed when the
+// subscription ends; Err then reports why.
+func (s *Subscription) Messages() <-chan *StreamMessage {
+	return s.messages
+}
+
+// Err returns the error that ended the subscription, if any
+func (s *Subscription) Err() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.err
+}
+
+// Close ends the subscription and waits for it to shut down
+func (s *Subscription) Close() error {
+	s.cancel()
+	s.mu.Lock()
+	if s.conn != nil {
+		s.conn.Close()
+	}
+	s.mu.Unlock()
+	<-s.done
+	return nil
+}
+
+// WaitFor returns the next message for which match returns true, discarding
+// others, or an error once ctx is done or the subscription ends
+func (s *Subscription) WaitFor(ctx context.Context, match func(*StreamMessage) bool) (*StreamMessage, error) {
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case msg, ok := <-s.messages:
+			if !ok {
+				if err := s.Err(); err != nil {
+					return nil, err
+				}
+				return nil, ErrSubscriptionClosed
+			}
+			if match(msg) {
+				return msg, nil
+			}
+		}
+	}
+}
+
+// WaitForPeer returns the next peer_update for which match returns true
+func (s *Subscription) WaitForPeer(ctx context.Context, match func(*Peer) bool) (*Peer, error) {
+	var peer *Peer
+	_, err := s.WaitFor(ctx, func(msg *StreamMessage) bool {
+		p, err := msg.Peer()
+		if err != nil || !match(p) {
+			return false
+		}
+		peer = p
+		return true
+	})
+	return peer, err
+}
+
+// WaitForSession returns the next session_update for which match returns true
+func (s *Subscription) WaitForSession(ctx context.Context, match func(*Session) bool) (*Session, error) {
+	var session *Session
+	_, err := s.WaitFor(ctx, func(msg *StreamMessage) bool {
+		sess, err := msg.Session()
+		if err != nil || !match(sess) {
+			return false
+		}
+		session = sess
+		return true
+	})
+	return session, err
+}
+
+// WaitForAlert returns the next alert for which match returns true
+func (s *Subscription) WaitForAlert(ctx context.Context, match func(*Alert) bool) (*Alert, error) {
+	var alert *Alert
+	_, err := s.WaitFor(ctx, func(msg *StreamMessage) bool {
+		a, err := msg.Alert()
+		if err != nil || !match(a) {
+			return false
+		}
+		alert = a
+		return true
+	})
+	return alert, err
+}
+
+// run reads from conn and reconnects until ctx is done or a reconnect fails
+// permanently
+func (s *Subscription) run(ctx context.Context, conn *websocket.Conn) {
+	defer close(s.done)
+	defer close(s.messages)
+
+	for {
+		s.read(ctx, conn)
+		if ctx.Err() != nil {
+			return
+		}
+
+		var err error
+		conn, err = s.reconnect(ctx)
+		if err != nil {
+			if ctx.Err() == nil {
+				s.mu.Lock()
+				s.err = err
+				s.mu.Unlock()
+			}
+			return
+		}
+	}
+}
+
+// read delivers messages from conn until it fails or ctx is done
+func (s *Subscription) read(ctx context.Context, conn *websocket.Conn) {
+	defer conn.Close()
+
+	for {
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			if ctx.Err() == nil {
+				s.client.logger.Debug("WebSocket read failed", zap.Error(err))
+			}
+			return
+		}
+
+		// The server batches queued messages into one frame, one per line
+		for _, line := range bytes.Split(data, []byte{'\n'}) {
+			if len(line) == 0 {
+				continue
+			}
+			var msg StreamMessage
+			if err := json.Unmarshal(line, &msg); err != nil {
+				s.client.logger.Debug("Skipping malformed WebSocket message", zap.Error(err))
+				continue
+			}
+			s.mu.Lock()
+			s.lastSeq = msg.ID
+			s.mu.Unlock()
+			select {
+			case s.messages <- &msg:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}
+}
+
+// reconnect dials until it succeeds, ctx is done or the subscription can't
+// be re-established: the server rejects it or the token can't be refreshed
+func (s *Subscription) reconnect(ctx context.Context) (*websocket.Conn, error) {
+	policy := s.client.retryPolicy
+	for attempt := 1; ; attempt++ {
+		if err := policy.wait(ctx, attempt); err != nil {
+			return nil, err
+		}
+
+		s.client.logger.Debug("Reconnecting WebSocket", zap.Int("attempt", attempt))
+		conn, err := s.dial(ctx)
+		if err == nil {
+			return conn, nil
+		}
+
+		apiErr, ok := AsAPIError(err)
+		transient := errors.Is(err, errStreamDial) || (ok && apiErr.StatusCode >= http.StatusInternalServerError)
+		if !transient {
+			return nil, err
+		}
+		s.client.logger.Debug("WebSocket reconnect failed", zap.Error(err))
+	}
+}
+
+// dial opens the WebSocket. A 401 forces a token refresh and one more try,
+// since the server only checks the token during the handshake.
+func (s *Subscription) dial(ctx context.Context) (*websocket.Conn, error) {
+	conn, err := s.dialOnce(ctx)
+	if IsUnauthorized(err) {
+		s.client.tokenManager.expire()
+		conn, err = s.dialOnce(ctx)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	s.conn = conn
+	s.mu.Unlock()
+	return conn, nil
+}
+
+func (s *Subscription) dialOnce(ctx context.Context) (*websocket.Conn, error) {
+	s.mu.Lock()
+	lastSeq := s.lastSeq
+	s.mu.Unlock()
+
+	wsURL, err := s.client.streamURL(s.topics, lastSeq)
+	if err != nil {
+		return nil, err
+	}
+
+	authHeader, err := s.client.tokenManager.GetAuthorizationHeader(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get authorization header: %w", err)
+	}
+	header := http.Header{}
+	header.Set("Authorization", authHeader)
+	if id := requestID(ctx); id != "" {
+		header.Set("X-Request-ID", id)
+	}
+
+	dialer := websocket.Dialer{
+		Proxy:            http.ProxyFromEnvironment,
+		HandshakeTimeout: 10 * time.Second,
+	}
+	conn, resp, err := dialer.DialContext(ctx, wsURL, header)
+	if err != nil {
+		if resp != nil {
+			body, _ := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			apiErr := newAPIError(resp.StatusCode, body)
+			if apiErr.RequestID == "" {
+				apiErr.RequestID = resp.Header.Get("X-Request-ID")
+			}
+			return nil, apiErr
+		}
+		return nil, fmt.Errorf("%w: %v", errStreamDial, err)
+	}
+	return conn, nil
+}
+
+// EventHistory is a page of past stream messages, oldest first
+type EventHistory struct {
+	Events []*StreamMessage `json:"events"`
+	// LastSeq is the ID of the latest message broadcast
+	LastSeq uint64 `json:"last_seq"`
+	// HasMore reports that more messages follow the last one returned
+	HasMore bool `json:"has_more"`
+}
+
+// EventHistory lists up to limit past messages after the since sequence
+// number, of the given types or all types when none are given. A limit of
+// 0 uses the server's default.
+func (c *APIClient) EventHistory(ctx context.Context, since uint64, limit int, topics ...string) (*EventHistory, error) {
+	query := url.Values{}
+	if since > 0 {
+		query.Set("since", strconv.FormatUint(since, 10))
+	}
+	if limit > 0 {
+		query.Set("limit", strconv.Itoa(limit))
+	}
+	if len(topics) > 0 {
+		query.Set("topics", strings.Join(topics, ","))
+	}
+	path := "/api/v1/events/history"
+	if len(query) > 0 {
+		path += "?" + query.Encode()
+	}
+
+	resp, err := c.doRequest(ctx, "GET", path, nil, true)
+	if err != nil {
+		return nil, err
+	}
+
+	var history EventHistory
+	if err := c.parseResponse(resp, &history); err != nil {
+		return nil, err
+	}
+	return &history, nil
+}
+
+// streamURL converts the API base URL into the WebSocket endpoint URL,
+// resuming after lastSeq when it isn't 0
+func (c *APIClient) streamURL(topics []string, lastSeq uint64) (string, error) {
+	u, err := url.Parse(c.baseURL + "/api/v1/ws")
+	if err != nil {
+		return "", fmt.Errorf("invalid base URL: %w", err)
+	}
+
+	switch u.Scheme {
+	case "http":
+		u.Scheme = "ws"
+	case "https":
+		u.Scheme = "wss"
+	}
+
+	q := u.Query()
+	if len(topics) > 0 {
+		q.Set("topics", strings.Join(topics, ","))
+	}
+	if lastSeq > 0 {
+		q.Set("last_seq", strconv.FormatUint(lastSeq, 10))
+	}
+	u.RawQuery = q.Encode()
+	return u.String(), nil
+}
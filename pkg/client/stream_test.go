@@ -0,0 +1,216 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testUpgrader = websocket.Upgrader{}
+
+// streamServer serves login/refresh and hands each WebSocket connection to
+// onConn. Tokens are "access-N" where N counts issued tokens.
+type streamServer struct {
+	issued    atomic.Int32
+	refreshed atomic.Int32
+	accepted  atomic.Int32
+	validFrom atomic.Int32
+	onConn    func(conn *websocket.Conn, r *http.Request)
+}
+
+func (s *streamServer) token() string {
+	return "access-" + strconv.Itoa(int(s.issued.Add(1)))
+}
+
+func (s *streamServer) handler(t *testing.T) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v1/auth/login":
+			json.NewEncoder(w).Encode(LoginResponse{AccessToken: s.token(), RefreshToken: "refresh", ExpiresIn: 900})
+		case "/api/v1/auth/refresh":
+			s.refreshed.Add(1)
+			json.NewEncoder(w).Encode(TokenResponse{AccessToken: s.token(), RefreshToken: "refresh", ExpiresIn: 900})
+		case "/api/v1/ws":
+			n, err := strconv.Atoi(strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer access-"))
+			if err != nil || int32(n) < s.validFrom.Load() {
+				w.WriteHeader(http.StatusUnauthorized)
+				json.NewEncoder(w).Encode(ErrorResponse{Error: "Invalid token", Code: CodeInvalidToken})
+				return
+			}
+			conn, err := testUpgrader.Upgrade(w, r, nil)
+			require.NoError(t, err)
+			s.accepted.Add(1)
+			s.onConn(conn, r)
+		}
+	}
+}
+
+func subscribeTestClient(t *testing.T, srv *streamServer, topics ...string) *Subscription {
+	t.Helper()
+
+	client, _ := newTestServer(t, srv.handler(t))
+	client.retryPolicy = RetryPolicy{InitialBackoff: time.Millisecond, MaxBackoff: 10 * time.Millisecond}
+
+	_, err := client.Login(context.Background(), "admin", "admin")
+	require.NoError(t, err)
+
+	sub, err := client.Subscribe(context.Background(), topics...)
+	require.NoError(t, err)
+	t.Cleanup(func() { sub.Close() })
+	return sub
+}
+
+func waitCtx(t *testing.T) context.Context {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	t.Cleanup(cancel)
+	return ctx
+}
+
+func TestSubscribe(t *testing.T) {
+	t.Run("Decodes typed messages", func(t *testing.T) {
+		srv := &streamServer{onConn: func(conn *websocket.Conn, r *http.Request) {
+			assert.Equal(t, "peer_update,alert", r.URL.Query().Get("topics"))
+			// Queued messages arrive batched in one frame
+			conn.WriteMessage(websocket.TextMessage, []byte(
+				`{"id":1,"type":"peer_update","payload":{"id":7,"ip_address":"10.0.0.1","asn":65001}}`+"\n"+
+					`{"id":2,"type":"alert","payload":{"id":3,"type":"peer_down","severity":"warning"},"request_id":"req-1"}`))
+		}}
+		sub := subscribeTestClient(t, srv, MessagePeerUpdate, MessageAlert)
+
+		peer, err := sub.WaitForPeer(waitCtx(t), func(p *Peer) bool { return p.ID == 7 })
+		require.NoError(t, err)
+		assert.Equal(t, "10.0.0.1", peer.IPAddress)
+
+		msg, err := sub.WaitFor(waitCtx(t), func(m *StreamMessage) bool { return m.Type == MessageAlert })
+		require.NoError(t, err)
+		assert.Equal(t, uint64(2), msg.ID)
+		assert.Equal(t, "req-1", msg.RequestID)
+
+		alert, err := msg.Alert()
+		require.NoError(t, err)
+		assert.Equal(t, "peer_down", alert.Type)
+
+		_, err = msg.Peer()
+		assert.Error(t, err)
+	})
+
+	t.Run("Reconnects with a refreshed token", func(t *testing.T) {
+		srv := &streamServer{}
+		srv.onConn = func(conn *websocket.Conn, r *http.Request) {
+			if srv.accepted.Load() == 1 {
+				// Revoke the login token and drop the connection
+				srv.validFrom.Store(2)
+				conn.Close()
+				return
+			}
+			conn.WriteMessage(websocket.TextMessage, []byte(`{"id":5,"type":"session_update","payload":{"id":1,"state":"Established"}}`))
+		}
+		sub := subscribeTestClient(t, srv)
+
+		session, err := sub.WaitForSession(waitCtx(t), func(s *Session) bool { return true })
+		require.NoError(t, err)
+		assert.Equal(t, "Established", session.State)
+		assert.Equal(t, int32(1), srv.refreshed.Load())
+		assert.Equal(t, int32(2), srv.accepted.Load())
+	})
+
+	t.Run("Resumes after the last message received", func(t *testing.T) {
+		srv := &streamServer{}
+		srv.onConn = func(conn *websocket.Conn, r *http.Request) {
+			if srv.accepted.Load() == 1 {
+				assert.Empty(t, r.URL.Query().Get("last_seq"))
+				conn.WriteMessage(websocket.TextMessage, []byte(`{"id":7,"type":"alert","payload":{}}`))
+				conn.Close()
+				return
+			}
+			assert.Equal(t, "7", r.URL.Query().Get("last_seq"))
+			conn.WriteMessage(websocket.TextMessage, []byte(`{"id":8,"type":"alert","payload":{}}`))
+		}
+		sub := subscribeTestClient(t, srv)
+
+		msg, err := sub.WaitFor(waitCtx(t), func(m *StreamMessage) bool { return m.ID == 8 })
+		require.NoError(t, err)
+		assert.Equal(t, MessageAlert, msg.Type)
+	})
+
+	t.Run("Ends when the server rejects the reconnect", func(t *testing.T) {
+		srv := &streamServer{}
+		srv.onConn = func(conn *websocket.Conn, r *http.Request) {
+			// No token will be accepted again
+			srv.validFrom.Store(9)
+			conn.Close()
+		}
+		sub := subscribeTestClient(t, srv)
+
+		_, err := sub.WaitFor(waitCtx(t), func(*StreamMessage) bool { return true })
+		assert.True(t, IsUnauthorized(err))
+	})
+
+	t.Run("Returns handshake errors", func(t *testing.T) {
+		client, _ := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+			switch r.URL.Path {
+			case "/api/v1/auth/login":
+				json.NewEncoder(w).Encode(LoginResponse{AccessToken: "access", RefreshToken: "refresh", ExpiresIn: 900})
+			case "/api/v1/ws":
+				w.WriteHeader(http.StatusBadRequest)
+				json.NewEncoder(w).Encode(ErrorResponse{Error: `unknown topic "bogus"`, Code: CodeValidationFailed})
+			}
+		})
+		_, err := client.Login(context.Background(), "admin", "admin")
+		require.NoError(t, err)
+
+		_, err = client.Subscribe(context.Background(), "bogus")
+		assert.True(t, HasCode(err, CodeValidationFailed))
+	})
+}
+
+func TestEventHistory(t *testing.T) {
+	client, _ := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v1/auth/login":
+			json.NewEncoder(w).Encode(LoginResponse{AccessToken: "access", RefreshToken: "refresh", ExpiresIn: 900})
+		case "/api/v1/events/history":
+			assert.Equal(t, "10", r.URL.Query().Get("since"))
+			assert.Equal(t, "2", r.URL.Query().Get("limit"))
+			assert.Equal(t, "alert", r.URL.Query().Get("topics"))
+			w.Write([]byte(`{"events":[{"id":11,"type":"alert","time":"2026-01-02T03:04:05Z","payload":{"type":"peer_down"}}],"last_seq":12,"has_more":false}`))
+		}
+	})
+	_, err := client.Login(context.Background(), "admin", "admin")
+	require.NoError(t, err)
+
+	history, err := client.EventHistory(context.Background(), 10, 2, MessageAlert)
+	require.NoError(t, err)
+	require.Len(t, history.Events, 1)
+	assert.Equal(t, uint64(12), history.LastSeq)
+
+	alert, err := history.Events[0].Alert()
+	require.NoError(t, err)
+	assert.Equal(t, "peer_down", alert.Type)
+	assert.Equal(t, 2026, history.Events[0].Time.Year())
+}
+
+func TestStreamURL(t *testing.T) {
+	client := NewAPIClient("https://flintroute.example.com", nil)
+
+	u, err := client.streamURL(nil, 0)
+	require.NoError(t, err)
+	assert.Equal(t, "wss://flintroute.example.com/api/v1/ws", u)
+
+	u, err = client.streamURL([]string{MessageAlert}, 0)
+	require.NoError(t, err)
+	assert.Equal(t, "wss://flintroute.example.com/api/v1/ws?topics=alert", u)
+
+	u, err = client.streamURL([]string{MessageAlert}, 42)
+	require.NoError(t, err)
+	assert.Equal(t, "wss://flintroute.example.com/api/v1/ws?last_seq=42&topics=alert", u)
+}
@@ -0,0 +1,1723 @@
+package client
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// LoginRequest represents a login request
+type LoginRequest struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+// LoginResponse represents a login response
+type LoginResponse struct {
+	AccessToken  string   `json:"access_token"`
+	RefreshToken string   `json:"refresh_token"`
+	ExpiresIn    int64    `json:"expires_in"`
+	User         UserInfo `json:"user"`
+}
+
+// ImpersonateRequest is an optional reason for impersonating a user, kept
+// in the audit log
+type ImpersonateRequest struct {
+	Reason string `json:"reason,omitempty"`
+}
+
+// ImpersonateResponse is a short-lived token acting as a user, without a
+// refresh token
+type ImpersonateResponse struct {
+	AccessToken  string    `json:"access_token"`
+	ExpiresIn    int64     `json:"expires_in"`
+	ExpiresAt    time.Time `json:"expires_at"`
+	User         UserInfo  `json:"user"`
+	Impersonator UserInfo  `json:"impersonator"`
+}
+
+// UserInfo represents user information
+type UserInfo struct {
+	ID       uint   `json:"id"`
+	Username string `json:"username"`
+	Email    string `json:"email"`
+	Role     string `json:"role"`
+}
+
+// Profile represents the current user's profile
+type Profile struct {
+	ID        uint      `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	Username  string    `json:"username"`
+	Email     string    `json:"email"`
+	Role      string    `json:"role"`
+	Active    bool      `json:"active"`
+	// Preferences is the JSON object stored with UpdatePreferences
+	Preferences json.RawMessage `json:"preferences,omitempty"`
+	// RequestQuota is how many API requests per hour the user may make;
+	// nil uses the server default and 0 is unlimited
+	RequestQuota *int `json:"request_quota,omitempty"`
+}
+
+// UpdateProfileRequest represents a request to update the current user's
+// profile
+type UpdateProfileRequest struct {
+	Email string `json:"email"`
+}
+
+// ChangePasswordRequest represents a request to change the current user's
+// password. NewPassword must be at least 8 characters.
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password"`
+	NewPassword     string `json:"new_password"`
+}
+
+// LoginSession describes one of the current user's active sessions
+type LoginSession struct {
+	ID        uint      `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+	UserAgent string    `json:"user_agent,omitempty"`
+	ClientIP  string    `json:"client_ip,omitempty"`
+}
+
+// LoginSessionsResponse represents the response from listing sessions
+type LoginSessionsResponse struct {
+	Sessions []*LoginSession `json:"sessions"`
+}
+
+// UsageStats counts the API requests made in a period
+type UsageStats struct {
+	Requests int64 `json:"requests"`
+	// Errors are requests answered with a 4xx or 5xx status
+	Errors int64 `json:"errors"`
+	// Throttled are requests rejected for exceeding the quota
+	Throttled    int64   `json:"throttled"`
+	AvgLatencyMS float64 `json:"avg_latency_ms"`
+	MaxLatencyMS float64 `json:"max_latency_ms"`
+}
+
+// UsageHour is the usage of one hour, starting at Hour
+type UsageHour struct {
+	Hour time.Time `json:"hour"`
+	UsageStats
+}
+
+// RequestQuota is a user's request quota in the current hour
+type RequestQuota struct {
+	RequestsPerHour int       `json:"requests_per_hour"`
+	Remaining       int       `json:"remaining"`
+	ResetAt         time.Time `json:"reset_at"`
+}
+
+// Usage is a user's API usage over the last hours
+type Usage struct {
+	UserID   uint          `json:"user_id"`
+	Username string        `json:"username"`
+	Quota    *RequestQuota `json:"quota"` // nil when unlimited
+	Total    UsageStats    `json:"total"`
+	Hours    []UsageHour   `json:"hours,omitempty"`
+}
+
+// UsageListResponse represents the response from listing every user's usage
+type UsageListResponse struct {
+	Hours        int      `json:"hours"`
+	DefaultQuota int      `json:"default_quota"`
+	Users        []*Usage `json:"users"`
+}
+
+// SetRequestQuotaRequest sets a user's request quota. A nil
+// RequestsPerHour uses the server default; 0 is unlimited.
+type SetRequestQuotaRequest struct {
+	RequestsPerHour *int `json:"requests_per_hour"`
+}
+
+// Tenant owns peers, alerts and config versions when the server has
+// tenancy enabled
+type Tenant struct {
+	ID          uint      `json:"id"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+	Name        string    `json:"name"`
+	Description string    `json:"description,omitempty"`
+}
+
+// TenantRequest creates or renames a tenant
+type TenantRequest struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+}
+
+// TenantsResponse represents the response from listing tenants
+type TenantsResponse struct {
+	Tenants []*Tenant `json:"tenants"`
+}
+
+// TenantMember is a user's membership of a tenant. Role (user, operator)
+// replaces the user's own role within the tenant.
+type TenantMember struct {
+	ID        uint      `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	TenantID  uint      `json:"tenant_id"`
+	Tenant    *Tenant   `json:"tenant,omitempty"`
+	UserID    uint      `json:"user_id"`
+	Role      string    `json:"role"`
+}
+
+// TenantMemberRequest adds a member to a tenant or changes their role
+type TenantMemberRequest struct {
+	Role string `json:"role"`
+}
+
+// TenantMembersResponse represents the response from listing a tenant's
+// members
+type TenantMembersResponse struct {
+	Members []*TenantMember `json:"members"`
+}
+
+// TenantMembershipsResponse represents the response from listing your
+// tenants
+type TenantMembershipsResponse struct {
+	Tenants []*TenantMember `json:"tenants"`
+}
+
+// NotificationSettings are how a user is notified of alerts
+type NotificationSettings struct {
+	UpdatedAt time.Time `json:"updated_at,omitempty"`
+	// Rules are checked in order; the first that matches an alert picks its
+	// channels. Alerts no rule matches aren't notified.
+	Rules []NotificationRule `json:"rules"`
+	// QuietHours hold back notifications, except for the severities in
+	// QuietHoursBypass
+	QuietHours       []QuietHours `json:"quiet_hours"`
+	QuietHoursBypass []string     `json:"quiet_hours_bypass,omitempty"`
+	// Timezone is the IANA name quiet hours are in; empty is UTC
+	Timezone   string `json:"timezone,omitempty"`
+	WebhookURL string `json:"webhook_url,omitempty"` // receives the webhook channel
+	// Inherited is set when the user has no settings of their own and gets
+	// the defaults
+	Inherited bool `json:"inherited,omitempty"`
+}
+
+// Email templates
+const (
+	EmailTemplateAlert           = "alert"
+	EmailTemplateDigest          = "digest"
+	EmailTemplateChangeRequested = "change_requested"
+	EmailTemplatePasswordReset   = "password_reset"
+)
+
+// TestEmailRequest asks for an email template to be sent with sample data
+type TestEmailRequest struct {
+	Template string `json:"template"`
+	// To defaults to the admin's own address
+	To string `json:"to,omitempty"`
+}
+
+// TestEmail is a test email sent
+type TestEmail struct {
+	Template string `json:"template"`
+	To       string `json:"to"`
+	Subject  string `json:"subject"`
+}
+
+// NotificationRule sends alerts of the given severities and types, all of
+// them when empty, to channels: email, webhook or none
+type NotificationRule struct {
+	Severities []string `json:"severities,omitempty"`
+	Types      []string `json:"types,omitempty"`
+	Channels   []string `json:"channels"`
+}
+
+// QuietHours is a daily window from Start to End ("HH:MM"), ending the next
+// day when End is before Start, on Days ("mon" to "sun"; every day when
+// empty)
+type QuietHours struct {
+	Days  []string `json:"days,omitempty"`
+	Start string   `json:"start"`
+	End   string   `json:"end"`
+}
+
+// TokenResponse represents a token refresh response
+type TokenResponse struct {
+	AccessToken  string   `json:"access_token"`
+	RefreshToken string   `json:"refresh_token"`
+	ExpiresIn    int64    `json:"expires_in"`
+	User         UserInfo `json:"user"`
+}
+
+// RefreshRequest represents a token refresh request
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+// PeerRequest represents a request to create or update a BGP peer.
+// Password is only honoured on create; use SetPeerPassword to change it.
+// An ASN of 0 uses the router's.
+type PeerRequest struct {
+	Name            string `json:"name"`
+	IPAddress       string `json:"ip_address"`
+	ASN             uint32 `json:"asn"`
+	RemoteASN       uint32 `json:"remote_asn"`
+	Description     string `json:"description"`
+	Enabled         bool   `json:"enabled"`
+	Password        string `json:"password,omitempty"`
+	Multihop        int    `json:"multihop"`
+	UpdateSource    string `json:"update_source,omitempty"`
+	RouteMapIn      string `json:"route_map_in,omitempty"`
+	RouteMapOut     string `json:"route_map_out,omitempty"`
+	PrefixListIn    string `json:"prefix_list_in,omitempty"`
+	PrefixListOut   string `json:"prefix_list_out,omitempty"`
+	MaxPrefixes     int    `json:"max_prefixes"`
+	LocalPreference int    `json:"local_preference"`
+	// Tags are key/value labels used to group and filter peers. On update,
+	// nil keeps the peer's current tags.
+	Tags map[string]string `json:"tags,omitempty"`
+	PeerOptions
+}
+
+// PeerOptions holds a peer's timers (in seconds) and advanced options.
+// Zero values keep FRR's defaults; Keepalive and HoldTime are set together.
+type PeerOptions struct {
+	Keepalive           int  `json:"keepalive"`
+	HoldTime            int  `json:"holdtime"`
+	ConnectRetry        int  `json:"connect_retry"`
+	Passive             bool `json:"passive"`
+	TTLSecurityHops     int  `json:"ttl_security_hops"`
+	NextHopSelf         bool `json:"next_hop_self"`
+	SoftReconfigInbound bool `json:"soft_reconfiguration_inbound"`
+	RemovePrivateAS     bool `json:"remove_private_as"`
+	AllowASIn           int  `json:"allowas_in"`
+	// LocalAS is presented to an eBGP peer instead of the router's AS.
+	// LocalASMode is "", "no-prepend" or "replace-as".
+	LocalAS     uint32 `json:"local_as,omitempty"`
+	LocalASMode string `json:"local_as_mode,omitempty"`
+	// MaxPrefixThreshold is the percentage of MaxPrefixes that logs a
+	// warning. MaxPrefixAction is "shutdown" (the default) or
+	// "warning-only"; MaxPrefixRestart restarts a shut down session after
+	// that many minutes.
+	MaxPrefixThreshold int    `json:"max_prefix_threshold"`
+	MaxPrefixAction    string `json:"max_prefix_action,omitempty"`
+	MaxPrefixRestart   int    `json:"max_prefix_restart"`
+}
+
+// PeerPatchRequest represents a partial update of a BGP peer.
+// Nil fields are omitted and keep their current values.
+type PeerPatchRequest struct {
+	Name                *string `json:"name,omitempty"`
+	Description         *string `json:"description,omitempty"`
+	Enabled             *bool   `json:"enabled,omitempty"`
+	Multihop            *int    `json:"multihop,omitempty"`
+	UpdateSource        *string `json:"update_source,omitempty"`
+	RouteMapIn          *string `json:"route_map_in,omitempty"`
+	RouteMapOut         *string `json:"route_map_out,omitempty"`
+	PrefixListIn        *string `json:"prefix_list_in,omitempty"`
+	PrefixListOut       *string `json:"prefix_list_out,omitempty"`
+	MaxPrefixes         *int    `json:"max_prefixes,omitempty"`
+	MaxPrefixThreshold  *int    `json:"max_prefix_threshold,omitempty"`
+	MaxPrefixAction     *string `json:"max_prefix_action,omitempty"`
+	MaxPrefixRestart    *int    `json:"max_prefix_restart,omitempty"`
+	LocalPreference     *int    `json:"local_preference,omitempty"`
+	Keepalive           *int    `json:"keepalive,omitempty"`
+	HoldTime            *int    `json:"holdtime,omitempty"`
+	ConnectRetry        *int    `json:"connect_retry,omitempty"`
+	Passive             *bool   `json:"passive,omitempty"`
+	TTLSecurityHops     *int    `json:"ttl_security_hops,omitempty"`
+	NextHopSelf         *bool   `json:"next_hop_self,omitempty"`
+	SoftReconfigInbound *bool   `json:"soft_reconfiguration_inbound,omitempty"`
+	RemovePrivateAS     *bool   `json:"remove_private_as,omitempty"`
+	AllowASIn           *int    `json:"allowas_in,omitempty"`
+	LocalAS             *uint32 `json:"local_as,omitempty"`
+	LocalASMode         *string `json:"local_as_mode,omitempty"`
+	// Tags replaces all of the peer's tags when non-nil; an empty map
+	// removes them
+	Tags map[string]string `json:"tags,omitempty"`
+}
+
+// Peer represents a BGP peer
+type Peer struct {
+	ID              uint      `json:"id"`
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
+	Name            string    `json:"name"`
+	IPAddress       string    `json:"ip_address"`
+	ASN             uint32    `json:"asn"`
+	RemoteASN       uint32    `json:"remote_asn"`
+	Description     string    `json:"description"`
+	Enabled         bool      `json:"enabled"`
+	HasPassword     bool      `json:"has_password"`
+	Multihop        int       `json:"multihop"`
+	UpdateSource    string    `json:"update_source,omitempty"`
+	RouteMapIn      string    `json:"route_map_in,omitempty"`
+	RouteMapOut     string    `json:"route_map_out,omitempty"`
+	PrefixListIn    string    `json:"prefix_list_in,omitempty"`
+	PrefixListOut   string    `json:"prefix_list_out,omitempty"`
+	MaxPrefixes     int       `json:"max_prefixes"`
+	LocalPreference int       `json:"local_preference"`
+	PeerOptions
+	SyncStatus string            `json:"sync_status"`
+	SyncError  string            `json:"sync_error,omitempty"`
+	ManagedBy  string            `json:"managed_by,omitempty"`
+	Tags       map[string]string `json:"tags,omitempty"`
+	// LastFRRError is FRR's raw output the last time it rejected the
+	// peer's configuration, at LastFRRErrorAt
+	LastFRRError   string     `json:"last_frr_error,omitempty"`
+	LastFRRErrorAt *time.Time `json:"last_frr_error_at,omitempty"`
+	// MaintenanceSince is set while the peer is under maintenance, until
+	// MaintenanceUntil when that is set
+	MaintenanceSince *time.Time `json:"maintenance_since,omitempty"`
+	MaintenanceUntil *time.Time `json:"maintenance_until,omitempty"`
+	// TemplateID is the template the peer was provisioned from, and
+	// TemplateOverrides the template settings set on the peer itself
+	TemplateID        *uint    `json:"template_id,omitempty"`
+	TemplateOverrides []string `json:"template_overrides,omitempty"`
+	// TenantID is the tenant owning the peer, when tenancy is enabled
+	TenantID *uint `json:"tenant_id,omitempty"`
+}
+
+// PeerPlan is what creating or changing a peer would do, returned by dry
+// runs. Commands are the vtysh commands that would be applied, with
+// passwords redacted; Validated reports whether FRR checked them.
+type PeerPlan struct {
+	Peer      *Peer    `json:"peer"`
+	Commands  []string `json:"commands"`
+	Validated bool     `json:"validated"`
+	Warnings  []string `json:"warnings,omitempty"`
+}
+
+// Outcomes of a peer connection attempt
+const (
+	ProbeOpen        = "open"
+	ProbeRefused     = "refused"
+	ProbeTimeout     = "timeout"
+	ProbeUnreachable = "unreachable"
+	ProbeUnsupported = "unsupported"
+	ProbeError       = "error"
+)
+
+// PeerTest is the outcome of a pre-flight connection test of a peer.
+// Reachable reports whether the peer's host answered on the BGP port;
+// Ready whether it accepted a connection made the way FRR will make it.
+type PeerTest struct {
+	PeerID    uint      `json:"peer_id"`
+	IPAddress string    `json:"ip_address"`
+	TestedAt  time.Time `json:"tested_at"`
+	Reachable bool      `json:"reachable"`
+	Ready     bool      `json:"ready"`
+	// ProbedFrom is "router" when the test ran on the FRR host, or
+	// "flintroute" when it ran on the FlintRoute host
+	ProbedFrom    string     `json:"probed_from"`
+	SourceAddress string     `json:"source_address,omitempty"`
+	TCP           PeerProbe  `json:"tcp"`
+	MD5           *PeerProbe `json:"md5,omitempty"`
+	Warnings      []string   `json:"warnings,omitempty"`
+}
+
+// PeerProbe is the outcome of one connection attempt of a peer test
+type PeerProbe struct {
+	Result    string  `json:"result"`
+	LatencyMS float64 `json:"latency_ms,omitempty"`
+	Error     string  `json:"error,omitempty"`
+}
+
+// Bulk peer actions
+const (
+	BulkEnable  = "enable"
+	BulkDisable = "disable"
+	BulkDelete  = "delete"
+)
+
+// BulkPeersRequest applies an action to every peer matching all tag
+// selectors ("key:value", or "key" for any value)
+type BulkPeersRequest struct {
+	Tags   []string `json:"tags"`
+	Action string   `json:"action"`
+}
+
+// BulkPeerResult is the outcome of a bulk action on a single peer
+type BulkPeerResult struct {
+	PeerID    uint   `json:"peer_id"`
+	IPAddress string `json:"ip_address"`
+	Error     string `json:"error,omitempty"`
+}
+
+// BulkPeersResponse represents the response from a bulk peer action
+type BulkPeersResponse struct {
+	Action string            `json:"action"`
+	Peers  []*BulkPeerResult `json:"peers"`
+}
+
+// DeletedPeer is a peer in the trash. PurgeAt is nil when the trash is kept
+// until purged by hand.
+type DeletedPeer struct {
+	Peer
+	DeletedAt time.Time  `json:"deleted_at"`
+	PurgeAt   *time.Time `json:"purge_at,omitempty"`
+}
+
+// DeletedPeersResponse represents a list of peers in the trash
+type DeletedPeersResponse struct {
+	Peers []*DeletedPeer `json:"peers"`
+}
+
+// PurgeResponse represents the response from purging the trash
+type PurgeResponse struct {
+	Purged int     `json:"purged"`
+	Peers  []*Peer `json:"peers"`
+}
+
+// Session represents a BGP session
+type Session struct {
+	ID               uint      `json:"id"`
+	CreatedAt        time.Time `json:"created_at"`
+	UpdatedAt        time.Time `json:"updated_at"`
+	PeerID           uint      `json:"peer_id"`
+	Peer             *Peer     `json:"peer,omitempty"`
+	State            string    `json:"state"`
+	Uptime           int64     `json:"uptime"`
+	PrefixesReceived int       `json:"prefixes_received"`
+	PrefixesSent     int       `json:"prefixes_sent"`
+	MessagesReceived int64     `json:"messages_received"`
+	MessagesSent     int64     `json:"messages_sent"`
+	LastError        string    `json:"last_error"`
+	LastReset        time.Time `json:"last_reset"`
+	InMaintenance    bool      `json:"in_maintenance"`
+	// Negotiated with the peer; empty unless the session is established
+	RemoteRouterID  string   `json:"remote_router_id"`
+	BGPVersion      int      `json:"bgp_version"`
+	HoldTime        int      `json:"hold_time"`
+	KeepaliveTime   int      `json:"keepalive_time"`
+	FourByteASN     bool     `json:"four_byte_asn"`
+	AddressFamilies []string `json:"address_families"`
+	GracefulRestart bool     `json:"graceful_restart"`
+}
+
+// DriftEntry describes a difference between stored peers and FRR
+type DriftEntry struct {
+	Kind      string `json:"kind"`
+	PeerID    *uint  `json:"peer_id,omitempty"`
+	IPAddress string `json:"ip_address"`
+	Expected  string `json:"expected,omitempty"`
+	Actual    string `json:"actual,omitempty"`
+	Healed    bool   `json:"healed"`
+}
+
+// BGPGlobal represents the settings of FRR's default BGP instance. Zero
+// graceful restart timers keep FRR's defaults.
+type BGPGlobal struct {
+	CreatedAt          time.Time `json:"created_at"`
+	UpdatedAt          time.Time `json:"updated_at"`
+	ASN                uint32    `json:"asn"`
+	RouterID           string    `json:"router_id"`
+	GracefulRestart    bool      `json:"graceful_restart"`
+	RestartTime        int       `json:"graceful_restart_time"`
+	StalePathTime      int       `json:"graceful_restart_stalepath_time"`
+	EBGPRequiresPolicy bool      `json:"ebgp_requires_policy"`
+	LogNeighborChanges bool      `json:"log_neighbor_changes"`
+	AlwaysCompareMED   bool      `json:"always_compare_med"`
+	CompareRouterID    bool      `json:"bestpath_compare_router_id"`
+	MultipathRelax     bool      `json:"bestpath_multipath_relax"`
+	MEDMissingAsWorst  bool      `json:"bestpath_med_missing_as_worst"`
+}
+
+// BGPGlobalRequest replaces the BGP global configuration. A nil
+// EBGPRequiresPolicy keeps FRR's default of requiring policies.
+type BGPGlobalRequest struct {
+	ASN                uint32 `json:"asn"`
+	RouterID           string `json:"router_id,omitempty"`
+	GracefulRestart    bool   `json:"graceful_restart,omitempty"`
+	RestartTime        int    `json:"graceful_restart_time,omitempty"`
+	StalePathTime      int    `json:"graceful_restart_stalepath_time,omitempty"`
+	EBGPRequiresPolicy *bool  `json:"ebgp_requires_policy,omitempty"`
+	LogNeighborChanges bool   `json:"log_neighbor_changes,omitempty"`
+	AlwaysCompareMED   bool   `json:"always_compare_med,omitempty"`
+	CompareRouterID    bool   `json:"bestpath_compare_router_id,omitempty"`
+	MultipathRelax     bool   `json:"bestpath_multipath_relax,omitempty"`
+	MEDMissingAsWorst  bool   `json:"bestpath_med_missing_as_worst,omitempty"`
+}
+
+// CommunityList represents a BGP community-list
+type CommunityList struct {
+	ID        uint                 `json:"id"`
+	CreatedAt time.Time            `json:"created_at"`
+	UpdatedAt time.Time            `json:"updated_at"`
+	Name      string               `json:"name"`
+	Type      string               `json:"type"` // standard, expanded
+	Entries   []CommunityListEntry `json:"entries"`
+	ManagedBy string               `json:"managed_by,omitempty"`
+}
+
+// CommunityListEntry is a single sequence of a community-list. Value holds
+// space-separated communities for standard lists and a regular expression
+// for expanded lists.
+type CommunityListEntry struct {
+	Seq    int    `json:"seq"`
+	Action string `json:"action"` // permit, deny
+	Value  string `json:"value"`
+}
+
+// CommunityListRequest represents a request to create or replace a
+// community-list. Name is only used on create; Type defaults to standard.
+type CommunityListRequest struct {
+	Name    string               `json:"name,omitempty"`
+	Type    string               `json:"type,omitempty"`
+	Entries []CommunityListEntry `json:"entries"`
+}
+
+// PeerTemplate holds settings shared by the peers provisioned from it.
+// Settings it leaves unset are inherited from its parent; Resolved holds
+// the settings its peers get.
+type PeerTemplate struct {
+	ID          uint                  `json:"id"`
+	CreatedAt   time.Time             `json:"created_at"`
+	UpdatedAt   time.Time             `json:"updated_at"`
+	Name        string                `json:"name"`
+	Description string                `json:"description"`
+	ParentID    *uint                 `json:"parent_id,omitempty"`
+	Settings    PeerTemplateSettings  `json:"settings"`
+	Resolved    *PeerTemplateSettings `json:"resolved,omitempty"`
+}
+
+// PeerTemplateSettings are the peer settings a template can hold. Nil
+// settings are not set.
+type PeerTemplateSettings struct {
+	ASN                 *uint32           `json:"asn,omitempty"`
+	RemoteASN           *uint32           `json:"remote_asn,omitempty"`
+	Enabled             *bool             `json:"enabled,omitempty"`
+	Multihop            *int              `json:"multihop,omitempty"`
+	UpdateSource        *string           `json:"update_source,omitempty"`
+	RouteMapIn          *string           `json:"route_map_in,omitempty"`
+	RouteMapOut         *string           `json:"route_map_out,omitempty"`
+	PrefixListIn        *string           `json:"prefix_list_in,omitempty"`
+	PrefixListOut       *string           `json:"prefix_list_out,omitempty"`
+	MaxPrefixes         *int              `json:"max_prefixes,omitempty"`
+	MaxPrefixThreshold  *int              `json:"max_prefix_threshold,omitempty"`
+	MaxPrefixAction     *string           `json:"max_prefix_action,omitempty"`
+	MaxPrefixRestart    *int              `json:"max_prefix_restart,omitempty"`
+	LocalPreference     *int              `json:"local_preference,omitempty"`
+	Keepalive           *int              `json:"keepalive,omitempty"`
+	HoldTime            *int              `json:"holdtime,omitempty"`
+	ConnectRetry        *int              `json:"connect_retry,omitempty"`
+	Passive             *bool             `json:"passive,omitempty"`
+	TTLSecurityHops     *int              `json:"ttl_security_hops,omitempty"`
+	NextHopSelf         *bool             `json:"next_hop_self,omitempty"`
+	SoftReconfigInbound *bool             `json:"soft_reconfiguration_inbound,omitempty"`
+	RemovePrivateAS     *bool             `json:"remove_private_as,omitempty"`
+	AllowASIn           *int              `json:"allowas_in,omitempty"`
+	LocalAS             *uint32           `json:"local_as,omitempty"`
+	LocalASMode         *string           `json:"local_as_mode,omitempty"`
+	Tags                map[string]string `json:"tags,omitempty"`
+}
+
+// PeerTemplateRequest represents a request to create or replace a peer
+// template
+type PeerTemplateRequest struct {
+	Name        string               `json:"name"`
+	Description string               `json:"description,omitempty"`
+	ParentID    *uint                `json:"parent_id,omitempty"`
+	Settings    PeerTemplateSettings `json:"settings"`
+}
+
+// PeerTemplatesResponse represents a list of peer templates response
+type PeerTemplatesResponse struct {
+	Templates []*PeerTemplate `json:"templates"`
+}
+
+// TemplatePeerRequest represents a request to create a BGP peer from a
+// template. Settings left nil are taken from the template; those set are
+// recorded as the peer's overrides.
+type TemplatePeerRequest struct {
+	TemplateID  uint   `json:"template_id"`
+	Name        string `json:"name"`
+	IPAddress   string `json:"ip_address"`
+	Description string `json:"description,omitempty"`
+	Password    string `json:"password,omitempty"`
+	PeerTemplateSettings
+}
+
+// SyncPeerTemplateResponse represents the response from syncing a peer
+// template to its peers
+type SyncPeerTemplateResponse struct {
+	Results []*BulkPeerResult `json:"results"`
+}
+
+// PeeringDBNetwork represents a network registered in PeeringDB
+type PeeringDBNetwork struct {
+	ASN             uint32             `json:"asn"`
+	Name            string             `json:"name"`
+	AKA             string             `json:"aka,omitempty"`
+	Website         string             `json:"website,omitempty"`
+	IRRASSet        string             `json:"irr_as_set,omitempty"`
+	Type            string             `json:"type,omitempty"`
+	Policy          string             `json:"policy,omitempty"`
+	MaxPrefixesIPv4 int                `json:"max_prefixes_ipv4"`
+	MaxPrefixesIPv6 int                `json:"max_prefixes_ipv6"`
+	Contacts        []PeeringDBContact `json:"contacts"`
+	IXLANs          []PeeringDBIXLAN   `json:"ix_lans"`
+	FetchedAt       time.Time          `json:"fetched_at"`
+}
+
+// PeeringDBContact represents a public point of contact of a network
+type PeeringDBContact struct {
+	Role  string `json:"role"`
+	Name  string `json:"name,omitempty"`
+	Email string `json:"email,omitempty"`
+	Phone string `json:"phone,omitempty"`
+	URL   string `json:"url,omitempty"`
+}
+
+// PeeringDBIXLAN represents a network's presence on an IXP peering LAN
+type PeeringDBIXLAN struct {
+	IXID        int    `json:"ix_id"`
+	Name        string `json:"name"`
+	IPv4        string `json:"ipv4,omitempty"`
+	IPv6        string `json:"ipv6,omitempty"`
+	Speed       int    `json:"speed"`
+	RouteServer bool   `json:"route_server"`
+}
+
+// ASPathList represents a BGP as-path access-list
+type ASPathList struct {
+	ID        uint              `json:"id"`
+	CreatedAt time.Time         `json:"created_at"`
+	UpdatedAt time.Time         `json:"updated_at"`
+	Name      string            `json:"name"`
+	Entries   []ASPathListEntry `json:"entries"`
+	ManagedBy string            `json:"managed_by,omitempty"`
+}
+
+// ASPathListEntry is a single sequence of an as-path access-list
+type ASPathListEntry struct {
+	Seq    int    `json:"seq"`
+	Action string `json:"action"` // permit, deny
+	Regex  string `json:"regex"`
+}
+
+// ASPathListRequest represents a request to create or replace an as-path
+// access-list. Name is only used on create.
+type ASPathListRequest struct {
+	Name    string            `json:"name,omitempty"`
+	Entries []ASPathListEntry `json:"entries"`
+}
+
+// DriftReport represents the result of comparing stored peers with FRR
+type DriftReport struct {
+	CheckedAt time.Time     `json:"checked_at"`
+	InSync    bool          `json:"in_sync"`
+	Entries   []*DriftEntry `json:"entries"`
+}
+
+// PeerSyncResult is the outcome of applying a single peer to FRR
+type PeerSyncResult struct {
+	PeerID    uint   `json:"peer_id"`
+	IPAddress string `json:"ip_address"`
+	Applied   bool   `json:"applied"`
+	Error     string `json:"error,omitempty"`
+}
+
+// PeerSyncReport is the result of applying every enabled peer to FRR when
+// FlintRoute starts or FRR reconnects
+type PeerSyncReport struct {
+	Trigger  string            `json:"trigger"` // startup, reconnect
+	SyncedAt time.Time         `json:"synced_at"`
+	Applied  int               `json:"applied"`
+	Failed   int               `json:"failed"`
+	Peers    []*PeerSyncResult `json:"peers"`
+}
+
+// TopTalker is a peer's session counters, with the change in prefixes
+// received over the server's anomaly window
+type TopTalker struct {
+	PeerID           uint   `json:"peer_id"`
+	Name             string `json:"name"`
+	IPAddress        string `json:"ip_address"`
+	State            string `json:"state"`
+	PrefixesReceived int    `json:"prefixes_received"`
+	PrefixesSent     int    `json:"prefixes_sent"`
+	MessagesReceived int64  `json:"messages_received"`
+	MessagesSent     int64  `json:"messages_sent"`
+	PrefixChange     int    `json:"prefix_change"`
+}
+
+// SessionStats are peer and session counts for a group of peers. Key is
+// empty for peers without a value of the dimension.
+type SessionStats struct {
+	Key              string  `json:"key"`
+	Peers            int     `json:"peers"`
+	Enabled          int     `json:"enabled"`
+	Established      int     `json:"established"`
+	EstablishedRatio float64 `json:"established_ratio"`
+	PrefixesReceived int     `json:"prefixes_received"`
+	PrefixesSent     int     `json:"prefixes_sent"`
+	AverageUptime    int64   `json:"average_uptime"` // seconds
+}
+
+// SessionStatsReport is session statistics aggregated by one dimension
+type SessionStatsReport struct {
+	GeneratedAt time.Time       `json:"generated_at"`
+	By          string          `json:"by"`
+	TagKey      string          `json:"tag_key,omitempty"`
+	Total       *SessionStats   `json:"total"`
+	Groups      []*SessionStats `json:"groups"`
+}
+
+// SLAIncident is a period a peer's session was down. End is nil while it
+// is still down; maintenance incidents don't count against availability.
+type SLAIncident struct {
+	Start       time.Time  `json:"start"`
+	End         *time.Time `json:"end,omitempty"`
+	Duration    int64      `json:"duration"` // seconds
+	State       string     `json:"state"`
+	Maintenance bool       `json:"maintenance,omitempty"`
+}
+
+// PeerSLA is a peer's session availability over a report's range. Times
+// are in seconds.
+type PeerSLA struct {
+	PeerID              uint           `json:"peer_id"`
+	Name                string         `json:"name"`
+	IPAddress           string         `json:"ip_address"`
+	RemoteASN           uint32         `json:"remote_asn"`
+	Monitored           int64          `json:"monitored"`
+	Downtime            int64          `json:"downtime"`
+	MaintenanceDowntime int64          `json:"maintenance_downtime"`
+	Availability        float64        `json:"availability"` // percent
+	MTTR                int64          `json:"mttr"`
+	Incidents           []*SLAIncident `json:"incidents"`
+}
+
+// SLAReport is the session availability of peers over a range
+type SLAReport struct {
+	GeneratedAt time.Time  `json:"generated_at"`
+	Start       time.Time  `json:"start"`
+	End         time.Time  `json:"end"`
+	Peers       []*PeerSLA `json:"peers"`
+}
+
+// StateTransition is a peer's session changing state
+type StateTransition struct {
+	ID            uint      `json:"id"`
+	CreatedAt     time.Time `json:"created_at"`
+	OldState      string    `json:"old_state"`
+	NewState      string    `json:"new_state"`
+	InMaintenance bool      `json:"in_maintenance,omitempty"`
+}
+
+// StateSegment is a period a peer's session stayed in one state, "unknown"
+// while it wasn't monitored
+type StateSegment struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+	State string    `json:"state"`
+}
+
+// StateBucket is the time a peer's session spent in each state within one
+// bucket of a timeline, in seconds, and its number of transitions
+type StateBucket struct {
+	Start       time.Time        `json:"start"`
+	End         time.Time        `json:"end"`
+	States      map[string]int64 `json:"states"`
+	State       string           `json:"state"`
+	Transitions int              `json:"transitions"`
+}
+
+// MonitoringGap is a period session states weren't polled
+type MonitoringGap struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+}
+
+// StateTimeline is a peer's session states over a range, for swimlane
+// charts. Bucket is in seconds.
+type StateTimeline struct {
+	PeerID      uint               `json:"peer_id"`
+	IPAddress   string             `json:"ip_address"`
+	Start       time.Time          `json:"start"`
+	End         time.Time          `json:"end"`
+	Bucket      int64              `json:"bucket"`
+	Transitions []*StateTransition `json:"transitions"`
+	Segments    []*StateSegment    `json:"segments"`
+	Buckets     []*StateBucket     `json:"buckets"`
+	Gaps        []*MonitoringGap   `json:"gaps"`
+}
+
+// PrefixAnomaly is a sudden change in the number of prefixes a peer sends
+type PrefixAnomaly struct {
+	PeerID       uint      `json:"peer_id"`
+	Name         string    `json:"name"`
+	IPAddress    string    `json:"ip_address"`
+	Kind         string    `json:"kind"` // route_leak_suspected, prefix_withdrawal
+	Baseline     int       `json:"baseline"`
+	Current      int       `json:"current"`
+	Delta        int       `json:"delta"`
+	DeltaPercent float64   `json:"delta_percent"`
+	Since        time.Time `json:"since"`
+}
+
+// AnomalyReport is the result of a prefix count analysis
+type AnomalyReport struct {
+	AnalyzedAt time.Time        `json:"analyzed_at"`
+	Window     string           `json:"window"`
+	Peers      int              `json:"peers"`
+	Anomalies  []*PrefixAnomaly `json:"anomalies"`
+}
+
+// PeerReachability is the outcome of a peer's last reachability probe
+// round, alongside its BGP session state
+type PeerReachability struct {
+	PeerID              uint    `json:"peer_id"`
+	Name                string  `json:"name"`
+	IPAddress           string  `json:"ip_address"`
+	Sent                int     `json:"sent"`
+	Received            int     `json:"received"`
+	LossPercent         float64 `json:"loss_percent"`
+	AvgMS               float64 `json:"avg_ms"`
+	Error               string  `json:"error,omitempty"`
+	ConsecutiveFailures int     `json:"consecutive_failures"`
+	SessionState        string  `json:"session_state"`
+	Diagnosis           string  `json:"diagnosis"` // ok, degraded, bgp_down, ip_unreachable, unknown
+}
+
+// ReachabilityReport is the result of a reachability probe round of every
+// enabled peer
+type ReachabilityReport struct {
+	ProbedAt    time.Time           `json:"probed_at"`
+	Method      string              `json:"method"` // icmp or tcp
+	Unreachable int                 `json:"unreachable"`
+	Peers       []*PeerReachability `json:"peers"`
+}
+
+// ReachabilitySample is a round of IP-layer probes of a peer's address
+type ReachabilitySample struct {
+	ID          uint      `json:"id"`
+	CreatedAt   time.Time `json:"created_at"`
+	PeerID      uint      `json:"peer_id"`
+	Method      string    `json:"method"`
+	Sent        int       `json:"sent"`
+	Received    int       `json:"received"`
+	LossPercent float64   `json:"loss_percent"`
+	MinMS       float64   `json:"min_ms"`
+	AvgMS       float64   `json:"avg_ms"`
+	MaxMS       float64   `json:"max_ms"`
+	Error       string    `json:"error,omitempty"`
+}
+
+// PeerReachabilityHistory is a peer's reachability samples over a window
+type PeerReachabilityHistory struct {
+	PeerID  uint                  `json:"peer_id"`
+	Window  string                `json:"window"`
+	Samples []*ReachabilitySample `json:"samples"`
+}
+
+// Leadership is a FlintRoute instance's view of which instance leads when
+// several share a database. Without HA, Enabled is false and the instance
+// always leads.
+type Leadership struct {
+	Enabled    bool       `json:"enabled"`
+	Identity   string     `json:"identity"`
+	Leader     bool       `json:"leader"`
+	Holder     string     `json:"holder,omitempty"`
+	AcquiredAt *time.Time `json:"acquired_at,omitempty"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	Elections  int        `json:"elections"`
+}
+
+// FRRStatus describes the FRR instance FlintRoute manages. Uptime is in
+// seconds; it and Capabilities are empty when the transport doesn't report
+// them.
+type FRRStatus struct {
+	Version      string            `json:"version,omitempty"`
+	Uptime       int64             `json:"uptime,omitempty"`
+	Daemons      []FRRDaemonStatus `json:"daemons"`
+	Capabilities []string          `json:"capabilities,omitempty"`
+}
+
+// FRRTransaction is a change FlintRoute pushed to FRR. CommitID and
+// ParentCommitID are the SHA-256 of the running configuration after and
+// before it, as config versions' hashes are.
+type FRRTransaction struct {
+	ID        uint      `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	// Operation is peer.add, peer.update, peer.remove, global.apply,
+	// policy.apply, policy.remove, or out_of_band for a change made
+	// outside FlintRoute
+	Operation string `json:"operation"`
+	Target    string `json:"target,omitempty"`
+	Config    string `json:"config,omitempty"`
+	// Delta is how the running configuration changed, as lines prefixed
+	// with "- " and "+ "
+	Delta           []string `json:"delta"`
+	CommitID        string   `json:"commit_id,omitempty"`
+	ParentCommitID  string   `json:"parent_commit_id,omitempty"`
+	Result          string   `json:"result"` // succeeded or failed
+	Error           string   `json:"error,omitempty"`
+	DurationMS      int64    `json:"duration_ms"`
+	PeerID          *uint    `json:"peer_id,omitempty"`
+	ConfigVersionID *uint    `json:"config_version_id,omitempty"`
+	UserID          *uint    `json:"user_id,omitempty"`
+	Username        string   `json:"username,omitempty"`
+	JobID           *uint    `json:"job_id,omitempty"`
+	RequestID       string   `json:"request_id,omitempty"`
+	TenantID        *uint    `json:"tenant_id,omitempty"`
+}
+
+// FRRTransactionQueryParams filters the FRR transactions listed; zero
+// values match any
+type FRRTransactionQueryParams struct {
+	PeerID          uint
+	ConfigVersionID uint
+	JobID           uint
+	User            string
+	Operation       string
+	Result          string
+	// Before continues from a previous page's NextBefore
+	Before uint
+	Limit  int
+}
+
+// FRRTransactionPage is a page of FRR transactions, newest first.
+// NextBefore is 0 on the last page.
+type FRRTransactionPage struct {
+	Transactions []*FRRTransaction `json:"transactions"`
+	NextBefore   uint              `json:"next_before,omitempty"`
+}
+
+// FRRDaemonStatus is whether an FRR daemon is running
+type FRRDaemonStatus struct {
+	Name    string `json:"name"`
+	Running bool   `json:"running"`
+}
+
+// Running reports whether the named daemon is running
+func (s *FRRStatus) Running(name string) bool {
+	for _, daemon := range s.Daemons {
+		if daemon.Name == name {
+			return daemon.Running
+		}
+	}
+	return false
+}
+
+// AtLeast reports whether FRR's version is minimum or newer, comparing
+// dotted versions numerically. An unknown version is never new enough.
+func (s *FRRStatus) AtLeast(minimum string) bool {
+	if s.Version == "" {
+		return false
+	}
+	have, want := versionParts(s.Version), versionParts(minimum)
+	for i := 0; i < len(have) || i < len(want); i++ {
+		var a, b int
+		if i < len(have) {
+			a = have[i]
+		}
+		if i < len(want) {
+			b = want[i]
+		}
+		if a != b {
+			return a > b
+		}
+	}
+	return true
+}
+
+// versionParts returns the numeric components of a version such as
+// "9.1-dev"
+func versionParts(version string) []int {
+	if i := strings.IndexAny(version, "-+ "); i >= 0 {
+		version = version[:i]
+	}
+	var parts []int
+	for _, field := range strings.Split(version, ".") {
+		n, err := strconv.Atoi(field)
+		if err != nil {
+			break
+		}
+		parts = append(parts, n)
+	}
+	return parts
+}
+
+// Job represents a background job such as a config restore, reconciliation
+// or GitOps sync. Result holds the operation's JSON result once it succeeds.
+type Job struct {
+	ID         uint            `json:"id"`
+	CreatedAt  time.Time       `json:"created_at"`
+	UpdatedAt  time.Time       `json:"updated_at"`
+	Type       string          `json:"type"`
+	Status     string          `json:"status"`
+	Progress   int             `json:"progress"`
+	Message    string          `json:"message,omitempty"`
+	Result     json.RawMessage `json:"result,omitempty"`
+	Error      string          `json:"error,omitempty"`
+	CreatedBy  *uint           `json:"created_by,omitempty"`
+	RequestID  string          `json:"request_id,omitempty"`
+	StartedAt  *time.Time      `json:"started_at,omitempty"`
+	FinishedAt *time.Time      `json:"finished_at,omitempty"`
+	// RunAt is when a scheduled job runs, and AnnouncedAt when it was
+	// announced ahead of running
+	RunAt       *time.Time `json:"run_at,omitempty"`
+	AnnouncedAt *time.Time `json:"announced_at,omitempty"`
+	// TenantID is the tenant the job was queued for, when tenancy is
+	// enabled
+	TenantID *uint `json:"tenant_id,omitempty"`
+}
+
+// Job statuses
+const (
+	JobQueued    = "queued"
+	JobRunning   = "running"
+	JobSucceeded = "succeeded"
+	JobFailed    = "failed"
+	JobCancelled = "cancelled"
+)
+
+// Done reports whether the job has finished, successfully or not, or was
+// cancelled before it ran
+func (j *Job) Done() bool {
+	return j.Status == JobSucceeded || j.Status == JobFailed || j.Status == JobCancelled
+}
+
+// ChangeRequest is a peer creation, peer deletion or config restore held
+// for approval by a second admin. Result holds the applied change's JSON
+// result: the created peer, the deleted peer or the queued restore job.
+type ChangeRequest struct {
+	ID          uint            `json:"id"`
+	CreatedAt   time.Time       `json:"created_at"`
+	UpdatedAt   time.Time       `json:"updated_at"`
+	Operation   string          `json:"operation"`
+	Status      string          `json:"status"`
+	Summary     string          `json:"summary"`
+	Payload     json.RawMessage `json:"payload"`
+	RequestedBy uint            `json:"requested_by"`
+	ReviewedBy  *uint           `json:"reviewed_by,omitempty"`
+	ReviewedAt  *time.Time      `json:"reviewed_at,omitempty"`
+	Comment     string          `json:"comment,omitempty"`
+	Result      json.RawMessage `json:"result,omitempty"`
+	Error       string          `json:"error,omitempty"`
+	// TenantID is the tenant the change was submitted for, when tenancy
+	// is enabled
+	TenantID *uint `json:"tenant_id,omitempty"`
+}
+
+// Change request statuses
+const (
+	ChangePending  = "pending"
+	ChangeRejected = "rejected"
+	ChangeApplied  = "applied"
+	ChangeFailed   = "failed"
+)
+
+// ReviewChangeRequest represents an approval or rejection of a change
+// request
+type ReviewChangeRequest struct {
+	Comment string `json:"comment,omitempty"`
+}
+
+// GitOpsChange is a single operation in a GitOps plan
+type GitOpsChange struct {
+	Action string   `json:"action"`
+	Kind   string   `json:"kind"`
+	Name   string   `json:"name"`
+	Fields []string `json:"fields,omitempty"`
+}
+
+// GitOpsPlan lists the changes needed to sync a repository commit
+type GitOpsPlan struct {
+	CommitSHA string          `json:"commit_sha"`
+	Changes   []*GitOpsChange `json:"changes"`
+	Conflicts []string        `json:"conflicts,omitempty"`
+}
+
+// GitOpsResult describes a GitOps sync
+type GitOpsResult struct {
+	CommitSHA string          `json:"commit_sha"`
+	Plan      *GitOpsPlan     `json:"plan"`
+	Applied   []*GitOpsChange `json:"applied"`
+	Version   *ConfigVersion  `json:"version,omitempty"`
+}
+
+// GitOpsStatus reports the outcome of the most recent GitOps sync
+type GitOpsStatus struct {
+	LastSyncAt *time.Time    `json:"last_sync_at,omitempty"`
+	CommitSHA  string        `json:"commit_sha,omitempty"`
+	LastError  string        `json:"last_error,omitempty"`
+	LastResult *GitOpsResult `json:"last_result,omitempty"`
+}
+
+// ConfigVersion represents a configuration backup
+type ConfigVersion struct {
+	ID          uint      `json:"id"`
+	CreatedAt   time.Time `json:"created_at"`
+	Description string    `json:"description"`
+	Config      string    `json:"config"`
+	Hash        string    `json:"hash"`
+	CreatedBy   uint      `json:"created_by"`
+	User        *UserInfo `json:"user,omitempty"`
+	CommitSHA   string    `json:"commit_sha,omitempty"`
+	// Size is the length of Config in bytes
+	Size int64 `json:"size"`
+	// Encoding and StorageKey describe how the server stores Config;
+	// Config itself is always returned as plain text
+	Encoding   string `json:"encoding,omitempty"`
+	StorageKey string `json:"storage_key,omitempty"`
+	// BGPGlobal is the BGP global configuration when the version was
+	// taken, restored along with it
+	BGPGlobal *BGPGlobal `json:"bgp_global,omitempty"`
+	Name      string     `json:"name,omitempty"`
+	Labels    []string   `json:"labels,omitempty"`
+	// Pinned versions are never deleted by retention pruning
+	Pinned bool `json:"pinned"`
+}
+
+// BackupConfigRequest represents a request to backup configuration
+type BackupConfigRequest struct {
+	Description string   `json:"description"`
+	Name        string   `json:"name,omitempty"`
+	Labels      []string `json:"labels,omitempty"`
+	Pinned      bool     `json:"pinned,omitempty"`
+}
+
+// ConfigVersionUpdate renames, relabels or pins a config version. Nil
+// fields are left as they are; an empty name or label list clears them.
+type ConfigVersionUpdate struct {
+	Name   *string   `json:"name,omitempty"`
+	Labels *[]string `json:"labels,omitempty"`
+	Pinned *bool     `json:"pinned,omitempty"`
+}
+
+// RestoreOptions overrides how a restore is verified. Unset fields default
+// to the server's config_versions.restore settings.
+type RestoreOptions struct {
+	// VerifyWindow is how long sessions are watched after the restore,
+	// such as "5m"; "0" skips verification
+	VerifyWindow    string `json:"verify_window,omitempty"`
+	MaxSessionsDown *int   `json:"max_sessions_down,omitempty"`
+}
+
+// RestoreVerification is how a restore is verified: if more than
+// MaxSessionsDown established sessions go down within Window, the restore
+// is rolled back
+type RestoreVerification struct {
+	Window          string `json:"window"`
+	MaxSessionsDown int    `json:"max_sessions_down"`
+}
+
+// RestorePeerCheck is a peer a restore adds, removes or reconfigures
+type RestorePeerCheck struct {
+	// Name is the neighbor's IP address
+	Name         string   `json:"name"`
+	Change       string   `json:"change"` // added, removed or changed
+	Lines        []string `json:"lines"`
+	PeerID       *uint    `json:"peer_id,omitempty"`
+	PeerName     string   `json:"peer_name,omitempty"`
+	SessionState string   `json:"session_state,omitempty"`
+}
+
+// RestorePreview shows what restoring a configuration version would change
+type RestorePreview struct {
+	VersionID   uint   `json:"version_id"`
+	Description string `json:"description"`
+	// Diff compares FRR's running configuration with the version's, as
+	// "- " and "+ " lines
+	Diff         []string            `json:"diff"`
+	Peers        []RestorePeerCheck  `json:"peers"`
+	BGPGlobal    bool                `json:"bgp_global"`
+	Verification RestoreVerification `json:"verification"`
+}
+
+// Restore outcomes
+const (
+	RestoreApplied    = "applied"
+	RestoreVerified   = "verified"
+	RestoreRolledBack = "rolled_back"
+)
+
+// RestoreStep is a logged step of a restore
+type RestoreStep struct {
+	At      time.Time `json:"at"`
+	Message string    `json:"message"`
+}
+
+// RestoreResult is the result of a restore
+type RestoreResult struct {
+	VersionID uint     `json:"version_id"`
+	Name      string   `json:"name"`
+	Labels    []string `json:"labels"`
+	Pinned    bool     `json:"pinned"`
+	// SnapshotID is the version holding the configuration from before the
+	// restore
+	SnapshotID   uint                `json:"snapshot_id,omitempty"`
+	Outcome      string              `json:"outcome"`
+	Verification RestoreVerification `json:"verification"`
+	SessionsDown []string            `json:"sessions_down,omitempty"`
+	Log          []RestoreStep       `json:"log"`
+}
+
+// ImportItem is an object of FRR's running configuration, imported or
+// skipped
+type ImportItem struct {
+	Kind   string `json:"kind"` // peer, route-map, prefix-list, community-list, as-path-list
+	Name   string `json:"name"`
+	Reason string `json:"reason,omitempty"`
+}
+
+// UnsupportedStatement is a statement of FRR's running configuration that
+// FlintRoute could not model. Line is 1-based.
+type UnsupportedStatement struct {
+	Line          int    `json:"line"`
+	AddressFamily string `json:"address_family,omitempty"`
+	Text          string `json:"text"`
+	Reason        string `json:"reason"`
+}
+
+// ImportReport is the result of importing FRR's running configuration
+type ImportReport struct {
+	DryRun     bool      `json:"dry_run"`
+	ImportedAt time.Time `json:"imported_at"`
+	ASN        uint32    `json:"asn"`
+	// PeerGroups were flattened into their members, which are tagged with
+	// "peer-group"
+	PeerGroups  []string                `json:"peer_groups"`
+	Imported    []*ImportItem           `json:"imported"`
+	Skipped     []*ImportItem           `json:"skipped"`
+	Unsupported []*UnsupportedStatement `json:"unsupported"`
+}
+
+// AdoptableNeighbor is a neighbor FRR runs that FlintRoute doesn't manage,
+// with the peer it would be stored as. Reason says why it can't be
+// adopted, if it can't.
+type AdoptableNeighbor struct {
+	IPAddress string `json:"ip_address"`
+	Peer      *Peer  `json:"peer"`
+	Reason    string `json:"reason,omitempty"`
+}
+
+// AdoptReport is the result of adopting FRR neighbors as peers.
+// Candidates are the neighbors FlintRoute still doesn't manage.
+type AdoptReport struct {
+	AdoptedAt  time.Time            `json:"adopted_at"`
+	ASN        uint32               `json:"asn"`
+	Adopted    []*Peer              `json:"adopted"`
+	Skipped    []*ImportItem        `json:"skipped"`
+	Candidates []*AdoptableNeighbor `json:"candidates"`
+}
+
+// StateChange is a single operation of a state import
+type StateChange struct {
+	Action string   `json:"action"`
+	Kind   string   `json:"kind"`
+	Name   string   `json:"name"`
+	Fields []string `json:"fields,omitempty"`
+}
+
+// StateCredential is a secret generated for a user or webhook created by a
+// state import. It is only returned once.
+type StateCredential struct {
+	Kind   string `json:"kind"`
+	Name   string `json:"name"`
+	Secret string `json:"secret"`
+}
+
+// StateImportResult is the result of importing a state document
+type StateImportResult struct {
+	DryRun      bool               `json:"dry_run"`
+	Changes     []*StateChange     `json:"changes"`
+	Conflicts   []string           `json:"conflicts,omitempty"`
+	Credentials []*StateCredential `json:"credentials,omitempty"`
+}
+
+// DatabaseSnapshot is a snapshot of FlintRoute's own database
+type DatabaseSnapshot struct {
+	ID            uint      `json:"id"`
+	CreatedAt     time.Time `json:"created_at"`
+	Key           string    `json:"key"`
+	Size          int64     `json:"size"`
+	Checksum      string    `json:"checksum"`
+	SchemaVersion string    `json:"schema_version"`
+	Note          string    `json:"note,omitempty"`
+	CreatedBy     string    `json:"created_by,omitempty"`
+}
+
+// CreateSnapshotRequest represents a request to snapshot the database
+type CreateSnapshotRequest struct {
+	Note string `json:"note"`
+}
+
+// RestoreSnapshotRequest represents a request to restore a database
+// snapshot
+type RestoreSnapshotRequest struct {
+	Confirm bool `json:"confirm"`
+}
+
+// RestoreSnapshotResult describes a completed database restore
+type RestoreSnapshotResult struct {
+	Snapshot *DatabaseSnapshot `json:"snapshot"`
+	// Backup is the snapshot taken right before restoring, to undo it
+	Backup     *DatabaseSnapshot `json:"backup"`
+	RestoredAt time.Time         `json:"restored_at"`
+	// Sync is the result of pushing the restored peers to FRR
+	Sync    *PeerSyncReport `json:"sync,omitempty"`
+	Warning string          `json:"warning,omitempty"`
+}
+
+// AuditEntry records an administrative action
+type AuditEntry struct {
+	ID        uint      `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	Username  string    `json:"username"`
+	Action    string    `json:"action"`
+	Target    string    `json:"target"`
+	Detail    string    `json:"detail,omitempty"`
+}
+
+// Activity feed item types
+const (
+	ActivityAudit         = "audit"
+	ActivityAlert         = "alert"
+	ActivitySession       = "session"
+	ActivityConfigVersion = "config_version"
+)
+
+// ActivityItem represents an audit entry, alert, session event or config
+// version in the activity feed. ID is the ID of the underlying record.
+type ActivityItem struct {
+	Type     string    `json:"type"`
+	ID       uint      `json:"id"`
+	Time     time.Time `json:"time"`
+	Summary  string    `json:"summary"`
+	Severity string    `json:"severity,omitempty"`
+	PeerID   *uint     `json:"peer_id,omitempty"`
+	Actor    string    `json:"actor,omitempty"`
+}
+
+// ActivityQueryParams represents query parameters for listing the activity
+// feed
+type ActivityQueryParams struct {
+	// Types limits the feed to these item types; audit entries are only
+	// listed for admins
+	Types []string `json:"types,omitempty"`
+	// Cursor continues from a previous page's NextCursor
+	Cursor string `json:"cursor,omitempty"`
+	Limit  int    `json:"limit,omitempty"`
+}
+
+// ActivityPage represents a page of the activity feed. NextCursor is empty
+// on the last page.
+type ActivityPage struct {
+	Items      []*ActivityItem `json:"items"`
+	NextCursor string          `json:"next_cursor,omitempty"`
+}
+
+// Alert represents a system alert
+type Alert struct {
+	ID             uint              `json:"id"`
+	CreatedAt      time.Time         `json:"created_at"`
+	UpdatedAt      time.Time         `json:"updated_at"`
+	Type           string            `json:"type"`
+	Severity       string            `json:"severity"`
+	Message        string            `json:"message"`
+	Details        string            `json:"details"`
+	PeerID         *uint             `json:"peer_id,omitempty"`
+	Peer           *Peer             `json:"peer,omitempty"`
+	Acknowledged   bool              `json:"acknowledged"`
+	AcknowledgedAt *time.Time        `json:"acknowledged_at,omitempty"`
+	AcknowledgedBy *uint             `json:"acknowledged_by,omitempty"`
+	User           *UserInfo         `json:"user,omitempty"`
+	Source         string            `json:"source"`
+	Fingerprint    string            `json:"fingerprint,omitempty"`
+	Labels         map[string]string `json:"labels,omitempty"`
+	ResolvedAt     *time.Time        `json:"resolved_at,omitempty"`
+	ArchivedAt     *time.Time        `json:"archived_at,omitempty"`
+	// Occurrences counts repeats of the alert for the same type and peer
+	Occurrences int       `json:"occurrences"`
+	FirstSeenAt time.Time `json:"first_seen_at"`
+	LastSeenAt  time.Time `json:"last_seen_at"`
+	// Suppressed counts occurrences not notified during an alert storm
+	Suppressed int `json:"suppressed,omitempty"`
+}
+
+// Alert sources
+const (
+	AlertSourceFlintRoute   = "flintroute"
+	AlertSourceAlertmanager = "alertmanager"
+)
+
+// Export formats of ExportAlerts and ExportSessions
+const (
+	ExportCSV    = "csv"
+	ExportNDJSON = "ndjson"
+)
+
+// AlertQueryParams represents query parameters for listing alerts
+type AlertQueryParams struct {
+	Acknowledged *bool  `json:"acknowledged,omitempty"`
+	Severity     string `json:"severity,omitempty"`
+	Source       string `json:"source,omitempty"`
+	// Archived lists only archived alerts when true; archived alerts are
+	// otherwise hidden
+	Archived *bool `json:"archived,omitempty"`
+	// Tags lists only alerts of peers matching every tag selector
+	Tags []string `json:"tags,omitempty"`
+}
+
+// PageParams selects a page of a listing paginated by cursor
+type PageParams struct {
+	// Cursor continues from a previous page's NextCursor
+	Cursor string `json:"cursor,omitempty"`
+	// Limit is the number of items per page; 0 uses the server's default
+	// of 100
+	Limit int `json:"limit,omitempty"`
+}
+
+// AcknowledgeAlertsRequest filters the alerts acknowledged by
+// AcknowledgeAllAlerts. Empty fields match every alert.
+type AcknowledgeAlertsRequest struct {
+	Severity string     `json:"severity,omitempty"`
+	Source   string     `json:"source,omitempty"`
+	Type     string     `json:"type,omitempty"`
+	PeerID   *uint      `json:"peer_id,omitempty"`
+	Before   *time.Time `json:"before,omitempty"`
+}
+
+// AlertSeverityCount counts alerts of one severity
+type AlertSeverityCount struct {
+	Total          int64 `json:"total"`
+	Unacknowledged int64 `json:"unacknowledged"`
+}
+
+// AlertSummary counts unarchived alerts by severity
+type AlertSummary struct {
+	Total          int64                         `json:"total"`
+	Unacknowledged int64                         `json:"unacknowledged"`
+	BySeverity     map[string]AlertSeverityCount `json:"by_severity"`
+}
+
+// AlertmanagerAlert is a FlintRoute alert in Alertmanager's alerts API
+// format
+type AlertmanagerAlert struct {
+	Labels       map[string]string `json:"labels"`
+	Annotations  map[string]string `json:"annotations,omitempty"`
+	StartsAt     time.Time         `json:"startsAt"`
+	EndsAt       *time.Time        `json:"endsAt,omitempty"`
+	GeneratorURL string            `json:"generatorURL,omitempty"`
+}
+
+// Webhook represents a webhook subscription
+type Webhook struct {
+	ID          uint      `json:"id"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+	URL         string    `json:"url"`
+	Description string    `json:"description"`
+	Events      []string  `json:"events"`
+	Active      bool      `json:"active"`
+	CreatedBy   uint      `json:"created_by"`
+	// Secret is only returned when the webhook is created
+	Secret string `json:"secret,omitempty"`
+}
+
+// WebhookRequest represents a request to create or update a webhook
+// subscription. A nil Secret is generated on create and left unchanged on
+// update.
+type WebhookRequest struct {
+	URL         string   `json:"url"`
+	Description string   `json:"description,omitempty"`
+	Events      []string `json:"events"`
+	Active      *bool    `json:"active,omitempty"`
+	Secret      *string  `json:"secret,omitempty"`
+}
+
+// WebhookDelivery represents a single delivery of an event to a webhook
+type WebhookDelivery struct {
+	ID             uint       `json:"id"`
+	CreatedAt      time.Time  `json:"created_at"`
+	SubscriptionID uint       `json:"subscription_id"`
+	EventID        string     `json:"event_id"`
+	Event          string     `json:"event"`
+	Payload        string     `json:"payload"`
+	Status         string     `json:"status"`
+	Attempts       int        `json:"attempts"`
+	ResponseStatus int        `json:"response_status"`
+	LastError      string     `json:"last_error,omitempty"`
+	NextAttemptAt  *time.Time `json:"next_attempt_at,omitempty"`
+	DeliveredAt    *time.Time `json:"delivered_at,omitempty"`
+}
+
+// WebhookEvent is the JSON body FlintRoute delivers to webhook endpoints
+type WebhookEvent struct {
+	ID        string          `json:"id"`
+	Type      string          `json:"type"`
+	CreatedAt time.Time       `json:"created_at"`
+	RequestID string          `json:"request_id,omitempty"`
+	Data      json.RawMessage `json:"data"`
+}
+
+// ErrorResponse represents an API error response
+type ErrorResponse struct {
+	Error     string       `json:"error"`
+	Code      ErrorCode    `json:"code"`
+	Errors    []FieldError `json:"errors,omitempty"`
+	Details   []FieldError `json:"details,omitempty"` // sent by servers without Errors
+	RequestID string       `json:"request_id,omitempty"`
+	FRR       *FRRDetail   `json:"frr,omitempty"`
+}
+
+// FRRDetail locates configuration FRR rejected and keeps its raw output
+type FRRDetail struct {
+	// Command is the rejected statement, when FRR said which
+	Command string `json:"command,omitempty"`
+	// Line is the rejected statement's line in the checked configuration
+	Line int `json:"line,omitempty"`
+	// Path is the northbound data path of the rejected node
+	Path string `json:"path,omitempty"`
+	Raw  string `json:"raw"`
+}
+
+// FieldError describes a problem with a single request field
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// PeerPasswordRequest represents a request to rotate a peer's password
+type PeerPasswordRequest struct {
+	Password string `json:"password"`
+}
+
+// MaintenanceRequest represents a request to put a peer under maintenance.
+// A nil Until keeps the peer under maintenance until it is ended.
+type MaintenanceRequest struct {
+	Until *time.Time `json:"until,omitempty"`
+}
+
+// DemoStateRequest represents a request to pin a peer's simulated session
+// state in demo mode
+type DemoStateRequest struct {
+	State     string `json:"state"`
+	LastError string `json:"last_error,omitempty"`
+}
+
+// Peer actions that can be scheduled
+const (
+	PeerActionEnable   = "enable"
+	PeerActionShutdown = "shutdown"
+)
+
+// SchedulePeerActionRequest represents a request to enable or shut down a
+// peer at RunAt
+type SchedulePeerActionRequest struct {
+	Action string    `json:"action"`
+	RunAt  time.Time `json:"run_at"`
+}
+
+// ScheduledPeerAction represents a scheduled peer action and the job that
+// runs it. Its ID is the job's ID.
+type ScheduledPeerAction struct {
+	Job
+	PeerID uint   `json:"peer_id"`
+	Action string `json:"action"`
+}
+
+// ScheduledPeerActionsResponse represents the response from listing
+// scheduled peer actions
+type ScheduledPeerActionsResponse struct {
+	Actions []*ScheduledPeerAction `json:"actions"`
+}
+
+// MessageResponse represents a simple message response
+type MessageResponse struct {
+	Message string `json:"message"`
+}
+
+// PeersResponse represents a list of peers response
+type PeersResponse struct {
+	Peers []*Peer `json:"peers"`
+}
+
+// PeerSearchParams represents query parameters for searching peers
+type PeerSearchParams struct {
+	// Query is a search such as
+	// asn:65010 state:Established tag:pop=ams name~"transit"
+	Query  string `json:"q,omitempty"`
+	Limit  int    `json:"limit,omitempty"`
+	Offset int    `json:"offset,omitempty"`
+}
+
+// FacetCount is how many peers matching a search have a value
+type FacetCount struct {
+	Value string `json:"value"`
+	Count int64  `json:"count"`
+}
+
+// PeerFacets count the peers matching a search by session state, remote
+// AS, tag ("key=value"), enabled and sync status, most common first
+type PeerFacets struct {
+	State      []FacetCount `json:"state"`
+	ASN        []FacetCount `json:"asn"`
+	Tag        []FacetCount `json:"tag"`
+	Enabled    []FacetCount `json:"enabled"`
+	SyncStatus []FacetCount `json:"sync_status"`
+}
+
+// PeerSearchResult is a page of the peers matching a search, with the
+// total and facets of every match
+type PeerSearchResult struct {
+	Query  string     `json:"query"`
+	Limit  int        `json:"limit"`
+	Offset int        `json:"offset"`
+	Total  int64      `json:"total"`
+	Peers  []*Peer    `json:"peers"`
+	Facets PeerFacets `json:"facets"`
+}
+
+// SessionsResponse represents a list of sessions response
+type SessionsResponse struct {
+	Sessions []*Session `json:"sessions"`
+}
+
+// ConfigVersionsResponse represents a list of config versions response
+type ConfigVersionsResponse struct {
+	Versions []*ConfigVersion `json:"versions"`
+}
+
+// SnapshotsResponse represents a list of database snapshots response
+type SnapshotsResponse struct {
+	Snapshots []*DatabaseSnapshot `json:"snapshots"`
+}
+
+// AuditEntriesResponse represents a page of audit entries response.
+// NextCursor is empty on the last page.
+type AuditEntriesResponse struct {
+	Entries    []*AuditEntry `json:"entries"`
+	NextCursor string        `json:"next_cursor,omitempty"`
+}
+
+// AlertsResponse represents a list of alerts response. NextCursor is set
+// when a page was requested and more alerts follow.
+type AlertsResponse struct {
+	Alerts     []*Alert `json:"alerts"`
+	NextCursor string   `json:"next_cursor,omitempty"`
+}
+
+// CommunityListsResponse represents a list of community-lists response
+type CommunityListsResponse struct {
+	CommunityLists []*CommunityList `json:"community_lists"`
+}
+
+// ASPathListsResponse represents a list of as-path access-lists response
+type ASPathListsResponse struct {
+	ASPathLists []*ASPathList `json:"as_path_lists"`
+}
+
+// WebhooksResponse represents a list of webhooks response
+type WebhooksResponse struct {
+	Webhooks []*Webhook `json:"webhooks"`
+}
+
+// WebhookDeliveriesResponse represents a list of webhook deliveries response
+type WebhookDeliveriesResponse struct {
+	Deliveries []*WebhookDelivery `json:"deliveries"`
+}
@@ -0,0 +1,33 @@
+package client
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+)
+
+// Headers FlintRoute sets on webhook deliveries
+const (
+	WebhookEventHeader     = "X-FlintRoute-Event"
+	WebhookDeliveryHeader  = "X-FlintRoute-Delivery"
+	WebhookSignatureHeader = "X-FlintRoute-Signature-256"
+)
+
+// VerifyWebhookSignature reports whether signature, the value of the
+// X-FlintRoute-Signature-256 header, is the HMAC-SHA256 of body under the
+// webhook's secret. Receivers should verify the raw request body before
+// decoding it.
+func VerifyWebhookSignature(secret string, body []byte, signature string) bool {
+	if secret == "" || !strings.HasPrefix(signature, "sha256=") {
+		return false
+	}
+
+	expected, err := hex.DecodeString(strings.TrimPrefix(signature, "sha256="))
+	if err != nil {
+		return false
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hmac.Equal(mac.Sum(nil), expected)
+}
@@ -0,0 +1,2705 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.10
+// 	protoc        (unknown)
+// source: flintroute/v1/flintroute.proto
+
+package flintroutepb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// Peer is a BGP peer. Its password is never returned; has_password says
+// whether it has one.
+type Peer struct {
+	state                      protoimpl.MessageState `protogen:"open.v1"`
+	Id                         uint32                 `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	Name                       string                 `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	IpAddress                  string                 `protobuf:"bytes,3,opt,name=ip_address,json=ipAddress,proto3" json:"ip_address,omitempty"`
+	Asn                        uint32                 `protobuf:"varint,4,opt,name=asn,proto3" json:"asn,omitempty"`
+	RemoteAsn                  uint32                 `protobuf:"varint,5,opt,name=remote_asn,json=remoteAsn,proto3" json:"remote_asn,omitempty"`
+	Description                string                 `protobuf:"bytes,6,opt,name=description,proto3" json:"description,omitempty"`
+	Enabled                    bool                   `protobuf:"varint,7,opt,name=enabled,proto3" json:"enabled,omitempty"`
+	HasPassword                bool                   `protobuf:"varint,8,opt,name=has_password,json=hasPassword,proto3" json:"has_password,omitempty"`
+	Multihop                   int32                  `protobuf:"varint,9,opt,name=multihop,proto3" json:"multihop,omitempty"`
+	UpdateSource               string                 `protobuf:"bytes,10,opt,name=update_source,json=updateSource,proto3" json:"update_source,omitempty"`
+	RouteMapIn                 string                 `protobuf:"bytes,11,opt,name=route_map_in,json=routeMapIn,proto3" json:"route_map_in,omitempty"`
+	RouteMapOut                string                 `protobuf:"bytes,12,opt,name=route_map_out,json=routeMapOut,proto3" json:"route_map_out,omitempty"`
+	PrefixListIn               string                 `protobuf:"bytes,13,opt,name=prefix_list_in,json=prefixListIn,proto3" json:"prefix_list_in,omitempty"`
+	PrefixListOut              string                 `protobuf:"bytes,14,opt,name=prefix_list_out,json=prefixListOut,proto3" json:"prefix_list_out,omitempty"`
+	MaxPrefixes                int32                  `protobuf:"varint,15,opt,name=max_prefixes,json=maxPrefixes,proto3" json:"max_prefixes,omitempty"`
+	MaxPrefixThreshold         int32                  `protobuf:"varint,16,opt,name=max_prefix_threshold,json=maxPrefixThreshold,proto3" json:"max_prefix_threshold,omitempty"`
+	MaxPrefixAction            string                 `protobuf:"bytes,17,opt,name=max_prefix_action,json=maxPrefixAction,proto3" json:"max_prefix_action,omitempty"`
+	MaxPrefixRestart           int32                  `protobuf:"varint,18,opt,name=max_prefix_restart,json=maxPrefixRestart,proto3" json:"max_prefix_restart,omitempty"`
+	LocalPreference            int32                  `protobuf:"varint,19,opt,name=local_preference,json=localPreference,proto3" json:"local_preference,omitempty"`
+	Keepalive                  int32                  `protobuf:"varint,20,opt,name=keepalive,proto3" json:"keepalive,omitempty"`
+	Holdtime                   int32                  `protobuf:"varint,21,opt,name=holdtime,proto3" json:"holdtime,omitempty"`
+	ConnectRetry               int32                  `protobuf:"varint,22,opt,name=connect_retry,json=connectRetry,proto3" json:"connect_retry,omitempty"`
+	Passive                    bool                   `protobuf:"varint,23,opt,name=passive,proto3" json:"passive,omitempty"`
+	TtlSecurityHops            int32                  `protobuf:"varint,24,opt,name=ttl_security_hops,json=ttlSecurityHops,proto3" json:"ttl_security_hops,omitempty"`
+	NextHopSelf                bool                   `protobuf:"varint,25,opt,name=next_hop_self,json=nextHopSelf,proto3" json:"next_hop_self,omitempty"`
+	SoftReconfigurationInbound bool                   `protobuf:"varint,26,opt,name=soft_reconfiguration_inbound,json=softReconfigurationInbound,proto3" json:"soft_reconfiguration_inbound,omitempty"`
+	RemovePrivateAs            bool                   `protobuf:"varint,27,opt,name=remove_private_as,json=removePrivateAs,proto3" json:"remove_private_as,omitempty"`
+	AllowasIn                  int32                  `protobuf:"varint,28,opt,name=allowas_in,json=allowasIn,proto3" json:"allowas_in,omitempty"`
+	LocalAs                    uint32                 `protobuf:"varint,29,opt,name=local_as,json=localAs,proto3" json:"local_as,omitempty"`
+	LocalAsMode                string                 `protobuf:"bytes,30,opt,name=local_as_mode,json=localAsMode,proto3" json:"local_as_mode,omitempty"`
+	Tags                       map[string]string      `protobuf:"bytes,31,rep,name=tags,proto3" json:"tags,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	// sync_status is "synced", or "pending" while FRR has yet to apply the
+	// peer
+	SyncStatus       string                 `protobuf:"bytes,32,opt,name=sync_status,json=syncStatus,proto3" json:"sync_status,omitempty"`
+	SyncError        string                 `protobuf:"bytes,33,opt,name=sync_error,json=syncError,proto3" json:"sync_error,omitempty"`
+	ManagedBy        string                 `protobuf:"bytes,34,opt,name=managed_by,json=managedBy,proto3" json:"managed_by,omitempty"`
+	TemplateId       *uint32                `protobuf:"varint,35,opt,name=template_id,json=templateId,proto3,oneof" json:"template_id,omitempty"`
+	TenantId         *uint32                `protobuf:"varint,36,opt,name=tenant_id,json=tenantId,proto3,oneof" json:"tenant_id,omitempty"`
+	MaintenanceSince *timestamppb.Timestamp `protobuf:"bytes,37,opt,name=maintenance_since,json=maintenanceSince,proto3" json:"maintenance_since,omitempty"`
+	MaintenanceUntil *timestamppb.Timestamp `protobuf:"bytes,38,opt,name=maintenance_until,json=maintenanceUntil,proto3" json:"maintenance_until,omitempty"`
+	CreatedAt        *timestamppb.Timestamp `protobuf:"bytes,39,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
+	UpdatedAt        *timestamppb.Timestamp `protobuf:"bytes,40,opt,name=updated_at,json=updatedAt,proto3" json:"updated_at,omitempty"`
+	unknownFields    protoimpl.UnknownFields
+	sizeCache        protoimpl.SizeCache
+}
+
+func (x *Peer) Reset() {
+	*x = Peer{}
+	mi := &file_flintroute_v1_flintroute_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Peer) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Peer) ProtoMessage() {}
+
+func (x *Peer) ProtoReflect() protoreflect.Message {
+	mi := &file_flintroute_v1_flintroute_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Peer.ProtoReflect.Descriptor instead.
+func (*Peer) Descriptor() ([]byte, []int) {
+	return file_flintroute_v1_flintroute_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *Peer) GetId() uint32 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *Peer) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *Peer) GetIpAddress() string {
+	if x != nil {
+		return x.IpAddress
+	}
+	return ""
+}
+
+func (x *Peer) GetAsn() uint32 {
+	if x != nil {
+		return x.Asn
+	}
+	return 0
+}
+
+func (x *Peer) GetRemoteAsn() uint32 {
+	if x != nil {
+		return x.RemoteAsn
+	}
+	return 0
+}
+
+func (x *Peer) GetDescription() string {
+	if x != nil {
+		return x.Description
+	}
+	return ""
+}
+
+func (x *Peer) GetEnabled() bool {
+	if x != nil {
+		return x.Enabled
+	}
+	return false
+}
+
+func (x *Peer) GetHasPassword() bool {
+	if x != nil {
+		return x.HasPassword
+	}
+	return false
+}
+
+func (x *Peer) GetMultihop() int32 {
+	if x != nil {
+		return x.Multihop
+	}
+	return 0
+}
+
+func (x *Peer) GetUpdateSource() string {
+	if x != nil {
+		return x.UpdateSource
+	}
+	return ""
+}
+
+func (x *Peer) GetRouteMapIn() string {
+	if x != nil {
+		return x.RouteMapIn
+	}
+	return ""
+}
+
+func (x *Peer) GetRouteMapOut() string {
+	if x != nil {
+		return x.RouteMapOut
+	}
+	return ""
+}
+
+func (x *Peer) GetPrefixListIn() string {
+	if x != nil {
+		return x.PrefixListIn
+	}
+	return ""
+}
+
+func (x *Peer) GetPrefixListOut() string {
+	if x != nil {
+		return x.PrefixListOut
+	}
+	return ""
+}
+
+func (x *Peer) GetMaxPrefixes() int32 {
+	if x != nil {
+		return x.MaxPrefixes
+	}
+	return 0
+}
+
+func (x *Peer) GetMaxPrefixThreshold() int32 {
+	if x != nil {
+		return x.MaxPrefixThreshold
+	}
+	return 0
+}
+
+func (x *Peer) GetMaxPrefixAction() string {
+	if x != nil {
+		return x.MaxPrefixAction
+	}
+	return ""
+}
+
+func (x *Peer) GetMaxPrefixRestart() int32 {
+	if x != nil {
+		return x.MaxPrefixRestart
+	}
+	return 0
+}
+
+func (x *Peer) GetLocalPreference() int32 {
+	if x != nil {
+		return x.LocalPreference
+	}
+	return 0
+}
+
+func (x *Peer) GetKeepalive() int32 {
+	if x != nil {
+		return x.Keepalive
+	}
+	return 0
+}
+
+func (x *Peer) GetHoldtime() int32 {
+	if x != nil {
+		return x.Holdtime
+	}
+	return 0
+}
+
+func (x *Peer) GetConnectRetry() int32 {
+	if x != nil {
+		return x.ConnectRetry
+	}
+	return 0
+}
+
+func (x *Peer) GetPassive() bool {
+	if x != nil {
+		return x.Passive
+	}
+	return false
+}
+
+func (x *Peer) GetTtlSecurityHops() int32 {
+	if x != nil {
+		return x.TtlSecurityHops
+	}
+	return 0
+}
+
+func (x *Peer) GetNextHopSelf() bool {
+	if x != nil {
+		return x.NextHopSelf
+	}
+	return false
+}
+
+func (x *Peer) GetSoftReconfigurationInbound() bool {
+	if x != nil {
+		return x.SoftReconfigurationInbound
+	}
+	return false
+}
+
+func (x *Peer) GetRemovePrivateAs() bool {
+	if x != nil {
+		return x.RemovePrivateAs
+	}
+	return false
+}
+
+func (x *Peer) GetAllowasIn() int32 {
+	if x != nil {
+		return x.AllowasIn
+	}
+	return 0
+}
+
+func (x *Peer) GetLocalAs() uint32 {
+	if x != nil {
+		return x.LocalAs
+	}
+	return 0
+}
+
+func (x *Peer) GetLocalAsMode() string {
+	if x != nil {
+		return x.LocalAsMode
+	}
+	return ""
+}
+
+func (x *Peer) GetTags() map[string]string {
+	if x != nil {
+		return x.Tags
+	}
+	return nil
+}
+
+func (x *Peer) GetSyncStatus() string {
+	if x != nil {
+		return x.SyncStatus
+	}
+	return ""
+}
+
+func (x *Peer) GetSyncError() string {
+	if x != nil {
+		return x.SyncError
+	}
+	return ""
+}
+
+func (x *Peer) GetManagedBy() string {
+	if x != nil {
+		return x.ManagedBy
+	}
+	return ""
+}
+
+func (x *Peer) GetTemplateId() uint32 {
+	if x != nil && x.TemplateId != nil {
+		return *x.TemplateId
+	}
+	return 0
+}
+
+func (x *Peer) GetTenantId() uint32 {
+	if x != nil && x.TenantId != nil {
+		return *x.TenantId
+	}
+	return 0
+}
+
+func (x *Peer) GetMaintenanceSince() *timestamppb.Timestamp {
+	if x != nil {
+		return x.MaintenanceSince
+	}
+	return nil
+}
+
+func (x *Peer) GetMaintenanceUntil() *timestamppb.Timestamp {
+	if x != nil {
+		return x.MaintenanceUntil
+	}
+	return nil
+}
+
+func (x *Peer) GetCreatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return nil
+}
+
+func (x *Peer) GetUpdatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.UpdatedAt
+	}
+	return nil
+}
+
+type ListPeersRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// tags are selectors such as "pop:fra1", or "pop" for any value
+	Tags          []string `protobuf:"bytes,1,rep,name=tags,proto3" json:"tags,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListPeersRequest) Reset() {
+	*x = ListPeersRequest{}
+	mi := &file_flintroute_v1_flintroute_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListPeersRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListPeersRequest) ProtoMessage() {}
+
+func (x *ListPeersRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_flintroute_v1_flintroute_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListPeersRequest.ProtoReflect.Descriptor instead.
+func (*ListPeersRequest) Descriptor() ([]byte, []int) {
+	return file_flintroute_v1_flintroute_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *ListPeersRequest) GetTags() []string {
+	if x != nil {
+		return x.Tags
+	}
+	return nil
+}
+
+type ListPeersResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Peers         []*Peer                `protobuf:"bytes,1,rep,name=peers,proto3" json:"peers,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListPeersResponse) Reset() {
+	*x = ListPeersResponse{}
+	mi := &file_flintroute_v1_flintroute_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListPeersResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListPeersResponse) ProtoMessage() {}
+
+func (x *ListPeersResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_flintroute_v1_flintroute_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListPeersResponse.ProtoReflect.Descriptor instead.
+func (*ListPeersResponse) Descriptor() ([]byte, []int) {
+	return file_flintroute_v1_flintroute_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *ListPeersResponse) GetPeers() []*Peer {
+	if x != nil {
+		return x.Peers
+	}
+	return nil
+}
+
+type GetPeerRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            uint32                 `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetPeerRequest) Reset() {
+	*x = GetPeerRequest{}
+	mi := &file_flintroute_v1_flintroute_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetPeerRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetPeerRequest) ProtoMessage() {}
+
+func (x *GetPeerRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_flintroute_v1_flintroute_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetPeerRequest.ProtoReflect.Descriptor instead.
+func (*GetPeerRequest) Descriptor() ([]byte, []int) {
+	return file_flintroute_v1_flintroute_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *GetPeerRequest) GetId() uint32 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+type CreatePeerRequest struct {
+	state      protoimpl.MessageState `protogen:"open.v1"`
+	Name       string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	IpAddress  string                 `protobuf:"bytes,2,opt,name=ip_address,json=ipAddress,proto3" json:"ip_address,omitempty"`
+	TemplateId *uint32                `protobuf:"varint,3,opt,name=template_id,json=templateId,proto3,oneof" json:"template_id,omitempty"`
+	// asn defaults to the router's
+	Asn                        uint32            `protobuf:"varint,4,opt,name=asn,proto3" json:"asn,omitempty"`
+	RemoteAsn                  uint32            `protobuf:"varint,5,opt,name=remote_asn,json=remoteAsn,proto3" json:"remote_asn,omitempty"`
+	Description                string            `protobuf:"bytes,6,opt,name=description,proto3" json:"description,omitempty"`
+	Enabled                    bool              `protobuf:"varint,7,opt,name=enabled,proto3" json:"enabled,omitempty"`
+	Password                   string            `protobuf:"bytes,8,opt,name=password,proto3" json:"password,omitempty"`
+	Multihop                   int32             `protobuf:"varint,9,opt,name=multihop,proto3" json:"multihop,omitempty"`
+	UpdateSource               string            `protobuf:"bytes,10,opt,name=update_source,json=updateSource,proto3" json:"update_source,omitempty"`
+	RouteMapIn                 string            `protobuf:"bytes,11,opt,name=route_map_in,json=routeMapIn,proto3" json:"route_map_in,omitempty"`
+	RouteMapOut                string            `protobuf:"bytes,12,opt,name=route_map_out,json=routeMapOut,proto3" json:"route_map_out,omitempty"`
+	PrefixListIn               string            `protobuf:"bytes,13,opt,name=prefix_list_in,json=prefixListIn,proto3" json:"prefix_list_in,omitempty"`
+	PrefixListOut              string            `protobuf:"bytes,14,opt,name=prefix_list_out,json=prefixListOut,proto3" json:"prefix_list_out,omitempty"`
+	MaxPrefixes                int32             `protobuf:"varint,15,opt,name=max_prefixes,json=maxPrefixes,proto3" json:"max_prefixes,omitempty"`
+	MaxPrefixThreshold         int32             `protobuf:"varint,16,opt,name=max_prefix_threshold,json=maxPrefixThreshold,proto3" json:"max_prefix_threshold,omitempty"`
+	MaxPrefixAction            string            `protobuf:"bytes,17,opt,name=max_prefix_action,json=maxPrefixAction,proto3" json:"max_prefix_action,omitempty"`
+	MaxPrefixRestart           int32             `protobuf:"varint,18,opt,name=max_prefix_restart,json=maxPrefixRestart,proto3" json:"max_prefix_restart,omitempty"`
+	LocalPreference            int32             `protobuf:"varint,19,opt,name=local_preference,json=localPreference,proto3" json:"local_preference,omitempty"`
+	Keepalive                  int32             `protobuf:"varint,20,opt,name=keepalive,proto3" json:"keepalive,omitempty"`
+	Holdtime                   int32             `protobuf:"varint,21,opt,name=holdtime,proto3" json:"holdtime,omitempty"`
+	ConnectRetry               int32             `protobuf:"varint,22,opt,name=connect_retry,json=connectRetry,proto3" json:"connect_retry,omitempty"`
+	Passive                    bool              `protobuf:"varint,23,opt,name=passive,proto3" json:"passive,omitempty"`
+	TtlSecurityHops            int32             `protobuf:"varint,24,opt,name=ttl_security_hops,json=ttlSecurityHops,proto3" json:"ttl_security_hops,omitempty"`
+	NextHopSelf                bool              `protobuf:"varint,25,opt,name=next_hop_self,json=nextHopSelf,proto3" json:"next_hop_self,omitempty"`
+	SoftReconfigurationInbound bool              `protobuf:"varint,26,opt,name=soft_reconfiguration_inbound,json=softReconfigurationInbound,proto3" json:"soft_reconfiguration_inbound,omitempty"`
+	RemovePrivateAs            bool              `protobuf:"varint,27,opt,name=remove_private_as,json=removePrivateAs,proto3" json:"remove_private_as,omitempty"`
+	AllowasIn                  int32             `protobuf:"varint,28,opt,name=allowas_in,json=allowasIn,proto3" json:"allowas_in,omitempty"`
+	LocalAs                    uint32            `protobuf:"varint,29,opt,name=local_as,json=localAs,proto3" json:"local_as,omitempty"`
+	LocalAsMode                string            `protobuf:"bytes,30,opt,name=local_as_mode,json=localAsMode,proto3" json:"local_as_mode,omitempty"`
+	Tags                       map[string]string `protobuf:"bytes,31,rep,name=tags,proto3" json:"tags,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	unknownFields              protoimpl.UnknownFields
+	sizeCache                  protoimpl.SizeCache
+}
+
+func (x *CreatePeerRequest) Reset() {
+	*x = CreatePeerRequest{}
+	mi := &file_flintroute_v1_flintroute_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CreatePeerRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CreatePeerRequest) ProtoMessage() {}
+
+func (x *CreatePeerRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_flintroute_v1_flintroute_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CreatePeerRequest.ProtoReflect.Descriptor instead.
+func (*CreatePeerRequest) Descriptor() ([]byte, []int) {
+	return file_flintroute_v1_flintroute_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *CreatePeerRequest) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *CreatePeerRequest) GetIpAddress() string {
+	if x != nil {
+		return x.IpAddress
+	}
+	return ""
+}
+
+func (x *CreatePeerRequest) GetTemplateId() uint32 {
+	if x != nil && x.TemplateId != nil {
+		return *x.TemplateId
+	}
+	return 0
+}
+
+func (x *CreatePeerRequest) GetAsn() uint32 {
+	if x != nil {
+		return x.Asn
+	}
+	return 0
+}
+
+func (x *CreatePeerRequest) GetRemoteAsn() uint32 {
+	if x != nil {
+		return x.RemoteAsn
+	}
+	return 0
+}
+
+func (x *CreatePeerRequest) GetDescription() string {
+	if x != nil {
+		return x.Description
+	}
+	return ""
+}
+
+func (x *CreatePeerRequest) GetEnabled() bool {
+	if x != nil {
+		return x.Enabled
+	}
+	return false
+}
+
+func (x *CreatePeerRequest) GetPassword() string {
+	if x != nil {
+		return x.Password
+	}
+	return ""
+}
+
+func (x *CreatePeerRequest) GetMultihop() int32 {
+	if x != nil {
+		return x.Multihop
+	}
+	return 0
+}
+
+func (x *CreatePeerRequest) GetUpdateSource() string {
+	if x != nil {
+		return x.UpdateSource
+	}
+	return ""
+}
+
+func (x *CreatePeerRequest) GetRouteMapIn() string {
+	if x != nil {
+		return x.RouteMapIn
+	}
+	return ""
+}
+
+func (x *CreatePeerRequest) GetRouteMapOut() string {
+	if x != nil {
+		return x.RouteMapOut
+	}
+	return ""
+}
+
+func (x *CreatePeerRequest) GetPrefixListIn() string {
+	if x != nil {
+		return x.PrefixListIn
+	}
+	return ""
+}
+
+func (x *CreatePeerRequest) GetPrefixListOut() string {
+	if x != nil {
+		return x.PrefixListOut
+	}
+	return ""
+}
+
+func (x *CreatePeerRequest) GetMaxPrefixes() int32 {
+	if x != nil {
+		return x.MaxPrefixes
+	}
+	return 0
+}
+
+func (x *CreatePeerRequest) GetMaxPrefixThreshold() int32 {
+	if x != nil {
+		return x.MaxPrefixThreshold
+	}
+	return 0
+}
+
+func (x *CreatePeerRequest) GetMaxPrefixAction() string {
+	if x != nil {
+		return x.MaxPrefixAction
+	}
+	return ""
+}
+
+func (x *CreatePeerRequest) GetMaxPrefixRestart() int32 {
+	if x != nil {
+		return x.MaxPrefixRestart
+	}
+	return 0
+}
+
+func (x *CreatePeerRequest) GetLocalPreference() int32 {
+	if x != nil {
+		return x.LocalPreference
+	}
+	return 0
+}
+
+func (x *CreatePeerRequest) GetKeepalive() int32 {
+	if x != nil {
+		return x.Keepalive
+	}
+	return 0
+}
+
+func (x *CreatePeerRequest) GetHoldtime() int32 {
+	if x != nil {
+		return x.Holdtime
+	}
+	return 0
+}
+
+func (x *CreatePeerRequest) GetConnectRetry() int32 {
+	if x != nil {
+		return x.ConnectRetry
+	}
+	return 0
+}
+
+func (x *CreatePeerRequest) GetPassive() bool {
+	if x != nil {
+		return x.Passive
+	}
+	return false
+}
+
+func (x *CreatePeerRequest) GetTtlSecurityHops() int32 {
+	if x != nil {
+		return x.TtlSecurityHops
+	}
+	return 0
+}
+
+func (x *CreatePeerRequest) GetNextHopSelf() bool {
+	if x != nil {
+		return x.NextHopSelf
+	}
+	return false
+}
+
+func (x *CreatePeerRequest) GetSoftReconfigurationInbound() bool {
+	if x != nil {
+		return x.SoftReconfigurationInbound
+	}
+	return false
+}
+
+func (x *CreatePeerRequest) GetRemovePrivateAs() bool {
+	if x != nil {
+		return x.RemovePrivateAs
+	}
+	return false
+}
+
+func (x *CreatePeerRequest) GetAllowasIn() int32 {
+	if x != nil {
+		return x.AllowasIn
+	}
+	return 0
+}
+
+func (x *CreatePeerRequest) GetLocalAs() uint32 {
+	if x != nil {
+		return x.LocalAs
+	}
+	return 0
+}
+
+func (x *CreatePeerRequest) GetLocalAsMode() string {
+	if x != nil {
+		return x.LocalAsMode
+	}
+	return ""
+}
+
+func (x *CreatePeerRequest) GetTags() map[string]string {
+	if x != nil {
+		return x.Tags
+	}
+	return nil
+}
+
+type UpdatePeerRequest struct {
+	state                      protoimpl.MessageState `protogen:"open.v1"`
+	Id                         uint32                 `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	Name                       *string                `protobuf:"bytes,2,opt,name=name,proto3,oneof" json:"name,omitempty"`
+	Description                *string                `protobuf:"bytes,3,opt,name=description,proto3,oneof" json:"description,omitempty"`
+	Enabled                    *bool                  `protobuf:"varint,4,opt,name=enabled,proto3,oneof" json:"enabled,omitempty"`
+	Multihop                   *int32                 `protobuf:"varint,5,opt,name=multihop,proto3,oneof" json:"multihop,omitempty"`
+	UpdateSource               *string                `protobuf:"bytes,6,opt,name=update_source,json=updateSource,proto3,oneof" json:"update_source,omitempty"`
+	RouteMapIn                 *string                `protobuf:"bytes,7,opt,name=route_map_in,json=routeMapIn,proto3,oneof" json:"route_map_in,omitempty"`
+	RouteMapOut                *string                `protobuf:"bytes,8,opt,name=route_map_out,json=routeMapOut,proto3,oneof" json:"route_map_out,omitempty"`
+	PrefixListIn               *string                `protobuf:"bytes,9,opt,name=prefix_list_in,json=prefixListIn,proto3,oneof" json:"prefix_list_in,omitempty"`
+	PrefixListOut              *string                `protobuf:"bytes,10,opt,name=prefix_list_out,json=prefixListOut,proto3,oneof" json:"prefix_list_out,omitempty"`
+	MaxPrefixes                *int32                 `protobuf:"varint,11,opt,name=max_prefixes,json=maxPrefixes,proto3,oneof" json:"max_prefixes,omitempty"`
+	MaxPrefixThreshold         *int32                 `protobuf:"varint,12,opt,name=max_prefix_threshold,json=maxPrefixThreshold,proto3,oneof" json:"max_prefix_threshold,omitempty"`
+	MaxPrefixAction            *string                `protobuf:"bytes,13,opt,name=max_prefix_action,json=maxPrefixAction,proto3,oneof" json:"max_prefix_action,omitempty"`
+	MaxPrefixRestart           *int32                 `protobuf:"varint,14,opt,name=max_prefix_restart,json=maxPrefixRestart,proto3,oneof" json:"max_prefix_restart,omitempty"`
+	LocalPreference            *int32                 `protobuf:"varint,15,opt,name=local_preference,json=localPreference,proto3,oneof" json:"local_preference,omitempty"`
+	Keepalive                  *int32                 `protobuf:"varint,16,opt,name=keepalive,proto3,oneof" json:"keepalive,omitempty"`
+	Holdtime                   *int32                 `protobuf:"varint,17,opt,name=holdtime,proto3,oneof" json:"holdtime,omitempty"`
+	ConnectRetry               *int32                 `protobuf:"varint,18,opt,name=connect_retry,json=connectRetry,proto3,oneof" json:"connect_retry,omitempty"`
+	Passive                    *bool                  `protobuf:"varint,19,opt,name=passive,proto3,oneof" json:"passive,omitempty"`
+	TtlSecurityHops            *int32                 `protobuf:"varint,20,opt,name=ttl_security_hops,json=ttlSecurityHops,proto3,oneof" json:"ttl_security_hops,omitempty"`
+	NextHopSelf                *bool                  `protobuf:"varint,21,opt,name=next_hop_self,json=nextHopSelf,proto3,oneof" json:"next_hop_self,omitempty"`
+	SoftReconfigurationInbound *bool                  `protobuf:"varint,22,opt,name=soft_reconfiguration_inbound,json=softReconfigurationInbound,proto3,oneof" json:"soft_reconfiguration_inbound,omitempty"`
+	RemovePrivateAs            *bool                  `protobuf:"varint,23,opt,name=remove_private_as,json=removePrivateAs,proto3,oneof" json:"remove_private_as,omitempty"`
+	AllowasIn                  *int32                 `protobuf:"varint,24,opt,name=allowas_in,json=allowasIn,proto3,oneof" json:"allowas_in,omitempty"`
+	LocalAs                    *uint32                `protobuf:"varint,25,opt,name=local_as,json=localAs,proto3,oneof" json:"local_as,omitempty"`
+	LocalAsMode                *string                `protobuf:"bytes,26,opt,name=local_as_mode,json=localAsMode,proto3,oneof" json:"local_as_mode,omitempty"`
+	// tags replaces all of the peer's tags when set
+	Tags          *PeerTags `protobuf:"bytes,27,opt,name=tags,proto3" json:"tags,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UpdatePeerRequest) Reset() {
+	*x = UpdatePeerRequest{}
+	mi := &file_flintroute_v1_flintroute_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UpdatePeerRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UpdatePeerRequest) ProtoMessage() {}
+
+func (x *UpdatePeerRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_flintroute_v1_flintroute_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UpdatePeerRequest.ProtoReflect.Descriptor instead.
+func (*UpdatePeerRequest) Descriptor() ([]byte, []int) {
+	return file_flintroute_v1_flintroute_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *UpdatePeerRequest) GetId() uint32 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *UpdatePeerRequest) GetName() string {
+	if x != nil && x.Name != nil {
+		return *x.Name
+	}
+	return ""
+}
+
+func (x *UpdatePeerRequest) GetDescription() string {
+	if x != nil && x.Description != nil {
+		return *x.Description
+	}
+	return ""
+}
+
+func (x *UpdatePeerRequest) GetEnabled() bool {
+	if x != nil && x.Enabled != nil {
+		return *x.Enabled
+	}
+	return false
+}
+
+func (x *UpdatePeerRequest) GetMultihop() int32 {
+	if x != nil && x.Multihop != nil {
+		return *x.Multihop
+	}
+	return 0
+}
+
+func (x *UpdatePeerRequest) GetUpdateSource() string {
+	if x != nil && x.UpdateSource != nil {
+		return *x.UpdateSource
+	}
+	return ""
+}
+
+func (x *UpdatePeerRequest) GetRouteMapIn() string {
+	if x != nil && x.RouteMapIn != nil {
+		return *x.RouteMapIn
+	}
+	return ""
+}
+
+func (x *UpdatePeerRequest) GetRouteMapOut() string {
+	if x != nil && x.RouteMapOut != nil {
+		return *x.RouteMapOut
+	}
+	return ""
+}
+
+func (x *UpdatePeerRequest) GetPrefixListIn() string {
+	if x != nil && x.PrefixListIn != nil {
+		return *x.PrefixListIn
+	}
+	return ""
+}
+
+func (x *UpdatePeerRequest) GetPrefixListOut() string {
+	if x != nil && x.PrefixListOut != nil {
+		return *x.PrefixListOut
+	}
+	return ""
+}
+
+func (x *UpdatePeerRequest) GetMaxPrefixes() int32 {
+	if x != nil && x.MaxPrefixes != nil {
+		return *x.MaxPrefixes
+	}
+	return 0
+}
+
+func (x *UpdatePeerRequest) GetMaxPrefixThreshold() int32 {
+	if x != nil && x.MaxPrefixThreshold != nil {
+		return *x.MaxPrefixThreshold
+	}
+	return 0
+}
+
+func (x *UpdatePeerRequest) GetMaxPrefixAction() string {
+	if x != nil && x.MaxPrefixAction != nil {
+		return *x.MaxPrefixAction
+	}
+	return ""
+}
+
+func (x *UpdatePeerRequest) GetMaxPrefixRestart() int32 {
+	if x != nil && x.MaxPrefixRestart != nil {
+		return *x.MaxPrefixRestart
+	}
+	return 0
+}
+
+func (x *UpdatePeerRequest) GetLocalPreference() int32 {
+	if x != nil && x.LocalPreference != nil {
+		return *x.LocalPreference
+	}
+	return 0
+}
+
+func (x *UpdatePeerRequest) GetKeepalive() int32 {
+	if x != nil && x.Keepalive != nil {
+		return *x.Keepalive
+	}
+	return 0
+}
+
+func (x *UpdatePeerRequest) GetHoldtime() int32 {
+	if x != nil && x.Holdtime != nil {
+		return *x.Holdtime
+	}
+	return 0
+}
+
+func (x *UpdatePeerRequest) GetConnectRetry() int32 {
+	if x != nil && x.ConnectRetry != nil {
+		return *x.ConnectRetry
+	}
+	return 0
+}
+
+func (x *UpdatePeerRequest) GetPassive() bool {
+	if x != nil && x.Passive != nil {
+		return *x.Passive
+	}
+	return false
+}
+
+func (x *UpdatePeerRequest) GetTtlSecurityHops() int32 {
+	if x != nil && x.TtlSecurityHops != nil {
+		return *x.TtlSecurityHops
+	}
+	return 0
+}
+
+func (x *UpdatePeerRequest) GetNextHopSelf() bool {
+	if x != nil && x.NextHopSelf != nil {
+		return *x.NextHopSelf
+	}
+	return false
+}
+
+func (x *UpdatePeerRequest) GetSoftReconfigurationInbound() bool {
+	if x != nil && x.SoftReconfigurationInbound != nil {
+		return *x.SoftReconfigurationInbound
+	}
+	return false
+}
+
+func (x *UpdatePeerRequest) GetRemovePrivateAs() bool {
+	if x != nil && x.RemovePrivateAs != nil {
+		return *x.RemovePrivateAs
+	}
+	return false
+}
+
+func (x *UpdatePeerRequest) GetAllowasIn() int32 {
+	if x != nil && x.AllowasIn != nil {
+		return *x.AllowasIn
+	}
+	return 0
+}
+
+func (x *UpdatePeerRequest) GetLocalAs() uint32 {
+	if x != nil && x.LocalAs != nil {
+		return *x.LocalAs
+	}
+	return 0
+}
+
+func (x *UpdatePeerRequest) GetLocalAsMode() string {
+	if x != nil && x.LocalAsMode != nil {
+		return *x.LocalAsMode
+	}
+	return ""
+}
+
+func (x *UpdatePeerRequest) GetTags() *PeerTags {
+	if x != nil {
+		return x.Tags
+	}
+	return nil
+}
+
+// PeerTags wraps a peer's tags so that an update can tell setting no tags
+// from leaving them alone
+type PeerTags struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Values        map[string]string      `protobuf:"bytes,1,rep,name=values,proto3" json:"values,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PeerTags) Reset() {
+	*x = PeerTags{}
+	mi := &file_flintroute_v1_flintroute_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PeerTags) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PeerTags) ProtoMessage() {}
+
+func (x *PeerTags) ProtoReflect() protoreflect.Message {
+	mi := &file_flintroute_v1_flintroute_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PeerTags.ProtoReflect.Descriptor instead.
+func (*PeerTags) Descriptor() ([]byte, []int) {
+	return file_flintroute_v1_flintroute_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *PeerTags) GetValues() map[string]string {
+	if x != nil {
+		return x.Values
+	}
+	return nil
+}
+
+type DeletePeerRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            uint32                 `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeletePeerRequest) Reset() {
+	*x = DeletePeerRequest{}
+	mi := &file_flintroute_v1_flintroute_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeletePeerRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeletePeerRequest) ProtoMessage() {}
+
+func (x *DeletePeerRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_flintroute_v1_flintroute_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeletePeerRequest.ProtoReflect.Descriptor instead.
+func (*DeletePeerRequest) Descriptor() ([]byte, []int) {
+	return file_flintroute_v1_flintroute_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *DeletePeerRequest) GetId() uint32 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+type DeletePeerResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeletePeerResponse) Reset() {
+	*x = DeletePeerResponse{}
+	mi := &file_flintroute_v1_flintroute_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeletePeerResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeletePeerResponse) ProtoMessage() {}
+
+func (x *DeletePeerResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_flintroute_v1_flintroute_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeletePeerResponse.ProtoReflect.Descriptor instead.
+func (*DeletePeerResponse) Descriptor() ([]byte, []int) {
+	return file_flintroute_v1_flintroute_proto_rawDescGZIP(), []int{8}
+}
+
+// Session is the state of a peer's BGP session
+type Session struct {
+	state     protoimpl.MessageState `protogen:"open.v1"`
+	Id        uint32                 `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	PeerId    uint32                 `protobuf:"varint,2,opt,name=peer_id,json=peerId,proto3" json:"peer_id,omitempty"`
+	PeerName  string                 `protobuf:"bytes,3,opt,name=peer_name,json=peerName,proto3" json:"peer_name,omitempty"`
+	IpAddress string                 `protobuf:"bytes,4,opt,name=ip_address,json=ipAddress,proto3" json:"ip_address,omitempty"`
+	// state is Idle, Connect, Active, OpenSent, OpenConfirm or Established
+	State string `protobuf:"bytes,5,opt,name=state,proto3" json:"state,omitempty"`
+	// uptime is in seconds
+	Uptime           int64                  `protobuf:"varint,6,opt,name=uptime,proto3" json:"uptime,omitempty"`
+	PrefixesReceived int32                  `protobuf:"varint,7,opt,name=prefixes_received,json=prefixesReceived,proto3" json:"prefixes_received,omitempty"`
+	PrefixesSent     int32                  `protobuf:"varint,8,opt,name=prefixes_sent,json=prefixesSent,proto3" json:"prefixes_sent,omitempty"`
+	MessagesReceived int64                  `protobuf:"varint,9,opt,name=messages_received,json=messagesReceived,proto3" json:"messages_received,omitempty"`
+	MessagesSent     int64                  `protobuf:"varint,10,opt,name=messages_sent,json=messagesSent,proto3" json:"messages_sent,omitempty"`
+	LastError        string                 `protobuf:"bytes,11,opt,name=last_error,json=lastError,proto3" json:"last_error,omitempty"`
+	LastReset        *timestamppb.Timestamp `protobuf:"bytes,12,opt,name=last_reset,json=lastReset,proto3" json:"last_reset,omitempty"`
+	InMaintenance    bool                   `protobuf:"varint,13,opt,name=in_maintenance,json=inMaintenance,proto3" json:"in_maintenance,omitempty"`
+	// Negotiated with the peer; empty unless the session is established
+	RemoteRouterId  string                 `protobuf:"bytes,14,opt,name=remote_router_id,json=remoteRouterId,proto3" json:"remote_router_id,omitempty"`
+	BgpVersion      int32                  `protobuf:"varint,15,opt,name=bgp_version,json=bgpVersion,proto3" json:"bgp_version,omitempty"`
+	HoldTime        int32                  `protobuf:"varint,16,opt,name=hold_time,json=holdTime,proto3" json:"hold_time,omitempty"`
+	KeepaliveTime   int32                  `protobuf:"varint,17,opt,name=keepalive_time,json=keepaliveTime,proto3" json:"keepalive_time,omitempty"`
+	FourByteAsn     bool                   `protobuf:"varint,18,opt,name=four_byte_asn,json=fourByteAsn,proto3" json:"four_byte_asn,omitempty"`
+	AddressFamilies []string               `protobuf:"bytes,19,rep,name=address_families,json=addressFamilies,proto3" json:"address_families,omitempty"`
+	GracefulRestart bool                   `protobuf:"varint,20,opt,name=graceful_restart,json=gracefulRestart,proto3" json:"graceful_restart,omitempty"`
+	UpdatedAt       *timestamppb.Timestamp `protobuf:"bytes,21,opt,name=updated_at,json=updatedAt,proto3" json:"updated_at,omitempty"`
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *Session) Reset() {
+	*x = Session{}
+	mi := &file_flintroute_v1_flintroute_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Session) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Session) ProtoMessage() {}
+
+func (x *Session) ProtoReflect() protoreflect.Message {
+	mi := &file_flintroute_v1_flintroute_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Session.ProtoReflect.Descriptor instead.
+func (*Session) Descriptor() ([]byte, []int) {
+	return file_flintroute_v1_flintroute_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *Session) GetId() uint32 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *Session) GetPeerId() uint32 {
+	if x != nil {
+		return x.PeerId
+	}
+	return 0
+}
+
+func (x *Session) GetPeerName() string {
+	if x != nil {
+		return x.PeerName
+	}
+	return ""
+}
+
+func (x *Session) GetIpAddress() string {
+	if x != nil {
+		return x.IpAddress
+	}
+	return ""
+}
+
+func (x *Session) GetState() string {
+	if x != nil {
+		return x.State
+	}
+	return ""
+}
+
+func (x *Session) GetUptime() int64 {
+	if x != nil {
+		return x.Uptime
+	}
+	return 0
+}
+
+func (x *Session) GetPrefixesReceived() int32 {
+	if x != nil {
+		return x.PrefixesReceived
+	}
+	return 0
+}
+
+func (x *Session) GetPrefixesSent() int32 {
+	if x != nil {
+		return x.PrefixesSent
+	}
+	return 0
+}
+
+func (x *Session) GetMessagesReceived() int64 {
+	if x != nil {
+		return x.MessagesReceived
+	}
+	return 0
+}
+
+func (x *Session) GetMessagesSent() int64 {
+	if x != nil {
+		return x.MessagesSent
+	}
+	return 0
+}
+
+func (x *Session) GetLastError() string {
+	if x != nil {
+		return x.LastError
+	}
+	return ""
+}
+
+func (x *Session) GetLastReset() *timestamppb.Timestamp {
+	if x != nil {
+		return x.LastReset
+	}
+	return nil
+}
+
+func (x *Session) GetInMaintenance() bool {
+	if x != nil {
+		return x.InMaintenance
+	}
+	return false
+}
+
+func (x *Session) GetRemoteRouterId() string {
+	if x != nil {
+		return x.RemoteRouterId
+	}
+	return ""
+}
+
+func (x *Session) GetBgpVersion() int32 {
+	if x != nil {
+		return x.BgpVersion
+	}
+	return 0
+}
+
+func (x *Session) GetHoldTime() int32 {
+	if x != nil {
+		return x.HoldTime
+	}
+	return 0
+}
+
+func (x *Session) GetKeepaliveTime() int32 {
+	if x != nil {
+		return x.KeepaliveTime
+	}
+	return 0
+}
+
+func (x *Session) GetFourByteAsn() bool {
+	if x != nil {
+		return x.FourByteAsn
+	}
+	return false
+}
+
+func (x *Session) GetAddressFamilies() []string {
+	if x != nil {
+		return x.AddressFamilies
+	}
+	return nil
+}
+
+func (x *Session) GetGracefulRestart() bool {
+	if x != nil {
+		return x.GracefulRestart
+	}
+	return false
+}
+
+func (x *Session) GetUpdatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.UpdatedAt
+	}
+	return nil
+}
+
+type ListSessionsRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// tags are selectors such as "pop:fra1", or "pop" for any value
+	Tags          []string `protobuf:"bytes,1,rep,name=tags,proto3" json:"tags,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListSessionsRequest) Reset() {
+	*x = ListSessionsRequest{}
+	mi := &file_flintroute_v1_flintroute_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListSessionsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListSessionsRequest) ProtoMessage() {}
+
+func (x *ListSessionsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_flintroute_v1_flintroute_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListSessionsRequest.ProtoReflect.Descriptor instead.
+func (*ListSessionsRequest) Descriptor() ([]byte, []int) {
+	return file_flintroute_v1_flintroute_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *ListSessionsRequest) GetTags() []string {
+	if x != nil {
+		return x.Tags
+	}
+	return nil
+}
+
+type ListSessionsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Sessions      []*Session             `protobuf:"bytes,1,rep,name=sessions,proto3" json:"sessions,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListSessionsResponse) Reset() {
+	*x = ListSessionsResponse{}
+	mi := &file_flintroute_v1_flintroute_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListSessionsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListSessionsResponse) ProtoMessage() {}
+
+func (x *ListSessionsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_flintroute_v1_flintroute_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListSessionsResponse.ProtoReflect.Descriptor instead.
+func (*ListSessionsResponse) Descriptor() ([]byte, []int) {
+	return file_flintroute_v1_flintroute_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *ListSessionsResponse) GetSessions() []*Session {
+	if x != nil {
+		return x.Sessions
+	}
+	return nil
+}
+
+type GetSessionRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	PeerId        uint32                 `protobuf:"varint,1,opt,name=peer_id,json=peerId,proto3" json:"peer_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetSessionRequest) Reset() {
+	*x = GetSessionRequest{}
+	mi := &file_flintroute_v1_flintroute_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetSessionRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetSessionRequest) ProtoMessage() {}
+
+func (x *GetSessionRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_flintroute_v1_flintroute_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetSessionRequest.ProtoReflect.Descriptor instead.
+func (*GetSessionRequest) Descriptor() ([]byte, []int) {
+	return file_flintroute_v1_flintroute_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *GetSessionRequest) GetPeerId() uint32 {
+	if x != nil {
+		return x.PeerId
+	}
+	return 0
+}
+
+type GetRunningConfigRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetRunningConfigRequest) Reset() {
+	*x = GetRunningConfigRequest{}
+	mi := &file_flintroute_v1_flintroute_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetRunningConfigRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetRunningConfigRequest) ProtoMessage() {}
+
+func (x *GetRunningConfigRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_flintroute_v1_flintroute_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetRunningConfigRequest.ProtoReflect.Descriptor instead.
+func (*GetRunningConfigRequest) Descriptor() ([]byte, []int) {
+	return file_flintroute_v1_flintroute_proto_rawDescGZIP(), []int{13}
+}
+
+type GetRunningConfigResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Config        string                 `protobuf:"bytes,1,opt,name=config,proto3" json:"config,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetRunningConfigResponse) Reset() {
+	*x = GetRunningConfigResponse{}
+	mi := &file_flintroute_v1_flintroute_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetRunningConfigResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetRunningConfigResponse) ProtoMessage() {}
+
+func (x *GetRunningConfigResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_flintroute_v1_flintroute_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetRunningConfigResponse.ProtoReflect.Descriptor instead.
+func (*GetRunningConfigResponse) Descriptor() ([]byte, []int) {
+	return file_flintroute_v1_flintroute_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *GetRunningConfigResponse) GetConfig() string {
+	if x != nil {
+		return x.Config
+	}
+	return ""
+}
+
+// ConfigVersion is a backup of FRR's configuration
+type ConfigVersion struct {
+	state             protoimpl.MessageState `protogen:"open.v1"`
+	Id                uint32                 `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	Description       string                 `protobuf:"bytes,2,opt,name=description,proto3" json:"description,omitempty"`
+	Config            string                 `protobuf:"bytes,3,opt,name=config,proto3" json:"config,omitempty"`
+	Hash              string                 `protobuf:"bytes,4,opt,name=hash,proto3" json:"hash,omitempty"`
+	CreatedBy         uint32                 `protobuf:"varint,5,opt,name=created_by,json=createdBy,proto3" json:"created_by,omitempty"`
+	CreatedByUsername string                 `protobuf:"bytes,6,opt,name=created_by_username,json=createdByUsername,proto3" json:"created_by_username,omitempty"`
+	// commit_sha is the Git commit a GitOps sync took the version from
+	CommitSha     string                 `protobuf:"bytes,7,opt,name=commit_sha,json=commitSha,proto3" json:"commit_sha,omitempty"`
+	Name          string                 `protobuf:"bytes,8,opt,name=name,proto3" json:"name,omitempty"`
+	Labels        []string               `protobuf:"bytes,9,rep,name=labels,proto3" json:"labels,omitempty"`
+	Pinned        bool                   `protobuf:"varint,10,opt,name=pinned,proto3" json:"pinned,omitempty"`
+	TenantId      *uint32                `protobuf:"varint,11,opt,name=tenant_id,json=tenantId,proto3,oneof" json:"tenant_id,omitempty"`
+	CreatedAt     *timestamppb.Timestamp `protobuf:"bytes,12,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ConfigVersion) Reset() {
+	*x = ConfigVersion{}
+	mi := &file_flintroute_v1_flintroute_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ConfigVersion) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ConfigVersion) ProtoMessage() {}
+
+func (x *ConfigVersion) ProtoReflect() protoreflect.Message {
+	mi := &file_flintroute_v1_flintroute_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ConfigVersion.ProtoReflect.Descriptor instead.
+func (*ConfigVersion) Descriptor() ([]byte, []int) {
+	return file_flintroute_v1_flintroute_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *ConfigVersion) GetId() uint32 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *ConfigVersion) GetDescription() string {
+	if x != nil {
+		return x.Description
+	}
+	return ""
+}
+
+func (x *ConfigVersion) GetConfig() string {
+	if x != nil {
+		return x.Config
+	}
+	return ""
+}
+
+func (x *ConfigVersion) GetHash() string {
+	if x != nil {
+		return x.Hash
+	}
+	return ""
+}
+
+func (x *ConfigVersion) GetCreatedBy() uint32 {
+	if x != nil {
+		return x.CreatedBy
+	}
+	return 0
+}
+
+func (x *ConfigVersion) GetCreatedByUsername() string {
+	if x != nil {
+		return x.CreatedByUsername
+	}
+	return ""
+}
+
+func (x *ConfigVersion) GetCommitSha() string {
+	if x != nil {
+		return x.CommitSha
+	}
+	return ""
+}
+
+func (x *ConfigVersion) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *ConfigVersion) GetLabels() []string {
+	if x != nil {
+		return x.Labels
+	}
+	return nil
+}
+
+func (x *ConfigVersion) GetPinned() bool {
+	if x != nil {
+		return x.Pinned
+	}
+	return false
+}
+
+func (x *ConfigVersion) GetTenantId() uint32 {
+	if x != nil && x.TenantId != nil {
+		return *x.TenantId
+	}
+	return 0
+}
+
+func (x *ConfigVersion) GetCreatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return nil
+}
+
+type ListConfigVersionsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListConfigVersionsRequest) Reset() {
+	*x = ListConfigVersionsRequest{}
+	mi := &file_flintroute_v1_flintroute_proto_msgTypes[16]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListConfigVersionsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListConfigVersionsRequest) ProtoMessage() {}
+
+func (x *ListConfigVersionsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_flintroute_v1_flintroute_proto_msgTypes[16]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListConfigVersionsRequest.ProtoReflect.Descriptor instead.
+func (*ListConfigVersionsRequest) Descriptor() ([]byte, []int) {
+	return file_flintroute_v1_flintroute_proto_rawDescGZIP(), []int{16}
+}
+
+type ListConfigVersionsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Versions      []*ConfigVersion       `protobuf:"bytes,1,rep,name=versions,proto3" json:"versions,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListConfigVersionsResponse) Reset() {
+	*x = ListConfigVersionsResponse{}
+	mi := &file_flintroute_v1_flintroute_proto_msgTypes[17]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListConfigVersionsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListConfigVersionsResponse) ProtoMessage() {}
+
+func (x *ListConfigVersionsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_flintroute_v1_flintroute_proto_msgTypes[17]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListConfigVersionsResponse.ProtoReflect.Descriptor instead.
+func (*ListConfigVersionsResponse) Descriptor() ([]byte, []int) {
+	return file_flintroute_v1_flintroute_proto_rawDescGZIP(), []int{17}
+}
+
+func (x *ListConfigVersionsResponse) GetVersions() []*ConfigVersion {
+	if x != nil {
+		return x.Versions
+	}
+	return nil
+}
+
+type BackupConfigRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Description   string                 `protobuf:"bytes,1,opt,name=description,proto3" json:"description,omitempty"`
+	Name          string                 `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	Labels        []string               `protobuf:"bytes,3,rep,name=labels,proto3" json:"labels,omitempty"`
+	Pinned        bool                   `protobuf:"varint,4,opt,name=pinned,proto3" json:"pinned,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BackupConfigRequest) Reset() {
+	*x = BackupConfigRequest{}
+	mi := &file_flintroute_v1_flintroute_proto_msgTypes[18]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BackupConfigRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BackupConfigRequest) ProtoMessage() {}
+
+func (x *BackupConfigRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_flintroute_v1_flintroute_proto_msgTypes[18]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BackupConfigRequest.ProtoReflect.Descriptor instead.
+func (*BackupConfigRequest) Descriptor() ([]byte, []int) {
+	return file_flintroute_v1_flintroute_proto_rawDescGZIP(), []int{18}
+}
+
+func (x *BackupConfigRequest) GetDescription() string {
+	if x != nil {
+		return x.Description
+	}
+	return ""
+}
+
+func (x *BackupConfigRequest) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *BackupConfigRequest) GetLabels() []string {
+	if x != nil {
+		return x.Labels
+	}
+	return nil
+}
+
+func (x *BackupConfigRequest) GetPinned() bool {
+	if x != nil {
+		return x.Pinned
+	}
+	return false
+}
+
+type BackupConfigResponse struct {
+	state   protoimpl.MessageState `protogen:"open.v1"`
+	Version *ConfigVersion         `protobuf:"bytes,1,opt,name=version,proto3" json:"version,omitempty"`
+	// created is false when the configuration was already backed up, in
+	// version
+	Created       bool `protobuf:"varint,2,opt,name=created,proto3" json:"created,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BackupConfigResponse) Reset() {
+	*x = BackupConfigResponse{}
+	mi := &file_flintroute_v1_flintroute_proto_msgTypes[19]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BackupConfigResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BackupConfigResponse) ProtoMessage() {}
+
+func (x *BackupConfigResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_flintroute_v1_flintroute_proto_msgTypes[19]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BackupConfigResponse.ProtoReflect.Descriptor instead.
+func (*BackupConfigResponse) Descriptor() ([]byte, []int) {
+	return file_flintroute_v1_flintroute_proto_rawDescGZIP(), []int{19}
+}
+
+func (x *BackupConfigResponse) GetVersion() *ConfigVersion {
+	if x != nil {
+		return x.Version
+	}
+	return nil
+}
+
+func (x *BackupConfigResponse) GetCreated() bool {
+	if x != nil {
+		return x.Created
+	}
+	return false
+}
+
+// Alert is an alert raised by FlintRoute or received from Alertmanager
+type Alert struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Id    uint32                 `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	Type  string                 `protobuf:"bytes,2,opt,name=type,proto3" json:"type,omitempty"`
+	// severity is info, warning, error or critical
+	Severity       string                 `protobuf:"bytes,3,opt,name=severity,proto3" json:"severity,omitempty"`
+	Message        string                 `protobuf:"bytes,4,opt,name=message,proto3" json:"message,omitempty"`
+	Details        string                 `protobuf:"bytes,5,opt,name=details,proto3" json:"details,omitempty"`
+	PeerId         *uint32                `protobuf:"varint,6,opt,name=peer_id,json=peerId,proto3,oneof" json:"peer_id,omitempty"`
+	Acknowledged   bool                   `protobuf:"varint,7,opt,name=acknowledged,proto3" json:"acknowledged,omitempty"`
+	AcknowledgedAt *timestamppb.Timestamp `protobuf:"bytes,8,opt,name=acknowledged_at,json=acknowledgedAt,proto3" json:"acknowledged_at,omitempty"`
+	AcknowledgedBy *uint32                `protobuf:"varint,9,opt,name=acknowledged_by,json=acknowledgedBy,proto3,oneof" json:"acknowledged_by,omitempty"`
+	// source is flintroute or alertmanager
+	Source        string                 `protobuf:"bytes,10,opt,name=source,proto3" json:"source,omitempty"`
+	Labels        map[string]string      `protobuf:"bytes,11,rep,name=labels,proto3" json:"labels,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	Occurrences   int32                  `protobuf:"varint,12,opt,name=occurrences,proto3" json:"occurrences,omitempty"`
+	FirstSeenAt   *timestamppb.Timestamp `protobuf:"bytes,13,opt,name=first_seen_at,json=firstSeenAt,proto3" json:"first_seen_at,omitempty"`
+	ResolvedAt    *timestamppb.Timestamp `protobuf:"bytes,14,opt,name=resolved_at,json=resolvedAt,proto3" json:"resolved_at,omitempty"`
+	ArchivedAt    *timestamppb.Timestamp `protobuf:"bytes,15,opt,name=archived_at,json=archivedAt,proto3" json:"archived_at,omitempty"`
+	CreatedAt     *timestamppb.Timestamp `protobuf:"bytes,16,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Alert) Reset() {
+	*x = Alert{}
+	mi := &file_flintroute_v1_flintroute_proto_msgTypes[20]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Alert) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Alert) ProtoMessage() {}
+
+func (x *Alert) ProtoReflect() protoreflect.Message {
+	mi := &file_flintroute_v1_flintroute_proto_msgTypes[20]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Alert.ProtoReflect.Descriptor instead.
+func (*Alert) Descriptor() ([]byte, []int) {
+	return file_flintroute_v1_flintroute_proto_rawDescGZIP(), []int{20}
+}
+
+func (x *Alert) GetId() uint32 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *Alert) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+func (x *Alert) GetSeverity() string {
+	if x != nil {
+		return x.Severity
+	}
+	return ""
+}
+
+func (x *Alert) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+func (x *Alert) GetDetails() string {
+	if x != nil {
+		return x.Details
+	}
+	return ""
+}
+
+func (x *Alert) GetPeerId() uint32 {
+	if x != nil && x.PeerId != nil {
+		return *x.PeerId
+	}
+	return 0
+}
+
+func (x *Alert) GetAcknowledged() bool {
+	if x != nil {
+		return x.Acknowledged
+	}
+	return false
+}
+
+func (x *Alert) GetAcknowledgedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.AcknowledgedAt
+	}
+	return nil
+}
+
+func (x *Alert) GetAcknowledgedBy() uint32 {
+	if x != nil && x.AcknowledgedBy != nil {
+		return *x.AcknowledgedBy
+	}
+	return 0
+}
+
+func (x *Alert) GetSource() string {
+	if x != nil {
+		return x.Source
+	}
+	return ""
+}
+
+func (x *Alert) GetLabels() map[string]string {
+	if x != nil {
+		return x.Labels
+	}
+	return nil
+}
+
+func (x *Alert) GetOccurrences() int32 {
+	if x != nil {
+		return x.Occurrences
+	}
+	return 0
+}
+
+func (x *Alert) GetFirstSeenAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.FirstSeenAt
+	}
+	return nil
+}
+
+func (x *Alert) GetResolvedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.ResolvedAt
+	}
+	return nil
+}
+
+func (x *Alert) GetArchivedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.ArchivedAt
+	}
+	return nil
+}
+
+func (x *Alert) GetCreatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return nil
+}
+
+type ListAlertsRequest struct {
+	state        protoimpl.MessageState `protogen:"open.v1"`
+	Severity     string                 `protobuf:"bytes,1,opt,name=severity,proto3" json:"severity,omitempty"`
+	Source       string                 `protobuf:"bytes,2,opt,name=source,proto3" json:"source,omitempty"`
+	Acknowledged *bool                  `protobuf:"varint,3,opt,name=acknowledged,proto3,oneof" json:"acknowledged,omitempty"`
+	// archived lists archived alerts instead of current ones
+	Archived bool `protobuf:"varint,4,opt,name=archived,proto3" json:"archived,omitempty"`
+	// tags are selectors such as "pop:fra1" for alerts of peers with every
+	// given tag
+	Tags          []string `protobuf:"bytes,5,rep,name=tags,proto3" json:"tags,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListAlertsRequest) Reset() {
+	*x = ListAlertsRequest{}
+	mi := &file_flintroute_v1_flintroute_proto_msgTypes[21]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListAlertsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListAlertsRequest) ProtoMessage() {}
+
+func (x *ListAlertsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_flintroute_v1_flintroute_proto_msgTypes[21]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListAlertsRequest.ProtoReflect.Descriptor instead.
+func (*ListAlertsRequest) Descriptor() ([]byte, []int) {
+	return file_flintroute_v1_flintroute_proto_rawDescGZIP(), []int{21}
+}
+
+func (x *ListAlertsRequest) GetSeverity() string {
+	if x != nil {
+		return x.Severity
+	}
+	return ""
+}
+
+func (x *ListAlertsRequest) GetSource() string {
+	if x != nil {
+		return x.Source
+	}
+	return ""
+}
+
+func (x *ListAlertsRequest) GetAcknowledged() bool {
+	if x != nil && x.Acknowledged != nil {
+		return *x.Acknowledged
+	}
+	return false
+}
+
+func (x *ListAlertsRequest) GetArchived() bool {
+	if x != nil {
+		return x.Archived
+	}
+	return false
+}
+
+func (x *ListAlertsRequest) GetTags() []string {
+	if x != nil {
+		return x.Tags
+	}
+	return nil
+}
+
+type ListAlertsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Alerts        []*Alert               `protobuf:"bytes,1,rep,name=alerts,proto3" json:"alerts,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListAlertsResponse) Reset() {
+	*x = ListAlertsResponse{}
+	mi := &file_flintroute_v1_flintroute_proto_msgTypes[22]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListAlertsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListAlertsResponse) ProtoMessage() {}
+
+func (x *ListAlertsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_flintroute_v1_flintroute_proto_msgTypes[22]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListAlertsResponse.ProtoReflect.Descriptor instead.
+func (*ListAlertsResponse) Descriptor() ([]byte, []int) {
+	return file_flintroute_v1_flintroute_proto_rawDescGZIP(), []int{22}
+}
+
+func (x *ListAlertsResponse) GetAlerts() []*Alert {
+	if x != nil {
+		return x.Alerts
+	}
+	return nil
+}
+
+type AcknowledgeAlertRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            uint32                 `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AcknowledgeAlertRequest) Reset() {
+	*x = AcknowledgeAlertRequest{}
+	mi := &file_flintroute_v1_flintroute_proto_msgTypes[23]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AcknowledgeAlertRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AcknowledgeAlertRequest) ProtoMessage() {}
+
+func (x *AcknowledgeAlertRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_flintroute_v1_flintroute_proto_msgTypes[23]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AcknowledgeAlertRequest.ProtoReflect.Descriptor instead.
+func (*AcknowledgeAlertRequest) Descriptor() ([]byte, []int) {
+	return file_flintroute_v1_flintroute_proto_rawDescGZIP(), []int{23}
+}
+
+func (x *AcknowledgeAlertRequest) GetId() uint32 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+var File_flintroute_v1_flintroute_proto protoreflect.FileDescriptor
+
+const file_flintroute_v1_flintroute_proto_rawDesc = "" +
+	"\n" +
+	"\x1eflintroute/v1/flintroute.proto\x12\rflintroute.v1\x1a\x1fgoogle/protobuf/timestamp.proto\"\xd6\f\n" +
+	"\x04Peer\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\rR\x02id\x12\x12\n" +
+	"\x04name\x18\x02 \x01(\tR\x04name\x12\x1d\n" +
+	"\n" +
+	"ip_address\x18\x03 \x01(\tR\tipAddress\x12\x10\n" +
+	"\x03asn\x18\x04 \x01(\rR\x03asn\x12\x1d\n" +
+	"\n" +
+	"remote_asn\x18\x05 \x01(\rR\tremoteAsn\x12 \n" +
+	"\vdescription\x18\x06 \x01(\tR\vdescription\x12\x18\n" +
+	"\aenabled\x18\a \x01(\bR\aenabled\x12!\n" +
+	"\fhas_password\x18\b \x01(\bR\vhasPassword\x12\x1a\n" +
+	"\bmultihop\x18\t \x01(\x05R\bmultihop\x12#\n" +
+	"\rupdate_source\x18\n" +
+	" \x01(\tR\fupdateSource\x12 \n" +
+	"\froute_map_in\x18\v \x01(\tR\n" +
+	"routeMapIn\x12\"\n" +
+	"\rroute_map_out\x18\f \x01(\tR\vrouteMapOut\x12$\n" +
+	"\x0eprefix_list_in\x18\r \x01(\tR\fprefixListIn\x12&\n" +
+	"\x0fprefix_list_out\x18\x0e \x01(\tR\rprefixListOut\x12!\n" +
+	"\fmax_prefixes\x18\x0f \x01(\x05R\vmaxPrefixes\x120\n" +
+	"\x14max_prefix_threshold\x18\x10 \x01(\x05R\x12maxPrefixThreshold\x12*\n" +
+	"\x11max_prefix_action\x18\x11 \x01(\tR\x0fmaxPrefixAction\x12,\n" +
+	"\x12max_prefix_restart\x18\x12 \x01(\x05R\x10maxPrefixRestart\x12)\n" +
+	"\x10local_preference\x18\x13 \x01(\x05R\x0flocalPreference\x12\x1c\n" +
+	"\tkeepalive\x18\x14 \x01(\x05R\tkeepalive\x12\x1a\n" +
+	"\bholdtime\x18\x15 \x01(\x05R\bholdtime\x12#\n" +
+	"\rconnect_retry\x18\x16 \x01(\x05R\fconnectRetry\x12\x18\n" +
+	"\apassive\x18\x17 \x01(\bR\apassive\x12*\n" +
+	"\x11ttl_security_hops\x18\x18 \x01(\x05R\x0fttlSecurityHops\x12\"\n" +
+	"\rnext_hop_self\x18\x19 \x01(\bR\vnextHopSelf\x12@\n" +
+	"\x1csoft_reconfiguration_inbound\x18\x1a \x01(\bR\x1asoftReconfigurationInbound\x12*\n" +
+	"\x11remove_private_as\x18\x1b \x01(\bR\x0fremovePrivateAs\x12\x1d\n" +
+	"\n" +
+	"allowas_in\x18\x1c \x01(\x05R\tallowasIn\x12\x19\n" +
+	"\blocal_as\x18\x1d \x01(\rR\alocalAs\x12\"\n" +
+	"\rlocal_as_mode\x18\x1e \x01(\tR\vlocalAsMode\x121\n" +
+	"\x04tags\x18\x1f \x03(\v2\x1d.flintroute.v1.Peer.TagsEntryR\x04tags\x12\x1f\n" +
+	"\vsync_status\x18  \x01(\tR\n" +
+	"syncStatus\x12\x1d\n" +
+	"\n" +
+	"sync_error\x18! \x01(\tR\tsyncError\x12\x1d\n" +
+	"\n" +
+	"managed_by\x18\" \x01(\tR\tmanagedBy\x12$\n" +
+	"\vtemplate_id\x18# \x01(\rH\x00R\n" +
+	"templateId\x88\x01\x01\x12 \n" +
+	"\ttenant_id\x18$ \x01(\rH\x01R\btenantId\x88\x01\x01\x12G\n" +
+	"\x11maintenance_since\x18% \x01(\v2\x1a.google.protobuf.TimestampR\x10maintenanceSince\x12G\n" +
+	"\x11maintenance_until\x18& \x01(\v2\x1a.google.protobuf.TimestampR\x10maintenanceUntil\x129\n" +
+	"\n" +
+	"created_at\x18' \x01(\v2\x1a.google.protobuf.TimestampR\tcreatedAt\x129\n" +
+	"\n" +
+	"updated_at\x18( \x01(\v2\x1a.google.protobuf.TimestampR\tupdatedAt\x1a7\n" +
+	"\tTagsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01B\x0e\n" +
+	"\f_template_idB\f\n" +
+	"\n" +
+	"_tenant_id\"&\n" +
+	"\x10ListPeersRequest\x12\x12\n" +
+	"\x04tags\x18\x01 \x03(\tR\x04tags\">\n" +
+	"\x11ListPeersResponse\x12)\n" +
+	"\x05peers\x18\x01 \x03(\v2\x13.flintroute.v1.PeerR\x05peers\" \n" +
+	"\x0eGetPeerRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\rR\x02id\"\xc2\t\n" +
+	"\x11CreatePeerRequest\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12\x1d\n" +
+	"\n" +
+	"ip_address\x18\x02 \x01(\tR\tipAddress\x12$\n" +
+	"\vtemplate_id\x18\x03 \x01(\rH\x00R\n" +
+	"templateId\x88\x01\x01\x12\x10\n" +
+	"\x03asn\x18\x04 \x01(\rR\x03asn\x12\x1d\n" +
+	"\n" +
+	"remote_asn\x18\x05 \x01(\rR\tremoteAsn\x12 \n" +
+	"\vdescription\x18\x06 \x01(\tR\vdescription\x12\x18\n" +
+	"\aenabled\x18\a \x01(\bR\aenabled\x12\x1a\n" +
+	"\bpassword\x18\b \x01(\tR\bpassword\x12\x1a\n" +
+	"\bmultihop\x18\t \x01(\x05R\bmultihop\x12#\n" +
+	"\rupdate_source\x18\n" +
+	" \x01(\tR\fupdateSource\x12 \n" +
+	"\froute_map_in\x18\v \x01(\tR\n" +
+	"routeMapIn\x12\"\n" +
+	"\rroute_map_out\x18\f \x01(\tR\vrouteMapOut\x12$\n" +
+	"\x0eprefix_list_in\x18\r \x01(\tR\fprefixListIn\x12&\n" +
+	"\x0fprefix_list_out\x18\x0e \x01(\tR\rprefixListOut\x12!\n" +
+	"\fmax_prefixes\x18\x0f \x01(\x05R\vmaxPrefixes\x120\n" +
+	"\x14max_prefix_threshold\x18\x10 \x01(\x05R\x12maxPrefixThreshold\x12*\n" +
+	"\x11max_prefix_action\x18\x11 \x01(\tR\x0fmaxPrefixAction\x12,\n" +
+	"\x12max_prefix_restart\x18\x12 \x01(\x05R\x10maxPrefixRestart\x12)\n" +
+	"\x10local_preference\x18\x13 \x01(\x05R\x0flocalPreference\x12\x1c\n" +
+	"\tkeepalive\x18\x14 \x01(\x05R\tkeepalive\x12\x1a\n" +
+	"\bholdtime\x18\x15 \x01(\x05R\bholdtime\x12#\n" +
+	"\rconnect_retry\x18\x16 \x01(\x05R\fconnectRetry\x12\x18\n" +
+	"\apassive\x18\x17 \x01(\bR\apassive\x12*\n" +
+	"\x11ttl_security_hops\x18\x18 \x01(\x05R\x0fttlSecurityHops\x12\"\n" +
+	"\rnext_hop_self\x18\x19 \x01(\bR\vnextHopSelf\x12@\n" +
+	"\x1csoft_reconfiguration_inbound\x18\x1a \x01(\bR\x1asoftReconfigurationInbound\x12*\n" +
+	"\x11remove_private_as\x18\x1b \x01(\bR\x0fremovePrivateAs\x12\x1d\n" +
+	"\n" +
+	"allowas_in\x18\x1c \x01(\x05R\tallowasIn\x12\x19\n" +
+	"\blocal_as\x18\x1d \x01(\rR\alocalAs\x12\"\n" +
+	"\rlocal_as_mode\x18\x1e \x01(\tR\vlocalAsMode\x12>\n" +
+	"\x04tags\x18\x1f \x03(\v2*.flintroute.v1.CreatePeerRequest.TagsEntryR\x04tags\x1a7\n" +
+	"\tTagsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01B\x0e\n" +
+	"\f_template_id\"\xa1\f\n" +
+	"\x11UpdatePeerRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\rR\x02id\x12\x17\n" +
+	"\x04name\x18\x02 \x01(\tH\x00R\x04name\x88\x01\x01\x12%\n" +
+	"\vdescription\x18\x03 \x01(\tH\x01R\vdescription\x88\x01\x01\x12\x1d\n" +
+	"\aenabled\x18\x04 \x01(\bH\x02R\aenabled\x88\x01\x01\x12\x1f\n" +
+	"\bmultihop\x18\x05 \x01(\x05H\x03R\bmultihop\x88\x01\x01\x12(\n" +
+	"\rupdate_source\x18\x06 \x01(\tH\x04R\fupdateSource\x88\x01\x01\x12%\n" +
+	"\froute_map_in\x18\a \x01(\tH\x05R\n" +
+	"routeMapIn\x88\x01\x01\x12'\n" +
+	"\rroute_map_out\x18\b \x01(\tH\x06R\vrouteMapOut\x88\x01\x01\x12)\n" +
+	"\x0eprefix_list_in\x18\t \x01(\tH\aR\fprefixListIn\x88\x01\x01\x12+\n" +
+	"\x0fprefix_list_out\x18\n" +
+	" \x01(\tH\bR\rprefixListOut\x88\x01\x01\x12&\n" +
+	"\fmax_prefixes\x18\v \x01(\x05H\tR\vmaxPrefixes\x88\x01\x01\x125\n" +
+	"\x14max_prefix_threshold\x18\f \x01(\x05H\n" +
+	"R\x12maxPrefixThreshold\x88\x01\x01\x12/\n" +
+	"\x11max_prefix_action\x18\r \x01(\tH\vR\x0fmaxPrefixAction\x88\x01\x01\x121\n" +
+	"\x12max_prefix_restart\x18\x0e \x01(\x05H\fR\x10maxPrefixRestart\x88\x01\x01\x12.\n" +
+	"\x10local_preference\x18\x0f \x01(\x05H\rR\x0flocalPreference\x88\x01\x01\x12!\n" +
+	"\tkeepalive\x18\x10 \x01(\x05H\x0eR\tkeepalive\x88\x01\x01\x12\x1f\n" +
+	"\bholdtime\x18\x11 \x01(\x05H\x0fR\bholdtime\x88\x01\x01\x12(\n" +
+	"\rconnect_retry\x18\x12 \x01(\x05H\x10R\fconnectRetry\x88\x01\x01\x12\x1d\n" +
+	"\apassive\x18\x13 \x01(\bH\x11R\apassive\x88\x01\x01\x12/\n" +
+	"\x11ttl_security_hops\x18\x14 \x01(\x05H\x12R\x0fttlSecurityHops\x88\x01\x01\x12'\n" +
+	"\rnext_hop_self\x18\x15 \x01(\bH\x13R\vnextHopSelf\x88\x01\x01\x12E\n" +
+	"\x1csoft_reconfiguration_inbound\x18\x16 \x01(\bH\x14R\x1asoftReconfigurationInbound\x88\x01\x01\x12/\n" +
+	"\x11remove_private_as\x18\x17 \x01(\bH\x15R\x0fremovePrivateAs\x88\x01\x01\x12\"\n" +
+	"\n" +
+	"allowas_in\x18\x18 \x01(\x05H\x16R\tallowasIn\x88\x01\x01\x12\x1e\n" +
+	"\blocal_as\x18\x19 \x01(\rH\x17R\alocalAs\x88\x01\x01\x12'\n" +
+	"\rlocal_as_mode\x18\x1a \x01(\tH\x18R\vlocalAsMode\x88\x01\x01\x12+\n" +
+	"\x04tags\x18\x1b \x01(\v2\x17.flintroute.v1.PeerTagsR\x04tagsB\a\n" +
+	"\x05_nameB\x0e\n" +
+	"\f_descriptionB\n" +
+	"\n" +
+	"\b_enabledB\v\n" +
+	"\t_multihopB\x10\n" +
+	"\x0e_update_sourceB\x0f\n" +
+	"\r_route_map_inB\x10\n" +
+	"\x0e_route_map_outB\x11\n" +
+	"\x0f_prefix_list_inB\x12\n" +
+	"\x10_prefix_list_outB\x0f\n" +
+	"\r_max_prefixesB\x17\n" +
+	"\x15_max_prefix_thresholdB\x14\n" +
+	"\x12_max_prefix_actionB\x15\n" +
+	"\x13_max_prefix_restartB\x13\n" +
+	"\x11_local_preferenceB\f\n" +
+	"\n" +
+	"_keepaliveB\v\n" +
+	"\t_holdtimeB\x10\n" +
+	"\x0e_connect_retryB\n" +
+	"\n" +
+	"\b_passiveB\x14\n" +
+	"\x12_ttl_security_hopsB\x10\n" +
+	"\x0e_next_hop_selfB\x1f\n" +
+	"\x1d_soft_reconfiguration_inboundB\x14\n" +
+	"\x12_remove_private_asB\r\n" +
+	"\v_allowas_inB\v\n" +
+	"\t_local_asB\x10\n" +
+	"\x0e_local_as_mode\"\x82\x01\n" +
+	"\bPeerTags\x12;\n" +
+	"\x06values\x18\x01 \x03(\v2#.flintroute.v1.PeerTags.ValuesEntryR\x06values\x1a9\n" +
+	"\vValuesEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"#\n" +
+	"\x11DeletePeerRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\rR\x02id\"\x14\n" +
+	"\x12DeletePeerResponse\"\x85\x06\n" +
+	"\aSession\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\rR\x02id\x12\x17\n" +
+	"\apeer_id\x18\x02 \x01(\rR\x06peerId\x12\x1b\n" +
+	"\tpeer_name\x18\x03 \x01(\tR\bpeerName\x12\x1d\n" +
+	"\n" +
+	"ip_address\x18\x04 \x01(\tR\tipAddress\x12\x14\n" +
+	"\x05state\x18\x05 \x01(\tR\x05state\x12\x16\n" +
+	"\x06uptime\x18\x06 \x01(\x03R\x06uptime\x12+\n" +
+	"\x11prefixes_received\x18\a \x01(\x05R\x10prefixesReceived\x12#\n" +
+	"\rprefixes_sent\x18\b \x01(\x05R\fprefixesSent\x12+\n" +
+	"\x11messages_received\x18\t \x01(\x03R\x10messagesReceived\x12#\n" +
+	"\rmessages_sent\x18\n" +
+	" \x01(\x03R\fmessagesSent\x12\x1d\n" +
+	"\n" +
+	"last_error\x18\v \x01(\tR\tlastError\x129\n" +
+	"\n" +
+	"last_reset\x18\f \x01(\v2\x1a.google.protobuf.TimestampR\tlastReset\x12%\n" +
+	"\x0ein_maintenance\x18\r \x01(\bR\rinMaintenance\x12(\n" +
+	"\x10remote_router_id\x18\x0e \x01(\tR\x0eremoteRouterId\x12\x1f\n" +
+	"\vbgp_version\x18\x0f \x01(\x05R\n" +
+	"bgpVersion\x12\x1b\n" +
+	"\thold_time\x18\x10 \x01(\x05R\bholdTime\x12%\n" +
+	"\x0ekeepalive_time\x18\x11 \x01(\x05R\rkeepaliveTime\x12\"\n" +
+	"\rfour_byte_asn\x18\x12 \x01(\bR\vfourByteAsn\x12)\n" +
+	"\x10address_families\x18\x13 \x03(\tR\x0faddressFamilies\x12)\n" +
+	"\x10graceful_restart\x18\x14 \x01(\bR\x0fgracefulRestart\x129\n" +
+	"\n" +
+	"updated_at\x18\x15 \x01(\v2\x1a.google.protobuf.TimestampR\tupdatedAt\")\n" +
+	"\x13ListSessionsRequest\x12\x12\n" +
+	"\x04tags\x18\x01 \x03(\tR\x04tags\"J\n" +
+	"\x14ListSessionsResponse\x122\n" +
+	"\bsessions\x18\x01 \x03(\v2\x16.flintroute.v1.SessionR\bsessions\",\n" +
+	"\x11GetSessionRequest\x12\x17\n" +
+	"\apeer_id\x18\x01 \x01(\rR\x06peerId\"\x19\n" +
+	"\x17GetRunningConfigRequest\"2\n" +
+	"\x18GetRunningConfigResponse\x12\x16\n" +
+	"\x06config\x18\x01 \x01(\tR\x06config\"\x8a\x03\n" +
+	"\rConfigVersion\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\rR\x02id\x12 \n" +
+	"\vdescription\x18\x02 \x01(\tR\vdescription\x12\x16\n" +
+	"\x06config\x18\x03 \x01(\tR\x06config\x12\x12\n" +
+	"\x04hash\x18\x04 \x01(\tR\x04hash\x12\x1d\n" +
+	"\n" +
+	"created_by\x18\x05 \x01(\rR\tcreatedBy\x12.\n" +
+	"\x13created_by_username\x18\x06 \x01(\tR\x11createdByUsername\x12\x1d\n" +
+	"\n" +
+	"commit_sha\x18\a \x01(\tR\tcommitSha\x12\x12\n" +
+	"\x04name\x18\b \x01(\tR\x04name\x12\x16\n" +
+	"\x06labels\x18\t \x03(\tR\x06labels\x12\x16\n" +
+	"\x06pinned\x18\n" +
+	" \x01(\bR\x06pinned\x12 \n" +
+	"\ttenant_id\x18\v \x01(\rH\x00R\btenantId\x88\x01\x01\x129\n" +
+	"\n" +
+	"created_at\x18\f \x01(\v2\x1a.google.protobuf.TimestampR\tcreatedAtB\f\n" +
+	"\n" +
+	"_tenant_id\"\x1b\n" +
+	"\x19ListConfigVersionsRequest\"V\n" +
+	"\x1aListConfigVersionsResponse\x128\n" +
+	"\bversions\x18\x01 \x03(\v2\x1c.flintroute.v1.ConfigVersionR\bversions\"{\n" +
+	"\x13BackupConfigRequest\x12 \n" +
+	"\vdescription\x18\x01 \x01(\tR\vdescription\x12\x12\n" +
+	"\x04name\x18\x02 \x01(\tR\x04name\x12\x16\n" +
+	"\x06labels\x18\x03 \x03(\tR\x06labels\x12\x16\n" +
+	"\x06pinned\x18\x04 \x01(\bR\x06pinned\"h\n" +
+	"\x14BackupConfigResponse\x126\n" +
+	"\aversion\x18\x01 \x01(\v2\x1c.flintroute.v1.ConfigVersionR\aversion\x12\x18\n" +
+	"\acreated\x18\x02 \x01(\bR\acreated\"\xf4\x05\n" +
+	"\x05Alert\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\rR\x02id\x12\x12\n" +
+	"\x04type\x18\x02 \x01(\tR\x04type\x12\x1a\n" +
+	"\bseverity\x18\x03 \x01(\tR\bseverity\x12\x18\n" +
+	"\amessage\x18\x04 \x01(\tR\amessage\x12\x18\n" +
+	"\adetails\x18\x05 \x01(\tR\adetails\x12\x1c\n" +
+	"\apeer_id\x18\x06 \x01(\rH\x00R\x06peerId\x88\x01\x01\x12\"\n" +
+	"\facknowledged\x18\a \x01(\bR\facknowledged\x12C\n" +
+	"\x0facknowledged_at\x18\b \x01(\v2\x1a.google.protobuf.TimestampR\x0eacknowledgedAt\x12,\n" +
+	"\x0facknowledged_by\x18\t \x01(\rH\x01R\x0eacknowledgedBy\x88\x01\x01\x12\x16\n" +
+	"\x06source\x18\n" +
+	" \x01(\tR\x06source\x128\n" +
+	"\x06labels\x18\v \x03(\v2 .flintroute.v1.Alert.LabelsEntryR\x06labels\x12 \n" +
+	"\voccurrences\x18\f \x01(\x05R\voccurrences\x12>\n" +
+	"\rfirst_seen_at\x18\r \x01(\v2\x1a.google.protobuf.TimestampR\vfirstSeenAt\x12;\n" +
+	"\vresolved_at\x18\x0e \x01(\v2\x1a.google.protobuf.TimestampR\n" +
+	"resolvedAt\x12;\n" +
+	"\varchived_at\x18\x0f \x01(\v2\x1a.google.protobuf.TimestampR\n" +
+	"archivedAt\x129\n" +
+	"\n" +
+	"created_at\x18\x10 \x01(\v2\x1a.google.protobuf.TimestampR\tcreatedAt\x1a9\n" +
+	"\vLabelsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01B\n" +
+	"\n" +
+	"\b_peer_idB\x12\n" +
+	"\x10_acknowledged_by\"\xb1\x01\n" +
+	"\x11ListAlertsRequest\x12\x1a\n" +
+	"\bseverity\x18\x01 \x01(\tR\bseverity\x12\x16\n" +
+	"\x06source\x18\x02 \x01(\tR\x06source\x12'\n" +
+	"\facknowledged\x18\x03 \x01(\bH\x00R\facknowledged\x88\x01\x01\x12\x1a\n" +
+	"\barchived\x18\x04 \x01(\bR\barchived\x12\x12\n" +
+	"\x04tags\x18\x05 \x03(\tR\x04tagsB\x0f\n" +
+	"\r_acknowledged\"B\n" +
+	"\x12ListAlertsResponse\x12,\n" +
+	"\x06alerts\x18\x01 \x03(\v2\x14.flintroute.v1.AlertR\x06alerts\")\n" +
+	"\x17AcknowledgeAlertRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\rR\x02id2\xf9\x02\n" +
+	"\vPeerService\x12N\n" +
+	"\tListPeers\x12\x1f.flintroute.v1.ListPeersRequest\x1a .flintroute.v1.ListPeersResponse\x12=\n" +
+	"\aGetPeer\x12\x1d.flintroute.v1.GetPeerRequest\x1a\x13.flintroute.v1.Peer\x12C\n" +
+	"\n" +
+	"CreatePeer\x12 .flintroute.v1.CreatePeerRequest\x1a\x13.flintroute.v1.Peer\x12C\n" +
+	"\n" +
+	"UpdatePeer\x12 .flintroute.v1.UpdatePeerRequest\x1a\x13.flintroute.v1.Peer\x12Q\n" +
+	"\n" +
+	"DeletePeer\x12 .flintroute.v1.DeletePeerRequest\x1a!.flintroute.v1.DeletePeerResponse2\xb1\x01\n" +
+	"\x0eSessionService\x12W\n" +
+	"\fListSessions\x12\".flintroute.v1.ListSessionsRequest\x1a#.flintroute.v1.ListSessionsResponse\x12F\n" +
+	"\n" +
+	"GetSession\x12 .flintroute.v1.GetSessionRequest\x1a\x16.flintroute.v1.Session2\xb8\x02\n" +
+	"\rConfigService\x12c\n" +
+	"\x10GetRunningConfig\x12&.flintroute.v1.GetRunningConfigRequest\x1a'.flintroute.v1.GetRunningConfigResponse\x12i\n" +
+	"\x12ListConfigVersions\x12(.flintroute.v1.ListConfigVersionsRequest\x1a).flintroute.v1.ListConfigVersionsResponse\x12W\n" +
+	"\fBackupConfig\x12\".flintroute.v1.BackupConfigRequest\x1a#.flintroute.v1.BackupConfigResponse2\xb3\x01\n" +
+	"\fAlertService\x12Q\n" +
+	"\n" +
+	"ListAlerts\x12 .flintroute.v1.ListAlertsRequest\x1a!.flintroute.v1.ListAlertsResponse\x12P\n" +
+	"\x10AcknowledgeAlert\x12&.flintroute.v1.AcknowledgeAlertRequest\x1a\x14.flintroute.v1.AlertB3Z1github.com/padminisys/flintroute/pkg/flintroutepbb\x06proto3"
+
+var (
+	file_flintroute_v1_flintroute_proto_rawDescOnce sync.Once
+	file_flintroute_v1_flintroute_proto_rawDescData []byte
+)
+
+func file_flintroute_v1_flintroute_proto_rawDescGZIP() []byte {
+	file_flintroute_v1_flintroute_proto_rawDescOnce.Do(func() {
+		file_flintroute_v1_flintroute_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_flintroute_v1_flintroute_proto_rawDesc), len(file_flintroute_v1_flintroute_proto_rawDesc)))
+	})
+	return file_flintroute_v1_flintroute_proto_rawDescData
+}
+
+var file_flintroute_v1_flintroute_proto_msgTypes = make([]protoimpl.MessageInfo, 28)
+var file_flintroute_v1_flintroute_proto_goTypes = []any{
+	(*Peer)(nil),                       // 0: flintroute.v1.Peer
+	(*ListPeersRequest)(nil),           // 1: flintroute.v1.ListPeersRequest
+	(*ListPeersResponse)(nil),          // 2: flintroute.v1.ListPeersResponse
+	(*GetPeerRequest)(nil),             // 3: flintroute.v1.GetPeerRequest
+	(*CreatePeerRequest)(nil),          // 4: flintroute.v1.CreatePeerRequest
+	(*UpdatePeerRequest)(nil),          // 5: flintroute.v1.UpdatePeerRequest
+	(*PeerTags)(nil),                   // 6: flintroute.v1.PeerTags
+	(*DeletePeerRequest)(nil),          // 7: flintroute.v1.DeletePeerRequest
+	(*DeletePeerResponse)(nil),         // 8: flintroute.v1.DeletePeerResponse
+	(*Session)(nil),                    // 9: flintroute.v1.Session
+	(*ListSessionsRequest)(nil),        // 10: flintroute.v1.ListSessionsRequest
+	(*ListSessionsResponse)(nil),       // 11: flintroute.v1.ListSessionsResponse
+	(*GetSessionRequest)(nil),          // 12: flintroute.v1.GetSessionRequest
+	(*GetRunningConfigRequest)(nil),    // 13: flintroute.v1.GetRunningConfigRequest
+	(*GetRunningConfigResponse)(nil),   // 14: flintroute.v1.GetRunningConfigResponse
+	(*ConfigVersion)(nil),              // 15: flintroute.v1.ConfigVersion
+	(*ListConfigVersionsRequest)(nil),  // 16: flintroute.v1.ListConfigVersionsRequest
+	(*ListConfigVersionsResponse)(nil), // 17: flintroute.v1.ListConfigVersionsResponse
+	(*BackupConfigRequest)(nil),        // 18: flintroute.v1.BackupConfigRequest
+	(*BackupConfigResponse)(nil),       // 19: flintroute.v1.BackupConfigResponse
+	(*Alert)(nil),                      // 20: flintroute.v1.Alert
+	(*ListAlertsRequest)(nil),          // 21: flintroute.v1.ListAlertsRequest
+	(*ListAlertsResponse)(nil),         // 22: flintroute.v1.ListAlertsResponse
+	(*AcknowledgeAlertRequest)(nil),    // 23: flintroute.v1.AcknowledgeAlertRequest
+	nil,                                // 24: flintroute.v1.Peer.TagsEntry
+	nil,                                // 25: flintroute.v1.CreatePeerRequest.TagsEntry
+	nil,                                // 26: flintroute.v1.PeerTags.ValuesEntry
+	nil,                                // 27: flintroute.v1.Alert.LabelsEntry
+	(*timestamppb.Timestamp)(nil),      // 28: google.protobuf.Timestamp
+}
+var file_flintroute_v1_flintroute_proto_depIdxs = []int32{
+	24, // 0: flintroute.v1.Peer.tags:type_name -> flintroute.v1.Peer.TagsEntry
+	28, // 1: flintroute.v1.Peer.maintenance_since:type_name -> google.protobuf.Timestamp
+	28, // 2: flintroute.v1.Peer.maintenance_until:type_name -> google.protobuf.Timestamp
+	28, // 3: flintroute.v1.Peer.created_at:type_name -> google.protobuf.Timestamp
+	28, // 4: flintroute.v1.Peer.updated_at:type_name -> google.protobuf.Timestamp
+	0,  // 5: flintroute.v1.ListPeersResponse.peers:type_name -> flintroute.v1.Peer
+	25, // 6: flintroute.v1.CreatePeerRequest.tags:type_name -> flintroute.v1.CreatePeerRequest.TagsEntry
+	6,  // 7: flintroute.v1.UpdatePeerRequest.tags:type_name -> flintroute.v1.PeerTags
+	26, // 8: flintroute.v1.PeerTags.values:type_name -> flintroute.v1.PeerTags.ValuesEntry
+	28, // 9: flintroute.v1.Session.last_reset:type_name -> google.protobuf.Timestamp
+	28, // 10: flintroute.v1.Session.updated_at:type_name -> google.protobuf.Timestamp
+	9,  // 11: flintroute.v1.ListSessionsResponse.sessions:type_name -> flintroute.v1.Session
+	28, // 12: flintroute.v1.ConfigVersion.created_at:type_name -> google.protobuf.Timestamp
+	15, // 13: flintroute.v1.ListConfigVersionsResponse.versions:type_name -> flintroute.v1.ConfigVersion
+	15, // 14: flintroute.v1.BackupConfigResponse.version:type_name -> flintroute.v1.ConfigVersion
+	28, // 15: flintroute.v1.Alert.acknowledged_at:type_name -> google.protobuf.Timestamp
+	27, // 16: flintroute.v1.Alert.labels:type_name -> flintroute.v1.Alert.LabelsEntry
+	28, // 17: flintroute.v1.Alert.first_seen_at:type_name -> google.protobuf.Timestamp
+	28, // 18: flintroute.v1.Alert.resolved_at:type_name -> google.protobuf.Timestamp
+	28, // 19: flintroute.v1.Alert.archived_at:type_name -> google.protobuf.Timestamp
+	28, // 20: flintroute.v1.Alert.created_at:type_name -> google.protobuf.Timestamp
+	20, // 21: flintroute.v1.ListAlertsResponse.alerts:type_name -> flintroute.v1.Alert
+	1,  // 22: flintroute.v1.PeerService.ListPeers:input_type -> flintroute.v1.ListPeersRequest
+	3,  // 23: flintroute.v1.PeerService.GetPeer:input_type -> flintroute.v1.GetPeerRequest
+	4,  // 24: flintroute.v1.PeerService.CreatePeer:input_type -> flintroute.v1.CreatePeerRequest
+	5,  // 25: flintroute.v1.PeerService.UpdatePeer:input_type -> flintroute.v1.UpdatePeerRequest
+	7,  // 26: flintroute.v1.PeerService.DeletePeer:input_type -> flintroute.v1.DeletePeerRequest
+	10, // 27: flintroute.v1.SessionService.ListSessions:input_type -> flintroute.v1.ListSessionsRequest
+	12, // 28: flintroute.v1.SessionService.GetSession:input_type -> flintroute.v1.GetSessionRequest
+	13, // 29: flintroute.v1.ConfigService.GetRunningConfig:input_type -> flintroute.v1.GetRunningConfigRequest
+	16, // 30: flintroute.v1.ConfigService.ListConfigVersions:input_type -> flintroute.v1.ListConfigVersionsRequest
+	18, // 31: flintroute.v1.ConfigService.BackupConfig:input_type -> flintroute.v1.BackupConfigRequest
+	21, // 32: flintroute.v1.AlertService.ListAlerts:input_type -> flintroute.v1.ListAlertsRequest
+	23, // 33: flintroute.v1.AlertService.AcknowledgeAlert:input_type -> flintroute.v1.AcknowledgeAlertRequest
+	2,  // 34: flintroute.v1.PeerService.ListPeers:output_type -> flintroute.v1.ListPeersResponse
+	0,  // 35: flintroute.v1.PeerService.GetPeer:output_type -> flintroute.v1.Peer
+	0,  // 36: flintroute.v1.PeerService.CreatePeer:output_type -> flintroute.v1.Peer
+	0,  // 37: flintroute.v1.PeerService.UpdatePeer:output_type -> flintroute.v1.Peer
+	8,  // 38: flintroute.v1.PeerService.DeletePeer:output_type -> flintroute.v1.DeletePeerResponse
+	11, // 39: flintroute.v1.SessionService.ListSessions:output_type -> flintroute.v1.ListSessionsResponse
+	9,  // 40: flintroute.v1.SessionService.GetSession:output_type -> flintroute.v1.Session
+	14, // 41: flintroute.v1.ConfigService.GetRunningConfig:output_type -> flintroute.v1.GetRunningConfigResponse
+	17, // 42: flintroute.v1.ConfigService.ListConfigVersions:output_type -> flintroute.v1.ListConfigVersionsResponse
+	19, // 43: flintroute.v1.ConfigService.BackupConfig:output_type -> flintroute.v1.BackupConfigResponse
+	22, // 44: flintroute.v1.AlertService.ListAlerts:output_type -> flintroute.v1.ListAlertsResponse
+	20, // 45: flintroute.v1.AlertService.AcknowledgeAlert:output_type -> flintroute.v1.Alert
+	34, // [34:46] is the sub-list for method output_type
+	22, // [22:34] is the sub-list for method input_type
+	22, // [22:22] is the sub-list for extension type_name
+	22, // [22:22] is the sub-list for extension extendee
+	0,  // [0:22] is the sub-list for field type_name
+}
+
+func init() { file_flintroute_v1_flintroute_proto_init() }
+func file_flintroute_v1_flintroute_proto_init() {
+	if File_flintroute_v1_flintroute_proto != nil {
+		return
+	}
+	file_flintroute_v1_flintroute_proto_msgTypes[0].OneofWrappers = []any{}
+	file_flintroute_v1_flintroute_proto_msgTypes[4].OneofWrappers = []any{}
+	file_flintroute_v1_flintroute_proto_msgTypes[5].OneofWrappers = []any{}
+	file_flintroute_v1_flintroute_proto_msgTypes[15].OneofWrappers = []any{}
+	file_flintroute_v1_flintroute_proto_msgTypes[20].OneofWrappers = []any{}
+	file_flintroute_v1_flintroute_proto_msgTypes[21].OneofWrappers = []any{}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_flintroute_v1_flintroute_proto_rawDesc), len(file_flintroute_v1_flintroute_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   28,
+			NumExtensions: 0,
+			NumServices:   4,
+		},
+		GoTypes:           file_flintroute_v1_flintroute_proto_goTypes,
+		DependencyIndexes: file_flintroute_v1_flintroute_proto_depIdxs,
+		MessageInfos:      file_flintroute_v1_flintroute_proto_msgTypes,
+	}.Build()
+	File_flintroute_v1_flintroute_proto = out.File
+	file_flintroute_v1_flintroute_proto_goTypes = nil
+	file_flintroute_v1_flintroute_proto_depIdxs = nil
+}
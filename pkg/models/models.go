@@ -0,0 +1,906 @@
+package models
+
+import (
+	"encoding/json"
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// User represents a system user
+type User struct {
+	ID           uint            `gorm:"primarykey" json:"id"`
+	CreatedAt    time.Time       `json:"created_at"`
+	UpdatedAt    time.Time       `json:"updated_at"`
+	DeletedAt    gorm.DeletedAt  `gorm:"index" json:"-"`
+	Username     string          `gorm:"uniqueIndex;not null" json:"username"`
+	PasswordHash string          `gorm:"not null" json:"-"`
+	Email        string          `gorm:"uniqueIndex" json:"email"`
+	Role         string          `gorm:"not null;default:'user'" json:"role"` // admin, operator, user
+	Active       bool            `gorm:"not null;default:true" json:"active"`
+	Preferences  json.RawMessage `gorm:"type:text" json:"preferences,omitempty"` // user-defined JSON object, e.g. notification settings
+	// RequestQuota is how many API requests per hour the user may make;
+	// nil uses server.request_quota and 0 is unlimited
+	RequestQuota *int `json:"request_quota,omitempty"`
+}
+
+// Tenant is an organization whose peers, alerts and config versions are
+// kept apart from other tenants' when multi-tenancy is enabled
+type Tenant struct {
+	ID          uint      `gorm:"primarykey" json:"id"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+	Name        string    `gorm:"uniqueIndex;not null" json:"name"`
+	Description string    `json:"description"`
+}
+
+// TenantMember makes a user a member of a tenant. Role replaces the user's
+// own role while acting for the tenant.
+type TenantMember struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	TenantID  uint      `gorm:"not null;uniqueIndex:idx_tenant_members_tenant_user" json:"tenant_id"`
+	Tenant    *Tenant   `gorm:"foreignKey:TenantID" json:"tenant,omitempty"`
+	UserID    uint      `gorm:"not null;uniqueIndex:idx_tenant_members_tenant_user;index" json:"user_id"`
+	User      *User     `gorm:"foreignKey:UserID" json:"user,omitempty"`
+	Role      string    `gorm:"not null;default:'user'" json:"role"` // operator, user
+}
+
+// BGPPeer represents a BGP peer configuration
+type BGPPeer struct {
+	ID                  uint           `gorm:"primarykey" json:"id"`
+	CreatedAt           time.Time      `json:"created_at"`
+	UpdatedAt           time.Time      `json:"updated_at"`
+	DeletedAt           gorm.DeletedAt `gorm:"index" json:"-"`
+	Name                string         `gorm:"not null;index" json:"name"`
+	IPAddress           string         `gorm:"uniqueIndex;not null" json:"ip_address"`
+	ASN                 uint32         `gorm:"not null" json:"asn"`
+	RemoteASN           uint32         `gorm:"not null;index" json:"remote_asn"`
+	Description         string         `json:"description"`
+	Enabled             bool           `gorm:"not null;default:true" json:"enabled"`
+	Password            string         `json:"-"` // encrypted at rest, never serialized
+	HasPassword         bool           `gorm:"-" json:"has_password"`
+	Multihop            int            `gorm:"default:1" json:"multihop"`
+	UpdateSource        string         `json:"update_source"`
+	RouteMapIn          string         `json:"route_map_in"`
+	RouteMapOut         string         `json:"route_map_out"`
+	PrefixListIn        string         `json:"prefix_list_in"`
+	PrefixListOut       string         `json:"prefix_list_out"`
+	MaxPrefixes         int            `json:"max_prefixes"`
+	MaxPrefixThreshold  int            `json:"max_prefix_threshold"` // percent of MaxPrefixes that logs a warning; 0 keeps FRR's default
+	MaxPrefixAction     string         `json:"max_prefix_action"`    // shutdown (default), warning-only
+	MaxPrefixRestart    int            `json:"max_prefix_restart"`   // minutes before a shut down session restarts; 0 waits for a manual clear
+	LocalPreference     int            `json:"local_preference"`
+	Keepalive           int            `json:"keepalive"`     // seconds, set with HoldTime; 0 keeps FRR's default
+	HoldTime            int            `json:"holdtime"`      // seconds, set with Keepalive; 0 keeps FRR's default
+	ConnectRetry        int            `json:"connect_retry"` // seconds; 0 keeps FRR's default
+	Passive             bool           `json:"passive"`
+	TTLSecurityHops     int            `json:"ttl_security_hops"` // GTSM (RFC 5082); 0 disables
+	NextHopSelf         bool           `json:"next_hop_self"`
+	SoftReconfigInbound bool           `json:"soft_reconfiguration_inbound"`
+	RemovePrivateAS     bool           `json:"remove_private_as"`
+	AllowASIn           int            `json:"allowas_in"`                                         // times the local AS may appear; 0 disables
+	LocalAS             uint32         `json:"local_as,omitempty"`                                 // AS presented to this eBGP peer instead of ASN; 0 disables
+	LocalASMode         string         `json:"local_as_mode,omitempty"`                            // empty, no-prepend, replace-as
+	SyncStatus          string         `gorm:"not null;default:'synced';index" json:"sync_status"` // synced, pending
+	SyncError           string         `json:"sync_error,omitempty"`
+	LastFRRError        string         `json:"last_frr_error,omitempty"`          // FRR's raw output the last time it rejected the peer's configuration
+	LastFRRErrorAt      *time.Time     `json:"last_frr_error_at,omitempty"`       // when FRR last rejected it
+	ManagedBy           string         `gorm:"index" json:"managed_by,omitempty"` // empty for API-managed peers, "gitops"
+	MaintenanceSince    *time.Time     `json:"maintenance_since,omitempty"`       // set while the peer is under maintenance
+	MaintenanceUntil    *time.Time     `json:"maintenance_until,omitempty"`       // end of the maintenance window; nil lasts until ended
+	Tags                PeerTags       `gorm:"foreignKey:PeerID" json:"tags,omitempty"`
+	// TemplateID is the template the peer was provisioned from, and
+	// TemplateOverrides the template settings set on the peer itself, which
+	// syncing the template leaves alone
+	TemplateID        *uint    `gorm:"index" json:"template_id,omitempty"`
+	TemplateOverrides []string `gorm:"serializer:json" json:"template_overrides,omitempty"`
+	// TenantID is the tenant owning the peer; nil for peers outside any
+	TenantID *uint `gorm:"index" json:"tenant_id,omitempty"`
+}
+
+// Maximum-prefix actions
+const (
+	MaxPrefixShutdown    = "shutdown"
+	MaxPrefixWarningOnly = "warning-only"
+)
+
+// Local AS modes. By default the local AS is prepended to the router's AS
+// in paths received from the peer; replace-as also sends paths to the peer
+// with the local AS only, as used when migrating between ASNs.
+const (
+	LocalASNoPrepend = "no-prepend"
+	LocalASReplaceAS = "replace-as"
+)
+
+// AfterFind sets derived fields after loading a peer
+func (p *BGPPeer) AfterFind(tx *gorm.DB) error {
+	p.HasPassword = p.Password != ""
+	return nil
+}
+
+// AfterSave sets derived fields after creating or updating a peer
+func (p *BGPPeer) AfterSave(tx *gorm.DB) error {
+	p.HasPassword = p.Password != ""
+	return nil
+}
+
+// InMaintenance reports whether the peer is under maintenance at now
+func (p *BGPPeer) InMaintenance(now time.Time) bool {
+	return p.MaintenanceSince != nil && (p.MaintenanceUntil == nil || now.Before(*p.MaintenanceUntil))
+}
+
+// OwnerTenantID returns the tenant owning the peer, for scoping the events
+// about it
+func (p *BGPPeer) OwnerTenantID() *uint {
+	return p.TenantID
+}
+
+// PeerTag is a key/value label on a BGP peer, used to group and filter
+// peers. A peer has at most one value per key.
+type PeerTag struct {
+	ID     uint   `gorm:"primarykey"`
+	PeerID uint   `gorm:"not null;uniqueIndex:idx_peer_tags_peer_key"`
+	Key    string `gorm:"not null;uniqueIndex:idx_peer_tags_peer_key;index:idx_peer_tags_key_value"`
+	Value  string `gorm:"not null;index:idx_peer_tags_key_value"`
+}
+
+// TableName specifies the table name for PeerTag
+func (PeerTag) TableName() string {
+	return "peer_tags"
+}
+
+// PeerTags are a peer's tags. They are serialized as a JSON object of keys
+// to values.
+type PeerTags []PeerTag
+
+// NewPeerTags converts a map of keys to values into tags sorted by key. A
+// nil map gives nil tags.
+func NewPeerTags(tags map[string]string) PeerTags {
+	if tags == nil {
+		return nil
+	}
+
+	keys := make([]string, 0, len(tags))
+	for key := range tags {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	result := make(PeerTags, 0, len(keys))
+	for _, key := range keys {
+		result = append(result, PeerTag{Key: key, Value: tags[key]})
+	}
+	return result
+}
+
+// Map returns the tags as a map of keys to values
+func (t PeerTags) Map() map[string]string {
+	m := make(map[string]string, len(t))
+	for _, tag := range t {
+		m[tag.Key] = tag.Value
+	}
+	return m
+}
+
+// MarshalJSON encodes the tags as a JSON object
+func (t PeerTags) MarshalJSON() ([]byte, error) {
+	return json.Marshal(t.Map())
+}
+
+// UnmarshalJSON decodes the tags from a JSON object
+func (t *PeerTags) UnmarshalJSON(data []byte) error {
+	var m map[string]string
+	if err := json.Unmarshal(data, &m); err != nil {
+		return err
+	}
+	*t = NewPeerTags(m)
+	return nil
+}
+
+// Peer sync statuses
+const (
+	PeerSyncSynced  = "synced"
+	PeerSyncPending = "pending"
+)
+
+// BGPSession represents the runtime state of a BGP session
+type BGPSession struct {
+	ID               uint      `gorm:"primarykey" json:"id"`
+	CreatedAt        time.Time `json:"created_at"`
+	UpdatedAt        time.Time `json:"updated_at"`
+	PeerID           uint      `gorm:"not null;index" json:"peer_id"`
+	Peer             BGPPeer   `gorm:"foreignKey:PeerID" json:"peer,omitempty"`
+	State            string    `gorm:"not null;index" json:"state"` // Idle, Connect, Active, OpenSent, OpenConfirm, Established
+	Uptime           int64     `json:"uptime"`                      // seconds
+	PrefixesReceived int       `json:"prefixes_received"`
+	PrefixesSent     int       `json:"prefixes_sent"`
+	MessagesReceived int64     `json:"messages_received"`
+	MessagesSent     int64     `json:"messages_sent"`
+	LastError        string    `json:"last_error"`
+	LastReset        time.Time `json:"last_reset"`
+	InMaintenance    bool      `gorm:"-" json:"in_maintenance"` // whether the peer is under maintenance
+	// Negotiated with the peer; empty unless the session is established
+	RemoteRouterID  string   `json:"remote_router_id"`
+	BGPVersion      int      `json:"bgp_version"`
+	HoldTime        int      `json:"hold_time"`      // seconds
+	KeepaliveTime   int      `json:"keepalive_time"` // seconds
+	FourByteASN     bool     `json:"four_byte_asn"`
+	AddressFamilies []string `gorm:"serializer:json" json:"address_families"` // e.g. ipv4-unicast
+	GracefulRestart bool     `json:"graceful_restart"`
+}
+
+// OwnerTenantID returns the tenant owning the session's peer, for scoping
+// the events about it
+func (s *BGPSession) OwnerTenantID() *uint {
+	return s.Peer.TenantID
+}
+
+// SessionEvent records a BGP session changing state
+type SessionEvent struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time `gorm:"index" json:"created_at"`
+	PeerID    uint      `gorm:"not null;index" json:"peer_id"`
+	IPAddress string    `gorm:"not null" json:"ip_address"`
+	OldState  string    `json:"old_state"`
+	NewState  string    `gorm:"not null" json:"new_state"`
+	// InMaintenance is set for changes while the peer was under maintenance
+	InMaintenance bool `json:"in_maintenance,omitempty"`
+}
+
+// MonitoringGap is a period BGP session states weren't polled, because
+// FlintRoute wasn't running, monitoring was paused or its cycles failed.
+// Session events can't tell what happened to sessions during it.
+type MonitoringGap struct {
+	ID    uint      `gorm:"primarykey" json:"id"`
+	Start time.Time `gorm:"index" json:"start"`
+	End   time.Time `gorm:"index" json:"end"`
+}
+
+// MonitorStats records the health of the BGP session monitor's polling
+// cycles. A single row is kept and updated after every cycle, so the
+// counters survive restarts and leader changes.
+type MonitorStats struct {
+	ID        uint      `gorm:"primarykey" json:"-"`
+	UpdatedAt time.Time `json:"-"`
+	// Cycles counts every poll; FailedCycles those with errors and
+	// SlowCycles those that took longer than the poll interval
+	Cycles       int64 `json:"cycles"`
+	FailedCycles int64 `json:"failed_cycles"`
+	SlowCycles   int64 `json:"slow_cycles"`
+	// ConsecutiveFailures and ConsecutiveSlow count the latest failed and
+	// slow cycles in a row
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+	ConsecutiveSlow     int        `json:"consecutive_slow"`
+	LastCycleAt         *time.Time `json:"last_cycle_at,omitempty"`
+	LastDurationMS      int64      `json:"last_duration_ms"`
+	LastPeersPolled     int        `json:"last_peers_polled"`
+	LastErrors          int        `json:"last_errors"`
+	LastError           string     `json:"last_error,omitempty"`
+	// LastSuccessAt is when a cycle last finished without errors
+	LastSuccessAt *time.Time `json:"last_success_at,omitempty"`
+}
+
+// ConfigVersion represents a configuration backup
+type ConfigVersion struct {
+	ID          uint      `gorm:"primarykey" json:"id"`
+	CreatedAt   time.Time `json:"created_at"`
+	Description string    `json:"description"`
+	Config      string    `gorm:"type:text;not null" json:"config"`
+	Hash        string    `gorm:"uniqueIndex:idx_config_versions_tenant_hash;uniqueIndex:idx_config_versions_hash,where:tenant_id IS NULL;not null" json:"hash"`
+	CreatedBy   uint      `json:"created_by"`
+	User        User      `gorm:"foreignKey:CreatedBy" json:"user,omitempty"`
+	CommitSHA   string    `gorm:"index" json:"commit_sha,omitempty"` // Git commit the version was synced from
+	// Size is the length of the uncompressed configuration in bytes
+	Size int64 `json:"size"`
+	// Encoding is how the stored configuration is encoded: "" for plain
+	// text or ConfigEncodingGzip
+	Encoding string `json:"encoding,omitempty"`
+	// StorageKey locates the configuration in the external blob store;
+	// empty when it is held in Config
+	StorageKey string `json:"storage_key,omitempty"`
+	// BGPGlobal is FlintRoute's BGP global configuration when the version
+	// was taken, restored along with it; nil when none was set
+	BGPGlobal *BGPGlobalConfig `gorm:"serializer:json" json:"bgp_global,omitempty"`
+	// TenantID is the tenant that took the version; nil outside any
+	TenantID *uint `gorm:"uniqueIndex:idx_config_versions_tenant_hash" json:"tenant_id,omitempty"`
+	// Name is an operator-given name, such as "before 9.1 upgrade"
+	Name string `json:"name,omitempty"`
+	// Labels tag the version, such as "golden" or "pre-migration"
+	Labels []string `gorm:"serializer:json" json:"labels,omitempty"`
+	// Pinned versions are never deleted by retention pruning
+	Pinned bool `gorm:"not null;default:false;index" json:"pinned"`
+}
+
+// ConfigEncodingGzip marks a config version stored gzip-compressed. In the
+// Config column the compressed bytes are base64-encoded.
+const ConfigEncodingGzip = "gzip"
+
+// BGPGlobalConfig holds the settings of FRR's default BGP instance. There
+// is at most one, with ID BGPGlobalConfigID.
+type BGPGlobalConfig struct {
+	ID                 uint      `gorm:"primarykey" json:"-"`
+	CreatedAt          time.Time `json:"created_at"`
+	UpdatedAt          time.Time `json:"updated_at"`
+	ASN                uint32    `gorm:"not null" json:"asn"`
+	RouterID           string    `json:"router_id"` // IPv4 address; empty lets FRR choose
+	GracefulRestart    bool      `json:"graceful_restart"`
+	RestartTime        int       `json:"graceful_restart_time"`           // seconds; 0 keeps FRR's default
+	StalePathTime      int       `json:"graceful_restart_stalepath_time"` // seconds; 0 keeps FRR's default
+	EBGPRequiresPolicy bool      `json:"ebgp_requires_policy"`
+	LogNeighborChanges bool      `json:"log_neighbor_changes"`
+	AlwaysCompareMED   bool      `json:"always_compare_med"`
+	CompareRouterID    bool      `json:"bestpath_compare_router_id"`
+	MultipathRelax     bool      `json:"bestpath_multipath_relax"`
+	MEDMissingAsWorst  bool      `json:"bestpath_med_missing_as_worst"`
+}
+
+// BGPGlobalConfigID is the ID of the single BGPGlobalConfig row
+const BGPGlobalConfigID = 1
+
+// RoutingPolicy represents a named FRR policy object such as a route-map
+// or prefix-list. Config holds the rendered FRR configuration.
+type RoutingPolicy struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	Kind      string    `gorm:"not null;uniqueIndex:idx_policy_kind_name" json:"kind"` // route-map, prefix-list
+	Name      string    `gorm:"not null;uniqueIndex:idx_policy_kind_name" json:"name"`
+	Config    string    `gorm:"type:text;not null" json:"config"`
+	ManagedBy string    `gorm:"index" json:"managed_by,omitempty"`
+}
+
+// Routing policy kinds
+const (
+	PolicyRouteMap      = "route-map"
+	PolicyPrefixList    = "prefix-list"
+	PolicyCommunityList = "community-list"
+	PolicyASPathList    = "as-path-list"
+)
+
+// CommunityList represents an FRR BGP community-list. Entries of standard
+// lists hold space-separated communities; entries of expanded lists hold a
+// regular expression.
+type CommunityList struct {
+	ID        uint                 `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time            `json:"created_at"`
+	UpdatedAt time.Time            `json:"updated_at"`
+	Name      string               `gorm:"uniqueIndex;not null" json:"name"`
+	Type      string               `gorm:"not null;default:'standard'" json:"type"` // standard, expanded
+	Entries   []CommunityListEntry `gorm:"serializer:json;not null" json:"entries"`
+	ManagedBy string               `gorm:"index" json:"managed_by,omitempty"`
+}
+
+// CommunityListEntry is a single sequence of a community-list
+type CommunityListEntry struct {
+	Seq    int    `json:"seq"`
+	Action string `json:"action"` // permit, deny
+	Value  string `json:"value"`
+}
+
+// Community-list types
+const (
+	CommunityListStandard = "standard"
+	CommunityListExpanded = "expanded"
+)
+
+// ASPathList represents an FRR BGP as-path access-list
+type ASPathList struct {
+	ID        uint              `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time         `json:"created_at"`
+	UpdatedAt time.Time         `json:"updated_at"`
+	Name      string            `gorm:"uniqueIndex;not null" json:"name"`
+	Entries   []ASPathListEntry `gorm:"serializer:json;not null" json:"entries"`
+	ManagedBy string            `gorm:"index" json:"managed_by,omitempty"`
+}
+
+// ASPathListEntry is a single sequence of an as-path access-list. Regex is
+// matched against the AS path, with "_" matching a delimiter.
+type ASPathListEntry struct {
+	Seq    int    `json:"seq"`
+	Action string `json:"action"` // permit, deny
+	Regex  string `json:"regex"`
+}
+
+// ManagedByGitOps marks objects owned by the GitOps sync
+const ManagedByGitOps = "gitops"
+
+// Alert represents a system alert
+type Alert struct {
+	ID             uint              `gorm:"primarykey;index:idx_alerts_created_at_id,priority:2" json:"id"`
+	CreatedAt      time.Time         `gorm:"index:idx_alerts_created_at_id,priority:1" json:"created_at"`
+	UpdatedAt      time.Time         `json:"updated_at"`
+	DeletedAt      gorm.DeletedAt    `gorm:"index" json:"-"`
+	Type           string            `gorm:"not null;index" json:"type"` // peer_down, peer_up, config_change, etc.
+	Severity       string            `gorm:"not null" json:"severity"`   // info, warning, error, critical
+	Message        string            `gorm:"not null" json:"message"`
+	Details        string            `gorm:"type:text" json:"details"`
+	PeerID         *uint             `gorm:"index" json:"peer_id,omitempty"`
+	Peer           *BGPPeer          `gorm:"foreignKey:PeerID" json:"peer,omitempty"`
+	Acknowledged   bool              `gorm:"not null;default:false" json:"acknowledged"`
+	AcknowledgedAt *time.Time        `json:"acknowledged_at,omitempty"`
+	AcknowledgedBy *uint             `json:"acknowledged_by,omitempty"`
+	User           *User             `gorm:"foreignKey:AcknowledgedBy" json:"user,omitempty"`
+	Source         string            `gorm:"not null;default:'flintroute';index" json:"source"` // flintroute, alertmanager
+	Fingerprint    string            `gorm:"index" json:"fingerprint,omitempty"`                // Alertmanager fingerprint of ingested alerts
+	Labels         map[string]string `gorm:"serializer:json" json:"labels,omitempty"`
+	ResolvedAt     *time.Time        `json:"resolved_at,omitempty"`
+	ArchivedAt     *time.Time        `gorm:"index" json:"archived_at,omitempty"` // set by the retention policy; archived alerts are hidden by default
+	// Repeats of an open alert of the same type and peer, or tenant for
+	// alerts without a peer, are counted on it rather than stored as new
+	// alerts
+	Occurrences int       `gorm:"not null;default:1" json:"occurrences"`
+	FirstSeenAt time.Time `json:"first_seen_at"`
+	LastSeenAt  time.Time `gorm:"index" json:"last_seen_at"`
+	// Suppressed counts occurrences not notified during an alert storm
+	Suppressed int `gorm:"not null;default:0" json:"suppressed,omitempty"`
+	// TenantID is the tenant of the alert's peer; nil for alerts outside
+	// any tenant
+	TenantID *uint `gorm:"index" json:"tenant_id,omitempty"`
+}
+
+// OwnerTenantID returns the tenant of the alert, for scoping the events
+// about it
+func (a *Alert) OwnerTenantID() *uint {
+	return a.TenantID
+}
+
+// BeforeCreate stamps a new alert as seen once, now
+func (a *Alert) BeforeCreate(tx *gorm.DB) error {
+	now := time.Now()
+	if a.Occurrences == 0 {
+		a.Occurrences = 1
+	}
+	if a.FirstSeenAt.IsZero() {
+		a.FirstSeenAt = now
+	}
+	if a.LastSeenAt.IsZero() {
+		a.LastSeenAt = a.FirstSeenAt
+	}
+	return nil
+}
+
+// Alert sources
+const (
+	AlertSourceFlintRoute   = "flintroute"
+	AlertSourceAlertmanager = "alertmanager"
+)
+
+// RefreshToken represents a JWT refresh token
+type RefreshToken struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UserID    uint      `gorm:"not null;index" json:"user_id"`
+	User      User      `gorm:"foreignKey:UserID" json:"-"`
+	Token     string    `gorm:"uniqueIndex;not null" json:"token"`
+	ExpiresAt time.Time `gorm:"not null;index" json:"expires_at"`
+	Revoked   bool      `gorm:"not null;default:false" json:"revoked"`
+	UserAgent string    `json:"user_agent,omitempty"` // of the client that logged in
+	ClientIP  string    `json:"client_ip,omitempty"`
+}
+
+// RevokedToken denylists access tokens before they expire. A row with a JTI
+// revokes that token; a row without one revokes every token issued to the
+// user up to CreatedAt. Rows are pruned once ExpiresAt has passed.
+type RevokedToken struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	JTI       string    `gorm:"index" json:"jti,omitempty"`
+	UserID    uint      `gorm:"not null;index" json:"user_id"`
+	ExpiresAt time.Time `gorm:"not null;index" json:"expires_at"`
+}
+
+// ChangeRequest is a configuration change held until a second admin
+// approves it, when the two-person rule is enabled
+type ChangeRequest struct {
+	ID          uint            `gorm:"primarykey" json:"id"`
+	CreatedAt   time.Time       `json:"created_at"`
+	UpdatedAt   time.Time       `json:"updated_at"`
+	Operation   string          `gorm:"not null;index" json:"operation"`                // peer.create, peer.delete, config.restore
+	Status      string          `gorm:"not null;default:'pending';index" json:"status"` // pending, rejected, applied, failed
+	Summary     string          `json:"summary"`
+	Payload     json.RawMessage `gorm:"type:text" json:"payload"` // parameters of the operation
+	Secret      string          `json:"-"`                        // encrypted secret the payload can't carry, such as a peer password
+	RequestedBy uint            `gorm:"not null;index" json:"requested_by"`
+	ReviewedBy  *uint           `json:"reviewed_by,omitempty"`
+	ReviewedAt  *time.Time      `json:"reviewed_at,omitempty"`
+	Comment     string          `json:"comment,omitempty"` // the reviewer's
+	Result      json.RawMessage `gorm:"type:text" json:"result,omitempty"`
+	Error       string          `json:"error,omitempty"`
+	// TenantID is the tenant the change was requested for; nil outside any
+	TenantID *uint `gorm:"index" json:"tenant_id,omitempty"`
+}
+
+// OwnerTenantID returns the tenant the change was requested for, for
+// scoping the events about it
+func (c *ChangeRequest) OwnerTenantID() *uint {
+	return c.TenantID
+}
+
+// Change request statuses
+const (
+	ChangePending  = "pending"
+	ChangeRejected = "rejected"
+	ChangeApplied  = "applied"
+	ChangeFailed   = "failed"
+)
+
+// WebhookSubscription represents an endpoint that receives signed
+// lifecycle event webhooks
+type WebhookSubscription struct {
+	ID          uint           `gorm:"primarykey" json:"id"`
+	CreatedAt   time.Time      `json:"created_at"`
+	UpdatedAt   time.Time      `json:"updated_at"`
+	DeletedAt   gorm.DeletedAt `gorm:"index" json:"-"`
+	URL         string         `gorm:"not null" json:"url"`
+	Description string         `json:"description"`
+	Events      []string       `gorm:"serializer:json;not null" json:"events"` // event names, "prefix.*" or "*"
+	Secret      string         `gorm:"not null" json:"-"`                      // HMAC signing secret, encrypted at rest
+	Active      bool           `gorm:"not null;default:true" json:"active"`
+	CreatedBy   uint           `json:"created_by"`
+}
+
+// WebhookDelivery records an attempt to deliver an event to a subscription
+type WebhookDelivery struct {
+	ID             uint       `gorm:"primarykey" json:"id"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+	SubscriptionID uint       `gorm:"not null;index" json:"subscription_id"`
+	EventID        string     `gorm:"not null;index" json:"event_id"`
+	Event          string     `gorm:"not null;index" json:"event"`
+	Payload        string     `gorm:"type:text;not null" json:"payload"`
+	Status         string     `gorm:"not null;default:'pending';index" json:"status"` // pending, succeeded, failed
+	Attempts       int        `gorm:"not null;default:0" json:"attempts"`
+	ResponseStatus int        `json:"response_status,omitempty"`
+	LastError      string     `json:"last_error,omitempty"`
+	NextAttemptAt  *time.Time `gorm:"index" json:"next_attempt_at,omitempty"`
+	DeliveredAt    *time.Time `json:"delivered_at,omitempty"`
+}
+
+// Webhook delivery statuses
+const (
+	DeliveryPending   = "pending"
+	DeliverySucceeded = "succeeded"
+	DeliveryFailed    = "failed"
+)
+
+// Job is a long-running operation run in the background by the job queue
+type Job struct {
+	ID          uint            `gorm:"primarykey" json:"id"`
+	CreatedAt   time.Time       `json:"created_at"`
+	UpdatedAt   time.Time       `json:"updated_at"`
+	Type        string          `gorm:"not null;index" json:"type"`
+	Status      string          `gorm:"not null;default:'queued';index" json:"status"` // queued, running, succeeded, failed, cancelled
+	Payload     string          `gorm:"type:text" json:"-"`                            // JSON-encoded parameters
+	Progress    int             `gorm:"not null;default:0" json:"progress"`            // percent complete
+	Message     string          `json:"message,omitempty"`
+	Result      json.RawMessage `gorm:"type:text" json:"result,omitempty"`
+	Error       string          `json:"error,omitempty"`
+	CreatedBy   *uint           `json:"created_by,omitempty"`
+	RequestID   string          `json:"request_id,omitempty"`
+	TraceParent string          `json:"-"` // W3C traceparent of the request that queued it
+	StartedAt   *time.Time      `json:"started_at,omitempty"`
+	FinishedAt  *time.Time      `json:"finished_at,omitempty"`
+	// RunAt holds a scheduled job back until then
+	RunAt *time.Time `gorm:"index" json:"run_at,omitempty"`
+	// AnnouncedAt is when a scheduled job was announced ahead of running
+	AnnouncedAt *time.Time `json:"announced_at,omitempty"`
+	// TenantID is the tenant the job was queued for; nil for jobs acting
+	// across tenants
+	TenantID *uint `gorm:"index" json:"tenant_id,omitempty"`
+}
+
+// OwnerTenantID returns the tenant the job was queued for, for scoping the
+// events of its progress
+func (j *Job) OwnerTenantID() *uint {
+	return j.TenantID
+}
+
+// Job statuses
+const (
+	JobQueued    = "queued"
+	JobRunning   = "running"
+	JobSucceeded = "succeeded"
+	JobFailed    = "failed"
+	JobCancelled = "cancelled"
+)
+
+// Done reports whether the job has finished, successfully or not, or was
+// cancelled before it ran
+func (j *Job) Done() bool {
+	return j.Status == JobSucceeded || j.Status == JobFailed || j.Status == JobCancelled
+}
+
+// StreamEvent is a message broadcast to WebSocket and SSE clients, kept so
+// clients can catch up on what they missed. ID is the message's sequence
+// number and Data the JSON message as sent.
+type StreamEvent struct {
+	ID        uint64    `gorm:"primarykey;autoIncrement:false" json:"id"`
+	CreatedAt time.Time `gorm:"index" json:"created_at"`
+	Type      string    `gorm:"not null;index" json:"type"`
+	// TenantID is the tenant the event's payload belongs to; nil for
+	// events outside any
+	TenantID *uint  `gorm:"index" json:"tenant_id,omitempty"`
+	Data     string `gorm:"type:text;not null" json:"-"`
+}
+
+// PrefixSample records a peer's prefix counts whenever they change, for
+// detecting sudden changes over time
+type PrefixSample struct {
+	ID               uint      `gorm:"primarykey" json:"id"`
+	CreatedAt        time.Time `gorm:"index" json:"created_at"`
+	PeerID           uint      `gorm:"not null;index" json:"peer_id"`
+	PrefixesReceived int       `json:"prefixes_received"`
+	PrefixesSent     int       `json:"prefixes_sent"`
+}
+
+// ReachabilitySample records a round of IP-layer probes of a peer's
+// address, independent of its BGP session. Latencies are of the probes
+// answered, in milliseconds.
+type ReachabilitySample struct {
+	ID          uint      `gorm:"primarykey" json:"id"`
+	CreatedAt   time.Time `gorm:"index" json:"created_at"`
+	PeerID      uint      `gorm:"not null;index" json:"peer_id"`
+	Method      string    `gorm:"not null" json:"method"` // icmp or tcp
+	Sent        int       `json:"sent"`
+	Received    int       `json:"received"`
+	LossPercent float64   `json:"loss_percent"`
+	MinMS       float64   `json:"min_ms"`
+	AvgMS       float64   `json:"avg_ms"`
+	MaxMS       float64   `json:"max_ms"`
+	// Error is why the last lost probe failed, such as "refused" or
+	// "timeout"
+	Error string `json:"error,omitempty"`
+}
+
+// LeaderLease records which of the FlintRoute instances sharing a database
+// leads. The holder renews it well before ExpiresAt; once it has expired
+// another instance may take it over.
+type LeaderLease struct {
+	Name       string    `gorm:"primarykey" json:"name"`
+	Holder     string    `gorm:"not null" json:"holder"`
+	AcquiredAt time.Time `json:"acquired_at"`
+	RenewedAt  time.Time `json:"renewed_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+}
+
+// DatabaseSnapshot is a copy of FlintRoute's own database, kept in the
+// snapshot storage backend under Key
+type DatabaseSnapshot struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	Key       string    `gorm:"not null;uniqueIndex" json:"key"`
+	Size      int64     `json:"size"`
+	// Checksum is the hex SHA-256 of the snapshot, checked before restoring
+	Checksum      string `gorm:"not null" json:"checksum"`
+	SchemaVersion string `json:"schema_version"`
+	Note          string `json:"note,omitempty"`
+	CreatedBy     string `json:"created_by,omitempty"`
+}
+
+// AuditEntry records an administrative action, such as restoring the
+// database
+type AuditEntry struct {
+	ID        uint      `gorm:"primarykey;index:idx_audit_entries_created_at_id,priority:2" json:"id"`
+	CreatedAt time.Time `gorm:"index;index:idx_audit_entries_created_at_id,priority:1" json:"created_at"`
+	Username  string    `json:"username,omitempty"`
+	Action    string    `gorm:"not null;index" json:"action"`
+	Target    string    `json:"target,omitempty"`
+	Detail    string    `json:"detail,omitempty"`
+}
+
+// FRRTransaction records a change FlintRoute pushed to FRR. CommitID and
+// ParentCommitID are the hex SHA-256 of FRR's running configuration after
+// and before the change, so the transaction that produced a backed-up
+// configuration has the config version's hash as its CommitID.
+type FRRTransaction struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time `gorm:"index" json:"created_at"`
+	// Operation is the change made, such as "peer.add" or "policy.remove"
+	Operation string `gorm:"not null;index" json:"operation"`
+	// Target is the peer's IP address, or the policy's kind and name
+	Target string `json:"target,omitempty"`
+	// Config is the configuration pushed, rendered in FRR's syntax with
+	// passwords redacted; empty for removals
+	Config string `gorm:"type:text" json:"config,omitempty"`
+	// Delta is how the running configuration changed, as lines prefixed
+	// with "- " and "+ "
+	Delta          []string `gorm:"serializer:json" json:"delta"`
+	CommitID       string   `gorm:"index" json:"commit_id,omitempty"`
+	ParentCommitID string   `json:"parent_commit_id,omitempty"`
+	Result         string   `gorm:"not null;index" json:"result"` // succeeded or failed
+	Error          string   `json:"error,omitempty"`
+	DurationMS     int64    `json:"duration_ms"`
+	PeerID         *uint    `gorm:"index" json:"peer_id,omitempty"`
+	// ConfigVersionID is the config version being restored, or rolled
+	// back to, when the change was made
+	ConfigVersionID *uint  `gorm:"index" json:"config_version_id,omitempty"`
+	UserID          *uint  `gorm:"index" json:"user_id,omitempty"`
+	Username        string `json:"username,omitempty"`
+	JobID           *uint  `gorm:"index" json:"job_id,omitempty"`
+	RequestID       string `json:"request_id,omitempty"`
+	// TenantID is the tenant the change was made for; nil outside any
+	TenantID *uint `gorm:"index" json:"tenant_id,omitempty"`
+}
+
+// FRR transaction results
+const (
+	FRRTransactionSucceeded = "succeeded"
+	FRRTransactionFailed    = "failed"
+)
+
+// IdempotencyKey records the response to a POST sent with an
+// Idempotency-Key header, so a retry with the same key gets it again
+// instead of repeating the change. StatusCode is 0 while the first request
+// is still running.
+type IdempotencyKey struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UserID    uint      `gorm:"not null;uniqueIndex:idx_idempotency_keys_user_key" json:"user_id"`
+	// Tenant is the X-Tenant-ID header the request was sent with, so a user
+	// acting for several tenants has separate keys in each
+	Tenant string `gorm:"not null;default:'';uniqueIndex:idx_idempotency_keys_user_key" json:"tenant,omitempty"`
+	Key    string `gorm:"not null;uniqueIndex:idx_idempotency_keys_user_key" json:"key"`
+	// RequestHash is the hex SHA-256 of the method, URL, tenant and body
+	RequestHash string    `gorm:"not null" json:"-"`
+	StatusCode  int       `json:"status_code"`
+	ContentType string    `json:"-"`
+	Body        []byte    `json:"-"`
+	ExpiresAt   time.Time `gorm:"not null;index" json:"expires_at"`
+}
+
+// NotificationSettings are how a user is notified of alerts. The row with
+// UserID 0 holds the defaults admins set for users without their own.
+type NotificationSettings struct {
+	ID        uint      `gorm:"primarykey" json:"-"`
+	CreatedAt time.Time `json:"-"`
+	UpdatedAt time.Time `json:"updated_at"`
+	UserID    uint      `gorm:"not null;uniqueIndex" json:"-"`
+	// Rules are checked in order; the first that matches an alert picks its
+	// channels. Alerts no rule matches aren't notified.
+	Rules []NotificationRule `gorm:"serializer:json" json:"rules"`
+	// QuietHours hold back notifications, except for the severities in
+	// QuietHoursBypass
+	QuietHours       []QuietHours `gorm:"serializer:json" json:"quiet_hours"`
+	QuietHoursBypass []string     `gorm:"serializer:json" json:"quiet_hours_bypass,omitempty"`
+	// Timezone is the IANA name quiet hours are in; empty is UTC
+	Timezone   string `json:"timezone,omitempty"`
+	WebhookURL string `json:"webhook_url,omitempty"` // receives the webhook channel
+}
+
+// TableName overrides GORM's plural of a name that already is one
+func (NotificationSettings) TableName() string { return "notification_settings" }
+
+// NotificationRule sends alerts of the given severities and types, all of
+// them when empty, to channels: email, webhook or none
+type NotificationRule struct {
+	Severities []string `json:"severities,omitempty"`
+	Types      []string `json:"types,omitempty"`
+	Channels   []string `json:"channels"`
+}
+
+// QuietHours is a daily window from Start to End ("HH:MM"), ending the next
+// day when End is before Start, on Days ("mon" to "sun"; every day when
+// empty)
+type QuietHours struct {
+	Days  []string `json:"days,omitempty"`
+	Start string   `json:"start"`
+	End   string   `json:"end"`
+}
+
+// PeerTemplate holds settings shared by the peers provisioned from it, such
+// as the peers of an IXP or a class of customers. Settings a template leaves
+// unset (nil) are inherited from its parent, if any.
+type PeerTemplate struct {
+	ID          uint                 `gorm:"primarykey" json:"id"`
+	CreatedAt   time.Time            `json:"created_at"`
+	UpdatedAt   time.Time            `json:"updated_at"`
+	Name        string               `gorm:"uniqueIndex;not null" json:"name"`
+	Description string               `json:"description"`
+	ParentID    *uint                `gorm:"index" json:"parent_id,omitempty"`
+	Settings    PeerTemplateSettings `gorm:"serializer:json" json:"settings"`
+}
+
+// PeerTemplateSettings are the peer settings a template can hold, named as
+// in BGPPeer. Nil settings are not set by the template.
+type PeerTemplateSettings struct {
+	ASN                 *uint32           `json:"asn,omitempty"`
+	RemoteASN           *uint32           `json:"remote_asn,omitempty"`
+	Enabled             *bool             `json:"enabled,omitempty"`
+	Multihop            *int              `json:"multihop,omitempty"`
+	UpdateSource        *string           `json:"update_source,omitempty"`
+	RouteMapIn          *string           `json:"route_map_in,omitempty"`
+	RouteMapOut         *string           `json:"route_map_out,omitempty"`
+	PrefixListIn        *string           `json:"prefix_list_in,omitempty"`
+	PrefixListOut       *string           `json:"prefix_list_out,omitempty"`
+	MaxPrefixes         *int              `json:"max_prefixes,omitempty"`
+	MaxPrefixThreshold  *int              `json:"max_prefix_threshold,omitempty"`
+	MaxPrefixAction     *string           `json:"max_prefix_action,omitempty"`
+	MaxPrefixRestart    *int              `json:"max_prefix_restart,omitempty"`
+	LocalPreference     *int              `json:"local_preference,omitempty"`
+	Keepalive           *int              `json:"keepalive,omitempty"`
+	HoldTime            *int              `json:"holdtime,omitempty"`
+	ConnectRetry        *int              `json:"connect_retry,omitempty"`
+	Passive             *bool             `json:"passive,omitempty"`
+	TTLSecurityHops     *int              `json:"ttl_security_hops,omitempty"`
+	NextHopSelf         *bool             `json:"next_hop_self,omitempty"`
+	SoftReconfigInbound *bool             `json:"soft_reconfiguration_inbound,omitempty"`
+	RemovePrivateAS     *bool             `json:"remove_private_as,omitempty"`
+	AllowASIn           *int              `json:"allowas_in,omitempty"`
+	LocalAS             *uint32           `json:"local_as,omitempty"`
+	LocalASMode         *string           `json:"local_as_mode,omitempty"`
+	Tags                map[string]string `json:"tags,omitempty"`
+}
+
+// TableName overrides for GORM
+func (PeerTemplate) TableName() string { return "peer_templates" }
+
+// All returns a zero value of every model stored in its own table, parents
+// before the tables referring to them. The server creates them through its
+// migrations; tools that share the schema, such as the functional test
+// harness, can migrate or clean them from this list.
+func All() []interface{} {
+	return []interface{}{
+		&User{},
+		&Tenant{},
+		&TenantMember{},
+		&BGPPeer{},
+		&PeerTag{},
+		&BGPSession{},
+		&ConfigVersion{},
+		&RoutingPolicy{},
+		&CommunityList{},
+		&ASPathList{},
+		&BGPGlobalConfig{},
+		&Alert{},
+		&RefreshToken{},
+		&RevokedToken{},
+		&ChangeRequest{},
+		&WebhookSubscription{},
+		&WebhookDelivery{},
+		&Job{},
+		&StreamEvent{},
+		&PrefixSample{},
+		&ReachabilitySample{},
+		&LeaderLease{},
+		&DatabaseSnapshot{},
+		&AuditEntry{},
+		&IdempotencyKey{},
+		&NotificationSettings{},
+		&PeerTemplate{},
+		&SessionEvent{},
+		&MonitorStats{},
+		&FRRTransaction{},
+		&MonitoringGap{},
+	}
+}
+
+// TableName overrides for GORM
+func (User) TableName() string                { return "users" }
+func (Tenant) TableName() string              { return "tenants" }
+func (TenantMember) TableName() string        { return "tenant_members" }
+func (BGPPeer) TableName() string             { return "bgp_peers" }
+func (BGPSession) TableName() string          { return "bgp_sessions" }
+func (ConfigVersion) TableName() string       { return "config_versions" }
+func (Alert) TableName() string               { return "alerts" }
+func (RoutingPolicy) TableName() string       { return "routing_policies" }
+func (CommunityList) TableName() string       { return "community_lists" }
+func (ASPathList) TableName() string          { return "as_path_lists" }
+func (BGPGlobalConfig) TableName() string     { return "bgp_global_config" }
+func (WebhookSubscription) TableName() string { return "webhook_subscriptions" }
+func (WebhookDelivery) TableName() string     { return "webhook_deliveries" }
+func (RefreshToken) TableName() string        { return "refresh_tokens" }
+func (Job) TableName() string                 { return "jobs" }
+func (StreamEvent) TableName() string         { return "stream_events" }
+func (PrefixSample) TableName() string        { return "prefix_samples" }
+func (ReachabilitySample) TableName() string  { return "reachability_samples" }
+func (LeaderLease) TableName() string         { return "leader_leases" }
+func (DatabaseSnapshot) TableName() string    { return "database_snapshots" }
+func (AuditEntry) TableName() string          { return "audit_entries" }
+func (IdempotencyKey) TableName() string      { return "idempotency_keys" }
+func (SessionEvent) TableName() string        { return "session_events" }
+func (MonitorStats) TableName() string        { return "monitor_stats" }
+func (FRRTransaction) TableName() string      { return "frr_transactions" }
+func (MonitoringGap) TableName() string       { return "monitoring_gaps" }
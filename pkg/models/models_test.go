@@ -20,6 +20,9 @@ func setupTestDB(t *testing.T) *gorm.DB {
 		&ConfigVersion{},
 		&Alert{},
 		&RefreshToken{},
+		&RoutingPolicy{},
+		&WebhookSubscription{},
+		&WebhookDelivery{},
 	)
 	assert.NoError(t, err)
 
@@ -228,6 +231,33 @@ func TestConfigVersionModel(t *testing.T) {
 	})
 }
 
+func TestRoutingPolicyModel(t *testing.T) {
+	db := setupTestDB(t)
+
+	t.Run("Create policy", func(t *testing.T) {
+		policy := RoutingPolicy{
+			Kind:   PolicyPrefixList,
+			Name:   "PL-IN",
+			Config: "ip prefix-list PL-IN seq 10 permit 10.0.0.0/8\n",
+		}
+		err := db.Create(&policy).Error
+		assert.NoError(t, err)
+		assert.NotZero(t, policy.ID)
+	})
+
+	t.Run("Unique kind and name", func(t *testing.T) {
+		duplicate := RoutingPolicy{Kind: PolicyPrefixList, Name: "PL-IN", Config: "!"}
+		assert.Error(t, db.Create(&duplicate).Error)
+
+		sameName := RoutingPolicy{Kind: PolicyRouteMap, Name: "PL-IN", Config: "!"}
+		assert.NoError(t, db.Create(&sameName).Error)
+	})
+
+	t.Run("Table name", func(t *testing.T) {
+		assert.Equal(t, "routing_policies", RoutingPolicy{}.TableName())
+	})
+}
+
 func TestAlertModel(t *testing.T) {
 	db := setupTestDB(t)
 
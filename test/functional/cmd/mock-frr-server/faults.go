@@ -0,0 +1,262 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"path"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// AllRPCs is the RPC name that scripts a fault for every RPC
+const AllRPCs = "*"
+
+// RPCError is an error injected into an RPC
+type RPCError struct {
+	Code    codes.Code `json:"code"`
+	Message string     `json:"message"`
+	// Remaining is the number of calls still to fail, 0 failing every call
+	// until the error is cleared
+	Remaining int `json:"remaining"`
+	// Rate is the fraction of calls that fail, picked at random; 0 fails
+	// every call. Calls that go through don't count towards Remaining.
+	Rate float64 `json:"rate,omitempty"`
+}
+
+// clock is the time source of the faults and the listener drop, replaced in
+// tests
+type clock interface {
+	// NewTimer returns a channel that receives once d has passed and a
+	// function stopping the timer
+	NewTimer(d time.Duration) (<-chan time.Time, func() bool)
+	// AfterFunc calls f in its own goroutine once d has passed
+	AfterFunc(d time.Duration, f func())
+}
+
+// realClock is the wall clock
+type realClock struct{}
+
+func (realClock) NewTimer(d time.Duration) (<-chan time.Time, func() bool) {
+	timer := time.NewTimer(d)
+	return timer.C, timer.Stop
+}
+
+func (realClock) AfterFunc(d time.Duration, f func()) {
+	time.AfterFunc(d, f)
+}
+
+// Faults holds the faults scripted through the HTTP control plane, keyed by
+// RPC name (AddBGPPeer, GetBGPSessionState, ...) or AllRPCs
+type Faults struct {
+	mu      sync.Mutex
+	errors  map[string]*RPCError
+	latency map[string]time.Duration
+	rand    *rand.Rand // picks the calls failing at a rate; guarded by mu
+	clock   clock
+}
+
+// FaultsSnapshot is the scripted faults as reported by GET /faults
+type FaultsSnapshot struct {
+	Errors  map[string]RPCError `json:"errors"`
+	Latency map[string]Duration `json:"latency"`
+}
+
+// NewFaults creates an empty fault registry
+func NewFaults() *Faults {
+	return newFaults(rand.New(rand.NewSource(time.Now().UnixNano())), realClock{})
+}
+
+// newFaults creates an empty fault registry drawing on rng and clock
+func newFaults(rng *rand.Rand, clock clock) *Faults {
+	return &Faults{
+		errors:  make(map[string]*RPCError),
+		latency: make(map[string]time.Duration),
+		rand:    rng,
+		clock:   clock,
+	}
+}
+
+// SetError makes the next count calls of rpc fail with code, or every call
+// when count is 0. With a rate between 0 and 1, only that fraction of the
+// calls fails.
+func (f *Faults) SetError(rpc string, code codes.Code, message string, count int, rate float64) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if message == "" {
+		message = fmt.Sprintf("simulated %s error", code)
+	}
+	f.errors[rpc] = &RPCError{Code: code, Message: message, Remaining: count, Rate: rate}
+}
+
+// SetLatency delays every call of rpc, removing the delay when latency is 0
+func (f *Faults) SetLatency(rpc string, latency time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if latency <= 0 {
+		delete(f.latency, rpc)
+		return
+	}
+	f.latency[rpc] = latency
+}
+
+// Clear removes the faults scripted for rpc, or all faults when rpc is empty
+func (f *Faults) Clear(rpc string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if rpc == "" {
+		f.errors = make(map[string]*RPCError)
+		f.latency = make(map[string]time.Duration)
+		return
+	}
+	delete(f.errors, rpc)
+	delete(f.latency, rpc)
+}
+
+// Snapshot returns a copy of the scripted faults
+func (f *Faults) Snapshot() FaultsSnapshot {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	snapshot := FaultsSnapshot{
+		Errors:  make(map[string]RPCError, len(f.errors)),
+		Latency: make(map[string]Duration, len(f.latency)),
+	}
+	for rpc, e := range f.errors {
+		snapshot.Errors[rpc] = *e
+	}
+	for rpc, latency := range f.latency {
+		snapshot.Latency[rpc] = Duration(latency)
+	}
+	return snapshot
+}
+
+// Apply waits out the latency scripted for rpc and returns the error
+// scripted for it, if any. Errors scripted for rpc itself take precedence
+// over ones scripted for AllRPCs.
+func (f *Faults) Apply(ctx context.Context, rpc string) error {
+	f.mu.Lock()
+	latency, ok := f.latency[rpc]
+	if !ok {
+		latency = f.latency[AllRPCs]
+	}
+	f.mu.Unlock()
+
+	if latency > 0 {
+		elapsed, stop := f.clock.NewTimer(latency)
+		defer stop()
+		select {
+		case <-elapsed:
+		case <-ctx.Done():
+			return status.FromContextError(ctx.Err()).Err()
+		}
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	key := rpc
+	e, ok := f.errors[key]
+	if !ok {
+		key = AllRPCs
+		if e, ok = f.errors[key]; !ok {
+			return nil
+		}
+	}
+	if e.Rate > 0 && f.rand.Float64() >= e.Rate {
+		return nil
+	}
+	if e.Remaining > 0 {
+		e.Remaining--
+		if e.Remaining == 0 {
+			delete(f.errors, key)
+		}
+	}
+	return status.Error(e.Code, e.Message)
+}
+
+// UnaryInterceptor applies the scripted faults to unary gRPC calls
+func (f *Faults) UnaryInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if err := f.Apply(ctx, path.Base(info.FullMethod)); err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamInterceptor applies the scripted faults to streaming gRPC calls
+func (f *Faults) StreamInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if err := f.Apply(ss.Context(), path.Base(info.FullMethod)); err != nil {
+			return err
+		}
+		return handler(srv, ss)
+	}
+}
+
+// httpStatus maps an injected gRPC code to the status the HTTP interface
+// answers with
+func httpStatus(code codes.Code) int {
+	switch code {
+	case codes.InvalidArgument, codes.FailedPrecondition, codes.OutOfRange:
+		return http.StatusBadRequest
+	case codes.Unauthenticated:
+		return http.StatusUnauthorized
+	case codes.PermissionDenied:
+		return http.StatusForbidden
+	case codes.NotFound:
+		return http.StatusNotFound
+	case codes.AlreadyExists, codes.Aborted:
+		return http.StatusConflict
+	case codes.ResourceExhausted:
+		return http.StatusTooManyRequests
+	case codes.Unimplemented:
+		return http.StatusNotImplemented
+	case codes.Unavailable:
+		return http.StatusServiceUnavailable
+	case codes.DeadlineExceeded:
+		return http.StatusGatewayTimeout
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// Duration is a time.Duration that is read from and written to JSON as a
+// string such as "500ms". Plain numbers are read as nanoseconds.
+type Duration time.Duration
+
+// MarshalJSON implements json.Marshaler
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// UnmarshalJSON implements json.Unmarshaler
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var value interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+	switch v := value.(type) {
+	case float64:
+		*d = Duration(v)
+	case string:
+		parsed, err := time.ParseDuration(v)
+		if err != nil {
+			return err
+		}
+		*d = Duration(parsed)
+	default:
+		return fmt.Errorf("invalid duration: %s", data)
+	}
+	return nil
+}
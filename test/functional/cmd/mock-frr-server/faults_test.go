@@ -0,0 +1,361 @@
+package main
+
+import (
+	"context"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/padminisys/flintroute/pkg/bgpsim"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// seed seeds the random numbers picking the calls that fail at a rate
+const seed = 42
+
+// fakeClock is a clock that only moves on Advance
+type fakeClock struct {
+	mu     sync.Mutex
+	now    time.Duration
+	timers []*fakeTimer
+}
+
+type fakeTimer struct {
+	at time.Duration
+	c  chan time.Time
+	f  func()
+}
+
+func (c *fakeClock) NewTimer(d time.Duration) (<-chan time.Time, func() bool) {
+	timer := &fakeTimer{c: make(chan time.Time, 1)}
+	c.add(timer, d)
+	return timer.c, func() bool { return c.remove(timer) }
+}
+
+func (c *fakeClock) AfterFunc(d time.Duration, f func()) {
+	c.add(&fakeTimer{f: f}, d)
+}
+
+func (c *fakeClock) add(timer *fakeTimer, d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	timer.at = c.now + d
+	c.timers = append(c.timers, timer)
+}
+
+func (c *fakeClock) remove(timer *fakeTimer) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i, pending := range c.timers {
+		if pending == timer {
+			c.timers = append(c.timers[:i], c.timers[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// pending returns the number of timers yet to fire
+func (c *fakeClock) pending() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.timers)
+}
+
+// Advance moves the clock by d, firing the timers that are due
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	c.now += d
+	var due []*fakeTimer
+	pending := c.timers[:0]
+	for _, timer := range c.timers {
+		if timer.at <= c.now {
+			due = append(due, timer)
+		} else {
+			pending = append(pending, timer)
+		}
+	}
+	c.timers = pending
+	c.mu.Unlock()
+
+	for _, timer := range due {
+		if timer.f != nil {
+			timer.f()
+		} else {
+			timer.c <- time.Time{}
+		}
+	}
+}
+
+func TestFaultErrorRates(t *testing.T) {
+	// failures returns the number of calls out of calls that the seeded
+	// random numbers fail at rate, stopping at count failures unless 0
+	failures := func(rate float64, count, calls int) int {
+		if rate == 0 {
+			rate = 1
+		}
+		rng := rand.New(rand.NewSource(seed))
+		failed := 0
+		for i := 0; i < calls && (count == 0 || failed < count); i++ {
+			if rng.Float64() < rate {
+				failed++
+			}
+		}
+		return failed
+	}
+
+	tests := []struct {
+		name  string
+		rpc   string
+		count int
+		rate  float64
+		want  int
+	}{
+		{name: "Every call", rpc: "AddBGPPeer", want: 1000},
+		{name: "Number of calls", rpc: "AddBGPPeer", count: 3, want: 3},
+		{name: "Rate", rpc: "AddBGPPeer", rate: 0.25, want: failures(0.25, 0, 1000)},
+		{name: "Rate and number of calls", rpc: "AddBGPPeer", count: 5, rate: 0.5, want: 5},
+		{name: "Every RPC", rpc: AllRPCs, rate: 0.1, want: failures(0.1, 0, 1000)},
+		{name: "Other RPC", rpc: "DeleteBGPPeer", want: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			faults := newFaults(rand.New(rand.NewSource(seed)), &fakeClock{})
+			faults.SetError(tt.rpc, codes.Unavailable, "", tt.count, tt.rate)
+
+			failed := 0
+			for i := 0; i < 1000; i++ {
+				err := faults.Apply(context.Background(), "AddBGPPeer")
+				if err != nil {
+					failed++
+					assert.Equal(t, codes.Unavailable, status.Code(err))
+					assert.Equal(t, "simulated Unavailable error", status.Convert(err).Message())
+				}
+			}
+			assert.Equal(t, tt.want, failed)
+			if tt.rate > 0 && tt.count == 0 {
+				assert.InDelta(t, tt.rate*1000, failed, 50, "about the rate of calls fail")
+			}
+			if tt.count > 0 {
+				assert.Empty(t, faults.Snapshot().Errors, "the error is cleared once used up")
+			}
+		})
+	}
+
+	t.Run("The RPC's own error comes first", func(t *testing.T) {
+		faults := newFaults(rand.New(rand.NewSource(seed)), &fakeClock{})
+		faults.SetError(AllRPCs, codes.Internal, "everything", 0, 0)
+		faults.SetError("AddBGPPeer", codes.AlreadyExists, "peer exists", 1, 0)
+
+		assert.Equal(t, codes.AlreadyExists, status.Code(faults.Apply(context.Background(), "AddBGPPeer")))
+		assert.Equal(t, codes.Internal, status.Code(faults.Apply(context.Background(), "AddBGPPeer")))
+
+		faults.Clear("")
+		assert.NoError(t, faults.Apply(context.Background(), "AddBGPPeer"))
+	})
+
+	t.Run("Rate is validated", func(t *testing.T) {
+		server := NewMockFRRServer(&ServerConfig{}, zap.NewNop())
+		for _, body := range []string{
+			`{"rpc": "AddBGPPeer", "code": "UNAVAILABLE", "rate": 1.5}`,
+			`{"rpc": "AddBGPPeer", "code": "UNAVAILABLE", "rate": -0.1}`,
+		} {
+			w := httptest.NewRecorder()
+			server.handleInjectError(w, httptest.NewRequest(http.MethodPost, "/faults/error", strings.NewReader(body)))
+			assert.Equal(t, http.StatusBadRequest, w.Code, body)
+		}
+		assert.Empty(t, server.faults.Snapshot().Errors)
+	})
+}
+
+func TestFaultLatency(t *testing.T) {
+	// apply applies the faults of rpc in the background, returning the
+	// channel its result arrives on
+	apply := func(ctx context.Context, faults *Faults, rpc string) <-chan error {
+		done := make(chan error, 1)
+		go func() { done <- faults.Apply(ctx, rpc) }()
+		return done
+	}
+	// waiting waits until clock has a pending timer
+	waiting := func(t *testing.T, clock *fakeClock) {
+		t.Helper()
+		require.Eventually(t, func() bool { return clock.pending() == 1 }, time.Second, time.Millisecond)
+	}
+
+	tests := []struct {
+		name string
+		rpc  string
+		want time.Duration
+	}{
+		{name: "Latency of the RPC", rpc: "AddBGPPeer", want: 2 * time.Second},
+		{name: "Latency of every RPC", rpc: "GetBGPSessionState", want: 500 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clock := &fakeClock{}
+			faults := newFaults(rand.New(rand.NewSource(seed)), clock)
+			faults.SetLatency(AllRPCs, 500*time.Millisecond)
+			faults.SetLatency("AddBGPPeer", 2*time.Second)
+
+			done := apply(context.Background(), faults, tt.rpc)
+			waiting(t, clock)
+			clock.Advance(tt.want - time.Millisecond)
+			select {
+			case <-done:
+				t.Fatal("the call returned before its latency had passed")
+			default:
+			}
+
+			clock.Advance(time.Millisecond)
+			assert.NoError(t, <-done)
+		})
+	}
+
+	t.Run("Error after the latency", func(t *testing.T) {
+		clock := &fakeClock{}
+		faults := newFaults(rand.New(rand.NewSource(seed)), clock)
+		faults.SetLatency("AddBGPPeer", time.Second)
+		faults.SetError("AddBGPPeer", codes.DeadlineExceeded, "too slow", 1, 0)
+
+		done := apply(context.Background(), faults, "AddBGPPeer")
+		waiting(t, clock)
+		clock.Advance(time.Second)
+		assert.Equal(t, codes.DeadlineExceeded, status.Code(<-done))
+	})
+
+	t.Run("Cancelled while delayed", func(t *testing.T) {
+		clock := &fakeClock{}
+		faults := newFaults(rand.New(rand.NewSource(seed)), clock)
+		faults.SetLatency("AddBGPPeer", time.Minute)
+		faults.SetError("AddBGPPeer", codes.Internal, "", 0, 0)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		done := apply(ctx, faults, "AddBGPPeer")
+		waiting(t, clock)
+		cancel()
+
+		assert.Equal(t, codes.Canceled, status.Code(<-done))
+		assert.Zero(t, clock.pending(), "the timer is stopped")
+	})
+
+	t.Run("Zero latency removes the delay", func(t *testing.T) {
+		clock := &fakeClock{}
+		faults := newFaults(rand.New(rand.NewSource(seed)), clock)
+		faults.SetLatency("AddBGPPeer", time.Second)
+		faults.SetLatency("AddBGPPeer", 0)
+
+		assert.NoError(t, faults.Apply(context.Background(), "AddBGPPeer"))
+		assert.Empty(t, faults.Snapshot().Latency)
+	})
+}
+
+func TestDropListener(t *testing.T) {
+	// newServer returns a server whose gRPC listener is up, timed by clock
+	newServer := func(clock *fakeClock) *MockFRRServer {
+		server := NewMockFRRServer(&ServerConfig{}, zap.NewNop())
+		server.clock = clock
+		server.grpcServer = grpc.NewServer()
+		return server
+	}
+
+	t.Run("Comes back after the duration", func(t *testing.T) {
+		clock := &fakeClock{}
+		server := newServer(clock)
+
+		require.NoError(t, server.DropListener(5*time.Second))
+		assert.True(t, server.Dropped())
+		assert.EqualError(t, server.DropListener(time.Second), "listener is already dropped")
+
+		clock.Advance(5*time.Second - time.Millisecond)
+		assert.True(t, server.Dropped())
+		clock.Advance(time.Millisecond)
+		assert.False(t, server.Dropped())
+		assert.EqualError(t, server.RestoreListener(), "listener is not dropped")
+	})
+
+	t.Run("Stays dropped until restored", func(t *testing.T) {
+		clock := &fakeClock{}
+		server := newServer(clock)
+
+		require.NoError(t, server.DropListener(0))
+		clock.Advance(time.Hour)
+		assert.True(t, server.Dropped())
+
+		require.NoError(t, server.RestoreListener())
+		assert.False(t, server.Dropped())
+	})
+
+	t.Run("An earlier drop's timer leaves a later drop alone", func(t *testing.T) {
+		clock := &fakeClock{}
+		server := newServer(clock)
+
+		require.NoError(t, server.DropListener(5*time.Second))
+		require.NoError(t, server.RestoreListener())
+		require.NoError(t, server.DropListener(0))
+
+		clock.Advance(5 * time.Second)
+		assert.True(t, server.Dropped())
+	})
+
+	t.Run("Refused before starting and after stopping", func(t *testing.T) {
+		server := NewMockFRRServer(&ServerConfig{}, zap.NewNop())
+		assert.EqualError(t, server.DropListener(0), "listener is not started")
+
+		server = newServer(&fakeClock{})
+		server.Stop()
+		assert.EqualError(t, server.DropListener(0), "server is stopped")
+	})
+}
+
+func TestScenarioOrdering(t *testing.T) {
+	const ip = "192.0.2.1"
+	server := NewMockFRRServer(&ServerConfig{}, zap.NewNop())
+	require.NoError(t, server.state.AddPeer(&bgpsim.PeerState{IPAddress: ip, RemoteASN: 65002}))
+
+	post := func(t *testing.T, handler http.HandlerFunc, body string) {
+		t.Helper()
+		w := httptest.NewRecorder()
+		handler(w, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body)))
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	}
+	// reaches waits for the session to reach state and checks it stays there
+	reaches := func(t *testing.T, state string) {
+		t.Helper()
+		require.Eventually(t, func() bool {
+			session, err := server.state.GetSessionState(ip)
+			return err == nil && session.State == state
+		}, time.Second, time.Millisecond)
+		time.Sleep(10 * time.Millisecond)
+		session, err := server.state.GetSessionState(ip)
+		require.NoError(t, err)
+		assert.Equal(t, state, session.State, "the session moved on from a held state")
+	}
+
+	// Stepping the hold through the states walks the flap through them one
+	// at a time, which fails if a step is skipped or out of order
+	post(t, server.handleHold, `{"ip_address": "`+ip+`", "state": "Connect"}`)
+	post(t, server.handleFlap, `{"ip_address": "`+ip+`", "interval": "1ms"}`)
+	reaches(t, bgpsim.StateConnect)
+	session, _ := server.state.GetSessionState(ip)
+	assert.Equal(t, "simulated session flap", session.LastError)
+
+	for _, state := range []string{bgpsim.StateActive, bgpsim.StateOpenSent, bgpsim.StateOpenConfirm, bgpsim.StateEstablished} {
+		post(t, server.handleHold, `{"ip_address": "`+ip+`", "state": "`+state+`"}`)
+		reaches(t, state)
+	}
+
+	post(t, server.handleRelease, `{"ip_address": "`+ip+`"}`)
+	w := httptest.NewRecorder()
+	server.handleRelease(w, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"ip_address": "`+ip+`"}`)))
+	assert.Equal(t, http.StatusNotFound, w.Code, "the hold is gone once released")
+}
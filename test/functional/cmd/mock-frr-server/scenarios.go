@@ -0,0 +1,387 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/padminisys/flintroute/pkg/bgpsim"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// FlapRequest is the body of POST /scenarios/flap
+type FlapRequest struct {
+	IPAddress string `json:"ip_address"`
+	// Count is the number of flaps, 1 when unset
+	Count int `json:"count"`
+	// Interval is the wait between flaps, session_state_delay when unset
+	Interval Duration `json:"interval"`
+}
+
+// HoldRequest is the body of POST /scenarios/hold
+type HoldRequest struct {
+	IPAddress string `json:"ip_address"`
+	// State is the establishment state to stop at, OpenSent when unset
+	State string `json:"state"`
+	// Duration releases the hold after it has passed; unset holds until
+	// POST /scenarios/release
+	Duration Duration `json:"duration"`
+}
+
+// ReleaseRequest is the body of POST /scenarios/release
+type ReleaseRequest struct {
+	IPAddress string `json:"ip_address"`
+}
+
+// InjectRoutesRequest is the body of POST /routes/inject
+type InjectRoutesRequest struct {
+	Peer   string              `json:"peer"`
+	Routes []bgpsim.RouteEntry `json:"routes"`
+	// Generate injects Count consecutive prefixes from Start, e.g. 1000 /24s
+	// from 10.100.0.0/24, with default attributes
+	Generate *struct {
+		Start string `json:"start"`
+		Count int    `json:"count"`
+	} `json:"generate"`
+}
+
+// WithdrawRoutesRequest is the body of POST /routes/withdraw
+type WithdrawRoutesRequest struct {
+	Peer string `json:"peer"`
+	// Prefixes are the prefixes to withdraw; unset withdraws every route of
+	// the peer
+	Prefixes []string `json:"prefixes"`
+}
+
+// ErrorFaultRequest is the body of POST /faults/error
+type ErrorFaultRequest struct {
+	// RPC is the RPC to fail, or "*" for every RPC
+	RPC string `json:"rpc"`
+	// Code is the gRPC status code, as a number or a name such as
+	// "UNAVAILABLE"
+	Code    codes.Code `json:"code"`
+	Message string     `json:"message"`
+	// Count is the number of calls to fail; unset fails every call until
+	// cleared
+	Count int `json:"count"`
+	// Rate is the fraction of calls to fail, between 0 and 1; unset fails
+	// every call
+	Rate float64 `json:"rate"`
+}
+
+// LatencyFaultRequest is the body of POST /faults/latency
+type LatencyFaultRequest struct {
+	// RPC is the RPC to delay, or "*" for every RPC
+	RPC string `json:"rpc"`
+	// Latency is added to every call; unset removes the delay
+	Latency Duration `json:"latency"`
+}
+
+// ClearFaultsRequest is the optional body of POST /faults/clear
+type ClearFaultsRequest struct {
+	// RPC is the RPC whose faults to clear; unset clears every fault
+	RPC string `json:"rpc"`
+}
+
+// DropListenerRequest is the optional body of POST /grpc/drop
+type DropListenerRequest struct {
+	// Duration restores the listener after it has passed; unset keeps it
+	// dropped until POST /grpc/restore
+	Duration Duration `json:"duration"`
+}
+
+// applyFaults applies the faults scripted for rpc to an HTTP call, writing
+// the injected error. It reports whether the call should go on.
+func (s *MockFRRServer) applyFaults(w http.ResponseWriter, r *http.Request, rpc string) bool {
+	err := s.faults.Apply(r.Context(), rpc)
+	if err == nil {
+		return true
+	}
+
+	st := status.Convert(err)
+	s.logger.Debug("Injected RPC error", zap.String("rpc", rpc), zap.String("code", st.Code().String()))
+	http.Error(w, st.Message(), httpStatus(st.Code()))
+	return false
+}
+
+func (s *MockFRRServer) handleFlap(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req FlapRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if req.Count < 0 {
+		http.Error(w, "count must be non-negative", http.StatusBadRequest)
+		return
+	}
+	if req.Count == 0 {
+		req.Count = 1
+	}
+	interval := time.Duration(req.Interval)
+	if interval <= 0 {
+		interval = s.config.Simulation.SessionStateDelay
+	}
+
+	if err := s.state.SimulateFlap(req.IPAddress, req.Count, interval, s.config.Simulation.SessionStateDelay); err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	s.logger.Info("Scripted session flap",
+		zap.String("ip_address", req.IPAddress),
+		zap.Int("count", req.Count),
+		zap.Duration("interval", interval),
+	)
+	writeSuccess(w, "session flap scheduled")
+}
+
+func (s *MockFRRServer) handleHold(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req HoldRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if req.State == "" {
+		req.State = bgpsim.StateOpenSent
+	}
+
+	if err := s.state.HoldState(req.IPAddress, req.State, time.Duration(req.Duration)); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	s.logger.Info("Scripted state hold",
+		zap.String("ip_address", req.IPAddress),
+		zap.String("state", req.State),
+		zap.Duration("duration", time.Duration(req.Duration)),
+	)
+	writeSuccess(w, "state hold set")
+}
+
+func (s *MockFRRServer) handleRelease(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req ReleaseRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := s.state.ReleaseState(req.IPAddress); err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	writeSuccess(w, "state hold released")
+}
+
+func (s *MockFRRServer) handleInjectRoutes(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req InjectRoutesRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if len(req.Routes) == 0 && req.Generate == nil {
+		http.Error(w, "routes or generate is required", http.StatusBadRequest)
+		return
+	}
+	if req.Generate != nil && req.Generate.Count <= 0 {
+		http.Error(w, "generate count must be positive", http.StatusBadRequest)
+		return
+	}
+
+	if err := s.state.InjectRoutes(req.Peer, req.Routes); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	count := len(req.Routes)
+	if req.Generate != nil {
+		if err := s.state.GenerateRoutes(req.Peer, req.Generate.Start, req.Generate.Count); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		count += req.Generate.Count
+	}
+
+	s.logger.Info("Injected routes", zap.String("peer", req.Peer), zap.Int("count", count))
+	writeSuccess(w, fmt.Sprintf("%d routes injected", count))
+}
+
+func (s *MockFRRServer) handleWithdrawRoutes(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req WithdrawRoutesRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	removed, err := s.state.WithdrawRoutes(req.Peer, req.Prefixes)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	s.logger.Info("Withdrew routes", zap.String("peer", req.Peer), zap.Int("count", removed))
+	writeSuccess(w, fmt.Sprintf("%d routes withdrawn", removed))
+}
+
+func (s *MockFRRServer) handleGetFaults(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"faults":           s.faults.Snapshot(),
+		"listener_dropped": s.Dropped(),
+	})
+}
+
+func (s *MockFRRServer) handleInjectError(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req ErrorFaultRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if req.RPC == "" {
+		http.Error(w, "rpc is required", http.StatusBadRequest)
+		return
+	}
+	if req.Code == codes.OK {
+		http.Error(w, "code must be an error code", http.StatusBadRequest)
+		return
+	}
+	if req.Count < 0 {
+		http.Error(w, "count must be non-negative", http.StatusBadRequest)
+		return
+	}
+	if req.Rate < 0 || req.Rate > 1 {
+		http.Error(w, "rate must be between 0 and 1", http.StatusBadRequest)
+		return
+	}
+
+	s.faults.SetError(req.RPC, req.Code, req.Message, req.Count, req.Rate)
+
+	s.logger.Info("Scripted RPC error",
+		zap.String("rpc", req.RPC),
+		zap.String("code", req.Code.String()),
+		zap.Int("count", req.Count),
+		zap.Float64("rate", req.Rate),
+	)
+	writeSuccess(w, "error injected")
+}
+
+func (s *MockFRRServer) handleInjectLatency(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req LatencyFaultRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if req.RPC == "" {
+		http.Error(w, "rpc is required", http.StatusBadRequest)
+		return
+	}
+
+	s.faults.SetLatency(req.RPC, time.Duration(req.Latency))
+
+	s.logger.Info("Scripted RPC latency",
+		zap.String("rpc", req.RPC),
+		zap.Duration("latency", time.Duration(req.Latency)),
+	)
+	writeSuccess(w, "latency set")
+}
+
+func (s *MockFRRServer) handleClearFaults(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req ClearFaultsRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	s.faults.Clear(req.RPC)
+	writeSuccess(w, "faults cleared")
+}
+
+func (s *MockFRRServer) handleDropListener(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req DropListenerRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	if err := s.DropListener(time.Duration(req.Duration)); err != nil {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+
+	writeSuccess(w, "listener dropped")
+}
+
+func (s *MockFRRServer) handleRestoreListener(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if err := s.RestoreListener(); err != nil {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+
+	writeSuccess(w, "listener restored")
+}
+
+// writeSuccess writes the success response the HTTP interface answers
+// control requests with
+func writeSuccess(w http.ResponseWriter, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"message": message,
+	})
+}
@@ -3,43 +3,49 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net"
 	"net/http"
+	"sync"
+	"time"
 
+	"github.com/padminisys/flintroute/pkg/bgpsim"
 	"go.uber.org/zap"
 	"google.golang.org/grpc"
 )
 
 // MockFRRServer implements a mock FRR gRPC service
 type MockFRRServer struct {
-	state      *BGPState
+	state      *bgpsim.BGPState
+	faults     *Faults
 	config     *ServerConfig
 	logger     *zap.Logger
-	grpcServer *grpc.Server
 	httpServer *http.Server
+	clock      clock
+
+	// grpcMu guards the gRPC server, which is replaced whenever the listener
+	// is restored after being dropped
+	grpcMu     sync.Mutex
+	grpcServer *grpc.Server
+	restored   chan struct{} // non-nil while the listener is dropped
+	stopped    bool
 }
 
 // NewMockFRRServer creates a new mock FRR server instance
 func NewMockFRRServer(config *ServerConfig, logger *zap.Logger) *MockFRRServer {
 	return &MockFRRServer{
-		state:  NewBGPState(),
+		state:  bgpsim.NewBGPState(),
+		faults: NewFaults(),
 		config: config,
 		logger: logger,
+		clock:  realClock{},
 	}
 }
 
-// Start starts the mock FRR server
+// Start starts the mock FRR server. It serves gRPC until stopped, listening
+// again whenever a dropped listener is restored.
 func (s *MockFRRServer) Start() error {
-	// Create gRPC server
-	s.grpcServer = grpc.NewServer()
-
-	// Start gRPC listener
-	lis, err := net.Listen("tcp", s.config.GetAddress())
-	if err != nil {
-		return fmt.Errorf("failed to listen: %w", err)
-	}
-
 	s.logger.Info("Mock FRR server starting",
 		zap.String("address", s.config.GetAddress()),
 	)
@@ -47,20 +53,133 @@ func (s *MockFRRServer) Start() error {
 	// Start HTTP server for testing/debugging
 	go s.startHTTPServer()
 
-	// Start gRPC server
-	if err := s.grpcServer.Serve(lis); err != nil {
+	for {
+		if err := s.serveGRPC(); err != nil {
+			return err
+		}
+
+		// Serve returned because the server was stopped or the listener dropped
+		s.grpcMu.Lock()
+		restored := s.restored
+		stopped := s.stopped
+		s.grpcMu.Unlock()
+		if stopped || restored == nil {
+			return nil
+		}
+		<-restored
+	}
+}
+
+// serveGRPC listens on the configured address and serves gRPC until the
+// server is stopped
+func (s *MockFRRServer) serveGRPC() error {
+	s.grpcMu.Lock()
+	if s.stopped {
+		s.grpcMu.Unlock()
+		return nil
+	}
+	server := grpc.NewServer(
+		grpc.UnaryInterceptor(s.faults.UnaryInterceptor()),
+		grpc.StreamInterceptor(s.faults.StreamInterceptor()),
+	)
+	s.grpcServer = server
+	s.grpcMu.Unlock()
+
+	lis, err := net.Listen("tcp", s.config.GetAddress())
+	if err != nil {
+		return fmt.Errorf("failed to listen: %w", err)
+	}
+
+	if err := server.Serve(lis); err != nil && !errors.Is(err, grpc.ErrServerStopped) {
 		return fmt.Errorf("failed to serve: %w", err)
 	}
 
 	return nil
 }
 
+// DropListener closes the gRPC listener and every open connection, as if FRR
+// went away. The listener comes back after duration when it is positive,
+// and otherwise on RestoreListener.
+func (s *MockFRRServer) DropListener(duration time.Duration) error {
+	s.grpcMu.Lock()
+	if s.stopped {
+		s.grpcMu.Unlock()
+		return fmt.Errorf("server is stopped")
+	}
+	if s.restored != nil {
+		s.grpcMu.Unlock()
+		return fmt.Errorf("listener is already dropped")
+	}
+	if s.grpcServer == nil {
+		s.grpcMu.Unlock()
+		return fmt.Errorf("listener is not started")
+	}
+	restored := make(chan struct{})
+	s.restored = restored
+	server := s.grpcServer
+	s.grpcMu.Unlock()
+
+	s.logger.Info("Dropping gRPC listener", zap.Duration("duration", duration))
+	server.Stop()
+
+	if duration > 0 {
+		s.clock.AfterFunc(duration, func() {
+			s.restoreListener(restored)
+		})
+	}
+
+	return nil
+}
+
+// RestoreListener brings a dropped gRPC listener back
+func (s *MockFRRServer) RestoreListener() error {
+	s.grpcMu.Lock()
+	restored := s.restored
+	s.grpcMu.Unlock()
+
+	if restored == nil || !s.restoreListener(restored) {
+		return fmt.Errorf("listener is not dropped")
+	}
+	return nil
+}
+
+// restoreListener ends the drop identified by restored, reporting false when
+// it has already ended
+func (s *MockFRRServer) restoreListener(restored chan struct{}) bool {
+	s.grpcMu.Lock()
+	defer s.grpcMu.Unlock()
+
+	if s.restored != restored {
+		return false
+	}
+	s.restored = nil
+	close(restored)
+	s.logger.Info("Restoring gRPC listener")
+	return true
+}
+
+// Dropped reports whether the gRPC listener is dropped
+func (s *MockFRRServer) Dropped() bool {
+	s.grpcMu.Lock()
+	defer s.grpcMu.Unlock()
+	return s.restored != nil
+}
+
 // Stop stops the mock FRR server
 func (s *MockFRRServer) Stop() {
 	s.logger.Info("Stopping mock FRR server")
 
-	if s.grpcServer != nil {
-		s.grpcServer.GracefulStop()
+	s.grpcMu.Lock()
+	s.stopped = true
+	if s.restored != nil {
+		close(s.restored)
+		s.restored = nil
+	}
+	server := s.grpcServer
+	s.grpcMu.Unlock()
+
+	if server != nil {
+		server.GracefulStop()
 	}
 
 	if s.httpServer != nil {
@@ -92,6 +211,22 @@ func (s *MockFRRServer) startHTTPServer() {
 	// Config endpoint
 	mux.HandleFunc("/config", s.handleGetConfig)
 
+	// RIB endpoint
+	mux.HandleFunc("/bgp/routes", s.handleGetRoutes)
+
+	// Scenario scripting and fault injection endpoints
+	mux.HandleFunc("/scenarios/flap", s.handleFlap)
+	mux.HandleFunc("/scenarios/hold", s.handleHold)
+	mux.HandleFunc("/scenarios/release", s.handleRelease)
+	mux.HandleFunc("/routes/inject", s.handleInjectRoutes)
+	mux.HandleFunc("/routes/withdraw", s.handleWithdrawRoutes)
+	mux.HandleFunc("/faults", s.handleGetFaults)
+	mux.HandleFunc("/faults/error", s.handleInjectError)
+	mux.HandleFunc("/faults/latency", s.handleInjectLatency)
+	mux.HandleFunc("/faults/clear", s.handleClearFaults)
+	mux.HandleFunc("/grpc/drop", s.handleDropListener)
+	mux.HandleFunc("/grpc/restore", s.handleRestoreListener)
+
 	httpPort := s.config.Server.Port + 1000 // HTTP on port+1000
 	httpAddr := fmt.Sprintf("%s:%d", s.config.Server.Host, httpPort)
 
@@ -122,6 +257,7 @@ func (s *MockFRRServer) handleStats(w http.ResponseWriter, r *http.Request) {
 	stats := map[string]interface{}{
 		"total_peers":          s.state.GetPeerCount(),
 		"established_sessions": s.state.GetEstablishedSessionCount(),
+		"total_routes":         s.state.GetRouteCount(),
 	}
 	json.NewEncoder(w).Encode(stats)
 }
@@ -138,7 +274,7 @@ func (s *MockFRRServer) handleAddPeer(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var peer PeerState
+	var peer bgpsim.PeerState
 	if err := json.NewDecoder(r.Body).Decode(&peer); err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
@@ -149,6 +285,9 @@ func (s *MockFRRServer) handleAddPeer(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "simulated error: failed to add peer", http.StatusInternalServerError)
 		return
 	}
+	if !s.applyFaults(w, r, "AddBGPPeer") {
+		return
+	}
 
 	if err := s.state.AddPeer(&peer); err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
@@ -184,6 +323,9 @@ func (s *MockFRRServer) handleRemovePeer(w http.ResponseWriter, r *http.Request)
 		http.Error(w, "simulated error: failed to remove peer", http.StatusInternalServerError)
 		return
 	}
+	if !s.applyFaults(w, r, "RemoveBGPPeer") {
+		return
+	}
 
 	if err := s.state.RemovePeer(req.IPAddress); err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
@@ -203,7 +345,7 @@ func (s *MockFRRServer) handleUpdatePeer(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	var peer PeerState
+	var peer bgpsim.PeerState
 	if err := json.NewDecoder(r.Body).Decode(&peer); err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
@@ -214,6 +356,9 @@ func (s *MockFRRServer) handleUpdatePeer(w http.ResponseWriter, r *http.Request)
 		http.Error(w, "simulated error: failed to update peer", http.StatusInternalServerError)
 		return
 	}
+	if !s.applyFaults(w, r, "UpdateBGPPeer") {
+		return
+	}
 
 	if err := s.state.UpdatePeer(&peer); err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
@@ -228,6 +373,9 @@ func (s *MockFRRServer) handleUpdatePeer(w http.ResponseWriter, r *http.Request)
 }
 
 func (s *MockFRRServer) handleGetAllSessions(w http.ResponseWriter, r *http.Request) {
+	if !s.applyFaults(w, r, "GetAllBGPSessions") {
+		return
+	}
 	w.Header().Set("Content-Type", "application/json")
 	sessions := s.state.GetAllSessions()
 	json.NewEncoder(w).Encode(sessions)
@@ -240,6 +388,10 @@ func (s *MockFRRServer) handleGetSessionState(w http.ResponseWriter, r *http.Req
 		return
 	}
 
+	if !s.applyFaults(w, r, "GetBGPSessionState") {
+		return
+	}
+
 	session, err := s.state.GetSessionState(ipAddress)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusNotFound)
@@ -250,7 +402,33 @@ func (s *MockFRRServer) handleGetSessionState(w http.ResponseWriter, r *http.Req
 	json.NewEncoder(w).Encode(session)
 }
 
+func (s *MockFRRServer) handleGetRoutes(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	filter := bgpsim.RouteFilter{
+		Peer:     query.Get("peer"),
+		Prefix:   query.Get("prefix"),
+		Family:   query.Get("family"),
+		BestOnly: query.Get("best") == "true",
+	}
+
+	if !s.applyFaults(w, r, "GetBGPRoutes") {
+		return
+	}
+
+	routes, err := s.state.GetRoutes(filter)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(routes)
+}
+
 func (s *MockFRRServer) handleGetConfig(w http.ResponseWriter, r *http.Request) {
+	if !s.applyFaults(w, r, "GetRunningConfig") {
+		return
+	}
 	config := s.generateMockConfig()
 	w.Header().Set("Content-Type", "text/plain")
 	w.Write([]byte(config))
@@ -0,0 +1,71 @@
+// Command test-runner discovers and runs the functional tests under ./tests
+// against a running FlintRoute server and writes JSON and JUnit reports.
+// Run it from the test/functional directory.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/yourusername/flintroute/test/functional/pkg/runner"
+)
+
+func main() {
+	configPath := flag.String("config", "", "Path to runner configuration file (defaults are used when empty)")
+	pattern := flag.String("pattern", "*_test.go", "Glob matched against test file names")
+	parallel := flag.Int("parallel", 0, "Number of test files to run at once (0 uses the configuration)")
+	logLevel := flag.String("log-level", "", "Log level: debug, info, warn or error (overrides the configuration)")
+	flag.Parse()
+
+	config, err := runner.LoadConfig(*configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to load config: %v\n", err)
+		os.Exit(2)
+	}
+	if *parallel > 0 {
+		config.Parallel = *parallel > 1
+		config.Workers = *parallel
+	}
+	if *logLevel != "" {
+		config.LogLevel = *logLevel
+	}
+
+	executor, err := runner.NewTestExecutor(config)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to create test executor: %v\n", err)
+		os.Exit(2)
+	}
+
+	if err := executor.Setup(); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to set up test environment: %v\n", err)
+		executor.Teardown()
+		os.Exit(2)
+	}
+
+	// An interrupt stops starting test files; the reports cover the ones run
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	runErr := executor.RunTests(ctx, *pattern)
+	if runErr == nil {
+		runErr = executor.GenerateReports()
+	}
+	if err := executor.Teardown(); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to tear down test environment: %v\n", err)
+	}
+
+	if runErr != nil {
+		fmt.Fprintf(os.Stderr, "Test run failed: %v\n", runErr)
+		os.Exit(2)
+	}
+	if ctx.Err() != nil {
+		fmt.Fprintln(os.Stderr, "Test run interrupted")
+		os.Exit(2)
+	}
+	if executor.GetResults().HasFailures() {
+		os.Exit(1)
+	}
+}
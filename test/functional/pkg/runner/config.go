@@ -3,6 +3,7 @@ package runner
 import (
 	"fmt"
 	"os"
+	"runtime"
 	"time"
 
 	"gopkg.in/yaml.v3"
@@ -14,14 +15,21 @@ type TestConfig struct {
 	DatabasePath     string        `yaml:"database_path"`
 	MockFRRURL       string        `yaml:"mock_frr_url"`
 	Timeout          time.Duration `yaml:"timeout"`
+	TestTimeout      time.Duration `yaml:"test_timeout"` // per test file, including the build
 	CleanupOnSuccess bool          `yaml:"cleanup_on_success"`
 	LogLevel         string        `yaml:"log_level"`
 	Parallel         bool          `yaml:"parallel"`
+	Workers          int           `yaml:"workers"` // test files run at once when parallel; defaults to the CPU count
 	FixturesPath     string        `yaml:"fixtures_path"`
 	ResultsPath      string        `yaml:"results_path"`
 	LogsPath         string        `yaml:"logs_path"`
 	MaxRetries       int           `yaml:"max_retries"`
-	RetryDelay       time.Duration `yaml:"retry_delay"`
+	RetryDelay       time.Duration `yaml:"retry_delay"`     // before the first retry, doubling for every further one
+	MaxRetryDelay    time.Duration `yaml:"max_retry_delay"` // caps the doubled retry delay
+	RetryOn          []string      `yaml:"retry_on"`        // output substrings marking an infrastructure error worth a retry
+	ReportFormats    []string      `yaml:"report_formats"` // json, xml, html and markdown
+
+	Environment EnvironmentConfig `yaml:"environment"`
 }
 
 // DefaultConfig returns a default test configuration
@@ -31,6 +39,7 @@ func DefaultConfig() *TestConfig {
 		DatabasePath:     "./tmp/test.db",
 		MockFRRURL:       "localhost:50051",
 		Timeout:          30 * time.Second,
+		TestTimeout:      5 * time.Minute,
 		CleanupOnSuccess: true,
 		LogLevel:         "info",
 		Parallel:         false,
@@ -39,6 +48,15 @@ func DefaultConfig() *TestConfig {
 		LogsPath:         "./logs",
 		MaxRetries:       3,
 		RetryDelay:       1 * time.Second,
+		MaxRetryDelay:    30 * time.Second,
+		RetryOn:          DefaultRetryOn(),
+		ReportFormats:    []string{ReportJSON, ReportXML, ReportHTML, ReportMarkdown},
+		Environment: EnvironmentConfig{
+			RepoRoot:       "../..",
+			ServerPackage:  "./cmd/flintroute",
+			MockFRRDir:     "./cmd/mock-frr-server",
+			StartupTimeout: 2 * time.Minute,
+		},
 	}
 }
 
@@ -85,6 +103,10 @@ func (c *TestConfig) Validate() error {
 		return fmt.Errorf("timeout must be positive")
 	}
 
+	if c.TestTimeout <= 0 {
+		c.TestTimeout = 5 * time.Minute
+	}
+
 	if c.LogLevel == "" {
 		c.LogLevel = "info"
 	}
@@ -109,9 +131,54 @@ func (c *TestConfig) Validate() error {
 		c.RetryDelay = 1 * time.Second
 	}
 
+	if c.MaxRetryDelay < c.RetryDelay {
+		c.MaxRetryDelay = max(c.RetryDelay, 30*time.Second)
+	}
+
+	if len(c.RetryOn) == 0 {
+		c.RetryOn = DefaultRetryOn()
+	}
+
+	if len(c.ReportFormats) == 0 {
+		c.ReportFormats = []string{ReportJSON, ReportXML, ReportHTML, ReportMarkdown}
+	}
+	for _, format := range c.ReportFormats {
+		switch format {
+		case ReportJSON, ReportXML, ReportHTML, ReportMarkdown:
+		default:
+			return fmt.Errorf("invalid report format: %s (must be json, xml, html or markdown)", format)
+		}
+	}
+
+	if c.Environment.StartupTimeout <= 0 {
+		c.Environment.StartupTimeout = 2 * time.Minute
+	}
+
+	if c.Parallel && c.Workers <= 0 {
+		c.Workers = runtime.NumCPU()
+	}
+
 	return nil
 }
 
+// RetryBackoff returns the delay before retrying a test that failed on the
+// given attempt: retry_delay after the first, doubling up to max_retry_delay
+func (c *TestConfig) RetryBackoff(attempt int) time.Duration {
+	delay := c.RetryDelay
+	for i := 1; i < attempt && delay < c.MaxRetryDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, c.MaxRetryDelay)
+}
+
+// WorkerCount returns how many test files run at once
+func (c *TestConfig) WorkerCount() int {
+	if !c.Parallel || c.Workers < 1 {
+		return 1
+	}
+	return c.Workers
+}
+
 // SaveConfig saves the configuration to a YAML file
 func (c *TestConfig) SaveConfig(configPath string) error {
 	data, err := yaml.Marshal(c)
@@ -0,0 +1,337 @@
+package runner
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/yourusername/flintroute/test/functional/pkg/testutil"
+	"go.uber.org/zap"
+)
+
+// EnvironmentConfig configures the environment manager, which starts the
+// FlintRoute server and the mock FRR server for a test run instead of
+// expecting them at server_url and mock_frr_url
+type EnvironmentConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// RepoRoot is the FlintRoute repository the server is built from
+	RepoRoot string `yaml:"repo_root"`
+	// ServerPackage is the server's main package, relative to RepoRoot
+	ServerPackage string `yaml:"server_package"`
+	// ServerBinary is a prebuilt server, used instead of building one
+	ServerBinary string `yaml:"server_binary"`
+	// MockFRRDir is the mock FRR server's module directory
+	MockFRRDir string `yaml:"mock_frr_dir"`
+	// MockFRRBinary is a prebuilt mock FRR server, used instead of building one
+	MockFRRBinary string `yaml:"mock_frr_binary"`
+	// StartupTimeout bounds the build and the wait for both servers
+	StartupTimeout time.Duration `yaml:"startup_timeout"`
+	// KeepDir keeps the temporary directory, with the databases and the
+	// servers' logs, after teardown
+	KeepDir bool `yaml:"keep_dir"`
+}
+
+// Environment is a FlintRoute server and mock FRR server started for a test
+// run on ephemeral ports, with their state in a temporary directory
+type Environment struct {
+	config EnvironmentConfig
+	logger *testutil.TestLogger
+
+	dir     string
+	server  *exec.Cmd
+	mockFRR *exec.Cmd
+	exited  map[*exec.Cmd]chan error
+
+	// ServerURL is the base URL of the started FlintRoute server
+	ServerURL string
+	// MockFRRAddr is the host:port of the started mock FRR server
+	MockFRRAddr string
+}
+
+// NewEnvironment creates an environment manager. Nothing is started until
+// Start.
+func NewEnvironment(config EnvironmentConfig, logger *testutil.TestLogger) *Environment {
+	return &Environment{
+		config: config,
+		logger: logger,
+		exited: make(map[*exec.Cmd]chan error),
+	}
+}
+
+// Start builds and launches the mock FRR server and the FlintRoute server
+// and waits until both accept requests. On failure everything started so
+// far is stopped again.
+func (env *Environment) Start(ctx context.Context) (err error) {
+	ctx, cancel := context.WithTimeout(ctx, env.config.StartupTimeout)
+	defer cancel()
+	defer func() {
+		if err != nil {
+			env.Stop()
+		}
+	}()
+
+	env.dir, err = os.MkdirTemp("", "flintroute-functional-")
+	if err != nil {
+		return fmt.Errorf("failed to create environment directory: %w", err)
+	}
+	for _, dir := range []string{"bin", "logs"} {
+		if err := os.MkdirAll(filepath.Join(env.dir, dir), 0755); err != nil {
+			return fmt.Errorf("failed to create environment directory: %w", err)
+		}
+	}
+	env.logger.Info("Environment directory created", zap.String("dir", env.dir))
+
+	serverBinary, err := env.binary(ctx, env.config.ServerBinary, env.config.RepoRoot, env.config.ServerPackage, "flintroute")
+	if err != nil {
+		return err
+	}
+	mockFRRBinary, err := env.binary(ctx, env.config.MockFRRBinary, env.config.MockFRRDir, ".", "mock-frr-server")
+	if err != nil {
+		return err
+	}
+
+	ports, err := allocatePorts("127.0.0.1", 2)
+	if err != nil {
+		return err
+	}
+	env.MockFRRAddr = net.JoinHostPort("127.0.0.1", strconv.Itoa(ports[0]))
+	env.ServerURL = "http://" + net.JoinHostPort("127.0.0.1", strconv.Itoa(ports[1]))
+
+	// Mock FRR server
+	mockConfig := filepath.Join(env.dir, "mock-frr.yaml")
+	if err := os.WriteFile(mockConfig, []byte(fmt.Sprintf(mockFRRConfigTemplate,
+		ports[0], filepath.Join(env.dir, "logs", "mock-frr-server.json"))), 0644); err != nil {
+		return fmt.Errorf("failed to write mock FRR config: %w", err)
+	}
+	env.mockFRR, err = env.launch("mock-frr-server", mockFRRBinary, []string{"-config", mockConfig}, nil)
+	if err != nil {
+		return err
+	}
+	if err := env.waitFor(ctx, env.mockFRR, "mock-frr-server", func() error {
+		conn, err := net.DialTimeout("tcp", env.MockFRRAddr, time.Second)
+		if err == nil {
+			conn.Close()
+		}
+		return err
+	}); err != nil {
+		return err
+	}
+	env.logger.Info("Mock FRR server ready", zap.String("addr", env.MockFRRAddr))
+
+	// FlintRoute server. Running it in the environment directory keeps it
+	// from picking up a config file; everything is set through the
+	// environment.
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return fmt.Errorf("failed to generate JWT secret: %w", err)
+	}
+	env.server, err = env.launch("flintroute", serverBinary, nil, []string{
+		"FLINTROUTE_SERVER_HOST=127.0.0.1",
+		"FLINTROUTE_SERVER_PORT=" + strconv.Itoa(ports[1]),
+		"FLINTROUTE_DATABASE_PATH=" + env.DatabasePath(),
+		"FLINTROUTE_DATABASE_ENCRYPTION_KEY_FILE=" + filepath.Join(env.dir, "encryption.key"),
+		"FLINTROUTE_FRR_TRANSPORT=grpc",
+		"FLINTROUTE_FRR_GRPC_HOST=127.0.0.1",
+		"FLINTROUTE_FRR_GRPC_PORT=" + strconv.Itoa(ports[0]),
+		// Don't hold readiness back for long when the mock doesn't speak gRPC
+		"FLINTROUTE_FRR_STARTUP_TIMEOUT=5s",
+		"FLINTROUTE_AUTH_JWT_SECRET=" + hex.EncodeToString(secret),
+	})
+	if err != nil {
+		return err
+	}
+	client := &http.Client{Timeout: time.Second}
+	if err := env.waitFor(ctx, env.server, "flintroute", func() error {
+		resp, err := client.Get(env.ServerURL + "/health/ready")
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return fmt.Errorf("readiness check returned %d", resp.StatusCode)
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+	env.logger.Info("FlintRoute server ready", zap.String("url", env.ServerURL))
+
+	return nil
+}
+
+// Stop stops both servers and removes the environment directory unless
+// configured to keep it
+func (env *Environment) Stop() error {
+	var errs []error
+	// Stop the server first so that it doesn't log FRR connection errors
+	for _, cmd := range []*exec.Cmd{env.server, env.mockFRR} {
+		if err := env.stop(cmd); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	env.server, env.mockFRR = nil, nil
+
+	if env.dir != "" {
+		if env.config.KeepDir {
+			env.logger.Info("Environment directory kept", zap.String("dir", env.dir))
+		} else if err := os.RemoveAll(env.dir); err != nil {
+			errs = append(errs, fmt.Errorf("failed to remove environment directory: %w", err))
+		}
+		env.dir = ""
+	}
+
+	return errors.Join(errs...)
+}
+
+// Env returns the environment variables that point a test process at the
+// started servers
+func (env *Environment) Env() []string {
+	return []string{
+		testutil.EnvServerURL + "=" + env.ServerURL,
+		testutil.EnvMockFRRAddr + "=" + env.MockFRRAddr,
+	}
+}
+
+// DatabasePath returns the started server's database file
+func (env *Environment) DatabasePath() string {
+	return filepath.Join(env.dir, "flintroute.db")
+}
+
+// binary returns prebuilt if set, and otherwise builds pkg in dir into the
+// environment's bin directory
+func (env *Environment) binary(ctx context.Context, prebuilt, dir, pkg, name string) (string, error) {
+	if prebuilt != "" {
+		return filepath.Abs(prebuilt)
+	}
+
+	output := filepath.Join(env.dir, "bin", name)
+	cmd := exec.CommandContext(ctx, "go", "build", "-o", output, pkg)
+	cmd.Dir = dir
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	env.logger.Info("Building binary", zap.String("binary", name), zap.String("dir", dir), zap.String("package", pkg))
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("failed to build %s: %w: %s", name, err, strings.TrimSpace(stderr.String()))
+	}
+	return output, nil
+}
+
+// launch starts a binary in the environment directory, logging its output
+// to logs/<name>.log
+func (env *Environment) launch(name, binary string, args, extraEnv []string) (*exec.Cmd, error) {
+	logFile, err := os.Create(filepath.Join(env.dir, "logs", name+".log"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %s log: %w", name, err)
+	}
+
+	cmd := exec.Command(binary, args...)
+	cmd.Dir = env.dir
+	cmd.Env = append(os.Environ(), extraEnv...)
+	cmd.Stdout = logFile
+	cmd.Stderr = logFile
+	if err := cmd.Start(); err != nil {
+		logFile.Close()
+		return nil, fmt.Errorf("failed to start %s: %w", name, err)
+	}
+
+	exited := make(chan error, 1)
+	env.exited[cmd] = exited
+	go func() {
+		exited <- cmd.Wait()
+		logFile.Close()
+	}()
+
+	env.logger.Info("Process started", zap.String("process", name), zap.Int("pid", cmd.Process.Pid))
+	return cmd, nil
+}
+
+// waitFor polls ready until it succeeds, cmd exits or ctx is done
+func (env *Environment) waitFor(ctx context.Context, cmd *exec.Cmd, name string, ready func() error) error {
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+
+	for {
+		err := ready()
+		if err == nil {
+			return nil
+		}
+
+		select {
+		case exitErr := <-env.exited[cmd]:
+			// Keep the result for stop
+			env.exited[cmd] <- exitErr
+			return fmt.Errorf("%s exited before becoming ready (%v): %s", name, exitErr, env.logTail(name))
+		case <-ctx.Done():
+			return fmt.Errorf("%s not ready after %s: %w", name, env.config.StartupTimeout, err)
+		case <-ticker.C:
+		}
+	}
+}
+
+// stop interrupts a process and kills it if it hasn't exited after a grace
+// period
+func (env *Environment) stop(cmd *exec.Cmd) error {
+	if cmd == nil {
+		return nil
+	}
+	exited := env.exited[cmd]
+	delete(env.exited, cmd)
+
+	select {
+	case <-exited:
+		// Already gone
+		return nil
+	default:
+	}
+
+	if err := cmd.Process.Signal(os.Interrupt); err != nil {
+		return cmd.Process.Kill()
+	}
+	select {
+	case <-exited:
+		return nil
+	case <-time.After(10 * time.Second):
+		env.logger.Warn("Process didn't stop, killing it", zap.Int("pid", cmd.Process.Pid))
+		return cmd.Process.Kill()
+	}
+}
+
+// logTail returns the end of a process's log, for error messages
+func (env *Environment) logTail(name string) string {
+	data, err := os.ReadFile(filepath.Join(env.dir, "logs", name+".log"))
+	if err != nil {
+		return ""
+	}
+	const maxTail = 2048
+	if len(data) > maxTail {
+		data = data[len(data)-maxTail:]
+	}
+	return strings.TrimSpace(string(data))
+}
+
+// mockFRRConfigTemplate is the mock FRR server's configuration, taking the
+// port and the log file
+const mockFRRConfigTemplate = `server:
+  host: 127.0.0.1
+  port: %d
+
+simulation:
+  session_state_delay: 100ms
+  error_injection: false
+
+logging:
+  level: info
+  file: %s
+`
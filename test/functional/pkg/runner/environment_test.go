@@ -0,0 +1,147 @@
+package runner
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yourusername/flintroute/test/functional/pkg/testutil"
+)
+
+// fakeServersEnv makes the test binary, launched as both servers, stand in
+// for them. Its value is what the fake mock FRR server does: "listen" on its
+// configured port, or "hang" without listening. The fake FlintRoute server
+// always fails to start.
+const fakeServersEnv = "RUNNER_TEST_FAKE_SERVERS"
+
+func TestMain(m *testing.M) {
+	if behavior := os.Getenv(fakeServersEnv); behavior != "" {
+		os.Exit(fakeServer(behavior, os.Args[1:]))
+	}
+	os.Exit(m.Run())
+}
+
+// fakeServer runs the test binary as a fake server, telling the mock FRR
+// server apart by its -config argument
+func fakeServer(behavior string, args []string) int {
+	if len(args) != 2 || args[0] != "-config" {
+		fmt.Fprintln(os.Stderr, "failed to open database: database is locked")
+		return 1
+	}
+	if behavior == "listen" {
+		port, err := configuredPort(args[1])
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		l, err := net.Listen("tcp", net.JoinHostPort("127.0.0.1", port))
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		defer l.Close()
+	}
+	// Serve until interrupted
+	select {}
+}
+
+// configuredPort reads the port from a mock FRR configuration
+func configuredPort(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if port, ok := strings.CutPrefix(strings.TrimSpace(scanner.Text()), "port: "); ok {
+			return port, nil
+		}
+	}
+	return "", fmt.Errorf("no port in %s", path)
+}
+
+func TestEnvironmentStartFailure(t *testing.T) {
+	logger, err := testutil.NewTestLogger("", "error")
+	require.NoError(t, err)
+	self, err := os.Executable()
+	require.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		behavior string
+		config   EnvironmentConfig
+		wantErr  string
+		started  bool // the mock FRR server was started and given ports
+	}{
+		{
+			name:     "FlintRoute server exits",
+			behavior: "listen",
+			config:   EnvironmentConfig{ServerBinary: self, MockFRRBinary: self, StartupTimeout: 10 * time.Second},
+			wantErr:  "flintroute exited before becoming ready",
+			started:  true,
+		},
+		{
+			name:     "Mock FRR server never ready",
+			behavior: "hang",
+			config:   EnvironmentConfig{ServerBinary: self, MockFRRBinary: self, StartupTimeout: 300 * time.Millisecond},
+			wantErr:  "mock-frr-server not ready after 300ms",
+			started:  true,
+		},
+		{
+			name:     "Mock FRR server doesn't build",
+			behavior: "listen",
+			config:   EnvironmentConfig{ServerBinary: self, MockFRRDir: "", StartupTimeout: 10 * time.Second},
+			wantErr:  "failed to build mock-frr-server",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmp := t.TempDir()
+			t.Setenv("TMPDIR", tmp)
+			t.Setenv(fakeServersEnv, tt.behavior)
+			if tt.config.MockFRRBinary == "" {
+				// A module without a main package
+				tt.config.MockFRRDir = t.TempDir()
+				require.NoError(t, os.WriteFile(filepath.Join(tt.config.MockFRRDir, "go.mod"), []byte("module empty\n"), 0644))
+			}
+
+			env := NewEnvironment(tt.config, logger)
+			err := env.Start(t.Context())
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.wantErr)
+
+			// Every process started is stopped
+			assert.True(t, env.server == nil, "the FlintRoute server is still running")
+			assert.True(t, env.mockFRR == nil, "the mock FRR server is still running")
+			assert.Len(t, env.exited, 0, "processes are still tracked")
+
+			// The environment directory is removed
+			entries, err := os.ReadDir(tmp)
+			require.NoError(t, err)
+			assert.Empty(t, entries)
+
+			// The allocated ports are free again, the mock FRR server's
+			// included
+			if !tt.started {
+				assert.Empty(t, env.MockFRRAddr)
+				return
+			}
+			for _, addr := range []string{env.MockFRRAddr, strings.TrimPrefix(env.ServerURL, "http://")} {
+				l, err := net.Listen("tcp", addr)
+				if assert.NoError(t, err, "port %s is still in use", addr) {
+					l.Close()
+				}
+			}
+		})
+	}
+}
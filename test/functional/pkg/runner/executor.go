@@ -1,24 +1,34 @@
 package runner
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
 	"os"
+	"os/exec"
 	"path/filepath"
 	"strings"
 	"time"
 
-	"github.com/yourusername/flintroute/test/functional/pkg/client"
+	"github.com/padminisys/flintroute/pkg/client"
 	"github.com/yourusername/flintroute/test/functional/pkg/testutil"
+	"go.uber.org/zap"
 )
 
 // TestExecutor manages test execution
 type TestExecutor struct {
-	config      *TestConfig
-	apiClient   *client.APIClient
-	dbManager   *testutil.DatabaseManager
-	logger      *testutil.TestLogger
-	results     *TestResults
+	config        *TestConfig
+	apiClient     *client.APIClient
+	dbManager     *testutil.DatabaseManager
+	logger        *testutil.TestLogger
+	results       *TestResults
 	fixtureLoader *testutil.FixtureLoader
+	environment   *Environment
 }
 
 // NewTestExecutor creates a new test executor
@@ -64,6 +74,18 @@ func (e *TestExecutor) Setup() error {
 	e.logger = logger
 	e.logger.Info("Test executor initialized")
 
+	// Start the servers the tests run against
+	if e.config.Environment.Enabled {
+		e.environment = NewEnvironment(e.config.Environment, logger)
+		if err := e.environment.Start(context.Background()); err != nil {
+			e.environment = nil
+			return fmt.Errorf("failed to start test environment: %w", err)
+		}
+		e.config.ServerURL = e.environment.ServerURL
+		e.config.MockFRRURL = e.environment.MockFRRAddr
+		e.logger.Info("Test environment started")
+	}
+
 	// Initialize API client
 	e.apiClient = client.NewAPIClient(e.config.ServerURL, logger.GetZapLogger())
 	e.apiClient.SetTimeout(e.config.Timeout)
@@ -86,7 +108,7 @@ func (e *TestExecutor) Setup() error {
 	e.logger.Info("Fixture loader initialized")
 
 	// Verify server is reachable
-	if err := e.apiClient.HealthCheck(); err != nil {
+	if err := e.apiClient.HealthCheck(context.Background()); err != nil {
 		return fmt.Errorf("server health check failed: %w", err)
 	}
 	e.logger.Info("Server health check passed")
@@ -96,8 +118,21 @@ func (e *TestExecutor) Setup() error {
 
 // Teardown cleans up the test environment
 func (e *TestExecutor) Teardown() error {
+	// Setup failed before anything was created
+	if e.logger == nil {
+		return nil
+	}
+
 	e.logger.Info("Starting teardown")
 
+	// Stop the servers started for the run
+	if e.environment != nil {
+		if err := e.environment.Stop(); err != nil {
+			e.logger.Error("Failed to stop test environment", zap.Error(err))
+		}
+		e.environment = nil
+	}
+
 	// Close database
 	if e.dbManager != nil {
 		if err := e.dbManager.Close(); err != nil {
@@ -124,8 +159,9 @@ func (e *TestExecutor) Teardown() error {
 	return nil
 }
 
-// RunTests discovers and runs tests matching the pattern
-func (e *TestExecutor) RunTests(pattern string) error {
+// RunTests discovers and runs tests matching the pattern. Once ctx is done,
+// the test files that haven't started are reported as skipped.
+func (e *TestExecutor) RunTests(ctx context.Context, pattern string) error {
 	e.logger.Info("Starting test run")
 
 	// Discover tests
@@ -141,19 +177,24 @@ func (e *TestExecutor) RunTests(pattern string) error {
 
 	e.logger.Info("Tests discovered")
 
-	// Run tests
-	for _, testPath := range tests {
-		result, err := e.ExecuteTest(testPath)
-		if err != nil {
-			e.logger.Error("Failed to execute test")
-			result = &TestResult{
-				Name:     testPath,
-				Status:   "failed",
-				Error:    err.Error(),
-				Duration: 0,
+	// Run tests, on several workers when parallel execution is enabled
+	workers := e.config.WorkerCount()
+	if workers > len(tests) {
+		workers = len(tests)
+	}
+	if workers > 1 {
+		e.logger.Info("Running tests in parallel", zap.Int("workers", workers))
+		if err := e.runParallel(ctx, tests, workers); err != nil {
+			return fmt.Errorf("failed to run tests in parallel: %w", err)
+		}
+	} else {
+		for _, testPath := range tests {
+			if ctx.Err() != nil {
+				e.results.AddResult(cancelledResult(testPath))
+				continue
 			}
+			e.results.AddResult(e.runTest(testPath, nil))
 		}
-		e.results.AddResult(result)
 	}
 
 	// Finalize results
@@ -162,6 +203,85 @@ func (e *TestExecutor) RunTests(pattern string) error {
 	return nil
 }
 
+// runTest executes a test file on worker w, or in the shared environment
+// when w is nil. A failure caused by the infrastructure rather than the test
+// is retried up to max_retries times with exponential backoff, and a test
+// that passes on a retry is reported as flaky.
+func (e *TestExecutor) runTest(testPath string, w *worker) *TestResult {
+	result := e.retryTest(testPath, func() *TestResult {
+		return e.attemptTest(testPath, w)
+	})
+	if w != nil {
+		result.Worker = w.id
+	}
+	return result
+}
+
+// retryTest runs attempt until it doesn't fail on an infrastructure error,
+// or max_retries retries have failed
+func (e *TestExecutor) retryTest(testPath string, attempt func() *TestResult) *TestResult {
+	var result *TestResult
+	var reason string
+	for n := 1; ; n++ {
+		result = attempt()
+		result.Attempts = n
+		result.RetryReason = reason
+		if result.Status != "failed" {
+			result.Flaky = n > 1
+			break
+		}
+
+		reason = retryReason(result, e.config.RetryOn)
+		if reason == "" {
+			break
+		}
+		if n > e.config.MaxRetries {
+			e.logger.Warn("Giving up on test after retries",
+				zap.String("test", testPath),
+				zap.Int("attempts", n),
+				zap.String("reason", reason),
+			)
+			break
+		}
+
+		delay := e.config.RetryBackoff(n)
+		e.logger.Warn("Retrying test after infrastructure error",
+			zap.String("test", testPath),
+			zap.Int("attempt", n+1),
+			zap.String("reason", reason),
+			zap.Duration("delay", delay),
+		)
+		time.Sleep(delay)
+	}
+	return result
+}
+
+// attemptTest executes a test file once. A test that can't be executed is
+// reported as failed.
+func (e *TestExecutor) attemptTest(testPath string, w *worker) *TestResult {
+	var result *TestResult
+	var err error
+	if w != nil {
+		// Every test file starts with an empty worker database
+		if err = w.dbManager.Clean(); err != nil {
+			err = fmt.Errorf("failed to clean worker %d database: %w", w.id, err)
+		}
+	}
+	if err == nil {
+		result, err = e.executeTest(testPath, w)
+	}
+	if err != nil {
+		e.logger.Error("Failed to execute test", zap.String("test", testPath), zap.Error(err))
+		result = &TestResult{
+			Name:     testPath,
+			Status:   "failed",
+			Error:    err.Error(),
+			Duration: 0,
+		}
+	}
+	return result
+}
+
 // DiscoverTests finds all test files matching the pattern
 func (e *TestExecutor) DiscoverTests(pattern string) ([]string, error) {
 	testsDir := "./tests"
@@ -203,32 +323,184 @@ func (e *TestExecutor) DiscoverTests(pattern string) ([]string, error) {
 	return tests, nil
 }
 
-// ExecuteTest executes a single test file
+// ExecuteTest executes a single test file by running its test functions
+// with go test in the file's package directory. The run is killed once it
+// exceeds the configured per-test timeout.
 func (e *TestExecutor) ExecuteTest(testPath string) (*TestResult, error) {
+	return e.executeTest(testPath, nil)
+}
+
+// executeTest executes a test file, pointing it at worker w's resources
+// when w isn't nil
+func (e *TestExecutor) executeTest(testPath string, w *worker) (*TestResult, error) {
 	startTime := time.Now()
 	testName := filepath.Base(testPath)
 
 	e.logger.LogTestStart(testName)
 
-	result := &TestResult{
-		Name:   testName,
-		Status: "passed",
+	names, err := testFunctions(testPath)
+	if err != nil {
+		return nil, err
 	}
 
-	// Note: In a real implementation, this would execute the Go test file
-	// For now, this is a placeholder that would need to be integrated with
-	// the actual test execution mechanism (e.g., using go test command or
-	// importing and running test functions directly)
+	result := &TestResult{Name: testName}
+	if len(names) == 0 {
+		result.Status = "skipped"
+		result.Error = "no test functions found"
+		e.logger.LogTestSkipped(testName, result.Error)
+		return result, nil
+	}
 
-	// Placeholder implementation
-	e.logger.Info("Test execution placeholder")
+	ctx, cancel := context.WithTimeout(context.Background(), e.config.TestTimeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, "go", "test",
+		"-count=1",
+		"-json",
+		"-timeout", e.config.TestTimeout.String(),
+		"-run", "^("+strings.Join(names, "|")+")$",
+		".",
+	)
+	cmd.Dir = filepath.Dir(testPath)
+	cmd.Env = os.Environ()
+	if e.environment != nil {
+		cmd.Env = append(cmd.Env, e.environment.Env()...)
+	}
+	if w != nil {
+		cmd.Env = append(cmd.Env, w.env()...)
+	}
+	// The test binary may outlive a killed go command and keep the output
+	// pipes open
+	cmd.WaitDelay = 5 * time.Second
 
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	runErr := cmd.Run()
 	result.Duration = time.Since(startTime)
+
+	run := parseTestEvents(stdout.Bytes())
+	result.Output = run.output + stderr.String()
+
+	var exitErr *exec.ExitError
+	switch {
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		result.Status = "failed"
+		result.Error = fmt.Sprintf("timed out after %s", e.config.TestTimeout)
+	case runErr == nil && len(run.skipped) == len(names):
+		result.Status = "skipped"
+		result.Error = "all tests skipped"
+	case runErr == nil:
+		result.Status = "passed"
+	case errors.As(runErr, &exitErr):
+		result.Status = "failed"
+		if len(run.failed) > 0 {
+			result.Error = "failed: " + strings.Join(run.failed, ", ")
+		} else {
+			// No test failed, so the package didn't build or go test itself
+			// failed
+			result.Error = fmt.Sprintf("go test exited with code %d: %s", exitErr.ExitCode(),
+				strings.TrimSpace(run.buildOutput+stderr.String()))
+		}
+	default:
+		return nil, fmt.Errorf("failed to run go test: %w", runErr)
+	}
+
+	e.logger.Info("Test file executed",
+		zap.String("test", testName),
+		zap.String("status", result.Status),
+		zap.Strings("passed", run.passed),
+		zap.Strings("failed", run.failed),
+		zap.Strings("skipped", run.skipped),
+	)
 	e.logger.LogTestEnd(testName, result.Status == "passed", result.Duration)
 
 	return result, nil
 }
 
+// testFunctions returns the names of the top-level test functions declared
+// in a test file
+func testFunctions(testPath string) ([]string, error) {
+	file, err := parser.ParseFile(token.NewFileSet(), testPath, nil, parser.SkipObjectResolution)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", testPath, err)
+	}
+
+	var names []string
+	for _, decl := range file.Decls {
+		fn, ok := decl.(*ast.FuncDecl)
+		if !ok || fn.Recv != nil || !strings.HasPrefix(fn.Name.Name, "Test") {
+			continue
+		}
+		// TestMain has a *testing.M parameter and isn't a test
+		if fn.Name.Name == "TestMain" || fn.Type.Params.NumFields() != 1 {
+			continue
+		}
+		names = append(names, fn.Name.Name)
+	}
+	return names, nil
+}
+
+// testEvent is a line of go test -json output
+type testEvent struct {
+	Action string `json:"Action"`
+	Test   string `json:"Test"`
+	Output string `json:"Output"`
+}
+
+// testRun is the outcome of a go test -json run
+type testRun struct {
+	passed  []string
+	failed  []string
+	skipped []string
+	output  string
+	// buildOutput holds the compiler's output when the package failed to
+	// build
+	buildOutput string
+}
+
+// parseTestEvents collects the outcome of the top-level tests and the
+// printed output from go test -json output. Lines that aren't events, such
+// as build errors, are kept as output.
+func parseTestEvents(data []byte) testRun {
+	var run testRun
+	var output strings.Builder
+
+	for _, line := range bytes.Split(data, []byte("\n")) {
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+
+		var event testEvent
+		if err := json.Unmarshal(line, &event); err != nil {
+			output.Write(line)
+			output.WriteByte('\n')
+			continue
+		}
+
+		output.WriteString(event.Output)
+		if event.Action == "build-output" {
+			run.buildOutput += event.Output
+		}
+		// Subtest outcomes are part of their parent's
+		if event.Test == "" || strings.Contains(event.Test, "/") {
+			continue
+		}
+		switch event.Action {
+		case "pass":
+			run.passed = append(run.passed, event.Test)
+		case "fail":
+			run.failed = append(run.failed, event.Test)
+		case "skip":
+			run.skipped = append(run.skipped, event.Test)
+		}
+	}
+
+	run.output = output.String()
+	return run
+}
+
 // GetResults returns the test results
 func (e *TestExecutor) GetResults() *TestResults {
 	return e.results
@@ -262,26 +534,46 @@ func (e *TestExecutor) CleanDatabase() error {
 	return e.dbManager.Clean()
 }
 
-// GenerateReports generates test reports in multiple formats
+// GenerateReports generates test reports in the configured formats,
+// comparing the results against the previous JSON report first
 func (e *TestExecutor) GenerateReports() error {
 	timestamp := time.Now().Format("20060102-150405")
 
-	// Generate JSON report
-	jsonPath := filepath.Join(e.config.ResultsPath, fmt.Sprintf("results-%s.json", timestamp))
-	if err := e.results.GenerateJSONReport(jsonPath); err != nil {
-		return fmt.Errorf("failed to generate JSON report: %w", err)
+	// Compare against the previous run. Without a usable previous report the
+	// reports just have no trend.
+	previousPath, err := FindPreviousResults(e.config.ResultsPath)
+	if err != nil {
+		e.logger.Warn("Failed to find previous results", zap.Error(err))
+	} else if previousPath != "" {
+		previous, err := LoadResults(previousPath)
+		if err != nil {
+			e.logger.Warn("Failed to load previous results", zap.String("path", previousPath), zap.Error(err))
+		} else {
+			e.results.CompareWith(previous, filepath.Base(previousPath))
+			e.logger.Info("Compared with previous results", zap.String("path", previousPath))
+		}
 	}
-	e.logger.Info("JSON report generated")
 
-	// Generate XML report
-	xmlPath := filepath.Join(e.config.ResultsPath, fmt.Sprintf("results-%s.xml", timestamp))
-	if err := e.results.GenerateXMLReport(xmlPath); err != nil {
-		return fmt.Errorf("failed to generate XML report: %w", err)
+	generators := map[string]struct {
+		ext      string
+		generate func(string) error
+	}{
+		ReportJSON:     {"json", e.results.GenerateJSONReport},
+		ReportXML:      {"xml", e.results.GenerateXMLReport},
+		ReportHTML:     {"html", e.results.GenerateHTMLReport},
+		ReportMarkdown: {"md", e.results.GenerateMarkdownReport},
+	}
+	for _, format := range e.config.ReportFormats {
+		generator := generators[format]
+		path := filepath.Join(e.config.ResultsPath, fmt.Sprintf("results-%s.%s", timestamp, generator.ext))
+		if err := generator.generate(path); err != nil {
+			return fmt.Errorf("failed to generate %s report: %w", format, err)
+		}
+		e.logger.Info("Report generated", zap.String("format", format), zap.String("path", path))
 	}
-	e.logger.Info("XML report generated")
 
 	// Print summary
 	e.results.PrintSummary()
 
 	return nil
-}
\ No newline at end of file
+}
@@ -0,0 +1,159 @@
+package runner
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseTestEvents(t *testing.T) {
+	tests := []struct {
+		name        string
+		events      []string
+		passed      []string
+		failed      []string
+		skipped     []string
+		output      string
+		buildOutput string
+	}{
+		{
+			name: "pass, fail and skip",
+			events: []string{
+				`{"Action":"run","Test":"TestLogin"}`,
+				`{"Action":"pass","Test":"TestLogin","Elapsed":0.1}`,
+				`{"Action":"run","Test":"TestLogout"}`,
+				`{"Action":"fail","Test":"TestLogout","Elapsed":0.2}`,
+				`{"Action":"run","Test":"TestRefresh"}`,
+				`{"Action":"skip","Test":"TestRefresh","Elapsed":0}`,
+				`{"Action":"fail","Elapsed":0.3}`,
+			},
+			passed:  []string{"TestLogin"},
+			failed:  []string{"TestLogout"},
+			skipped: []string{"TestRefresh"},
+		},
+		{
+			name: "output interleaved between tests",
+			events: []string{
+				`{"Action":"run","Test":"TestA"}`,
+				`{"Action":"output","Test":"TestA","Output":"=== RUN   TestA\n"}`,
+				`{"Action":"run","Test":"TestB"}`,
+				`{"Action":"output","Test":"TestB","Output":"=== RUN   TestB\n"}`,
+				`{"Action":"output","Test":"TestA","Output":"a_test.go:10: from A\n"}`,
+				`{"Action":"pass","Test":"TestA"}`,
+				`{"Action":"output","Output":"PASS\n"}`,
+				`{"Action":"pass","Test":"TestB"}`,
+			},
+			passed: []string{"TestA", "TestB"},
+			output: "=== RUN   TestA\n=== RUN   TestB\na_test.go:10: from A\nPASS\n",
+		},
+		{
+			name: "subtests",
+			events: []string{
+				`{"Action":"run","Test":"TestPeers"}`,
+				`{"Action":"run","Test":"TestPeers/create"}`,
+				`{"Action":"fail","Test":"TestPeers/create"}`,
+				`{"Action":"run","Test":"TestPeers/delete"}`,
+				`{"Action":"skip","Test":"TestPeers/delete"}`,
+				`{"Action":"fail","Test":"TestPeers"}`,
+			},
+			failed: []string{"TestPeers"},
+		},
+		{
+			name: "build failure without test events",
+			events: []string{
+				`{"ImportPath":"example.com/tests [example.com/tests.test]","Action":"build-output","Output":"# example.com/tests\n"}`,
+				`{"ImportPath":"example.com/tests [example.com/tests.test]","Action":"build-output","Output":"./a_test.go:5:2: undefined: client\n"}`,
+				`{"ImportPath":"example.com/tests [example.com/tests.test]","Action":"build-fail"}`,
+				`FAIL	example.com/tests [build failed]`,
+			},
+			output:      "# example.com/tests\n./a_test.go:5:2: undefined: client\nFAIL\texample.com/tests [build failed]\n",
+			buildOutput: "# example.com/tests\n./a_test.go:5:2: undefined: client\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			run := parseTestEvents([]byte(strings.Join(tt.events, "\n") + "\n"))
+
+			assert.Equal(t, tt.passed, run.passed)
+			assert.Equal(t, tt.failed, run.failed)
+			assert.Equal(t, tt.skipped, run.skipped)
+			if tt.output != "" {
+				assert.Equal(t, tt.output, run.output)
+			}
+			assert.Equal(t, tt.buildOutput, run.buildOutput)
+		})
+	}
+}
+
+func TestTestFunctions(t *testing.T) {
+	tests := []struct {
+		name    string
+		source  string
+		want    []string
+		wantErr bool
+	}{
+		{
+			name: "top-level tests",
+			source: `package tests
+
+import "testing"
+
+func TestLogin(t *testing.T) {}
+
+func TestLogout(t *testing.T) {
+	t.Run("twice", func(t *testing.T) {})
+}
+`,
+			want: []string{"TestLogin", "TestLogout"},
+		},
+		{
+			name: "skips TestMain, helpers and methods",
+			source: `package tests
+
+import "testing"
+
+type suite struct{}
+
+func TestMain(m *testing.M) {}
+
+func (suite) TestMethod(t *testing.T) {}
+
+func TestHelper(t *testing.T, name string) {}
+
+func helper(t *testing.T) {}
+
+func TestPeers(t *testing.T) {}
+`,
+			want: []string{"TestPeers"},
+		},
+		{
+			name:   "no tests",
+			source: "package tests\n",
+		},
+		{
+			name:    "syntax error",
+			source:  "package tests\n\nfunc TestBroken(t *testing.T) {\n",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "example_test.go")
+			require.NoError(t, os.WriteFile(path, []byte(tt.source), 0o644))
+
+			names, err := testFunctions(path)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, names)
+		})
+	}
+}
@@ -0,0 +1,238 @@
+package runner
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"os"
+	"strings"
+	"time"
+)
+
+// Report formats
+const (
+	ReportJSON     = "json"
+	ReportXML      = "xml"
+	ReportHTML     = "html"
+	ReportMarkdown = "markdown"
+)
+
+// markdownErrorLimit bounds the error excerpt of a failed test in the
+// Markdown summary, which is meant for PR comments
+const markdownErrorLimit = 500
+
+// GenerateHTMLReport generates a self-contained HTML report with a duration
+// chart and every test's error and output
+func (tr *TestResults) GenerateHTMLReport(path string) error {
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+
+	var longest time.Duration
+	for _, test := range tr.Tests {
+		longest = max(longest, test.Duration)
+	}
+
+	var buf bytes.Buffer
+	err := htmlReport.Execute(&buf, map[string]interface{}{
+		"Title":   "FlintRoute Functional Tests",
+		"Results": tr,
+		"Stats":   tr.GetStats(),
+		"Longest": longest,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to render HTML: %w", err)
+	}
+
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		return fmt.Errorf("failed to write HTML report: %w", err)
+	}
+
+	return nil
+}
+
+// GenerateMarkdownReport generates a Markdown summary suitable for a PR
+// comment: the totals, the failed and flaky tests and the trend
+func (tr *TestResults) GenerateMarkdownReport(path string) error {
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+
+	stats := tr.GetStats()
+	var b strings.Builder
+
+	icon := "✅"
+	if stats.Failed > 0 {
+		icon = "❌"
+	}
+	fmt.Fprintf(&b, "## %s FlintRoute Functional Tests\n\n", icon)
+	b.WriteString("| Total | Passed | Failed | Skipped | Flaky | Duration |\n")
+	b.WriteString("|------:|-------:|-------:|--------:|------:|---------:|\n")
+	fmt.Fprintf(&b, "| %d | %d | %d | %d | %d | %s |\n",
+		stats.Total, stats.Passed, stats.Failed, stats.Skipped, stats.Flaky, stats.Duration.Round(time.Millisecond))
+
+	if t := tr.Trend; t != nil {
+		fmt.Fprintf(&b, "\nCompared with `%s`: %s passed, %s failed, %s duration.\n",
+			t.PreviousFile, signed(t.PassedDelta), signed(t.FailedDelta), signedDuration(t.DurationDelta))
+		writeMarkdownList(&b, "New failures", t.NewFailures)
+		writeMarkdownList(&b, "Fixed", t.Fixed)
+		writeMarkdownList(&b, "Still flaky", t.StillFlaky)
+		if len(t.Slower) > 0 {
+			b.WriteString("\n**Slower**\n\n")
+			for _, change := range t.Slower {
+				fmt.Fprintf(&b, "- `%s`: %s → %s\n", change.Name,
+					change.Previous.Round(time.Millisecond), change.Current.Round(time.Millisecond))
+			}
+		}
+	}
+
+	if stats.Failed > 0 {
+		b.WriteString("\n### Failed\n\n")
+		for _, test := range tr.Tests {
+			if test.Status != "failed" {
+				continue
+			}
+			attempts := ""
+			if test.Attempts > 1 {
+				attempts = fmt.Sprintf(", %d attempts", test.Attempts)
+			}
+			fmt.Fprintf(&b, "<details><summary><code>%s</code> (%s%s)</summary>\n\n```\n%s\n```\n\n</details>\n",
+				test.Name, test.Duration.Round(time.Millisecond), attempts, truncate(test.Error, markdownErrorLimit))
+		}
+	}
+
+	if stats.Flaky > 0 {
+		b.WriteString("\n### Flaky\n\n")
+		for _, test := range tr.Tests {
+			if test.Flaky {
+				fmt.Fprintf(&b, "- `%s` passed on attempt %d after %q\n", test.Name, test.Attempts, test.RetryReason)
+			}
+		}
+	}
+
+	if err := os.WriteFile(path, []byte(b.String()), 0644); err != nil {
+		return fmt.Errorf("failed to write Markdown report: %w", err)
+	}
+
+	return nil
+}
+
+// writeMarkdownList writes a bold heading and a list of test names, or
+// nothing when there are no names
+func writeMarkdownList(b *strings.Builder, heading string, names []string) {
+	if len(names) == 0 {
+		return
+	}
+	fmt.Fprintf(b, "\n**%s**\n\n", heading)
+	for _, name := range names {
+		fmt.Fprintf(b, "- `%s`\n", name)
+	}
+}
+
+// truncate shortens s to at most limit bytes, marking the cut
+func truncate(s string, limit int) string {
+	s = strings.TrimSpace(s)
+	if len(s) <= limit {
+		return s
+	}
+	return s[:limit] + "…"
+}
+
+// signed formats n with its sign
+func signed(n int) string {
+	return fmt.Sprintf("%+d", n)
+}
+
+// signedDuration formats d with its sign
+func signedDuration(d time.Duration) string {
+	d = d.Round(time.Millisecond)
+	if d < 0 {
+		return d.String()
+	}
+	return "+" + d.String()
+}
+
+var htmlReport = template.Must(template.New("report").Funcs(template.FuncMap{
+	"ms": func(d time.Duration) string {
+		return d.Round(time.Millisecond).String()
+	},
+	"percent": func(d, longest time.Duration) float64 {
+		if longest <= 0 {
+			return 0
+		}
+		return float64(d) / float64(longest) * 100
+	},
+	"signed":         signed,
+	"signedDuration": signedDuration,
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { font-family: sans-serif; margin: 2em; color: #222; }
+table { border-collapse: collapse; }
+th, td { padding: 4px 12px; text-align: left; }
+.summary td { font-size: 1.4em; text-align: center; }
+.passed { color: #1a7f37; }
+.failed { color: #cf222e; }
+.skipped { color: #6e7781; }
+.flaky { color: #9a6700; }
+.chart { width: 100%; max-width: 60em; }
+.bar { height: 1em; background: #1a7f37; }
+.bar.failed { background: #cf222e; }
+.bar.skipped { background: #8c959f; }
+details { margin: 4px 0; }
+summary { cursor: pointer; }
+pre { background: #f6f8fa; padding: 8px; overflow-x: auto; max-height: 30em; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<p>Started {{.Results.StartTime.Format "2006-01-02 15:04:05"}}, took {{ms .Stats.Duration}}.</p>
+
+<table class="summary">
+<tr><th>Total</th><th>Passed</th><th>Failed</th><th>Skipped</th><th>Flaky</th></tr>
+<tr>
+<td>{{.Stats.Total}}</td>
+<td class="passed">{{.Stats.Passed}}</td>
+<td class="failed">{{.Stats.Failed}}</td>
+<td class="skipped">{{.Stats.Skipped}}</td>
+<td class="flaky">{{.Stats.Flaky}}</td>
+</tr>
+</table>
+
+{{with .Results.Trend}}
+<h2>Since {{.PreviousFile}}</h2>
+<p>{{signed .PassedDelta}} passed, {{signed .FailedDelta}} failed, {{signedDuration .DurationDelta}} duration.</p>
+<ul>
+{{range .NewFailures}}<li class="failed">New failure: {{.}}</li>{{end}}
+{{range .Fixed}}<li class="passed">Fixed: {{.}}</li>{{end}}
+{{range .StillFlaky}}<li class="flaky">Still flaky: {{.}}</li>{{end}}
+{{range .Slower}}<li>Slower: {{.Name}} {{ms .Previous}} → {{ms .Current}}</li>{{end}}
+{{range .AddedTests}}<li>Added: {{.}}</li>{{end}}
+{{range .RemovedTests}}<li>Removed: {{.}}</li>{{end}}
+</ul>
+{{end}}
+
+<h2>Durations</h2>
+<table class="chart">
+{{range .Results.Tests}}
+<tr>
+<td>{{.Name}}</td>
+<td style="width: 70%"><div class="bar {{.Status}}" style="width: {{percent .Duration $.Longest}}%"></div></td>
+<td>{{ms .Duration}}</td>
+</tr>
+{{end}}
+</table>
+
+<h2>Tests</h2>
+{{range .Results.Tests}}
+<details{{if eq .Status "failed"}} open{{end}}>
+<summary><span class="{{.Status}}">{{.Status}}</span> {{.Name}} ({{ms .Duration}}{{if gt .Attempts 1}}, {{.Attempts}} attempts{{end}}{{if .Worker}}, worker {{.Worker}}{{end}}){{if .Flaky}} <span class="flaky">flaky</span>{{end}}</summary>
+{{if .RetryReason}}<p>Retried after infrastructure error: <code>{{.RetryReason}}</code></p>{{end}}
+{{if .Error}}<pre>{{.Error}}</pre>{{end}}
+{{if .Output}}<details><summary>Output</summary><pre>{{.Output}}</pre></details>{{end}}
+</details>
+{{end}}
+</body>
+</html>
+`))
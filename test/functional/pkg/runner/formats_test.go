@@ -0,0 +1,122 @@
+package runner
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReportFormats(t *testing.T) {
+	start := time.Date(2024, 5, 2, 9, 0, 0, 0, time.UTC)
+	// newRun returns a run with a pass, a failure with a long error, a skip
+	// and a flaky pass, compared against an earlier run
+	newRun := func() *TestResults {
+		results := newResults(start, 3*time.Second,
+			&TestResult{Name: "TestLogin", Status: "passed", Duration: 1200 * time.Millisecond, Worker: 1},
+			&TestResult{Name: "TestPeers", Status: "failed", Duration: time.Second, Error: "expected <201>\n" + strings.Repeat("x", 600)},
+			&TestResult{Name: "TestAlerts", Status: "skipped", Error: "no FRR"},
+			&TestResult{Name: "TestSearch", Status: "passed", Duration: 500 * time.Millisecond,
+				Attempts: 2, Flaky: true, RetryReason: "connection refused"},
+		)
+		previous := newResults(start.Add(-time.Hour), 4*time.Second,
+			&TestResult{Name: "TestLogin", Status: "passed", Duration: 100 * time.Millisecond},
+			&TestResult{Name: "TestPeers", Status: "passed"},
+			&TestResult{Name: "TestLegacy", Status: "failed"},
+		)
+		results.CompareWith(previous, "results-20240502-080000.json")
+		return results
+	}
+
+	tests := []struct {
+		format   string
+		generate func(*TestResults, string) error
+		want     []string
+		notWant  []string
+	}{
+		{
+			format:   ReportJSON,
+			generate: (*TestResults).GenerateJSONReport,
+			want: []string{
+				`"name": "TestPeers"`,
+				`"status": "failed"`,
+				`"retry_reason": "connection refused"`,
+				`"previous_file": "results-20240502-080000.json"`,
+				`"new_failures": [`,
+				`"removed_tests": [`,
+			},
+		},
+		{
+			format:   ReportXML,
+			generate: (*TestResults).GenerateXMLReport,
+			want: []string{
+				`<?xml version="1.0" encoding="UTF-8"?>`,
+				`<testsuite name="FlintRoute Functional Tests" tests="4" failures="1" skipped="1" time="3" timestamp="2024-05-02T09:00:00Z">`,
+				`<failure message="Test failed" type="AssertionError">expected &lt;201&gt;`,
+				`<skipped message="no FRR"></skipped>`,
+				`<property name="attempts" value="2"></property>`,
+				`<property name="retry_reason" value="connection refused"></property>`,
+			},
+			notWant: []string{"previous_file"},
+		},
+		{
+			format:   ReportHTML,
+			generate: (*TestResults).GenerateHTMLReport,
+			want: []string{
+				"<title>FlintRoute Functional Tests</title>",
+				"Started 2024-05-02 09:00:00, took 3s.",
+				"<h2>Since results-20240502-080000.json</h2>",
+				"&#43;0 passed, &#43;0 failed, -1s duration.",
+				`<li class="failed">New failure: TestPeers</li>`,
+				"<li>Removed: TestLegacy</li>",
+				`<div class="bar passed" style="width: 100%">`,
+				"<pre>expected &lt;201&gt;",
+				"1.2s, worker 1",
+				`<span class="flaky">flaky</span>`,
+				"<code>connection refused</code>",
+			},
+		},
+		{
+			format:   ReportMarkdown,
+			generate: (*TestResults).GenerateMarkdownReport,
+			want: []string{
+				"## ❌ FlintRoute Functional Tests",
+				"| 4 | 2 | 1 | 1 | 1 | 3s |",
+				"Compared with `results-20240502-080000.json`: +0 passed, +0 failed, -1s duration.",
+				"**New failures**\n\n- `TestPeers`",
+				"**Slower**\n\n- `TestLogin`: 100ms → 1.2s",
+				"<details><summary><code>TestPeers</code> (1s)</summary>",
+				"x…\n```",
+				"- `TestSearch` passed on attempt 2 after \"connection refused\"",
+			},
+			notWant: []string{"TestAlerts", strings.Repeat("x", 500)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "results."+tt.format)
+			require.NoError(t, tt.generate(newRun(), path))
+
+			data, err := os.ReadFile(path)
+			require.NoError(t, err)
+			for _, want := range tt.want {
+				assert.Contains(t, string(data), want)
+			}
+			for _, notWant := range tt.notWant {
+				assert.NotContains(t, string(data), notWant)
+			}
+		})
+	}
+
+	t.Run("Unwritable path", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "missing", "results")
+		for _, tt := range tests {
+			assert.Error(t, tt.generate(newRun(), path), tt.format)
+		}
+	})
+}
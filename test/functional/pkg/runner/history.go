@@ -0,0 +1,127 @@
+package runner
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// A test counts as slower than in the previous run when it takes at least
+// slowdownFactor times as long and slowdownMinimum longer
+const (
+	slowdownFactor  = 1.5
+	slowdownMinimum = time.Second
+)
+
+// Trend compares a run against the previous one
+type Trend struct {
+	PreviousFile      string           `json:"previous_file"`
+	PreviousStartTime time.Time        `json:"previous_start_time"`
+	PassedDelta       int              `json:"passed_delta"`
+	FailedDelta       int              `json:"failed_delta"`
+	DurationDelta     time.Duration    `json:"duration_delta"`
+	NewFailures       []string         `json:"new_failures,omitempty"`  // failed now, didn't before
+	Fixed             []string         `json:"fixed,omitempty"`         // failed before, passed now
+	StillFlaky        []string         `json:"still_flaky,omitempty"`   // flaky in both runs
+	AddedTests        []string         `json:"added_tests,omitempty"`   // not in the previous run
+	RemovedTests      []string         `json:"removed_tests,omitempty"` // only in the previous run
+	Slower            []DurationChange `json:"slower,omitempty"`
+}
+
+// DurationChange is a test whose duration changed between runs
+type DurationChange struct {
+	Name     string        `json:"name"`
+	Previous time.Duration `json:"previous"`
+	Current  time.Duration `json:"current"`
+}
+
+// FindPreviousResults returns the latest JSON report in dir, or "" when
+// there is none. Report names embed their timestamp, so the latest sorts
+// last.
+func FindPreviousResults(dir string) (string, error) {
+	matches, err := filepath.Glob(filepath.Join(dir, "results-*.json"))
+	if err != nil {
+		return "", fmt.Errorf("failed to list results: %w", err)
+	}
+	if len(matches) == 0 {
+		return "", nil
+	}
+	sort.Strings(matches)
+	return matches[len(matches)-1], nil
+}
+
+// LoadResults reads a JSON report
+func LoadResults(path string) (*TestResults, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read results: %w", err)
+	}
+
+	results := &TestResults{}
+	if err := json.Unmarshal(data, results); err != nil {
+		return nil, fmt.Errorf("failed to parse results: %w", err)
+	}
+
+	return results, nil
+}
+
+// CompareWith sets the trend of the results against previous, read from
+// previousFile
+func (tr *TestResults) CompareWith(previous *TestResults, previousFile string) {
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+
+	stats := tr.GetStats()
+	previousStats := previous.GetStats()
+	trend := &Trend{
+		PreviousFile:      previousFile,
+		PreviousStartTime: previous.StartTime,
+		PassedDelta:       stats.Passed - previousStats.Passed,
+		FailedDelta:       stats.Failed - previousStats.Failed,
+		DurationDelta:     stats.Duration - previousStats.Duration,
+	}
+
+	before := make(map[string]*TestResult, len(previous.Tests))
+	for _, test := range previous.Tests {
+		before[test.Name] = test
+	}
+	current := make(map[string]bool, len(tr.Tests))
+
+	for _, test := range tr.Tests {
+		current[test.Name] = true
+		old, ok := before[test.Name]
+		if !ok {
+			trend.AddedTests = append(trend.AddedTests, test.Name)
+			continue
+		}
+
+		switch {
+		case test.Status == "failed" && old.Status != "failed":
+			trend.NewFailures = append(trend.NewFailures, test.Name)
+		case test.Status == "passed" && old.Status == "failed":
+			trend.Fixed = append(trend.Fixed, test.Name)
+		}
+		if test.Flaky && old.Flaky {
+			trend.StillFlaky = append(trend.StillFlaky, test.Name)
+		}
+		if test.Status == "passed" && old.Status == "passed" &&
+			float64(test.Duration) >= float64(old.Duration)*slowdownFactor &&
+			test.Duration-old.Duration >= slowdownMinimum {
+			trend.Slower = append(trend.Slower, DurationChange{
+				Name:     test.Name,
+				Previous: old.Duration,
+				Current:  test.Duration,
+			})
+		}
+	}
+	for _, test := range previous.Tests {
+		if !current[test.Name] {
+			trend.RemovedTests = append(trend.RemovedTests, test.Name)
+		}
+	}
+
+	tr.Trend = trend
+}
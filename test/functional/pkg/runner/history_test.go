@@ -0,0 +1,151 @@
+package runner
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newResults returns results of a run that started at start and took took
+func newResults(start time.Time, took time.Duration, tests ...*TestResult) *TestResults {
+	return &TestResults{Tests: tests, StartTime: start, EndTime: start.Add(took)}
+}
+
+func TestCompareWith(t *testing.T) {
+	start := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	passed := func(name string, duration time.Duration) *TestResult {
+		return &TestResult{Name: name, Status: "passed", Duration: duration}
+	}
+	failed := func(name string) *TestResult {
+		return &TestResult{Name: name, Status: "failed", Duration: time.Second}
+	}
+	flaky := func(name string) *TestResult {
+		return &TestResult{Name: name, Status: "passed", Duration: time.Second, Attempts: 2, Flaky: true}
+	}
+
+	tests := []struct {
+		name     string
+		previous []*TestResult
+		current  []*TestResult
+		want     Trend
+	}{
+		{
+			name:     "regression",
+			previous: []*TestResult{passed("TestLogin", time.Second), passed("TestPeers", time.Second)},
+			current:  []*TestResult{failed("TestLogin"), passed("TestPeers", time.Second)},
+			want:     Trend{PassedDelta: -1, FailedDelta: 1, NewFailures: []string{"TestLogin"}},
+		},
+		{
+			name:     "improvement",
+			previous: []*TestResult{failed("TestLogin"), failed("TestPeers")},
+			current:  []*TestResult{passed("TestLogin", time.Second), failed("TestPeers")},
+			want:     Trend{PassedDelta: 1, FailedDelta: -1, Fixed: []string{"TestLogin"}},
+		},
+		{
+			name:     "flaky flip-flop",
+			previous: []*TestResult{flaky("TestLogin"), flaky("TestPeers"), passed("TestAlerts", time.Second)},
+			current:  []*TestResult{flaky("TestLogin"), failed("TestPeers"), flaky("TestAlerts")},
+			want: Trend{
+				PassedDelta: -1,
+				FailedDelta: 1,
+				NewFailures: []string{"TestPeers"},
+				StillFlaky:  []string{"TestLogin"},
+			},
+		},
+		{
+			name:    "empty history",
+			current: []*TestResult{passed("TestLogin", time.Second), failed("TestPeers")},
+			want:    Trend{PassedDelta: 1, FailedDelta: 1, AddedTests: []string{"TestLogin", "TestPeers"}},
+		},
+		{
+			name:     "added and removed tests",
+			previous: []*TestResult{passed("TestLogin", time.Second), passed("TestLegacy", time.Second)},
+			current:  []*TestResult{passed("TestLogin", time.Second), passed("TestSearch", time.Second)},
+			want:     Trend{AddedTests: []string{"TestSearch"}, RemovedTests: []string{"TestLegacy"}},
+		},
+		{
+			name: "slowdowns",
+			previous: []*TestResult{
+				passed("TestLogin", time.Second),
+				passed("TestPeers", 10*time.Second),
+				passed("TestAlerts", 100*time.Millisecond),
+			},
+			current: []*TestResult{
+				passed("TestLogin", 3*time.Second),
+				passed("TestPeers", 14*time.Second),
+				passed("TestAlerts", 900*time.Millisecond),
+			},
+			want: Trend{Slower: []DurationChange{{Name: "TestLogin", Previous: time.Second, Current: 3 * time.Second}}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			previous := newResults(start, time.Minute, tt.previous...)
+			current := newResults(start.Add(time.Hour), 90*time.Second, tt.current...)
+
+			current.CompareWith(previous, "results-20240501-120000.json")
+
+			tt.want.PreviousFile = "results-20240501-120000.json"
+			tt.want.PreviousStartTime = start
+			tt.want.DurationDelta = 30 * time.Second
+			require.NotNil(t, current.Trend)
+			assert.Equal(t, tt.want, *current.Trend)
+		})
+	}
+}
+
+func TestFindPreviousResults(t *testing.T) {
+	t.Run("No history", func(t *testing.T) {
+		path, err := FindPreviousResults(t.TempDir())
+		require.NoError(t, err)
+		assert.Empty(t, path)
+	})
+
+	t.Run("Latest report", func(t *testing.T) {
+		dir := t.TempDir()
+		for _, name := range []string{
+			"results-20240501-120000.json",
+			"results-20240502-090000.json",
+			"results-20240502-090000.xml",
+			"results-20240430-235959.json",
+		} {
+			require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte("{}"), 0644))
+		}
+
+		path, err := FindPreviousResults(dir)
+		require.NoError(t, err)
+		assert.Equal(t, filepath.Join(dir, "results-20240502-090000.json"), path)
+	})
+}
+
+func TestLoadResults(t *testing.T) {
+	dir := t.TempDir()
+
+	t.Run("Round trip", func(t *testing.T) {
+		start := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+		results := newResults(start, time.Minute, &TestResult{Name: "TestLogin", Status: "passed", Attempts: 2, Flaky: true})
+		path := filepath.Join(dir, "results.json")
+		require.NoError(t, results.GenerateJSONReport(path))
+
+		loaded, err := LoadResults(path)
+		require.NoError(t, err)
+		assert.Equal(t, results.Tests, loaded.Tests)
+		assert.True(t, start.Equal(loaded.StartTime))
+	})
+
+	t.Run("Invalid report", func(t *testing.T) {
+		path := filepath.Join(dir, "broken.json")
+		require.NoError(t, os.WriteFile(path, []byte("{"), 0644))
+
+		_, err := LoadResults(path)
+		assert.ErrorContains(t, err, "failed to parse results")
+
+		_, err = LoadResults(filepath.Join(dir, "missing.json"))
+		assert.ErrorContains(t, err, "failed to read results")
+	})
+}
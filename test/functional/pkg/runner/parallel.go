@@ -0,0 +1,191 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/yourusername/flintroute/test/functional/pkg/testutil"
+	"go.uber.org/zap"
+)
+
+// worker is a slot test files run in. Each worker has its own database file
+// and mock FRR port, so that test files running at the same time don't see
+// each other's data.
+type worker struct {
+	id          int
+	dbPath      string
+	mockFRRAddr string
+	dbManager   *testutil.DatabaseManager
+}
+
+// env returns the environment variables that point a test process at the
+// worker's resources
+func (w *worker) env() []string {
+	return []string{
+		testutil.EnvWorkerID + "=" + strconv.Itoa(w.id),
+		testutil.EnvDatabasePath + "=" + w.dbPath,
+		testutil.EnvMockFRRAddr + "=" + w.mockFRRAddr,
+	}
+}
+
+// runParallel runs test files on n workers and adds their results in
+// discovery order
+func (e *TestExecutor) runParallel(ctx context.Context, tests []string, n int) error {
+	workers, err := e.startWorkers(n)
+	if err != nil {
+		e.stopWorkers(workers)
+		return err
+	}
+
+	for _, result := range runPool(ctx, tests, workers, e.runTest) {
+		e.results.AddResult(result)
+	}
+
+	e.stopWorkers(workers)
+	return nil
+}
+
+// runPool runs test files with run, one at a time on each worker, and
+// returns their results in discovery order. Once ctx is done, the files
+// that haven't started are reported as cancelled while the running ones
+// finish.
+func runPool(ctx context.Context, tests []string, workers []*worker, run func(string, *worker) *TestResult) []*TestResult {
+	results := make([]*TestResult, len(tests))
+	queue := make(chan int)
+	var wg sync.WaitGroup
+	for _, w := range workers {
+		wg.Add(1)
+		go func(w *worker) {
+			defer wg.Done()
+			for i := range queue {
+				results[i] = run(tests[i], w)
+			}
+		}(w)
+	}
+	for i := range tests {
+		if ctx.Err() == nil {
+			select {
+			case queue <- i:
+				continue
+			case <-ctx.Done():
+			}
+		}
+		results[i] = cancelledResult(tests[i])
+	}
+	close(queue)
+	wg.Wait()
+
+	return results
+}
+
+// cancelledResult is the result of a test file the run was cancelled before
+func cancelledResult(testPath string) *TestResult {
+	return &TestResult{
+		Name:   filepath.Base(testPath),
+		Status: "skipped",
+		Error:  "run cancelled",
+	}
+}
+
+// startWorkers creates n workers with fresh databases and distinct mock FRR
+// ports. Workers share the mock FRR server of a started environment, which
+// the started FlintRoute server talks to.
+func (e *TestExecutor) startWorkers(n int) ([]*worker, error) {
+	host, _, err := net.SplitHostPort(e.config.MockFRRURL)
+	if err != nil {
+		host = "localhost"
+	}
+	var ports []int
+	if e.environment == nil {
+		if ports, err = allocatePorts(host, n); err != nil {
+			return nil, err
+		}
+	}
+
+	workers := make([]*worker, 0, n)
+	for i := 0; i < n; i++ {
+		w := &worker{
+			id:     i + 1,
+			dbPath: workerDatabasePath(e.config.DatabasePath, i+1),
+		}
+		if e.environment != nil {
+			w.mockFRRAddr = e.environment.MockFRRAddr
+		} else {
+			w.mockFRRAddr = net.JoinHostPort(host, strconv.Itoa(ports[i]))
+		}
+
+		// Start from an empty database rather than one left by an earlier run
+		if err := os.Remove(w.dbPath); err != nil && !os.IsNotExist(err) {
+			return workers, fmt.Errorf("failed to remove worker %d database: %w", w.id, err)
+		}
+		w.dbManager, err = testutil.NewDatabaseManager(w.dbPath, e.logger.GetZapLogger())
+		if err != nil {
+			return workers, fmt.Errorf("failed to create worker %d database manager: %w", w.id, err)
+		}
+		workers = append(workers, w)
+		if err := w.dbManager.Initialize(); err != nil {
+			return workers, fmt.Errorf("failed to initialize worker %d database: %w", w.id, err)
+		}
+
+		e.logger.Info("Worker started",
+			zap.Int("worker", w.id),
+			zap.String("database", w.dbPath),
+			zap.String("mock_frr_addr", w.mockFRRAddr),
+		)
+	}
+
+	return workers, nil
+}
+
+// stopWorkers closes the workers' databases, removing the files when the run
+// succeeded and cleanup is configured
+func (e *TestExecutor) stopWorkers(workers []*worker) {
+	cleanup := e.config.CleanupOnSuccess && !e.results.HasFailures()
+	for _, w := range workers {
+		if w.dbManager != nil {
+			if err := w.dbManager.Close(); err != nil {
+				e.logger.Error("Failed to close worker database", zap.Int("worker", w.id), zap.Error(err))
+			}
+		}
+		if cleanup {
+			if err := os.Remove(w.dbPath); err != nil && !os.IsNotExist(err) {
+				e.logger.Warn("Failed to remove worker database", zap.Int("worker", w.id), zap.Error(err))
+			}
+		}
+	}
+}
+
+// workerDatabasePath returns the database file of worker id, next to the
+// configured one: ./tmp/test.db becomes ./tmp/test-w1.db
+func workerDatabasePath(path string, id int) string {
+	ext := filepath.Ext(path)
+	return fmt.Sprintf("%s-w%d%s", strings.TrimSuffix(path, ext), id, ext)
+}
+
+// allocatePorts finds n free TCP ports on host. The listeners are held until
+// every port is found so that no port is handed out twice.
+func allocatePorts(host string, n int) ([]int, error) {
+	listeners := make([]net.Listener, 0, n)
+	defer func() {
+		for _, l := range listeners {
+			l.Close()
+		}
+	}()
+
+	ports := make([]int, 0, n)
+	for i := 0; i < n; i++ {
+		l, err := net.Listen("tcp", net.JoinHostPort(host, "0"))
+		if err != nil {
+			return nil, fmt.Errorf("failed to allocate mock FRR port: %w", err)
+		}
+		listeners = append(listeners, l)
+		ports = append(ports, l.Addr().(*net.TCPAddr).Port)
+	}
+	return ports, nil
+}
@@ -0,0 +1,173 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeRun runs test files on workers without running anything, recording
+// how many run at once. Test files block until released.
+type fakeRun struct {
+	mu      sync.Mutex
+	started []string
+	running int
+	peak    int
+	release chan struct{}
+	status  func(testPath string) string
+}
+
+func newFakeRun() *fakeRun {
+	return &fakeRun{
+		release: make(chan struct{}),
+		status:  func(string) string { return "passed" },
+	}
+}
+
+func (f *fakeRun) run(testPath string, w *worker) *TestResult {
+	f.mu.Lock()
+	f.started = append(f.started, testPath)
+	f.running++
+	f.peak = max(f.peak, f.running)
+	f.mu.Unlock()
+
+	<-f.release
+
+	f.mu.Lock()
+	f.running--
+	f.mu.Unlock()
+	return &TestResult{Name: testPath, Status: f.status(testPath), Worker: w.id}
+}
+
+// runningNow returns the number of test files running
+func (f *fakeRun) runningNow() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.running
+}
+
+// testFiles returns the paths of n test files
+func testFiles(n int) []string {
+	tests := make([]string, n)
+	for i := range tests {
+		tests[i] = fmt.Sprintf("tests/t%02d_test.go", i)
+	}
+	return tests
+}
+
+// fakeWorkers returns n workers without resources
+func fakeWorkers(n int) []*worker {
+	workers := make([]*worker, n)
+	for i := range workers {
+		workers[i] = &worker{id: i + 1}
+	}
+	return workers
+}
+
+func TestRunPool(t *testing.T) {
+	t.Run("Runs at most one test file per worker", func(t *testing.T) {
+		tests := []struct {
+			workers, files, want int
+		}{
+			{workers: 1, files: 4, want: 1},
+			{workers: 3, files: 10, want: 3},
+			{workers: 4, files: 2, want: 2},
+		}
+		for _, tt := range tests {
+			t.Run(fmt.Sprintf("%d workers, %d files", tt.workers, tt.files), func(t *testing.T) {
+				fake := newFakeRun()
+				done := make(chan []*TestResult)
+				go func() {
+					done <- runPool(context.Background(), testFiles(tt.files), fakeWorkers(tt.workers), fake.run)
+				}()
+
+				require.Eventually(t, func() bool { return fake.runningNow() == tt.want }, time.Second, time.Millisecond)
+				time.Sleep(10 * time.Millisecond)
+				assert.Equal(t, tt.want, fake.runningNow(), "no more files start while every worker is busy")
+
+				close(fake.release)
+				results := <-done
+				assert.Equal(t, tt.want, fake.peak)
+				require.Len(t, results, tt.files)
+				for i, result := range results {
+					assert.Equal(t, testFiles(tt.files)[i], result.Name, "results are in discovery order")
+					assert.Equal(t, "passed", result.Status)
+					assert.GreaterOrEqual(t, result.Worker, 1)
+					assert.LessOrEqual(t, result.Worker, tt.workers)
+				}
+			})
+		}
+	})
+
+	t.Run("Failing test files don't stop the others", func(t *testing.T) {
+		fake := newFakeRun()
+		close(fake.release)
+		failing := map[string]bool{"tests/t00_test.go": true, "tests/t03_test.go": true}
+		fake.status = func(testPath string) string {
+			if failing[testPath] {
+				return "failed"
+			}
+			return "passed"
+		}
+
+		results := runPool(context.Background(), testFiles(6), fakeWorkers(2), fake.run)
+
+		assert.Len(t, fake.started, 6)
+		for _, result := range results {
+			want := "passed"
+			if failing[result.Name] {
+				want = "failed"
+			}
+			assert.Equal(t, want, result.Status, result.Name)
+		}
+	})
+
+	t.Run("Cancelling skips the test files not started", func(t *testing.T) {
+		fake := newFakeRun()
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan []*TestResult)
+		go func() {
+			done <- runPool(ctx, testFiles(5), fakeWorkers(2), fake.run)
+		}()
+
+		require.Eventually(t, func() bool { return fake.runningNow() == 2 }, time.Second, time.Millisecond)
+		cancel()
+		close(fake.release)
+		results := <-done
+
+		assert.Len(t, fake.started, 2, "the running files finish and no more start")
+		require.Len(t, results, 5)
+		for i, result := range results {
+			if i < 2 {
+				assert.Equal(t, "passed", result.Status)
+				continue
+			}
+			assert.Equal(t, fmt.Sprintf("t%02d_test.go", i), result.Name)
+			assert.Equal(t, "skipped", result.Status)
+			assert.Equal(t, "run cancelled", result.Error)
+		}
+	})
+
+	t.Run("Cancelled before starting", func(t *testing.T) {
+		fake := newFakeRun()
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		results := runPool(ctx, testFiles(3), fakeWorkers(2), fake.run)
+
+		assert.Empty(t, fake.started)
+		for _, result := range results {
+			assert.Equal(t, "skipped", result.Status)
+		}
+	})
+}
+
+func TestWorkerDatabasePath(t *testing.T) {
+	assert.Equal(t, "./tmp/test-w1.db", workerDatabasePath("./tmp/test.db", 1))
+	assert.Equal(t, "/var/lib/flintroute-w12", workerDatabasePath("/var/lib/flintroute", 12))
+}
@@ -5,6 +5,7 @@ import (
 	"encoding/xml"
 	"fmt"
 	"os"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -15,6 +16,7 @@ type TestResults struct {
 	Tests     []*TestResult `json:"tests" xml:"testcase"`
 	StartTime time.Time     `json:"start_time" xml:"start_time,attr"`
 	EndTime   time.Time     `json:"end_time" xml:"end_time,attr"`
+	Trend     *Trend        `json:"trend,omitempty" xml:"-"` // comparison against the previous run
 	mu        sync.Mutex
 }
 
@@ -25,6 +27,11 @@ type TestResult struct {
 	Duration time.Duration `json:"duration" xml:"time,attr"`
 	Error    string        `json:"error,omitempty" xml:"error,omitempty"`
 	Output   string        `json:"output,omitempty" xml:"system-out,omitempty"`
+	Worker   int           `json:"worker,omitempty" xml:"worker,attr,omitempty"`     // parallel worker that ran the test
+	Attempts int           `json:"attempts,omitempty" xml:"attempts,attr,omitempty"` // runs including retries
+	Flaky    bool          `json:"flaky,omitempty" xml:"flaky,attr,omitempty"`       // passed only on a retry
+	// RetryReason is the infrastructure error that caused the last retry
+	RetryReason string `json:"retry_reason,omitempty" xml:"retry_reason,attr,omitempty"`
 }
 
 // TestStats represents test statistics
@@ -33,6 +40,7 @@ type TestStats struct {
 	Passed   int           `json:"passed"`
 	Failed   int           `json:"failed"`
 	Skipped  int           `json:"skipped"`
+	Flaky    int           `json:"flaky"`
 	Duration time.Duration `json:"duration"`
 }
 
@@ -86,12 +94,23 @@ type testSuite struct {
 }
 
 type testCase struct {
-	Name      string   `xml:"name,attr"`
-	ClassName string   `xml:"classname,attr"`
-	Time      float64  `xml:"time,attr"`
-	Failure   *failure `xml:"failure,omitempty"`
-	Skipped   *skipped `xml:"skipped,omitempty"`
-	SystemOut string   `xml:"system-out,omitempty"`
+	Name       string      `xml:"name,attr"`
+	ClassName  string      `xml:"classname,attr"`
+	Time       float64     `xml:"time,attr"`
+	Failure    *failure    `xml:"failure,omitempty"`
+	Skipped    *skipped    `xml:"skipped,omitempty"`
+	Properties *properties `xml:"properties,omitempty"`
+	SystemOut  string      `xml:"system-out,omitempty"`
+}
+
+// properties carries the retry details of a test case
+type properties struct {
+	Property []property `xml:"property"`
+}
+
+type property struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value,attr"`
 }
 
 type failure struct {
@@ -140,6 +159,14 @@ func (tr *TestResults) GenerateXMLReport(path string) error {
 			}
 		}
 
+		if test.Attempts > 1 {
+			tc.Properties = &properties{Property: []property{
+				{Name: "attempts", Value: strconv.Itoa(test.Attempts)},
+				{Name: "flaky", Value: strconv.FormatBool(test.Flaky)},
+				{Name: "retry_reason", Value: test.RetryReason},
+			}}
+		}
+
 		suite.TestCases = append(suite.TestCases, tc)
 	}
 
@@ -172,7 +199,12 @@ func (tr *TestResults) PrintSummary() {
 	fmt.Printf("Passed:         %d (%.1f%%)\n", stats.Passed, float64(stats.Passed)/float64(stats.Total)*100)
 	fmt.Printf("Failed:         %d (%.1f%%)\n", stats.Failed, float64(stats.Failed)/float64(stats.Total)*100)
 	fmt.Printf("Skipped:        %d (%.1f%%)\n", stats.Skipped, float64(stats.Skipped)/float64(stats.Total)*100)
+	fmt.Printf("Flaky:          %d\n", stats.Flaky)
 	fmt.Printf("Total Duration: %s\n", stats.Duration)
+	if tr.Trend != nil {
+		fmt.Printf("Since Previous: %d new failures, %d fixed (%s)\n",
+			len(tr.Trend.NewFailures), len(tr.Trend.Fixed), tr.Trend.PreviousFile)
+	}
 	fmt.Println(strings.Repeat("=", 60))
 
 	if stats.Failed > 0 {
@@ -187,6 +219,15 @@ func (tr *TestResults) PrintSummary() {
 		}
 	}
 
+	if stats.Flaky > 0 {
+		fmt.Println("\nFlaky Tests:")
+		for _, test := range tr.Tests {
+			if test.Flaky {
+				fmt.Printf("  ⚠ %s (passed on attempt %d after %s)\n", test.Name, test.Attempts, test.RetryReason)
+			}
+		}
+	}
+
 	if stats.Skipped > 0 {
 		fmt.Println("\nSkipped Tests:")
 		for _, test := range tr.Tests {
@@ -218,6 +259,9 @@ func (tr *TestResults) GetStats() *TestStats {
 		case "skipped":
 			stats.Skipped++
 		}
+		if test.Flaky {
+			stats.Flaky++
+		}
 	}
 
 	return stats
@@ -264,6 +308,20 @@ func (tr *TestResults) GetPassedTests() []*TestResult {
 	return passed
 }
 
+// GetFlakyTests returns all tests that passed only on a retry
+func (tr *TestResults) GetFlakyTests() []*TestResult {
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+
+	flaky := make([]*TestResult, 0)
+	for _, test := range tr.Tests {
+		if test.Flaky {
+			flaky = append(flaky, test)
+		}
+	}
+	return flaky
+}
+
 // GetSkippedTests returns all skipped tests
 func (tr *TestResults) GetSkippedTests() []*TestResult {
 	tr.mu.Lock()
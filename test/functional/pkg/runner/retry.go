@@ -0,0 +1,32 @@
+package runner
+
+import "strings"
+
+// DefaultRetryOn returns the output substrings that by default mark a
+// failure as caused by the infrastructure, such as the server or the mock
+// FRR server being unreachable, rather than by the test
+func DefaultRetryOn() []string {
+	return []string{
+		"connection refused",
+		"connection reset by peer",
+		"broken pipe",
+		"i/o timeout",
+		"no such host",
+		"TLS handshake timeout",
+		"server misbehaving",
+		"503 Service Unavailable",
+		"database is locked",
+	}
+}
+
+// retryReason returns the first of patterns found in a failed result's error
+// or output, ignoring case, or "" when the failure isn't worth a retry
+func retryReason(result *TestResult, patterns []string) string {
+	text := strings.ToLower(result.Error + "\n" + result.Output)
+	for _, pattern := range patterns {
+		if pattern != "" && strings.Contains(text, strings.ToLower(pattern)) {
+			return pattern
+		}
+	}
+	return ""
+}
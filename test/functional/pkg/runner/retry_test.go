@@ -0,0 +1,127 @@
+package runner
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yourusername/flintroute/test/functional/pkg/testutil"
+)
+
+func TestRetryReason(t *testing.T) {
+	tests := []struct {
+		name   string
+		result TestResult
+		want   string
+	}{
+		{
+			name:   "server down",
+			result: TestResult{Output: `Post "http://localhost:8080/api/v1/auth/login": dial tcp 127.0.0.1:8080: connect: connection refused`},
+			want:   "connection refused",
+		},
+		{
+			name:   "mock FRR server timing out",
+			result: TestResult{Output: "rpc error: code = Unavailable desc = dial tcp 127.0.0.1:50051: i/o timeout"},
+			want:   "i/o timeout",
+		},
+		{
+			name:   "server restarting",
+			result: TestResult{Output: "login_test.go:42: unexpected status: 503 Service Unavailable"},
+			want:   "503 Service Unavailable",
+		},
+		{
+			name:   "matched in the error, ignoring case",
+			result: TestResult{Error: "failed to clean worker 2 database: Database Is Locked"},
+			want:   "database is locked",
+		},
+		{
+			name: "assertion failure",
+			result: TestResult{Output: "peers_test.go:31: \n\tError Trace:\tpeers_test.go:31\n" +
+				"\tError:      \tNot equal: \n\t            \texpected: 201\n\t            \tactual  : 400"},
+		},
+		{
+			name:   "not found",
+			result: TestResult{Output: "peers_test.go:57: unexpected status: 404 Not Found"},
+		},
+		{
+			name:   "panic",
+			result: TestResult{Output: "panic: runtime error: invalid memory address or nil pointer dereference"},
+		},
+		{
+			name:   "build failure",
+			result: TestResult{Error: "build failed", Output: "./login_test.go:12:2: undefined: client"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.result.Status = "failed"
+			assert.Equal(t, tt.want, retryReason(&tt.result, DefaultRetryOn()))
+		})
+	}
+
+	t.Run("Custom patterns", func(t *testing.T) {
+		result := &TestResult{Status: "failed", Output: "context deadline exceeded"}
+		assert.Empty(t, retryReason(result, DefaultRetryOn()))
+		assert.Empty(t, retryReason(result, []string{""}), "empty patterns match nothing")
+		assert.Equal(t, "deadline exceeded", retryReason(result, []string{"deadline exceeded"}))
+	})
+}
+
+func TestRetryTest(t *testing.T) {
+	logger, err := testutil.NewTestLogger("", "error")
+	require.NoError(t, err)
+	newExecutor := func(maxRetries int) *TestExecutor {
+		return &TestExecutor{
+			config: &TestConfig{
+				MaxRetries:    maxRetries,
+				RetryDelay:    time.Nanosecond,
+				MaxRetryDelay: time.Nanosecond,
+				RetryOn:       DefaultRetryOn(),
+			},
+			logger: logger,
+		}
+	}
+	failing := func(output string, calls *int) func() *TestResult {
+		return func() *TestResult {
+			*calls++
+			return &TestResult{Name: "login_test.go", Status: "failed", Output: output}
+		}
+	}
+
+	t.Run("Stops at the retry limit", func(t *testing.T) {
+		var calls int
+		result := newExecutor(2).retryTest("login_test.go", failing("connect: connection refused", &calls))
+
+		assert.Equal(t, 3, calls, "the first attempt and two retries")
+		assert.Equal(t, 3, result.Attempts)
+		assert.Equal(t, "failed", result.Status)
+		assert.Equal(t, "connection refused", result.RetryReason)
+		assert.False(t, result.Flaky)
+	})
+
+	t.Run("Doesn't retry test failures", func(t *testing.T) {
+		var calls int
+		result := newExecutor(2).retryTest("login_test.go", failing("expected: 201, actual: 400", &calls))
+
+		assert.Equal(t, 1, calls)
+		assert.Equal(t, 1, result.Attempts)
+		assert.Empty(t, result.RetryReason)
+	})
+
+	t.Run("Reports a pass on a retry as flaky", func(t *testing.T) {
+		var calls int
+		result := newExecutor(2).retryTest("login_test.go", func() *TestResult {
+			calls++
+			if calls == 1 {
+				return &TestResult{Status: "failed", Output: "write: broken pipe"}
+			}
+			return &TestResult{Status: "passed"}
+		})
+
+		assert.Equal(t, 2, result.Attempts)
+		assert.True(t, result.Flaky)
+		assert.Equal(t, "broken pipe", result.RetryReason)
+	})
+}
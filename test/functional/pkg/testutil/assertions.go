@@ -6,7 +6,7 @@ import (
 	"strings"
 	"testing"
 
-	"github.com/yourusername/flintroute/test/functional/pkg/client"
+	"github.com/padminisys/flintroute/pkg/client"
 )
 
 // AssertPeerEqual asserts that two peers are equal
@@ -250,4 +250,4 @@ func AssertPeerNotExists(t *testing.T, peers []*client.Peer, ipAddress string) {
 			return
 		}
 	}
-}
\ No newline at end of file
+}
@@ -3,6 +3,7 @@ package testutil
 import (
 	"fmt"
 
+	"github.com/padminisys/flintroute/pkg/models"
 	"go.uber.org/zap"
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
@@ -51,17 +52,7 @@ func (dm *DatabaseManager) Initialize() error {
 
 // migrateSchema runs database migrations
 func (dm *DatabaseManager) migrateSchema() error {
-	// Define all models that need to be migrated
-	models := []interface{}{
-		&User{},
-		&BGPPeer{},
-		&BGPSession{},
-		&ConfigVersion{},
-		&Alert{},
-		&RefreshToken{},
-	}
-
-	for _, model := range models {
+	for _, model := range models.All() {
 		if err := dm.db.AutoMigrate(model); err != nil {
 			return fmt.Errorf("failed to migrate model: %w", err)
 		}
@@ -78,16 +69,7 @@ func (dm *DatabaseManager) Clean() error {
 
 // CleanTables removes all data from all tables
 func (dm *DatabaseManager) CleanTables() error {
-	tables := []string{
-		"refresh_tokens",
-		"alerts",
-		"bgp_sessions",
-		"bgp_peers",
-		"config_versions",
-		"users",
-	}
-
-	for _, table := range tables {
+	for _, table := range dm.tables() {
 		if err := dm.db.Exec(fmt.Sprintf("DELETE FROM %s", table)).Error; err != nil {
 			return fmt.Errorf("failed to clean table %s: %w", table, err)
 		}
@@ -99,16 +81,7 @@ func (dm *DatabaseManager) CleanTables() error {
 
 // DropAllTables drops all tables from the database
 func (dm *DatabaseManager) DropAllTables() error {
-	tables := []string{
-		"refresh_tokens",
-		"alerts",
-		"bgp_sessions",
-		"bgp_peers",
-		"config_versions",
-		"users",
-	}
-
-	for _, table := range tables {
+	for _, table := range dm.tables() {
 		if err := dm.db.Migrator().DropTable(table); err != nil {
 			dm.logger.Warn("Failed to drop table", zap.String("table", table), zap.Error(err))
 		}
@@ -118,6 +91,22 @@ func (dm *DatabaseManager) DropAllTables() error {
 	return nil
 }
 
+// tables returns the names of the model tables, children before the tables
+// they refer to
+func (dm *DatabaseManager) tables() []string {
+	all := models.All()
+	tables := make([]string, 0, len(all))
+	for i := len(all) - 1; i >= 0; i-- {
+		stmt := &gorm.Statement{DB: dm.db}
+		if err := stmt.Parse(all[i]); err != nil {
+			dm.logger.Warn("Failed to parse model", zap.Error(err))
+			continue
+		}
+		tables = append(tables, stmt.Schema.Table)
+	}
+	return tables
+}
+
 // GetDB returns the underlying GORM database instance
 func (dm *DatabaseManager) GetDB() *gorm.DB {
 	return dm.db
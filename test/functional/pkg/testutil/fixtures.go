@@ -2,14 +2,13 @@ package testutil
 
 import (
 	"fmt"
-	"os"
 	"path/filepath"
 
 	"go.uber.org/zap"
-	"gopkg.in/yaml.v3"
 )
 
-// FixtureLoader loads test fixtures from YAML files
+// FixtureLoader loads test fixtures from YAML files. Fixture files are Go
+// templates, see TemplateData.
 type FixtureLoader struct {
 	basePath string
 	logger   *zap.Logger
@@ -61,20 +60,15 @@ type SessionFixture struct {
 	MessagesReceived int64  `yaml:"messages_received"`
 	MessagesSent     int64  `yaml:"messages_sent"`
 	LastError        string `yaml:"last_error"`
+	// PeerIP identifies the peer by address when seeding, instead of PeerID
+	PeerIP string `yaml:"peer_ip"`
 }
 
-// LoadPeer loads a peer fixture by name
+// LoadPeer loads a peer fixture by name, e.g. "valid/basic_peer"
 func (fl *FixtureLoader) LoadPeer(name string) (*PeerFixture, error) {
-	path := filepath.Join(fl.basePath, "peers", name+".yaml")
-	
-	data, err := os.ReadFile(path)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read peer fixture %s: %w", name, err)
-	}
-
 	var peer PeerFixture
-	if err := yaml.Unmarshal(data, &peer); err != nil {
-		return nil, fmt.Errorf("failed to parse peer fixture %s: %w", name, err)
+	if err := fl.loadFixture("peer", "peers", name, TemplateData{}, &peer); err != nil {
+		return nil, err
 	}
 
 	fl.logger.Debug("Peer fixture loaded", zap.String("name", name))
@@ -83,16 +77,9 @@ func (fl *FixtureLoader) LoadPeer(name string) (*PeerFixture, error) {
 
 // LoadUser loads a user fixture by name
 func (fl *FixtureLoader) LoadUser(name string) (*UserFixture, error) {
-	path := filepath.Join(fl.basePath, "users", name+".yaml")
-	
-	data, err := os.ReadFile(path)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read user fixture %s: %w", name, err)
-	}
-
 	var user UserFixture
-	if err := yaml.Unmarshal(data, &user); err != nil {
-		return nil, fmt.Errorf("failed to parse user fixture %s: %w", name, err)
+	if err := fl.loadFixture("user", "users", name, TemplateData{}, &user); err != nil {
+		return nil, err
 	}
 
 	fl.logger.Debug("User fixture loaded", zap.String("name", name))
@@ -101,16 +88,9 @@ func (fl *FixtureLoader) LoadUser(name string) (*UserFixture, error) {
 
 // LoadSession loads a session fixture by name
 func (fl *FixtureLoader) LoadSession(name string) (*SessionFixture, error) {
-	path := filepath.Join(fl.basePath, "sessions", name+".yaml")
-	
-	data, err := os.ReadFile(path)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read session fixture %s: %w", name, err)
-	}
-
 	var session SessionFixture
-	if err := yaml.Unmarshal(data, &session); err != nil {
-		return nil, fmt.Errorf("failed to parse session fixture %s: %w", name, err)
+	if err := fl.loadFixture("session", "sessions", name, TemplateData{}, &session); err != nil {
+		return nil, err
 	}
 
 	fl.logger.Debug("Session fixture loaded", zap.String("name", name))
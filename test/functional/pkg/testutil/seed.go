@@ -0,0 +1,120 @@
+package testutil
+
+import (
+	"fmt"
+
+	"go.uber.org/zap"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+// SeedResult holds the records created by Seed
+type SeedResult struct {
+	Users    []*User
+	Peers    []*BGPPeer
+	Sessions []*BGPSession
+}
+
+// Seed writes a fixture set straight into the database in one transaction,
+// bypassing the API. User passwords are hashed like the server does, and
+// sessions with a peer IP are attached to the peer seeded with that address.
+func (dm *DatabaseManager) Seed(set *FixtureSet) (*SeedResult, error) {
+	result := &SeedResult{}
+
+	err := dm.db.Transaction(func(tx *gorm.DB) error {
+		for _, fixture := range set.Users {
+			// MinCost keeps seeding hundreds of users fast
+			hash, err := bcrypt.GenerateFromPassword([]byte(fixture.Password), bcrypt.MinCost)
+			if err != nil {
+				return fmt.Errorf("failed to hash password of user %s: %w", fixture.Username, err)
+			}
+			user := &User{
+				Username:     fixture.Username,
+				Email:        fixture.Email,
+				PasswordHash: string(hash),
+				Role:         fixture.Role,
+				Active:       fixture.Active,
+			}
+			if err := tx.Create(user).Error; err != nil {
+				return fmt.Errorf("failed to seed user %s: %w", fixture.Username, err)
+			}
+			// Create replaces a false Active by the column default
+			if !fixture.Active {
+				if err := tx.Model(user).Update("active", false).Error; err != nil {
+					return fmt.Errorf("failed to deactivate user %s: %w", fixture.Username, err)
+				}
+			}
+			result.Users = append(result.Users, user)
+		}
+
+		peerIDs := make(map[string]uint, len(set.Peers))
+		for _, fixture := range set.Peers {
+			peer := &BGPPeer{
+				Name:            fixture.Name,
+				IPAddress:       fixture.IPAddress,
+				ASN:             fixture.ASN,
+				RemoteASN:       fixture.RemoteASN,
+				Description:     fixture.Description,
+				Enabled:         fixture.Enabled,
+				Password:        fixture.Password,
+				Multihop:        fixture.Multihop,
+				UpdateSource:    fixture.UpdateSource,
+				RouteMapIn:      fixture.RouteMapIn,
+				RouteMapOut:     fixture.RouteMapOut,
+				PrefixListIn:    fixture.PrefixListIn,
+				PrefixListOut:   fixture.PrefixListOut,
+				MaxPrefixes:     fixture.MaxPrefixes,
+				LocalPreference: fixture.LocalPreference,
+			}
+			if err := tx.Create(peer).Error; err != nil {
+				return fmt.Errorf("failed to seed peer %s: %w", fixture.IPAddress, err)
+			}
+			// Create replaces a false Enabled by the column default
+			if !fixture.Enabled {
+				if err := tx.Model(peer).Update("enabled", false).Error; err != nil {
+					return fmt.Errorf("failed to disable peer %s: %w", fixture.IPAddress, err)
+				}
+			}
+			peerIDs[peer.IPAddress] = peer.ID
+			result.Peers = append(result.Peers, peer)
+		}
+
+		for _, fixture := range set.Sessions {
+			peerID := fixture.PeerID
+			if fixture.PeerIP != "" {
+				id, ok := peerIDs[fixture.PeerIP]
+				if !ok {
+					return fmt.Errorf("session refers to peer %s, which is not in the set", fixture.PeerIP)
+				}
+				peerID = id
+			}
+			session := &BGPSession{
+				PeerID:           peerID,
+				State:            fixture.State,
+				Uptime:           fixture.Uptime,
+				PrefixesReceived: fixture.PrefixesReceived,
+				PrefixesSent:     fixture.PrefixesSent,
+				MessagesReceived: fixture.MessagesReceived,
+				MessagesSent:     fixture.MessagesSent,
+				LastError:        fixture.LastError,
+			}
+			if err := tx.Omit("Peer").Create(session).Error; err != nil {
+				return fmt.Errorf("failed to seed session of peer %d: %w", peerID, err)
+			}
+			result.Sessions = append(result.Sessions, session)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to seed fixture set %s: %w", set.Name, err)
+	}
+
+	dm.logger.Info("Fixture set seeded",
+		zap.String("name", set.Name),
+		zap.Int("users", len(result.Users)),
+		zap.Int("peers", len(result.Peers)),
+		zap.Int("sessions", len(result.Sessions)),
+	)
+	return result, nil
+}
@@ -0,0 +1,272 @@
+package testutil
+
+import (
+	"bytes"
+	"fmt"
+	"math/rand/v2"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"sync"
+	"text/template"
+
+	"go.uber.org/zap"
+	"gopkg.in/yaml.v3"
+)
+
+// Private 16-bit ASN range used by randASN
+const (
+	privateASNMin = 64512
+	privateASNMax = 65534
+)
+
+// TemplateData is what fixture files are executed with as Go templates.
+// Static fixtures have no actions and render as they are.
+type TemplateData struct {
+	// Index is the position of the fixture in a generated batch, from 0
+	Index int
+	// Vars are the caller's variables, e.g. {{ .Vars.region }}
+	Vars map[string]interface{}
+}
+
+// fixtureRand is the random source of the template functions. Seed it with
+// SeedFixtures to make generated fixtures reproducible.
+var fixtureRand = struct {
+	sync.Mutex
+	*rand.Rand
+}{Rand: rand.New(rand.NewPCG(rand.Uint64(), rand.Uint64()))}
+
+// SeedFixtures makes the random values of fixture templates reproducible
+func SeedFixtures(seed uint64) {
+	fixtureRand.Lock()
+	defer fixtureRand.Unlock()
+	fixtureRand.Rand = rand.New(rand.NewPCG(seed, seed))
+}
+
+// fixtureFuncs are the functions available in fixture templates
+var fixtureFuncs = template.FuncMap{
+	// add returns a + b, e.g. {{ add 65000 .Index }}
+	"add": func(a, b int) int {
+		return a + b
+	},
+	// default returns value, or def when value is empty, e.g.
+	// {{ default 65000 (index .Vars "local_asn") }}. index is needed for
+	// optional variables, since a missing .Vars.name is an error.
+	"default": func(def, value interface{}) interface{} {
+		if value == nil || value == "" || value == 0 {
+			return def
+		}
+		return value
+	},
+	// randInt returns a random integer in [min, max]
+	"randInt": func(min, max int) (int, error) {
+		if max < min {
+			return 0, fmt.Errorf("randInt: max %d is less than min %d", max, min)
+		}
+		fixtureRand.Lock()
+		defer fixtureRand.Unlock()
+		return min + fixtureRand.IntN(max-min+1), nil
+	},
+	// randASN returns a random private 16-bit ASN
+	"randASN": func() int {
+		fixtureRand.Lock()
+		defer fixtureRand.Unlock()
+		return privateASNMin + fixtureRand.IntN(privateASNMax-privateASNMin+1)
+	},
+	// ipAdd returns the address n after base, e.g. {{ ipAdd "10.0.0.1" .Index }}
+	"ipAdd": func(base string, n int) (string, error) {
+		addr, err := netip.ParseAddr(base)
+		if err != nil {
+			return "", fmt.Errorf("ipAdd: %w", err)
+		}
+		for i := 0; i < n; i++ {
+			if addr = addr.Next(); !addr.IsValid() {
+				return "", fmt.Errorf("ipAdd: %s + %d overflows", base, n)
+			}
+		}
+		return addr.String(), nil
+	},
+}
+
+// render reads a fixture file and executes it as a template
+func (fl *FixtureLoader) render(path string, data TemplateData) ([]byte, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	tmpl, err := template.New(filepath.Base(path)).
+		Funcs(fixtureFuncs).
+		Option("missingkey=error").
+		Parse(string(content))
+	if err != nil {
+		return nil, fmt.Errorf("invalid template: %w", err)
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return nil, fmt.Errorf("failed to execute template: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// loadFixture renders the fixture name of a kind (peer, user, session) from
+// dir and decodes it into out
+func (fl *FixtureLoader) loadFixture(kind, dir, name string, data TemplateData, out interface{}) error {
+	content, err := fl.render(filepath.Join(fl.basePath, dir, name+".yaml"), data)
+	if err != nil {
+		return fmt.Errorf("failed to read %s fixture %s: %w", kind, name, err)
+	}
+
+	if err := yaml.Unmarshal(content, out); err != nil {
+		return fmt.Errorf("failed to parse %s fixture %s: %w", kind, name, err)
+	}
+
+	return nil
+}
+
+// GeneratePeers renders the peer fixture name count times, with Index
+// running from 0, e.g. to create many peers from peers/templates/bulk_peer
+func (fl *FixtureLoader) GeneratePeers(name string, count int, vars map[string]interface{}) ([]*PeerFixture, error) {
+	peers := make([]*PeerFixture, 0, count)
+	for i := 0; i < count; i++ {
+		var peer PeerFixture
+		if err := fl.loadFixture("peer", "peers", name, TemplateData{Index: i, Vars: vars}, &peer); err != nil {
+			return nil, err
+		}
+		peers = append(peers, &peer)
+	}
+
+	fl.logger.Debug("Peer fixtures generated", zap.String("name", name), zap.Int("count", count))
+	return peers, nil
+}
+
+// FixtureSet is a group of fixtures seeded together with
+// DatabaseManager.Seed
+type FixtureSet struct {
+	Name     string
+	Users    []*UserFixture
+	Peers    []*PeerFixture
+	Sessions []*SessionFixture
+}
+
+// fixtureSetFile is the format of sets/<name>.yaml. Every entry names a
+// fixture of its kind and may repeat it count times; vars extend the set's.
+//
+//	vars:
+//	  region: eu
+//	users:
+//	  - fixture: admin_user
+//	peers:
+//	  - fixture: valid/basic_peer
+//	  - fixture: templates/bulk_peer
+//	    count: 50
+//	sessions:
+//	  - fixture: established_session
+//	    peer_ip: 192.168.1.100
+type fixtureSetFile struct {
+	Vars     map[string]interface{} `yaml:"vars"`
+	Users    []fixtureSetEntry      `yaml:"users"`
+	Peers    []fixtureSetEntry      `yaml:"peers"`
+	Sessions []fixtureSetEntry      `yaml:"sessions"`
+}
+
+type fixtureSetEntry struct {
+	Fixture string                 `yaml:"fixture"`
+	Count   int                    `yaml:"count"`
+	Vars    map[string]interface{} `yaml:"vars"`
+	// PeerIP attaches a session to the seeded peer with this address
+	PeerIP string `yaml:"peer_ip"`
+}
+
+// LoadSet loads the fixture set sets/<name>.yaml, rendering every fixture it
+// lists. vars override the set's variables.
+func (fl *FixtureLoader) LoadSet(name string, vars map[string]interface{}) (*FixtureSet, error) {
+	content, err := fl.render(filepath.Join(fl.basePath, "sets", name+".yaml"), TemplateData{Vars: vars})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read fixture set %s: %w", name, err)
+	}
+
+	var file fixtureSetFile
+	if err := yaml.Unmarshal(content, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse fixture set %s: %w", name, err)
+	}
+
+	set := &FixtureSet{Name: name}
+	setVars := mergeVars(file.Vars, vars)
+
+	err = expandSetEntries(file.Users, setVars, func(entry fixtureSetEntry, data TemplateData) error {
+		var user UserFixture
+		if err := fl.loadFixture("user", "users", entry.Fixture, data, &user); err != nil {
+			return err
+		}
+		set.Users = append(set.Users, &user)
+		return nil
+	})
+	if err == nil {
+		err = expandSetEntries(file.Peers, setVars, func(entry fixtureSetEntry, data TemplateData) error {
+			var peer PeerFixture
+			if err := fl.loadFixture("peer", "peers", entry.Fixture, data, &peer); err != nil {
+				return err
+			}
+			set.Peers = append(set.Peers, &peer)
+			return nil
+		})
+	}
+	if err == nil {
+		err = expandSetEntries(file.Sessions, setVars, func(entry fixtureSetEntry, data TemplateData) error {
+			var session SessionFixture
+			if err := fl.loadFixture("session", "sessions", entry.Fixture, data, &session); err != nil {
+				return err
+			}
+			if entry.PeerIP != "" {
+				session.PeerIP = entry.PeerIP
+			}
+			set.Sessions = append(set.Sessions, &session)
+			return nil
+		})
+	}
+	if err != nil {
+		return nil, fmt.Errorf("fixture set %s: %w", name, err)
+	}
+
+	fl.logger.Debug("Fixture set loaded",
+		zap.String("name", name),
+		zap.Int("users", len(set.Users)),
+		zap.Int("peers", len(set.Peers)),
+		zap.Int("sessions", len(set.Sessions)),
+	)
+	return set, nil
+}
+
+// expandSetEntries calls load for every fixture of entries, count times each
+func expandSetEntries(entries []fixtureSetEntry, vars map[string]interface{}, load func(fixtureSetEntry, TemplateData) error) error {
+	for _, entry := range entries {
+		if entry.Fixture == "" {
+			return fmt.Errorf("entry without a fixture")
+		}
+		count := entry.Count
+		if count <= 0 {
+			count = 1
+		}
+		entryVars := mergeVars(vars, entry.Vars)
+		for i := 0; i < count; i++ {
+			if err := load(entry, TemplateData{Index: i, Vars: entryVars}); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// mergeVars returns base extended with override
+func mergeVars(base, override map[string]interface{}) map[string]interface{} {
+	merged := make(map[string]interface{}, len(base)+len(override))
+	for k, v := range base {
+		merged[k] = v
+	}
+	for k, v := range override {
+		merged[k] = v
+	}
+	return merged
+}
@@ -0,0 +1,136 @@
+package testutil
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// writeFixtures writes files, keyed by their path under the fixtures
+// directory, and returns a loader reading them
+func writeFixtures(t *testing.T, files map[string]string) *FixtureLoader {
+	t.Helper()
+
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	}
+	return NewFixtureLoader(dir, zap.NewNop())
+}
+
+func TestFixtureTemplates(t *testing.T) {
+	const regionalPeer = `name: {{ .Vars.region }} peer {{ .Index }}
+ip_address: {{ ipAdd .Vars.base_ip .Index }}
+asn: {{ default 65000 (index .Vars "local_asn") }}
+remote_asn: {{ add 64600 .Index }}
+enabled: true
+`
+
+	t.Run("Substitutes variables", func(t *testing.T) {
+		loader := writeFixtures(t, map[string]string{"peers/regional.yaml": regionalPeer})
+
+		peers, err := loader.GeneratePeers("regional", 2, map[string]interface{}{
+			"region":    "eu",
+			"base_ip":   "10.0.0.254",
+			"local_asn": 65100,
+		})
+		require.NoError(t, err)
+		require.Len(t, peers, 2)
+		assert.Equal(t, "eu peer 0", peers[0].Name)
+		assert.Equal(t, "10.0.0.254", peers[0].IPAddress)
+		assert.Equal(t, uint32(65100), peers[0].ASN)
+		assert.Equal(t, uint32(64600), peers[0].RemoteASN)
+		assert.Equal(t, "eu peer 1", peers[1].Name)
+		assert.Equal(t, "10.0.0.255", peers[1].IPAddress)
+		assert.Equal(t, uint32(64601), peers[1].RemoteASN)
+	})
+
+	t.Run("Falls back to defaults for optional variables", func(t *testing.T) {
+		loader := writeFixtures(t, map[string]string{"peers/regional.yaml": regionalPeer})
+
+		peers, err := loader.GeneratePeers("regional", 1, map[string]interface{}{"region": "us", "base_ip": "10.1.0.1"})
+		require.NoError(t, err)
+		assert.Equal(t, uint32(65000), peers[0].ASN)
+	})
+
+	t.Run("Missing variable is an error", func(t *testing.T) {
+		loader := writeFixtures(t, map[string]string{"peers/regional.yaml": regionalPeer})
+
+		_, err := loader.GeneratePeers("regional", 1, map[string]interface{}{"base_ip": "10.1.0.1"})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), `map has no entry for key "region"`)
+
+		// Static loaders have no variables at all
+		_, err = loader.LoadPeer("regional")
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "failed to execute template")
+	})
+
+	t.Run("Invalid template", func(t *testing.T) {
+		loader := writeFixtures(t, map[string]string{"peers/broken.yaml": "name: {{ .Index\n"})
+
+		_, err := loader.LoadPeer("broken")
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "invalid template")
+	})
+
+	t.Run("Expands nested fixtures of a set", func(t *testing.T) {
+		loader := writeFixtures(t, map[string]string{
+			"peers/regional.yaml": regionalPeer,
+			"users/operator.yaml": "username: {{ .Vars.region }}-operator\nrole: operator\nactive: true\n",
+			"sessions/up.yaml":    "state: Established\nprefixes_received: {{ add 10 .Index }}\n",
+			"sets/network.yaml": `vars:
+  region: eu
+  base_ip: {{ default "10.0.0.1" (index .Vars "base_ip") }}
+users:
+  - fixture: operator
+peers:
+  - fixture: regional
+    count: 2
+  - fixture: regional
+    vars:
+      region: us
+      base_ip: 10.9.0.1
+sessions:
+  - fixture: up
+    peer_ip: 10.9.0.1
+`,
+		})
+
+		set, err := loader.LoadSet("network", map[string]interface{}{"base_ip": "10.5.0.1"})
+		require.NoError(t, err)
+		assert.Equal(t, "network", set.Name)
+
+		require.Len(t, set.Users, 1)
+		assert.Equal(t, "eu-operator", set.Users[0].Username)
+
+		require.Len(t, set.Peers, 3)
+		assert.Equal(t, "eu peer 0", set.Peers[0].Name)
+		assert.Equal(t, "10.5.0.1", set.Peers[0].IPAddress, "the caller's vars override the set's")
+		assert.Equal(t, "10.5.0.2", set.Peers[1].IPAddress)
+		assert.Equal(t, "us peer 0", set.Peers[2].Name, "an entry's vars override the set's")
+		assert.Equal(t, "10.9.0.1", set.Peers[2].IPAddress)
+
+		require.Len(t, set.Sessions, 1)
+		assert.Equal(t, "10.9.0.1", set.Sessions[0].PeerIP)
+		assert.Equal(t, 10, set.Sessions[0].PrefixesReceived)
+	})
+
+	t.Run("Missing variable in a nested fixture fails the set", func(t *testing.T) {
+		loader := writeFixtures(t, map[string]string{
+			"peers/regional.yaml": regionalPeer,
+			"sets/network.yaml":   "vars:\n  region: eu\npeers:\n  - fixture: regional\n",
+		})
+
+		_, err := loader.LoadSet("network", nil)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "fixture set network")
+		assert.Contains(t, err.Error(), `map has no entry for key "base_ip"`)
+	})
+}
@@ -0,0 +1,47 @@
+package testutil
+
+import "os"
+
+// Environment variables the test runner sets for every test process, so
+// that tests use the servers it started and, in parallel workers, the
+// worker's own resources
+const (
+	EnvServerURL    = "FLINTROUTE_TEST_SERVER_URL"
+	EnvWorkerID     = "FLINTROUTE_TEST_WORKER"
+	EnvDatabasePath = "FLINTROUTE_TEST_DATABASE"
+	EnvMockFRRAddr  = "FLINTROUTE_TEST_MOCK_FRR_ADDR"
+)
+
+// ServerURL returns the URL of the FlintRoute server the runner started, or
+// fallback when the test isn't run by the runner or the server wasn't
+// started by it
+func ServerURL(fallback string) string {
+	return envOr(EnvServerURL, fallback)
+}
+
+// WorkerID returns the ID of the runner worker executing the test, or ""
+// when the test isn't run by the runner
+func WorkerID() string {
+	return os.Getenv(EnvWorkerID)
+}
+
+// WorkerDatabasePath returns the database file of the runner worker
+// executing the test, or fallback when the test isn't run by the runner
+func WorkerDatabasePath(fallback string) string {
+	return envOr(EnvDatabasePath, fallback)
+}
+
+// WorkerMockFRRAddr returns the host:port allocated to the mock FRR server
+// of the runner worker executing the test, or fallback when the test isn't
+// run by the runner
+func WorkerMockFRRAddr(fallback string) string {
+	return envOr(EnvMockFRRAddr, fallback)
+}
+
+// envOr returns the environment variable key, or fallback when it is unset
+func envOr(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
@@ -1,13 +1,14 @@
 package authentication_test
 
 import (
+	"context"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
-	"github.com/yourusername/flintroute/test/functional/pkg/client"
+	"github.com/padminisys/flintroute/pkg/client"
 	"github.com/yourusername/flintroute/test/functional/pkg/testutil"
 )
 
@@ -20,9 +21,10 @@ func TestLogin(t *testing.T) {
 
 	logger.LogTestStart("TestLogin")
 	startTime := time.Now()
+	ctx := context.Background()
 
 	// Create API client
-	apiClient := client.NewAPIClient("http://localhost:8080", logger.GetZapLogger())
+	apiClient := client.NewAPIClient(testutil.ServerURL("http://localhost:8080"), logger.GetZapLogger())
 
 	// Load fixture
 	fixtureLoader := testutil.NewFixtureLoader("../../fixtures", logger.GetZapLogger())
@@ -33,7 +35,7 @@ func TestLogin(t *testing.T) {
 	t.Run("successful_login", func(t *testing.T) {
 		logger.Info("Testing successful login")
 
-		resp, err := apiClient.Login(adminUser.Username, adminUser.Password)
+		resp, err := apiClient.Login(ctx, adminUser.Username, adminUser.Password)
 		require.NoError(t, err, "Login should succeed with valid credentials")
 
 		// Verify response structure
@@ -53,7 +55,7 @@ func TestLogin(t *testing.T) {
 	t.Run("invalid_credentials", func(t *testing.T) {
 		logger.Info("Testing invalid credentials")
 
-		_, err := apiClient.Login("invalid_user", "wrong_password")
+		_, err := apiClient.Login(ctx, "invalid_user", "wrong_password")
 		assert.Error(t, err, "Login should fail with invalid credentials")
 
 		logger.Info("Invalid credentials test passed")
@@ -63,7 +65,7 @@ func TestLogin(t *testing.T) {
 	t.Run("empty_username", func(t *testing.T) {
 		logger.Info("Testing empty username")
 
-		_, err := apiClient.Login("", adminUser.Password)
+		_, err := apiClient.Login(ctx, "", adminUser.Password)
 		assert.Error(t, err, "Login should fail with empty username")
 
 		logger.Info("Empty username test passed")
@@ -73,7 +75,7 @@ func TestLogin(t *testing.T) {
 	t.Run("empty_password", func(t *testing.T) {
 		logger.Info("Testing empty password")
 
-		_, err := apiClient.Login(adminUser.Username, "")
+		_, err := apiClient.Login(ctx, adminUser.Username, "")
 		assert.Error(t, err, "Login should fail with empty password")
 
 		logger.Info("Empty password test passed")
@@ -84,14 +86,14 @@ func TestLogin(t *testing.T) {
 		logger.Info("Testing authenticated request")
 
 		// First login
-		_, err := apiClient.Login(adminUser.Username, adminUser.Password)
+		_, err := apiClient.Login(ctx, adminUser.Username, adminUser.Password)
 		require.NoError(t, err, "Login should succeed")
 
 		// Verify client is authenticated
 		assert.True(t, apiClient.IsAuthenticated(), "Client should be authenticated after login")
 
 		// Make an authenticated request (health check doesn't require auth, but we can test the mechanism)
-		err = apiClient.HealthCheck()
+		err = apiClient.HealthCheck(ctx)
 		assert.NoError(t, err, "Health check should succeed")
 
 		logger.Info("Authenticated request test passed")
@@ -102,11 +104,11 @@ func TestLogin(t *testing.T) {
 		logger.Info("Testing logout")
 
 		// First login
-		_, err := apiClient.Login(adminUser.Username, adminUser.Password)
+		_, err := apiClient.Login(ctx, adminUser.Username, adminUser.Password)
 		require.NoError(t, err, "Login should succeed")
 
 		// Logout
-		err = apiClient.Logout()
+		err = apiClient.Logout(ctx)
 		assert.NoError(t, err, "Logout should succeed")
 
 		// Verify client is no longer authenticated
@@ -128,15 +130,16 @@ func TestHealthCheck(t *testing.T) {
 
 	logger.LogTestStart("TestHealthCheck")
 	startTime := time.Now()
+	ctx := context.Background()
 
 	// Create API client
-	apiClient := client.NewAPIClient("http://localhost:8080", logger.GetZapLogger())
+	apiClient := client.NewAPIClient(testutil.ServerURL("http://localhost:8080"), logger.GetZapLogger())
 
 	// Test: Health check without authentication
 	t.Run("health_check_no_auth", func(t *testing.T) {
 		logger.Info("Testing health check without authentication")
 
-		err := apiClient.HealthCheck()
+		err := apiClient.HealthCheck(ctx)
 		assert.NoError(t, err, "Health check should succeed without authentication")
 
 		logger.Info("Health check test passed")
@@ -155,9 +158,10 @@ func TestTokenRefresh(t *testing.T) {
 
 	logger.LogTestStart("TestTokenRefresh")
 	startTime := time.Now()
+	ctx := context.Background()
 
 	// Create API client
-	apiClient := client.NewAPIClient("http://localhost:8080", logger.GetZapLogger())
+	apiClient := client.NewAPIClient(testutil.ServerURL("http://localhost:8080"), logger.GetZapLogger())
 
 	// Load fixture
 	fixtureLoader := testutil.NewFixtureLoader("../../fixtures", logger.GetZapLogger())
@@ -169,7 +173,7 @@ func TestTokenRefresh(t *testing.T) {
 		logger.Info("Testing token refresh")
 
 		// First login
-		loginResp, err := apiClient.Login(adminUser.Username, adminUser.Password)
+		loginResp, err := apiClient.Login(ctx, adminUser.Username, adminUser.Password)
 		require.NoError(t, err, "Login should succeed")
 
 		// Get the refresh token
@@ -177,7 +181,7 @@ func TestTokenRefresh(t *testing.T) {
 		assert.NotEmpty(t, refreshToken, "Refresh token should not be empty")
 
 		// Refresh the token
-		tokenResp, err := apiClient.RefreshToken(refreshToken)
+		tokenResp, err := apiClient.RefreshToken(ctx, refreshToken)
 		require.NoError(t, err, "Token refresh should succeed")
 
 		// Verify new tokens
@@ -194,7 +198,7 @@ func TestTokenRefresh(t *testing.T) {
 	t.Run("invalid_refresh_token", func(t *testing.T) {
 		logger.Info("Testing invalid refresh token")
 
-		_, err := apiClient.RefreshToken("invalid_token")
+		_, err := apiClient.RefreshToken(ctx, "invalid_token")
 		assert.Error(t, err, "Token refresh should fail with invalid token")
 
 		logger.Info("Invalid refresh token test passed")
@@ -202,4 +206,4 @@ func TestTokenRefresh(t *testing.T) {
 
 	duration := time.Since(startTime)
 	logger.LogTestEnd("TestTokenRefresh", !t.Failed(), duration)
-}
\ No newline at end of file
+}
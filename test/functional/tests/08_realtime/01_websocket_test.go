@@ -0,0 +1,164 @@
+package realtime_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/padminisys/flintroute/pkg/client"
+	"github.com/yourusername/flintroute/test/functional/pkg/testutil"
+)
+
+// TestWebSocketPeerUpdates verifies peer changes are pushed over the WebSocket
+func TestWebSocketPeerUpdates(t *testing.T) {
+	// Setup logger
+	logger, err := testutil.NewTestLogger("../../logs/test.log", "debug")
+	require.NoError(t, err, "Failed to create test logger")
+	defer logger.Close()
+
+	logger.LogTestStart("TestWebSocketPeerUpdates")
+	startTime := time.Now()
+	ctx := context.Background()
+
+	// Create API client
+	apiClient := client.NewAPIClient(testutil.ServerURL("http://localhost:8080"), logger.GetZapLogger())
+
+	// Load fixtures
+	fixtureLoader := testutil.NewFixtureLoader("../../fixtures", logger.GetZapLogger())
+	adminUser, err := fixtureLoader.LoadUser("admin_user")
+	require.NoError(t, err, "Failed to load admin user fixture")
+	peerFixture, err := fixtureLoader.LoadPeer("valid/basic_peer")
+	require.NoError(t, err, "Failed to load peer fixture")
+
+	_, err = apiClient.Login(ctx, adminUser.Username, adminUser.Password)
+	require.NoError(t, err, "Login should succeed")
+
+	sub, err := apiClient.Subscribe(ctx, client.MessagePeerUpdate)
+	require.NoError(t, err, "WebSocket subscription should succeed")
+	defer sub.Close()
+
+	var peerID uint
+
+	// Test: Peer creation is pushed
+	t.Run("peer_created", func(t *testing.T) {
+		logger.Info("Testing peer_update after peer creation")
+
+		created, err := apiClient.CreatePeer(ctx, &client.PeerRequest{
+			Name:        peerFixture.Name,
+			IPAddress:   peerFixture.IPAddress,
+			ASN:         peerFixture.ASN,
+			RemoteASN:   peerFixture.RemoteASN,
+			Description: peerFixture.Description,
+			Enabled:     peerFixture.Enabled,
+		})
+		require.NoError(t, err, "Peer creation should succeed")
+		peerID = created.ID
+
+		waitCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+		defer cancel()
+
+		peer, err := sub.WaitForPeer(waitCtx, func(p *client.Peer) bool { return p.ID == created.ID })
+		require.NoError(t, err, "peer_update should arrive after creation")
+		assert.Equal(t, peerFixture.IPAddress, peer.IPAddress, "IP address should match")
+		assert.Equal(t, peerFixture.RemoteASN, peer.RemoteASN, "Remote ASN should match")
+
+		logger.Info("Peer creation update test passed")
+	})
+
+	// Test: Peer update is pushed
+	t.Run("peer_updated", func(t *testing.T) {
+		require.NotZero(t, peerID, "Peer must have been created")
+		logger.Info("Testing peer_update after peer change")
+
+		description := "Updated over WebSocket test"
+		_, err := apiClient.PatchPeer(ctx, peerID, &client.PeerPatchRequest{Description: &description})
+		require.NoError(t, err, "Peer update should succeed")
+
+		waitCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+		defer cancel()
+
+		peer, err := sub.WaitForPeer(waitCtx, func(p *client.Peer) bool {
+			return p.ID == peerID && p.Description == description
+		})
+		require.NoError(t, err, "peer_update should arrive after update")
+		assert.Equal(t, description, peer.Description, "Description should match")
+
+		logger.Info("Peer change update test passed")
+	})
+
+	if peerID != 0 {
+		assert.NoError(t, apiClient.DeletePeer(ctx, peerID), "Peer cleanup should succeed")
+	}
+
+	duration := time.Since(startTime)
+	logger.LogTestEnd("TestWebSocketPeerUpdates", !t.Failed(), duration)
+}
+
+// TestWebSocketTopicFilter verifies subscriptions only receive their topics
+func TestWebSocketTopicFilter(t *testing.T) {
+	// Setup logger
+	logger, err := testutil.NewTestLogger("../../logs/test.log", "debug")
+	require.NoError(t, err, "Failed to create test logger")
+	defer logger.Close()
+
+	logger.LogTestStart("TestWebSocketTopicFilter")
+	startTime := time.Now()
+	ctx := context.Background()
+
+	// Create API client
+	apiClient := client.NewAPIClient(testutil.ServerURL("http://localhost:8080"), logger.GetZapLogger())
+
+	// Load fixtures
+	fixtureLoader := testutil.NewFixtureLoader("../../fixtures", logger.GetZapLogger())
+	adminUser, err := fixtureLoader.LoadUser("admin_user")
+	require.NoError(t, err, "Failed to load admin user fixture")
+	peerFixture, err := fixtureLoader.LoadPeer("valid/peer_with_multihop")
+	require.NoError(t, err, "Failed to load peer fixture")
+
+	_, err = apiClient.Login(ctx, adminUser.Username, adminUser.Password)
+	require.NoError(t, err, "Login should succeed")
+
+	// Test: Unknown topics are rejected
+	t.Run("unknown_topic", func(t *testing.T) {
+		logger.Info("Testing unknown topic")
+
+		_, err := apiClient.Subscribe(ctx, "bogus")
+		assert.True(t, client.IsBadRequest(err), "Unknown topic should be rejected")
+
+		logger.Info("Unknown topic test passed")
+	})
+
+	// Test: Alert subscription doesn't receive peer updates
+	t.Run("alert_only", func(t *testing.T) {
+		logger.Info("Testing alert-only subscription")
+
+		sub, err := apiClient.Subscribe(ctx, client.MessageAlert)
+		require.NoError(t, err, "WebSocket subscription should succeed")
+		defer sub.Close()
+
+		created, err := apiClient.CreatePeer(ctx, &client.PeerRequest{
+			Name:      peerFixture.Name,
+			IPAddress: peerFixture.IPAddress,
+			ASN:       peerFixture.ASN,
+			RemoteASN: peerFixture.RemoteASN,
+			Enabled:   peerFixture.Enabled,
+			Multihop:  peerFixture.Multihop,
+		})
+		require.NoError(t, err, "Peer creation should succeed")
+		defer apiClient.DeletePeer(ctx, created.ID)
+
+		waitCtx, cancel := context.WithTimeout(ctx, 2*time.Second)
+		defer cancel()
+
+		_, err = sub.WaitFor(waitCtx, func(m *client.StreamMessage) bool { return m.Type == client.MessagePeerUpdate })
+		assert.ErrorIs(t, err, context.DeadlineExceeded, "Alert subscription should not receive peer updates")
+
+		logger.Info("Alert-only subscription test passed")
+	})
+
+	duration := time.Since(startTime)
+	logger.LogTestEnd("TestWebSocketTopicFilter", !t.Failed(), duration)
+}